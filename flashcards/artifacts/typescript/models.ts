@@ -0,0 +1,1972 @@
+// Code generated by go run ./cmd/tsgen; DO NOT EDIT.
+// TypeScript types for the flashcards API, generated from the Go models.
+
+/** Kinds of activity feed items */
+export const ActivityFlashcardsCreated = "flashcards_created";
+export const ActivityCardsReviewed = "cards_reviewed";
+export const ActivitySessionCompleted = "session_completed";
+export const ActivityImportFinished = "import_finished";
+
+/**
+ * API key scopes. A read-only key can make GET requests; a generate-quiz
+ * key can generate quizzes and start and take quiz sessions.
+ */
+export const APIKeyScopeReadOnly = "read-only";
+export const APIKeyScopeGenerateQuiz = "generate-quiz";
+
+export const AttachmentStatusPending = "pending";
+export const AttachmentStatusReady = "ready";
+
+export const DefaultBrandingName = "Flashcards";
+export const DefaultBrandingPrimaryColor = "#2563eb";
+export const DefaultBrandingAccentColor = "#f59e0b";
+
+/** Curator decisions on a similarity flag */
+export const FlagStatusPending = "pending";
+export const FlagStatusDismissed = "dismissed";
+export const FlagStatusConfirmed = "confirmed";
+
+export const AgeLevelChildren = "children";
+export const AgeLevelTeen = "teen";
+export const AgeLevelAdult = "adult";
+
+export const DeadLetterStatusRetrying = "retrying";
+export const DeadLetterStatusDead = "dead";
+export const DeadLetterStatusResolved = "resolved";
+
+/** Kinds of background work that can fail into the dead-letter queue */
+export const DeadLetterKindStripeWebhook = "stripe-webhook";
+export const DeadLetterKindSimilarityCheck = "similarity-check";
+export const DeadLetterKindImportJob = "import-job";
+
+/** Kinds of deck maintenance suggestions */
+export const SuggestionDuplicate = "duplicate";
+export const SuggestionLeech = "leech";
+export const SuggestionStale = "stale";
+export const SuggestionUntagged = "untagged";
+
+/** Statuses of a deck maintenance suggestion */
+export const SuggestionOpen = "open";
+export const SuggestionApplied = "applied";
+export const SuggestionDismissed = "dismissed";
+
+/** Kinds of content an embed can show */
+export const EmbedKindFlashcard = "flashcard";
+export const EmbedKindDeck = "deck";
+
+/** Import job statuses. A failed job can be resumed. */
+export const ImportJobStatusRunning = "running";
+export const ImportJobStatusFailed = "failed";
+export const ImportJobStatusCompleted = "completed";
+
+/** What an import job creates */
+export const ImportKindNotes = "notes";
+
+export const JobStatusQueued = "queued";
+export const JobStatusRunning = "running";
+export const JobStatusCompleted = "completed";
+export const JobStatusFailed = "failed";
+
+/** Kinds of background job */
+export const JobKindGenerateFlashcards = "generate-flashcards";
+export const JobKindSummarizeNotes = "summarize-notes";
+export const JobKindReembedCatalog = "reembed-catalog";
+
+/** Types of the JSON messages exchanged over a live quiz WebSocket */
+export const LiveQuizMessageStart = "start";
+export const LiveQuizMessageResume = "resume";
+export const LiveQuizMessageAnswer = "answer";
+export const LiveQuizMessageSkip = "skip";
+export const LiveQuizMessageSession = "session";
+export const LiveQuizMessageQuestion = "question";
+export const LiveQuizMessageResult = "result";
+export const LiveQuizMessageComplete = "complete";
+export const LiveQuizMessageError = "error";
+export const LiveQuizMessagePing = "ping";
+export const LiveQuizMessagePong = "pong";
+
+export const MaintenanceModeOff = "off";
+export const MaintenanceModeReadOnly = "read-only";
+export const MaintenanceModeFull = "full";
+
+/** Plan tiers an organization can be on */
+export const PlanFree = "free";
+export const PlanPro = "pro";
+export const PlanEnterprise = "enterprise";
+
+/**
+ * Roles a user can hold in their organization. Owners and admins can invite
+ * members.
+ */
+export const RoleOwner = "owner";
+export const RoleAdmin = "admin";
+export const RoleMember = "member";
+
+/** Stripe subscription statuses the plan mapping acts on */
+export const SubscriptionActive = "active";
+export const SubscriptionTrialing = "trialing";
+export const SubscriptionPastDue = "past_due";
+export const SubscriptionUnpaid = "unpaid";
+
+/**
+ * Where the prompt template in use was loaded from, from lowest to highest
+ * precedence
+ */
+export const PromptSourceBuiltIn = "builtin";
+export const PromptSourceFile = "file";
+export const PromptSourceDatabase = "database";
+
+/** How the difficulty of generated questions was chosen */
+export const DifficultySourceOption = "option";
+export const DifficultySourceMessage = "message";
+export const DifficultySourceCalibrated = "calibrated";
+export const DifficultySourceDefault = "default";
+
+export const SessionStatusInProgress = "in_progress";
+export const SessionStatusCompleted = "completed";
+export const QuestionStatusUnanswered = "unanswered";
+export const QuestionStatusAnswered = "answered";
+export const QuestionStatusSkipped = "skipped";
+export const QuestionSourceGenerated = "generated";
+export const QuestionSourceBank = "question-bank";
+export const QuestionSourceCache = "cache";
+export const QueuedSessionStatusQueued = "queued";
+export const QueuedSessionStatusCompleted = "completed";
+export const QueuedSessionStatusFailed = "failed";
+
+/** REST hook triggers, named the way Zapier and Make expect */
+export const RestHookTriggerNewNote = "new_note";
+export const RestHookTriggerQuizCompleted = "quiz_completed";
+export const RestHookTriggerLowScore = "low_score";
+
+export const RatingAgain = "again";
+export const RatingHard = "hard";
+export const RatingGood = "good";
+export const RatingEasy = "easy";
+export const CardStateNew = "new";
+export const CardStateLearning = "learning";
+export const CardStateReview = "review";
+export const CardStateRelearning = "relearning";
+export const ReviewModeScheduled = "scheduled";
+export const ReviewModeCram = "cram";
+export const ReviewOrderDue = "due";
+export const ReviewOrderRandom = "random";
+export const ReviewOrderHardestFirst = "hardest-first";
+export const ReviewOrderBySubdeck = "by-subdeck";
+export const ReviewOrderInterleaved = "interleaved";
+export const NewCardOrderSequential = "sequential";
+export const NewCardOrderRandom = "random";
+export const NewCardPositionAfterReviews = "after-reviews";
+export const NewCardPositionBeforeReviews = "before-reviews";
+export const NewCardPositionMixed = "mixed";
+export const SyncStatusApplied = "applied";
+export const SyncStatusDuplicate = "duplicate";
+export const SyncStatusConflict = "conflict";
+export const SyncStatusRejected = "rejected";
+export const LeechActionTag = "tag";
+export const LeechActionSuspend = "suspend";
+
+/** Scopes spend is measured at */
+export const SpendScopeUser = "user";
+export const SpendScopeOrganization = "organization";
+
+/** CEFR proficiency levels used when generating example sentences */
+export const CEFRLevelA1 = "A1";
+export const CEFRLevelA2 = "A2";
+export const CEFRLevelB1 = "B1";
+export const CEFRLevelB2 = "B2";
+export const CEFRLevelC1 = "C1";
+export const CEFRLevelC2 = "C2";
+
+/** Events a webhook can subscribe to */
+export const WebhookEventNoteCreated = "note.created";
+export const WebhookEventQuizGenerated = "quiz.generated";
+export const WebhookEventQuizCompleted = "quiz.completed";
+export const WebhookEventReviewCompleted = "review.completed";
+
+/**
+ * Webhooks are registered through /webhooks, and REST hooks by automation
+ * platforms such as Zapier through /hooks
+ */
+export const WebhookKindWebhook = "webhook";
+export const WebhookKindRestHook = "rest-hook";
+
+export const WebhookDeliveryStatusPending = "pending";
+export const WebhookDeliveryStatusDelivered = "delivered";
+export const WebhookDeliveryStatusFailed = "failed";
+
+/** ActivityFeed lists what a user did over the last Days days, newest first */
+export interface ActivityFeed {
+  days: number;
+  since: string;
+  items: ActivityItem[];
+}
+
+/**
+ * ActivityItem is one entry of the activity feed. Flashcards created and
+ * reviewed are grouped per deck and day, with OccurredAt the latest of the
+ * group. Count is the cards created or reviewed, the questions of a quiz
+ * session or the items an import processed. Correct is the session's
+ * correct answers, or the reviewed cards not rated again. ResourceID is
+ * the quiz session or import job, and Status how the import ended.
+ */
+export interface ActivityItem {
+  type: string;
+  occurredAt: string;
+  count: number;
+  correct?: number;
+  deckId?: number;
+  resourceId?: number;
+  status?: string;
+}
+
+/**
+ * RequestStats aggregates requests to one route by one user within an hour.
+ * UserID is 0 for unauthenticated requests.
+ */
+export interface RequestStats {
+  bucket: string;
+  route: string;
+  method: string;
+  userId: number;
+  requests: number;
+  clientErrors: number;
+  serverErrors: number;
+  totalLatencyMs: number;
+  maxLatencyMs: number;
+}
+
+/** RouteAnalytics summarises one route over the requested window */
+export interface RouteAnalytics {
+  route: string;
+  method: string;
+  requests: number;
+  clientErrors: number;
+  serverErrors: number;
+  errorRate: number;
+  avgLatencyMs: number;
+  maxLatencyMs: number;
+  users: number;
+}
+
+/** UserAnalytics summarises one user's requests over the requested window */
+export interface UserAnalytics {
+  userId: number;
+  requests: number;
+  clientErrors: number;
+  serverErrors: number;
+  errorRate: number;
+  avgLatencyMs: number;
+  maxLatencyMs: number;
+  routes: number;
+}
+
+/**
+ * APIKey authenticates a programmatic client as its user, within its
+ * scopes. Key is only set in the response creating it.
+ */
+export interface APIKey {
+  id: number;
+  userId: number;
+  name: string;
+  prefix: string;
+  key?: string;
+  scopes: string[];
+  requests: number;
+  lastUsedAt: string | null;
+  createdAt: string;
+  revokedAt?: string;
+}
+
+export interface CreateAPIKeyRequest {
+  name: string;
+  scopes: string[];
+}
+
+/** APIKeyUsage is the number of requests made with a key on one day */
+export interface APIKeyUsage {
+  day: string;
+  requests: number;
+}
+
+/**
+ * Attachment is an uploaded file owned by a user, such as the photo of a
+ * handwritten answer. Its data is kept in Postgres, or in object storage
+ * under StorageKey when that is configured, and shared with every other
+ * attachment with the same ContentHash.
+ */
+export interface Attachment {
+  id: number;
+  userId: number;
+  filename: string;
+  contentType: string;
+  size: number;
+  status: string;
+  createdAt: string;
+}
+
+/**
+ * AttachmentBlob is the content of the attachments with one SHA-256 hash,
+ * stored once. RefCount is the number of attachments referencing it.
+ */
+export interface AttachmentBlob {
+  hash: string;
+  contentType: string;
+  size: number;
+  refCount: number;
+  releasedAt?: string;
+  createdAt: string;
+}
+
+/**
+ * CreateAttachmentUploadRequest describes a file the client is about to
+ * upload straight to object storage
+ */
+export interface CreateAttachmentUploadRequest {
+  filename: string;
+  contentType: string;
+  size: number;
+}
+
+/**
+ * AttachmentUpload tells the client where to upload the file: a PUT of its
+ * bytes to UploadURL with Headers, before ExpiresAt. The upload is then
+ * completed to make the attachment ready.
+ */
+export interface AttachmentUpload {
+  attachment: Attachment | null;
+  uploadUrl: string;
+  method: string;
+  headers: Record<string, string>;
+  expiresAt: string;
+}
+
+/**
+ * Branding is how the deployment presents itself, so clients can be themed
+ * without being forked. Empty URLs and support email are unset.
+ */
+export interface Branding {
+  name: string;
+  logoUrl: string;
+  primaryColor: string;
+  accentColor: string;
+  supportEmail: string;
+  supportUrl: string;
+  updatedAt: string;
+}
+
+export interface UpdateBrandingRequest {
+  name?: string;
+  logoUrl?: string;
+  primaryColor?: string;
+  accentColor?: string;
+  supportEmail?: string;
+  supportUrl?: string;
+}
+
+/** PublishedDeck is a deck listed in the public catalog */
+export interface PublishedDeck {
+  deckId: number;
+  userId: number;
+  name: string;
+  description: string;
+  cardCount: number;
+  publishedAt: string;
+}
+
+/**
+ * SimilarityFlag marks a published deck whose cards nearly duplicate
+ * another published deck's. MatchedCards of its TotalCards had a near
+ * duplicate in the matched deck.
+ */
+export interface SimilarityFlag {
+  id: number;
+  deckId: number;
+  matchedDeckId: number;
+  matchedCards: number;
+  totalCards: number;
+  maxSimilarity: number;
+  status: string;
+  createdAt: string;
+  reviewedAt: string | null;
+}
+
+/** Confirming a flag removes the copy from the catalog */
+export interface ReviewSimilarityFlagRequest {
+  status: string;
+}
+
+export interface ContentFilterSettings {
+  enabled: boolean;
+  ageLevel: string;
+  bannedTopics: string[];
+  updatedAt: string;
+}
+
+export interface UpdateContentFilterRequest {
+  enabled?: boolean;
+  ageLevel?: string;
+  bannedTopics?: string[];
+}
+
+/**
+ * DailyQuestionSubscription emails the user one question from a deck each
+ * morning. LastSessionID is the one-question quiz session last sent.
+ */
+export interface DailyQuestionSubscription {
+  id: number;
+  userId: number;
+  deckId: number;
+  lastSessionId?: number;
+  lastSentAt?: string;
+  createdAt: string;
+}
+
+export interface CreateDailyQuestionSubscriptionRequest {
+  deckId: number;
+}
+
+/**
+ * AnswerDailyQuestionRequest answers the question from a daily question
+ * email with the token from one of its links
+ */
+export interface AnswerDailyQuestionRequest {
+  token: string;
+  answer: string;
+}
+
+/**
+ * DailyQuestionReply is an email reply to a daily question, as forwarded by
+ * the mail provider's inbound webhook. The token is found in the quoted
+ * original.
+ */
+export interface DailyQuestionReply {
+  text: string;
+}
+
+/**
+ * DailyQuestionResult is the graded answer to a daily question. Correct is
+ * nil for questions that cannot be graded automatically.
+ */
+export interface DailyQuestionResult {
+  sessionId: number;
+  question: string;
+  answer: string;
+  correct: boolean | null;
+  correctAnswer?: string;
+  explanation?: string;
+}
+
+/**
+ * DeadLetter is a piece of background work that failed. Key identifies
+ * the work within its kind, such as a Stripe event or import job ID, and
+ * Payload holds what a retry needs. NextAttemptAt is only set while the
+ * entry is retrying.
+ */
+export interface DeadLetter {
+  id: number;
+  kind: string;
+  key: string;
+  userId?: number;
+  payload: unknown;
+  error: string;
+  attempts: number;
+  status: string;
+  nextAttemptAt?: string;
+  createdAt: string;
+  updatedAt: string;
+  resolvedAt?: string;
+}
+
+export interface Deck {
+  id: number;
+  userId: number;
+  parentId: number | null;
+  name: string;
+  description: string;
+  reviewOrder: string;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface CreateDeckRequest {
+  name: string;
+  description: string;
+  parentId?: number;
+  reviewOrder?: string;
+}
+
+/** A ParentID of 0 moves the deck to the top level */
+export interface UpdateDeckRequest {
+  name?: string;
+  description?: string;
+  parentId?: number;
+  reviewOrder?: string;
+}
+
+/**
+ * DeckTerminology is the course vocabulary injected into generation prompts
+ * for notes in a deck
+ */
+export interface DeckTerminology {
+  deckId: number;
+  preferredTerms: PreferredTerm[];
+  abbreviations: Abbreviation[];
+  doNotSimplify: string[];
+  stopWords: string[];
+  updatedAt: string;
+}
+
+/** PreferredTerm is a term to use instead of its alternatives */
+export interface PreferredTerm {
+  term: string;
+  avoid?: string[];
+}
+
+export interface Abbreviation {
+  short: string;
+  expansion: string;
+}
+
+export interface UpdateDeckTerminologyRequest {
+  preferredTerms?: PreferredTerm[];
+  abbreviations?: Abbreviation[];
+  doNotSimplify?: string[];
+  stopWords?: string[];
+}
+
+/**
+ * DeckStudySettings controls how a deck introduces new cards and the
+ * learning steps, such as "1m" and "10m", cards climb before they graduate
+ * to day intervals. Relearning steps apply after a lapse. A card becomes a
+ * leech after LeechThreshold lapses, and LeechAction decides what happens
+ * to it then; a threshold of 0 turns leech detection off.
+ */
+export interface DeckStudySettings {
+  deckId: number;
+  learningSteps: string[];
+  relearningSteps: string[];
+  graduatingIntervalDays: number;
+  easyIntervalDays: number;
+  newCardsPerDay: number;
+  newCardOrder: string;
+  newCardPosition: string;
+  leechThreshold: number;
+  leechAction: string;
+  updatedAt: string;
+}
+
+/** Omitted fields take their defaults; an empty steps list skips that ladder */
+export interface UpdateDeckStudySettingsRequest {
+  learningSteps?: string[];
+  relearningSteps?: string[];
+  graduatingIntervalDays?: number;
+  easyIntervalDays?: number;
+  newCardsPerDay?: number;
+  newCardOrder?: string;
+  newCardPosition?: string;
+  leechThreshold?: number;
+  leechAction?: string;
+}
+
+/**
+ * StoredDeckExport is a deck export written to object storage, downloaded
+ * from a pre-signed URL until it expires
+ */
+export interface StoredDeckExport {
+  id: number;
+  userId: number;
+  deckId: number | null;
+  format: string;
+  filename: string;
+  contentType: string;
+  size: number;
+  expiresAt: string;
+  createdAt: string;
+  /** Pre-signed and short-lived; fetch the export again for a fresh one */
+  downloadUrl: string;
+}
+
+/**
+ * DeckImportItem is one card of an imported file. Row is its line in a CSV
+ * file or its position among the notes of an Anki package. ExistingID is
+ * the flashcard it duplicates or conflicts with.
+ */
+export interface DeckImportItem {
+  row: number;
+  deck?: string;
+  front?: string;
+  flashcardId?: number;
+  existingId?: number;
+  reason?: string;
+}
+
+/**
+ * DeckImportDeck is a deck that receives imported cards. New decks are
+ * created by the import, so DeckID is only set for them once they exist.
+ */
+export interface DeckImportDeck {
+  path: string;
+  deckId?: number;
+  new: boolean;
+}
+
+/**
+ * DeckImportReport lists every card of the file as created, skipped or in
+ * conflict with a card that has the same front but a different back
+ */
+export interface DeckImportReport {
+  validateOnly: boolean;
+  summary: ImportSummary;
+  decks: DeckImportDeck[];
+  created: DeckImportItem[];
+  skipped: DeckImportItem[];
+  conflicts: DeckImportItem[];
+}
+
+/**
+ * DeckSuggestion is a maintenance change the weekly analysis proposes for
+ * a deck, and Action what applying it does:
+ *   - duplicate: cards with the same content, ignoring case; applying keeps
+ *     the oldest and deletes the rest
+ *   - leech: a card forgotten again and again; applying resets its
+ *     schedule so it is learned from scratch
+ *   - stale: cards generated from a note edited since; applying generates
+ *     as many new cards from the note and deletes these
+ *   - untagged: cards whose notes have no tags; applying tags the notes
+ *     with the deck's name
+ */
+export interface DeckSuggestion {
+  id: number;
+  userId: number;
+  deckId: number;
+  kind: string;
+  flashcardIds: number[];
+  noteIds: number[];
+  action: string;
+  status: string;
+  createdAt: string;
+  resolvedAt?: string;
+}
+
+/**
+ * AppliedSuggestion is a suggestion once applied, with the cards applying
+ * it created and deleted
+ */
+export interface AppliedSuggestion {
+  suggestion: DeckSuggestion;
+  created: Flashcard[];
+  deletedIds: number[];
+}
+
+/**
+ * DigestCard is a card for a user's daily review digest: one failed in a
+ * review during the day or added that day
+ */
+export interface DigestCard {
+  userId: number;
+  email: string;
+  flashcard: Flashcard | null;
+  failed: boolean;
+}
+
+/**
+ * RedeemReviewLinkRequest exchanges the token from a digest email's review
+ * link for a session
+ */
+export interface RedeemReviewLinkRequest {
+  token: string;
+}
+
+/**
+ * ReviewLinkSession signs the user in for a review of the cards a review
+ * link was sent for
+ */
+export interface ReviewLinkSession {
+  auth: AuthResponse | null;
+  queue: DueQueue | null;
+}
+
+/**
+ * Embed publishes one flashcard or a preview of one deck, read-only, to
+ * anyone with its token. Token is only returned when the embed is created.
+ */
+export interface Embed {
+  id: number;
+  userId: number;
+  kind: string;
+  flashcardId: number | null;
+  deckId: number | null;
+  token?: string;
+  createdAt: string;
+}
+
+/** Exactly one of FlashcardID and DeckID must be set */
+export interface CreateEmbedRequest {
+  flashcardId?: number;
+  deckId?: number;
+}
+
+/**
+ * EmbedContent is what viewers of an embed see: a single card, or a deck's
+ * name and description with its first few cards
+ */
+export interface EmbedContent {
+  kind: string;
+  deckName?: string;
+  description?: string;
+  cardCount: number;
+  cards: FlashcardHTML[];
+}
+
+/**
+ * Flashcard is a card with a front to prompt recall and a back with the
+ * answer. Mnemonic holds memory aids generated for the card, one per line.
+ * Content holds both sides in one string, "Q: <front>\nA: <back>"
+ * or the front alone for a card without a back, for clients that predate
+ * the separate sides; it is kept in step with them. Version goes up by one
+ * with every update and is sent as the ETag, which an update must name in
+ * If-Match.
+ */
+export interface Flashcard {
+  id: number;
+  userId: number;
+  deckId: number | null;
+  front: string;
+  back: string;
+  hint?: string;
+  mnemonic?: string;
+  sourceNoteId?: number;
+  content: string;
+  version: number;
+  createdAt: string;
+  updatedAt: string;
+}
+
+/**
+ * CreateFlashcardRequest takes the card's sides, or for older clients its
+ * Content, which is split into sides the same way stored content is
+ */
+export interface CreateFlashcardRequest {
+  front?: string;
+  back?: string;
+  hint?: string;
+  sourceNoteId?: number;
+  content?: string;
+  deckId?: number;
+}
+
+/**
+ * An empty Back or Hint clears it, and a DeckID or SourceNoteID of 0
+ * removes the link. Content replaces both sides and cannot be combined
+ * with Front or Back.
+ */
+export interface UpdateFlashcardRequest {
+  front?: string;
+  back?: string;
+  hint?: string;
+  sourceNoteId?: number;
+  content?: string;
+  deckId?: number;
+}
+
+export interface GenerateFlashcardsRequest {
+  count?: number;
+}
+
+/**
+ * FlashcardHTML is a flashcard's sides rendered from Markdown to sanitized
+ * HTML. Back is empty for cards without a separate answer.
+ */
+export interface FlashcardHTML {
+  flashcardId: number;
+  front: string;
+  back?: string;
+}
+
+/**
+ * IdempotencyRecord is the stored outcome of a request sent with an
+ * Idempotency-Key. StatusCode is nil while the request is in progress.
+ */
+export interface IdempotencyRecord {
+  userId: number;
+  key: string;
+  requestHash: string;
+  statusCode: number | null;
+  createdAt: string;
+  expiresAt: string;
+}
+
+/**
+ * ImportJob is a large import committed in chunks in the background.
+ * ProcessedItems of TotalItems have been created so far.
+ */
+export interface ImportJob {
+  id: number;
+  userId: number;
+  kind: string;
+  status: string;
+  deckId: number | null;
+  chunkSize: number;
+  totalItems: number;
+  processedItems: number;
+  totalChunks: number;
+  committedChunks: number;
+  skipped: NoteImportItem[];
+  error: string | null;
+  createdAt: string;
+  updatedAt: string;
+  completedAt: string | null;
+}
+
+/**
+ * Job is expensive work queued to run in the background. Result is set
+ * once it completes and Error while it is retrying or after it failed.
+ */
+export interface Job {
+  id: number;
+  userId: number;
+  kind: string;
+  payload: unknown;
+  status: string;
+  result?: unknown;
+  error?: string;
+  attempts: number;
+  createdAt: string;
+  updatedAt: string;
+  startedAt?: string;
+  completedAt?: string;
+}
+
+export interface CreateJobRequest {
+  kind: string;
+  payload?: unknown;
+}
+
+/**
+ * GenerateFlashcardsJobPayload generates Count flashcards from each of the
+ * user's notes
+ */
+export interface GenerateFlashcardsJobPayload {
+  count?: number;
+  skipExisting?: boolean;
+}
+
+/**
+ * SummarizeNotesJobPayload summarizes the user's notes again, or only
+ * those without a summary when OnlyMissing is set
+ */
+export interface SummarizeNotesJobPayload {
+  onlyMissing?: boolean;
+}
+
+/**
+ * LiveQuizMessage is one message of a live quiz. Type says which of the
+ * other fields are set.
+ */
+export interface LiveQuizMessage {
+  type: string;
+  sessionId?: number;
+  /** start: the bearer token the connection signs in with */
+  token?: string;
+  /**
+   * resume, and sent with session and every question: a token that lets a
+   * dropped connection rejoin the session without signing in again
+   */
+  resumeToken?: string;
+  /**
+   * answer and skip: the position of the question, which must be the one
+   * served last; result: the position graded
+   */
+  position?: number;
+  answer?: string;
+  timeSpentMs?: number;
+  /**
+   * question: the question to answer, without its answer key, and how
+   * many questions are left including it
+   */
+  question?: SessionQuestion;
+  remaining?: number;
+  /** result: nil Correct means the answer cannot be graded automatically */
+  correct?: boolean;
+  correctAnswer?: string;
+  explanation?: string;
+  report?: SessionReport;
+  error?: string;
+}
+
+/**
+ * Maintenance is whether the API is in maintenance, with what clients show
+ * in a banner meanwhile. EndsAt is when it is expected to end, if known.
+ */
+export interface Maintenance {
+  mode: string;
+  message?: string;
+  startedAt?: string;
+  endsAt?: string;
+  updatedAt: string;
+}
+
+export interface UpdateMaintenanceRequest {
+  mode: string;
+  message?: string;
+  endsAt?: string;
+}
+
+/**
+ * ServiceMeta tells clients what the server can currently do, so they can
+ * hide features instead of failing on them
+ */
+export interface ServiceMeta {
+  capabilities: ServiceCapabilities;
+  llm: LLMStatus;
+  /** Set while the API is in maintenance */
+  maintenance?: Maintenance;
+}
+
+export interface ServiceCapabilities {
+  /** Questions, flashcards and feedback can be generated on demand */
+  generation: boolean;
+  /** Quiz sessions can be started from previously generated questions */
+  questionBank: boolean;
+  /** Session requests are queued while generation is unavailable */
+  generationQueue: boolean;
+}
+
+/**
+ * LLMStatus is the state of the LLM provider as seen by this server.
+ * RetryAfterSeconds is when the next call is let through to check whether
+ * the provider is back.
+ */
+export interface LLMStatus {
+  available: boolean;
+  unavailableSince?: string;
+  retryAfterSeconds?: number;
+}
+
+/**
+ * Note is a user's study note. Version goes up by one with every update
+ * and is sent as the ETag, which an update must name in If-Match.
+ */
+export interface Note {
+  id: number;
+  userId: number;
+  deckId: number | null;
+  title: string;
+  /**
+   * Summary is written by the LLM after the note is created or its
+   * content changes, and is empty until then
+   */
+  summary: string;
+  /** Source is where the note came from, such as a URL or a book chapter */
+  source: string;
+  /**
+   * Language is the ISO 639-1 code of the note's language, detected
+   * from its content unless set, and empty until it is known
+   */
+  language: string;
+  content: string;
+  tags: string[];
+  version: number;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface CreateNoteRequest {
+  title?: string;
+  source?: string;
+  /** Language overrides detection, as an ISO 639-1 code */
+  language?: string;
+  content: string;
+  deckId?: number;
+}
+
+/** A DeckID of 0 removes the note from its deck */
+export interface UpdateNoteRequest {
+  title?: string;
+  source?: string;
+  /** An empty Language detects it again from the content */
+  language?: string;
+  content?: string;
+  deckId?: number;
+}
+
+/**
+ * NoteImportItem is one section of an imported file, or a file that could
+ * not be read. Line is where the section starts. NoteID is only set for
+ * notes that were actually created.
+ */
+export interface NoteImportItem {
+  file: string;
+  line?: number;
+  heading?: string;
+  noteId?: number;
+  length?: number;
+  reason?: string;
+}
+
+/**
+ * ImportSummary counts the outcome of every section or row of an import.
+ * QuotaError is set when a validate-only import would exceed the quota.
+ */
+export interface ImportSummary {
+  total: number;
+  importable: number;
+  duplicates: number;
+  conflicts: number;
+  errors: number;
+  quotaError?: string;
+}
+
+export interface NoteImportReport {
+  validateOnly: boolean;
+  summary: ImportSummary;
+  created: NoteImportItem[];
+  skipped: NoteImportItem[];
+}
+
+/** OnboardingStep is one item of the checklist shown to new users */
+export interface OnboardingStep {
+  key: string;
+  title: string;
+  done: boolean;
+}
+
+export interface OnboardingChecklist {
+  steps: OnboardingStep[];
+  completed: number;
+  total: number;
+  done: boolean;
+}
+
+/**
+ * Limits caps what an organization can store and spend. Zero means
+ * unlimited.
+ */
+export interface Limits {
+  maxNotes: number;
+  maxCards: number;
+  monthlyLlmTokens: number;
+}
+
+/**
+ * Organization groups users under a plan. Each override, when set, replaces
+ * the plan's limit for this organization. The Stripe fields are set once the
+ * organization subscribes through billing.
+ */
+export interface Organization {
+  id: number;
+  name: string;
+  plan: string;
+  maxNotesOverride: number | null;
+  maxCardsOverride: number | null;
+  monthlyLlmTokensOverride: number | null;
+  subscriptionStatus?: string;
+  createdAt: string;
+  updatedAt: string;
+}
+
+/**
+ * Usage is what an organization, or a user outside one, has stored and
+ * spent this month
+ */
+export interface Usage {
+  notes: number;
+  cards: number;
+  llmTokens: number;
+}
+
+/**
+ * Quota reports the limits in effect next to current usage. OrganizationID
+ * is nil for users outside an organization.
+ */
+export interface Quota {
+  organizationId: number | null;
+  plan: string;
+  subscriptionStatus?: string;
+  limits: Limits;
+  usage: Usage;
+  month: string;
+}
+
+export interface OrganizationDetails {
+  organization: Organization | null;
+  quota: Quota | null;
+}
+
+export interface CreateOrganizationRequest {
+  name: string;
+  plan?: string;
+}
+
+/**
+ * UpdateQuotaRequest changes an organization's plan and limit overrides.
+ * Omitted fields are unchanged; ResetOverrides clears all overrides first.
+ */
+export interface UpdateQuotaRequest {
+  plan?: string;
+  maxNotes?: number;
+  maxCards?: number;
+  monthlyLlmTokens?: number;
+  resetOverrides?: boolean;
+}
+
+/** AddOrganizationMemberRequest adds a user with Role, member by default */
+export interface AddOrganizationMemberRequest {
+  userId: number;
+  role?: string;
+}
+
+/** Membership is the organization a user belongs to and their role in it */
+export interface Membership {
+  organizationId: number;
+  role: string;
+}
+
+/**
+ * OrganizationInvite lets the holder of its token join an organization with
+ * Role. Token is only returned when the invite is created.
+ */
+export interface OrganizationInvite {
+  id: number;
+  organizationId: number;
+  email: string;
+  role: string;
+  invitedBy: number | null;
+  acceptedBy?: number;
+  expiresAt: string;
+  acceptedAt?: string;
+  createdAt: string;
+  token?: string;
+  url?: string;
+  emailSent: boolean;
+}
+
+export interface CreateInviteRequest {
+  email: string;
+  role?: string;
+}
+
+/**
+ * CreateOwnedOrganizationRequest creates an organization on the free plan
+ * with the caller as its owner
+ */
+export interface CreateOwnedOrganizationRequest {
+  name: string;
+}
+
+/**
+ * MembershipDetails is returned when a user creates or joins an
+ * organization
+ */
+export interface MembershipDetails {
+  organization: Organization | null;
+  role: string;
+}
+
+export interface CreateCheckoutRequest {
+  plan: string;
+}
+
+/** CheckoutSession is a Stripe-hosted checkout page the client redirects to */
+export interface CheckoutSession {
+  id: string;
+  url: string;
+}
+
+/** ListParams controls paging and ordering of list endpoints */
+export interface ListParams {
+  limit: number;
+  offset: number;
+  sort: string;
+  order: string;
+}
+
+/** Page wraps one page of a list response */
+export interface Page<T> {
+  items: T[];
+  total: number;
+  limit: number;
+  offset: number;
+  next?: string;
+}
+
+export interface ProgressReport {
+  periodStart: string;
+  periodEnd: string;
+  sessionsCompleted: number;
+  questionsAnswered: number;
+  accuracy: number;
+  previousAccuracy: number;
+  /** "up", "down" or "flat" */
+  accuracyTrend: string;
+  currentStreakDays: number;
+  dailyActivity: DailyActivity[];
+}
+
+export interface DailyActivity {
+  date: string;
+  answered: number;
+  correct: number;
+}
+
+export interface ReportRecipient {
+  id: number;
+  userId: number;
+  email: string;
+  createdAt: string;
+}
+
+export interface CreateReportRecipientRequest {
+  email: string;
+}
+
+export interface PromptTemplate {
+  name: string;
+  version: number;
+  source: string;
+  template: string;
+  createdAt?: string;
+}
+
+export interface UpdatePromptTemplateRequest {
+  template: string;
+}
+
+export interface Message {
+  /** "user" or "assistant" */
+  role: string;
+  content: string;
+  question?: QuestionData;
+}
+
+export interface QuestionData {
+  id: string;
+  text: string;
+  /** "multiple-choice", "essay", etc. */
+  type: string;
+  options?: string[];
+  correctAnswer?: string;
+  explanation?: string;
+  difficulty: string;
+  basedOnNotes: number[];
+  imageUrl?: string;
+  imageAltText?: string;
+  accessibility?: AccessibilityMetadata;
+}
+
+/**
+ * AccessibilityMetadata helps clients serve questions to screen readers and
+ * learners who need simpler wording
+ */
+export interface AccessibilityMetadata {
+  /** OptionLabels are spoken-friendly versions of Options, index-aligned */
+  optionLabels?: string[];
+  /**
+   * PlainLanguageText is a simplified rewording of the question stem,
+   * generated on demand
+   */
+  plainLanguageText?: string;
+}
+
+export interface EssayFeedback {
+  questionId: string;
+  score: number;
+  maxScore: number;
+  feedback: string;
+}
+
+/**
+ * DifficultyCalibration is the difficulty chosen for generated questions and
+ * why. Accuracy and Answers describe the recent answers a calibrated
+ * difficulty was based on.
+ */
+export interface DifficultyCalibration {
+  difficulty: string;
+  source: string;
+  accuracy?: number;
+  answers?: number;
+}
+
+export interface QuizSession {
+  id: number;
+  userId: number;
+  status: string;
+  currentPosition: number;
+  questions: SessionQuestion[];
+  createdAt: string;
+  updatedAt: string;
+  /**
+   * Set on a newly created session whose questions were generated
+   * rather than given, see QuestionSourceGenerated
+   */
+  questionSource?: string;
+}
+
+export interface SessionQuestion {
+  position: number;
+  question: QuestionData;
+  status: string;
+  answer: string;
+  flagged: boolean;
+  timeSpentMs: number;
+  updatedAt: string;
+  /** Set when the answer was submitted as an image */
+  attachmentId?: number;
+  grading?: AnswerGrading;
+}
+
+/**
+ * AnswerGrading is the result of grading an image answer with a vision
+ * model. Score gives partial credit for the work shown.
+ */
+export interface AnswerGrading {
+  finalAnswer: string;
+  correct: boolean;
+  score: number;
+  maxScore: number;
+  feedback: string;
+}
+
+/**
+ * CreateQuizSessionRequest starts a session over the given questions or,
+ * when Questions is empty, over Count questions generated from the selected
+ * notes and decks
+ */
+export interface CreateQuizSessionRequest {
+  questions: QuestionData[];
+  noteIds?: number[];
+  deckIds?: number[];
+  count?: number;
+  difficulty?: string;
+  questionType?: string;
+}
+
+/**
+ * QueuedQuizSession is a request for a generated session that arrived while
+ * the LLM provider was unavailable. SessionID is set once it is completed.
+ */
+export interface QueuedQuizSession {
+  id: number;
+  userId: number;
+  request: CreateQuizSessionRequest;
+  status: string;
+  sessionId?: number;
+  error?: string;
+  attempts: number;
+  nextAttemptAt?: string;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface UpdateSessionQuestionRequest {
+  answer?: string;
+  status?: string;
+  flagged?: boolean;
+  timeSpentMs?: number;
+}
+
+/**
+ * SessionReport summarizes a quiz session, listing flagged questions with
+ * the follow-up actions available for each.
+ */
+export interface SessionReport {
+  sessionId: number;
+  status: string;
+  totalQuestions: number;
+  answered: number;
+  skipped: number;
+  unanswered: number;
+  correct: number;
+  incorrect: number;
+  ungraded: number;
+  scorePercent: number;
+  totalTimeMs: number;
+  results: QuestionResult[];
+  weakTopics: string[];
+  flagged: FlaggedItem[];
+  generatedAt: string;
+  completedAt?: string;
+}
+
+/**
+ * QuestionResult is the outcome of one question. Correct is nil for
+ * questions that cannot be graded automatically, such as essays.
+ */
+export interface QuestionResult {
+  position: number;
+  questionText: string;
+  type: string;
+  difficulty: string;
+  status: string;
+  answer: string;
+  correctAnswer?: string;
+  correct: boolean | null;
+  explanation?: string;
+  timeSpentMs: number;
+  grading?: AnswerGrading;
+}
+
+export interface EmailReportRequest {
+  to: string;
+  format?: string;
+}
+
+export interface FlaggedItem {
+  position: number;
+  question: QuestionData;
+  answer: string;
+  actions: string[];
+}
+
+export interface QuestionExplanation {
+  questionId: string;
+  explanation: string;
+}
+
+/**
+ * SubscribeRestHookRequest is what an automation platform sends when a
+ * user turns on a zap that uses one of the triggers
+ */
+export interface SubscribeRestHookRequest {
+  hookUrl: string;
+  event: string;
+}
+
+/**
+ * RestHook is a subscription to one trigger. The platform keeps ID to
+ * unsubscribe when the zap is turned off.
+ */
+export interface RestHook {
+  id: number;
+  hookUrl: string;
+  event: string;
+  createdAt: string;
+}
+
+/**
+ * NoteTrigger is the new_note payload. Trigger payloads are flat, with lists
+ * joined by commas, and their fields are only ever added to, so automations
+ * built on them keep working.
+ */
+export interface NoteTrigger {
+  id: number;
+  title: string;
+  content: string;
+  source: string;
+  language: string;
+  tags: string;
+  deckId: number | null;
+  createdAt: string;
+}
+
+/**
+ * QuizTrigger is the quiz_completed and low_score payload. ID is the quiz
+ * session's.
+ */
+export interface QuizTrigger {
+  id: number;
+  totalQuestions: number;
+  answered: number;
+  correct: number;
+  incorrect: number;
+  scorePercent: number;
+  weakTopics: string;
+  completedAt: string;
+}
+
+/**
+ * Schedule is the spaced-repetition state of a flashcard. Step is the
+ * card's position on the learning or relearning ladder. Leech marks a card
+ * lapsed so often it needs rewriting, and a suspended card is left out of
+ * the review queues until it is unsuspended.
+ */
+export interface Schedule {
+  flashcardId: number;
+  state: string;
+  dueAt: string;
+  intervalDays: number;
+  easeFactor: number;
+  repetitions: number;
+  lapses: number;
+  step: number;
+  leech: boolean;
+  suspended: boolean;
+  lastReviewedAt: string | null;
+  updatedAt: string;
+}
+
+/** Review is one answered card in the review log */
+export interface Review {
+  id: number;
+  userId: number;
+  flashcardId: number;
+  mode: string;
+  rating: string;
+  previousState: string;
+  intervalDays: number;
+  easeFactor: number;
+  dueAt: string;
+  reviewedAt: string;
+  /** Set for reviews synced from an offline client */
+  clientId?: string;
+}
+
+export interface DueCard {
+  flashcard: Flashcard | null;
+  schedule: Schedule | null;
+  rendered?: RenderedCard;
+}
+
+/**
+ * RenderedCard is a flashcard split into the sides shown during review,
+ * with the media a client should preload before showing it. Mnemonic is
+ * only set for cards the learner has found hard.
+ */
+export interface RenderedCard {
+  front: string;
+  back: string;
+  hint?: string;
+  mnemonic?: string;
+  audioUrl?: string;
+  imageUrls?: string[];
+}
+
+/**
+ * DueQueue is the ordered list of cards to review now. Total counts the
+ * cards due, with new cards capped at what is left of the daily limit. In
+ * cram mode the queue covers every card regardless of due date.
+ */
+export interface DueQueue {
+  deckId: number | null;
+  mode: string;
+  order: string;
+  total: number;
+  newCards: number;
+  cards: DueCard[];
+}
+
+/** Mode defaults to scheduled */
+export interface SubmitReviewRequest {
+  flashcardId: number;
+  rating: string;
+  mode?: string;
+}
+
+/**
+ * NewLeech is set when this review made the card a leech, for clients to
+ * offer reformulating it
+ */
+export interface ReviewResult {
+  review: Review | null;
+  schedule: Schedule | null;
+  newLeech?: boolean;
+}
+
+/**
+ * SyncReview is a review answered while offline. ClientID identifies it
+ * across retries and ReviewedAt is when it was answered on the client.
+ */
+export interface SyncReview {
+  clientId: string;
+  flashcardId: number;
+  rating: string;
+  mode?: string;
+  reviewedAt: string;
+}
+
+export interface SyncReviewsRequest {
+  reviews: SyncReview[];
+}
+
+/** SyncReviewResult reports what happened to one synced review */
+export interface SyncReviewResult {
+  clientId: string;
+  status: string;
+  error?: string;
+  review?: Review;
+  schedule?: Schedule;
+}
+
+/** SyncReviewsResponse lists results in the order the reviews were applied */
+export interface SyncReviewsResponse {
+  applied: number;
+  duplicates: number;
+  conflicts: number;
+  rejected: number;
+  results: SyncReviewResult[];
+}
+
+/**
+ * RoutingRule maps a generation request to a provider and model. Empty
+ * match fields act as wildcards; rules are evaluated in ascending priority
+ * order. OrganizationID matches the members of one organization and Plan
+ * the users on a plan, where users outside an organization are on the
+ * free plan. An empty Provider keeps the configured LLM_PROVIDER.
+ */
+export interface RoutingRule {
+  id: number;
+  questionType?: string;
+  difficulty?: string;
+  organizationId?: number;
+  plan?: string;
+  provider?: string;
+  model: string;
+  priority: number;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface CreateRoutingRuleRequest {
+  questionType?: string;
+  difficulty?: string;
+  organizationId?: number;
+  plan?: string;
+  provider?: string;
+  model: string;
+  priority?: number;
+}
+
+/**
+ * SpendAnomaly records a spike in token usage over the trailing baseline.
+ * SubjectID is a user or organization id depending on Scope.
+ */
+export interface SpendAnomaly {
+  id: number;
+  scope: string;
+  subjectId: number;
+  recentTokens: number;
+  baselineTokensPerHour: number;
+  detectedAt: string;
+  throttledUntil: string | null;
+}
+
+/**
+ * StudyAnalytics summarizes a user's flashcard reviews and quiz answers
+ * over the last Days days
+ */
+export interface StudyAnalytics {
+  days: number;
+  since: string;
+  dailyReviews: DailyReviews[];
+  accuracyByDifficulty: DifficultyAccuracy[];
+  currentStreakDays: number;
+  longestStreakDays: number;
+  mostMissedNotes: MissedNote[];
+}
+
+/**
+ * DailyReviews counts the flashcard reviews of one day; Recalled excludes
+ * cards rated again
+ */
+export interface DailyReviews {
+  date: string;
+  reviewed: number;
+  recalled: number;
+}
+
+/**
+ * DifficultyAccuracy is the share of graded quiz answers that were correct
+ * for questions of one difficulty
+ */
+export interface DifficultyAccuracy {
+  difficulty: string;
+  graded: number;
+  correct: number;
+  accuracy: number;
+}
+
+/** MissedNote is a note behind quiz questions answered wrongly */
+export interface MissedNote {
+  noteId: number;
+  excerpt: string;
+  missed: number;
+  graded: number;
+  missRate: number;
+}
+
+export interface Tag {
+  id: number;
+  name: string;
+  noteCount: number;
+  createdAt: string;
+}
+
+export interface AddTagsRequest {
+  tags: string[];
+}
+
+export interface Todo {
+  id: number;
+  userId: number;
+  title: string;
+  description: string;
+  completed: boolean;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface CreateTodoRequest {
+  title: string;
+  description: string;
+}
+
+export interface UpdateTodoRequest {
+  title?: string;
+  description?: string;
+  completed?: boolean;
+}
+
+/**
+ * LLMCall is one metered call to the LLM provider. CostUSD is estimated
+ * from the model's price per token.
+ */
+export interface LLMCall {
+  id: number;
+  userId: number;
+  model: string;
+  inputTokens: number;
+  outputTokens: number;
+  costUsd: number;
+  createdAt: string;
+}
+
+/** LLMUsageTotals adds up calls, tokens and cost */
+export interface LLMUsageTotals {
+  calls: number;
+  inputTokens: number;
+  outputTokens: number;
+  costUsd: number;
+}
+
+/** DailyLLMUsage is a user's LLM usage on one day (UTC) */
+export interface DailyLLMUsage {
+  day: string;
+  calls: number;
+  inputTokens: number;
+  outputTokens: number;
+  costUsd: number;
+}
+
+/** ModelLLMUsage is a user's LLM usage of one model */
+export interface ModelLLMUsage {
+  model: string;
+  calls: number;
+  inputTokens: number;
+  outputTokens: number;
+  costUsd: number;
+}
+
+/** UserLLMUsage is one user's LLM usage over a window */
+export interface UserLLMUsage {
+  userId: number;
+  calls: number;
+  inputTokens: number;
+  outputTokens: number;
+  costUsd: number;
+}
+
+/** LLMBudget is the user's spend this month against the monthly budget */
+export interface LLMBudget {
+  month: string;
+  monthlyUsd: number;
+  spentUsd: number;
+  remainingUsd: number;
+  exceeded: boolean;
+}
+
+/**
+ * LLMUsageSummary reports the user's LLM usage since From, in total, per
+ * day and per model. Budget is set when a monthly budget is configured.
+ */
+export interface LLMUsageSummary {
+  from: string;
+  total: LLMUsageTotals;
+  days: DailyLLMUsage[];
+  models: ModelLLMUsage[];
+  budget?: LLMBudget;
+}
+
+export interface User {
+  id: number;
+  email: string;
+  isAdmin: boolean;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface RegisterRequest {
+  email: string;
+  password: string;
+}
+
+export interface LoginRequest {
+  email: string;
+  password: string;
+}
+
+export interface AuthResponse {
+  token: string;
+  expiresAt: string;
+  user: User | null;
+}
+
+/** Vocabulary holds the language-learning details of a flashcard */
+export interface Vocabulary {
+  flashcardId: number;
+  term: string;
+  language: string;
+  ipa: string;
+  pronunciation: string;
+  cefrLevel: string;
+  examples: ExampleSentence[];
+  hasAudio: boolean;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface ExampleSentence {
+  text: string;
+  translation?: string;
+}
+
+export interface UpdateVocabularyRequest {
+  term?: string;
+  language?: string;
+  ipa?: string;
+  pronunciation?: string;
+}
+
+export interface GenerateExamplesRequest {
+  cefrLevel: string;
+  count?: number;
+}
+
+/**
+ * Webhook POSTs the events it subscribes to to URL. Secret signs each
+ * payload and is only returned when the webhook is created. A REST hook
+ * subscribes to one trigger and is sent the trigger payload alone.
+ */
+export interface Webhook {
+  id: number;
+  userId: number;
+  kind: string;
+  url: string;
+  secret?: string;
+  events: string[];
+  active: boolean;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface CreateWebhookRequest {
+  url: string;
+  events: string[];
+}
+
+/** UpdateWebhookRequest changes only the fields that are set */
+export interface UpdateWebhookRequest {
+  url?: string;
+  events?: string[];
+  active?: boolean;
+}
+
+/**
+ * WebhookDelivery is one event sent to a webhook, with the outcome of its
+ * last attempt. NextAttemptAt is only set while it is pending.
+ */
+export interface WebhookDelivery {
+  id: number;
+  webhookId: number;
+  event: string;
+  payload: unknown;
+  status: string;
+  attempts: number;
+  responseStatus?: number;
+  error?: string;
+  nextAttemptAt?: string;
+  createdAt: string;
+  updatedAt: string;
+  deliveredAt?: string;
+}
+
+/**
+ * QuizGeneratedEvent is the data of a quiz.generated event. Source tells
+ * generated questions from ones served from the question bank.
+ */
+export interface QuizGeneratedEvent {
+  noteIds: number[];
+  source: string;
+  difficulty?: string;
+  questions: QuestionData[];
+}
+
+/**
+ * WebhookEvent is the body POSTed to a webhook. ID is the delivery's, so
+ * receivers can tell a retry from a new event.
+ */
+export interface WebhookEvent {
+  id: number;
+  event: string;
+  createdAt: string;
+  data: unknown;
+}
+
+/** Request and Response structs local to the handler */
+export interface QuizRequest {
+  noteIds: number[];
+  conversation: Message[];
+  options: {
+    difficulty?: string;
+    questionType?: string;
+    compressionTarget?: number;
+    count?: number;
+    forceRegenerate?: boolean;
+  };
+}
+
+export interface QuizResponse {
+  success: boolean;
+  data: QuizResponseData;
+  metadata: QuizMetadata;
+}
+
+export interface QuizResponseData {
+  conversation: Message[];
+}
+
+export interface QuizMetadata {
+  generatedAt: string;
+  tokensUsed: number;
+  processingTimeMs: number;
+  compressionRatio: number;
+  context: ContextUsage;
+  /**
+   * "question-bank" when the LLM was unavailable and earlier questions
+   * were served instead, "cache" when the same quiz was generated recently
+   */
+  source: string;
+  /**
+   * The difficulty used and whether it came from the options, the message
+   * or the user's recent answers
+   */
+  difficulty: DifficultyCalibration;
+}
+
+export interface EssayGradeRequest {
+  question: QuestionData;
+  answer: string;
+}
+
+/**
+ * ContextUsage reports how notes content was fitted into the model's
+ * context window. DroppedTokens is the part of the notes no prompt saw.
+ */
+export interface ContextUsage {
+  model: string;
+  contextWindow: number;
+  noteTokens: number;
+  promptTokens: number;
+  droppedTokens: number;
+  chunks: number;
+}
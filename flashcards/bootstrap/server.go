@@ -0,0 +1,159 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+type shutdownHook struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// Server runs the HTTP server until SIGINT or SIGTERM, then shuts down in
+// order: readiness turns false, streaming requests are cancelled, in-flight
+// requests drain for up to the shutdown timeout, and the registered hooks
+// run in reverse order of registration.
+type Server struct {
+	httpServer      *http.Server
+	shutdownTimeout time.Duration
+	hooks           []shutdownHook
+	ready           atomic.Bool
+
+	streamCtx    context.Context
+	cancelStream context.CancelFunc
+}
+
+func NewServer(addr string, shutdownTimeout time.Duration) *Server {
+	streamCtx, cancelStream := context.WithCancel(context.Background())
+	return &Server{
+		httpServer: &http.Server{
+			Addr:              addr,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		shutdownTimeout: shutdownTimeout,
+		streamCtx:       streamCtx,
+		cancelStream:    cancelStream,
+	}
+}
+
+// OnShutdown registers a hook to run after requests have drained. Hooks run
+// in reverse order, so register resources before the things that use them.
+func (s *Server) OnShutdown(name string, run func(ctx context.Context) error) {
+	s.hooks = append(s.hooks, shutdownHook{name: name, run: run})
+}
+
+// Close registers a resource, such as a database pool, to close on shutdown
+func (s *Server) Close(name string, closer interface{ Close() error }) {
+	s.OnShutdown(name, func(ctx context.Context) error {
+		return closer.Close()
+	})
+}
+
+// Ready reports whether the server is accepting traffic
+func (s *Server) Ready() bool {
+	return s.ready.Load()
+}
+
+// ReadinessHandler answers 200 while serving and 503 once shutdown starts,
+// so load balancers stop routing new requests here
+func (s *Server) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !s.Ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"status": "shutting down"}`))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "ready"}`))
+}
+
+// Streaming is middleware for long-lived responses such as LLM streams.
+// Their contexts are cancelled as soon as shutdown starts instead of being
+// left to run out the drain timeout.
+func (s *Server) Streaming(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		stop := context.AfterFunc(s.streamCtx, cancel)
+		defer stop()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Run serves handler until the process is signalled or the server fails,
+// then shuts down. It returns an error only when the server could not start
+// or serve.
+func (s *Server) Run(handler http.Handler) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s.httpServer.Handler = handler
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.httpServer.ListenAndServe()
+	}()
+	s.ready.Store(true)
+	slog.Info("Server starting", "addr", s.httpServer.Addr)
+
+	var err error
+	select {
+	case err = <-serveErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+	case <-ctx.Done():
+		slog.Info("Shutdown signal received")
+	}
+
+	s.Shutdown()
+	return err
+}
+
+// Shutdown stops serving and runs the shutdown hooks. Run calls it once
+// signalled; call it directly when the handler was served another way, as
+// tests do.
+func (s *Server) Shutdown() {
+	s.ready.Store(false)
+	start := time.Now()
+
+	s.cancelStream()
+
+	ctx := context.Background()
+	if s.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.shutdownTimeout)
+		defer cancel()
+	}
+
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		slog.Warn("Requests did not drain before the shutdown timeout", "error", err)
+		s.httpServer.Close()
+	}
+
+	// Hooks get their own deadline so resources still close after a slow drain
+	hookCtx := context.Background()
+	if s.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		hookCtx, cancel = context.WithTimeout(hookCtx, s.shutdownTimeout)
+		defer cancel()
+	}
+
+	for i := len(s.hooks) - 1; i >= 0; i-- {
+		hook := s.hooks[i]
+		if err := hook.run(hookCtx); err != nil {
+			slog.Error("Shutdown step failed", "step", hook.name, "error", err)
+		}
+	}
+
+	slog.Info("Server stopped", "duration_ms", time.Since(start).Milliseconds())
+}
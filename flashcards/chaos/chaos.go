@@ -0,0 +1,98 @@
+// Package chaos injects faults at configurable rates so the retry,
+// circuit-breaker and degradation paths can be exercised outside of real
+// outages. It is opt-in and meant for local and staging runs: every rate
+// defaults to zero, and with all rates zero nothing is wrapped.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// ErrInjected marks a fault that was injected rather than real
+var ErrInjected = errors.New("chaos: injected fault")
+
+// Config sets how often each fault is injected, as a share of calls from
+// 0 to 1
+type Config struct {
+	// Requests and LLM calls are delayed by up to Latency
+	LatencyRate float64
+	Latency     time.Duration
+	// LLM calls fail as if the provider were down, or answer with output
+	// that is not the JSON asked for
+	LLMErrorRate     float64
+	LLMMalformedRate float64
+	// Database queries, statements and transactions fail before reaching
+	// the database
+	DBErrorRate float64
+}
+
+func (c Config) Enabled() bool {
+	return c.LatencyRate > 0 || c.LLMErrorRate > 0 || c.LLMMalformedRate > 0 || c.DBErrorRate > 0
+}
+
+// Injector decides, call by call, whether to inject a fault. A nil Injector
+// never does.
+type Injector struct {
+	cfg Config
+}
+
+// New returns an Injector for the config, or nil when it injects nothing
+func New(cfg Config) *Injector {
+	if !cfg.Enabled() {
+		return nil
+	}
+	return &Injector{cfg: cfg}
+}
+
+func (i *Injector) Config() Config {
+	if i == nil {
+		return Config{}
+	}
+	return i.cfg
+}
+
+// Delay sleeps for a random time up to the configured latency at
+// LatencyRate, returning early with the context's error when it is done
+func (i *Injector) Delay(ctx context.Context) error {
+	if i == nil || i.cfg.Latency <= 0 || !roll(i.cfg.LatencyRate) {
+		return nil
+	}
+
+	timer := time.NewTimer(rand.N(i.cfg.Latency) + 1)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// LLMError reports whether this LLM call should fail
+func (i *Injector) LLMError() bool {
+	return i != nil && roll(i.cfg.LLMErrorRate)
+}
+
+// MalformLLMOutput returns the output cut short at LLMMalformedRate, so it is
+// no longer the JSON the caller asked for, and unchanged otherwise
+func (i *Injector) MalformLLMOutput(output string) string {
+	if i == nil || !roll(i.cfg.LLMMalformedRate) {
+		return output
+	}
+	if len(output) < 2 {
+		return "{"
+	}
+	return output[:rand.N(len(output)-1)+1]
+}
+
+// DBError reports whether this database call should fail
+func (i *Injector) DBError() bool {
+	return i != nil && roll(i.cfg.DBErrorRate)
+}
+
+func roll(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"flashcards/models"
+)
+
+// Register creates an account and uses its token for later requests
+func (c *Client) Register(ctx context.Context, email, password string) (*models.AuthResponse, error) {
+	var auth models.AuthResponse
+	req := &request{method: http.MethodPost, path: "/auth/register", body: &models.RegisterRequest{Email: email, Password: password}}
+	if _, err := c.do(ctx, req, &auth); err != nil {
+		return nil, err
+	}
+	c.SetToken(auth.Token)
+	return &auth, nil
+}
+
+// Login signs in and uses the token for later requests
+func (c *Client) Login(ctx context.Context, email, password string) (*models.AuthResponse, error) {
+	var auth models.AuthResponse
+	req := &request{method: http.MethodPost, path: "/auth/login", body: &models.LoginRequest{Email: email, Password: password}}
+	if _, err := c.do(ctx, req, &auth); err != nil {
+		return nil, err
+	}
+	c.SetToken(auth.Token)
+	return &auth, nil
+}
+
+func (c *Client) CurrentUser(ctx context.Context) (*models.User, error) {
+	var user models.User
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: "/auth/me"}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Meta reports which features the server can offer right now
+func (c *Client) Meta(ctx context.Context) (*models.ServiceMeta, error) {
+	var meta models.ServiceMeta
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: "/meta"}, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
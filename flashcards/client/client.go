@@ -0,0 +1,299 @@
+// Package client is a Go client for the flashcards HTTP API, shared by the
+// command line tools and other Go programs that talk to the server.
+//
+//	c := client.New(client.Config{BaseURL: "http://localhost:8080"})
+//	if _, err := c.Login(ctx, "me@example.com", "password"); err != nil { ... }
+//	note, err := c.CreateNote(ctx, &models.CreateNoteRequest{Content: "..."})
+//
+// Requests that the server turned away without acting on them, because of
+// rate limiting or an unavailable LLM provider, are retried after the delay
+// the server asks for. Requests that are safe to repeat are also retried
+// after network errors and gateway failures.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_TIMEOUT     = 2 * time.Minute
+	DEFAULT_MAX_RETRIES = 3
+	DEFAULT_RETRY_WAIT  = 500 * time.Millisecond
+	// A retry the server asks to wait longer for than this is not attempted
+	DEFAULT_MAX_RETRY_WAIT = 30 * time.Second
+
+	IDEMPOTENCY_KEY_HEADER = "Idempotency-Key"
+	REQUEST_ID_HEADER      = "X-Request-ID"
+)
+
+// Config configures a Client. Zero values select the defaults; a negative
+// MaxRetries disables retries.
+type Config struct {
+	BaseURL string
+	// Token is sent as a bearer token. Register and Login replace it.
+	Token        string
+	HTTPClient   *http.Client
+	MaxRetries   int
+	RetryWait    time.Duration
+	MaxRetryWait time.Duration
+}
+
+// Client calls the API. It is safe for concurrent use.
+type Client struct {
+	baseURL      string
+	httpClient   *http.Client
+	maxRetries   int
+	retryWait    time.Duration
+	maxRetryWait time.Duration
+
+	mu    sync.RWMutex
+	token string
+}
+
+func New(cfg Config) *Client {
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DEFAULT_TIMEOUT}
+	}
+	maxRetries := cfg.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = DEFAULT_MAX_RETRIES
+	}
+	retryWait := cfg.RetryWait
+	if retryWait <= 0 {
+		retryWait = DEFAULT_RETRY_WAIT
+	}
+	maxRetryWait := cfg.MaxRetryWait
+	if maxRetryWait <= 0 {
+		maxRetryWait = DEFAULT_MAX_RETRY_WAIT
+	}
+
+	return &Client{
+		baseURL:      strings.TrimRight(cfg.BaseURL, "/"),
+		httpClient:   httpClient,
+		maxRetries:   max(maxRetries, 0),
+		retryWait:    retryWait,
+		maxRetryWait: maxRetryWait,
+		token:        cfg.Token,
+	}
+}
+
+// Token returns the bearer token requests are sent with
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// APIError is an error response from the server
+type APIError struct {
+	StatusCode int
+	Message    string
+	RequestID  string
+	// RetryAfter is set when the server said when to try again
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("flashcards API: %d %s (request %s)", e.StatusCode, e.Message, e.RequestID)
+	}
+	return fmt.Sprintf("flashcards API: %d %s", e.StatusCode, e.Message)
+}
+
+// IsStatus reports whether err is an APIError with the status code
+func IsStatus(err error, statusCode int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
+
+// request is one API call. Its body is encoded once so it can be resent.
+type request struct {
+	method string
+	path   string
+	body   any
+	// idempotencyKey, when set, makes a POST safe to resend
+	idempotencyKey string
+	accept         string
+	// version, when set, is sent as If-Match so an update is refused if
+	// the resource changed since it was read
+	version int
+}
+
+// safeToRepeat reports whether sending the request twice has the same effect
+// as sending it once, so it can be retried when the outcome is unknown
+func (r *request) safeToRepeat() bool {
+	switch r.method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return r.idempotencyKey != ""
+}
+
+// do sends the request and decodes a successful JSON response into out,
+// when set. It returns the response status code.
+func (c *Client) do(ctx context.Context, req *request, out any) (int, error) {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to decode %s %s response: %w", req.method, req.path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// send sends the request, retrying as the retry policy allows, and returns
+// the first successful response with its body unread. Error responses are
+// returned as *APIError.
+func (c *Client) send(ctx context.Context, req *request) (*http.Response, error) {
+	var payload []byte
+	if req.body != nil {
+		var err error
+		if payload, err = json.Marshal(req.body); err != nil {
+			return nil, fmt.Errorf("failed to encode %s %s request: %w", req.method, req.path, err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(ctx, req, payload)
+		if err == nil && resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		var wait time.Duration
+		retry := false
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			retry = req.safeToRepeat()
+		} else {
+			apiErr := readAPIError(resp)
+			err = apiErr
+			retry, wait = c.retryable(req, apiErr)
+		}
+
+		if !retry || attempt >= c.maxRetries {
+			return nil, err
+		}
+		if wait == 0 {
+			wait = c.retryWait << attempt
+		}
+		if wait > c.maxRetryWait {
+			return nil, err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, req *request, payload []byte) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, c.baseURL+req.path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build %s %s request: %w", req.method, req.path, err)
+	}
+
+	if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	accept := req.accept
+	if accept == "" {
+		accept = "application/json"
+	}
+	httpReq.Header.Set("Accept", accept)
+	if token := c.Token(); token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+	if req.idempotencyKey != "" {
+		httpReq.Header.Set(IDEMPOTENCY_KEY_HEADER, req.idempotencyKey)
+	}
+	if req.version > 0 {
+		httpReq.Header.Set("If-Match", strconv.Quote(strconv.Itoa(req.version)))
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", req.method, req.path, err)
+	}
+	return resp, nil
+}
+
+// retryable decides whether an error response is worth retrying and how
+// long the server asked to wait. Rate limited requests and those refused
+// while the LLM provider is down were not acted on, so any request can be
+// resent; after a gateway error only requests safe to repeat are.
+func (c *Client) retryable(req *request, apiErr *APIError) (bool, time.Duration) {
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true, apiErr.RetryAfter
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return req.safeToRepeat(), apiErr.RetryAfter
+	}
+	return false, 0
+}
+
+// readAPIError reads the {"error": "..."} body the server answers errors
+// with and closes it
+func readAPIError(resp *http.Response) *APIError {
+	defer resp.Body.Close()
+
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    http.StatusText(resp.StatusCode),
+		RequestID:  resp.Header.Get(REQUEST_ID_HEADER),
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	var body struct {
+		Error string `json:"error"`
+	}
+	if data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10)); err == nil {
+		if json.Unmarshal(data, &body) == nil && body.Error != "" {
+			apiErr.Message = body.Error
+		}
+	}
+	return apiErr
+}
+
+// newIdempotencyKey returns a random key identifying one logical request
+// across its retries
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
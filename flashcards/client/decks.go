@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"flashcards/models"
+)
+
+func (c *Client) CreateDeck(ctx context.Context, req *models.CreateDeckRequest) (*models.Deck, error) {
+	var deck models.Deck
+	if _, err := c.do(ctx, &request{method: http.MethodPost, path: "/decks", body: req}, &deck); err != nil {
+		return nil, err
+	}
+	return &deck, nil
+}
+
+func (c *Client) GetDeck(ctx context.Context, id int) (*models.Deck, error) {
+	var deck models.Deck
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: fmt.Sprintf("/decks/%d", id)}, &deck); err != nil {
+		return nil, err
+	}
+	return &deck, nil
+}
+
+func (c *Client) ListDecks(ctx context.Context) ([]*models.Deck, error) {
+	var decks []*models.Deck
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: "/decks"}, &decks); err != nil {
+		return nil, err
+	}
+	return decks, nil
+}
+
+func (c *Client) UpdateDeck(ctx context.Context, id int, req *models.UpdateDeckRequest) (*models.Deck, error) {
+	var deck models.Deck
+	if _, err := c.do(ctx, &request{method: http.MethodPut, path: fmt.Sprintf("/decks/%d", id), body: req}, &deck); err != nil {
+		return nil, err
+	}
+	return &deck, nil
+}
+
+func (c *Client) DeleteDeck(ctx context.Context, id int) error {
+	_, err := c.do(ctx, &request{method: http.MethodDelete, path: fmt.Sprintf("/decks/%d", id)}, nil)
+	return err
+}
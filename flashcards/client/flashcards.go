@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+)
+
+func (c *Client) CreateFlashcard(ctx context.Context, req *models.CreateFlashcardRequest) (*models.Flashcard, error) {
+	var flashcard models.Flashcard
+	if _, err := c.do(ctx, &request{method: http.MethodPost, path: "/flashcards", body: req}, &flashcard); err != nil {
+		return nil, err
+	}
+	return &flashcard, nil
+}
+
+func (c *Client) GetFlashcard(ctx context.Context, id int) (*models.Flashcard, error) {
+	var flashcard models.Flashcard
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: fmt.Sprintf("/flashcards/%d", id)}, &flashcard); err != nil {
+		return nil, err
+	}
+	return &flashcard, nil
+}
+
+func (c *Client) ListFlashcards(ctx context.Context, filter models.FlashcardFilter, params models.ListParams) (*models.Page[*models.Flashcard], error) {
+	query := listQuery(params)
+	if filter.DeckID != nil {
+		query.Set("deckId", strconv.Itoa(*filter.DeckID))
+	}
+	if filter.Query != "" {
+		query.Set("q", filter.Query)
+	}
+
+	var page models.Page[*models.Flashcard]
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: withQuery("/flashcards", query)}, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// UpdateFlashcard updates the flashcard if it is still at version, the
+// Version of the flashcard as last read. A flashcard changed since fails
+// with a 409 APIError.
+func (c *Client) UpdateFlashcard(ctx context.Context, id, version int, req *models.UpdateFlashcardRequest) (*models.Flashcard, error) {
+	var flashcard models.Flashcard
+	if _, err := c.do(ctx, &request{method: http.MethodPut, path: fmt.Sprintf("/flashcards/%d", id), body: req, version: version}, &flashcard); err != nil {
+		return nil, err
+	}
+	return &flashcard, nil
+}
+
+func (c *Client) DeleteFlashcard(ctx context.Context, id int) error {
+	_, err := c.do(ctx, &request{method: http.MethodDelete, path: fmt.Sprintf("/flashcards/%d", id)}, nil)
+	return err
+}
+
+// GenerateFlashcards has the LLM extract up to count flashcards from a note;
+// 0 lets the server choose
+func (c *Client) GenerateFlashcards(ctx context.Context, noteID, count int) ([]*models.Flashcard, error) {
+	var flashcards []*models.Flashcard
+	req := &request{method: http.MethodPost, path: fmt.Sprintf("/notes/%d/generate-flashcards", noteID), body: &models.GenerateFlashcardsRequest{Count: count}}
+	if _, err := c.do(ctx, req, &flashcards); err != nil {
+		return nil, err
+	}
+	return flashcards, nil
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"flashcards/models"
+)
+
+func (c *Client) CreateNote(ctx context.Context, req *models.CreateNoteRequest) (*models.Note, error) {
+	var note models.Note
+	if _, err := c.do(ctx, &request{method: http.MethodPost, path: "/notes", body: req}, &note); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+func (c *Client) GetNote(ctx context.Context, id int) (*models.Note, error) {
+	var note models.Note
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: fmt.Sprintf("/notes/%d", id)}, &note); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// ListNotes returns one page of notes; pass Offset from the previous page
+// plus its Limit to fetch the next one
+func (c *Client) ListNotes(ctx context.Context, filter models.NoteFilter, params models.ListParams) (*models.Page[*models.Note], error) {
+	query := listQuery(params)
+	if filter.Tag != "" {
+		query.Set("tag", filter.Tag)
+	}
+	if filter.DeckID != nil {
+		query.Set("deckId", strconv.Itoa(*filter.DeckID))
+	}
+	if filter.Query != "" {
+		query.Set("q", filter.Query)
+	}
+	if filter.Language != "" {
+		query.Set("language", filter.Language)
+	}
+
+	var page models.Page[*models.Note]
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: withQuery("/notes", query)}, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// UpdateNote updates the note if it is still at version, the Version of
+// the note as last read. A note changed since fails with a 409 APIError.
+func (c *Client) UpdateNote(ctx context.Context, id, version int, req *models.UpdateNoteRequest) (*models.Note, error) {
+	var note models.Note
+	if _, err := c.do(ctx, &request{method: http.MethodPut, path: fmt.Sprintf("/notes/%d", id), body: req, version: version}, &note); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+func (c *Client) DeleteNote(ctx context.Context, id int) error {
+	_, err := c.do(ctx, &request{method: http.MethodDelete, path: fmt.Sprintf("/notes/%d", id)}, nil)
+	return err
+}
+
+func listQuery(params models.ListParams) url.Values {
+	query := url.Values{}
+	if params.Limit > 0 {
+		query.Set("limit", strconv.Itoa(params.Limit))
+	}
+	if params.Offset > 0 {
+		query.Set("offset", strconv.Itoa(params.Offset))
+	}
+	if params.Sort != "" {
+		query.Set("sort", params.Sort)
+	}
+	if params.Order != "" {
+		query.Set("order", params.Order)
+	}
+	return query
+}
+
+func withQuery(path string, query url.Values) string {
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
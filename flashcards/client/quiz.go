@@ -0,0 +1,173 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"flashcards/models"
+)
+
+// QuizRequest asks for questions continuing a quiz conversation. The last
+// message of the conversation must be from the user.
+type QuizRequest struct {
+	NoteIds      []int            `json:"noteIds"`
+	Conversation []models.Message `json:"conversation"`
+	Options      QuizOptions      `json:"options"`
+}
+
+type QuizOptions struct {
+	Difficulty        string `json:"difficulty,omitempty"`
+	QuestionType      string `json:"questionType,omitempty"`
+	CompressionTarget int    `json:"compressionTarget,omitempty"`
+	Count             int    `json:"count,omitempty"`
+}
+
+// QuizResponse carries the conversation with the new questions appended
+type QuizResponse struct {
+	Success bool `json:"success"`
+	Data    struct {
+		Conversation []models.Message `json:"conversation"`
+	} `json:"data"`
+	Metadata QuizMetadata `json:"metadata"`
+}
+
+type QuizMetadata struct {
+	GeneratedAt      string                       `json:"generatedAt"`
+	TokensUsed       int                          `json:"tokensUsed"`
+	ProcessingTimeMs int                          `json:"processingTimeMs"`
+	CompressionRatio float64                      `json:"compressionRatio"`
+	Context          ContextUsage                 `json:"context"`
+	Source           string                       `json:"source"`
+	Difficulty       models.DifficultyCalibration `json:"difficulty"`
+}
+
+// ContextUsage reports how the notes were fitted into the model's context
+// window
+type ContextUsage struct {
+	Model         string `json:"model"`
+	ContextWindow int    `json:"contextWindow"`
+	NoteTokens    int    `json:"noteTokens"`
+	PromptTokens  int    `json:"promptTokens"`
+	DroppedTokens int    `json:"droppedTokens"`
+	Chunks        int    `json:"chunks"`
+}
+
+// GenerateQuiz generates the next questions of a quiz conversation. Each
+// call is sent with its own Idempotency-Key, so retries never generate, or
+// bill, the questions twice.
+func (c *Client) GenerateQuiz(ctx context.Context, req *QuizRequest) (*QuizResponse, error) {
+	var resp QuizResponse
+	call := &request{method: http.MethodPost, path: "/notes/generate-quiz", body: req, idempotencyKey: newIdempotencyKey()}
+	if _, err := c.do(ctx, call, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// PlainLanguageVariant returns the question with a simplified wording of its
+// stem in its accessibility metadata
+func (c *Client) PlainLanguageVariant(ctx context.Context, question models.QuestionData) (*models.QuestionData, error) {
+	var updated models.QuestionData
+	if _, err := c.do(ctx, &request{method: http.MethodPost, path: "/quiz/questions/plain-language", body: question}, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// EssayStream receives essay feedback as it is generated. Either callback
+// may be nil; an error returned from one stops reading the stream.
+type EssayStream struct {
+	OnScore    func(score, maxScore int) error
+	OnFeedback func(text string) error
+}
+
+// GradeEssay grades an essay answer, passing the score and then the
+// feedback to stream as the server sends them, and returns the complete
+// result
+func (c *Client) GradeEssay(ctx context.Context, question models.QuestionData, answer string, stream EssayStream) (*models.EssayFeedback, error) {
+	body := map[string]any{"question": question, "answer": answer}
+	resp, err := c.send(ctx, &request{method: http.MethodPost, path: "/quiz/grade-essay", body: body, accept: "text/event-stream"})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var feedback *models.EssayFeedback
+	err = readEvents(resp, func(event string, data []byte) error {
+		switch event {
+		case "score":
+			var score struct {
+				Score    int `json:"score"`
+				MaxScore int `json:"maxScore"`
+			}
+			if err := json.Unmarshal(data, &score); err != nil {
+				return fmt.Errorf("failed to decode score event: %w", err)
+			}
+			if stream.OnScore != nil {
+				return stream.OnScore(score.Score, score.MaxScore)
+			}
+		case "feedback":
+			var chunk struct {
+				Text string `json:"text"`
+			}
+			if err := json.Unmarshal(data, &chunk); err != nil {
+				return fmt.Errorf("failed to decode feedback event: %w", err)
+			}
+			if stream.OnFeedback != nil {
+				return stream.OnFeedback(chunk.Text)
+			}
+		case "error":
+			var failure struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal(data, &failure)
+			return &APIError{StatusCode: resp.StatusCode, Message: failure.Error, RequestID: resp.Header.Get(REQUEST_ID_HEADER)}
+		case "done":
+			feedback = &models.EssayFeedback{}
+			if err := json.Unmarshal(data, feedback); err != nil {
+				return fmt.Errorf("failed to decode done event: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if feedback == nil {
+		return nil, fmt.Errorf("essay feedback stream ended before it was done")
+	}
+	return feedback, nil
+}
+
+// readEvents calls handle for each Server-Sent Event in the response body
+// until it ends or handle returns an error
+func readEvents(resp *http.Response, handle func(event string, data []byte) error) error {
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+
+	event, data := "", []string{}
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				if err := handle(event, []byte(strings.Join(data, "\n"))); err != nil {
+					return err
+				}
+			}
+			event, data = "", data[:0]
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read event stream: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"flashcards/models"
+)
+
+// Queued session requests are polled this often by WaitForQueuedSession
+const DEFAULT_QUEUED_SESSION_POLL_INTERVAL = 5 * time.Second
+
+// CreateSession starts a quiz session. When the questions must be generated
+// and the LLM provider is down the server may queue the request instead;
+// then the session is nil and the queued request is returned, to pass to
+// WaitForQueuedSession.
+func (c *Client) CreateSession(ctx context.Context, req *models.CreateQuizSessionRequest) (*models.QuizSession, *models.QueuedQuizSession, error) {
+	var body json.RawMessage
+	statusCode, err := c.do(ctx, &request{method: http.MethodPost, path: "/quiz/sessions", body: req}, &body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if statusCode == http.StatusAccepted {
+		var queued models.QueuedQuizSession
+		if err := json.Unmarshal(body, &queued); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode queued quiz session: %w", err)
+		}
+		return nil, &queued, nil
+	}
+
+	var session models.QuizSession
+	if err := json.Unmarshal(body, &session); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode quiz session: %w", err)
+	}
+	return &session, nil, nil
+}
+
+func (c *Client) GetQueuedSession(ctx context.Context, id int) (*models.QueuedQuizSession, error) {
+	var queued models.QueuedQuizSession
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: fmt.Sprintf("/quiz/sessions/queued/%d", id)}, &queued); err != nil {
+		return nil, err
+	}
+	return &queued, nil
+}
+
+// WaitForQueuedSession polls a queued session request until the session is
+// created and returns it. A zero interval selects the default.
+func (c *Client) WaitForQueuedSession(ctx context.Context, id int, interval time.Duration) (*models.QuizSession, error) {
+	if interval <= 0 {
+		interval = DEFAULT_QUEUED_SESSION_POLL_INTERVAL
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		queued, err := c.GetQueuedSession(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		switch queued.Status {
+		case models.QueuedSessionStatusCompleted:
+			if queued.SessionID == nil {
+				return nil, fmt.Errorf("queued quiz session %d completed without a session", id)
+			}
+			return c.GetSession(ctx, *queued.SessionID)
+		case models.QueuedSessionStatusFailed:
+			return nil, fmt.Errorf("queued quiz session %d failed: %s", id, queued.Error)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Client) GetSession(ctx context.Context, id int) (*models.QuizSession, error) {
+	var session models.QuizSession
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: fmt.Sprintf("/quiz/sessions/%d", id)}, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// SubmitAnswer answers the question at position, recording how long the
+// learner took when timeSpent is positive, and returns the updated session
+// with the answer graded where possible
+func (c *Client) SubmitAnswer(ctx context.Context, sessionID, position int, answer string, timeSpent time.Duration) (*models.QuizSession, error) {
+	req := &models.UpdateSessionQuestionRequest{Answer: &answer}
+	if timeSpent > 0 {
+		timeSpentMs := int(timeSpent.Milliseconds())
+		req.TimeSpentMs = &timeSpentMs
+	}
+	return c.UpdateQuestion(ctx, sessionID, position, req)
+}
+
+func (c *Client) UpdateQuestion(ctx context.Context, sessionID, position int, req *models.UpdateSessionQuestionRequest) (*models.QuizSession, error) {
+	var session models.QuizSession
+	path := fmt.Sprintf("/quiz/sessions/%d/questions/%d", sessionID, position)
+	if _, err := c.do(ctx, &request{method: http.MethodPut, path: path, body: req}, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (c *Client) SkipQuestion(ctx context.Context, sessionID, position int) (*models.QuizSession, error) {
+	var session models.QuizSession
+	path := fmt.Sprintf("/quiz/sessions/%d/questions/%d/skip", sessionID, position)
+	if _, err := c.do(ctx, &request{method: http.MethodPost, path: path}, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// NextQuestion returns the next question to answer, or nil once every
+// question has been answered or skipped
+func (c *Client) NextQuestion(ctx context.Context, sessionID int) (*models.SessionQuestion, error) {
+	var question *models.SessionQuestion
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: fmt.Sprintf("/quiz/sessions/%d/next", sessionID)}, &question); err != nil {
+		return nil, err
+	}
+	return question, nil
+}
+
+func (c *Client) CompleteSession(ctx context.Context, sessionID int) (*models.SessionReport, error) {
+	var report models.SessionReport
+	if _, err := c.do(ctx, &request{method: http.MethodPost, path: fmt.Sprintf("/quiz/sessions/%d/complete", sessionID)}, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (c *Client) GetReport(ctx context.Context, sessionID int) (*models.SessionReport, error) {
+	var report models.SessionReport
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: fmt.Sprintf("/quiz/sessions/%d/report", sessionID)}, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
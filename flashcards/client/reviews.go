@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"flashcards/models"
+)
+
+// DueCards lists the cards due now, in deckID and its subdecks when set.
+// Zero limit and empty order leave the server defaults.
+func (c *Client) DueCards(ctx context.Context, deckID *int, order string, limit int) (*models.DueQueue, error) {
+	query := url.Values{}
+	if deckID != nil {
+		query.Set("deckId", strconv.Itoa(*deckID))
+	}
+	if order != "" {
+		query.Set("order", order)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var queue models.DueQueue
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: withQuery("/reviews/due", query)}, &queue); err != nil {
+		return nil, err
+	}
+	return &queue, nil
+}
+
+func (c *Client) SubmitReview(ctx context.Context, req *models.SubmitReviewRequest) (*models.ReviewResult, error) {
+	var result models.ReviewResult
+	if _, err := c.do(ctx, &request{method: http.MethodPost, path: "/reviews", body: req}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,609 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"flashcards/handlers"
+	"flashcards/openapi"
+)
+
+// contractCall is one request of the contract scenario. {name} in the path,
+// body and headers is replaced by the id an earlier call saved as name, and
+// {etag} by the last ETag answered.
+type contractCall struct {
+	method  string
+	path    string
+	body    string
+	headers map[string]string
+	status  int
+	// save keeps the id of the response for later calls
+	save string
+}
+
+// contractCalls walks every documented operation with valid requests, in
+// an order where each finds what it needs. Operations that cannot succeed
+// without a mail server, a WebSocket or the weekly analysis are sent the
+// request that gets their documented error.
+func contractCalls(t *testing.T) []contractCall {
+	noteFile, noteType := multipartFile(t, "files", "ribosomes.md", []byte("# Ribosomes\n\nRibosomes build proteins."))
+	imageFile, imageType := multipartFile(t, "image", "answer.png", testPNG(t))
+	audioFile, audioType := multipartFile(t, "audio", "answer.mp3", []byte("ID3 recorded answer"))
+	question := `{"id":"q1","text":"What do mitochondria make?","type":"multiple-choice","options":["A) ATP","B) DNA"],"correctAnswer":"A) ATP","difficulty":"easy","basedOnNotes":[{note}]}`
+	session := "/quiz/sessions/{session}"
+
+	return []contractCall{
+		{method: "POST", path: "/auth/register", body: `{"email":"ada@example.com","password":"correct horse battery"}`, status: http.StatusCreated},
+		{method: "POST", path: "/auth/login", body: `{"email":"ada@example.com","password":"correct horse battery"}`, status: http.StatusOK},
+		{method: "GET", path: "/auth/me", status: http.StatusOK},
+
+		{method: "POST", path: "/decks", body: `{"name":"Biology","description":"Cells"}`, status: http.StatusCreated, save: "deck"},
+		{method: "GET", path: "/decks", status: http.StatusOK},
+		{method: "GET", path: "/decks/{deck}", status: http.StatusOK},
+		{method: "PUT", path: "/decks/{deck}", body: `{"description":"Cells and energy"}`, status: http.StatusOK},
+		{method: "GET", path: "/decks/{deck}/terminology", status: http.StatusOK},
+		{method: "PUT", path: "/decks/{deck}/terminology", body: `{"doNotSimplify":["mitochondria"]}`, status: http.StatusOK},
+		{method: "GET", path: "/decks/{deck}/study-settings", status: http.StatusOK},
+		{method: "PUT", path: "/decks/{deck}/study-settings", body: `{"newCardsPerDay":15}`, status: http.StatusOK},
+
+		{method: "POST", path: "/notes", body: `{"title":"Cells","content":"Mitochondria make ATP, the energy currency of the cell.","deckId":{deck}}`, status: http.StatusCreated, save: "note"},
+		{method: "GET", path: "/notes?limit=10&sort=createdAt&order=desc", status: http.StatusOK},
+		{method: "GET", path: "/notes/{note}", status: http.StatusOK},
+		{method: "PUT", path: "/notes/{note}", body: `{"content":"Mitochondria make ATP, the energy currency of cells."}`, headers: map[string]string{"If-Match": "{etag}"}, status: http.StatusOK},
+		{method: "POST", path: "/notes/import", body: noteFile, headers: map[string]string{"Content-Type": noteType}, status: http.StatusCreated},
+		{method: "POST", path: "/notes/{note}/tags", body: `{"tags":["biology"]}`, status: http.StatusOK},
+		{method: "GET", path: "/tags", status: http.StatusOK},
+		{method: "DELETE", path: "/notes/{note}/tags/biology", status: http.StatusNoContent},
+
+		{method: "POST", path: "/flashcards", body: `{"front":"What do mitochondria make?","back":"ATP","deckId":{deck},"sourceNoteId":{note}}`, status: http.StatusCreated, save: "card"},
+		{method: "GET", path: "/flashcards?deckId={deck}", status: http.StatusOK},
+		{method: "GET", path: "/flashcards/{card}", status: http.StatusOK},
+		{method: "PUT", path: "/flashcards/{card}", body: `{"hint":"Energy"}`, headers: map[string]string{"If-Match": "{etag}"}, status: http.StatusOK},
+		{method: "GET", path: "/flashcards/{card}/render", status: http.StatusOK},
+		{method: "POST", path: "/flashcards/{card}/mnemonic", status: http.StatusOK},
+		{method: "POST", path: "/notes/{note}/generate-flashcards", body: `{"count":1}`, status: http.StatusCreated},
+
+		// Suggestions only come from the weekly analysis
+		{method: "GET", path: "/decks/{deck}/suggestions", status: http.StatusOK},
+		{method: "POST", path: "/decks/{deck}/suggestions/999/apply", status: http.StatusNotFound},
+		{method: "POST", path: "/decks/{deck}/suggestions/999/dismiss", status: http.StatusNotFound},
+
+		{method: "POST", path: "/notes/generate-quiz", body: `{"noteIds":[{note}],"conversation":[{"role":"user","content":"Quiz me on cells"}],"options":{"questionType":"multiple-choice"}}`, status: http.StatusOK},
+		{method: "POST", path: "/quiz/questions/plain-language", body: question, status: http.StatusOK},
+		{method: "POST", path: "/quiz/grade-essay", body: `{"question":{"id":"q2","text":"Why do cells need mitochondria?","type":"essay","difficulty":"medium","basedOnNotes":[{note}]},"answer":"They make ATP."}`, status: http.StatusOK},
+
+		{method: "POST", path: "/quiz/sessions", body: `{"questions":[],"noteIds":[{note}],"count":4,"questionType":"multiple-choice"}`, status: http.StatusCreated, save: "session"},
+		{method: "GET", path: "/quiz/sessions/queued/999", status: http.StatusNotFound},
+		{method: "GET", path: session, status: http.StatusOK},
+		{method: "GET", path: session + "/next", status: http.StatusOK},
+		{method: "PUT", path: session + "/questions/0", body: `{"answer":"A) ATP","timeSpentMs":3000}`, status: http.StatusOK},
+		{method: "POST", path: session + "/questions/1/flag", status: http.StatusOK},
+		{method: "DELETE", path: session + "/questions/1/flag", status: http.StatusOK},
+		{method: "POST", path: session + "/questions/0/explain", status: http.StatusOK},
+		{method: "POST", path: session + "/questions/3/regenerate", status: http.StatusOK},
+		{method: "POST", path: session + "/questions/3/skip", status: http.StatusOK},
+		// Skipped questions come round again until they are answered
+		{method: "GET", path: session + "/next", status: http.StatusOK},
+		{method: "PUT", path: session + "/questions/3", body: `{"answer":"B) Glucose","timeSpentMs":4000}`, status: http.StatusOK},
+		{method: "POST", path: session + "/questions/1/image-answer", body: imageFile, headers: map[string]string{"Content-Type": imageType}, status: http.StatusOK},
+		{method: "POST", path: session + "/questions/2/audio-answer", body: audioFile, headers: map[string]string{"Content-Type": audioType}, status: http.StatusOK},
+		{method: "GET", path: session + "/next", status: http.StatusNoContent},
+		{method: "POST", path: session + "/complete", status: http.StatusOK},
+		{method: "GET", path: session + "/report", status: http.StatusOK},
+		// No mail server is configured
+		{method: "POST", path: session + "/report/email", body: `{"to":"ada@example.com"}`, status: http.StatusServiceUnavailable},
+		// A plain GET is refused the WebSocket upgrade
+		{method: "GET", path: "/ws/quiz", status: http.StatusBadRequest},
+
+		{method: "POST", path: "/jobs", body: `{"kind":"summarize-notes","payload":{"onlyMissing":true}}`, status: http.StatusAccepted, save: "job"},
+		{method: "GET", path: "/jobs?status=queued", status: http.StatusOK},
+		{method: "GET", path: "/jobs/{job}", status: http.StatusOK},
+		{method: "POST", path: "/admin/jobs", body: `{"kind":"reembed-catalog"}`, status: http.StatusForbidden},
+
+		{method: "POST", path: "/daily-questions/subscriptions", body: `{"deckId":{deck}}`, status: http.StatusCreated, save: "subscription"},
+		{method: "GET", path: "/daily-questions/subscriptions", status: http.StatusOK},
+		{method: "DELETE", path: "/daily-questions/subscriptions/{subscription}", status: http.StatusNoContent},
+		// Answer tokens are only sent by email
+		{method: "POST", path: "/daily-questions/answer", body: `{"token":"not-a-token","answer":"ATP"}`, status: http.StatusUnauthorized},
+		{method: "POST", path: "/daily-questions/replies", body: `{"text":"ATP\n\n> Daily question code: not.signed"}`, status: http.StatusUnauthorized},
+
+		{method: "POST", path: "/webhooks", body: `{"url":"https://example.com/hooks/flashcards","events":["note.created"]}`, status: http.StatusCreated, save: "webhook"},
+		{method: "GET", path: "/webhooks", status: http.StatusOK},
+		{method: "GET", path: "/webhooks/{webhook}", status: http.StatusOK},
+		{method: "PUT", path: "/webhooks/{webhook}", body: `{"active":false}`, status: http.StatusOK},
+		{method: "GET", path: "/webhooks/{webhook}/deliveries", status: http.StatusOK},
+		{method: "DELETE", path: "/webhooks/{webhook}", status: http.StatusNoContent},
+
+		{method: "POST", path: "/hooks", body: `{"hookUrl":"https://example.com/hooks/zap","event":"new_note"}`, status: http.StatusCreated, save: "hook"},
+		{method: "GET", path: "/hooks/samples/new_note", status: http.StatusOK},
+		{method: "DELETE", path: "/hooks/{hook}", status: http.StatusNoContent},
+
+		{method: "DELETE", path: "/flashcards/{card}", status: http.StatusNoContent},
+		{method: "DELETE", path: "/notes/{note}", status: http.StatusNoContent},
+		{method: "DELETE", path: "/decks/{deck}", status: http.StatusNoContent},
+	}
+}
+
+// TestAPIMatchesOpenAPIDocument serves the whole API and checks every
+// answer against the OpenAPI document: the status must be one the
+// operation documents and the body must match its schema. Every documented
+// operation has to be exercised, so a route added to the document without
+// a call here fails too.
+func TestAPIMatchesOpenAPIDocument(t *testing.T) {
+	app := newTestApp(t, &fakeOpenAI{})
+	contract := newContract(handlers.BuildOpenAPIDocument())
+
+	t.Run("invalid requests", func(t *testing.T) {
+		anonymous := &testClient{t: t, handler: app}
+		stranger := &testClient{t: t, handler: app}
+		stranger.register("mallory@example.com")
+
+		for _, op := range contract.operations {
+			path := op.examplePath()
+			if !op.public {
+				contract.check(t, anonymous, op.method, path, "", nil, http.StatusUnauthorized)
+			}
+
+			// Preconditions in headers are met so the body is what is refused
+			headers := map[string]string{}
+			for _, parameter := range op.Parameters {
+				if parameter.In == "header" {
+					headers[parameter.Name] = `"1"`
+				}
+			}
+			// Only admins get as far as reading the body
+			status := http.StatusBadRequest
+			if strings.HasPrefix(op.path, "/admin/") {
+				status = http.StatusForbidden
+			}
+			switch op.requestContentType() {
+			case "application/json":
+				contract.check(t, stranger, op.method, path, `{"unterminated`, headers, status)
+			case "multipart/form-data":
+				headers["Content-Type"] = "multipart/form-data; boundary=missing"
+				contract.check(t, stranger, op.method, path, "not a form", headers, status)
+			}
+		}
+	})
+
+	t.Run("valid requests", func(t *testing.T) {
+		client := &testClient{t: t, handler: app}
+		saved := map[string]string{}
+		for _, call := range contractCalls(t) {
+			fill := func(s string) string {
+				for name, value := range saved {
+					s = strings.ReplaceAll(s, "{"+name+"}", value)
+				}
+				return s
+			}
+			headers := map[string]string{}
+			for key, value := range call.headers {
+				headers[key] = fill(value)
+			}
+
+			response := contract.check(t, client, call.method, fill(call.path), fill(call.body), headers, call.status)
+			if op := contract.find(call.method, fill(call.path)); op != nil && response.Code == call.status {
+				op.exercised = true
+			}
+
+			var body struct {
+				ID    json.Number `json:"id"`
+				Token string      `json:"token"`
+			}
+			json.Unmarshal(response.Body.Bytes(), &body)
+			if body.Token != "" {
+				client.token = body.Token
+			}
+			if call.save != "" {
+				if body.ID == "" {
+					t.Fatalf("%s %s answered no id to save as %s: %s", call.method, call.path, call.save, response.Body)
+				}
+				saved[call.save] = body.ID.String()
+			}
+			if etag := response.Header().Get("ETag"); etag != "" {
+				saved["etag"] = etag
+			}
+		}
+
+		for _, op := range contract.operations {
+			if !op.exercised {
+				t.Errorf("%s %s is documented but no valid request to it succeeded", op.method, op.path)
+			}
+		}
+	})
+}
+
+// contract holds the operations of an OpenAPI document, matched against
+// requests by method and path
+type contract struct {
+	doc        *openapi.Document
+	operations []*contractOperation
+}
+
+type contractOperation struct {
+	*openapi.Operation
+	method  string
+	path    string
+	pattern *regexp.Regexp
+	public  bool
+
+	exercised bool
+}
+
+var documentParameter = regexp.MustCompile(`\{([a-zA-Z]+)\}`)
+
+func newContract(doc *openapi.Document) *contract {
+	c := &contract{doc: doc}
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			integers := map[string]bool{}
+			for _, param := range op.Parameters {
+				if param.In == "path" && param.Schema.Type == "integer" {
+					integers[param.Name] = true
+				}
+			}
+			pattern := documentParameter.ReplaceAllStringFunc(path, func(match string) string {
+				if integers[match[1:len(match)-1]] {
+					return "[0-9]+"
+				}
+				return "[^/]+"
+			})
+
+			c.operations = append(c.operations, &contractOperation{
+				Operation: op,
+				method:    strings.ToUpper(method),
+				path:      path,
+				pattern:   regexp.MustCompile("^" + pattern + "$"),
+				public:    op.Security != nil && len(*op.Security) == 0,
+			})
+		}
+	}
+	sort.Slice(c.operations, func(i, j int) bool {
+		return c.operations[i].path+c.operations[i].method < c.operations[j].path+c.operations[j].method
+	})
+	return c
+}
+
+// examplePath fills the operation's path parameters with values of their
+// type
+func (op *contractOperation) examplePath() string {
+	return documentParameter.ReplaceAllStringFunc(op.path, func(match string) string {
+		for _, param := range op.Parameters {
+			if "{"+param.Name+"}" == match && param.Schema.Type == "integer" {
+				return "1"
+			}
+		}
+		return "x"
+	})
+}
+
+func (op *contractOperation) requestContentType() string {
+	if op.RequestBody == nil {
+		return ""
+	}
+	for contentType := range op.RequestBody.Content {
+		return contentType
+	}
+	return ""
+}
+
+// find returns the operation serving method and target, or nil
+func (c *contract) find(method, target string) *contractOperation {
+	path, _, _ := strings.Cut(target, "?")
+	for _, op := range c.operations {
+		if op.method == method && op.pattern.MatchString(path) {
+			return op
+		}
+	}
+	return nil
+}
+
+// check sends a request as client and reports where it or its answer
+// strays from the document
+func (c *contract) check(t *testing.T, client *testClient, method, target, body string, headers map[string]string, want int) *httptest.ResponseRecorder {
+	t.Helper()
+	name := method + " " + target
+	response := client.do(method, target, body, headers)
+
+	op := c.find(method, target)
+	if op == nil {
+		t.Errorf("%s is not in the OpenAPI document", name)
+		return response
+	}
+	if response.Code != want {
+		t.Errorf("%s = %d, want %d: %s", name, response.Code, want, response.Body)
+	}
+
+	if want < 300 && op.requestContentType() == "application/json" && body != "" {
+		if err := c.validate(op.RequestBody.Content["application/json"].Schema, body); err != nil {
+			t.Errorf("%s sent a body the document does not describe: %v", name, err)
+		}
+	}
+
+	documented, ok := op.Responses[strconv.Itoa(response.Code)]
+	if !ok {
+		t.Errorf("%s answered %d, which the document does not list", name, response.Code)
+		return response
+	}
+	if len(documented.Content) == 0 {
+		if response.Body.Len() > 0 {
+			t.Errorf("%s answered %d with a body, which the document does not describe: %s", name, response.Code, response.Body)
+		}
+		return response
+	}
+
+	contentType, _, _ := strings.Cut(response.Header().Get("Content-Type"), ";")
+	media, ok := documented.Content[contentType]
+	if !ok {
+		t.Errorf("%s answered %d as %q, which the document does not list", name, response.Code, contentType)
+		return response
+	}
+	if contentType == "application/json" {
+		if err := c.validate(media.Schema, response.Body.String()); err != nil {
+			t.Errorf("%s answered %d with a body that does not match the document: %v\n%s", name, response.Code, err, response.Body)
+		}
+	}
+	return response
+}
+
+// validate checks a JSON document against a schema of the OpenAPI document
+func (c *contract) validate(schema *openapi.Schema, document string) error {
+	decoder := json.NewDecoder(strings.NewReader(document))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("not JSON: %v", err)
+	}
+	return c.validateValue(schema, value, "body")
+}
+
+func (c *contract) validateValue(schema *openapi.Schema, value any, path string) error {
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		component, ok := c.doc.Components.Schemas[name]
+		if !ok {
+			return fmt.Errorf("%s refers to the missing schema %s", path, name)
+		}
+		return c.validateValue(component, value, path)
+	}
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return nil
+		}
+		return fmt.Errorf("%s is null but not nullable", path)
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s is not an object", path)
+		}
+		for _, field := range schema.Required {
+			if _, ok := object[field]; !ok {
+				return fmt.Errorf("%s.%s is required", path, field)
+			}
+		}
+		fields := make([]string, 0, len(object))
+		for field := range object {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			property, ok := schema.Properties[field]
+			if !ok {
+				property = schema.AdditionalProperties
+			}
+			if property == nil {
+				return fmt.Errorf("%s.%s is not in the schema", path, field)
+			}
+			// A pointer to a struct is optional but cannot be marked
+			// nullable next to its $ref in OpenAPI 3.0
+			if object[field] == nil && property.Ref != "" && !contains(schema.Required, field) {
+				continue
+			}
+			if err := c.validateValue(property, object[field], path+"."+field); err != nil {
+				return err
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s is not an array", path)
+		}
+		for i, item := range items {
+			if schema.Items == nil {
+				break
+			}
+			if err := c.validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s is not a string", path)
+		}
+		if len(schema.Enum) > 0 && !contains(schema.Enum, text) {
+			return fmt.Errorf("%s is %q, not one of %v", path, text, schema.Enum)
+		}
+		switch schema.Format {
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339Nano, text); err != nil {
+				return fmt.Errorf("%s is not a date-time: %v", path, err)
+			}
+		case "byte":
+			if _, err := base64.StdEncoding.DecodeString(text); err != nil {
+				return fmt.Errorf("%s is not base64: %v", path, err)
+			}
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if _, err := number.Int64(); !ok || err != nil {
+			return fmt.Errorf("%s is not an integer", path)
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return fmt.Errorf("%s is not a number", path)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s is not a boolean", path)
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// multipartFile encodes content as a form with one file, returning the body
+// and its Content-Type
+func multipartFile(t *testing.T, field, filename string, content []byte) (string, string) {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile(field, filename)
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	part.Write(content)
+	if err := form.Close(); err != nil {
+		t.Fatalf("multipart Close: %v", err)
+	}
+	return body.String(), form.FormDataContentType()
+}
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	return encoded.Bytes()
+}
+
+// fakeOpenAI answers the OpenAI APIs the server calls. Chat completions
+// that ask for a tool get canned arguments for it, numbered so no two
+// generated questions are alike; other completions get a short graded
+// explanation. Embeddings are derived from the text, so equal texts match.
+type fakeOpenAI struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *fakeOpenAI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.calls++
+	call := f.calls
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/chat/completions":
+		var request struct {
+			Stream bool `json:"stream"`
+			Tools  []struct {
+				Function struct {
+					Name string `json:"name"`
+				} `json:"function"`
+			} `json:"tools"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		text := "Score: 8/10\nMitochondria turn food into ATP, the energy cells run on."
+		if request.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, chunk := range []string{
+				fmt.Sprintf(`{"id":"chatcmpl-%d","object":"chat.completion.chunk","created":1,"model":"gpt-4o-mini","choices":[{"index":0,"delta":{"role":"assistant","content":%q},"finish_reason":null}]}`, call, text),
+				fmt.Sprintf(`{"id":"chatcmpl-%d","object":"chat.completion.chunk","created":1,"model":"gpt-4o-mini","choices":[{"index":0,"delta":{},"finish_reason":"stop"}],"usage":{"prompt_tokens":40,"completion_tokens":20,"total_tokens":60}}`, call),
+				"[DONE]",
+			} {
+				fmt.Fprintf(w, "data: %s\n\n", chunk)
+			}
+			return
+		}
+
+		message := map[string]any{"role": "assistant", "content": text}
+		finish := "stop"
+		if len(request.Tools) > 0 {
+			name := request.Tools[0].Function.Name
+			message = map[string]any{"role": "assistant", "content": "", "tool_calls": []map[string]any{{
+				"id": fmt.Sprintf("call_%d", call), "type": "function",
+				"function": map[string]any{"name": name, "arguments": fakeToolArguments(name, call)},
+			}}}
+			finish = "tool_calls"
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"id": fmt.Sprintf("chatcmpl-%d", call), "object": "chat.completion", "created": 1, "model": "gpt-4o-mini",
+			"choices": []map[string]any{{"index": 0, "message": message, "finish_reason": finish}},
+			"usage":   map[string]int{"prompt_tokens": 40, "completion_tokens": 20, "total_tokens": 60},
+		})
+	case "/embeddings":
+		var request struct {
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data := make([]map[string]any, len(request.Input))
+		for i, text := range request.Input {
+			sum := sha256.Sum256([]byte(text))
+			embedding := make([]float64, 16)
+			for j := range embedding {
+				embedding[j] = float64(sum[j]) - 127.5
+			}
+			data[i] = map[string]any{"index": i, "embedding": embedding}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	case "/audio/transcriptions":
+		json.NewEncoder(w).Encode(map[string]string{"text": "A) ATP"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// fakeToolArguments returns arguments for the output schema name that pass
+// its validation
+func fakeToolArguments(name string, call int) string {
+	switch name {
+	case "quiz_question":
+		return fmt.Sprintf(`{"question":"Question %d: what do mitochondria make?","type":"multiple-choice","options":["A) ATP","B) DNA"],"correctAnswer":"A) ATP","explanation":"Mitochondria make ATP.","difficulty":"easy"}`, call)
+	case "flashcards":
+		return fmt.Sprintf(`{"flashcards":[{"question":"Card %d: what is the energy currency of the cell?","answer":"ATP"}]}`, call)
+	case "answer_grading":
+		return `{"finalAnswer":"A) ATP","correct":true,"score":9,"feedback":"Right, mitochondria make ATP."}`
+	case "flashcard_rewrite":
+		return `{"front":"What do mitochondria make?","back":"ATP","hint":"Energy"}`
+	case "flashcard_mnemonics":
+		return `{"mnemonics":[{"technique":"imagery","text":"Picture a power plant inside every cell."}]}`
+	case "vocabulary_details":
+		return `{"ipa":"ˌmaɪtəˈkɒndriən","pronunciation":"my-tuh-KON-dree-un","examples":[{"text":"The mitochondrion makes ATP.","translation":"La mitocondria produce ATP."}]}`
+	}
+	return `{}`
+}
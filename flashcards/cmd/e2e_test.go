@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Set E2E_RECORD=1 with OPENAI_API_KEY to record the cassettes again from
+// the real API, or from E2E_RECORD_URL when it is set
+const (
+	E2E_RECORD_ENV     = "E2E_RECORD"
+	E2E_RECORD_URL_ENV = "E2E_RECORD_URL"
+	E2E_RECORD_URL     = "https://api.openai.com/v1"
+)
+
+// interaction is one recorded exchange with the OpenAI API
+type interaction struct {
+	Request struct {
+		Method string `json:"method"`
+		Path   string `json:"path"`
+		Body   string `json:"body"`
+	} `json:"request"`
+	Response struct {
+		Status      int    `json:"status"`
+		ContentType string `json:"contentType"`
+		Body        string `json:"body"`
+	} `json:"response"`
+}
+
+// cassette answers the OpenAI APIs the server calls with the responses
+// recorded in testdata/cassettes, so scenarios run the same way every time
+// without a key or a network. A request is matched by method, path and
+// body; identical requests get their recorded responses in order. Requests
+// that were never recorded fail the test.
+//
+// When recording, requests are sent on to the API instead and every
+// exchange is written to the cassette once the test ends.
+type cassette struct {
+	t    *testing.T
+	path string
+
+	// Set when recording
+	upstream string
+	key      string
+
+	mu           sync.Mutex
+	interactions []interaction
+	played       []bool
+	misses       []string
+}
+
+// newCassette loads testdata/cassettes/name.json, or starts recording it
+// when E2E_RECORD is set. It must be created before newTestApp replaces
+// OPENAI_API_KEY.
+func newCassette(t *testing.T, name string) *cassette {
+	t.Helper()
+	c := &cassette{t: t, path: filepath.Join("testdata", "cassettes", name+".json")}
+
+	if os.Getenv(E2E_RECORD_ENV) != "" {
+		c.upstream = strings.TrimSuffix(os.Getenv(E2E_RECORD_URL_ENV), "/")
+		if c.upstream == "" {
+			c.upstream = E2E_RECORD_URL
+		}
+		c.key = os.Getenv("OPENAI_API_KEY")
+		if c.key == "" && c.upstream == E2E_RECORD_URL {
+			t.Fatal("recording from the OpenAI API needs OPENAI_API_KEY")
+		}
+		t.Cleanup(c.save)
+		return c
+	}
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		t.Fatalf("read cassette: %v; record it with %s=1", err, E2E_RECORD_ENV)
+	}
+	if err := json.Unmarshal(data, &c.interactions); err != nil {
+		t.Fatalf("parse cassette %s: %v", c.path, err)
+	}
+	c.played = make([]bool, len(c.interactions))
+	t.Cleanup(c.check)
+	return c
+}
+
+func (c *cassette) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var recorded interaction
+	if c.upstream != "" {
+		recorded, err = c.record(r, body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+	} else {
+		var ok bool
+		if recorded, ok = c.play(r.Method, r.URL.Path, string(body)); !ok {
+			http.Error(w, `{"error":{"message":"no recorded response"}}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", recorded.Response.ContentType)
+	w.WriteHeader(recorded.Response.Status)
+	io.WriteString(w, recorded.Response.Body)
+}
+
+// play finds the first unplayed interaction recorded for the request
+func (c *cassette) play(method, path, body string) (interaction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, recorded := range c.interactions {
+		if !c.played[i] && recorded.Request.Method == method && recorded.Request.Path == path && recorded.Request.Body == body {
+			c.played[i] = true
+			return recorded, true
+		}
+	}
+	c.misses = append(c.misses, fmt.Sprintf("%s %s %s", method, path, body))
+	return interaction{}, false
+}
+
+// record sends the request on to the API and keeps the exchange
+func (c *cassette) record(r *http.Request, body []byte) (interaction, error) {
+	request, err := http.NewRequestWithContext(r.Context(), r.Method, c.upstream+r.URL.Path, bytes.NewReader(body))
+	if err != nil {
+		return interaction{}, err
+	}
+	request.Header.Set("Content-Type", r.Header.Get("Content-Type"))
+	if c.key != "" {
+		request.Header.Set("Authorization", "Bearer "+c.key)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return interaction{}, err
+	}
+	defer response.Body.Close()
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return interaction{}, err
+	}
+
+	var recorded interaction
+	recorded.Request.Method = r.Method
+	recorded.Request.Path = r.URL.Path
+	recorded.Request.Body = string(body)
+	recorded.Response.Status = response.StatusCode
+	recorded.Response.ContentType = response.Header.Get("Content-Type")
+	recorded.Response.Body = string(responseBody)
+
+	c.mu.Lock()
+	c.interactions = append(c.interactions, recorded)
+	c.mu.Unlock()
+	return recorded, nil
+}
+
+// save writes the recorded interactions to the cassette
+func (c *cassette) save() {
+	if c.t.Failed() {
+		c.t.Logf("not saving %s: the test failed", c.path)
+		return
+	}
+	data, err := json.MarshalIndent(c.interactions, "", "  ")
+	if err != nil {
+		c.t.Fatalf("encode cassette: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		c.t.Fatalf("create cassette directory: %v", err)
+	}
+	if err := os.WriteFile(c.path, append(data, '\n'), 0o644); err != nil {
+		c.t.Fatalf("write cassette: %v", err)
+	}
+}
+
+// check fails the test for requests that were not recorded
+func (c *cassette) check() {
+	for _, miss := range c.misses {
+		c.t.Errorf("request not in %s, record it again with %s=1: %s", c.path, E2E_RECORD_ENV, miss)
+	}
+}
+
+// TestQuizScenario studies two notes from start to finish: it creates
+// them, asks for a quiz question about them, answers a generated quiz
+// session and checks the answers show up in the study statistics.
+func TestQuizScenario(t *testing.T) {
+	llm := newCassette(t, "quiz_scenario")
+	client := &testClient{t: t, handler: newTestApp(t, llm)}
+	client.register("grace@example.com")
+
+	// call sends a request, checks its status and reads the response into v
+	call := func(method, path, body string, status int, v any) {
+		t.Helper()
+		response := client.do(method, path, body, nil)
+		if response.Code != status {
+			t.Fatalf("%s %s = %d, want %d: %s", method, path, response.Code, status, response.Body)
+		}
+		if v != nil {
+			if err := json.Unmarshal(response.Body.Bytes(), v); err != nil {
+				t.Fatalf("%s %s: %v: %s", method, path, err, response.Body)
+			}
+		}
+	}
+
+	var noteIDs []int
+	for _, note := range []struct{ title, content string }{
+		{"Mitochondria", "Mitochondria are the organelles that turn glucose and oxygen into ATP, the energy currency of the cell."},
+		{"Ribosomes", "Ribosomes read messenger RNA and join amino acids into proteins."},
+	} {
+		body, _ := json.Marshal(map[string]string{"title": note.title, "content": note.content})
+		var created struct {
+			ID int `json:"id"`
+		}
+		call("POST", "/notes", string(body), http.StatusCreated, &created)
+		noteIDs = append(noteIDs, created.ID)
+	}
+	ids, _ := json.Marshal(noteIDs)
+
+	var quiz struct {
+		Success bool `json:"success"`
+		Data    struct {
+			Conversation []struct {
+				Role     string `json:"role"`
+				Question *struct {
+					Text    string   `json:"text"`
+					Options []string `json:"options"`
+				} `json:"question"`
+			} `json:"conversation"`
+		} `json:"data"`
+	}
+	call("POST", "/notes/generate-quiz",
+		`{"noteIds":`+string(ids)+`,"conversation":[{"role":"user","content":"Quiz me on these notes"}],"options":{"questionType":"multiple-choice"}}`,
+		http.StatusOK, &quiz)
+	last := quiz.Data.Conversation[len(quiz.Data.Conversation)-1]
+	if !quiz.Success || last.Role != "assistant" || last.Question == nil || last.Question.Text == "" || len(last.Question.Options) < 2 {
+		t.Fatalf("generate-quiz did not end the conversation with a multiple-choice question: %+v", quiz)
+	}
+
+	var session struct {
+		ID        int `json:"id"`
+		Questions []struct {
+			Position int `json:"position"`
+			Question struct {
+				Options       []string `json:"options"`
+				CorrectAnswer string   `json:"correctAnswer"`
+				Difficulty    string   `json:"difficulty"`
+			} `json:"question"`
+		} `json:"questions"`
+	}
+	call("POST", "/quiz/sessions", `{"noteIds":`+string(ids)+`,"count":3,"questionType":"multiple-choice"}`, http.StatusCreated, &session)
+	if len(session.Questions) != 3 {
+		t.Fatalf("session has %d questions, want 3", len(session.Questions))
+	}
+
+	// Every other answer is wrong
+	graded := map[string]int{}
+	correct := map[string]int{}
+	for i, question := range session.Questions {
+		answer := question.Question.CorrectAnswer
+		if i%2 == 1 {
+			for _, option := range question.Question.Options {
+				if option != answer {
+					answer = option
+					break
+				}
+			}
+		} else {
+			correct[question.Question.Difficulty]++
+		}
+		graded[question.Question.Difficulty]++
+
+		body, _ := json.Marshal(map[string]any{"answer": answer, "timeSpentMs": 5000})
+		call("PUT", fmt.Sprintf("/quiz/sessions/%d/questions/%d", session.ID, question.Position), string(body), http.StatusOK, nil)
+	}
+	call("POST", fmt.Sprintf("/quiz/sessions/%d/complete", session.ID), "", http.StatusOK, nil)
+
+	var stats struct {
+		AccuracyByDifficulty []struct {
+			Difficulty string `json:"difficulty"`
+			Graded     int    `json:"graded"`
+			Correct    int    `json:"correct"`
+		} `json:"accuracyByDifficulty"`
+	}
+	call("GET", "/analytics/study", "", http.StatusOK, &stats)
+	if len(stats.AccuracyByDifficulty) != len(graded) {
+		t.Fatalf("stats cover difficulties %+v, want %v", stats.AccuracyByDifficulty, graded)
+	}
+	for _, accuracy := range stats.AccuracyByDifficulty {
+		if accuracy.Graded != graded[accuracy.Difficulty] || accuracy.Correct != correct[accuracy.Difficulty] {
+			t.Errorf("%s questions: %d of %d correct, want %d of %d", accuracy.Difficulty,
+				accuracy.Correct, accuracy.Graded, correct[accuracy.Difficulty], graded[accuracy.Difficulty])
+		}
+	}
+}
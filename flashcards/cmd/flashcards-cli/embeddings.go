@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newEmbeddingsCommand(app *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "embeddings",
+		Short: "Manage catalog embeddings",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "regenerate",
+		Short: "Embed the cards of every published deck again with the configured model",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			catalogService, err := app.catalog()
+			if err != nil {
+				return err
+			}
+			decks, cards, err := catalogService.ReembedPublishedDecks(cmd.Context())
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Embedded %d card(s) in %d deck(s)\n", cards, decks)
+			return nil
+		},
+	})
+	return cmd
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+
+	"flashcards/services"
+
+	"github.com/spf13/cobra"
+)
+
+func newFlashcardsCommand(app *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "flashcards",
+		Short: "Manage flashcards",
+	}
+	cmd.AddCommand(newFlashcardsGenerateCommand(app))
+	return cmd
+}
+
+func newFlashcardsGenerateCommand(app *app) *cobra.Command {
+	var email string
+	var count int
+	var skipExisting bool
+
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate flashcards from every note of a user",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			userID, err := app.userID(cmd.Context(), email)
+			if err != nil {
+				return err
+			}
+			flashcardService, err := app.flashcards(cmd.Context())
+			if err != nil {
+				return err
+			}
+			result, err := flashcardService.GenerateFromAllNotes(cmd.Context(), userID, count, skipExisting)
+			if result != nil {
+				fmt.Fprintf(cmd.OutOrStdout(), "Generated %d flashcard(s) from %d note(s); %d skipped, %d failed\n",
+					result.Flashcards, result.Notes, result.Skipped, result.Failed)
+			}
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&email, "user", "", "email of the user who owns the notes")
+	cmd.Flags().IntVar(&count, "count", services.DEFAULT_GENERATED_FLASHCARDS, "flashcards to generate per note")
+	cmd.Flags().BoolVar(&skipExisting, "skip-existing", false, "skip notes that already have flashcards")
+	cmd.MarkFlagRequired("user")
+	return cmd
+}
@@ -0,0 +1,278 @@
+// Command flashcards-cli runs administration and batch jobs against the
+// database through the service layer, without going through the HTTP API:
+// migrations, note import and export, re-embedding the catalog,
+// generating flashcards from every note of a user and granting the admin
+// role.
+//
+//	go run ./cmd/flashcards-cli migrate
+//	go run ./cmd/flashcards-cli flashcards generate --user demo@example.com
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"flashcards/config"
+	"flashcards/db"
+	"flashcards/services"
+
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	app := &app{}
+	root := &cobra.Command{
+		Use:           "flashcards-cli",
+		Short:         "Administration and batch jobs for the flashcards API",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			slog.SetDefault(services.NewLogger(cfg.LogFormat, cfg.LogLevel))
+			app.cfg = cfg
+			return nil
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			app.Close()
+		},
+	}
+	root.AddCommand(newMigrateCommand(app), newNotesCommand(app), newEmbeddingsCommand(app), newFlashcardsCommand(app), newUsersCommand(app))
+
+	if err := root.ExecuteContext(ctx); err != nil {
+		app.Close()
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// app opens the database and the services a command needs on first use,
+// and closes the database when it is done
+type app struct {
+	cfg  *config.Config
+	pool *db.Database
+
+	deckService      *services.DeckService
+	noteService      *services.NoteService
+	quotaService     *services.QuotaService
+	quizService      *services.QuizService
+	flashcardService *services.FlashcardService
+	authService      *services.AuthService
+	userRepo         db.UserStore
+	organizationRepo db.OrganizationRepository
+}
+
+func (a *app) Close() {
+	if a.pool != nil {
+		a.pool.Close()
+		a.pool = nil
+	}
+}
+
+// database opens the pool the repositories share on first use
+func (a *app) database() (*db.Database, error) {
+	if a.pool == nil {
+		database, err := db.Open(a.cfg.StorageDriver, a.cfg.DatabaseURL, a.cfg.SQLitePath)
+		if err != nil {
+			return nil, err
+		}
+		a.pool = database
+	}
+	return a.pool, nil
+}
+
+func (a *app) mailer() *services.Mailer {
+	return services.NewMailer(services.MailerConfig{
+		Host:     a.cfg.SMTPHost,
+		Port:     a.cfg.SMTPPort,
+		Username: a.cfg.SMTPUsername,
+		Password: a.cfg.SMTPPassword,
+		From:     a.cfg.SMTPFrom,
+	})
+}
+
+func (a *app) embeddingClient() *services.EmbeddingClient {
+	return services.NewEmbeddingClient(services.EmbeddingConfig{
+		BaseURL: a.cfg.EmbeddingBaseURL,
+		APIKey:  a.cfg.EmbeddingAPIKey,
+		Model:   a.cfg.EmbeddingModel,
+	})
+}
+
+func (a *app) users() (db.UserStore, error) {
+	if a.userRepo == nil {
+		database, err := a.database()
+		if err != nil {
+			return nil, err
+		}
+		repo := db.NewUserStore(database)
+		a.userRepo = repo
+	}
+	return a.userRepo, nil
+}
+
+func (a *app) auth() (*services.AuthService, error) {
+	if a.authService == nil {
+		repo, err := a.users()
+		if err != nil {
+			return nil, err
+		}
+		a.authService = services.NewAuthService(repo, a.cfg.JWTSecret, a.cfg.JWTTTL)
+	}
+	return a.authService, nil
+}
+
+// userID looks up a user by email
+func (a *app) userID(ctx context.Context, email string) (int, error) {
+	repo, err := a.users()
+	if err != nil {
+		return 0, err
+	}
+
+	user, err := repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return 0, err
+	}
+	return user.ID, nil
+}
+
+func (a *app) decks() (*services.DeckService, error) {
+	if a.deckService == nil {
+		database, err := a.database()
+		if err != nil {
+			return nil, err
+		}
+		repo := db.NewDeckStore(database)
+		a.deckService = services.NewDeckService(repo, nil)
+	}
+	return a.deckService, nil
+}
+
+func (a *app) quotas() (*services.QuotaService, error) {
+	if a.quotaService == nil {
+		database, err := a.database()
+		if err != nil {
+			return nil, err
+		}
+		organizationRepo := db.NewOrganizationStore(database)
+		spendRepo := db.NewSpendStore(database)
+		spendService := services.NewSpendService(spendRepo, a.mailer(), services.SpendConfig{
+			SpikeMultiplier:  a.cfg.SpendSpikeMultiplier,
+			MinTokens:        a.cfg.SpendMinTokens,
+			AlertEmails:      a.cfg.SpendAlertEmails,
+			ThrottleDuration: a.cfg.SpendThrottleDuration,
+		})
+		a.organizationRepo = organizationRepo
+		a.quotaService = services.NewQuotaService(organizationRepo, spendService)
+	}
+	return a.quotaService, nil
+}
+
+func (a *app) notes() (*services.NoteService, error) {
+	if a.noteService == nil {
+		deckService, err := a.decks()
+		if err != nil {
+			return nil, err
+		}
+		quotaService, err := a.quotas()
+		if err != nil {
+			return nil, err
+		}
+		database, err := a.database()
+		if err != nil {
+			return nil, err
+		}
+		repo := db.NewNoteStore(database)
+		a.noteService = services.NewNoteService(repo, deckService, quotaService, nil)
+	}
+	return a.noteService, nil
+}
+
+func (a *app) quiz(ctx context.Context) (*services.QuizService, error) {
+	if a.quizService != nil {
+		return a.quizService, nil
+	}
+
+	noteService, err := a.notes()
+	if err != nil {
+		return nil, err
+	}
+	deckService, err := a.decks()
+	if err != nil {
+		return nil, err
+	}
+	quotaService, err := a.quotas()
+	if err != nil {
+		return nil, err
+	}
+
+	database, err := a.database()
+	if err != nil {
+		return nil, err
+	}
+	routingRepo := db.NewRoutingRuleStore(database)
+	contentFilterRepo := db.NewContentFilterStore(database)
+	promptRepo := db.NewPromptTemplateStore(database)
+	sessionRepo := db.NewQuizSessionStore(database)
+
+	promptRegistry, err := services.NewPromptRegistry(ctx, promptRepo, a.cfg.PromptTemplateDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load prompt templates: %w", err)
+	}
+
+	a.quizService, err = services.NewQuizService(noteService, deckService, services.NewRoutingService(routingRepo, a.organizationRepo), services.NewContentFilterService(contentFilterRepo), promptRegistry, nil, sessionRepo, services.ProviderConfig{
+		Provider:         a.cfg.LLMProvider,
+		Model:            a.cfg.LLMModel,
+		VisionModel:      a.cfg.LLMVisionModel,
+		BaseURL:          a.cfg.LLMBaseURL,
+		APIKey:           a.cfg.LLMAPIKey(),
+		ProviderAPIKeys:  a.cfg.LLMProviderAPIKeys(),
+		ProviderBaseURLs: a.cfg.LLMProviderBaseURLs,
+		ContextWindow:    a.cfg.LLMContextWindow,
+		Timeout:          a.cfg.LLMTimeout,
+		Quotas:           quotaService,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize quiz service: %w", err)
+	}
+	return a.quizService, nil
+}
+
+func (a *app) flashcards(ctx context.Context) (*services.FlashcardService, error) {
+	if a.flashcardService == nil {
+		quizService, err := a.quiz(ctx)
+		if err != nil {
+			return nil, err
+		}
+		database, err := a.database()
+		if err != nil {
+			return nil, err
+		}
+		repo := db.NewFlashcardStore(database)
+		a.flashcardService = services.NewFlashcardService(repo, a.noteService, a.deckService, quizService, a.quotaService)
+	}
+	return a.flashcardService, nil
+}
+
+func (a *app) catalog() (*services.CatalogService, error) {
+	deckService, err := a.decks()
+	if err != nil {
+		return nil, err
+	}
+	database, err := a.database()
+	if err != nil {
+		return nil, err
+	}
+	repo := db.NewCatalogStore(database)
+	deadLetterRepo := db.NewDeadLetterStore(database)
+	return services.NewCatalogService(repo, deckService, a.embeddingClient(), a.mailer(), a.cfg.CuratorEmails, services.NewDeadLetterService(deadLetterRepo), a.cfg.AppURL), nil
+}
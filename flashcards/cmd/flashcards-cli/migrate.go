@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+
+	"flashcards/db"
+
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCommand(app *app) *cobra.Command {
+	var dir string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply pending database migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// The SQLite schema is created when the database is opened
+			if app.cfg.StorageDriver == db.STORAGE_SQLITE {
+				fmt.Fprintln(cmd.OutOrStdout(), "SQLite storage needs no migrations")
+				return nil
+			}
+
+			var migrations []db.Migration
+			var err error
+			if dryRun {
+				migrations, err = db.PendingMigrations(cmd.Context(), app.cfg.DatabaseURL, dir)
+			} else {
+				migrations, err = db.Migrate(cmd.Context(), app.cfg.DatabaseURL, dir)
+			}
+			for _, migration := range migrations {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", migration.Version, migration.Name)
+			}
+			if err != nil {
+				return err
+			}
+
+			verb := "Applied"
+			if dryRun {
+				verb = "Pending:"
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %d migration(s)\n", verb, len(migrations))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&dir, "dir", "supabase/migrations", "directory containing the SQL migration files")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "list pending migrations without applying them")
+	return cmd
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"flashcards/models"
+
+	"github.com/spf13/cobra"
+)
+
+func newNotesCommand(app *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "notes",
+		Short: "Import and export notes",
+	}
+	cmd.AddCommand(newNotesImportCommand(app), newNotesExportCommand(app))
+	return cmd
+}
+
+func newNotesImportCommand(app *app) *cobra.Command {
+	var email string
+	var deckID, headingLevel int
+	var validateOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "import FILE...",
+		Short: "Import Markdown files or zip archives as notes",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := &models.ImportNotesRequest{HeadingLevel: headingLevel, ValidateOnly: validateOnly}
+			if deckID != 0 {
+				req.DeckID = &deckID
+			}
+			for _, path := range args {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				req.Files = append(req.Files, models.ImportFile{Name: filepath.Base(path), Data: data})
+			}
+
+			userID, err := app.userID(cmd.Context(), email)
+			if err != nil {
+				return err
+			}
+			noteService, err := app.notes()
+			if err != nil {
+				return err
+			}
+			report, err := noteService.ImportNotes(cmd.Context(), userID, req)
+			if err != nil {
+				return err
+			}
+			return writeJSON(cmd, report)
+		},
+	}
+	cmd.Flags().StringVar(&email, "user", "", "email of the user who owns the notes")
+	cmd.Flags().IntVar(&deckID, "deck", 0, "deck to import the notes into")
+	cmd.Flags().IntVar(&headingLevel, "heading-level", 0, "deepest heading level that starts a new note (default 2)")
+	cmd.Flags().BoolVar(&validateOnly, "validate-only", false, "report what would be imported without writing anything")
+	cmd.MarkFlagRequired("user")
+	return cmd
+}
+
+func newNotesExportCommand(app *app) *cobra.Command {
+	var email, out string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export all of a user's notes as JSON",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			userID, err := app.userID(cmd.Context(), email)
+			if err != nil {
+				return err
+			}
+			noteService, err := app.notes()
+			if err != nil {
+				return err
+			}
+			notes, err := noteService.GetAllNotes(cmd.Context(), userID)
+			if err != nil {
+				return err
+			}
+
+			if out != "" {
+				file, err := os.Create(out)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				cmd.SetOut(file)
+			}
+			if err := writeJSON(cmd, notes); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Exported %d note(s)\n", len(notes))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&email, "user", "", "email of the user who owns the notes")
+	cmd.Flags().StringVarP(&out, "out", "o", "", "file to write to instead of stdout")
+	cmd.MarkFlagRequired("user")
+	return cmd
+}
+
+func writeJSON(cmd *cobra.Command, v any) error {
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
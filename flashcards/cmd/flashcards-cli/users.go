@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newUsersCommand(app *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "users",
+		Short: "Manage user roles",
+	}
+	cmd.AddCommand(
+		newUsersAdminCommand(app, "grant-admin EMAIL", "Let a user use the /admin endpoints", true),
+		newUsersAdminCommand(app, "revoke-admin EMAIL", "Take away a user's admin role", false),
+	)
+	return cmd
+}
+
+func newUsersAdminCommand(app *app, use, short string, isAdmin bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			authService, err := app.auth()
+			if err != nil {
+				return err
+			}
+			user, err := authService.SetAdmin(cmd.Context(), args[0], isAdmin)
+			if err != nil {
+				return err
+			}
+
+			verb := "Granted"
+			if !isAdmin {
+				verb = "Revoked"
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s the admin role of %s\n", verb, user.Email)
+			return nil
+		},
+	}
+}
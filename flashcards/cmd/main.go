@@ -1,81 +1,557 @@
 package main
 
 import (
-	"fmt"
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
+	"flashcards/bootstrap"
+	"flashcards/chaos"
 	"flashcards/config"
 	"flashcards/db"
 	"flashcards/handlers"
+	"flashcards/models"
 	"flashcards/services"
+	"flashcards/storage"
 
 	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("Failed to load configuration", "error", err)
+	}
+	slog.SetDefault(services.NewLogger(cfg.LogFormat, cfg.LogLevel))
 
-	if cfg.DatabaseURL == "" {
-		log.Fatal("DB_URL environment variable is required")
+	server := bootstrap.NewServer(":"+cfg.Port, cfg.ShutdownTimeout)
+	if err := server.Run(newHandler(cfg, server)); err != nil {
+		fatal("Server failed", "error", err)
 	}
+}
 
-	todoRepo, err := db.NewPostgresTodoRepository(cfg.DatabaseURL)
-	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+// newHandler opens the storage and services cfg describes and routes the
+// API to them. Everything it opens is registered with server to be closed
+// on shutdown.
+func newHandler(cfg *config.Config, server *bootstrap.Server) http.Handler {
+	if cfg.OTLPEndpoint != "" {
+		shutdownTracing, err := services.SetupTracing(context.Background(), cfg.ServiceName)
+		if err != nil {
+			fatal("Failed to initialize tracing", "error", err)
+		}
+		// Registered first so spans from the rest of shutdown are flushed
+		server.OnShutdown("tracing", shutdownTracing)
 	}
-	defer todoRepo.Close()
 
-	noteRepo, err := db.NewPostgresNoteRepository(cfg.DatabaseURL)
+	// Fault injection is opt-in and must be enabled before any pool opens
+	faults := chaos.New(chaos.Config{
+		LatencyRate:      cfg.ChaosLatencyRate,
+		Latency:          cfg.ChaosLatency,
+		LLMErrorRate:     cfg.ChaosLLMErrorRate,
+		LLMMalformedRate: cfg.ChaosLLMMalformedRate,
+		DBErrorRate:      cfg.ChaosDBErrorRate,
+	})
+	if faults != nil {
+		slog.Warn("Fault injection is enabled", "latency_rate", cfg.ChaosLatencyRate, "latency", cfg.ChaosLatency,
+			"llm_error_rate", cfg.ChaosLLMErrorRate, "llm_malformed_rate", cfg.ChaosLLMMalformedRate, "db_error_rate", cfg.ChaosDBErrorRate)
+		db.EnableChaos(faults)
+	}
+
+	// Pool settings apply only to pools opened after them
+	db.ConfigurePool(db.PoolConfig{
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
+		ConnMaxIdleTime: cfg.DBConnMaxIdleTime,
+	})
+
+	// Every repository shares one pool, which closes after the services
+	// using it have shut down
+	database, err := db.Open(cfg.StorageDriver, cfg.DatabaseURL, cfg.SQLitePath)
 	if err != nil {
-		log.Fatalf("Failed to initialize note database: %v", err)
+		fatal("Failed to open database", "error", err)
 	}
-	defer noteRepo.Close()
+	server.Close("database", database)
+
+	// Transactions services run across several repositories
+	unitOfWork := db.NewUnitOfWork(database)
+	userRepo := db.NewUserStore(database)
+	authService := services.NewAuthService(userRepo, cfg.JWTSecret, cfg.JWTTTL)
+
+	apiKeyRepo := db.NewAPIKeyStore(database)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	authHandler := handlers.NewAuthHandler(authService, apiKeyService)
+	server.OnShutdown("api key usage", apiKeyService.Flush)
+
+	analyticsRepo := db.NewAnalyticsStore(database)
+	analyticsService := services.NewAnalyticsService(analyticsRepo, cfg.AnalyticsRetention)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
+	// Runs before the database closes, so requests drained during shutdown
+	// are kept
+	server.OnShutdown("request analytics", analyticsService.Flush)
 
+	todoRepo := db.NewTodoStore(database)
+	noteRepo := db.NewNoteStore(database)
 	todoService := services.NewTodoService(todoRepo)
 	todoHandler := handlers.NewTodoHandler(todoService)
 
-	noteService := services.NewNoteService(noteRepo)
+	deckRepo := db.NewDeckStore(database)
+	// Redis shares the note and quiz caches and rate limits between instances
+	var redisClient *redis.Client
+	if cfg.RedisURL != "" {
+		redisClient, err = services.NewRedisClient(context.Background(), cfg.RedisURL)
+		if err != nil {
+			fatal("Failed to initialize Redis", "error", err)
+		}
+		server.Close("redis", redisClient)
+	}
+
+	var noteCache services.NoteCache
+	if cfg.NoteCacheTTL > 0 {
+		noteCache = services.NewMemoryNoteCache(cfg.NoteCacheTTL)
+		if redisClient != nil {
+			noteCache = services.NewRedisNoteCache(redisClient, cfg.NoteCacheTTL)
+		}
+	}
+
+	deckService := services.NewDeckService(deckRepo, noteCache)
+	deckHandler := handlers.NewDeckHandler(deckService)
+
+	organizationRepo := db.NewOrganizationStore(database)
+	mailer := services.NewMailer(services.MailerConfig{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.SMTPFrom,
+	})
+
+	spendRepo := db.NewSpendStore(database)
+	spendService := services.NewSpendService(spendRepo, mailer, services.SpendConfig{
+		SpikeMultiplier:  cfg.SpendSpikeMultiplier,
+		MinTokens:        cfg.SpendMinTokens,
+		AlertEmails:      cfg.SpendAlertEmails,
+		ThrottleDuration: cfg.SpendThrottleDuration,
+	})
+	spendHandler := handlers.NewSpendHandler(spendService)
+
+	quotaService := services.NewQuotaService(organizationRepo, spendService)
+	organizationHandler := handlers.NewOrganizationHandler(quotaService)
+
+	usageRepo := db.NewUsageStore(database)
+	usageService, err := services.NewUsageService(usageRepo, cfg.LLMMonthlyBudgetUSD, cfg.LLMPrices)
+	if err != nil {
+		fatal("Failed to initialize usage service", "error", err)
+	}
+	usageHandler := handlers.NewUsageHandler(usageService)
+
+	deadLetterRepo := db.NewDeadLetterStore(database)
+	deadLetterService := services.NewDeadLetterService(deadLetterRepo)
+	deadLetterHandler := handlers.NewDeadLetterHandler(deadLetterService)
+
+	billingService := services.NewBillingService(organizationRepo, services.StripeConfig{
+		SecretKey:     cfg.StripeSecretKey,
+		WebhookSecret: cfg.StripeWebhookSecret,
+		Prices: map[string]string{
+			models.PlanPro:        cfg.StripePricePro,
+			models.PlanEnterprise: cfg.StripePriceEnterprise,
+		},
+		SuccessURL: cfg.BillingSuccessURL,
+		CancelURL:  cfg.BillingCancelURL,
+	}, deadLetterService)
+	deadLetterService.Handle(models.DeadLetterKindStripeWebhook, billingService.RetryWebhook)
+	billingHandler := handlers.NewBillingHandler(billingService)
+
+	noteService := services.NewNoteService(noteRepo, deckService, quotaService, noteCache)
 	noteHandler := handlers.NewNoteHandler(noteService)
 
-	quizService, err := services.NewQuizService(noteService, cfg.OpenAIAPIKey)
+	tagRepo := db.NewTagStore(database)
+	tagService := services.NewTagService(tagRepo, noteService)
+	tagHandler := handlers.NewTagHandler(tagService)
+
+	routingRepo := db.NewRoutingRuleStore(database)
+	routingService := services.NewRoutingService(routingRepo, organizationRepo)
+	routingHandler := handlers.NewRoutingHandler(routingService)
+
+	contentFilterRepo := db.NewContentFilterStore(database)
+	contentFilterService := services.NewContentFilterService(contentFilterRepo)
+	contentFilterHandler := handlers.NewContentFilterHandler(contentFilterService)
+
+	brandingRepo := db.NewBrandingStore(database)
+	brandingHandler := handlers.NewBrandingHandler(services.NewBrandingService(brandingRepo))
+
+	maintenanceRepo := db.NewMaintenanceStore(database)
+	maintenanceService := services.NewMaintenanceService(maintenanceRepo)
+	maintenanceHandler := handlers.NewMaintenanceHandler(maintenanceService)
+
+	promptRepo := db.NewPromptTemplateStore(database)
+	promptRegistry, err := services.NewPromptRegistry(context.Background(), promptRepo, cfg.PromptTemplateDir)
 	if err != nil {
-		log.Fatalf("Failed to initialize quiz service: %v", err)
+		fatal("Failed to load prompt templates", "error", err)
 	}
-	quizHandler := handlers.NewQuizHandler(quizService)
+	promptHandler := handlers.NewPromptHandler(promptRegistry)
+
+	sessionRepo := db.NewQuizSessionStore(database)
+	questionEmbeddingRepo := db.NewQuestionEmbeddingStore(database)
+	embeddingClient := services.NewEmbeddingClient(services.EmbeddingConfig{
+		BaseURL: cfg.EmbeddingBaseURL,
+		APIKey:  cfg.EmbeddingAPIKey,
+		Model:   cfg.EmbeddingModel,
+	})
+	questionDedupService := services.NewQuestionDedupService(questionEmbeddingRepo, embeddingClient, cfg.QuestionSimilarityThreshold, cfg.QuestionDedupRegenerations)
+
+	llmAvailability := services.NewLLMAvailability(cfg.LLMFailureThreshold, cfg.LLMRetryInterval)
+	quizService, err := services.NewQuizService(noteService, deckService, routingService, contentFilterService, promptRegistry, questionDedupService, sessionRepo, services.ProviderConfig{
+		Provider:         cfg.LLMProvider,
+		Model:            cfg.LLMModel,
+		VisionModel:      cfg.LLMVisionModel,
+		BaseURL:          cfg.LLMBaseURL,
+		APIKey:           cfg.LLMAPIKey(),
+		ProviderAPIKeys:  cfg.LLMProviderAPIKeys(),
+		ProviderBaseURLs: cfg.LLMProviderBaseURLs,
+		ContextWindow:    cfg.LLMContextWindow,
+		Timeout:          cfg.LLMTimeout,
+		Quotas:           quotaService,
+		Usage:            usageService,
+		Availability:     llmAvailability,
+		Chaos:            faults,
+	})
+	if err != nil {
+		fatal("Failed to initialize quiz service", "error", err)
+	}
+	if cfg.QuizCacheTTL > 0 {
+		if redisClient != nil {
+			quizService.CacheQuizzesIn(services.NewRedisQuizCache(redisClient, cfg.QuizCacheTTL))
+		} else {
+			quizService.CacheQuizzesIn(services.NewMemoryQuizCache(cfg.QuizCacheTTL))
+		}
+	}
+	noteService.EnrichWith(quizService)
+	server.OnShutdown("note enrichment", noteService.Stop)
+	metaHandler := handlers.NewMetaHandler(quizService, maintenanceService)
+	openAPIHandler, err := handlers.NewOpenAPIHandler()
+	if err != nil {
+		fatal("Failed to build OpenAPI document", "error", err)
+	}
+
+	idempotencyRepo := db.NewIdempotencyStore(database)
+	idempotencyService := services.NewIdempotencyService(idempotencyRepo, cfg.IdempotencyTTL)
+	var rateLimiter, llmRateLimiter services.RateLimiter
+	if redisClient != nil {
+		rateLimiter = services.NewRedisRateLimiter(redisClient, "requests", cfg.RateLimitRPS, cfg.RateLimitBurst)
+		llmRateLimiter = services.NewRedisRateLimiter(redisClient, "llm", cfg.LLMRateLimitRPS, cfg.LLMRateLimitBurst)
+	} else {
+		rateLimiter = services.NewMemoryRateLimiter(cfg.RateLimitRPS, cfg.RateLimitBurst)
+		llmRateLimiter = services.NewMemoryRateLimiter(cfg.LLMRateLimitRPS, cfg.LLMRateLimitBurst)
+	}
+	rateLimit := handlers.NewRateLimit(rateLimiter, cfg.ClientIPHeader)
+	quizHandler := handlers.NewQuizHandler(quizService, idempotencyService, handlers.NewRateLimit(llmRateLimiter, cfg.ClientIPHeader))
+
+	flashcardRepo := db.NewFlashcardStore(database)
+	flashcardService := services.NewFlashcardService(flashcardRepo, noteService, deckService, quizService, quotaService)
+	flashcardHandler := handlers.NewFlashcardHandler(flashcardService)
+
+	vocabularyRepo := db.NewVocabularyStore(database)
+	ttsClient := services.NewTTSClient(services.TTSConfig{
+		BaseURL: cfg.TTSBaseURL,
+		APIKey:  cfg.TTSAPIKey,
+		Model:   cfg.TTSModel,
+		Voice:   cfg.TTSVoice,
+
+		LanguageVoices: cfg.TTSLanguageVoices,
+	})
+
+	vocabularyService := services.NewVocabularyService(vocabularyRepo, flashcardService, quizService, ttsClient)
+	vocabularyHandler := handlers.NewVocabularyHandler(vocabularyService)
+
+	reviewRepo := db.NewReviewStore(database)
+	reviewService := services.NewReviewService(reviewRepo, flashcardService, deckService, vocabularyService)
+	reviewHandler := handlers.NewReviewHandler(reviewService, authService)
+
+	// Without object storage, attachments stay in Postgres and exports are
+	// only streamed
+	var objectStore storage.Store
+	var storageHandler *handlers.StorageHandler
+	if cfg.ObjectStorageDriver != "" {
+		objectStore, err = storage.New(storage.Config{
+			Driver:          cfg.ObjectStorageDriver,
+			Dir:             cfg.ObjectStorageDir,
+			BaseURL:         cfg.ObjectStorageBaseURL,
+			SigningSecret:   cfg.JWTSecret,
+			Bucket:          cfg.ObjectStorageBucket,
+			Region:          cfg.ObjectStorageRegion,
+			Endpoint:        cfg.ObjectStorageEndpoint,
+			AccessKeyID:     cfg.ObjectStorageAccessKeyID,
+			SecretAccessKey: cfg.ObjectStorageSecretAccessKey,
+			PathStyle:       cfg.ObjectStoragePathStyle,
+		})
+		if err != nil {
+			fatal("Failed to initialize object storage", "error", err)
+		}
+		if local, ok := objectStore.(*storage.LocalStore); ok {
+			storageHandler = handlers.NewStorageHandler(local)
+		}
+	}
+
+	attachmentRepo := db.NewAttachmentStore(database)
+	attachmentService := services.NewAttachmentService(attachmentRepo, objectStore)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService)
+
+	transcriptionClient := services.NewTranscriptionClient(services.TranscriptionConfig{
+		BaseURL: cfg.TranscriptionBaseURL,
+		APIKey:  cfg.TranscriptionAPIKey,
+		Model:   cfg.TranscriptionModel,
+	})
+	sessionService := services.NewQuizSessionService(sessionRepo, quizService, noteService, deckService, attachmentService, mailer, transcriptionClient)
+	sessionHandler := handlers.NewQuizSessionHandler(sessionService)
+	liveQuizHandler := handlers.NewLiveQuizHandler(services.NewLiveQuizService(sessionService, authService))
+
+	recipientRepo := db.NewReportRecipientStore(database)
+	progressService := services.NewProgressService(sessionRepo, recipientRepo, mailer)
+	progressHandler := handlers.NewProgressHandler(progressService)
+
+	digestRepo := db.NewDigestStore(database)
+	digestService := services.NewDigestService(digestRepo, authService, mailer, cfg.AppURL)
+
+	dailyQuestionRepo := db.NewDailyQuestionStore(database)
+	dailyQuestionService := services.NewDailyQuestionService(dailyQuestionRepo, deckService, sessionService, authService, mailer, cfg.AppURL, cfg.DailyQuestionReplyTo, cfg.DailyQuestionHour)
+	dailyQuestionHandler := handlers.NewDailyQuestionHandler(dailyQuestionService)
+
+	studyAnalyticsRepo := db.NewStudyAnalyticsStore(database)
+	studyAnalyticsService := services.NewStudyAnalyticsService(studyAnalyticsRepo)
+	studyAnalyticsHandler := handlers.NewStudyAnalyticsHandler(studyAnalyticsService)
+
+	activityRepo := db.NewActivityStore(database)
+	activityService := services.NewActivityService(activityRepo)
+	activityHandler := handlers.NewActivityHandler(activityService)
+
+	deckSuggestionRepo := db.NewDeckSuggestionStore(database)
+	deckSuggestionService := services.NewDeckSuggestionService(deckSuggestionRepo, deckService, flashcardService, tagService)
+	deckSuggestionHandler := handlers.NewDeckSuggestionHandler(deckSuggestionService)
+
+	inviteRepo := db.NewInviteStore(database)
+	membershipService := services.NewMembershipService(organizationRepo, inviteRepo, userRepo, mailer, cfg.AppURL)
+	membershipHandler := handlers.NewMembershipHandler(membershipService)
+
+	onboardingRepo := db.NewOnboardingStore(database)
+	onboardingService := services.NewOnboardingService(onboardingRepo)
+	onboardingHandler := handlers.NewOnboardingHandler(onboardingService)
+
+	catalogRepo := db.NewCatalogStore(database)
+	catalogService := services.NewCatalogService(catalogRepo, deckService, embeddingClient, mailer, cfg.CuratorEmails, deadLetterService, cfg.AppURL)
+	deadLetterService.Handle(models.DeadLetterKindSimilarityCheck, catalogService.RetrySimilarityCheck)
+	catalogHandler := handlers.NewCatalogHandler(catalogService)
+
+	deckExportRepo := db.NewDeckExportStore(database)
+	deckExportService := services.NewDeckExportService(deckService, flashcardService, deckExportRepo, objectStore, cfg.ExportRetention)
+	deckExportHandler := handlers.NewDeckExportHandler(deckExportService)
+
+	deckImportService := services.NewDeckImportService(deckService, flashcardService, quotaService, unitOfWork)
+	deckImportHandler := handlers.NewDeckImportHandler(deckImportService)
+
+	embedRepo := db.NewEmbedStore(database)
+	embedService := services.NewEmbedService(embedRepo, flashcardService, deckService)
+	embedHandler := handlers.NewEmbedHandler(embedService)
+
+	importJobRepo := db.NewImportJobStore(database)
+	importJobService := services.NewImportJobService(importJobRepo, noteService, quotaService, deadLetterService)
+	deadLetterService.Handle(models.DeadLetterKindImportJob, importJobService.RetryImportJob)
+	server.OnShutdown("import jobs", importJobService.Stop)
+	importJobHandler := handlers.NewImportJobHandler(importJobService, idempotencyService)
+
+	jobRepo := db.NewJobStore(database)
+	jobService := services.NewJobService(jobRepo)
+	jobService.Handle(models.JobKindGenerateFlashcards, flashcardService.RunGenerateFlashcardsJob)
+	jobService.Handle(models.JobKindSummarizeNotes, noteService.RunSummarizeNotesJob)
+	jobService.HandleAdmin(models.JobKindReembedCatalog, catalogService.RunReembedJob)
+	jobHandler := handlers.NewJobHandler(jobService)
+
+	webhookRepo := db.NewWebhookStore(database)
+	webhookService := services.NewWebhookService(webhookRepo, noteService, sessionService)
+	noteService.PublishEventsTo(webhookService)
+	quizService.PublishEventsTo(webhookService)
+	sessionService.PublishEventsTo(webhookService)
+	reviewService.PublishEventsTo(webhookService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	restHookHandler := handlers.NewRestHookHandler(webhookService)
+
+	scheduledJobRepo := db.NewScheduledJobStore(database)
+	scheduler := services.NewScheduler(scheduledJobRepo)
+	if cfg.ProgressReportInterval > 0 {
+		scheduler.Every("weekly-progress-report", cfg.ProgressReportInterval, progressService.SendWeeklyReports)
+	}
+	if cfg.DeckSuggestionInterval > 0 {
+		scheduler.Every("weekly-deck-suggestions", cfg.DeckSuggestionInterval, deckSuggestionService.AnalyzeDecks)
+	}
+	if cfg.DigestInterval > 0 {
+		scheduler.Every("daily-review-digest", cfg.DigestInterval, digestService.SendDailyDigests)
+	}
+	scheduler.Every("daily-questions", services.DAILY_QUESTION_CHECK_INTERVAL, dailyQuestionService.SendDailyQuestions)
+	scheduler.EveryInstance("request-analytics-flush", services.ANALYTICS_FLUSH_INTERVAL, analyticsService.Flush)
+	scheduler.Every("request-analytics-cleanup", 24*time.Hour, analyticsService.DeleteExpired)
+	scheduler.Every("llm-call-cleanup", 24*time.Hour, usageService.DeleteExpired)
+	scheduler.EveryInstance("api-key-usage-flush", services.API_KEY_FLUSH_INTERVAL, apiKeyService.Flush)
+	if cfg.SpendCheckInterval > 0 {
+		scheduler.Every("llm-spend-anomaly-check", cfg.SpendCheckInterval, spendService.DetectAnomalies)
+	}
+	scheduler.Every("import-job-stale-check", time.Minute, importJobService.FailStaleJobs)
+	// Dead letters, queued sessions, background jobs and webhook deliveries
+	// are leased row by row, so every instance can poll for them and share
+	// the work
+	scheduler.EveryInstance("dead-letter-retry", services.DEAD_LETTER_POLL_INTERVAL, deadLetterService.RetryDue)
+	scheduler.EveryInstance("prompt-template-reload", services.PROMPT_RELOAD_INTERVAL, promptRegistry.Reload)
+	scheduler.EveryInstance("queued-quiz-sessions", services.QUEUED_SESSION_POLL_INTERVAL, sessionService.ProcessQueuedSessions)
+	scheduler.EveryInstance("background-jobs", services.JOB_POLL_INTERVAL, jobService.RunDue)
+	scheduler.EveryInstance("webhook-deliveries", services.WEBHOOK_POLL_INTERVAL, webhookService.DeliverDue)
+	scheduler.Every("webhook-delivery-cleanup", 24*time.Hour, webhookService.DeleteExpired)
+	scheduler.Every("question-embedding-cleanup", 24*time.Hour, questionDedupService.DeleteExpired)
+	scheduler.Every("attachment-blob-cleanup", time.Hour, attachmentService.DeleteReleasedBlobs)
+	if objectStore != nil {
+		scheduler.Every("deck-export-cleanup", time.Hour, deckExportService.DeleteExpired)
+		scheduler.Every("pending-upload-cleanup", time.Hour, attachmentService.DeletePendingUploads)
+	}
+	scheduler.EveryInstance("rate-limit-sweep", time.Minute, func(ctx context.Context) error {
+		rateLimiter.Sweep(ctx)
+		return llmRateLimiter.Sweep(ctx)
+	})
+	if idempotencyService.Enabled() {
+		scheduler.Every("idempotency-key-cleanup", time.Hour, idempotencyService.DeleteExpired)
+	}
+	scheduler.Start()
+	server.OnShutdown("scheduler", func(ctx context.Context) error {
+		scheduler.Stop()
+		return nil
+	})
 
 	router := mux.NewRouter()
 
-	router.Use(corsMiddleware)
+	router.Use(handlers.Tracing)
+	router.Use(handlers.RequestLogger)
+	router.Use(analyticsHandler.Middleware)
+	router.Use(maintenanceHandler.Middleware)
+	router.Use(handlers.Compress)
+	router.Use(handlers.LimitRequestBody(int64(cfg.MaxRequestBytes)))
 	router.Use(jsonMiddleware)
-
-	todoHandler.RegisterRoutes(router)
-	noteHandler.RegisterRoutes(router)
-	quizHandler.RegisterRoutes(router)
+	router.Use(timeoutMiddleware(cfg.RequestTimeout))
 
 	router.HandleFunc("/health", healthCheckHandler).Methods("GET")
+	router.HandleFunc("/ready", server.ReadinessHandler).Methods("GET")
+	router.HandleFunc("/metrics", handlers.NewMetricsHandler(db.PoolStats).GetMetrics).Methods("GET")
+	billingHandler.RegisterWebhookRoutes(router)
 
-	addr := ":" + cfg.Port
-	fmt.Printf("Server starting on port %s\n", cfg.Port)
-
-	if err := http.ListenAndServe(addr, router); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	// Public routes are rate limited per client IP
+	public := router.NewRoute().Subrouter()
+	public.Use(handlers.Chaos(faults))
+	public.Use(rateLimit.Middleware)
+	authHandler.RegisterRoutes(public)
+	reviewHandler.RegisterPublicRoutes(public)
+	dailyQuestionHandler.RegisterPublicRoutes(public)
+	embedHandler.RegisterPublicRoutes(public)
+	catalogHandler.RegisterPublicRoutes(public)
+	brandingHandler.RegisterPublicRoutes(public)
+	if storageHandler != nil {
+		storageHandler.RegisterPublicRoutes(public)
 	}
-}
+	metaHandler.RegisterRoutes(public)
+	openAPIHandler.RegisterRoutes(public)
 
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	// Live quizzes sign in over the connection and close when shutdown starts
+	live := public.NewRoute().Subrouter()
+	live.Use(server.Streaming)
+	liveQuizHandler.RegisterRoutes(live)
+
+	// Everything else requires a valid token and is rate limited per user
+	api := router.NewRoute().Subrouter()
+	api.Use(handlers.Chaos(faults))
+	api.Use(authHandler.Middleware)
+	api.Use(rateLimit.Middleware)
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
+	authHandler.RegisterAuthenticatedRoutes(api)
+	apiKeyHandler.RegisterRoutes(api)
+	todoHandler.RegisterRoutes(api)
+	noteHandler.RegisterRoutes(api)
+	deckHandler.RegisterRoutes(api)
+	tagHandler.RegisterRoutes(api)
+	flashcardHandler.RegisterRoutes(api)
+	vocabularyHandler.RegisterRoutes(api)
+	reviewHandler.RegisterRoutes(api)
+	quizHandler.RegisterRoutes(api)
+	sessionHandler.RegisterRoutes(api)
+	attachmentHandler.RegisterRoutes(api)
+	progressHandler.RegisterRoutes(api)
+	dailyQuestionHandler.RegisterRoutes(api)
+	studyAnalyticsHandler.RegisterRoutes(api)
+	activityHandler.RegisterRoutes(api)
+	deckSuggestionHandler.RegisterRoutes(api)
+	organizationHandler.RegisterRoutes(api)
+	usageHandler.RegisterRoutes(api)
+	billingHandler.RegisterRoutes(api)
+	membershipHandler.RegisterRoutes(api)
+	onboardingHandler.RegisterRoutes(api)
+	catalogHandler.RegisterRoutes(api)
+	embedHandler.RegisterRoutes(api)
+	deckExportHandler.RegisterRoutes(api)
+	deckImportHandler.RegisterRoutes(api)
+	importJobHandler.RegisterRoutes(api)
+	jobHandler.RegisterRoutes(api)
+	webhookHandler.RegisterRoutes(api)
+	restHookHandler.RegisterRoutes(api)
+
+	// Routes under /admin also require the admin role
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Use(authHandler.RequireAdmin)
+	routingHandler.RegisterAdminRoutes(admin)
+	contentFilterHandler.RegisterAdminRoutes(admin)
+	brandingHandler.RegisterAdminRoutes(admin)
+	maintenanceHandler.RegisterAdminRoutes(admin)
+	promptHandler.RegisterAdminRoutes(admin)
+	organizationHandler.RegisterAdminRoutes(admin)
+	usageHandler.RegisterAdminRoutes(admin)
+	catalogHandler.RegisterAdminRoutes(admin)
+	analyticsHandler.RegisterAdminRoutes(admin)
+	spendHandler.RegisterAdminRoutes(admin)
+	jobHandler.RegisterAdminRoutes(admin)
+	deadLetterHandler.RegisterAdminRoutes(admin)
+
+	// Streaming responses are cancelled as soon as shutdown starts
+	streaming := api.NewRoute().Subrouter()
+	streaming.Use(server.Streaming)
+	quizHandler.RegisterStreamingRoutes(streaming)
+
+	// Opening connections now keeps that cost off the first requests after
+	// a deploy; the server starts once warm-up finishes or times out
+	if cfg.WarmupTimeout > 0 {
+		warmup := services.NewWarmup(cfg.WarmupTimeout)
+		// One after another, so every repository prepares its queries on
+		// the same idle connections of the shared pool
+		warmup.Add("database", func(ctx context.Context) error {
+			for _, repo := range []db.Warmer{userRepo, noteRepo, flashcardRepo, reviewRepo} {
+				if err := repo.Warm(ctx); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		warmup.Add("llm connection", func(ctx context.Context) error {
+			return services.WarmLLMConnection(ctx, services.ProviderConfig{Provider: cfg.LLMProvider, BaseURL: cfg.LLMBaseURL})
+		})
+		if cfg.WarmupDueQueueUsers > 0 {
+			warmup.Add("due queues", func(ctx context.Context) error {
+				return reviewService.WarmDueQueues(ctx, cfg.WarmupDueQueueUsers)
+			})
 		}
+		warmup.Run(context.Background())
+	}
 
-		next.ServeHTTP(w, r)
-	})
+	// CORS and the security headers wrap the router so preflight requests
+	// and unknown routes, which skip its middleware, are covered too
+	cors := handlers.NewCORS(cfg.CORSAllowedOrigins, cfg.CORSAllowedMethods, cfg.CORSAllowedHeaders, cfg.CORSMaxAge)
+	return handlers.SecurityHeaders(cfg.HSTSMaxAge)(cors.Middleware(router))
 }
 
 func jsonMiddleware(next http.Handler) http.Handler {
@@ -85,7 +561,32 @@ func jsonMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// timeoutMiddleware cancels the request context after timeout so database
+// queries and LLM calls stop once the deadline passes. The context is also
+// cancelled when the client disconnects. WebSocket connections outlive any
+// one request and time out when idle instead.
+func timeoutMiddleware(timeout time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if timeout <= 0 || strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
 func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"status": "healthy"}`))
 }
+
+// fatal logs the error and exits, for failures during startup
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"flashcards/bootstrap"
+	"flashcards/config"
+	"flashcards/services"
+)
+
+// newTestApp builds the whole API as main does, on SQLite in a temporary
+// directory, with every OpenAI API it calls pointed at llm so nothing leaves
+// the machine. It is shut down when the test ends.
+func newTestApp(t *testing.T, llm http.Handler) http.Handler {
+	t.Helper()
+	llmServer := httptest.NewServer(llm)
+	t.Cleanup(llmServer.Close)
+
+	for key, value := range map[string]string{
+		"STORAGE_DRIVER":         "sqlite",
+		"SQLITE_PATH":            filepath.Join(t.TempDir(), "flashcards.db"),
+		"JWT_SECRET":             "0123456789abcdef0123456789abcdef",
+		"LLM_PROVIDER":           "openai",
+		"LLM_MODEL":              "gpt-4o-mini",
+		"LLM_BASE_URL":           llmServer.URL,
+		"OPENAI_API_KEY":         "test-key",
+		"EMBEDDING_BASE_URL":     llmServer.URL,
+		"TRANSCRIPTION_BASE_URL": llmServer.URL,
+		"TTS_BASE_URL":           llmServer.URL,
+		"WARMUP_TIMEOUT":         "0",
+		"RATE_LIMIT_RPS":         "1000",
+		"RATE_LIMIT_BURST":       "1000",
+		"LLM_RATE_LIMIT_RPS":     "1000",
+		"LLM_RATE_LIMIT_BURST":   "1000",
+		"LOG_LEVEL":              "error",
+	} {
+		t.Setenv(key, value)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	slog.SetDefault(services.NewLogger(cfg.LogFormat, cfg.LogLevel))
+	server := bootstrap.NewServer(":0", cfg.ShutdownTimeout)
+	handler := newHandler(cfg, server)
+	t.Cleanup(server.Shutdown)
+	return handler
+}
+
+// testClient sends requests to an app as one user
+type testClient struct {
+	t       *testing.T
+	handler http.Handler
+	token   string
+}
+
+// register signs up a new user and sends the following requests as them
+func (c *testClient) register(email string) {
+	c.t.Helper()
+	var auth struct {
+		Token string `json:"token"`
+	}
+	response := c.do("POST", "/auth/register", `{"email":"`+email+`","password":"correct horse battery"}`, nil)
+	if response.Code != http.StatusCreated {
+		c.t.Fatalf("register %s = %d %s", email, response.Code, response.Body)
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &auth); err != nil {
+		c.t.Fatalf("register %s: %v", email, err)
+	}
+	c.token = auth.Token
+}
+
+// do sends a request with body, which may be empty, and headers
+func (c *testClient) do(method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = bytes.NewBufferString(body)
+	}
+	request := httptest.NewRequest(method, path, reader)
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	response := httptest.NewRecorder()
+	c.handler.ServeHTTP(response, request)
+	return response
+}
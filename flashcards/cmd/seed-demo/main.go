@@ -0,0 +1,136 @@
+// Command seed-demo asks the LLM for a realistic demo dataset on a subject
+// and stores it for a new user: decks with notes and flashcards, and a
+// completed quiz session per deck. It is meant for local demos and
+// screenshots, not for production databases.
+//
+//	go run ./cmd/seed-demo -subject "Cell biology" -email demo@example.com
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"flashcards/config"
+	"flashcards/db"
+	"flashcards/models"
+	"flashcards/services"
+)
+
+func main() {
+	subject := flag.String("subject", "", "subject the demo learner is studying (required)")
+	email := flag.String("email", "demo@example.com", "email of the demo user to create")
+	password := flag.String("password", "demo-password", "password of the demo user")
+	decks := flag.Int("decks", services.DEFAULT_DEMO_DECKS, "number of decks")
+	notes := flag.Int("notes", services.DEFAULT_DEMO_NOTES_PER_DECK, "notes per deck")
+	cards := flag.Int("cards", services.DEFAULT_DEMO_CARDS_PER_DECK, "flashcards per deck")
+	questions := flag.Int("questions", services.DEFAULT_DEMO_QUESTIONS_PER_DECK, "answered quiz questions per deck")
+	timeout := flag.Duration("timeout", 5*time.Minute, "time allowed for generating the dataset")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("Failed to load configuration", "error", err)
+	}
+	slog.SetDefault(services.NewLogger(cfg.LogFormat, cfg.LogLevel))
+
+	if *subject == "" {
+		fmt.Fprintln(os.Stderr, "usage: seed-demo -subject <subject> [flags]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	database, err := db.Open(cfg.StorageDriver, cfg.DatabaseURL, cfg.SQLitePath)
+	if err != nil {
+		fatal("Failed to open database", "error", err)
+	}
+	defer database.Close()
+
+	userRepo := db.NewUserStore(database)
+	deckRepo := db.NewDeckStore(database)
+	noteRepo := db.NewNoteStore(database)
+	flashcardRepo := db.NewFlashcardStore(database)
+	organizationRepo := db.NewOrganizationStore(database)
+	spendRepo := db.NewSpendStore(database)
+	routingRepo := db.NewRoutingRuleStore(database)
+	contentFilterRepo := db.NewContentFilterStore(database)
+	promptRepo := db.NewPromptTemplateStore(database)
+	sessionRepo := db.NewQuizSessionStore(database)
+	attachmentRepo := db.NewAttachmentStore(database)
+
+	ctx := context.Background()
+
+	mailer := services.NewMailer(services.MailerConfig{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.SMTPFrom,
+	})
+	spendService := services.NewSpendService(spendRepo, mailer, services.SpendConfig{
+		SpikeMultiplier:  cfg.SpendSpikeMultiplier,
+		MinTokens:        cfg.SpendMinTokens,
+		AlertEmails:      cfg.SpendAlertEmails,
+		ThrottleDuration: cfg.SpendThrottleDuration,
+	})
+	quotaService := services.NewQuotaService(organizationRepo, spendService)
+	authService := services.NewAuthService(userRepo, cfg.JWTSecret, cfg.JWTTTL)
+	deckService := services.NewDeckService(deckRepo, nil)
+	noteService := services.NewNoteService(noteRepo, deckService, quotaService, nil)
+
+	promptRegistry, err := services.NewPromptRegistry(ctx, promptRepo, cfg.PromptTemplateDir)
+	if err != nil {
+		fatal("Failed to load prompt templates", "error", err)
+	}
+
+	quizService, err := services.NewQuizService(noteService, deckService, services.NewRoutingService(routingRepo, organizationRepo), services.NewContentFilterService(contentFilterRepo), promptRegistry, nil, sessionRepo, services.ProviderConfig{
+		Provider:         cfg.LLMProvider,
+		Model:            cfg.LLMModel,
+		VisionModel:      cfg.LLMVisionModel,
+		BaseURL:          cfg.LLMBaseURL,
+		APIKey:           cfg.LLMAPIKey(),
+		ProviderAPIKeys:  cfg.LLMProviderAPIKeys(),
+		ProviderBaseURLs: cfg.LLMProviderBaseURLs,
+		ContextWindow:    cfg.LLMContextWindow,
+		Timeout:          *timeout,
+		Quotas:           quotaService,
+	})
+	if err != nil {
+		fatal("Failed to initialize quiz service", "error", err)
+	}
+
+	flashcardService := services.NewFlashcardService(flashcardRepo, noteService, deckService, quizService, quotaService)
+	sessionService := services.NewQuizSessionService(sessionRepo, quizService, noteService, deckService, services.NewAttachmentService(attachmentRepo, nil), mailer, nil)
+	seedService := services.NewDemoSeedService(quizService, deckService, noteService, flashcardService, sessionService)
+
+	auth, err := authService.Register(ctx, &models.RegisterRequest{Email: *email, Password: *password})
+	if err != nil {
+		fatal("Failed to create demo user", "email", *email, "error", err)
+	}
+	ctx = services.ContextWithLogger(ctx, slog.Default().With("user_id", auth.User.ID))
+
+	summary, err := seedService.Seed(ctx, auth.User.ID, services.DemoDatasetOptions{
+		Subject:          *subject,
+		Decks:            *decks,
+		NotesPerDeck:     *notes,
+		CardsPerDeck:     *cards,
+		QuestionsPerDeck: *questions,
+	})
+	if err != nil {
+		fatal("Failed to seed demo dataset", "error", err)
+	}
+
+	fmt.Printf("Seeded %d decks, %d notes, %d flashcards and %d quiz sessions for %s\n",
+		summary.Decks, summary.Notes, summary.Flashcards, summary.Sessions, auth.User.Email)
+	fmt.Printf("Log in with password %q or use this token until %s:\n%s\n",
+		*password, auth.ExpiresAt.Format(time.RFC3339), auth.Token)
+}
+
+// fatal logs the error and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
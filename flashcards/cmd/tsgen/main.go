@@ -0,0 +1,507 @@
+// Command tsgen generates TypeScript types for the API from the Go models,
+// so frontend clients stay in sync as the models change. It reads the JSON
+// struct tags of the models and of the request and response envelopes
+// declared in the handlers, along with the types they refer to, and the
+// string constants of the models.
+//
+//	go run ./cmd/tsgen                # writes artifacts/typescript/models.ts
+//	go run ./cmd/tsgen -check         # fails when the file is out of date
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const DEFAULT_OUTPUT = "artifacts/typescript/models.ts"
+
+// Packages whose JSON types are generated, and those the generated types
+// may refer to
+var (
+	rootPackages    = []string{"models", "handlers"}
+	packageDirs     = map[string]string{"models": "models", "handlers": "handlers", "services": "services"}
+	basicTypes      = map[string]string{"string": "string", "bool": "boolean", "any": "unknown", "error": "string"}
+	numericTypes    = []string{"int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64"}
+	externalTypes   = map[string]string{"time.Time": "string", "time.Duration": "number", "json.RawMessage": "unknown"}
+	generatedHeader = "// Code generated by go run ./cmd/tsgen; DO NOT EDIT.\n// TypeScript types for the flashcards API, generated from the Go models.\n"
+)
+
+func main() {
+	out := flag.String("out", DEFAULT_OUTPUT, "file to write the TypeScript types to")
+	check := flag.Bool("check", false, "fail instead of writing when the file is out of date")
+	flag.Parse()
+
+	gen, err := newGenerator(packageDirs)
+	if err != nil {
+		fatal("Failed to read Go packages", "error", err)
+	}
+	source, err := gen.generate()
+	if err != nil {
+		fatal("Failed to generate TypeScript types", "error", err)
+	}
+
+	if *check {
+		current, err := os.ReadFile(*out)
+		if err != nil || !bytes.Equal(current, source) {
+			fatal("TypeScript types are out of date, run go run ./cmd/tsgen", "file", *out)
+		}
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(*out), 0o755); err != nil {
+		fatal("Failed to create output directory", "error", err)
+	}
+	if err := os.WriteFile(*out, source, 0o644); err != nil {
+		fatal("Failed to write TypeScript types", "error", err)
+	}
+	slog.Info("Wrote TypeScript types", "file", *out, "types", len(gen.emitted))
+}
+
+// typeDecl is a Go type declaration with its documentation
+type typeDecl struct {
+	pkg  string
+	spec *ast.TypeSpec
+	doc  *ast.CommentGroup
+}
+
+type generator struct {
+	files   map[string][]*ast.File
+	types   map[string]map[string]*typeDecl
+	order   map[string][]string
+	emitted map[string]string
+	queue   []*typeDecl
+	out     strings.Builder
+}
+
+// newGenerator parses the non-test files of each package, in file name
+// order so the output is stable
+func newGenerator(dirs map[string]string) (*generator, error) {
+	g := &generator{
+		files:   make(map[string][]*ast.File),
+		types:   make(map[string]map[string]*typeDecl),
+		order:   make(map[string][]string),
+		emitted: make(map[string]string),
+	}
+	fset := token.NewFileSet()
+
+	for pkg, dir := range dirs {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(paths)
+
+		g.types[pkg] = make(map[string]*typeDecl)
+		for _, path := range paths {
+			if strings.HasSuffix(path, "_test.go") {
+				continue
+			}
+			file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+			if err != nil {
+				return nil, err
+			}
+			g.files[pkg] = append(g.files[pkg], file)
+
+			for _, decl := range file.Decls {
+				genDecl, ok := decl.(*ast.GenDecl)
+				if !ok || genDecl.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range genDecl.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					doc := typeSpec.Doc
+					if doc == nil && len(genDecl.Specs) == 1 {
+						doc = genDecl.Doc
+					}
+					g.types[pkg][typeSpec.Name.Name] = &typeDecl{pkg: pkg, spec: typeSpec, doc: doc}
+					g.order[pkg] = append(g.order[pkg], typeSpec.Name.Name)
+				}
+			}
+		}
+	}
+	return g, nil
+}
+
+func (g *generator) generate() ([]byte, error) {
+	g.out.WriteString(generatedHeader)
+	g.writeConstants("models")
+
+	// The JSON types of the root packages, then whatever they refer to
+	for _, pkg := range rootPackages {
+		for _, name := range g.order[pkg] {
+			decl := g.types[pkg][name]
+			if ast.IsExported(name) && hasJSONTags(decl.spec) {
+				if err := g.enqueue(decl); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	for i := 0; i < len(g.queue); i++ {
+		if err := g.writeType(g.queue[i]); err != nil {
+			return nil, err
+		}
+	}
+	return []byte(g.out.String()), nil
+}
+
+// enqueue schedules a type to be written once. Types are written under
+// their Go name, so two packages may not both contribute the same name.
+func (g *generator) enqueue(decl *typeDecl) error {
+	name := decl.spec.Name.Name
+	if pkg, ok := g.emitted[name]; ok {
+		if pkg != decl.pkg {
+			return fmt.Errorf("type %s is declared in both %s and %s", name, pkg, decl.pkg)
+		}
+		return nil
+	}
+	g.emitted[name] = decl.pkg
+	g.queue = append(g.queue, decl)
+	return nil
+}
+
+// writeConstants writes the package's exported string constants, such as
+// statuses and ratings, so clients can compare against them
+func (g *generator) writeConstants(pkg string) {
+	for _, file := range g.files[pkg] {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.CONST {
+				continue
+			}
+
+			var lines []string
+			for _, spec := range genDecl.Specs {
+				valueSpec := spec.(*ast.ValueSpec)
+				for i, name := range valueSpec.Names {
+					if !ast.IsExported(name.Name) || i >= len(valueSpec.Values) {
+						continue
+					}
+					literal, ok := valueSpec.Values[i].(*ast.BasicLit)
+					if !ok || literal.Kind != token.STRING {
+						continue
+					}
+					value, err := strconv.Unquote(literal.Value)
+					if err != nil {
+						continue
+					}
+					lines = append(lines, fmt.Sprintf("export const %s = %s;\n", name.Name, strconv.Quote(value)))
+				}
+			}
+			if len(lines) == 0 {
+				continue
+			}
+
+			g.out.WriteString("\n")
+			writeDoc(&g.out, genDecl.Doc, "")
+			for _, line := range lines {
+				g.out.WriteString(line)
+			}
+		}
+	}
+}
+
+func (g *generator) writeType(decl *typeDecl) error {
+	spec := decl.spec
+	name := spec.Name.Name
+
+	params := map[string]bool{}
+	var paramNames []string
+	if spec.TypeParams != nil {
+		for _, field := range spec.TypeParams.List {
+			for _, param := range field.Names {
+				params[param.Name] = true
+				paramNames = append(paramNames, param.Name)
+			}
+		}
+	}
+	if len(paramNames) > 0 {
+		name += "<" + strings.Join(paramNames, ", ") + ">"
+	}
+
+	g.out.WriteString("\n")
+	writeDoc(&g.out, decl.doc, "")
+
+	structType, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		typ, err := g.tsType(decl.pkg, spec.Type, params, "")
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", decl.pkg, spec.Name.Name, err)
+		}
+		fmt.Fprintf(&g.out, "export type %s = %s;\n", name, typ)
+		return nil
+	}
+
+	var extends []string
+	body, err := g.structBody(decl.pkg, structType, params, "", &extends)
+	if err != nil {
+		return fmt.Errorf("%s.%s: %w", decl.pkg, spec.Name.Name, err)
+	}
+	fmt.Fprintf(&g.out, "export interface %s", name)
+	if len(extends) > 0 {
+		fmt.Fprintf(&g.out, " extends %s", strings.Join(extends, ", "))
+	}
+	fmt.Fprintf(&g.out, " %s\n", body)
+	return nil
+}
+
+// structBody renders the fields of a struct the way encoding/json encodes
+// them. Embedded structs without a JSON name are added to extends, since
+// their fields are promoted.
+func (g *generator) structBody(pkg string, structType *ast.StructType, params map[string]bool, indent string, extends *[]string) (string, error) {
+	var b strings.Builder
+	b.WriteString("{\n")
+
+	for _, field := range structType.Fields.List {
+		tag := ""
+		if field.Tag != nil {
+			unquoted, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return "", err
+			}
+			tag = reflect.StructTag(unquoted).Get("json")
+		}
+		jsonName, options, _ := strings.Cut(tag, ",")
+		if jsonName == "-" && options == "" {
+			continue
+		}
+
+		names := field.Names
+		if len(names) == 0 {
+			embedded := field.Type
+			if star, ok := embedded.(*ast.StarExpr); ok {
+				embedded = star.X
+			}
+			if jsonName == "" {
+				typ, err := g.tsType(pkg, embedded, params, indent)
+				if err != nil {
+					return "", err
+				}
+				*extends = append(*extends, typ)
+				continue
+			}
+			names = []*ast.Ident{ast.NewIdent(typeName(embedded))}
+		}
+
+		for _, ident := range names {
+			if !ast.IsExported(ident.Name) {
+				continue
+			}
+			name := ident.Name
+			if jsonName != "" {
+				name = jsonName
+			}
+
+			typ, err := g.tsType(pkg, field.Type, params, indent+"  ")
+			if err != nil {
+				return "", fmt.Errorf("field %s: %w", ident.Name, err)
+			}
+			if hasOption(options, "string") {
+				typ = "string"
+			}
+
+			optional := ""
+			if hasOption(options, "omitempty") || hasOption(options, "omitzero") {
+				optional = "?"
+			} else if _, ok := field.Type.(*ast.StarExpr); ok {
+				typ += " | null"
+			}
+
+			doc := field.Doc
+			if doc == nil {
+				doc = field.Comment
+			}
+			writeDoc(&b, doc, indent+"  ")
+			fmt.Fprintf(&b, "%s  %s%s: %s;\n", indent, propertyName(name), optional, typ)
+		}
+	}
+
+	b.WriteString(indent + "}")
+	return b.String(), nil
+}
+
+// tsType maps a Go type expression to TypeScript, scheduling the named
+// types it refers to
+func (g *generator) tsType(pkg string, expr ast.Expr, params map[string]bool, indent string) (string, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if params[t.Name] {
+			return t.Name, nil
+		}
+		if typ, ok := basicTypes[t.Name]; ok {
+			return typ, nil
+		}
+		for _, numeric := range numericTypes {
+			if t.Name == numeric {
+				return "number", nil
+			}
+		}
+		return g.namedType(pkg, t.Name)
+	case *ast.StarExpr:
+		return g.tsType(pkg, t.X, params, indent)
+	case *ast.ArrayType:
+		if ident, ok := t.Elt.(*ast.Ident); ok && (ident.Name == "byte" || ident.Name == "uint8") {
+			// encoding/json writes []byte as base64
+			return "string", nil
+		}
+		elem, err := g.tsType(pkg, t.Elt, params, indent)
+		if err != nil {
+			return "", err
+		}
+		if strings.Contains(elem, " ") && !strings.HasPrefix(elem, "{") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]", nil
+	case *ast.MapType:
+		value, err := g.tsType(pkg, t.Value, params, indent)
+		if err != nil {
+			return "", err
+		}
+		// Object keys are strings in JSON whatever the Go key type
+		return "Record<string, " + value + ">", nil
+	case *ast.SelectorExpr:
+		qualifier, ok := t.X.(*ast.Ident)
+		if !ok {
+			return "", fmt.Errorf("unsupported type %T", t.X)
+		}
+		if typ, ok := externalTypes[qualifier.Name+"."+t.Sel.Name]; ok {
+			return typ, nil
+		}
+		if _, ok := g.types[qualifier.Name]; ok {
+			return g.namedType(qualifier.Name, t.Sel.Name)
+		}
+		slog.Warn("Unknown type, using unknown", "type", qualifier.Name+"."+t.Sel.Name)
+		return "unknown", nil
+	case *ast.InterfaceType:
+		return "unknown", nil
+	case *ast.StructType:
+		var extends []string
+		body, err := g.structBody(pkg, t, params, indent, &extends)
+		if err != nil {
+			return "", err
+		}
+		for _, embedded := range extends {
+			body = embedded + " & " + body
+		}
+		return body, nil
+	case *ast.IndexExpr:
+		return g.genericType(pkg, t.X, []ast.Expr{t.Index}, params, indent)
+	case *ast.IndexListExpr:
+		return g.genericType(pkg, t.X, t.Indices, params, indent)
+	}
+	return "", fmt.Errorf("unsupported type %T", expr)
+}
+
+func (g *generator) genericType(pkg string, base ast.Expr, args []ast.Expr, params map[string]bool, indent string) (string, error) {
+	name, err := g.tsType(pkg, base, params, indent)
+	if err != nil {
+		return "", err
+	}
+	typeArgs := make([]string, len(args))
+	for i, arg := range args {
+		if typeArgs[i], err = g.tsType(pkg, arg, params, indent); err != nil {
+			return "", err
+		}
+	}
+	return name + "<" + strings.Join(typeArgs, ", ") + ">", nil
+}
+
+func (g *generator) namedType(pkg, name string) (string, error) {
+	decl, ok := g.types[pkg][name]
+	if !ok {
+		return "", fmt.Errorf("unknown type %s.%s", pkg, name)
+	}
+	if err := g.enqueue(decl); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// hasJSONTags reports whether a type declaration is a struct with JSON tags,
+// which marks it as part of the API rather than internal state
+func hasJSONTags(spec *ast.TypeSpec) bool {
+	structType, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		return false
+	}
+	for _, field := range structType.Fields.List {
+		if field.Tag == nil {
+			continue
+		}
+		unquoted, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			continue
+		}
+		if _, ok := reflect.StructTag(unquoted).Lookup("json"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func hasOption(options, option string) bool {
+	for _, candidate := range strings.Split(options, ",") {
+		if candidate == option {
+			return true
+		}
+	}
+	return false
+}
+
+func typeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	}
+	return ""
+}
+
+// propertyName quotes JSON names that are not valid identifiers
+func propertyName(name string) string {
+	for i, r := range name {
+		if !(r == '_' || r == '$' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return strconv.Quote(name)
+		}
+	}
+	return name
+}
+
+// writeDoc turns a Go comment into a JSDoc comment
+func writeDoc(b *strings.Builder, doc *ast.CommentGroup, indent string) {
+	if doc == nil {
+		return
+	}
+	text := strings.TrimSpace(doc.Text())
+	if text == "" {
+		return
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) == 1 {
+		fmt.Fprintf(b, "%s/** %s */\n", indent, strings.ReplaceAll(lines[0], "*/", "*\\/"))
+		return
+	}
+	fmt.Fprintf(b, "%s/**\n", indent)
+	for _, line := range lines {
+		fmt.Fprintf(b, "%s * %s\n", indent, strings.TrimRight(strings.ReplaceAll(line, "*/", "*\\/"), " "))
+	}
+	fmt.Fprintf(b, "%s */\n", indent)
+}
+
+// fatal logs the error and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -1,43 +1,325 @@
 package config
 
 import (
-	"log"
-	"os"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	DatabaseURL   string
-	Port          string
-	OpenAIAPIKey  string
+	DatabaseURL      string
+	Port             string
+	OpenAIAPIKey     string
+	AnthropicAPIKey  string
+	LLMProvider      string
+	LLMModel         string
+	LLMVisionModel   string
+	LLMContextWindow int
+	LLMBaseURL       string
+	SMTPHost         string
+	SMTPPort         string
+	SMTPUsername     string
+	SMTPPassword     string
+	SMTPFrom         string
+	JWTSecret        string
+	TTSAPIKey        string
+	TTSBaseURL       string
+	TTSModel         string
+	TTSVoice         string
+	EmbeddingAPIKey  string
+	EmbeddingBaseURL string
+	EmbeddingModel   string
+	LogFormat        string
+	LogLevel         string
+	OTLPEndpoint     string
+	ServiceName      string
+	AppURL           string
+	RedisURL         string
+	ClientIPHeader   string
+
+	// Base URLs of the LLM providers routing rules may pick instead of
+	// LLMProvider, by provider name
+	LLMProviderBaseURLs map[string]string
+
+	// Browser origins allowed to call the API, such as
+	// https://app.example.com, or "*" for any; the methods and request
+	// headers they may use; and how long browsers cache a preflight answer
+	CORSAllowedOrigins []string
+	CORSAllowedMethods []string
+	CORSAllowedHeaders []string
+	CORSMaxAge         time.Duration
+
+	// Request bodies over MaxRequestBytes are refused; multipart uploads
+	// have their own limits. Strict-Transport-Security is sent when
+	// HSTSMaxAge is set.
+	MaxRequestBytes int
+	HSTSMaxAge      time.Duration
+
+	// Object storage for attachments and deck exports: "local" for files
+	// under ObjectStorageDir, "s3", "gcs", or "" to keep attachments in
+	// Postgres and stream exports. Local URLs point at
+	// ObjectStorageBaseURL. Stored exports are deleted after
+	// ExportRetention.
+	ObjectStorageDriver          string
+	ObjectStorageDir             string
+	ObjectStorageBaseURL         string
+	ObjectStorageBucket          string
+	ObjectStorageRegion          string
+	ObjectStorageEndpoint        string
+	ObjectStorageAccessKeyID     string
+	ObjectStorageSecretAccessKey string
+	ObjectStoragePathStyle       bool
+	ExportRetention              time.Duration
+
+	// Directory of prompt template files overriding the built-in prompts
+	PromptTemplateDir string
+
+	// Where all data is kept: "postgres", or "sqlite" for the file at
+	// SQLitePath, which needs no database server
+	StorageDriver string
+	SQLitePath    string
+
+	// TTS voices for notes in a language, keyed by ISO 639-1 code, used
+	// instead of TTSVoice
+	TTSLanguageVoices map[string]string
+
+	// Speech-to-text for spoken quiz answers; disabled without an API key
+	TranscriptionAPIKey  string
+	TranscriptionBaseURL string
+	TranscriptionModel   string
+
+	// Daily question emails go out on the first check after this hour
+	// (UTC); replies to them go to DailyQuestionReplyTo, whose inbound mail
+	// the provider posts to /daily-questions/replies. Without it the emails
+	// offer only answer links.
+	DailyQuestionHour    int
+	DailyQuestionReplyTo string
+
+	StripeSecretKey       string
+	StripeWebhookSecret   string
+	StripePricePro        string
+	StripePriceEnterprise string
+	BillingSuccessURL     string
+	BillingCancelURL      string
+
+	SpendSpikeMultiplier  int
+	SpendMinTokens        int
+	SpendAlertEmails      []string
+	CuratorEmails         []string
+	SpendCheckInterval    time.Duration
+	SpendThrottleDuration time.Duration
+	// Estimated LLM spend per user per calendar month, in USD, after which
+	// generation is refused. 0 disables the budget.
+	LLMMonthlyBudgetUSD float64
+	// Per-model prices overriding the built-in ones, as "input/output" USD
+	// per million tokens
+	LLMPrices map[string]string
+
+	RateLimitRPS      float64
+	RateLimitBurst    int
+	LLMRateLimitRPS   float64
+	LLMRateLimitBurst int
+	// Consecutive failed LLM calls after which the provider is treated as
+	// down until LLMRetryInterval has passed
+	LLMFailureThreshold int
+
+	// Generated questions at least this similar to a recent one on the
+	// same notes are regenerated, up to QuestionDedupRegenerations times
+	QuestionSimilarityThreshold float64
+	QuestionDedupRegenerations  int
+
+	ProgressReportInterval time.Duration
+	DeckSuggestionInterval time.Duration
+	DigestInterval         time.Duration
+	JWTTTL                 time.Duration
+	RequestTimeout         time.Duration
+	LLMTimeout             time.Duration
+	LLMRetryInterval       time.Duration
+	ShutdownTimeout        time.Duration
+	IdempotencyTTL         time.Duration
+	AnalyticsRetention     time.Duration
+	NoteCacheTTL           time.Duration
+	QuizCacheTTL           time.Duration
+
+	// Settings of the Postgres connection pool every repository shares;
+	// 0 keeps the database/sql default
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBConnMaxIdleTime time.Duration
+
+	// Startup warm-up is cut off after WarmupTimeout; 0 skips it. Due
+	// queues are preloaded for up to WarmupDueQueueUsers recent reviewers.
+	WarmupTimeout       time.Duration
+	WarmupDueQueueUsers int
+
+	// Fault injection for resilience testing, as shares of calls from 0
+	// to 1. All zero, the default, injects nothing.
+	ChaosLatencyRate      float64
+	ChaosLatency          time.Duration
+	ChaosLLMErrorRate     float64
+	ChaosLLMMalformedRate float64
+	ChaosDBErrorRate      float64
 }
 
-func Load() *Config {
+// Load reads the configuration from environment variables, falling back to
+// the YAML file named by CONFIG_FILE, or config.yaml when it exists, and
+// then to defaults. A .env file is loaded into the environment first. Every
+// unparsable or invalid setting is reported in the returned error, one per
+// line.
+func Load() (*Config, error) {
 	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found or error loading .env file")
+		slog.Info("No .env file found or error loading .env file")
+	}
+
+	l, err := newLoader()
+	if err != nil {
+		return nil, err
 	}
 
 	config := &Config{
-		DatabaseURL:  getEnv("DB_URL"),
-		Port:         getEnvWithDefault("PORT", "8080"),
-		OpenAIAPIKey: getEnv("OPENAI_API_KEY"),
+		DatabaseURL:      l.string("DB_URL", ""),
+		Port:             l.string("PORT", "8080"),
+		OpenAIAPIKey:     l.string("OPENAI_API_KEY", ""),
+		AnthropicAPIKey:  l.string("ANTHROPIC_API_KEY", ""),
+		LLMProvider:      l.string("LLM_PROVIDER", "openai"),
+		LLMModel:         l.string("LLM_MODEL", ""),
+		LLMVisionModel:   l.string("LLM_VISION_MODEL", ""),
+		LLMContextWindow: l.int("LLM_CONTEXT_WINDOW", 0),
+		LLMBaseURL:       l.string("LLM_BASE_URL", ""),
+		SMTPHost:         l.string("SMTP_HOST", ""),
+		SMTPPort:         l.string("SMTP_PORT", "587"),
+		SMTPUsername:     l.string("SMTP_USERNAME", ""),
+		SMTPPassword:     l.string("SMTP_PASSWORD", ""),
+		SMTPFrom:         l.string("SMTP_FROM", "no-reply@flashcards.local"),
+		JWTSecret:        l.string("JWT_SECRET", ""),
+		TTSAPIKey:        l.string("TTS_API_KEY", l.lookup("OPENAI_API_KEY")),
+		TTSBaseURL:       l.string("TTS_BASE_URL", "https://api.openai.com/v1"),
+		TTSModel:         l.string("TTS_MODEL", "tts-1"),
+		TTSVoice:         l.string("TTS_VOICE", "alloy"),
+		EmbeddingAPIKey:  l.string("EMBEDDING_API_KEY", l.lookup("OPENAI_API_KEY")),
+		EmbeddingBaseURL: l.string("EMBEDDING_BASE_URL", "https://api.openai.com/v1"),
+		EmbeddingModel:   l.string("EMBEDDING_MODEL", "text-embedding-3-small"),
+		LogFormat:        l.string("LOG_FORMAT", "json"),
+		LogLevel:         l.string("LOG_LEVEL", "info"),
+		OTLPEndpoint:     l.string("OTEL_EXPORTER_OTLP_ENDPOINT", l.lookup("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")),
+		ServiceName:      l.string("OTEL_SERVICE_NAME", "flashcards"),
+		AppURL:           l.string("APP_URL", "http://localhost:3000"),
+		RedisURL:         l.string("REDIS_URL", ""),
+		ClientIPHeader:   l.string("CLIENT_IP_HEADER", ""),
+
+		LLMProviderBaseURLs: l.keyValues("LLM_PROVIDER_BASE_URLS", nil),
+
+		CORSAllowedOrigins: l.list("CORS_ALLOWED_ORIGINS", nil),
+		CORSAllowedMethods: l.list("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+		CORSAllowedHeaders: l.list("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Authorization", "X-Request-ID", "Idempotency-Key", "If-Match", "X-API-Key", "traceparent", "tracestate"}),
+		CORSMaxAge:         l.duration("CORS_MAX_AGE", 10*time.Minute),
+
+		MaxRequestBytes: l.int("MAX_REQUEST_BYTES", 2*1024*1024),
+		HSTSMaxAge:      l.duration("HSTS_MAX_AGE", 0),
+
+		ObjectStorageDriver:          l.string("OBJECT_STORAGE_DRIVER", ""),
+		ObjectStorageDir:             l.string("OBJECT_STORAGE_DIR", "storage"),
+		ObjectStorageBaseURL:         l.string("OBJECT_STORAGE_BASE_URL", l.string("APP_URL", "http://localhost:3000")),
+		ObjectStorageBucket:          l.string("OBJECT_STORAGE_BUCKET", ""),
+		ObjectStorageRegion:          l.string("OBJECT_STORAGE_REGION", ""),
+		ObjectStorageEndpoint:        l.string("OBJECT_STORAGE_ENDPOINT", ""),
+		ObjectStorageAccessKeyID:     l.string("OBJECT_STORAGE_ACCESS_KEY_ID", ""),
+		ObjectStorageSecretAccessKey: l.string("OBJECT_STORAGE_SECRET_ACCESS_KEY", ""),
+		ObjectStoragePathStyle:       l.bool("OBJECT_STORAGE_PATH_STYLE", false),
+		ExportRetention:              l.duration("EXPORT_RETENTION", 24*time.Hour),
+
+		PromptTemplateDir: l.string("PROMPT_TEMPLATE_DIR", ""),
+
+		StorageDriver: l.string("STORAGE_DRIVER", "postgres"),
+		SQLitePath:    l.string("SQLITE_PATH", "flashcards.db"),
+
+		TTSLanguageVoices: l.keyValues("TTS_LANGUAGE_VOICES", nil),
+
+		TranscriptionAPIKey:  l.string("TRANSCRIPTION_API_KEY", l.lookup("OPENAI_API_KEY")),
+		TranscriptionBaseURL: l.string("TRANSCRIPTION_BASE_URL", "https://api.openai.com/v1"),
+		TranscriptionModel:   l.string("TRANSCRIPTION_MODEL", "whisper-1"),
+
+		DailyQuestionHour:    l.int("DAILY_QUESTION_HOUR", 7),
+		DailyQuestionReplyTo: l.string("DAILY_QUESTION_REPLY_TO", ""),
+
+		StripeSecretKey:       l.string("STRIPE_SECRET_KEY", ""),
+		StripeWebhookSecret:   l.string("STRIPE_WEBHOOK_SECRET", ""),
+		StripePricePro:        l.string("STRIPE_PRICE_PRO", ""),
+		StripePriceEnterprise: l.string("STRIPE_PRICE_ENTERPRISE", ""),
+		BillingSuccessURL:     l.string("BILLING_SUCCESS_URL", "http://localhost:3000/billing/success"),
+		BillingCancelURL:      l.string("BILLING_CANCEL_URL", "http://localhost:3000/billing"),
+
+		SpendSpikeMultiplier:  l.int("SPEND_SPIKE_MULTIPLIER", 5),
+		SpendMinTokens:        l.int("SPEND_MIN_TOKENS", 50000),
+		SpendAlertEmails:      l.list("SPEND_ALERT_EMAILS", nil),
+		CuratorEmails:         l.list("CURATOR_EMAILS", nil),
+		SpendCheckInterval:    l.duration("SPEND_CHECK_INTERVAL", 15*time.Minute),
+		SpendThrottleDuration: l.duration("SPEND_THROTTLE_DURATION", 0),
+		LLMMonthlyBudgetUSD:   l.float("LLM_MONTHLY_BUDGET_USD", 0),
+		LLMPrices:             l.keyValues("LLM_PRICES", nil),
+
+		RateLimitRPS:      l.float("RATE_LIMIT_RPS", 10),
+		RateLimitBurst:    l.int("RATE_LIMIT_BURST", 20),
+		LLMRateLimitRPS:   l.float("LLM_RATE_LIMIT_RPS", 0.2),
+		LLMRateLimitBurst: l.int("LLM_RATE_LIMIT_BURST", 5),
+
+		LLMFailureThreshold: l.int("LLM_FAILURE_THRESHOLD", 5),
+
+		QuestionSimilarityThreshold: l.float("QUESTION_SIMILARITY_THRESHOLD", 0.9),
+		QuestionDedupRegenerations:  l.int("QUESTION_DEDUP_REGENERATIONS", 2),
+
+		ProgressReportInterval: l.duration("PROGRESS_REPORT_INTERVAL", 7*24*time.Hour),
+		DeckSuggestionInterval: l.duration("DECK_SUGGESTION_INTERVAL", 7*24*time.Hour),
+		DigestInterval:         l.duration("DIGEST_INTERVAL", 24*time.Hour),
+		JWTTTL:                 l.duration("JWT_TTL", 24*time.Hour),
+		RequestTimeout:         l.duration("REQUEST_TIMEOUT", 2*time.Minute),
+		LLMTimeout:             l.duration("LLM_TIMEOUT", 90*time.Second),
+		LLMRetryInterval:       l.duration("LLM_RETRY_INTERVAL", 30*time.Second),
+		ShutdownTimeout:        l.duration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		IdempotencyTTL:         l.duration("IDEMPOTENCY_TTL", 24*time.Hour),
+		AnalyticsRetention:     l.duration("ANALYTICS_RETENTION", 30*24*time.Hour),
+		NoteCacheTTL:           l.duration("NOTE_CACHE_TTL", 5*time.Minute),
+		QuizCacheTTL:           l.duration("QUIZ_CACHE_TTL", time.Hour),
+
+		DBMaxOpenConns:    l.int("DB_MAX_OPEN_CONNS", 10),
+		DBMaxIdleConns:    l.int("DB_MAX_IDLE_CONNS", 2),
+		DBConnMaxLifetime: l.duration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+		DBConnMaxIdleTime: l.duration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+
+		WarmupTimeout:       l.duration("WARMUP_TIMEOUT", 15*time.Second),
+		WarmupDueQueueUsers: l.int("WARMUP_DUE_QUEUE_USERS", 0),
+
+		ChaosLatencyRate:      l.float("CHAOS_LATENCY_RATE", 0),
+		ChaosLatency:          l.duration("CHAOS_LATENCY", 2*time.Second),
+		ChaosLLMErrorRate:     l.float("CHAOS_LLM_ERROR_RATE", 0),
+		ChaosLLMMalformedRate: l.float("CHAOS_LLM_MALFORMED_RATE", 0),
+		ChaosDBErrorRate:      l.float("CHAOS_DB_ERROR_RATE", 0),
 	}
 
-	return config
+	if err := errors.Join(append(l.errs, config.Validate())...); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return config, nil
 }
 
-func getEnv(key string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		panic("Required environment variable not set: " + key)
-	}
-	return value
+// LLMProviderAPIKeys returns the API key of each LLM provider that has
+// one, for routing rules that pick another provider than LLM_PROVIDER
+func (c *Config) LLMProviderAPIKeys() map[string]string {
+	return map[string]string{"openai": c.OpenAIAPIKey, "anthropic": c.AnthropicAPIKey}
 }
 
-func getEnvWithDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// LLMAPIKey returns the API key for the configured LLM provider
+func (c *Config) LLMAPIKey() string {
+	switch c.LLMProvider {
+	case "anthropic":
+		return c.AnthropicAPIKey
+	case "openai":
+		return c.OpenAIAPIKey
+	default:
+		return ""
 	}
-	return defaultValue
 }
@@ -0,0 +1,210 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config file read when CONFIG_FILE is not set, if it exists
+const DEFAULT_CONFIG_FILE = "config.yaml"
+
+// loader reads settings from the environment and the config file, noting
+// values that fail to parse instead of stopping at the first
+type loader struct {
+	file map[string]string
+	errs []error
+}
+
+// newLoader reads the config file. A missing default file is not an error,
+// but a missing CONFIG_FILE is.
+func newLoader() (*loader, error) {
+	path, explicit := os.Getenv("CONFIG_FILE"), true
+	if path == "" {
+		path, explicit = DEFAULT_CONFIG_FILE, false
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return &loader{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	file, err := parseConfigFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return &loader{file: file}, nil
+}
+
+// parseConfigFile reads a YAML mapping of setting names, as in the
+// environment, to values. Lists are joined with commas and mappings written
+// as key:value pairs, the forms list and keyValues parse.
+func parseConfigFile(data []byte) (map[string]string, error) {
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	file := make(map[string]string, len(raw))
+	for key, value := range raw {
+		text, err := configFileValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		file[strings.ToUpper(key)] = text
+	}
+	return file, nil
+}
+
+func configFileValue(value any) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case []any:
+		items := make([]string, len(value))
+		for i, item := range value {
+			text, err := configFileValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = text
+		}
+		return strings.Join(items, ","), nil
+	case map[string]any:
+		pairs := make([]string, 0, len(value))
+		for key, item := range value {
+			text, err := configFileValue(item)
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, key+":"+text)
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ","), nil
+	case string, bool, int, float64:
+		return fmt.Sprint(value), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", value)
+	}
+}
+
+// lookup returns the setting from the environment, or else the config file
+func (l *loader) lookup(key string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return l.file[key]
+}
+
+func (l *loader) invalid(key, kind, value string) {
+	l.errs = append(l.errs, fmt.Errorf("%s %q is not a valid %s", key, value, kind))
+}
+
+func (l *loader) string(key, defaultValue string) string {
+	if value := l.lookup(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func (l *loader) int(key string, defaultValue int) int {
+	value := l.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		l.invalid(key, "integer", value)
+		return defaultValue
+	}
+	return number
+}
+
+func (l *loader) float(key string, defaultValue float64) float64 {
+	value := l.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		l.invalid(key, "number", value)
+		return defaultValue
+	}
+	return number
+}
+
+func (l *loader) bool(key string, defaultValue bool) bool {
+	value := l.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		l.invalid(key, "boolean", value)
+		return defaultValue
+	}
+	return b
+}
+
+func (l *loader) duration(key string, defaultValue time.Duration) time.Duration {
+	value := l.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		l.invalid(key, "duration such as 30s or 5m", value)
+		return defaultValue
+	}
+	return duration
+}
+
+// list splits a comma-separated value, dropping empty entries
+func (l *loader) list(key string, defaultValue []string) []string {
+	value := l.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// keyValues parses a comma-separated list of key:value pairs, such as
+// "es:nova,fr:shimmer". Keys are lowercased.
+func (l *loader) keyValues(key string, defaultValue map[string]string) map[string]string {
+	items := l.list(key, nil)
+	if items == nil {
+		return defaultValue
+	}
+
+	values := make(map[string]string, len(items))
+	for _, item := range items {
+		name, value, ok := strings.Cut(item, ":")
+		name, value = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			l.invalid(key, "key:value pair", item)
+			continue
+		}
+		values[name] = value
+	}
+	return values
+}
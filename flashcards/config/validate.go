@@ -0,0 +1,168 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validate checks settings the services would otherwise reject late or
+// misread, and returns every problem found, one per line
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s "+format, append([]any{key}, args...)...))
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		if !slices.Contains(allowed, value) {
+			fail(key, "%q must be one of %v", value, allowed)
+		}
+	}
+
+	if c.DatabaseURL == "" && c.StorageDriver != "sqlite" {
+		fail("DB_URL", "is required unless STORAGE_DRIVER is sqlite")
+	}
+	if c.JWTSecret == "" {
+		fail("JWT_SECRET", "is required")
+	}
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		fail("PORT", "%q is not a port number", c.Port)
+	}
+	if c.SMTPHost != "" {
+		if port, err := strconv.Atoi(c.SMTPPort); err != nil || port < 1 || port > 65535 {
+			fail("SMTP_PORT", "%q is not a port number", c.SMTPPort)
+		}
+	}
+
+	oneOf("LLM_PROVIDER", c.LLMProvider, "openai", "anthropic", "ollama")
+	switch {
+	case c.LLMProvider == "openai" && c.OpenAIAPIKey == "":
+		fail("OPENAI_API_KEY", "is required when LLM_PROVIDER is openai")
+	case c.LLMProvider == "anthropic" && c.AnthropicAPIKey == "":
+		fail("ANTHROPIC_API_KEY", "is required when LLM_PROVIDER is anthropic")
+	}
+	for provider, baseURL := range c.LLMProviderBaseURLs {
+		oneOf("LLM_PROVIDER_BASE_URLS", provider, "openai", "anthropic", "ollama")
+		if u, err := url.Parse(baseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("LLM_PROVIDER_BASE_URLS", "%q is not an http or https URL", baseURL)
+		}
+	}
+	if c.LLMContextWindow < 0 {
+		fail("LLM_CONTEXT_WINDOW", "cannot be negative")
+	}
+	if c.LLMFailureThreshold < 1 {
+		fail("LLM_FAILURE_THRESHOLD", "must be at least 1")
+	}
+
+	oneOf("STORAGE_DRIVER", c.StorageDriver, "postgres", "sqlite")
+	oneOf("OBJECT_STORAGE_DRIVER", c.ObjectStorageDriver, "", "local", "s3", "gcs")
+	if c.ObjectStorageDriver == "s3" || c.ObjectStorageDriver == "gcs" {
+		for key, value := range map[string]string{
+			"OBJECT_STORAGE_BUCKET":            c.ObjectStorageBucket,
+			"OBJECT_STORAGE_ACCESS_KEY_ID":     c.ObjectStorageAccessKeyID,
+			"OBJECT_STORAGE_SECRET_ACCESS_KEY": c.ObjectStorageSecretAccessKey,
+		} {
+			if value == "" {
+				fail(key, "is required when OBJECT_STORAGE_DRIVER is %s", c.ObjectStorageDriver)
+			}
+		}
+	}
+	oneOf("LOG_FORMAT", c.LogFormat, "json", "text")
+	oneOf("LOG_LEVEL", c.LogLevel, "debug", "info", "warn", "error")
+
+	for _, origin := range c.CORSAllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.TrimSuffix(u.Path, "/") != "" {
+			fail("CORS_ALLOWED_ORIGINS", "%q is not an origin such as https://app.example.com", origin)
+		}
+	}
+	if len(c.CORSAllowedMethods) == 0 {
+		fail("CORS_ALLOWED_METHODS", "cannot be empty")
+	}
+	if c.MaxRequestBytes < 1 {
+		fail("MAX_REQUEST_BYTES", "must be at least 1")
+	}
+
+	if c.StripeSecretKey != "" && c.StripeWebhookSecret == "" {
+		fail("STRIPE_WEBHOOK_SECRET", "is required with STRIPE_SECRET_KEY")
+	}
+
+	for key, rate := range map[string]float64{
+		"RATE_LIMIT_RPS":         c.RateLimitRPS,
+		"LLM_RATE_LIMIT_RPS":     c.LLMRateLimitRPS,
+		"LLM_MONTHLY_BUDGET_USD": c.LLMMonthlyBudgetUSD,
+	} {
+		if rate < 0 {
+			fail(key, "cannot be negative")
+		}
+	}
+	for key, count := range map[string]int{
+		"RATE_LIMIT_BURST":             c.RateLimitBurst,
+		"LLM_RATE_LIMIT_BURST":         c.LLMRateLimitBurst,
+		"SPEND_SPIKE_MULTIPLIER":       c.SpendSpikeMultiplier,
+		"SPEND_MIN_TOKENS":             c.SpendMinTokens,
+		"DB_MAX_OPEN_CONNS":            c.DBMaxOpenConns,
+		"DB_MAX_IDLE_CONNS":            c.DBMaxIdleConns,
+		"WARMUP_DUE_QUEUE_USERS":       c.WarmupDueQueueUsers,
+		"QUESTION_DEDUP_REGENERATIONS": c.QuestionDedupRegenerations,
+	} {
+		if count < 0 {
+			fail(key, "cannot be negative")
+		}
+	}
+	for key, share := range map[string]float64{
+		"QUESTION_SIMILARITY_THRESHOLD": c.QuestionSimilarityThreshold,
+		"CHAOS_LATENCY_RATE":            c.ChaosLatencyRate,
+		"CHAOS_LLM_ERROR_RATE":          c.ChaosLLMErrorRate,
+		"CHAOS_LLM_MALFORMED_RATE":      c.ChaosLLMMalformedRate,
+		"CHAOS_DB_ERROR_RATE":           c.ChaosDBErrorRate,
+	} {
+		if share < 0 || share > 1 {
+			fail(key, "%v must be between 0 and 1", share)
+		}
+	}
+	for key, duration := range map[string]time.Duration{
+		"SPEND_CHECK_INTERVAL":     c.SpendCheckInterval,
+		"SPEND_THROTTLE_DURATION":  c.SpendThrottleDuration,
+		"PROGRESS_REPORT_INTERVAL": c.ProgressReportInterval,
+		"DECK_SUGGESTION_INTERVAL": c.DeckSuggestionInterval,
+		"DIGEST_INTERVAL":          c.DigestInterval,
+		"REQUEST_TIMEOUT":          c.RequestTimeout,
+		"LLM_TIMEOUT":              c.LLMTimeout,
+		"LLM_RETRY_INTERVAL":       c.LLMRetryInterval,
+		"SHUTDOWN_TIMEOUT":         c.ShutdownTimeout,
+		"IDEMPOTENCY_TTL":          c.IdempotencyTTL,
+		"ANALYTICS_RETENTION":      c.AnalyticsRetention,
+		"NOTE_CACHE_TTL":           c.NoteCacheTTL,
+		"QUIZ_CACHE_TTL":           c.QuizCacheTTL,
+		"DB_CONN_MAX_LIFETIME":     c.DBConnMaxLifetime,
+		"DB_CONN_MAX_IDLE_TIME":    c.DBConnMaxIdleTime,
+		"WARMUP_TIMEOUT":           c.WarmupTimeout,
+		"CHAOS_LATENCY":            c.ChaosLatency,
+		"CORS_MAX_AGE":             c.CORSMaxAge,
+		"HSTS_MAX_AGE":             c.HSTSMaxAge,
+	} {
+		if duration < 0 {
+			fail(key, "cannot be negative")
+		}
+	}
+	if c.DailyQuestionHour < 0 || c.DailyQuestionHour > 23 {
+		fail("DAILY_QUESTION_HOUR", "%d must be between 0 and 23", c.DailyQuestionHour)
+	}
+	if c.JWTTTL <= 0 {
+		fail("JWT_TTL", "must be positive")
+	}
+	if c.ExportRetention <= 0 {
+		fail("EXPORT_RETENTION", "must be positive")
+	}
+
+	// Map iteration order varies; report problems in a stable order
+	slices.SortFunc(errs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+	return errors.Join(errs...)
+}
@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+// Orders accepted by analytics reports, mapped to their expressions. Only
+// these are ever interpolated into SQL.
+var analyticsSortExpressions = map[string]string{
+	"requests":   "requests",
+	"errors":     "clientErrors + serverErrors",
+	"errorRate":  "(clientErrors + serverErrors)::float / GREATEST(requests, 1)",
+	"avgLatency": "totalLatencyMs::float / GREATEST(requests, 1)",
+	"maxLatency": "maxLatencyMs",
+}
+
+type AnalyticsRepository interface {
+	SaveRequestStats(ctx context.Context, stats []*models.RequestStats) error
+	GetRouteAnalytics(ctx context.Context, query models.AnalyticsQuery) ([]*models.RouteAnalytics, error)
+	GetUserAnalytics(ctx context.Context, query models.AnalyticsQuery) ([]*models.UserAnalytics, error)
+	DeleteRequestStatsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type PostgresAnalyticsRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresAnalyticsRepository(db *sql.DB) *PostgresAnalyticsRepository {
+	return &PostgresAnalyticsRepository{db: db}
+}
+
+// SaveRequestStats adds the counts to the stored rows for each bucket,
+// route and user in a single transaction
+func (r *PostgresAnalyticsRepository) SaveRequestStats(ctx context.Context, stats []*models.RequestStats) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO gocourse.request_stats 
+			(bucket, route, method, user_id, requests, clientErrors, serverErrors, totalLatencyMs, maxLatencyMs) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) 
+		ON CONFLICT (bucket, route, method, user_id) DO UPDATE 
+		SET requests = gocourse.request_stats.requests + EXCLUDED.requests, 
+			clientErrors = gocourse.request_stats.clientErrors + EXCLUDED.clientErrors, 
+			serverErrors = gocourse.request_stats.serverErrors + EXCLUDED.serverErrors, 
+			totalLatencyMs = gocourse.request_stats.totalLatencyMs + EXCLUDED.totalLatencyMs, 
+			maxLatencyMs = GREATEST(gocourse.request_stats.maxLatencyMs, EXCLUDED.maxLatencyMs)`
+
+	for _, stat := range stats {
+		_, err := tx.ExecContext(ctx, query, stat.Bucket, stat.Route, stat.Method, stat.UserID, stat.Requests,
+			stat.ClientErrors, stat.ServerErrors, stat.TotalLatencyMs, stat.MaxLatencyMs)
+		if err != nil {
+			return models.Internal("failed to save request stats: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit request stats: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresAnalyticsRepository) GetRouteAnalytics(ctx context.Context, query models.AnalyticsQuery) ([]*models.RouteAnalytics, error) {
+	order, ok := analyticsSortExpressions[query.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sort field %q", query.Sort)
+	}
+
+	sqlQuery := `
+		SELECT route, method, requests, clientErrors, serverErrors, totalLatencyMs, maxLatencyMs, users 
+		FROM ( 
+			SELECT route, method, SUM(requests) AS requests, SUM(clientErrors) AS clientErrors, 
+				SUM(serverErrors) AS serverErrors, SUM(totalLatencyMs) AS totalLatencyMs, 
+				MAX(maxLatencyMs) AS maxLatencyMs, COUNT(DISTINCT NULLIF(user_id, 0)) AS users 
+			FROM gocourse.request_stats 
+			WHERE bucket >= $1 
+			GROUP BY route, method 
+		) totals 
+		ORDER BY ` + order + ` DESC, route 
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, query.Since, query.Limit)
+	if err != nil {
+		return nil, models.Internal("failed to get route analytics: %w", err)
+	}
+	defer rows.Close()
+
+	routes := []*models.RouteAnalytics{}
+	for rows.Next() {
+		route := &models.RouteAnalytics{}
+		var totalLatencyMs int64
+		if err := rows.Scan(&route.Route, &route.Method, &route.Requests, &route.ClientErrors, &route.ServerErrors,
+			&totalLatencyMs, &route.MaxLatencyMs, &route.Users); err != nil {
+			return nil, models.Internal("failed to scan route analytics: %w", err)
+		}
+		route.ErrorRate, route.AvgLatencyMs = rates(route.Requests, route.ClientErrors+route.ServerErrors, totalLatencyMs)
+		routes = append(routes, route)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, models.Internal("failed to iterate route analytics: %w", err)
+	}
+
+	return routes, nil
+}
+
+// GetUserAnalytics reports authenticated users only
+func (r *PostgresAnalyticsRepository) GetUserAnalytics(ctx context.Context, query models.AnalyticsQuery) ([]*models.UserAnalytics, error) {
+	order, ok := analyticsSortExpressions[query.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sort field %q", query.Sort)
+	}
+
+	sqlQuery := `
+		SELECT user_id, requests, clientErrors, serverErrors, totalLatencyMs, maxLatencyMs, routes 
+		FROM ( 
+			SELECT user_id, SUM(requests) AS requests, SUM(clientErrors) AS clientErrors, 
+				SUM(serverErrors) AS serverErrors, SUM(totalLatencyMs) AS totalLatencyMs, 
+				MAX(maxLatencyMs) AS maxLatencyMs, COUNT(DISTINCT (route, method)) AS routes 
+			FROM gocourse.request_stats 
+			WHERE bucket >= $1 AND user_id <> 0 
+			GROUP BY user_id 
+		) totals 
+		ORDER BY ` + order + ` DESC, user_id 
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, query.Since, query.Limit)
+	if err != nil {
+		return nil, models.Internal("failed to get user analytics: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*models.UserAnalytics{}
+	for rows.Next() {
+		user := &models.UserAnalytics{}
+		var totalLatencyMs int64
+		if err := rows.Scan(&user.UserID, &user.Requests, &user.ClientErrors, &user.ServerErrors,
+			&totalLatencyMs, &user.MaxLatencyMs, &user.Routes); err != nil {
+			return nil, models.Internal("failed to scan user analytics: %w", err)
+		}
+		user.ErrorRate, user.AvgLatencyMs = rates(user.Requests, user.ClientErrors+user.ServerErrors, totalLatencyMs)
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, models.Internal("failed to iterate user analytics: %w", err)
+	}
+
+	return users, nil
+}
+
+func (r *PostgresAnalyticsRepository) DeleteRequestStatsBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := "DELETE FROM gocourse.request_stats WHERE bucket < $1"
+
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, models.Internal("failed to delete request stats: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, models.Internal("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
+
+func rates(requests, errors int, totalLatencyMs int64) (float64, float64) {
+	if requests == 0 {
+		return 0, 0
+	}
+	return float64(errors) / float64(requests), float64(totalLatencyMs) / float64(requests)
+}
@@ -0,0 +1,202 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+type APIKeyRepository interface {
+	CountAPIKeys(ctx context.Context, userID int) (int, error)
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	GetAPIKeys(ctx context.Context, userID int) ([]*models.APIKey, error)
+	GetAPIKey(ctx context.Context, userID, id int) (*models.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id int) error
+	SaveAPIKeyUsage(ctx context.Context, usage []*models.APIKeyUsage, lastUsedAt map[int]time.Time) error
+	GetAPIKeyUsage(ctx context.Context, id int, since time.Time) ([]*models.APIKeyUsage, error)
+}
+
+type PostgresAPIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresAPIKeyRepository(db *sql.DB) *PostgresAPIKeyRepository {
+	return &PostgresAPIKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, user_id, name, prefix, keyHash, scopes, requests, lastUsedAt, createdAt, revokedAt`
+
+func scanAPIKey(row interface{ Scan(...any) error }) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, pq.Array(&key.Scopes), &key.Requests,
+		&key.LastUsedAt, &key.CreatedAt, &key.RevokedAt)
+	return key, err
+}
+
+// CountAPIKeys counts the user's keys that have not been revoked
+func (r *PostgresAPIKeyRepository) CountAPIKeys(ctx context.Context, userID int) (int, error) {
+	query := `SELECT COUNT(*) FROM gocourse.api_keys WHERE user_id = $1 AND revokedAt IS NULL`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, models.Internal("failed to count api keys: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *PostgresAPIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	query := `
+		INSERT INTO gocourse.api_keys (user_id, name, prefix, keyHash, scopes) 
+		VALUES ($1, $2, $3, $4, $5) 
+		RETURNING id, createdAt`
+
+	row := r.db.QueryRowContext(ctx, query, key.UserID, key.Name, key.Prefix, key.KeyHash, pq.Array(key.Scopes))
+	if err := row.Scan(&key.ID, &key.CreatedAt); err != nil {
+		return models.Internal("failed to create api key: %w", err)
+	}
+
+	return nil
+}
+
+// GetAPIKeys returns the user's keys, revoked ones included, newest first
+func (r *PostgresAPIKeyRepository) GetAPIKeys(ctx context.Context, userID int) ([]*models.APIKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + ` 
+		FROM gocourse.api_keys 
+		WHERE user_id = $1 
+		ORDER BY createdAt DESC, id DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, models.Internal("failed to get api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (r *PostgresAPIKeyRepository) GetAPIKey(ctx context.Context, userID, id int) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM gocourse.api_keys WHERE id = $1 AND user_id = $2`
+
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("api key with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// GetAPIKeyByHash returns the key with the hash, if it has not been
+// revoked
+func (r *PostgresAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM gocourse.api_keys WHERE keyHash = $1 AND revokedAt IS NULL`
+
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, query, keyHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("api key not found")
+		}
+		return nil, models.Internal("failed to get api key: %w", err)
+	}
+
+	return key, nil
+}
+
+func (r *PostgresAPIKeyRepository) RevokeAPIKey(ctx context.Context, userID, id int) error {
+	query := `UPDATE gocourse.api_keys SET revokedAt = NOW() WHERE id = $1 AND user_id = $2 AND revokedAt IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return models.Internal("failed to revoke api key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return models.NotFound("api key with id %d not found", id)
+	}
+
+	return nil
+}
+
+// SaveAPIKeyUsage adds the request counts to each key's day and total, and
+// moves its lastUsedAt forward, in a single transaction
+func (r *PostgresAPIKeyRepository) SaveAPIKeyUsage(ctx context.Context, usage []*models.APIKeyUsage, lastUsedAt map[int]time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	dayQuery := `
+		INSERT INTO gocourse.api_key_usage (api_key_id, day, requests) 
+		VALUES ($1, $2, $3) 
+		ON CONFLICT (api_key_id, day) DO UPDATE 
+		SET requests = gocourse.api_key_usage.requests + EXCLUDED.requests`
+	keyQuery := `
+		UPDATE gocourse.api_keys 
+		SET requests = requests + $2, lastUsedAt = GREATEST(lastUsedAt, $3) 
+		WHERE id = $1`
+
+	for _, day := range usage {
+		if _, err := tx.ExecContext(ctx, dayQuery, day.APIKeyID, day.Day, day.Requests); err != nil {
+			return models.Internal("failed to save api key usage: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, keyQuery, day.APIKeyID, day.Requests, lastUsedAt[day.APIKeyID]); err != nil {
+			return models.Internal("failed to save api key usage: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit api key usage: %w", err)
+	}
+
+	return nil
+}
+
+// GetAPIKeyUsage returns the key's requests per day since the given day,
+// oldest first. Days without requests are left out.
+func (r *PostgresAPIKeyRepository) GetAPIKeyUsage(ctx context.Context, id int, since time.Time) ([]*models.APIKeyUsage, error) {
+	query := `
+		SELECT day, requests 
+		FROM gocourse.api_key_usage 
+		WHERE api_key_id = $1 AND day >= $2 
+		ORDER BY day`
+
+	rows, err := r.db.QueryContext(ctx, query, id, since)
+	if err != nil {
+		return nil, models.Internal("failed to get api key usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []*models.APIKeyUsage{}
+	for rows.Next() {
+		day := &models.APIKeyUsage{APIKeyID: id}
+		if err := rows.Scan(&day.Day, &day.Requests); err != nil {
+			return nil, models.Internal("failed to scan api key usage: %w", err)
+		}
+		usage = append(usage, day)
+	}
+
+	return usage, rows.Err()
+}
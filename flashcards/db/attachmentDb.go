@@ -0,0 +1,300 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+
+	_ "github.com/lib/pq"
+)
+
+type AttachmentRepository interface {
+	CreateAttachment(ctx context.Context, attachment *models.Attachment) error
+	GetAttachment(ctx context.Context, userID, id int) (*models.Attachment, error)
+	CompleteAttachment(ctx context.Context, attachment *models.Attachment) error
+	DeleteAttachment(ctx context.Context, userID, id int) error
+	GetPendingAttachments(ctx context.Context, before time.Time, limit int) ([]*models.Attachment, error)
+	GetBlob(ctx context.Context, hash string) (*models.AttachmentBlob, error)
+	DeleteReleasedBlobs(ctx context.Context, before time.Time, limit int) ([]*models.AttachmentBlob, error)
+}
+
+type PostgresAttachmentRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresAttachmentRepository(db *sql.DB) *PostgresAttachmentRepository {
+	return &PostgresAttachmentRepository{db: db}
+}
+
+// CreateAttachment stores an attachment. One with a ContentHash references
+// the blob with that hash, which is created from its data or StorageKey if
+// there is none yet; StorageKey is then set to the blob's.
+func (r *PostgresAttachmentRepository) CreateAttachment(ctx context.Context, attachment *models.Attachment) error {
+	if attachment.ContentHash == "" {
+		return r.insertAttachment(ctx, r.db, attachment, attachment.Data, attachment.StorageKey)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	storageKey, err := referenceBlob(ctx, tx, attachment)
+	if err != nil {
+		return err
+	}
+	if err := r.insertAttachment(ctx, tx, attachment, nil, ""); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit attachment: %w", err)
+	}
+
+	attachment.StorageKey = storageKey
+	return nil
+}
+
+func (r *PostgresAttachmentRepository) insertAttachment(ctx context.Context, q queryer, attachment *models.Attachment, data []byte, storageKey string) error {
+	query := `
+		INSERT INTO gocourse.attachments (user_id, filename, contentType, size, data, storageKey, status, contentHash) 
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, NULLIF($8, '')) 
+		RETURNING id, createdAt`
+
+	// Data held in object storage is not stored here as well
+	if storageKey != "" {
+		data = nil
+	}
+
+	row := q.QueryRowContext(ctx, query, attachment.UserID, attachment.Filename, attachment.ContentType, attachment.Size,
+		data, storageKey, attachment.Status, attachment.ContentHash)
+	if err := row.Scan(&attachment.ID, &attachment.CreatedAt); err != nil {
+		return models.Internal("failed to create attachment: %w", err)
+	}
+
+	return nil
+}
+
+// referenceBlob adds a reference to the blob with the attachment's
+// ContentHash, creating it from the attachment's StorageKey, or its data
+// when that is empty, if there is none. It returns the blob's storage key.
+func referenceBlob(ctx context.Context, q queryer, attachment *models.Attachment) (string, error) {
+	query := `
+		INSERT INTO gocourse.attachment_blobs (hash, contentType, size, data, storageKey) 
+		VALUES ($1, $2, $3, $4, NULLIF($5, '')) 
+		ON CONFLICT (hash) DO UPDATE 
+		SET refCount = gocourse.attachment_blobs.refCount + 1, releasedAt = NULL 
+		RETURNING COALESCE(storageKey, '')`
+
+	var data []byte
+	if attachment.StorageKey == "" {
+		data = attachment.Data
+	}
+
+	var storageKey string
+	row := q.QueryRowContext(ctx, query, attachment.ContentHash, attachment.ContentType, attachment.Size, data, attachment.StorageKey)
+	if err := row.Scan(&storageKey); err != nil {
+		return "", models.Internal("failed to reference attachment blob: %w", err)
+	}
+
+	return storageKey, nil
+}
+
+// releaseBlob removes a reference to the blob, stamping when it was left
+// unreferenced
+func releaseBlob(ctx context.Context, q queryer, hash string) error {
+	query := `
+		UPDATE gocourse.attachment_blobs 
+		SET refCount = refCount - 1, releasedAt = CASE WHEN refCount = 1 THEN NOW() END 
+		WHERE hash = $1`
+
+	if _, err := q.ExecContext(ctx, query, hash); err != nil {
+		return models.Internal("failed to release attachment blob: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresAttachmentRepository) GetAttachment(ctx context.Context, userID, id int) (*models.Attachment, error) {
+	query := `
+		SELECT a.id, a.user_id, a.filename, a.contentType, a.size, a.status, COALESCE(b.data, a.data), 
+			COALESCE(b.storageKey, a.storageKey, ''), COALESCE(a.contentHash, ''), a.createdAt 
+		FROM gocourse.attachments a 
+		LEFT JOIN gocourse.attachment_blobs b ON b.hash = a.contentHash 
+		WHERE a.id = $1 AND a.user_id = $2`
+
+	attachment := &models.Attachment{}
+	row := r.db.QueryRowContext(ctx, query, id, userID)
+
+	err := row.Scan(&attachment.ID, &attachment.UserID, &attachment.Filename, &attachment.ContentType,
+		&attachment.Size, &attachment.Status, &attachment.Data, &attachment.StorageKey, &attachment.ContentHash, &attachment.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("attachment with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get attachment: %w", err)
+	}
+
+	return attachment, nil
+}
+
+// CompleteAttachment marks a pending attachment ready, with the content
+// type, size and ContentHash of its upload. The upload becomes the blob for
+// the hash unless there already is one; StorageKey is set to the blob's.
+func (r *PostgresAttachmentRepository) CompleteAttachment(ctx context.Context, attachment *models.Attachment) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	storageKey, err := referenceBlob(ctx, tx, attachment)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE gocourse.attachments 
+		SET status = $1, contentType = $2, size = $3, contentHash = $4, storageKey = NULL 
+		WHERE id = $5 AND user_id = $6 AND status = $7`
+
+	result, err := tx.ExecContext(ctx, query, models.AttachmentStatusReady, attachment.ContentType, attachment.Size,
+		attachment.ContentHash, attachment.ID, attachment.UserID, models.AttachmentStatusPending)
+	if err != nil {
+		return models.Internal("failed to complete attachment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return models.Conflict("attachment with id %d is not pending", attachment.ID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit attachment: %w", err)
+	}
+
+	attachment.Status = models.AttachmentStatusReady
+	attachment.StorageKey = storageKey
+	return nil
+}
+
+// DeleteAttachment deletes an attachment and releases its blob. The blob
+// is only deleted later, by DeleteReleasedBlobs.
+func (r *PostgresAttachmentRepository) DeleteAttachment(ctx context.Context, userID, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM gocourse.attachments WHERE id = $1 AND user_id = $2 RETURNING COALESCE(contentHash, '')`
+
+	var hash string
+	if err := tx.QueryRowContext(ctx, query, id, userID).Scan(&hash); err != nil {
+		if err == sql.ErrNoRows {
+			return models.NotFound("attachment with id %d not found", id)
+		}
+		return models.Internal("failed to delete attachment: %w", err)
+	}
+
+	if hash != "" {
+		if err := releaseBlob(ctx, tx, hash); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit attachment deletion: %w", err)
+	}
+
+	return nil
+}
+
+// GetPendingAttachments returns up to limit attachments still pending
+// since before, oldest first
+func (r *PostgresAttachmentRepository) GetPendingAttachments(ctx context.Context, before time.Time, limit int) ([]*models.Attachment, error) {
+	query := `
+		SELECT id, user_id, filename, contentType, size, status, COALESCE(storageKey, ''), createdAt 
+		FROM gocourse.attachments 
+		WHERE status = $1 AND createdAt < $2 
+		ORDER BY createdAt 
+		LIMIT $3`
+
+	rows, err := r.db.QueryContext(ctx, query, models.AttachmentStatusPending, before, limit)
+	if err != nil {
+		return nil, models.Internal("failed to get pending attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []*models.Attachment
+	for rows.Next() {
+		attachment := &models.Attachment{}
+		if err := rows.Scan(&attachment.ID, &attachment.UserID, &attachment.Filename, &attachment.ContentType,
+			&attachment.Size, &attachment.Status, &attachment.StorageKey, &attachment.CreatedAt); err != nil {
+			return nil, models.Internal("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, rows.Err()
+}
+
+func (r *PostgresAttachmentRepository) GetBlob(ctx context.Context, hash string) (*models.AttachmentBlob, error) {
+	query := `
+		SELECT hash, contentType, size, COALESCE(storageKey, ''), refCount, releasedAt, createdAt 
+		FROM gocourse.attachment_blobs 
+		WHERE hash = $1`
+
+	blob := &models.AttachmentBlob{}
+	err := r.db.QueryRowContext(ctx, query, hash).Scan(&blob.Hash, &blob.ContentType, &blob.Size, &blob.StorageKey,
+		&blob.RefCount, &blob.ReleasedAt, &blob.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("attachment blob %s not found", hash)
+		}
+		return nil, models.Internal("failed to get attachment blob: %w", err)
+	}
+
+	return blob, nil
+}
+
+// DeleteReleasedBlobs deletes up to limit blobs that have been
+// unreferenced since before and returns them, so their objects can be
+// deleted. A blob referenced again meanwhile is kept.
+func (r *PostgresAttachmentRepository) DeleteReleasedBlobs(ctx context.Context, before time.Time, limit int) ([]*models.AttachmentBlob, error) {
+	query := `
+		DELETE FROM gocourse.attachment_blobs 
+		WHERE hash IN ( 
+			SELECT hash FROM gocourse.attachment_blobs b 
+			WHERE refCount = 0 AND releasedAt < $1 
+				AND NOT EXISTS (SELECT 1 FROM gocourse.attachments a WHERE a.contentHash = b.hash) 
+			ORDER BY releasedAt 
+			LIMIT $2 
+			FOR UPDATE SKIP LOCKED 
+		) AND refCount = 0 
+		RETURNING hash, contentType, size, COALESCE(storageKey, ''), refCount, releasedAt, createdAt`
+
+	rows, err := r.db.QueryContext(ctx, query, before, limit)
+	if err != nil {
+		return nil, models.Internal("failed to delete released attachment blobs: %w", err)
+	}
+	defer rows.Close()
+
+	var blobs []*models.AttachmentBlob
+	for rows.Next() {
+		blob := &models.AttachmentBlob{}
+		if err := rows.Scan(&blob.Hash, &blob.ContentType, &blob.Size, &blob.StorageKey, &blob.RefCount,
+			&blob.ReleasedAt, &blob.CreatedAt); err != nil {
+			return nil, models.Internal("failed to scan attachment blob: %w", err)
+		}
+		blobs = append(blobs, blob)
+	}
+
+	return blobs, rows.Err()
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"flashcards/models"
+
+	_ "github.com/lib/pq"
+)
+
+type BrandingRepository interface {
+	GetBranding(ctx context.Context) (*models.Branding, error)
+	SaveBranding(ctx context.Context, branding *models.Branding) error
+}
+
+type PostgresBrandingRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresBrandingRepository(db *sql.DB) *PostgresBrandingRepository {
+	return &PostgresBrandingRepository{db: db}
+}
+
+func (r *PostgresBrandingRepository) GetBranding(ctx context.Context) (*models.Branding, error) {
+	query := `
+		SELECT name, logoUrl, primaryColor, accentColor, supportEmail, supportUrl, updatedAt 
+		FROM gocourse.branding 
+		WHERE id = 1`
+
+	branding := &models.Branding{}
+	row := r.db.QueryRowContext(ctx, query)
+
+	err := row.Scan(&branding.Name, &branding.LogoURL, &branding.PrimaryColor, &branding.AccentColor,
+		&branding.SupportEmail, &branding.SupportURL, &branding.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &models.Branding{
+				Name:         models.DefaultBrandingName,
+				PrimaryColor: models.DefaultBrandingPrimaryColor,
+				AccentColor:  models.DefaultBrandingAccentColor,
+			}, nil
+		}
+		return nil, models.Internal("failed to get branding: %w", err)
+	}
+
+	return branding, nil
+}
+
+func (r *PostgresBrandingRepository) SaveBranding(ctx context.Context, branding *models.Branding) error {
+	query := `
+		INSERT INTO gocourse.branding (id, name, logoUrl, primaryColor, accentColor, supportEmail, supportUrl, updatedAt) 
+		VALUES (1, $1, $2, $3, $4, $5, $6, NOW()) 
+		ON CONFLICT (id) DO UPDATE 
+		SET name = EXCLUDED.name, logoUrl = EXCLUDED.logoUrl, primaryColor = EXCLUDED.primaryColor, 
+			accentColor = EXCLUDED.accentColor, supportEmail = EXCLUDED.supportEmail, supportUrl = EXCLUDED.supportUrl, 
+			updatedAt = NOW() 
+		RETURNING updatedAt`
+
+	row := r.db.QueryRowContext(ctx, query, branding.Name, branding.LogoURL, branding.PrimaryColor, branding.AccentColor,
+		branding.SupportEmail, branding.SupportURL)
+	if err := row.Scan(&branding.UpdatedAt); err != nil {
+		return models.Internal("failed to save branding: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+
+	"flashcards/chaos"
+
+	"github.com/lib/pq"
+)
+
+const CHAOS_DRIVER_NAME = "postgres-chaos"
+
+// driverName is the database/sql driver pools are opened with
+var driverName = "postgres"
+
+// EnableChaos makes pools opened from now on fail queries, statements and
+// transactions at the injector's DBErrorRate. Call it once at startup,
+// before creating repositories.
+func EnableChaos(injector *chaos.Injector) {
+	if injector == nil || injector.Config().DBErrorRate <= 0 {
+		return
+	}
+	sql.Register(CHAOS_DRIVER_NAME, &chaosDriver{Driver: pq.Driver{}, injector: injector})
+	driverName = CHAOS_DRIVER_NAME
+}
+
+func injectedDBError() error {
+	return fmt.Errorf("database unavailable: %w", chaos.ErrInjected)
+}
+
+type chaosDriver struct {
+	driver.Driver
+	injector *chaos.Injector
+}
+
+func (d *chaosDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &chaosConn{Conn: conn, injector: d.injector}, nil
+}
+
+// chaosConn fails calls before passing them to the connection. Optional
+// interfaces the connection lacks answer driver.ErrSkip, so database/sql
+// falls back as it would without the wrapper.
+type chaosConn struct {
+	driver.Conn
+	injector *chaos.Injector
+}
+
+func (c *chaosConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if c.injector.DBError() {
+		return nil, injectedDBError()
+	}
+	return queryer.QueryContext(ctx, query, args)
+}
+
+func (c *chaosConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if c.injector.DBError() {
+		return nil, injectedDBError()
+	}
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (c *chaosConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if c.injector.DBError() {
+		return nil, injectedDBError()
+	}
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *chaosConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.injector.DBError() {
+		return nil, injectedDBError()
+	}
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+// Ping is never failed, so the pool still opens and readiness reflects the
+// real database
+func (c *chaosConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *chaosConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *chaosConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
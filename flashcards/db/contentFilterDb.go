@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+type ContentFilterRepository interface {
+	GetSettings(ctx context.Context) (*models.ContentFilterSettings, error)
+	SaveSettings(ctx context.Context, settings *models.ContentFilterSettings) error
+}
+
+type PostgresContentFilterRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresContentFilterRepository(db *sql.DB) *PostgresContentFilterRepository {
+	return &PostgresContentFilterRepository{db: db}
+}
+
+func (r *PostgresContentFilterRepository) GetSettings(ctx context.Context) (*models.ContentFilterSettings, error) {
+	query := `
+		SELECT enabled, ageLevel, bannedTopics, updatedAt 
+		FROM gocourse.content_filter_settings 
+		WHERE id = 1`
+
+	settings := &models.ContentFilterSettings{}
+	row := r.db.QueryRowContext(ctx, query)
+
+	err := row.Scan(&settings.Enabled, &settings.AgeLevel, pq.Array(&settings.BannedTopics), &settings.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &models.ContentFilterSettings{AgeLevel: models.AgeLevelAdult, BannedTopics: []string{}}, nil
+		}
+		return nil, models.Internal("failed to get content filter settings: %w", err)
+	}
+
+	return settings, nil
+}
+
+func (r *PostgresContentFilterRepository) SaveSettings(ctx context.Context, settings *models.ContentFilterSettings) error {
+	query := `
+		INSERT INTO gocourse.content_filter_settings (id, enabled, ageLevel, bannedTopics, updatedAt) 
+		VALUES (1, $1, $2, $3, NOW()) 
+		ON CONFLICT (id) DO UPDATE 
+		SET enabled = EXCLUDED.enabled, ageLevel = EXCLUDED.ageLevel, bannedTopics = EXCLUDED.bannedTopics, updatedAt = NOW() 
+		RETURNING updatedAt`
+
+	row := r.db.QueryRowContext(ctx, query, settings.Enabled, settings.AgeLevel, pq.Array(settings.BannedTopics))
+	if err := row.Scan(&settings.UpdatedAt); err != nil {
+		return models.Internal("failed to save content filter settings: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+type DailyQuestionRepository interface {
+	CreateSubscription(ctx context.Context, subscription *models.DailyQuestionSubscription) error
+	GetSubscriptions(ctx context.Context, userID int) ([]*models.DailyQuestionSubscription, error)
+	GetSubscriptionsNotSentSince(ctx context.Context, since time.Time) ([]*models.DailyQuestionSubscription, error)
+	MarkSubscriptionSent(ctx context.Context, id, sessionID int, sentAt time.Time) error
+	DeleteSubscription(ctx context.Context, userID, id int) error
+}
+
+type PostgresDailyQuestionRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresDailyQuestionRepository(db *sql.DB) *PostgresDailyQuestionRepository {
+	return &PostgresDailyQuestionRepository{db: db}
+}
+
+func (r *PostgresDailyQuestionRepository) CreateSubscription(ctx context.Context, subscription *models.DailyQuestionSubscription) error {
+	query := `
+		INSERT INTO gocourse.daily_question_subscriptions (user_id, deck_id) 
+		VALUES ($1, $2) 
+		RETURNING id, createdAt`
+
+	err := r.db.QueryRowContext(ctx, query, subscription.UserID, subscription.DeckID).Scan(&subscription.ID, &subscription.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return models.Conflict("already subscribed to daily questions from deck %d", subscription.DeckID)
+		}
+		return models.Internal("failed to create daily question subscription: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresDailyQuestionRepository) GetSubscriptions(ctx context.Context, userID int) ([]*models.DailyQuestionSubscription, error) {
+	query := `
+		SELECT s.id, s.user_id, s.deck_id, s.last_session_id, s.lastSentAt, s.createdAt, u.email 
+		FROM gocourse.daily_question_subscriptions s 
+		JOIN gocourse.users u ON u.id = s.user_id 
+		WHERE s.user_id = $1 
+		ORDER BY s.createdAt ASC, s.id ASC`
+
+	return r.querySubscriptions(ctx, query, userID)
+}
+
+// GetSubscriptionsNotSentSince returns every user's subscriptions that have
+// not had a question sent since the given time, for the scheduled job
+func (r *PostgresDailyQuestionRepository) GetSubscriptionsNotSentSince(ctx context.Context, since time.Time) ([]*models.DailyQuestionSubscription, error) {
+	query := `
+		SELECT s.id, s.user_id, s.deck_id, s.last_session_id, s.lastSentAt, s.createdAt, u.email 
+		FROM gocourse.daily_question_subscriptions s 
+		JOIN gocourse.users u ON u.id = s.user_id 
+		WHERE s.lastSentAt IS NULL OR s.lastSentAt < $1 
+		ORDER BY s.user_id ASC, s.id ASC`
+
+	return r.querySubscriptions(ctx, query, since)
+}
+
+func (r *PostgresDailyQuestionRepository) querySubscriptions(ctx context.Context, query string, args ...any) ([]*models.DailyQuestionSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Internal("failed to query daily question subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := make([]*models.DailyQuestionSubscription, 0)
+	for rows.Next() {
+		subscription := &models.DailyQuestionSubscription{}
+		err := rows.Scan(&subscription.ID, &subscription.UserID, &subscription.DeckID, &subscription.LastSessionID,
+			&subscription.LastSentAt, &subscription.CreatedAt, &subscription.Email)
+		if err != nil {
+			return nil, models.Internal("failed to scan daily question subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over daily question subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (r *PostgresDailyQuestionRepository) MarkSubscriptionSent(ctx context.Context, id, sessionID int, sentAt time.Time) error {
+	query := "UPDATE gocourse.daily_question_subscriptions SET last_session_id = $2, lastSentAt = $3 WHERE id = $1"
+
+	if _, err := r.db.ExecContext(ctx, query, id, sessionID, sentAt); err != nil {
+		return models.Internal("failed to mark daily question subscription sent: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresDailyQuestionRepository) DeleteSubscription(ctx context.Context, userID, id int) error {
+	query := "DELETE FROM gocourse.daily_question_subscriptions WHERE id = $1 AND user_id = $2"
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return models.Internal("failed to delete daily question subscription: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("daily question subscription with id %d not found", id)
+	}
+
+	return nil
+}
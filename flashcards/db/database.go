@@ -0,0 +1,219 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/XSAM/otelsql"
+	_ "github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"flashcards/models"
+)
+
+// How many connections warmPool opens, the number database/sql keeps idle
+// by default. A smaller MaxIdleConns lowers it.
+const WARMUP_CONNECTIONS = 2
+
+// Warmer is a repository that can open its connections and prepare its
+// hottest queries ahead of the first requests
+type Warmer interface {
+	Warm(ctx context.Context) error
+}
+
+// PoolConfig sizes the Postgres connection pool the repositories share.
+// Zero values keep the database/sql defaults: unlimited open connections,
+// two idle ones, and connections reused for as long as they live.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+var (
+	poolConfig PoolConfig
+
+	poolsMu sync.Mutex
+	pools   = make(map[string]*sql.DB)
+)
+
+// ConfigurePool sets the settings pools opened from now on use. Call it
+// once at startup, before creating repositories.
+func ConfigurePool(cfg PoolConfig) {
+	poolConfig = cfg
+}
+
+// PoolStats returns the statistics of every pool opened, keyed by the name
+// it was opened under
+func PoolStats() map[string]sql.DBStats {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	stats := make(map[string]sql.DBStats, len(pools))
+	for name, db := range pools {
+		stats[name] = db.Stats()
+	}
+	return stats
+}
+
+// Database is the connection pool every repository of a storage driver
+// shares. Repositories are created on it with the New*Store functions and
+// do not close it; the server closes it once, after them.
+type Database struct {
+	driver string
+	db     *sql.DB
+}
+
+// Open opens the pool of the storage driver: Postgres at databaseURL, sized
+// by ConfigurePool, or the SQLite file at sqlitePath. It is registered
+// under the driver's name for PoolStats.
+func Open(driver, databaseURL, sqlitePath string) (*Database, error) {
+	var db *sql.DB
+	var err error
+	switch driver {
+	case STORAGE_POSTGRES:
+		db, err = openDatabase(driver, databaseURL)
+	case STORAGE_SQLITE:
+		db, err = openSQLite(driver, sqlitePath)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return &Database{driver: driver, db: db}, nil
+}
+
+func (d *Database) Close() error {
+	return d.db.Close()
+}
+
+// openDatabase opens a Postgres connection pool, sized by ConfigurePool,
+// that records a span for every query, as a child of the span in the
+// query's context. The driver injects faults when EnableChaos was called.
+// The pool is registered under name for PoolStats.
+func openDatabase(name, databaseURL string) (*sql.DB, error) {
+	db, err := otelsql.Open(driverName, databaseURL, otelsql.WithAttributes(semconv.DBSystemPostgreSQL))
+	if err != nil {
+		return nil, err
+	}
+
+	if poolConfig.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(poolConfig.MaxOpenConns)
+	}
+	if poolConfig.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(poolConfig.MaxIdleConns)
+	}
+	if poolConfig.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(poolConfig.ConnMaxLifetime)
+	}
+	if poolConfig.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(poolConfig.ConnMaxIdleTime)
+	}
+
+	poolsMu.Lock()
+	pools[name] = db
+	poolsMu.Unlock()
+	return db, nil
+}
+
+// warmPool opens the pool's idle connections and prepares the queries on
+// each, which loads their tables into the connection's catalog cache. The
+// statements are closed again; the connections stay open in the pool, for
+// the next repository sharing it to warm.
+func warmPool(ctx context.Context, db *sql.DB, queries ...string) error {
+	count := WARMUP_CONNECTIONS
+	if poolConfig.MaxIdleConns > 0 {
+		count = min(count, poolConfig.MaxIdleConns)
+	}
+	conns := make([]*sql.Conn, 0, count)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	// Connections are held until all are open, so each one is different
+	for range count {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return models.Internal("failed to open connection: %w", err)
+		}
+		conns = append(conns, conn)
+
+		for _, query := range queries {
+			stmt, err := conn.PrepareContext(ctx, query)
+			if err != nil {
+				return models.Internal("failed to prepare query: %w", err)
+			}
+			stmt.Close()
+		}
+	}
+
+	return nil
+}
+
+// updatableColumns lists the columns of each table an update may set. The
+// update builders write fields into the query as they are, and a row's id,
+// owner, version and timestamps are theirs to manage, so nothing else passes.
+var updatableColumns = map[string]map[string]bool{
+	"notes": {
+		"title": true, "content": true, "summary": true, "source": true, "language": true, "deck_id": true,
+	},
+	"flashcards": {
+		"front": true, "back": true, "hint": true, "mnemonic": true, "content": true, "deck_id": true, "source_note_id": true,
+	},
+	"decks": {
+		"name": true, "description": true, "reviewOrder": true, "parent_id": true,
+	},
+	"todos": {
+		"title": true, "description": true, "completed": true,
+	},
+	"quiz_sessions": {
+		"status": true, "currentPosition": true,
+	},
+	"quiz_session_questions": {
+		"answer": true, "status": true, "flagged": true, "timeSpentMs": true, "attachment_id": true, "grading": true, "correct": true,
+	},
+}
+
+// checkUpdates rejects an update with no fields or with a field that is not
+// an updatable column of table
+func checkUpdates(table string, updates map[string]any) error {
+	if len(updates) == 0 {
+		return models.Invalid("no updates provided")
+	}
+	columns := updatableColumns[table]
+	for field := range updates {
+		if !columns[field] {
+			return models.Invalid("cannot update field %q", field)
+		}
+	}
+	return nil
+}
+
+// versionMismatch explains why an update guarded by a version changed no
+// rows: the row is gone, or another update bumped its version first
+func versionMismatch(ctx context.Context, db queryer, table, name string, userID, id, version int) error {
+	var current int
+	err := db.QueryRowContext(ctx, "SELECT version FROM gocourse."+table+" WHERE id = $1 AND user_id = $2", id, userID).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.NotFound("%s with id %d not found", name, id)
+	}
+	if err != nil {
+		return models.Internal("failed to check %s version: %w", name, err)
+	}
+
+	return models.Conflict("%s %d is at version %d, not %d; fetch it again and retry", name, id, current, version)
+}
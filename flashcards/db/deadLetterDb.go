@@ -0,0 +1,230 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+const deadLetterColumns = `id, kind, key, user_id, payload, error, attempts, status, nextAttemptAt, createdAt, updatedAt, resolvedAt`
+
+type DeadLetterRepository interface {
+	RecordDeadLetter(ctx context.Context, letter *models.DeadLetter) error
+	GetDeadLetter(ctx context.Context, id int) (*models.DeadLetter, error)
+	ListDeadLetters(ctx context.Context, filter models.DeadLetterFilter, params models.ListParams) ([]*models.DeadLetter, int, error)
+	ClaimDueDeadLetters(ctx context.Context, limit int, lease time.Duration) ([]*models.DeadLetter, error)
+	ResolveDeadLetter(ctx context.Context, id int) error
+	FailDeadLetterAttempt(ctx context.Context, id int, message string, nextAttemptAt *time.Time) error
+	RequeueDeadLetter(ctx context.Context, id int) (*models.DeadLetter, error)
+	DeleteDeadLetter(ctx context.Context, id int) error
+}
+
+type PostgresDeadLetterRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresDeadLetterRepository(db *sql.DB) *PostgresDeadLetterRepository {
+	return &PostgresDeadLetterRepository{db: db}
+}
+
+// RecordDeadLetter adds a failed piece of work. When the same work already
+// has an open entry, that entry takes the new error and payload and keeps
+// its attempts and schedule.
+func (r *PostgresDeadLetterRepository) RecordDeadLetter(ctx context.Context, letter *models.DeadLetter) error {
+	query := `
+		INSERT INTO gocourse.dead_letters (kind, key, user_id, payload, error, attempts, status, nextAttemptAt) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
+		ON CONFLICT (kind, key) WHERE status <> 'resolved' DO UPDATE 
+		SET payload = EXCLUDED.payload, error = EXCLUDED.error, updatedAt = NOW() 
+		RETURNING ` + deadLetterColumns
+
+	row := r.db.QueryRowContext(ctx, query, letter.Kind, letter.Key, letter.UserID, []byte(letter.Payload), letter.Error,
+		letter.Attempts, letter.Status, letter.NextAttemptAt)
+	recorded, err := scanDeadLetter(row)
+	if err != nil {
+		return models.Internal("failed to record dead letter: %w", err)
+	}
+
+	*letter = *recorded
+	return nil
+}
+
+func (r *PostgresDeadLetterRepository) GetDeadLetter(ctx context.Context, id int) (*models.DeadLetter, error) {
+	query := `SELECT ` + deadLetterColumns + ` FROM gocourse.dead_letters WHERE id = $1`
+
+	letter, err := scanDeadLetter(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("dead letter with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get dead letter: %w", err)
+	}
+
+	return letter, nil
+}
+
+// ListDeadLetters returns one page of entries matching the filter and the
+// total number that match
+func (r *PostgresDeadLetterRepository) ListDeadLetters(ctx context.Context, filter models.DeadLetterFilter, params models.ListParams) ([]*models.DeadLetter, int, error) {
+	where := ` WHERE ($1 = '' OR d.status = $1) AND ($2 = '' OR d.kind = $2)`
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM gocourse.dead_letters d` + where
+	if err := r.db.QueryRowContext(ctx, countQuery, filter.Status, filter.Kind).Scan(&total); err != nil {
+		return nil, 0, models.Internal("failed to count dead letters: %w", err)
+	}
+
+	orderBy, err := orderByClause("d", params)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := `SELECT ` + deadLetterColumns + ` FROM gocourse.dead_letters d` + where + orderBy + ` LIMIT $3 OFFSET $4`
+
+	rows, err := r.db.QueryContext(ctx, query, filter.Status, filter.Kind, params.Limit, params.Offset)
+	if err != nil {
+		return nil, 0, models.Internal("failed to query dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	letters := make([]*models.DeadLetter, 0)
+	for rows.Next() {
+		letter, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, 0, models.Internal("failed to scan dead letter: %w", err)
+		}
+		letters = append(letters, letter)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating over dead letters: %w", err)
+	}
+
+	return letters, total, nil
+}
+
+// ClaimDueDeadLetters returns retrying entries whose next attempt is due
+// and pushes that attempt back by lease, so another server polling at the
+// same time skips them and an attempt cut short by a crash is picked up
+// again once the lease runs out
+func (r *PostgresDeadLetterRepository) ClaimDueDeadLetters(ctx context.Context, limit int, lease time.Duration) ([]*models.DeadLetter, error) {
+	query := `
+		UPDATE gocourse.dead_letters 
+		SET nextAttemptAt = NOW() + make_interval(secs => $1) 
+		WHERE id IN ( 
+			SELECT id FROM gocourse.dead_letters 
+			WHERE status = $2 AND nextAttemptAt <= NOW() 
+			ORDER BY nextAttemptAt 
+			LIMIT $3 
+			FOR UPDATE SKIP LOCKED 
+		) 
+		RETURNING ` + deadLetterColumns
+
+	rows, err := r.db.QueryContext(ctx, query, lease.Seconds(), models.DeadLetterStatusRetrying, limit)
+	if err != nil {
+		return nil, models.Internal("failed to claim dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	letters := make([]*models.DeadLetter, 0)
+	for rows.Next() {
+		letter, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan dead letter: %w", err)
+		}
+		letters = append(letters, letter)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over dead letters: %w", err)
+	}
+
+	return letters, nil
+}
+
+func (r *PostgresDeadLetterRepository) ResolveDeadLetter(ctx context.Context, id int) error {
+	query := `
+		UPDATE gocourse.dead_letters 
+		SET status = $2, nextAttemptAt = NULL, resolvedAt = NOW(), updatedAt = NOW() 
+		WHERE id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, id, models.DeadLetterStatusResolved); err != nil {
+		return models.Internal("failed to resolve dead letter: %w", err)
+	}
+
+	return nil
+}
+
+// FailDeadLetterAttempt counts a failed retry. Without a next attempt the
+// entry is dead.
+func (r *PostgresDeadLetterRepository) FailDeadLetterAttempt(ctx context.Context, id int, message string, nextAttemptAt *time.Time) error {
+	query := `
+		UPDATE gocourse.dead_letters 
+		SET attempts = attempts + 1, error = $2, nextAttemptAt = $3, 
+			status = CASE WHEN $3::TIMESTAMP IS NULL THEN $4 ELSE $5 END, updatedAt = NOW() 
+		WHERE id = $1 AND status <> $6`
+
+	_, err := r.db.ExecContext(ctx, query, id, message, nextAttemptAt,
+		models.DeadLetterStatusDead, models.DeadLetterStatusRetrying, models.DeadLetterStatusResolved)
+	if err != nil {
+		return models.Internal("failed to update dead letter: %w", err)
+	}
+
+	return nil
+}
+
+// RequeueDeadLetter makes an open entry due for a retry now, including a
+// dead one
+func (r *PostgresDeadLetterRepository) RequeueDeadLetter(ctx context.Context, id int) (*models.DeadLetter, error) {
+	query := `
+		UPDATE gocourse.dead_letters 
+		SET status = $2, nextAttemptAt = NOW(), updatedAt = NOW() 
+		WHERE id = $1 AND status <> $3 
+		RETURNING ` + deadLetterColumns
+
+	letter, err := scanDeadLetter(r.db.QueryRowContext(ctx, query, id, models.DeadLetterStatusRetrying, models.DeadLetterStatusResolved))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			if _, getErr := r.GetDeadLetter(ctx, id); getErr != nil {
+				return nil, getErr
+			}
+			return nil, models.Conflict("dead letter %d is already resolved", id)
+		}
+		return nil, models.Internal("failed to requeue dead letter: %w", err)
+	}
+
+	return letter, nil
+}
+
+func (r *PostgresDeadLetterRepository) DeleteDeadLetter(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM gocourse.dead_letters WHERE id = $1", id)
+	if err != nil {
+		return models.Internal("failed to delete dead letter: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("dead letter with id %d not found", id)
+	}
+
+	return nil
+}
+
+func scanDeadLetter(row interface{ Scan(...any) error }) (*models.DeadLetter, error) {
+	letter := &models.DeadLetter{}
+	var payload []byte
+	err := row.Scan(&letter.ID, &letter.Kind, &letter.Key, &letter.UserID, &payload, &letter.Error, &letter.Attempts,
+		&letter.Status, &letter.NextAttemptAt, &letter.CreatedAt, &letter.UpdatedAt, &letter.ResolvedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	letter.Payload = payload
+	return letter, nil
+}
@@ -0,0 +1,323 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+type DeckRepository interface {
+	CreateDeck(ctx context.Context, deck *models.Deck) error
+	GetDeckByID(ctx context.Context, userID, id int) (*models.Deck, error)
+	GetAllDecks(ctx context.Context, userID int) ([]*models.Deck, error)
+	UpdateDeck(ctx context.Context, userID, id int, updates map[string]any) error
+	DeleteDeck(ctx context.Context, userID, id int) error
+	GetDeckTreeIDs(ctx context.Context, userID, id int) ([]int, error)
+	GetTerminology(ctx context.Context, userID int, deckIDs []int) ([]*models.DeckTerminology, error)
+	SaveTerminology(ctx context.Context, terminology *models.DeckTerminology) error
+	GetStudySettings(ctx context.Context, userID, deckID int) (*models.DeckStudySettings, error)
+	SaveStudySettings(ctx context.Context, settings *models.DeckStudySettings) error
+}
+
+type PostgresDeckRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresDeckRepository(db *sql.DB) *PostgresDeckRepository {
+	return &PostgresDeckRepository{db: db}
+}
+
+func (r *PostgresDeckRepository) CreateDeck(ctx context.Context, deck *models.Deck) error {
+	query := `
+		INSERT INTO gocourse.decks (user_id, parent_id, name, description, reviewOrder) 
+		VALUES ($1, $2, $3, $4, $5) 
+		RETURNING id, createdAt, updatedAt`
+
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, deck.UserID, deck.ParentID, deck.Name, deck.Description, deck.ReviewOrder)
+
+	err := row.Scan(&deck.ID, &deck.CreatedAt, &deck.UpdatedAt)
+	if err != nil {
+		return models.Internal("failed to create deck: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresDeckRepository) GetDeckByID(ctx context.Context, userID, id int) (*models.Deck, error) {
+	query := `
+		SELECT id, user_id, parent_id, name, description, reviewOrder, createdAt, updatedAt 
+		FROM gocourse.decks 
+		WHERE id = $1 AND user_id = $2`
+
+	deck := &models.Deck{}
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, id, userID)
+
+	err := row.Scan(&deck.ID, &deck.UserID, &deck.ParentID, &deck.Name, &deck.Description, &deck.ReviewOrder, &deck.CreatedAt, &deck.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("deck with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get deck: %w", err)
+	}
+
+	return deck, nil
+}
+
+func (r *PostgresDeckRepository) GetAllDecks(ctx context.Context, userID int) ([]*models.Deck, error) {
+	query := `
+		SELECT id, user_id, parent_id, name, description, reviewOrder, createdAt, updatedAt 
+		FROM gocourse.decks 
+		WHERE user_id = $1 
+		ORDER BY name ASC`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, models.Internal("failed to query decks: %w", err)
+	}
+	defer rows.Close()
+
+	decks := make([]*models.Deck, 0)
+	for rows.Next() {
+		deck := &models.Deck{}
+		err := rows.Scan(&deck.ID, &deck.UserID, &deck.ParentID, &deck.Name, &deck.Description, &deck.ReviewOrder, &deck.CreatedAt, &deck.UpdatedAt)
+		if err != nil {
+			return nil, models.Internal("failed to scan deck: %w", err)
+		}
+		decks = append(decks, deck)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over decks: %w", err)
+	}
+
+	return decks, nil
+}
+
+func (r *PostgresDeckRepository) UpdateDeck(ctx context.Context, userID, id int, updates map[string]any) error {
+	if err := checkUpdates("decks", updates); err != nil {
+		return err
+	}
+
+	query := "UPDATE gocourse.decks SET "
+	args := []any{}
+	argIndex := 1
+
+	for field, value := range updates {
+		if argIndex > 1 {
+			query += ", "
+		}
+		query += fmt.Sprintf("%s = $%d", field, argIndex)
+		args = append(args, value)
+		argIndex++
+	}
+
+	query += fmt.Sprintf(", updatedAt = NOW() WHERE id = $%d AND user_id = $%d", argIndex, argIndex+1)
+	args = append(args, id, userID)
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return models.Internal("failed to update deck: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("deck with id %d not found", id)
+	}
+
+	return nil
+}
+
+func (r *PostgresDeckRepository) DeleteDeck(ctx context.Context, userID, id int) error {
+	query := "DELETE FROM gocourse.decks WHERE id = $1 AND user_id = $2"
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return models.Internal("failed to delete deck: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("deck with id %d not found", id)
+	}
+
+	return nil
+}
+
+// GetDeckTreeIDs returns the deck and all of its subdecks, at any depth
+func (r *PostgresDeckRepository) GetDeckTreeIDs(ctx context.Context, userID, id int) ([]int, error) {
+	query := `
+		WITH RECURSIVE tree AS (
+			SELECT id FROM gocourse.decks WHERE id = $1 AND user_id = $2
+			UNION
+			SELECT d.id FROM gocourse.decks d JOIN tree t ON d.parent_id = t.id WHERE d.user_id = $2
+		)
+		SELECT id FROM tree`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, id, userID)
+	if err != nil {
+		return nil, models.Internal("failed to query subdecks: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var deckID int
+		if err := rows.Scan(&deckID); err != nil {
+			return nil, models.Internal("failed to scan subdeck: %w", err)
+		}
+		ids = append(ids, deckID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over subdecks: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return nil, models.NotFound("deck with id %d not found", id)
+	}
+
+	return ids, nil
+}
+
+// GetTerminology returns the terminology of the user's decks among deckIDs.
+// Decks without a saved dictionary are left out.
+func (r *PostgresDeckRepository) GetTerminology(ctx context.Context, userID int, deckIDs []int) ([]*models.DeckTerminology, error) {
+	query := `
+		SELECT dt.deck_id, dt.preferredTerms, dt.abbreviations, dt.doNotSimplify, dt.stopWords, dt.updatedAt 
+		FROM gocourse.deck_terminology dt 
+		JOIN gocourse.decks d ON d.id = dt.deck_id 
+		WHERE d.user_id = $1 AND dt.deck_id = ANY($2) 
+		ORDER BY dt.deck_id ASC`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, pq.Array(deckIDs))
+	if err != nil {
+		return nil, models.Internal("failed to query deck terminology: %w", err)
+	}
+	defer rows.Close()
+
+	terminologies := make([]*models.DeckTerminology, 0)
+	for rows.Next() {
+		terminology := &models.DeckTerminology{}
+		var preferredJSON, abbreviationsJSON []byte
+		err := rows.Scan(&terminology.DeckID, &preferredJSON, &abbreviationsJSON,
+			pq.Array(&terminology.DoNotSimplify), pq.Array(&terminology.StopWords), &terminology.UpdatedAt)
+		if err != nil {
+			return nil, models.Internal("failed to scan deck terminology: %w", err)
+		}
+		if err := json.Unmarshal(preferredJSON, &terminology.PreferredTerms); err != nil {
+			return nil, models.Internal("failed to decode preferred terms: %w", err)
+		}
+		if err := json.Unmarshal(abbreviationsJSON, &terminology.Abbreviations); err != nil {
+			return nil, models.Internal("failed to decode abbreviations: %w", err)
+		}
+		terminologies = append(terminologies, terminology)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over deck terminology: %w", err)
+	}
+
+	return terminologies, nil
+}
+
+// SaveTerminology replaces a deck's terminology dictionary
+func (r *PostgresDeckRepository) SaveTerminology(ctx context.Context, terminology *models.DeckTerminology) error {
+	preferredJSON, err := json.Marshal(terminology.PreferredTerms)
+	if err != nil {
+		return models.Internal("failed to encode preferred terms: %w", err)
+	}
+
+	abbreviationsJSON, err := json.Marshal(terminology.Abbreviations)
+	if err != nil {
+		return models.Internal("failed to encode abbreviations: %w", err)
+	}
+
+	query := `
+		INSERT INTO gocourse.deck_terminology (deck_id, preferredTerms, abbreviations, doNotSimplify, stopWords) 
+		VALUES ($1, $2, $3, $4, $5) 
+		ON CONFLICT (deck_id) DO UPDATE SET 
+			preferredTerms = EXCLUDED.preferredTerms, 
+			abbreviations = EXCLUDED.abbreviations, 
+			doNotSimplify = EXCLUDED.doNotSimplify, 
+			stopWords = EXCLUDED.stopWords, 
+			updatedAt = NOW() 
+		RETURNING updatedAt`
+
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, terminology.DeckID, preferredJSON, abbreviationsJSON,
+		pq.Array(terminology.DoNotSimplify), pq.Array(terminology.StopWords))
+
+	if err := row.Scan(&terminology.UpdatedAt); err != nil {
+		return models.Internal("failed to save deck terminology: %w", err)
+	}
+
+	return nil
+}
+
+// GetStudySettings returns the saved study settings of one of the user's decks
+func (r *PostgresDeckRepository) GetStudySettings(ctx context.Context, userID, deckID int) (*models.DeckStudySettings, error) {
+	query := `
+		SELECT ss.deck_id, ss.learningSteps, ss.relearningSteps, ss.graduatingIntervalDays, ss.easyIntervalDays, 
+			ss.newCardsPerDay, ss.newCardOrder, ss.newCardPosition, ss.leechThreshold, ss.leechAction, ss.updatedAt 
+		FROM gocourse.deck_study_settings ss 
+		JOIN gocourse.decks d ON d.id = ss.deck_id 
+		WHERE ss.deck_id = $1 AND d.user_id = $2`
+
+	settings := &models.DeckStudySettings{}
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, deckID, userID)
+
+	err := row.Scan(&settings.DeckID, pq.Array(&settings.LearningSteps), pq.Array(&settings.RelearningSteps),
+		&settings.GraduatingIntervalDays, &settings.EasyIntervalDays, &settings.NewCardsPerDay,
+		&settings.NewCardOrder, &settings.NewCardPosition, &settings.LeechThreshold, &settings.LeechAction, &settings.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("study settings for deck with id %d not found", deckID)
+		}
+		return nil, models.Internal("failed to get study settings: %w", err)
+	}
+
+	return settings, nil
+}
+
+// SaveStudySettings replaces a deck's study settings
+func (r *PostgresDeckRepository) SaveStudySettings(ctx context.Context, settings *models.DeckStudySettings) error {
+	query := `
+		INSERT INTO gocourse.deck_study_settings 
+			(deck_id, learningSteps, relearningSteps, graduatingIntervalDays, easyIntervalDays, newCardsPerDay, newCardOrder, newCardPosition, 
+			leechThreshold, leechAction) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) 
+		ON CONFLICT (deck_id) DO UPDATE SET 
+			learningSteps = EXCLUDED.learningSteps, 
+			relearningSteps = EXCLUDED.relearningSteps, 
+			graduatingIntervalDays = EXCLUDED.graduatingIntervalDays, 
+			easyIntervalDays = EXCLUDED.easyIntervalDays, 
+			newCardsPerDay = EXCLUDED.newCardsPerDay, 
+			newCardOrder = EXCLUDED.newCardOrder, 
+			newCardPosition = EXCLUDED.newCardPosition, 
+			leechThreshold = EXCLUDED.leechThreshold, 
+			leechAction = EXCLUDED.leechAction, 
+			updatedAt = NOW() 
+		RETURNING updatedAt`
+
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, settings.DeckID, pq.Array(settings.LearningSteps), pq.Array(settings.RelearningSteps),
+		settings.GraduatingIntervalDays, settings.EasyIntervalDays, settings.NewCardsPerDay,
+		settings.NewCardOrder, settings.NewCardPosition, settings.LeechThreshold, settings.LeechAction)
+
+	if err := row.Scan(&settings.UpdatedAt); err != nil {
+		return models.Internal("failed to save study settings: %w", err)
+	}
+
+	return nil
+}
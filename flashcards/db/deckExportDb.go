@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+
+	_ "github.com/lib/pq"
+)
+
+type DeckExportRepository interface {
+	CreateExport(ctx context.Context, export *models.StoredDeckExport) error
+	GetExport(ctx context.Context, userID, id int) (*models.StoredDeckExport, error)
+	GetExports(ctx context.Context, userID int) ([]*models.StoredDeckExport, error)
+	GetExpiredExports(ctx context.Context, now time.Time, limit int) ([]*models.StoredDeckExport, error)
+	DeleteExport(ctx context.Context, id int) error
+}
+
+type PostgresDeckExportRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresDeckExportRepository(db *sql.DB) *PostgresDeckExportRepository {
+	return &PostgresDeckExportRepository{db: db}
+}
+
+const deckExportColumns = `id, user_id, deck_id, format, filename, contentType, size, storageKey, expiresAt, createdAt`
+
+func scanDeckExport(row interface{ Scan(...any) error }) (*models.StoredDeckExport, error) {
+	export := &models.StoredDeckExport{}
+	err := row.Scan(&export.ID, &export.UserID, &export.DeckID, &export.Format, &export.Filename, &export.ContentType,
+		&export.Size, &export.StorageKey, &export.ExpiresAt, &export.CreatedAt)
+	return export, err
+}
+
+func (r *PostgresDeckExportRepository) CreateExport(ctx context.Context, export *models.StoredDeckExport) error {
+	query := `
+		INSERT INTO gocourse.deck_exports (user_id, deck_id, format, filename, contentType, size, storageKey, expiresAt) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
+		RETURNING id, createdAt`
+
+	row := r.db.QueryRowContext(ctx, query, export.UserID, export.DeckID, export.Format, export.Filename,
+		export.ContentType, export.Size, export.StorageKey, export.ExpiresAt)
+	if err := row.Scan(&export.ID, &export.CreatedAt); err != nil {
+		return models.Internal("failed to create deck export: %w", err)
+	}
+
+	return nil
+}
+
+// GetExport returns one of the user's exports that has not expired
+func (r *PostgresDeckExportRepository) GetExport(ctx context.Context, userID, id int) (*models.StoredDeckExport, error) {
+	query := `
+		SELECT ` + deckExportColumns + ` 
+		FROM gocourse.deck_exports 
+		WHERE id = $1 AND user_id = $2 AND expiresAt > NOW()`
+
+	export, err := scanDeckExport(r.db.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("deck export with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get deck export: %w", err)
+	}
+
+	return export, nil
+}
+
+// GetExports returns the user's exports that have not expired, newest
+// first
+func (r *PostgresDeckExportRepository) GetExports(ctx context.Context, userID int) ([]*models.StoredDeckExport, error) {
+	query := `
+		SELECT ` + deckExportColumns + ` 
+		FROM gocourse.deck_exports 
+		WHERE user_id = $1 AND expiresAt > NOW() 
+		ORDER BY createdAt DESC`
+
+	return r.queryExports(ctx, query, userID)
+}
+
+// GetExpiredExports returns up to limit exports that expired by now,
+// oldest first
+func (r *PostgresDeckExportRepository) GetExpiredExports(ctx context.Context, now time.Time, limit int) ([]*models.StoredDeckExport, error) {
+	query := `
+		SELECT ` + deckExportColumns + ` 
+		FROM gocourse.deck_exports 
+		WHERE expiresAt <= $1 
+		ORDER BY expiresAt 
+		LIMIT $2`
+
+	return r.queryExports(ctx, query, now, limit)
+}
+
+func (r *PostgresDeckExportRepository) queryExports(ctx context.Context, query string, args ...any) ([]*models.StoredDeckExport, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Internal("failed to get deck exports: %w", err)
+	}
+	defer rows.Close()
+
+	var exports []*models.StoredDeckExport
+	for rows.Next() {
+		export, err := scanDeckExport(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan deck export: %w", err)
+		}
+		exports = append(exports, export)
+	}
+
+	return exports, rows.Err()
+}
+
+func (r *PostgresDeckExportRepository) DeleteExport(ctx context.Context, id int) error {
+	query := `DELETE FROM gocourse.deck_exports WHERE id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		return models.Internal("failed to delete deck export: %w", err)
+	}
+
+	return nil
+}
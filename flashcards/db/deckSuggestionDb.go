@@ -0,0 +1,233 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+const deckSuggestionColumns = "id, user_id, deck_id, kind, flashcard_ids, note_ids, status, createdAt, resolvedAt"
+
+type DeckSuggestionRepository interface {
+	FindSuggestions(ctx context.Context, leechLapses int) ([]*models.DeckSuggestion, error)
+	ReplaceOpenSuggestions(ctx context.Context, suggestions []*models.DeckSuggestion) (int, error)
+	ListOpenSuggestions(ctx context.Context, userID, deckID int) ([]*models.DeckSuggestion, error)
+	GetSuggestion(ctx context.Context, userID, deckID, id int) (*models.DeckSuggestion, error)
+	ResolveSuggestion(ctx context.Context, userID, deckID, id int, status string) (*models.DeckSuggestion, error)
+	ResetSchedules(ctx context.Context, userID int, flashcardIDs []int) error
+}
+
+type PostgresDeckSuggestionRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresDeckSuggestionRepository(db *sql.DB) *PostgresDeckSuggestionRepository {
+	return &PostgresDeckSuggestionRepository{db: db}
+}
+
+// FindSuggestions analyzes the cards of every deck. Duplicates are cards of
+// a deck whose content matches ignoring case and surrounding space; leeches
+// have lapsed at least leechLapses times; stale cards come from a note
+// updated after them, grouped per note; untagged cards come from notes
+// without tags, grouped per deck.
+func (r *PostgresDeckSuggestionRepository) FindSuggestions(ctx context.Context, leechLapses int) ([]*models.DeckSuggestion, error) {
+	query := `
+		SELECT user_id, deck_id, 'duplicate', array_agg(id ORDER BY id), '{}'::INTEGER[] 
+		FROM gocourse.flashcards 
+		WHERE deck_id IS NOT NULL 
+		GROUP BY user_id, deck_id, LOWER(BTRIM(content)) 
+		HAVING COUNT(*) > 1 
+		UNION ALL 
+		SELECT f.user_id, f.deck_id, 'leech', ARRAY[f.id], '{}'::INTEGER[] 
+		FROM gocourse.flashcards f 
+		JOIN gocourse.flashcard_schedules s ON s.flashcard_id = f.id 
+		WHERE f.deck_id IS NOT NULL AND s.lapses >= $1 
+		UNION ALL 
+		SELECT f.user_id, f.deck_id, 'stale', array_agg(f.id ORDER BY f.id), ARRAY[n.id] 
+		FROM gocourse.flashcards f 
+		JOIN gocourse.notes n ON n.id = f.source_note_id 
+		WHERE f.deck_id IS NOT NULL AND n.updatedAt > f.updatedAt 
+		GROUP BY f.user_id, f.deck_id, n.id 
+		UNION ALL 
+		SELECT f.user_id, f.deck_id, 'untagged', array_agg(f.id ORDER BY f.id), array_agg(DISTINCT n.id) 
+		FROM gocourse.flashcards f 
+		JOIN gocourse.notes n ON n.id = f.source_note_id 
+		WHERE f.deck_id IS NOT NULL 
+			AND NOT EXISTS (SELECT 1 FROM gocourse.note_tags nt WHERE nt.note_id = n.id) 
+		GROUP BY f.user_id, f.deck_id`
+
+	rows, err := r.db.QueryContext(ctx, query, leechLapses)
+	if err != nil {
+		return nil, models.Internal("failed to find deck suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions := make([]*models.DeckSuggestion, 0)
+	for rows.Next() {
+		suggestion := &models.DeckSuggestion{Status: models.SuggestionOpen}
+		var flashcardIDs, noteIDs pq.Int64Array
+		if err := rows.Scan(&suggestion.UserID, &suggestion.DeckID, &suggestion.Kind, &flashcardIDs, &noteIDs); err != nil {
+			return nil, models.Internal("failed to scan deck suggestion: %w", err)
+		}
+		suggestion.FlashcardIDs, suggestion.NoteIDs = intSlice(flashcardIDs), intSlice(noteIDs)
+		suggestions = append(suggestions, suggestion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over deck suggestions: %w", err)
+	}
+
+	return suggestions, nil
+}
+
+// ReplaceOpenSuggestions swaps all open suggestions for the given ones in
+// one transaction, and reports how many were stored. Suggestions matching
+// one dismissed earlier, for the same deck, kind and cards, are skipped.
+func (r *PostgresDeckSuggestionRepository) ReplaceOpenSuggestions(ctx context.Context, suggestions []*models.DeckSuggestion) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM gocourse.deck_suggestions WHERE status = 'open'"); err != nil {
+		return 0, models.Internal("failed to delete open deck suggestions: %w", err)
+	}
+
+	query := `
+		INSERT INTO gocourse.deck_suggestions (user_id, deck_id, kind, flashcard_ids, note_ids) 
+		SELECT $1, $2, $3, $4, $5 
+		WHERE NOT EXISTS ( 
+			SELECT 1 FROM gocourse.deck_suggestions 
+			WHERE deck_id = $2 AND kind = $3 AND flashcard_ids = $4 AND status = 'dismissed' 
+		)`
+
+	stored := 0
+	for _, suggestion := range suggestions {
+		result, err := tx.ExecContext(ctx, query, suggestion.UserID, suggestion.DeckID, suggestion.Kind,
+			pq.Array(suggestion.FlashcardIDs), pq.Array(suggestion.NoteIDs))
+		if err != nil {
+			return 0, models.Internal("failed to store deck suggestion: %w", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return 0, models.Internal("failed to get rows affected: %w", err)
+		}
+		stored += int(rowsAffected)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, models.Internal("failed to commit deck suggestions: %w", err)
+	}
+
+	return stored, nil
+}
+
+// ListOpenSuggestions returns the deck's open suggestions by kind, then
+// oldest card first
+func (r *PostgresDeckSuggestionRepository) ListOpenSuggestions(ctx context.Context, userID, deckID int) ([]*models.DeckSuggestion, error) {
+	query := `
+		SELECT ` + deckSuggestionColumns + ` 
+		FROM gocourse.deck_suggestions 
+		WHERE user_id = $1 AND deck_id = $2 AND status = 'open' 
+		ORDER BY kind, flashcard_ids[1], id`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, deckID)
+	if err != nil {
+		return nil, models.Internal("failed to query deck suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions := make([]*models.DeckSuggestion, 0)
+	for rows.Next() {
+		suggestion, err := scanDeckSuggestion(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan deck suggestion: %w", err)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over deck suggestions: %w", err)
+	}
+
+	return suggestions, nil
+}
+
+func (r *PostgresDeckSuggestionRepository) GetSuggestion(ctx context.Context, userID, deckID, id int) (*models.DeckSuggestion, error) {
+	query := "SELECT " + deckSuggestionColumns + " FROM gocourse.deck_suggestions WHERE id = $1 AND user_id = $2 AND deck_id = $3"
+
+	suggestion, err := scanDeckSuggestion(r.db.QueryRowContext(ctx, query, id, userID, deckID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NotFound("suggestion with id %d not found", id)
+	}
+	if err != nil {
+		return nil, models.Internal("failed to get deck suggestion: %w", err)
+	}
+
+	return suggestion, nil
+}
+
+// ResolveSuggestion marks an open suggestion applied or dismissed. One
+// resolved already is a conflict.
+func (r *PostgresDeckSuggestionRepository) ResolveSuggestion(ctx context.Context, userID, deckID, id int, status string) (*models.DeckSuggestion, error) {
+	query := `
+		UPDATE gocourse.deck_suggestions 
+		SET status = $1, resolvedAt = NOW() 
+		WHERE id = $2 AND user_id = $3 AND deck_id = $4 AND status = 'open' 
+		RETURNING ` + deckSuggestionColumns
+
+	suggestion, err := scanDeckSuggestion(r.db.QueryRowContext(ctx, query, status, id, userID, deckID))
+	if errors.Is(err, sql.ErrNoRows) {
+		current, err := r.GetSuggestion(ctx, userID, deckID, id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, models.Conflict("suggestion %d is already %s", id, current.Status)
+	}
+	if err != nil {
+		return nil, models.Internal("failed to resolve deck suggestion: %w", err)
+	}
+
+	return suggestion, nil
+}
+
+// ResetSchedules drops the scheduling state of the user's cards, which
+// makes them new again
+func (r *PostgresDeckSuggestionRepository) ResetSchedules(ctx context.Context, userID int, flashcardIDs []int) error {
+	query := `
+		DELETE FROM gocourse.flashcard_schedules s 
+		USING gocourse.flashcards f 
+		WHERE s.flashcard_id = f.id AND f.user_id = $1 AND f.id = ANY($2)`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, pq.Array(flashcardIDs)); err != nil {
+		return models.Internal("failed to reset schedules: %w", err)
+	}
+
+	return nil
+}
+
+func scanDeckSuggestion(row interface{ Scan(...any) error }) (*models.DeckSuggestion, error) {
+	suggestion := &models.DeckSuggestion{}
+	var flashcardIDs, noteIDs pq.Int64Array
+	err := row.Scan(&suggestion.ID, &suggestion.UserID, &suggestion.DeckID, &suggestion.Kind, &flashcardIDs, &noteIDs,
+		&suggestion.Status, &suggestion.CreatedAt, &suggestion.ResolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	suggestion.FlashcardIDs, suggestion.NoteIDs = intSlice(flashcardIDs), intSlice(noteIDs)
+	return suggestion, nil
+}
+
+func intSlice(values pq.Int64Array) []int {
+	ints := make([]int, len(values))
+	for i, value := range values {
+		ints[i] = int(value)
+	}
+	return ints
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"flashcards/models"
+)
+
+type EmbedRepository interface {
+	CreateEmbed(ctx context.Context, embed *models.Embed, tokenHash string) error
+	GetEmbedByTokenHash(ctx context.Context, tokenHash string) (*models.Embed, error)
+	ListEmbeds(ctx context.Context, userID int) ([]*models.Embed, error)
+	DeleteEmbed(ctx context.Context, userID, id int) error
+}
+
+type PostgresEmbedRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresEmbedRepository(db *sql.DB) *PostgresEmbedRepository {
+	return &PostgresEmbedRepository{db: db}
+}
+
+func (r *PostgresEmbedRepository) CreateEmbed(ctx context.Context, embed *models.Embed, tokenHash string) error {
+	query := `
+		INSERT INTO gocourse.embeds (user_id, flashcard_id, deck_id, tokenHash) 
+		VALUES ($1, $2, $3, $4) 
+		RETURNING id, createdAt`
+
+	row := r.db.QueryRowContext(ctx, query, embed.UserID, embed.FlashcardID, embed.DeckID, tokenHash)
+	if err := row.Scan(&embed.ID, &embed.CreatedAt); err != nil {
+		return models.Internal("failed to create embed: %w", err)
+	}
+	embed.Kind = embedKind(embed)
+
+	return nil
+}
+
+func (r *PostgresEmbedRepository) GetEmbedByTokenHash(ctx context.Context, tokenHash string) (*models.Embed, error) {
+	query := `
+		SELECT id, user_id, flashcard_id, deck_id, createdAt 
+		FROM gocourse.embeds 
+		WHERE tokenHash = $1`
+
+	embed := &models.Embed{}
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(&embed.ID, &embed.UserID, &embed.FlashcardID, &embed.DeckID, &embed.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("embed not found")
+		}
+		return nil, models.Internal("failed to get embed: %w", err)
+	}
+	embed.Kind = embedKind(embed)
+
+	return embed, nil
+}
+
+func (r *PostgresEmbedRepository) ListEmbeds(ctx context.Context, userID int) ([]*models.Embed, error) {
+	query := `
+		SELECT id, user_id, flashcard_id, deck_id, createdAt 
+		FROM gocourse.embeds 
+		WHERE user_id = $1 
+		ORDER BY createdAt DESC, id DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, models.Internal("failed to list embeds: %w", err)
+	}
+	defer rows.Close()
+
+	embeds := []*models.Embed{}
+	for rows.Next() {
+		embed := &models.Embed{}
+		if err := rows.Scan(&embed.ID, &embed.UserID, &embed.FlashcardID, &embed.DeckID, &embed.CreatedAt); err != nil {
+			return nil, models.Internal("failed to scan embed: %w", err)
+		}
+		embed.Kind = embedKind(embed)
+		embeds = append(embeds, embed)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over embeds: %w", err)
+	}
+
+	return embeds, nil
+}
+
+func (r *PostgresEmbedRepository) DeleteEmbed(ctx context.Context, userID, id int) error {
+	query := "DELETE FROM gocourse.embeds WHERE id = $1 AND user_id = $2"
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return models.Internal("failed to delete embed: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("embed with id %d not found", id)
+	}
+
+	return nil
+}
+
+func embedKind(embed *models.Embed) string {
+	if embed.DeckID != nil {
+		return models.EmbedKindDeck
+	}
+	return models.EmbedKindFlashcard
+}
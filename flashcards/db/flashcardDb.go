@@ -0,0 +1,295 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+type FlashcardRepository interface {
+	CreateFlashcard(ctx context.Context, flashcard *models.Flashcard) error
+	CreateFlashcards(ctx context.Context, flashcards []*models.Flashcard) error
+	GetFlashcardByID(ctx context.Context, userID, id int) (*models.Flashcard, error)
+	ListFlashcards(ctx context.Context, userID int, filter models.FlashcardFilter, params models.ListParams) ([]*models.Flashcard, int, error)
+	GetFlashcardsByDecks(ctx context.Context, userID int, deckIDs []int) ([]*models.Flashcard, error)
+	GetFlashcardIDsByContentHash(ctx context.Context, userID int, hashes []string) (map[string]int, error)
+	UpdateFlashcard(ctx context.Context, userID, id, version int, updates map[string]any) error
+	DeleteFlashcard(ctx context.Context, userID, id int) error
+}
+
+// Columns read into a flashcard by flashcardScanArgs, from the flashcards
+// table aliased as f
+const flashcardColumns = "f.id, f.user_id, f.deck_id, f.front, f.back, f.hint, f.mnemonic, f.source_note_id, f.content, f.version, f.createdAt, f.updatedAt"
+
+func flashcardScanArgs(flashcard *models.Flashcard) []any {
+	return []any{&flashcard.ID, &flashcard.UserID, &flashcard.DeckID, &flashcard.Front, &flashcard.Back, &flashcard.Hint, &flashcard.Mnemonic,
+		&flashcard.SourceNoteID, &flashcard.Content, &flashcard.Version, &flashcard.CreatedAt, &flashcard.UpdatedAt}
+}
+
+// syncFlashcardSides fills in the sides of a flashcard given only content,
+// and the content of one given sides
+func syncFlashcardSides(flashcard *models.Flashcard) {
+	if flashcard.Front == "" {
+		flashcard.Front, flashcard.Back = models.SplitFlashcardContent(flashcard.Content)
+	}
+	flashcard.Content = models.FlashcardContent(flashcard.Front, flashcard.Back)
+}
+
+const createFlashcardQuery = `
+		INSERT INTO gocourse.flashcards (user_id, deck_id, front, back, hint, source_note_id, content) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7) 
+		RETURNING id, version, createdAt, updatedAt`
+
+func createFlashcardArgs(flashcard *models.Flashcard) []any {
+	return []any{flashcard.UserID, flashcard.DeckID, flashcard.Front, flashcard.Back, flashcard.Hint, flashcard.SourceNoteID, flashcard.Content}
+}
+
+type PostgresFlashcardRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresFlashcardRepository(db *sql.DB) *PostgresFlashcardRepository {
+	return &PostgresFlashcardRepository{db: db}
+}
+
+func (r *PostgresFlashcardRepository) CreateFlashcard(ctx context.Context, flashcard *models.Flashcard) error {
+	syncFlashcardSides(flashcard)
+	row := executor(ctx, r.db).QueryRowContext(ctx, createFlashcardQuery, createFlashcardArgs(flashcard)...)
+
+	err := row.Scan(&flashcard.ID, &flashcard.Version, &flashcard.CreatedAt, &flashcard.UpdatedAt)
+	if err != nil {
+		return models.Internal("failed to create flashcard: %w", err)
+	}
+
+	return nil
+}
+
+// CreateFlashcards inserts a batch of flashcards in a single transaction
+func (r *PostgresFlashcardRepository) CreateFlashcards(ctx context.Context, flashcards []*models.Flashcard) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, flashcard := range flashcards {
+		syncFlashcardSides(flashcard)
+		row := tx.QueryRowContext(ctx, createFlashcardQuery, createFlashcardArgs(flashcard)...)
+		if err := row.Scan(&flashcard.ID, &flashcard.Version, &flashcard.CreatedAt, &flashcard.UpdatedAt); err != nil {
+			return models.Internal("failed to create flashcard: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit flashcards: %w", err)
+	}
+
+	return nil
+}
+
+// Warm prepares the single card read
+func (r *PostgresFlashcardRepository) Warm(ctx context.Context) error {
+	return warmPool(ctx, r.db, "SELECT "+flashcardColumns+" FROM gocourse.flashcards f WHERE f.id = $1 AND f.user_id = $2")
+}
+
+func (r *PostgresFlashcardRepository) GetFlashcardByID(ctx context.Context, userID, id int) (*models.Flashcard, error) {
+	query := `
+		SELECT ` + flashcardColumns + ` 
+		FROM gocourse.flashcards f 
+		WHERE f.id = $1 AND f.user_id = $2`
+
+	flashcard := &models.Flashcard{}
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, id, userID)
+
+	err := row.Scan(flashcardScanArgs(flashcard)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("flashcard with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get flashcard: %w", err)
+	}
+
+	return flashcard, nil
+}
+
+// ListFlashcards returns one page of the user's flashcards matching the
+// filter, along with the total number of matching flashcards
+func (r *PostgresFlashcardRepository) ListFlashcards(ctx context.Context, userID int, filter models.FlashcardFilter, params models.ListParams) ([]*models.Flashcard, int, error) {
+	where := " WHERE f.user_id = $1"
+	args := []any{userID}
+
+	if filter.DeckID != nil {
+		args = append(args, *filter.DeckID)
+		where += fmt.Sprintf(" AND f.deck_id = $%d", len(args))
+	}
+
+	if filter.Query != "" {
+		args = append(args, likePattern(filter.Query))
+		where += fmt.Sprintf(" AND f.content ILIKE $%d", len(args))
+	}
+
+	var total int
+	if err := executor(ctx, r.db).QueryRowContext(ctx, "SELECT COUNT(*) FROM gocourse.flashcards f"+where, args...).Scan(&total); err != nil {
+		return nil, 0, models.Internal("failed to count flashcards: %w", err)
+	}
+
+	orderBy, err := orderByClause("f", params)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT ` + flashcardColumns + ` 
+		FROM gocourse.flashcards f` + where + orderBy +
+		fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, 0, models.Internal("failed to query flashcards: %w", err)
+	}
+	defer rows.Close()
+
+	flashcards := make([]*models.Flashcard, 0)
+	for rows.Next() {
+		flashcard := &models.Flashcard{}
+		err := rows.Scan(flashcardScanArgs(flashcard)...)
+		if err != nil {
+			return nil, 0, models.Internal("failed to scan flashcard: %w", err)
+		}
+		flashcards = append(flashcards, flashcard)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating over flashcards: %w", err)
+	}
+
+	return flashcards, total, nil
+}
+
+// GetFlashcardsByDecks returns all of the user's flashcards in deckIDs,
+// oldest first
+func (r *PostgresFlashcardRepository) GetFlashcardsByDecks(ctx context.Context, userID int, deckIDs []int) ([]*models.Flashcard, error) {
+	query := `
+		SELECT ` + flashcardColumns + ` 
+		FROM gocourse.flashcards f 
+		WHERE f.user_id = $1 AND f.deck_id = ANY($2) 
+		ORDER BY f.id ASC`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, pq.Array(deckIDs))
+	if err != nil {
+		return nil, models.Internal("failed to query flashcards: %w", err)
+	}
+	defer rows.Close()
+
+	flashcards := make([]*models.Flashcard, 0)
+	for rows.Next() {
+		flashcard := &models.Flashcard{}
+		err := rows.Scan(flashcardScanArgs(flashcard)...)
+		if err != nil {
+			return nil, models.Internal("failed to scan flashcard: %w", err)
+		}
+		flashcards = append(flashcards, flashcard)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over flashcards: %w", err)
+	}
+
+	return flashcards, nil
+}
+
+// GetFlashcardIDsByContentHash maps each hash that matches one of the
+// user's flashcards to that flashcard's ID
+func (r *PostgresFlashcardRepository) GetFlashcardIDsByContentHash(ctx context.Context, userID int, hashes []string) (map[string]int, error) {
+	query := `
+		SELECT DISTINCT ON (contentHash) contentHash, id 
+		FROM gocourse.flashcards 
+		WHERE user_id = $1 AND contentHash = ANY($2) 
+		ORDER BY contentHash, id ASC`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, pq.Array(hashes))
+	if err != nil {
+		return nil, models.Internal("failed to query flashcards: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make(map[string]int)
+	for rows.Next() {
+		var hash string
+		var id int
+		if err := rows.Scan(&hash, &id); err != nil {
+			return nil, models.Internal("failed to scan flashcard: %w", err)
+		}
+		ids[hash] = id
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over flashcards: %w", err)
+	}
+
+	return ids, nil
+}
+
+// UpdateFlashcard applies the updates if the flashcard is still at version,
+// and bumps its version. A flashcard changed since is a conflict.
+func (r *PostgresFlashcardRepository) UpdateFlashcard(ctx context.Context, userID, id, version int, updates map[string]any) error {
+	if err := checkUpdates("flashcards", updates); err != nil {
+		return err
+	}
+
+	query := "UPDATE gocourse.flashcards SET "
+	args := []any{}
+	argIndex := 1
+
+	for field, value := range updates {
+		if argIndex > 1 {
+			query += ", "
+		}
+		query += fmt.Sprintf("%s = $%d", field, argIndex)
+		args = append(args, value)
+		argIndex++
+	}
+
+	query += fmt.Sprintf(", version = version + 1, updatedAt = NOW() WHERE id = $%d AND user_id = $%d AND version = $%d", argIndex, argIndex+1, argIndex+2)
+	args = append(args, id, userID, version)
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return models.Internal("failed to update flashcard: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return versionMismatch(ctx, executor(ctx, r.db), "flashcards", "flashcard", userID, id, version)
+	}
+
+	return nil
+}
+
+func (r *PostgresFlashcardRepository) DeleteFlashcard(ctx context.Context, userID, id int) error {
+	query := "DELETE FROM gocourse.flashcards WHERE id = $1 AND user_id = $2"
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return models.Internal("failed to delete flashcard: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("flashcard with id %d not found", id)
+	}
+
+	return nil
+}
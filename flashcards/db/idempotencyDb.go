@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+type IdempotencyRepository interface {
+	ReserveKey(ctx context.Context, record *models.IdempotencyRecord, staleBefore time.Time) (bool, error)
+	GetKey(ctx context.Context, userID int, key string) (*models.IdempotencyRecord, error)
+	CompleteKey(ctx context.Context, userID int, key string, statusCode int, body []byte) error
+	DeleteKey(ctx context.Context, userID int, key string) error
+	DeleteExpiredKeys(ctx context.Context) (int64, error)
+}
+
+type PostgresIdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresIdempotencyRepository(db *sql.DB) *PostgresIdempotencyRepository {
+	return &PostgresIdempotencyRepository{db: db}
+}
+
+// ReserveKey claims the key for a new request. It succeeds when the key is
+// unused, expired, or held by an in-progress request that started before
+// staleBefore and is assumed to have died; otherwise it returns false and
+// the existing record should be read with GetKey.
+func (r *PostgresIdempotencyRepository) ReserveKey(ctx context.Context, record *models.IdempotencyRecord, staleBefore time.Time) (bool, error) {
+	query := `
+		INSERT INTO gocourse.idempotency_keys (user_id, idempotencyKey, requestHash, expiresAt) 
+		VALUES ($1, $2, $3, $4) 
+		ON CONFLICT (user_id, idempotencyKey) DO UPDATE 
+		SET requestHash = EXCLUDED.requestHash, statusCode = NULL, responseBody = NULL, 
+			createdAt = NOW(), expiresAt = EXCLUDED.expiresAt 
+		WHERE gocourse.idempotency_keys.expiresAt < NOW() 
+			OR (gocourse.idempotency_keys.statusCode IS NULL AND gocourse.idempotency_keys.createdAt < $5) 
+		RETURNING createdAt`
+
+	err := r.db.QueryRowContext(ctx, query, record.UserID, record.Key, record.RequestHash, record.ExpiresAt, staleBefore).
+		Scan(&record.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, models.Internal("failed to reserve idempotency key: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *PostgresIdempotencyRepository) GetKey(ctx context.Context, userID int, key string) (*models.IdempotencyRecord, error) {
+	query := `
+		SELECT user_id, idempotencyKey, requestHash, statusCode, responseBody, createdAt, expiresAt 
+		FROM gocourse.idempotency_keys 
+		WHERE user_id = $1 AND idempotencyKey = $2`
+
+	record := &models.IdempotencyRecord{}
+	err := r.db.QueryRowContext(ctx, query, userID, key).Scan(&record.UserID, &record.Key, &record.RequestHash,
+		&record.StatusCode, &record.ResponseBody, &record.CreatedAt, &record.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("idempotency key %s not found", key)
+		}
+		return nil, models.Internal("failed to get idempotency key: %w", err)
+	}
+
+	return record, nil
+}
+
+// CompleteKey stores the response to replay for the key
+func (r *PostgresIdempotencyRepository) CompleteKey(ctx context.Context, userID int, key string, statusCode int, body []byte) error {
+	query := "UPDATE gocourse.idempotency_keys SET statusCode = $1, responseBody = $2 WHERE user_id = $3 AND idempotencyKey = $4"
+
+	if _, err := r.db.ExecContext(ctx, query, statusCode, body, userID, key); err != nil {
+		return models.Internal("failed to save idempotent response: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresIdempotencyRepository) DeleteKey(ctx context.Context, userID int, key string) error {
+	query := "DELETE FROM gocourse.idempotency_keys WHERE user_id = $1 AND idempotencyKey = $2"
+
+	if _, err := r.db.ExecContext(ctx, query, userID, key); err != nil {
+		return models.Internal("failed to delete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresIdempotencyRepository) DeleteExpiredKeys(ctx context.Context) (int64, error) {
+	query := "DELETE FROM gocourse.idempotency_keys WHERE expiresAt < NOW()"
+
+	result, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, models.Internal("failed to delete expired idempotency keys: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, models.Internal("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,354 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+// Tables that import job items are committed into, by job kind
+var importTargetTables = map[string]string{
+	models.ImportKindNotes: "gocourse.notes",
+}
+
+const importJobSelectQuery = `
+		SELECT id, user_id, kind, status, deck_id, chunkSize, totalItems, processedItems, committedChunks, 
+			skipped, error, createdAt, updatedAt, completedAt 
+		FROM gocourse.import_jobs`
+
+type ImportJobRepository interface {
+	CreateImportJob(ctx context.Context, job *models.ImportJob, items []models.ImportJobItem) error
+	GetImportJob(ctx context.Context, userID, id int) (*models.ImportJob, error)
+	ListImportJobs(ctx context.Context, userID int) ([]*models.ImportJob, error)
+	GetNextImportChunk(ctx context.Context, jobID int) (int, int, bool, error)
+	CommitImportChunk(ctx context.Context, jobID, chunk int) (int, error)
+	ResumeImportJob(ctx context.Context, userID, id int) error
+	FinishImportJob(ctx context.Context, id int, status string, message *string) error
+	FailStaleImportJobs(ctx context.Context, before time.Time, message string) ([]*models.ImportJob, error)
+}
+
+type PostgresImportJobRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresImportJobRepository(db *sql.DB) *PostgresImportJobRepository {
+	return &PostgresImportJobRepository{db: db}
+}
+
+// CreateImportJob stores the job together with all of its items in a single
+// transaction
+func (r *PostgresImportJobRepository) CreateImportJob(ctx context.Context, job *models.ImportJob, items []models.ImportJobItem) error {
+	skippedJSON, err := json.Marshal(job.Skipped)
+	if err != nil {
+		return models.Internal("failed to encode skipped items: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	jobQuery := `
+		INSERT INTO gocourse.import_jobs (user_id, kind, status, deck_id, chunkSize, totalItems, skipped) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7) 
+		RETURNING id, createdAt, updatedAt`
+
+	row := tx.QueryRowContext(ctx, jobQuery, job.UserID, job.Kind, job.Status, job.DeckID, job.ChunkSize, job.TotalItems, skippedJSON)
+	if err := row.Scan(&job.ID, &job.CreatedAt, &job.UpdatedAt); err != nil {
+		return models.Internal("failed to create import job: %w", err)
+	}
+
+	positions := make([]int64, len(items))
+	chunks := make([]int64, len(items))
+	files := make([]string, len(items))
+	headings := make([]string, len(items))
+	contents := make([]string, len(items))
+	for i, item := range items {
+		positions[i], chunks[i] = int64(item.Position), int64(item.Chunk)
+		files[i], headings[i], contents[i] = item.File, item.Heading, item.Content
+	}
+
+	itemQuery := `
+		INSERT INTO gocourse.import_job_items (job_id, position, chunk, file, heading, content) 
+		SELECT $1, * FROM unnest($2::int[], $3::int[], $4::text[], $5::text[], $6::text[])`
+
+	_, err = tx.ExecContext(ctx, itemQuery, job.ID, pq.Array(positions), pq.Array(chunks),
+		pq.Array(files), pq.Array(headings), pq.Array(contents))
+	if err != nil {
+		return models.Internal("failed to create import job items: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit import job: %w", err)
+	}
+
+	job.TotalChunks = totalImportChunks(job)
+	return nil
+}
+
+func (r *PostgresImportJobRepository) GetImportJob(ctx context.Context, userID, id int) (*models.ImportJob, error) {
+	query := importJobSelectQuery + `
+		WHERE id = $1 AND user_id = $2`
+
+	job, err := scanImportJob(r.db.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("import job with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get import job: %w", err)
+	}
+
+	return job, nil
+}
+
+func (r *PostgresImportJobRepository) ListImportJobs(ctx context.Context, userID int) ([]*models.ImportJob, error) {
+	query := importJobSelectQuery + `
+		WHERE user_id = $1 
+		ORDER BY createdAt DESC, id DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, models.Internal("failed to query import jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]*models.ImportJob, 0)
+	for rows.Next() {
+		job, err := scanImportJob(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan import job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over import jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// GetNextImportChunk returns the first chunk of the job with uncommitted
+// items and how many it has, or false once every chunk is committed
+func (r *PostgresImportJobRepository) GetNextImportChunk(ctx context.Context, jobID int) (int, int, bool, error) {
+	query := `
+		SELECT chunk, COUNT(*) 
+		FROM gocourse.import_job_items 
+		WHERE job_id = $1 AND NOT committed 
+		GROUP BY chunk 
+		ORDER BY chunk 
+		LIMIT 1`
+
+	var chunk, count int
+	if err := r.db.QueryRowContext(ctx, query, jobID).Scan(&chunk, &count); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, 0, false, nil
+		}
+		return 0, 0, false, models.Internal("failed to get next import chunk: %w", err)
+	}
+
+	return chunk, count, true, nil
+}
+
+// CommitImportChunk creates the records for the chunk's uncommitted items
+// and advances the job's progress in one transaction, so a chunk is either
+// wholly committed or not at all. The job row is locked first; a worker
+// that resumes a job while another still runs finds the chunk committed
+// and creates nothing. It returns the number of records created.
+func (r *PostgresImportJobRepository) CommitImportChunk(ctx context.Context, jobID, chunk int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID int
+	var deckID *int
+	var kind, status string
+	jobQuery := "SELECT user_id, deck_id, kind, status FROM gocourse.import_jobs WHERE id = $1 FOR UPDATE"
+	if err := tx.QueryRowContext(ctx, jobQuery, jobID).Scan(&userID, &deckID, &kind, &status); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, models.NotFound("import job with id %d not found", jobID)
+		}
+		return 0, models.Internal("failed to lock import job: %w", err)
+	}
+	if status != models.ImportJobStatusRunning {
+		return 0, fmt.Errorf("import job %d is no longer running", jobID)
+	}
+
+	table, ok := importTargetTables[kind]
+	if !ok {
+		return 0, fmt.Errorf("unsupported import kind: %s", kind)
+	}
+
+	itemsQuery := `
+		SELECT position, content 
+		FROM gocourse.import_job_items 
+		WHERE job_id = $1 AND chunk = $2 AND NOT committed 
+		ORDER BY position`
+
+	rows, err := tx.QueryContext(ctx, itemsQuery, jobID, chunk)
+	if err != nil {
+		return 0, models.Internal("failed to query import job items: %w", err)
+	}
+	var items []models.ImportJobItem
+	for rows.Next() {
+		var item models.ImportJobItem
+		if err := rows.Scan(&item.Position, &item.Content); err != nil {
+			rows.Close()
+			return 0, models.Internal("failed to scan import job item: %w", err)
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating over import job items: %w", err)
+	}
+
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	insertQuery := fmt.Sprintf(`
+		INSERT INTO %s (user_id, deck_id, content) 
+		VALUES ($1, $2, $3) 
+		RETURNING id`, table)
+	commitQuery := `
+		UPDATE gocourse.import_job_items 
+		SET committed = TRUE, recordId = $3 
+		WHERE job_id = $1 AND position = $2`
+
+	for _, item := range items {
+		var recordID int
+		if err := tx.QueryRowContext(ctx, insertQuery, userID, deckID, item.Content).Scan(&recordID); err != nil {
+			return 0, models.Internal("failed to import item %d: %w", item.Position, err)
+		}
+		if _, err := tx.ExecContext(ctx, commitQuery, jobID, item.Position, recordID); err != nil {
+			return 0, models.Internal("failed to commit import job item: %w", err)
+		}
+	}
+
+	progressQuery := `
+		UPDATE gocourse.import_jobs 
+		SET processedItems = processedItems + $2, committedChunks = committedChunks + 1, updatedAt = NOW() 
+		WHERE id = $1`
+
+	if _, err := tx.ExecContext(ctx, progressQuery, jobID, len(items)); err != nil {
+		return 0, models.Internal("failed to update import job progress: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, models.Internal("failed to commit import chunk: %w", err)
+	}
+
+	return len(items), nil
+}
+
+// ResumeImportJob moves a failed job back to running. Only one caller can
+// resume a job.
+func (r *PostgresImportJobRepository) ResumeImportJob(ctx context.Context, userID, id int) error {
+	query := `
+		UPDATE gocourse.import_jobs 
+		SET status = $3, error = NULL, updatedAt = NOW() 
+		WHERE id = $1 AND user_id = $2 AND status = $4`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID, models.ImportJobStatusRunning, models.ImportJobStatusFailed)
+	if err != nil {
+		return models.Internal("failed to resume import job: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		if _, err := r.GetImportJob(ctx, userID, id); err != nil {
+			return err
+		}
+		return models.Conflict("only a failed import job can be resumed")
+	}
+
+	return nil
+}
+
+// FinishImportJob completes or fails a running job. A job that is no
+// longer running, such as one already failed as stale, keeps its status.
+func (r *PostgresImportJobRepository) FinishImportJob(ctx context.Context, id int, status string, message *string) error {
+	query := `
+		UPDATE gocourse.import_jobs 
+		SET status = $2, error = $3, updatedAt = NOW(), 
+			completedAt = CASE WHEN $4 THEN NOW() ELSE completedAt END 
+		WHERE id = $1 AND status = $5`
+
+	_, err := r.db.ExecContext(ctx, query, id, status, message, status == models.ImportJobStatusCompleted, models.ImportJobStatusRunning)
+	if err != nil {
+		return models.Internal("failed to finish import job: %w", err)
+	}
+
+	return nil
+}
+
+// FailStaleImportJobs fails running jobs that have made no progress since
+// before, such as those whose server stopped mid-import, so they can be
+// resumed. It returns the jobs it failed.
+func (r *PostgresImportJobRepository) FailStaleImportJobs(ctx context.Context, before time.Time, message string) ([]*models.ImportJob, error) {
+	query := `
+		UPDATE gocourse.import_jobs 
+		SET status = $1, error = $2, updatedAt = NOW() 
+		WHERE status = $3 AND updatedAt < $4 
+		RETURNING id, user_id, kind, status, deck_id, chunkSize, totalItems, processedItems, committedChunks, 
+			skipped, error, createdAt, updatedAt, completedAt`
+
+	rows, err := r.db.QueryContext(ctx, query, models.ImportJobStatusFailed, message, models.ImportJobStatusRunning, before)
+	if err != nil {
+		return nil, models.Internal("failed to fail stale import jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]*models.ImportJob, 0)
+	for rows.Next() {
+		job, err := scanImportJob(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan import job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over import jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+func scanImportJob(row interface{ Scan(...any) error }) (*models.ImportJob, error) {
+	job := &models.ImportJob{}
+	var skippedJSON []byte
+	err := row.Scan(&job.ID, &job.UserID, &job.Kind, &job.Status, &job.DeckID, &job.ChunkSize, &job.TotalItems,
+		&job.ProcessedItems, &job.CommittedChunks, &skippedJSON, &job.Error, &job.CreatedAt, &job.UpdatedAt, &job.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(skippedJSON, &job.Skipped); err != nil {
+		return nil, models.Internal("failed to decode skipped items: %w", err)
+	}
+	job.TotalChunks = totalImportChunks(job)
+
+	return job, nil
+}
+
+func totalImportChunks(job *models.ImportJob) int {
+	if job.ChunkSize <= 0 {
+		return 0
+	}
+	return (job.TotalItems + job.ChunkSize - 1) / job.ChunkSize
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"flashcards/models"
+)
+
+type InviteRepository interface {
+	CreateInvite(ctx context.Context, invite *models.OrganizationInvite, tokenHash string) error
+	GetInviteByTokenHash(ctx context.Context, tokenHash string) (*models.OrganizationInvite, error)
+	AcceptInvite(ctx context.Context, invite *models.OrganizationInvite, userID int) error
+}
+
+type PostgresInviteRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresInviteRepository(db *sql.DB) *PostgresInviteRepository {
+	return &PostgresInviteRepository{db: db}
+}
+
+func (r *PostgresInviteRepository) CreateInvite(ctx context.Context, invite *models.OrganizationInvite, tokenHash string) error {
+	query := `
+		INSERT INTO gocourse.organization_invites (organization_id, email, role, tokenHash, invited_by, expiresAt) 
+		VALUES ($1, $2, $3, $4, $5, $6) 
+		RETURNING id, createdAt`
+
+	row := r.db.QueryRowContext(ctx, query, invite.OrganizationID, invite.Email, invite.Role, tokenHash,
+		invite.InvitedBy, invite.ExpiresAt)
+	if err := row.Scan(&invite.ID, &invite.CreatedAt); err != nil {
+		return models.Internal("failed to create invite: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresInviteRepository) GetInviteByTokenHash(ctx context.Context, tokenHash string) (*models.OrganizationInvite, error) {
+	query := `
+		SELECT id, organization_id, email, role, invited_by, accepted_by, expiresAt, acceptedAt, createdAt 
+		FROM gocourse.organization_invites 
+		WHERE tokenHash = $1`
+
+	invite := &models.OrganizationInvite{}
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(&invite.ID, &invite.OrganizationID, &invite.Email, &invite.Role,
+		&invite.InvitedBy, &invite.AcceptedBy, &invite.ExpiresAt, &invite.AcceptedAt, &invite.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("invite not found")
+		}
+		return nil, models.Internal("failed to get invite: %w", err)
+	}
+
+	return invite, nil
+}
+
+// AcceptInvite marks the invite accepted and adds the user to its
+// organization in a single transaction. It fails when the invite was
+// already used or the user already belongs to an organization.
+func (r *PostgresInviteRepository) AcceptInvite(ctx context.Context, invite *models.OrganizationInvite, userID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	inviteQuery := `
+		UPDATE gocourse.organization_invites 
+		SET acceptedAt = NOW(), accepted_by = $1 
+		WHERE id = $2 AND acceptedAt IS NULL 
+		RETURNING acceptedAt`
+
+	err = tx.QueryRowContext(ctx, inviteQuery, userID, invite.ID).Scan(&invite.AcceptedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Conflict("invite has already been accepted")
+		}
+		return models.Internal("failed to accept invite: %w", err)
+	}
+	invite.AcceptedBy = &userID
+
+	memberQuery := `
+		UPDATE gocourse.users 
+		SET organization_id = $1, organizationRole = $2, updatedAt = NOW() 
+		WHERE id = $3 AND organization_id IS NULL`
+
+	result, err := tx.ExecContext(ctx, memberQuery, invite.OrganizationID, invite.Role, userID)
+	if err != nil {
+		return models.Internal("failed to add organization member: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.Conflict("you already belong to an organization")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit invite: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+const jobColumns = `id, user_id, kind, payload, status, result, error, attempts, createdAt, updatedAt, startedAt, completedAt`
+
+type JobRepository interface {
+	CreateJob(ctx context.Context, job *models.Job) error
+	GetJob(ctx context.Context, userID, id int) (*models.Job, error)
+	ListJobs(ctx context.Context, userID int, filter models.JobFilter, params models.ListParams) ([]*models.Job, int, error)
+	ClaimNextJob(ctx context.Context, lease time.Duration) (*models.Job, error)
+	ExtendJobLease(ctx context.Context, id int, lease time.Duration) error
+	CompleteJob(ctx context.Context, id int, result []byte) error
+	FailJobAttempt(ctx context.Context, id int, message string, nextAttemptAt *time.Time) error
+	ReleaseJob(ctx context.Context, id int) error
+}
+
+type PostgresJobRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresJobRepository(db *sql.DB) *PostgresJobRepository {
+	return &PostgresJobRepository{db: db}
+}
+
+func (r *PostgresJobRepository) CreateJob(ctx context.Context, job *models.Job) error {
+	query := `
+		INSERT INTO gocourse.jobs (user_id, kind, payload, status) 
+		VALUES ($1, $2, $3, $4) 
+		RETURNING ` + jobColumns
+
+	created, err := scanJob(r.db.QueryRowContext(ctx, query, job.UserID, job.Kind, []byte(job.Payload), models.JobStatusQueued))
+	if err != nil {
+		return models.Internal("failed to create job: %w", err)
+	}
+
+	*job = *created
+	return nil
+}
+
+func (r *PostgresJobRepository) GetJob(ctx context.Context, userID, id int) (*models.Job, error) {
+	query := `SELECT ` + jobColumns + ` FROM gocourse.jobs WHERE id = $1 AND user_id = $2`
+
+	job, err := scanJob(r.db.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("job with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get job: %w", err)
+	}
+
+	return job, nil
+}
+
+// ListJobs returns one page of the user's jobs matching the filter and the
+// total number that match
+func (r *PostgresJobRepository) ListJobs(ctx context.Context, userID int, filter models.JobFilter, params models.ListParams) ([]*models.Job, int, error) {
+	where := ` WHERE j.user_id = $1 AND ($2 = '' OR j.status = $2) AND ($3 = '' OR j.kind = $3)`
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM gocourse.jobs j` + where
+	if err := r.db.QueryRowContext(ctx, countQuery, userID, filter.Status, filter.Kind).Scan(&total); err != nil {
+		return nil, 0, models.Internal("failed to count jobs: %w", err)
+	}
+
+	orderBy, err := orderByClause("j", params)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := `SELECT ` + jobColumns + ` FROM gocourse.jobs j` + where + orderBy + ` LIMIT $4 OFFSET $5`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, filter.Status, filter.Kind, params.Limit, params.Offset)
+	if err != nil {
+		return nil, 0, models.Internal("failed to query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]*models.Job, 0)
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, 0, models.Internal("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating over jobs: %w", err)
+	}
+
+	return jobs, total, nil
+}
+
+// ClaimNextJob marks the oldest due job running and holds it for lease, so
+// other workers skip it until the lease runs out. It returns nil when no
+// job is due. A running job is only due again when its worker stopped
+// extending the lease.
+func (r *PostgresJobRepository) ClaimNextJob(ctx context.Context, lease time.Duration) (*models.Job, error) {
+	query := `
+		UPDATE gocourse.jobs 
+		SET status = $2, nextAttemptAt = NOW() + make_interval(secs => $1), 
+			startedAt = COALESCE(startedAt, NOW()), updatedAt = NOW() 
+		WHERE id = ( 
+			SELECT id FROM gocourse.jobs 
+			WHERE status IN ($2, $3) AND nextAttemptAt <= NOW() 
+			ORDER BY nextAttemptAt, id 
+			LIMIT 1 
+			FOR UPDATE SKIP LOCKED 
+		) 
+		RETURNING ` + jobColumns
+
+	job, err := scanJob(r.db.QueryRowContext(ctx, query, lease.Seconds(), models.JobStatusRunning, models.JobStatusQueued))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, models.Internal("failed to claim job: %w", err)
+	}
+
+	return job, nil
+}
+
+func (r *PostgresJobRepository) ExtendJobLease(ctx context.Context, id int, lease time.Duration) error {
+	query := `
+		UPDATE gocourse.jobs 
+		SET nextAttemptAt = NOW() + make_interval(secs => $2), updatedAt = NOW() 
+		WHERE id = $1 AND status = $3`
+
+	if _, err := r.db.ExecContext(ctx, query, id, lease.Seconds(), models.JobStatusRunning); err != nil {
+		return models.Internal("failed to extend job lease: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresJobRepository) CompleteJob(ctx context.Context, id int, result []byte) error {
+	query := `
+		UPDATE gocourse.jobs 
+		SET status = $2, result = $3, error = '', attempts = attempts + 1, nextAttemptAt = NULL, 
+			completedAt = NOW(), updatedAt = NOW() 
+		WHERE id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, id, models.JobStatusCompleted, result); err != nil {
+		return models.Internal("failed to complete job: %w", err)
+	}
+
+	return nil
+}
+
+// FailJobAttempt counts a failed attempt and queues the job again at
+// nextAttemptAt. Without a next attempt the job has failed for good.
+func (r *PostgresJobRepository) FailJobAttempt(ctx context.Context, id int, message string, nextAttemptAt *time.Time) error {
+	query := `
+		UPDATE gocourse.jobs 
+		SET attempts = attempts + 1, error = $2, nextAttemptAt = $3, 
+			status = CASE WHEN $3::TIMESTAMP IS NULL THEN $4 ELSE $5 END, 
+			completedAt = CASE WHEN $3::TIMESTAMP IS NULL THEN NOW() END, updatedAt = NOW() 
+		WHERE id = $1`
+
+	_, err := r.db.ExecContext(ctx, query, id, message, nextAttemptAt, models.JobStatusFailed, models.JobStatusQueued)
+	if err != nil {
+		return models.Internal("failed to update job: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseJob queues a running job again straight away without counting an
+// attempt, for a worker that is shutting down
+func (r *PostgresJobRepository) ReleaseJob(ctx context.Context, id int) error {
+	query := `
+		UPDATE gocourse.jobs 
+		SET status = $2, nextAttemptAt = NOW(), updatedAt = NOW() 
+		WHERE id = $1 AND status = $3`
+
+	if _, err := r.db.ExecContext(ctx, query, id, models.JobStatusQueued, models.JobStatusRunning); err != nil {
+		return models.Internal("failed to release job: %w", err)
+	}
+
+	return nil
+}
+
+func scanJob(row interface{ Scan(...any) error }) (*models.Job, error) {
+	job := &models.Job{}
+	var payload, result []byte
+	err := row.Scan(&job.ID, &job.UserID, &job.Kind, &payload, &job.Status, &result, &job.Error, &job.Attempts,
+		&job.CreatedAt, &job.UpdatedAt, &job.StartedAt, &job.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Payload = payload
+	if result != nil {
+		job.Result = result
+	}
+	return job, nil
+}
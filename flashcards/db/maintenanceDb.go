@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"flashcards/models"
+
+	_ "github.com/lib/pq"
+)
+
+type MaintenanceRepository interface {
+	GetMaintenance(ctx context.Context) (*models.Maintenance, error)
+	SaveMaintenance(ctx context.Context, maintenance *models.Maintenance) error
+}
+
+type PostgresMaintenanceRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresMaintenanceRepository(db *sql.DB) *PostgresMaintenanceRepository {
+	return &PostgresMaintenanceRepository{db: db}
+}
+
+func (r *PostgresMaintenanceRepository) GetMaintenance(ctx context.Context) (*models.Maintenance, error) {
+	query := `
+		SELECT mode, message, startedAt, endsAt, updatedAt 
+		FROM gocourse.maintenance 
+		WHERE id = 1`
+
+	maintenance := &models.Maintenance{}
+	row := r.db.QueryRowContext(ctx, query)
+
+	err := row.Scan(&maintenance.Mode, &maintenance.Message, &maintenance.StartedAt, &maintenance.EndsAt, &maintenance.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &models.Maintenance{Mode: models.MaintenanceModeOff}, nil
+		}
+		return nil, models.Internal("failed to get maintenance: %w", err)
+	}
+
+	return maintenance, nil
+}
+
+func (r *PostgresMaintenanceRepository) SaveMaintenance(ctx context.Context, maintenance *models.Maintenance) error {
+	query := `
+		INSERT INTO gocourse.maintenance (id, mode, message, startedAt, endsAt, updatedAt) 
+		VALUES (1, $1, $2, $3, $4, NOW()) 
+		ON CONFLICT (id) DO UPDATE 
+		SET mode = EXCLUDED.mode, message = EXCLUDED.message, startedAt = EXCLUDED.startedAt, 
+			endsAt = EXCLUDED.endsAt, updatedAt = NOW() 
+		RETURNING updatedAt`
+
+	row := r.db.QueryRowContext(ctx, query, maintenance.Mode, maintenance.Message, maintenance.StartedAt, maintenance.EndsAt)
+	if err := row.Scan(&maintenance.UpdatedAt); err != nil {
+		return models.Internal("failed to save maintenance: %w", err)
+	}
+
+	return nil
+}
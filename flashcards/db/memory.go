@@ -0,0 +1,342 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"flashcards/models"
+)
+
+// MemoryStore holds the data of the in-memory repositories, so services and
+// handlers can be tested without a database. IDs count up from 1 per table
+// in creation order, and timestamps come from the clock the store is
+// created with, so results are deterministic. Repositories built on one
+// store see each other's data, and deletes cascade as they do in Postgres.
+type MemoryStore struct {
+	// Held by a repository for each call, and by a MemoryUnitOfWork for its
+	// whole function
+	mu  sync.Mutex
+	now func() time.Time
+
+	data memoryData
+}
+
+// memoryData is the store's tables. Rows are held by value and slices in
+// them are replaced rather than changed, so a copy of the maps is a
+// snapshot a unit of work can restore.
+type memoryData struct {
+	nextIDs       map[string]int
+	users         map[int]models.User
+	decks         map[int]models.Deck
+	terminology   map[int]models.DeckTerminology
+	studySettings map[int]models.DeckStudySettings
+	notes         map[int]models.Note
+	tags          map[string]models.Tag
+	flashcards    map[int]models.Flashcard
+	todos         map[int]models.Todo
+	jobRuns       map[string]time.Time
+	organizations map[int]models.Organization
+	stripeEvents  map[string]bool
+	// Keyed by user ID
+	memberships map[int]models.Membership
+	// LLM tokens per user, by month and by hour
+	llmTokens       map[memoryTokenMonth]int
+	llmHourlyTokens map[memoryTokenHour]int
+	// Keyed by flashcard ID
+	schedules        map[int]models.Schedule
+	reviews          map[int]models.Review
+	quizSessions     map[int]models.QuizSession
+	sessionQuestions map[memorySessionQuestionKey]memorySessionQuestion
+	queuedSessions   map[int]models.QueuedQuizSession
+	importJobs       map[int]models.ImportJob
+	// Keyed by job ID
+	importItems map[int][]memoryImportItem
+	invites     map[int]memoryInvite
+	// Settings rows, nil until saved
+	branding      *models.Branding
+	contentFilter *models.ContentFilterSettings
+	maintenance   *models.Maintenance
+
+	promptTemplates    map[memoryPromptKey]models.PromptTemplate
+	routingRules       map[int]models.RoutingRule
+	questionEmbeddings map[int]models.QuestionEmbedding
+	requestStats       map[memoryStatsKey]models.RequestStats
+	// Keyed by flashcard ID
+	vocabulary map[int]memoryVocabulary
+
+	embeds            map[int]memoryEmbed
+	reportRecipients  map[int]models.ReportRecipient
+	dailyQuestions    map[int]models.DailyQuestionSubscription
+	idempotencyKeys   map[memoryIdempotencyKey]models.IdempotencyRecord
+	deckExports       map[int]models.StoredDeckExport
+	deckSuggestions   map[int]models.DeckSuggestion
+	llmCalls          map[int64]models.LLMCall
+	spendAnomalies    map[int]models.SpendAnomaly
+	attachments       map[int]models.Attachment
+	attachmentBlobs   map[string]memoryBlob
+	apiKeys           map[int]models.APIKey
+	apiKeyUsage       map[memoryAPIKeyDay]int64
+	deadLetters       map[int]models.DeadLetter
+	jobs              map[int]memoryJob
+	webhooks          map[int]models.Webhook
+	webhookDeliveries map[int]models.WebhookDelivery
+	similarityFlags   map[int]models.SimilarityFlag
+	// Keyed by deck ID
+	publishedDecks map[int]models.PublishedDeck
+	// Keyed by flashcard ID
+	cardEmbeddings map[int]models.CardEmbedding
+}
+
+type memoryTokenMonth struct {
+	userID int
+	month  time.Time
+}
+
+type memoryTokenHour struct {
+	userID int
+	hour   time.Time
+}
+
+// NewMemoryStore creates an empty store reading the time from now, or from
+// time.Now when now is nil
+func NewMemoryStore(now func() time.Time) *MemoryStore {
+	if now == nil {
+		now = time.Now
+	}
+
+	return &MemoryStore{
+		now: now,
+		data: memoryData{
+			nextIDs:       make(map[string]int),
+			users:         make(map[int]models.User),
+			decks:         make(map[int]models.Deck),
+			terminology:   make(map[int]models.DeckTerminology),
+			studySettings: make(map[int]models.DeckStudySettings),
+			notes:         make(map[int]models.Note),
+			tags:          make(map[string]models.Tag),
+			flashcards:    make(map[int]models.Flashcard),
+			todos:         make(map[int]models.Todo),
+			jobRuns:       make(map[string]time.Time),
+			organizations: make(map[int]models.Organization),
+			stripeEvents:  make(map[string]bool),
+			memberships:   make(map[int]models.Membership),
+			llmTokens:     make(map[memoryTokenMonth]int),
+
+			schedules:        make(map[int]models.Schedule),
+			reviews:          make(map[int]models.Review),
+			quizSessions:     make(map[int]models.QuizSession),
+			sessionQuestions: make(map[memorySessionQuestionKey]memorySessionQuestion),
+			queuedSessions:   make(map[int]models.QueuedQuizSession),
+			importJobs:       make(map[int]models.ImportJob),
+			importItems:      make(map[int][]memoryImportItem),
+			invites:          make(map[int]memoryInvite),
+
+			promptTemplates:    make(map[memoryPromptKey]models.PromptTemplate),
+			routingRules:       make(map[int]models.RoutingRule),
+			questionEmbeddings: make(map[int]models.QuestionEmbedding),
+			requestStats:       make(map[memoryStatsKey]models.RequestStats),
+			llmHourlyTokens:    make(map[memoryTokenHour]int),
+
+			embeds:            make(map[int]memoryEmbed),
+			reportRecipients:  make(map[int]models.ReportRecipient),
+			dailyQuestions:    make(map[int]models.DailyQuestionSubscription),
+			idempotencyKeys:   make(map[memoryIdempotencyKey]models.IdempotencyRecord),
+			deckExports:       make(map[int]models.StoredDeckExport),
+			vocabulary:        make(map[int]memoryVocabulary),
+			deckSuggestions:   make(map[int]models.DeckSuggestion),
+			llmCalls:          make(map[int64]models.LLMCall),
+			spendAnomalies:    make(map[int]models.SpendAnomaly),
+			attachments:       make(map[int]models.Attachment),
+			attachmentBlobs:   make(map[string]memoryBlob),
+			apiKeys:           make(map[int]models.APIKey),
+			apiKeyUsage:       make(map[memoryAPIKeyDay]int64),
+			deadLetters:       make(map[int]models.DeadLetter),
+			jobs:              make(map[int]memoryJob),
+			webhooks:          make(map[int]models.Webhook),
+			webhookDeliveries: make(map[int]models.WebhookDelivery),
+			similarityFlags:   make(map[int]models.SimilarityFlag),
+			publishedDecks:    make(map[int]models.PublishedDeck),
+			cardEmbeddings:    make(map[int]models.CardEmbedding),
+		},
+	}
+}
+
+// lock takes mu for a repository call and returns the function that
+// releases it. Inside a unit of work, which already holds mu, it does
+// nothing.
+func (s *MemoryStore) lock(ctx context.Context) func() {
+	if ctx.Value(memoryTxKey{}) == s {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// nextID returns the next ID of table. Callers hold mu.
+func (s *MemoryStore) nextID(table string) int {
+	s.data.nextIDs[table]++
+	return s.data.nextIDs[table]
+}
+
+func (d memoryData) clone() memoryData {
+	return memoryData{
+		nextIDs:       maps.Clone(d.nextIDs),
+		users:         maps.Clone(d.users),
+		decks:         maps.Clone(d.decks),
+		terminology:   maps.Clone(d.terminology),
+		studySettings: maps.Clone(d.studySettings),
+		notes:         maps.Clone(d.notes),
+		tags:          maps.Clone(d.tags),
+		flashcards:    maps.Clone(d.flashcards),
+		todos:         maps.Clone(d.todos),
+		jobRuns:       maps.Clone(d.jobRuns),
+		organizations: maps.Clone(d.organizations),
+		stripeEvents:  maps.Clone(d.stripeEvents),
+		memberships:   maps.Clone(d.memberships),
+		llmTokens:     maps.Clone(d.llmTokens),
+
+		schedules:        maps.Clone(d.schedules),
+		reviews:          maps.Clone(d.reviews),
+		quizSessions:     maps.Clone(d.quizSessions),
+		sessionQuestions: maps.Clone(d.sessionQuestions),
+		queuedSessions:   maps.Clone(d.queuedSessions),
+		importJobs:       maps.Clone(d.importJobs),
+		importItems:      maps.Clone(d.importItems),
+		invites:          maps.Clone(d.invites),
+
+		branding:      d.branding,
+		contentFilter: d.contentFilter,
+		maintenance:   d.maintenance,
+
+		promptTemplates:    maps.Clone(d.promptTemplates),
+		routingRules:       maps.Clone(d.routingRules),
+		questionEmbeddings: maps.Clone(d.questionEmbeddings),
+		requestStats:       maps.Clone(d.requestStats),
+		llmHourlyTokens:    maps.Clone(d.llmHourlyTokens),
+
+		embeds:            maps.Clone(d.embeds),
+		reportRecipients:  maps.Clone(d.reportRecipients),
+		dailyQuestions:    maps.Clone(d.dailyQuestions),
+		idempotencyKeys:   maps.Clone(d.idempotencyKeys),
+		deckExports:       maps.Clone(d.deckExports),
+		vocabulary:        maps.Clone(d.vocabulary),
+		deckSuggestions:   maps.Clone(d.deckSuggestions),
+		llmCalls:          maps.Clone(d.llmCalls),
+		spendAnomalies:    maps.Clone(d.spendAnomalies),
+		attachments:       maps.Clone(d.attachments),
+		attachmentBlobs:   maps.Clone(d.attachmentBlobs),
+		apiKeys:           maps.Clone(d.apiKeys),
+		apiKeyUsage:       maps.Clone(d.apiKeyUsage),
+		deadLetters:       maps.Clone(d.deadLetters),
+		jobs:              maps.Clone(d.jobs),
+		webhooks:          maps.Clone(d.webhooks),
+		webhookDeliveries: maps.Clone(d.webhookDeliveries),
+		similarityFlags:   maps.Clone(d.similarityFlags),
+		publishedDecks:    maps.Clone(d.publishedDecks),
+		cardEmbeddings:    maps.Clone(d.cardEmbeddings),
+	}
+}
+
+type memoryTxKey struct{}
+
+// MemoryUnitOfWork runs functions against a MemoryStore, restoring the
+// store as it was when the function returns an error or panics. It holds
+// the store for the whole function, so other units of work and writes
+// made outside of one wait for it, and a rollback never undoes them.
+// Repositories called inside the function must be given its context.
+type MemoryUnitOfWork struct {
+	store *MemoryStore
+}
+
+func NewMemoryUnitOfWork(store *MemoryStore) *MemoryUnitOfWork {
+	return &MemoryUnitOfWork{store: store}
+}
+
+func (u *MemoryUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if ctx.Value(memoryTxKey{}) == u.store {
+		return fn(ctx)
+	}
+
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+
+	snapshot := u.store.data.clone()
+	committed := false
+	defer func() {
+		if !committed {
+			u.store.data = snapshot
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, memoryTxKey{}, u.store)); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// memoryPage sorts items as orderByClause would and returns the page
+// params asks for. created, updated and id read an item's sort columns.
+func memoryPage[T any](items []T, params models.ListParams, created, updated func(T) time.Time, id func(T) int) ([]T, error) {
+	if _, ok := sortColumns[params.Sort]; !ok {
+		return nil, fmt.Errorf("unsupported sort field %q", params.Sort)
+	}
+
+	compare := func(a, b T) int {
+		var order int
+		switch params.Sort {
+		case "createdAt":
+			order = created(a).Compare(created(b))
+		case "updatedAt":
+			order = updated(a).Compare(updated(b))
+		}
+		if order == 0 {
+			order = id(a) - id(b)
+		}
+		if params.Order != "asc" {
+			order = -order
+		}
+		return order
+	}
+	slices.SortFunc(items, compare)
+
+	start := min(params.Offset, len(items))
+	end := len(items)
+	if params.Limit > 0 {
+		end = min(start+params.Limit, len(items))
+	}
+	return items[start:end], nil
+}
+
+// memoryContains matches like ILIKE with a likePattern: a case-insensitive
+// substring
+func memoryContains(value, query string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(query))
+}
+
+// memoryIntUpdate reads an update to a nullable integer column, which
+// services set to an int or nil
+func memoryIntUpdate(field string, value any) (*int, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case int:
+		return &v, nil
+	case *int:
+		return v, nil
+	}
+	return nil, fmt.Errorf("invalid value for %s: %v", field, value)
+}
+
+// memoryStringUpdate reads an update to a text column
+func memoryStringUpdate(field string, value any) (string, error) {
+	if v, ok := value.(string); ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("invalid value for %s: %v", field, value)
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+type memoryAPIKeyDay struct {
+	apiKeyID int
+	day      time.Time
+}
+
+type MemoryAPIKeyRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryAPIKeyRepository(store *MemoryStore) *MemoryAPIKeyRepository {
+	return &MemoryAPIKeyRepository{store: store}
+}
+
+// CountAPIKeys counts the user's keys that have not been revoked
+func (r *MemoryAPIKeyRepository) CountAPIKeys(ctx context.Context, userID int) (int, error) {
+	defer r.store.lock(ctx)()
+
+	count := 0
+	for _, key := range r.store.data.apiKeys {
+		if key.UserID == userID && key.RevokedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryAPIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	defer r.store.lock(ctx)()
+
+	key.ID, key.CreatedAt = r.store.nextID("api_keys"), r.store.now()
+	stored := *key
+	stored.Key, stored.Scopes = "", slices.Clone(key.Scopes)
+	r.store.data.apiKeys[key.ID] = stored
+	return nil
+}
+
+// GetAPIKeys returns the user's keys, revoked ones included, newest first
+func (r *MemoryAPIKeyRepository) GetAPIKeys(ctx context.Context, userID int) ([]*models.APIKey, error) {
+	defer r.store.lock(ctx)()
+
+	var keys []*models.APIKey
+	for _, key := range r.store.data.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, memoryAPIKey(key))
+		}
+	}
+	slices.SortFunc(keys, func(a, b *models.APIKey) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), b.ID-a.ID)
+	})
+	return keys, nil
+}
+
+func (r *MemoryAPIKeyRepository) GetAPIKey(ctx context.Context, userID, id int) (*models.APIKey, error) {
+	defer r.store.lock(ctx)()
+
+	key, ok := r.store.data.apiKeys[id]
+	if !ok || key.UserID != userID {
+		return nil, models.NotFound("api key with id %d not found", id)
+	}
+	return memoryAPIKey(key), nil
+}
+
+// GetAPIKeyByHash returns the key with the hash, if it has not been
+// revoked
+func (r *MemoryAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	defer r.store.lock(ctx)()
+
+	for _, key := range r.store.data.apiKeys {
+		if key.KeyHash == keyHash && key.RevokedAt == nil {
+			return memoryAPIKey(key), nil
+		}
+	}
+	return nil, models.NotFound("api key not found")
+}
+
+func (r *MemoryAPIKeyRepository) RevokeAPIKey(ctx context.Context, userID, id int) error {
+	defer r.store.lock(ctx)()
+
+	key, ok := r.store.data.apiKeys[id]
+	if !ok || key.UserID != userID || key.RevokedAt != nil {
+		return models.NotFound("api key with id %d not found", id)
+	}
+	now := r.store.now()
+	key.RevokedAt = &now
+	r.store.data.apiKeys[id] = key
+	return nil
+}
+
+// SaveAPIKeyUsage adds the request counts to each key's day and total, and
+// moves its lastUsedAt forward
+func (r *MemoryAPIKeyRepository) SaveAPIKeyUsage(ctx context.Context, usage []*models.APIKeyUsage, lastUsedAt map[int]time.Time) error {
+	defer r.store.lock(ctx)()
+
+	for _, day := range usage {
+		r.store.data.apiKeyUsage[memoryAPIKeyDay{day.APIKeyID, day.Day}] += day.Requests
+
+		key, ok := r.store.data.apiKeys[day.APIKeyID]
+		if !ok {
+			continue
+		}
+		key.Requests += day.Requests
+		if usedAt := lastUsedAt[day.APIKeyID]; key.LastUsedAt == nil || usedAt.After(*key.LastUsedAt) {
+			key.LastUsedAt = &usedAt
+		}
+		r.store.data.apiKeys[day.APIKeyID] = key
+	}
+	return nil
+}
+
+// GetAPIKeyUsage returns the key's requests per day since the given day,
+// oldest first. Days without requests are left out.
+func (r *MemoryAPIKeyRepository) GetAPIKeyUsage(ctx context.Context, id int, since time.Time) ([]*models.APIKeyUsage, error) {
+	defer r.store.lock(ctx)()
+
+	usage := []*models.APIKeyUsage{}
+	for key, requests := range r.store.data.apiKeyUsage {
+		if key.apiKeyID == id && !key.day.Before(since) {
+			usage = append(usage, &models.APIKeyUsage{APIKeyID: id, Day: key.day, Requests: requests})
+		}
+	}
+	slices.SortFunc(usage, func(a, b *models.APIKeyUsage) int { return a.Day.Compare(b.Day) })
+	return usage, nil
+}
+
+// memoryAPIKey copies a stored key for a caller
+func memoryAPIKey(key models.APIKey) *models.APIKey {
+	key.Scopes = slices.Clone(key.Scopes)
+	return &key
+}
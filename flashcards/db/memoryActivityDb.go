@@ -0,0 +1,120 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+type MemoryActivityRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryActivityRepository(store *MemoryStore) *MemoryActivityRepository {
+	return &MemoryActivityRepository{store: store}
+}
+
+// memoryActivityGroup is the key flashcards created and reviewed are
+// grouped by: the day and the deck
+type memoryActivityGroup struct {
+	day    time.Time
+	deckID int
+	inDeck bool
+}
+
+// GetActivity returns up to limit of the user's most recent activity items
+// since the given time, newest first
+func (r *MemoryActivityRepository) GetActivity(ctx context.Context, userID int, since time.Time, limit int) ([]models.ActivityItem, error) {
+	defer r.store.lock(ctx)()
+
+	created := make(map[memoryActivityGroup]*models.ActivityItem)
+	for _, flashcard := range r.store.data.flashcards {
+		if flashcard.UserID != userID || flashcard.CreatedAt.Before(since) {
+			continue
+		}
+		item := memoryActivityItem(created, models.ActivityFlashcardsCreated, flashcard.CreatedAt, flashcard.DeckID)
+		item.Count++
+	}
+
+	reviewed := make(map[memoryActivityGroup]*models.ActivityItem)
+	for _, review := range r.store.data.reviews {
+		flashcard, ok := r.store.data.flashcards[review.FlashcardID]
+		if !ok || review.UserID != userID || review.ReviewedAt.Before(since) {
+			continue
+		}
+		item := memoryActivityItem(reviewed, models.ActivityCardsReviewed, review.ReviewedAt, flashcard.DeckID)
+		item.Count++
+		if item.Correct == nil {
+			item.Correct = new(int)
+		}
+		if review.Rating != "again" {
+			*item.Correct++
+		}
+	}
+
+	items := make([]models.ActivityItem, 0)
+	for _, group := range []map[memoryActivityGroup]*models.ActivityItem{created, reviewed} {
+		for _, item := range group {
+			items = append(items, *item)
+		}
+	}
+
+	for _, session := range r.store.data.quizSessions {
+		if session.UserID != userID || session.Status != models.SessionStatusCompleted || session.UpdatedAt.Before(since) {
+			continue
+		}
+		count, correct := 0, 0
+		for _, row := range r.store.data.sessionQuestions {
+			if row.sessionID != session.ID {
+				continue
+			}
+			count++
+			if row.correct != nil && *row.correct {
+				correct++
+			}
+		}
+		items = append(items, models.ActivityItem{Type: models.ActivitySessionCompleted, OccurredAt: session.UpdatedAt,
+			Count: count, Correct: &correct, ResourceID: &session.ID})
+	}
+
+	for _, job := range r.store.data.importJobs {
+		occurredAt := job.UpdatedAt
+		if job.CompletedAt != nil {
+			occurredAt = *job.CompletedAt
+		}
+		if job.UserID != userID || job.Status != models.ImportJobStatusCompleted && job.Status != models.ImportJobStatusFailed ||
+			occurredAt.Before(since) {
+			continue
+		}
+		items = append(items, models.ActivityItem{Type: models.ActivityImportFinished, OccurredAt: occurredAt,
+			Count: job.ProcessedItems, DeckID: job.DeckID, ResourceID: &job.ID, Status: job.Status})
+	}
+
+	slices.SortFunc(items, func(a, b models.ActivityItem) int {
+		return cmp.Or(b.OccurredAt.Compare(a.OccurredAt), cmp.Compare(a.Type, b.Type))
+	})
+	return items[:min(limit, len(items))], nil
+}
+
+// memoryActivityItem returns the item of groups for the day of at and the
+// deck, creating it when needed, with OccurredAt the latest time seen
+func memoryActivityItem(groups map[memoryActivityGroup]*models.ActivityItem, kind string, at time.Time, deckID *int) *models.ActivityItem {
+	utc := at.UTC()
+	key := memoryActivityGroup{day: time.Date(utc.Year(), utc.Month(), utc.Day(), 0, 0, 0, 0, time.UTC)}
+	if deckID != nil {
+		key.deckID, key.inDeck = *deckID, true
+	}
+
+	item, ok := groups[key]
+	if !ok {
+		item = &models.ActivityItem{Type: kind, OccurredAt: at, DeckID: deckID}
+		groups[key] = item
+	}
+	if at.After(item.OccurredAt) {
+		item.OccurredAt = at
+	}
+	return item
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+// memoryStatsKey is the primary key of request_stats
+type memoryStatsKey struct {
+	bucket int64
+	route  string
+	method string
+	userID int
+}
+
+// memoryStatsTotals sums request stats over a group of rows
+type memoryStatsTotals struct {
+	models.RequestStats
+	users  map[int]bool
+	routes map[[2]string]bool
+}
+
+// sortValue is the value of one of analyticsSortExpressions for the totals
+func (t *memoryStatsTotals) sortValue(sort string) float64 {
+	requests := float64(max(t.Requests, 1))
+	switch sort {
+	case "errors":
+		return float64(t.ClientErrors + t.ServerErrors)
+	case "errorRate":
+		return float64(t.ClientErrors+t.ServerErrors) / requests
+	case "avgLatency":
+		return float64(t.TotalLatencyMs) / requests
+	case "maxLatency":
+		return float64(t.MaxLatencyMs)
+	}
+	return float64(t.Requests)
+}
+
+type MemoryAnalyticsRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryAnalyticsRepository(store *MemoryStore) *MemoryAnalyticsRepository {
+	return &MemoryAnalyticsRepository{store: store}
+}
+
+// SaveRequestStats adds the counts to the stored rows for each bucket,
+// route and user
+func (r *MemoryAnalyticsRepository) SaveRequestStats(ctx context.Context, stats []*models.RequestStats) error {
+	defer r.store.lock(ctx)()
+
+	for _, stat := range stats {
+		key := memoryStatsKey{bucket: stat.Bucket.UnixNano(), route: stat.Route, method: stat.Method, userID: stat.UserID}
+		stored, ok := r.store.data.requestStats[key]
+		if !ok {
+			stored = models.RequestStats{Bucket: stat.Bucket, Route: stat.Route, Method: stat.Method, UserID: stat.UserID}
+		}
+		stored.Requests += stat.Requests
+		stored.ClientErrors += stat.ClientErrors
+		stored.ServerErrors += stat.ServerErrors
+		stored.TotalLatencyMs += stat.TotalLatencyMs
+		stored.MaxLatencyMs = max(stored.MaxLatencyMs, stat.MaxLatencyMs)
+		r.store.data.requestStats[key] = stored
+	}
+	return nil
+}
+
+func (r *MemoryAnalyticsRepository) GetRouteAnalytics(ctx context.Context, query models.AnalyticsQuery) ([]*models.RouteAnalytics, error) {
+	groups, err := memoryAnalyticsTotals(ctx, r.store, query, false, func(stat models.RequestStats) [2]string {
+		return [2]string{stat.Route, stat.Method}
+	}, func(a, b [2]string) int {
+		return cmp.Or(cmp.Compare(a[0], b[0]), cmp.Compare(a[1], b[1]))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	routes := make([]*models.RouteAnalytics, 0, len(groups))
+	for _, totals := range groups {
+		route := &models.RouteAnalytics{Route: totals.Route, Method: totals.Method, Requests: totals.Requests,
+			ClientErrors: totals.ClientErrors, ServerErrors: totals.ServerErrors, MaxLatencyMs: totals.MaxLatencyMs, Users: len(totals.users)}
+		route.ErrorRate, route.AvgLatencyMs = rates(route.Requests, route.ClientErrors+route.ServerErrors, totals.TotalLatencyMs)
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// GetUserAnalytics reports authenticated users only
+func (r *MemoryAnalyticsRepository) GetUserAnalytics(ctx context.Context, query models.AnalyticsQuery) ([]*models.UserAnalytics, error) {
+	groups, err := memoryAnalyticsTotals(ctx, r.store, query, true, func(stat models.RequestStats) int { return stat.UserID }, cmp.Compare[int])
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*models.UserAnalytics, 0, len(groups))
+	for _, totals := range groups {
+		user := &models.UserAnalytics{UserID: totals.UserID, Requests: totals.Requests, ClientErrors: totals.ClientErrors,
+			ServerErrors: totals.ServerErrors, MaxLatencyMs: totals.MaxLatencyMs, Routes: len(totals.routes)}
+		user.ErrorRate, user.AvgLatencyMs = rates(user.Requests, user.ClientErrors+user.ServerErrors, totals.TotalLatencyMs)
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (r *MemoryAnalyticsRepository) DeleteRequestStatsBefore(ctx context.Context, before time.Time) (int64, error) {
+	defer r.store.lock(ctx)()
+
+	var deleted int64
+	maps.DeleteFunc(r.store.data.requestStats, func(_ memoryStatsKey, stat models.RequestStats) bool {
+		if stat.Bucket.Before(before) {
+			deleted++
+			return true
+		}
+		return false
+	})
+	return deleted, nil
+}
+
+// memoryAnalyticsTotals sums the rows since query.Since by the group key
+// picks, leaving out anonymous requests when authenticated is set, and
+// returns the top query.Limit groups in the order query.Sort asks for, then
+// by key
+func memoryAnalyticsTotals[K comparable](ctx context.Context, store *MemoryStore, query models.AnalyticsQuery, authenticated bool, key func(models.RequestStats) K, compareKeys func(a, b K) int) ([]*memoryStatsTotals, error) {
+	if _, ok := analyticsSortExpressions[query.Sort]; !ok {
+		return nil, fmt.Errorf("unsupported sort field %q", query.Sort)
+	}
+
+	defer store.lock(ctx)()
+
+	groups := make(map[K]*memoryStatsTotals)
+	for _, stat := range store.data.requestStats {
+		if stat.Bucket.Before(query.Since) || authenticated && stat.UserID == 0 {
+			continue
+		}
+
+		totals, ok := groups[key(stat)]
+		if !ok {
+			totals = &memoryStatsTotals{RequestStats: stat, users: map[int]bool{}, routes: map[[2]string]bool{}}
+			totals.Requests, totals.ClientErrors, totals.ServerErrors, totals.TotalLatencyMs, totals.MaxLatencyMs = 0, 0, 0, 0, 0
+			groups[key(stat)] = totals
+		}
+		totals.Requests += stat.Requests
+		totals.ClientErrors += stat.ClientErrors
+		totals.ServerErrors += stat.ServerErrors
+		totals.TotalLatencyMs += stat.TotalLatencyMs
+		totals.MaxLatencyMs = max(totals.MaxLatencyMs, stat.MaxLatencyMs)
+		if stat.UserID != 0 {
+			totals.users[stat.UserID] = true
+		}
+		totals.routes[[2]string{stat.Route, stat.Method}] = true
+	}
+
+	keys := slices.SortedFunc(maps.Keys(groups), func(a, b K) int {
+		return cmp.Or(cmp.Compare(groups[b].sortValue(query.Sort), groups[a].sortValue(query.Sort)), compareKeys(a, b))
+	})
+
+	totals := make([]*memoryStatsTotals, 0, min(query.Limit, len(keys)))
+	for _, key := range keys[:min(query.Limit, len(keys))] {
+		totals = append(totals, groups[key])
+	}
+	return totals, nil
+}
@@ -0,0 +1,187 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+// memoryBlob is a row of attachment_blobs
+type memoryBlob struct {
+	blob models.AttachmentBlob
+	data []byte
+}
+
+type MemoryAttachmentRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryAttachmentRepository(store *MemoryStore) *MemoryAttachmentRepository {
+	return &MemoryAttachmentRepository{store: store}
+}
+
+// CreateAttachment stores an attachment. One with a ContentHash references
+// the blob with that hash, which is created from its data or StorageKey if
+// there is none yet; StorageKey is then set to the blob's.
+func (r *MemoryAttachmentRepository) CreateAttachment(ctx context.Context, attachment *models.Attachment) error {
+	defer r.store.lock(ctx)()
+
+	stored := *attachment
+	if attachment.ContentHash != "" {
+		attachment.StorageKey = r.referenceBlob(attachment)
+		stored.Data, stored.StorageKey = nil, ""
+	} else if stored.StorageKey != "" {
+		// Data held in object storage is not stored here as well
+		stored.Data = nil
+	}
+
+	attachment.ID, attachment.CreatedAt = r.store.nextID("attachments"), r.store.now()
+	stored.ID, stored.CreatedAt = attachment.ID, attachment.CreatedAt
+	stored.Data = slices.Clone(stored.Data)
+	r.store.data.attachments[attachment.ID] = stored
+	return nil
+}
+
+func (r *MemoryAttachmentRepository) GetAttachment(ctx context.Context, userID, id int) (*models.Attachment, error) {
+	defer r.store.lock(ctx)()
+
+	attachment, ok := r.store.data.attachments[id]
+	if !ok || attachment.UserID != userID {
+		return nil, models.NotFound("attachment with id %d not found", id)
+	}
+	if blob, ok := r.store.data.attachmentBlobs[attachment.ContentHash]; ok && attachment.ContentHash != "" {
+		if blob.data != nil {
+			attachment.Data = blob.data
+		}
+		attachment.StorageKey = cmp.Or(blob.blob.StorageKey, attachment.StorageKey)
+	}
+	attachment.Data = slices.Clone(attachment.Data)
+	return &attachment, nil
+}
+
+// CompleteAttachment marks a pending attachment ready, with the content
+// type, size and ContentHash of its upload. The upload becomes the blob for
+// the hash unless there already is one; StorageKey is set to the blob's.
+func (r *MemoryAttachmentRepository) CompleteAttachment(ctx context.Context, attachment *models.Attachment) error {
+	defer r.store.lock(ctx)()
+
+	stored, ok := r.store.data.attachments[attachment.ID]
+	if !ok || stored.UserID != attachment.UserID || stored.Status != models.AttachmentStatusPending {
+		return models.Conflict("attachment with id %d is not pending", attachment.ID)
+	}
+
+	storageKey := r.referenceBlob(attachment)
+	stored.Status, stored.ContentType, stored.Size = models.AttachmentStatusReady, attachment.ContentType, attachment.Size
+	stored.ContentHash, stored.StorageKey = attachment.ContentHash, ""
+	r.store.data.attachments[attachment.ID] = stored
+
+	attachment.Status = models.AttachmentStatusReady
+	attachment.StorageKey = storageKey
+	return nil
+}
+
+// DeleteAttachment deletes an attachment and releases its blob. The blob
+// is only deleted later, by DeleteReleasedBlobs.
+func (r *MemoryAttachmentRepository) DeleteAttachment(ctx context.Context, userID, id int) error {
+	defer r.store.lock(ctx)()
+
+	attachment, ok := r.store.data.attachments[id]
+	if !ok || attachment.UserID != userID {
+		return models.NotFound("attachment with id %d not found", id)
+	}
+	delete(r.store.data.attachments, id)
+
+	if row, ok := r.store.data.attachmentBlobs[attachment.ContentHash]; ok && attachment.ContentHash != "" {
+		row.blob.RefCount--
+		if row.blob.RefCount == 0 {
+			now := r.store.now()
+			row.blob.ReleasedAt = &now
+		}
+		r.store.data.attachmentBlobs[attachment.ContentHash] = row
+	}
+	return nil
+}
+
+// GetPendingAttachments returns up to limit attachments still pending
+// since before, oldest first
+func (r *MemoryAttachmentRepository) GetPendingAttachments(ctx context.Context, before time.Time, limit int) ([]*models.Attachment, error) {
+	defer r.store.lock(ctx)()
+
+	var attachments []*models.Attachment
+	for _, attachment := range r.store.data.attachments {
+		if attachment.Status == models.AttachmentStatusPending && attachment.CreatedAt.Before(before) {
+			attachment.Data, attachment.ContentHash = nil, ""
+			attachments = append(attachments, &attachment)
+		}
+	}
+	slices.SortFunc(attachments, func(a, b *models.Attachment) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), a.ID-b.ID)
+	})
+	return attachments[:min(limit, len(attachments))], nil
+}
+
+func (r *MemoryAttachmentRepository) GetBlob(ctx context.Context, hash string) (*models.AttachmentBlob, error) {
+	defer r.store.lock(ctx)()
+
+	row, ok := r.store.data.attachmentBlobs[hash]
+	if !ok {
+		return nil, models.NotFound("attachment blob %s not found", hash)
+	}
+	return &row.blob, nil
+}
+
+// DeleteReleasedBlobs deletes up to limit blobs that have been
+// unreferenced since before and returns them, so their objects can be
+// deleted
+func (r *MemoryAttachmentRepository) DeleteReleasedBlobs(ctx context.Context, before time.Time, limit int) ([]*models.AttachmentBlob, error) {
+	defer r.store.lock(ctx)()
+
+	var blobs []*models.AttachmentBlob
+	for _, row := range r.store.data.attachmentBlobs {
+		referenced := false
+		for _, attachment := range r.store.data.attachments {
+			referenced = referenced || attachment.ContentHash == row.blob.Hash
+		}
+		if row.blob.RefCount == 0 && row.blob.ReleasedAt != nil && row.blob.ReleasedAt.Before(before) && !referenced {
+			blobs = append(blobs, &row.blob)
+		}
+	}
+	slices.SortFunc(blobs, func(a, b *models.AttachmentBlob) int {
+		return cmp.Or(a.ReleasedAt.Compare(*b.ReleasedAt), cmp.Compare(a.Hash, b.Hash))
+	})
+
+	blobs = blobs[:min(limit, len(blobs))]
+	for _, blob := range blobs {
+		delete(r.store.data.attachmentBlobs, blob.Hash)
+	}
+	return blobs, nil
+}
+
+// referenceBlob adds a reference to the blob with the attachment's
+// ContentHash, creating it from the attachment's StorageKey, or its data
+// when that is empty, if there is none. It returns the blob's storage key.
+// Callers hold mu.
+func (r *MemoryAttachmentRepository) referenceBlob(attachment *models.Attachment) string {
+	row, ok := r.store.data.attachmentBlobs[attachment.ContentHash]
+	if ok {
+		row.blob.RefCount++
+		row.blob.ReleasedAt = nil
+	} else {
+		row = memoryBlob{blob: models.AttachmentBlob{
+			Hash:        attachment.ContentHash,
+			ContentType: attachment.ContentType,
+			Size:        attachment.Size,
+			StorageKey:  attachment.StorageKey,
+			RefCount:    1,
+			CreatedAt:   r.store.now(),
+		}}
+		if attachment.StorageKey == "" {
+			row.data = slices.Clone(attachment.Data)
+		}
+	}
+	r.store.data.attachmentBlobs[attachment.ContentHash] = row
+	return row.blob.StorageKey
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+
+	"flashcards/models"
+)
+
+type MemoryBrandingRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryBrandingRepository(store *MemoryStore) *MemoryBrandingRepository {
+	return &MemoryBrandingRepository{store: store}
+}
+
+// GetBranding returns the saved branding, or the defaults before any is
+// saved
+func (r *MemoryBrandingRepository) GetBranding(ctx context.Context) (*models.Branding, error) {
+	defer r.store.lock(ctx)()
+
+	if r.store.data.branding == nil {
+		return &models.Branding{
+			Name:         models.DefaultBrandingName,
+			PrimaryColor: models.DefaultBrandingPrimaryColor,
+			AccentColor:  models.DefaultBrandingAccentColor,
+		}, nil
+	}
+	branding := *r.store.data.branding
+	return &branding, nil
+}
+
+func (r *MemoryBrandingRepository) SaveBranding(ctx context.Context, branding *models.Branding) error {
+	defer r.store.lock(ctx)()
+
+	branding.UpdatedAt = r.store.now()
+	stored := *branding
+	r.store.data.branding = &stored
+	return nil
+}
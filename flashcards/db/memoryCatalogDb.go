@@ -0,0 +1,210 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+
+	"flashcards/models"
+)
+
+type MemoryCatalogRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryCatalogRepository(store *MemoryStore) *MemoryCatalogRepository {
+	return &MemoryCatalogRepository{store: store}
+}
+
+// GetDeckCards returns the flashcards directly in the deck, not in its
+// subdecks
+func (r *MemoryCatalogRepository) GetDeckCards(ctx context.Context, userID, deckID int) ([]*models.Flashcard, error) {
+	defer r.store.lock(ctx)()
+
+	return r.deckCards(deckID, func(flashcard models.Flashcard) bool { return flashcard.UserID == userID }), nil
+}
+
+func (r *MemoryCatalogRepository) IsPublished(ctx context.Context, deckID int) (bool, error) {
+	defer r.store.lock(ctx)()
+
+	_, ok := r.store.data.publishedDecks[deckID]
+	return ok, nil
+}
+
+// GetPublishedEmbeddings returns the card embeddings of every published
+// deck not owned by the user. Only embeddings from the same model are
+// comparable, so others are skipped.
+func (r *MemoryCatalogRepository) GetPublishedEmbeddings(ctx context.Context, excludeUserID int, model string) ([]*models.CardEmbedding, error) {
+	defer r.store.lock(ctx)()
+
+	embeddings := []*models.CardEmbedding{}
+	for _, id := range slices.Sorted(maps.Keys(r.store.data.cardEmbeddings)) {
+		embedding := r.store.data.cardEmbeddings[id]
+		if published, ok := r.store.data.publishedDecks[embedding.DeckID]; ok && published.UserID != excludeUserID && embedding.Model == model {
+			embedding.Vector = slices.Clone(embedding.Vector)
+			embeddings = append(embeddings, &embedding)
+		}
+	}
+	return embeddings, nil
+}
+
+// PublishDeck lists the deck in the catalog with its card embeddings and
+// any similarity flags raised against it
+func (r *MemoryCatalogRepository) PublishDeck(ctx context.Context, userID, deckID int, embeddings []*models.CardEmbedding, flags []*models.SimilarityFlag) error {
+	defer r.store.lock(ctx)()
+
+	if _, ok := r.store.data.publishedDecks[deckID]; ok {
+		return models.Conflict("deck %d is already published", deckID)
+	}
+	r.store.data.publishedDecks[deckID] = models.PublishedDeck{DeckID: deckID, UserID: userID, PublishedAt: r.store.now()}
+	r.saveSimilarityCheck(deckID, embeddings, flags)
+	return nil
+}
+
+// SaveSimilarityCheck stores the result of a similarity check that ran
+// after the deck was published
+func (r *MemoryCatalogRepository) SaveSimilarityCheck(ctx context.Context, deckID int, embeddings []*models.CardEmbedding, flags []*models.SimilarityFlag) error {
+	defer r.store.lock(ctx)()
+
+	r.saveSimilarityCheck(deckID, embeddings, flags)
+	return nil
+}
+
+// saveSimilarityCheck replaces the cards' embeddings and adds the flags.
+// Callers hold mu.
+func (r *MemoryCatalogRepository) saveSimilarityCheck(deckID int, embeddings []*models.CardEmbedding, flags []*models.SimilarityFlag) {
+	for _, embedding := range embeddings {
+		stored := *embedding
+		stored.DeckID, stored.Vector = deckID, slices.Clone(embedding.Vector)
+		r.store.data.cardEmbeddings[embedding.FlashcardID] = stored
+	}
+	for _, flag := range flags {
+		flag.ID, flag.Status, flag.CreatedAt = r.store.nextID("deck_similarity_flags"), models.FlagStatusPending, r.store.now()
+		r.store.data.similarityFlags[flag.ID] = *flag
+	}
+}
+
+// UnpublishDeck removes the deck and its card embeddings from the catalog
+func (r *MemoryCatalogRepository) UnpublishDeck(ctx context.Context, deckID int) error {
+	defer r.store.lock(ctx)()
+
+	if _, ok := r.store.data.publishedDecks[deckID]; !ok {
+		return models.NotFound("published deck with id %d not found", deckID)
+	}
+	r.store.unpublishDeck(deckID)
+	return nil
+}
+
+// ListPublishedDecks returns one page of the catalog, newest first
+func (r *MemoryCatalogRepository) ListPublishedDecks(ctx context.Context, limit, offset int) ([]*models.PublishedDeck, int, error) {
+	defer r.store.lock(ctx)()
+
+	decks := []*models.PublishedDeck{}
+	for deckID := range r.store.data.publishedDecks {
+		decks = append(decks, r.publishedDeck(deckID))
+	}
+	slices.SortFunc(decks, func(a, b *models.PublishedDeck) int {
+		return cmp.Or(b.PublishedAt.Compare(a.PublishedAt), b.DeckID-a.DeckID)
+	})
+
+	start := min(offset, len(decks))
+	return decks[start:min(start+limit, len(decks))], len(decks), nil
+}
+
+// GetPublishedDeck returns the catalog entry of a published deck
+func (r *MemoryCatalogRepository) GetPublishedDeck(ctx context.Context, deckID int) (*models.PublishedDeck, error) {
+	defer r.store.lock(ctx)()
+
+	if _, ok := r.store.data.publishedDecks[deckID]; !ok {
+		return nil, models.NotFound("published deck with ID %d not found", deckID)
+	}
+	return r.publishedDeck(deckID), nil
+}
+
+// GetRecentDeckCards returns the most recently added cards directly in a
+// published deck, newest first
+func (r *MemoryCatalogRepository) GetRecentDeckCards(ctx context.Context, deckID, limit int) ([]*models.Flashcard, error) {
+	defer r.store.lock(ctx)()
+
+	published, ok := r.store.data.publishedDecks[deckID]
+	if !ok {
+		return []*models.Flashcard{}, nil
+	}
+	flashcards := r.deckCards(deckID, func(flashcard models.Flashcard) bool { return flashcard.UserID == published.UserID })
+	slices.SortFunc(flashcards, func(a, b *models.Flashcard) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), b.ID-a.ID)
+	})
+	return flashcards[:min(limit, len(flashcards))], nil
+}
+
+// GetSimilarityFlags returns the most recent flags with the given status
+func (r *MemoryCatalogRepository) GetSimilarityFlags(ctx context.Context, status string, limit int) ([]*models.SimilarityFlag, error) {
+	defer r.store.lock(ctx)()
+
+	flags := []*models.SimilarityFlag{}
+	for _, flag := range r.store.data.similarityFlags {
+		if flag.Status == status {
+			flags = append(flags, &flag)
+		}
+	}
+	slices.SortFunc(flags, func(a, b *models.SimilarityFlag) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), b.ID-a.ID)
+	})
+	return flags[:min(limit, len(flags))], nil
+}
+
+// ReviewSimilarityFlag records a curator's decision. Confirming a flag
+// takes the flagged deck out of the catalog.
+func (r *MemoryCatalogRepository) ReviewSimilarityFlag(ctx context.Context, id int, status string) (*models.SimilarityFlag, error) {
+	defer r.store.lock(ctx)()
+
+	flag, ok := r.store.data.similarityFlags[id]
+	if !ok {
+		return nil, models.NotFound("similarity flag with id %d not found", id)
+	}
+	now := r.store.now()
+	flag.Status, flag.ReviewedAt = status, &now
+	r.store.data.similarityFlags[id] = flag
+
+	if status == models.FlagStatusConfirmed {
+		r.store.unpublishDeck(flag.DeckID)
+	}
+	return &flag, nil
+}
+
+// deckCards returns the matching flashcards directly in the deck, by ID.
+// Callers hold mu.
+func (r *MemoryCatalogRepository) deckCards(deckID int, match func(models.Flashcard) bool) []*models.Flashcard {
+	flashcards := []*models.Flashcard{}
+	for _, id := range slices.Sorted(maps.Keys(r.store.data.flashcards)) {
+		flashcard := r.store.data.flashcards[id]
+		if flashcard.DeckID != nil && *flashcard.DeckID == deckID && match(flashcard) {
+			flashcards = append(flashcards, &flashcard)
+		}
+	}
+	return flashcards
+}
+
+// publishedDeck returns the catalog entry of a published deck with its
+// deck's name and card count. Callers hold mu.
+func (r *MemoryCatalogRepository) publishedDeck(deckID int) *models.PublishedDeck {
+	published := r.store.data.publishedDecks[deckID]
+	deck := r.store.data.decks[deckID]
+	published.Name, published.Description = deck.Name, deck.Description
+	for _, flashcard := range r.store.data.flashcards {
+		if flashcard.DeckID != nil && *flashcard.DeckID == deckID {
+			published.CardCount++
+		}
+	}
+	return &published
+}
+
+// unpublishDeck removes a deck and its card embeddings from the catalog.
+// Callers hold mu.
+func (s *MemoryStore) unpublishDeck(deckID int) {
+	delete(s.data.publishedDecks, deckID)
+	maps.DeleteFunc(s.data.cardEmbeddings, func(_ int, embedding models.CardEmbedding) bool {
+		return embedding.DeckID == deckID
+	})
+}
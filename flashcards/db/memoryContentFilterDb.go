@@ -0,0 +1,39 @@
+package db
+
+import (
+	"context"
+	"slices"
+
+	"flashcards/models"
+)
+
+type MemoryContentFilterRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryContentFilterRepository(store *MemoryStore) *MemoryContentFilterRepository {
+	return &MemoryContentFilterRepository{store: store}
+}
+
+// GetSettings returns the saved settings, or the filter off for adults
+// before any are saved
+func (r *MemoryContentFilterRepository) GetSettings(ctx context.Context) (*models.ContentFilterSettings, error) {
+	defer r.store.lock(ctx)()
+
+	if r.store.data.contentFilter == nil {
+		return &models.ContentFilterSettings{AgeLevel: models.AgeLevelAdult, BannedTopics: []string{}}, nil
+	}
+	settings := *r.store.data.contentFilter
+	settings.BannedTopics = slices.Clone(settings.BannedTopics)
+	return &settings, nil
+}
+
+func (r *MemoryContentFilterRepository) SaveSettings(ctx context.Context, settings *models.ContentFilterSettings) error {
+	defer r.store.lock(ctx)()
+
+	settings.UpdatedAt = r.store.now()
+	stored := *settings
+	stored.BannedTopics = append([]string{}, settings.BannedTopics...)
+	r.store.data.contentFilter = &stored
+	return nil
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+type MemoryDailyQuestionRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryDailyQuestionRepository(store *MemoryStore) *MemoryDailyQuestionRepository {
+	return &MemoryDailyQuestionRepository{store: store}
+}
+
+func (r *MemoryDailyQuestionRepository) CreateSubscription(ctx context.Context, subscription *models.DailyQuestionSubscription) error {
+	defer r.store.lock(ctx)()
+
+	for _, existing := range r.store.data.dailyQuestions {
+		if existing.UserID == subscription.UserID && existing.DeckID == subscription.DeckID {
+			return models.Conflict("already subscribed to daily questions from deck %d", subscription.DeckID)
+		}
+	}
+
+	subscription.ID, subscription.CreatedAt = r.store.nextID("daily_question_subscriptions"), r.store.now()
+	stored := *subscription
+	stored.Email = ""
+	r.store.data.dailyQuestions[subscription.ID] = stored
+	return nil
+}
+
+// GetSubscriptions returns the user's subscriptions, oldest first
+func (r *MemoryDailyQuestionRepository) GetSubscriptions(ctx context.Context, userID int) ([]*models.DailyQuestionSubscription, error) {
+	subscriptions := r.subscriptions(ctx, func(subscription models.DailyQuestionSubscription) bool {
+		return subscription.UserID == userID
+	})
+	slices.SortFunc(subscriptions, func(a, b *models.DailyQuestionSubscription) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), a.ID-b.ID)
+	})
+	return subscriptions, nil
+}
+
+// GetSubscriptionsNotSentSince returns every user's subscriptions that have
+// not had a question sent since the given time, for the scheduled job
+func (r *MemoryDailyQuestionRepository) GetSubscriptionsNotSentSince(ctx context.Context, since time.Time) ([]*models.DailyQuestionSubscription, error) {
+	subscriptions := r.subscriptions(ctx, func(subscription models.DailyQuestionSubscription) bool {
+		return subscription.LastSentAt == nil || subscription.LastSentAt.Before(since)
+	})
+	slices.SortFunc(subscriptions, func(a, b *models.DailyQuestionSubscription) int {
+		return cmp.Or(a.UserID-b.UserID, a.ID-b.ID)
+	})
+	return subscriptions, nil
+}
+
+// subscriptions returns the matching subscriptions with their user's email
+func (r *MemoryDailyQuestionRepository) subscriptions(ctx context.Context, match func(models.DailyQuestionSubscription) bool) []*models.DailyQuestionSubscription {
+	defer r.store.lock(ctx)()
+
+	subscriptions := make([]*models.DailyQuestionSubscription, 0)
+	for _, subscription := range r.store.data.dailyQuestions {
+		user, ok := r.store.data.users[subscription.UserID]
+		if !ok || !match(subscription) {
+			continue
+		}
+		subscription.Email = user.Email
+		subscriptions = append(subscriptions, &subscription)
+	}
+	return subscriptions
+}
+
+func (r *MemoryDailyQuestionRepository) MarkSubscriptionSent(ctx context.Context, id, sessionID int, sentAt time.Time) error {
+	defer r.store.lock(ctx)()
+
+	if subscription, ok := r.store.data.dailyQuestions[id]; ok {
+		subscription.LastSessionID, subscription.LastSentAt = &sessionID, &sentAt
+		r.store.data.dailyQuestions[id] = subscription
+	}
+	return nil
+}
+
+func (r *MemoryDailyQuestionRepository) DeleteSubscription(ctx context.Context, userID, id int) error {
+	defer r.store.lock(ctx)()
+
+	subscription, ok := r.store.data.dailyQuestions[id]
+	if !ok || subscription.UserID != userID {
+		return models.NotFound("daily question subscription with id %d not found", id)
+	}
+	delete(r.store.data.dailyQuestions, id)
+	return nil
+}
@@ -0,0 +1,165 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+type MemoryDeadLetterRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryDeadLetterRepository(store *MemoryStore) *MemoryDeadLetterRepository {
+	return &MemoryDeadLetterRepository{store: store}
+}
+
+// RecordDeadLetter adds a failed piece of work. When the same work already
+// has an open entry, that entry takes the new error and payload and keeps
+// its attempts and schedule.
+func (r *MemoryDeadLetterRepository) RecordDeadLetter(ctx context.Context, letter *models.DeadLetter) error {
+	defer r.store.lock(ctx)()
+
+	now := r.store.now()
+	for id, existing := range r.store.data.deadLetters {
+		if existing.Kind == letter.Kind && existing.Key == letter.Key && existing.Status != models.DeadLetterStatusResolved {
+			existing.Payload, existing.Error, existing.UpdatedAt = slices.Clone(letter.Payload), letter.Error, now
+			r.store.data.deadLetters[id] = existing
+			*letter = *memoryDeadLetter(existing)
+			return nil
+		}
+	}
+
+	stored := *letter
+	stored.ID, stored.CreatedAt, stored.UpdatedAt, stored.ResolvedAt = r.store.nextID("dead_letters"), now, now, nil
+	stored.Payload = slices.Clone(letter.Payload)
+	r.store.data.deadLetters[stored.ID] = stored
+	*letter = *memoryDeadLetter(stored)
+	return nil
+}
+
+func (r *MemoryDeadLetterRepository) GetDeadLetter(ctx context.Context, id int) (*models.DeadLetter, error) {
+	defer r.store.lock(ctx)()
+
+	letter, ok := r.store.data.deadLetters[id]
+	if !ok {
+		return nil, models.NotFound("dead letter with id %d not found", id)
+	}
+	return memoryDeadLetter(letter), nil
+}
+
+// ListDeadLetters returns one page of entries matching the filter and the
+// total number that match
+func (r *MemoryDeadLetterRepository) ListDeadLetters(ctx context.Context, filter models.DeadLetterFilter, params models.ListParams) ([]*models.DeadLetter, int, error) {
+	defer r.store.lock(ctx)()
+
+	letters := make([]*models.DeadLetter, 0)
+	for _, letter := range r.store.data.deadLetters {
+		if (filter.Status == "" || letter.Status == filter.Status) && (filter.Kind == "" || letter.Kind == filter.Kind) {
+			letters = append(letters, memoryDeadLetter(letter))
+		}
+	}
+
+	page, err := memoryPage(letters, params,
+		func(l *models.DeadLetter) time.Time { return l.CreatedAt },
+		func(l *models.DeadLetter) time.Time { return l.UpdatedAt },
+		func(l *models.DeadLetter) int { return l.ID })
+	if err != nil {
+		return nil, 0, err
+	}
+	return page, len(letters), nil
+}
+
+// ClaimDueDeadLetters returns retrying entries whose next attempt is due by
+// the store's clock and pushes that attempt back by lease
+func (r *MemoryDeadLetterRepository) ClaimDueDeadLetters(ctx context.Context, limit int, lease time.Duration) ([]*models.DeadLetter, error) {
+	defer r.store.lock(ctx)()
+
+	now := r.store.now()
+	due := make([]models.DeadLetter, 0)
+	for _, letter := range r.store.data.deadLetters {
+		if letter.Status == models.DeadLetterStatusRetrying && letter.NextAttemptAt != nil && !letter.NextAttemptAt.After(now) {
+			due = append(due, letter)
+		}
+	}
+	slices.SortFunc(due, func(a, b models.DeadLetter) int {
+		return cmp.Or(a.NextAttemptAt.Compare(*b.NextAttemptAt), a.ID-b.ID)
+	})
+
+	letters := make([]*models.DeadLetter, 0)
+	for _, letter := range due[:min(limit, len(due))] {
+		nextAttemptAt := now.Add(lease)
+		letter.NextAttemptAt = &nextAttemptAt
+		r.store.data.deadLetters[letter.ID] = letter
+		letters = append(letters, memoryDeadLetter(letter))
+	}
+	return letters, nil
+}
+
+func (r *MemoryDeadLetterRepository) ResolveDeadLetter(ctx context.Context, id int) error {
+	defer r.store.lock(ctx)()
+
+	if letter, ok := r.store.data.deadLetters[id]; ok {
+		now := r.store.now()
+		letter.Status, letter.NextAttemptAt, letter.ResolvedAt, letter.UpdatedAt = models.DeadLetterStatusResolved, nil, &now, now
+		r.store.data.deadLetters[id] = letter
+	}
+	return nil
+}
+
+// FailDeadLetterAttempt counts a failed retry. Without a next attempt the
+// entry is dead.
+func (r *MemoryDeadLetterRepository) FailDeadLetterAttempt(ctx context.Context, id int, message string, nextAttemptAt *time.Time) error {
+	defer r.store.lock(ctx)()
+
+	letter, ok := r.store.data.deadLetters[id]
+	if !ok || letter.Status == models.DeadLetterStatusResolved {
+		return nil
+	}
+	letter.Attempts++
+	letter.Error, letter.NextAttemptAt, letter.UpdatedAt = message, nextAttemptAt, r.store.now()
+	letter.Status = models.DeadLetterStatusRetrying
+	if nextAttemptAt == nil {
+		letter.Status = models.DeadLetterStatusDead
+	}
+	r.store.data.deadLetters[id] = letter
+	return nil
+}
+
+// RequeueDeadLetter makes an open entry due for a retry now, including a
+// dead one
+func (r *MemoryDeadLetterRepository) RequeueDeadLetter(ctx context.Context, id int) (*models.DeadLetter, error) {
+	defer r.store.lock(ctx)()
+
+	letter, ok := r.store.data.deadLetters[id]
+	if !ok {
+		return nil, models.NotFound("dead letter with id %d not found", id)
+	}
+	if letter.Status == models.DeadLetterStatusResolved {
+		return nil, models.Conflict("dead letter %d is already resolved", id)
+	}
+
+	now := r.store.now()
+	letter.Status, letter.NextAttemptAt, letter.UpdatedAt = models.DeadLetterStatusRetrying, &now, now
+	r.store.data.deadLetters[id] = letter
+	return memoryDeadLetter(letter), nil
+}
+
+func (r *MemoryDeadLetterRepository) DeleteDeadLetter(ctx context.Context, id int) error {
+	defer r.store.lock(ctx)()
+
+	if _, ok := r.store.data.deadLetters[id]; !ok {
+		return models.NotFound("dead letter with id %d not found", id)
+	}
+	delete(r.store.data.deadLetters, id)
+	return nil
+}
+
+// memoryDeadLetter copies a stored entry for a caller
+func memoryDeadLetter(letter models.DeadLetter) *models.DeadLetter {
+	letter.Payload = slices.Clone(letter.Payload)
+	return &letter
+}
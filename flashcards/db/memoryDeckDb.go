@@ -0,0 +1,226 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"flashcards/models"
+)
+
+type MemoryDeckRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryDeckRepository(store *MemoryStore) *MemoryDeckRepository {
+	return &MemoryDeckRepository{store: store}
+}
+
+func (r *MemoryDeckRepository) CreateDeck(ctx context.Context, deck *models.Deck) error {
+	defer r.store.lock(ctx)()
+
+	now := r.store.now()
+	deck.ID, deck.CreatedAt, deck.UpdatedAt = r.store.nextID("decks"), now, now
+	r.store.data.decks[deck.ID] = *deck
+	return nil
+}
+
+func (r *MemoryDeckRepository) GetDeckByID(ctx context.Context, userID, id int) (*models.Deck, error) {
+	defer r.store.lock(ctx)()
+
+	deck, ok := r.store.data.decks[id]
+	if !ok || deck.UserID != userID {
+		return nil, models.NotFound("deck with id %d not found", id)
+	}
+	return &deck, nil
+}
+
+// GetAllDecks returns the user's decks by name
+func (r *MemoryDeckRepository) GetAllDecks(ctx context.Context, userID int) ([]*models.Deck, error) {
+	defer r.store.lock(ctx)()
+
+	decks := make([]*models.Deck, 0)
+	for _, deck := range r.store.data.decks {
+		if deck.UserID == userID {
+			decks = append(decks, &deck)
+		}
+	}
+	slices.SortFunc(decks, func(a, b *models.Deck) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), a.ID-b.ID)
+	})
+	return decks, nil
+}
+
+func (r *MemoryDeckRepository) UpdateDeck(ctx context.Context, userID, id int, updates map[string]any) error {
+	if len(updates) == 0 {
+		return models.Invalid("no updates provided")
+	}
+
+	defer r.store.lock(ctx)()
+
+	deck, ok := r.store.data.decks[id]
+	if !ok || deck.UserID != userID {
+		return models.NotFound("deck with id %d not found", id)
+	}
+
+	for field, value := range updates {
+		var err error
+		switch field {
+		case "name":
+			deck.Name, err = memoryStringUpdate(field, value)
+		case "description":
+			deck.Description, err = memoryStringUpdate(field, value)
+		case "reviewOrder":
+			deck.ReviewOrder, err = memoryStringUpdate(field, value)
+		case "parent_id":
+			deck.ParentID, err = memoryIntUpdate(field, value)
+		default:
+			err = fmt.Errorf("unknown deck field %q", field)
+		}
+		if err != nil {
+			return models.Internal("failed to update deck: %w", err)
+		}
+	}
+
+	deck.UpdatedAt = r.store.now()
+	r.store.data.decks[id] = deck
+	return nil
+}
+
+// DeleteDeck deletes the deck with its terminology and study settings.
+// Its subdecks move to the top level, and its notes and flashcards out of
+// any deck.
+func (r *MemoryDeckRepository) DeleteDeck(ctx context.Context, userID, id int) error {
+	defer r.store.lock(ctx)()
+
+	deck, ok := r.store.data.decks[id]
+	if !ok || deck.UserID != userID {
+		return models.NotFound("deck with id %d not found", id)
+	}
+
+	delete(r.store.data.decks, id)
+	delete(r.store.data.terminology, id)
+	delete(r.store.data.studySettings, id)
+
+	for childID, child := range r.store.data.decks {
+		if child.ParentID != nil && *child.ParentID == id {
+			child.ParentID = nil
+			r.store.data.decks[childID] = child
+		}
+	}
+	for noteID, note := range r.store.data.notes {
+		if note.DeckID != nil && *note.DeckID == id {
+			note.DeckID = nil
+			r.store.data.notes[noteID] = note
+		}
+	}
+	for flashcardID, flashcard := range r.store.data.flashcards {
+		if flashcard.DeckID != nil && *flashcard.DeckID == id {
+			flashcard.DeckID = nil
+			r.store.data.flashcards[flashcardID] = flashcard
+		}
+	}
+	for jobID, job := range r.store.data.importJobs {
+		if job.DeckID != nil && *job.DeckID == id {
+			job.DeckID = nil
+			r.store.data.importJobs[jobID] = job
+		}
+	}
+	for exportID, export := range r.store.data.deckExports {
+		if export.DeckID != nil && *export.DeckID == id {
+			export.DeckID = nil
+			r.store.data.deckExports[exportID] = export
+		}
+	}
+
+	r.store.unpublishDeck(id)
+	maps.DeleteFunc(r.store.data.similarityFlags, func(_ int, flag models.SimilarityFlag) bool {
+		return flag.DeckID == id || flag.MatchedDeckID == id
+	})
+	maps.DeleteFunc(r.store.data.deckSuggestions, func(_ int, suggestion models.DeckSuggestion) bool {
+		return suggestion.DeckID == id
+	})
+	maps.DeleteFunc(r.store.data.dailyQuestions, func(_ int, subscription models.DailyQuestionSubscription) bool {
+		return subscription.DeckID == id
+	})
+	maps.DeleteFunc(r.store.data.embeds, func(_ int, row memoryEmbed) bool {
+		return row.embed.DeckID != nil && *row.embed.DeckID == id
+	})
+
+	return nil
+}
+
+// GetDeckTreeIDs returns the deck and all of its subdecks, at any depth
+func (r *MemoryDeckRepository) GetDeckTreeIDs(ctx context.Context, userID, id int) ([]int, error) {
+	defer r.store.lock(ctx)()
+
+	if deck, ok := r.store.data.decks[id]; !ok || deck.UserID != userID {
+		return nil, models.NotFound("deck with id %d not found", id)
+	}
+
+	deckIDs := slices.Sorted(maps.Keys(r.store.data.decks))
+	ids := []int{id}
+	for i := 0; i < len(ids); i++ {
+		for _, deckID := range deckIDs {
+			deck := r.store.data.decks[deckID]
+			if deck.UserID == userID && deck.ParentID != nil && *deck.ParentID == ids[i] && !slices.Contains(ids, deck.ID) {
+				ids = append(ids, deck.ID)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// GetTerminology returns the terminology of the user's decks among deckIDs.
+// Decks without a saved dictionary are left out.
+func (r *MemoryDeckRepository) GetTerminology(ctx context.Context, userID int, deckIDs []int) ([]*models.DeckTerminology, error) {
+	defer r.store.lock(ctx)()
+
+	terminologies := make([]*models.DeckTerminology, 0)
+	for _, deckID := range slices.Compact(slices.Sorted(slices.Values(deckIDs))) {
+		terminology, ok := r.store.data.terminology[deckID]
+		if ok && r.store.data.decks[deckID].UserID == userID {
+			terminologies = append(terminologies, &terminology)
+		}
+	}
+	return terminologies, nil
+}
+
+// SaveTerminology replaces a deck's terminology dictionary
+func (r *MemoryDeckRepository) SaveTerminology(ctx context.Context, terminology *models.DeckTerminology) error {
+	defer r.store.lock(ctx)()
+
+	if _, ok := r.store.data.decks[terminology.DeckID]; !ok {
+		return models.Internal("failed to save deck terminology: deck %d does not exist", terminology.DeckID)
+	}
+
+	terminology.UpdatedAt = r.store.now()
+	r.store.data.terminology[terminology.DeckID] = *terminology
+	return nil
+}
+
+// GetStudySettings returns the saved study settings of one of the user's decks
+func (r *MemoryDeckRepository) GetStudySettings(ctx context.Context, userID, deckID int) (*models.DeckStudySettings, error) {
+	defer r.store.lock(ctx)()
+
+	settings, ok := r.store.data.studySettings[deckID]
+	if !ok || r.store.data.decks[deckID].UserID != userID {
+		return nil, models.NotFound("study settings for deck with id %d not found", deckID)
+	}
+	return &settings, nil
+}
+
+// SaveStudySettings replaces a deck's study settings
+func (r *MemoryDeckRepository) SaveStudySettings(ctx context.Context, settings *models.DeckStudySettings) error {
+	defer r.store.lock(ctx)()
+
+	if _, ok := r.store.data.decks[settings.DeckID]; !ok {
+		return models.Internal("failed to save study settings: deck %d does not exist", settings.DeckID)
+	}
+
+	settings.UpdatedAt = r.store.now()
+	r.store.data.studySettings[settings.DeckID] = *settings
+	return nil
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+type MemoryDeckExportRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryDeckExportRepository(store *MemoryStore) *MemoryDeckExportRepository {
+	return &MemoryDeckExportRepository{store: store}
+}
+
+func (r *MemoryDeckExportRepository) CreateExport(ctx context.Context, export *models.StoredDeckExport) error {
+	defer r.store.lock(ctx)()
+
+	export.ID, export.CreatedAt = r.store.nextID("deck_exports"), r.store.now()
+	stored := *export
+	stored.DownloadURL = ""
+	r.store.data.deckExports[export.ID] = stored
+	return nil
+}
+
+// GetExport returns one of the user's exports that has not expired
+func (r *MemoryDeckExportRepository) GetExport(ctx context.Context, userID, id int) (*models.StoredDeckExport, error) {
+	defer r.store.lock(ctx)()
+
+	export, ok := r.store.data.deckExports[id]
+	if !ok || export.UserID != userID || !export.ExpiresAt.After(r.store.now()) {
+		return nil, models.NotFound("deck export with id %d not found", id)
+	}
+	return &export, nil
+}
+
+// GetExports returns the user's exports that have not expired, newest
+// first
+func (r *MemoryDeckExportRepository) GetExports(ctx context.Context, userID int) ([]*models.StoredDeckExport, error) {
+	defer r.store.lock(ctx)()
+
+	now := r.store.now()
+	exports := r.exports(func(export models.StoredDeckExport) bool {
+		return export.UserID == userID && export.ExpiresAt.After(now)
+	})
+	slices.SortFunc(exports, func(a, b *models.StoredDeckExport) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), b.ID-a.ID)
+	})
+	return exports, nil
+}
+
+// GetExpiredExports returns up to limit exports that expired by now,
+// oldest first
+func (r *MemoryDeckExportRepository) GetExpiredExports(ctx context.Context, now time.Time, limit int) ([]*models.StoredDeckExport, error) {
+	defer r.store.lock(ctx)()
+
+	exports := r.exports(func(export models.StoredDeckExport) bool { return !export.ExpiresAt.After(now) })
+	slices.SortFunc(exports, func(a, b *models.StoredDeckExport) int {
+		return cmp.Or(a.ExpiresAt.Compare(b.ExpiresAt), a.ID-b.ID)
+	})
+	return exports[:min(limit, len(exports))], nil
+}
+
+// exports returns the matching exports. Callers hold mu.
+func (r *MemoryDeckExportRepository) exports(match func(models.StoredDeckExport) bool) []*models.StoredDeckExport {
+	exports := make([]*models.StoredDeckExport, 0)
+	for _, export := range r.store.data.deckExports {
+		if match(export) {
+			exports = append(exports, &export)
+		}
+	}
+	return exports
+}
+
+func (r *MemoryDeckExportRepository) DeleteExport(ctx context.Context, id int) error {
+	defer r.store.lock(ctx)()
+
+	delete(r.store.data.deckExports, id)
+	return nil
+}
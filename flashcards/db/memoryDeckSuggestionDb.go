@@ -0,0 +1,196 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"flashcards/models"
+)
+
+type MemoryDeckSuggestionRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryDeckSuggestionRepository(store *MemoryStore) *MemoryDeckSuggestionRepository {
+	return &MemoryDeckSuggestionRepository{store: store}
+}
+
+// memorySuggestionGroup groups cards of one deck into a suggestion
+type memorySuggestionGroup struct {
+	userID int
+	deckID int
+	kind   string
+	by     string
+}
+
+// FindSuggestions analyzes the cards of every deck. Duplicates are cards of
+// a deck whose content matches ignoring case and surrounding space; leeches
+// have lapsed at least leechLapses times; stale cards come from a note
+// updated after them, grouped per note; untagged cards come from notes
+// without tags, grouped per deck.
+func (r *MemoryDeckSuggestionRepository) FindSuggestions(ctx context.Context, leechLapses int) ([]*models.DeckSuggestion, error) {
+	defer r.store.lock(ctx)()
+
+	groups := make(map[memorySuggestionGroup]*models.DeckSuggestion)
+	var order []memorySuggestionGroup
+	add := func(group memorySuggestionGroup, flashcardID int, noteID *int) {
+		suggestion, ok := groups[group]
+		if !ok {
+			suggestion = &models.DeckSuggestion{UserID: group.userID, DeckID: group.deckID, Kind: group.kind,
+				FlashcardIDs: []int{}, NoteIDs: []int{}, Status: models.SuggestionOpen}
+			groups[group] = suggestion
+			order = append(order, group)
+		}
+		suggestion.FlashcardIDs = append(suggestion.FlashcardIDs, flashcardID)
+		if noteID != nil && !slices.Contains(suggestion.NoteIDs, *noteID) {
+			suggestion.NoteIDs = append(suggestion.NoteIDs, *noteID)
+		}
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(r.store.data.flashcards)) {
+		flashcard := r.store.data.flashcards[id]
+		if flashcard.DeckID == nil {
+			continue
+		}
+		deck := memorySuggestionGroup{userID: flashcard.UserID, deckID: *flashcard.DeckID}
+
+		add(memorySuggestionGroup{deck.userID, deck.deckID, models.SuggestionDuplicate,
+			strings.ToLower(strings.TrimSpace(flashcard.Content))}, id, nil)
+		if schedule, ok := r.store.data.schedules[id]; ok && schedule.Lapses >= leechLapses {
+			add(memorySuggestionGroup{deck.userID, deck.deckID, models.SuggestionLeech, strconv.Itoa(id)}, id, nil)
+		}
+		if flashcard.SourceNoteID == nil {
+			continue
+		}
+		note, ok := r.store.data.notes[*flashcard.SourceNoteID]
+		if !ok {
+			continue
+		}
+		if note.UpdatedAt.After(flashcard.UpdatedAt) {
+			add(memorySuggestionGroup{deck.userID, deck.deckID, models.SuggestionStale, strconv.Itoa(note.ID)}, id, &note.ID)
+		}
+		if len(note.Tags) == 0 {
+			add(memorySuggestionGroup{deck.userID, deck.deckID, models.SuggestionUntagged, ""}, id, &note.ID)
+		}
+	}
+
+	suggestions := make([]*models.DeckSuggestion, 0)
+	for _, group := range order {
+		suggestion := groups[group]
+		if group.kind == models.SuggestionDuplicate && len(suggestion.FlashcardIDs) < 2 {
+			continue
+		}
+		slices.Sort(suggestion.NoteIDs)
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, nil
+}
+
+// ReplaceOpenSuggestions swaps all open suggestions for the given ones, and
+// reports how many were stored. Suggestions matching one dismissed earlier,
+// for the same deck, kind and cards, are skipped.
+func (r *MemoryDeckSuggestionRepository) ReplaceOpenSuggestions(ctx context.Context, suggestions []*models.DeckSuggestion) (int, error) {
+	defer r.store.lock(ctx)()
+
+	maps.DeleteFunc(r.store.data.deckSuggestions, func(_ int, suggestion models.DeckSuggestion) bool {
+		return suggestion.Status == models.SuggestionOpen
+	})
+
+	now := r.store.now()
+	stored := 0
+	for _, suggestion := range suggestions {
+		dismissed := false
+		for _, existing := range r.store.data.deckSuggestions {
+			dismissed = dismissed || existing.Status == models.SuggestionDismissed && existing.DeckID == suggestion.DeckID &&
+				existing.Kind == suggestion.Kind && slices.Equal(existing.FlashcardIDs, suggestion.FlashcardIDs)
+		}
+		if dismissed {
+			continue
+		}
+
+		id := r.store.nextID("deck_suggestions")
+		r.store.data.deckSuggestions[id] = models.DeckSuggestion{
+			ID:           id,
+			UserID:       suggestion.UserID,
+			DeckID:       suggestion.DeckID,
+			Kind:         suggestion.Kind,
+			FlashcardIDs: slices.Clone(suggestion.FlashcardIDs),
+			NoteIDs:      slices.Clone(suggestion.NoteIDs),
+			Status:       models.SuggestionOpen,
+			CreatedAt:    now,
+		}
+		stored++
+	}
+	return stored, nil
+}
+
+// ListOpenSuggestions returns the deck's open suggestions by kind, then
+// oldest card first
+func (r *MemoryDeckSuggestionRepository) ListOpenSuggestions(ctx context.Context, userID, deckID int) ([]*models.DeckSuggestion, error) {
+	defer r.store.lock(ctx)()
+
+	suggestions := make([]*models.DeckSuggestion, 0)
+	for _, suggestion := range r.store.data.deckSuggestions {
+		if suggestion.UserID == userID && suggestion.DeckID == deckID && suggestion.Status == models.SuggestionOpen {
+			suggestions = append(suggestions, memoryDeckSuggestion(suggestion))
+		}
+	}
+	slices.SortFunc(suggestions, func(a, b *models.DeckSuggestion) int {
+		return cmp.Or(cmp.Compare(a.Kind, b.Kind), slices.Compare(a.FlashcardIDs[:min(1, len(a.FlashcardIDs))],
+			b.FlashcardIDs[:min(1, len(b.FlashcardIDs))]), a.ID-b.ID)
+	})
+	return suggestions, nil
+}
+
+func (r *MemoryDeckSuggestionRepository) GetSuggestion(ctx context.Context, userID, deckID, id int) (*models.DeckSuggestion, error) {
+	defer r.store.lock(ctx)()
+
+	suggestion, ok := r.store.data.deckSuggestions[id]
+	if !ok || suggestion.UserID != userID || suggestion.DeckID != deckID {
+		return nil, models.NotFound("suggestion with id %d not found", id)
+	}
+	return memoryDeckSuggestion(suggestion), nil
+}
+
+// ResolveSuggestion marks an open suggestion applied or dismissed. One
+// resolved already is a conflict.
+func (r *MemoryDeckSuggestionRepository) ResolveSuggestion(ctx context.Context, userID, deckID, id int, status string) (*models.DeckSuggestion, error) {
+	defer r.store.lock(ctx)()
+
+	suggestion, ok := r.store.data.deckSuggestions[id]
+	if !ok || suggestion.UserID != userID || suggestion.DeckID != deckID {
+		return nil, models.NotFound("suggestion with id %d not found", id)
+	}
+	if suggestion.Status != models.SuggestionOpen {
+		return nil, models.Conflict("suggestion %d is already %s", id, suggestion.Status)
+	}
+
+	now := r.store.now()
+	suggestion.Status, suggestion.ResolvedAt = status, &now
+	r.store.data.deckSuggestions[id] = suggestion
+	return memoryDeckSuggestion(suggestion), nil
+}
+
+// ResetSchedules drops the scheduling state of the user's cards, which
+// makes them new again
+func (r *MemoryDeckSuggestionRepository) ResetSchedules(ctx context.Context, userID int, flashcardIDs []int) error {
+	defer r.store.lock(ctx)()
+
+	for _, id := range flashcardIDs {
+		if flashcard, ok := r.store.data.flashcards[id]; ok && flashcard.UserID == userID {
+			delete(r.store.data.schedules, id)
+		}
+	}
+	return nil
+}
+
+// memoryDeckSuggestion copies a stored suggestion for a caller
+func memoryDeckSuggestion(suggestion models.DeckSuggestion) *models.DeckSuggestion {
+	suggestion.FlashcardIDs = slices.Clone(suggestion.FlashcardIDs)
+	suggestion.NoteIDs = slices.Clone(suggestion.NoteIDs)
+	return &suggestion
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+type MemoryDigestRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryDigestRepository(store *MemoryStore) *MemoryDigestRepository {
+	return &MemoryDigestRepository{store: store}
+}
+
+// GetDigestCards returns, for every user, the cards rated again in a review
+// between since and until or created in that window, ordered by user with
+// failed cards first
+func (r *MemoryDigestRepository) GetDigestCards(ctx context.Context, since, until time.Time) ([]*models.DigestCard, error) {
+	defer r.store.lock(ctx)()
+
+	inWindow := func(at time.Time) bool { return !at.Before(since) && at.Before(until) }
+
+	failed := make(map[int]bool)
+	for _, review := range r.store.data.reviews {
+		if review.Rating == "again" && inWindow(review.ReviewedAt) {
+			failed[review.FlashcardID] = true
+		}
+	}
+
+	cards := make([]*models.DigestCard, 0)
+	for _, id := range slices.Sorted(maps.Keys(r.store.data.flashcards)) {
+		flashcard := r.store.data.flashcards[id]
+		user, ok := r.store.data.users[flashcard.UserID]
+		if !ok || !failed[id] && !inWindow(flashcard.CreatedAt) {
+			continue
+		}
+		cards = append(cards, &models.DigestCard{UserID: user.ID, Email: user.Email, Flashcard: &flashcard, Failed: failed[id]})
+	}
+
+	slices.SortStableFunc(cards, func(a, b *models.DigestCard) int {
+		if a.UserID != b.UserID {
+			return a.UserID - b.UserID
+		}
+		switch {
+		case a.Failed && !b.Failed:
+			return -1
+		case !a.Failed && b.Failed:
+			return 1
+		}
+		return 0
+	})
+	return cards, nil
+}
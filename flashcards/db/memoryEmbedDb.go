@@ -0,0 +1,72 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"flashcards/models"
+)
+
+// memoryEmbed is a row of embeds
+type memoryEmbed struct {
+	embed     models.Embed
+	tokenHash string
+}
+
+type MemoryEmbedRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryEmbedRepository(store *MemoryStore) *MemoryEmbedRepository {
+	return &MemoryEmbedRepository{store: store}
+}
+
+func (r *MemoryEmbedRepository) CreateEmbed(ctx context.Context, embed *models.Embed, tokenHash string) error {
+	defer r.store.lock(ctx)()
+
+	embed.ID, embed.CreatedAt = r.store.nextID("embeds"), r.store.now()
+	embed.Kind = embedKind(embed)
+	stored := *embed
+	stored.Token = ""
+	r.store.data.embeds[embed.ID] = memoryEmbed{embed: stored, tokenHash: tokenHash}
+	return nil
+}
+
+func (r *MemoryEmbedRepository) GetEmbedByTokenHash(ctx context.Context, tokenHash string) (*models.Embed, error) {
+	defer r.store.lock(ctx)()
+
+	for _, row := range r.store.data.embeds {
+		if row.tokenHash == tokenHash {
+			return &row.embed, nil
+		}
+	}
+	return nil, models.NotFound("embed not found")
+}
+
+// ListEmbeds returns the user's embeds, newest first
+func (r *MemoryEmbedRepository) ListEmbeds(ctx context.Context, userID int) ([]*models.Embed, error) {
+	defer r.store.lock(ctx)()
+
+	embeds := make([]*models.Embed, 0)
+	for _, row := range r.store.data.embeds {
+		if row.embed.UserID == userID {
+			embeds = append(embeds, &row.embed)
+		}
+	}
+	slices.SortFunc(embeds, func(a, b *models.Embed) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), b.ID-a.ID)
+	})
+	return embeds, nil
+}
+
+func (r *MemoryEmbedRepository) DeleteEmbed(ctx context.Context, userID, id int) error {
+	defer r.store.lock(ctx)()
+
+	row, ok := r.store.data.embeds[id]
+	if !ok || row.embed.UserID != userID {
+		return models.NotFound("embed with id %d not found", id)
+	}
+	delete(r.store.data.embeds, id)
+	return nil
+}
@@ -0,0 +1,174 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+type MemoryFlashcardRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryFlashcardRepository(store *MemoryStore) *MemoryFlashcardRepository {
+	return &MemoryFlashcardRepository{store: store}
+}
+
+func (r *MemoryFlashcardRepository) CreateFlashcard(ctx context.Context, flashcard *models.Flashcard) error {
+	defer r.store.lock(ctx)()
+
+	r.createFlashcard(flashcard)
+	return nil
+}
+
+// CreateFlashcards inserts a batch of flashcards
+func (r *MemoryFlashcardRepository) CreateFlashcards(ctx context.Context, flashcards []*models.Flashcard) error {
+	defer r.store.lock(ctx)()
+
+	for _, flashcard := range flashcards {
+		r.createFlashcard(flashcard)
+	}
+	return nil
+}
+
+func (r *MemoryFlashcardRepository) createFlashcard(flashcard *models.Flashcard) {
+	syncFlashcardSides(flashcard)
+	now := r.store.now()
+	flashcard.ID, flashcard.Version, flashcard.CreatedAt, flashcard.UpdatedAt = r.store.nextID("flashcards"), 1, now, now
+	r.store.data.flashcards[flashcard.ID] = *flashcard
+}
+
+func (r *MemoryFlashcardRepository) GetFlashcardByID(ctx context.Context, userID, id int) (*models.Flashcard, error) {
+	defer r.store.lock(ctx)()
+
+	flashcard, ok := r.store.data.flashcards[id]
+	if !ok || flashcard.UserID != userID {
+		return nil, models.NotFound("flashcard with id %d not found", id)
+	}
+	return &flashcard, nil
+}
+
+// ListFlashcards returns one page of the user's flashcards matching the
+// filter, along with the total number of matching flashcards
+func (r *MemoryFlashcardRepository) ListFlashcards(ctx context.Context, userID int, filter models.FlashcardFilter, params models.ListParams) ([]*models.Flashcard, int, error) {
+	flashcards := r.userFlashcards(ctx, userID, func(flashcard models.Flashcard) bool {
+		return (filter.DeckID == nil || flashcard.DeckID != nil && *flashcard.DeckID == *filter.DeckID) &&
+			(filter.Query == "" || memoryContains(flashcard.Content, filter.Query))
+	})
+
+	page, err := memoryPage(flashcards, params,
+		func(f *models.Flashcard) time.Time { return f.CreatedAt },
+		func(f *models.Flashcard) time.Time { return f.UpdatedAt },
+		func(f *models.Flashcard) int { return f.ID })
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return page, len(flashcards), nil
+}
+
+// GetFlashcardsByDecks returns all of the user's flashcards in deckIDs,
+// oldest first
+func (r *MemoryFlashcardRepository) GetFlashcardsByDecks(ctx context.Context, userID int, deckIDs []int) ([]*models.Flashcard, error) {
+	return r.userFlashcards(ctx, userID, func(flashcard models.Flashcard) bool {
+		return flashcard.DeckID != nil && slices.Contains(deckIDs, *flashcard.DeckID)
+	}), nil
+}
+
+// GetFlashcardIDsByContentHash maps each hash that matches one of the
+// user's flashcards to the oldest such flashcard's ID
+func (r *MemoryFlashcardRepository) GetFlashcardIDsByContentHash(ctx context.Context, userID int, hashes []string) (map[string]int, error) {
+	ids := make(map[string]int)
+	for _, flashcard := range r.userFlashcards(ctx, userID, func(models.Flashcard) bool { return true }) {
+		hash := flashcardContentHash(flashcard.Content)
+		if _, seen := ids[hash]; !seen && slices.Contains(hashes, hash) {
+			ids[hash] = flashcard.ID
+		}
+	}
+	return ids, nil
+}
+
+// userFlashcards returns the user's flashcards that match, by ID
+func (r *MemoryFlashcardRepository) userFlashcards(ctx context.Context, userID int, match func(models.Flashcard) bool) []*models.Flashcard {
+	defer r.store.lock(ctx)()
+
+	flashcards := make([]*models.Flashcard, 0)
+	for _, id := range slices.Sorted(maps.Keys(r.store.data.flashcards)) {
+		if flashcard := r.store.data.flashcards[id]; flashcard.UserID == userID && match(flashcard) {
+			flashcards = append(flashcards, &flashcard)
+		}
+	}
+	return flashcards
+}
+
+// UpdateFlashcard applies the updates if the flashcard is still at version,
+// and bumps its version. A flashcard changed since is a conflict.
+func (r *MemoryFlashcardRepository) UpdateFlashcard(ctx context.Context, userID, id, version int, updates map[string]any) error {
+	if len(updates) == 0 {
+		return models.Invalid("no updates provided")
+	}
+
+	defer r.store.lock(ctx)()
+
+	flashcard, ok := r.store.data.flashcards[id]
+	if !ok || flashcard.UserID != userID {
+		return models.NotFound("flashcard with id %d not found", id)
+	}
+	if flashcard.Version != version {
+		return models.Conflict("flashcard %d is at version %d, not %d; fetch it again and retry", id, flashcard.Version, version)
+	}
+
+	for field, value := range updates {
+		var err error
+		switch field {
+		case "front":
+			flashcard.Front, err = memoryStringUpdate(field, value)
+		case "back":
+			flashcard.Back, err = memoryStringUpdate(field, value)
+		case "hint":
+			flashcard.Hint, err = memoryStringUpdate(field, value)
+		case "mnemonic":
+			flashcard.Mnemonic, err = memoryStringUpdate(field, value)
+		case "content":
+			flashcard.Content, err = memoryStringUpdate(field, value)
+		case "deck_id":
+			flashcard.DeckID, err = memoryIntUpdate(field, value)
+		case "source_note_id":
+			flashcard.SourceNoteID, err = memoryIntUpdate(field, value)
+		default:
+			err = fmt.Errorf("unknown flashcard field %q", field)
+		}
+		if err != nil {
+			return models.Internal("failed to update flashcard: %w", err)
+		}
+	}
+
+	flashcard.Version++
+	flashcard.UpdatedAt = r.store.now()
+	r.store.data.flashcards[id] = flashcard
+	return nil
+}
+
+func (r *MemoryFlashcardRepository) DeleteFlashcard(ctx context.Context, userID, id int) error {
+	defer r.store.lock(ctx)()
+
+	flashcard, ok := r.store.data.flashcards[id]
+	if !ok || flashcard.UserID != userID {
+		return models.NotFound("flashcard with id %d not found", id)
+	}
+	delete(r.store.data.flashcards, id)
+	delete(r.store.data.schedules, id)
+	maps.DeleteFunc(r.store.data.reviews, func(_ int, review models.Review) bool {
+		return review.FlashcardID == id
+	})
+	maps.DeleteFunc(r.store.data.embeds, func(_ int, row memoryEmbed) bool {
+		return row.embed.FlashcardID != nil && *row.embed.FlashcardID == id
+	})
+	delete(r.store.data.vocabulary, id)
+	delete(r.store.data.cardEmbeddings, id)
+	return nil
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+type memoryIdempotencyKey struct {
+	userID int
+	key    string
+}
+
+type MemoryIdempotencyRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryIdempotencyRepository(store *MemoryStore) *MemoryIdempotencyRepository {
+	return &MemoryIdempotencyRepository{store: store}
+}
+
+// ReserveKey claims the key for a new request. It succeeds when the key is
+// unused, expired, or held by an in-progress request that started before
+// staleBefore; otherwise it returns false.
+func (r *MemoryIdempotencyRepository) ReserveKey(ctx context.Context, record *models.IdempotencyRecord, staleBefore time.Time) (bool, error) {
+	defer r.store.lock(ctx)()
+
+	now := r.store.now()
+	key := memoryIdempotencyKey{record.UserID, record.Key}
+	if existing, ok := r.store.data.idempotencyKeys[key]; ok && !existing.ExpiresAt.Before(now) &&
+		(existing.StatusCode != nil || !existing.CreatedAt.Before(staleBefore)) {
+		return false, nil
+	}
+
+	record.CreatedAt = now
+	r.store.data.idempotencyKeys[key] = models.IdempotencyRecord{
+		UserID:      record.UserID,
+		Key:         record.Key,
+		RequestHash: record.RequestHash,
+		CreatedAt:   now,
+		ExpiresAt:   record.ExpiresAt,
+	}
+	return true, nil
+}
+
+func (r *MemoryIdempotencyRepository) GetKey(ctx context.Context, userID int, key string) (*models.IdempotencyRecord, error) {
+	defer r.store.lock(ctx)()
+
+	record, ok := r.store.data.idempotencyKeys[memoryIdempotencyKey{userID, key}]
+	if !ok {
+		return nil, models.NotFound("idempotency key %s not found", key)
+	}
+	record.ResponseBody = slices.Clone(record.ResponseBody)
+	return &record, nil
+}
+
+// CompleteKey stores the response to replay for the key
+func (r *MemoryIdempotencyRepository) CompleteKey(ctx context.Context, userID int, key string, statusCode int, body []byte) error {
+	defer r.store.lock(ctx)()
+
+	if record, ok := r.store.data.idempotencyKeys[memoryIdempotencyKey{userID, key}]; ok {
+		record.StatusCode, record.ResponseBody = &statusCode, slices.Clone(body)
+		r.store.data.idempotencyKeys[memoryIdempotencyKey{userID, key}] = record
+	}
+	return nil
+}
+
+func (r *MemoryIdempotencyRepository) DeleteKey(ctx context.Context, userID int, key string) error {
+	defer r.store.lock(ctx)()
+
+	delete(r.store.data.idempotencyKeys, memoryIdempotencyKey{userID, key})
+	return nil
+}
+
+func (r *MemoryIdempotencyRepository) DeleteExpiredKeys(ctx context.Context) (int64, error) {
+	defer r.store.lock(ctx)()
+
+	now := r.store.now()
+	var deleted int64
+	for key, record := range r.store.data.idempotencyKeys {
+		if record.ExpiresAt.Before(now) {
+			delete(r.store.data.idempotencyKeys, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
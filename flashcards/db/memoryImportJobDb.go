@@ -0,0 +1,190 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+// memoryImportItem is a row of import_job_items
+type memoryImportItem struct {
+	item      models.ImportJobItem
+	committed bool
+}
+
+type MemoryImportJobRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryImportJobRepository(store *MemoryStore) *MemoryImportJobRepository {
+	return &MemoryImportJobRepository{store: store}
+}
+
+// CreateImportJob stores the job together with all of its items
+func (r *MemoryImportJobRepository) CreateImportJob(ctx context.Context, job *models.ImportJob, items []models.ImportJobItem) error {
+	defer r.store.lock(ctx)()
+
+	now := r.store.now()
+	job.ID, job.CreatedAt, job.UpdatedAt = r.store.nextID("import_jobs"), now, now
+	job.TotalChunks = totalImportChunks(job)
+
+	stored := *job
+	stored.Skipped = slices.Clone(job.Skipped)
+	r.store.data.importJobs[job.ID] = stored
+
+	rows := make([]memoryImportItem, len(items))
+	for i, item := range items {
+		rows[i] = memoryImportItem{item: item}
+	}
+	r.store.data.importItems[job.ID] = rows
+	return nil
+}
+
+func (r *MemoryImportJobRepository) GetImportJob(ctx context.Context, userID, id int) (*models.ImportJob, error) {
+	defer r.store.lock(ctx)()
+
+	job, ok := r.store.data.importJobs[id]
+	if !ok || job.UserID != userID {
+		return nil, models.NotFound("import job with id %d not found", id)
+	}
+	return memoryImportJob(job), nil
+}
+
+// ListImportJobs returns the user's import jobs, newest first
+func (r *MemoryImportJobRepository) ListImportJobs(ctx context.Context, userID int) ([]*models.ImportJob, error) {
+	defer r.store.lock(ctx)()
+
+	jobs := make([]*models.ImportJob, 0)
+	for _, job := range r.store.data.importJobs {
+		if job.UserID == userID {
+			jobs = append(jobs, memoryImportJob(job))
+		}
+	}
+	slices.SortFunc(jobs, func(a, b *models.ImportJob) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), b.ID-a.ID)
+	})
+	return jobs, nil
+}
+
+// GetNextImportChunk returns the first chunk of the job with uncommitted
+// items and how many it has, or false once every chunk is committed
+func (r *MemoryImportJobRepository) GetNextImportChunk(ctx context.Context, jobID int) (int, int, bool, error) {
+	defer r.store.lock(ctx)()
+
+	chunk, count := 0, 0
+	for _, row := range r.store.data.importItems[jobID] {
+		switch {
+		case row.committed:
+		case count == 0 || row.item.Chunk < chunk:
+			chunk, count = row.item.Chunk, 1
+		case row.item.Chunk == chunk:
+			count++
+		}
+	}
+	return chunk, count, count > 0, nil
+}
+
+// CommitImportChunk creates the notes for the chunk's uncommitted items and
+// advances the job's progress. It returns the number of notes created.
+func (r *MemoryImportJobRepository) CommitImportChunk(ctx context.Context, jobID, chunk int) (int, error) {
+	defer r.store.lock(ctx)()
+
+	job, ok := r.store.data.importJobs[jobID]
+	if !ok {
+		return 0, models.NotFound("import job with id %d not found", jobID)
+	}
+	if job.Status != models.ImportJobStatusRunning {
+		return 0, fmt.Errorf("import job %d is no longer running", jobID)
+	}
+	if job.Kind != models.ImportKindNotes {
+		return 0, fmt.Errorf("unsupported import kind: %s", job.Kind)
+	}
+
+	rows := slices.Clone(r.store.data.importItems[jobID])
+	slices.SortFunc(rows, func(a, b memoryImportItem) int { return a.item.Position - b.item.Position })
+
+	notes := &MemoryNoteRepository{store: r.store}
+	created := 0
+	for i, row := range rows {
+		if row.committed || row.item.Chunk != chunk {
+			continue
+		}
+		notes.createNote(&models.Note{UserID: job.UserID, DeckID: job.DeckID, Content: row.item.Content})
+		rows[i].committed = true
+		created++
+	}
+	if created == 0 {
+		return 0, nil
+	}
+	r.store.data.importItems[jobID] = rows
+
+	job.ProcessedItems += created
+	job.CommittedChunks++
+	job.UpdatedAt = r.store.now()
+	r.store.data.importJobs[jobID] = job
+	return created, nil
+}
+
+// ResumeImportJob moves a failed job back to running
+func (r *MemoryImportJobRepository) ResumeImportJob(ctx context.Context, userID, id int) error {
+	defer r.store.lock(ctx)()
+
+	job, ok := r.store.data.importJobs[id]
+	if !ok || job.UserID != userID {
+		return models.NotFound("import job with id %d not found", id)
+	}
+	if job.Status != models.ImportJobStatusFailed {
+		return models.Conflict("only a failed import job can be resumed")
+	}
+
+	job.Status, job.Error, job.UpdatedAt = models.ImportJobStatusRunning, nil, r.store.now()
+	r.store.data.importJobs[id] = job
+	return nil
+}
+
+// FinishImportJob completes or fails a running job. A job that is no
+// longer running keeps its status.
+func (r *MemoryImportJobRepository) FinishImportJob(ctx context.Context, id int, status string, message *string) error {
+	defer r.store.lock(ctx)()
+
+	job, ok := r.store.data.importJobs[id]
+	if !ok || job.Status != models.ImportJobStatusRunning {
+		return nil
+	}
+
+	now := r.store.now()
+	job.Status, job.Error, job.UpdatedAt = status, message, now
+	if status == models.ImportJobStatusCompleted {
+		job.CompletedAt = &now
+	}
+	r.store.data.importJobs[id] = job
+	return nil
+}
+
+// FailStaleImportJobs fails running jobs that have made no progress since
+// before and returns them
+func (r *MemoryImportJobRepository) FailStaleImportJobs(ctx context.Context, before time.Time, message string) ([]*models.ImportJob, error) {
+	defer r.store.lock(ctx)()
+
+	jobs := make([]*models.ImportJob, 0)
+	for id, job := range r.store.data.importJobs {
+		if job.Status != models.ImportJobStatusRunning || !job.UpdatedAt.Before(before) {
+			continue
+		}
+		job.Status, job.Error, job.UpdatedAt = models.ImportJobStatusFailed, &message, r.store.now()
+		r.store.data.importJobs[id] = job
+		jobs = append(jobs, memoryImportJob(job))
+	}
+	slices.SortFunc(jobs, func(a, b *models.ImportJob) int { return a.ID - b.ID })
+	return jobs, nil
+}
+
+// memoryImportJob copies a stored job for a caller
+func memoryImportJob(job models.ImportJob) *models.ImportJob {
+	job.Skipped = slices.Clone(job.Skipped)
+	return &job
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+
+	"flashcards/models"
+)
+
+// memoryInvite is a row of organization_invites
+type memoryInvite struct {
+	invite    models.OrganizationInvite
+	tokenHash string
+}
+
+type MemoryInviteRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryInviteRepository(store *MemoryStore) *MemoryInviteRepository {
+	return &MemoryInviteRepository{store: store}
+}
+
+func (r *MemoryInviteRepository) CreateInvite(ctx context.Context, invite *models.OrganizationInvite, tokenHash string) error {
+	defer r.store.lock(ctx)()
+
+	invite.ID, invite.CreatedAt = r.store.nextID("organization_invites"), r.store.now()
+	stored := *invite
+	stored.Token, stored.URL, stored.EmailSent = "", "", false
+	r.store.data.invites[invite.ID] = memoryInvite{invite: stored, tokenHash: tokenHash}
+	return nil
+}
+
+func (r *MemoryInviteRepository) GetInviteByTokenHash(ctx context.Context, tokenHash string) (*models.OrganizationInvite, error) {
+	defer r.store.lock(ctx)()
+
+	for _, row := range r.store.data.invites {
+		if row.tokenHash == tokenHash {
+			return &row.invite, nil
+		}
+	}
+	return nil, models.NotFound("invite not found")
+}
+
+// AcceptInvite marks the invite accepted and adds the user to its
+// organization. It fails when the invite was already used or the user
+// already belongs to an organization.
+func (r *MemoryInviteRepository) AcceptInvite(ctx context.Context, invite *models.OrganizationInvite, userID int) error {
+	defer r.store.lock(ctx)()
+
+	row, ok := r.store.data.invites[invite.ID]
+	if !ok || row.invite.AcceptedAt != nil {
+		return models.Conflict("invite has already been accepted")
+	}
+	if _, ok := r.store.data.memberships[userID]; ok {
+		return models.Conflict("you already belong to an organization")
+	}
+
+	now := r.store.now()
+	row.invite.AcceptedAt, row.invite.AcceptedBy = &now, &userID
+	r.store.data.invites[invite.ID] = row
+	r.store.data.memberships[userID] = models.Membership{OrganizationID: row.invite.OrganizationID, Role: row.invite.Role}
+
+	invite.AcceptedAt, invite.AcceptedBy = row.invite.AcceptedAt, row.invite.AcceptedBy
+	return nil
+}
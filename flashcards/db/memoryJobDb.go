@@ -0,0 +1,171 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+// memoryJob is a row of jobs. NextAttemptAt is kept apart from the job
+// since only the database uses it, as the lease of a running job.
+type memoryJob struct {
+	job           models.Job
+	nextAttemptAt *time.Time
+}
+
+type MemoryJobRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryJobRepository(store *MemoryStore) *MemoryJobRepository {
+	return &MemoryJobRepository{store: store}
+}
+
+func (r *MemoryJobRepository) CreateJob(ctx context.Context, job *models.Job) error {
+	defer r.store.lock(ctx)()
+
+	now := r.store.now()
+	*job = models.Job{
+		ID:        r.store.nextID("jobs"),
+		UserID:    job.UserID,
+		Kind:      job.Kind,
+		Payload:   slices.Clone(job.Payload),
+		Status:    models.JobStatusQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	r.store.data.jobs[job.ID] = memoryJob{job: *memoryJobCopy(*job), nextAttemptAt: &now}
+	return nil
+}
+
+func (r *MemoryJobRepository) GetJob(ctx context.Context, userID, id int) (*models.Job, error) {
+	defer r.store.lock(ctx)()
+
+	row, ok := r.store.data.jobs[id]
+	if !ok || row.job.UserID != userID {
+		return nil, models.NotFound("job with id %d not found", id)
+	}
+	return memoryJobCopy(row.job), nil
+}
+
+// ListJobs returns one page of the user's jobs matching the filter and the
+// total number that match
+func (r *MemoryJobRepository) ListJobs(ctx context.Context, userID int, filter models.JobFilter, params models.ListParams) ([]*models.Job, int, error) {
+	defer r.store.lock(ctx)()
+
+	jobs := make([]*models.Job, 0)
+	for _, row := range r.store.data.jobs {
+		if row.job.UserID == userID && (filter.Status == "" || row.job.Status == filter.Status) &&
+			(filter.Kind == "" || row.job.Kind == filter.Kind) {
+			jobs = append(jobs, memoryJobCopy(row.job))
+		}
+	}
+
+	page, err := memoryPage(jobs, params,
+		func(j *models.Job) time.Time { return j.CreatedAt },
+		func(j *models.Job) time.Time { return j.UpdatedAt },
+		func(j *models.Job) int { return j.ID })
+	if err != nil {
+		return nil, 0, err
+	}
+	return page, len(jobs), nil
+}
+
+// ClaimNextJob marks the oldest due job running and holds it for lease. It
+// returns nil when no job is due. A running job is only due again when its
+// worker stopped extending the lease.
+func (r *MemoryJobRepository) ClaimNextJob(ctx context.Context, lease time.Duration) (*models.Job, error) {
+	defer r.store.lock(ctx)()
+
+	now := r.store.now()
+	var next *memoryJob
+	for _, row := range r.store.data.jobs {
+		if (row.job.Status != models.JobStatusQueued && row.job.Status != models.JobStatusRunning) ||
+			row.nextAttemptAt == nil || row.nextAttemptAt.After(now) {
+			continue
+		}
+		if next == nil || cmp.Or(row.nextAttemptAt.Compare(*next.nextAttemptAt), row.job.ID-next.job.ID) < 0 {
+			next = &row
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+
+	leaseEnd := now.Add(lease)
+	next.nextAttemptAt = &leaseEnd
+	next.job.Status, next.job.UpdatedAt = models.JobStatusRunning, now
+	if next.job.StartedAt == nil {
+		next.job.StartedAt = &now
+	}
+	r.store.data.jobs[next.job.ID] = *next
+	return memoryJobCopy(next.job), nil
+}
+
+func (r *MemoryJobRepository) ExtendJobLease(ctx context.Context, id int, lease time.Duration) error {
+	defer r.store.lock(ctx)()
+
+	if row, ok := r.store.data.jobs[id]; ok && row.job.Status == models.JobStatusRunning {
+		now := r.store.now()
+		leaseEnd := now.Add(lease)
+		row.nextAttemptAt, row.job.UpdatedAt = &leaseEnd, now
+		r.store.data.jobs[id] = row
+	}
+	return nil
+}
+
+func (r *MemoryJobRepository) CompleteJob(ctx context.Context, id int, result []byte) error {
+	defer r.store.lock(ctx)()
+
+	if row, ok := r.store.data.jobs[id]; ok {
+		now := r.store.now()
+		row.job.Status, row.job.Result, row.job.Error = models.JobStatusCompleted, slices.Clone(result), ""
+		row.job.Attempts++
+		row.job.CompletedAt, row.job.UpdatedAt, row.nextAttemptAt = &now, now, nil
+		r.store.data.jobs[id] = row
+	}
+	return nil
+}
+
+// FailJobAttempt counts a failed attempt and queues the job again at
+// nextAttemptAt. Without a next attempt the job has failed for good.
+func (r *MemoryJobRepository) FailJobAttempt(ctx context.Context, id int, message string, nextAttemptAt *time.Time) error {
+	defer r.store.lock(ctx)()
+
+	row, ok := r.store.data.jobs[id]
+	if !ok {
+		return nil
+	}
+	now := r.store.now()
+	row.job.Attempts++
+	row.job.Error, row.nextAttemptAt, row.job.UpdatedAt = message, nextAttemptAt, now
+	row.job.Status, row.job.CompletedAt = models.JobStatusQueued, nil
+	if nextAttemptAt == nil {
+		row.job.Status, row.job.CompletedAt = models.JobStatusFailed, &now
+	}
+	r.store.data.jobs[id] = row
+	return nil
+}
+
+// ReleaseJob queues a running job again straight away without counting an
+// attempt, for a worker that is shutting down
+func (r *MemoryJobRepository) ReleaseJob(ctx context.Context, id int) error {
+	defer r.store.lock(ctx)()
+
+	if row, ok := r.store.data.jobs[id]; ok && row.job.Status == models.JobStatusRunning {
+		now := r.store.now()
+		row.job.Status, row.nextAttemptAt, row.job.UpdatedAt = models.JobStatusQueued, &now, now
+		r.store.data.jobs[id] = row
+	}
+	return nil
+}
+
+// memoryJobCopy copies a stored job for a caller
+func memoryJobCopy(job models.Job) *models.Job {
+	job.Payload = slices.Clone(job.Payload)
+	job.Result = slices.Clone(job.Result)
+	return &job
+}
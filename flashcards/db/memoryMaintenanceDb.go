@@ -0,0 +1,36 @@
+package db
+
+import (
+	"context"
+
+	"flashcards/models"
+)
+
+type MemoryMaintenanceRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryMaintenanceRepository(store *MemoryStore) *MemoryMaintenanceRepository {
+	return &MemoryMaintenanceRepository{store: store}
+}
+
+// GetMaintenance returns the saved maintenance window, or maintenance off
+// before any is saved
+func (r *MemoryMaintenanceRepository) GetMaintenance(ctx context.Context) (*models.Maintenance, error) {
+	defer r.store.lock(ctx)()
+
+	if r.store.data.maintenance == nil {
+		return &models.Maintenance{Mode: models.MaintenanceModeOff}, nil
+	}
+	maintenance := *r.store.data.maintenance
+	return &maintenance, nil
+}
+
+func (r *MemoryMaintenanceRepository) SaveMaintenance(ctx context.Context, maintenance *models.Maintenance) error {
+	defer r.store.lock(ctx)()
+
+	maintenance.UpdatedAt = r.store.now()
+	stored := *maintenance
+	r.store.data.maintenance = &stored
+	return nil
+}
@@ -0,0 +1,184 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+type MemoryNoteRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryNoteRepository(store *MemoryStore) *MemoryNoteRepository {
+	return &MemoryNoteRepository{store: store}
+}
+
+func (r *MemoryNoteRepository) CreateNote(ctx context.Context, note *models.Note) error {
+	defer r.store.lock(ctx)()
+
+	r.createNote(note)
+	return nil
+}
+
+// CreateNotes inserts a batch of notes
+func (r *MemoryNoteRepository) CreateNotes(ctx context.Context, notes []*models.Note) error {
+	defer r.store.lock(ctx)()
+
+	for _, note := range notes {
+		r.createNote(note)
+	}
+	return nil
+}
+
+func (r *MemoryNoteRepository) createNote(note *models.Note) {
+	now := r.store.now()
+	note.ID, note.Version, note.CreatedAt, note.UpdatedAt = r.store.nextID("notes"), 1, now, now
+	note.Tags = []string{}
+	r.store.data.notes[note.ID] = *note
+}
+
+func (r *MemoryNoteRepository) GetNoteByID(ctx context.Context, userID, id int) (*models.Note, error) {
+	defer r.store.lock(ctx)()
+
+	note, ok := r.store.data.notes[id]
+	if !ok || note.UserID != userID {
+		return nil, models.NotFound("note with id %d not found", id)
+	}
+	return memoryNote(note), nil
+}
+
+// GetAllNotes returns the user's notes, newest first
+func (r *MemoryNoteRepository) GetAllNotes(ctx context.Context, userID int) ([]*models.Note, error) {
+	notes, _, err := r.ListNotes(ctx, userID, models.NoteFilter{}, models.ListParams{Sort: "createdAt", Order: "desc"})
+	return notes, err
+}
+
+// ListNotes returns one page of the user's notes matching the filter, along
+// with the total number of matching notes
+func (r *MemoryNoteRepository) ListNotes(ctx context.Context, userID int, filter models.NoteFilter, params models.ListParams) ([]*models.Note, int, error) {
+	defer r.store.lock(ctx)()
+
+	notes := make([]*models.Note, 0)
+	for _, id := range slices.Sorted(maps.Keys(r.store.data.notes)) {
+		note := r.store.data.notes[id]
+		if note.UserID != userID ||
+			filter.Tag != "" && !slices.Contains(note.Tags, filter.Tag) ||
+			filter.DeckID != nil && (note.DeckID == nil || *note.DeckID != *filter.DeckID) ||
+			filter.Language != "" && note.Language != filter.Language ||
+			filter.Query != "" && !memoryContains(note.Title, filter.Query) && !memoryContains(note.Content, filter.Query) {
+			continue
+		}
+		notes = append(notes, memoryNote(note))
+	}
+
+	page, err := memoryPage(notes, params,
+		func(n *models.Note) time.Time { return n.CreatedAt },
+		func(n *models.Note) time.Time { return n.UpdatedAt },
+		func(n *models.Note) int { return n.ID })
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return page, len(notes), nil
+}
+
+// UpdateNote applies the updates if the note is still at version, and bumps
+// its version. A note changed since is a conflict.
+func (r *MemoryNoteRepository) UpdateNote(ctx context.Context, userID, id, version int, updates map[string]any) error {
+	if len(updates) == 0 {
+		return models.Invalid("no updates provided")
+	}
+
+	defer r.store.lock(ctx)()
+
+	note, ok := r.store.data.notes[id]
+	if !ok || note.UserID != userID {
+		return models.NotFound("note with id %d not found", id)
+	}
+	if note.Version != version {
+		return models.Conflict("note %d is at version %d, not %d; fetch it again and retry", id, note.Version, version)
+	}
+
+	for field, value := range updates {
+		var err error
+		switch field {
+		case "title":
+			note.Title, err = memoryStringUpdate(field, value)
+		case "summary":
+			note.Summary, err = memoryStringUpdate(field, value)
+		case "source":
+			note.Source, err = memoryStringUpdate(field, value)
+		case "language":
+			note.Language, err = memoryStringUpdate(field, value)
+		case "content":
+			note.Content, err = memoryStringUpdate(field, value)
+		case "deck_id":
+			note.DeckID, err = memoryIntUpdate(field, value)
+		default:
+			err = fmt.Errorf("unknown note field %q", field)
+		}
+		if err != nil {
+			return models.Internal("failed to update note: %w", err)
+		}
+	}
+
+	note.Version++
+	note.UpdatedAt = r.store.now()
+	r.store.data.notes[id] = note
+	return nil
+}
+
+// SetNoteSummary stores the summary of a note as long as its content is
+// still the content that was summarized. It leaves updatedAt alone.
+func (r *MemoryNoteRepository) SetNoteSummary(ctx context.Context, id int, content, summary string) error {
+	defer r.store.lock(ctx)()
+
+	if note, ok := r.store.data.notes[id]; ok && note.Content == content {
+		note.Summary = summary
+		r.store.data.notes[id] = note
+	}
+	return nil
+}
+
+// SetNoteLanguage stores a language detected in the background, under the
+// same condition as SetNoteSummary
+func (r *MemoryNoteRepository) SetNoteLanguage(ctx context.Context, id int, content, language string) error {
+	defer r.store.lock(ctx)()
+
+	if note, ok := r.store.data.notes[id]; ok && note.Content == content {
+		note.Language = language
+		r.store.data.notes[id] = note
+	}
+	return nil
+}
+
+// DeleteNote deletes the note; flashcards generated from it are kept
+// without a source note
+func (r *MemoryNoteRepository) DeleteNote(ctx context.Context, userID, id int) error {
+	defer r.store.lock(ctx)()
+
+	note, ok := r.store.data.notes[id]
+	if !ok || note.UserID != userID {
+		return models.NotFound("note with id %d not found", id)
+	}
+
+	delete(r.store.data.notes, id)
+	for flashcardID, flashcard := range r.store.data.flashcards {
+		if flashcard.SourceNoteID != nil && *flashcard.SourceNoteID == id {
+			flashcard.SourceNoteID = nil
+			r.store.data.flashcards[flashcardID] = flashcard
+		}
+	}
+	return nil
+}
+
+// memoryNote copies a stored note for a caller, who may change its tags
+func memoryNote(note models.Note) *models.Note {
+	note.Tags = slices.Clone(note.Tags)
+	return &note
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+
+	"flashcards/models"
+)
+
+type MemoryOnboardingRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryOnboardingRepository(store *MemoryStore) *MemoryOnboardingRepository {
+	return &MemoryOnboardingRepository{store: store}
+}
+
+// GetOnboardingProgress counts the user's decks, notes, flashcards and
+// reviews, and the invites sent for their organization
+func (r *MemoryOnboardingRepository) GetOnboardingProgress(ctx context.Context, userID int) (*models.OnboardingProgress, error) {
+	defer r.store.lock(ctx)()
+
+	if _, ok := r.store.data.users[userID]; !ok {
+		return nil, models.NotFound("user with id %d not found", userID)
+	}
+
+	progress := &models.OnboardingProgress{}
+	if membership, ok := r.store.data.memberships[userID]; ok {
+		progress.Membership = &membership
+		for _, row := range r.store.data.invites {
+			if row.invite.OrganizationID == membership.OrganizationID {
+				progress.InvitesSent++
+			}
+		}
+	}
+
+	for _, deck := range r.store.data.decks {
+		if deck.UserID == userID {
+			progress.Decks++
+		}
+	}
+	for _, note := range r.store.data.notes {
+		if note.UserID == userID {
+			progress.Notes++
+		}
+	}
+	for _, flashcard := range r.store.data.flashcards {
+		if flashcard.UserID == userID {
+			progress.Flashcards++
+		}
+	}
+	for _, review := range r.store.data.reviews {
+		if review.UserID == userID {
+			progress.Reviews++
+		}
+	}
+	return progress, nil
+}
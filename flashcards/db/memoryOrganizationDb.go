@@ -0,0 +1,227 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"flashcards/models"
+)
+
+type MemoryOrganizationRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryOrganizationRepository(store *MemoryStore) *MemoryOrganizationRepository {
+	return &MemoryOrganizationRepository{store: store}
+}
+
+func (r *MemoryOrganizationRepository) CreateOrganization(ctx context.Context, organization *models.Organization) error {
+	defer r.store.lock(ctx)()
+
+	r.createOrganization(organization)
+	return nil
+}
+
+func (r *MemoryOrganizationRepository) createOrganization(organization *models.Organization) {
+	now := r.store.now()
+	organization.ID, organization.CreatedAt, organization.UpdatedAt = r.store.nextID("organizations"), now, now
+	r.store.data.organizations[organization.ID] = *organization
+}
+
+func (r *MemoryOrganizationRepository) GetOrganization(ctx context.Context, id int) (*models.Organization, error) {
+	defer r.store.lock(ctx)()
+
+	organization, ok := r.store.data.organizations[id]
+	if !ok {
+		return nil, models.NotFound("organization with id %d not found", id)
+	}
+	return &organization, nil
+}
+
+// GetUserOrganization returns the organization the user belongs to
+func (r *MemoryOrganizationRepository) GetUserOrganization(ctx context.Context, userID int) (*models.Organization, error) {
+	defer r.store.lock(ctx)()
+
+	membership, ok := r.store.data.memberships[userID]
+	if !ok {
+		return nil, models.NotFound("organization for user with id %d not found", userID)
+	}
+	organization := r.store.data.organizations[membership.OrganizationID]
+	return &organization, nil
+}
+
+// UpdateOrganization saves the organization's name, plan and overrides
+func (r *MemoryOrganizationRepository) UpdateOrganization(ctx context.Context, organization *models.Organization) error {
+	defer r.store.lock(ctx)()
+
+	stored, ok := r.store.data.organizations[organization.ID]
+	if !ok {
+		return models.NotFound("organization with id %d not found", organization.ID)
+	}
+
+	stored.Name, stored.Plan = organization.Name, organization.Plan
+	stored.MaxNotesOverride, stored.MaxCardsOverride = organization.MaxNotesOverride, organization.MaxCardsOverride
+	stored.MonthlyLLMTokensOverride = organization.MonthlyLLMTokensOverride
+	stored.UpdatedAt = r.store.now()
+	r.store.data.organizations[organization.ID] = stored
+
+	organization.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+// HasStripeEvent reports whether the Stripe event was already applied
+func (r *MemoryOrganizationRepository) HasStripeEvent(ctx context.Context, eventID string) (bool, error) {
+	defer r.store.lock(ctx)()
+
+	return r.store.data.stripeEvents[eventID], nil
+}
+
+// RecordStripeEvent marks the Stripe event as applied
+func (r *MemoryOrganizationRepository) RecordStripeEvent(ctx context.Context, eventID string) error {
+	defer r.store.lock(ctx)()
+
+	r.store.data.stripeEvents[eventID] = true
+	return nil
+}
+
+// CreateOwnedOrganization creates an organization with the user as its
+// owner. It fails when the user already belongs to an organization.
+func (r *MemoryOrganizationRepository) CreateOwnedOrganization(ctx context.Context, organization *models.Organization, ownerID int) error {
+	defer r.store.lock(ctx)()
+
+	if _, ok := r.store.data.memberships[ownerID]; ok {
+		return models.Conflict("you already belong to an organization")
+	}
+
+	r.createOrganization(organization)
+	r.store.data.memberships[ownerID] = models.Membership{OrganizationID: organization.ID, Role: models.RoleOwner}
+	return nil
+}
+
+// SetUserOrganization moves a user into an organization with the given
+// role, or out of any organization when organizationID is nil
+func (r *MemoryOrganizationRepository) SetUserOrganization(ctx context.Context, userID int, organizationID *int, role string) error {
+	defer r.store.lock(ctx)()
+
+	if _, ok := r.store.data.users[userID]; !ok {
+		return models.NotFound("user with id %d not found", userID)
+	}
+
+	if organizationID == nil {
+		delete(r.store.data.memberships, userID)
+	} else {
+		r.store.data.memberships[userID] = models.Membership{OrganizationID: *organizationID, Role: role}
+	}
+	return nil
+}
+
+// GetMembership returns the user's organization and role
+func (r *MemoryOrganizationRepository) GetMembership(ctx context.Context, userID int) (*models.Membership, error) {
+	defer r.store.lock(ctx)()
+
+	membership, ok := r.store.data.memberships[userID]
+	if !ok {
+		return nil, models.NotFound("organization for user with id %d not found", userID)
+	}
+	return &membership, nil
+}
+
+// GetOrganizationByStripeCustomer finds the organization billed to a Stripe
+// customer
+func (r *MemoryOrganizationRepository) GetOrganizationByStripeCustomer(ctx context.Context, customerID string) (*models.Organization, error) {
+	defer r.store.lock(ctx)()
+
+	for _, organization := range r.store.data.organizations {
+		if organization.StripeCustomerID != nil && *organization.StripeCustomerID == customerID {
+			return &organization, nil
+		}
+	}
+	return nil, models.NotFound("organization for stripe customer %s not found", customerID)
+}
+
+// UpdateOrganizationBilling saves the organization's plan and Stripe
+// subscription state
+func (r *MemoryOrganizationRepository) UpdateOrganizationBilling(ctx context.Context, organization *models.Organization) error {
+	defer r.store.lock(ctx)()
+
+	stored, ok := r.store.data.organizations[organization.ID]
+	if !ok {
+		return models.NotFound("organization with id %d not found", organization.ID)
+	}
+	if customerID := organization.StripeCustomerID; customerID != nil {
+		for id, other := range r.store.data.organizations {
+			if id != organization.ID && other.StripeCustomerID != nil && *other.StripeCustomerID == *customerID {
+				return models.Conflict("organization for stripe customer %s already exists", *customerID)
+			}
+		}
+	}
+
+	stored.Plan, stored.StripeCustomerID = organization.Plan, organization.StripeCustomerID
+	stored.StripeSubscriptionID, stored.SubscriptionStatus = organization.StripeSubscriptionID, organization.SubscriptionStatus
+	stored.StripeEventAt = organization.StripeEventAt
+	stored.UpdatedAt = r.store.now()
+	r.store.data.organizations[organization.ID] = stored
+
+	organization.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+// GetOrganizationUsage totals the notes, cards and month's LLM tokens of all
+// members of an organization
+func (r *MemoryOrganizationRepository) GetOrganizationUsage(ctx context.Context, organizationID int, month time.Time) (*models.Usage, error) {
+	defer r.store.lock(ctx)()
+
+	return r.usage(func(userID int) bool {
+		membership, ok := r.store.data.memberships[userID]
+		return ok && membership.OrganizationID == organizationID
+	}, month), nil
+}
+
+// GetUserUsage totals usage across the user's organization, or for the user
+// alone when they are not in one
+func (r *MemoryOrganizationRepository) GetUserUsage(ctx context.Context, userID int, month time.Time) (*models.Usage, error) {
+	defer r.store.lock(ctx)()
+
+	own, inOrganization := r.store.data.memberships[userID]
+	return r.usage(func(memberID int) bool {
+		if memberID == userID {
+			return true
+		}
+		membership, ok := r.store.data.memberships[memberID]
+		return inOrganization && ok && membership.OrganizationID == own.OrganizationID
+	}, month), nil
+}
+
+// usage totals the notes, cards and month's tokens of the users member
+// accepts. Callers hold mu.
+func (r *MemoryOrganizationRepository) usage(member func(userID int) bool, month time.Time) *models.Usage {
+	usage := &models.Usage{}
+	for _, note := range r.store.data.notes {
+		if member(note.UserID) {
+			usage.Notes++
+		}
+	}
+	for _, flashcard := range r.store.data.flashcards {
+		if member(flashcard.UserID) {
+			usage.Cards++
+		}
+	}
+	for key, tokens := range r.store.data.llmTokens {
+		if member(key.userID) && key.month.Equal(month) {
+			usage.LLMTokens += tokens
+		}
+	}
+	return usage
+}
+
+// AddLLMTokens adds to the user's token counts for the month and the hour
+// containing at
+func (r *MemoryOrganizationRepository) AddLLMTokens(ctx context.Context, userID int, at time.Time, tokens int) error {
+	defer r.store.lock(ctx)()
+
+	at = at.UTC()
+	month := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, time.UTC)
+	r.store.data.llmTokens[memoryTokenMonth{userID: userID, month: month}] += tokens
+	r.store.data.llmHourlyTokens[memoryTokenHour{userID: userID, hour: at.Truncate(time.Hour)}] += tokens
+	return nil
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"flashcards/models"
+)
+
+type memoryPromptKey struct {
+	name    string
+	version int
+}
+
+type MemoryPromptTemplateRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryPromptTemplateRepository(store *MemoryStore) *MemoryPromptTemplateRepository {
+	return &MemoryPromptTemplateRepository{store: store}
+}
+
+// GetLatestPromptTemplates returns the highest version of each template, by
+// name
+func (r *MemoryPromptTemplateRepository) GetLatestPromptTemplates(ctx context.Context) ([]*models.PromptTemplate, error) {
+	defer r.store.lock(ctx)()
+
+	latest := make(map[string]models.PromptTemplate)
+	for key, prompt := range r.store.data.promptTemplates {
+		if existing, ok := latest[key.name]; !ok || prompt.Version > existing.Version {
+			latest[key.name] = prompt
+		}
+	}
+
+	prompts := make([]*models.PromptTemplate, 0, len(latest))
+	for _, prompt := range latest {
+		prompts = append(prompts, &prompt)
+	}
+	slices.SortFunc(prompts, func(a, b *models.PromptTemplate) int { return cmp.Compare(a.Name, b.Name) })
+	return prompts, nil
+}
+
+// ListPromptTemplateVersions returns every version of a template, newest
+// first
+func (r *MemoryPromptTemplateRepository) ListPromptTemplateVersions(ctx context.Context, name string) ([]*models.PromptTemplate, error) {
+	defer r.store.lock(ctx)()
+
+	prompts := make([]*models.PromptTemplate, 0)
+	for key, prompt := range r.store.data.promptTemplates {
+		if key.name == name {
+			prompts = append(prompts, &prompt)
+		}
+	}
+	slices.SortFunc(prompts, func(a, b *models.PromptTemplate) int { return b.Version - a.Version })
+	return prompts, nil
+}
+
+// CreatePromptTemplateVersion stores template as the next version of name
+func (r *MemoryPromptTemplateRepository) CreatePromptTemplateVersion(ctx context.Context, name, template string) (*models.PromptTemplate, error) {
+	defer r.store.lock(ctx)()
+
+	version := 1
+	for key := range r.store.data.promptTemplates {
+		if key.name == name && key.version >= version {
+			version = key.version + 1
+		}
+	}
+
+	createdAt := r.store.now()
+	prompt := models.PromptTemplate{Name: name, Version: version, Source: models.PromptSourceDatabase, Template: template, CreatedAt: &createdAt}
+	r.store.data.promptTemplates[memoryPromptKey{name, version}] = prompt
+	return &prompt, nil
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+type MemoryQuestionEmbeddingRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryQuestionEmbeddingRepository(store *MemoryStore) *MemoryQuestionEmbeddingRepository {
+	return &MemoryQuestionEmbeddingRepository{store: store}
+}
+
+// GetRecentQuestionEmbeddings returns the user's newest question embeddings
+// from the given model that share a note with noteIDs, or any note when
+// noteIDs is empty
+func (r *MemoryQuestionEmbeddingRepository) GetRecentQuestionEmbeddings(ctx context.Context, userID int, noteIDs []int, model string, limit int) ([]*models.QuestionEmbedding, error) {
+	defer r.store.lock(ctx)()
+
+	ids := slices.SortedFunc(maps.Keys(r.store.data.questionEmbeddings), func(a, b int) int {
+		embeddings := r.store.data.questionEmbeddings
+		return cmp.Or(embeddings[b].CreatedAt.Compare(embeddings[a].CreatedAt), b-a)
+	})
+
+	embeddings := make([]*models.QuestionEmbedding, 0)
+	for _, id := range ids {
+		embedding := r.store.data.questionEmbeddings[id]
+		if embedding.UserID != userID || embedding.Model != model ||
+			len(noteIDs) > 0 && !slices.ContainsFunc(embedding.NoteIDs, func(id int) bool { return slices.Contains(noteIDs, id) }) {
+			continue
+		}
+		if len(embeddings) == limit {
+			break
+		}
+		embedding.NoteIDs, embedding.Vector = slices.Clone(embedding.NoteIDs), slices.Clone(embedding.Vector)
+		embeddings = append(embeddings, &embedding)
+	}
+	return embeddings, nil
+}
+
+func (r *MemoryQuestionEmbeddingRepository) SaveQuestionEmbedding(ctx context.Context, embedding *models.QuestionEmbedding) error {
+	defer r.store.lock(ctx)()
+
+	embedding.CreatedAt = r.store.now()
+	stored := *embedding
+	stored.NoteIDs, stored.Vector = slices.Clone(embedding.NoteIDs), slices.Clone(embedding.Vector)
+	r.store.data.questionEmbeddings[r.store.nextID("question_embeddings")] = stored
+	return nil
+}
+
+func (r *MemoryQuestionEmbeddingRepository) DeleteQuestionEmbeddingsBefore(ctx context.Context, before time.Time) (int64, error) {
+	defer r.store.lock(ctx)()
+
+	var deleted int64
+	maps.DeleteFunc(r.store.data.questionEmbeddings, func(_ int, embedding models.QuestionEmbedding) bool {
+		if embedding.CreatedAt.Before(before) {
+			deleted++
+			return true
+		}
+		return false
+	})
+	return deleted, nil
+}
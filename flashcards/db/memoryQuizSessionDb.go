@@ -0,0 +1,373 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+// memorySessionQuestion is a row of quiz_session_questions. Correct is kept
+// apart from the question since only the database records it.
+type memorySessionQuestion struct {
+	sessionID int
+	question  models.SessionQuestion
+	correct   *bool
+}
+
+type memorySessionQuestionKey struct {
+	sessionID int
+	position  int
+}
+
+type MemoryQuizSessionRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryQuizSessionRepository(store *MemoryStore) *MemoryQuizSessionRepository {
+	return &MemoryQuizSessionRepository{store: store}
+}
+
+func (r *MemoryQuizSessionRepository) CreateSession(ctx context.Context, session *models.QuizSession) error {
+	defer r.store.lock(ctx)()
+
+	now := r.store.now()
+	session.ID, session.CreatedAt, session.UpdatedAt = r.store.nextID("quiz_sessions"), now, now
+
+	stored := *session
+	stored.Questions = nil
+	r.store.data.quizSessions[session.ID] = stored
+
+	for i := range session.Questions {
+		question := &session.Questions[i]
+		question.UpdatedAt = now
+		r.store.data.sessionQuestions[memorySessionQuestionKey{session.ID, question.Position}] = memorySessionQuestion{
+			sessionID: session.ID,
+			question:  memorySessionQuestionCopy(*question),
+		}
+	}
+	return nil
+}
+
+func (r *MemoryQuizSessionRepository) GetSessionByID(ctx context.Context, userID, id int) (*models.QuizSession, error) {
+	defer r.store.lock(ctx)()
+
+	return r.session(userID, id)
+}
+
+// GetSessionsUpdatedSince returns sessions with activity after the given
+// time, including their questions
+func (r *MemoryQuizSessionRepository) GetSessionsUpdatedSince(ctx context.Context, userID int, since time.Time) ([]*models.QuizSession, error) {
+	defer r.store.lock(ctx)()
+
+	sessions := make([]*models.QuizSession, 0)
+	for _, stored := range r.store.data.quizSessions {
+		if stored.UserID != userID || stored.UpdatedAt.Before(since) {
+			continue
+		}
+		session, err := r.session(userID, stored.ID)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	slices.SortFunc(sessions, func(a, b *models.QuizSession) int {
+		return cmp.Or(a.UpdatedAt.Compare(b.UpdatedAt), a.ID-b.ID)
+	})
+	return sessions, nil
+}
+
+func (r *MemoryQuizSessionRepository) UpdateSession(ctx context.Context, userID, id int, updates map[string]any) error {
+	if len(updates) == 0 {
+		return models.Invalid("no updates provided")
+	}
+
+	defer r.store.lock(ctx)()
+
+	session, ok := r.store.data.quizSessions[id]
+	if !ok || session.UserID != userID {
+		return models.NotFound("quiz session with id %d not found", id)
+	}
+
+	for field, value := range updates {
+		var err error
+		switch field {
+		case "status":
+			session.Status, err = memoryStringUpdate(field, value)
+		case "currentPosition":
+			position, ok := value.(int)
+			if !ok {
+				err = fmt.Errorf("invalid value for %s: %v", field, value)
+			}
+			session.CurrentPosition = position
+		default:
+			err = fmt.Errorf("unknown quiz session field %q", field)
+		}
+		if err != nil {
+			return models.Internal("failed to update quiz session: %w", err)
+		}
+	}
+
+	session.UpdatedAt = r.store.now()
+	r.store.data.quizSessions[id] = session
+	return nil
+}
+
+func (r *MemoryQuizSessionRepository) UpdateSessionQuestion(ctx context.Context, userID, sessionID, position int, updates map[string]any) error {
+	if len(updates) == 0 {
+		return models.Invalid("no updates provided")
+	}
+
+	defer r.store.lock(ctx)()
+
+	key := memorySessionQuestionKey{sessionID, position}
+	row, ok := r.store.data.sessionQuestions[key]
+	session, owned := r.store.data.quizSessions[sessionID]
+	if !ok || !owned || session.UserID != userID {
+		return models.NotFound("question %d in quiz session with id %d not found", position, sessionID)
+	}
+
+	question := &row.question
+	for field, value := range updates {
+		var err error
+		switch field {
+		case "answer":
+			question.Answer, err = memoryStringUpdate(field, value)
+		case "status":
+			question.Status, err = memoryStringUpdate(field, value)
+		case "flagged":
+			flagged, ok := value.(bool)
+			if !ok {
+				err = fmt.Errorf("invalid value for %s: %v", field, value)
+			}
+			question.Flagged = flagged
+		case "timeSpentMs":
+			timeSpent, ok := value.(int)
+			if !ok {
+				err = fmt.Errorf("invalid value for %s: %v", field, value)
+			}
+			question.TimeSpentMs = timeSpent
+		case "attachment_id":
+			question.AttachmentID, err = memoryIntUpdate(field, value)
+		case "grading":
+			switch grading := value.(type) {
+			case nil:
+				question.Grading = nil
+			case *models.AnswerGrading:
+				copied := *grading
+				question.Grading = &copied
+			default:
+				err = fmt.Errorf("invalid value for %s: %v", field, value)
+			}
+		case "correct":
+			switch correct := value.(type) {
+			case nil:
+				row.correct = nil
+			case bool:
+				row.correct = &correct
+			default:
+				err = fmt.Errorf("invalid value for %s: %v", field, value)
+			}
+		default:
+			err = fmt.Errorf("unknown session question field %q", field)
+		}
+		if err != nil {
+			return models.Internal("failed to update session question: %w", err)
+		}
+	}
+
+	now := r.store.now()
+	question.UpdatedAt = now
+	r.store.data.sessionQuestions[key] = row
+
+	// Touch the session so updatedAt reflects the latest activity
+	session.UpdatedAt = now
+	r.store.data.quizSessions[sessionID] = session
+	return nil
+}
+
+// GetBankQuestions returns up to limit distinct questions from the user's
+// earlier sessions, the latest asked of each text. Unlike Postgres, which
+// shuffles them, the store returns them most recently asked first so tests
+// see the same questions every run.
+func (r *MemoryQuizSessionRepository) GetBankQuestions(ctx context.Context, userID int, noteIDs []int, difficulty, questionType string, limit int) ([]models.QuestionData, error) {
+	defer r.store.lock(ctx)()
+
+	latest := make(map[string]models.SessionQuestion)
+	for _, row := range r.store.data.sessionQuestions {
+		question := row.question.Question
+		if r.store.data.quizSessions[row.sessionID].UserID != userID ||
+			difficulty != "" && question.Difficulty != difficulty ||
+			questionType != "" && question.Type != questionType ||
+			len(noteIDs) > 0 && !slices.ContainsFunc(question.BasedOnNotes, func(id int) bool { return slices.Contains(noteIDs, id) }) {
+			continue
+		}
+		if existing, ok := latest[question.Text]; !ok || row.question.UpdatedAt.After(existing.UpdatedAt) {
+			latest[question.Text] = row.question
+		}
+	}
+
+	asked := slices.SortedFunc(maps.Values(latest), func(a, b models.SessionQuestion) int {
+		return cmp.Or(b.UpdatedAt.Compare(a.UpdatedAt), cmp.Compare(a.Question.Text, b.Question.Text))
+	})
+
+	questions := make([]models.QuestionData, 0, min(limit, len(asked)))
+	for _, question := range asked[:min(limit, len(asked))] {
+		questions = append(questions, memorySessionQuestionCopy(question).Question)
+	}
+	return questions, nil
+}
+
+// GetRecentGradedAnswers returns the user's latest graded answers, most
+// recent first, to questions based on one of the notes or on a note sharing
+// a tag with one. With no notes given every answer counts.
+func (r *MemoryQuizSessionRepository) GetRecentGradedAnswers(ctx context.Context, userID int, noteIDs []int, limit int) ([]models.GradedAnswer, error) {
+	defer r.store.lock(ctx)()
+
+	topics := make(map[int]bool)
+	for _, id := range noteIDs {
+		topics[id] = true
+		for _, tag := range r.store.data.notes[id].Tags {
+			for _, note := range r.store.data.notes {
+				if slices.Contains(note.Tags, tag) {
+					topics[note.ID] = true
+				}
+			}
+		}
+	}
+
+	answers := make([]models.GradedAnswer, 0)
+	for _, row := range r.store.data.sessionQuestions {
+		if r.store.data.quizSessions[row.sessionID].UserID != userID || row.question.Status != models.QuestionStatusAnswered || row.correct == nil ||
+			len(noteIDs) > 0 && !slices.ContainsFunc(row.question.Question.BasedOnNotes, func(id int) bool { return topics[id] }) {
+			continue
+		}
+		answers = append(answers, models.GradedAnswer{
+			Difficulty: row.question.Question.Difficulty,
+			Correct:    *row.correct,
+			AnsweredAt: row.question.UpdatedAt,
+		})
+	}
+
+	slices.SortStableFunc(answers, func(a, b models.GradedAnswer) int {
+		return b.AnsweredAt.Compare(a.AnsweredAt)
+	})
+	return answers[:min(limit, len(answers))], nil
+}
+
+func (r *MemoryQuizSessionRepository) CreateQueuedSession(ctx context.Context, queued *models.QueuedQuizSession) error {
+	defer r.store.lock(ctx)()
+
+	now := r.store.now()
+	queued.ID, queued.CreatedAt, queued.UpdatedAt = r.store.nextID("queued_quiz_sessions"), now, now
+	queued.SessionID, queued.Error, queued.Attempts = nil, "", 0
+	r.store.data.queuedSessions[queued.ID] = *queued
+	return nil
+}
+
+func (r *MemoryQuizSessionRepository) GetQueuedSession(ctx context.Context, userID, id int) (*models.QueuedQuizSession, error) {
+	defer r.store.lock(ctx)()
+
+	queued, ok := r.store.data.queuedSessions[id]
+	if !ok || queued.UserID != userID {
+		return nil, models.NotFound("queued quiz session with id %d not found", id)
+	}
+	return &queued, nil
+}
+
+// ClaimDueQueuedSessions returns queued requests whose next attempt is due
+// by the store's clock and pushes that attempt back by lease
+func (r *MemoryQuizSessionRepository) ClaimDueQueuedSessions(ctx context.Context, limit int, lease time.Duration) ([]*models.QueuedQuizSession, error) {
+	defer r.store.lock(ctx)()
+
+	now := r.store.now()
+	due := make([]models.QueuedQuizSession, 0)
+	for _, queued := range r.store.data.queuedSessions {
+		if queued.Status == models.QueuedSessionStatusQueued && queued.NextAttemptAt != nil && !queued.NextAttemptAt.After(now) {
+			due = append(due, queued)
+		}
+	}
+	slices.SortFunc(due, func(a, b models.QueuedQuizSession) int {
+		return cmp.Or(a.NextAttemptAt.Compare(*b.NextAttemptAt), a.ID-b.ID)
+	})
+
+	claimed := make([]*models.QueuedQuizSession, 0)
+	for _, queued := range due[:min(limit, len(due))] {
+		nextAttemptAt := now.Add(lease)
+		queued.NextAttemptAt = &nextAttemptAt
+		r.store.data.queuedSessions[queued.ID] = queued
+		claimed = append(claimed, &queued)
+	}
+	return claimed, nil
+}
+
+func (r *MemoryQuizSessionRepository) CompleteQueuedSession(ctx context.Context, id, sessionID int) error {
+	defer r.store.lock(ctx)()
+
+	if queued, ok := r.store.data.queuedSessions[id]; ok {
+		queued.Status, queued.SessionID, queued.Error, queued.NextAttemptAt = models.QueuedSessionStatusCompleted, &sessionID, "", nil
+		queued.UpdatedAt = r.store.now()
+		r.store.data.queuedSessions[id] = queued
+	}
+	return nil
+}
+
+// FailQueuedSessionAttempt counts a failed attempt. Without a next attempt
+// the request has failed for good.
+func (r *MemoryQuizSessionRepository) FailQueuedSessionAttempt(ctx context.Context, id int, message string, nextAttemptAt *time.Time) error {
+	defer r.store.lock(ctx)()
+
+	if queued, ok := r.store.data.queuedSessions[id]; ok {
+		queued.Attempts++
+		queued.Error, queued.NextAttemptAt = message, nextAttemptAt
+		queued.Status = models.QueuedSessionStatusQueued
+		if nextAttemptAt == nil {
+			queued.Status = models.QueuedSessionStatusFailed
+		}
+		queued.UpdatedAt = r.store.now()
+		r.store.data.queuedSessions[id] = queued
+	}
+	return nil
+}
+
+// session returns the user's session with its questions by position.
+// Callers hold mu.
+func (r *MemoryQuizSessionRepository) session(userID, id int) (*models.QuizSession, error) {
+	session, ok := r.store.data.quizSessions[id]
+	if !ok || session.UserID != userID {
+		return nil, models.NotFound("quiz session with id %d not found", id)
+	}
+
+	session.Questions = make([]models.SessionQuestion, 0)
+	for _, row := range r.store.data.sessionQuestions {
+		if row.sessionID == id {
+			session.Questions = append(session.Questions, memorySessionQuestionCopy(row.question))
+		}
+	}
+	slices.SortFunc(session.Questions, func(a, b models.SessionQuestion) int {
+		return a.Position - b.Position
+	})
+	return &session, nil
+}
+
+// memorySessionQuestionCopy copies a question as Postgres would by storing
+// it as JSON, so the caller and the store never share its slices
+func memorySessionQuestionCopy(question models.SessionQuestion) models.SessionQuestion {
+	data := &question.Question
+	data.Options = slices.Clone(data.Options)
+	data.BasedOnNotes = slices.Clone(data.BasedOnNotes)
+	if data.Accessibility != nil {
+		accessibility := *data.Accessibility
+		accessibility.OptionLabels = slices.Clone(accessibility.OptionLabels)
+		data.Accessibility = &accessibility
+	}
+	if question.Grading != nil {
+		grading := *question.Grading
+		question.Grading = &grading
+	}
+	return question
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"flashcards/models"
+)
+
+type MemoryReportRecipientRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryReportRecipientRepository(store *MemoryStore) *MemoryReportRecipientRepository {
+	return &MemoryReportRecipientRepository{store: store}
+}
+
+func (r *MemoryReportRecipientRepository) CreateRecipient(ctx context.Context, recipient *models.ReportRecipient) error {
+	defer r.store.lock(ctx)()
+
+	recipient.ID, recipient.CreatedAt = r.store.nextID("report_recipients"), r.store.now()
+	r.store.data.reportRecipients[recipient.ID] = *recipient
+	return nil
+}
+
+// GetAllRecipients returns the user's recipients by email
+func (r *MemoryReportRecipientRepository) GetAllRecipients(ctx context.Context, userID int) ([]*models.ReportRecipient, error) {
+	return r.recipients(ctx, func(recipient models.ReportRecipient) bool { return recipient.UserID == userID }), nil
+}
+
+// GetRecipientsForAllUsers returns every recipient by user, then by email
+func (r *MemoryReportRecipientRepository) GetRecipientsForAllUsers(ctx context.Context) ([]*models.ReportRecipient, error) {
+	return r.recipients(ctx, func(models.ReportRecipient) bool { return true }), nil
+}
+
+func (r *MemoryReportRecipientRepository) recipients(ctx context.Context, match func(models.ReportRecipient) bool) []*models.ReportRecipient {
+	defer r.store.lock(ctx)()
+
+	recipients := make([]*models.ReportRecipient, 0)
+	for _, recipient := range r.store.data.reportRecipients {
+		if match(recipient) {
+			recipients = append(recipients, &recipient)
+		}
+	}
+	slices.SortFunc(recipients, func(a, b *models.ReportRecipient) int {
+		return cmp.Or(a.UserID-b.UserID, cmp.Compare(a.Email, b.Email), a.ID-b.ID)
+	})
+	return recipients
+}
+
+func (r *MemoryReportRecipientRepository) DeleteRecipient(ctx context.Context, userID, id int) error {
+	defer r.store.lock(ctx)()
+
+	recipient, ok := r.store.data.reportRecipients[id]
+	if !ok || recipient.UserID != userID {
+		return models.NotFound("report recipient with id %d not found", id)
+	}
+	delete(r.store.data.reportRecipients, id)
+	return nil
+}
@@ -0,0 +1,266 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+type MemoryReviewRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryReviewRepository(store *MemoryStore) *MemoryReviewRepository {
+	return &MemoryReviewRepository{store: store}
+}
+
+// GetDueCards returns up to limit of the user's cards that are due at now,
+// along with the total number due and how many of those are new, ordered
+// as the Postgres repository orders them
+func (r *MemoryReviewRepository) GetDueCards(ctx context.Context, userID int, deckIDs []int, now time.Time, limit int) ([]*models.DueCard, int, int, error) {
+	defer r.store.lock(ctx)()
+
+	cards := r.cards(userID, deckIDs, func(card *models.DueCard) bool {
+		return !card.Schedule.DueAt.After(now) && !card.Schedule.Suspended
+	})
+
+	newTotal := 0
+	for _, card := range cards {
+		if card.Schedule.State == models.CardStateNew {
+			newTotal++
+		}
+	}
+
+	slices.SortStableFunc(cards, func(a, b *models.DueCard) int {
+		aNew, bNew := a.Schedule.State == models.CardStateNew, b.Schedule.State == models.CardStateNew
+		if aNew != bNew {
+			if aNew {
+				return 1
+			}
+			return -1
+		}
+		return compareDue(a, b)
+	})
+
+	return cards[:min(limit, len(cards))], len(cards), newTotal, nil
+}
+
+// GetCramCards returns up to limit of the user's unsuspended cards, earliest
+// due first, along with the total number of cards
+func (r *MemoryReviewRepository) GetCramCards(ctx context.Context, userID int, deckIDs []int, limit int) ([]*models.DueCard, int, error) {
+	defer r.store.lock(ctx)()
+
+	cards := r.cards(userID, deckIDs, func(card *models.DueCard) bool { return !card.Schedule.Suspended })
+	slices.SortStableFunc(cards, compareDue)
+	return cards[:min(limit, len(cards))], len(cards), nil
+}
+
+// GetCardsByIDs returns the user's cards among flashcardIDs, earliest due
+// first
+func (r *MemoryReviewRepository) GetCardsByIDs(ctx context.Context, userID int, flashcardIDs []int) ([]*models.DueCard, error) {
+	defer r.store.lock(ctx)()
+
+	cards := r.cards(userID, nil, func(card *models.DueCard) bool { return slices.Contains(flashcardIDs, card.Flashcard.ID) })
+	slices.SortStableFunc(cards, compareDue)
+	return cards, nil
+}
+
+// CountNewCardsReviewed counts the user's cards reviewed for the first time
+// since the given time
+func (r *MemoryReviewRepository) CountNewCardsReviewed(ctx context.Context, userID int, deckIDs []int, since time.Time) (int, error) {
+	defer r.store.lock(ctx)()
+
+	counted := make(map[int]bool)
+	for _, review := range r.store.data.reviews {
+		flashcard, ok := r.store.data.flashcards[review.FlashcardID]
+		if !ok || review.UserID != userID || review.Mode != models.ReviewModeScheduled ||
+			review.PreviousState != models.CardStateNew || review.ReviewedAt.Before(since) || !memoryInDecks(flashcard.DeckID, deckIDs) {
+			continue
+		}
+		counted[review.FlashcardID] = true
+	}
+	return len(counted), nil
+}
+
+func (r *MemoryReviewRepository) GetSchedule(ctx context.Context, userID, flashcardID int) (*models.Schedule, error) {
+	defer r.store.lock(ctx)()
+
+	schedule, ok := r.store.data.schedules[flashcardID]
+	if !ok || r.store.data.flashcards[flashcardID].UserID != userID {
+		return nil, models.NotFound("schedule for flashcard with id %d not found", flashcardID)
+	}
+	return &schedule, nil
+}
+
+// SaveReview records a review and stores the card's new schedule. A nil
+// schedule leaves the card's schedule untouched.
+func (r *MemoryReviewRepository) SaveReview(ctx context.Context, review *models.Review, schedule *models.Schedule) error {
+	defer r.store.lock(ctx)()
+
+	if review.ClientID != nil {
+		for _, existing := range r.store.data.reviews {
+			if existing.UserID == review.UserID && existing.ClientID != nil && *existing.ClientID == *review.ClientID {
+				return models.Conflict("review with client id %s already exists", *review.ClientID)
+			}
+		}
+	}
+
+	if schedule != nil {
+		r.saveSchedule(schedule)
+	}
+	review.ID = r.store.nextID("reviews")
+	r.store.data.reviews[review.ID] = *review
+	return nil
+}
+
+// GetLatestReview returns the user's most recent review
+func (r *MemoryReviewRepository) GetLatestReview(ctx context.Context, userID int) (*models.Review, error) {
+	defer r.store.lock(ctx)()
+
+	var latest *models.Review
+	for _, review := range r.store.data.reviews {
+		if review.UserID != userID {
+			continue
+		}
+		if latest == nil || cmp.Or(review.ReviewedAt.Compare(latest.ReviewedAt), review.ID-latest.ID) > 0 {
+			latest = &review
+		}
+	}
+	if latest == nil {
+		return nil, models.NotFound("review to undo not found")
+	}
+	return latest, nil
+}
+
+// GetReviewByClientID returns the review a client synced under clientID
+func (r *MemoryReviewRepository) GetReviewByClientID(ctx context.Context, userID int, clientID string) (*models.Review, error) {
+	defer r.store.lock(ctx)()
+
+	for _, review := range r.store.data.reviews {
+		if review.UserID == userID && review.ClientID != nil && *review.ClientID == clientID {
+			return &review, nil
+		}
+	}
+	return nil, models.NotFound("review with client id %s not found", clientID)
+}
+
+// UndoReview deletes a review and, for scheduled reviews, puts the card's
+// schedule back as it was before
+func (r *MemoryReviewRepository) UndoReview(ctx context.Context, review *models.Review) error {
+	defer r.store.lock(ctx)()
+
+	stored, ok := r.store.data.reviews[review.ID]
+	if !ok || stored.UserID != review.UserID {
+		return models.NotFound("review with id %d not found", review.ID)
+	}
+	delete(r.store.data.reviews, review.ID)
+
+	if review.Mode == models.ReviewModeScheduled {
+		if review.PreviousSchedule != nil {
+			r.saveSchedule(review.PreviousSchedule)
+		} else {
+			delete(r.store.data.schedules, review.FlashcardID)
+		}
+	}
+	return nil
+}
+
+// GetActiveUserIDs returns up to limit users who reviewed cards since the
+// given time, most recently active first
+func (r *MemoryReviewRepository) GetActiveUserIDs(ctx context.Context, since time.Time, limit int) ([]int, error) {
+	defer r.store.lock(ctx)()
+
+	lastReviewed := make(map[int]time.Time)
+	for _, review := range r.store.data.reviews {
+		if !review.ReviewedAt.Before(since) && review.ReviewedAt.After(lastReviewed[review.UserID]) {
+			lastReviewed[review.UserID] = review.ReviewedAt
+		}
+	}
+
+	userIDs := slices.SortedFunc(maps.Keys(lastReviewed), func(a, b int) int {
+		return cmp.Or(lastReviewed[b].Compare(lastReviewed[a]), a-b)
+	})
+	return userIDs[:min(limit, len(userIDs))], nil
+}
+
+// GetLeechCards returns the user's leeches with their schedules, most
+// lapses first
+func (r *MemoryReviewRepository) GetLeechCards(ctx context.Context, userID int, deckIDs []int) ([]*models.DueCard, error) {
+	defer r.store.lock(ctx)()
+
+	cards := r.cards(userID, deckIDs, func(card *models.DueCard) bool { return card.Schedule.Leech })
+	slices.SortStableFunc(cards, func(a, b *models.DueCard) int {
+		return b.Schedule.Lapses - a.Schedule.Lapses
+	})
+	return cards, nil
+}
+
+// SetSuspended suspends or unsuspends one of the user's scheduled cards
+func (r *MemoryReviewRepository) SetSuspended(ctx context.Context, userID, flashcardID int, suspended bool) error {
+	defer r.store.lock(ctx)()
+
+	schedule, ok := r.store.data.schedules[flashcardID]
+	if !ok || r.store.data.flashcards[flashcardID].UserID != userID {
+		return models.NotFound("schedule for flashcard with id %d not found", flashcardID)
+	}
+	schedule.Suspended, schedule.UpdatedAt = suspended, r.store.now()
+	r.store.data.schedules[flashcardID] = schedule
+	return nil
+}
+
+// ResetSchedule drops the scheduling state of one of the user's cards,
+// which makes it new again and clears its leech mark
+func (r *MemoryReviewRepository) ResetSchedule(ctx context.Context, userID, flashcardID int) error {
+	defer r.store.lock(ctx)()
+
+	if flashcard, ok := r.store.data.flashcards[flashcardID]; ok && flashcard.UserID == userID {
+		delete(r.store.data.schedules, flashcardID)
+	}
+	return nil
+}
+
+// cards returns the user's flashcards in deckIDs, or in any deck when nil,
+// with their schedules, in ID order. Cards that were never reviewed get
+// the schedule of a new card due from its creation. Callers hold mu.
+func (r *MemoryReviewRepository) cards(userID int, deckIDs []int, match func(*models.DueCard) bool) []*models.DueCard {
+	cards := make([]*models.DueCard, 0)
+	for _, id := range slices.Sorted(maps.Keys(r.store.data.flashcards)) {
+		flashcard := r.store.data.flashcards[id]
+		if flashcard.UserID != userID || !memoryInDecks(flashcard.DeckID, deckIDs) {
+			continue
+		}
+
+		schedule, ok := r.store.data.schedules[id]
+		if !ok {
+			schedule = models.Schedule{FlashcardID: id, State: models.CardStateNew, DueAt: flashcard.CreatedAt,
+				EaseFactor: 2.5, UpdatedAt: flashcard.CreatedAt}
+		}
+
+		card := &models.DueCard{Flashcard: &flashcard, Schedule: &schedule}
+		if match(card) {
+			cards = append(cards, card)
+		}
+	}
+	return cards
+}
+
+// saveSchedule inserts or replaces a card's schedule. Callers hold mu.
+func (r *MemoryReviewRepository) saveSchedule(schedule *models.Schedule) {
+	schedule.UpdatedAt = r.store.now()
+	r.store.data.schedules[schedule.FlashcardID] = *schedule
+}
+
+// compareDue orders cards earliest due first, then by ID
+func compareDue(a, b *models.DueCard) int {
+	return cmp.Or(a.Schedule.DueAt.Compare(b.Schedule.DueAt), a.Flashcard.ID-b.Flashcard.ID)
+}
+
+// memoryInDecks matches like deck_id = ANY(deckIDs), where a nil deckIDs
+// matches every card
+func memoryInDecks(deckID *int, deckIDs []int) bool {
+	return deckIDs == nil || deckID != nil && slices.Contains(deckIDs, *deckID)
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"flashcards/models"
+)
+
+type MemoryRoutingRuleRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryRoutingRuleRepository(store *MemoryStore) *MemoryRoutingRuleRepository {
+	return &MemoryRoutingRuleRepository{store: store}
+}
+
+func (r *MemoryRoutingRuleRepository) CreateRule(ctx context.Context, rule *models.RoutingRule) error {
+	defer r.store.lock(ctx)()
+
+	now := r.store.now()
+	rule.ID, rule.CreatedAt, rule.UpdatedAt = r.store.nextID("model_routing_rules"), now, now
+	r.store.data.routingRules[rule.ID] = *rule
+	return nil
+}
+
+// GetAllRules returns the rules by priority, then in creation order
+func (r *MemoryRoutingRuleRepository) GetAllRules(ctx context.Context) ([]*models.RoutingRule, error) {
+	defer r.store.lock(ctx)()
+
+	rules := make([]*models.RoutingRule, 0, len(r.store.data.routingRules))
+	for _, rule := range r.store.data.routingRules {
+		rules = append(rules, &rule)
+	}
+	slices.SortFunc(rules, func(a, b *models.RoutingRule) int {
+		return cmp.Or(a.Priority-b.Priority, a.ID-b.ID)
+	})
+	return rules, nil
+}
+
+func (r *MemoryRoutingRuleRepository) DeleteRule(ctx context.Context, id int) error {
+	defer r.store.lock(ctx)()
+
+	if _, ok := r.store.data.routingRules[id]; !ok {
+		return models.NotFound("routing rule with id %d not found", id)
+	}
+	delete(r.store.data.routingRules, id)
+	return nil
+}
@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+type MemoryScheduledJobRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryScheduledJobRepository(store *MemoryStore) *MemoryScheduledJobRepository {
+	return &MemoryScheduledJobRepository{store: store}
+}
+
+// ClaimJobRun claims the next run of a job when it has never run or was
+// last claimed at least since ago, by the store's clock
+func (r *MemoryScheduledJobRepository) ClaimJobRun(ctx context.Context, name, instance string, since time.Duration) (bool, error) {
+	defer r.store.lock(ctx)()
+
+	now := r.store.now()
+	if claimedAt, ok := r.store.data.jobRuns[name]; ok && claimedAt.After(now.Add(-since)) {
+		return false, nil
+	}
+	r.store.data.jobRuns[name] = now
+	return true, nil
+}
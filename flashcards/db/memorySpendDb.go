@@ -0,0 +1,135 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+type MemorySpendRepository struct {
+	store *MemoryStore
+}
+
+func NewMemorySpendRepository(store *MemoryStore) *MemorySpendRepository {
+	return &MemorySpendRepository{store: store}
+}
+
+// GetSpendWindows totals hourly token usage per user and per organization,
+// split into the baseline period [baselineStart, recentStart) and the
+// recent window from recentStart. Subjects with no recent usage are left
+// out.
+func (r *MemorySpendRepository) GetSpendWindows(ctx context.Context, baselineStart, recentStart time.Time) ([]*models.SpendWindow, error) {
+	defer r.store.lock(ctx)()
+
+	users := make(map[int]*models.SpendWindow)
+	organizations := make(map[int]*models.SpendWindow)
+	add := func(windows map[int]*models.SpendWindow, scope string, subjectID int, hour time.Time, tokens int) {
+		window, ok := windows[subjectID]
+		if !ok {
+			window = &models.SpendWindow{Scope: scope, SubjectID: subjectID}
+			windows[subjectID] = window
+		}
+		if hour.Before(recentStart) {
+			window.BaselineTokens += tokens
+		} else {
+			window.RecentTokens += tokens
+		}
+	}
+
+	for key, tokens := range r.store.data.llmHourlyTokens {
+		if _, ok := r.store.data.users[key.userID]; !ok || key.hour.Before(baselineStart) {
+			continue
+		}
+		add(users, models.SpendScopeUser, key.userID, key.hour, tokens)
+		if membership, ok := r.store.data.memberships[key.userID]; ok {
+			add(organizations, models.SpendScopeOrganization, membership.OrganizationID, key.hour, tokens)
+		}
+	}
+
+	windows := []*models.SpendWindow{}
+	for _, byID := range []map[int]*models.SpendWindow{users, organizations} {
+		for _, id := range slices.Sorted(maps.Keys(byID)) {
+			if byID[id].RecentTokens > 0 {
+				windows = append(windows, byID[id])
+			}
+		}
+	}
+	return windows, nil
+}
+
+func (r *MemorySpendRepository) CreateAnomaly(ctx context.Context, anomaly *models.SpendAnomaly) error {
+	defer r.store.lock(ctx)()
+
+	anomaly.ID, anomaly.DetectedAt = r.store.nextID("llm_spend_anomalies"), r.store.now()
+	r.store.data.spendAnomalies[anomaly.ID] = *anomaly
+	return nil
+}
+
+// HasAnomalySince reports whether the subject already had an anomaly
+// detected since the given time
+func (r *MemorySpendRepository) HasAnomalySince(ctx context.Context, scope string, subjectID int, since time.Time) (bool, error) {
+	defer r.store.lock(ctx)()
+
+	for _, anomaly := range r.store.data.spendAnomalies {
+		if anomaly.Scope == scope && anomaly.SubjectID == subjectID && !anomaly.DetectedAt.Before(since) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetThrottledUntil returns when the latest active throttle on the user or
+// their organization ends, or nil when neither is throttled
+func (r *MemorySpendRepository) GetThrottledUntil(ctx context.Context, userID int) (*time.Time, error) {
+	defer r.store.lock(ctx)()
+
+	if _, ok := r.store.data.users[userID]; !ok {
+		return nil, nil
+	}
+	membership, inOrganization := r.store.data.memberships[userID]
+
+	now := r.store.now()
+	var throttledUntil *time.Time
+	for _, anomaly := range r.store.data.spendAnomalies {
+		if anomaly.ThrottledUntil == nil || !anomaly.ThrottledUntil.After(now) ||
+			!(anomaly.Scope == models.SpendScopeUser && anomaly.SubjectID == userID ||
+				anomaly.Scope == models.SpendScopeOrganization && inOrganization && anomaly.SubjectID == membership.OrganizationID) {
+			continue
+		}
+		if throttledUntil == nil || anomaly.ThrottledUntil.After(*throttledUntil) {
+			throttledUntil = anomaly.ThrottledUntil
+		}
+	}
+	return throttledUntil, nil
+}
+
+// GetAnomalies returns the most recently detected anomalies
+func (r *MemorySpendRepository) GetAnomalies(ctx context.Context, limit int) ([]*models.SpendAnomaly, error) {
+	defer r.store.lock(ctx)()
+
+	anomalies := []*models.SpendAnomaly{}
+	for _, anomaly := range r.store.data.spendAnomalies {
+		anomalies = append(anomalies, &anomaly)
+	}
+	slices.SortFunc(anomalies, func(a, b *models.SpendAnomaly) int {
+		return cmp.Or(b.DetectedAt.Compare(a.DetectedAt), b.ID-a.ID)
+	})
+	return anomalies[:min(limit, len(anomalies))], nil
+}
+
+// LiftThrottle ends the anomaly's throttle now
+func (r *MemorySpendRepository) LiftThrottle(ctx context.Context, id int) (*models.SpendAnomaly, error) {
+	defer r.store.lock(ctx)()
+
+	anomaly, ok := r.store.data.spendAnomalies[id]
+	if !ok {
+		return nil, models.NotFound("spend anomaly with id %d not found", id)
+	}
+	anomaly.ThrottledUntil = nil
+	r.store.data.spendAnomalies[id] = anomaly
+	return &anomaly, nil
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+type MemoryStudyAnalyticsRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryStudyAnalyticsRepository(store *MemoryStore) *MemoryStudyAnalyticsRepository {
+	return &MemoryStudyAnalyticsRepository{store: store}
+}
+
+// GetDailyReviews counts the user's flashcard reviews per UTC day since the
+// given time. Days without reviews are left out.
+func (r *MemoryStudyAnalyticsRepository) GetDailyReviews(ctx context.Context, userID int, since time.Time) ([]models.DailyReviews, error) {
+	defer r.store.lock(ctx)()
+
+	byDay := make(map[string]models.DailyReviews)
+	for _, review := range r.store.data.reviews {
+		if review.UserID != userID || review.ReviewedAt.Before(since) {
+			continue
+		}
+		date := review.ReviewedAt.UTC().Format(time.DateOnly)
+		day := byDay[date]
+		day.Date = date
+		day.Reviewed++
+		if review.Rating != models.RatingAgain {
+			day.Recalled++
+		}
+		byDay[date] = day
+	}
+
+	days := make([]models.DailyReviews, 0, len(byDay))
+	for _, date := range slices.Sorted(maps.Keys(byDay)) {
+		days = append(days, byDay[date])
+	}
+	return days, nil
+}
+
+// GetAccuracyByDifficulty counts the user's graded quiz answers, and how
+// many were correct, per question difficulty
+func (r *MemoryStudyAnalyticsRepository) GetAccuracyByDifficulty(ctx context.Context, userID int, since time.Time) ([]models.DifficultyAccuracy, error) {
+	defer r.store.lock(ctx)()
+
+	byDifficulty := make(map[string]models.DifficultyAccuracy)
+	for _, row := range r.gradedAnswers(userID, since) {
+		difficulty := cmp.Or(row.question.Question.Difficulty, "unknown")
+		accuracy := byDifficulty[difficulty]
+		accuracy.Difficulty = difficulty
+		accuracy.Graded++
+		if *row.correct {
+			accuracy.Correct++
+		}
+		byDifficulty[difficulty] = accuracy
+	}
+
+	accuracies := make([]models.DifficultyAccuracy, 0, len(byDifficulty))
+	for _, difficulty := range slices.Sorted(maps.Keys(byDifficulty)) {
+		accuracies = append(accuracies, byDifficulty[difficulty])
+	}
+	return accuracies, nil
+}
+
+// GetActiveDays returns the UTC days since the given time on which the
+// user reviewed a flashcard or answered a quiz question, most recent first
+func (r *MemoryStudyAnalyticsRepository) GetActiveDays(ctx context.Context, userID int, since time.Time) ([]time.Time, error) {
+	defer r.store.lock(ctx)()
+
+	active := make(map[time.Time]bool)
+	for _, review := range r.store.data.reviews {
+		if review.UserID == userID && !review.ReviewedAt.Before(since) {
+			active[review.ReviewedAt.UTC().Truncate(24*time.Hour)] = true
+		}
+	}
+	for _, row := range r.store.data.sessionQuestions {
+		if r.store.data.quizSessions[row.sessionID].UserID == userID && row.question.Status == models.QuestionStatusAnswered &&
+			!row.question.UpdatedAt.Before(since) {
+			active[row.question.UpdatedAt.UTC().Truncate(24*time.Hour)] = true
+		}
+	}
+
+	return slices.SortedFunc(maps.Keys(active), func(a, b time.Time) int { return b.Compare(a) }), nil
+}
+
+// GetMostMissedNotes returns the user's notes behind the most wrongly
+// answered quiz questions, with how many graded answers drew on each
+func (r *MemoryStudyAnalyticsRepository) GetMostMissedNotes(ctx context.Context, userID int, since time.Time, limit int) ([]models.MissedNote, error) {
+	defer r.store.lock(ctx)()
+
+	byNote := make(map[int]models.MissedNote)
+	for _, row := range r.gradedAnswers(userID, since) {
+		for _, noteID := range row.question.Question.BasedOnNotes {
+			note, ok := r.store.data.notes[noteID]
+			if !ok || note.UserID != userID {
+				continue
+			}
+			missed := byNote[noteID]
+			missed.NoteID = noteID
+			missed.Excerpt = string([]rune(note.Content)[:min(120, len([]rune(note.Content)))])
+			missed.Graded++
+			if !*row.correct {
+				missed.Missed++
+			}
+			byNote[noteID] = missed
+		}
+	}
+
+	notes := make([]models.MissedNote, 0)
+	for _, note := range byNote {
+		if note.Missed > 0 {
+			notes = append(notes, note)
+		}
+	}
+	slices.SortFunc(notes, func(a, b models.MissedNote) int {
+		return cmp.Or(b.Missed-a.Missed, a.NoteID-b.NoteID)
+	})
+	return notes[:min(limit, len(notes))], nil
+}
+
+// gradedAnswers returns the user's answered questions with a grade since
+// the given time. Callers hold mu.
+func (r *MemoryStudyAnalyticsRepository) gradedAnswers(userID int, since time.Time) []memorySessionQuestion {
+	rows := make([]memorySessionQuestion, 0)
+	for _, row := range r.store.data.sessionQuestions {
+		if r.store.data.quizSessions[row.sessionID].UserID == userID && row.question.Status == models.QuestionStatusAnswered &&
+			row.correct != nil && !row.question.UpdatedAt.Before(since) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
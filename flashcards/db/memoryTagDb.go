@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"flashcards/models"
+)
+
+type MemoryTagRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryTagRepository(store *MemoryStore) *MemoryTagRepository {
+	return &MemoryTagRepository{store: store}
+}
+
+// GetAllTags returns the tags used on the user's notes, by name
+func (r *MemoryTagRepository) GetAllTags(ctx context.Context, userID int) ([]*models.Tag, error) {
+	defer r.store.lock(ctx)()
+
+	counts := make(map[string]int)
+	for _, note := range r.store.data.notes {
+		if note.UserID != userID {
+			continue
+		}
+		for _, name := range note.Tags {
+			counts[name]++
+		}
+	}
+
+	tags := make([]*models.Tag, 0, len(counts))
+	for _, name := range slices.Sorted(maps.Keys(counts)) {
+		tag := r.store.data.tags[name]
+		tag.NoteCount = counts[name]
+		tags = append(tags, &tag)
+	}
+	return tags, nil
+}
+
+// AddTagsToNote creates any missing tags and links them to the note.
+// Tags already attached to the note are left untouched.
+func (r *MemoryTagRepository) AddTagsToNote(ctx context.Context, userID, noteID int, names []string) error {
+	defer r.store.lock(ctx)()
+
+	note, ok := r.store.data.notes[noteID]
+	if !ok || note.UserID != userID {
+		return models.NotFound("note with id %d not found", noteID)
+	}
+
+	tags := slices.Clone(note.Tags)
+	for _, name := range names {
+		if _, ok := r.store.data.tags[name]; !ok {
+			r.store.data.tags[name] = models.Tag{ID: r.store.nextID("tags"), Name: name, CreatedAt: r.store.now()}
+		}
+		if !slices.Contains(tags, name) {
+			tags = append(tags, name)
+		}
+	}
+	slices.Sort(tags)
+
+	note.Tags = tags
+	r.store.data.notes[noteID] = note
+	return nil
+}
+
+func (r *MemoryTagRepository) RemoveTagFromNote(ctx context.Context, userID, noteID int, name string) error {
+	defer r.store.lock(ctx)()
+
+	note, ok := r.store.data.notes[noteID]
+	index := slices.Index(note.Tags, name)
+	if !ok || note.UserID != userID || index < 0 {
+		return models.NotFound("tag %q on note with id %d not found", name, noteID)
+	}
+
+	note.Tags = slices.Delete(slices.Clone(note.Tags), index, index+1)
+	r.store.data.notes[noteID] = note
+	return nil
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"flashcards/models"
+)
+
+type MemoryTodoRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryTodoRepository(store *MemoryStore) *MemoryTodoRepository {
+	return &MemoryTodoRepository{store: store}
+}
+
+func (r *MemoryTodoRepository) CreateTodo(ctx context.Context, todo *models.Todo) error {
+	defer r.store.lock(ctx)()
+
+	now := r.store.now()
+	todo.ID, todo.CreatedAt, todo.UpdatedAt = r.store.nextID("todos"), now, now
+	r.store.data.todos[todo.ID] = *todo
+	return nil
+}
+
+func (r *MemoryTodoRepository) GetTodoByID(ctx context.Context, userID, id int) (*models.Todo, error) {
+	defer r.store.lock(ctx)()
+
+	todo, ok := r.store.data.todos[id]
+	if !ok || todo.UserID != userID {
+		return nil, models.NotFound("todo with id %d not found", id)
+	}
+	return &todo, nil
+}
+
+// GetAllTodos returns the user's todos, newest first
+func (r *MemoryTodoRepository) GetAllTodos(ctx context.Context, userID int) ([]*models.Todo, error) {
+	defer r.store.lock(ctx)()
+
+	todos := make([]*models.Todo, 0)
+	for _, id := range slices.Backward(slices.Sorted(maps.Keys(r.store.data.todos))) {
+		if todo := r.store.data.todos[id]; todo.UserID == userID {
+			todos = append(todos, &todo)
+		}
+	}
+	return todos, nil
+}
+
+func (r *MemoryTodoRepository) UpdateTodo(ctx context.Context, userID, id int, updates map[string]any) error {
+	if len(updates) == 0 {
+		return models.Invalid("no updates provided")
+	}
+
+	defer r.store.lock(ctx)()
+
+	todo, ok := r.store.data.todos[id]
+	if !ok || todo.UserID != userID {
+		return models.NotFound("todo with id %d not found", id)
+	}
+
+	for field, value := range updates {
+		var err error
+		switch field {
+		case "title":
+			todo.Title, err = memoryStringUpdate(field, value)
+		case "description":
+			todo.Description, err = memoryStringUpdate(field, value)
+		case "completed":
+			completed, ok := value.(bool)
+			if !ok {
+				err = fmt.Errorf("invalid value for %s: %v", field, value)
+			}
+			todo.Completed = completed
+		default:
+			err = fmt.Errorf("unknown todo field %q", field)
+		}
+		if err != nil {
+			return models.Internal("failed to update todo: %w", err)
+		}
+	}
+
+	todo.UpdatedAt = r.store.now()
+	r.store.data.todos[id] = todo
+	return nil
+}
+
+func (r *MemoryTodoRepository) DeleteTodo(ctx context.Context, userID, id int) error {
+	defer r.store.lock(ctx)()
+
+	todo, ok := r.store.data.todos[id]
+	if !ok || todo.UserID != userID {
+		return models.NotFound("todo with id %d not found", id)
+	}
+	delete(r.store.data.todos, id)
+	return nil
+}
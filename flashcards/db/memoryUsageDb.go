@@ -0,0 +1,129 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+type MemoryUsageRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryUsageRepository(store *MemoryStore) *MemoryUsageRepository {
+	return &MemoryUsageRepository{store: store}
+}
+
+func (r *MemoryUsageRepository) RecordLLMCall(ctx context.Context, call *models.LLMCall) error {
+	defer r.store.lock(ctx)()
+
+	call.ID, call.CreatedAt = int64(r.store.nextID("llm_calls")), r.store.now()
+	r.store.data.llmCalls[call.ID] = *call
+	return nil
+}
+
+// GetCostSince returns the estimated cost of the user's calls since the
+// given time
+func (r *MemoryUsageRepository) GetCostSince(ctx context.Context, userID int, since time.Time) (float64, error) {
+	cost := 0.0
+	for _, call := range r.calls(ctx, since) {
+		if call.UserID == userID {
+			cost += call.CostUSD
+		}
+	}
+	return cost, nil
+}
+
+// GetDailyUsage totals the user's calls per UTC day since the given time,
+// oldest first. Days without calls are left out.
+func (r *MemoryUsageRepository) GetDailyUsage(ctx context.Context, userID int, since time.Time) ([]*models.DailyLLMUsage, error) {
+	byDay := make(map[time.Time]*models.DailyLLMUsage)
+	for _, call := range r.calls(ctx, since) {
+		if call.UserID != userID {
+			continue
+		}
+		day := call.CreatedAt.UTC().Truncate(24 * time.Hour)
+		if byDay[day] == nil {
+			byDay[day] = &models.DailyLLMUsage{Day: day}
+		}
+		usage := byDay[day]
+		usage.Calls, usage.InputTokens, usage.OutputTokens, usage.CostUSD = usage.Calls+1,
+			usage.InputTokens+call.InputTokens, usage.OutputTokens+call.OutputTokens, usage.CostUSD+call.CostUSD
+	}
+
+	days := []*models.DailyLLMUsage{}
+	for _, day := range slices.SortedFunc(maps.Keys(byDay), time.Time.Compare) {
+		days = append(days, byDay[day])
+	}
+	return days, nil
+}
+
+// GetModelUsage totals the user's calls per model since the given time,
+// costliest first
+func (r *MemoryUsageRepository) GetModelUsage(ctx context.Context, userID int, since time.Time) ([]*models.ModelLLMUsage, error) {
+	byModel := make(map[string]*models.ModelLLMUsage)
+	for _, call := range r.calls(ctx, since) {
+		if call.UserID != userID {
+			continue
+		}
+		if byModel[call.Model] == nil {
+			byModel[call.Model] = &models.ModelLLMUsage{Model: call.Model}
+		}
+		usage := byModel[call.Model]
+		usage.Calls, usage.InputTokens, usage.OutputTokens, usage.CostUSD = usage.Calls+1,
+			usage.InputTokens+call.InputTokens, usage.OutputTokens+call.OutputTokens, usage.CostUSD+call.CostUSD
+	}
+
+	return slices.SortedFunc(maps.Values(byModel), func(a, b *models.ModelLLMUsage) int {
+		return cmp.Or(cmp.Compare(b.CostUSD, a.CostUSD), cmp.Compare(a.Model, b.Model))
+	}), nil
+}
+
+// GetUsageByUser totals calls per user since the given time, returning the
+// limit costliest users
+func (r *MemoryUsageRepository) GetUsageByUser(ctx context.Context, since time.Time, limit int) ([]*models.UserLLMUsage, error) {
+	byUser := make(map[int]*models.UserLLMUsage)
+	for _, call := range r.calls(ctx, since) {
+		if byUser[call.UserID] == nil {
+			byUser[call.UserID] = &models.UserLLMUsage{UserID: call.UserID}
+		}
+		usage := byUser[call.UserID]
+		usage.Calls, usage.InputTokens, usage.OutputTokens, usage.CostUSD = usage.Calls+1,
+			usage.InputTokens+call.InputTokens, usage.OutputTokens+call.OutputTokens, usage.CostUSD+call.CostUSD
+	}
+
+	usage := slices.SortedFunc(maps.Values(byUser), func(a, b *models.UserLLMUsage) int {
+		return cmp.Or(cmp.Compare(b.CostUSD, a.CostUSD), a.UserID-b.UserID)
+	})
+	return usage[:min(limit, len(usage))], nil
+}
+
+func (r *MemoryUsageRepository) DeleteLLMCallsBefore(ctx context.Context, before time.Time) (int64, error) {
+	defer r.store.lock(ctx)()
+
+	var deleted int64
+	for id, call := range r.store.data.llmCalls {
+		if call.CreatedAt.Before(before) {
+			delete(r.store.data.llmCalls, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// calls returns every call made since the given time
+func (r *MemoryUsageRepository) calls(ctx context.Context, since time.Time) []models.LLMCall {
+	defer r.store.lock(ctx)()
+
+	calls := make([]models.LLMCall, 0)
+	for _, call := range r.store.data.llmCalls {
+		if !call.CreatedAt.Before(since) {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
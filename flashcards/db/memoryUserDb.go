@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+
+	"flashcards/models"
+)
+
+type MemoryUserRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryUserRepository(store *MemoryStore) *MemoryUserRepository {
+	return &MemoryUserRepository{store: store}
+}
+
+func (r *MemoryUserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	defer r.store.lock(ctx)()
+
+	for _, existing := range r.store.data.users {
+		if existing.Email == user.Email {
+			return models.Conflict("user with email %s already exists", user.Email)
+		}
+	}
+
+	now := r.store.now()
+	user.ID, user.CreatedAt, user.UpdatedAt = r.store.nextID("users"), now, now
+	r.store.data.users[user.ID] = *user
+	return nil
+}
+
+func (r *MemoryUserRepository) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	defer r.store.lock(ctx)()
+
+	user, ok := r.store.data.users[id]
+	if !ok {
+		return nil, models.NotFound("user with id %d not found", id)
+	}
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	defer r.store.lock(ctx)()
+
+	for _, user := range r.store.data.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, models.NotFound("user with email %s not found", email)
+}
+
+func (r *MemoryUserRepository) SetUserAdmin(ctx context.Context, id int, isAdmin bool) error {
+	defer r.store.lock(ctx)()
+
+	user, ok := r.store.data.users[id]
+	if !ok {
+		return models.NotFound("user with id %d not found", id)
+	}
+	user.IsAdmin, user.UpdatedAt = isAdmin, r.store.now()
+	r.store.data.users[id] = user
+	return nil
+}
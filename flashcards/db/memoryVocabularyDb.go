@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"slices"
+
+	"flashcards/models"
+)
+
+// memoryVocabulary is a row of flashcard_vocabulary
+type memoryVocabulary struct {
+	vocabulary models.Vocabulary
+	audio      []byte
+}
+
+type MemoryVocabularyRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryVocabularyRepository(store *MemoryStore) *MemoryVocabularyRepository {
+	return &MemoryVocabularyRepository{store: store}
+}
+
+func (r *MemoryVocabularyRepository) GetVocabulary(ctx context.Context, userID, flashcardID int) (*models.Vocabulary, error) {
+	defer r.store.lock(ctx)()
+
+	row, ok := r.row(userID, flashcardID)
+	if !ok {
+		return nil, models.NotFound("vocabulary for flashcard with id %d not found", flashcardID)
+	}
+	vocabulary := row.vocabulary
+	vocabulary.Examples = slices.Clone(vocabulary.Examples)
+	vocabulary.HasAudio = row.audio != nil
+	return &vocabulary, nil
+}
+
+// SaveVocabulary creates or replaces the text fields of a flashcard's
+// vocabulary. Stored audio is kept unless the term changes.
+func (r *MemoryVocabularyRepository) SaveVocabulary(ctx context.Context, vocabulary *models.Vocabulary) error {
+	defer r.store.lock(ctx)()
+
+	now := r.store.now()
+	row, exists := r.store.data.vocabulary[vocabulary.FlashcardID]
+	if !exists {
+		row.vocabulary.CreatedAt = now
+	}
+	if row.vocabulary.Term != vocabulary.Term {
+		row.audio = nil
+	}
+
+	createdAt, contentType := row.vocabulary.CreatedAt, row.vocabulary.AudioContentType
+	row.vocabulary = *vocabulary
+	row.vocabulary.Examples = slices.Clone(vocabulary.Examples)
+	row.vocabulary.CreatedAt, row.vocabulary.UpdatedAt, row.vocabulary.AudioContentType = createdAt, now, contentType
+	r.store.data.vocabulary[vocabulary.FlashcardID] = row
+
+	vocabulary.CreatedAt, vocabulary.UpdatedAt, vocabulary.HasAudio = createdAt, now, row.audio != nil
+	return nil
+}
+
+func (r *MemoryVocabularyRepository) SaveAudio(ctx context.Context, flashcardID int, audio []byte, contentType string) error {
+	defer r.store.lock(ctx)()
+
+	row, ok := r.store.data.vocabulary[flashcardID]
+	if !ok {
+		return models.NotFound("vocabulary for flashcard with id %d not found", flashcardID)
+	}
+	row.audio = slices.Clone(audio)
+	row.vocabulary.AudioContentType, row.vocabulary.UpdatedAt = contentType, r.store.now()
+	r.store.data.vocabulary[flashcardID] = row
+	return nil
+}
+
+func (r *MemoryVocabularyRepository) GetAudio(ctx context.Context, userID, flashcardID int) ([]byte, string, error) {
+	defer r.store.lock(ctx)()
+
+	row, ok := r.row(userID, flashcardID)
+	if !ok || row.audio == nil {
+		return nil, "", models.NotFound("audio for flashcard with id %d not found", flashcardID)
+	}
+	return slices.Clone(row.audio), row.vocabulary.AudioContentType, nil
+}
+
+// GetFlashcardIDsWithAudio returns which of the given flashcards have stored
+// audio
+func (r *MemoryVocabularyRepository) GetFlashcardIDsWithAudio(ctx context.Context, userID int, flashcardIDs []int) ([]int, error) {
+	defer r.store.lock(ctx)()
+
+	ids := make([]int, 0)
+	for _, id := range flashcardIDs {
+		if row, ok := r.row(userID, id); ok && row.audio != nil && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// row returns the vocabulary of one of the user's flashcards. Callers hold
+// mu.
+func (r *MemoryVocabularyRepository) row(userID, flashcardID int) (memoryVocabulary, bool) {
+	row, ok := r.store.data.vocabulary[flashcardID]
+	if !ok || r.store.data.flashcards[flashcardID].UserID != userID {
+		return memoryVocabulary{}, false
+	}
+	return row, true
+}
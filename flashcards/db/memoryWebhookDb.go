@@ -0,0 +1,234 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"flashcards/models"
+)
+
+type MemoryWebhookRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryWebhookRepository(store *MemoryStore) *MemoryWebhookRepository {
+	return &MemoryWebhookRepository{store: store}
+}
+
+// CountWebhooks counts the user's webhooks of the given kind
+func (r *MemoryWebhookRepository) CountWebhooks(ctx context.Context, userID int, kind string) (int, error) {
+	defer r.store.lock(ctx)()
+
+	count := 0
+	for _, webhook := range r.store.data.webhooks {
+		if webhook.UserID == userID && webhook.Kind == kind {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryWebhookRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	defer r.store.lock(ctx)()
+
+	now := r.store.now()
+	webhook.ID, webhook.CreatedAt, webhook.UpdatedAt = r.store.nextID("webhooks"), now, now
+	webhook.Events = slices.Clone(webhook.Events)
+	r.store.data.webhooks[webhook.ID] = *memoryWebhook(*webhook)
+	return nil
+}
+
+// GetWebhooks returns the user's webhooks by ID
+func (r *MemoryWebhookRepository) GetWebhooks(ctx context.Context, userID int) ([]*models.Webhook, error) {
+	defer r.store.lock(ctx)()
+
+	webhooks := make([]*models.Webhook, 0)
+	for _, webhook := range r.store.data.webhooks {
+		if webhook.UserID == userID {
+			webhooks = append(webhooks, memoryWebhook(webhook))
+		}
+	}
+	slices.SortFunc(webhooks, func(a, b *models.Webhook) int { return a.ID - b.ID })
+	return webhooks, nil
+}
+
+func (r *MemoryWebhookRepository) GetWebhook(ctx context.Context, userID, id int) (*models.Webhook, error) {
+	defer r.store.lock(ctx)()
+
+	webhook, ok := r.store.data.webhooks[id]
+	if !ok || webhook.UserID != userID {
+		return nil, models.NotFound("webhook with id %d not found", id)
+	}
+	return memoryWebhook(webhook), nil
+}
+
+// UpdateWebhook saves the webhook's URL, events and active flag
+func (r *MemoryWebhookRepository) UpdateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	defer r.store.lock(ctx)()
+
+	stored, ok := r.store.data.webhooks[webhook.ID]
+	if !ok || stored.UserID != webhook.UserID {
+		return models.NotFound("webhook with id %d not found", webhook.ID)
+	}
+	stored.URL, stored.Events, stored.Active = webhook.URL, slices.Clone(webhook.Events), webhook.Active
+	stored.UpdatedAt = r.store.now()
+	r.store.data.webhooks[webhook.ID] = stored
+	*webhook = *memoryWebhook(stored)
+	return nil
+}
+
+func (r *MemoryWebhookRepository) DeleteWebhook(ctx context.Context, userID, id int) error {
+	defer r.store.lock(ctx)()
+
+	webhook, ok := r.store.data.webhooks[id]
+	if !ok || webhook.UserID != userID {
+		return models.NotFound("webhook with id %d not found", id)
+	}
+	delete(r.store.data.webhooks, id)
+	for deliveryID, delivery := range r.store.data.webhookDeliveries {
+		if delivery.WebhookID == id {
+			delete(r.store.data.webhookDeliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+// CreateDeliveries queues the event for each of the user's active webhooks
+// of the given kind subscribed to it and returns how many were queued
+func (r *MemoryWebhookRepository) CreateDeliveries(ctx context.Context, userID int, kind, event string, payload []byte) (int, error) {
+	defer r.store.lock(ctx)()
+
+	now := r.store.now()
+	created := 0
+	for _, webhook := range r.store.data.webhooks {
+		if webhook.UserID != userID || webhook.Kind != kind || !webhook.Active || !slices.Contains(webhook.Events, event) {
+			continue
+		}
+		id := r.store.nextID("webhook_deliveries")
+		r.store.data.webhookDeliveries[id] = models.WebhookDelivery{
+			ID:            id,
+			WebhookID:     webhook.ID,
+			Event:         event,
+			Payload:       slices.Clone(payload),
+			Status:        models.WebhookDeliveryStatusPending,
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		created++
+	}
+	return created, nil
+}
+
+// ListDeliveries returns one page of a webhook's delivery log and the total
+// number of deliveries
+func (r *MemoryWebhookRepository) ListDeliveries(ctx context.Context, webhookID int, params models.ListParams) ([]*models.WebhookDelivery, int, error) {
+	defer r.store.lock(ctx)()
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for _, delivery := range r.store.data.webhookDeliveries {
+		if delivery.WebhookID == webhookID {
+			deliveries = append(deliveries, memoryWebhookDelivery(delivery))
+		}
+	}
+
+	page, err := memoryPage(deliveries, params,
+		func(d *models.WebhookDelivery) time.Time { return d.CreatedAt },
+		func(d *models.WebhookDelivery) time.Time { return d.UpdatedAt },
+		func(d *models.WebhookDelivery) int { return d.ID })
+	if err != nil {
+		return nil, 0, err
+	}
+	return page, len(deliveries), nil
+}
+
+// ClaimDueDeliveries returns pending deliveries whose next attempt is due
+// by the store's clock, with their webhook's URL, secret, owner and kind,
+// and pushes that attempt back by lease
+func (r *MemoryWebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	defer r.store.lock(ctx)()
+
+	now := r.store.now()
+	due := make([]models.WebhookDelivery, 0)
+	for _, delivery := range r.store.data.webhookDeliveries {
+		if delivery.Status == models.WebhookDeliveryStatusPending && delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	slices.SortFunc(due, func(a, b models.WebhookDelivery) int {
+		return cmp.Or(a.NextAttemptAt.Compare(*b.NextAttemptAt), a.ID-b.ID)
+	})
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for _, delivery := range due[:min(limit, len(due))] {
+		nextAttemptAt := now.Add(lease)
+		delivery.NextAttemptAt, delivery.UpdatedAt = &nextAttemptAt, now
+		r.store.data.webhookDeliveries[delivery.ID] = delivery
+
+		claimed := memoryWebhookDelivery(delivery)
+		webhook := r.store.data.webhooks[delivery.WebhookID]
+		claimed.URL, claimed.Secret, claimed.UserID, claimed.Kind = webhook.URL, webhook.Secret, webhook.UserID, webhook.Kind
+		deliveries = append(deliveries, claimed)
+	}
+	return deliveries, nil
+}
+
+func (r *MemoryWebhookRepository) MarkDelivered(ctx context.Context, id, responseStatus int) error {
+	defer r.store.lock(ctx)()
+
+	if delivery, ok := r.store.data.webhookDeliveries[id]; ok {
+		now := r.store.now()
+		delivery.Status, delivery.ResponseStatus, delivery.Error = models.WebhookDeliveryStatusDelivered, &responseStatus, ""
+		delivery.Attempts++
+		delivery.NextAttemptAt, delivery.DeliveredAt, delivery.UpdatedAt = nil, &now, now
+		r.store.data.webhookDeliveries[id] = delivery
+	}
+	return nil
+}
+
+// FailDeliveryAttempt counts a failed attempt and tries again at
+// nextAttemptAt. Without a next attempt the delivery has failed for good.
+func (r *MemoryWebhookRepository) FailDeliveryAttempt(ctx context.Context, id int, responseStatus *int, message string, nextAttemptAt *time.Time) error {
+	defer r.store.lock(ctx)()
+
+	if delivery, ok := r.store.data.webhookDeliveries[id]; ok {
+		delivery.Attempts++
+		delivery.ResponseStatus, delivery.Error, delivery.NextAttemptAt = responseStatus, message, nextAttemptAt
+		delivery.Status = models.WebhookDeliveryStatusPending
+		if nextAttemptAt == nil {
+			delivery.Status = models.WebhookDeliveryStatusFailed
+		}
+		delivery.UpdatedAt = r.store.now()
+		r.store.data.webhookDeliveries[id] = delivery
+	}
+	return nil
+}
+
+// DeleteDeliveriesBefore removes finished deliveries created before the
+// given time. Pending ones are kept until they finish.
+func (r *MemoryWebhookRepository) DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error) {
+	defer r.store.lock(ctx)()
+
+	var deleted int64
+	for id, delivery := range r.store.data.webhookDeliveries {
+		if delivery.CreatedAt.Before(before) && delivery.Status != models.WebhookDeliveryStatusPending {
+			delete(r.store.data.webhookDeliveries, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// memoryWebhook copies a stored webhook for a caller
+func memoryWebhook(webhook models.Webhook) *models.Webhook {
+	webhook.Events = slices.Clone(webhook.Events)
+	return &webhook
+}
+
+// memoryWebhookDelivery copies a stored delivery for a caller
+func memoryWebhookDelivery(delivery models.WebhookDelivery) *models.WebhookDelivery {
+	delivery.Payload = slices.Clone(delivery.Payload)
+	return &delivery
+}
@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"flashcards/models"
+)
+
+// Each memory repository stands in for the Postgres one behind the same
+// interface
+var (
+	_ ActivityRepository          = (*MemoryActivityRepository)(nil)
+	_ AnalyticsRepository         = (*MemoryAnalyticsRepository)(nil)
+	_ APIKeyRepository            = (*MemoryAPIKeyRepository)(nil)
+	_ AttachmentRepository        = (*MemoryAttachmentRepository)(nil)
+	_ BrandingRepository          = (*MemoryBrandingRepository)(nil)
+	_ CatalogRepository           = (*MemoryCatalogRepository)(nil)
+	_ ContentFilterRepository     = (*MemoryContentFilterRepository)(nil)
+	_ DailyQuestionRepository     = (*MemoryDailyQuestionRepository)(nil)
+	_ DeadLetterRepository        = (*MemoryDeadLetterRepository)(nil)
+	_ DeckRepository              = (*MemoryDeckRepository)(nil)
+	_ DeckExportRepository        = (*MemoryDeckExportRepository)(nil)
+	_ DeckSuggestionRepository    = (*MemoryDeckSuggestionRepository)(nil)
+	_ DigestRepository            = (*MemoryDigestRepository)(nil)
+	_ EmbedRepository             = (*MemoryEmbedRepository)(nil)
+	_ FlashcardRepository         = (*MemoryFlashcardRepository)(nil)
+	_ IdempotencyRepository       = (*MemoryIdempotencyRepository)(nil)
+	_ ImportJobRepository         = (*MemoryImportJobRepository)(nil)
+	_ InviteRepository            = (*MemoryInviteRepository)(nil)
+	_ JobRepository               = (*MemoryJobRepository)(nil)
+	_ MaintenanceRepository       = (*MemoryMaintenanceRepository)(nil)
+	_ NoteRepository              = (*MemoryNoteRepository)(nil)
+	_ OnboardingRepository        = (*MemoryOnboardingRepository)(nil)
+	_ OrganizationRepository      = (*MemoryOrganizationRepository)(nil)
+	_ PromptTemplateRepository    = (*MemoryPromptTemplateRepository)(nil)
+	_ QuestionEmbeddingRepository = (*MemoryQuestionEmbeddingRepository)(nil)
+	_ QuizSessionRepository       = (*MemoryQuizSessionRepository)(nil)
+	_ ReportRecipientRepository   = (*MemoryReportRecipientRepository)(nil)
+	_ ReviewRepository            = (*MemoryReviewRepository)(nil)
+	_ RoutingRuleRepository       = (*MemoryRoutingRuleRepository)(nil)
+	_ ScheduledJobRepository      = (*MemoryScheduledJobRepository)(nil)
+	_ SpendRepository             = (*MemorySpendRepository)(nil)
+	_ StudyAnalyticsRepository    = (*MemoryStudyAnalyticsRepository)(nil)
+	_ TagRepository               = (*MemoryTagRepository)(nil)
+	_ TodoRepository              = (*MemoryTodoRepository)(nil)
+	_ UsageRepository             = (*MemoryUsageRepository)(nil)
+	_ UserRepository              = (*MemoryUserRepository)(nil)
+	_ VocabularyRepository        = (*MemoryVocabularyRepository)(nil)
+	_ WebhookRepository           = (*MemoryWebhookRepository)(nil)
+	_ UnitOfWork                  = (*MemoryUnitOfWork)(nil)
+)
+
+var memoryTestTime = time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+
+func newTestMemoryStore() *MemoryStore {
+	return NewMemoryStore(func() time.Time { return memoryTestTime })
+}
+
+func TestMemoryStoreAssignsIDsAndTimes(t *testing.T) {
+	ctx := context.Background()
+	store := newTestMemoryStore()
+	users := NewMemoryUserRepository(store)
+	decks := NewMemoryDeckRepository(store)
+
+	user := &models.User{Email: "ada@example.com"}
+	if err := users.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	for want := 1; want <= 2; want++ {
+		deck := &models.Deck{UserID: user.ID, Name: "Deck"}
+		if err := decks.CreateDeck(ctx, deck); err != nil {
+			t.Fatalf("CreateDeck: %v", err)
+		}
+		if deck.ID != want || !deck.CreatedAt.Equal(memoryTestTime) || !deck.UpdatedAt.Equal(memoryTestTime) {
+			t.Errorf("deck = %d created %v, want %d created %v", deck.ID, deck.CreatedAt, want, memoryTestTime)
+		}
+	}
+	if user.ID != 1 {
+		t.Errorf("user ID = %d, want 1", user.ID)
+	}
+
+	if err := users.CreateUser(ctx, &models.User{Email: "ada@example.com"}); !errors.Is(err, models.ErrConflict) {
+		t.Errorf("CreateUser with a taken email = %v, want a conflict", err)
+	}
+	if _, err := decks.GetDeckByID(ctx, user.ID+1, 1); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("GetDeckByID for another user = %v, want not found", err)
+	}
+}
+
+func TestMemoryDeleteFlashcardCascades(t *testing.T) {
+	ctx := context.Background()
+	store := newTestMemoryStore()
+	flashcards := NewMemoryFlashcardRepository(store)
+	reviews := NewMemoryReviewRepository(store)
+	vocabulary := NewMemoryVocabularyRepository(store)
+
+	flashcard := &models.Flashcard{UserID: 1, Front: "hola", Back: "hello"}
+	if err := flashcards.CreateFlashcard(ctx, flashcard); err != nil {
+		t.Fatalf("CreateFlashcard: %v", err)
+	}
+	review := &models.Review{UserID: 1, FlashcardID: flashcard.ID, Rating: "good", ReviewedAt: memoryTestTime}
+	schedule := &models.Schedule{FlashcardID: flashcard.ID, State: models.CardStateNew, DueAt: memoryTestTime}
+	if err := reviews.SaveReview(ctx, review, schedule); err != nil {
+		t.Fatalf("SaveReview: %v", err)
+	}
+	if err := vocabulary.SaveVocabulary(ctx, &models.Vocabulary{FlashcardID: flashcard.ID, Term: "hola"}); err != nil {
+		t.Fatalf("SaveVocabulary: %v", err)
+	}
+
+	if err := flashcards.DeleteFlashcard(ctx, 2, flashcard.ID); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("DeleteFlashcard for another user = %v, want not found", err)
+	}
+	if err := flashcards.DeleteFlashcard(ctx, 1, flashcard.ID); err != nil {
+		t.Fatalf("DeleteFlashcard: %v", err)
+	}
+
+	if _, err := reviews.GetSchedule(ctx, 1, flashcard.ID); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("GetSchedule after delete = %v, want not found", err)
+	}
+	if _, err := reviews.GetLatestReview(ctx, 1); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("GetLatestReview after delete = %v, want not found", err)
+	}
+	if _, err := vocabulary.GetVocabulary(ctx, 1, flashcard.ID); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("GetVocabulary after delete = %v, want not found", err)
+	}
+}
+
+func TestMemoryUnitOfWorkRollsBack(t *testing.T) {
+	ctx := context.Background()
+	store := newTestMemoryStore()
+	uow := NewMemoryUnitOfWork(store)
+	decks := NewMemoryDeckRepository(store)
+
+	failed := errors.New("failed")
+	err := uow.Do(ctx, func(ctx context.Context) error {
+		if err := decks.CreateDeck(ctx, &models.Deck{UserID: 1, Name: "Rolled back"}); err != nil {
+			return err
+		}
+		// Nested units of work join the outer one
+		return uow.Do(ctx, func(ctx context.Context) error { return failed })
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("Do = %v, want %v", err, failed)
+	}
+	if all, _ := decks.GetAllDecks(ctx, 1); len(all) != 0 {
+		t.Fatalf("decks after rollback = %v, want none", all)
+	}
+
+	// The rolled back ID is handed out again, as the store is restored to
+	// how it was before the unit of work
+	err = uow.Do(ctx, func(ctx context.Context) error {
+		return decks.CreateDeck(ctx, &models.Deck{UserID: 1, Name: "Kept"})
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if all, _ := decks.GetAllDecks(ctx, 1); len(all) != 1 || all[0].ID != 1 || all[0].Name != "Kept" {
+		t.Fatalf("decks after commit = %v, want deck 1 Kept", all)
+	}
+}
+
+// A write made outside a unit of work waits for it, so rolling the unit
+// back does not undo the write
+func TestMemoryUnitOfWorkRollbackKeepsOtherWrites(t *testing.T) {
+	ctx := context.Background()
+	store := newTestMemoryStore()
+	uow := NewMemoryUnitOfWork(store)
+	decks := NewMemoryDeckRepository(store)
+
+	writing := make(chan struct{})
+	written := make(chan error)
+	failed := errors.New("failed")
+	err := uow.Do(ctx, func(txCtx context.Context) error {
+		if err := decks.CreateDeck(txCtx, &models.Deck{UserID: 1, Name: "Rolled back"}); err != nil {
+			return err
+		}
+		go func() {
+			close(writing)
+			written <- decks.CreateDeck(ctx, &models.Deck{UserID: 2, Name: "Outside"})
+		}()
+		<-writing
+		time.Sleep(10 * time.Millisecond)
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("Do = %v, want %v", err, failed)
+	}
+	if err := <-written; err != nil {
+		t.Fatalf("CreateDeck outside the unit: %v", err)
+	}
+
+	if all, _ := decks.GetAllDecks(ctx, 1); len(all) != 0 {
+		t.Errorf("decks of user 1 = %v, want none", all)
+	}
+	if all, _ := decks.GetAllDecks(ctx, 2); len(all) != 1 || all[0].Name != "Outside" {
+		t.Errorf("decks of user 2 = %v, want the deck written outside the unit", all)
+	}
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Migration is one SQL file of supabase/migrations. Version is the
+// timestamp that starts its file name.
+type Migration struct {
+	Version string
+	Name    string
+	Path    string
+}
+
+// The table the Supabase CLI records applied migrations in, so migrations
+// applied by either tool are skipped by the other
+const createMigrationsTableQuery = `
+		CREATE SCHEMA IF NOT EXISTS supabase_migrations;
+		CREATE TABLE IF NOT EXISTS supabase_migrations.schema_migrations (
+			version TEXT NOT NULL PRIMARY KEY,
+			statements TEXT[],
+			name TEXT
+		)`
+
+// ReadMigrations lists the <version>_<name>.sql files in dir, oldest first
+func ReadMigrations(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var migrations []Migration
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		version, name, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+		if !ok || version == "" {
+			return nil, fmt.Errorf("migration file %s is not named <version>_<name>.sql", entry.Name())
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, Path: filepath.Join(dir, entry.Name())})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// PendingMigrations returns the migrations in dir not yet applied to the
+// database
+func PendingMigrations(ctx context.Context, databaseURL, dir string) ([]Migration, error) {
+	db, err := openDatabase("migrate", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	return pendingMigrations(ctx, db, dir)
+}
+
+// Migrate applies the pending migrations in dir in version order, each in
+// its own transaction, and returns those applied. It stops at the first
+// that fails, leaving the ones before it applied.
+func Migrate(ctx context.Context, databaseURL, dir string) ([]Migration, error) {
+	db, err := openDatabase("migrate", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	pending, err := pendingMigrations(ctx, db, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range pending {
+		if err := applyMigration(ctx, db, migration); err != nil {
+			return applied, err
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+func pendingMigrations(ctx context.Context, db *sql.DB, dir string) ([]Migration, error) {
+	migrations, err := ReadMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.ExecContext(ctx, createMigrationsTableQuery); err != nil {
+		return nil, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT version FROM supabase_migrations.schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[string]bool{}
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	var pending []Migration
+	for _, migration := range migrations {
+		if !applied[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, migration Migration) error {
+	statements, err := os.ReadFile(migration.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read migration %s: %w", migration.Version, err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, string(statements)); err != nil {
+		return fmt.Errorf("failed to apply migration %s_%s: %w", migration.Version, migration.Name, err)
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO supabase_migrations.schema_migrations (version, name, statements) VALUES ($1, $2, $3)",
+		migration.Version, migration.Name, pq.Array([]string{string(statements)}))
+	if err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", migration.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %s: %w", migration.Version, err)
+	}
+	return nil
+}
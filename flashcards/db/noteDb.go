@@ -1,93 +1,184 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
 	"flashcards/models"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+// Notes are selected together with their tag names
+const noteSelectQuery = `
+		SELECT n.id, n.user_id, n.deck_id, n.title, n.summary, n.source, n.language, n.content, n.version, n.createdAt, n.updatedAt, 
+			COALESCE(array_agg(t.name ORDER BY t.name) FILTER (WHERE t.name IS NOT NULL), '{}') AS tags 
+		FROM gocourse.notes n 
+		LEFT JOIN gocourse.note_tags nt ON nt.note_id = n.id 
+		LEFT JOIN gocourse.tags t ON t.id = nt.tag_id`
+
 type NoteRepository interface {
-	CreateNote(note *models.Note) error
-	GetNoteByID(id int) (*models.Note, error)
-	GetAllNotes() ([]*models.Note, error)
-	UpdateNote(id int, updates map[string]any) error
-	DeleteNote(id int) error
+	CreateNote(ctx context.Context, note *models.Note) error
+	CreateNotes(ctx context.Context, notes []*models.Note) error
+	GetNoteByID(ctx context.Context, userID, id int) (*models.Note, error)
+	GetAllNotes(ctx context.Context, userID int) ([]*models.Note, error)
+	ListNotes(ctx context.Context, userID int, filter models.NoteFilter, params models.ListParams) ([]*models.Note, int, error)
+	UpdateNote(ctx context.Context, userID, id, version int, updates map[string]any) error
+	SetNoteSummary(ctx context.Context, id int, content, summary string) error
+	SetNoteLanguage(ctx context.Context, id int, content, language string) error
+	DeleteNote(ctx context.Context, userID, id int) error
 }
 
 type PostgresNoteRepository struct {
 	db *sql.DB
 }
 
-func NewPostgresNoteRepository(databaseURL string) (*PostgresNoteRepository, error) {
-	db, err := sql.Open("postgres", databaseURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
-
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
-	}
-
-	return &PostgresNoteRepository{db: db}, nil
+func NewPostgresNoteRepository(db *sql.DB) *PostgresNoteRepository {
+	return &PostgresNoteRepository{db: db}
 }
 
-func (r *PostgresNoteRepository) CreateNote(note *models.Note) error {
+func (r *PostgresNoteRepository) CreateNote(ctx context.Context, note *models.Note) error {
 	query := `
-		INSERT INTO gocourse.notes (content) 
-		VALUES ($1) 
-		RETURNING id, createdAt, updatedAt`
+		INSERT INTO gocourse.notes (user_id, deck_id, title, source, language, content) 
+		VALUES ($1, $2, $3, $4, $5, $6) 
+		RETURNING id, version, createdAt, updatedAt`
 
-	row := r.db.QueryRow(query, note.Content)
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, note.UserID, note.DeckID, note.Title, note.Source, note.Language, note.Content)
 
-	err := row.Scan(&note.ID, &note.CreatedAt, &note.UpdatedAt)
+	err := row.Scan(&note.ID, &note.Version, &note.CreatedAt, &note.UpdatedAt)
 	if err != nil {
-		return fmt.Errorf("failed to create note: %w", err)
+		return models.Internal("failed to create note: %w", err)
 	}
 
 	return nil
 }
 
-func (r *PostgresNoteRepository) GetNoteByID(id int) (*models.Note, error) {
+// CreateNotes inserts a batch of notes in a single transaction
+func (r *PostgresNoteRepository) CreateNotes(ctx context.Context, notes []*models.Note) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
-		SELECT id, content, createdAt, updatedAt 
-		FROM gocourse.notes 
-		WHERE id = $1`
+		INSERT INTO gocourse.notes (user_id, deck_id, title, source, language, content) 
+		VALUES ($1, $2, $3, $4, $5, $6) 
+		RETURNING id, version, createdAt, updatedAt`
+
+	for _, note := range notes {
+		row := tx.QueryRowContext(ctx, query, note.UserID, note.DeckID, note.Title, note.Source, note.Language, note.Content)
+		if err := row.Scan(&note.ID, &note.Version, &note.CreatedAt, &note.UpdatedAt); err != nil {
+			return models.Internal("failed to create note: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit notes: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresNoteRepository) GetNoteByID(ctx context.Context, userID, id int) (*models.Note, error) {
+	query := noteSelectQuery + `
+		WHERE n.id = $1 AND n.user_id = $2 
+		GROUP BY n.id`
 
 	note := &models.Note{}
-	row := r.db.QueryRow(query, id)
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, id, userID)
 
-	err := row.Scan(&note.ID, &note.Content, &note.CreatedAt, &note.UpdatedAt)
+	err := row.Scan(&note.ID, &note.UserID, &note.DeckID, &note.Title, &note.Summary, &note.Source, &note.Language, &note.Content, &note.Version, &note.CreatedAt, &note.UpdatedAt, pq.Array(&note.Tags))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("note with id %d not found", id)
+			return nil, models.NotFound("note with id %d not found", id)
 		}
-		return nil, fmt.Errorf("failed to get note: %w", err)
+		return nil, models.Internal("failed to get note: %w", err)
 	}
 
 	return note, nil
 }
 
-func (r *PostgresNoteRepository) GetAllNotes() ([]*models.Note, error) {
-	query := `
-		SELECT id, content, createdAt, updatedAt 
-		FROM gocourse.notes 
-		ORDER BY createdAt DESC`
+func (r *PostgresNoteRepository) GetAllNotes(ctx context.Context, userID int) ([]*models.Note, error) {
+	query := noteSelectQuery + `
+		WHERE n.user_id = $1 
+		GROUP BY n.id 
+		ORDER BY n.createdAt DESC`
+
+	return r.queryNotes(ctx, query, userID)
+}
+
+// ListNotes returns one page of the user's notes matching the filter, along
+// with the total number of matching notes
+func (r *PostgresNoteRepository) ListNotes(ctx context.Context, userID int, filter models.NoteFilter, params models.ListParams) ([]*models.Note, int, error) {
+	where := " WHERE n.user_id = $1"
+	args := []any{userID}
+
+	if filter.Tag != "" {
+		args = append(args, filter.Tag)
+		where += fmt.Sprintf(` AND n.id IN (
+			SELECT nt2.note_id 
+			FROM gocourse.note_tags nt2 
+			JOIN gocourse.tags t2 ON t2.id = nt2.tag_id 
+			WHERE t2.name = $%d
+		)`, len(args))
+	}
+
+	if filter.DeckID != nil {
+		args = append(args, *filter.DeckID)
+		where += fmt.Sprintf(" AND n.deck_id = $%d", len(args))
+	}
+
+	if filter.Language != "" {
+		args = append(args, filter.Language)
+		where += fmt.Sprintf(" AND n.language = $%d", len(args))
+	}
+
+	if filter.Query != "" {
+		args = append(args, likePattern(filter.Query))
+		where += fmt.Sprintf(" AND (n.title ILIKE $%d OR n.content ILIKE $%d)", len(args), len(args))
+	}
 
-	rows, err := r.db.Query(query)
+	var total int
+	if err := executor(ctx, r.db).QueryRowContext(ctx, "SELECT COUNT(*) FROM gocourse.notes n"+where, args...).Scan(&total); err != nil {
+		return nil, 0, models.Internal("failed to count notes: %w", err)
+	}
+
+	orderBy, err := orderByClause("n", params)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := noteSelectQuery + where + " GROUP BY n.id" + orderBy +
+		fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+
+	notes, err := r.queryNotes(ctx, query, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return notes, total, nil
+}
+
+// Warm prepares the note list read while building prompts
+func (r *PostgresNoteRepository) Warm(ctx context.Context) error {
+	return warmPool(ctx, r.db, noteSelectQuery+" WHERE n.user_id = $1 GROUP BY n.id ORDER BY n.createdAt DESC")
+}
+
+func (r *PostgresNoteRepository) queryNotes(ctx context.Context, query string, args ...any) ([]*models.Note, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query notes: %w", err)
+		return nil, models.Internal("failed to query notes: %w", err)
 	}
 	defer rows.Close()
 
 	notes := make([]*models.Note, 0)
 	for rows.Next() {
 		note := &models.Note{}
-		err := rows.Scan(&note.ID, &note.Content, &note.CreatedAt, &note.UpdatedAt)
+		err := rows.Scan(&note.ID, &note.UserID, &note.DeckID, &note.Title, &note.Summary, &note.Source, &note.Language, &note.Content, &note.Version, &note.CreatedAt, &note.UpdatedAt, pq.Array(&note.Tags))
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan note: %w", err)
+			return nil, models.Internal("failed to scan note: %w", err)
 		}
 		notes = append(notes, note)
 	}
@@ -99,9 +190,11 @@ func (r *PostgresNoteRepository) GetAllNotes() ([]*models.Note, error) {
 	return notes, nil
 }
 
-func (r *PostgresNoteRepository) UpdateNote(id int, updates map[string]any) error {
-	if len(updates) == 0 {
-		return fmt.Errorf("no updates provided")
+// UpdateNote applies the updates if the note is still at version, and bumps
+// its version. A note changed since is a conflict.
+func (r *PostgresNoteRepository) UpdateNote(ctx context.Context, userID, id, version int, updates map[string]any) error {
+	if err := checkUpdates("notes", updates); err != nil {
+		return err
 	}
 
 	query := "UPDATE gocourse.notes SET "
@@ -117,46 +210,67 @@ func (r *PostgresNoteRepository) UpdateNote(id int, updates map[string]any) erro
 		argIndex++
 	}
 
-	query += fmt.Sprintf(", updatedAt = NOW() WHERE id = $%d", argIndex)
-	args = append(args, id)
+	query += fmt.Sprintf(", version = version + 1, updatedAt = NOW() WHERE id = $%d AND user_id = $%d AND version = $%d", argIndex, argIndex+1, argIndex+2)
+	args = append(args, id, userID, version)
 
-	result, err := r.db.Exec(query, args...)
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, args...)
 	if err != nil {
-		return fmt.Errorf("failed to update note: %w", err)
+		return models.Internal("failed to update note: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return models.Internal("failed to get rows affected: %w", err)
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("note with id %d not found", id)
+		return versionMismatch(ctx, executor(ctx, r.db), "notes", "note", userID, id, version)
 	}
 
 	return nil
 }
 
-func (r *PostgresNoteRepository) DeleteNote(id int) error {
-	query := "DELETE FROM gocourse.notes WHERE id = $1"
+// SetNoteSummary stores the summary of a note as long as its content is
+// still the content that was summarized, so a summary that finishes after
+// an edit is dropped. It leaves updatedAt alone.
+func (r *PostgresNoteRepository) SetNoteSummary(ctx context.Context, id int, content, summary string) error {
+	query := "UPDATE gocourse.notes SET summary = $1 WHERE id = $2 AND content = $3"
 
-	result, err := r.db.Exec(query, id)
+	if _, err := executor(ctx, r.db).ExecContext(ctx, query, summary, id, content); err != nil {
+		return models.Internal("failed to set note summary: %w", err)
+	}
+
+	return nil
+}
+
+// SetNoteLanguage stores a language detected in the background, under the
+// same condition as SetNoteSummary
+func (r *PostgresNoteRepository) SetNoteLanguage(ctx context.Context, id int, content, language string) error {
+	query := "UPDATE gocourse.notes SET language = $1 WHERE id = $2 AND content = $3"
+
+	if _, err := executor(ctx, r.db).ExecContext(ctx, query, language, id, content); err != nil {
+		return models.Internal("failed to set note language: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresNoteRepository) DeleteNote(ctx context.Context, userID, id int) error {
+	query := "DELETE FROM gocourse.notes WHERE id = $1 AND user_id = $2"
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, id, userID)
 	if err != nil {
-		return fmt.Errorf("failed to delete note: %w", err)
+		return models.Internal("failed to delete note: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return models.Internal("failed to get rows affected: %w", err)
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("note with id %d not found", id)
+		return models.NotFound("note with id %d not found", id)
 	}
 
 	return nil
 }
-
-func (r *PostgresNoteRepository) Close() error {
-	return r.db.Close()
-}
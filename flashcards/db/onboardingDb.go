@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"flashcards/models"
+)
+
+type OnboardingRepository interface {
+	GetOnboardingProgress(ctx context.Context, userID int) (*models.OnboardingProgress, error)
+}
+
+type PostgresOnboardingRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresOnboardingRepository(db *sql.DB) *PostgresOnboardingRepository {
+	return &PostgresOnboardingRepository{db: db}
+}
+
+// GetOnboardingProgress counts the user's decks, notes, flashcards and
+// reviews, and the invites sent for their organization
+func (r *PostgresOnboardingRepository) GetOnboardingProgress(ctx context.Context, userID int) (*models.OnboardingProgress, error) {
+	query := `
+		SELECT u.organization_id, COALESCE(u.organizationRole, $2), 
+			(SELECT COUNT(*) FROM gocourse.decks WHERE user_id = u.id), 
+			(SELECT COUNT(*) FROM gocourse.notes WHERE user_id = u.id), 
+			(SELECT COUNT(*) FROM gocourse.flashcards WHERE user_id = u.id), 
+			(SELECT COUNT(*) FROM gocourse.reviews WHERE user_id = u.id), 
+			(SELECT COUNT(*) FROM gocourse.organization_invites WHERE organization_id = u.organization_id) 
+		FROM gocourse.users u 
+		WHERE u.id = $1`
+
+	var organizationID sql.NullInt64
+	var role string
+	progress := &models.OnboardingProgress{}
+	err := r.db.QueryRowContext(ctx, query, userID, models.RoleMember).Scan(&organizationID, &role, &progress.Decks,
+		&progress.Notes, &progress.Flashcards, &progress.Reviews, &progress.InvitesSent)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("user with id %d not found", userID)
+		}
+		return nil, models.Internal("failed to get onboarding progress: %w", err)
+	}
+
+	if organizationID.Valid {
+		progress.Membership = &models.Membership{OrganizationID: int(organizationID.Int64), Role: role}
+	}
+
+	return progress, nil
+}
@@ -0,0 +1,333 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+type OrganizationRepository interface {
+	CreateOrganization(ctx context.Context, organization *models.Organization) error
+	GetOrganization(ctx context.Context, id int) (*models.Organization, error)
+	GetUserOrganization(ctx context.Context, userID int) (*models.Organization, error)
+	UpdateOrganization(ctx context.Context, organization *models.Organization) error
+	CreateOwnedOrganization(ctx context.Context, organization *models.Organization, ownerID int) error
+	SetUserOrganization(ctx context.Context, userID int, organizationID *int, role string) error
+	GetMembership(ctx context.Context, userID int) (*models.Membership, error)
+	GetOrganizationByStripeCustomer(ctx context.Context, customerID string) (*models.Organization, error)
+	UpdateOrganizationBilling(ctx context.Context, organization *models.Organization) error
+	HasStripeEvent(ctx context.Context, eventID string) (bool, error)
+	RecordStripeEvent(ctx context.Context, eventID string) error
+	GetOrganizationUsage(ctx context.Context, organizationID int, month time.Time) (*models.Usage, error)
+	GetUserUsage(ctx context.Context, userID int, month time.Time) (*models.Usage, error)
+	AddLLMTokens(ctx context.Context, userID int, at time.Time, tokens int) error
+}
+
+type PostgresOrganizationRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresOrganizationRepository(db *sql.DB) *PostgresOrganizationRepository {
+	return &PostgresOrganizationRepository{db: db}
+}
+
+func (r *PostgresOrganizationRepository) CreateOrganization(ctx context.Context, organization *models.Organization) error {
+	query := `
+		INSERT INTO gocourse.organizations (name, plan, maxNotes, maxCards, monthlyLlmTokens) 
+		VALUES ($1, $2, $3, $4, $5) 
+		RETURNING id, createdAt, updatedAt`
+
+	row := r.db.QueryRowContext(ctx, query, organization.Name, organization.Plan, organization.MaxNotesOverride,
+		organization.MaxCardsOverride, organization.MonthlyLLMTokensOverride)
+
+	err := row.Scan(&organization.ID, &organization.CreatedAt, &organization.UpdatedAt)
+	if err != nil {
+		return models.Internal("failed to create organization: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresOrganizationRepository) GetOrganization(ctx context.Context, id int) (*models.Organization, error) {
+	query := organizationSelect + `
+		WHERE o.id = $1`
+
+	organization, err := scanOrganization(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("organization with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get organization: %w", err)
+	}
+
+	return organization, nil
+}
+
+// GetUserOrganization returns the organization the user belongs to
+func (r *PostgresOrganizationRepository) GetUserOrganization(ctx context.Context, userID int) (*models.Organization, error) {
+	query := organizationSelect + `
+		JOIN gocourse.users u ON u.organization_id = o.id 
+		WHERE u.id = $1`
+
+	organization, err := scanOrganization(r.db.QueryRowContext(ctx, query, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("organization for user with id %d not found", userID)
+		}
+		return nil, models.Internal("failed to get organization: %w", err)
+	}
+
+	return organization, nil
+}
+
+// UpdateOrganization saves the organization's name, plan and overrides
+func (r *PostgresOrganizationRepository) UpdateOrganization(ctx context.Context, organization *models.Organization) error {
+	query := `
+		UPDATE gocourse.organizations 
+		SET name = $1, plan = $2, maxNotes = $3, maxCards = $4, monthlyLlmTokens = $5, updatedAt = NOW() 
+		WHERE id = $6 
+		RETURNING updatedAt`
+
+	row := r.db.QueryRowContext(ctx, query, organization.Name, organization.Plan, organization.MaxNotesOverride,
+		organization.MaxCardsOverride, organization.MonthlyLLMTokensOverride, organization.ID)
+	if err := row.Scan(&organization.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return models.NotFound("organization with id %d not found", organization.ID)
+		}
+		return models.Internal("failed to update organization: %w", err)
+	}
+
+	return nil
+}
+
+// GetOrganizationByStripeCustomer finds the organization billed to a Stripe
+// customer
+func (r *PostgresOrganizationRepository) GetOrganizationByStripeCustomer(ctx context.Context, customerID string) (*models.Organization, error) {
+	query := organizationSelect + `
+		WHERE o.stripeCustomerId = $1`
+
+	organization, err := scanOrganization(r.db.QueryRowContext(ctx, query, customerID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("organization for stripe customer %s not found", customerID)
+		}
+		return nil, models.Internal("failed to get organization: %w", err)
+	}
+
+	return organization, nil
+}
+
+// UpdateOrganizationBilling saves the organization's plan and Stripe
+// subscription state
+func (r *PostgresOrganizationRepository) UpdateOrganizationBilling(ctx context.Context, organization *models.Organization) error {
+	query := `
+		UPDATE gocourse.organizations 
+		SET plan = $1, stripeCustomerId = $2, stripeSubscriptionId = $3, subscriptionStatus = $4, stripeEventAt = $5, updatedAt = NOW() 
+		WHERE id = $6 
+		RETURNING updatedAt`
+
+	row := r.db.QueryRowContext(ctx, query, organization.Plan, organization.StripeCustomerID,
+		organization.StripeSubscriptionID, organization.SubscriptionStatus, organization.StripeEventAt, organization.ID)
+	if err := row.Scan(&organization.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return models.NotFound("organization with id %d not found", organization.ID)
+		}
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" && organization.StripeCustomerID != nil {
+			return models.Conflict("organization for stripe customer %s already exists", *organization.StripeCustomerID)
+		}
+		return models.Internal("failed to update organization billing: %w", err)
+	}
+
+	return nil
+}
+
+// HasStripeEvent reports whether the Stripe event was already applied
+func (r *PostgresOrganizationRepository) HasStripeEvent(ctx context.Context, eventID string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM gocourse.stripe_events WHERE id = $1)", eventID).Scan(&exists)
+	if err != nil {
+		return false, models.Internal("failed to check stripe event: %w", err)
+	}
+
+	return exists, nil
+}
+
+// RecordStripeEvent marks the Stripe event as applied
+func (r *PostgresOrganizationRepository) RecordStripeEvent(ctx context.Context, eventID string) error {
+	query := "INSERT INTO gocourse.stripe_events (id) VALUES ($1) ON CONFLICT (id) DO NOTHING"
+
+	if _, err := r.db.ExecContext(ctx, query, eventID); err != nil {
+		return models.Internal("failed to record stripe event: %w", err)
+	}
+
+	return nil
+}
+
+// CreateOwnedOrganization creates an organization with the user as its owner
+// in a single transaction. It fails when the user already belongs to an
+// organization.
+func (r *PostgresOrganizationRepository) CreateOwnedOrganization(ctx context.Context, organization *models.Organization, ownerID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO gocourse.organizations (name, plan) 
+		VALUES ($1, $2) 
+		RETURNING id, createdAt, updatedAt`
+
+	row := tx.QueryRowContext(ctx, query, organization.Name, organization.Plan)
+	if err := row.Scan(&organization.ID, &organization.CreatedAt, &organization.UpdatedAt); err != nil {
+		return models.Internal("failed to create organization: %w", err)
+	}
+
+	ownerQuery := `
+		UPDATE gocourse.users 
+		SET organization_id = $1, organizationRole = $2, updatedAt = NOW() 
+		WHERE id = $3 AND organization_id IS NULL`
+
+	result, err := tx.ExecContext(ctx, ownerQuery, organization.ID, models.RoleOwner, ownerID)
+	if err != nil {
+		return models.Internal("failed to set organization owner: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.Conflict("you already belong to an organization")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit organization: %w", err)
+	}
+
+	return nil
+}
+
+// SetUserOrganization moves a user into an organization with the given
+// role, or out of any organization when organizationID is nil
+func (r *PostgresOrganizationRepository) SetUserOrganization(ctx context.Context, userID int, organizationID *int, role string) error {
+	query := "UPDATE gocourse.users SET organization_id = $1, organizationRole = $2, updatedAt = NOW() WHERE id = $3"
+
+	var roleValue *string
+	if organizationID != nil {
+		roleValue = &role
+	}
+
+	result, err := r.db.ExecContext(ctx, query, organizationID, roleValue, userID)
+	if err != nil {
+		return models.Internal("failed to update user organization: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("user with id %d not found", userID)
+	}
+
+	return nil
+}
+
+// GetMembership returns the user's organization and role
+func (r *PostgresOrganizationRepository) GetMembership(ctx context.Context, userID int) (*models.Membership, error) {
+	query := `
+		SELECT organization_id, COALESCE(organizationRole, $2) 
+		FROM gocourse.users 
+		WHERE id = $1 AND organization_id IS NOT NULL`
+
+	membership := &models.Membership{}
+	err := r.db.QueryRowContext(ctx, query, userID, models.RoleMember).Scan(&membership.OrganizationID, &membership.Role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("organization for user with id %d not found", userID)
+		}
+		return nil, models.Internal("failed to get membership: %w", err)
+	}
+
+	return membership, nil
+}
+
+// GetOrganizationUsage totals the notes, cards and month's LLM tokens of all
+// members of an organization
+func (r *PostgresOrganizationRepository) GetOrganizationUsage(ctx context.Context, organizationID int, month time.Time) (*models.Usage, error) {
+	return r.getUsage(ctx, "SELECT id FROM gocourse.users WHERE organization_id = $1", organizationID, month)
+}
+
+// GetUserUsage totals usage across the user's organization, or for the user
+// alone when they are not in one
+func (r *PostgresOrganizationRepository) GetUserUsage(ctx context.Context, userID int, month time.Time) (*models.Usage, error) {
+	members := `
+		SELECT m.id FROM gocourse.users m 
+		JOIN gocourse.users u ON m.id = u.id OR m.organization_id = u.organization_id 
+		WHERE u.id = $1`
+	return r.getUsage(ctx, members, userID, month)
+}
+
+func (r *PostgresOrganizationRepository) getUsage(ctx context.Context, members string, id int, month time.Time) (*models.Usage, error) {
+	query := `
+		WITH members AS (` + members + `) 
+		SELECT 
+			(SELECT COUNT(*) FROM gocourse.notes WHERE user_id IN (SELECT id FROM members)), 
+			(SELECT COUNT(*) FROM gocourse.flashcards WHERE user_id IN (SELECT id FROM members)), 
+			(SELECT COALESCE(SUM(tokens), 0) FROM gocourse.llm_token_usage WHERE user_id IN (SELECT id FROM members) AND month = $2)`
+
+	usage := &models.Usage{}
+	err := r.db.QueryRowContext(ctx, query, id, month).Scan(&usage.Notes, &usage.Cards, &usage.LLMTokens)
+	if err != nil {
+		return nil, models.Internal("failed to get usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// AddLLMTokens adds to the user's token counts for the month and the hour
+// containing at
+func (r *PostgresOrganizationRepository) AddLLMTokens(ctx context.Context, userID int, at time.Time, tokens int) error {
+	query := `
+		WITH monthly AS ( 
+			INSERT INTO gocourse.llm_token_usage (user_id, month, tokens) 
+			VALUES ($1, $2, $4) 
+			ON CONFLICT (user_id, month) DO UPDATE SET tokens = gocourse.llm_token_usage.tokens + EXCLUDED.tokens 
+		) 
+		INSERT INTO gocourse.llm_token_usage_hourly (user_id, hour, tokens) 
+		VALUES ($1, $3, $4) 
+		ON CONFLICT (user_id, hour) DO UPDATE SET tokens = gocourse.llm_token_usage_hourly.tokens + EXCLUDED.tokens`
+
+	at = at.UTC()
+	month := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, time.UTC)
+	hour := at.Truncate(time.Hour)
+	if _, err := r.db.ExecContext(ctx, query, userID, month, hour, tokens); err != nil {
+		return models.Internal("failed to record LLM tokens: %w", err)
+	}
+
+	return nil
+}
+
+const organizationSelect = `
+		SELECT o.id, o.name, o.plan, o.maxNotes, o.maxCards, o.monthlyLlmTokens, 
+			o.stripeCustomerId, o.stripeSubscriptionId, o.subscriptionStatus, o.stripeEventAt, o.createdAt, o.updatedAt 
+		FROM gocourse.organizations o`
+
+func scanOrganization(row *sql.Row) (*models.Organization, error) {
+	organization := &models.Organization{}
+	err := row.Scan(&organization.ID, &organization.Name, &organization.Plan, &organization.MaxNotesOverride,
+		&organization.MaxCardsOverride, &organization.MonthlyLLMTokensOverride, &organization.StripeCustomerID,
+		&organization.StripeSubscriptionID, &organization.SubscriptionStatus, &organization.StripeEventAt, &organization.CreatedAt,
+		&organization.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return organization, nil
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"flashcards/models"
+)
+
+// Sort fields accepted by list queries, mapped to their columns. Only these
+// are ever interpolated into SQL.
+var sortColumns = map[string]string{
+	"createdAt": "createdAt",
+	"updatedAt": "updatedAt",
+	"id":        "id",
+}
+
+// orderByClause builds the ORDER BY clause for a list query on the table
+// with the given alias, breaking ties by id so pages are stable
+func orderByClause(alias string, params models.ListParams) (string, error) {
+	column, ok := sortColumns[params.Sort]
+	if !ok {
+		return "", fmt.Errorf("unsupported sort field %q", params.Sort)
+	}
+
+	direction := "DESC"
+	if params.Order == "asc" {
+		direction = "ASC"
+	}
+
+	return fmt.Sprintf(" ORDER BY %s.%s %s, %s.id %s", alias, column, direction, alias, direction), nil
+}
+
+// likePattern turns a search query into an ILIKE pattern matching it as a
+// literal substring
+func likePattern(query string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(query) + "%"
+}
@@ -0,0 +1,338 @@
+//go:build integration
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"flashcards/models"
+	"flashcards/testcontainer"
+)
+
+const (
+	TEST_POSTGRES_IMAGE   = "postgres:16-alpine"
+	TEST_POSTGRES_TIMEOUT = 2 * time.Minute
+)
+
+// testDatabaseURL is the database the integration tests share, and
+// testDB the pool their repositories share on it, as the server's do.
+// TestMain starts it in a container and applies supabase/migrations to it,
+// so each test creates its own user rather than expecting empty tables.
+var (
+	testDatabaseURL string
+	testDB          *sql.DB
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(runWithPostgres(m))
+}
+
+func runWithPostgres(m *testing.M) int {
+	ctx, cancel := context.WithTimeout(context.Background(), TEST_POSTGRES_TIMEOUT)
+	defer cancel()
+
+	container, err := testcontainer.Start(ctx, TEST_POSTGRES_IMAGE, "5432", "POSTGRES_PASSWORD=postgres")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer container.Close()
+	testDatabaseURL = "postgres://postgres:postgres@" + container.Addr + "/postgres?sslmode=disable"
+
+	err = container.Ready(ctx, func(ctx context.Context) error {
+		db, err := openDatabase("integration", testDatabaseURL)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return db.PingContext(ctx)
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if _, err := Migrate(ctx, testDatabaseURL, filepath.Join("..", "supabase", "migrations")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	testDB, err = openDatabase("integration", testDatabaseURL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer testDB.Close()
+
+	cancel()
+	return m.Run()
+}
+
+// createTestPostgresUser creates a user whose email is unique to the test
+func createTestPostgresUser(t *testing.T, name string) *models.User {
+	t.Helper()
+	users := NewPostgresUserRepository(testDB)
+	user := &models.User{Email: name + "." + strings.ToLower(t.Name()) + "@example.com", PasswordHash: "hash"}
+	if err := users.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return user
+}
+
+func TestPostgresMigrationsAreApplied(t *testing.T) {
+	dir := filepath.Join("..", "supabase", "migrations")
+	pending, err := PendingMigrations(context.Background(), testDatabaseURL, dir)
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("PendingMigrations = %v, %v, want none", pending, err)
+	}
+	if applied, err := Migrate(context.Background(), testDatabaseURL, dir); err != nil || len(applied) != 0 {
+		t.Fatalf("Migrate again = %v, %v, want nothing applied", applied, err)
+	}
+}
+
+func TestPostgresNoteVersions(t *testing.T) {
+	ctx := context.Background()
+	user := createTestPostgresUser(t, "ada")
+	stranger := createTestPostgresUser(t, "mallory")
+	notes := NewPostgresNoteRepository(testDB)
+
+	note := &models.Note{UserID: user.ID, Title: "Cells", Content: "Mitochondria make ATP"}
+	if err := notes.CreateNote(ctx, note); err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	if note.ID == 0 || note.Version != 1 || note.CreatedAt.IsZero() {
+		t.Fatalf("created note = %+v, want version 1 with an ID and time", note)
+	}
+
+	if err := notes.UpdateNote(ctx, user.ID, note.ID, 1, map[string]any{"content": "Mitochondria make ATP from glucose"}); err != nil {
+		t.Fatalf("UpdateNote: %v", err)
+	}
+	if err := notes.UpdateNote(ctx, user.ID, note.ID, 1, map[string]any{"content": "Stale"}); !errors.Is(err, models.ErrConflict) {
+		t.Errorf("UpdateNote at a stale version = %v, want a conflict", err)
+	}
+	if err := notes.UpdateNote(ctx, stranger.ID, note.ID, 2, map[string]any{"content": "Not mine"}); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("UpdateNote by another user = %v, want not found", err)
+	}
+	if err := notes.UpdateNote(ctx, user.ID, note.ID, 2, map[string]any{"content = 'x', title": "Injected"}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("UpdateNote of a field that is not a column = %v, want refused", err)
+	}
+
+	got, err := notes.GetNoteByID(ctx, user.ID, note.ID)
+	if err != nil {
+		t.Fatalf("GetNoteByID: %v", err)
+	}
+	if got.Version != 2 || got.Content != "Mitochondria make ATP from glucose" {
+		t.Errorf("note = version %d %q, want the first update at version 2", got.Version, got.Content)
+	}
+
+	if err := notes.DeleteNote(ctx, user.ID, note.ID); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+	if _, err := notes.GetNoteByID(ctx, user.ID, note.ID); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("GetNoteByID after DeleteNote = %v, want not found", err)
+	}
+}
+
+// Concurrent updates from the same version race on the row; exactly one
+// wins and the rest are told to fetch it again
+func TestPostgresConcurrentNoteUpdates(t *testing.T) {
+	ctx := context.Background()
+	user := createTestPostgresUser(t, "ada")
+	notes := NewPostgresNoteRepository(testDB)
+
+	note := &models.Note{UserID: user.ID, Title: "Cells", Content: "Original"}
+	if err := notes.CreateNote(ctx, note); err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+
+	const writers = 8
+	errs := make([]error, writers)
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Go(func() {
+			errs[i] = notes.UpdateNote(ctx, user.ID, note.ID, note.Version, map[string]any{"content": fmt.Sprintf("Writer %d", i)})
+		})
+	}
+	wg.Wait()
+
+	won := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			won++
+		case !errors.Is(err, models.ErrConflict):
+			t.Errorf("writer %d: %v, want success or a conflict", i, err)
+		}
+	}
+	if won != 1 {
+		t.Errorf("%d writers won, want 1", won)
+	}
+	if got, err := notes.GetNoteByID(ctx, user.ID, note.ID); err != nil || got.Version != note.Version+1 {
+		t.Errorf("note after the race = %+v, %v, want version %d", got, err, note.Version+1)
+	}
+}
+
+func TestPostgresTagsFilterNotes(t *testing.T) {
+	ctx := context.Background()
+	user := createTestPostgresUser(t, "ada")
+	notes := NewPostgresNoteRepository(testDB)
+	tags := NewPostgresTagRepository(testDB)
+
+	tagged := &models.Note{UserID: user.ID, Content: "Cells are the unit of life"}
+	untagged := &models.Note{UserID: user.ID, Content: "Mitochondria make ATP"}
+	for _, note := range []*models.Note{tagged, untagged} {
+		if err := notes.CreateNote(ctx, note); err != nil {
+			t.Fatalf("CreateNote: %v", err)
+		}
+	}
+	if err := tags.AddTagsToNote(ctx, user.ID, tagged.ID, []string{"bio", "cells"}); err != nil {
+		t.Fatalf("AddTagsToNote: %v", err)
+	}
+
+	listed, total, err := notes.ListNotes(ctx, user.ID, models.NoteFilter{Tag: "bio"}, models.ListParams{Limit: 10, Sort: "createdAt", Order: "desc"})
+	if err != nil {
+		t.Fatalf("ListNotes: %v", err)
+	}
+	if total != 1 || len(listed) != 1 || listed[0].ID != tagged.ID || !slices.Equal(listed[0].Tags, []string{"bio", "cells"}) {
+		t.Errorf("notes tagged bio = %+v (%d), want only the tagged note", listed, total)
+	}
+}
+
+func TestPostgresUnitOfWorkRollsBack(t *testing.T) {
+	ctx := context.Background()
+	user := createTestPostgresUser(t, "ada")
+	uow := NewPostgresUnitOfWork(testDB)
+	decks := NewPostgresDeckRepository(testDB)
+
+	failed := errors.New("failed")
+	err := uow.Do(ctx, func(ctx context.Context) error {
+		if err := decks.CreateDeck(ctx, &models.Deck{UserID: user.ID, Name: "Rolled back"}); err != nil {
+			return err
+		}
+		// Nested units of work join the outer one
+		return uow.Do(ctx, func(ctx context.Context) error { return failed })
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("Do = %v, want %v", err, failed)
+	}
+	if all, _ := decks.GetAllDecks(ctx, user.ID); len(all) != 0 {
+		t.Fatalf("decks after rollback = %v, want none", all)
+	}
+
+	err = uow.Do(ctx, func(ctx context.Context) error {
+		return decks.CreateDeck(ctx, &models.Deck{UserID: user.ID, Name: "Kept"})
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if all, _ := decks.GetAllDecks(ctx, user.ID); len(all) != 1 || all[0].Name != "Kept" {
+		t.Fatalf("decks after commit = %v, want Kept", all)
+	}
+}
+
+// Workers claiming at once each get a different job, and none gets a job
+// another holds the lease on
+func TestPostgresConcurrentJobClaims(t *testing.T) {
+	ctx := context.Background()
+	user := createTestPostgresUser(t, "ada")
+	jobs := NewPostgresJobRepository(testDB)
+
+	const queued = 4
+	for range queued {
+		if err := jobs.CreateJob(ctx, &models.Job{UserID: user.ID, Kind: models.JobKindSummarizeNotes, Payload: []byte(`{}`)}); err != nil {
+			t.Fatalf("CreateJob: %v", err)
+		}
+	}
+
+	const workers = 8
+	claimed := make([]*models.Job, workers)
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Go(func() { claimed[i], errs[i] = jobs.ClaimNextJob(ctx, time.Minute) })
+	}
+	wg.Wait()
+
+	seen := map[int]bool{}
+	for i, job := range claimed {
+		if errs[i] != nil {
+			t.Fatalf("ClaimNextJob: %v", errs[i])
+		}
+		if job == nil {
+			continue
+		}
+		if seen[job.ID] {
+			t.Errorf("job %d was claimed twice", job.ID)
+		}
+		seen[job.ID] = true
+	}
+	// Workers that found every job locked come back empty; what they
+	// skipped is still there for the next claim
+	for {
+		job, err := jobs.ClaimNextJob(ctx, time.Minute)
+		if err != nil {
+			t.Fatalf("ClaimNextJob: %v", err)
+		}
+		if job == nil {
+			break
+		}
+		if seen[job.ID] {
+			t.Fatalf("job %d was claimed again during its lease", job.ID)
+		}
+		seen[job.ID] = true
+	}
+	if len(seen) != queued {
+		t.Errorf("%d jobs claimed, want all %d", len(seen), queued)
+	}
+
+	for id := range seen {
+		if err := jobs.FailJobAttempt(ctx, id, "boom", nil); err != nil {
+			t.Fatalf("FailJobAttempt: %v", err)
+		}
+		failed, err := jobs.GetJob(ctx, user.ID, id)
+		if err != nil {
+			t.Fatalf("GetJob: %v", err)
+		}
+		if failed.Status != models.JobStatusFailed || failed.Attempts != 1 || failed.Error != "boom" {
+			t.Errorf("job after its last attempt = %+v, want failed once with boom", failed)
+		}
+	}
+}
+
+func TestPostgresWebhookDeliveries(t *testing.T) {
+	ctx := context.Background()
+	user := createTestPostgresUser(t, "ada")
+	webhooks := NewPostgresWebhookRepository(testDB)
+
+	for _, events := range [][]string{{models.WebhookEventNoteCreated}, {models.WebhookEventQuizCompleted}} {
+		webhook := &models.Webhook{UserID: user.ID, Kind: "webhook", URL: "https://example.com/hook", Secret: "s", Events: events, Active: true}
+		if err := webhooks.CreateWebhook(ctx, webhook); err != nil {
+			t.Fatalf("CreateWebhook: %v", err)
+		}
+	}
+
+	queued, err := webhooks.CreateDeliveries(ctx, user.ID, "webhook", models.WebhookEventNoteCreated, []byte(`{"id":1}`))
+	if err != nil || queued != 1 {
+		t.Fatalf("CreateDeliveries = %d, %v, want 1 for the subscribed webhook", queued, err)
+	}
+
+	claimed, err := webhooks.ClaimDueDeliveries(ctx, 10, time.Minute)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("ClaimDueDeliveries = %+v, %v, want one", claimed, err)
+	}
+	if delivery := claimed[0]; delivery.URL != "https://example.com/hook" || delivery.UserID != user.ID || string(delivery.Payload) != `{"id":1}` {
+		t.Errorf("claimed delivery = %+v, want its webhook's URL, owner and payload", delivery)
+	}
+	if again, err := webhooks.ClaimDueDeliveries(ctx, 10, time.Minute); err != nil || len(again) != 0 {
+		t.Errorf("ClaimDueDeliveries during the lease = %+v, %v, want none", again, err)
+	}
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+type PromptTemplateRepository interface {
+	GetLatestPromptTemplates(ctx context.Context) ([]*models.PromptTemplate, error)
+	ListPromptTemplateVersions(ctx context.Context, name string) ([]*models.PromptTemplate, error)
+	CreatePromptTemplateVersion(ctx context.Context, name, template string) (*models.PromptTemplate, error)
+}
+
+type PostgresPromptTemplateRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresPromptTemplateRepository(db *sql.DB) *PostgresPromptTemplateRepository {
+	return &PostgresPromptTemplateRepository{db: db}
+}
+
+// GetLatestPromptTemplates returns the highest version of each template
+func (r *PostgresPromptTemplateRepository) GetLatestPromptTemplates(ctx context.Context) ([]*models.PromptTemplate, error) {
+	query := `
+		SELECT DISTINCT ON (name) name, version, template, createdAt 
+		FROM gocourse.prompt_templates 
+		ORDER BY name, version DESC`
+
+	return r.queryPromptTemplates(ctx, query)
+}
+
+// ListPromptTemplateVersions returns every version of a template, newest
+// first
+func (r *PostgresPromptTemplateRepository) ListPromptTemplateVersions(ctx context.Context, name string) ([]*models.PromptTemplate, error) {
+	query := `
+		SELECT name, version, template, createdAt 
+		FROM gocourse.prompt_templates 
+		WHERE name = $1 
+		ORDER BY version DESC`
+
+	return r.queryPromptTemplates(ctx, query, name)
+}
+
+// CreatePromptTemplateVersion stores template as the next version of name
+func (r *PostgresPromptTemplateRepository) CreatePromptTemplateVersion(ctx context.Context, name, template string) (*models.PromptTemplate, error) {
+	query := `
+		INSERT INTO gocourse.prompt_templates (name, version, template) 
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2 
+		FROM gocourse.prompt_templates 
+		WHERE name = $1 
+		RETURNING name, version, template, createdAt`
+
+	prompt, err := scanPromptTemplate(r.db.QueryRowContext(ctx, query, name, template))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, models.Conflict("prompt template %s was changed at the same time; try again", name)
+		}
+		return nil, models.Internal("failed to create prompt template version: %w", err)
+	}
+
+	return prompt, nil
+}
+
+func (r *PostgresPromptTemplateRepository) queryPromptTemplates(ctx context.Context, query string, args ...any) ([]*models.PromptTemplate, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Internal("failed to query prompt templates: %w", err)
+	}
+	defer rows.Close()
+
+	prompts := make([]*models.PromptTemplate, 0)
+	for rows.Next() {
+		prompt, err := scanPromptTemplate(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan prompt template: %w", err)
+		}
+		prompts = append(prompts, prompt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over prompt templates: %w", err)
+	}
+
+	return prompts, nil
+}
+
+func scanPromptTemplate(row interface{ Scan(...any) error }) (*models.PromptTemplate, error) {
+	prompt := &models.PromptTemplate{Source: models.PromptSourceDatabase}
+	if err := row.Scan(&prompt.Name, &prompt.Version, &prompt.Template, &prompt.CreatedAt); err != nil {
+		return nil, err
+	}
+	return prompt, nil
+}
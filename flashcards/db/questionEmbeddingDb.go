@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+type QuestionEmbeddingRepository interface {
+	GetRecentQuestionEmbeddings(ctx context.Context, userID int, noteIDs []int, model string, limit int) ([]*models.QuestionEmbedding, error)
+	SaveQuestionEmbedding(ctx context.Context, embedding *models.QuestionEmbedding) error
+	DeleteQuestionEmbeddingsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type PostgresQuestionEmbeddingRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresQuestionEmbeddingRepository(db *sql.DB) *PostgresQuestionEmbeddingRepository {
+	return &PostgresQuestionEmbeddingRepository{db: db}
+}
+
+// GetRecentQuestionEmbeddings returns the user's newest question embeddings
+// from the given model that share a note with noteIDs, or any note when
+// noteIDs is empty
+func (r *PostgresQuestionEmbeddingRepository) GetRecentQuestionEmbeddings(ctx context.Context, userID int, noteIDs []int, model string, limit int) ([]*models.QuestionEmbedding, error) {
+	query := `
+		SELECT user_id, noteIds, questionText, model, embedding, createdAt 
+		FROM gocourse.question_embeddings 
+		WHERE user_id = $1 AND model = $2 
+			AND (cardinality($3::INTEGER[]) = 0 OR noteIds && $3::INTEGER[]) 
+		ORDER BY createdAt DESC 
+		LIMIT $4`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, model, pq.Array(noteIDs), limit)
+	if err != nil {
+		return nil, models.Internal("failed to query question embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	embeddings := make([]*models.QuestionEmbedding, 0)
+	for rows.Next() {
+		embedding := &models.QuestionEmbedding{}
+		var noteIDs pq.Int64Array
+		err := rows.Scan(&embedding.UserID, &noteIDs, &embedding.QuestionText, &embedding.Model, pq.Array(&embedding.Vector), &embedding.CreatedAt)
+		if err != nil {
+			return nil, models.Internal("failed to scan question embedding: %w", err)
+		}
+		embedding.NoteIDs = make([]int, len(noteIDs))
+		for i, id := range noteIDs {
+			embedding.NoteIDs[i] = int(id)
+		}
+		embeddings = append(embeddings, embedding)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over question embeddings: %w", err)
+	}
+
+	return embeddings, nil
+}
+
+func (r *PostgresQuestionEmbeddingRepository) SaveQuestionEmbedding(ctx context.Context, embedding *models.QuestionEmbedding) error {
+	query := `
+		INSERT INTO gocourse.question_embeddings (user_id, noteIds, questionText, model, embedding) 
+		VALUES ($1, $2, $3, $4, $5) 
+		RETURNING createdAt`
+
+	row := r.db.QueryRowContext(ctx, query, embedding.UserID, pq.Array(embedding.NoteIDs), embedding.QuestionText,
+		embedding.Model, pq.Array(embedding.Vector))
+	if err := row.Scan(&embedding.CreatedAt); err != nil {
+		return models.Internal("failed to save question embedding: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresQuestionEmbeddingRepository) DeleteQuestionEmbeddingsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM gocourse.question_embeddings WHERE createdAt < $1", before)
+	if err != nil {
+		return 0, models.Internal("failed to delete question embeddings: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, models.Internal("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
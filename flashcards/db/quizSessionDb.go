@@ -0,0 +1,490 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+type QuizSessionRepository interface {
+	CreateSession(ctx context.Context, session *models.QuizSession) error
+	GetSessionByID(ctx context.Context, userID, id int) (*models.QuizSession, error)
+	GetSessionsUpdatedSince(ctx context.Context, userID int, since time.Time) ([]*models.QuizSession, error)
+	UpdateSession(ctx context.Context, userID, id int, updates map[string]any) error
+	UpdateSessionQuestion(ctx context.Context, userID, sessionID, position int, updates map[string]any) error
+	GetBankQuestions(ctx context.Context, userID int, noteIDs []int, difficulty, questionType string, limit int) ([]models.QuestionData, error)
+	GetRecentGradedAnswers(ctx context.Context, userID int, noteIDs []int, limit int) ([]models.GradedAnswer, error)
+	CreateQueuedSession(ctx context.Context, queued *models.QueuedQuizSession) error
+	GetQueuedSession(ctx context.Context, userID, id int) (*models.QueuedQuizSession, error)
+	ClaimDueQueuedSessions(ctx context.Context, limit int, lease time.Duration) ([]*models.QueuedQuizSession, error)
+	CompleteQueuedSession(ctx context.Context, id, sessionID int) error
+	FailQueuedSessionAttempt(ctx context.Context, id int, message string, nextAttemptAt *time.Time) error
+}
+
+type PostgresQuizSessionRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresQuizSessionRepository(db *sql.DB) *PostgresQuizSessionRepository {
+	return &PostgresQuizSessionRepository{db: db}
+}
+
+func (r *PostgresQuizSessionRepository) CreateSession(ctx context.Context, session *models.QuizSession) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO gocourse.quiz_sessions (user_id, status, currentPosition) 
+		VALUES ($1, $2, $3) 
+		RETURNING id, createdAt, updatedAt`
+
+	row := tx.QueryRowContext(ctx, query, session.UserID, session.Status, session.CurrentPosition)
+	if err := row.Scan(&session.ID, &session.CreatedAt, &session.UpdatedAt); err != nil {
+		return models.Internal("failed to create quiz session: %w", err)
+	}
+
+	questionQuery := `
+		INSERT INTO gocourse.quiz_session_questions (session_id, position, question, status) 
+		VALUES ($1, $2, $3, $4) 
+		RETURNING updatedAt`
+
+	for i := range session.Questions {
+		question := &session.Questions[i]
+		questionJSON, err := json.Marshal(question.Question)
+		if err != nil {
+			return models.Internal("failed to encode question: %w", err)
+		}
+
+		row := tx.QueryRowContext(ctx, questionQuery, session.ID, question.Position, questionJSON, question.Status)
+		if err := row.Scan(&question.UpdatedAt); err != nil {
+			return models.Internal("failed to create session question: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit quiz session: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresQuizSessionRepository) GetSessionByID(ctx context.Context, userID, id int) (*models.QuizSession, error) {
+	query := `
+		SELECT id, user_id, status, currentPosition, createdAt, updatedAt 
+		FROM gocourse.quiz_sessions 
+		WHERE id = $1 AND user_id = $2`
+
+	session := &models.QuizSession{}
+	row := r.db.QueryRowContext(ctx, query, id, userID)
+
+	err := row.Scan(&session.ID, &session.UserID, &session.Status, &session.CurrentPosition, &session.CreatedAt, &session.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("quiz session with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get quiz session: %w", err)
+	}
+
+	questionsQuery := `
+		SELECT position, question, status, answer, flagged, timeSpentMs, updatedAt, attachment_id, grading 
+		FROM gocourse.quiz_session_questions 
+		WHERE session_id = $1 
+		ORDER BY position ASC`
+
+	rows, err := r.db.QueryContext(ctx, questionsQuery, id)
+	if err != nil {
+		return nil, models.Internal("failed to query session questions: %w", err)
+	}
+	defer rows.Close()
+
+	session.Questions = make([]models.SessionQuestion, 0)
+	for rows.Next() {
+		var question models.SessionQuestion
+		var questionJSON, gradingJSON []byte
+		var attachmentID sql.NullInt64
+		err := rows.Scan(&question.Position, &questionJSON, &question.Status, &question.Answer, &question.Flagged, &question.TimeSpentMs, &question.UpdatedAt, &attachmentID, &gradingJSON)
+		if err != nil {
+			return nil, models.Internal("failed to scan session question: %w", err)
+		}
+		if err := json.Unmarshal(questionJSON, &question.Question); err != nil {
+			return nil, models.Internal("failed to decode session question: %w", err)
+		}
+		if attachmentID.Valid {
+			id := int(attachmentID.Int64)
+			question.AttachmentID = &id
+		}
+		if gradingJSON != nil {
+			question.Grading = &models.AnswerGrading{}
+			if err := json.Unmarshal(gradingJSON, question.Grading); err != nil {
+				return nil, models.Internal("failed to decode answer grading: %w", err)
+			}
+		}
+		session.Questions = append(session.Questions, question)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over session questions: %w", err)
+	}
+
+	return session, nil
+}
+
+// GetSessionsUpdatedSince returns sessions with activity after the given
+// time, including their questions
+func (r *PostgresQuizSessionRepository) GetSessionsUpdatedSince(ctx context.Context, userID int, since time.Time) ([]*models.QuizSession, error) {
+	query := `
+		SELECT id 
+		FROM gocourse.quiz_sessions 
+		WHERE user_id = $1 AND updatedAt >= $2 
+		ORDER BY updatedAt ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, since)
+	if err != nil {
+		return nil, models.Internal("failed to query quiz sessions: %w", err)
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, models.Internal("failed to scan quiz session: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over quiz sessions: %w", err)
+	}
+
+	sessions := make([]*models.QuizSession, 0, len(ids))
+	for _, id := range ids {
+		session, err := r.GetSessionByID(ctx, userID, id)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+func (r *PostgresQuizSessionRepository) UpdateSession(ctx context.Context, userID, id int, updates map[string]any) error {
+	if err := checkUpdates("quiz_sessions", updates); err != nil {
+		return err
+	}
+
+	query := "UPDATE gocourse.quiz_sessions SET "
+	args := []any{}
+	argIndex := 1
+
+	for field, value := range updates {
+		if argIndex > 1 {
+			query += ", "
+		}
+		query += fmt.Sprintf("%s = $%d", field, argIndex)
+		args = append(args, value)
+		argIndex++
+	}
+
+	query += fmt.Sprintf(", updatedAt = NOW() WHERE id = $%d AND user_id = $%d", argIndex, argIndex+1)
+	args = append(args, id, userID)
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return models.Internal("failed to update quiz session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("quiz session with id %d not found", id)
+	}
+
+	return nil
+}
+
+func (r *PostgresQuizSessionRepository) UpdateSessionQuestion(ctx context.Context, userID, sessionID, position int, updates map[string]any) error {
+	if err := checkUpdates("quiz_session_questions", updates); err != nil {
+		return err
+	}
+
+	query := "UPDATE gocourse.quiz_session_questions SET "
+	args := []any{}
+	argIndex := 1
+
+	for field, value := range updates {
+		if grading, ok := value.(*models.AnswerGrading); ok {
+			gradingJSON, err := json.Marshal(grading)
+			if err != nil {
+				return models.Internal("failed to encode answer grading: %w", err)
+			}
+			value = gradingJSON
+		}
+		if argIndex > 1 {
+			query += ", "
+		}
+		query += fmt.Sprintf("%s = $%d", field, argIndex)
+		args = append(args, value)
+		argIndex++
+	}
+
+	query += fmt.Sprintf(", updatedAt = NOW() WHERE session_id = $%d AND position = $%d", argIndex, argIndex+1)
+	query += fmt.Sprintf(" AND session_id IN (SELECT id FROM gocourse.quiz_sessions WHERE user_id = $%d)", argIndex+2)
+	args = append(args, sessionID, position, userID)
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return models.Internal("failed to update session question: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("question %d in quiz session with id %d not found", position, sessionID)
+	}
+
+	// Touch the session so updatedAt reflects the latest activity
+	if _, err := r.db.ExecContext(ctx, "UPDATE gocourse.quiz_sessions SET updatedAt = NOW() WHERE id = $1", sessionID); err != nil {
+		return models.Internal("failed to update quiz session: %w", err)
+	}
+
+	return nil
+}
+
+// GetBankQuestions returns up to limit distinct questions from the user's
+// earlier sessions, in random order. With noteIDs set only questions based
+// on one of those notes are returned; empty difficulty and questionType
+// match any.
+func (r *PostgresQuizSessionRepository) GetBankQuestions(ctx context.Context, userID int, noteIDs []int, difficulty, questionType string, limit int) ([]models.QuestionData, error) {
+	query := `
+		SELECT question FROM ( 
+			SELECT DISTINCT ON (q.question->>'text') q.question 
+			FROM gocourse.quiz_session_questions q 
+			JOIN gocourse.quiz_sessions s ON s.id = q.session_id 
+			WHERE s.user_id = $1 
+				AND ($2 = '' OR q.question->>'difficulty' = $2) 
+				AND ($3 = '' OR q.question->>'type' = $3) 
+				AND (cardinality($4::INTEGER[]) = 0 OR EXISTS ( 
+					SELECT 1 FROM jsonb_array_elements_text(q.question->'basedOnNotes') note 
+					WHERE note::INTEGER = ANY($4::INTEGER[]) 
+				)) 
+			ORDER BY q.question->>'text', q.updatedAt DESC 
+		) bank 
+		ORDER BY random() 
+		LIMIT $5`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, difficulty, questionType, pq.Array(noteIDs), limit)
+	if err != nil {
+		return nil, models.Internal("failed to query question bank: %w", err)
+	}
+	defer rows.Close()
+
+	questions := make([]models.QuestionData, 0)
+	for rows.Next() {
+		var questionJSON []byte
+		if err := rows.Scan(&questionJSON); err != nil {
+			return nil, models.Internal("failed to scan bank question: %w", err)
+		}
+		var question models.QuestionData
+		if err := json.Unmarshal(questionJSON, &question); err != nil {
+			return nil, models.Internal("failed to decode bank question: %w", err)
+		}
+		questions = append(questions, question)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over bank questions: %w", err)
+	}
+
+	return questions, nil
+}
+
+// GetRecentGradedAnswers returns the user's latest graded answers, most
+// recent first, to questions on the same topics as the given notes: based on
+// one of the notes or on a note sharing a tag with one. With no notes given
+// every answer counts.
+func (r *PostgresQuizSessionRepository) GetRecentGradedAnswers(ctx context.Context, userID int, noteIDs []int, limit int) ([]models.GradedAnswer, error) {
+	query := `
+		SELECT COALESCE(q.question->>'difficulty', ''), q.correct, q.updatedAt 
+		FROM gocourse.quiz_session_questions q 
+		JOIN gocourse.quiz_sessions s ON s.id = q.session_id 
+		WHERE s.user_id = $1 AND q.status = 'answered' AND q.correct IS NOT NULL 
+			AND (cardinality($2::INTEGER[]) = 0 OR EXISTS ( 
+				SELECT 1 FROM jsonb_array_elements_text(COALESCE(q.question->'basedOnNotes', '[]'::jsonb)) note 
+				WHERE note::INTEGER = ANY($2::INTEGER[]) 
+					OR note::INTEGER IN ( 
+						SELECT topic.note_id 
+						FROM gocourse.note_tags topic 
+						JOIN gocourse.note_tags requested ON requested.tag_id = topic.tag_id 
+						WHERE requested.note_id = ANY($2::INTEGER[]) 
+					) 
+			)) 
+		ORDER BY q.updatedAt DESC 
+		LIMIT $3`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, pq.Array(noteIDs), limit)
+	if err != nil {
+		return nil, models.Internal("failed to get recent graded answers: %w", err)
+	}
+	defer rows.Close()
+
+	answers := make([]models.GradedAnswer, 0)
+	for rows.Next() {
+		var answer models.GradedAnswer
+		if err := rows.Scan(&answer.Difficulty, &answer.Correct, &answer.AnsweredAt); err != nil {
+			return nil, models.Internal("failed to scan graded answer: %w", err)
+		}
+		answers = append(answers, answer)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over graded answers: %w", err)
+	}
+
+	return answers, nil
+}
+
+const queuedSessionColumns = "id, user_id, request, status, session_id, error, attempts, nextAttemptAt, createdAt, updatedAt"
+
+func (r *PostgresQuizSessionRepository) CreateQueuedSession(ctx context.Context, queued *models.QueuedQuizSession) error {
+	requestJSON, err := json.Marshal(queued.Request)
+	if err != nil {
+		return models.Internal("failed to encode quiz session request: %w", err)
+	}
+
+	query := `
+		INSERT INTO gocourse.queued_quiz_sessions (user_id, request, status, nextAttemptAt) 
+		VALUES ($1, $2, $3, $4) 
+		RETURNING ` + queuedSessionColumns
+
+	row := r.db.QueryRowContext(ctx, query, queued.UserID, requestJSON, queued.Status, queued.NextAttemptAt)
+	created, err := scanQueuedSession(row)
+	if err != nil {
+		return models.Internal("failed to queue quiz session: %w", err)
+	}
+
+	*queued = *created
+	return nil
+}
+
+func (r *PostgresQuizSessionRepository) GetQueuedSession(ctx context.Context, userID, id int) (*models.QueuedQuizSession, error) {
+	query := `
+		SELECT ` + queuedSessionColumns + ` 
+		FROM gocourse.queued_quiz_sessions 
+		WHERE id = $1 AND user_id = $2`
+
+	queued, err := scanQueuedSession(r.db.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("queued quiz session with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get queued quiz session: %w", err)
+	}
+
+	return queued, nil
+}
+
+// ClaimDueQueuedSessions returns queued requests whose next attempt is due
+// and pushes that attempt back by lease, so another server polling at the
+// same time skips them
+func (r *PostgresQuizSessionRepository) ClaimDueQueuedSessions(ctx context.Context, limit int, lease time.Duration) ([]*models.QueuedQuizSession, error) {
+	query := `
+		UPDATE gocourse.queued_quiz_sessions 
+		SET nextAttemptAt = NOW() + make_interval(secs => $1) 
+		WHERE id IN ( 
+			SELECT id FROM gocourse.queued_quiz_sessions 
+			WHERE status = $2 AND nextAttemptAt <= NOW() 
+			ORDER BY nextAttemptAt 
+			LIMIT $3 
+			FOR UPDATE SKIP LOCKED 
+		) 
+		RETURNING ` + queuedSessionColumns
+
+	rows, err := r.db.QueryContext(ctx, query, lease.Seconds(), models.QueuedSessionStatusQueued, limit)
+	if err != nil {
+		return nil, models.Internal("failed to claim queued quiz sessions: %w", err)
+	}
+	defer rows.Close()
+
+	queued := make([]*models.QueuedQuizSession, 0)
+	for rows.Next() {
+		item, err := scanQueuedSession(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan queued quiz session: %w", err)
+		}
+		queued = append(queued, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over queued quiz sessions: %w", err)
+	}
+
+	return queued, nil
+}
+
+func (r *PostgresQuizSessionRepository) CompleteQueuedSession(ctx context.Context, id, sessionID int) error {
+	query := `
+		UPDATE gocourse.queued_quiz_sessions 
+		SET status = $2, session_id = $3, error = '', nextAttemptAt = NULL, updatedAt = NOW() 
+		WHERE id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, id, models.QueuedSessionStatusCompleted, sessionID); err != nil {
+		return models.Internal("failed to complete queued quiz session: %w", err)
+	}
+
+	return nil
+}
+
+// FailQueuedSessionAttempt counts a failed attempt. Without a next attempt
+// the request has failed for good.
+func (r *PostgresQuizSessionRepository) FailQueuedSessionAttempt(ctx context.Context, id int, message string, nextAttemptAt *time.Time) error {
+	query := `
+		UPDATE gocourse.queued_quiz_sessions 
+		SET attempts = attempts + 1, error = $2, nextAttemptAt = $3, 
+			status = CASE WHEN $3::TIMESTAMP IS NULL THEN $4 ELSE $5 END, updatedAt = NOW() 
+		WHERE id = $1`
+
+	_, err := r.db.ExecContext(ctx, query, id, message, nextAttemptAt,
+		models.QueuedSessionStatusFailed, models.QueuedSessionStatusQueued)
+	if err != nil {
+		return models.Internal("failed to update queued quiz session: %w", err)
+	}
+
+	return nil
+}
+
+func scanQueuedSession(row interface{ Scan(...any) error }) (*models.QueuedQuizSession, error) {
+	queued := &models.QueuedQuizSession{}
+	var requestJSON []byte
+	var sessionID sql.NullInt64
+	err := row.Scan(&queued.ID, &queued.UserID, &requestJSON, &queued.Status, &sessionID, &queued.Error,
+		&queued.Attempts, &queued.NextAttemptAt, &queued.CreatedAt, &queued.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(requestJSON, &queued.Request); err != nil {
+		return nil, models.Internal("failed to decode quiz session request: %w", err)
+	}
+	if sessionID.Valid {
+		id := int(sessionID.Int64)
+		queued.SessionID = &id
+	}
+	return queued, nil
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"flashcards/models"
+
+	_ "github.com/lib/pq"
+)
+
+type ReportRecipientRepository interface {
+	CreateRecipient(ctx context.Context, recipient *models.ReportRecipient) error
+	GetAllRecipients(ctx context.Context, userID int) ([]*models.ReportRecipient, error)
+	GetRecipientsForAllUsers(ctx context.Context) ([]*models.ReportRecipient, error)
+	DeleteRecipient(ctx context.Context, userID, id int) error
+}
+
+type PostgresReportRecipientRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresReportRecipientRepository(db *sql.DB) *PostgresReportRecipientRepository {
+	return &PostgresReportRecipientRepository{db: db}
+}
+
+func (r *PostgresReportRecipientRepository) CreateRecipient(ctx context.Context, recipient *models.ReportRecipient) error {
+	query := `
+		INSERT INTO gocourse.report_recipients (user_id, email) 
+		VALUES ($1, $2) 
+		RETURNING id, createdAt`
+
+	row := r.db.QueryRowContext(ctx, query, recipient.UserID, recipient.Email)
+
+	err := row.Scan(&recipient.ID, &recipient.CreatedAt)
+	if err != nil {
+		return models.Internal("failed to create report recipient: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresReportRecipientRepository) GetAllRecipients(ctx context.Context, userID int) ([]*models.ReportRecipient, error) {
+	query := `
+		SELECT id, user_id, email, createdAt 
+		FROM gocourse.report_recipients 
+		WHERE user_id = $1 
+		ORDER BY email ASC`
+
+	return r.queryRecipients(ctx, query, userID)
+}
+
+// GetRecipientsForAllUsers is used by the scheduled report job, which sends
+// each user's report to that user's recipients
+func (r *PostgresReportRecipientRepository) GetRecipientsForAllUsers(ctx context.Context) ([]*models.ReportRecipient, error) {
+	query := `
+		SELECT id, user_id, email, createdAt 
+		FROM gocourse.report_recipients 
+		WHERE user_id IS NOT NULL 
+		ORDER BY user_id ASC, email ASC`
+
+	return r.queryRecipients(ctx, query)
+}
+
+func (r *PostgresReportRecipientRepository) queryRecipients(ctx context.Context, query string, args ...any) ([]*models.ReportRecipient, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Internal("failed to query report recipients: %w", err)
+	}
+	defer rows.Close()
+
+	recipients := make([]*models.ReportRecipient, 0)
+	for rows.Next() {
+		recipient := &models.ReportRecipient{}
+		if err := rows.Scan(&recipient.ID, &recipient.UserID, &recipient.Email, &recipient.CreatedAt); err != nil {
+			return nil, models.Internal("failed to scan report recipient: %w", err)
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over report recipients: %w", err)
+	}
+
+	return recipients, nil
+}
+
+func (r *PostgresReportRecipientRepository) DeleteRecipient(ctx context.Context, userID, id int) error {
+	query := "DELETE FROM gocourse.report_recipients WHERE id = $1 AND user_id = $2"
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return models.Internal("failed to delete report recipient: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("report recipient with id %d not found", id)
+	}
+
+	return nil
+}
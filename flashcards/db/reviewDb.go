@@ -0,0 +1,467 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+type ReviewRepository interface {
+	GetDueCards(ctx context.Context, userID int, deckIDs []int, now time.Time, limit int) ([]*models.DueCard, int, int, error)
+	GetCramCards(ctx context.Context, userID int, deckIDs []int, limit int) ([]*models.DueCard, int, error)
+	GetCardsByIDs(ctx context.Context, userID int, flashcardIDs []int) ([]*models.DueCard, error)
+	CountNewCardsReviewed(ctx context.Context, userID int, deckIDs []int, since time.Time) (int, error)
+	GetSchedule(ctx context.Context, userID, flashcardID int) (*models.Schedule, error)
+	SaveReview(ctx context.Context, review *models.Review, schedule *models.Schedule) error
+	GetLatestReview(ctx context.Context, userID int) (*models.Review, error)
+	GetReviewByClientID(ctx context.Context, userID int, clientID string) (*models.Review, error)
+	UndoReview(ctx context.Context, review *models.Review) error
+	GetActiveUserIDs(ctx context.Context, since time.Time, limit int) ([]int, error)
+	GetLeechCards(ctx context.Context, userID int, deckIDs []int) ([]*models.DueCard, error)
+	SetSuspended(ctx context.Context, userID, flashcardID int, suspended bool) error
+	ResetSchedule(ctx context.Context, userID, flashcardID int) error
+}
+
+type PostgresReviewRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresReviewRepository(db *sql.DB) *PostgresReviewRepository {
+	return &PostgresReviewRepository{db: db}
+}
+
+// GetDueCards returns up to limit of the user's cards that are due at now,
+// along with the total number due and how many of those are new. Cards that
+// were never reviewed count as due from their creation. Cards already in
+// review or learning come first, each group earliest due first, so new cards
+// cannot crowd them out. Suspended cards are left out. A nil deckIDs
+// covers all decks.
+func (r *PostgresReviewRepository) GetDueCards(ctx context.Context, userID int, deckIDs []int, now time.Time, limit int) ([]*models.DueCard, int, int, error) {
+	where := dueCardsWhere
+	args := []any{userID, now}
+
+	if deckIDs != nil {
+		args = append(args, pq.Array(deckIDs))
+		where += fmt.Sprintf(" AND f.deck_id = ANY($%d)", len(args))
+	}
+
+	var total, newTotal int
+	countQuery := "SELECT COUNT(*), COUNT(*) FILTER (WHERE COALESCE(s.state, 'new') = 'new')" + dueCardsFrom + where
+	if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&total, &newTotal); err != nil {
+		return nil, 0, 0, models.Internal("failed to count due cards: %w", err)
+	}
+
+	args = append(args, limit)
+	query := dueCardsSelect + dueCardsFrom + where +
+		fmt.Sprintf(" ORDER BY COALESCE(s.state, 'new') = 'new' ASC, COALESCE(s.dueAt, f.createdAt) ASC, f.id ASC LIMIT $%d", len(args))
+
+	cards, err := r.queryCards(ctx, query, args...)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	return cards, total, newTotal, nil
+}
+
+// GetActiveUserIDs returns up to limit users who reviewed cards since the
+// given time, most recently active first
+func (r *PostgresReviewRepository) GetActiveUserIDs(ctx context.Context, since time.Time, limit int) ([]int, error) {
+	query := `
+		SELECT user_id 
+		FROM gocourse.reviews 
+		WHERE reviewedAt >= $1 
+		GROUP BY user_id 
+		ORDER BY MAX(reviewedAt) DESC 
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, since, limit)
+	if err != nil {
+		return nil, models.Internal("failed to query active users: %w", err)
+	}
+	defer rows.Close()
+
+	userIDs := make([]int, 0)
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, models.Internal("failed to scan active user: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over active users: %w", err)
+	}
+
+	return userIDs, nil
+}
+
+// Warm prepares the due queue queries
+func (r *PostgresReviewRepository) Warm(ctx context.Context) error {
+	return warmPool(ctx, r.db,
+		"SELECT COUNT(*), COUNT(*) FILTER (WHERE COALESCE(s.state, 'new') = 'new')"+dueCardsFrom+dueCardsWhere,
+		dueCardsSelect+dueCardsFrom+dueCardsWhere+" ORDER BY COALESCE(s.state, 'new') = 'new' ASC, COALESCE(s.dueAt, f.createdAt) ASC, f.id ASC LIMIT $3")
+}
+
+// GetCramCards returns up to limit of the user's cards regardless of when
+// they are due, earliest due first, along with the total number of cards.
+// Suspended cards are left out. A nil deckIDs covers all decks.
+func (r *PostgresReviewRepository) GetCramCards(ctx context.Context, userID int, deckIDs []int, limit int) ([]*models.DueCard, int, error) {
+	where := " WHERE f.user_id = $1 AND NOT COALESCE(s.suspended, FALSE)"
+	args := []any{userID}
+
+	if deckIDs != nil {
+		args = append(args, pq.Array(deckIDs))
+		where += fmt.Sprintf(" AND f.deck_id = ANY($%d)", len(args))
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*)"+dueCardsFrom+where, args...).Scan(&total); err != nil {
+		return nil, 0, models.Internal("failed to count cram cards: %w", err)
+	}
+
+	args = append(args, limit)
+	query := dueCardsSelect + dueCardsFrom + where +
+		fmt.Sprintf(" ORDER BY COALESCE(s.dueAt, f.createdAt) ASC, f.id ASC LIMIT $%d", len(args))
+
+	cards, err := r.queryCards(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return cards, total, nil
+}
+
+// GetCardsByIDs returns the user's cards among flashcardIDs with their
+// schedules, earliest due first. Cards that no longer exist are skipped.
+func (r *PostgresReviewRepository) GetCardsByIDs(ctx context.Context, userID int, flashcardIDs []int) ([]*models.DueCard, error) {
+	query := dueCardsSelect + dueCardsFrom +
+		" WHERE f.user_id = $1 AND f.id = ANY($2) ORDER BY COALESCE(s.dueAt, f.createdAt) ASC, f.id ASC"
+
+	return r.queryCards(ctx, query, userID, pq.Array(flashcardIDs))
+}
+
+const dueCardsSelect = `
+		SELECT ` + flashcardColumns + `, 
+			COALESCE(s.state, 'new'), COALESCE(s.dueAt, f.createdAt), COALESCE(s.intervalDays, 0), 
+			COALESCE(s.easeFactor, 2.5), COALESCE(s.repetitions, 0), COALESCE(s.lapses, 0), COALESCE(s.step, 0), 
+			COALESCE(s.leech, FALSE), COALESCE(s.suspended, FALSE), s.lastReviewedAt, COALESCE(s.updatedAt, f.createdAt)`
+
+const dueCardsFrom = `
+		FROM gocourse.flashcards f 
+		LEFT JOIN gocourse.flashcard_schedules s ON s.flashcard_id = f.id`
+
+const dueCardsWhere = " WHERE f.user_id = $1 AND COALESCE(s.dueAt, f.createdAt) <= $2 AND NOT COALESCE(s.suspended, FALSE)"
+
+func (r *PostgresReviewRepository) queryCards(ctx context.Context, query string, args ...any) ([]*models.DueCard, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Internal("failed to query due cards: %w", err)
+	}
+	defer rows.Close()
+
+	cards := make([]*models.DueCard, 0)
+	for rows.Next() {
+		flashcard := &models.Flashcard{}
+		schedule := &models.Schedule{}
+		err := rows.Scan(append(flashcardScanArgs(flashcard),
+			&schedule.State, &schedule.DueAt, &schedule.IntervalDays, &schedule.EaseFactor, &schedule.Repetitions, &schedule.Lapses,
+			&schedule.Step, &schedule.Leech, &schedule.Suspended, &schedule.LastReviewedAt, &schedule.UpdatedAt)...)
+		if err != nil {
+			return nil, models.Internal("failed to scan due card: %w", err)
+		}
+		schedule.FlashcardID = flashcard.ID
+		cards = append(cards, &models.DueCard{Flashcard: flashcard, Schedule: schedule})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over due cards: %w", err)
+	}
+
+	return cards, nil
+}
+
+// CountNewCardsReviewed counts the user's cards reviewed for the first time
+// since the given time. A nil deckIDs covers all decks.
+func (r *PostgresReviewRepository) CountNewCardsReviewed(ctx context.Context, userID int, deckIDs []int, since time.Time) (int, error) {
+	query := `
+		SELECT COUNT(DISTINCT rv.flashcard_id) 
+		FROM gocourse.reviews rv 
+		JOIN gocourse.flashcards f ON f.id = rv.flashcard_id 
+		WHERE rv.user_id = $1 AND rv.mode = 'scheduled' AND rv.previousState = 'new' AND rv.reviewedAt >= $2`
+	args := []any{userID, since}
+
+	if deckIDs != nil {
+		args = append(args, pq.Array(deckIDs))
+		query += fmt.Sprintf(" AND f.deck_id = ANY($%d)", len(args))
+	}
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, models.Internal("failed to count new cards reviewed: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetSchedule returns the scheduling state of one of the user's flashcards
+func (r *PostgresReviewRepository) GetSchedule(ctx context.Context, userID, flashcardID int) (*models.Schedule, error) {
+	query := `
+		SELECT s.flashcard_id, s.state, s.dueAt, s.intervalDays, s.easeFactor, s.repetitions, s.lapses, s.step, 
+			s.leech, s.suspended, s.lastReviewedAt, s.updatedAt 
+		FROM gocourse.flashcard_schedules s 
+		JOIN gocourse.flashcards f ON f.id = s.flashcard_id 
+		WHERE s.flashcard_id = $1 AND f.user_id = $2`
+
+	schedule := &models.Schedule{}
+	row := r.db.QueryRowContext(ctx, query, flashcardID, userID)
+
+	err := row.Scan(&schedule.FlashcardID, &schedule.State, &schedule.DueAt, &schedule.IntervalDays, &schedule.EaseFactor,
+		&schedule.Repetitions, &schedule.Lapses, &schedule.Step, &schedule.Leech, &schedule.Suspended, &schedule.LastReviewedAt,
+		&schedule.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("schedule for flashcard with id %d not found", flashcardID)
+		}
+		return nil, models.Internal("failed to get schedule: %w", err)
+	}
+
+	return schedule, nil
+}
+
+// SaveReview records a review and stores the card's new schedule in a
+// single transaction. A nil schedule leaves the card's schedule untouched.
+func (r *PostgresReviewRepository) SaveReview(ctx context.Context, review *models.Review, schedule *models.Schedule) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if schedule != nil {
+		if err := saveSchedule(ctx, tx, schedule); err != nil {
+			return err
+		}
+	}
+
+	var previousJSON []byte
+	if review.PreviousSchedule != nil {
+		if previousJSON, err = json.Marshal(review.PreviousSchedule); err != nil {
+			return models.Internal("failed to encode previous schedule: %w", err)
+		}
+	}
+
+	reviewQuery := `
+		INSERT INTO gocourse.reviews 
+			(user_id, flashcard_id, mode, rating, previousState, previousSchedule, intervalDays, easeFactor, dueAt, reviewedAt, clientId) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) 
+		RETURNING id`
+
+	row := tx.QueryRowContext(ctx, reviewQuery, review.UserID, review.FlashcardID, review.Mode, review.Rating, review.PreviousState,
+		previousJSON, review.IntervalDays, review.EaseFactor, review.DueAt, review.ReviewedAt, review.ClientID)
+	if err := row.Scan(&review.ID); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" && review.ClientID != nil {
+			return models.Conflict("review with client id %s already exists", *review.ClientID)
+		}
+		return models.Internal("failed to save review: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit review: %w", err)
+	}
+
+	return nil
+}
+
+// GetLatestReview returns the user's most recent review
+func (r *PostgresReviewRepository) GetLatestReview(ctx context.Context, userID int) (*models.Review, error) {
+	query := reviewSelect + `
+		WHERE user_id = $1 
+		ORDER BY reviewedAt DESC, id DESC 
+		LIMIT 1`
+
+	review, err := scanReview(r.db.QueryRowContext(ctx, query, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("review to undo not found")
+		}
+		return nil, models.Internal("failed to get latest review: %w", err)
+	}
+
+	return review, nil
+}
+
+// GetReviewByClientID returns the review a client synced under clientID
+func (r *PostgresReviewRepository) GetReviewByClientID(ctx context.Context, userID int, clientID string) (*models.Review, error) {
+	query := reviewSelect + `
+		WHERE user_id = $1 AND clientId = $2`
+
+	review, err := scanReview(r.db.QueryRowContext(ctx, query, userID, clientID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("review with client id %s not found", clientID)
+		}
+		return nil, models.Internal("failed to get review: %w", err)
+	}
+
+	return review, nil
+}
+
+const reviewSelect = `
+		SELECT id, user_id, flashcard_id, mode, rating, previousState, previousSchedule, intervalDays, easeFactor, dueAt, reviewedAt, clientId 
+		FROM gocourse.reviews`
+
+func scanReview(row *sql.Row) (*models.Review, error) {
+	review := &models.Review{}
+	var previousJSON []byte
+
+	err := row.Scan(&review.ID, &review.UserID, &review.FlashcardID, &review.Mode, &review.Rating, &review.PreviousState,
+		&previousJSON, &review.IntervalDays, &review.EaseFactor, &review.DueAt, &review.ReviewedAt, &review.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if previousJSON != nil {
+		review.PreviousSchedule = &models.Schedule{}
+		if err := json.Unmarshal(previousJSON, review.PreviousSchedule); err != nil {
+			return nil, models.Internal("failed to decode previous schedule: %w", err)
+		}
+	}
+
+	return review, nil
+}
+
+// UndoReview deletes a review and, for scheduled reviews, puts the card's
+// schedule back as it was before. A card that had never been scheduled
+// loses its schedule and is new again.
+func (r *PostgresReviewRepository) UndoReview(ctx context.Context, review *models.Review) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := "DELETE FROM gocourse.reviews WHERE id = $1 AND user_id = $2"
+
+	result, err := tx.ExecContext(ctx, query, review.ID, review.UserID)
+	if err != nil {
+		return models.Internal("failed to delete review: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("review with id %d not found", review.ID)
+	}
+
+	if review.Mode == models.ReviewModeScheduled {
+		if review.PreviousSchedule != nil {
+			if err := saveSchedule(ctx, tx, review.PreviousSchedule); err != nil {
+				return err
+			}
+		} else {
+			query := "DELETE FROM gocourse.flashcard_schedules WHERE flashcard_id = $1"
+			if _, err := tx.ExecContext(ctx, query, review.FlashcardID); err != nil {
+				return models.Internal("failed to delete schedule: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit review undo: %w", err)
+	}
+
+	return nil
+}
+
+// Insert or replace a card's schedule
+func saveSchedule(ctx context.Context, tx *sql.Tx, schedule *models.Schedule) error {
+	query := `
+		INSERT INTO gocourse.flashcard_schedules 
+			(flashcard_id, state, dueAt, intervalDays, easeFactor, repetitions, lapses, step, leech, suspended, lastReviewedAt) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) 
+		ON CONFLICT (flashcard_id) DO UPDATE SET 
+			state = EXCLUDED.state, 
+			dueAt = EXCLUDED.dueAt, 
+			intervalDays = EXCLUDED.intervalDays, 
+			easeFactor = EXCLUDED.easeFactor, 
+			repetitions = EXCLUDED.repetitions, 
+			lapses = EXCLUDED.lapses, 
+			step = EXCLUDED.step, 
+			leech = EXCLUDED.leech, 
+			suspended = EXCLUDED.suspended, 
+			lastReviewedAt = EXCLUDED.lastReviewedAt, 
+			updatedAt = NOW() 
+		RETURNING updatedAt`
+
+	row := tx.QueryRowContext(ctx, query, schedule.FlashcardID, schedule.State, schedule.DueAt, schedule.IntervalDays,
+		schedule.EaseFactor, schedule.Repetitions, schedule.Lapses, schedule.Step, schedule.Leech, schedule.Suspended, schedule.LastReviewedAt)
+	if err := row.Scan(&schedule.UpdatedAt); err != nil {
+		return models.Internal("failed to save schedule: %w", err)
+	}
+
+	return nil
+}
+
+// GetLeechCards returns the user's leeches with their schedules, most
+// lapses first. A nil deckIDs covers all decks.
+func (r *PostgresReviewRepository) GetLeechCards(ctx context.Context, userID int, deckIDs []int) ([]*models.DueCard, error) {
+	where := " WHERE f.user_id = $1 AND s.leech"
+	args := []any{userID}
+
+	if deckIDs != nil {
+		args = append(args, pq.Array(deckIDs))
+		where += fmt.Sprintf(" AND f.deck_id = ANY($%d)", len(args))
+	}
+
+	return r.queryCards(ctx, dueCardsSelect+dueCardsFrom+where+" ORDER BY s.lapses DESC, f.id ASC", args...)
+}
+
+// SetSuspended suspends or unsuspends one of the user's scheduled cards
+func (r *PostgresReviewRepository) SetSuspended(ctx context.Context, userID, flashcardID int, suspended bool) error {
+	query := `
+		UPDATE gocourse.flashcard_schedules s 
+		SET suspended = $3, updatedAt = NOW() 
+		FROM gocourse.flashcards f 
+		WHERE s.flashcard_id = f.id AND f.id = $1 AND f.user_id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, flashcardID, userID, suspended)
+	if err != nil {
+		return models.Internal("failed to update schedule: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("schedule for flashcard with id %d not found", flashcardID)
+	}
+
+	return nil
+}
+
+// ResetSchedule drops the scheduling state of one of the user's cards,
+// which makes it new again and clears its leech mark
+func (r *PostgresReviewRepository) ResetSchedule(ctx context.Context, userID, flashcardID int) error {
+	query := `
+		DELETE FROM gocourse.flashcard_schedules s 
+		USING gocourse.flashcards f 
+		WHERE s.flashcard_id = f.id AND f.id = $1 AND f.user_id = $2`
+
+	if _, err := r.db.ExecContext(ctx, query, flashcardID, userID); err != nil {
+		return models.Internal("failed to reset schedule: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"flashcards/models"
+
+	_ "github.com/lib/pq"
+)
+
+type RoutingRuleRepository interface {
+	CreateRule(ctx context.Context, rule *models.RoutingRule) error
+	GetAllRules(ctx context.Context) ([]*models.RoutingRule, error)
+	DeleteRule(ctx context.Context, id int) error
+}
+
+type PostgresRoutingRuleRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRoutingRuleRepository(db *sql.DB) *PostgresRoutingRuleRepository {
+	return &PostgresRoutingRuleRepository{db: db}
+}
+
+func (r *PostgresRoutingRuleRepository) CreateRule(ctx context.Context, rule *models.RoutingRule) error {
+	query := `
+		INSERT INTO gocourse.model_routing_rules (questionType, difficulty, organizationId, plan, provider, model, priority) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7) 
+		RETURNING id, createdAt, updatedAt`
+
+	row := r.db.QueryRowContext(ctx, query, rule.QuestionType, rule.Difficulty, rule.OrganizationID, rule.Plan, rule.Provider, rule.Model, rule.Priority)
+
+	err := row.Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return models.Internal("failed to create routing rule: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresRoutingRuleRepository) GetAllRules(ctx context.Context) ([]*models.RoutingRule, error) {
+	query := `
+		SELECT id, questionType, difficulty, organizationId, plan, provider, model, priority, createdAt, updatedAt 
+		FROM gocourse.model_routing_rules 
+		ORDER BY priority ASC, id ASC`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, models.Internal("failed to query routing rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := make([]*models.RoutingRule, 0)
+	for rows.Next() {
+		rule := &models.RoutingRule{}
+		err := rows.Scan(&rule.ID, &rule.QuestionType, &rule.Difficulty, &rule.OrganizationID, &rule.Plan, &rule.Provider, &rule.Model, &rule.Priority, &rule.CreatedAt, &rule.UpdatedAt)
+		if err != nil {
+			return nil, models.Internal("failed to scan routing rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over routing rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+func (r *PostgresRoutingRuleRepository) DeleteRule(ctx context.Context, id int) error {
+	query := "DELETE FROM gocourse.model_routing_rules WHERE id = $1"
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return models.Internal("failed to delete routing rule: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("routing rule with id %d not found", id)
+	}
+
+	return nil
+}
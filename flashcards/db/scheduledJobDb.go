@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+type ScheduledJobRepository interface {
+	ClaimJobRun(ctx context.Context, name, instance string, since time.Duration) (bool, error)
+}
+
+type PostgresScheduledJobRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresScheduledJobRepository(db *sql.DB) *PostgresScheduledJobRepository {
+	return &PostgresScheduledJobRepository{db: db}
+}
+
+// ClaimJobRun claims the next run of a job for an instance. It succeeds when
+// the job has never run or was last claimed at least since ago; otherwise
+// another instance has the run and it returns false. The claim is a single
+// statement, so two instances can never both win it.
+func (r *PostgresScheduledJobRepository) ClaimJobRun(ctx context.Context, name, instance string, since time.Duration) (bool, error) {
+	query := `
+		INSERT INTO gocourse.scheduled_job_runs (name, claimedBy, claimedAt) 
+		VALUES ($1, $2, NOW()) 
+		ON CONFLICT (name) DO UPDATE 
+		SET claimedBy = EXCLUDED.claimedBy, claimedAt = NOW() 
+		WHERE gocourse.scheduled_job_runs.claimedAt <= NOW() - make_interval(secs => $3) 
+		RETURNING name`
+
+	var claimed string
+	err := r.db.QueryRowContext(ctx, query, name, instance, since.Seconds()).Scan(&claimed)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, models.Internal("failed to claim scheduled job run: %w", err)
+	}
+
+	return true, nil
+}
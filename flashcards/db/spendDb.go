@@ -0,0 +1,190 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+type SpendRepository interface {
+	GetSpendWindows(ctx context.Context, baselineStart, recentStart time.Time) ([]*models.SpendWindow, error)
+	CreateAnomaly(ctx context.Context, anomaly *models.SpendAnomaly) error
+	HasAnomalySince(ctx context.Context, scope string, subjectID int, since time.Time) (bool, error)
+	GetThrottledUntil(ctx context.Context, userID int) (*time.Time, error)
+	GetAnomalies(ctx context.Context, limit int) ([]*models.SpendAnomaly, error)
+	LiftThrottle(ctx context.Context, id int) (*models.SpendAnomaly, error)
+}
+
+type PostgresSpendRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresSpendRepository(db *sql.DB) *PostgresSpendRepository {
+	return &PostgresSpendRepository{db: db}
+}
+
+// GetSpendWindows totals hourly token usage per user and per organization,
+// split into the baseline period [baselineStart, recentStart) and the
+// recent window from recentStart. Subjects with no recent usage are left
+// out.
+func (r *PostgresSpendRepository) GetSpendWindows(ctx context.Context, baselineStart, recentStart time.Time) ([]*models.SpendWindow, error) {
+	query := `
+		WITH usage AS ( 
+			SELECT h.user_id, u.organization_id, h.hour, h.tokens 
+			FROM gocourse.llm_token_usage_hourly h 
+			JOIN gocourse.users u ON u.id = h.user_id 
+			WHERE h.hour >= $1 
+		) 
+		SELECT $3, user_id, 
+			COALESCE(SUM(tokens) FILTER (WHERE hour >= $2), 0), 
+			COALESCE(SUM(tokens) FILTER (WHERE hour < $2), 0) 
+		FROM usage 
+		GROUP BY user_id 
+		HAVING SUM(tokens) FILTER (WHERE hour >= $2) > 0 
+		UNION ALL 
+		SELECT $4, organization_id, 
+			COALESCE(SUM(tokens) FILTER (WHERE hour >= $2), 0), 
+			COALESCE(SUM(tokens) FILTER (WHERE hour < $2), 0) 
+		FROM usage 
+		WHERE organization_id IS NOT NULL 
+		GROUP BY organization_id 
+		HAVING SUM(tokens) FILTER (WHERE hour >= $2) > 0`
+
+	rows, err := r.db.QueryContext(ctx, query, baselineStart, recentStart, models.SpendScopeUser, models.SpendScopeOrganization)
+	if err != nil {
+		return nil, models.Internal("failed to get spend windows: %w", err)
+	}
+	defer rows.Close()
+
+	windows := []*models.SpendWindow{}
+	for rows.Next() {
+		window := &models.SpendWindow{}
+		if err := rows.Scan(&window.Scope, &window.SubjectID, &window.RecentTokens, &window.BaselineTokens); err != nil {
+			return nil, models.Internal("failed to scan spend window: %w", err)
+		}
+		windows = append(windows, window)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, models.Internal("failed to iterate spend windows: %w", err)
+	}
+
+	return windows, nil
+}
+
+func (r *PostgresSpendRepository) CreateAnomaly(ctx context.Context, anomaly *models.SpendAnomaly) error {
+	query := `
+		INSERT INTO gocourse.llm_spend_anomalies (scope, subjectId, recentTokens, baselineTokensPerHour, throttledUntil) 
+		VALUES ($1, $2, $3, $4, $5) 
+		RETURNING id, detectedAt`
+
+	row := r.db.QueryRowContext(ctx, query, anomaly.Scope, anomaly.SubjectID, anomaly.RecentTokens,
+		anomaly.BaselineTokensPerHour, anomaly.ThrottledUntil)
+	if err := row.Scan(&anomaly.ID, &anomaly.DetectedAt); err != nil {
+		return models.Internal("failed to create spend anomaly: %w", err)
+	}
+
+	return nil
+}
+
+// HasAnomalySince reports whether the subject already had an anomaly
+// detected since the given time
+func (r *PostgresSpendRepository) HasAnomalySince(ctx context.Context, scope string, subjectID int, since time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS ( 
+			SELECT 1 FROM gocourse.llm_spend_anomalies 
+			WHERE scope = $1 AND subjectId = $2 AND detectedAt >= $3 
+		)`
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, query, scope, subjectID, since).Scan(&exists); err != nil {
+		return false, models.Internal("failed to check spend anomalies: %w", err)
+	}
+
+	return exists, nil
+}
+
+// GetThrottledUntil returns when the latest active throttle on the user or
+// their organization ends, or nil when neither is throttled
+func (r *PostgresSpendRepository) GetThrottledUntil(ctx context.Context, userID int) (*time.Time, error) {
+	query := `
+		SELECT MAX(a.throttledUntil) 
+		FROM gocourse.llm_spend_anomalies a 
+		JOIN gocourse.users u ON u.id = $1 
+		WHERE a.throttledUntil > NOW() 
+			AND ((a.scope = $2 AND a.subjectId = u.id) OR (a.scope = $3 AND a.subjectId = u.organization_id))`
+
+	var throttledUntil sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, userID, models.SpendScopeUser, models.SpendScopeOrganization).Scan(&throttledUntil)
+	if err != nil {
+		return nil, models.Internal("failed to check spend throttle: %w", err)
+	}
+
+	if !throttledUntil.Valid {
+		return nil, nil
+	}
+	return &throttledUntil.Time, nil
+}
+
+// GetAnomalies returns the most recently detected anomalies
+func (r *PostgresSpendRepository) GetAnomalies(ctx context.Context, limit int) ([]*models.SpendAnomaly, error) {
+	query := spendAnomalySelect + `
+		ORDER BY detectedAt DESC, id DESC 
+		LIMIT $1`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, models.Internal("failed to get spend anomalies: %w", err)
+	}
+	defer rows.Close()
+
+	anomalies := []*models.SpendAnomaly{}
+	for rows.Next() {
+		anomaly, err := scanSpendAnomaly(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan spend anomaly: %w", err)
+		}
+		anomalies = append(anomalies, anomaly)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, models.Internal("failed to iterate spend anomalies: %w", err)
+	}
+
+	return anomalies, nil
+}
+
+// LiftThrottle ends the anomaly's throttle now
+func (r *PostgresSpendRepository) LiftThrottle(ctx context.Context, id int) (*models.SpendAnomaly, error) {
+	query := `
+		UPDATE gocourse.llm_spend_anomalies 
+		SET throttledUntil = NULL 
+		WHERE id = $1 
+		RETURNING id, scope, subjectId, recentTokens, baselineTokensPerHour, detectedAt, throttledUntil`
+
+	anomaly, err := scanSpendAnomaly(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("spend anomaly with id %d not found", id)
+		}
+		return nil, models.Internal("failed to lift spend throttle: %w", err)
+	}
+
+	return anomaly, nil
+}
+
+const spendAnomalySelect = `
+		SELECT id, scope, subjectId, recentTokens, baselineTokensPerHour, detectedAt, throttledUntil 
+		FROM gocourse.llm_spend_anomalies`
+
+func scanSpendAnomaly(row interface{ Scan(...any) error }) (*models.SpendAnomaly, error) {
+	anomaly := &models.SpendAnomaly{}
+	err := row.Scan(&anomaly.ID, &anomaly.Scope, &anomaly.SubjectID, &anomaly.RecentTokens,
+		&anomaly.BaselineTokensPerHour, &anomaly.DetectedAt, &anomaly.ThrottledUntil)
+	if err != nil {
+		return nil, err
+	}
+	return anomaly, nil
+}
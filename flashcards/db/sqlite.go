@@ -0,0 +1,239 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"flashcards/models"
+)
+
+// The database/sql driver name modernc.org/sqlite registers
+const SQLITE_DRIVER = "sqlite"
+
+// Times are written as UTC text in a layout that sorts in time order, so
+// the repositories can compare and order them as strings
+const sqliteDSNOptions = "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)" +
+	"&_time_format=sqlite&_timezone=UTC&_txlock=immediate"
+
+// Layouts SQLite times come in: what the driver writes, and what
+// CURRENT_TIMESTAMP and date() return
+var sqliteTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// openSQLite opens the SQLite database file at path, creating it and its
+// tables if needed, and registers the pool under name for PoolStats. The
+// pool holds a single connection: SQLite allows one writer at a time, and
+// queueing for the connection beats failing with SQLITE_BUSY. Transactions
+// take the write lock when they begin, so two of them never deadlock
+// upgrading a read.
+func openSQLite(name, path string) (*sql.DB, error) {
+	db, err := sql.Open(SQLITE_DRIVER, "file:"+path+sqliteDSNOptions)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, models.Internal("failed to create schema: %w", err)
+	}
+
+	poolsMu.Lock()
+	pools[name] = db
+	poolsMu.Unlock()
+	return db, nil
+}
+
+// SQLiteUnitOfWork begins transactions on the connection the SQLite
+// repositories share, so any of them can run on the transaction
+type SQLiteUnitOfWork struct {
+	db *sql.DB
+}
+
+func NewSQLiteUnitOfWork(db *sql.DB) *SQLiteUnitOfWork {
+	return &SQLiteUnitOfWork{db: db}
+}
+
+func (u *SQLiteUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return runUnitOfWork(ctx, u.db, fn)
+}
+
+// sqlitePlaceholders returns n comma separated placeholders, to bind a
+// list where Postgres would take an array
+func sqlitePlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// sqliteJSONValue binds and scans a slice or struct SQLite keeps as JSON
+// text, where Postgres has an array or JSONB column. A nil slice is written
+// as an empty array and a nil pointer as NULL; NULL scans as the zero
+// value. A list bound this way is matched with IN (SELECT value FROM
+// json_each(?)).
+type sqliteJSONValue struct {
+	v any
+}
+
+func sqliteJSON(v any) sqliteJSONValue {
+	return sqliteJSONValue{v: v}
+}
+
+func (j sqliteJSONValue) Value() (driver.Value, error) {
+	value := reflect.ValueOf(j.v)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil, nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() == reflect.Slice && value.IsNil() {
+		return "[]", nil
+	}
+
+	data, err := json.Marshal(value.Interface())
+	if err != nil {
+		return nil, err
+	}
+	// Text, not a BLOB, which SQLite's JSON functions would read as JSONB
+	return string(data), nil
+}
+
+func (j sqliteJSONValue) Scan(src any) error {
+	switch src := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(src), j.v)
+	case []byte:
+		return json.Unmarshal(src, j.v)
+	}
+	return fmt.Errorf("cannot scan %T as JSON", src)
+}
+
+// sqliteTimeValue scans a time SQLite returns as text, as it does for
+// aggregates and expressions, which carry no column type. It scans into a
+// *time.Time, or a **time.Time left nil for NULL.
+type sqliteTimeValue struct {
+	dst any
+}
+
+func sqliteTime(dst any) sqliteTimeValue {
+	return sqliteTimeValue{dst: dst}
+}
+
+func (s sqliteTimeValue) Scan(src any) error {
+	var t time.Time
+	switch src := src.(type) {
+	case nil:
+		if dst, ok := s.dst.(**time.Time); ok {
+			*dst = nil
+			return nil
+		}
+		return errors.New("cannot scan NULL as a time")
+	case time.Time:
+		t = src
+	case string, []byte:
+		parsed, err := parseSQLiteTime(fmt.Sprint(src))
+		if err != nil {
+			return err
+		}
+		t = parsed
+	default:
+		return fmt.Errorf("cannot scan %T as a time", src)
+	}
+
+	switch dst := s.dst.(type) {
+	case *time.Time:
+		*dst = t
+	case **time.Time:
+		*dst = &t
+	default:
+		return fmt.Errorf("cannot scan a time into %T", s.dst)
+	}
+	return nil
+}
+
+func parseSQLiteTime(value string) (time.Time, error) {
+	for _, layout := range sqliteTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q as a time", value)
+}
+
+// isSQLiteUniqueViolation reports whether err is a unique or primary key
+// violation, SQLite's counterpart of Postgres error 23505
+func isSQLiteUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
+
+// sqliteUpdate applies updates to the row of table if it is still at
+// version, and bumps its version, like the Postgres repositories' updates.
+// name is what the row is called in errors.
+func sqliteUpdate(ctx context.Context, db *sql.DB, table, name string, userID, id, version int, updates map[string]any) error {
+	if err := checkUpdates(table, updates); err != nil {
+		return err
+	}
+
+	fields := make([]string, 0, len(updates))
+	args := make([]any, 0, len(updates)+4)
+	for field, value := range updates {
+		fields = append(fields, field+" = ?")
+		args = append(args, value)
+	}
+
+	query := "UPDATE " + table + " SET " + strings.Join(fields, ", ") +
+		", version = version + 1, updatedAt = ? WHERE id = ? AND user_id = ? AND version = ?"
+	args = append(args, time.Now().UTC(), id, userID, version)
+
+	result, err := executor(ctx, db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return models.Internal("failed to update %s: %w", name, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	var current int
+	err = executor(ctx, db).QueryRowContext(ctx, "SELECT version FROM "+table+" WHERE id = ? AND user_id = ?", id, userID).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.NotFound("%s with id %d not found", name, id)
+	}
+	if err != nil {
+		return models.Internal("failed to check %s version: %w", name, err)
+	}
+
+	return models.Conflict("%s %d is at version %d, not %d; fetch it again and retry", name, id, current, version)
+}
+
+// flashcardContentHash is the hex SHA-256 of a flashcard's content, the
+// value the Postgres contentHash column generates, for stores that cannot
+// compute it themselves
+func flashcardContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteAPIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteAPIKeyRepository(db *sql.DB) *SQLiteAPIKeyRepository {
+	return &SQLiteAPIKeyRepository{db: db}
+}
+
+func scanSQLiteAPIKey(row interface{ Scan(...any) error }) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, sqliteJSON(&key.Scopes), &key.Requests,
+		&key.LastUsedAt, &key.CreatedAt, &key.RevokedAt)
+	return key, err
+}
+
+// CountAPIKeys counts the user's keys that have not been revoked
+func (r *SQLiteAPIKeyRepository) CountAPIKeys(ctx context.Context, userID int) (int, error) {
+	query := `SELECT COUNT(*) FROM api_keys WHERE user_id = ? AND revokedAt IS NULL`
+
+	var count int
+	if err := executor(ctx, r.db).QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, models.Internal("failed to count api keys: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *SQLiteAPIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, keyHash, scopes, createdAt)
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+	now := time.Now().UTC()
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, key.UserID, key.Name, key.Prefix, key.KeyHash, sqliteJSON(key.Scopes), now)
+	if err != nil {
+		return models.Internal("failed to create api key: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Internal("failed to create api key: %w", err)
+	}
+
+	key.ID, key.CreatedAt = int(id), now
+	return nil
+}
+
+// GetAPIKeys returns the user's keys, revoked ones included, newest first
+func (r *SQLiteAPIKeyRepository) GetAPIKeys(ctx context.Context, userID int) ([]*models.APIKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE user_id = ?
+		ORDER BY createdAt DESC, id DESC`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, models.Internal("failed to get api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*models.APIKey
+	for rows.Next() {
+		key, err := scanSQLiteAPIKey(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (r *SQLiteAPIKeyRepository) GetAPIKey(ctx context.Context, userID, id int) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = ? AND user_id = ?`
+
+	key, err := scanSQLiteAPIKey(executor(ctx, r.db).QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("api key with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// GetAPIKeyByHash returns the key with the hash, if it has not been
+// revoked
+func (r *SQLiteAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE keyHash = ? AND revokedAt IS NULL`
+
+	key, err := scanSQLiteAPIKey(executor(ctx, r.db).QueryRowContext(ctx, query, keyHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("api key not found")
+		}
+		return nil, models.Internal("failed to get api key: %w", err)
+	}
+
+	return key, nil
+}
+
+func (r *SQLiteAPIKeyRepository) RevokeAPIKey(ctx context.Context, userID, id int) error {
+	query := `UPDATE api_keys SET revokedAt = ? WHERE id = ? AND user_id = ? AND revokedAt IS NULL`
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, time.Now().UTC(), id, userID)
+	if err != nil {
+		return models.Internal("failed to revoke api key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return models.NotFound("api key with id %d not found", id)
+	}
+
+	return nil
+}
+
+// SaveAPIKeyUsage adds the request counts to each key's day and total, and
+// moves its lastUsedAt forward, in a single transaction
+func (r *SQLiteAPIKeyRepository) SaveAPIKeyUsage(ctx context.Context, usage []*models.APIKeyUsage, lastUsedAt map[int]time.Time) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	dayQuery := `
+		INSERT INTO api_key_usage (api_key_id, day, requests)
+		VALUES (?, ?, ?)
+		ON CONFLICT (api_key_id, day) DO UPDATE
+		SET requests = requests + excluded.requests`
+	// Unlike GREATEST, SQLite's MAX is NULL when either side is
+	keyQuery := `
+		UPDATE api_keys
+		SET requests = requests + ?2, lastUsedAt = MAX(COALESCE(lastUsedAt, ?3), ?3)
+		WHERE id = ?1`
+
+	for _, day := range usage {
+		if _, err := tx.ExecContext(ctx, dayQuery, day.APIKeyID, day.Day.UTC(), day.Requests); err != nil {
+			return models.Internal("failed to save api key usage: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, keyQuery, day.APIKeyID, day.Requests, lastUsedAt[day.APIKeyID].UTC()); err != nil {
+			return models.Internal("failed to save api key usage: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit api key usage: %w", err)
+	}
+
+	return nil
+}
+
+// GetAPIKeyUsage returns the key's requests per day since the given day,
+// oldest first. Days without requests are left out.
+func (r *SQLiteAPIKeyRepository) GetAPIKeyUsage(ctx context.Context, id int, since time.Time) ([]*models.APIKeyUsage, error) {
+	query := `
+		SELECT day, requests
+		FROM api_key_usage
+		WHERE api_key_id = ? AND day >= ?
+		ORDER BY day`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, id, since.UTC())
+	if err != nil {
+		return nil, models.Internal("failed to get api key usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []*models.APIKeyUsage{}
+	for rows.Next() {
+		day := &models.APIKeyUsage{APIKeyID: id}
+		if err := rows.Scan(&day.Day, &day.Requests); err != nil {
+			return nil, models.Internal("failed to scan api key usage: %w", err)
+		}
+		usage = append(usage, day)
+	}
+
+	return usage, rows.Err()
+}
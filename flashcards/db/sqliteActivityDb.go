@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+// SQLiteActivityRepository reads the activity feed from the tables the
+// actions write to, like PostgresActivityRepository
+type SQLiteActivityRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteActivityRepository(db *sql.DB) *SQLiteActivityRepository {
+	return &SQLiteActivityRepository{db: db}
+}
+
+// GetActivity returns up to limit of the user's most recent activity items
+// since the given time, newest first
+func (r *SQLiteActivityRepository) GetActivity(ctx context.Context, userID int, since time.Time, limit int) ([]models.ActivityItem, error) {
+	query := `
+		SELECT type, occurredAt, count, correct, deck_id, resource_id, status
+		FROM (
+			SELECT ?4 AS type, MAX(f.createdAt) AS occurredAt, COUNT(*) AS count, NULL AS correct,
+				f.deck_id, NULL AS resource_id, '' AS status
+			FROM flashcards f
+			WHERE f.user_id = ?1 AND f.createdAt >= ?2
+			GROUP BY date(f.createdAt), f.deck_id
+			UNION ALL
+			SELECT ?5, MAX(rv.reviewedAt), COUNT(*), COUNT(*) FILTER (WHERE rv.rating <> 'again'),
+				f.deck_id, NULL, ''
+			FROM reviews rv
+			JOIN flashcards f ON f.id = rv.flashcard_id
+			WHERE rv.user_id = ?1 AND rv.reviewedAt >= ?2
+			GROUP BY date(rv.reviewedAt), f.deck_id
+			UNION ALL
+			SELECT ?6, s.updatedAt, COUNT(q.id), COUNT(q.id) FILTER (WHERE q.correct), NULL, s.id, ''
+			FROM quiz_sessions s
+			LEFT JOIN quiz_session_questions q ON q.session_id = s.id
+			WHERE s.user_id = ?1 AND s.status = ?8 AND s.updatedAt >= ?2
+			GROUP BY s.id
+			UNION ALL
+			SELECT ?7, COALESCE(j.completedAt, j.updatedAt), j.processedItems, NULL, j.deck_id, j.id, j.status
+			FROM import_jobs j
+			WHERE j.user_id = ?1 AND j.status IN (?9, ?10) AND COALESCE(j.completedAt, j.updatedAt) >= ?2
+		) activity
+		ORDER BY occurredAt DESC
+		LIMIT ?3`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, since.UTC(), limit,
+		models.ActivityFlashcardsCreated, models.ActivityCardsReviewed, models.ActivitySessionCompleted, models.ActivityImportFinished,
+		models.SessionStatusCompleted, models.ImportJobStatusCompleted, models.ImportJobStatusFailed)
+	if err != nil {
+		return nil, models.Internal("failed to get activity: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.ActivityItem{}
+	for rows.Next() {
+		var item models.ActivityItem
+		err := rows.Scan(&item.Type, sqliteTime(&item.OccurredAt), &item.Count, &item.Correct, &item.DeckID, &item.ResourceID, &item.Status)
+		if err != nil {
+			return nil, models.Internal("failed to scan activity: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over activity: %w", err)
+	}
+
+	return items, nil
+}
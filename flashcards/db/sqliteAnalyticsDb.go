@@ -0,0 +1,167 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+// analyticsSortExpressions in SQLite's dialect
+var sqliteAnalyticsSortExpressions = map[string]string{
+	"requests":   "requests",
+	"errors":     "clientErrors + serverErrors",
+	"errorRate":  "CAST(clientErrors + serverErrors AS REAL) / MAX(requests, 1)",
+	"avgLatency": "CAST(totalLatencyMs AS REAL) / MAX(requests, 1)",
+	"maxLatency": "maxLatencyMs",
+}
+
+type SQLiteAnalyticsRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteAnalyticsRepository(db *sql.DB) *SQLiteAnalyticsRepository {
+	return &SQLiteAnalyticsRepository{db: db}
+}
+
+// SaveRequestStats adds the counts to the stored rows for each bucket,
+// route and user in a single transaction
+func (r *SQLiteAnalyticsRepository) SaveRequestStats(ctx context.Context, stats []*models.RequestStats) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO request_stats
+			(bucket, route, method, user_id, requests, clientErrors, serverErrors, totalLatencyMs, maxLatencyMs)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (bucket, route, method, user_id) DO UPDATE
+		SET requests = requests + excluded.requests,
+			clientErrors = clientErrors + excluded.clientErrors,
+			serverErrors = serverErrors + excluded.serverErrors,
+			totalLatencyMs = totalLatencyMs + excluded.totalLatencyMs,
+			maxLatencyMs = MAX(maxLatencyMs, excluded.maxLatencyMs)`
+
+	for _, stat := range stats {
+		_, err := tx.ExecContext(ctx, query, stat.Bucket.UTC(), stat.Route, stat.Method, stat.UserID, stat.Requests,
+			stat.ClientErrors, stat.ServerErrors, stat.TotalLatencyMs, stat.MaxLatencyMs)
+		if err != nil {
+			return models.Internal("failed to save request stats: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit request stats: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteAnalyticsRepository) GetRouteAnalytics(ctx context.Context, query models.AnalyticsQuery) ([]*models.RouteAnalytics, error) {
+	order, ok := sqliteAnalyticsSortExpressions[query.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sort field %q", query.Sort)
+	}
+
+	sqlQuery := `
+		SELECT route, method, requests, clientErrors, serverErrors, totalLatencyMs, maxLatencyMs, users
+		FROM (
+			SELECT route, method, SUM(requests) AS requests, SUM(clientErrors) AS clientErrors,
+				SUM(serverErrors) AS serverErrors, SUM(totalLatencyMs) AS totalLatencyMs,
+				MAX(maxLatencyMs) AS maxLatencyMs, COUNT(DISTINCT NULLIF(user_id, 0)) AS users
+			FROM request_stats
+			WHERE bucket >= ?
+			GROUP BY route, method
+		) totals
+		ORDER BY ` + order + ` DESC, route
+		LIMIT ?`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, sqlQuery, query.Since.UTC(), query.Limit)
+	if err != nil {
+		return nil, models.Internal("failed to get route analytics: %w", err)
+	}
+	defer rows.Close()
+
+	routes := []*models.RouteAnalytics{}
+	for rows.Next() {
+		route := &models.RouteAnalytics{}
+		var totalLatencyMs int64
+		if err := rows.Scan(&route.Route, &route.Method, &route.Requests, &route.ClientErrors, &route.ServerErrors,
+			&totalLatencyMs, &route.MaxLatencyMs, &route.Users); err != nil {
+			return nil, models.Internal("failed to scan route analytics: %w", err)
+		}
+		route.ErrorRate, route.AvgLatencyMs = rates(route.Requests, route.ClientErrors+route.ServerErrors, totalLatencyMs)
+		routes = append(routes, route)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, models.Internal("failed to iterate route analytics: %w", err)
+	}
+
+	return routes, nil
+}
+
+// GetUserAnalytics reports authenticated users only
+func (r *SQLiteAnalyticsRepository) GetUserAnalytics(ctx context.Context, query models.AnalyticsQuery) ([]*models.UserAnalytics, error) {
+	order, ok := sqliteAnalyticsSortExpressions[query.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sort field %q", query.Sort)
+	}
+
+	sqlQuery := `
+		SELECT user_id, requests, clientErrors, serverErrors, totalLatencyMs, maxLatencyMs, routes
+		FROM (
+			SELECT user_id, SUM(requests) AS requests, SUM(clientErrors) AS clientErrors,
+				SUM(serverErrors) AS serverErrors, SUM(totalLatencyMs) AS totalLatencyMs,
+				MAX(maxLatencyMs) AS maxLatencyMs, COUNT(DISTINCT method || ' ' || route) AS routes
+			FROM request_stats
+			WHERE bucket >= ? AND user_id <> 0
+			GROUP BY user_id
+		) totals
+		ORDER BY ` + order + ` DESC, user_id
+		LIMIT ?`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, sqlQuery, query.Since.UTC(), query.Limit)
+	if err != nil {
+		return nil, models.Internal("failed to get user analytics: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*models.UserAnalytics{}
+	for rows.Next() {
+		user := &models.UserAnalytics{}
+		var totalLatencyMs int64
+		if err := rows.Scan(&user.UserID, &user.Requests, &user.ClientErrors, &user.ServerErrors,
+			&totalLatencyMs, &user.MaxLatencyMs, &user.Routes); err != nil {
+			return nil, models.Internal("failed to scan user analytics: %w", err)
+		}
+		user.ErrorRate, user.AvgLatencyMs = rates(user.Requests, user.ClientErrors+user.ServerErrors, totalLatencyMs)
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, models.Internal("failed to iterate user analytics: %w", err)
+	}
+
+	return users, nil
+}
+
+func (r *SQLiteAnalyticsRepository) DeleteRequestStatsBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := "DELETE FROM request_stats WHERE bucket < ?"
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, before.UTC())
+	if err != nil {
+		return 0, models.Internal("failed to delete request stats: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, models.Internal("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
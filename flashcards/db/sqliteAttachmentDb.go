@@ -0,0 +1,282 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteAttachmentRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteAttachmentRepository(db *sql.DB) *SQLiteAttachmentRepository {
+	return &SQLiteAttachmentRepository{db: db}
+}
+
+// CreateAttachment stores an attachment. One with a ContentHash references
+// the blob with that hash, which is created from its data or StorageKey if
+// there is none yet; StorageKey is then set to the blob's.
+func (r *SQLiteAttachmentRepository) CreateAttachment(ctx context.Context, attachment *models.Attachment) error {
+	if attachment.ContentHash == "" {
+		return r.insertAttachment(ctx, executor(ctx, r.db), attachment, attachment.Data, attachment.StorageKey)
+	}
+
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	storageKey, err := referenceSQLiteBlob(ctx, tx, attachment)
+	if err != nil {
+		return err
+	}
+	if err := r.insertAttachment(ctx, tx, attachment, nil, ""); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit attachment: %w", err)
+	}
+
+	attachment.StorageKey = storageKey
+	return nil
+}
+
+func (r *SQLiteAttachmentRepository) insertAttachment(ctx context.Context, q queryer, attachment *models.Attachment, data []byte, storageKey string) error {
+	query := `
+		INSERT INTO attachments (user_id, filename, contentType, size, data, storageKey, status, contentHash, createdAt) 
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), ?, NULLIF(?, ''), ?)`
+
+	// Data held in object storage is not stored here as well
+	if storageKey != "" {
+		data = nil
+	}
+
+	now := time.Now().UTC()
+	result, err := q.ExecContext(ctx, query, attachment.UserID, attachment.Filename, attachment.ContentType, attachment.Size,
+		data, storageKey, attachment.Status, attachment.ContentHash, now)
+	if err != nil {
+		return models.Internal("failed to create attachment: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Internal("failed to create attachment: %w", err)
+	}
+
+	attachment.ID, attachment.CreatedAt = int(id), now
+	return nil
+}
+
+// referenceSQLiteBlob adds a reference to the blob with the attachment's
+// ContentHash, like referenceBlob, and returns the blob's storage key
+func referenceSQLiteBlob(ctx context.Context, q queryer, attachment *models.Attachment) (string, error) {
+	query := `
+		INSERT INTO attachment_blobs (hash, contentType, size, data, storageKey, createdAt) 
+		VALUES (?, ?, ?, ?, NULLIF(?, ''), ?) 
+		ON CONFLICT (hash) DO UPDATE 
+		SET refCount = attachment_blobs.refCount + 1, releasedAt = NULL 
+		RETURNING COALESCE(storageKey, '')`
+
+	var data []byte
+	if attachment.StorageKey == "" {
+		data = attachment.Data
+	}
+
+	var storageKey string
+	row := q.QueryRowContext(ctx, query, attachment.ContentHash, attachment.ContentType, attachment.Size, data, attachment.StorageKey, time.Now().UTC())
+	if err := row.Scan(&storageKey); err != nil {
+		return "", models.Internal("failed to reference attachment blob: %w", err)
+	}
+
+	return storageKey, nil
+}
+
+func (r *SQLiteAttachmentRepository) GetAttachment(ctx context.Context, userID, id int) (*models.Attachment, error) {
+	query := `
+		SELECT a.id, a.user_id, a.filename, a.contentType, a.size, a.status, COALESCE(b.data, a.data), 
+			COALESCE(b.storageKey, a.storageKey, ''), COALESCE(a.contentHash, ''), a.createdAt 
+		FROM attachments a 
+		LEFT JOIN attachment_blobs b ON b.hash = a.contentHash 
+		WHERE a.id = ? AND a.user_id = ?`
+
+	attachment := &models.Attachment{}
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, id, userID)
+
+	err := row.Scan(&attachment.ID, &attachment.UserID, &attachment.Filename, &attachment.ContentType,
+		&attachment.Size, &attachment.Status, &attachment.Data, &attachment.StorageKey, &attachment.ContentHash, &attachment.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("attachment with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get attachment: %w", err)
+	}
+
+	return attachment, nil
+}
+
+// CompleteAttachment marks a pending attachment ready, with the content
+// type, size and ContentHash of its upload. The upload becomes the blob for
+// the hash unless there already is one; StorageKey is set to the blob's.
+func (r *SQLiteAttachmentRepository) CompleteAttachment(ctx context.Context, attachment *models.Attachment) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	storageKey, err := referenceSQLiteBlob(ctx, tx, attachment)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE attachments 
+		SET status = ?, contentType = ?, size = ?, contentHash = ?, storageKey = NULL 
+		WHERE id = ? AND user_id = ? AND status = ?`
+
+	result, err := tx.ExecContext(ctx, query, models.AttachmentStatusReady, attachment.ContentType, attachment.Size,
+		attachment.ContentHash, attachment.ID, attachment.UserID, models.AttachmentStatusPending)
+	if err != nil {
+		return models.Internal("failed to complete attachment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return models.Conflict("attachment with id %d is not pending", attachment.ID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit attachment: %w", err)
+	}
+
+	attachment.Status = models.AttachmentStatusReady
+	attachment.StorageKey = storageKey
+	return nil
+}
+
+// DeleteAttachment deletes an attachment and releases its blob. The blob
+// is only deleted later, by DeleteReleasedBlobs.
+func (r *SQLiteAttachmentRepository) DeleteAttachment(ctx context.Context, userID, id int) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM attachments WHERE id = ? AND user_id = ? RETURNING COALESCE(contentHash, '')`
+
+	var hash string
+	if err := tx.QueryRowContext(ctx, query, id, userID).Scan(&hash); err != nil {
+		if err == sql.ErrNoRows {
+			return models.NotFound("attachment with id %d not found", id)
+		}
+		return models.Internal("failed to delete attachment: %w", err)
+	}
+
+	if hash != "" {
+		release := `
+			UPDATE attachment_blobs 
+			SET refCount = refCount - 1, releasedAt = CASE WHEN refCount = 1 THEN ? END 
+			WHERE hash = ?`
+
+		if _, err := tx.ExecContext(ctx, release, time.Now().UTC(), hash); err != nil {
+			return models.Internal("failed to release attachment blob: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit attachment deletion: %w", err)
+	}
+
+	return nil
+}
+
+// GetPendingAttachments returns up to limit attachments still pending
+// since before, oldest first
+func (r *SQLiteAttachmentRepository) GetPendingAttachments(ctx context.Context, before time.Time, limit int) ([]*models.Attachment, error) {
+	query := `
+		SELECT id, user_id, filename, contentType, size, status, COALESCE(storageKey, ''), createdAt 
+		FROM attachments 
+		WHERE status = ? AND createdAt < ? 
+		ORDER BY createdAt 
+		LIMIT ?`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, models.AttachmentStatusPending, before.UTC(), limit)
+	if err != nil {
+		return nil, models.Internal("failed to get pending attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []*models.Attachment
+	for rows.Next() {
+		attachment := &models.Attachment{}
+		if err := rows.Scan(&attachment.ID, &attachment.UserID, &attachment.Filename, &attachment.ContentType,
+			&attachment.Size, &attachment.Status, &attachment.StorageKey, &attachment.CreatedAt); err != nil {
+			return nil, models.Internal("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, rows.Err()
+}
+
+func (r *SQLiteAttachmentRepository) GetBlob(ctx context.Context, hash string) (*models.AttachmentBlob, error) {
+	query := `
+		SELECT hash, contentType, size, COALESCE(storageKey, ''), refCount, releasedAt, createdAt 
+		FROM attachment_blobs 
+		WHERE hash = ?`
+
+	blob := &models.AttachmentBlob{}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, hash).Scan(&blob.Hash, &blob.ContentType, &blob.Size, &blob.StorageKey,
+		&blob.RefCount, &blob.ReleasedAt, &blob.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("attachment blob %s not found", hash)
+		}
+		return nil, models.Internal("failed to get attachment blob: %w", err)
+	}
+
+	return blob, nil
+}
+
+// DeleteReleasedBlobs deletes up to limit blobs that have been
+// unreferenced since before and returns them, so their objects can be
+// deleted. A blob referenced again meanwhile is kept.
+func (r *SQLiteAttachmentRepository) DeleteReleasedBlobs(ctx context.Context, before time.Time, limit int) ([]*models.AttachmentBlob, error) {
+	query := `
+		DELETE FROM attachment_blobs 
+		WHERE hash IN ( 
+			SELECT hash FROM attachment_blobs b 
+			WHERE refCount = 0 AND releasedAt < ? 
+				AND NOT EXISTS (SELECT 1 FROM attachments a WHERE a.contentHash = b.hash) 
+			ORDER BY releasedAt 
+			LIMIT ? 
+		) AND refCount = 0 
+		RETURNING hash, contentType, size, COALESCE(storageKey, ''), refCount, releasedAt, createdAt`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, before.UTC(), limit)
+	if err != nil {
+		return nil, models.Internal("failed to delete released attachment blobs: %w", err)
+	}
+	defer rows.Close()
+
+	var blobs []*models.AttachmentBlob
+	for rows.Next() {
+		blob := &models.AttachmentBlob{}
+		if err := rows.Scan(&blob.Hash, &blob.ContentType, &blob.Size, &blob.StorageKey, &blob.RefCount,
+			sqliteTime(&blob.ReleasedAt), sqliteTime(&blob.CreatedAt)); err != nil {
+			return nil, models.Internal("failed to scan attachment blob: %w", err)
+		}
+		blobs = append(blobs, blob)
+	}
+
+	return blobs, rows.Err()
+}
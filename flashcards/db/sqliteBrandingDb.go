@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteBrandingRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteBrandingRepository(db *sql.DB) *SQLiteBrandingRepository {
+	return &SQLiteBrandingRepository{db: db}
+}
+
+func (r *SQLiteBrandingRepository) GetBranding(ctx context.Context) (*models.Branding, error) {
+	query := `
+		SELECT name, logoUrl, primaryColor, accentColor, supportEmail, supportUrl, updatedAt
+		FROM branding
+		WHERE id = 1`
+
+	branding := &models.Branding{}
+	row := executor(ctx, r.db).QueryRowContext(ctx, query)
+
+	err := row.Scan(&branding.Name, &branding.LogoURL, &branding.PrimaryColor, &branding.AccentColor,
+		&branding.SupportEmail, &branding.SupportURL, &branding.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &models.Branding{
+				Name:         models.DefaultBrandingName,
+				PrimaryColor: models.DefaultBrandingPrimaryColor,
+				AccentColor:  models.DefaultBrandingAccentColor,
+			}, nil
+		}
+		return nil, models.Internal("failed to get branding: %w", err)
+	}
+
+	return branding, nil
+}
+
+func (r *SQLiteBrandingRepository) SaveBranding(ctx context.Context, branding *models.Branding) error {
+	query := `
+		INSERT INTO branding (id, name, logoUrl, primaryColor, accentColor, supportEmail, supportUrl, updatedAt)
+		VALUES (1, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE
+		SET name = excluded.name, logoUrl = excluded.logoUrl, primaryColor = excluded.primaryColor,
+			accentColor = excluded.accentColor, supportEmail = excluded.supportEmail, supportUrl = excluded.supportUrl,
+			updatedAt = excluded.updatedAt`
+
+	now := time.Now().UTC()
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, branding.Name, branding.LogoURL, branding.PrimaryColor, branding.AccentColor,
+		branding.SupportEmail, branding.SupportURL, now)
+	if err != nil {
+		return models.Internal("failed to save branding: %w", err)
+	}
+
+	branding.UpdatedAt = now
+	return nil
+}
@@ -0,0 +1,359 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+const sqliteSimilarityFlagColumns = "id, deck_id, matched_deck_id, matchedCards, totalCards, maxSimilarity, status, createdAt, reviewedAt"
+
+type SQLiteCatalogRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteCatalogRepository(db *sql.DB) *SQLiteCatalogRepository {
+	return &SQLiteCatalogRepository{db: db}
+}
+
+// GetDeckCards returns the flashcards directly in the deck, not in its
+// subdecks
+func (r *SQLiteCatalogRepository) GetDeckCards(ctx context.Context, userID, deckID int) ([]*models.Flashcard, error) {
+	query := `
+		SELECT ` + flashcardColumns + `
+		FROM flashcards f
+		WHERE f.user_id = ? AND f.deck_id = ?
+		ORDER BY f.id`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, deckID)
+	if err != nil {
+		return nil, models.Internal("failed to get deck cards: %w", err)
+	}
+	defer rows.Close()
+
+	flashcards := []*models.Flashcard{}
+	for rows.Next() {
+		flashcard := &models.Flashcard{}
+		err := rows.Scan(flashcardScanArgs(flashcard)...)
+		if err != nil {
+			return nil, models.Internal("failed to scan flashcard: %w", err)
+		}
+		flashcards = append(flashcards, flashcard)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, models.Internal("failed to iterate deck cards: %w", err)
+	}
+
+	return flashcards, nil
+}
+
+func (r *SQLiteCatalogRepository) IsPublished(ctx context.Context, deckID int) (bool, error) {
+	var exists bool
+	err := executor(ctx, r.db).QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM published_decks WHERE deck_id = ?)", deckID).Scan(&exists)
+	if err != nil {
+		return false, models.Internal("failed to check published deck: %w", err)
+	}
+
+	return exists, nil
+}
+
+// GetPublishedEmbeddings returns the card embeddings of every published
+// deck not owned by the user. Only embeddings from the same model are
+// comparable, so others are skipped.
+func (r *SQLiteCatalogRepository) GetPublishedEmbeddings(ctx context.Context, excludeUserID int, model string) ([]*models.CardEmbedding, error) {
+	query := `
+		SELECT e.flashcard_id, e.deck_id, e.model, e.embedding
+		FROM card_embeddings e
+		JOIN published_decks p ON p.deck_id = e.deck_id
+		WHERE p.user_id <> ? AND e.model = ?`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, excludeUserID, model)
+	if err != nil {
+		return nil, models.Internal("failed to get card embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	embeddings := []*models.CardEmbedding{}
+	for rows.Next() {
+		embedding := &models.CardEmbedding{}
+		if err := rows.Scan(&embedding.FlashcardID, &embedding.DeckID, &embedding.Model, sqliteJSON(&embedding.Vector)); err != nil {
+			return nil, models.Internal("failed to scan card embedding: %w", err)
+		}
+		embeddings = append(embeddings, embedding)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, models.Internal("failed to iterate card embeddings: %w", err)
+	}
+
+	return embeddings, nil
+}
+
+// PublishDeck lists the deck in the catalog with its card embeddings and
+// any similarity flags raised against it, in one transaction
+func (r *SQLiteCatalogRepository) PublishDeck(ctx context.Context, userID, deckID int, embeddings []*models.CardEmbedding, flags []*models.SimilarityFlag) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO published_decks (deck_id, user_id, publishedAt) VALUES (?, ?, ?)", deckID, userID, time.Now().UTC())
+	if err != nil {
+		if isSQLiteUniqueViolation(err) {
+			return models.Conflict("deck %d is already published", deckID)
+		}
+		return models.Internal("failed to publish deck: %w", err)
+	}
+
+	if err := saveSQLiteSimilarityCheck(ctx, tx, deckID, embeddings, flags); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit published deck: %w", err)
+	}
+
+	return nil
+}
+
+// SaveSimilarityCheck stores the result of a similarity check that ran
+// after the deck was published
+func (r *SQLiteCatalogRepository) SaveSimilarityCheck(ctx context.Context, deckID int, embeddings []*models.CardEmbedding, flags []*models.SimilarityFlag) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := saveSQLiteSimilarityCheck(ctx, tx, deckID, embeddings, flags); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit similarity check: %w", err)
+	}
+
+	return nil
+}
+
+func saveSQLiteSimilarityCheck(ctx context.Context, tx queryer, deckID int, embeddings []*models.CardEmbedding, flags []*models.SimilarityFlag) error {
+	for _, embedding := range embeddings {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO card_embeddings (flashcard_id, deck_id, model, embedding)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT (flashcard_id) DO UPDATE
+			SET deck_id = excluded.deck_id, model = excluded.model, embedding = excluded.embedding`,
+			embedding.FlashcardID, deckID, embedding.Model, sqliteJSON(embedding.Vector))
+		if err != nil {
+			return models.Internal("failed to save card embedding: %w", err)
+		}
+	}
+
+	now := time.Now().UTC()
+	for _, flag := range flags {
+		row := tx.QueryRowContext(ctx, `
+			INSERT INTO deck_similarity_flags (deck_id, matched_deck_id, matchedCards, totalCards, maxSimilarity, createdAt)
+			VALUES (?, ?, ?, ?, ?, ?)
+			RETURNING id, status`,
+			flag.DeckID, flag.MatchedDeckID, flag.MatchedCards, flag.TotalCards, flag.MaxSimilarity, now)
+		if err := row.Scan(&flag.ID, &flag.Status); err != nil {
+			return models.Internal("failed to create similarity flag: %w", err)
+		}
+		flag.CreatedAt = now
+	}
+
+	return nil
+}
+
+// UnpublishDeck removes the deck and its card embeddings from the catalog
+func (r *SQLiteCatalogRepository) UnpublishDeck(ctx context.Context, deckID int) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, "DELETE FROM published_decks WHERE deck_id = ?", deckID)
+	if err != nil {
+		return models.Internal("failed to unpublish deck: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("published deck with id %d not found", deckID)
+	}
+
+	return nil
+}
+
+// ListPublishedDecks returns one page of the catalog, newest first
+func (r *SQLiteCatalogRepository) ListPublishedDecks(ctx context.Context, limit, offset int) ([]*models.PublishedDeck, int, error) {
+	var total int
+	if err := executor(ctx, r.db).QueryRowContext(ctx, "SELECT COUNT(*) FROM published_decks").Scan(&total); err != nil {
+		return nil, 0, models.Internal("failed to count published decks: %w", err)
+	}
+
+	query := `
+		SELECT p.deck_id, p.user_id, d.name, d.description, p.publishedAt,
+			(SELECT COUNT(*) FROM flashcards f WHERE f.deck_id = p.deck_id)
+		FROM published_decks p
+		JOIN decks d ON d.id = p.deck_id
+		ORDER BY p.publishedAt DESC, p.deck_id DESC
+		LIMIT ? OFFSET ?`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, models.Internal("failed to get published decks: %w", err)
+	}
+	defer rows.Close()
+
+	decks := []*models.PublishedDeck{}
+	for rows.Next() {
+		deck := &models.PublishedDeck{}
+		if err := rows.Scan(&deck.DeckID, &deck.UserID, &deck.Name, &deck.Description, &deck.PublishedAt, &deck.CardCount); err != nil {
+			return nil, 0, models.Internal("failed to scan published deck: %w", err)
+		}
+		decks = append(decks, deck)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, models.Internal("failed to iterate published decks: %w", err)
+	}
+
+	return decks, total, nil
+}
+
+// GetPublishedDeck returns the catalog entry of a published deck
+func (r *SQLiteCatalogRepository) GetPublishedDeck(ctx context.Context, deckID int) (*models.PublishedDeck, error) {
+	query := `
+		SELECT p.deck_id, p.user_id, d.name, d.description, p.publishedAt,
+			(SELECT COUNT(*) FROM flashcards f WHERE f.deck_id = p.deck_id)
+		FROM published_decks p
+		JOIN decks d ON d.id = p.deck_id
+		WHERE p.deck_id = ?`
+
+	deck := &models.PublishedDeck{}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, deckID).Scan(&deck.DeckID, &deck.UserID, &deck.Name, &deck.Description, &deck.PublishedAt, &deck.CardCount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("published deck with ID %d not found", deckID)
+		}
+		return nil, models.Internal("failed to get published deck: %w", err)
+	}
+
+	return deck, nil
+}
+
+// GetRecentDeckCards returns the most recently added cards directly in a
+// published deck, newest first
+func (r *SQLiteCatalogRepository) GetRecentDeckCards(ctx context.Context, deckID, limit int) ([]*models.Flashcard, error) {
+	query := `
+		SELECT ` + flashcardColumns + `
+		FROM flashcards f
+		JOIN published_decks p ON p.deck_id = f.deck_id AND p.user_id = f.user_id
+		WHERE f.deck_id = ?
+		ORDER BY f.createdAt DESC, f.id DESC
+		LIMIT ?`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, deckID, limit)
+	if err != nil {
+		return nil, models.Internal("failed to get recent deck cards: %w", err)
+	}
+	defer rows.Close()
+
+	flashcards := []*models.Flashcard{}
+	for rows.Next() {
+		flashcard := &models.Flashcard{}
+		if err := rows.Scan(flashcardScanArgs(flashcard)...); err != nil {
+			return nil, models.Internal("failed to scan flashcard: %w", err)
+		}
+		flashcards = append(flashcards, flashcard)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, models.Internal("failed to iterate recent deck cards: %w", err)
+	}
+
+	return flashcards, nil
+}
+
+// GetSimilarityFlags returns the most recent flags with the given status
+func (r *SQLiteCatalogRepository) GetSimilarityFlags(ctx context.Context, status string, limit int) ([]*models.SimilarityFlag, error) {
+	query := `
+		SELECT ` + sqliteSimilarityFlagColumns + `
+		FROM deck_similarity_flags
+		WHERE status = ?
+		ORDER BY createdAt DESC, id DESC
+		LIMIT ?`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, status, limit)
+	if err != nil {
+		return nil, models.Internal("failed to get similarity flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := []*models.SimilarityFlag{}
+	for rows.Next() {
+		flag, err := scanSQLiteSimilarityFlag(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan similarity flag: %w", err)
+		}
+		flags = append(flags, flag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, models.Internal("failed to iterate similarity flags: %w", err)
+	}
+
+	return flags, nil
+}
+
+// ReviewSimilarityFlag records a curator's decision. Confirming a flag
+// takes the flagged deck out of the catalog.
+func (r *SQLiteCatalogRepository) ReviewSimilarityFlag(ctx context.Context, id int, status string) (*models.SimilarityFlag, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE deck_similarity_flags
+		SET status = ?, reviewedAt = ?
+		WHERE id = ?
+		RETURNING ` + sqliteSimilarityFlagColumns
+
+	flag, err := scanSQLiteSimilarityFlag(tx.QueryRowContext(ctx, query, status, time.Now().UTC(), id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("similarity flag with id %d not found", id)
+		}
+		return nil, models.Internal("failed to review similarity flag: %w", err)
+	}
+
+	if status == models.FlagStatusConfirmed {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM published_decks WHERE deck_id = ?", flag.DeckID); err != nil {
+			return nil, models.Internal("failed to unpublish deck: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, models.Internal("failed to commit similarity flag: %w", err)
+	}
+
+	return flag, nil
+}
+
+// scanSQLiteSimilarityFlag scans the sqliteSimilarityFlagColumns of a query
+// or, with the times untyped, of a RETURNING clause
+func scanSQLiteSimilarityFlag(row interface{ Scan(...any) error }) (*models.SimilarityFlag, error) {
+	flag := &models.SimilarityFlag{}
+	err := row.Scan(&flag.ID, &flag.DeckID, &flag.MatchedDeckID, &flag.MatchedCards, &flag.TotalCards,
+		&flag.MaxSimilarity, &flag.Status, sqliteTime(&flag.CreatedAt), sqliteTime(&flag.ReviewedAt))
+	if err != nil {
+		return nil, err
+	}
+	return flag, nil
+}
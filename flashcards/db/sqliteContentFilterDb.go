@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteContentFilterRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteContentFilterRepository(db *sql.DB) *SQLiteContentFilterRepository {
+	return &SQLiteContentFilterRepository{db: db}
+}
+
+func (r *SQLiteContentFilterRepository) GetSettings(ctx context.Context) (*models.ContentFilterSettings, error) {
+	query := `
+		SELECT enabled, ageLevel, bannedTopics, updatedAt
+		FROM content_filter_settings
+		WHERE id = 1`
+
+	settings := &models.ContentFilterSettings{}
+	row := executor(ctx, r.db).QueryRowContext(ctx, query)
+
+	err := row.Scan(&settings.Enabled, &settings.AgeLevel, sqliteJSON(&settings.BannedTopics), &settings.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &models.ContentFilterSettings{AgeLevel: models.AgeLevelAdult, BannedTopics: []string{}}, nil
+		}
+		return nil, models.Internal("failed to get content filter settings: %w", err)
+	}
+
+	return settings, nil
+}
+
+func (r *SQLiteContentFilterRepository) SaveSettings(ctx context.Context, settings *models.ContentFilterSettings) error {
+	query := `
+		INSERT INTO content_filter_settings (id, enabled, ageLevel, bannedTopics, updatedAt)
+		VALUES (1, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE
+		SET enabled = excluded.enabled, ageLevel = excluded.ageLevel, bannedTopics = excluded.bannedTopics, updatedAt = excluded.updatedAt`
+
+	now := time.Now().UTC()
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, settings.Enabled, settings.AgeLevel, sqliteJSON(settings.BannedTopics), now)
+	if err != nil {
+		return models.Internal("failed to save content filter settings: %w", err)
+	}
+
+	settings.UpdatedAt = now
+	return nil
+}
@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteDailyQuestionRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteDailyQuestionRepository(db *sql.DB) *SQLiteDailyQuestionRepository {
+	return &SQLiteDailyQuestionRepository{db: db}
+}
+
+func (r *SQLiteDailyQuestionRepository) CreateSubscription(ctx context.Context, subscription *models.DailyQuestionSubscription) error {
+	query := `
+		INSERT INTO daily_question_subscriptions (user_id, deck_id, createdAt)
+		VALUES (?, ?, ?)`
+
+	now := time.Now().UTC()
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, subscription.UserID, subscription.DeckID, now)
+	if err != nil {
+		if isSQLiteUniqueViolation(err) {
+			return models.Conflict("already subscribed to daily questions from deck %d", subscription.DeckID)
+		}
+		return models.Internal("failed to create daily question subscription: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Internal("failed to create daily question subscription: %w", err)
+	}
+
+	subscription.ID, subscription.CreatedAt = int(id), now
+	return nil
+}
+
+func (r *SQLiteDailyQuestionRepository) GetSubscriptions(ctx context.Context, userID int) ([]*models.DailyQuestionSubscription, error) {
+	query := `
+		SELECT s.id, s.user_id, s.deck_id, s.last_session_id, s.lastSentAt, s.createdAt, u.email
+		FROM daily_question_subscriptions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.user_id = ?
+		ORDER BY s.createdAt ASC, s.id ASC`
+
+	return r.querySubscriptions(ctx, query, userID)
+}
+
+// GetSubscriptionsNotSentSince returns every user's subscriptions that have
+// not had a question sent since the given time, for the scheduled job
+func (r *SQLiteDailyQuestionRepository) GetSubscriptionsNotSentSince(ctx context.Context, since time.Time) ([]*models.DailyQuestionSubscription, error) {
+	query := `
+		SELECT s.id, s.user_id, s.deck_id, s.last_session_id, s.lastSentAt, s.createdAt, u.email
+		FROM daily_question_subscriptions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.lastSentAt IS NULL OR s.lastSentAt < ?
+		ORDER BY s.user_id ASC, s.id ASC`
+
+	return r.querySubscriptions(ctx, query, since.UTC())
+}
+
+func (r *SQLiteDailyQuestionRepository) querySubscriptions(ctx context.Context, query string, args ...any) ([]*models.DailyQuestionSubscription, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Internal("failed to query daily question subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := make([]*models.DailyQuestionSubscription, 0)
+	for rows.Next() {
+		subscription := &models.DailyQuestionSubscription{}
+		err := rows.Scan(&subscription.ID, &subscription.UserID, &subscription.DeckID, &subscription.LastSessionID,
+			&subscription.LastSentAt, &subscription.CreatedAt, &subscription.Email)
+		if err != nil {
+			return nil, models.Internal("failed to scan daily question subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over daily question subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (r *SQLiteDailyQuestionRepository) MarkSubscriptionSent(ctx context.Context, id, sessionID int, sentAt time.Time) error {
+	query := "UPDATE daily_question_subscriptions SET last_session_id = ?, lastSentAt = ? WHERE id = ?"
+
+	if _, err := executor(ctx, r.db).ExecContext(ctx, query, sessionID, sentAt.UTC(), id); err != nil {
+		return models.Internal("failed to mark daily question subscription sent: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteDailyQuestionRepository) DeleteSubscription(ctx context.Context, userID, id int) error {
+	query := "DELETE FROM daily_question_subscriptions WHERE id = ? AND user_id = ?"
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return models.Internal("failed to delete daily question subscription: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("daily question subscription with id %d not found", id)
+	}
+
+	return nil
+}
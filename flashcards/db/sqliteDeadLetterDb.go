@@ -0,0 +1,221 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteDeadLetterRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteDeadLetterRepository(db *sql.DB) *SQLiteDeadLetterRepository {
+	return &SQLiteDeadLetterRepository{db: db}
+}
+
+// RecordDeadLetter adds a failed piece of work. When the same work already
+// has an open entry, that entry takes the new error and payload and keeps
+// its attempts and schedule.
+func (r *SQLiteDeadLetterRepository) RecordDeadLetter(ctx context.Context, letter *models.DeadLetter) error {
+	query := `
+		INSERT INTO dead_letters (kind, key, user_id, payload, error, attempts, status, nextAttemptAt, createdAt, updatedAt)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (kind, key) WHERE status <> 'resolved' DO UPDATE
+		SET payload = excluded.payload, error = excluded.error, updatedAt = excluded.updatedAt
+		RETURNING ` + deadLetterColumns
+
+	now := time.Now().UTC()
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, letter.Kind, letter.Key, letter.UserID, string(letter.Payload), letter.Error,
+		letter.Attempts, letter.Status, letter.NextAttemptAt, now, now)
+	recorded, err := scanSQLiteDeadLetter(row)
+	if err != nil {
+		return models.Internal("failed to record dead letter: %w", err)
+	}
+
+	*letter = *recorded
+	return nil
+}
+
+func (r *SQLiteDeadLetterRepository) GetDeadLetter(ctx context.Context, id int) (*models.DeadLetter, error) {
+	query := `SELECT ` + deadLetterColumns + ` FROM dead_letters WHERE id = ?`
+
+	letter, err := scanSQLiteDeadLetter(executor(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("dead letter with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get dead letter: %w", err)
+	}
+
+	return letter, nil
+}
+
+// ListDeadLetters returns one page of entries matching the filter and the
+// total number that match
+func (r *SQLiteDeadLetterRepository) ListDeadLetters(ctx context.Context, filter models.DeadLetterFilter, params models.ListParams) ([]*models.DeadLetter, int, error) {
+	where := ` WHERE (?1 = '' OR d.status = ?1) AND (?2 = '' OR d.kind = ?2)`
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM dead_letters d` + where
+	if err := executor(ctx, r.db).QueryRowContext(ctx, countQuery, filter.Status, filter.Kind).Scan(&total); err != nil {
+		return nil, 0, models.Internal("failed to count dead letters: %w", err)
+	}
+
+	orderBy, err := orderByClause("d", params)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := `SELECT ` + deadLetterColumns + ` FROM dead_letters d` + where + orderBy + ` LIMIT ?3 OFFSET ?4`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, filter.Status, filter.Kind, params.Limit, params.Offset)
+	if err != nil {
+		return nil, 0, models.Internal("failed to query dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	letters := make([]*models.DeadLetter, 0)
+	for rows.Next() {
+		letter, err := scanSQLiteDeadLetter(rows)
+		if err != nil {
+			return nil, 0, models.Internal("failed to scan dead letter: %w", err)
+		}
+		letters = append(letters, letter)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating over dead letters: %w", err)
+	}
+
+	return letters, total, nil
+}
+
+// ClaimDueDeadLetters returns retrying entries whose next attempt is due
+// and pushes that attempt back by lease, so an attempt cut short by a
+// crash is picked up again once the lease runs out
+func (r *SQLiteDeadLetterRepository) ClaimDueDeadLetters(ctx context.Context, limit int, lease time.Duration) ([]*models.DeadLetter, error) {
+	query := `
+		UPDATE dead_letters
+		SET nextAttemptAt = ?
+		WHERE id IN (
+			SELECT id FROM dead_letters
+			WHERE status = ? AND nextAttemptAt <= ?
+			ORDER BY nextAttemptAt
+			LIMIT ?
+		)
+		RETURNING ` + deadLetterColumns
+
+	now := time.Now().UTC()
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, now.Add(lease), models.DeadLetterStatusRetrying, now, limit)
+	if err != nil {
+		return nil, models.Internal("failed to claim dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	letters := make([]*models.DeadLetter, 0)
+	for rows.Next() {
+		letter, err := scanSQLiteDeadLetter(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan dead letter: %w", err)
+		}
+		letters = append(letters, letter)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over dead letters: %w", err)
+	}
+
+	return letters, nil
+}
+
+func (r *SQLiteDeadLetterRepository) ResolveDeadLetter(ctx context.Context, id int) error {
+	query := `
+		UPDATE dead_letters
+		SET status = ?2, nextAttemptAt = NULL, resolvedAt = ?3, updatedAt = ?3
+		WHERE id = ?1`
+
+	if _, err := executor(ctx, r.db).ExecContext(ctx, query, id, models.DeadLetterStatusResolved, time.Now().UTC()); err != nil {
+		return models.Internal("failed to resolve dead letter: %w", err)
+	}
+
+	return nil
+}
+
+// FailDeadLetterAttempt counts a failed retry. Without a next attempt the
+// entry is dead.
+func (r *SQLiteDeadLetterRepository) FailDeadLetterAttempt(ctx context.Context, id int, message string, nextAttemptAt *time.Time) error {
+	query := `
+		UPDATE dead_letters
+		SET attempts = attempts + 1, error = ?2, nextAttemptAt = ?3,
+			status = CASE WHEN ?3 IS NULL THEN ?4 ELSE ?5 END, updatedAt = ?7
+		WHERE id = ?1 AND status <> ?6`
+
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, id, message, nextAttemptAt,
+		models.DeadLetterStatusDead, models.DeadLetterStatusRetrying, models.DeadLetterStatusResolved, time.Now().UTC())
+	if err != nil {
+		return models.Internal("failed to update dead letter: %w", err)
+	}
+
+	return nil
+}
+
+// RequeueDeadLetter makes an open entry due for a retry now, including a
+// dead one
+func (r *SQLiteDeadLetterRepository) RequeueDeadLetter(ctx context.Context, id int) (*models.DeadLetter, error) {
+	query := `
+		UPDATE dead_letters
+		SET status = ?2, nextAttemptAt = ?4, updatedAt = ?4
+		WHERE id = ?1 AND status <> ?3
+		RETURNING ` + deadLetterColumns
+
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, id, models.DeadLetterStatusRetrying, models.DeadLetterStatusResolved, time.Now().UTC())
+	letter, err := scanSQLiteDeadLetter(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			if _, getErr := r.GetDeadLetter(ctx, id); getErr != nil {
+				return nil, getErr
+			}
+			return nil, models.Conflict("dead letter %d is already resolved", id)
+		}
+		return nil, models.Internal("failed to requeue dead letter: %w", err)
+	}
+
+	return letter, nil
+}
+
+func (r *SQLiteDeadLetterRepository) DeleteDeadLetter(ctx context.Context, id int) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, "DELETE FROM dead_letters WHERE id = ?", id)
+	if err != nil {
+		return models.Internal("failed to delete dead letter: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("dead letter with id %d not found", id)
+	}
+
+	return nil
+}
+
+// scanSQLiteDeadLetter scans the deadLetterColumns of a query or, with the
+// times untyped, of a RETURNING clause
+func scanSQLiteDeadLetter(row interface{ Scan(...any) error }) (*models.DeadLetter, error) {
+	letter := &models.DeadLetter{}
+	var payload []byte
+	err := row.Scan(&letter.ID, &letter.Kind, &letter.Key, &letter.UserID, &payload, &letter.Error, &letter.Attempts,
+		&letter.Status, sqliteTime(&letter.NextAttemptAt), sqliteTime(&letter.CreatedAt), sqliteTime(&letter.UpdatedAt),
+		sqliteTime(&letter.ResolvedAt))
+	if err != nil {
+		return nil, err
+	}
+
+	letter.Payload = payload
+	return letter, nil
+}
@@ -0,0 +1,290 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteDeckRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteDeckRepository(db *sql.DB) *SQLiteDeckRepository {
+	return &SQLiteDeckRepository{db: db}
+}
+
+func (r *SQLiteDeckRepository) CreateDeck(ctx context.Context, deck *models.Deck) error {
+	query := `
+		INSERT INTO decks (user_id, parent_id, name, description, reviewOrder, createdAt, updatedAt) 
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	now := time.Now().UTC()
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, deck.UserID, deck.ParentID, deck.Name, deck.Description, deck.ReviewOrder, now, now)
+	if err != nil {
+		return models.Internal("failed to create deck: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Internal("failed to create deck: %w", err)
+	}
+
+	deck.ID, deck.CreatedAt, deck.UpdatedAt = int(id), now, now
+	return nil
+}
+
+func (r *SQLiteDeckRepository) GetDeckByID(ctx context.Context, userID, id int) (*models.Deck, error) {
+	query := `
+		SELECT id, user_id, parent_id, name, description, reviewOrder, createdAt, updatedAt 
+		FROM decks 
+		WHERE id = ? AND user_id = ?`
+
+	deck := &models.Deck{}
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, id, userID)
+
+	err := row.Scan(&deck.ID, &deck.UserID, &deck.ParentID, &deck.Name, &deck.Description, &deck.ReviewOrder, &deck.CreatedAt, &deck.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("deck with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get deck: %w", err)
+	}
+
+	return deck, nil
+}
+
+func (r *SQLiteDeckRepository) GetAllDecks(ctx context.Context, userID int) ([]*models.Deck, error) {
+	query := `
+		SELECT id, user_id, parent_id, name, description, reviewOrder, createdAt, updatedAt 
+		FROM decks 
+		WHERE user_id = ? 
+		ORDER BY name ASC`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, models.Internal("failed to query decks: %w", err)
+	}
+	defer rows.Close()
+
+	decks := make([]*models.Deck, 0)
+	for rows.Next() {
+		deck := &models.Deck{}
+		err := rows.Scan(&deck.ID, &deck.UserID, &deck.ParentID, &deck.Name, &deck.Description, &deck.ReviewOrder, &deck.CreatedAt, &deck.UpdatedAt)
+		if err != nil {
+			return nil, models.Internal("failed to scan deck: %w", err)
+		}
+		decks = append(decks, deck)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over decks: %w", err)
+	}
+
+	return decks, nil
+}
+
+func (r *SQLiteDeckRepository) UpdateDeck(ctx context.Context, userID, id int, updates map[string]any) error {
+	if err := checkUpdates("decks", updates); err != nil {
+		return err
+	}
+
+	fields := make([]string, 0, len(updates))
+	args := make([]any, 0, len(updates)+3)
+	for field, value := range updates {
+		fields = append(fields, field+" = ?")
+		args = append(args, value)
+	}
+
+	query := "UPDATE decks SET " + strings.Join(fields, ", ") + ", updatedAt = ? WHERE id = ? AND user_id = ?"
+	args = append(args, time.Now().UTC(), id, userID)
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return models.Internal("failed to update deck: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("deck with id %d not found", id)
+	}
+
+	return nil
+}
+
+func (r *SQLiteDeckRepository) DeleteDeck(ctx context.Context, userID, id int) error {
+	query := "DELETE FROM decks WHERE id = ? AND user_id = ?"
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return models.Internal("failed to delete deck: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("deck with id %d not found", id)
+	}
+
+	return nil
+}
+
+// GetDeckTreeIDs returns the deck and all of its subdecks, at any depth
+func (r *SQLiteDeckRepository) GetDeckTreeIDs(ctx context.Context, userID, id int) ([]int, error) {
+	query := `
+		WITH RECURSIVE tree AS (
+			SELECT id FROM decks WHERE id = ?1 AND user_id = ?2
+			UNION
+			SELECT d.id FROM decks d JOIN tree t ON d.parent_id = t.id WHERE d.user_id = ?2
+		)
+		SELECT id FROM tree`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, id, userID)
+	if err != nil {
+		return nil, models.Internal("failed to query subdecks: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var deckID int
+		if err := rows.Scan(&deckID); err != nil {
+			return nil, models.Internal("failed to scan subdeck: %w", err)
+		}
+		ids = append(ids, deckID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over subdecks: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return nil, models.NotFound("deck with id %d not found", id)
+	}
+
+	return ids, nil
+}
+
+// GetTerminology returns the terminology of the user's decks among deckIDs.
+// Decks without a saved dictionary are left out.
+func (r *SQLiteDeckRepository) GetTerminology(ctx context.Context, userID int, deckIDs []int) ([]*models.DeckTerminology, error) {
+	query := `
+		SELECT dt.deck_id, dt.preferredTerms, dt.abbreviations, dt.doNotSimplify, dt.stopWords, dt.updatedAt 
+		FROM deck_terminology dt 
+		JOIN decks d ON d.id = dt.deck_id 
+		WHERE d.user_id = ? AND dt.deck_id IN (SELECT value FROM json_each(?)) 
+		ORDER BY dt.deck_id ASC`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, sqliteJSON(deckIDs))
+	if err != nil {
+		return nil, models.Internal("failed to query deck terminology: %w", err)
+	}
+	defer rows.Close()
+
+	terminologies := make([]*models.DeckTerminology, 0)
+	for rows.Next() {
+		terminology := &models.DeckTerminology{}
+		err := rows.Scan(&terminology.DeckID, sqliteJSON(&terminology.PreferredTerms), sqliteJSON(&terminology.Abbreviations),
+			sqliteJSON(&terminology.DoNotSimplify), sqliteJSON(&terminology.StopWords), &terminology.UpdatedAt)
+		if err != nil {
+			return nil, models.Internal("failed to scan deck terminology: %w", err)
+		}
+		terminologies = append(terminologies, terminology)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over deck terminology: %w", err)
+	}
+
+	return terminologies, nil
+}
+
+// SaveTerminology replaces a deck's terminology dictionary
+func (r *SQLiteDeckRepository) SaveTerminology(ctx context.Context, terminology *models.DeckTerminology) error {
+	query := `
+		INSERT INTO deck_terminology (deck_id, preferredTerms, abbreviations, doNotSimplify, stopWords, updatedAt) 
+		VALUES (?, ?, ?, ?, ?, ?) 
+		ON CONFLICT (deck_id) DO UPDATE SET 
+			preferredTerms = excluded.preferredTerms, 
+			abbreviations = excluded.abbreviations, 
+			doNotSimplify = excluded.doNotSimplify, 
+			stopWords = excluded.stopWords, 
+			updatedAt = excluded.updatedAt`
+
+	now := time.Now().UTC()
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, terminology.DeckID, sqliteJSON(terminology.PreferredTerms), sqliteJSON(terminology.Abbreviations),
+		sqliteJSON(terminology.DoNotSimplify), sqliteJSON(terminology.StopWords), now)
+	if err != nil {
+		return models.Internal("failed to save deck terminology: %w", err)
+	}
+
+	terminology.UpdatedAt = now
+	return nil
+}
+
+// GetStudySettings returns the saved study settings of one of the user's decks
+func (r *SQLiteDeckRepository) GetStudySettings(ctx context.Context, userID, deckID int) (*models.DeckStudySettings, error) {
+	query := `
+		SELECT ss.deck_id, ss.learningSteps, ss.relearningSteps, ss.graduatingIntervalDays, ss.easyIntervalDays, 
+			ss.newCardsPerDay, ss.newCardOrder, ss.newCardPosition, ss.leechThreshold, ss.leechAction, ss.updatedAt 
+		FROM deck_study_settings ss 
+		JOIN decks d ON d.id = ss.deck_id 
+		WHERE ss.deck_id = ? AND d.user_id = ?`
+
+	settings := &models.DeckStudySettings{}
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, deckID, userID)
+
+	err := row.Scan(&settings.DeckID, sqliteJSON(&settings.LearningSteps), sqliteJSON(&settings.RelearningSteps),
+		&settings.GraduatingIntervalDays, &settings.EasyIntervalDays, &settings.NewCardsPerDay,
+		&settings.NewCardOrder, &settings.NewCardPosition, &settings.LeechThreshold, &settings.LeechAction, &settings.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("study settings for deck with id %d not found", deckID)
+		}
+		return nil, models.Internal("failed to get study settings: %w", err)
+	}
+
+	return settings, nil
+}
+
+// SaveStudySettings replaces a deck's study settings
+func (r *SQLiteDeckRepository) SaveStudySettings(ctx context.Context, settings *models.DeckStudySettings) error {
+	query := `
+		INSERT INTO deck_study_settings 
+			(deck_id, learningSteps, relearningSteps, graduatingIntervalDays, easyIntervalDays, newCardsPerDay, newCardOrder, newCardPosition, 
+			leechThreshold, leechAction, updatedAt) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) 
+		ON CONFLICT (deck_id) DO UPDATE SET 
+			learningSteps = excluded.learningSteps, 
+			relearningSteps = excluded.relearningSteps, 
+			graduatingIntervalDays = excluded.graduatingIntervalDays, 
+			easyIntervalDays = excluded.easyIntervalDays, 
+			newCardsPerDay = excluded.newCardsPerDay, 
+			newCardOrder = excluded.newCardOrder, 
+			newCardPosition = excluded.newCardPosition, 
+			leechThreshold = excluded.leechThreshold, 
+			leechAction = excluded.leechAction, 
+			updatedAt = excluded.updatedAt`
+
+	now := time.Now().UTC()
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, settings.DeckID, sqliteJSON(settings.LearningSteps), sqliteJSON(settings.RelearningSteps),
+		settings.GraduatingIntervalDays, settings.EasyIntervalDays, settings.NewCardsPerDay,
+		settings.NewCardOrder, settings.NewCardPosition, settings.LeechThreshold, settings.LeechAction, now)
+	if err != nil {
+		return models.Internal("failed to save study settings: %w", err)
+	}
+
+	settings.UpdatedAt = now
+	return nil
+}
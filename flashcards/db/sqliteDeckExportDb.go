@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteDeckExportRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteDeckExportRepository(db *sql.DB) *SQLiteDeckExportRepository {
+	return &SQLiteDeckExportRepository{db: db}
+}
+
+func (r *SQLiteDeckExportRepository) CreateExport(ctx context.Context, export *models.StoredDeckExport) error {
+	query := `
+		INSERT INTO deck_exports (user_id, deck_id, format, filename, contentType, size, storageKey, expiresAt, createdAt)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	now := time.Now().UTC()
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, export.UserID, export.DeckID, export.Format, export.Filename,
+		export.ContentType, export.Size, export.StorageKey, export.ExpiresAt.UTC(), now)
+	if err != nil {
+		return models.Internal("failed to create deck export: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Internal("failed to create deck export: %w", err)
+	}
+
+	export.ID, export.CreatedAt = int(id), now
+	return nil
+}
+
+// GetExport returns one of the user's exports that has not expired
+func (r *SQLiteDeckExportRepository) GetExport(ctx context.Context, userID, id int) (*models.StoredDeckExport, error) {
+	query := `
+		SELECT ` + deckExportColumns + `
+		FROM deck_exports
+		WHERE id = ? AND user_id = ? AND expiresAt > ?`
+
+	export, err := scanDeckExport(executor(ctx, r.db).QueryRowContext(ctx, query, id, userID, time.Now().UTC()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("deck export with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get deck export: %w", err)
+	}
+
+	return export, nil
+}
+
+// GetExports returns the user's exports that have not expired, newest
+// first
+func (r *SQLiteDeckExportRepository) GetExports(ctx context.Context, userID int) ([]*models.StoredDeckExport, error) {
+	query := `
+		SELECT ` + deckExportColumns + `
+		FROM deck_exports
+		WHERE user_id = ? AND expiresAt > ?
+		ORDER BY createdAt DESC`
+
+	return r.queryExports(ctx, query, userID, time.Now().UTC())
+}
+
+// GetExpiredExports returns up to limit exports that expired by now,
+// oldest first
+func (r *SQLiteDeckExportRepository) GetExpiredExports(ctx context.Context, now time.Time, limit int) ([]*models.StoredDeckExport, error) {
+	query := `
+		SELECT ` + deckExportColumns + `
+		FROM deck_exports
+		WHERE expiresAt <= ?
+		ORDER BY expiresAt
+		LIMIT ?`
+
+	return r.queryExports(ctx, query, now.UTC(), limit)
+}
+
+func (r *SQLiteDeckExportRepository) queryExports(ctx context.Context, query string, args ...any) ([]*models.StoredDeckExport, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Internal("failed to get deck exports: %w", err)
+	}
+	defer rows.Close()
+
+	var exports []*models.StoredDeckExport
+	for rows.Next() {
+		export, err := scanDeckExport(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan deck export: %w", err)
+		}
+		exports = append(exports, export)
+	}
+
+	return exports, rows.Err()
+}
+
+func (r *SQLiteDeckExportRepository) DeleteExport(ctx context.Context, id int) error {
+	query := `DELETE FROM deck_exports WHERE id = ?`
+
+	if _, err := executor(ctx, r.db).ExecContext(ctx, query, id); err != nil {
+		return models.Internal("failed to delete deck export: %w", err)
+	}
+
+	return nil
+}
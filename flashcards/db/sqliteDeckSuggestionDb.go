@@ -0,0 +1,215 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteDeckSuggestionRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteDeckSuggestionRepository(db *sql.DB) *SQLiteDeckSuggestionRepository {
+	return &SQLiteDeckSuggestionRepository{db: db}
+}
+
+// FindSuggestions analyzes the cards of every deck, as
+// PostgresDeckSuggestionRepository's FindSuggestions documents
+func (r *SQLiteDeckSuggestionRepository) FindSuggestions(ctx context.Context, leechLapses int) ([]*models.DeckSuggestion, error) {
+	query := `
+		SELECT user_id, deck_id, 'duplicate', json_group_array(id ORDER BY id), '[]'
+		FROM flashcards
+		WHERE deck_id IS NOT NULL
+		GROUP BY user_id, deck_id, LOWER(TRIM(content))
+		HAVING COUNT(*) > 1
+		UNION ALL
+		SELECT f.user_id, f.deck_id, 'leech', json_array(f.id), '[]'
+		FROM flashcards f
+		JOIN flashcard_schedules s ON s.flashcard_id = f.id
+		WHERE f.deck_id IS NOT NULL AND s.lapses >= ?
+		UNION ALL
+		SELECT f.user_id, f.deck_id, 'stale', json_group_array(f.id ORDER BY f.id), json_array(n.id)
+		FROM flashcards f
+		JOIN notes n ON n.id = f.source_note_id
+		WHERE f.deck_id IS NOT NULL AND n.updatedAt > f.updatedAt
+		GROUP BY f.user_id, f.deck_id, n.id
+		UNION ALL
+		SELECT f.user_id, f.deck_id, 'untagged', json_group_array(f.id ORDER BY f.id), json_group_array(DISTINCT n.id ORDER BY n.id)
+		FROM flashcards f
+		JOIN notes n ON n.id = f.source_note_id
+		WHERE f.deck_id IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM note_tags nt WHERE nt.note_id = n.id)
+		GROUP BY f.user_id, f.deck_id`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, leechLapses)
+	if err != nil {
+		return nil, models.Internal("failed to find deck suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions := make([]*models.DeckSuggestion, 0)
+	for rows.Next() {
+		suggestion := &models.DeckSuggestion{Status: models.SuggestionOpen}
+		err := rows.Scan(&suggestion.UserID, &suggestion.DeckID, &suggestion.Kind,
+			sqliteJSON(&suggestion.FlashcardIDs), sqliteJSON(&suggestion.NoteIDs))
+		if err != nil {
+			return nil, models.Internal("failed to scan deck suggestion: %w", err)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over deck suggestions: %w", err)
+	}
+
+	return suggestions, nil
+}
+
+// ReplaceOpenSuggestions swaps all open suggestions for the given ones in
+// one transaction, skipping ones dismissed earlier, as
+// PostgresDeckSuggestionRepository's ReplaceOpenSuggestions documents
+func (r *SQLiteDeckSuggestionRepository) ReplaceOpenSuggestions(ctx context.Context, suggestions []*models.DeckSuggestion) (int, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return 0, models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM deck_suggestions WHERE status = 'open'"); err != nil {
+		return 0, models.Internal("failed to delete open deck suggestions: %w", err)
+	}
+
+	query := `
+		INSERT INTO deck_suggestions (user_id, deck_id, kind, flashcard_ids, note_ids, createdAt)
+		SELECT ?1, ?2, ?3, ?4, ?5, ?6
+		WHERE NOT EXISTS (
+			SELECT 1 FROM deck_suggestions
+			WHERE deck_id = ?2 AND kind = ?3 AND flashcard_ids = ?4 AND status = 'dismissed'
+		)`
+
+	now := time.Now().UTC()
+	stored := 0
+	for _, suggestion := range suggestions {
+		result, err := tx.ExecContext(ctx, query, suggestion.UserID, suggestion.DeckID, suggestion.Kind,
+			sqliteJSON(suggestion.FlashcardIDs), sqliteJSON(suggestion.NoteIDs), now)
+		if err != nil {
+			return 0, models.Internal("failed to store deck suggestion: %w", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return 0, models.Internal("failed to get rows affected: %w", err)
+		}
+		stored += int(rowsAffected)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, models.Internal("failed to commit deck suggestions: %w", err)
+	}
+
+	return stored, nil
+}
+
+// ListOpenSuggestions returns the deck's open suggestions by kind, then
+// oldest card first
+func (r *SQLiteDeckSuggestionRepository) ListOpenSuggestions(ctx context.Context, userID, deckID int) ([]*models.DeckSuggestion, error) {
+	query := `
+		SELECT ` + deckSuggestionColumns + `
+		FROM deck_suggestions
+		WHERE user_id = ? AND deck_id = ? AND status = 'open'
+		ORDER BY kind, json_extract(flashcard_ids, '$[0]'), id`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, deckID)
+	if err != nil {
+		return nil, models.Internal("failed to query deck suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions := make([]*models.DeckSuggestion, 0)
+	for rows.Next() {
+		suggestion, err := scanSQLiteDeckSuggestion(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan deck suggestion: %w", err)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over deck suggestions: %w", err)
+	}
+
+	return suggestions, nil
+}
+
+func (r *SQLiteDeckSuggestionRepository) GetSuggestion(ctx context.Context, userID, deckID, id int) (*models.DeckSuggestion, error) {
+	query := "SELECT " + deckSuggestionColumns + " FROM deck_suggestions WHERE id = ? AND user_id = ? AND deck_id = ?"
+
+	suggestion, err := scanSQLiteDeckSuggestion(executor(ctx, r.db).QueryRowContext(ctx, query, id, userID, deckID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.NotFound("suggestion with id %d not found", id)
+	}
+	if err != nil {
+		return nil, models.Internal("failed to get deck suggestion: %w", err)
+	}
+
+	return suggestion, nil
+}
+
+// ResolveSuggestion marks an open suggestion applied or dismissed. One
+// resolved already is a conflict.
+func (r *SQLiteDeckSuggestionRepository) ResolveSuggestion(ctx context.Context, userID, deckID, id int, status string) (*models.DeckSuggestion, error) {
+	query := `
+		UPDATE deck_suggestions
+		SET status = ?, resolvedAt = ?
+		WHERE id = ? AND user_id = ? AND deck_id = ? AND status = 'open'
+		RETURNING ` + deckSuggestionColumns
+
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, status, time.Now().UTC(), id, userID, deckID)
+	suggestion, err := scanSQLiteDeckSuggestion(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		current, err := r.GetSuggestion(ctx, userID, deckID, id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, models.Conflict("suggestion %d is already %s", id, current.Status)
+	}
+	if err != nil {
+		return nil, models.Internal("failed to resolve deck suggestion: %w", err)
+	}
+
+	return suggestion, nil
+}
+
+// ResetSchedules drops the scheduling state of the user's cards, which
+// makes them new again
+func (r *SQLiteDeckSuggestionRepository) ResetSchedules(ctx context.Context, userID int, flashcardIDs []int) error {
+	query := `
+		DELETE FROM flashcard_schedules
+		WHERE flashcard_id IN (
+			SELECT id FROM flashcards
+			WHERE user_id = ? AND id IN (SELECT value FROM json_each(?))
+		)`
+
+	if _, err := executor(ctx, r.db).ExecContext(ctx, query, userID, sqliteJSON(flashcardIDs)); err != nil {
+		return models.Internal("failed to reset schedules: %w", err)
+	}
+
+	return nil
+}
+
+// scanSQLiteDeckSuggestion scans the columns of deckSuggestionColumns, whose
+// times come back as text from RETURNING
+func scanSQLiteDeckSuggestion(row interface{ Scan(...any) error }) (*models.DeckSuggestion, error) {
+	suggestion := &models.DeckSuggestion{}
+	err := row.Scan(&suggestion.ID, &suggestion.UserID, &suggestion.DeckID, &suggestion.Kind,
+		sqliteJSON(&suggestion.FlashcardIDs), sqliteJSON(&suggestion.NoteIDs), &suggestion.Status,
+		sqliteTime(&suggestion.CreatedAt), sqliteTime(&suggestion.ResolvedAt))
+	if err != nil {
+		return nil, err
+	}
+	return suggestion, nil
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteDigestRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteDigestRepository(db *sql.DB) *SQLiteDigestRepository {
+	return &SQLiteDigestRepository{db: db}
+}
+
+// GetDigestCards returns, for every user, the cards rated again in a review
+// between since and until or created in that window, ordered by user with
+// failed cards first
+func (r *SQLiteDigestRepository) GetDigestCards(ctx context.Context, since, until time.Time) ([]*models.DigestCard, error) {
+	query := `
+		WITH failed AS (
+			SELECT DISTINCT flashcard_id
+			FROM reviews
+			WHERE rating = 'again' AND reviewedAt >= ?1 AND reviewedAt < ?2
+		)
+		SELECT u.id, u.email, ` + flashcardColumns + `,
+			failed.flashcard_id IS NOT NULL
+		FROM flashcards f
+		JOIN users u ON u.id = f.user_id
+		LEFT JOIN failed ON failed.flashcard_id = f.id
+		WHERE failed.flashcard_id IS NOT NULL OR (f.createdAt >= ?1 AND f.createdAt < ?2)
+		ORDER BY u.id, failed.flashcard_id IS NULL, f.id`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, since.UTC(), until.UTC())
+	if err != nil {
+		return nil, models.Internal("failed to get digest cards: %w", err)
+	}
+	defer rows.Close()
+
+	cards := []*models.DigestCard{}
+	for rows.Next() {
+		card := &models.DigestCard{Flashcard: &models.Flashcard{}}
+		args := append([]any{&card.UserID, &card.Email}, flashcardScanArgs(card.Flashcard)...)
+		err := rows.Scan(append(args, &card.Failed)...)
+		if err != nil {
+			return nil, models.Internal("failed to scan digest card: %w", err)
+		}
+		cards = append(cards, card)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, models.Internal("failed to iterate digest cards: %w", err)
+	}
+
+	return cards, nil
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteEmbedRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteEmbedRepository(db *sql.DB) *SQLiteEmbedRepository {
+	return &SQLiteEmbedRepository{db: db}
+}
+
+func (r *SQLiteEmbedRepository) CreateEmbed(ctx context.Context, embed *models.Embed, tokenHash string) error {
+	query := `
+		INSERT INTO embeds (user_id, flashcard_id, deck_id, tokenHash, createdAt)
+		VALUES (?, ?, ?, ?, ?)`
+
+	now := time.Now().UTC()
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, embed.UserID, embed.FlashcardID, embed.DeckID, tokenHash, now)
+	if err != nil {
+		return models.Internal("failed to create embed: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Internal("failed to create embed: %w", err)
+	}
+	embed.ID, embed.CreatedAt = int(id), now
+	embed.Kind = embedKind(embed)
+
+	return nil
+}
+
+func (r *SQLiteEmbedRepository) GetEmbedByTokenHash(ctx context.Context, tokenHash string) (*models.Embed, error) {
+	query := `
+		SELECT id, user_id, flashcard_id, deck_id, createdAt
+		FROM embeds
+		WHERE tokenHash = ?`
+
+	embed := &models.Embed{}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, tokenHash).Scan(&embed.ID, &embed.UserID, &embed.FlashcardID, &embed.DeckID, &embed.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("embed not found")
+		}
+		return nil, models.Internal("failed to get embed: %w", err)
+	}
+	embed.Kind = embedKind(embed)
+
+	return embed, nil
+}
+
+func (r *SQLiteEmbedRepository) ListEmbeds(ctx context.Context, userID int) ([]*models.Embed, error) {
+	query := `
+		SELECT id, user_id, flashcard_id, deck_id, createdAt
+		FROM embeds
+		WHERE user_id = ?
+		ORDER BY createdAt DESC, id DESC`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, models.Internal("failed to list embeds: %w", err)
+	}
+	defer rows.Close()
+
+	embeds := []*models.Embed{}
+	for rows.Next() {
+		embed := &models.Embed{}
+		if err := rows.Scan(&embed.ID, &embed.UserID, &embed.FlashcardID, &embed.DeckID, &embed.CreatedAt); err != nil {
+			return nil, models.Internal("failed to scan embed: %w", err)
+		}
+		embed.Kind = embedKind(embed)
+		embeds = append(embeds, embed)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over embeds: %w", err)
+	}
+
+	return embeds, nil
+}
+
+func (r *SQLiteEmbedRepository) DeleteEmbed(ctx context.Context, userID, id int) error {
+	query := "DELETE FROM embeds WHERE id = ? AND user_id = ?"
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return models.Internal("failed to delete embed: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("embed with id %d not found", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,249 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+const sqliteCreateFlashcardQuery = `
+		INSERT INTO flashcards (user_id, deck_id, front, back, hint, source_note_id, content, contentHash, createdAt, updatedAt) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// SQLiteFlashcardRepository keeps flashcards in a local SQLite file, next
+// to SQLiteNoteRepository's notes
+type SQLiteFlashcardRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteFlashcardRepository(db *sql.DB) *SQLiteFlashcardRepository {
+	return &SQLiteFlashcardRepository{db: db}
+}
+
+func (r *SQLiteFlashcardRepository) CreateFlashcard(ctx context.Context, flashcard *models.Flashcard) error {
+	if err := createSQLiteFlashcard(ctx, executor(ctx, r.db), flashcard); err != nil {
+		return models.Internal("failed to create flashcard: %w", err)
+	}
+
+	return nil
+}
+
+// CreateFlashcards inserts a batch of flashcards in a single transaction
+func (r *SQLiteFlashcardRepository) CreateFlashcards(ctx context.Context, flashcards []*models.Flashcard) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, flashcard := range flashcards {
+		if err := createSQLiteFlashcard(ctx, tx, flashcard); err != nil {
+			return models.Internal("failed to create flashcard: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit flashcards: %w", err)
+	}
+
+	return nil
+}
+
+func createSQLiteFlashcard(ctx context.Context, db queryer, flashcard *models.Flashcard) error {
+	syncFlashcardSides(flashcard)
+	now := time.Now().UTC()
+	args := append(createFlashcardArgs(flashcard), flashcardContentHash(flashcard.Content), now, now)
+
+	result, err := db.ExecContext(ctx, sqliteCreateFlashcardQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	flashcard.ID, flashcard.Version, flashcard.CreatedAt, flashcard.UpdatedAt = int(id), 1, now, now
+	return nil
+}
+
+// Warm opens the connection and prepares the single card read
+func (r *SQLiteFlashcardRepository) Warm(ctx context.Context) error {
+	stmt, err := r.db.PrepareContext(ctx, "SELECT "+flashcardColumns+" FROM flashcards f WHERE f.id = ? AND f.user_id = ?")
+	if err != nil {
+		return models.Internal("failed to prepare query: %w", err)
+	}
+	return stmt.Close()
+}
+
+func (r *SQLiteFlashcardRepository) GetFlashcardByID(ctx context.Context, userID, id int) (*models.Flashcard, error) {
+	query := "SELECT " + flashcardColumns + " FROM flashcards f WHERE f.id = ? AND f.user_id = ?"
+
+	flashcard := &models.Flashcard{}
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, id, userID)
+
+	err := row.Scan(flashcardScanArgs(flashcard)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("flashcard with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get flashcard: %w", err)
+	}
+
+	return flashcard, nil
+}
+
+// ListFlashcards returns one page of the user's flashcards matching the
+// filter, along with the total number of matching flashcards
+func (r *SQLiteFlashcardRepository) ListFlashcards(ctx context.Context, userID int, filter models.FlashcardFilter, params models.ListParams) ([]*models.Flashcard, int, error) {
+	where := " WHERE f.user_id = ?"
+	args := []any{userID}
+
+	if filter.DeckID != nil {
+		where += " AND f.deck_id = ?"
+		args = append(args, *filter.DeckID)
+	}
+
+	if filter.Query != "" {
+		where += ` AND f.content LIKE ? ESCAPE '\'`
+		args = append(args, likePattern(filter.Query))
+	}
+
+	var total int
+	if err := executor(ctx, r.db).QueryRowContext(ctx, "SELECT COUNT(*) FROM flashcards f"+where, args...).Scan(&total); err != nil {
+		return nil, 0, models.Internal("failed to count flashcards: %w", err)
+	}
+
+	orderBy, err := orderByClause("f", params)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := "SELECT " + flashcardColumns + " FROM flashcards f" + where + orderBy + " LIMIT ? OFFSET ?"
+
+	flashcards, err := r.queryFlashcards(ctx, query, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return flashcards, total, nil
+}
+
+// GetFlashcardsByDecks returns all of the user's flashcards in deckIDs,
+// oldest first
+func (r *SQLiteFlashcardRepository) GetFlashcardsByDecks(ctx context.Context, userID int, deckIDs []int) ([]*models.Flashcard, error) {
+	if len(deckIDs) == 0 {
+		return []*models.Flashcard{}, nil
+	}
+
+	query := "SELECT " + flashcardColumns + " FROM flashcards f WHERE f.user_id = ? AND f.deck_id IN (" +
+		sqlitePlaceholders(len(deckIDs)) + ") ORDER BY f.id ASC"
+
+	args := []any{userID}
+	for _, deckID := range deckIDs {
+		args = append(args, deckID)
+	}
+
+	return r.queryFlashcards(ctx, query, args...)
+}
+
+// GetFlashcardIDsByContentHash maps each hash that matches one of the
+// user's flashcards to the oldest such flashcard's ID
+func (r *SQLiteFlashcardRepository) GetFlashcardIDsByContentHash(ctx context.Context, userID int, hashes []string) (map[string]int, error) {
+	ids := make(map[string]int)
+	if len(hashes) == 0 {
+		return ids, nil
+	}
+
+	query := "SELECT contentHash, MIN(id) FROM flashcards WHERE user_id = ? AND contentHash IN (" +
+		sqlitePlaceholders(len(hashes)) + ") GROUP BY contentHash"
+
+	args := []any{userID}
+	for _, hash := range hashes {
+		args = append(args, hash)
+	}
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Internal("failed to query flashcards: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hash string
+		var id int
+		if err := rows.Scan(&hash, &id); err != nil {
+			return nil, models.Internal("failed to scan flashcard: %w", err)
+		}
+		ids[hash] = id
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over flashcards: %w", err)
+	}
+
+	return ids, nil
+}
+
+func (r *SQLiteFlashcardRepository) queryFlashcards(ctx context.Context, query string, args ...any) ([]*models.Flashcard, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Internal("failed to query flashcards: %w", err)
+	}
+	defer rows.Close()
+
+	flashcards := make([]*models.Flashcard, 0)
+	for rows.Next() {
+		flashcard := &models.Flashcard{}
+		err := rows.Scan(flashcardScanArgs(flashcard)...)
+		if err != nil {
+			return nil, models.Internal("failed to scan flashcard: %w", err)
+		}
+		flashcards = append(flashcards, flashcard)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over flashcards: %w", err)
+	}
+
+	return flashcards, nil
+}
+
+// UpdateFlashcard applies the updates if the flashcard is still at version,
+// and bumps its version. New content gets a new contentHash.
+func (r *SQLiteFlashcardRepository) UpdateFlashcard(ctx context.Context, userID, id, version int, updates map[string]any) error {
+	if content, ok := updates["content"].(string); ok {
+		withHash := make(map[string]any, len(updates)+1)
+		for field, value := range updates {
+			withHash[field] = value
+		}
+		withHash["contentHash"] = flashcardContentHash(content)
+		updates = withHash
+	}
+
+	return sqliteUpdate(ctx, r.db, "flashcards", "flashcard", userID, id, version, updates)
+}
+
+func (r *SQLiteFlashcardRepository) DeleteFlashcard(ctx context.Context, userID, id int) error {
+	query := "DELETE FROM flashcards WHERE id = ? AND user_id = ?"
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return models.Internal("failed to delete flashcard: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("flashcard with id %d not found", id)
+	}
+
+	return nil
+}
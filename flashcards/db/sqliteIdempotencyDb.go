@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteIdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteIdempotencyRepository(db *sql.DB) *SQLiteIdempotencyRepository {
+	return &SQLiteIdempotencyRepository{db: db}
+}
+
+// ReserveKey claims the key for a new request, as
+// PostgresIdempotencyRepository's ReserveKey documents
+func (r *SQLiteIdempotencyRepository) ReserveKey(ctx context.Context, record *models.IdempotencyRecord, staleBefore time.Time) (bool, error) {
+	query := `
+		INSERT INTO idempotency_keys (user_id, idempotencyKey, requestHash, expiresAt, createdAt)
+		VALUES (?1, ?2, ?3, ?4, ?6)
+		ON CONFLICT (user_id, idempotencyKey) DO UPDATE
+		SET requestHash = excluded.requestHash, statusCode = NULL, responseBody = NULL,
+			createdAt = excluded.createdAt, expiresAt = excluded.expiresAt
+		WHERE idempotency_keys.expiresAt < ?6
+			OR (idempotency_keys.statusCode IS NULL AND idempotency_keys.createdAt < ?5)
+		RETURNING createdAt`
+
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, record.UserID, record.Key, record.RequestHash,
+		record.ExpiresAt.UTC(), staleBefore.UTC(), time.Now().UTC()).Scan(sqliteTime(&record.CreatedAt))
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, models.Internal("failed to reserve idempotency key: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *SQLiteIdempotencyRepository) GetKey(ctx context.Context, userID int, key string) (*models.IdempotencyRecord, error) {
+	query := `
+		SELECT user_id, idempotencyKey, requestHash, statusCode, responseBody, createdAt, expiresAt
+		FROM idempotency_keys
+		WHERE user_id = ? AND idempotencyKey = ?`
+
+	record := &models.IdempotencyRecord{}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, userID, key).Scan(&record.UserID, &record.Key, &record.RequestHash,
+		&record.StatusCode, &record.ResponseBody, &record.CreatedAt, &record.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("idempotency key %s not found", key)
+		}
+		return nil, models.Internal("failed to get idempotency key: %w", err)
+	}
+
+	return record, nil
+}
+
+// CompleteKey stores the response to replay for the key
+func (r *SQLiteIdempotencyRepository) CompleteKey(ctx context.Context, userID int, key string, statusCode int, body []byte) error {
+	query := "UPDATE idempotency_keys SET statusCode = ?, responseBody = ? WHERE user_id = ? AND idempotencyKey = ?"
+
+	if _, err := executor(ctx, r.db).ExecContext(ctx, query, statusCode, body, userID, key); err != nil {
+		return models.Internal("failed to save idempotent response: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteIdempotencyRepository) DeleteKey(ctx context.Context, userID int, key string) error {
+	query := "DELETE FROM idempotency_keys WHERE user_id = ? AND idempotencyKey = ?"
+
+	if _, err := executor(ctx, r.db).ExecContext(ctx, query, userID, key); err != nil {
+		return models.Internal("failed to delete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteIdempotencyRepository) DeleteExpiredKeys(ctx context.Context) (int64, error) {
+	query := "DELETE FROM idempotency_keys WHERE expiresAt < ?"
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, time.Now().UTC())
+	if err != nil {
+		return 0, models.Internal("failed to delete expired idempotency keys: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, models.Internal("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,321 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+// Tables that import job items are committed into, by job kind
+var sqliteImportTargetTables = map[string]string{
+	models.ImportKindNotes: "notes",
+}
+
+const sqliteImportJobColumns = `id, user_id, kind, status, deck_id, chunkSize, totalItems, processedItems, committedChunks,
+			skipped, error, createdAt, updatedAt, completedAt`
+
+type SQLiteImportJobRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteImportJobRepository(db *sql.DB) *SQLiteImportJobRepository {
+	return &SQLiteImportJobRepository{db: db}
+}
+
+// CreateImportJob stores the job together with all of its items in a single
+// transaction
+func (r *SQLiteImportJobRepository) CreateImportJob(ctx context.Context, job *models.ImportJob, items []models.ImportJobItem) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	jobQuery := `
+		INSERT INTO import_jobs (user_id, kind, status, deck_id, chunkSize, totalItems, skipped, createdAt, updatedAt)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	now := time.Now().UTC()
+	result, err := tx.ExecContext(ctx, jobQuery, job.UserID, job.Kind, job.Status, job.DeckID, job.ChunkSize, job.TotalItems,
+		sqliteJSON(job.Skipped), now, now)
+	if err != nil {
+		return models.Internal("failed to create import job: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Internal("failed to create import job: %w", err)
+	}
+	job.ID, job.CreatedAt, job.UpdatedAt = int(id), now, now
+
+	itemQuery := `
+		INSERT INTO import_job_items (job_id, position, chunk, file, heading, content)
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+	for _, item := range items {
+		_, err := tx.ExecContext(ctx, itemQuery, job.ID, item.Position, item.Chunk, item.File, item.Heading, item.Content)
+		if err != nil {
+			return models.Internal("failed to create import job items: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit import job: %w", err)
+	}
+
+	job.TotalChunks = totalImportChunks(job)
+	return nil
+}
+
+func (r *SQLiteImportJobRepository) GetImportJob(ctx context.Context, userID, id int) (*models.ImportJob, error) {
+	query := `
+		SELECT ` + sqliteImportJobColumns + `
+		FROM import_jobs
+		WHERE id = ? AND user_id = ?`
+
+	job, err := scanSQLiteImportJob(executor(ctx, r.db).QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("import job with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get import job: %w", err)
+	}
+
+	return job, nil
+}
+
+func (r *SQLiteImportJobRepository) ListImportJobs(ctx context.Context, userID int) ([]*models.ImportJob, error) {
+	query := `
+		SELECT ` + sqliteImportJobColumns + `
+		FROM import_jobs
+		WHERE user_id = ?
+		ORDER BY createdAt DESC, id DESC`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, models.Internal("failed to query import jobs: %w", err)
+	}
+
+	return scanSQLiteImportJobs(rows)
+}
+
+// GetNextImportChunk returns the first chunk of the job with uncommitted
+// items and how many it has, or false once every chunk is committed
+func (r *SQLiteImportJobRepository) GetNextImportChunk(ctx context.Context, jobID int) (int, int, bool, error) {
+	query := `
+		SELECT chunk, COUNT(*)
+		FROM import_job_items
+		WHERE job_id = ? AND NOT committed
+		GROUP BY chunk
+		ORDER BY chunk
+		LIMIT 1`
+
+	var chunk, count int
+	if err := executor(ctx, r.db).QueryRowContext(ctx, query, jobID).Scan(&chunk, &count); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, 0, false, nil
+		}
+		return 0, 0, false, models.Internal("failed to get next import chunk: %w", err)
+	}
+
+	return chunk, count, true, nil
+}
+
+// CommitImportChunk creates the records for the chunk's uncommitted items
+// and advances the job's progress in one transaction, as
+// PostgresImportJobRepository's CommitImportChunk documents. The
+// transaction takes SQLite's write lock up front, so no row lock is needed.
+func (r *SQLiteImportJobRepository) CommitImportChunk(ctx context.Context, jobID, chunk int) (int, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return 0, models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID int
+	var deckID *int
+	var kind, status string
+	jobQuery := "SELECT user_id, deck_id, kind, status FROM import_jobs WHERE id = ?"
+	if err := tx.QueryRowContext(ctx, jobQuery, jobID).Scan(&userID, &deckID, &kind, &status); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, models.NotFound("import job with id %d not found", jobID)
+		}
+		return 0, models.Internal("failed to lock import job: %w", err)
+	}
+	if status != models.ImportJobStatusRunning {
+		return 0, fmt.Errorf("import job %d is no longer running", jobID)
+	}
+
+	table, ok := sqliteImportTargetTables[kind]
+	if !ok {
+		return 0, fmt.Errorf("unsupported import kind: %s", kind)
+	}
+
+	itemsQuery := `
+		SELECT position, content
+		FROM import_job_items
+		WHERE job_id = ? AND chunk = ? AND NOT committed
+		ORDER BY position`
+
+	rows, err := tx.QueryContext(ctx, itemsQuery, jobID, chunk)
+	if err != nil {
+		return 0, models.Internal("failed to query import job items: %w", err)
+	}
+	var items []models.ImportJobItem
+	for rows.Next() {
+		var item models.ImportJobItem
+		if err := rows.Scan(&item.Position, &item.Content); err != nil {
+			rows.Close()
+			return 0, models.Internal("failed to scan import job item: %w", err)
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating over import job items: %w", err)
+	}
+
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	insertQuery := fmt.Sprintf(`
+		INSERT INTO %s (user_id, deck_id, content, createdAt, updatedAt)
+		VALUES (?, ?, ?, ?, ?)`, table)
+	commitQuery := `
+		UPDATE import_job_items
+		SET committed = TRUE, recordId = ?
+		WHERE job_id = ? AND position = ?`
+
+	now := time.Now().UTC()
+	for _, item := range items {
+		result, err := tx.ExecContext(ctx, insertQuery, userID, deckID, item.Content, now, now)
+		if err != nil {
+			return 0, models.Internal("failed to import item %d: %w", item.Position, err)
+		}
+		recordID, err := result.LastInsertId()
+		if err != nil {
+			return 0, models.Internal("failed to import item %d: %w", item.Position, err)
+		}
+		if _, err := tx.ExecContext(ctx, commitQuery, recordID, jobID, item.Position); err != nil {
+			return 0, models.Internal("failed to commit import job item: %w", err)
+		}
+	}
+
+	progressQuery := `
+		UPDATE import_jobs
+		SET processedItems = processedItems + ?, committedChunks = committedChunks + 1, updatedAt = ?
+		WHERE id = ?`
+
+	if _, err := tx.ExecContext(ctx, progressQuery, len(items), now, jobID); err != nil {
+		return 0, models.Internal("failed to update import job progress: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, models.Internal("failed to commit import chunk: %w", err)
+	}
+
+	return len(items), nil
+}
+
+// ResumeImportJob moves a failed job back to running. Only one caller can
+// resume a job.
+func (r *SQLiteImportJobRepository) ResumeImportJob(ctx context.Context, userID, id int) error {
+	query := `
+		UPDATE import_jobs
+		SET status = ?, error = NULL, updatedAt = ?
+		WHERE id = ? AND user_id = ? AND status = ?`
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, models.ImportJobStatusRunning, time.Now().UTC(), id, userID,
+		models.ImportJobStatusFailed)
+	if err != nil {
+		return models.Internal("failed to resume import job: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		if _, err := r.GetImportJob(ctx, userID, id); err != nil {
+			return err
+		}
+		return models.Conflict("only a failed import job can be resumed")
+	}
+
+	return nil
+}
+
+// FinishImportJob completes or fails a running job. A job that is no
+// longer running, such as one already failed as stale, keeps its status.
+func (r *SQLiteImportJobRepository) FinishImportJob(ctx context.Context, id int, status string, message *string) error {
+	query := `
+		UPDATE import_jobs
+		SET status = ?1, error = ?2, updatedAt = ?3,
+			completedAt = CASE WHEN ?4 THEN ?3 ELSE completedAt END
+		WHERE id = ?5 AND status = ?6`
+
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, status, message, time.Now().UTC(),
+		status == models.ImportJobStatusCompleted, id, models.ImportJobStatusRunning)
+	if err != nil {
+		return models.Internal("failed to finish import job: %w", err)
+	}
+
+	return nil
+}
+
+// FailStaleImportJobs fails running jobs that have made no progress since
+// before, so they can be resumed. It returns the jobs it failed.
+func (r *SQLiteImportJobRepository) FailStaleImportJobs(ctx context.Context, before time.Time, message string) ([]*models.ImportJob, error) {
+	query := `
+		UPDATE import_jobs
+		SET status = ?, error = ?, updatedAt = ?
+		WHERE status = ? AND updatedAt < ?
+		RETURNING ` + sqliteImportJobColumns
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, models.ImportJobStatusFailed, message, time.Now().UTC(),
+		models.ImportJobStatusRunning, before.UTC())
+	if err != nil {
+		return nil, models.Internal("failed to fail stale import jobs: %w", err)
+	}
+
+	return scanSQLiteImportJobs(rows)
+}
+
+func scanSQLiteImportJobs(rows *sql.Rows) ([]*models.ImportJob, error) {
+	defer rows.Close()
+
+	jobs := make([]*models.ImportJob, 0)
+	for rows.Next() {
+		job, err := scanSQLiteImportJob(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan import job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over import jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// scanSQLiteImportJob scans the columns of sqliteImportJobColumns, whose
+// times come back as text from RETURNING
+func scanSQLiteImportJob(row interface{ Scan(...any) error }) (*models.ImportJob, error) {
+	job := &models.ImportJob{}
+	err := row.Scan(&job.ID, &job.UserID, &job.Kind, &job.Status, &job.DeckID, &job.ChunkSize, &job.TotalItems,
+		&job.ProcessedItems, &job.CommittedChunks, sqliteJSON(&job.Skipped), &job.Error, sqliteTime(&job.CreatedAt),
+		sqliteTime(&job.UpdatedAt), sqliteTime(&job.CompletedAt))
+	if err != nil {
+		return nil, err
+	}
+	job.TotalChunks = totalImportChunks(job)
+
+	return job, nil
+}
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteInviteRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteInviteRepository(db *sql.DB) *SQLiteInviteRepository {
+	return &SQLiteInviteRepository{db: db}
+}
+
+func (r *SQLiteInviteRepository) CreateInvite(ctx context.Context, invite *models.OrganizationInvite, tokenHash string) error {
+	query := `
+		INSERT INTO organization_invites (organization_id, email, role, tokenHash, invited_by, expiresAt, createdAt)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	now := time.Now().UTC()
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, invite.OrganizationID, invite.Email, invite.Role, tokenHash,
+		invite.InvitedBy, invite.ExpiresAt.UTC(), now)
+	if err != nil {
+		return models.Internal("failed to create invite: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Internal("failed to create invite: %w", err)
+	}
+
+	invite.ID, invite.CreatedAt = int(id), now
+	return nil
+}
+
+func (r *SQLiteInviteRepository) GetInviteByTokenHash(ctx context.Context, tokenHash string) (*models.OrganizationInvite, error) {
+	query := `
+		SELECT id, organization_id, email, role, invited_by, accepted_by, expiresAt, acceptedAt, createdAt
+		FROM organization_invites
+		WHERE tokenHash = ?`
+
+	invite := &models.OrganizationInvite{}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, tokenHash).Scan(&invite.ID, &invite.OrganizationID, &invite.Email, &invite.Role,
+		&invite.InvitedBy, &invite.AcceptedBy, &invite.ExpiresAt, &invite.AcceptedAt, &invite.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("invite not found")
+		}
+		return nil, models.Internal("failed to get invite: %w", err)
+	}
+
+	return invite, nil
+}
+
+// AcceptInvite marks the invite accepted and adds the user to its
+// organization in a single transaction. It fails when the invite was
+// already used or the user already belongs to an organization.
+func (r *SQLiteInviteRepository) AcceptInvite(ctx context.Context, invite *models.OrganizationInvite, userID int) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	inviteQuery := `
+		UPDATE organization_invites
+		SET acceptedAt = ?, accepted_by = ?
+		WHERE id = ? AND acceptedAt IS NULL`
+
+	now := time.Now().UTC()
+	result, err := tx.ExecContext(ctx, inviteQuery, now, userID, invite.ID)
+	if err != nil {
+		return models.Internal("failed to accept invite: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.Conflict("invite has already been accepted")
+	}
+	invite.AcceptedAt, invite.AcceptedBy = &now, &userID
+
+	memberQuery := `
+		UPDATE users
+		SET organization_id = ?, organizationRole = ?, updatedAt = ?
+		WHERE id = ? AND organization_id IS NULL`
+
+	result, err = tx.ExecContext(ctx, memberQuery, invite.OrganizationID, invite.Role, now, userID)
+	if err != nil {
+		return models.Internal("failed to add organization member: %w", err)
+	}
+
+	rowsAffected, err = result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.Conflict("you already belong to an organization")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit invite: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteJobRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteJobRepository(db *sql.DB) *SQLiteJobRepository {
+	return &SQLiteJobRepository{db: db}
+}
+
+func (r *SQLiteJobRepository) CreateJob(ctx context.Context, job *models.Job) error {
+	query := `
+		INSERT INTO jobs (user_id, kind, payload, status, nextAttemptAt, createdAt, updatedAt)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?5, ?5)
+		RETURNING ` + jobColumns
+
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, job.UserID, job.Kind, string(job.Payload), models.JobStatusQueued,
+		time.Now().UTC())
+	created, err := scanSQLiteJob(row)
+	if err != nil {
+		return models.Internal("failed to create job: %w", err)
+	}
+
+	*job = *created
+	return nil
+}
+
+func (r *SQLiteJobRepository) GetJob(ctx context.Context, userID, id int) (*models.Job, error) {
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE id = ? AND user_id = ?`
+
+	job, err := scanSQLiteJob(executor(ctx, r.db).QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("job with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get job: %w", err)
+	}
+
+	return job, nil
+}
+
+// ListJobs returns one page of the user's jobs matching the filter and the
+// total number that match
+func (r *SQLiteJobRepository) ListJobs(ctx context.Context, userID int, filter models.JobFilter, params models.ListParams) ([]*models.Job, int, error) {
+	where := ` WHERE j.user_id = ?1 AND (?2 = '' OR j.status = ?2) AND (?3 = '' OR j.kind = ?3)`
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM jobs j` + where
+	if err := executor(ctx, r.db).QueryRowContext(ctx, countQuery, userID, filter.Status, filter.Kind).Scan(&total); err != nil {
+		return nil, 0, models.Internal("failed to count jobs: %w", err)
+	}
+
+	orderBy, err := orderByClause("j", params)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := `SELECT ` + jobColumns + ` FROM jobs j` + where + orderBy + ` LIMIT ?4 OFFSET ?5`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, filter.Status, filter.Kind, params.Limit, params.Offset)
+	if err != nil {
+		return nil, 0, models.Internal("failed to query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]*models.Job, 0)
+	for rows.Next() {
+		job, err := scanSQLiteJob(rows)
+		if err != nil {
+			return nil, 0, models.Internal("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating over jobs: %w", err)
+	}
+
+	return jobs, total, nil
+}
+
+// ClaimNextJob marks the oldest due job running and holds it for lease, as
+// PostgresJobRepository's ClaimNextJob documents. SQLite has a single
+// writer, so the claim needs no row locks.
+func (r *SQLiteJobRepository) ClaimNextJob(ctx context.Context, lease time.Duration) (*models.Job, error) {
+	query := `
+		UPDATE jobs
+		SET status = ?1, nextAttemptAt = ?4,
+			startedAt = COALESCE(startedAt, ?3), updatedAt = ?3
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status IN (?1, ?2) AND nextAttemptAt <= ?3
+			ORDER BY nextAttemptAt, id
+			LIMIT 1
+		)
+		RETURNING ` + jobColumns
+
+	now := time.Now().UTC()
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, models.JobStatusRunning, models.JobStatusQueued, now, now.Add(lease))
+	job, err := scanSQLiteJob(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, models.Internal("failed to claim job: %w", err)
+	}
+
+	return job, nil
+}
+
+func (r *SQLiteJobRepository) ExtendJobLease(ctx context.Context, id int, lease time.Duration) error {
+	query := `
+		UPDATE jobs
+		SET nextAttemptAt = ?, updatedAt = ?
+		WHERE id = ? AND status = ?`
+
+	now := time.Now().UTC()
+	if _, err := executor(ctx, r.db).ExecContext(ctx, query, now.Add(lease), now, id, models.JobStatusRunning); err != nil {
+		return models.Internal("failed to extend job lease: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteJobRepository) CompleteJob(ctx context.Context, id int, result []byte) error {
+	query := `
+		UPDATE jobs
+		SET status = ?2, result = ?3, error = '', attempts = attempts + 1, nextAttemptAt = NULL,
+			completedAt = ?4, updatedAt = ?4
+		WHERE id = ?1`
+
+	var resultText *string
+	if result != nil {
+		text := string(result)
+		resultText = &text
+	}
+
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, id, models.JobStatusCompleted, resultText, time.Now().UTC())
+	if err != nil {
+		return models.Internal("failed to complete job: %w", err)
+	}
+
+	return nil
+}
+
+// FailJobAttempt counts a failed attempt and queues the job again at
+// nextAttemptAt. Without a next attempt the job has failed for good.
+func (r *SQLiteJobRepository) FailJobAttempt(ctx context.Context, id int, message string, nextAttemptAt *time.Time) error {
+	query := `
+		UPDATE jobs
+		SET attempts = attempts + 1, error = ?2, nextAttemptAt = ?3,
+			status = CASE WHEN ?3 IS NULL THEN ?4 ELSE ?5 END,
+			completedAt = CASE WHEN ?3 IS NULL THEN ?6 END, updatedAt = ?6
+		WHERE id = ?1`
+
+	var next *time.Time
+	if nextAttemptAt != nil {
+		utc := nextAttemptAt.UTC()
+		next = &utc
+	}
+
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, id, message, next, models.JobStatusFailed, models.JobStatusQueued,
+		time.Now().UTC())
+	if err != nil {
+		return models.Internal("failed to update job: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseJob queues a running job again straight away without counting an
+// attempt, for a worker that is shutting down
+func (r *SQLiteJobRepository) ReleaseJob(ctx context.Context, id int) error {
+	query := `
+		UPDATE jobs
+		SET status = ?2, nextAttemptAt = ?4, updatedAt = ?4
+		WHERE id = ?1 AND status = ?3`
+
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, id, models.JobStatusQueued, models.JobStatusRunning, time.Now().UTC())
+	if err != nil {
+		return models.Internal("failed to release job: %w", err)
+	}
+
+	return nil
+}
+
+// scanSQLiteJob scans the columns of jobColumns, whose times come back as
+// text from RETURNING
+func scanSQLiteJob(row interface{ Scan(...any) error }) (*models.Job, error) {
+	job := &models.Job{}
+	var payload, result []byte
+	err := row.Scan(&job.ID, &job.UserID, &job.Kind, &payload, &job.Status, &result, &job.Error, &job.Attempts,
+		sqliteTime(&job.CreatedAt), sqliteTime(&job.UpdatedAt), sqliteTime(&job.StartedAt), sqliteTime(&job.CompletedAt))
+	if err != nil {
+		return nil, err
+	}
+
+	job.Payload = payload
+	if result != nil {
+		job.Result = result
+	}
+	return job, nil
+}
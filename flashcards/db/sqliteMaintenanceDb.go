@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteMaintenanceRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteMaintenanceRepository(db *sql.DB) *SQLiteMaintenanceRepository {
+	return &SQLiteMaintenanceRepository{db: db}
+}
+
+func (r *SQLiteMaintenanceRepository) GetMaintenance(ctx context.Context) (*models.Maintenance, error) {
+	query := `
+		SELECT mode, message, startedAt, endsAt, updatedAt
+		FROM maintenance
+		WHERE id = 1`
+
+	maintenance := &models.Maintenance{}
+	row := executor(ctx, r.db).QueryRowContext(ctx, query)
+
+	err := row.Scan(&maintenance.Mode, &maintenance.Message, &maintenance.StartedAt, &maintenance.EndsAt, &maintenance.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &models.Maintenance{Mode: models.MaintenanceModeOff}, nil
+		}
+		return nil, models.Internal("failed to get maintenance: %w", err)
+	}
+
+	return maintenance, nil
+}
+
+func (r *SQLiteMaintenanceRepository) SaveMaintenance(ctx context.Context, maintenance *models.Maintenance) error {
+	query := `
+		INSERT INTO maintenance (id, mode, message, startedAt, endsAt, updatedAt)
+		VALUES (1, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE
+		SET mode = excluded.mode, message = excluded.message, startedAt = excluded.startedAt,
+			endsAt = excluded.endsAt, updatedAt = excluded.updatedAt`
+
+	now := time.Now().UTC()
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, maintenance.Mode, maintenance.Message, maintenance.StartedAt, maintenance.EndsAt, now)
+	if err != nil {
+		return models.Internal("failed to save maintenance: %w", err)
+	}
+
+	maintenance.UpdatedAt = now
+	return nil
+}
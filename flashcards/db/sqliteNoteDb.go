@@ -0,0 +1,239 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+// Notes are selected together with their tag names, like noteSelectQuery
+const sqliteNoteSelectQuery = `
+		SELECT n.id, n.user_id, n.deck_id, n.title, n.summary, n.source, n.language, n.content, n.version, n.createdAt, n.updatedAt, 
+			json_group_array(t.name ORDER BY t.name) FILTER (WHERE t.name IS NOT NULL) AS tags 
+		FROM notes n 
+		LEFT JOIN note_tags nt ON nt.note_id = n.id 
+		LEFT JOIN tags t ON t.id = nt.tag_id`
+
+const sqliteCreateNoteQuery = `
+		INSERT INTO notes (user_id, deck_id, title, source, language, content, createdAt, updatedAt) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+// SQLiteNoteRepository keeps notes in a local SQLite file, for running the
+// app without a Postgres server
+type SQLiteNoteRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteNoteRepository(db *sql.DB) *SQLiteNoteRepository {
+	return &SQLiteNoteRepository{db: db}
+}
+
+func (r *SQLiteNoteRepository) CreateNote(ctx context.Context, note *models.Note) error {
+	if err := createSQLiteNote(ctx, executor(ctx, r.db), note); err != nil {
+		return models.Internal("failed to create note: %w", err)
+	}
+
+	return nil
+}
+
+// CreateNotes inserts a batch of notes in a single transaction
+func (r *SQLiteNoteRepository) CreateNotes(ctx context.Context, notes []*models.Note) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, note := range notes {
+		if err := createSQLiteNote(ctx, tx, note); err != nil {
+			return models.Internal("failed to create note: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit notes: %w", err)
+	}
+
+	return nil
+}
+
+func createSQLiteNote(ctx context.Context, db queryer, note *models.Note) error {
+	now := time.Now().UTC()
+	result, err := db.ExecContext(ctx, sqliteCreateNoteQuery, note.UserID, note.DeckID, note.Title, note.Source, note.Language, note.Content, now, now)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	note.ID, note.Version, note.CreatedAt, note.UpdatedAt = int(id), 1, now, now
+	return nil
+}
+
+func (r *SQLiteNoteRepository) GetNoteByID(ctx context.Context, userID, id int) (*models.Note, error) {
+	query := sqliteNoteSelectQuery + `
+		WHERE n.id = ? AND n.user_id = ? 
+		GROUP BY n.id`
+
+	notes, err := r.queryNotes(ctx, query, id, userID)
+	if err != nil {
+		return nil, models.Internal("failed to get note: %w", err)
+	}
+	if len(notes) == 0 {
+		return nil, models.NotFound("note with id %d not found", id)
+	}
+
+	return notes[0], nil
+}
+
+func (r *SQLiteNoteRepository) GetAllNotes(ctx context.Context, userID int) ([]*models.Note, error) {
+	query := sqliteNoteSelectQuery + `
+		WHERE n.user_id = ? 
+		GROUP BY n.id 
+		ORDER BY n.createdAt DESC`
+
+	return r.queryNotes(ctx, query, userID)
+}
+
+// ListNotes returns one page of the user's notes matching the filter, along
+// with the total number of matching notes. The query is matched like ILIKE
+// for ASCII letters only, as SQLite's LIKE does not fold other case.
+func (r *SQLiteNoteRepository) ListNotes(ctx context.Context, userID int, filter models.NoteFilter, params models.ListParams) ([]*models.Note, int, error) {
+	where := " WHERE n.user_id = ?"
+	args := []any{userID}
+
+	if filter.Tag != "" {
+		where += ` AND n.id IN (
+			SELECT nt2.note_id 
+			FROM note_tags nt2 
+			JOIN tags t2 ON t2.id = nt2.tag_id 
+			WHERE t2.name = ?
+		)`
+		args = append(args, filter.Tag)
+	}
+
+	if filter.DeckID != nil {
+		where += " AND n.deck_id = ?"
+		args = append(args, *filter.DeckID)
+	}
+
+	if filter.Language != "" {
+		where += " AND n.language = ?"
+		args = append(args, filter.Language)
+	}
+
+	if filter.Query != "" {
+		pattern := likePattern(filter.Query)
+		where += ` AND (n.title LIKE ? ESCAPE '\' OR n.content LIKE ? ESCAPE '\')`
+		args = append(args, pattern, pattern)
+	}
+
+	var total int
+	if err := executor(ctx, r.db).QueryRowContext(ctx, "SELECT COUNT(*) FROM notes n"+where, args...).Scan(&total); err != nil {
+		return nil, 0, models.Internal("failed to count notes: %w", err)
+	}
+
+	orderBy, err := orderByClause("n", params)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := sqliteNoteSelectQuery + where + " GROUP BY n.id" + orderBy + " LIMIT ? OFFSET ?"
+
+	notes, err := r.queryNotes(ctx, query, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return notes, total, nil
+}
+
+// Warm opens the connection and prepares the note list read while
+// building prompts
+func (r *SQLiteNoteRepository) Warm(ctx context.Context) error {
+	stmt, err := r.db.PrepareContext(ctx, sqliteNoteSelectQuery+" WHERE n.user_id = ? GROUP BY n.id ORDER BY n.createdAt DESC")
+	if err != nil {
+		return models.Internal("failed to prepare query: %w", err)
+	}
+	return stmt.Close()
+}
+
+func (r *SQLiteNoteRepository) queryNotes(ctx context.Context, query string, args ...any) ([]*models.Note, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Internal("failed to query notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := make([]*models.Note, 0)
+	for rows.Next() {
+		note := &models.Note{}
+		err := rows.Scan(&note.ID, &note.UserID, &note.DeckID, &note.Title, &note.Summary, &note.Source, &note.Language, &note.Content, &note.Version, &note.CreatedAt, &note.UpdatedAt, sqliteJSON(&note.Tags))
+		if err != nil {
+			return nil, models.Internal("failed to scan note: %w", err)
+		}
+		notes = append(notes, note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over notes: %w", err)
+	}
+
+	return notes, nil
+}
+
+// UpdateNote applies the updates if the note is still at version, and bumps
+// its version. A note changed since is a conflict.
+func (r *SQLiteNoteRepository) UpdateNote(ctx context.Context, userID, id, version int, updates map[string]any) error {
+	return sqliteUpdate(ctx, r.db, "notes", "note", userID, id, version, updates)
+}
+
+// SetNoteSummary stores the summary of a note as long as its content is
+// still the content that was summarized. It leaves updatedAt alone.
+func (r *SQLiteNoteRepository) SetNoteSummary(ctx context.Context, id int, content, summary string) error {
+	query := "UPDATE notes SET summary = ? WHERE id = ? AND content = ?"
+
+	if _, err := executor(ctx, r.db).ExecContext(ctx, query, summary, id, content); err != nil {
+		return models.Internal("failed to set note summary: %w", err)
+	}
+
+	return nil
+}
+
+// SetNoteLanguage stores a language detected in the background, under the
+// same condition as SetNoteSummary
+func (r *SQLiteNoteRepository) SetNoteLanguage(ctx context.Context, id int, content, language string) error {
+	query := "UPDATE notes SET language = ? WHERE id = ? AND content = ?"
+
+	if _, err := executor(ctx, r.db).ExecContext(ctx, query, language, id, content); err != nil {
+		return models.Internal("failed to set note language: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteNoteRepository) DeleteNote(ctx context.Context, userID, id int) error {
+	query := "DELETE FROM notes WHERE id = ? AND user_id = ?"
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return models.Internal("failed to delete note: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("note with id %d not found", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"flashcards/models"
+)
+
+type SQLiteOnboardingRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteOnboardingRepository(db *sql.DB) *SQLiteOnboardingRepository {
+	return &SQLiteOnboardingRepository{db: db}
+}
+
+// GetOnboardingProgress counts the user's decks, notes, flashcards and
+// reviews, and the invites sent for their organization
+func (r *SQLiteOnboardingRepository) GetOnboardingProgress(ctx context.Context, userID int) (*models.OnboardingProgress, error) {
+	query := `
+		SELECT u.organization_id, COALESCE(u.organizationRole, ?2),
+			(SELECT COUNT(*) FROM decks WHERE user_id = u.id),
+			(SELECT COUNT(*) FROM notes WHERE user_id = u.id),
+			(SELECT COUNT(*) FROM flashcards WHERE user_id = u.id),
+			(SELECT COUNT(*) FROM reviews WHERE user_id = u.id),
+			(SELECT COUNT(*) FROM organization_invites WHERE organization_id = u.organization_id)
+		FROM users u
+		WHERE u.id = ?1`
+
+	var organizationID sql.NullInt64
+	var role string
+	progress := &models.OnboardingProgress{}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, userID, models.RoleMember).Scan(&organizationID, &role, &progress.Decks,
+		&progress.Notes, &progress.Flashcards, &progress.Reviews, &progress.InvitesSent)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("user with id %d not found", userID)
+		}
+		return nil, models.Internal("failed to get onboarding progress: %w", err)
+	}
+
+	if organizationID.Valid {
+		progress.Membership = &models.Membership{OrganizationID: int(organizationID.Int64), Role: role}
+	}
+
+	return progress, nil
+}
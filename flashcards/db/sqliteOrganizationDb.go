@@ -0,0 +1,342 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+const sqliteOrganizationSelect = `
+		SELECT o.id, o.name, o.plan, o.maxNotes, o.maxCards, o.monthlyLlmTokens,
+			o.stripeCustomerId, o.stripeSubscriptionId, o.subscriptionStatus, o.stripeEventAt, o.createdAt, o.updatedAt
+		FROM organizations o`
+
+type SQLiteOrganizationRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteOrganizationRepository(db *sql.DB) *SQLiteOrganizationRepository {
+	return &SQLiteOrganizationRepository{db: db}
+}
+
+func (r *SQLiteOrganizationRepository) CreateOrganization(ctx context.Context, organization *models.Organization) error {
+	query := `
+		INSERT INTO organizations (name, plan, maxNotes, maxCards, monthlyLlmTokens, createdAt, updatedAt)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	now := time.Now().UTC()
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, organization.Name, organization.Plan, organization.MaxNotesOverride,
+		organization.MaxCardsOverride, organization.MonthlyLLMTokensOverride, now, now)
+	if err != nil {
+		return models.Internal("failed to create organization: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Internal("failed to create organization: %w", err)
+	}
+
+	organization.ID, organization.CreatedAt, organization.UpdatedAt = int(id), now, now
+	return nil
+}
+
+func (r *SQLiteOrganizationRepository) GetOrganization(ctx context.Context, id int) (*models.Organization, error) {
+	query := sqliteOrganizationSelect + `
+		WHERE o.id = ?`
+
+	organization, err := scanOrganization(executor(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("organization with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get organization: %w", err)
+	}
+
+	return organization, nil
+}
+
+// GetUserOrganization returns the organization the user belongs to
+func (r *SQLiteOrganizationRepository) GetUserOrganization(ctx context.Context, userID int) (*models.Organization, error) {
+	query := sqliteOrganizationSelect + `
+		JOIN users u ON u.organization_id = o.id
+		WHERE u.id = ?`
+
+	organization, err := scanOrganization(executor(ctx, r.db).QueryRowContext(ctx, query, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("organization for user with id %d not found", userID)
+		}
+		return nil, models.Internal("failed to get organization: %w", err)
+	}
+
+	return organization, nil
+}
+
+// UpdateOrganization saves the organization's name, plan and overrides
+func (r *SQLiteOrganizationRepository) UpdateOrganization(ctx context.Context, organization *models.Organization) error {
+	query := `
+		UPDATE organizations
+		SET name = ?, plan = ?, maxNotes = ?, maxCards = ?, monthlyLlmTokens = ?, updatedAt = ?
+		WHERE id = ?`
+
+	now := time.Now().UTC()
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, organization.Name, organization.Plan, organization.MaxNotesOverride,
+		organization.MaxCardsOverride, organization.MonthlyLLMTokensOverride, now, organization.ID)
+	if err != nil {
+		return models.Internal("failed to update organization: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("organization with id %d not found", organization.ID)
+	}
+
+	organization.UpdatedAt = now
+	return nil
+}
+
+// GetOrganizationByStripeCustomer finds the organization billed to a Stripe
+// customer
+func (r *SQLiteOrganizationRepository) GetOrganizationByStripeCustomer(ctx context.Context, customerID string) (*models.Organization, error) {
+	query := sqliteOrganizationSelect + `
+		WHERE o.stripeCustomerId = ?`
+
+	organization, err := scanOrganization(executor(ctx, r.db).QueryRowContext(ctx, query, customerID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("organization for stripe customer %s not found", customerID)
+		}
+		return nil, models.Internal("failed to get organization: %w", err)
+	}
+
+	return organization, nil
+}
+
+// UpdateOrganizationBilling saves the organization's plan and Stripe
+// subscription state
+func (r *SQLiteOrganizationRepository) UpdateOrganizationBilling(ctx context.Context, organization *models.Organization) error {
+	query := `
+		UPDATE organizations
+		SET plan = ?, stripeCustomerId = ?, stripeSubscriptionId = ?, subscriptionStatus = ?, stripeEventAt = ?, updatedAt = ?
+		WHERE id = ?`
+
+	now := time.Now().UTC()
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, organization.Plan, organization.StripeCustomerID,
+		organization.StripeSubscriptionID, organization.SubscriptionStatus, organization.StripeEventAt, now, organization.ID)
+	if err != nil {
+		if isSQLiteUniqueViolation(err) && organization.StripeCustomerID != nil {
+			return models.Conflict("organization for stripe customer %s already exists", *organization.StripeCustomerID)
+		}
+		return models.Internal("failed to update organization billing: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("organization with id %d not found", organization.ID)
+	}
+
+	organization.UpdatedAt = now
+	return nil
+}
+
+// HasStripeEvent reports whether the Stripe event was already applied
+func (r *SQLiteOrganizationRepository) HasStripeEvent(ctx context.Context, eventID string) (bool, error) {
+	var exists bool
+	err := executor(ctx, r.db).QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM stripe_events WHERE id = ?)", eventID).Scan(&exists)
+	if err != nil {
+		return false, models.Internal("failed to check stripe event: %w", err)
+	}
+
+	return exists, nil
+}
+
+// RecordStripeEvent marks the Stripe event as applied
+func (r *SQLiteOrganizationRepository) RecordStripeEvent(ctx context.Context, eventID string) error {
+	query := "INSERT INTO stripe_events (id, processedAt) VALUES (?, ?) ON CONFLICT (id) DO NOTHING"
+
+	if _, err := executor(ctx, r.db).ExecContext(ctx, query, eventID, time.Now().UTC()); err != nil {
+		return models.Internal("failed to record stripe event: %w", err)
+	}
+
+	return nil
+}
+
+// CreateOwnedOrganization creates an organization with the user as its owner
+// in a single transaction. It fails when the user already belongs to an
+// organization.
+func (r *SQLiteOrganizationRepository) CreateOwnedOrganization(ctx context.Context, organization *models.Organization, ownerID int) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO organizations (name, plan, createdAt, updatedAt)
+		VALUES (?, ?, ?, ?)`
+
+	now := time.Now().UTC()
+	result, err := tx.ExecContext(ctx, query, organization.Name, organization.Plan, now, now)
+	if err != nil {
+		return models.Internal("failed to create organization: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Internal("failed to create organization: %w", err)
+	}
+	organization.ID, organization.CreatedAt, organization.UpdatedAt = int(id), now, now
+
+	ownerQuery := `
+		UPDATE users
+		SET organization_id = ?, organizationRole = ?, updatedAt = ?
+		WHERE id = ? AND organization_id IS NULL`
+
+	result, err = tx.ExecContext(ctx, ownerQuery, organization.ID, models.RoleOwner, now, ownerID)
+	if err != nil {
+		return models.Internal("failed to set organization owner: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.Conflict("you already belong to an organization")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit organization: %w", err)
+	}
+
+	return nil
+}
+
+// SetUserOrganization moves a user into an organization with the given
+// role, or out of any organization when organizationID is nil
+func (r *SQLiteOrganizationRepository) SetUserOrganization(ctx context.Context, userID int, organizationID *int, role string) error {
+	query := "UPDATE users SET organization_id = ?, organizationRole = ?, updatedAt = ? WHERE id = ?"
+
+	var roleValue *string
+	if organizationID != nil {
+		roleValue = &role
+	}
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, organizationID, roleValue, time.Now().UTC(), userID)
+	if err != nil {
+		return models.Internal("failed to update user organization: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("user with id %d not found", userID)
+	}
+
+	return nil
+}
+
+// GetMembership returns the user's organization and role
+func (r *SQLiteOrganizationRepository) GetMembership(ctx context.Context, userID int) (*models.Membership, error) {
+	query := `
+		SELECT organization_id, COALESCE(organizationRole, ?)
+		FROM users
+		WHERE id = ? AND organization_id IS NOT NULL`
+
+	membership := &models.Membership{}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, models.RoleMember, userID).Scan(&membership.OrganizationID, &membership.Role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("organization for user with id %d not found", userID)
+		}
+		return nil, models.Internal("failed to get membership: %w", err)
+	}
+
+	return membership, nil
+}
+
+// GetOrganizationUsage totals the notes, cards and month's LLM tokens of all
+// members of an organization
+func (r *SQLiteOrganizationRepository) GetOrganizationUsage(ctx context.Context, organizationID int, month time.Time) (*models.Usage, error) {
+	return r.getUsage(ctx, "SELECT id FROM users WHERE organization_id = ?", organizationID, month)
+}
+
+// GetUserUsage totals usage across the user's organization, or for the user
+// alone when they are not in one
+func (r *SQLiteOrganizationRepository) GetUserUsage(ctx context.Context, userID int, month time.Time) (*models.Usage, error) {
+	members := `
+		SELECT m.id FROM users m
+		JOIN users u ON m.id = u.id OR m.organization_id = u.organization_id
+		WHERE u.id = ?`
+	return r.getUsage(ctx, members, userID, month)
+}
+
+func (r *SQLiteOrganizationRepository) getUsage(ctx context.Context, members string, id int, month time.Time) (*models.Usage, error) {
+	query := `
+		WITH members AS (` + members + `)
+		SELECT
+			(SELECT COUNT(*) FROM notes WHERE user_id IN (SELECT id FROM members)),
+			(SELECT COUNT(*) FROM flashcards WHERE user_id IN (SELECT id FROM members)),
+			(SELECT COALESCE(SUM(tokens), 0) FROM llm_token_usage WHERE user_id IN (SELECT id FROM members) AND month = ?)`
+
+	usage := &models.Usage{}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, id, month.UTC()).Scan(&usage.Notes, &usage.Cards, &usage.LLMTokens)
+	if err != nil {
+		return nil, models.Internal("failed to get usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// AddLLMTokens adds to the user's token counts for the month and the hour
+// containing at. SQLite has no data-modifying CTEs, so the two counts are
+// written in one transaction.
+func (r *SQLiteOrganizationRepository) AddLLMTokens(ctx context.Context, userID int, at time.Time, tokens int) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	at = at.UTC()
+	month := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, time.UTC)
+	hour := at.Truncate(time.Hour)
+
+	monthlyQuery := `
+		INSERT INTO llm_token_usage (user_id, month, tokens)
+		VALUES (?, ?, ?)
+		ON CONFLICT (user_id, month) DO UPDATE SET tokens = tokens + excluded.tokens`
+
+	if _, err := tx.ExecContext(ctx, monthlyQuery, userID, month, tokens); err != nil {
+		return models.Internal("failed to record LLM tokens: %w", err)
+	}
+
+	hourlyQuery := `
+		INSERT INTO llm_token_usage_hourly (user_id, hour, tokens)
+		VALUES (?, ?, ?)
+		ON CONFLICT (user_id, hour) DO UPDATE SET tokens = tokens + excluded.tokens`
+
+	if _, err := tx.ExecContext(ctx, hourlyQuery, userID, hour, tokens); err != nil {
+		return models.Internal("failed to record LLM tokens: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit LLM tokens: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLitePromptTemplateRepository struct {
+	db *sql.DB
+}
+
+func NewSQLitePromptTemplateRepository(db *sql.DB) *SQLitePromptTemplateRepository {
+	return &SQLitePromptTemplateRepository{db: db}
+}
+
+// GetLatestPromptTemplates returns the highest version of each template
+func (r *SQLitePromptTemplateRepository) GetLatestPromptTemplates(ctx context.Context) ([]*models.PromptTemplate, error) {
+	query := `
+		SELECT name, version, template, createdAt
+		FROM prompt_templates p
+		WHERE version = (SELECT MAX(version) FROM prompt_templates WHERE name = p.name)
+		ORDER BY name`
+
+	return r.queryPromptTemplates(ctx, query)
+}
+
+// ListPromptTemplateVersions returns every version of a template, newest
+// first
+func (r *SQLitePromptTemplateRepository) ListPromptTemplateVersions(ctx context.Context, name string) ([]*models.PromptTemplate, error) {
+	query := `
+		SELECT name, version, template, createdAt
+		FROM prompt_templates
+		WHERE name = ?
+		ORDER BY version DESC`
+
+	return r.queryPromptTemplates(ctx, query, name)
+}
+
+// CreatePromptTemplateVersion stores template as the next version of name
+func (r *SQLitePromptTemplateRepository) CreatePromptTemplateVersion(ctx context.Context, name, template string) (*models.PromptTemplate, error) {
+	query := `
+		INSERT INTO prompt_templates (name, version, template, createdAt)
+		SELECT ?1, COALESCE(MAX(version), 0) + 1, ?2, ?3
+		FROM prompt_templates
+		WHERE name = ?1
+		RETURNING name, version, template, createdAt`
+
+	prompt := &models.PromptTemplate{Source: models.PromptSourceDatabase}
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, name, template, time.Now().UTC()).
+		Scan(&prompt.Name, &prompt.Version, &prompt.Template, sqliteTime(&prompt.CreatedAt))
+	if err != nil {
+		if isSQLiteUniqueViolation(err) {
+			return nil, models.Conflict("prompt template %s was changed at the same time; try again", name)
+		}
+		return nil, models.Internal("failed to create prompt template version: %w", err)
+	}
+
+	return prompt, nil
+}
+
+func (r *SQLitePromptTemplateRepository) queryPromptTemplates(ctx context.Context, query string, args ...any) ([]*models.PromptTemplate, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Internal("failed to query prompt templates: %w", err)
+	}
+	defer rows.Close()
+
+	prompts := make([]*models.PromptTemplate, 0)
+	for rows.Next() {
+		prompt, err := scanPromptTemplate(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan prompt template: %w", err)
+		}
+		prompts = append(prompts, prompt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over prompt templates: %w", err)
+	}
+
+	return prompts, nil
+}
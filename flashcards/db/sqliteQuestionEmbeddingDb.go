@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteQuestionEmbeddingRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteQuestionEmbeddingRepository(db *sql.DB) *SQLiteQuestionEmbeddingRepository {
+	return &SQLiteQuestionEmbeddingRepository{db: db}
+}
+
+// GetRecentQuestionEmbeddings returns the user's newest question embeddings
+// from the given model that share a note with noteIDs, or any note when
+// noteIDs is empty
+func (r *SQLiteQuestionEmbeddingRepository) GetRecentQuestionEmbeddings(ctx context.Context, userID int, noteIDs []int, model string, limit int) ([]*models.QuestionEmbedding, error) {
+	query := `
+		SELECT user_id, noteIds, questionText, model, embedding, createdAt
+		FROM question_embeddings
+		WHERE user_id = ?1 AND model = ?2
+			AND (json_array_length(?3) = 0 OR EXISTS (
+				SELECT 1 FROM json_each(noteIds)
+				WHERE value IN (SELECT value FROM json_each(?3))
+			))
+		ORDER BY createdAt DESC
+		LIMIT ?4`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, model, sqliteJSON(noteIDs), limit)
+	if err != nil {
+		return nil, models.Internal("failed to query question embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	embeddings := make([]*models.QuestionEmbedding, 0)
+	for rows.Next() {
+		embedding := &models.QuestionEmbedding{}
+		err := rows.Scan(&embedding.UserID, sqliteJSON(&embedding.NoteIDs), &embedding.QuestionText, &embedding.Model,
+			sqliteJSON(&embedding.Vector), &embedding.CreatedAt)
+		if err != nil {
+			return nil, models.Internal("failed to scan question embedding: %w", err)
+		}
+		embeddings = append(embeddings, embedding)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over question embeddings: %w", err)
+	}
+
+	return embeddings, nil
+}
+
+func (r *SQLiteQuestionEmbeddingRepository) SaveQuestionEmbedding(ctx context.Context, embedding *models.QuestionEmbedding) error {
+	query := `
+		INSERT INTO question_embeddings (user_id, noteIds, questionText, model, embedding, createdAt)
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+	now := time.Now().UTC()
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, embedding.UserID, sqliteJSON(embedding.NoteIDs), embedding.QuestionText,
+		embedding.Model, sqliteJSON(embedding.Vector), now)
+	if err != nil {
+		return models.Internal("failed to save question embedding: %w", err)
+	}
+
+	embedding.CreatedAt = now
+	return nil
+}
+
+func (r *SQLiteQuestionEmbeddingRepository) DeleteQuestionEmbeddingsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, "DELETE FROM question_embeddings WHERE createdAt < ?", before.UTC())
+	if err != nil {
+		return 0, models.Internal("failed to delete question embeddings: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, models.Internal("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,438 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteQuizSessionRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteQuizSessionRepository(db *sql.DB) *SQLiteQuizSessionRepository {
+	return &SQLiteQuizSessionRepository{db: db}
+}
+
+func (r *SQLiteQuizSessionRepository) CreateSession(ctx context.Context, session *models.QuizSession) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO quiz_sessions (user_id, status, currentPosition, createdAt, updatedAt)
+		VALUES (?, ?, ?, ?, ?)`
+
+	now := time.Now().UTC()
+	result, err := tx.ExecContext(ctx, query, session.UserID, session.Status, session.CurrentPosition, now, now)
+	if err != nil {
+		return models.Internal("failed to create quiz session: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Internal("failed to create quiz session: %w", err)
+	}
+	session.ID, session.CreatedAt, session.UpdatedAt = int(id), now, now
+
+	questionQuery := `
+		INSERT INTO quiz_session_questions (session_id, position, question, status, updatedAt)
+		VALUES (?, ?, ?, ?, ?)`
+
+	for i := range session.Questions {
+		question := &session.Questions[i]
+		_, err := tx.ExecContext(ctx, questionQuery, session.ID, question.Position, sqliteJSON(question.Question), question.Status, now)
+		if err != nil {
+			return models.Internal("failed to create session question: %w", err)
+		}
+		question.UpdatedAt = now
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit quiz session: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteQuizSessionRepository) GetSessionByID(ctx context.Context, userID, id int) (*models.QuizSession, error) {
+	query := `
+		SELECT id, user_id, status, currentPosition, createdAt, updatedAt
+		FROM quiz_sessions
+		WHERE id = ? AND user_id = ?`
+
+	session := &models.QuizSession{}
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, id, userID)
+
+	err := row.Scan(&session.ID, &session.UserID, &session.Status, &session.CurrentPosition, &session.CreatedAt, &session.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("quiz session with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get quiz session: %w", err)
+	}
+
+	questionsQuery := `
+		SELECT position, question, status, answer, flagged, timeSpentMs, updatedAt, attachment_id, grading
+		FROM quiz_session_questions
+		WHERE session_id = ?
+		ORDER BY position ASC`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, questionsQuery, id)
+	if err != nil {
+		return nil, models.Internal("failed to query session questions: %w", err)
+	}
+	defer rows.Close()
+
+	session.Questions = make([]models.SessionQuestion, 0)
+	for rows.Next() {
+		var question models.SessionQuestion
+		var attachmentID sql.NullInt64
+		err := rows.Scan(&question.Position, sqliteJSON(&question.Question), &question.Status, &question.Answer, &question.Flagged,
+			&question.TimeSpentMs, &question.UpdatedAt, &attachmentID, sqliteJSON(&question.Grading))
+		if err != nil {
+			return nil, models.Internal("failed to scan session question: %w", err)
+		}
+		if attachmentID.Valid {
+			id := int(attachmentID.Int64)
+			question.AttachmentID = &id
+		}
+		session.Questions = append(session.Questions, question)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over session questions: %w", err)
+	}
+
+	return session, nil
+}
+
+// GetSessionsUpdatedSince returns sessions with activity after the given
+// time, including their questions
+func (r *SQLiteQuizSessionRepository) GetSessionsUpdatedSince(ctx context.Context, userID int, since time.Time) ([]*models.QuizSession, error) {
+	query := `
+		SELECT id
+		FROM quiz_sessions
+		WHERE user_id = ? AND updatedAt >= ?
+		ORDER BY updatedAt ASC`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, since.UTC())
+	if err != nil {
+		return nil, models.Internal("failed to query quiz sessions: %w", err)
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, models.Internal("failed to scan quiz session: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over quiz sessions: %w", err)
+	}
+
+	sessions := make([]*models.QuizSession, 0, len(ids))
+	for _, id := range ids {
+		session, err := r.GetSessionByID(ctx, userID, id)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+func (r *SQLiteQuizSessionRepository) UpdateSession(ctx context.Context, userID, id int, updates map[string]any) error {
+	if err := checkUpdates("quiz_sessions", updates); err != nil {
+		return err
+	}
+
+	fields := make([]string, 0, len(updates))
+	args := make([]any, 0, len(updates)+3)
+	for field, value := range updates {
+		fields = append(fields, field+" = ?")
+		args = append(args, value)
+	}
+
+	query := "UPDATE quiz_sessions SET " + strings.Join(fields, ", ") + ", updatedAt = ? WHERE id = ? AND user_id = ?"
+	args = append(args, time.Now().UTC(), id, userID)
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return models.Internal("failed to update quiz session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("quiz session with id %d not found", id)
+	}
+
+	return nil
+}
+
+func (r *SQLiteQuizSessionRepository) UpdateSessionQuestion(ctx context.Context, userID, sessionID, position int, updates map[string]any) error {
+	if err := checkUpdates("quiz_session_questions", updates); err != nil {
+		return err
+	}
+
+	fields := make([]string, 0, len(updates))
+	args := make([]any, 0, len(updates)+4)
+	for field, value := range updates {
+		if grading, ok := value.(*models.AnswerGrading); ok {
+			value = sqliteJSON(grading)
+		}
+		fields = append(fields, field+" = ?")
+		args = append(args, value)
+	}
+
+	now := time.Now().UTC()
+	query := "UPDATE quiz_session_questions SET " + strings.Join(fields, ", ") +
+		", updatedAt = ? WHERE session_id = ? AND position = ? AND session_id IN (SELECT id FROM quiz_sessions WHERE user_id = ?)"
+	args = append(args, now, sessionID, position, userID)
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return models.Internal("failed to update session question: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("question %d in quiz session with id %d not found", position, sessionID)
+	}
+
+	// Touch the session so updatedAt reflects the latest activity
+	if _, err := executor(ctx, r.db).ExecContext(ctx, "UPDATE quiz_sessions SET updatedAt = ? WHERE id = ?", now, sessionID); err != nil {
+		return models.Internal("failed to update quiz session: %w", err)
+	}
+
+	return nil
+}
+
+// GetBankQuestions returns up to limit distinct questions from the user's
+// earlier sessions, in random order, as PostgresQuizSessionRepository's
+// GetBankQuestions documents
+func (r *SQLiteQuizSessionRepository) GetBankQuestions(ctx context.Context, userID int, noteIDs []int, difficulty, questionType string, limit int) ([]models.QuestionData, error) {
+	query := `
+		SELECT question FROM (
+			SELECT q.question,
+				ROW_NUMBER() OVER (PARTITION BY json_extract(q.question, '$.text') ORDER BY q.updatedAt DESC) AS latest
+			FROM quiz_session_questions q
+			JOIN quiz_sessions s ON s.id = q.session_id
+			WHERE s.user_id = ?1
+				AND (?2 = '' OR json_extract(q.question, '$.difficulty') = ?2)
+				AND (?3 = '' OR json_extract(q.question, '$.type') = ?3)
+				AND (json_array_length(?4) = 0 OR EXISTS (
+					SELECT 1 FROM json_each(q.question, '$.basedOnNotes') note
+					WHERE note.value IN (SELECT value FROM json_each(?4))
+				))
+		) bank
+		WHERE latest = 1
+		ORDER BY random()
+		LIMIT ?5`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, difficulty, questionType, sqliteJSON(noteIDs), limit)
+	if err != nil {
+		return nil, models.Internal("failed to query question bank: %w", err)
+	}
+	defer rows.Close()
+
+	questions := make([]models.QuestionData, 0)
+	for rows.Next() {
+		var question models.QuestionData
+		if err := rows.Scan(sqliteJSON(&question)); err != nil {
+			return nil, models.Internal("failed to scan bank question: %w", err)
+		}
+		questions = append(questions, question)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over bank questions: %w", err)
+	}
+
+	return questions, nil
+}
+
+// GetRecentGradedAnswers returns the user's latest graded answers, most
+// recent first, to questions on the same topics as the given notes, as
+// PostgresQuizSessionRepository's GetRecentGradedAnswers documents
+func (r *SQLiteQuizSessionRepository) GetRecentGradedAnswers(ctx context.Context, userID int, noteIDs []int, limit int) ([]models.GradedAnswer, error) {
+	query := `
+		SELECT COALESCE(json_extract(q.question, '$.difficulty'), ''), q.correct, q.updatedAt
+		FROM quiz_session_questions q
+		JOIN quiz_sessions s ON s.id = q.session_id
+		WHERE s.user_id = ?1 AND q.status = 'answered' AND q.correct IS NOT NULL
+			AND (json_array_length(?2) = 0 OR EXISTS (
+				SELECT 1 FROM json_each(q.question, '$.basedOnNotes') note
+				WHERE note.value IN (SELECT value FROM json_each(?2))
+					OR note.value IN (
+						SELECT topic.note_id
+						FROM note_tags topic
+						JOIN note_tags requested ON requested.tag_id = topic.tag_id
+						WHERE requested.note_id IN (SELECT value FROM json_each(?2))
+					)
+			))
+		ORDER BY q.updatedAt DESC
+		LIMIT ?3`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, sqliteJSON(noteIDs), limit)
+	if err != nil {
+		return nil, models.Internal("failed to get recent graded answers: %w", err)
+	}
+	defer rows.Close()
+
+	answers := make([]models.GradedAnswer, 0)
+	for rows.Next() {
+		var answer models.GradedAnswer
+		if err := rows.Scan(&answer.Difficulty, &answer.Correct, &answer.AnsweredAt); err != nil {
+			return nil, models.Internal("failed to scan graded answer: %w", err)
+		}
+		answers = append(answers, answer)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over graded answers: %w", err)
+	}
+
+	return answers, nil
+}
+
+func (r *SQLiteQuizSessionRepository) CreateQueuedSession(ctx context.Context, queued *models.QueuedQuizSession) error {
+	query := `
+		INSERT INTO queued_quiz_sessions (user_id, request, status, nextAttemptAt, createdAt, updatedAt)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING ` + queuedSessionColumns
+
+	now := time.Now().UTC()
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, queued.UserID, sqliteJSON(queued.Request), queued.Status, queued.NextAttemptAt, now, now)
+	created, err := scanSQLiteQueuedSession(row)
+	if err != nil {
+		return models.Internal("failed to queue quiz session: %w", err)
+	}
+
+	*queued = *created
+	return nil
+}
+
+func (r *SQLiteQuizSessionRepository) GetQueuedSession(ctx context.Context, userID, id int) (*models.QueuedQuizSession, error) {
+	query := `
+		SELECT ` + queuedSessionColumns + `
+		FROM queued_quiz_sessions
+		WHERE id = ? AND user_id = ?`
+
+	queued, err := scanSQLiteQueuedSession(executor(ctx, r.db).QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("queued quiz session with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get queued quiz session: %w", err)
+	}
+
+	return queued, nil
+}
+
+// ClaimDueQueuedSessions returns queued requests whose next attempt is due
+// and pushes that attempt back by lease. SQLite has a single writer, so no
+// two servers claim the same request.
+func (r *SQLiteQuizSessionRepository) ClaimDueQueuedSessions(ctx context.Context, limit int, lease time.Duration) ([]*models.QueuedQuizSession, error) {
+	query := `
+		UPDATE queued_quiz_sessions
+		SET nextAttemptAt = ?
+		WHERE id IN (
+			SELECT id FROM queued_quiz_sessions
+			WHERE status = ? AND nextAttemptAt <= ?
+			ORDER BY nextAttemptAt
+			LIMIT ?
+		)
+		RETURNING ` + queuedSessionColumns
+
+	now := time.Now().UTC()
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, now.Add(lease), models.QueuedSessionStatusQueued, now, limit)
+	if err != nil {
+		return nil, models.Internal("failed to claim queued quiz sessions: %w", err)
+	}
+	defer rows.Close()
+
+	queued := make([]*models.QueuedQuizSession, 0)
+	for rows.Next() {
+		item, err := scanSQLiteQueuedSession(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan queued quiz session: %w", err)
+		}
+		queued = append(queued, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over queued quiz sessions: %w", err)
+	}
+
+	return queued, nil
+}
+
+func (r *SQLiteQuizSessionRepository) CompleteQueuedSession(ctx context.Context, id, sessionID int) error {
+	query := `
+		UPDATE queued_quiz_sessions
+		SET status = ?, session_id = ?, error = '', nextAttemptAt = NULL, updatedAt = ?
+		WHERE id = ?`
+
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, models.QueuedSessionStatusCompleted, sessionID, time.Now().UTC(), id)
+	if err != nil {
+		return models.Internal("failed to complete queued quiz session: %w", err)
+	}
+
+	return nil
+}
+
+// FailQueuedSessionAttempt counts a failed attempt. Without a next attempt
+// the request has failed for good.
+func (r *SQLiteQuizSessionRepository) FailQueuedSessionAttempt(ctx context.Context, id int, message string, nextAttemptAt *time.Time) error {
+	query := `
+		UPDATE queued_quiz_sessions
+		SET attempts = attempts + 1, error = ?2, nextAttemptAt = ?3,
+			status = CASE WHEN ?3 IS NULL THEN ?4 ELSE ?5 END, updatedAt = ?6
+		WHERE id = ?1`
+
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, id, message, nextAttemptAt,
+		models.QueuedSessionStatusFailed, models.QueuedSessionStatusQueued, time.Now().UTC())
+	if err != nil {
+		return models.Internal("failed to update queued quiz session: %w", err)
+	}
+
+	return nil
+}
+
+// scanSQLiteQueuedSession scans the queuedSessionColumns. Its times come
+// through sqliteTime, as RETURNING gives them no column type.
+func scanSQLiteQueuedSession(row interface{ Scan(...any) error }) (*models.QueuedQuizSession, error) {
+	queued := &models.QueuedQuizSession{}
+	var sessionID sql.NullInt64
+	err := row.Scan(&queued.ID, &queued.UserID, sqliteJSON(&queued.Request), &queued.Status, &sessionID, &queued.Error,
+		&queued.Attempts, sqliteTime(&queued.NextAttemptAt), sqliteTime(&queued.CreatedAt), sqliteTime(&queued.UpdatedAt))
+	if err != nil {
+		return nil, err
+	}
+	if sessionID.Valid {
+		id := int(sessionID.Int64)
+		queued.SessionID = &id
+	}
+	return queued, nil
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteReportRecipientRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteReportRecipientRepository(db *sql.DB) *SQLiteReportRecipientRepository {
+	return &SQLiteReportRecipientRepository{db: db}
+}
+
+func (r *SQLiteReportRecipientRepository) CreateRecipient(ctx context.Context, recipient *models.ReportRecipient) error {
+	query := `
+		INSERT INTO report_recipients (user_id, email, createdAt)
+		VALUES (?, ?, ?)`
+
+	now := time.Now().UTC()
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, recipient.UserID, recipient.Email, now)
+	if err != nil {
+		return models.Internal("failed to create report recipient: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Internal("failed to create report recipient: %w", err)
+	}
+
+	recipient.ID, recipient.CreatedAt = int(id), now
+	return nil
+}
+
+func (r *SQLiteReportRecipientRepository) GetAllRecipients(ctx context.Context, userID int) ([]*models.ReportRecipient, error) {
+	query := `
+		SELECT id, user_id, email, createdAt
+		FROM report_recipients
+		WHERE user_id = ?
+		ORDER BY email ASC`
+
+	return r.queryRecipients(ctx, query, userID)
+}
+
+// GetRecipientsForAllUsers is used by the scheduled report job, which sends
+// each user's report to that user's recipients
+func (r *SQLiteReportRecipientRepository) GetRecipientsForAllUsers(ctx context.Context) ([]*models.ReportRecipient, error) {
+	query := `
+		SELECT id, user_id, email, createdAt
+		FROM report_recipients
+		WHERE user_id IS NOT NULL
+		ORDER BY user_id ASC, email ASC`
+
+	return r.queryRecipients(ctx, query)
+}
+
+func (r *SQLiteReportRecipientRepository) queryRecipients(ctx context.Context, query string, args ...any) ([]*models.ReportRecipient, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Internal("failed to query report recipients: %w", err)
+	}
+	defer rows.Close()
+
+	recipients := make([]*models.ReportRecipient, 0)
+	for rows.Next() {
+		recipient := &models.ReportRecipient{}
+		if err := rows.Scan(&recipient.ID, &recipient.UserID, &recipient.Email, &recipient.CreatedAt); err != nil {
+			return nil, models.Internal("failed to scan report recipient: %w", err)
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over report recipients: %w", err)
+	}
+
+	return recipients, nil
+}
+
+func (r *SQLiteReportRecipientRepository) DeleteRecipient(ctx context.Context, userID, id int) error {
+	query := "DELETE FROM report_recipients WHERE id = ? AND user_id = ?"
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return models.Internal("failed to delete report recipient: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("report recipient with id %d not found", id)
+	}
+
+	return nil
+}
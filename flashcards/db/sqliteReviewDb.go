@@ -0,0 +1,429 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+const sqliteDueCardsWhere = " WHERE f.user_id = ? AND COALESCE(s.dueAt, f.createdAt) <= ? AND NOT COALESCE(s.suspended, FALSE)"
+
+type SQLiteReviewRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteReviewRepository(db *sql.DB) *SQLiteReviewRepository {
+	return &SQLiteReviewRepository{db: db}
+}
+
+// GetDueCards returns up to limit of the user's cards that are due at now,
+// along with the total number due and how many of those are new, in the
+// order PostgresReviewRepository.GetDueCards documents. A nil deckIDs
+// covers all decks.
+func (r *SQLiteReviewRepository) GetDueCards(ctx context.Context, userID int, deckIDs []int, now time.Time, limit int) ([]*models.DueCard, int, int, error) {
+	where := sqliteDueCardsWhere
+	args := []any{userID, now.UTC()}
+
+	if deckIDs != nil {
+		where += " AND f.deck_id IN (SELECT value FROM json_each(?))"
+		args = append(args, sqliteJSON(deckIDs))
+	}
+
+	var total, newTotal int
+	countQuery := "SELECT COUNT(*), COUNT(*) FILTER (WHERE COALESCE(s.state, 'new') = 'new')" + sqliteDueCardsFrom + where
+	if err := executor(ctx, r.db).QueryRowContext(ctx, countQuery, args...).Scan(&total, &newTotal); err != nil {
+		return nil, 0, 0, models.Internal("failed to count due cards: %w", err)
+	}
+
+	query := dueCardsSelect + sqliteDueCardsFrom + where +
+		" ORDER BY COALESCE(s.state, 'new') = 'new' ASC, COALESCE(s.dueAt, f.createdAt) ASC, f.id ASC LIMIT ?"
+
+	cards, err := r.queryCards(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	return cards, total, newTotal, nil
+}
+
+// GetActiveUserIDs returns up to limit users who reviewed cards since the
+// given time, most recently active first
+func (r *SQLiteReviewRepository) GetActiveUserIDs(ctx context.Context, since time.Time, limit int) ([]int, error) {
+	query := `
+		SELECT user_id 
+		FROM reviews 
+		WHERE reviewedAt >= ? 
+		GROUP BY user_id 
+		ORDER BY MAX(reviewedAt) DESC 
+		LIMIT ?`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, since.UTC(), limit)
+	if err != nil {
+		return nil, models.Internal("failed to query active users: %w", err)
+	}
+	defer rows.Close()
+
+	userIDs := make([]int, 0)
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, models.Internal("failed to scan active user: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over active users: %w", err)
+	}
+
+	return userIDs, nil
+}
+
+// Warm opens the connection and prepares the due queue read
+func (r *SQLiteReviewRepository) Warm(ctx context.Context) error {
+	stmt, err := r.db.PrepareContext(ctx, dueCardsSelect+sqliteDueCardsFrom+sqliteDueCardsWhere+
+		" ORDER BY COALESCE(s.state, 'new') = 'new' ASC, COALESCE(s.dueAt, f.createdAt) ASC, f.id ASC LIMIT ?")
+	if err != nil {
+		return models.Internal("failed to prepare query: %w", err)
+	}
+	return stmt.Close()
+}
+
+// GetCramCards returns up to limit of the user's cards regardless of when
+// they are due, earliest due first, along with the total number of cards.
+// Suspended cards are left out. A nil deckIDs covers all decks.
+func (r *SQLiteReviewRepository) GetCramCards(ctx context.Context, userID int, deckIDs []int, limit int) ([]*models.DueCard, int, error) {
+	where := " WHERE f.user_id = ? AND NOT COALESCE(s.suspended, FALSE)"
+	args := []any{userID}
+
+	if deckIDs != nil {
+		where += " AND f.deck_id IN (SELECT value FROM json_each(?))"
+		args = append(args, sqliteJSON(deckIDs))
+	}
+
+	var total int
+	if err := executor(ctx, r.db).QueryRowContext(ctx, "SELECT COUNT(*)"+sqliteDueCardsFrom+where, args...).Scan(&total); err != nil {
+		return nil, 0, models.Internal("failed to count cram cards: %w", err)
+	}
+
+	query := dueCardsSelect + sqliteDueCardsFrom + where + " ORDER BY COALESCE(s.dueAt, f.createdAt) ASC, f.id ASC LIMIT ?"
+
+	cards, err := r.queryCards(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return cards, total, nil
+}
+
+// GetCardsByIDs returns the user's cards among flashcardIDs with their
+// schedules, earliest due first. Cards that no longer exist are skipped.
+func (r *SQLiteReviewRepository) GetCardsByIDs(ctx context.Context, userID int, flashcardIDs []int) ([]*models.DueCard, error) {
+	query := dueCardsSelect + sqliteDueCardsFrom +
+		" WHERE f.user_id = ? AND f.id IN (SELECT value FROM json_each(?)) ORDER BY COALESCE(s.dueAt, f.createdAt) ASC, f.id ASC"
+
+	return r.queryCards(ctx, query, userID, sqliteJSON(flashcardIDs))
+}
+
+const sqliteDueCardsFrom = `
+		FROM flashcards f 
+		LEFT JOIN flashcard_schedules s ON s.flashcard_id = f.id`
+
+func (r *SQLiteReviewRepository) queryCards(ctx context.Context, query string, args ...any) ([]*models.DueCard, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Internal("failed to query due cards: %w", err)
+	}
+	defer rows.Close()
+
+	cards := make([]*models.DueCard, 0)
+	for rows.Next() {
+		flashcard := &models.Flashcard{}
+		schedule := &models.Schedule{}
+		err := rows.Scan(append(flashcardScanArgs(flashcard),
+			&schedule.State, sqliteTime(&schedule.DueAt), &schedule.IntervalDays, &schedule.EaseFactor, &schedule.Repetitions, &schedule.Lapses,
+			&schedule.Step, &schedule.Leech, &schedule.Suspended, &schedule.LastReviewedAt, sqliteTime(&schedule.UpdatedAt))...)
+		if err != nil {
+			return nil, models.Internal("failed to scan due card: %w", err)
+		}
+		schedule.FlashcardID = flashcard.ID
+		cards = append(cards, &models.DueCard{Flashcard: flashcard, Schedule: schedule})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over due cards: %w", err)
+	}
+
+	return cards, nil
+}
+
+// CountNewCardsReviewed counts the user's cards reviewed for the first time
+// since the given time. A nil deckIDs covers all decks.
+func (r *SQLiteReviewRepository) CountNewCardsReviewed(ctx context.Context, userID int, deckIDs []int, since time.Time) (int, error) {
+	query := `
+		SELECT COUNT(DISTINCT rv.flashcard_id) 
+		FROM reviews rv 
+		JOIN flashcards f ON f.id = rv.flashcard_id 
+		WHERE rv.user_id = ? AND rv.mode = 'scheduled' AND rv.previousState = 'new' AND rv.reviewedAt >= ?`
+	args := []any{userID, since.UTC()}
+
+	if deckIDs != nil {
+		query += " AND f.deck_id IN (SELECT value FROM json_each(?))"
+		args = append(args, sqliteJSON(deckIDs))
+	}
+
+	var count int
+	if err := executor(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, models.Internal("failed to count new cards reviewed: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetSchedule returns the scheduling state of one of the user's flashcards
+func (r *SQLiteReviewRepository) GetSchedule(ctx context.Context, userID, flashcardID int) (*models.Schedule, error) {
+	query := `
+		SELECT s.flashcard_id, s.state, s.dueAt, s.intervalDays, s.easeFactor, s.repetitions, s.lapses, s.step, 
+			s.leech, s.suspended, s.lastReviewedAt, s.updatedAt 
+		FROM flashcard_schedules s 
+		JOIN flashcards f ON f.id = s.flashcard_id 
+		WHERE s.flashcard_id = ? AND f.user_id = ?`
+
+	schedule := &models.Schedule{}
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, flashcardID, userID)
+
+	err := row.Scan(&schedule.FlashcardID, &schedule.State, &schedule.DueAt, &schedule.IntervalDays, &schedule.EaseFactor,
+		&schedule.Repetitions, &schedule.Lapses, &schedule.Step, &schedule.Leech, &schedule.Suspended, &schedule.LastReviewedAt,
+		&schedule.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("schedule for flashcard with id %d not found", flashcardID)
+		}
+		return nil, models.Internal("failed to get schedule: %w", err)
+	}
+
+	return schedule, nil
+}
+
+// SaveReview records a review and stores the card's new schedule in a
+// single transaction. A nil schedule leaves the card's schedule untouched.
+func (r *SQLiteReviewRepository) SaveReview(ctx context.Context, review *models.Review, schedule *models.Schedule) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if schedule != nil {
+		if err := saveSQLiteSchedule(ctx, tx, schedule); err != nil {
+			return err
+		}
+	}
+
+	reviewQuery := `
+		INSERT INTO reviews 
+			(user_id, flashcard_id, mode, rating, previousState, previousSchedule, intervalDays, easeFactor, dueAt, reviewedAt, clientId) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := tx.ExecContext(ctx, reviewQuery, review.UserID, review.FlashcardID, review.Mode, review.Rating, review.PreviousState,
+		sqliteJSON(review.PreviousSchedule), review.IntervalDays, review.EaseFactor, review.DueAt.UTC(), review.ReviewedAt.UTC(), review.ClientID)
+	if err != nil {
+		if isSQLiteUniqueViolation(err) && review.ClientID != nil {
+			return models.Conflict("review with client id %s already exists", *review.ClientID)
+		}
+		return models.Internal("failed to save review: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Internal("failed to save review: %w", err)
+	}
+	review.ID = int(id)
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit review: %w", err)
+	}
+
+	return nil
+}
+
+// GetLatestReview returns the user's most recent review
+func (r *SQLiteReviewRepository) GetLatestReview(ctx context.Context, userID int) (*models.Review, error) {
+	query := sqliteReviewSelect + `
+		WHERE user_id = ? 
+		ORDER BY reviewedAt DESC, id DESC 
+		LIMIT 1`
+
+	review, err := scanSQLiteReview(executor(ctx, r.db).QueryRowContext(ctx, query, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("review to undo not found")
+		}
+		return nil, models.Internal("failed to get latest review: %w", err)
+	}
+
+	return review, nil
+}
+
+// GetReviewByClientID returns the review a client synced under clientID
+func (r *SQLiteReviewRepository) GetReviewByClientID(ctx context.Context, userID int, clientID string) (*models.Review, error) {
+	query := sqliteReviewSelect + `
+		WHERE user_id = ? AND clientId = ?`
+
+	review, err := scanSQLiteReview(executor(ctx, r.db).QueryRowContext(ctx, query, userID, clientID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("review with client id %s not found", clientID)
+		}
+		return nil, models.Internal("failed to get review: %w", err)
+	}
+
+	return review, nil
+}
+
+const sqliteReviewSelect = `
+		SELECT id, user_id, flashcard_id, mode, rating, previousState, previousSchedule, intervalDays, easeFactor, dueAt, reviewedAt, clientId 
+		FROM reviews`
+
+func scanSQLiteReview(row *sql.Row) (*models.Review, error) {
+	review := &models.Review{}
+
+	err := row.Scan(&review.ID, &review.UserID, &review.FlashcardID, &review.Mode, &review.Rating, &review.PreviousState,
+		sqliteJSON(&review.PreviousSchedule), &review.IntervalDays, &review.EaseFactor, &review.DueAt, &review.ReviewedAt, &review.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
+
+// UndoReview deletes a review and, for scheduled reviews, puts the card's
+// schedule back as it was before. A card that had never been scheduled
+// loses its schedule and is new again.
+func (r *SQLiteReviewRepository) UndoReview(ctx context.Context, review *models.Review) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := "DELETE FROM reviews WHERE id = ? AND user_id = ?"
+
+	result, err := tx.ExecContext(ctx, query, review.ID, review.UserID)
+	if err != nil {
+		return models.Internal("failed to delete review: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("review with id %d not found", review.ID)
+	}
+
+	if review.Mode == models.ReviewModeScheduled {
+		if review.PreviousSchedule != nil {
+			if err := saveSQLiteSchedule(ctx, tx, review.PreviousSchedule); err != nil {
+				return err
+			}
+		} else {
+			query := "DELETE FROM flashcard_schedules WHERE flashcard_id = ?"
+			if _, err := tx.ExecContext(ctx, query, review.FlashcardID); err != nil {
+				return models.Internal("failed to delete schedule: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit review undo: %w", err)
+	}
+
+	return nil
+}
+
+// Insert or replace a card's schedule
+func saveSQLiteSchedule(ctx context.Context, tx queryer, schedule *models.Schedule) error {
+	query := `
+		INSERT INTO flashcard_schedules 
+			(flashcard_id, state, dueAt, intervalDays, easeFactor, repetitions, lapses, step, leech, suspended, lastReviewedAt, updatedAt) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) 
+		ON CONFLICT (flashcard_id) DO UPDATE SET 
+			state = excluded.state, 
+			dueAt = excluded.dueAt, 
+			intervalDays = excluded.intervalDays, 
+			easeFactor = excluded.easeFactor, 
+			repetitions = excluded.repetitions, 
+			lapses = excluded.lapses, 
+			step = excluded.step, 
+			leech = excluded.leech, 
+			suspended = excluded.suspended, 
+			lastReviewedAt = excluded.lastReviewedAt, 
+			updatedAt = excluded.updatedAt`
+
+	now := time.Now().UTC()
+	_, err := tx.ExecContext(ctx, query, schedule.FlashcardID, schedule.State, schedule.DueAt.UTC(), schedule.IntervalDays,
+		schedule.EaseFactor, schedule.Repetitions, schedule.Lapses, schedule.Step, schedule.Leech, schedule.Suspended, schedule.LastReviewedAt, now)
+	if err != nil {
+		return models.Internal("failed to save schedule: %w", err)
+	}
+
+	schedule.UpdatedAt = now
+	return nil
+}
+
+// GetLeechCards returns the user's leeches with their schedules, most
+// lapses first. A nil deckIDs covers all decks.
+func (r *SQLiteReviewRepository) GetLeechCards(ctx context.Context, userID int, deckIDs []int) ([]*models.DueCard, error) {
+	where := " WHERE f.user_id = ? AND s.leech"
+	args := []any{userID}
+
+	if deckIDs != nil {
+		where += " AND f.deck_id IN (SELECT value FROM json_each(?))"
+		args = append(args, sqliteJSON(deckIDs))
+	}
+
+	return r.queryCards(ctx, dueCardsSelect+sqliteDueCardsFrom+where+" ORDER BY s.lapses DESC, f.id ASC", args...)
+}
+
+// SetSuspended suspends or unsuspends one of the user's scheduled cards
+func (r *SQLiteReviewRepository) SetSuspended(ctx context.Context, userID, flashcardID int, suspended bool) error {
+	query := `
+		UPDATE flashcard_schedules 
+		SET suspended = ?, updatedAt = ? 
+		WHERE flashcard_id = ? AND flashcard_id IN (SELECT id FROM flashcards WHERE user_id = ?)`
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, suspended, time.Now().UTC(), flashcardID, userID)
+	if err != nil {
+		return models.Internal("failed to update schedule: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("schedule for flashcard with id %d not found", flashcardID)
+	}
+
+	return nil
+}
+
+// ResetSchedule drops the scheduling state of one of the user's cards,
+// which makes it new again and clears its leech mark
+func (r *SQLiteReviewRepository) ResetSchedule(ctx context.Context, userID, flashcardID int) error {
+	query := `
+		DELETE FROM flashcard_schedules 
+		WHERE flashcard_id = ? AND flashcard_id IN (SELECT id FROM flashcards WHERE user_id = ?)`
+
+	if _, err := executor(ctx, r.db).ExecContext(ctx, query, flashcardID, userID); err != nil {
+		return models.Internal("failed to reset schedule: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteRoutingRuleRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteRoutingRuleRepository(db *sql.DB) *SQLiteRoutingRuleRepository {
+	return &SQLiteRoutingRuleRepository{db: db}
+}
+
+func (r *SQLiteRoutingRuleRepository) CreateRule(ctx context.Context, rule *models.RoutingRule) error {
+	query := `
+		INSERT INTO model_routing_rules (questionType, difficulty, organizationId, plan, provider, model, priority, createdAt, updatedAt)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	now := time.Now().UTC()
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, rule.QuestionType, rule.Difficulty, rule.OrganizationID, rule.Plan,
+		rule.Provider, rule.Model, rule.Priority, now, now)
+	if err != nil {
+		return models.Internal("failed to create routing rule: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Internal("failed to create routing rule: %w", err)
+	}
+
+	rule.ID, rule.CreatedAt, rule.UpdatedAt = int(id), now, now
+	return nil
+}
+
+func (r *SQLiteRoutingRuleRepository) GetAllRules(ctx context.Context) ([]*models.RoutingRule, error) {
+	query := `
+		SELECT id, questionType, difficulty, organizationId, plan, provider, model, priority, createdAt, updatedAt
+		FROM model_routing_rules
+		ORDER BY priority ASC, id ASC`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query)
+	if err != nil {
+		return nil, models.Internal("failed to query routing rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := make([]*models.RoutingRule, 0)
+	for rows.Next() {
+		rule := &models.RoutingRule{}
+		err := rows.Scan(&rule.ID, &rule.QuestionType, &rule.Difficulty, &rule.OrganizationID, &rule.Plan, &rule.Provider, &rule.Model, &rule.Priority, &rule.CreatedAt, &rule.UpdatedAt)
+		if err != nil {
+			return nil, models.Internal("failed to scan routing rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over routing rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+func (r *SQLiteRoutingRuleRepository) DeleteRule(ctx context.Context, id int) error {
+	query := "DELETE FROM model_routing_rules WHERE id = ?"
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, id)
+	if err != nil {
+		return models.Internal("failed to delete routing rule: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("routing rule with id %d not found", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteScheduledJobRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteScheduledJobRepository(db *sql.DB) *SQLiteScheduledJobRepository {
+	return &SQLiteScheduledJobRepository{db: db}
+}
+
+// ClaimJobRun claims the next run of a job for an instance, as
+// PostgresScheduledJobRepository's ClaimJobRun documents
+func (r *SQLiteScheduledJobRepository) ClaimJobRun(ctx context.Context, name, instance string, since time.Duration) (bool, error) {
+	query := `
+		INSERT INTO scheduled_job_runs (name, claimedBy, claimedAt)
+		VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE
+		SET claimedBy = excluded.claimedBy, claimedAt = excluded.claimedAt
+		WHERE scheduled_job_runs.claimedAt <= ?
+		RETURNING name`
+
+	now := time.Now().UTC()
+	var claimed string
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, name, instance, now, now.Add(-since)).Scan(&claimed)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, models.Internal("failed to claim scheduled job run: %w", err)
+	}
+
+	return true, nil
+}
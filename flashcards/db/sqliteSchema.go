@@ -0,0 +1,612 @@
+package db
+
+// sqliteSchema is the schema supabase/migrations builds in Postgres, as it
+// stands after the last migration, for SQLite. Arrays and JSONB are kept as
+// JSON text, BYTEA as BLOB and timestamps as UTC text, which sorts in time
+// order. contentHash is filled in by the repositories, as SQLite cannot
+// compute SHA-256, and foreign keys to tables declared further down are
+// only resolved when used.
+const sqliteSchema = `
+	CREATE TABLE IF NOT EXISTS organizations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		plan TEXT NOT NULL DEFAULT 'free',
+		maxNotes INTEGER,
+		maxCards INTEGER,
+		monthlyLlmTokens INTEGER,
+		stripeCustomerId TEXT,
+		stripeSubscriptionId TEXT,
+		subscriptionStatus TEXT,
+		stripeEventAt DATETIME,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_organizations_stripe_customer_id ON organizations(stripeCustomerId) WHERE stripeCustomerId IS NOT NULL;
+
+	CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		email TEXT NOT NULL UNIQUE,
+		passwordHash TEXT NOT NULL,
+		organization_id INTEGER REFERENCES organizations(id) ON DELETE SET NULL,
+		organizationRole TEXT,
+		isAdmin BOOLEAN NOT NULL DEFAULT FALSE,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_users_organization_id ON users(organization_id);
+
+	CREATE TABLE IF NOT EXISTS todos (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+		title TEXT NOT NULL,
+		description TEXT,
+		completed BOOLEAN DEFAULT FALSE,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_todos_user_id ON todos(user_id);
+
+	CREATE TABLE IF NOT EXISTS decks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		parent_id INTEGER REFERENCES decks(id) ON DELETE SET NULL,
+		name TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		reviewOrder TEXT NOT NULL DEFAULT 'due',
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_decks_user_id ON decks(user_id);
+	CREATE INDEX IF NOT EXISTS idx_decks_parent_id ON decks(parent_id);
+
+	CREATE TABLE IF NOT EXISTS deck_terminology (
+		deck_id INTEGER PRIMARY KEY REFERENCES decks(id) ON DELETE CASCADE,
+		preferredTerms TEXT NOT NULL DEFAULT '[]',
+		abbreviations TEXT NOT NULL DEFAULT '[]',
+		doNotSimplify TEXT NOT NULL DEFAULT '[]',
+		stopWords TEXT NOT NULL DEFAULT '[]',
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS deck_study_settings (
+		deck_id INTEGER PRIMARY KEY REFERENCES decks(id) ON DELETE CASCADE,
+		learningSteps TEXT NOT NULL DEFAULT '[]',
+		relearningSteps TEXT NOT NULL DEFAULT '[]',
+		graduatingIntervalDays INTEGER NOT NULL DEFAULT 1,
+		easyIntervalDays INTEGER NOT NULL DEFAULT 4,
+		newCardsPerDay INTEGER NOT NULL DEFAULT 20,
+		newCardOrder TEXT NOT NULL DEFAULT 'sequential',
+		newCardPosition TEXT NOT NULL DEFAULT 'after-reviews',
+		leechThreshold INTEGER NOT NULL DEFAULT 8,
+		leechAction TEXT NOT NULL DEFAULT 'tag',
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS notes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		deck_id INTEGER REFERENCES decks(id) ON DELETE SET NULL,
+		title TEXT NOT NULL DEFAULT '',
+		summary TEXT NOT NULL DEFAULT '',
+		source TEXT NOT NULL DEFAULT '',
+		language TEXT NOT NULL DEFAULT '',
+		content TEXT NOT NULL,
+		version INTEGER NOT NULL DEFAULT 1,
+		createdAt DATETIME NOT NULL,
+		updatedAt DATETIME NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_notes_user_created_at ON notes(user_id, createdAt);
+	CREATE INDEX IF NOT EXISTS idx_notes_deck_id ON notes(deck_id);
+	CREATE INDEX IF NOT EXISTS idx_notes_user_language ON notes(user_id, language);
+
+	CREATE TABLE IF NOT EXISTS tags (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS note_tags (
+		note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+		tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (note_id, tag_id)
+	);
+	CREATE INDEX IF NOT EXISTS idx_note_tags_tag_id ON note_tags(tag_id);
+
+	CREATE TABLE IF NOT EXISTS flashcards (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		deck_id INTEGER REFERENCES decks(id) ON DELETE SET NULL,
+		front TEXT NOT NULL DEFAULT '',
+		back TEXT NOT NULL DEFAULT '',
+		hint TEXT NOT NULL DEFAULT '',
+		mnemonic TEXT NOT NULL DEFAULT '',
+		source_note_id INTEGER REFERENCES notes(id) ON DELETE SET NULL,
+		content TEXT NOT NULL,
+		contentHash TEXT NOT NULL,
+		version INTEGER NOT NULL DEFAULT 1,
+		createdAt DATETIME NOT NULL,
+		updatedAt DATETIME NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_flashcards_user_created_at ON flashcards(user_id, createdAt);
+	CREATE INDEX IF NOT EXISTS idx_flashcards_content_hash ON flashcards(user_id, contentHash);
+	CREATE INDEX IF NOT EXISTS idx_flashcards_deck_id ON flashcards(deck_id);
+	CREATE INDEX IF NOT EXISTS idx_flashcards_source_note ON flashcards(source_note_id);
+
+	CREATE TABLE IF NOT EXISTS flashcard_vocabulary (
+		flashcard_id INTEGER PRIMARY KEY REFERENCES flashcards(id) ON DELETE CASCADE,
+		term TEXT NOT NULL,
+		language TEXT NOT NULL DEFAULT '',
+		ipa TEXT NOT NULL DEFAULT '',
+		pronunciation TEXT NOT NULL DEFAULT '',
+		cefrLevel TEXT NOT NULL DEFAULT '',
+		examples TEXT NOT NULL DEFAULT '[]',
+		audio BLOB,
+		audioContentType TEXT NOT NULL DEFAULT '',
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS flashcard_schedules (
+		flashcard_id INTEGER PRIMARY KEY REFERENCES flashcards(id) ON DELETE CASCADE,
+		state TEXT NOT NULL DEFAULT 'new',
+		dueAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		intervalDays INTEGER NOT NULL DEFAULT 0,
+		easeFactor REAL NOT NULL DEFAULT 2.5,
+		repetitions INTEGER NOT NULL DEFAULT 0,
+		lapses INTEGER NOT NULL DEFAULT 0,
+		step INTEGER NOT NULL DEFAULT 0,
+		leech BOOLEAN NOT NULL DEFAULT FALSE,
+		suspended BOOLEAN NOT NULL DEFAULT FALSE,
+		lastReviewedAt DATETIME,
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_flashcard_schedules_due_at ON flashcard_schedules(dueAt);
+
+	CREATE TABLE IF NOT EXISTS reviews (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		flashcard_id INTEGER NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+		mode TEXT NOT NULL DEFAULT 'scheduled',
+		rating TEXT NOT NULL,
+		previousState TEXT NOT NULL DEFAULT 'review',
+		previousSchedule TEXT,
+		intervalDays INTEGER NOT NULL,
+		easeFactor REAL NOT NULL,
+		dueAt DATETIME NOT NULL,
+		reviewedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		clientId TEXT
+	);
+	CREATE INDEX IF NOT EXISTS idx_reviews_user_id_reviewed_at ON reviews(user_id, reviewedAt);
+	CREATE INDEX IF NOT EXISTS idx_reviews_flashcard_id ON reviews(flashcard_id);
+	CREATE INDEX IF NOT EXISTS idx_reviews_user_id_previous_state ON reviews(user_id, previousState, reviewedAt);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_reviews_user_client_id ON reviews(user_id, clientId) WHERE clientId IS NOT NULL;
+
+	CREATE TABLE IF NOT EXISTS attachment_blobs (
+		hash TEXT PRIMARY KEY,
+		contentType TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		data BLOB,
+		storageKey TEXT,
+		refCount INTEGER NOT NULL DEFAULT 1,
+		releasedAt DATETIME,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_attachment_blobs_released ON attachment_blobs(releasedAt) WHERE refCount = 0;
+
+	CREATE TABLE IF NOT EXISTS attachments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		filename TEXT NOT NULL DEFAULT '',
+		contentType TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		data BLOB,
+		storageKey TEXT,
+		status TEXT NOT NULL DEFAULT 'ready',
+		contentHash TEXT REFERENCES attachment_blobs(hash),
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_attachments_user_id ON attachments(user_id);
+	CREATE INDEX IF NOT EXISTS idx_attachments_pending ON attachments(createdAt) WHERE status = 'pending';
+	CREATE INDEX IF NOT EXISTS idx_attachments_content_hash ON attachments(contentHash);
+
+	CREATE TABLE IF NOT EXISTS quiz_sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+		status TEXT NOT NULL DEFAULT 'in_progress',
+		currentPosition INTEGER NOT NULL DEFAULT 0,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_quiz_sessions_user_id ON quiz_sessions(user_id);
+
+	CREATE TABLE IF NOT EXISTS quiz_session_questions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id INTEGER NOT NULL REFERENCES quiz_sessions(id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		question TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'unanswered',
+		answer TEXT NOT NULL DEFAULT '',
+		flagged BOOLEAN NOT NULL DEFAULT FALSE,
+		timeSpentMs INTEGER NOT NULL DEFAULT 0,
+		attachment_id INTEGER REFERENCES attachments(id) ON DELETE SET NULL,
+		grading TEXT,
+		correct BOOLEAN,
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (session_id, position)
+	);
+	CREATE INDEX IF NOT EXISTS idx_quiz_session_questions_updated_at ON quiz_session_questions(updatedAt);
+
+	CREATE TABLE IF NOT EXISTS queued_quiz_sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		request TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'queued',
+		session_id INTEGER REFERENCES quiz_sessions(id) ON DELETE SET NULL,
+		error TEXT NOT NULL DEFAULT '',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		nextAttemptAt DATETIME DEFAULT CURRENT_TIMESTAMP,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_queued_quiz_sessions_user_id ON queued_quiz_sessions(user_id);
+	CREATE INDEX IF NOT EXISTS idx_queued_quiz_sessions_due ON queued_quiz_sessions(nextAttemptAt) WHERE status = 'queued';
+
+	CREATE TABLE IF NOT EXISTS question_embeddings (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		noteIds TEXT NOT NULL DEFAULT '[]',
+		questionText TEXT NOT NULL,
+		model TEXT NOT NULL,
+		embedding TEXT NOT NULL,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_question_embeddings_user_id ON question_embeddings(user_id, createdAt);
+
+	CREATE TABLE IF NOT EXISTS model_routing_rules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		questionType TEXT NOT NULL DEFAULT '',
+		difficulty TEXT NOT NULL DEFAULT '',
+		organizationId INTEGER REFERENCES organizations(id) ON DELETE CASCADE,
+		plan TEXT NOT NULL DEFAULT '',
+		provider TEXT NOT NULL DEFAULT '',
+		model TEXT NOT NULL,
+		priority INTEGER NOT NULL DEFAULT 100,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_model_routing_rules_priority ON model_routing_rules(priority);
+
+	CREATE TABLE IF NOT EXISTS report_recipients (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+		email TEXT NOT NULL,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_id, email)
+	);
+
+	CREATE TABLE IF NOT EXISTS content_filter_settings (
+		id INTEGER PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+		enabled BOOLEAN NOT NULL DEFAULT FALSE,
+		ageLevel TEXT NOT NULL DEFAULT 'adult',
+		bannedTopics TEXT NOT NULL DEFAULT '[]',
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO content_filter_settings (id) VALUES (1) ON CONFLICT DO NOTHING;
+
+	CREATE TABLE IF NOT EXISTS branding (
+		id INTEGER PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+		name TEXT NOT NULL DEFAULT 'Flashcards',
+		logoUrl TEXT NOT NULL DEFAULT '',
+		primaryColor TEXT NOT NULL DEFAULT '#2563eb',
+		accentColor TEXT NOT NULL DEFAULT '#f59e0b',
+		supportEmail TEXT NOT NULL DEFAULT '',
+		supportUrl TEXT NOT NULL DEFAULT '',
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO branding (id) VALUES (1) ON CONFLICT DO NOTHING;
+
+	CREATE TABLE IF NOT EXISTS maintenance (
+		id INTEGER PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+		mode TEXT NOT NULL DEFAULT 'off',
+		message TEXT NOT NULL DEFAULT '',
+		startedAt DATETIME,
+		endsAt DATETIME,
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO maintenance (id) VALUES (1) ON CONFLICT DO NOTHING;
+
+	CREATE TABLE IF NOT EXISTS prompt_templates (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		template TEXT NOT NULL,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (name, version)
+	);
+
+	CREATE TABLE IF NOT EXISTS llm_token_usage (
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		month DATE NOT NULL,
+		tokens INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (user_id, month)
+	);
+
+	CREATE TABLE IF NOT EXISTS llm_token_usage_hourly (
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		hour DATETIME NOT NULL,
+		tokens INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (user_id, hour)
+	);
+	CREATE INDEX IF NOT EXISTS idx_llm_token_usage_hourly_hour ON llm_token_usage_hourly(hour);
+
+	CREATE TABLE IF NOT EXISTS llm_spend_anomalies (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		scope TEXT NOT NULL,
+		subjectId INTEGER NOT NULL,
+		recentTokens INTEGER NOT NULL,
+		baselineTokensPerHour REAL NOT NULL,
+		detectedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		throttledUntil DATETIME
+	);
+	CREATE INDEX IF NOT EXISTS idx_llm_spend_anomalies_subject ON llm_spend_anomalies(scope, subjectId, detectedAt);
+
+	CREATE TABLE IF NOT EXISTS llm_calls (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		model TEXT NOT NULL,
+		inputTokens INTEGER NOT NULL,
+		outputTokens INTEGER NOT NULL,
+		costUsd NUMERIC NOT NULL,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_llm_calls_user_created ON llm_calls(user_id, createdAt);
+	CREATE INDEX IF NOT EXISTS idx_llm_calls_created ON llm_calls(createdAt);
+
+	CREATE TABLE IF NOT EXISTS organization_invites (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+		email TEXT NOT NULL,
+		role TEXT NOT NULL DEFAULT 'member',
+		tokenHash TEXT NOT NULL UNIQUE,
+		invited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		accepted_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		expiresAt DATETIME NOT NULL,
+		acceptedAt DATETIME,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_organization_invites_organization_id ON organization_invites(organization_id);
+
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		idempotencyKey TEXT NOT NULL,
+		requestHash TEXT NOT NULL,
+		statusCode INTEGER,
+		responseBody BLOB,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		expiresAt DATETIME NOT NULL,
+		PRIMARY KEY (user_id, idempotencyKey)
+	);
+	CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expiresAt);
+
+	CREATE TABLE IF NOT EXISTS request_stats (
+		bucket DATETIME NOT NULL,
+		route TEXT NOT NULL,
+		method TEXT NOT NULL,
+		user_id INTEGER NOT NULL DEFAULT 0,
+		requests INTEGER NOT NULL DEFAULT 0,
+		clientErrors INTEGER NOT NULL DEFAULT 0,
+		serverErrors INTEGER NOT NULL DEFAULT 0,
+		totalLatencyMs INTEGER NOT NULL DEFAULT 0,
+		maxLatencyMs INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (bucket, route, method, user_id)
+	);
+	CREATE INDEX IF NOT EXISTS idx_request_stats_user_id ON request_stats(user_id, bucket);
+
+	CREATE TABLE IF NOT EXISTS published_decks (
+		deck_id INTEGER PRIMARY KEY REFERENCES decks(id) ON DELETE CASCADE,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		publishedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_published_decks_published_at ON published_decks(publishedAt);
+
+	CREATE TABLE IF NOT EXISTS card_embeddings (
+		flashcard_id INTEGER PRIMARY KEY REFERENCES flashcards(id) ON DELETE CASCADE,
+		deck_id INTEGER NOT NULL REFERENCES published_decks(deck_id) ON DELETE CASCADE,
+		model TEXT NOT NULL,
+		embedding TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_card_embeddings_deck_id ON card_embeddings(deck_id);
+
+	CREATE TABLE IF NOT EXISTS deck_similarity_flags (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		deck_id INTEGER NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+		matched_deck_id INTEGER NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+		matchedCards INTEGER NOT NULL,
+		totalCards INTEGER NOT NULL,
+		maxSimilarity REAL NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		reviewedAt DATETIME
+	);
+	CREATE INDEX IF NOT EXISTS idx_deck_similarity_flags_status ON deck_similarity_flags(status, createdAt);
+
+	CREATE TABLE IF NOT EXISTS embeds (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		flashcard_id INTEGER REFERENCES flashcards(id) ON DELETE CASCADE,
+		deck_id INTEGER REFERENCES decks(id) ON DELETE CASCADE,
+		tokenHash TEXT NOT NULL UNIQUE,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		CHECK ((flashcard_id IS NULL) <> (deck_id IS NULL))
+	);
+	CREATE INDEX IF NOT EXISTS idx_embeds_user_id ON embeds(user_id);
+
+	CREATE TABLE IF NOT EXISTS import_jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		kind TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'running',
+		deck_id INTEGER REFERENCES decks(id) ON DELETE SET NULL,
+		chunkSize INTEGER NOT NULL,
+		totalItems INTEGER NOT NULL,
+		processedItems INTEGER NOT NULL DEFAULT 0,
+		committedChunks INTEGER NOT NULL DEFAULT 0,
+		skipped TEXT NOT NULL DEFAULT '[]',
+		error TEXT,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		completedAt DATETIME
+	);
+	CREATE INDEX IF NOT EXISTS idx_import_jobs_user_id ON import_jobs(user_id, createdAt);
+	CREATE INDEX IF NOT EXISTS idx_import_jobs_status ON import_jobs(status, updatedAt);
+
+	CREATE TABLE IF NOT EXISTS import_job_items (
+		job_id INTEGER NOT NULL REFERENCES import_jobs(id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		chunk INTEGER NOT NULL,
+		file TEXT NOT NULL DEFAULT '',
+		heading TEXT NOT NULL DEFAULT '',
+		content TEXT NOT NULL,
+		committed BOOLEAN NOT NULL DEFAULT FALSE,
+		recordId INTEGER,
+		PRIMARY KEY (job_id, position)
+	);
+	CREATE INDEX IF NOT EXISTS idx_import_job_items_pending ON import_job_items(job_id, chunk) WHERE NOT committed;
+
+	CREATE TABLE IF NOT EXISTS dead_letters (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL,
+		key TEXT NOT NULL,
+		user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+		payload TEXT NOT NULL DEFAULT '{}',
+		error TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 1,
+		status TEXT NOT NULL DEFAULT 'retrying',
+		nextAttemptAt DATETIME,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		resolvedAt DATETIME
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_dead_letters_open ON dead_letters(kind, key) WHERE status <> 'resolved';
+	CREATE INDEX IF NOT EXISTS idx_dead_letters_due ON dead_letters(nextAttemptAt) WHERE status = 'retrying';
+	CREATE INDEX IF NOT EXISTS idx_dead_letters_created_at ON dead_letters(createdAt);
+
+	CREATE TABLE IF NOT EXISTS scheduled_job_runs (
+		name TEXT PRIMARY KEY,
+		claimedBy TEXT NOT NULL,
+		claimedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS deck_suggestions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		deck_id INTEGER NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+		kind TEXT NOT NULL,
+		flashcard_ids TEXT NOT NULL,
+		note_ids TEXT NOT NULL DEFAULT '[]',
+		status TEXT NOT NULL DEFAULT 'open',
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		resolvedAt DATETIME
+	);
+	CREATE INDEX IF NOT EXISTS idx_deck_suggestions_deck_id ON deck_suggestions(deck_id, status);
+
+	CREATE TABLE IF NOT EXISTS daily_question_subscriptions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		deck_id INTEGER NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+		last_session_id INTEGER REFERENCES quiz_sessions(id) ON DELETE SET NULL,
+		lastSentAt DATETIME,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_id, deck_id)
+	);
+	CREATE INDEX IF NOT EXISTS idx_daily_question_subscriptions_last_sent_at ON daily_question_subscriptions(lastSentAt);
+
+	CREATE TABLE IF NOT EXISTS jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		kind TEXT NOT NULL,
+		payload TEXT NOT NULL DEFAULT '{}',
+		status TEXT NOT NULL DEFAULT 'queued',
+		result TEXT,
+		error TEXT NOT NULL DEFAULT '',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		nextAttemptAt DATETIME DEFAULT CURRENT_TIMESTAMP,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		startedAt DATETIME,
+		completedAt DATETIME
+	);
+	CREATE INDEX IF NOT EXISTS idx_jobs_due ON jobs(nextAttemptAt) WHERE status IN ('queued', 'running');
+	CREATE INDEX IF NOT EXISTS idx_jobs_user_id ON jobs(user_id, createdAt);
+
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		kind TEXT NOT NULL DEFAULT 'webhook',
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		events TEXT NOT NULL DEFAULT '[]',
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks(user_id);
+
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+		event TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		responseStatus INTEGER,
+		error TEXT NOT NULL DEFAULT '',
+		nextAttemptAt DATETIME DEFAULT CURRENT_TIMESTAMP,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		deliveredAt DATETIME
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(nextAttemptAt) WHERE status = 'pending';
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, createdAt);
+
+	CREATE TABLE IF NOT EXISTS deck_exports (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		deck_id INTEGER REFERENCES decks(id) ON DELETE SET NULL,
+		format TEXT NOT NULL,
+		filename TEXT NOT NULL,
+		contentType TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		storageKey TEXT NOT NULL,
+		expiresAt DATETIME NOT NULL,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_deck_exports_user_id ON deck_exports(user_id);
+	CREATE INDEX IF NOT EXISTS idx_deck_exports_expires_at ON deck_exports(expiresAt);
+
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		keyHash TEXT NOT NULL UNIQUE,
+		scopes TEXT NOT NULL DEFAULT '[]',
+		requests INTEGER NOT NULL DEFAULT 0,
+		lastUsedAt DATETIME,
+		createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		revokedAt DATETIME
+	);
+	CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+
+	CREATE TABLE IF NOT EXISTS api_key_usage (
+		api_key_id INTEGER NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+		day DATE NOT NULL,
+		requests INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (api_key_id, day)
+	);
+
+	CREATE TABLE IF NOT EXISTS stripe_events (
+		id TEXT PRIMARY KEY,
+		processedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`
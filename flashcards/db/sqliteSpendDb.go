@@ -0,0 +1,188 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+const sqliteSpendAnomalyColumns = "id, scope, subjectId, recentTokens, baselineTokensPerHour, detectedAt, throttledUntil"
+
+type SQLiteSpendRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteSpendRepository(db *sql.DB) *SQLiteSpendRepository {
+	return &SQLiteSpendRepository{db: db}
+}
+
+// GetSpendWindows totals hourly token usage per user and per organization,
+// split into the baseline period [baselineStart, recentStart) and the
+// recent window from recentStart. Subjects with no recent usage are left
+// out.
+func (r *SQLiteSpendRepository) GetSpendWindows(ctx context.Context, baselineStart, recentStart time.Time) ([]*models.SpendWindow, error) {
+	query := `
+		WITH usage AS (
+			SELECT h.user_id, u.organization_id, h.hour, h.tokens
+			FROM llm_token_usage_hourly h
+			JOIN users u ON u.id = h.user_id
+			WHERE h.hour >= ?1
+		)
+		SELECT ?3, user_id,
+			COALESCE(SUM(tokens) FILTER (WHERE hour >= ?2), 0),
+			COALESCE(SUM(tokens) FILTER (WHERE hour < ?2), 0)
+		FROM usage
+		GROUP BY user_id
+		HAVING SUM(tokens) FILTER (WHERE hour >= ?2) > 0
+		UNION ALL
+		SELECT ?4, organization_id,
+			COALESCE(SUM(tokens) FILTER (WHERE hour >= ?2), 0),
+			COALESCE(SUM(tokens) FILTER (WHERE hour < ?2), 0)
+		FROM usage
+		WHERE organization_id IS NOT NULL
+		GROUP BY organization_id
+		HAVING SUM(tokens) FILTER (WHERE hour >= ?2) > 0`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, baselineStart.UTC(), recentStart.UTC(),
+		models.SpendScopeUser, models.SpendScopeOrganization)
+	if err != nil {
+		return nil, models.Internal("failed to get spend windows: %w", err)
+	}
+	defer rows.Close()
+
+	windows := []*models.SpendWindow{}
+	for rows.Next() {
+		window := &models.SpendWindow{}
+		if err := rows.Scan(&window.Scope, &window.SubjectID, &window.RecentTokens, &window.BaselineTokens); err != nil {
+			return nil, models.Internal("failed to scan spend window: %w", err)
+		}
+		windows = append(windows, window)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, models.Internal("failed to iterate spend windows: %w", err)
+	}
+
+	return windows, nil
+}
+
+func (r *SQLiteSpendRepository) CreateAnomaly(ctx context.Context, anomaly *models.SpendAnomaly) error {
+	query := `
+		INSERT INTO llm_spend_anomalies (scope, subjectId, recentTokens, baselineTokensPerHour, throttledUntil, detectedAt)
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+	now := time.Now().UTC()
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, anomaly.Scope, anomaly.SubjectID, anomaly.RecentTokens,
+		anomaly.BaselineTokensPerHour, anomaly.ThrottledUntil, now)
+	if err != nil {
+		return models.Internal("failed to create spend anomaly: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Internal("failed to create spend anomaly: %w", err)
+	}
+
+	anomaly.ID, anomaly.DetectedAt = int(id), now
+	return nil
+}
+
+// HasAnomalySince reports whether the subject already had an anomaly
+// detected since the given time
+func (r *SQLiteSpendRepository) HasAnomalySince(ctx context.Context, scope string, subjectID int, since time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM llm_spend_anomalies
+			WHERE scope = ? AND subjectId = ? AND detectedAt >= ?
+		)`
+
+	var exists bool
+	if err := executor(ctx, r.db).QueryRowContext(ctx, query, scope, subjectID, since.UTC()).Scan(&exists); err != nil {
+		return false, models.Internal("failed to check spend anomalies: %w", err)
+	}
+
+	return exists, nil
+}
+
+// GetThrottledUntil returns when the latest active throttle on the user or
+// their organization ends, or nil when neither is throttled
+func (r *SQLiteSpendRepository) GetThrottledUntil(ctx context.Context, userID int) (*time.Time, error) {
+	query := `
+		SELECT MAX(a.throttledUntil)
+		FROM llm_spend_anomalies a
+		JOIN users u ON u.id = ?1
+		WHERE a.throttledUntil > ?4
+			AND ((a.scope = ?2 AND a.subjectId = u.id) OR (a.scope = ?3 AND a.subjectId = u.organization_id))`
+
+	var throttledUntil *time.Time
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, userID, models.SpendScopeUser, models.SpendScopeOrganization,
+		time.Now().UTC()).Scan(sqliteTime(&throttledUntil))
+	if err != nil {
+		return nil, models.Internal("failed to check spend throttle: %w", err)
+	}
+
+	return throttledUntil, nil
+}
+
+// GetAnomalies returns the most recently detected anomalies
+func (r *SQLiteSpendRepository) GetAnomalies(ctx context.Context, limit int) ([]*models.SpendAnomaly, error) {
+	query := `
+		SELECT ` + sqliteSpendAnomalyColumns + `
+		FROM llm_spend_anomalies
+		ORDER BY detectedAt DESC, id DESC
+		LIMIT ?`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, models.Internal("failed to get spend anomalies: %w", err)
+	}
+	defer rows.Close()
+
+	anomalies := []*models.SpendAnomaly{}
+	for rows.Next() {
+		anomaly, err := scanSQLiteSpendAnomaly(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan spend anomaly: %w", err)
+		}
+		anomalies = append(anomalies, anomaly)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, models.Internal("failed to iterate spend anomalies: %w", err)
+	}
+
+	return anomalies, nil
+}
+
+// LiftThrottle ends the anomaly's throttle now
+func (r *SQLiteSpendRepository) LiftThrottle(ctx context.Context, id int) (*models.SpendAnomaly, error) {
+	query := `
+		UPDATE llm_spend_anomalies
+		SET throttledUntil = NULL
+		WHERE id = ?
+		RETURNING ` + sqliteSpendAnomalyColumns
+
+	anomaly, err := scanSQLiteSpendAnomaly(executor(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("spend anomaly with id %d not found", id)
+		}
+		return nil, models.Internal("failed to lift spend throttle: %w", err)
+	}
+
+	return anomaly, nil
+}
+
+// scanSQLiteSpendAnomaly scans the sqliteSpendAnomalyColumns of a query or,
+// with the times untyped, of a RETURNING clause
+func scanSQLiteSpendAnomaly(row interface{ Scan(...any) error }) (*models.SpendAnomaly, error) {
+	anomaly := &models.SpendAnomaly{}
+	err := row.Scan(&anomaly.ID, &anomaly.Scope, &anomaly.SubjectID, &anomaly.RecentTokens,
+		&anomaly.BaselineTokensPerHour, sqliteTime(&anomaly.DetectedAt), sqliteTime(&anomaly.ThrottledUntil))
+	if err != nil {
+		return nil, err
+	}
+	return anomaly, nil
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteStudyAnalyticsRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteStudyAnalyticsRepository(db *sql.DB) *SQLiteStudyAnalyticsRepository {
+	return &SQLiteStudyAnalyticsRepository{db: db}
+}
+
+// GetDailyReviews counts the user's flashcard reviews per day since the
+// given time. Days without reviews are left out.
+func (r *SQLiteStudyAnalyticsRepository) GetDailyReviews(ctx context.Context, userID int, since time.Time) ([]models.DailyReviews, error) {
+	query := `
+		SELECT date(reviewedAt) AS day, COUNT(*), COUNT(*) FILTER (WHERE rating <> 'again')
+		FROM reviews
+		WHERE user_id = ? AND reviewedAt >= ?
+		GROUP BY day
+		ORDER BY day`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, since.UTC())
+	if err != nil {
+		return nil, models.Internal("failed to get daily reviews: %w", err)
+	}
+	defer rows.Close()
+
+	days := []models.DailyReviews{}
+	for rows.Next() {
+		var day models.DailyReviews
+		if err := rows.Scan(&day.Date, &day.Reviewed, &day.Recalled); err != nil {
+			return nil, models.Internal("failed to scan daily reviews: %w", err)
+		}
+		days = append(days, day)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, models.Internal("failed to iterate daily reviews: %w", err)
+	}
+
+	return days, nil
+}
+
+// GetAccuracyByDifficulty counts the user's graded quiz answers, and how
+// many were correct, per question difficulty
+func (r *SQLiteStudyAnalyticsRepository) GetAccuracyByDifficulty(ctx context.Context, userID int, since time.Time) ([]models.DifficultyAccuracy, error) {
+	query := `
+		SELECT COALESCE(NULLIF(json_extract(q.question, '$.difficulty'), ''), 'unknown') AS difficulty,
+			COUNT(*), COUNT(*) FILTER (WHERE q.correct)
+		FROM quiz_session_questions q
+		JOIN quiz_sessions s ON s.id = q.session_id
+		WHERE s.user_id = ? AND q.status = 'answered' AND q.correct IS NOT NULL AND q.updatedAt >= ?
+		GROUP BY difficulty
+		ORDER BY difficulty`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, since.UTC())
+	if err != nil {
+		return nil, models.Internal("failed to get accuracy by difficulty: %w", err)
+	}
+	defer rows.Close()
+
+	accuracies := []models.DifficultyAccuracy{}
+	for rows.Next() {
+		var accuracy models.DifficultyAccuracy
+		if err := rows.Scan(&accuracy.Difficulty, &accuracy.Graded, &accuracy.Correct); err != nil {
+			return nil, models.Internal("failed to scan difficulty accuracy: %w", err)
+		}
+		accuracies = append(accuracies, accuracy)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, models.Internal("failed to iterate difficulty accuracy: %w", err)
+	}
+
+	return accuracies, nil
+}
+
+// GetActiveDays returns the days since the given time on which the user
+// reviewed a flashcard or answered a quiz question, most recent first
+func (r *SQLiteStudyAnalyticsRepository) GetActiveDays(ctx context.Context, userID int, since time.Time) ([]time.Time, error) {
+	query := `
+		SELECT DISTINCT date(activeAt) AS day
+		FROM (
+			SELECT reviewedAt AS activeAt
+			FROM reviews
+			WHERE user_id = ?1 AND reviewedAt >= ?2
+			UNION ALL
+			SELECT q.updatedAt
+			FROM quiz_session_questions q
+			JOIN quiz_sessions s ON s.id = q.session_id
+			WHERE s.user_id = ?1 AND q.status = 'answered' AND q.updatedAt >= ?2
+		) activity
+		ORDER BY day DESC`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, since.UTC())
+	if err != nil {
+		return nil, models.Internal("failed to get active days: %w", err)
+	}
+	defer rows.Close()
+
+	days := []time.Time{}
+	for rows.Next() {
+		var day time.Time
+		if err := rows.Scan(sqliteTime(&day)); err != nil {
+			return nil, models.Internal("failed to scan active day: %w", err)
+		}
+		days = append(days, day)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, models.Internal("failed to iterate active days: %w", err)
+	}
+
+	return days, nil
+}
+
+// GetMostMissedNotes returns the user's notes behind the most wrongly
+// answered quiz questions, with how many graded answers drew on each
+func (r *SQLiteStudyAnalyticsRepository) GetMostMissedNotes(ctx context.Context, userID int, since time.Time, limit int) ([]models.MissedNote, error) {
+	query := `
+		SELECT n.id, substr(n.content, 1, 120),
+			COUNT(*) FILTER (WHERE NOT q.correct) AS missed, COUNT(*)
+		FROM quiz_session_questions q
+		JOIN quiz_sessions s ON s.id = q.session_id
+		JOIN json_each(q.question, '$.basedOnNotes') AS based_on
+		JOIN notes n ON n.id = based_on.value AND n.user_id = s.user_id
+		WHERE s.user_id = ? AND q.status = 'answered' AND q.correct IS NOT NULL AND q.updatedAt >= ?
+		GROUP BY n.id
+		HAVING COUNT(*) FILTER (WHERE NOT q.correct) > 0
+		ORDER BY missed DESC, n.id
+		LIMIT ?`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, since.UTC(), limit)
+	if err != nil {
+		return nil, models.Internal("failed to get most missed notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := []models.MissedNote{}
+	for rows.Next() {
+		var note models.MissedNote
+		if err := rows.Scan(&note.NoteID, &note.Excerpt, &note.Missed, &note.Graded); err != nil {
+			return nil, models.Internal("failed to scan missed note: %w", err)
+		}
+		notes = append(notes, note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, models.Internal("failed to iterate missed notes: %w", err)
+	}
+
+	return notes, nil
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"flashcards/models"
+)
+
+type SQLiteTagRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteTagRepository(db *sql.DB) *SQLiteTagRepository {
+	return &SQLiteTagRepository{db: db}
+}
+
+// GetAllTags returns the tags used on the user's notes. Tag names are shared
+// between users, so only tags with at least one of the user's notes are listed.
+func (r *SQLiteTagRepository) GetAllTags(ctx context.Context, userID int) ([]*models.Tag, error) {
+	query := `
+		SELECT t.id, t.name, t.createdAt, COUNT(nt.note_id) 
+		FROM tags t 
+		JOIN note_tags nt ON nt.tag_id = t.id 
+		JOIN notes n ON n.id = nt.note_id 
+		WHERE n.user_id = ? 
+		GROUP BY t.id 
+		ORDER BY t.name ASC`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, models.Internal("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make([]*models.Tag, 0)
+	for rows.Next() {
+		tag := &models.Tag{}
+		err := rows.Scan(&tag.ID, &tag.Name, &tag.CreatedAt, &tag.NoteCount)
+		if err != nil {
+			return nil, models.Internal("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// AddTagsToNote creates any missing tags and links them to the note.
+// Tags already attached to the note are left untouched.
+func (r *SQLiteTagRepository) AddTagsToNote(ctx context.Context, userID, noteID int, names []string) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM notes WHERE id = ? AND user_id = ?)", noteID, userID).Scan(&exists); err != nil {
+		return models.Internal("failed to check note: %w", err)
+	}
+	if !exists {
+		return models.NotFound("note with id %d not found", noteID)
+	}
+
+	tagQuery := `
+		INSERT INTO tags (name) 
+		VALUES (?) 
+		ON CONFLICT (name) DO UPDATE SET name = excluded.name 
+		RETURNING id`
+
+	linkQuery := `
+		INSERT INTO note_tags (note_id, tag_id) 
+		VALUES (?, ?) 
+		ON CONFLICT DO NOTHING`
+
+	for _, name := range names {
+		var tagID int
+		if err := tx.QueryRowContext(ctx, tagQuery, name).Scan(&tagID); err != nil {
+			return models.Internal("failed to create tag: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, linkQuery, noteID, tagID); err != nil {
+			return models.Internal("failed to tag note: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit tags: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteTagRepository) RemoveTagFromNote(ctx context.Context, userID, noteID int, name string) error {
+	query := `
+		DELETE FROM note_tags 
+		WHERE note_id = ? AND tag_id = (SELECT id FROM tags WHERE name = ?) 
+			AND note_id IN (SELECT id FROM notes WHERE user_id = ?)`
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, noteID, name, userID)
+	if err != nil {
+		return models.Internal("failed to remove tag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("tag %q on note with id %d not found", name, noteID)
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteTodoRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteTodoRepository(db *sql.DB) *SQLiteTodoRepository {
+	return &SQLiteTodoRepository{db: db}
+}
+
+func (r *SQLiteTodoRepository) CreateTodo(ctx context.Context, todo *models.Todo) error {
+	query := `
+		INSERT INTO todos (user_id, title, description, completed, createdAt, updatedAt) 
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+	now := time.Now().UTC()
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, todo.UserID, todo.Title, todo.Description, todo.Completed, now, now)
+	if err != nil {
+		return models.Internal("failed to create todo: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Internal("failed to create todo: %w", err)
+	}
+
+	todo.ID, todo.CreatedAt, todo.UpdatedAt = int(id), now, now
+	return nil
+}
+
+func (r *SQLiteTodoRepository) GetTodoByID(ctx context.Context, userID, id int) (*models.Todo, error) {
+	query := `
+		SELECT id, user_id, title, description, completed, createdAt, updatedAt 
+		FROM todos 
+		WHERE id = ? AND user_id = ?`
+
+	todo := &models.Todo{}
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, id, userID)
+
+	err := row.Scan(&todo.ID, &todo.UserID, &todo.Title, &todo.Description, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("todo with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get todo: %w", err)
+	}
+
+	return todo, nil
+}
+
+func (r *SQLiteTodoRepository) GetAllTodos(ctx context.Context, userID int) ([]*models.Todo, error) {
+	query := `
+		SELECT id, user_id, title, description, completed, createdAt, updatedAt 
+		FROM todos 
+		WHERE user_id = ? 
+		ORDER BY createdAt DESC`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, models.Internal("failed to query todos: %w", err)
+	}
+	defer rows.Close()
+
+	todos := make([]*models.Todo, 0)
+	for rows.Next() {
+		todo := &models.Todo{}
+		err := rows.Scan(&todo.ID, &todo.UserID, &todo.Title, &todo.Description, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt)
+		if err != nil {
+			return nil, models.Internal("failed to scan todo: %w", err)
+		}
+		todos = append(todos, todo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over todos: %w", err)
+	}
+
+	return todos, nil
+}
+
+func (r *SQLiteTodoRepository) UpdateTodo(ctx context.Context, userID, id int, updates map[string]any) error {
+	if err := checkUpdates("todos", updates); err != nil {
+		return err
+	}
+
+	fields := make([]string, 0, len(updates))
+	args := make([]any, 0, len(updates)+3)
+	for field, value := range updates {
+		fields = append(fields, field+" = ?")
+		args = append(args, value)
+	}
+
+	query := "UPDATE todos SET " + strings.Join(fields, ", ") + ", updatedAt = ? WHERE id = ? AND user_id = ?"
+	args = append(args, time.Now().UTC(), id, userID)
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return models.Internal("failed to update todo: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("todo with id %d not found", id)
+	}
+
+	return nil
+}
+
+func (r *SQLiteTodoRepository) DeleteTodo(ctx context.Context, userID, id int) error {
+	query := "DELETE FROM todos WHERE id = ? AND user_id = ?"
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return models.Internal("failed to delete todo: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("todo with id %d not found", id)
+	}
+
+	return nil
+}
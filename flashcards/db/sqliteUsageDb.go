@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteUsageRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteUsageRepository(db *sql.DB) *SQLiteUsageRepository {
+	return &SQLiteUsageRepository{db: db}
+}
+
+func (r *SQLiteUsageRepository) RecordLLMCall(ctx context.Context, call *models.LLMCall) error {
+	query := `
+		INSERT INTO llm_calls (user_id, model, inputTokens, outputTokens, costUsd, createdAt)
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+	now := time.Now().UTC()
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, call.UserID, call.Model, call.InputTokens, call.OutputTokens, call.CostUSD, now)
+	if err != nil {
+		return models.Internal("failed to record llm call: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Internal("failed to record llm call: %w", err)
+	}
+
+	call.ID, call.CreatedAt = id, now
+	return nil
+}
+
+// GetCostSince returns the estimated cost of the user's calls since the
+// given time
+func (r *SQLiteUsageRepository) GetCostSince(ctx context.Context, userID int, since time.Time) (float64, error) {
+	query := `SELECT COALESCE(SUM(costUsd), 0) FROM llm_calls WHERE user_id = ? AND createdAt >= ?`
+
+	var cost float64
+	if err := executor(ctx, r.db).QueryRowContext(ctx, query, userID, since.UTC()).Scan(&cost); err != nil {
+		return 0, models.Internal("failed to get llm cost: %w", err)
+	}
+
+	return cost, nil
+}
+
+// GetDailyUsage totals the user's calls per day since the given time,
+// oldest first. Days without calls are left out.
+func (r *SQLiteUsageRepository) GetDailyUsage(ctx context.Context, userID int, since time.Time) ([]*models.DailyLLMUsage, error) {
+	query := `
+		SELECT date(createdAt), COUNT(*), SUM(inputTokens), SUM(outputTokens), SUM(costUsd)
+		FROM llm_calls
+		WHERE user_id = ? AND createdAt >= ?
+		GROUP BY 1
+		ORDER BY 1`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, since.UTC())
+	if err != nil {
+		return nil, models.Internal("failed to get daily llm usage: %w", err)
+	}
+	defer rows.Close()
+
+	days := []*models.DailyLLMUsage{}
+	for rows.Next() {
+		day := &models.DailyLLMUsage{}
+		if err := rows.Scan(sqliteTime(&day.Day), &day.Calls, &day.InputTokens, &day.OutputTokens, &day.CostUSD); err != nil {
+			return nil, models.Internal("failed to scan daily llm usage: %w", err)
+		}
+		days = append(days, day)
+	}
+
+	return days, rows.Err()
+}
+
+// GetModelUsage totals the user's calls per model since the given time,
+// costliest first
+func (r *SQLiteUsageRepository) GetModelUsage(ctx context.Context, userID int, since time.Time) ([]*models.ModelLLMUsage, error) {
+	query := `
+		SELECT model, COUNT(*), SUM(inputTokens), SUM(outputTokens), SUM(costUsd)
+		FROM llm_calls
+		WHERE user_id = ? AND createdAt >= ?
+		GROUP BY model
+		ORDER BY 5 DESC, model`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID, since.UTC())
+	if err != nil {
+		return nil, models.Internal("failed to get llm usage by model: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []*models.ModelLLMUsage{}
+	for rows.Next() {
+		model := &models.ModelLLMUsage{}
+		if err := rows.Scan(&model.Model, &model.Calls, &model.InputTokens, &model.OutputTokens, &model.CostUSD); err != nil {
+			return nil, models.Internal("failed to scan llm usage by model: %w", err)
+		}
+		usage = append(usage, model)
+	}
+
+	return usage, rows.Err()
+}
+
+// GetUsageByUser totals calls per user since the given time, returning the
+// limit costliest users
+func (r *SQLiteUsageRepository) GetUsageByUser(ctx context.Context, since time.Time, limit int) ([]*models.UserLLMUsage, error) {
+	query := `
+		SELECT user_id, COUNT(*), SUM(inputTokens), SUM(outputTokens), SUM(costUsd)
+		FROM llm_calls
+		WHERE createdAt >= ?
+		GROUP BY user_id
+		ORDER BY 5 DESC, user_id
+		LIMIT ?`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, since.UTC(), limit)
+	if err != nil {
+		return nil, models.Internal("failed to get llm usage by user: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []*models.UserLLMUsage{}
+	for rows.Next() {
+		user := &models.UserLLMUsage{}
+		if err := rows.Scan(&user.UserID, &user.Calls, &user.InputTokens, &user.OutputTokens, &user.CostUSD); err != nil {
+			return nil, models.Internal("failed to scan llm usage by user: %w", err)
+		}
+		usage = append(usage, user)
+	}
+
+	return usage, rows.Err()
+}
+
+func (r *SQLiteUsageRepository) DeleteLLMCallsBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM llm_calls WHERE createdAt < ?`
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, before.UTC())
+	if err != nil {
+		return 0, models.Internal("failed to delete llm calls: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, models.Internal("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+const sqliteUserColumns = "id, email, passwordHash, isAdmin, createdAt, updatedAt"
+
+type SQLiteUserRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteUserRepository(db *sql.DB) *SQLiteUserRepository {
+	return &SQLiteUserRepository{db: db}
+}
+
+func (r *SQLiteUserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	query := `
+		INSERT INTO users (email, passwordHash, createdAt, updatedAt) 
+		VALUES (?, ?, ?, ?)`
+
+	now := time.Now().UTC()
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, user.Email, user.PasswordHash, now, now)
+	if err != nil {
+		if isSQLiteUniqueViolation(err) {
+			return models.Conflict("user with email %s already exists", user.Email)
+		}
+		return models.Internal("failed to create user: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return models.Internal("failed to create user: %w", err)
+	}
+
+	user.ID, user.CreatedAt, user.UpdatedAt = int(id), now, now
+	return nil
+}
+
+func (r *SQLiteUserRepository) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	query := "SELECT " + sqliteUserColumns + " FROM users WHERE id = ?"
+
+	user := &models.User{}
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, id)
+
+	err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.IsAdmin, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("user with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *SQLiteUserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	query := "SELECT " + sqliteUserColumns + " FROM users WHERE email = ?"
+
+	user := &models.User{}
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, email)
+
+	err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.IsAdmin, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("user with email %s not found", email)
+		}
+		return nil, models.Internal("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// SetUserAdmin grants or revokes the user's access to the admin endpoints
+func (r *SQLiteUserRepository) SetUserAdmin(ctx context.Context, id int, isAdmin bool) error {
+	query := "UPDATE users SET isAdmin = ?, updatedAt = ? WHERE id = ?"
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, isAdmin, time.Now().UTC(), id)
+	if err != nil {
+		return models.Internal("failed to update user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return models.NotFound("user with id %d not found", id)
+	}
+
+	return nil
+}
+
+// Warm opens the connection and prepares the user read behind sign-in
+func (r *SQLiteUserRepository) Warm(ctx context.Context) error {
+	stmt, err := r.db.PrepareContext(ctx, "SELECT "+sqliteUserColumns+" FROM users WHERE email = ?")
+	if err != nil {
+		return models.Internal("failed to prepare query: %w", err)
+	}
+	return stmt.Close()
+}
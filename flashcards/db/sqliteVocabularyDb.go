@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteVocabularyRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteVocabularyRepository(db *sql.DB) *SQLiteVocabularyRepository {
+	return &SQLiteVocabularyRepository{db: db}
+}
+
+func (r *SQLiteVocabularyRepository) GetVocabulary(ctx context.Context, userID, flashcardID int) (*models.Vocabulary, error) {
+	query := `
+		SELECT v.flashcard_id, v.term, v.language, v.ipa, v.pronunciation, v.cefrLevel, v.examples, 
+			v.audio IS NOT NULL, v.audioContentType, v.createdAt, v.updatedAt 
+		FROM flashcard_vocabulary v 
+		JOIN flashcards f ON f.id = v.flashcard_id 
+		WHERE v.flashcard_id = ? AND f.user_id = ?`
+
+	vocabulary := &models.Vocabulary{}
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, flashcardID, userID)
+
+	err := row.Scan(&vocabulary.FlashcardID, &vocabulary.Term, &vocabulary.Language, &vocabulary.IPA,
+		&vocabulary.Pronunciation, &vocabulary.CEFRLevel, sqliteJSON(&vocabulary.Examples), &vocabulary.HasAudio,
+		&vocabulary.AudioContentType, &vocabulary.CreatedAt, &vocabulary.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("vocabulary for flashcard with id %d not found", flashcardID)
+		}
+		return nil, models.Internal("failed to get vocabulary: %w", err)
+	}
+
+	return vocabulary, nil
+}
+
+// SaveVocabulary creates or replaces the text fields of a flashcard's
+// vocabulary. Stored audio is kept unless the term changes.
+func (r *SQLiteVocabularyRepository) SaveVocabulary(ctx context.Context, vocabulary *models.Vocabulary) error {
+	query := `
+		INSERT INTO flashcard_vocabulary (flashcard_id, term, language, ipa, pronunciation, cefrLevel, examples, createdAt, updatedAt) 
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?8) 
+		ON CONFLICT (flashcard_id) DO UPDATE SET 
+			term = excluded.term, 
+			language = excluded.language, 
+			ipa = excluded.ipa, 
+			pronunciation = excluded.pronunciation, 
+			cefrLevel = excluded.cefrLevel, 
+			examples = excluded.examples, 
+			audio = CASE WHEN flashcard_vocabulary.term = excluded.term THEN flashcard_vocabulary.audio END, 
+			updatedAt = excluded.updatedAt 
+		RETURNING createdAt, updatedAt, audio IS NOT NULL`
+
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, vocabulary.FlashcardID, vocabulary.Term, vocabulary.Language, vocabulary.IPA,
+		vocabulary.Pronunciation, vocabulary.CEFRLevel, sqliteJSON(vocabulary.Examples), time.Now().UTC())
+
+	if err := row.Scan(sqliteTime(&vocabulary.CreatedAt), sqliteTime(&vocabulary.UpdatedAt), &vocabulary.HasAudio); err != nil {
+		return models.Internal("failed to save vocabulary: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteVocabularyRepository) SaveAudio(ctx context.Context, flashcardID int, audio []byte, contentType string) error {
+	query := `
+		UPDATE flashcard_vocabulary 
+		SET audio = ?, audioContentType = ?, updatedAt = ? 
+		WHERE flashcard_id = ?`
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, audio, contentType, time.Now().UTC(), flashcardID)
+	if err != nil {
+		return models.Internal("failed to save audio: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("vocabulary for flashcard with id %d not found", flashcardID)
+	}
+
+	return nil
+}
+
+func (r *SQLiteVocabularyRepository) GetAudio(ctx context.Context, userID, flashcardID int) ([]byte, string, error) {
+	query := `
+		SELECT v.audio, v.audioContentType 
+		FROM flashcard_vocabulary v 
+		JOIN flashcards f ON f.id = v.flashcard_id 
+		WHERE v.flashcard_id = ? AND f.user_id = ? AND v.audio IS NOT NULL`
+
+	var audio []byte
+	var contentType string
+	err := executor(ctx, r.db).QueryRowContext(ctx, query, flashcardID, userID).Scan(&audio, &contentType)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", models.NotFound("audio for flashcard with id %d not found", flashcardID)
+		}
+		return nil, "", models.Internal("failed to get audio: %w", err)
+	}
+
+	return audio, contentType, nil
+}
+
+// GetFlashcardIDsWithAudio returns which of the given flashcards have stored
+// audio
+func (r *SQLiteVocabularyRepository) GetFlashcardIDsWithAudio(ctx context.Context, userID int, flashcardIDs []int) ([]int, error) {
+	query := `
+		SELECT v.flashcard_id 
+		FROM flashcard_vocabulary v 
+		JOIN flashcards f ON f.id = v.flashcard_id 
+		WHERE v.flashcard_id IN (SELECT value FROM json_each(?)) AND f.user_id = ? AND v.audio IS NOT NULL`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, sqliteJSON(flashcardIDs), userID)
+	if err != nil {
+		return nil, models.Internal("failed to query flashcards with audio: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, models.Internal("failed to scan flashcard id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, models.Internal("failed to iterate flashcards with audio: %w", err)
+	}
+
+	return ids, nil
+}
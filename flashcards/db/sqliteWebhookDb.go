@@ -0,0 +1,314 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+)
+
+type SQLiteWebhookRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteWebhookRepository(db *sql.DB) *SQLiteWebhookRepository {
+	return &SQLiteWebhookRepository{db: db}
+}
+
+// CountWebhooks counts the user's webhooks of the given kind
+func (r *SQLiteWebhookRepository) CountWebhooks(ctx context.Context, userID int, kind string) (int, error) {
+	var count int
+	if err := executor(ctx, r.db).QueryRowContext(ctx, "SELECT COUNT(*) FROM webhooks WHERE user_id = ? AND kind = ?", userID, kind).Scan(&count); err != nil {
+		return 0, models.Internal("failed to count webhooks: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *SQLiteWebhookRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	query := `
+		INSERT INTO webhooks (user_id, kind, url, secret, events, active, createdAt, updatedAt)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?7)
+		RETURNING ` + webhookColumns
+
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, webhook.UserID, webhook.Kind, webhook.URL, webhook.Secret,
+		sqliteJSON(webhook.Events), webhook.Active, time.Now().UTC())
+	created, err := scanSQLiteWebhook(row)
+	if err != nil {
+		return models.Internal("failed to create webhook: %w", err)
+	}
+
+	*webhook = *created
+	return nil
+}
+
+func (r *SQLiteWebhookRepository) GetWebhooks(ctx context.Context, userID int) ([]*models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE user_id = ? ORDER BY id`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, models.Internal("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := make([]*models.Webhook, 0)
+	for rows.Next() {
+		webhook, err := scanSQLiteWebhook(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+func (r *SQLiteWebhookRepository) GetWebhook(ctx context.Context, userID, id int) (*models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = ? AND user_id = ?`
+
+	webhook, err := scanSQLiteWebhook(executor(ctx, r.db).QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("webhook with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+func (r *SQLiteWebhookRepository) UpdateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	query := `
+		UPDATE webhooks
+		SET url = ?, events = ?, active = ?, updatedAt = ?
+		WHERE id = ? AND user_id = ?
+		RETURNING ` + webhookColumns
+
+	row := executor(ctx, r.db).QueryRowContext(ctx, query, webhook.URL, sqliteJSON(webhook.Events), webhook.Active,
+		time.Now().UTC(), webhook.ID, webhook.UserID)
+	updated, err := scanSQLiteWebhook(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.NotFound("webhook with id %d not found", webhook.ID)
+		}
+		return models.Internal("failed to update webhook: %w", err)
+	}
+
+	*webhook = *updated
+	return nil
+}
+
+func (r *SQLiteWebhookRepository) DeleteWebhook(ctx context.Context, userID, id int) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, "DELETE FROM webhooks WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return models.Internal("failed to delete webhook: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("webhook with id %d not found", id)
+	}
+
+	return nil
+}
+
+// CreateDeliveries queues the event for each of the user's active webhooks
+// of the given kind subscribed to it and returns how many were queued
+func (r *SQLiteWebhookRepository) CreateDeliveries(ctx context.Context, userID int, kind, event string, payload []byte) (int, error) {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, status, nextAttemptAt, createdAt, updatedAt)
+		SELECT id, ?3, ?4, ?5, ?6, ?6, ?6 FROM webhooks
+		WHERE user_id = ?1 AND kind = ?2 AND active AND ?3 IN (SELECT value FROM json_each(events))`
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, userID, kind, event, string(payload),
+		models.WebhookDeliveryStatusPending, time.Now().UTC())
+	if err != nil {
+		return 0, models.Internal("failed to queue webhook deliveries: %w", err)
+	}
+
+	created, err := result.RowsAffected()
+	if err != nil {
+		return 0, models.Internal("failed to get rows affected: %w", err)
+	}
+
+	return int(created), nil
+}
+
+// ListDeliveries returns one page of a webhook's delivery log and the total
+// number of deliveries
+func (r *SQLiteWebhookRepository) ListDeliveries(ctx context.Context, webhookID int, params models.ListParams) ([]*models.WebhookDelivery, int, error) {
+	var total int
+	if err := executor(ctx, r.db).QueryRowContext(ctx, "SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = ?", webhookID).Scan(&total); err != nil {
+		return nil, 0, models.Internal("failed to count webhook deliveries: %w", err)
+	}
+
+	orderBy, err := orderByClause("d", params)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries d WHERE d.webhook_id = ?` + orderBy + ` LIMIT ? OFFSET ?`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, webhookID, params.Limit, params.Offset)
+	if err != nil {
+		return nil, 0, models.Internal("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery := &models.WebhookDelivery{}
+		if err := rows.Scan(webhookDeliveryScanArgs(delivery)...); err != nil {
+			return nil, 0, models.Internal("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating over webhook deliveries: %w", err)
+	}
+
+	return deliveries, total, nil
+}
+
+// ClaimDueDeliveries returns pending deliveries whose next attempt is due,
+// with their webhook's URL, secret, owner and kind, and pushes that attempt
+// back by lease. SQLite's RETURNING cannot read the joined webhook, so the
+// due deliveries are selected and then pushed back in one transaction.
+func (r *SQLiteWebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT ` + webhookDeliveryColumns + `, w.url, w.secret, w.user_id, w.kind
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.status = ? AND d.nextAttemptAt <= ?
+		ORDER BY d.nextAttemptAt, d.id
+		LIMIT ?`
+
+	now := time.Now().UTC()
+	rows, err := tx.QueryContext(ctx, query, models.WebhookDeliveryStatusPending, now, limit)
+	if err != nil {
+		return nil, models.Internal("failed to claim webhook deliveries: %w", err)
+	}
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	ids := make([]int, 0)
+	for rows.Next() {
+		delivery := &models.WebhookDelivery{}
+		if err := rows.Scan(append(webhookDeliveryScanArgs(delivery), &delivery.URL, &delivery.Secret, &delivery.UserID, &delivery.Kind)...); err != nil {
+			rows.Close()
+			return nil, models.Internal("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+		ids = append(ids, delivery.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over webhook deliveries: %w", err)
+	}
+
+	if len(deliveries) == 0 {
+		return deliveries, nil
+	}
+
+	leaseQuery := `
+		UPDATE webhook_deliveries
+		SET nextAttemptAt = ?, updatedAt = ?
+		WHERE id IN (SELECT value FROM json_each(?))`
+
+	nextAttemptAt := now.Add(lease)
+	if _, err := tx.ExecContext(ctx, leaseQuery, nextAttemptAt, now, sqliteJSON(ids)); err != nil {
+		return nil, models.Internal("failed to claim webhook deliveries: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, models.Internal("failed to commit webhook deliveries: %w", err)
+	}
+
+	for _, delivery := range deliveries {
+		delivery.NextAttemptAt, delivery.UpdatedAt = &nextAttemptAt, now
+	}
+	return deliveries, nil
+}
+
+func (r *SQLiteWebhookRepository) MarkDelivered(ctx context.Context, id, responseStatus int) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = ?2, attempts = attempts + 1, responseStatus = ?3, error = '', nextAttemptAt = NULL,
+			deliveredAt = ?4, updatedAt = ?4
+		WHERE id = ?1`
+
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, id, models.WebhookDeliveryStatusDelivered, responseStatus, time.Now().UTC())
+	if err != nil {
+		return models.Internal("failed to update webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// FailDeliveryAttempt counts a failed attempt and tries again at
+// nextAttemptAt. Without a next attempt the delivery has failed for good.
+func (r *SQLiteWebhookRepository) FailDeliveryAttempt(ctx context.Context, id int, responseStatus *int, message string, nextAttemptAt *time.Time) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET attempts = attempts + 1, responseStatus = ?2, error = ?3, nextAttemptAt = ?4,
+			status = CASE WHEN ?4 IS NULL THEN ?5 ELSE ?6 END, updatedAt = ?7
+		WHERE id = ?1`
+
+	var next *time.Time
+	if nextAttemptAt != nil {
+		utc := nextAttemptAt.UTC()
+		next = &utc
+	}
+
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, id, responseStatus, message, next,
+		models.WebhookDeliveryStatusFailed, models.WebhookDeliveryStatusPending, time.Now().UTC())
+	if err != nil {
+		return models.Internal("failed to update webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteDeliveriesBefore removes finished deliveries created before the
+// given time. Pending ones are kept until they finish.
+func (r *SQLiteWebhookRepository) DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE createdAt < ? AND status <> ?",
+		before.UTC(), models.WebhookDeliveryStatusPending)
+	if err != nil {
+		return 0, models.Internal("failed to delete webhook deliveries: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, models.Internal("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// scanSQLiteWebhook scans the columns of webhookColumns, whose times come
+// back as text from RETURNING
+func scanSQLiteWebhook(row interface{ Scan(...any) error }) (*models.Webhook, error) {
+	webhook := &models.Webhook{}
+	err := row.Scan(&webhook.ID, &webhook.UserID, &webhook.Kind, &webhook.URL, &webhook.Secret, sqliteJSON(&webhook.Events), &webhook.Active,
+		sqliteTime(&webhook.CreatedAt), sqliteTime(&webhook.UpdatedAt))
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
@@ -0,0 +1,335 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"flashcards/models"
+)
+
+// Each SQLite repository stands in for the Postgres one behind the same
+// interface
+var (
+	_ ActivityRepository          = (*SQLiteActivityRepository)(nil)
+	_ AnalyticsRepository         = (*SQLiteAnalyticsRepository)(nil)
+	_ APIKeyRepository            = (*SQLiteAPIKeyRepository)(nil)
+	_ AttachmentRepository        = (*SQLiteAttachmentRepository)(nil)
+	_ BrandingRepository          = (*SQLiteBrandingRepository)(nil)
+	_ CatalogRepository           = (*SQLiteCatalogRepository)(nil)
+	_ ContentFilterRepository     = (*SQLiteContentFilterRepository)(nil)
+	_ DailyQuestionRepository     = (*SQLiteDailyQuestionRepository)(nil)
+	_ DeadLetterRepository        = (*SQLiteDeadLetterRepository)(nil)
+	_ DeckRepository              = (*SQLiteDeckRepository)(nil)
+	_ DeckExportRepository        = (*SQLiteDeckExportRepository)(nil)
+	_ DeckSuggestionRepository    = (*SQLiteDeckSuggestionRepository)(nil)
+	_ DigestRepository            = (*SQLiteDigestRepository)(nil)
+	_ EmbedRepository             = (*SQLiteEmbedRepository)(nil)
+	_ FlashcardRepository         = (*SQLiteFlashcardRepository)(nil)
+	_ IdempotencyRepository       = (*SQLiteIdempotencyRepository)(nil)
+	_ ImportJobRepository         = (*SQLiteImportJobRepository)(nil)
+	_ InviteRepository            = (*SQLiteInviteRepository)(nil)
+	_ JobRepository               = (*SQLiteJobRepository)(nil)
+	_ MaintenanceRepository       = (*SQLiteMaintenanceRepository)(nil)
+	_ NoteRepository              = (*SQLiteNoteRepository)(nil)
+	_ OnboardingRepository        = (*SQLiteOnboardingRepository)(nil)
+	_ OrganizationRepository      = (*SQLiteOrganizationRepository)(nil)
+	_ PromptTemplateRepository    = (*SQLitePromptTemplateRepository)(nil)
+	_ QuestionEmbeddingRepository = (*SQLiteQuestionEmbeddingRepository)(nil)
+	_ QuizSessionRepository       = (*SQLiteQuizSessionRepository)(nil)
+	_ ReportRecipientRepository   = (*SQLiteReportRecipientRepository)(nil)
+	_ ReviewRepository            = (*SQLiteReviewRepository)(nil)
+	_ RoutingRuleRepository       = (*SQLiteRoutingRuleRepository)(nil)
+	_ ScheduledJobRepository      = (*SQLiteScheduledJobRepository)(nil)
+	_ SpendRepository             = (*SQLiteSpendRepository)(nil)
+	_ StudyAnalyticsRepository    = (*SQLiteStudyAnalyticsRepository)(nil)
+	_ TagRepository               = (*SQLiteTagRepository)(nil)
+	_ TodoRepository              = (*SQLiteTodoRepository)(nil)
+	_ UsageRepository             = (*SQLiteUsageRepository)(nil)
+	_ UserRepository              = (*SQLiteUserRepository)(nil)
+	_ VocabularyRepository        = (*SQLiteVocabularyRepository)(nil)
+	_ WebhookRepository           = (*SQLiteWebhookRepository)(nil)
+	_ UnitOfWork                  = (*SQLiteUnitOfWork)(nil)
+)
+
+// openTestSQLite opens a fresh database file, closed when the test ends
+func openTestSQLite(t testing.TB, path string) *sql.DB {
+	t.Helper()
+	database, err := openSQLite("test", path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+func newTestSQLite(t *testing.T) *sql.DB {
+	return openTestSQLite(t, filepath.Join(t.TempDir(), "flashcards.db"))
+}
+
+func createTestSQLiteUser(t *testing.T, database *sql.DB) *models.User {
+	t.Helper()
+	users := NewSQLiteUserRepository(database)
+	user := &models.User{Email: "ada@example.com", PasswordHash: "hash"}
+	if err := users.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return user
+}
+
+func TestSQLiteTagsFilterNotes(t *testing.T) {
+	ctx := context.Background()
+	database := newTestSQLite(t)
+	user := createTestSQLiteUser(t, database)
+	notes := NewSQLiteNoteRepository(database)
+	tags := NewSQLiteTagRepository(database)
+
+	tagged := &models.Note{UserID: user.ID, Content: "Cells are the unit of life"}
+	untagged := &models.Note{UserID: user.ID, Content: "Mitochondria make ATP"}
+	for _, note := range []*models.Note{tagged, untagged} {
+		if err := notes.CreateNote(ctx, note); err != nil {
+			t.Fatalf("CreateNote: %v", err)
+		}
+	}
+	if err := tags.AddTagsToNote(ctx, user.ID, tagged.ID, []string{"bio", "cells"}); err != nil {
+		t.Fatalf("AddTagsToNote: %v", err)
+	}
+
+	all, err := tags.GetAllTags(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetAllTags: %v", err)
+	}
+	if len(all) != 2 || all[0].Name != "bio" || all[0].NoteCount != 1 {
+		t.Fatalf("tags = %+v, want bio and cells on one note each", all)
+	}
+
+	got, err := notes.GetNoteByID(ctx, user.ID, tagged.ID)
+	if err != nil {
+		t.Fatalf("GetNoteByID: %v", err)
+	}
+	if !slices.Equal(got.Tags, []string{"bio", "cells"}) || !got.CreatedAt.Equal(tagged.CreatedAt) {
+		t.Errorf("note = tags %v created %v, want [bio cells] created %v", got.Tags, got.CreatedAt, tagged.CreatedAt)
+	}
+}
+
+func TestSQLiteUnitOfWorkRollsBack(t *testing.T) {
+	ctx := context.Background()
+	database := newTestSQLite(t)
+	user := createTestSQLiteUser(t, database)
+	uow := NewSQLiteUnitOfWork(database)
+	decks := NewSQLiteDeckRepository(database)
+
+	failed := errors.New("failed")
+	err := uow.Do(ctx, func(ctx context.Context) error {
+		if err := decks.CreateDeck(ctx, &models.Deck{UserID: user.ID, Name: "Rolled back"}); err != nil {
+			return err
+		}
+		// Nested units of work join the outer one
+		return uow.Do(ctx, func(ctx context.Context) error { return failed })
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("Do = %v, want %v", err, failed)
+	}
+	if all, _ := decks.GetAllDecks(ctx, user.ID); len(all) != 0 {
+		t.Fatalf("decks after rollback = %v, want none", all)
+	}
+
+	err = uow.Do(ctx, func(ctx context.Context) error {
+		return decks.CreateDeck(ctx, &models.Deck{UserID: user.ID, Name: "Kept"})
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if all, _ := decks.GetAllDecks(ctx, user.ID); len(all) != 1 || all[0].Name != "Kept" {
+		t.Fatalf("decks after commit = %v, want Kept", all)
+	}
+}
+
+func TestSQLiteJobLease(t *testing.T) {
+	ctx := context.Background()
+	database := newTestSQLite(t)
+	user := createTestSQLiteUser(t, database)
+	jobs := NewSQLiteJobRepository(database)
+
+	job := &models.Job{UserID: user.ID, Kind: models.JobKindSummarizeNotes, Payload: []byte(`{}`)}
+	if err := jobs.CreateJob(ctx, job); err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	if job.ID == 0 || job.Status != models.JobStatusQueued || job.CreatedAt.IsZero() {
+		t.Fatalf("created job = %+v, want a queued job with an ID and time", job)
+	}
+
+	claimed, err := jobs.ClaimNextJob(ctx, time.Minute)
+	if err != nil || claimed == nil || claimed.ID != job.ID || claimed.Status != models.JobStatusRunning || claimed.StartedAt == nil {
+		t.Fatalf("ClaimNextJob = %+v, %v, want job %d running", claimed, err, job.ID)
+	}
+	// The lease holds the job until it runs out
+	if again, err := jobs.ClaimNextJob(ctx, time.Minute); err != nil || again != nil {
+		t.Fatalf("ClaimNextJob during the lease = %+v, %v, want none", again, err)
+	}
+
+	if err := jobs.FailJobAttempt(ctx, job.ID, "boom", nil); err != nil {
+		t.Fatalf("FailJobAttempt: %v", err)
+	}
+	failed, err := jobs.GetJob(ctx, user.ID, job.ID)
+	if err != nil {
+		t.Fatalf("GetJob: %v", err)
+	}
+	if failed.Status != models.JobStatusFailed || failed.Attempts != 1 || failed.Error != "boom" || failed.CompletedAt == nil {
+		t.Errorf("job after its last attempt = %+v, want failed once with boom", failed)
+	}
+}
+
+func TestSQLiteDeckSuggestionsSkipDismissed(t *testing.T) {
+	ctx := context.Background()
+	database := newTestSQLite(t)
+	user := createTestSQLiteUser(t, database)
+	decks := NewSQLiteDeckRepository(database)
+	flashcards := NewSQLiteFlashcardRepository(database)
+	suggestions := NewSQLiteDeckSuggestionRepository(database)
+
+	deck := &models.Deck{UserID: user.ID, Name: "Bio"}
+	if err := decks.CreateDeck(ctx, deck); err != nil {
+		t.Fatalf("CreateDeck: %v", err)
+	}
+	for _, content := range []string{"Cell", " cell ", "Nucleus"} {
+		flashcard := &models.Flashcard{UserID: user.ID, DeckID: &deck.ID, Content: content}
+		if err := flashcards.CreateFlashcard(ctx, flashcard); err != nil {
+			t.Fatalf("CreateFlashcard: %v", err)
+		}
+	}
+
+	found, err := suggestions.FindSuggestions(ctx, 8)
+	if err != nil {
+		t.Fatalf("FindSuggestions: %v", err)
+	}
+	if len(found) != 1 || found[0].Kind != models.SuggestionDuplicate || !slices.Equal(found[0].FlashcardIDs, []int{1, 2}) {
+		t.Fatalf("suggestions = %+v, want cards 1 and 2 as duplicates", found)
+	}
+	if stored, err := suggestions.ReplaceOpenSuggestions(ctx, found); err != nil || stored != 1 {
+		t.Fatalf("ReplaceOpenSuggestions = %d, %v, want 1", stored, err)
+	}
+
+	open, err := suggestions.ListOpenSuggestions(ctx, user.ID, deck.ID)
+	if err != nil || len(open) != 1 {
+		t.Fatalf("ListOpenSuggestions = %+v, %v, want one", open, err)
+	}
+	dismissed, err := suggestions.ResolveSuggestion(ctx, user.ID, deck.ID, open[0].ID, models.SuggestionDismissed)
+	if err != nil || dismissed.ResolvedAt == nil {
+		t.Fatalf("ResolveSuggestion = %+v, %v, want it resolved", dismissed, err)
+	}
+	if _, err := suggestions.ResolveSuggestion(ctx, user.ID, deck.ID, open[0].ID, models.SuggestionDismissed); !errors.Is(err, models.ErrConflict) {
+		t.Errorf("ResolveSuggestion twice = %v, want a conflict", err)
+	}
+
+	if stored, err := suggestions.ReplaceOpenSuggestions(ctx, found); err != nil || stored != 0 {
+		t.Errorf("ReplaceOpenSuggestions after dismissing = %d, %v, want 0", stored, err)
+	}
+}
+
+func TestSQLiteWebhookDeliveries(t *testing.T) {
+	ctx := context.Background()
+	database := newTestSQLite(t)
+	user := createTestSQLiteUser(t, database)
+	webhooks := NewSQLiteWebhookRepository(database)
+
+	for _, events := range [][]string{{models.WebhookEventNoteCreated}, {models.WebhookEventQuizCompleted}} {
+		webhook := &models.Webhook{UserID: user.ID, Kind: "webhook", URL: "https://example.com/hook", Secret: "s", Events: events, Active: true}
+		if err := webhooks.CreateWebhook(ctx, webhook); err != nil {
+			t.Fatalf("CreateWebhook: %v", err)
+		}
+	}
+
+	queued, err := webhooks.CreateDeliveries(ctx, user.ID, "webhook", models.WebhookEventNoteCreated, []byte(`{"id":1}`))
+	if err != nil || queued != 1 {
+		t.Fatalf("CreateDeliveries = %d, %v, want 1 for the subscribed webhook", queued, err)
+	}
+
+	claimed, err := webhooks.ClaimDueDeliveries(ctx, 10, time.Minute)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("ClaimDueDeliveries = %+v, %v, want one", claimed, err)
+	}
+	if delivery := claimed[0]; delivery.URL != "https://example.com/hook" || delivery.UserID != user.ID || string(delivery.Payload) != `{"id":1}` {
+		t.Errorf("claimed delivery = %+v, want its webhook's URL, owner and payload", delivery)
+	}
+	if again, err := webhooks.ClaimDueDeliveries(ctx, 10, time.Minute); err != nil || len(again) != 0 {
+		t.Errorf("ClaimDueDeliveries during the lease = %+v, %v, want none", again, err)
+	}
+}
+
+// FuzzSQLiteUpdateNote throws arbitrary fields and values at the update
+// builder. Fields that are not updatable columns of notes, among them the
+// note's id, owner and version, must be refused before reaching the query,
+// and no update may touch another user's note.
+func FuzzSQLiteUpdateNote(f *testing.F) {
+	for _, seed := range []struct{ field, value string }{
+		{"content", "Photosynthesis"},
+		{"title", "日本語のノート 🌱"},
+		{"content = 'x', user_id", "2"},
+		{"content = content; DROP TABLE notes; --", ""},
+		{"missing_column", "x"},
+		{"", "x"},
+		{"version", "'; --"},
+		{"user_id", "2"},
+		{"id", "1"},
+		{"updatedAt", "2000-01-01"},
+	} {
+		f.Add(seed.field, seed.value)
+	}
+
+	ctx := context.Background()
+	database := openTestSQLite(f, filepath.Join(f.TempDir(), "flashcards.db"))
+	users := NewSQLiteUserRepository(database)
+	notes := NewSQLiteNoteRepository(database)
+
+	owner, other := &models.User{Email: "owner@example.com"}, &models.User{Email: "other@example.com"}
+	for _, user := range []*models.User{owner, other} {
+		if err := users.CreateUser(ctx, user); err != nil {
+			f.Fatalf("CreateUser: %v", err)
+		}
+	}
+	untouched := &models.Note{UserID: other.ID, Content: "Not yours"}
+	if err := notes.CreateNote(ctx, untouched); err != nil {
+		f.Fatalf("CreateNote: %v", err)
+	}
+
+	f.Fuzz(func(t *testing.T, field, value string) {
+		note := &models.Note{UserID: owner.ID, Content: "Cells"}
+		if err := notes.CreateNote(ctx, note); err != nil {
+			t.Fatalf("CreateNote: %v", err)
+		}
+
+		err := notes.UpdateNote(ctx, owner.ID, note.ID, note.Version, map[string]any{field: value})
+		refused := errors.Is(err, models.ErrValidation)
+		switch {
+		case field == "id" || field == "user_id" || field == "version" || field == "updatedAt":
+			if !refused {
+				t.Fatalf("UpdateNote let the caller set %q: %v", field, err)
+			}
+		case !updatableColumns["notes"][field]:
+			if !refused {
+				t.Fatalf("UpdateNote with field %q = %v, want it refused", field, err)
+			}
+		case err == nil:
+			updated, err := notes.GetNoteByID(ctx, owner.ID, note.ID)
+			if err != nil {
+				t.Fatalf("note %d after update of %q: %v", note.ID, field, err)
+			}
+			if updated.Version != note.Version+1 {
+				t.Errorf("version after update of %q = %d, want %d", field, updated.Version, note.Version+1)
+			}
+		}
+
+		got, err := notes.GetNoteByID(ctx, other.ID, untouched.ID)
+		if err != nil {
+			t.Fatalf("GetNoteByID of the other user's note: %v", err)
+		}
+		if got.Content != untouched.Content || got.Version != untouched.Version {
+			t.Fatalf("update of %q = %q changed another user's note to %+v", field, value, got)
+		}
+	})
+}
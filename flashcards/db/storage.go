@@ -0,0 +1,352 @@
+package db
+
+// Storage drivers every repository can be opened on
+const (
+	STORAGE_POSTGRES = "postgres"
+	STORAGE_SQLITE   = "sqlite"
+)
+
+// NoteStore is a NoteRepository the server warms up
+type NoteStore interface {
+	NoteRepository
+	Warmer
+}
+
+// FlashcardStore is a FlashcardRepository the server warms up
+type FlashcardStore interface {
+	FlashcardRepository
+	Warmer
+}
+
+// ReviewStore is a ReviewRepository the server warms up
+type ReviewStore interface {
+	ReviewRepository
+	Warmer
+}
+
+// UserStore is a UserRepository the server warms up
+type UserStore interface {
+	UserRepository
+	Warmer
+}
+
+// NewNoteStore returns the note repository of the database's storage
+// driver, on its shared pool
+func NewNoteStore(database *Database) NoteStore {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteNoteRepository(database.db)
+	}
+	return NewPostgresNoteRepository(database.db)
+}
+
+// NewFlashcardStore returns the flashcard repository, like NewNoteStore
+func NewFlashcardStore(database *Database) FlashcardStore {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteFlashcardRepository(database.db)
+	}
+	return NewPostgresFlashcardRepository(database.db)
+}
+
+// NewUnitOfWork returns the unit of work of the database's storage
+// driver, which runs transactions across the repositories on the same pool
+func NewUnitOfWork(database *Database) UnitOfWork {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteUnitOfWork(database.db)
+	}
+	return NewPostgresUnitOfWork(database.db)
+}
+
+// NewActivityStore returns the activity repository, like NewNoteStore
+func NewActivityStore(database *Database) ActivityRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteActivityRepository(database.db)
+	}
+	return NewPostgresActivityRepository(database.db)
+}
+
+// NewAnalyticsStore returns the analytics repository, like NewNoteStore
+func NewAnalyticsStore(database *Database) AnalyticsRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteAnalyticsRepository(database.db)
+	}
+	return NewPostgresAnalyticsRepository(database.db)
+}
+
+// NewAPIKeyStore returns the API key repository, like NewNoteStore
+func NewAPIKeyStore(database *Database) APIKeyRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteAPIKeyRepository(database.db)
+	}
+	return NewPostgresAPIKeyRepository(database.db)
+}
+
+// NewAttachmentStore returns the attachment repository, like NewNoteStore
+func NewAttachmentStore(database *Database) AttachmentRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteAttachmentRepository(database.db)
+	}
+	return NewPostgresAttachmentRepository(database.db)
+}
+
+// NewBrandingStore returns the branding repository, like NewNoteStore
+func NewBrandingStore(database *Database) BrandingRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteBrandingRepository(database.db)
+	}
+	return NewPostgresBrandingRepository(database.db)
+}
+
+// NewCatalogStore returns the catalog repository, like NewNoteStore
+func NewCatalogStore(database *Database) CatalogRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteCatalogRepository(database.db)
+	}
+	return NewPostgresCatalogRepository(database.db)
+}
+
+// NewContentFilterStore returns the content filter repository, like
+// NewNoteStore
+func NewContentFilterStore(database *Database) ContentFilterRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteContentFilterRepository(database.db)
+	}
+	return NewPostgresContentFilterRepository(database.db)
+}
+
+// NewDailyQuestionStore returns the daily question repository, like
+// NewNoteStore
+func NewDailyQuestionStore(database *Database) DailyQuestionRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteDailyQuestionRepository(database.db)
+	}
+	return NewPostgresDailyQuestionRepository(database.db)
+}
+
+// NewDeadLetterStore returns the dead letter repository, like NewNoteStore
+func NewDeadLetterStore(database *Database) DeadLetterRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteDeadLetterRepository(database.db)
+	}
+	return NewPostgresDeadLetterRepository(database.db)
+}
+
+// NewDeckStore returns the deck repository, like NewNoteStore
+func NewDeckStore(database *Database) DeckRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteDeckRepository(database.db)
+	}
+	return NewPostgresDeckRepository(database.db)
+}
+
+// NewDeckExportStore returns the deck export repository, like NewNoteStore
+func NewDeckExportStore(database *Database) DeckExportRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteDeckExportRepository(database.db)
+	}
+	return NewPostgresDeckExportRepository(database.db)
+}
+
+// NewDeckSuggestionStore returns the deck suggestion repository, like
+// NewNoteStore
+func NewDeckSuggestionStore(database *Database) DeckSuggestionRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteDeckSuggestionRepository(database.db)
+	}
+	return NewPostgresDeckSuggestionRepository(database.db)
+}
+
+// NewDigestStore returns the digest repository, like NewNoteStore
+func NewDigestStore(database *Database) DigestRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteDigestRepository(database.db)
+	}
+	return NewPostgresDigestRepository(database.db)
+}
+
+// NewEmbedStore returns the embed repository, like NewNoteStore
+func NewEmbedStore(database *Database) EmbedRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteEmbedRepository(database.db)
+	}
+	return NewPostgresEmbedRepository(database.db)
+}
+
+// NewIdempotencyStore returns the idempotency repository, like NewNoteStore
+func NewIdempotencyStore(database *Database) IdempotencyRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteIdempotencyRepository(database.db)
+	}
+	return NewPostgresIdempotencyRepository(database.db)
+}
+
+// NewImportJobStore returns the import job repository, like NewNoteStore
+func NewImportJobStore(database *Database) ImportJobRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteImportJobRepository(database.db)
+	}
+	return NewPostgresImportJobRepository(database.db)
+}
+
+// NewInviteStore returns the invite repository, like NewNoteStore
+func NewInviteStore(database *Database) InviteRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteInviteRepository(database.db)
+	}
+	return NewPostgresInviteRepository(database.db)
+}
+
+// NewJobStore returns the job repository, like NewNoteStore
+func NewJobStore(database *Database) JobRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteJobRepository(database.db)
+	}
+	return NewPostgresJobRepository(database.db)
+}
+
+// NewMaintenanceStore returns the maintenance repository, like NewNoteStore
+func NewMaintenanceStore(database *Database) MaintenanceRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteMaintenanceRepository(database.db)
+	}
+	return NewPostgresMaintenanceRepository(database.db)
+}
+
+// NewOnboardingStore returns the onboarding repository, like NewNoteStore
+func NewOnboardingStore(database *Database) OnboardingRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteOnboardingRepository(database.db)
+	}
+	return NewPostgresOnboardingRepository(database.db)
+}
+
+// NewOrganizationStore returns the organization repository, like NewNoteStore
+func NewOrganizationStore(database *Database) OrganizationRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteOrganizationRepository(database.db)
+	}
+	return NewPostgresOrganizationRepository(database.db)
+}
+
+// NewPromptTemplateStore returns the prompt template repository, like
+// NewNoteStore
+func NewPromptTemplateStore(database *Database) PromptTemplateRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLitePromptTemplateRepository(database.db)
+	}
+	return NewPostgresPromptTemplateRepository(database.db)
+}
+
+// NewQuestionEmbeddingStore returns the question embedding repository, like
+// NewNoteStore
+func NewQuestionEmbeddingStore(database *Database) QuestionEmbeddingRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteQuestionEmbeddingRepository(database.db)
+	}
+	return NewPostgresQuestionEmbeddingRepository(database.db)
+}
+
+// NewQuizSessionStore returns the quiz session repository, like NewNoteStore
+func NewQuizSessionStore(database *Database) QuizSessionRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteQuizSessionRepository(database.db)
+	}
+	return NewPostgresQuizSessionRepository(database.db)
+}
+
+// NewReportRecipientStore returns the report recipient repository, like
+// NewNoteStore
+func NewReportRecipientStore(database *Database) ReportRecipientRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteReportRecipientRepository(database.db)
+	}
+	return NewPostgresReportRecipientRepository(database.db)
+}
+
+// NewReviewStore returns the review repository, like NewNoteStore
+func NewReviewStore(database *Database) ReviewStore {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteReviewRepository(database.db)
+	}
+	return NewPostgresReviewRepository(database.db)
+}
+
+// NewRoutingRuleStore returns the routing rule repository, like NewNoteStore
+func NewRoutingRuleStore(database *Database) RoutingRuleRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteRoutingRuleRepository(database.db)
+	}
+	return NewPostgresRoutingRuleRepository(database.db)
+}
+
+// NewScheduledJobStore returns the scheduled job repository, like NewNoteStore
+func NewScheduledJobStore(database *Database) ScheduledJobRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteScheduledJobRepository(database.db)
+	}
+	return NewPostgresScheduledJobRepository(database.db)
+}
+
+// NewSpendStore returns the spend repository, like NewNoteStore
+func NewSpendStore(database *Database) SpendRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteSpendRepository(database.db)
+	}
+	return NewPostgresSpendRepository(database.db)
+}
+
+// NewStudyAnalyticsStore returns the study analytics repository, like
+// NewNoteStore
+func NewStudyAnalyticsStore(database *Database) StudyAnalyticsRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteStudyAnalyticsRepository(database.db)
+	}
+	return NewPostgresStudyAnalyticsRepository(database.db)
+}
+
+// NewTagStore returns the tag repository, like NewNoteStore
+func NewTagStore(database *Database) TagRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteTagRepository(database.db)
+	}
+	return NewPostgresTagRepository(database.db)
+}
+
+// NewTodoStore returns the todo repository, like NewNoteStore
+func NewTodoStore(database *Database) TodoRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteTodoRepository(database.db)
+	}
+	return NewPostgresTodoRepository(database.db)
+}
+
+// NewUsageStore returns the usage repository, like NewNoteStore
+func NewUsageStore(database *Database) UsageRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteUsageRepository(database.db)
+	}
+	return NewPostgresUsageRepository(database.db)
+}
+
+// NewUserStore returns the user repository, like NewNoteStore
+func NewUserStore(database *Database) UserStore {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteUserRepository(database.db)
+	}
+	return NewPostgresUserRepository(database.db)
+}
+
+// NewVocabularyStore returns the vocabulary repository, like NewNoteStore
+func NewVocabularyStore(database *Database) VocabularyRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteVocabularyRepository(database.db)
+	}
+	return NewPostgresVocabularyRepository(database.db)
+}
+
+// NewWebhookStore returns the webhook repository, like NewNoteStore
+func NewWebhookStore(database *Database) WebhookRepository {
+	if database.driver == STORAGE_SQLITE {
+		return NewSQLiteWebhookRepository(database.db)
+	}
+	return NewPostgresWebhookRepository(database.db)
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"flashcards/models"
+
+	_ "github.com/lib/pq"
+)
+
+type TagRepository interface {
+	GetAllTags(ctx context.Context, userID int) ([]*models.Tag, error)
+	AddTagsToNote(ctx context.Context, userID, noteID int, names []string) error
+	RemoveTagFromNote(ctx context.Context, userID, noteID int, name string) error
+}
+
+type PostgresTagRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresTagRepository(db *sql.DB) *PostgresTagRepository {
+	return &PostgresTagRepository{db: db}
+}
+
+// GetAllTags returns the tags used on the user's notes. Tag names are shared
+// between users, so only tags with at least one of the user's notes are listed.
+func (r *PostgresTagRepository) GetAllTags(ctx context.Context, userID int) ([]*models.Tag, error) {
+	query := `
+		SELECT t.id, t.name, t.createdAt, COUNT(nt.note_id) 
+		FROM gocourse.tags t 
+		JOIN gocourse.note_tags nt ON nt.tag_id = t.id 
+		JOIN gocourse.notes n ON n.id = nt.note_id 
+		WHERE n.user_id = $1 
+		GROUP BY t.id 
+		ORDER BY t.name ASC`
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, models.Internal("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make([]*models.Tag, 0)
+	for rows.Next() {
+		tag := &models.Tag{}
+		err := rows.Scan(&tag.ID, &tag.Name, &tag.CreatedAt, &tag.NoteCount)
+		if err != nil {
+			return nil, models.Internal("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// AddTagsToNote creates any missing tags and links them to the note.
+// Tags already attached to the note are left untouched.
+func (r *PostgresTagRepository) AddTagsToNote(ctx context.Context, userID, noteID int, names []string) error {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM gocourse.notes WHERE id = $1 AND user_id = $2)", noteID, userID).Scan(&exists); err != nil {
+		return models.Internal("failed to check note: %w", err)
+	}
+	if !exists {
+		return models.NotFound("note with id %d not found", noteID)
+	}
+
+	tagQuery := `
+		INSERT INTO gocourse.tags (name) 
+		VALUES ($1) 
+		ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name 
+		RETURNING id`
+
+	linkQuery := `
+		INSERT INTO gocourse.note_tags (note_id, tag_id) 
+		VALUES ($1, $2) 
+		ON CONFLICT DO NOTHING`
+
+	for _, name := range names {
+		var tagID int
+		if err := tx.QueryRowContext(ctx, tagQuery, name).Scan(&tagID); err != nil {
+			return models.Internal("failed to create tag: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, linkQuery, noteID, tagID); err != nil {
+			return models.Internal("failed to tag note: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit tags: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresTagRepository) RemoveTagFromNote(ctx context.Context, userID, noteID int, name string) error {
+	query := `
+		DELETE FROM gocourse.note_tags 
+		WHERE note_id = $1 AND tag_id = (SELECT id FROM gocourse.tags WHERE name = $2) 
+			AND note_id IN (SELECT id FROM gocourse.notes WHERE user_id = $3)`
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, noteID, name, userID)
+	if err != nil {
+		return models.Internal("failed to remove tag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("tag %q on note with id %d not found", name, noteID)
+	}
+
+	return nil
+}
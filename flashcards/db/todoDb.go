@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
@@ -10,84 +11,76 @@ import (
 )
 
 type TodoRepository interface {
-	CreateTodo(todo *models.Todo) error
-	GetTodoByID(id int) (*models.Todo, error)
-	GetAllTodos() ([]*models.Todo, error)
-	UpdateTodo(id int, updates map[string]any) error
-	DeleteTodo(id int) error
+	CreateTodo(ctx context.Context, todo *models.Todo) error
+	GetTodoByID(ctx context.Context, userID, id int) (*models.Todo, error)
+	GetAllTodos(ctx context.Context, userID int) ([]*models.Todo, error)
+	UpdateTodo(ctx context.Context, userID, id int, updates map[string]any) error
+	DeleteTodo(ctx context.Context, userID, id int) error
 }
 
 type PostgresTodoRepository struct {
 	db *sql.DB
 }
 
-func NewPostgresTodoRepository(databaseURL string) (*PostgresTodoRepository, error) {
-	db, err := sql.Open("postgres", databaseURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
-
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
-	}
-
-	return &PostgresTodoRepository{db: db}, nil
+func NewPostgresTodoRepository(db *sql.DB) *PostgresTodoRepository {
+	return &PostgresTodoRepository{db: db}
 }
 
-func (r *PostgresTodoRepository) CreateTodo(todo *models.Todo) error {
+func (r *PostgresTodoRepository) CreateTodo(ctx context.Context, todo *models.Todo) error {
 	query := `
-		INSERT INTO gocourse.todos (title, description, completed) 
-		VALUES ($1, $2, $3) 
+		INSERT INTO gocourse.todos (user_id, title, description, completed) 
+		VALUES ($1, $2, $3, $4) 
 		RETURNING id, createdAt, updatedAt`
 
-	row := r.db.QueryRow(query, todo.Title, todo.Description, todo.Completed)
+	row := r.db.QueryRowContext(ctx, query, todo.UserID, todo.Title, todo.Description, todo.Completed)
 
 	err := row.Scan(&todo.ID, &todo.CreatedAt, &todo.UpdatedAt)
 	if err != nil {
-		return fmt.Errorf("failed to create todo: %w", err)
+		return models.Internal("failed to create todo: %w", err)
 	}
 
 	return nil
 }
 
-func (r *PostgresTodoRepository) GetTodoByID(id int) (*models.Todo, error) {
+func (r *PostgresTodoRepository) GetTodoByID(ctx context.Context, userID, id int) (*models.Todo, error) {
 	query := `
-		SELECT id, title, description, completed, createdAt, updatedAt 
+		SELECT id, user_id, title, description, completed, createdAt, updatedAt 
 		FROM gocourse.todos 
-		WHERE id = $1`
+		WHERE id = $1 AND user_id = $2`
 
 	todo := &models.Todo{}
-	row := r.db.QueryRow(query, id)
+	row := r.db.QueryRowContext(ctx, query, id, userID)
 
-	err := row.Scan(&todo.ID, &todo.Title, &todo.Description, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt)
+	err := row.Scan(&todo.ID, &todo.UserID, &todo.Title, &todo.Description, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("todo with id %d not found", id)
+			return nil, models.NotFound("todo with id %d not found", id)
 		}
-		return nil, fmt.Errorf("failed to get todo: %w", err)
+		return nil, models.Internal("failed to get todo: %w", err)
 	}
 
 	return todo, nil
 }
 
-func (r *PostgresTodoRepository) GetAllTodos() ([]*models.Todo, error) {
+func (r *PostgresTodoRepository) GetAllTodos(ctx context.Context, userID int) ([]*models.Todo, error) {
 	query := `
-		SELECT id, title, description, completed, createdAt, updatedAt 
+		SELECT id, user_id, title, description, completed, createdAt, updatedAt 
 		FROM gocourse.todos 
+		WHERE user_id = $1 
 		ORDER BY createdAt DESC`
 
-	rows, err := r.db.Query(query)
+	rows, err := r.db.QueryContext(ctx, query, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query todos: %w", err)
+		return nil, models.Internal("failed to query todos: %w", err)
 	}
 	defer rows.Close()
 
 	todos := make([]*models.Todo, 0)
 	for rows.Next() {
 		todo := &models.Todo{}
-		err := rows.Scan(&todo.ID, &todo.Title, &todo.Description, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt)
+		err := rows.Scan(&todo.ID, &todo.UserID, &todo.Title, &todo.Description, &todo.Completed, &todo.CreatedAt, &todo.UpdatedAt)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan todo: %w", err)
+			return nil, models.Internal("failed to scan todo: %w", err)
 		}
 		todos = append(todos, todo)
 	}
@@ -99,9 +92,9 @@ func (r *PostgresTodoRepository) GetAllTodos() ([]*models.Todo, error) {
 	return todos, nil
 }
 
-func (r *PostgresTodoRepository) UpdateTodo(id int, updates map[string]any) error {
-	if len(updates) == 0 {
-		return fmt.Errorf("no updates provided")
+func (r *PostgresTodoRepository) UpdateTodo(ctx context.Context, userID, id int, updates map[string]any) error {
+	if err := checkUpdates("todos", updates); err != nil {
+		return err
 	}
 
 	query := "UPDATE gocourse.todos SET "
@@ -117,46 +110,42 @@ func (r *PostgresTodoRepository) UpdateTodo(id int, updates map[string]any) erro
 		argIndex++
 	}
 
-	query += fmt.Sprintf(", updatedAt = NOW() WHERE id = $%d", argIndex)
-	args = append(args, id)
+	query += fmt.Sprintf(", updatedAt = NOW() WHERE id = $%d AND user_id = $%d", argIndex, argIndex+1)
+	args = append(args, id, userID)
 
-	result, err := r.db.Exec(query, args...)
+	result, err := r.db.ExecContext(ctx, query, args...)
 	if err != nil {
-		return fmt.Errorf("failed to update todo: %w", err)
+		return models.Internal("failed to update todo: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return models.Internal("failed to get rows affected: %w", err)
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("todo with id %d not found", id)
+		return models.NotFound("todo with id %d not found", id)
 	}
 
 	return nil
 }
 
-func (r *PostgresTodoRepository) DeleteTodo(id int) error {
-	query := "DELETE FROM gocourse.todos WHERE id = $1"
+func (r *PostgresTodoRepository) DeleteTodo(ctx context.Context, userID, id int) error {
+	query := "DELETE FROM gocourse.todos WHERE id = $1 AND user_id = $2"
 
-	result, err := r.db.Exec(query, id)
+	result, err := r.db.ExecContext(ctx, query, id, userID)
 	if err != nil {
-		return fmt.Errorf("failed to delete todo: %w", err)
+		return models.Internal("failed to delete todo: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return models.Internal("failed to get rows affected: %w", err)
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("todo with id %d not found", id)
+		return models.NotFound("todo with id %d not found", id)
 	}
 
 	return nil
 }
-
-func (r *PostgresTodoRepository) Close() error {
-	return r.db.Close()
-}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"flashcards/models"
+)
+
+// UnitOfWork runs a function in one database transaction, so services can
+// compose operations of several repositories atomically. Repositories run
+// their statements on the transaction carried by the function's context,
+// which is committed when the function returns nil and rolled back when it
+// returns an error or panics. A unit of work started inside another joins
+// it.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txContextKey struct{}
+
+// PostgresUnitOfWork begins transactions on the pool the repositories
+// share, so any of them can run on the transaction
+type PostgresUnitOfWork struct {
+	db *sql.DB
+}
+
+func NewPostgresUnitOfWork(db *sql.DB) *PostgresUnitOfWork {
+	return &PostgresUnitOfWork{db: db}
+}
+
+func (u *PostgresUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return runUnitOfWork(ctx, u.db, fn)
+}
+
+// runUnitOfWork runs fn in a transaction begun on db, or in the one ctx
+// already carries
+func runUnitOfWork(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txContextKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Internal("failed to begin transaction: %w", err)
+	}
+	// Rolls back on an error or panic; a no-op after Commit
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txContextKey{}, tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Internal("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// queryer is what repositories run statements on: their pool, or the
+// transaction of a unit of work
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// executor returns the transaction of the unit of work in ctx, or db
+// outside one
+func executor(ctx context.Context, db *sql.DB) queryer {
+	if tx, ok := ctx.Value(txContextKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// scopedTx is a transaction a repository method began, or the unit of
+// work's it joined. A joined transaction is committed or rolled back by
+// the unit of work, so Commit and Rollback leave it alone.
+type scopedTx struct {
+	*sql.Tx
+	joined bool
+}
+
+// beginTx begins a transaction on db for a repository method that needs
+// several statements to succeed together, or joins the unit of work in ctx
+func beginTx(ctx context.Context, db *sql.DB) (*scopedTx, error) {
+	if tx, ok := ctx.Value(txContextKey{}).(*sql.Tx); ok {
+		return &scopedTx{Tx: tx, joined: true}, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &scopedTx{Tx: tx}, nil
+}
+
+func (t *scopedTx) Commit() error {
+	if t.joined {
+		return nil
+	}
+	return t.Tx.Commit()
+}
+
+func (t *scopedTx) Rollback() error {
+	if t.joined {
+		return nil
+	}
+	return t.Tx.Rollback()
+}
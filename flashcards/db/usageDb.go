@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"flashcards/models"
+
+	_ "github.com/lib/pq"
+)
+
+type UsageRepository interface {
+	RecordLLMCall(ctx context.Context, call *models.LLMCall) error
+	GetCostSince(ctx context.Context, userID int, since time.Time) (float64, error)
+	GetDailyUsage(ctx context.Context, userID int, since time.Time) ([]*models.DailyLLMUsage, error)
+	GetModelUsage(ctx context.Context, userID int, since time.Time) ([]*models.ModelLLMUsage, error)
+	GetUsageByUser(ctx context.Context, since time.Time, limit int) ([]*models.UserLLMUsage, error)
+	DeleteLLMCallsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type PostgresUsageRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresUsageRepository(db *sql.DB) *PostgresUsageRepository {
+	return &PostgresUsageRepository{db: db}
+}
+
+func (r *PostgresUsageRepository) RecordLLMCall(ctx context.Context, call *models.LLMCall) error {
+	query := `
+		INSERT INTO gocourse.llm_calls (user_id, model, inputTokens, outputTokens, costUsd) 
+		VALUES ($1, $2, $3, $4, $5) 
+		RETURNING id, createdAt`
+
+	row := r.db.QueryRowContext(ctx, query, call.UserID, call.Model, call.InputTokens, call.OutputTokens, call.CostUSD)
+	if err := row.Scan(&call.ID, &call.CreatedAt); err != nil {
+		return models.Internal("failed to record llm call: %w", err)
+	}
+
+	return nil
+}
+
+// GetCostSince returns the estimated cost of the user's calls since the
+// given time
+func (r *PostgresUsageRepository) GetCostSince(ctx context.Context, userID int, since time.Time) (float64, error) {
+	query := `SELECT COALESCE(SUM(costUsd), 0) FROM gocourse.llm_calls WHERE user_id = $1 AND createdAt >= $2`
+
+	var cost float64
+	if err := r.db.QueryRowContext(ctx, query, userID, since).Scan(&cost); err != nil {
+		return 0, models.Internal("failed to get llm cost: %w", err)
+	}
+
+	return cost, nil
+}
+
+// GetDailyUsage totals the user's calls per day since the given time,
+// oldest first. Days without calls are left out.
+func (r *PostgresUsageRepository) GetDailyUsage(ctx context.Context, userID int, since time.Time) ([]*models.DailyLLMUsage, error) {
+	query := `
+		SELECT date_trunc('day', createdAt), COUNT(*), SUM(inputTokens), SUM(outputTokens), SUM(costUsd) 
+		FROM gocourse.llm_calls 
+		WHERE user_id = $1 AND createdAt >= $2 
+		GROUP BY 1 
+		ORDER BY 1`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, since)
+	if err != nil {
+		return nil, models.Internal("failed to get daily llm usage: %w", err)
+	}
+	defer rows.Close()
+
+	days := []*models.DailyLLMUsage{}
+	for rows.Next() {
+		day := &models.DailyLLMUsage{}
+		if err := rows.Scan(&day.Day, &day.Calls, &day.InputTokens, &day.OutputTokens, &day.CostUSD); err != nil {
+			return nil, models.Internal("failed to scan daily llm usage: %w", err)
+		}
+		days = append(days, day)
+	}
+
+	return days, rows.Err()
+}
+
+// GetModelUsage totals the user's calls per model since the given time,
+// costliest first
+func (r *PostgresUsageRepository) GetModelUsage(ctx context.Context, userID int, since time.Time) ([]*models.ModelLLMUsage, error) {
+	query := `
+		SELECT model, COUNT(*), SUM(inputTokens), SUM(outputTokens), SUM(costUsd) 
+		FROM gocourse.llm_calls 
+		WHERE user_id = $1 AND createdAt >= $2 
+		GROUP BY model 
+		ORDER BY 5 DESC, model`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, since)
+	if err != nil {
+		return nil, models.Internal("failed to get llm usage by model: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []*models.ModelLLMUsage{}
+	for rows.Next() {
+		model := &models.ModelLLMUsage{}
+		if err := rows.Scan(&model.Model, &model.Calls, &model.InputTokens, &model.OutputTokens, &model.CostUSD); err != nil {
+			return nil, models.Internal("failed to scan llm usage by model: %w", err)
+		}
+		usage = append(usage, model)
+	}
+
+	return usage, rows.Err()
+}
+
+// GetUsageByUser totals calls per user since the given time, returning the
+// limit costliest users
+func (r *PostgresUsageRepository) GetUsageByUser(ctx context.Context, since time.Time, limit int) ([]*models.UserLLMUsage, error) {
+	query := `
+		SELECT user_id, COUNT(*), SUM(inputTokens), SUM(outputTokens), SUM(costUsd) 
+		FROM gocourse.llm_calls 
+		WHERE createdAt >= $1 
+		GROUP BY user_id 
+		ORDER BY 5 DESC, user_id 
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, since, limit)
+	if err != nil {
+		return nil, models.Internal("failed to get llm usage by user: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []*models.UserLLMUsage{}
+	for rows.Next() {
+		user := &models.UserLLMUsage{}
+		if err := rows.Scan(&user.UserID, &user.Calls, &user.InputTokens, &user.OutputTokens, &user.CostUSD); err != nil {
+			return nil, models.Internal("failed to scan llm usage by user: %w", err)
+		}
+		usage = append(usage, user)
+	}
+
+	return usage, rows.Err()
+}
+
+func (r *PostgresUsageRepository) DeleteLLMCallsBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM gocourse.llm_calls WHERE createdAt < $1`
+
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, models.Internal("failed to delete llm calls: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, models.Internal("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+type UserRepository interface {
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUserByID(ctx context.Context, id int) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	SetUserAdmin(ctx context.Context, id int, isAdmin bool) error
+}
+
+type PostgresUserRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresUserRepository(db *sql.DB) *PostgresUserRepository {
+	return &PostgresUserRepository{db: db}
+}
+
+func (r *PostgresUserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	query := `
+		INSERT INTO gocourse.users (email, passwordHash) 
+		VALUES ($1, $2) 
+		RETURNING id, createdAt, updatedAt`
+
+	row := r.db.QueryRowContext(ctx, query, user.Email, user.PasswordHash)
+
+	err := row.Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return models.Conflict("user with email %s already exists", user.Email)
+		}
+		return models.Internal("failed to create user: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresUserRepository) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	query := `
+		SELECT id, email, passwordHash, isAdmin, createdAt, updatedAt 
+		FROM gocourse.users 
+		WHERE id = $1`
+
+	user := &models.User{}
+	row := r.db.QueryRowContext(ctx, query, id)
+
+	err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.IsAdmin, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("user with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *PostgresUserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	query := `
+		SELECT id, email, passwordHash, isAdmin, createdAt, updatedAt 
+		FROM gocourse.users 
+		WHERE email = $1`
+
+	user := &models.User{}
+	row := r.db.QueryRowContext(ctx, query, email)
+
+	err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.IsAdmin, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("user with email %s not found", email)
+		}
+		return nil, models.Internal("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// SetUserAdmin grants or revokes the user's access to the admin endpoints
+func (r *PostgresUserRepository) SetUserAdmin(ctx context.Context, id int, isAdmin bool) error {
+	query := `
+		UPDATE gocourse.users 
+		SET isAdmin = $2, updatedAt = NOW() 
+		WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id, isAdmin)
+	if err != nil {
+		return models.Internal("failed to update user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return models.NotFound("user with id %d not found", id)
+	}
+
+	return nil
+}
+
+// Warm prepares the user reads behind sign-in
+func (r *PostgresUserRepository) Warm(ctx context.Context) error {
+	return warmPool(ctx, r.db,
+		"SELECT id, email, passwordHash, isAdmin, createdAt, updatedAt FROM gocourse.users WHERE id = $1",
+		"SELECT id, email, passwordHash, isAdmin, createdAt, updatedAt FROM gocourse.users WHERE email = $1")
+}
@@ -0,0 +1,162 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+type VocabularyRepository interface {
+	GetVocabulary(ctx context.Context, userID, flashcardID int) (*models.Vocabulary, error)
+	SaveVocabulary(ctx context.Context, vocabulary *models.Vocabulary) error
+	SaveAudio(ctx context.Context, flashcardID int, audio []byte, contentType string) error
+	GetAudio(ctx context.Context, userID, flashcardID int) ([]byte, string, error)
+	GetFlashcardIDsWithAudio(ctx context.Context, userID int, flashcardIDs []int) ([]int, error)
+}
+
+type PostgresVocabularyRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresVocabularyRepository(db *sql.DB) *PostgresVocabularyRepository {
+	return &PostgresVocabularyRepository{db: db}
+}
+
+func (r *PostgresVocabularyRepository) GetVocabulary(ctx context.Context, userID, flashcardID int) (*models.Vocabulary, error) {
+	query := `
+		SELECT v.flashcard_id, v.term, v.language, v.ipa, v.pronunciation, v.cefrLevel, v.examples, 
+			v.audio IS NOT NULL, v.audioContentType, v.createdAt, v.updatedAt 
+		FROM gocourse.flashcard_vocabulary v 
+		JOIN gocourse.flashcards f ON f.id = v.flashcard_id 
+		WHERE v.flashcard_id = $1 AND f.user_id = $2`
+
+	vocabulary := &models.Vocabulary{}
+	var examplesJSON []byte
+	row := r.db.QueryRowContext(ctx, query, flashcardID, userID)
+
+	err := row.Scan(&vocabulary.FlashcardID, &vocabulary.Term, &vocabulary.Language, &vocabulary.IPA,
+		&vocabulary.Pronunciation, &vocabulary.CEFRLevel, &examplesJSON, &vocabulary.HasAudio,
+		&vocabulary.AudioContentType, &vocabulary.CreatedAt, &vocabulary.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("vocabulary for flashcard with id %d not found", flashcardID)
+		}
+		return nil, models.Internal("failed to get vocabulary: %w", err)
+	}
+
+	if err := json.Unmarshal(examplesJSON, &vocabulary.Examples); err != nil {
+		return nil, models.Internal("failed to decode example sentences: %w", err)
+	}
+
+	return vocabulary, nil
+}
+
+// SaveVocabulary creates or replaces the text fields of a flashcard's
+// vocabulary. Stored audio is kept unless the term changes.
+func (r *PostgresVocabularyRepository) SaveVocabulary(ctx context.Context, vocabulary *models.Vocabulary) error {
+	examplesJSON, err := json.Marshal(vocabulary.Examples)
+	if err != nil {
+		return models.Internal("failed to encode example sentences: %w", err)
+	}
+
+	query := `
+		INSERT INTO gocourse.flashcard_vocabulary (flashcard_id, term, language, ipa, pronunciation, cefrLevel, examples) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7) 
+		ON CONFLICT (flashcard_id) DO UPDATE SET 
+			term = EXCLUDED.term, 
+			language = EXCLUDED.language, 
+			ipa = EXCLUDED.ipa, 
+			pronunciation = EXCLUDED.pronunciation, 
+			cefrLevel = EXCLUDED.cefrLevel, 
+			examples = EXCLUDED.examples, 
+			audio = CASE WHEN gocourse.flashcard_vocabulary.term = EXCLUDED.term THEN gocourse.flashcard_vocabulary.audio END, 
+			updatedAt = NOW() 
+		RETURNING createdAt, updatedAt, audio IS NOT NULL`
+
+	row := r.db.QueryRowContext(ctx, query, vocabulary.FlashcardID, vocabulary.Term, vocabulary.Language, vocabulary.IPA,
+		vocabulary.Pronunciation, vocabulary.CEFRLevel, examplesJSON)
+
+	if err := row.Scan(&vocabulary.CreatedAt, &vocabulary.UpdatedAt, &vocabulary.HasAudio); err != nil {
+		return models.Internal("failed to save vocabulary: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresVocabularyRepository) SaveAudio(ctx context.Context, flashcardID int, audio []byte, contentType string) error {
+	query := `
+		UPDATE gocourse.flashcard_vocabulary 
+		SET audio = $1, audioContentType = $2, updatedAt = NOW() 
+		WHERE flashcard_id = $3`
+
+	result, err := r.db.ExecContext(ctx, query, audio, contentType, flashcardID)
+	if err != nil {
+		return models.Internal("failed to save audio: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("vocabulary for flashcard with id %d not found", flashcardID)
+	}
+
+	return nil
+}
+
+func (r *PostgresVocabularyRepository) GetAudio(ctx context.Context, userID, flashcardID int) ([]byte, string, error) {
+	query := `
+		SELECT v.audio, v.audioContentType 
+		FROM gocourse.flashcard_vocabulary v 
+		JOIN gocourse.flashcards f ON f.id = v.flashcard_id 
+		WHERE v.flashcard_id = $1 AND f.user_id = $2 AND v.audio IS NOT NULL`
+
+	var audio []byte
+	var contentType string
+	err := r.db.QueryRowContext(ctx, query, flashcardID, userID).Scan(&audio, &contentType)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", models.NotFound("audio for flashcard with id %d not found", flashcardID)
+		}
+		return nil, "", models.Internal("failed to get audio: %w", err)
+	}
+
+	return audio, contentType, nil
+}
+
+// GetFlashcardIDsWithAudio returns which of the given flashcards have stored
+// audio
+func (r *PostgresVocabularyRepository) GetFlashcardIDsWithAudio(ctx context.Context, userID int, flashcardIDs []int) ([]int, error) {
+	query := `
+		SELECT v.flashcard_id 
+		FROM gocourse.flashcard_vocabulary v 
+		JOIN gocourse.flashcards f ON f.id = v.flashcard_id 
+		WHERE v.flashcard_id = ANY($1) AND f.user_id = $2 AND v.audio IS NOT NULL`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(flashcardIDs), userID)
+	if err != nil {
+		return nil, models.Internal("failed to query flashcards with audio: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, models.Internal("failed to scan flashcard id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, models.Internal("failed to iterate flashcards with audio: %w", err)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,302 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"flashcards/models"
+
+	"github.com/lib/pq"
+)
+
+const webhookColumns = `id, user_id, kind, url, secret, events, active, createdAt, updatedAt`
+
+const webhookDeliveryColumns = `d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.responseStatus, d.error, ` +
+	`d.nextAttemptAt, d.createdAt, d.updatedAt, d.deliveredAt`
+
+type WebhookRepository interface {
+	CountWebhooks(ctx context.Context, userID int, kind string) (int, error)
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) error
+	GetWebhooks(ctx context.Context, userID int) ([]*models.Webhook, error)
+	GetWebhook(ctx context.Context, userID, id int) (*models.Webhook, error)
+	UpdateWebhook(ctx context.Context, webhook *models.Webhook) error
+	DeleteWebhook(ctx context.Context, userID, id int) error
+	CreateDeliveries(ctx context.Context, userID int, kind, event string, payload []byte) (int, error)
+	ListDeliveries(ctx context.Context, webhookID int, params models.ListParams) ([]*models.WebhookDelivery, int, error)
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, id, responseStatus int) error
+	FailDeliveryAttempt(ctx context.Context, id int, responseStatus *int, message string, nextAttemptAt *time.Time) error
+	DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type PostgresWebhookRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresWebhookRepository(db *sql.DB) *PostgresWebhookRepository {
+	return &PostgresWebhookRepository{db: db}
+}
+
+// CountWebhooks counts the user's webhooks of the given kind
+func (r *PostgresWebhookRepository) CountWebhooks(ctx context.Context, userID int, kind string) (int, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM gocourse.webhooks WHERE user_id = $1 AND kind = $2", userID, kind).Scan(&count); err != nil {
+		return 0, models.Internal("failed to count webhooks: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *PostgresWebhookRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	query := `
+		INSERT INTO gocourse.webhooks (user_id, kind, url, secret, events, active) 
+		VALUES ($1, $2, $3, $4, $5, $6) 
+		RETURNING ` + webhookColumns
+
+	created, err := scanWebhook(r.db.QueryRowContext(ctx, query, webhook.UserID, webhook.Kind, webhook.URL, webhook.Secret,
+		pq.Array(webhook.Events), webhook.Active))
+	if err != nil {
+		return models.Internal("failed to create webhook: %w", err)
+	}
+
+	*webhook = *created
+	return nil
+}
+
+func (r *PostgresWebhookRepository) GetWebhooks(ctx context.Context, userID int) ([]*models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM gocourse.webhooks WHERE user_id = $1 ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, models.Internal("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := make([]*models.Webhook, 0)
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, models.Internal("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+func (r *PostgresWebhookRepository) GetWebhook(ctx context.Context, userID, id int) (*models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM gocourse.webhooks WHERE id = $1 AND user_id = $2`
+
+	webhook, err := scanWebhook(r.db.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.NotFound("webhook with id %d not found", id)
+		}
+		return nil, models.Internal("failed to get webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+func (r *PostgresWebhookRepository) UpdateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	query := `
+		UPDATE gocourse.webhooks 
+		SET url = $3, events = $4, active = $5, updatedAt = NOW() 
+		WHERE id = $1 AND user_id = $2 
+		RETURNING ` + webhookColumns
+
+	updated, err := scanWebhook(r.db.QueryRowContext(ctx, query, webhook.ID, webhook.UserID, webhook.URL, pq.Array(webhook.Events), webhook.Active))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.NotFound("webhook with id %d not found", webhook.ID)
+		}
+		return models.Internal("failed to update webhook: %w", err)
+	}
+
+	*webhook = *updated
+	return nil
+}
+
+func (r *PostgresWebhookRepository) DeleteWebhook(ctx context.Context, userID, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM gocourse.webhooks WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return models.Internal("failed to delete webhook: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Internal("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.NotFound("webhook with id %d not found", id)
+	}
+
+	return nil
+}
+
+// CreateDeliveries queues the event for each of the user's active webhooks
+// of the given kind subscribed to it and returns how many were queued
+func (r *PostgresWebhookRepository) CreateDeliveries(ctx context.Context, userID int, kind, event string, payload []byte) (int, error) {
+	query := `
+		INSERT INTO gocourse.webhook_deliveries (webhook_id, event, payload, status) 
+		SELECT id, $3, $4, $5 FROM gocourse.webhooks 
+		WHERE user_id = $1 AND kind = $2 AND active AND $3 = ANY(events)`
+
+	result, err := r.db.ExecContext(ctx, query, userID, kind, event, payload, models.WebhookDeliveryStatusPending)
+	if err != nil {
+		return 0, models.Internal("failed to queue webhook deliveries: %w", err)
+	}
+
+	created, err := result.RowsAffected()
+	if err != nil {
+		return 0, models.Internal("failed to get rows affected: %w", err)
+	}
+
+	return int(created), nil
+}
+
+// ListDeliveries returns one page of a webhook's delivery log and the total
+// number of deliveries
+func (r *PostgresWebhookRepository) ListDeliveries(ctx context.Context, webhookID int, params models.ListParams) ([]*models.WebhookDelivery, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM gocourse.webhook_deliveries WHERE webhook_id = $1", webhookID).Scan(&total); err != nil {
+		return nil, 0, models.Internal("failed to count webhook deliveries: %w", err)
+	}
+
+	orderBy, err := orderByClause("d", params)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM gocourse.webhook_deliveries d WHERE d.webhook_id = $1` + orderBy + ` LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, webhookID, params.Limit, params.Offset)
+	if err != nil {
+		return nil, 0, models.Internal("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery := &models.WebhookDelivery{}
+		if err := rows.Scan(webhookDeliveryScanArgs(delivery)...); err != nil {
+			return nil, 0, models.Internal("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating over webhook deliveries: %w", err)
+	}
+
+	return deliveries, total, nil
+}
+
+// ClaimDueDeliveries returns pending deliveries whose next attempt is due,
+// with their webhook's URL, secret, owner and kind, and pushes that attempt back by
+// lease so another server polling at the same time skips them
+func (r *PostgresWebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	query := `
+		UPDATE gocourse.webhook_deliveries d 
+		SET nextAttemptAt = NOW() + make_interval(secs => $1), updatedAt = NOW() 
+		FROM gocourse.webhooks w 
+		WHERE w.id = d.webhook_id AND d.id IN ( 
+			SELECT id FROM gocourse.webhook_deliveries 
+			WHERE status = $2 AND nextAttemptAt <= NOW() 
+			ORDER BY nextAttemptAt, id 
+			LIMIT $3 
+			FOR UPDATE SKIP LOCKED 
+		) 
+		RETURNING ` + webhookDeliveryColumns + `, w.url, w.secret, w.user_id, w.kind`
+
+	rows, err := r.db.QueryContext(ctx, query, lease.Seconds(), models.WebhookDeliveryStatusPending, limit)
+	if err != nil {
+		return nil, models.Internal("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery := &models.WebhookDelivery{}
+		if err := rows.Scan(append(webhookDeliveryScanArgs(delivery), &delivery.URL, &delivery.Secret, &delivery.UserID, &delivery.Kind)...); err != nil {
+			return nil, models.Internal("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *PostgresWebhookRepository) MarkDelivered(ctx context.Context, id, responseStatus int) error {
+	query := `
+		UPDATE gocourse.webhook_deliveries 
+		SET status = $2, attempts = attempts + 1, responseStatus = $3, error = '', nextAttemptAt = NULL, 
+			deliveredAt = NOW(), updatedAt = NOW() 
+		WHERE id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, id, models.WebhookDeliveryStatusDelivered, responseStatus); err != nil {
+		return models.Internal("failed to update webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// FailDeliveryAttempt counts a failed attempt and tries again at
+// nextAttemptAt. Without a next attempt the delivery has failed for good.
+func (r *PostgresWebhookRepository) FailDeliveryAttempt(ctx context.Context, id int, responseStatus *int, message string, nextAttemptAt *time.Time) error {
+	query := `
+		UPDATE gocourse.webhook_deliveries 
+		SET attempts = attempts + 1, responseStatus = $2, error = $3, nextAttemptAt = $4, 
+			status = CASE WHEN $4::TIMESTAMP IS NULL THEN $5 ELSE $6 END, updatedAt = NOW() 
+		WHERE id = $1`
+
+	_, err := r.db.ExecContext(ctx, query, id, responseStatus, message, nextAttemptAt,
+		models.WebhookDeliveryStatusFailed, models.WebhookDeliveryStatusPending)
+	if err != nil {
+		return models.Internal("failed to update webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteDeliveriesBefore removes finished deliveries created before the
+// given time. Pending ones are kept until they finish.
+func (r *PostgresWebhookRepository) DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM gocourse.webhook_deliveries WHERE createdAt < $1 AND status <> $2",
+		before, models.WebhookDeliveryStatusPending)
+	if err != nil {
+		return 0, models.Internal("failed to delete webhook deliveries: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, models.Internal("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
+
+func scanWebhook(row interface{ Scan(...any) error }) (*models.Webhook, error) {
+	webhook := &models.Webhook{}
+	err := row.Scan(&webhook.ID, &webhook.UserID, &webhook.Kind, &webhook.URL, &webhook.Secret, pq.Array(&webhook.Events), &webhook.Active,
+		&webhook.CreatedAt, &webhook.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func webhookDeliveryScanArgs(delivery *models.WebhookDelivery) []any {
+	return []any{&delivery.ID, &delivery.WebhookID, &delivery.Event, (*[]byte)(&delivery.Payload), &delivery.Status, &delivery.Attempts,
+		&delivery.ResponseStatus, &delivery.Error, &delivery.NextAttemptAt, &delivery.CreatedAt, &delivery.UpdatedAt, &delivery.DeliveredAt}
+}
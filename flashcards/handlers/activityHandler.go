@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type ActivityHandler struct {
+	service *services.ActivityService
+}
+
+func NewActivityHandler(service *services.ActivityService) *ActivityHandler {
+	return &ActivityHandler{service: service}
+}
+
+func (h *ActivityHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/activity", h.GetActivity).Methods("GET")
+}
+
+// GetActivity returns the user's recent actions, newest first. Supports
+// days, the range ending today (default 7), and limit (default 50).
+func (h *ActivityHandler) GetActivity(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	days, limit := 0, 0
+	var err error
+	if value := query.Get("days"); value != "" {
+		if days, err = strconv.Atoi(value); err != nil {
+			h.writeErrorResponse(w, http.StatusBadRequest, "days must be a number")
+			return
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil {
+			h.writeErrorResponse(w, http.StatusBadRequest, "limit must be a number")
+			return
+		}
+	}
+
+	feed, err := h.service.GetActivity(r.Context(), userIDFromRequest(r), days, limit)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve activity")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, feed)
+}
+
+func (h *ActivityHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *ActivityHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
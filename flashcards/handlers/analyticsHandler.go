@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+// Probe routes are left out of analytics so they do not drown real traffic
+var analyticsIgnoredRoutes = []string{"/health", "/ready", "/metrics"}
+
+type AnalyticsHandler struct {
+	service *services.AnalyticsService
+}
+
+func NewAnalyticsHandler(service *services.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{service: service}
+}
+
+func (h *AnalyticsHandler) RegisterAdminRoutes(router *mux.Router) {
+	router.HandleFunc("/analytics/routes", h.GetRouteAnalytics).Methods("GET")
+	router.HandleFunc("/analytics/users", h.GetUserAnalytics).Methods("GET")
+}
+
+// Middleware records the route, user, status and latency of each request.
+// It must run inside RequestLogger, which tracks the user set by the auth
+// middleware.
+func (h *AnalyticsHandler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+		if mux.CurrentRoute(r) == nil || containsString(analyticsIgnoredRoutes, route) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		startTime := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		userID := 0
+		if entry, ok := r.Context().Value(requestLogContextKey{}).(*requestLog); ok {
+			userID = entry.userID
+		}
+		h.service.Record(route, r.Method, userID, recorder.status, time.Since(startTime))
+	})
+}
+
+// GetRouteAnalytics lists routes by request count, errors or latency.
+// Supports window (a Go duration, default 24h), sort and limit.
+func (h *AnalyticsHandler) GetRouteAnalytics(w http.ResponseWriter, r *http.Request) {
+	window, sort, limit, err := parseAnalyticsParams(r)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	routes, err := h.service.GetRouteAnalytics(r.Context(), window, sort, limit)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve route analytics")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, routes)
+}
+
+// GetUserAnalytics lists users by request count, errors or latency, to
+// find abusive clients. Supports window, sort and limit.
+func (h *AnalyticsHandler) GetUserAnalytics(w http.ResponseWriter, r *http.Request) {
+	window, sort, limit, err := parseAnalyticsParams(r)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	users, err := h.service.GetUserAnalytics(r.Context(), window, sort, limit)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve user analytics")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, users)
+}
+
+func parseAnalyticsParams(r *http.Request) (time.Duration, string, int, error) {
+	query := r.URL.Query()
+
+	var window time.Duration
+	if value := query.Get("window"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, "", 0, fmt.Errorf("window must be a duration such as 24h")
+		}
+		window = parsed
+	}
+
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, "", 0, fmt.Errorf("limit must be an integer")
+		}
+		limit = parsed
+	}
+
+	return window, query.Get("sort"), limit, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *AnalyticsHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *AnalyticsHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type APIKeyHandler struct {
+	service *services.APIKeyService
+}
+
+func NewAPIKeyHandler(service *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+func (h *APIKeyHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api-keys", h.GetAPIKeys).Methods("GET")
+	router.HandleFunc("/api-keys", h.CreateAPIKey).Methods("POST")
+	router.HandleFunc("/api-keys/{id:[0-9]+}", h.RevokeAPIKey).Methods("DELETE")
+	router.HandleFunc("/api-keys/{id:[0-9]+}/usage", h.GetUsage).Methods("GET")
+}
+
+func (h *APIKeyHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.GetAPIKeys(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		writeError(w, err, "Failed to retrieve API keys")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, keys)
+}
+
+// CreateAPIKey issues a key and answers with it, which is the only time it
+// is shown
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	key, err := h.service.CreateAPIKey(r.Context(), userIDFromRequest(r), &req)
+	if err != nil {
+		writeError(w, err, "Failed to create API key")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, key)
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	if err := h.service.RevokeAPIKey(r.Context(), userIDFromRequest(r), id); err != nil {
+		writeError(w, err, "Failed to revoke API key")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUsage returns the key's requests per day. Supports days, the range
+// ending today (default 30).
+func (h *APIKeyHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	days := 0
+	if value := r.URL.Query().Get("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			h.writeErrorResponse(w, http.StatusBadRequest, "days must be a number")
+			return
+		}
+		days = parsed
+	}
+
+	usage, err := h.service.GetUsage(r.Context(), userIDFromRequest(r), id, days)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve API key usage")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, usage)
+}
+
+func (h *APIKeyHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *APIKeyHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type AttachmentHandler struct {
+	service *services.AttachmentService
+}
+
+func NewAttachmentHandler(service *services.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{service: service}
+}
+
+func (h *AttachmentHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/attachments/uploads", h.CreateUpload).Methods("POST")
+	router.HandleFunc("/attachments/{id:[0-9]+}", h.GetAttachment).Methods("GET")
+	router.HandleFunc("/attachments/{id:[0-9]+}", h.DeleteAttachment).Methods("DELETE")
+	router.HandleFunc("/attachments/{id:[0-9]+}/complete", h.CompleteUpload).Methods("POST")
+}
+
+// CreateUpload answers with a pre-signed URL the client uploads an image
+// to directly, when object storage is configured
+func (h *AttachmentHandler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAttachmentUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	upload, err := h.service.CreateUpload(r.Context(), userIDFromRequest(r), &req)
+	if err != nil {
+		writeError(w, err, "Failed to create attachment upload")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, upload)
+}
+
+// CompleteUpload checks the directly uploaded image and makes the
+// attachment ready
+func (h *AttachmentHandler) CompleteUpload(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid attachment ID")
+		return
+	}
+
+	attachment, err := h.service.CompleteUpload(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to complete attachment upload")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, attachment)
+}
+
+// GetAttachment serves the stored file with its original content type, or
+// redirects to a short-lived download URL when it is in object storage
+func (h *AttachmentHandler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid attachment ID")
+		return
+	}
+
+	attachment, err := h.service.GetAttachment(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve attachment")
+		return
+	}
+
+	if attachment.Status == models.AttachmentStatusPending {
+		h.writeErrorResponse(w, http.StatusConflict, "Attachment upload is not complete")
+		return
+	}
+
+	if attachment.StorageKey != "" {
+		url, err := h.service.DownloadURL(attachment)
+		if err != nil {
+			services.LoggerFromContext(r.Context()).Error("Failed to sign attachment URL", "attachment_id", id, "error", err)
+			writeError(w, err, "Failed to retrieve attachment")
+			return
+		}
+		w.Header().Set("Cache-Control", "private, no-store")
+		http.Redirect(w, r, url, http.StatusFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(attachment.Size))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	w.Write(attachment.Data)
+}
+
+func (h *AttachmentHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid attachment ID")
+		return
+	}
+
+	if err := h.service.DeleteAttachment(r.Context(), userIDFromRequest(r), id); err != nil {
+		writeError(w, err, "Failed to delete attachment")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AttachmentHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *AttachmentHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type contextKey string
+
+const userIDContextKey contextKey = "userID"
+
+// API_KEY_HEADER carries an API key in place of a bearer token
+const API_KEY_HEADER = "X-API-Key"
+
+type AuthHandler struct {
+	service *services.AuthService
+	apiKeys *services.APIKeyService
+}
+
+func NewAuthHandler(service *services.AuthService, apiKeys *services.APIKeyService) *AuthHandler {
+	return &AuthHandler{service: service, apiKeys: apiKeys}
+}
+
+// RegisterRoutes registers the public auth endpoints. /auth/me needs a
+// token, so it is registered on the authenticated router instead.
+func (h *AuthHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/auth/register", h.Register).Methods("POST")
+	router.HandleFunc("/auth/login", h.Login).Methods("POST")
+}
+
+func (h *AuthHandler) RegisterAuthenticatedRoutes(router *mux.Router) {
+	router.HandleFunc("/auth/me", h.GetCurrentUser).Methods("GET")
+}
+
+// Middleware rejects requests without a valid bearer token or X-API-Key
+// header and stores the authenticated user ID in the request context.
+// Requests made with an API key are limited to the key's scopes.
+func (h *AuthHandler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Let CORS preflight requests through
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if raw := r.Header.Get(API_KEY_HEADER); raw != "" {
+			h.serveAPIKey(w, r, raw, next)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			h.writeErrorResponse(w, http.StatusUnauthorized, "Missing bearer token")
+			return
+		}
+
+		userID, err := h.service.Authenticate(token)
+		if err != nil {
+			h.writeErrorResponse(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		ctx := context.WithValue(withRequestUser(r, userID), userIDContextKey, userID)
+		ctx = services.ContextWithUser(ctx, userID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireAdmin rejects requests from users who are not admins. It runs
+// after Middleware, which sets the user.
+func (h *AuthHandler) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		isAdmin, err := h.service.IsAdmin(r.Context(), userIDFromRequest(r))
+		if err != nil {
+			services.LoggerFromContext(r.Context()).Error("Failed to check admin role", "error", err)
+			writeError(w, err, "Failed to check admin role")
+			return
+		}
+		if !isAdmin {
+			h.writeErrorResponse(w, http.StatusForbidden, "Admin role required")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *AuthHandler) serveAPIKey(w http.ResponseWriter, r *http.Request, raw string, next http.Handler) {
+	key, err := h.apiKeys.Authenticate(r.Context(), raw)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAPIKey) {
+			h.writeErrorResponse(w, http.StatusUnauthorized, err.Error())
+		} else {
+			services.LoggerFromContext(r.Context()).Error("Failed to authenticate API key", "error", err)
+			writeError(w, err, "Failed to authenticate API key")
+		}
+		return
+	}
+
+	if !apiKeyAllows(key, r.Method, routeTemplate(r)) {
+		h.writeErrorResponse(w, http.StatusForbidden, "API key does not have the scope for this request")
+		return
+	}
+	h.apiKeys.Record(key.ID)
+
+	ctx := context.WithValue(withRequestUser(r, key.UserID), userIDContextKey, key.UserID)
+	ctx = services.ContextWithUser(ctx, key.UserID)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// apiKeyAllows reports whether the key's scopes cover the request. A
+// read-only key can make GET requests; a generate-quiz key can generate
+// quizzes and start and take quiz sessions. API keys are never managed
+// with an API key, and never reach the admin endpoints.
+func apiKeyAllows(key *models.APIKey, method, route string) bool {
+	if strings.HasPrefix(route, "/api-keys") || strings.HasPrefix(route, "/admin/") {
+		return false
+	}
+	if key.HasScope(models.APIKeyScopeReadOnly) && (method == http.MethodGet || method == http.MethodHead) {
+		return true
+	}
+	if key.HasScope(models.APIKeyScopeGenerateQuiz) {
+		return (method == http.MethodPost && route == "/notes/generate-quiz") || strings.HasPrefix(route, "/quiz/sessions")
+	}
+	return false
+}
+
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var req models.RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	resp, err := h.service.Register(r.Context(), &req)
+	if err != nil {
+		writeError(w, err, "Failed to register")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, resp)
+}
+
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	resp, err := h.service.Login(r.Context(), &req)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *AuthHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.service.GetUserByID(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		writeError(w, err, "Failed to retrieve user")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, user)
+}
+
+// userIDFromRequest returns the authenticated user set by the auth
+// middleware. Handlers on the authenticated router can rely on it being set.
+func userIDFromRequest(r *http.Request) int {
+	userID, _ := r.Context().Value(userIDContextKey).(int)
+	return userID
+}
+
+func (h *AuthHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *AuthHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
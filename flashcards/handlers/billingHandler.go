@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+const MAX_WEBHOOK_BYTES = 1024 * 1024
+
+type BillingHandler struct {
+	service *services.BillingService
+}
+
+func NewBillingHandler(service *services.BillingService) *BillingHandler {
+	return &BillingHandler{service: service}
+}
+
+func (h *BillingHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/billing/checkout", h.CreateCheckout).Methods("POST")
+}
+
+// RegisterWebhookRoutes registers the Stripe webhook, which is
+// authenticated by its signature rather than a bearer token
+func (h *BillingHandler) RegisterWebhookRoutes(router *mux.Router) {
+	router.HandleFunc("/billing/webhook", h.Webhook).Methods("POST")
+}
+
+func (h *BillingHandler) CreateCheckout(w http.ResponseWriter, r *http.Request) {
+	if !h.service.Enabled() {
+		h.writeErrorResponse(w, http.StatusServiceUnavailable, "Billing is not configured")
+		return
+	}
+
+	var req models.CreateCheckoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	session, err := h.service.CreateCheckoutSession(r.Context(), userIDFromRequest(r), &req)
+	if err != nil {
+		writeError(w, err, "Failed to create checkout session")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, session)
+}
+
+// Webhook applies Stripe subscription events. Events that fail to apply
+// are queued for retry; only when that fails too does it answer 500, so
+// Stripe retries the event itself.
+func (h *BillingHandler) Webhook(w http.ResponseWriter, r *http.Request) {
+	if !h.service.Enabled() {
+		h.writeErrorResponse(w, http.StatusServiceUnavailable, "Billing is not configured")
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MAX_WEBHOOK_BYTES))
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+
+	err = h.service.HandleWebhook(r.Context(), payload, r.Header.Get("Stripe-Signature"))
+	if err != nil {
+		writeError(w, err, "Failed to process event")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]bool{"received": true})
+}
+
+func (h *BillingHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *BillingHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
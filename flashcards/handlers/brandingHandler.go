@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type BrandingHandler struct {
+	service *services.BrandingService
+}
+
+func NewBrandingHandler(service *services.BrandingService) *BrandingHandler {
+	return &BrandingHandler{service: service}
+}
+
+// RegisterPublicRoutes adds the branding, which clients load before anyone
+// signs in
+func (h *BrandingHandler) RegisterPublicRoutes(router *mux.Router) {
+	router.HandleFunc("/branding", h.GetBranding).Methods("GET")
+}
+
+func (h *BrandingHandler) RegisterAdminRoutes(router *mux.Router) {
+	router.HandleFunc("/branding", h.UpdateBranding).Methods("PUT")
+}
+
+func (h *BrandingHandler) GetBranding(w http.ResponseWriter, r *http.Request) {
+	branding, err := h.service.GetBranding(r.Context())
+	if err != nil {
+		writeError(w, err, "Failed to retrieve branding")
+		return
+	}
+
+	// Branding rarely changes, and a few minutes of staleness is acceptable
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	h.writeJSONResponse(w, http.StatusOK, branding)
+}
+
+func (h *BrandingHandler) UpdateBranding(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateBrandingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	branding, err := h.service.UpdateBranding(r.Context(), &req)
+	if err != nil {
+		writeError(w, err, "Failed to update branding")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, branding)
+}
+
+func (h *BrandingHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *BrandingHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
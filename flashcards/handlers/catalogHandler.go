@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type CatalogHandler struct {
+	service *services.CatalogService
+}
+
+func NewCatalogHandler(service *services.CatalogService) *CatalogHandler {
+	return &CatalogHandler{service: service}
+}
+
+// RegisterPublicRoutes adds the deck feeds, which feed readers load without
+// an account
+func (h *CatalogHandler) RegisterPublicRoutes(router *mux.Router) {
+	router.HandleFunc("/catalog/decks/{id:[0-9]+}/feed.atom", h.GetDeckFeed).Methods("GET")
+}
+
+func (h *CatalogHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/catalog/decks", h.ListPublishedDecks).Methods("GET")
+	router.HandleFunc("/decks/{id:[0-9]+}/publish", h.PublishDeck).Methods("POST")
+	router.HandleFunc("/decks/{id:[0-9]+}/publish", h.UnpublishDeck).Methods("DELETE")
+}
+
+func (h *CatalogHandler) RegisterAdminRoutes(router *mux.Router) {
+	router.HandleFunc("/deck-flags", h.GetSimilarityFlags).Methods("GET")
+	router.HandleFunc("/deck-flags/{id:[0-9]+}", h.ReviewSimilarityFlag).Methods("PUT")
+}
+
+func (h *CatalogHandler) ListPublishedDecks(w http.ResponseWriter, r *http.Request) {
+	params, err := parseListParams(r)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.service.ListPublishedDecks(r.Context(), params)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve published decks")
+		return
+	}
+
+	setNextPageLink(r, page)
+	h.writeJSONResponse(w, http.StatusOK, page)
+}
+
+// GetDeckFeed serves an Atom feed of the cards recently added to a
+// published deck
+func (h *CatalogHandler) GetDeckFeed(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid deck ID")
+		return
+	}
+
+	feed, err := h.service.GetDeckFeed(r.Context(), id, r.URL.Path)
+	if err != nil {
+		// Decks that are not published are not named to readers
+		if isNotFound(err) {
+			h.writeErrorResponse(w, http.StatusNotFound, "deck feed not found")
+		} else {
+			writeError(w, err, "Failed to load deck feed")
+		}
+		return
+	}
+
+	// Feed readers poll, and a few minutes of staleness is acceptable
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Header().Set("Content-Type", services.ATOM_CONTENT_TYPE)
+	w.WriteHeader(http.StatusOK)
+	w.Write(feed)
+}
+
+// PublishDeck lists the deck in the catalog and checks it for copies of
+// other published decks
+func (h *CatalogHandler) PublishDeck(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid deck ID")
+		return
+	}
+
+	deck, err := h.service.PublishDeck(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to publish deck")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, deck)
+}
+
+func (h *CatalogHandler) UnpublishDeck(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid deck ID")
+		return
+	}
+
+	if err := h.service.UnpublishDeck(r.Context(), userIDFromRequest(r), id); err != nil {
+		writeError(w, err, "Failed to unpublish deck")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSimilarityFlags lists decks flagged as possible copies. Supports
+// status (pending, dismissed or confirmed) and limit.
+func (h *CatalogHandler) GetSimilarityFlags(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			h.writeErrorResponse(w, http.StatusBadRequest, "limit must be an integer")
+			return
+		}
+		limit = parsed
+	}
+
+	flags, err := h.service.GetSimilarityFlags(r.Context(), r.URL.Query().Get("status"), limit)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve deck flags")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, flags)
+}
+
+func (h *CatalogHandler) ReviewSimilarityFlag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid flag ID")
+		return
+	}
+
+	var req models.ReviewSimilarityFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	flag, err := h.service.ReviewSimilarityFlag(r.Context(), id, &req)
+	if err != nil {
+		writeError(w, err, "Failed to review deck flag")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, flag)
+}
+
+func (h *CatalogHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *CatalogHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
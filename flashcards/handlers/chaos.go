@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"flashcards/chaos"
+
+	"github.com/gorilla/mux"
+)
+
+// Chaos delays requests at the injector's latency rate, so clients'
+// timeouts and retries can be tried against a slow server. Health checks
+// are registered outside it.
+func Chaos(injector *chaos.Injector) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := injector.Delay(r.Context()); err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Request timed out"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Responses smaller than this are sent uncompressed, since gzip's overhead
+// outweighs what it saves on them
+const COMPRESSION_MIN_SIZE = 1024
+
+// Content types that are compressed already and gain nothing from gzip.
+// Event streams are left alone too, so each event reaches the client as
+// soon as it is written.
+var uncompressedContentTypes = []string{
+	"application/zip",
+	"application/gzip",
+	"application/pdf",
+	"image/",
+	"audio/",
+	"video/",
+	"text/event-stream",
+}
+
+var gzipWriters = sync.Pool{
+	New: func() any { return gzip.NewWriter(io.Discard) },
+}
+
+// Compress gzips responses for clients that accept it. Bodies are
+// compressed as they are written, so streamed responses such as exports
+// stay streamed. Small responses, already compressed content and event
+// streams are sent as they are.
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, status: http.StatusOK}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip, either
+// by name or through "*", with a nonzero quality
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				quality = parsed
+			}
+		}
+		return quality > 0
+	}
+	return false
+}
+
+// compressWriter holds the start of the body until it knows whether the
+// response is worth compressing: once COMPRESSION_MIN_SIZE bytes are
+// written it compresses, and a response that ends or flushes before then
+// is sent as it is.
+type compressWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	decided     bool
+	pending     []byte
+	gz          *gzip.Writer
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.status = status
+	cw.wroteHeader = true
+
+	if !cw.compressible() {
+		cw.start(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if !cw.decided {
+		cw.pending = append(cw.pending, b...)
+		if len(cw.pending) < COMPRESSION_MIN_SIZE {
+			return len(b), nil
+		}
+		if err := cw.start(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	if cw.gz != nil {
+		return cw.gz.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush sends what has been written so far. A response flushed before it
+// reached COMPRESSION_MIN_SIZE is streaming small pieces and stays
+// uncompressed.
+func (cw *compressWriter) Flush() {
+	if cw.wroteHeader && !cw.decided {
+		cw.start(false)
+	}
+	if cw.gz != nil {
+		cw.gz.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// compressible reports whether the response headers allow compressing it
+func (cw *compressWriter) compressible() bool {
+	if cw.status < http.StatusOK || cw.status == http.StatusNoContent || cw.status == http.StatusNotModified {
+		return false
+	}
+
+	header := cw.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil && length < COMPRESSION_MIN_SIZE {
+		return false
+	}
+
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	for _, uncompressed := range uncompressedContentTypes {
+		if strings.HasPrefix(contentType, uncompressed) {
+			return false
+		}
+	}
+	return true
+}
+
+// start sends the headers and the pending body, compressed or not
+func (cw *compressWriter) start(compress bool) error {
+	cw.decided = true
+	pending := cw.pending
+	cw.pending = nil
+
+	if compress {
+		header := cw.Header()
+		// Left unset, the type would be sniffed from the compressed bytes
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", http.DetectContentType(pending))
+		}
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", "gzip")
+
+		cw.gz = gzipWriters.Get().(*gzip.Writer)
+		cw.gz.Reset(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(pending) == 0 {
+		return nil
+	}
+	if cw.gz != nil {
+		_, err := cw.gz.Write(pending)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(pending)
+	return err
+}
+
+// close sends a response that ended before it was decided and finishes
+// the compressed stream
+func (cw *compressWriter) close() {
+	if cw.wroteHeader && !cw.decided {
+		cw.start(false)
+	}
+	if cw.gz != nil {
+		cw.gz.Close()
+		gzipWriters.Put(cw.gz)
+		cw.gz = nil
+	}
+}
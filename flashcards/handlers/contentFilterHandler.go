@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type ContentFilterHandler struct {
+	service *services.ContentFilterService
+}
+
+func NewContentFilterHandler(service *services.ContentFilterService) *ContentFilterHandler {
+	return &ContentFilterHandler{service: service}
+}
+
+func (h *ContentFilterHandler) RegisterAdminRoutes(router *mux.Router) {
+	router.HandleFunc("/content-filter", h.GetSettings).Methods("GET")
+	router.HandleFunc("/content-filter", h.UpdateSettings).Methods("PUT")
+}
+
+func (h *ContentFilterHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.service.GetSettings(r.Context())
+	if err != nil {
+		writeError(w, err, "Failed to retrieve content filter settings")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, settings)
+}
+
+func (h *ContentFilterHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateContentFilterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	settings, err := h.service.UpdateSettings(r.Context(), &req)
+	if err != nil {
+		writeError(w, err, "Failed to update content filter settings")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, settings)
+}
+
+func (h *ContentFilterHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *ContentFilterHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Response headers browsers let cross-origin callers read
+const CORS_EXPOSED_HEADERS = "X-Request-ID, ETag, Location, Idempotent-Replayed, Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset"
+
+// CORS lets browser frontends on the allowed origins call the API. It wraps
+// the whole router rather than being router middleware, because the router
+// skips its middleware for preflight OPTIONS requests, which match no
+// route's methods.
+type CORS struct {
+	origins []string
+	methods string
+	headers string
+	maxAge  string
+}
+
+// NewCORS takes the allowed origins, such as https://app.example.com, or
+// "*" for any; the methods and request headers allowed; and how long
+// browsers may cache a preflight answer
+func NewCORS(origins, methods, headers []string, maxAge time.Duration) *CORS {
+	allowed := make([]string, len(origins))
+	for i, origin := range origins {
+		allowed[i] = strings.ToLower(strings.TrimSuffix(origin, "/"))
+	}
+	return &CORS{
+		origins: allowed,
+		methods: strings.Join(methods, ", "),
+		headers: strings.Join(headers, ", "),
+		maxAge:  strconv.Itoa(int(maxAge.Seconds())),
+	}
+}
+
+// Middleware answers preflight requests itself and adds the CORS headers
+// to responses for allowed origins. Requests from other origins are served
+// without them, so browsers keep their responses from the calling page.
+func (c *CORS) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		allowed := c.allowOrigin(origin)
+		if allowed != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowed)
+			w.Header().Set("Access-Control-Expose-Headers", CORS_EXPOSED_HEADERS)
+		}
+
+		if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if allowed != "" {
+			w.Header().Set("Access-Control-Allow-Methods", c.methods)
+			w.Header().Set("Access-Control-Allow-Headers", c.headers)
+			w.Header().Set("Access-Control-Max-Age", c.maxAge)
+		}
+		w.Header().Add("Vary", "Access-Control-Request-Method, Access-Control-Request-Headers")
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for a request
+// from origin, or "" when the origin is not allowed
+func (c *CORS) allowOrigin(origin string) string {
+	if slices.Contains(c.origins, "*") {
+		return "*"
+	}
+	if slices.Contains(c.origins, strings.ToLower(origin)) {
+		return origin
+	}
+	return ""
+}
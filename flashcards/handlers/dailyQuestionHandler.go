@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type DailyQuestionHandler struct {
+	service *services.DailyQuestionService
+}
+
+func NewDailyQuestionHandler(service *services.DailyQuestionService) *DailyQuestionHandler {
+	return &DailyQuestionHandler{service: service}
+}
+
+// RegisterPublicRoutes registers the answer endpoints, which need no token
+// since the daily question email signs the answer
+func (h *DailyQuestionHandler) RegisterPublicRoutes(router *mux.Router) {
+	router.HandleFunc("/daily-questions/answer", h.Answer).Methods("POST")
+	router.HandleFunc("/daily-questions/replies", h.AnswerReply).Methods("POST")
+}
+
+func (h *DailyQuestionHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/daily-questions/subscriptions", h.GetSubscriptions).Methods("GET")
+	router.HandleFunc("/daily-questions/subscriptions", h.CreateSubscription).Methods("POST")
+	router.HandleFunc("/daily-questions/subscriptions/{id:[0-9]+}", h.DeleteSubscription).Methods("DELETE")
+}
+
+func (h *DailyQuestionHandler) GetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := h.service.GetSubscriptions(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		writeError(w, err, "Failed to retrieve daily question subscriptions")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, subscriptions)
+}
+
+// CreateSubscription starts a daily question email from a deck
+func (h *DailyQuestionHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateDailyQuestionSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	subscription, err := h.service.CreateSubscription(r.Context(), userIDFromRequest(r), &req)
+	if err != nil {
+		writeError(w, err, "Failed to create daily question subscription")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, subscription)
+}
+
+func (h *DailyQuestionHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid daily question subscription ID")
+		return
+	}
+
+	if err := h.service.DeleteSubscription(r.Context(), userIDFromRequest(r), id); err != nil {
+		writeError(w, err, "Failed to delete daily question subscription")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Answer records the answer picked from a daily question email's links
+func (h *DailyQuestionHandler) Answer(w http.ResponseWriter, r *http.Request) {
+	var req models.AnswerDailyQuestionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	result, err := h.service.Answer(r.Context(), &req)
+	h.writeAnswer(w, result, err)
+}
+
+// AnswerReply records the answer from an email reply to a daily question,
+// posted by the mail provider's inbound webhook
+func (h *DailyQuestionHandler) AnswerReply(w http.ResponseWriter, r *http.Request) {
+	var reply models.DailyQuestionReply
+	if err := json.NewDecoder(r.Body).Decode(&reply); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	result, err := h.service.AnswerReply(r.Context(), &reply)
+	h.writeAnswer(w, result, err)
+}
+
+func (h *DailyQuestionHandler) writeAnswer(w http.ResponseWriter, result *models.DailyQuestionResult, err error) {
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidToken) {
+			h.writeErrorResponse(w, http.StatusUnauthorized, err.Error())
+		} else {
+			writeError(w, err, "Failed to record daily question answer")
+		}
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, result)
+}
+
+func (h *DailyQuestionHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *DailyQuestionHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type DeadLetterHandler struct {
+	service *services.DeadLetterService
+}
+
+func NewDeadLetterHandler(service *services.DeadLetterService) *DeadLetterHandler {
+	return &DeadLetterHandler{service: service}
+}
+
+func (h *DeadLetterHandler) RegisterAdminRoutes(router *mux.Router) {
+	router.HandleFunc("/dead-letters", h.ListDeadLetters).Methods("GET")
+	router.HandleFunc("/dead-letters/{id:[0-9]+}", h.GetDeadLetter).Methods("GET")
+	router.HandleFunc("/dead-letters/{id:[0-9]+}/retry", h.RetryDeadLetter).Methods("POST")
+	router.HandleFunc("/dead-letters/{id:[0-9]+}", h.DeleteDeadLetter).Methods("DELETE")
+}
+
+// ListDeadLetters lists failed background work. Supports status (retrying,
+// dead or resolved), kind and the usual list parameters.
+func (h *DeadLetterHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	params, err := parseListParams(r)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := models.DeadLetterFilter{
+		Status: r.URL.Query().Get("status"),
+		Kind:   r.URL.Query().Get("kind"),
+	}
+
+	page, err := h.service.ListDeadLetters(r.Context(), filter, params)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve dead letters")
+		return
+	}
+
+	setNextPageLink(r, page)
+	h.writeJSONResponse(w, http.StatusOK, page)
+}
+
+func (h *DeadLetterHandler) GetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid dead letter ID")
+		return
+	}
+
+	letter, err := h.service.GetDeadLetter(r.Context(), id)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve dead letter")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, letter)
+}
+
+// RetryDeadLetter queues the work for a retry on the next poll, including
+// work that ran out of attempts
+func (h *DeadLetterHandler) RetryDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid dead letter ID")
+		return
+	}
+
+	letter, err := h.service.RetryDeadLetter(r.Context(), id)
+	if err != nil {
+		writeError(w, err, "Failed to retry dead letter")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusAccepted, letter)
+}
+
+func (h *DeadLetterHandler) DeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid dead letter ID")
+		return
+	}
+
+	if err := h.service.DeleteDeadLetter(r.Context(), id); err != nil {
+		writeError(w, err, "Failed to delete dead letter")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *DeadLetterHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *DeadLetterHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type DeckExportHandler struct {
+	service *services.DeckExportService
+}
+
+func NewDeckExportHandler(service *services.DeckExportService) *DeckExportHandler {
+	return &DeckExportHandler{service: service}
+}
+
+func (h *DeckExportHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/decks/{id:[0-9]+}/export", h.ExportDeck).Methods("GET")
+	router.HandleFunc("/decks/{id:[0-9]+}/exports", h.StoreExport).Methods("POST")
+	router.HandleFunc("/deck-exports", h.GetExports).Methods("GET")
+	router.HandleFunc("/deck-exports/{id:[0-9]+}", h.GetExport).Methods("GET")
+}
+
+// ExportDeck downloads the deck and its subdecks as an Anki package
+// (?format=apkg, the default) or as CSV (?format=csv)
+func (h *DeckExportHandler) ExportDeck(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid deck ID")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = services.EXPORT_FORMAT_APKG
+	}
+	if services.ExportContentType(format) == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "format must be one of: apkg, csv")
+		return
+	}
+
+	export, err := h.service.ExportDeck(r.Context(), userIDFromRequest(r), id, format)
+	if err != nil {
+		writeError(w, err, "Failed to export deck")
+		return
+	}
+
+	// The file is streamed, so a failure part way through can only cut
+	// the download short
+	w.Header().Set("Content-Type", export.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.Filename))
+	w.WriteHeader(http.StatusOK)
+	if err := export.Encode(w); err != nil {
+		services.LoggerFromContext(r.Context()).Error("Failed to write deck export", "deck_id", id, "error", err)
+	}
+}
+
+// StoreExport writes the export to object storage and answers with a
+// pre-signed download URL. The format is chosen as for ExportDeck.
+func (h *DeckExportHandler) StoreExport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid deck ID")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = services.EXPORT_FORMAT_APKG
+	}
+
+	export, err := h.service.StoreExport(r.Context(), userIDFromRequest(r), id, format)
+	if err != nil {
+		writeError(w, err, "Failed to export deck")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, export)
+}
+
+// GetExports lists the stored exports that have not expired, each with a
+// fresh download URL
+func (h *DeckExportHandler) GetExports(w http.ResponseWriter, r *http.Request) {
+	exports, err := h.service.GetExports(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		writeError(w, err, "Failed to retrieve deck exports")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, exports)
+}
+
+func (h *DeckExportHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid deck export ID")
+		return
+	}
+
+	export, err := h.service.GetExport(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve deck export")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, export)
+}
+
+func (h *DeckExportHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *DeckExportHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
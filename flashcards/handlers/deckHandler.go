@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type DeckHandler struct {
+	service *services.DeckService
+}
+
+func NewDeckHandler(service *services.DeckService) *DeckHandler {
+	return &DeckHandler{service: service}
+}
+
+func (h *DeckHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/decks", h.CreateDeck).Methods("POST")
+	router.HandleFunc("/decks", h.GetAllDecks).Methods("GET")
+	router.HandleFunc("/decks/{id:[0-9]+}", h.GetDeckByID).Methods("GET")
+	router.HandleFunc("/decks/{id:[0-9]+}", h.UpdateDeck).Methods("PUT")
+	router.HandleFunc("/decks/{id:[0-9]+}", h.DeleteDeck).Methods("DELETE")
+	router.HandleFunc("/decks/{id:[0-9]+}/terminology", h.GetTerminology).Methods("GET")
+	router.HandleFunc("/decks/{id:[0-9]+}/terminology", h.UpdateTerminology).Methods("PUT")
+	router.HandleFunc("/decks/{id:[0-9]+}/study-settings", h.GetStudySettings).Methods("GET")
+	router.HandleFunc("/decks/{id:[0-9]+}/study-settings", h.UpdateStudySettings).Methods("PUT")
+}
+
+func (h *DeckHandler) CreateDeck(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateDeckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	deck, err := h.service.CreateDeck(r.Context(), userIDFromRequest(r), &req)
+	if err != nil {
+		writeError(w, err, "Failed to create deck")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, deck)
+}
+
+func (h *DeckHandler) GetAllDecks(w http.ResponseWriter, r *http.Request) {
+	decks, err := h.service.GetAllDecks(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		writeError(w, err, "Failed to retrieve decks")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, decks)
+}
+
+func (h *DeckHandler) GetDeckByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid deck ID")
+		return
+	}
+
+	deck, err := h.service.GetDeckByID(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve deck")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, deck)
+}
+
+func (h *DeckHandler) UpdateDeck(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid deck ID")
+		return
+	}
+
+	var req models.UpdateDeckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	deck, err := h.service.UpdateDeck(r.Context(), userIDFromRequest(r), id, &req)
+	if err != nil {
+		writeError(w, err, "Failed to update deck")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, deck)
+}
+
+func (h *DeckHandler) DeleteDeck(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid deck ID")
+		return
+	}
+
+	err = h.service.DeleteDeck(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to delete deck")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *DeckHandler) GetTerminology(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid deck ID")
+		return
+	}
+
+	terminology, err := h.service.GetTerminology(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve deck terminology")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, terminology)
+}
+
+// UpdateTerminology replaces the whole dictionary; omitted lists are cleared
+func (h *DeckHandler) UpdateTerminology(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid deck ID")
+		return
+	}
+
+	var req models.UpdateDeckTerminologyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	terminology, err := h.service.UpdateTerminology(r.Context(), userIDFromRequest(r), id, &req)
+	if err != nil {
+		writeError(w, err, "Failed to update deck terminology")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, terminology)
+}
+
+func (h *DeckHandler) GetStudySettings(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid deck ID")
+		return
+	}
+
+	settings, err := h.service.GetStudySettings(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve study settings")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, settings)
+}
+
+// UpdateStudySettings replaces the deck's settings; omitted fields reset to
+// their defaults
+func (h *DeckHandler) UpdateStudySettings(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid deck ID")
+		return
+	}
+
+	var req models.UpdateDeckStudySettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	settings, err := h.service.UpdateStudySettings(r.Context(), userIDFromRequest(r), id, &req)
+	if err != nil {
+		writeError(w, err, "Failed to save study settings")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, settings)
+}
+
+func (h *DeckHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *DeckHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type DeckImportHandler struct {
+	service *services.DeckImportService
+}
+
+func NewDeckImportHandler(service *services.DeckImportService) *DeckImportHandler {
+	return &DeckImportHandler{service: service}
+}
+
+func (h *DeckImportHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/decks/import", h.ImportDeck).Methods("POST")
+}
+
+// ImportDeck accepts a multipart upload of an Anki package or CSV file in
+// a "file" field, with optional "deckId" to import under and
+// "validateOnly" fields. It responds with the decks and cards created, the
+// cards skipped and the conflicts; validateOnly reports the same without
+// creating anything.
+func (h *DeckImportHandler) ImportDeck(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, services.MAX_DECK_IMPORT_BYTES+MULTIPART_OVERHEAD_BYTES)
+	if err := r.ParseMultipartForm(services.MAX_IMPORT_BYTES); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid multipart upload or file too large")
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Failed to read "+header.Filename)
+		return
+	}
+
+	req := &models.ImportDeckRequest{File: models.ImportFile{Name: header.Filename, Data: data}}
+	if value := r.FormValue("deckId"); value != "" {
+		deckID, err := strconv.Atoi(value)
+		if err != nil {
+			h.writeErrorResponse(w, http.StatusBadRequest, "deckId must be an integer")
+			return
+		}
+		req.DeckID = &deckID
+	}
+	if value := r.FormValue("validateOnly"); value != "" {
+		validateOnly, err := strconv.ParseBool(value)
+		if err != nil {
+			h.writeErrorResponse(w, http.StatusBadRequest, "validateOnly must be true or false")
+			return
+		}
+		req.ValidateOnly = validateOnly
+	}
+
+	report, err := h.service.ImportDeck(r.Context(), userIDFromRequest(r), req)
+	if err != nil {
+		writeError(w, err, "Failed to import deck")
+		return
+	}
+
+	statusCode := http.StatusCreated
+	if report.ValidateOnly {
+		statusCode = http.StatusOK
+	}
+	h.writeJSONResponse(w, statusCode, report)
+}
+
+func (h *DeckImportHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *DeckImportHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type DeckSuggestionHandler struct {
+	service *services.DeckSuggestionService
+}
+
+func NewDeckSuggestionHandler(service *services.DeckSuggestionService) *DeckSuggestionHandler {
+	return &DeckSuggestionHandler{service: service}
+}
+
+func (h *DeckSuggestionHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/decks/{id:[0-9]+}/suggestions", h.ListSuggestions).Methods("GET")
+	router.HandleFunc("/decks/{id:[0-9]+}/suggestions/{suggestionId:[0-9]+}/apply", h.ApplySuggestion).Methods("POST")
+	router.HandleFunc("/decks/{id:[0-9]+}/suggestions/{suggestionId:[0-9]+}/dismiss", h.DismissSuggestion).Methods("POST")
+}
+
+// ListSuggestions returns the deck's open maintenance suggestions from the
+// last weekly analysis
+func (h *DeckSuggestionHandler) ListSuggestions(w http.ResponseWriter, r *http.Request) {
+	deckID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid deck ID")
+		return
+	}
+
+	suggestions, err := h.service.ListSuggestions(r.Context(), userIDFromRequest(r), deckID)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve suggestions")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, suggestions)
+}
+
+// ApplySuggestion makes the suggested change and returns the cards it
+// created and deleted
+func (h *DeckSuggestionHandler) ApplySuggestion(w http.ResponseWriter, r *http.Request) {
+	deckID, suggestionID, ok := h.suggestionIDs(w, r)
+	if !ok {
+		return
+	}
+
+	applied, err := h.service.ApplySuggestion(r.Context(), userIDFromRequest(r), deckID, suggestionID)
+	if err != nil {
+		writeError(w, err, "Failed to apply suggestion")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, applied)
+}
+
+func (h *DeckSuggestionHandler) DismissSuggestion(w http.ResponseWriter, r *http.Request) {
+	deckID, suggestionID, ok := h.suggestionIDs(w, r)
+	if !ok {
+		return
+	}
+
+	suggestion, err := h.service.DismissSuggestion(r.Context(), userIDFromRequest(r), deckID, suggestionID)
+	if err != nil {
+		writeError(w, err, "Failed to dismiss suggestion")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, suggestion)
+}
+
+func (h *DeckSuggestionHandler) suggestionIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	vars := mux.Vars(r)
+	deckID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid deck ID")
+		return 0, 0, false
+	}
+
+	suggestionID, err := strconv.Atoi(vars["suggestionId"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid suggestion ID")
+		return 0, 0, false
+	}
+
+	return deckID, suggestionID, true
+}
+
+func (h *DeckSuggestionHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *DeckSuggestionHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
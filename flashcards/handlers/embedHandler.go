@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type EmbedHandler struct {
+	service *services.EmbedService
+}
+
+func NewEmbedHandler(service *services.EmbedService) *EmbedHandler {
+	return &EmbedHandler{service: service}
+}
+
+func (h *EmbedHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/embeds", h.CreateEmbed).Methods("POST")
+	router.HandleFunc("/embeds", h.ListEmbeds).Methods("GET")
+	router.HandleFunc("/embeds/{id:[0-9]+}", h.DeleteEmbed).Methods("DELETE")
+}
+
+// RegisterPublicRoutes adds the routes embedded pages load. They need no
+// account, only the embed token.
+func (h *EmbedHandler) RegisterPublicRoutes(router *mux.Router) {
+	router.HandleFunc("/embed/{token:[A-Za-z0-9_-]+}", h.GetEmbed).Methods("GET")
+}
+
+func (h *EmbedHandler) CreateEmbed(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateEmbedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	embed, err := h.service.CreateEmbed(r.Context(), userIDFromRequest(r), &req)
+	if err != nil {
+		writeError(w, err, "Failed to create embed")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, embed)
+}
+
+func (h *EmbedHandler) ListEmbeds(w http.ResponseWriter, r *http.Request) {
+	embeds, err := h.service.ListEmbeds(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		writeError(w, err, "Failed to retrieve embeds")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, embeds)
+}
+
+// DeleteEmbed revokes the embed; pages that embed it stop showing the cards
+func (h *EmbedHandler) DeleteEmbed(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid embed ID")
+		return
+	}
+
+	if err := h.service.DeleteEmbed(r.Context(), userIDFromRequest(r), id); err != nil {
+		writeError(w, err, "Failed to delete embed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetEmbed serves the embedded card or deck preview as JSON, or with
+// ?format=html as a standalone page for an iframe
+func (h *EmbedHandler) GetEmbed(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "html" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "format must be one of: json, html")
+		return
+	}
+
+	content, err := h.service.GetEmbedContent(r.Context(), mux.Vars(r)["token"])
+	if err != nil {
+		// The owner's flashcards and decks are not named to viewers
+		writeError(w, err, "Failed to load embed")
+		return
+	}
+
+	// Embeds are loaded from other sites, whatever origins may call the
+	// rest of the API, and a few minutes of staleness after an edit is
+	// acceptable
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Cross-Origin-Resource-Policy", "cross-origin")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	if format != "html" {
+		h.writeJSONResponse(w, http.StatusOK, content)
+		return
+	}
+
+	// Any site may frame the page, but it runs no script and loads nothing
+	// but images
+	w.Header().Set("Content-Security-Policy", "sandbox; default-src 'none'; img-src https: http: data:; style-src 'unsafe-inline'; frame-ancestors *")
+	w.Header().Del("X-Frame-Options")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(services.EmbedHTMLDocument(content)))
+}
+
+func (h *EmbedHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *EmbedHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
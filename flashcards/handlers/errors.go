@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"flashcards/models"
+)
+
+// errorStatus maps an error from a service to the status it is answered
+// with. Domain errors carry their kind; anything else is a server failure.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, models.ErrGone):
+		return http.StatusGone
+	case errors.Is(err, models.ErrQuotaExceeded):
+		return http.StatusPaymentRequired
+	case errors.Is(err, models.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, models.ErrUpstream):
+		return http.StatusBadGateway
+	case errors.Is(err, models.ErrInternal):
+		return http.StatusInternalServerError
+	}
+	return http.StatusInternalServerError
+}
+
+// writeError answers a service error with the status errorStatus maps it
+// to. Client errors show their message; server and upstream failures show
+// fallback so storage and provider details stay in the logs. An
+// unavailable LLM answers 503.
+func writeError(w http.ResponseWriter, err error, fallback string) {
+	if writeLLMUnavailable(w, err) {
+		return
+	}
+
+	statusCode := errorStatus(err)
+	message := err.Error()
+	if statusCode == http.StatusInternalServerError || statusCode == http.StatusBadGateway {
+		message = fallback
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, models.ErrNotFound)
+}
+
+func isConflict(err error) bool {
+	return errors.Is(err, models.ErrConflict)
+}
+
+// isInternal reports whether err is a failure of storage, the server or a
+// service the request depends on, rather than of the request
+func isInternal(err error) bool {
+	return errors.Is(err, models.ErrInternal) || errors.Is(err, models.ErrUpstream)
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// setETag sends a resource's version as its ETag, for the client to return
+// in If-Match when it updates the resource
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// ifMatchVersion reads the version an update is based on from If-Match.
+// Without one it answers 428, so clients cannot overwrite changes they have
+// not seen, and reports false. A weak ETag is accepted as the same version.
+func ifMatchVersion(w http.ResponseWriter, r *http.Request) (int, bool) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" {
+		writeVersionError(w, http.StatusPreconditionRequired, "If-Match header with the ETag from the last GET is required")
+		return 0, false
+	}
+
+	unquoted, err := strconv.Unquote(strings.TrimPrefix(value, "W/"))
+	if err != nil {
+		writeVersionError(w, http.StatusBadRequest, "If-Match must be a single ETag such as \"3\"")
+		return 0, false
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version < 1 {
+		writeVersionError(w, http.StatusBadRequest, "If-Match must be a single ETag such as \"3\"")
+		return 0, false
+	}
+	return version, true
+}
+
+func writeVersionError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
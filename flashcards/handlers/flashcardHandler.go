@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type FlashcardHandler struct {
+	service *services.FlashcardService
+}
+
+func NewFlashcardHandler(service *services.FlashcardService) *FlashcardHandler {
+	return &FlashcardHandler{service: service}
+}
+
+func (h *FlashcardHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/flashcards", h.CreateFlashcard).Methods("POST")
+	router.HandleFunc("/flashcards", h.GetAllFlashcards).Methods("GET")
+	router.HandleFunc("/flashcards/{id:[0-9]+}", h.GetFlashcardByID).Methods("GET")
+	router.HandleFunc("/flashcards/{id:[0-9]+}", h.UpdateFlashcard).Methods("PUT")
+	router.HandleFunc("/flashcards/{id:[0-9]+}", h.DeleteFlashcard).Methods("DELETE")
+	router.HandleFunc("/flashcards/{id:[0-9]+}/render", h.RenderFlashcard).Methods("GET")
+	router.HandleFunc("/flashcards/{id:[0-9]+}/mnemonic", h.GenerateMnemonic).Methods("POST")
+	router.HandleFunc("/notes/{id:[0-9]+}/generate-flashcards", h.GenerateFromNote).Methods("POST")
+}
+
+func (h *FlashcardHandler) CreateFlashcard(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateFlashcardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	flashcard, err := h.service.CreateFlashcard(r.Context(), userIDFromRequest(r), &req)
+	if err != nil {
+		writeError(w, err, "Failed to create flashcard")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, flashcard)
+}
+
+// GetAllFlashcards lists flashcards a page at a time. Supports limit,
+// offset, sort (createdAt, updatedAt, id) and order, and filters by deckId
+// and q.
+func (h *FlashcardHandler) GetAllFlashcards(w http.ResponseWriter, r *http.Request) {
+	params, err := parseListParams(r)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	deckID, err := parseDeckIDFilter(r)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := models.FlashcardFilter{
+		DeckID: deckID,
+		Query:  r.URL.Query().Get("q"),
+	}
+
+	page, err := h.service.ListFlashcards(r.Context(), userIDFromRequest(r), filter, params)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve flashcards")
+		return
+	}
+
+	setNextPageLink(r, page)
+	h.writeJSONResponse(w, http.StatusOK, page)
+}
+
+func (h *FlashcardHandler) GetFlashcardByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid flashcard ID")
+		return
+	}
+
+	flashcard, err := h.service.GetFlashcardByID(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve flashcard")
+		return
+	}
+
+	setETag(w, flashcard.Version)
+	h.writeJSONResponse(w, http.StatusOK, flashcard)
+}
+
+// GenerateMnemonic generates memory aids for the flashcard and answers with
+// the card they were saved on
+func (h *FlashcardHandler) GenerateMnemonic(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid flashcard ID")
+		return
+	}
+
+	flashcard, err := h.service.GenerateMnemonic(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to generate mnemonic")
+		return
+	}
+
+	setETag(w, flashcard.Version)
+	h.writeJSONResponse(w, http.StatusOK, flashcard)
+}
+
+// RenderFlashcard returns the flashcard's sides as sanitized HTML, or with
+// ?format=html a standalone page served under a sandboxing CSP
+func (h *FlashcardHandler) RenderFlashcard(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid flashcard ID")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "html" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "format must be one of: json, html")
+		return
+	}
+
+	rendered, err := h.service.RenderFlashcard(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to render flashcard")
+		return
+	}
+
+	if format != "html" {
+		h.writeJSONResponse(w, http.StatusOK, rendered)
+		return
+	}
+
+	// The page runs no script and loads nothing but images, so even markup
+	// that got past the renderer cannot act on the user's behalf
+	w.Header().Set("Content-Security-Policy", "sandbox; default-src 'none'; img-src https: http: data:; style-src 'unsafe-inline'")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(services.FlashcardHTMLDocument(rendered)))
+}
+
+func (h *FlashcardHandler) UpdateFlashcard(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid flashcard ID")
+		return
+	}
+
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateFlashcardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	flashcard, err := h.service.UpdateFlashcard(r.Context(), userIDFromRequest(r), id, version, &req)
+	if err != nil {
+		writeError(w, err, "Failed to update flashcard")
+		return
+	}
+
+	setETag(w, flashcard.Version)
+	h.writeJSONResponse(w, http.StatusOK, flashcard)
+}
+
+func (h *FlashcardHandler) DeleteFlashcard(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid flashcard ID")
+		return
+	}
+
+	err = h.service.DeleteFlashcard(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to delete flashcard")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *FlashcardHandler) GenerateFromNote(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	noteID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid note ID")
+		return
+	}
+
+	var req models.GenerateFlashcardsRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+			return
+		}
+	}
+
+	flashcards, err := h.service.GenerateFromNote(r.Context(), userIDFromRequest(r), noteID, &req)
+	if err != nil {
+		writeError(w, err, "Failed to generate flashcards: "+err.Error())
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, flashcards)
+}
+
+func (h *FlashcardHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *FlashcardHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
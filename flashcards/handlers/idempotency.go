@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"flashcards/services"
+)
+
+const IDEMPOTENCY_KEY_HEADER = "Idempotency-Key"
+
+// idempotent wraps a handler so that requests carrying an Idempotency-Key
+// header run once per key: successful responses are stored and replayed to
+// retries with the same key and body, and failed ones free the key so the
+// retry runs again.
+func idempotent(service *services.IdempotencyService, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSpace(r.Header.Get(IDEMPOTENCY_KEY_HEADER))
+		if key == "" || !service.Enabled() {
+			next(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeIdempotencyError(w, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID := userIDFromRequest(r)
+		record, err := service.Begin(r.Context(), userID, key, services.HashRequest(r.Method, r.URL.Path, body))
+		if err != nil {
+			writeError(w, err, "Failed to check Idempotency-Key")
+			return
+		}
+
+		if record != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(*record.StatusCode)
+			w.Write(record.ResponseBody)
+			return
+		}
+
+		rec := &responseCapture{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+
+		if rec.status >= 200 && rec.status < 300 {
+			service.Complete(r.Context(), userID, key, rec.status, rec.body.Bytes())
+		} else {
+			service.Release(r.Context(), userID, key)
+		}
+	}
+}
+
+// responseCapture passes a response through while keeping a copy of its
+// status and body
+type responseCapture struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rec *responseCapture) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseCapture) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+func writeIdempotencyError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
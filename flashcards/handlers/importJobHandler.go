@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type ImportJobHandler struct {
+	service     *services.ImportJobService
+	idempotency *services.IdempotencyService
+}
+
+func NewImportJobHandler(service *services.ImportJobService, idempotency *services.IdempotencyService) *ImportJobHandler {
+	return &ImportJobHandler{service: service, idempotency: idempotency}
+}
+
+func (h *ImportJobHandler) RegisterRoutes(router *mux.Router) {
+	// The body is limited before the idempotency check reads it
+	router.HandleFunc("/imports/notes", limitBody(services.MAX_IMPORT_BYTES+MULTIPART_OVERHEAD_BYTES,
+		idempotent(h.idempotency, h.StartNoteImport))).Methods("POST")
+	router.HandleFunc("/imports", h.ListImportJobs).Methods("GET")
+	router.HandleFunc("/imports/{id:[0-9]+}", h.GetImportJob).Methods("GET")
+	router.HandleFunc("/imports/{id:[0-9]+}/resume", h.ResumeImportJob).Methods("POST")
+}
+
+// StartNoteImport takes the same upload as POST /notes/import, plus an
+// optional "chunkSize", and responds 202 with a job to poll for progress.
+// Sending an Idempotency-Key makes a retried upload return the first job
+// instead of starting another. With validateOnly it responds 200 with the
+// import report and starts nothing.
+func (h *ImportJobHandler) StartNoteImport(w http.ResponseWriter, r *http.Request) {
+	req, err := parseImportNotesForm(r)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chunkSize := 0
+	if value := r.FormValue("chunkSize"); value != "" {
+		chunkSize, err = strconv.Atoi(value)
+		if err != nil {
+			h.writeErrorResponse(w, http.StatusBadRequest, "chunkSize must be an integer")
+			return
+		}
+	}
+
+	if req.ValidateOnly {
+		report, err := h.service.ValidateNoteImport(r.Context(), userIDFromRequest(r), req, chunkSize)
+		if err != nil {
+			writeError(w, err, "Failed to start import")
+			return
+		}
+		h.writeJSONResponse(w, http.StatusOK, report)
+		return
+	}
+
+	job, err := h.service.StartNoteImport(r.Context(), userIDFromRequest(r), req, chunkSize)
+	if err != nil {
+		writeError(w, err, "Failed to start import")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusAccepted, job)
+}
+
+func (h *ImportJobHandler) ListImportJobs(w http.ResponseWriter, r *http.Request) {
+	jobs, err := h.service.ListImportJobs(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		writeError(w, err, "Failed to retrieve import jobs")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, jobs)
+}
+
+// GetImportJob reports the job's status and progress: items and chunks
+// committed so far, the sections skipped while parsing, and the error that
+// stopped a failed job
+func (h *ImportJobHandler) GetImportJob(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid import job ID")
+		return
+	}
+
+	job, err := h.service.GetImportJob(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve import job")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, job)
+}
+
+// ResumeImportJob restarts a failed job from its first uncommitted chunk
+func (h *ImportJobHandler) ResumeImportJob(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid import job ID")
+		return
+	}
+
+	job, err := h.service.ResumeImportJob(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to resume import job")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusAccepted, job)
+}
+
+// limitBody caps the request body at limit bytes
+func limitBody(limit int64, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next(w, r)
+	}
+}
+
+func (h *ImportJobHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *ImportJobHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type JobHandler struct {
+	service *services.JobService
+}
+
+func NewJobHandler(service *services.JobService) *JobHandler {
+	return &JobHandler{service: service}
+}
+
+func (h *JobHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/jobs", h.CreateJob).Methods("POST")
+	router.HandleFunc("/jobs", h.ListJobs).Methods("GET")
+	router.HandleFunc("/jobs/{id:[0-9]+}", h.GetJob).Methods("GET")
+}
+
+func (h *JobHandler) RegisterAdminRoutes(router *mux.Router) {
+	router.HandleFunc("/jobs", h.CreateAdminJob).Methods("POST")
+}
+
+// CreateJob queues background work and answers 202 with the job to poll
+func (h *JobHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
+	h.createJob(w, r, false)
+}
+
+// CreateAdminJob queues background work that is not scoped to one user,
+// such as re-embedding the catalog
+func (h *JobHandler) CreateAdminJob(w http.ResponseWriter, r *http.Request) {
+	h.createJob(w, r, true)
+}
+
+func (h *JobHandler) createJob(w http.ResponseWriter, r *http.Request, admin bool) {
+	var req models.CreateJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	job, err := h.service.Enqueue(r.Context(), userIDFromRequest(r), &req, admin)
+	if err != nil {
+		writeError(w, err, "Failed to queue job")
+		return
+	}
+
+	w.Header().Set("Location", "/jobs/"+strconv.Itoa(job.ID))
+	h.writeJSONResponse(w, http.StatusAccepted, job)
+}
+
+// ListJobs lists the user's jobs. Supports status, kind and the usual list
+// parameters.
+func (h *JobHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	params, err := parseListParams(r)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := models.JobFilter{
+		Status: r.URL.Query().Get("status"),
+		Kind:   r.URL.Query().Get("kind"),
+	}
+
+	page, err := h.service.ListJobs(r.Context(), userIDFromRequest(r), filter, params)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve jobs")
+		return
+	}
+
+	setNextPageLink(r, page)
+	h.writeJSONResponse(w, http.StatusOK, page)
+}
+
+// GetJob reports a job's status, and its result once it has completed
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid job ID")
+		return
+	}
+
+	job, err := h.service.GetJob(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve job")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, job)
+}
+
+func (h *JobHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *JobHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
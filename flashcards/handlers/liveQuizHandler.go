@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// The server pings this often so proxies keep the connection open and
+	// dead clients are noticed
+	LIVE_QUIZ_PING_INTERVAL = 25 * time.Second
+	// Connections that send nothing, not even a pong, for this long are
+	// closed; the client can rejoin with its resume token
+	LIVE_QUIZ_IDLE_TIMEOUT = 60 * time.Second
+)
+
+type LiveQuizHandler struct {
+	service *services.LiveQuizService
+}
+
+func NewLiveQuizHandler(service *services.LiveQuizService) *LiveQuizHandler {
+	return &LiveQuizHandler{service: service}
+}
+
+// RegisterRoutes registers the live quiz WebSocket. Browsers cannot set
+// headers on a WebSocket handshake, so it needs no token; the connection
+// signs in with its first message instead.
+func (h *LiveQuizHandler) RegisterRoutes(router *mux.Router) {
+	router.Handle("/ws/quiz", h.upgrade(websocket.Server{Handler: h.serve})).Methods("GET")
+}
+
+// upgrade answers requests that do not ask for a WebSocket with a JSON
+// error, as every other route does, rather than a failed handshake
+func (h *LiveQuizHandler) upgrade(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			writeError(w, models.Invalid("this endpoint requires a WebSocket upgrade"), "")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serve runs a live quiz over one connection. After the client joins with
+// a start or resume message, the server sends the session's resume token
+// and the first question; each answer or skip is followed by its result
+// and the next question, until the session completes and the connection
+// closes with the report.
+func (h *LiveQuizHandler) serve(conn *websocket.Conn) {
+	defer conn.Close()
+
+	// Blocked reads end when the server shuts down
+	ctx := conn.Request().Context()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	conn.SetReadDeadline(time.Now().Add(LIVE_QUIZ_IDLE_TIMEOUT))
+	var join models.LiveQuizMessage
+	if err := websocket.JSON.Receive(conn, &join); err != nil {
+		h.sendError(conn, "Invalid JSON message")
+		return
+	}
+
+	userID, sessionID, err := h.service.Join(ctx, &join)
+	if err != nil {
+		h.sendServiceError(conn, err, "Failed to join quiz session")
+		return
+	}
+	ctx = services.ContextWithUser(withRequestUser(conn.Request(), userID), userID)
+	logger := services.LoggerFromContext(ctx).With("session_id", sessionID)
+
+	welcome, err := h.service.Welcome(userID, sessionID)
+	if err != nil {
+		h.sendServiceError(conn, err, "Failed to join quiz session")
+		return
+	}
+	if err := h.send(conn, welcome); err != nil {
+		return
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go h.heartbeat(conn, done)
+
+	position, ok := h.sendNext(ctx, conn, userID, sessionID)
+	for ok {
+		conn.SetReadDeadline(time.Now().Add(LIVE_QUIZ_IDLE_TIMEOUT))
+		var msg models.LiveQuizMessage
+		if err := websocket.JSON.Receive(conn, &msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				h.sendError(conn, "Invalid JSON message")
+				continue
+			}
+			logger.Debug("Live quiz connection closed", "error", err)
+			return
+		}
+
+		switch msg.Type {
+		case models.LiveQuizMessagePing:
+			ok = h.send(conn, &models.LiveQuizMessage{Type: models.LiveQuizMessagePong}) == nil
+		case models.LiveQuizMessagePong:
+			// Receiving it already extended the deadline
+		case models.LiveQuizMessageAnswer, models.LiveQuizMessageSkip:
+			if msg.Position != nil && *msg.Position != position {
+				h.sendError(conn, fmt.Sprintf("question %d is not the current question", *msg.Position))
+				continue
+			}
+
+			if msg.Type == models.LiveQuizMessageAnswer {
+				result, err := h.service.Answer(ctx, userID, sessionID, position, msg.Answer, msg.TimeSpentMs)
+				if err != nil {
+					h.sendServiceError(conn, err, "Failed to save answer")
+					continue
+				}
+				if err := h.send(conn, result); err != nil {
+					return
+				}
+			} else if err := h.service.Skip(ctx, userID, sessionID, position); err != nil {
+				h.sendServiceError(conn, err, "Failed to skip question")
+				continue
+			}
+
+			position, ok = h.sendNext(ctx, conn, userID, sessionID)
+		default:
+			h.sendError(conn, fmt.Sprintf("unknown message type %q", msg.Type))
+		}
+	}
+}
+
+// sendNext sends the next question and returns its position. It returns
+// false once the connection should close: the session is complete or the
+// message could not be sent.
+func (h *LiveQuizHandler) sendNext(ctx context.Context, conn *websocket.Conn, userID, sessionID int) (int, bool) {
+	next, err := h.service.Next(ctx, userID, sessionID)
+	if err != nil {
+		h.sendServiceError(conn, err, "Failed to load the next question")
+		return 0, false
+	}
+
+	if err := h.send(conn, next); err != nil || next.Question == nil {
+		return 0, false
+	}
+	return next.Question.Position, true
+}
+
+func (h *LiveQuizHandler) heartbeat(conn *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(LIVE_QUIZ_PING_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := h.send(conn, &models.LiveQuizMessage{Type: models.LiveQuizMessagePing}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// send writes a message; writes are serialized by the connection, so the
+// heartbeat can send alongside the quiz
+func (h *LiveQuizHandler) send(conn *websocket.Conn, msg *models.LiveQuizMessage) error {
+	return websocket.JSON.Send(conn, msg)
+}
+
+// Client errors show their message; storage failures show fallback
+func (h *LiveQuizHandler) sendServiceError(conn *websocket.Conn, err error, fallback string) {
+	message := err.Error()
+	if isInternal(err) {
+		message = fallback
+	}
+	h.sendError(conn, message)
+}
+
+func (h *LiveQuizHandler) sendError(conn *websocket.Conn, message string) {
+	h.send(conn, &models.LiveQuizMessage{Type: models.LiveQuizMessageError, Error: message})
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+// Probes, the feature report with the maintenance banner, and the branding
+// are served during any maintenance
+var maintenanceExemptRoutes = []string{"/health", "/ready", "/metrics", "/meta", "/branding"}
+
+// Signing in and turning maintenance off stay possible, or an admin whose
+// token expired could not end it. Registering creates a user, so it is not
+// exempt.
+var maintenanceControlRoutes = []string{"/auth/login", "/admin/maintenance"}
+
+type MaintenanceHandler struct {
+	service *services.MaintenanceService
+}
+
+func NewMaintenanceHandler(service *services.MaintenanceService) *MaintenanceHandler {
+	return &MaintenanceHandler{service: service}
+}
+
+func (h *MaintenanceHandler) RegisterAdminRoutes(router *mux.Router) {
+	router.HandleFunc("/maintenance", h.GetMaintenance).Methods("GET")
+	router.HandleFunc("/maintenance", h.UpdateMaintenance).Methods("PUT")
+}
+
+// Middleware answers 503 with Retry-After to writes in read-only mode, and
+// to every request in full mode
+func (h *MaintenanceHandler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		maintenance := h.service.GetMaintenance(r.Context())
+		if !maintenance.Active() || maintenanceExempt(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		readOnly := maintenance.Mode == models.MaintenanceModeReadOnly
+		if readOnly && (r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		message := "The service is down for maintenance"
+		if readOnly {
+			message = "The service is read-only for maintenance"
+		}
+		if maintenance.Message != "" {
+			message += ": " + maintenance.Message
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(services.MaintenanceRetryAfter(maintenance)))))
+		h.writeErrorResponse(w, http.StatusServiceUnavailable, message)
+	})
+}
+
+func maintenanceExempt(path string) bool {
+	return containsString(maintenanceExemptRoutes, path) || containsString(maintenanceControlRoutes, path)
+}
+
+func (h *MaintenanceHandler) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	h.writeJSONResponse(w, http.StatusOK, h.service.GetMaintenance(r.Context()))
+}
+
+// UpdateMaintenance switches between off, read-only and full maintenance,
+// with an optional banner message and expected end time
+func (h *MaintenanceHandler) UpdateMaintenance(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateMaintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	maintenance, err := h.service.UpdateMaintenance(r.Context(), &req)
+	if err != nil {
+		writeError(w, err, "Failed to update maintenance mode")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, maintenance)
+}
+
+func (h *MaintenanceHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *MaintenanceHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type MembershipHandler struct {
+	service *services.MembershipService
+}
+
+func NewMembershipHandler(service *services.MembershipService) *MembershipHandler {
+	return &MembershipHandler{service: service}
+}
+
+func (h *MembershipHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/orgs", h.CreateOrganization).Methods("POST")
+	router.HandleFunc("/orgs/{id:[0-9]+}/invites", h.CreateInvite).Methods("POST")
+	router.HandleFunc("/invites/{token}/accept", h.AcceptInvite).Methods("POST")
+}
+
+// CreateOrganization creates an organization owned by the current user
+func (h *MembershipHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateOwnedOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	membership, err := h.service.CreateOrganization(r.Context(), userIDFromRequest(r), &req)
+	if err != nil {
+		writeError(w, err, "Failed to create organization")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, membership)
+}
+
+func (h *MembershipHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid organization ID")
+		return
+	}
+
+	var req models.CreateInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	invite, err := h.service.CreateInvite(r.Context(), userIDFromRequest(r), id, &req)
+	if err != nil {
+		writeError(w, err, "Failed to create invite")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, invite)
+}
+
+func (h *MembershipHandler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
+	membership, err := h.service.AcceptInvite(r.Context(), userIDFromRequest(r), mux.Vars(r)["token"])
+	if err != nil {
+		writeError(w, err, "Failed to accept invite")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, membership)
+}
+
+func (h *MembershipHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *MembershipHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type MetaHandler struct {
+	quizService *services.QuizService
+	maintenance *services.MaintenanceService
+}
+
+func NewMetaHandler(quizService *services.QuizService, maintenance *services.MaintenanceService) *MetaHandler {
+	return &MetaHandler{quizService: quizService, maintenance: maintenance}
+}
+
+func (h *MetaHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/meta", h.GetMeta).Methods("GET")
+}
+
+// GetMeta reports which features are available right now. Clients check it
+// to hide generation while the LLM provider is down instead of letting
+// every request fail, and to show a banner during maintenance.
+func (h *MetaHandler) GetMeta(w http.ResponseWriter, r *http.Request) {
+	status := h.quizService.LLMStatus()
+	meta := models.ServiceMeta{
+		Capabilities: models.ServiceCapabilities{
+			Generation:      status.Available,
+			QuestionBank:    true,
+			GenerationQueue: true,
+		},
+		LLM: status,
+	}
+
+	// Generating, starting sessions and queueing are all writes
+	if maintenance := h.maintenance.GetMaintenance(r.Context()); maintenance.Active() {
+		meta.Capabilities = models.ServiceCapabilities{}
+		meta.Maintenance = maintenance
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.writeJSONResponse(w, http.StatusOK, meta)
+}
+
+func (h *MetaHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+// writeLLMUnavailable answers 503 with Retry-After when err comes from the
+// LLM provider being down, and reports whether it did
+func writeLLMUnavailable(w http.ResponseWriter, err error) bool {
+	var unavailable *services.LLMUnavailableError
+	if !errors.As(err, &unavailable) {
+		return false
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(unavailable.RetryAfter))))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(map[string]string{"error": "Generation is temporarily unavailable: " + unavailable.Error()})
+	return true
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+)
+
+// MetricsHandler serves metrics in the Prometheus text format. Today they
+// are the statistics of the database connection pools: the one the
+// repositories share, and any opened to run migrations.
+type MetricsHandler struct {
+	poolStats func() map[string]sql.DBStats
+}
+
+func NewMetricsHandler(poolStats func() map[string]sql.DBStats) *MetricsHandler {
+	return &MetricsHandler{poolStats: poolStats}
+}
+
+// poolMetric is one statistic reported for every pool
+type poolMetric struct {
+	name  string
+	kind  string
+	help  string
+	value func(sql.DBStats) float64
+}
+
+var poolMetrics = []poolMetric{
+	{"db_pool_max_open_connections", "gauge", "Maximum number of open connections to the database",
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+	{"db_pool_open_connections", "gauge", "Established connections, in use and idle",
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+	{"db_pool_in_use_connections", "gauge", "Connections currently in use",
+		func(s sql.DBStats) float64 { return float64(s.InUse) }},
+	{"db_pool_idle_connections", "gauge", "Idle connections",
+		func(s sql.DBStats) float64 { return float64(s.Idle) }},
+	{"db_pool_wait_count_total", "counter", "Connections waited for because the pool was at its limit",
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+	{"db_pool_wait_duration_seconds_total", "counter", "Time spent waiting for a connection",
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+	{"db_pool_max_idle_closed_total", "counter", "Connections closed because the pool had MaxIdleConns idle",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+	{"db_pool_max_idle_time_closed_total", "counter", "Connections closed after ConnMaxIdleTime idle",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }},
+	{"db_pool_max_lifetime_closed_total", "counter", "Connections closed after ConnMaxLifetime",
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+}
+
+func (h *MetricsHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	stats := h.poolStats()
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	writePoolMetrics(w, names, stats)
+}
+
+func writePoolMetrics(w io.Writer, names []string, stats map[string]sql.DBStats) {
+	for _, metric := range poolMetrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", metric.name, metric.kind)
+		for _, name := range names {
+			fmt.Fprintf(w, "%s{pool=%q} %g\n", metric.name, name, metric.value(stats[name]))
+		}
+	}
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 
@@ -22,6 +24,7 @@ func NewNoteHandler(service *services.NoteService) *NoteHandler {
 func (h *NoteHandler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/notes", h.CreateNote).Methods("POST")
 	router.HandleFunc("/notes", h.GetAllNotes).Methods("GET")
+	router.HandleFunc("/notes/import", h.ImportNotes).Methods("POST")
 	router.HandleFunc("/notes/{id:[0-9]+}", h.GetNoteByID).Methods("GET")
 	router.HandleFunc("/notes/{id:[0-9]+}", h.UpdateNote).Methods("PUT")
 	router.HandleFunc("/notes/{id:[0-9]+}", h.DeleteNote).Methods("DELETE")
@@ -34,23 +37,71 @@ func (h *NoteHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	note, err := h.service.CreateNote(&req)
+	note, err := h.service.CreateNote(r.Context(), userIDFromRequest(r), &req)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		writeError(w, err, "Failed to create note")
 		return
 	}
 
 	h.writeJSONResponse(w, http.StatusCreated, note)
 }
 
+// ImportNotes accepts a multipart upload of Markdown files or zip archives
+// in repeated "files" fields, with optional "deckId", "headingLevel" and
+// "validateOnly" fields. It responds with the notes created and the
+// sections skipped; validateOnly reports the same without creating any
+// notes.
+func (h *NoteHandler) ImportNotes(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, services.MAX_IMPORT_BYTES+MULTIPART_OVERHEAD_BYTES)
+	req, err := parseImportNotesForm(r)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := h.service.ImportNotes(r.Context(), userIDFromRequest(r), req)
+	if err != nil {
+		writeError(w, err, "Failed to import notes")
+		return
+	}
+
+	statusCode := http.StatusCreated
+	if report.ValidateOnly {
+		statusCode = http.StatusOK
+	}
+	h.writeJSONResponse(w, statusCode, report)
+}
+
+// GetAllNotes lists notes a page at a time. Supports limit, offset, sort
+// (createdAt, updatedAt, id) and order, and filters by tag, deckId and q.
 func (h *NoteHandler) GetAllNotes(w http.ResponseWriter, r *http.Request) {
-	notes, err := h.service.GetAllNotes()
+	params, err := parseListParams(r)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	deckID, err := parseDeckIDFilter(r)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := models.NoteFilter{
+		Tag:      r.URL.Query().Get("tag"),
+		DeckID:   deckID,
+		Query:    r.URL.Query().Get("q"),
+		Language: r.URL.Query().Get("language"),
+	}
+
+	page, err := h.service.ListNotes(r.Context(), userIDFromRequest(r), filter, params)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve notes")
+		writeError(w, err, "Failed to retrieve notes")
 		return
 	}
 
-	h.writeJSONResponse(w, http.StatusOK, notes)
+	setNextPageLink(r, page)
+	h.writeJSONResponse(w, http.StatusOK, page)
 }
 
 func (h *NoteHandler) GetNoteByID(w http.ResponseWriter, r *http.Request) {
@@ -61,16 +112,13 @@ func (h *NoteHandler) GetNoteByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	note, err := h.service.GetNoteByID(id)
+	note, err := h.service.GetNoteByID(r.Context(), userIDFromRequest(r), id)
 	if err != nil {
-		if containsNoteNotFound(err.Error()) {
-			h.writeErrorResponse(w, http.StatusNotFound, err.Error())
-		} else {
-			h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve note")
-		}
+		writeError(w, err, "Failed to retrieve note")
 		return
 	}
 
+	setETag(w, note.Version)
 	h.writeJSONResponse(w, http.StatusOK, note)
 }
 
@@ -82,22 +130,24 @@ func (h *NoteHandler) UpdateNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
 	var req models.UpdateNoteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
 		return
 	}
 
-	note, err := h.service.UpdateNote(id, &req)
+	note, err := h.service.UpdateNote(r.Context(), userIDFromRequest(r), id, version, &req)
 	if err != nil {
-		if containsNoteNotFound(err.Error()) {
-			h.writeErrorResponse(w, http.StatusNotFound, err.Error())
-		} else {
-			h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
-		}
+		writeError(w, err, "Failed to update note")
 		return
 	}
 
+	setETag(w, note.Version)
 	h.writeJSONResponse(w, http.StatusOK, note)
 }
 
@@ -109,19 +159,65 @@ func (h *NoteHandler) DeleteNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.DeleteNote(id)
+	err = h.service.DeleteNote(r.Context(), userIDFromRequest(r), id)
 	if err != nil {
-		if containsNoteNotFound(err.Error()) {
-			h.writeErrorResponse(w, http.StatusNotFound, err.Error())
-		} else {
-			h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete note")
-		}
+		writeError(w, err, "Failed to delete note")
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// parseImportNotesForm reads a multipart import upload: Markdown files or
+// zip archives in repeated "files" fields, with optional "deckId",
+// "headingLevel" and "validateOnly" fields. "dryRun" is still accepted as
+// the original name of validateOnly.
+func parseImportNotesForm(r *http.Request) (*models.ImportNotesRequest, error) {
+	if err := r.ParseMultipartForm(services.MAX_IMPORT_BYTES); err != nil {
+		return nil, fmt.Errorf("Invalid multipart upload or files too large")
+	}
+
+	req := &models.ImportNotesRequest{}
+	for _, header := range r.MultipartForm.File["files"] {
+		file, err := header.Open()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read %s", header.Filename)
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read %s", header.Filename)
+		}
+		req.Files = append(req.Files, models.ImportFile{Name: header.Filename, Data: data})
+	}
+
+	if value := r.FormValue("deckId"); value != "" {
+		deckID, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("deckId must be an integer")
+		}
+		req.DeckID = &deckID
+	}
+	if value := r.FormValue("headingLevel"); value != "" {
+		headingLevel, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("headingLevel must be an integer")
+		}
+		req.HeadingLevel = headingLevel
+	}
+	for _, field := range []string{"dryRun", "validateOnly"} {
+		if value := r.FormValue(field); value != "" {
+			validateOnly, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be true or false", field)
+			}
+			req.ValidateOnly = validateOnly
+		}
+	}
+
+	return req, nil
+}
+
 func (h *NoteHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -133,8 +229,3 @@ func (h *NoteHandler) writeErrorResponse(w http.ResponseWriter, statusCode int,
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
-
-func containsNoteNotFound(message string) bool {
-	return len(message) > 0 && (message[len(message)-9:] == "not found" ||
-		message[:len("note with id")] == "note with id")
-}
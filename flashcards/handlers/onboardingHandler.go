@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type OnboardingHandler struct {
+	service *services.OnboardingService
+}
+
+func NewOnboardingHandler(service *services.OnboardingService) *OnboardingHandler {
+	return &OnboardingHandler{service: service}
+}
+
+func (h *OnboardingHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/onboarding", h.GetChecklist).Methods("GET")
+}
+
+// GetChecklist returns the current user's setup steps and which are done
+func (h *OnboardingHandler) GetChecklist(w http.ResponseWriter, r *http.Request) {
+	checklist, err := h.service.GetChecklist(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		writeError(w, err, "Failed to retrieve onboarding checklist")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, checklist)
+}
+
+func (h *OnboardingHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *OnboardingHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"flashcards/models"
+	"flashcards/openapi"
+
+	"github.com/gorilla/mux"
+)
+
+const API_VERSION = "1.0.0"
+
+// Swagger UI loads its script and styles from unpkg and the document from
+// this server
+const SWAGGER_UI_CONTENT_SECURITY_POLICY = "default-src 'none'; script-src https://unpkg.com 'unsafe-inline'; style-src https://unpkg.com 'unsafe-inline'; img-src 'self' data: https:; connect-src 'self'; frame-ancestors 'none'"
+
+// Swagger UI is loaded from a CDN so the server does not have to ship it
+const SWAGGER_UI_PAGE = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Flashcards API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+<script>SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});</script>
+</body>
+</html>
+`
+
+type OpenAPIHandler struct {
+	document []byte
+}
+
+// NewOpenAPIHandler builds the OpenAPI document once; it only changes with
+// the code
+func NewOpenAPIHandler() (*OpenAPIHandler, error) {
+	document, err := json.MarshalIndent(BuildOpenAPIDocument(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAPIHandler{document: document}, nil
+}
+
+func (h *OpenAPIHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/openapi.json", h.GetDocument).Methods("GET")
+	router.HandleFunc("/docs", h.GetDocs).Methods("GET")
+}
+
+func (h *OpenAPIHandler) GetDocument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(h.document)
+}
+
+// GetDocs serves Swagger UI over the OpenAPI document
+func (h *OpenAPIHandler) GetDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Security-Policy", SWAGGER_UI_CONTENT_SECURITY_POLICY)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(SWAGGER_UI_PAGE))
+}
+
+// BuildOpenAPIDocument describes the flashcard, note, deck and quiz
+// endpoints. Add a route here when adding one of those to a handler.
+func BuildOpenAPIDocument() *openapi.Document {
+	b := openapi.NewBuilder(openapi.Info{
+		Title:       "Flashcards API",
+		Description: "Notes, decks and flashcards with LLM-generated quizzes. Errors are answered as {\"error\": \"...\"}.",
+		Version:     API_VERSION,
+	})
+
+	listQuery := []openapi.Parameter{
+		openapi.QueryParameter("limit", "integer", "Page size"),
+		openapi.QueryParameter("offset", "integer", "Items to skip"),
+		openapi.QueryParameter("sort", "string", "Field to sort by"),
+		openapi.QueryParameter("order", "string", "asc or desc"),
+		openapi.QueryParameter("q", "string", "Search the content"),
+		openapi.QueryParameter("deckId", "integer", "Only items in this deck"),
+	}
+	notFound := []int{http.StatusNotFound}
+	generation := []int{http.StatusNotFound, http.StatusPaymentRequired, http.StatusServiceUnavailable}
+	// Updates guarded by the version read, as an ETag, with GET
+	versioned := []int{http.StatusNotFound, http.StatusConflict, http.StatusPreconditionRequired}
+	ifMatch := openapi.HeaderParameter("If-Match", "The ETag from the last GET; the update is refused if the resource has changed since")
+
+	// Auth
+	b.Add(openapi.Route{Method: "POST", Path: "/auth/register", Tag: "auth", Summary: "Create an account",
+		Request: models.RegisterRequest{}, Status: http.StatusCreated, Response: models.AuthResponse{},
+		Errors: []int{http.StatusConflict}, Public: true})
+	b.Add(openapi.Route{Method: "POST", Path: "/auth/login", Tag: "auth", Summary: "Log in and get a token",
+		Request: models.LoginRequest{}, Response: models.AuthResponse{},
+		Errors: []int{http.StatusUnauthorized}, Public: true})
+	b.Add(openapi.Route{Method: "GET", Path: "/auth/me", Tag: "auth", Summary: "Get the current user",
+		Response: models.User{}})
+
+	// Flashcards
+	b.Add(openapi.Route{Method: "POST", Path: "/flashcards", Tag: "flashcards", Summary: "Create a flashcard",
+		Request: models.CreateFlashcardRequest{}, Status: http.StatusCreated, Response: models.Flashcard{},
+		Errors: []int{http.StatusNotFound, http.StatusPaymentRequired}})
+	b.Add(openapi.Route{Method: "GET", Path: "/flashcards", Tag: "flashcards", Summary: "List flashcards",
+		Query: listQuery, Response: models.Page[*models.Flashcard]{}})
+	b.Add(openapi.Route{Method: "GET", Path: "/flashcards/{id:[0-9]+}", Tag: "flashcards", Summary: "Get a flashcard",
+		Description: "The ETag header holds the flashcard's version.",
+		Response:    models.Flashcard{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "PUT", Path: "/flashcards/{id:[0-9]+}", Tag: "flashcards", Summary: "Update a flashcard",
+		Description: "Answers 409 if the flashcard changed since the version in If-Match was read.",
+		Headers:     []openapi.Parameter{ifMatch},
+		Request:     models.UpdateFlashcardRequest{}, Response: models.Flashcard{}, Errors: versioned})
+	b.Add(openapi.Route{Method: "DELETE", Path: "/flashcards/{id:[0-9]+}", Tag: "flashcards", Summary: "Delete a flashcard",
+		Status: http.StatusNoContent, Errors: notFound})
+	b.Add(openapi.Route{Method: "GET", Path: "/flashcards/{id:[0-9]+}/render", Tag: "flashcards", Summary: "Render a flashcard as HTML",
+		Description: "Returns the sides as sanitized HTML; ?format=html returns a standalone page instead.",
+		Query:       []openapi.Parameter{openapi.QueryParameter("format", "string", "json or html")},
+		Response:    models.FlashcardHTML{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "POST", Path: "/flashcards/{id:[0-9]+}/mnemonic", Tag: "flashcards", Summary: "Generate memory aids for a flashcard",
+		Description: "Saves the aids in the flashcard's mnemonic, which review shows for cards the learner has found hard.",
+		Response:    models.Flashcard{}, Errors: generation})
+	b.Add(openapi.Route{Method: "POST", Path: "/notes/{id:[0-9]+}/generate-flashcards", Tag: "flashcards", Summary: "Generate flashcards from a note",
+		Request: models.GenerateFlashcardsRequest{}, Status: http.StatusCreated, Response: []*models.Flashcard{},
+		Errors: generation})
+
+	// Notes
+	b.Add(openapi.Route{Method: "POST", Path: "/notes", Tag: "notes", Summary: "Create a note",
+		Request: models.CreateNoteRequest{}, Status: http.StatusCreated, Response: models.Note{},
+		Errors: []int{http.StatusNotFound, http.StatusPaymentRequired}})
+	b.Add(openapi.Route{Method: "GET", Path: "/notes", Tag: "notes", Summary: "List notes",
+		Query: append(listQuery, openapi.QueryParameter("tag", "string", "Only notes with this tag"),
+			openapi.QueryParameter("language", "string", "Only notes in this language, as an ISO 639-1 code")),
+		Response: models.Page[*models.Note]{}})
+	b.Add(openapi.Route{Method: "POST", Path: "/notes/import", Tag: "notes", Summary: "Import Markdown files as notes",
+		Description:        "A validateOnly import answers 200 with the report and creates nothing.",
+		RequestContentType: "multipart/form-data",
+		RequestSchema: &openapi.Schema{Type: "object", Required: []string{"files"}, Properties: map[string]*openapi.Schema{
+			"files":        {Type: "array", Items: &openapi.Schema{Type: "string", Format: "binary"}},
+			"deckId":       {Type: "integer"},
+			"headingLevel": {Type: "integer"},
+			"validateOnly": {Type: "boolean"},
+		}},
+		Status: http.StatusCreated, Response: models.NoteImportReport{},
+		OtherResponses: map[int]any{http.StatusOK: models.NoteImportReport{}},
+		Errors:         []int{http.StatusPaymentRequired}})
+	b.Add(openapi.Route{Method: "GET", Path: "/notes/{id:[0-9]+}", Tag: "notes", Summary: "Get a note",
+		Description: "The ETag header holds the note's version.",
+		Response:    models.Note{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "PUT", Path: "/notes/{id:[0-9]+}", Tag: "notes", Summary: "Update a note",
+		Description: "Answers 409 if the note changed since the version in If-Match was read.",
+		Headers:     []openapi.Parameter{ifMatch},
+		Request:     models.UpdateNoteRequest{}, Response: models.Note{}, Errors: versioned})
+	b.Add(openapi.Route{Method: "DELETE", Path: "/notes/{id:[0-9]+}", Tag: "notes", Summary: "Delete a note",
+		Status: http.StatusNoContent, Errors: notFound})
+	b.Add(openapi.Route{Method: "GET", Path: "/tags", Tag: "notes", Summary: "List tags",
+		Response: []*models.Tag{}})
+	b.Add(openapi.Route{Method: "POST", Path: "/notes/{id:[0-9]+}/tags", Tag: "notes", Summary: "Tag a note",
+		Request: models.AddTagsRequest{}, Response: models.Note{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "DELETE", Path: "/notes/{id:[0-9]+}/tags/{tag}", Tag: "notes", Summary: "Remove a tag from a note",
+		Status: http.StatusNoContent, Errors: notFound})
+
+	// Decks
+	b.Add(openapi.Route{Method: "POST", Path: "/decks", Tag: "decks", Summary: "Create a deck",
+		Request: models.CreateDeckRequest{}, Status: http.StatusCreated, Response: models.Deck{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "GET", Path: "/decks", Tag: "decks", Summary: "List decks",
+		Response: []*models.Deck{}})
+	b.Add(openapi.Route{Method: "GET", Path: "/decks/{id:[0-9]+}", Tag: "decks", Summary: "Get a deck",
+		Response: models.Deck{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "PUT", Path: "/decks/{id:[0-9]+}", Tag: "decks", Summary: "Update a deck",
+		Request: models.UpdateDeckRequest{}, Response: models.Deck{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "DELETE", Path: "/decks/{id:[0-9]+}", Tag: "decks", Summary: "Delete a deck and its subdecks",
+		Status: http.StatusNoContent, Errors: notFound})
+	b.Add(openapi.Route{Method: "GET", Path: "/decks/{id:[0-9]+}/terminology", Tag: "decks", Summary: "Get a deck's terminology",
+		Response: models.DeckTerminology{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "PUT", Path: "/decks/{id:[0-9]+}/terminology", Tag: "decks", Summary: "Replace a deck's terminology",
+		Request: models.UpdateDeckTerminologyRequest{}, Response: models.DeckTerminology{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "GET", Path: "/decks/{id:[0-9]+}/study-settings", Tag: "decks", Summary: "Get a deck's study settings",
+		Response: models.DeckStudySettings{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "PUT", Path: "/decks/{id:[0-9]+}/study-settings", Tag: "decks", Summary: "Update a deck's study settings",
+		Request: models.UpdateDeckStudySettingsRequest{}, Response: models.DeckStudySettings{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "GET", Path: "/decks/{id:[0-9]+}/suggestions", Tag: "decks", Summary: "List a deck's maintenance suggestions",
+		Description: "Open suggestions from the weekly analysis: duplicate cards, leeches, cards from edited notes and cards from untagged notes.",
+		Response:    []*models.DeckSuggestion{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "POST", Path: "/decks/{id:[0-9]+}/suggestions/{suggestionId:[0-9]+}/apply", Tag: "decks", Summary: "Apply a maintenance suggestion",
+		Response: models.AppliedSuggestion{}, Errors: []int{http.StatusNotFound, http.StatusConflict, http.StatusPaymentRequired, http.StatusServiceUnavailable}})
+	b.Add(openapi.Route{Method: "POST", Path: "/decks/{id:[0-9]+}/suggestions/{suggestionId:[0-9]+}/dismiss", Tag: "decks", Summary: "Dismiss a maintenance suggestion",
+		Response: models.DeckSuggestion{}, Errors: []int{http.StatusNotFound, http.StatusConflict}})
+
+	// Quiz generation
+	b.Add(openapi.Route{Method: "POST", Path: "/notes/generate-quiz", Tag: "quiz", Summary: "Generate the next quiz question",
+		Description: "Send an Idempotency-Key header to make retries safe; a repeated key replays the first response.",
+		Request:     QuizRequest{}, Response: QuizResponse{},
+		Errors: []int{http.StatusNotFound, http.StatusPaymentRequired, http.StatusConflict, http.StatusServiceUnavailable}})
+	b.Add(openapi.Route{Method: "POST", Path: "/quiz/questions/plain-language", Tag: "quiz", Summary: "Rewrite a question in plain language",
+		Request: models.QuestionData{}, Response: models.QuestionData{},
+		Errors: []int{http.StatusPaymentRequired, http.StatusServiceUnavailable}})
+	b.Add(openapi.Route{Method: "POST", Path: "/quiz/grade-essay", Tag: "quiz", Summary: "Grade an essay answer",
+		Description: "Streams Server-Sent Events: a score event, feedback events with chunks of narrative, then a done event with the EssayFeedback.",
+		Request:     EssayGradeRequest{}, ResponseContentType: "text/event-stream", ResponseSchema: &openapi.Schema{Type: "string"},
+		Errors: []int{http.StatusPaymentRequired, http.StatusServiceUnavailable}})
+	b.Schema(models.EssayFeedback{})
+
+	// Quiz sessions
+	b.Add(openapi.Route{Method: "POST", Path: "/quiz/sessions", Tag: "quiz-sessions", Summary: "Start a quiz session",
+		Description: "Answers 202 with a QueuedQuizSession when questions must be generated while the LLM provider is unavailable.",
+		Request:     models.CreateQuizSessionRequest{}, Status: http.StatusCreated, Response: models.QuizSession{},
+		OtherResponses: map[int]any{http.StatusAccepted: models.QueuedQuizSession{}},
+		Errors:         []int{http.StatusNotFound, http.StatusPaymentRequired}})
+	b.Add(openapi.Route{Method: "GET", Path: "/quiz/sessions/queued/{id:[0-9]+}", Tag: "quiz-sessions", Summary: "Get a queued quiz session",
+		Response: models.QueuedQuizSession{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "GET", Path: "/quiz/sessions/{id:[0-9]+}", Tag: "quiz-sessions", Summary: "Get a quiz session",
+		Response: models.QuizSession{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "PUT", Path: "/quiz/sessions/{id:[0-9]+}/questions/{position:[0-9]+}", Tag: "quiz-sessions", Summary: "Answer or update a question",
+		Request: models.UpdateSessionQuestionRequest{}, Response: models.QuizSession{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "POST", Path: "/quiz/sessions/{id:[0-9]+}/questions/{position:[0-9]+}/image-answer", Tag: "quiz-sessions", Summary: "Answer a question with a photo",
+		RequestContentType: "multipart/form-data",
+		RequestSchema: &openapi.Schema{Type: "object", Required: []string{"image"}, Properties: map[string]*openapi.Schema{
+			"image":       {Type: "string", Format: "binary"},
+			"timeSpentMs": {Type: "integer"},
+		}},
+		Response: models.QuizSession{}, Errors: generation})
+	b.Add(openapi.Route{Method: "POST", Path: "/quiz/sessions/{id:[0-9]+}/questions/{position:[0-9]+}/audio-answer", Tag: "quiz-sessions", Summary: "Answer a question by voice",
+		Description:        "The recording is transcribed and the transcript graded like a typed answer. Answers 503 when transcription is not configured.",
+		RequestContentType: "multipart/form-data",
+		RequestSchema: &openapi.Schema{Type: "object", Required: []string{"audio"}, Properties: map[string]*openapi.Schema{
+			"audio":       {Type: "string", Format: "binary"},
+			"timeSpentMs": {Type: "integer"},
+		}},
+		Response: models.QuizSession{}, Errors: []int{http.StatusNotFound, http.StatusServiceUnavailable}})
+	b.Add(openapi.Route{Method: "POST", Path: "/quiz/sessions/{id:[0-9]+}/questions/{position:[0-9]+}/skip", Tag: "quiz-sessions", Summary: "Skip a question",
+		Response: models.QuizSession{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "POST", Path: "/quiz/sessions/{id:[0-9]+}/questions/{position:[0-9]+}/flag", Tag: "quiz-sessions", Summary: "Flag a question for review",
+		Response: models.QuizSession{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "DELETE", Path: "/quiz/sessions/{id:[0-9]+}/questions/{position:[0-9]+}/flag", Tag: "quiz-sessions", Summary: "Unflag a question",
+		Response: models.QuizSession{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "POST", Path: "/quiz/sessions/{id:[0-9]+}/questions/{position:[0-9]+}/explain", Tag: "quiz-sessions", Summary: "Explain a question's answer",
+		Response: models.QuestionExplanation{}, Errors: generation})
+	b.Add(openapi.Route{Method: "POST", Path: "/quiz/sessions/{id:[0-9]+}/questions/{position:[0-9]+}/regenerate", Tag: "quiz-sessions", Summary: "Replace a question with a new one",
+		Response: models.QuestionData{}, Errors: generation})
+	b.Add(openapi.Route{Method: "GET", Path: "/quiz/sessions/{id:[0-9]+}/next", Tag: "quiz-sessions", Summary: "Get the next unanswered question, or 204 when none is left",
+		Response: models.SessionQuestion{}, OtherResponses: map[int]any{http.StatusNoContent: nil}, Errors: notFound})
+	b.Add(openapi.Route{Method: "POST", Path: "/quiz/sessions/{id:[0-9]+}/complete", Tag: "quiz-sessions", Summary: "Complete a session and get its report",
+		Response: models.SessionReport{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "GET", Path: "/quiz/sessions/{id:[0-9]+}/report", Tag: "quiz-sessions", Summary: "Get a session report",
+		Description: "?format=markdown or ?format=pdf downloads the report as a document instead.",
+		Query:       []openapi.Parameter{openapi.QueryParameter("format", "string", "json, markdown or pdf")},
+		Response:    models.SessionReport{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "POST", Path: "/quiz/sessions/{id:[0-9]+}/report/email", Tag: "quiz-sessions", Summary: "Email a session report",
+		Request: models.EmailReportRequest{}, Response: map[string]string{}, Errors: []int{http.StatusNotFound, http.StatusBadGateway, http.StatusServiceUnavailable}})
+	b.Add(openapi.Route{Method: "GET", Path: "/ws/quiz", Tag: "quiz-sessions", Summary: "Run a session as a live quiz over a WebSocket",
+		Description: "The connection signs in with its first message, {\"type\":\"start\",\"token\":...,\"sessionId\":...} or {\"type\":\"resume\",\"resumeToken\":...}. " +
+			"Every message in either direction is a LiveQuizMessage: the server sends question, result, complete, error and ping messages, " +
+			"and the client sends answer, skip and pong messages. Requests that are not a WebSocket upgrade answer 400.",
+		Status: http.StatusSwitchingProtocols, Public: true})
+	b.Schema(models.LiveQuizMessage{})
+
+	// Background jobs
+	b.Add(openapi.Route{Method: "POST", Path: "/jobs", Tag: "jobs", Summary: "Queue background work",
+		Description: "Kinds: generate-flashcards ({\"count\", \"skipExisting\"}) generates flashcards from every note, " +
+			"summarize-notes ({\"onlyMissing\"}) writes note summaries again. Poll the job with GET /jobs/{id}.",
+		Request: models.CreateJobRequest{}, Status: http.StatusAccepted, Response: models.Job{}})
+	b.Add(openapi.Route{Method: "GET", Path: "/jobs", Tag: "jobs", Summary: "List background jobs",
+		Query: append(listQuery[:4:4],
+			openapi.QueryParameter("status", "string", "queued, running, completed or failed"),
+			openapi.QueryParameter("kind", "string", "Only jobs of this kind")),
+		Response: models.Page[*models.Job]{}})
+	b.Add(openapi.Route{Method: "GET", Path: "/jobs/{id:[0-9]+}", Tag: "jobs", Summary: "Get a background job's status and result",
+		Response: models.Job{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "POST", Path: "/admin/jobs", Tag: "jobs", Summary: "Queue background work for the whole catalog",
+		Description: "Kinds: reembed-catalog embeds the cards of every published deck again. Requires the admin role.",
+		Request:     models.CreateJobRequest{}, Status: http.StatusAccepted, Response: models.Job{}, Errors: []int{http.StatusForbidden}})
+
+	// Daily questions
+	b.Add(openapi.Route{Method: "GET", Path: "/daily-questions/subscriptions", Tag: "daily-questions", Summary: "List daily question subscriptions",
+		Response: []models.DailyQuestionSubscription{}})
+	b.Add(openapi.Route{Method: "POST", Path: "/daily-questions/subscriptions", Tag: "daily-questions", Summary: "Get a question from a deck by email each morning",
+		Request: models.CreateDailyQuestionSubscriptionRequest{}, Status: http.StatusCreated, Response: models.DailyQuestionSubscription{},
+		Errors: []int{http.StatusNotFound, http.StatusConflict}})
+	b.Add(openapi.Route{Method: "DELETE", Path: "/daily-questions/subscriptions/{id:[0-9]+}", Tag: "daily-questions", Summary: "Unsubscribe from a deck's daily question",
+		Status: http.StatusNoContent, Errors: notFound})
+	b.Add(openapi.Route{Method: "POST", Path: "/daily-questions/answer", Tag: "daily-questions", Summary: "Answer a daily question with the token from its email",
+		Request: models.AnswerDailyQuestionRequest{}, Response: models.DailyQuestionResult{},
+		Errors: []int{http.StatusUnauthorized, http.StatusConflict}, Public: true})
+	b.Add(openapi.Route{Method: "POST", Path: "/daily-questions/replies", Tag: "daily-questions", Summary: "Answer a daily question from an email reply",
+		Description: "For the mail provider's inbound webhook. The answer is the first line of the reply and the token is read from the quoted email.",
+		Request:     models.DailyQuestionReply{}, Response: models.DailyQuestionResult{},
+		Errors: []int{http.StatusUnauthorized, http.StatusConflict}, Public: true})
+
+	// Webhooks
+	b.Add(openapi.Route{Method: "GET", Path: "/webhooks", Tag: "webhooks", Summary: "List webhooks",
+		Response: []models.Webhook{}})
+	b.Add(openapi.Route{Method: "POST", Path: "/webhooks", Tag: "webhooks", Summary: "Register a webhook",
+		Description: "Events: note.created, quiz.generated, quiz.completed, review.completed. The response carries the signing secret, which is only shown once. " +
+			"Each delivery is a POST of {\"id\", \"event\", \"createdAt\", \"data\"} with an X-Webhook-Signature header of t=<unix time>,v1=<hex HMAC-SHA256 of \"<t>.<body>\">.",
+		Request: models.CreateWebhookRequest{}, Status: http.StatusCreated, Response: models.Webhook{}})
+	b.Add(openapi.Route{Method: "GET", Path: "/webhooks/{id:[0-9]+}", Tag: "webhooks", Summary: "Get a webhook",
+		Response: models.Webhook{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "PUT", Path: "/webhooks/{id:[0-9]+}", Tag: "webhooks", Summary: "Change a webhook's URL or events, or pause it",
+		Request: models.UpdateWebhookRequest{}, Response: models.Webhook{}, Errors: notFound})
+	b.Add(openapi.Route{Method: "DELETE", Path: "/webhooks/{id:[0-9]+}", Tag: "webhooks", Summary: "Delete a webhook",
+		Status: http.StatusNoContent, Errors: notFound})
+	b.Add(openapi.Route{Method: "GET", Path: "/webhooks/{id:[0-9]+}/deliveries", Tag: "webhooks", Summary: "List a webhook's deliveries",
+		Query: listQuery[:4], Response: models.Page[*models.WebhookDelivery]{}, Errors: notFound})
+	b.Schema(models.WebhookEvent{})
+
+	// REST hooks
+	b.Add(openapi.Route{Method: "POST", Path: "/hooks", Tag: "rest-hooks", Summary: "Subscribe a REST hook to a trigger",
+		Description: "For Zapier, Make and similar platforms. Triggers: new_note, quiz_completed, low_score (a quiz scoring below 60%). " +
+			"Each delivery POSTs the trigger payload alone, a NoteTrigger or QuizTrigger; answering 410 unsubscribes the hook.",
+		Request: models.SubscribeRestHookRequest{}, Status: http.StatusCreated, Response: models.RestHook{}})
+	b.Add(openapi.Route{Method: "DELETE", Path: "/hooks/{id:[0-9]+}", Tag: "rest-hooks", Summary: "Unsubscribe a REST hook",
+		Status: http.StatusNoContent, Errors: notFound})
+	b.Add(openapi.Route{Method: "GET", Path: "/hooks/samples/{event}", Tag: "rest-hooks", Summary: "Get recent payloads of a trigger",
+		Description: "Up to three of the user's most recent notes or quizzes as trigger payloads, newest first.",
+		Response:    []any{}})
+	b.Schema(models.NoteTrigger{})
+	b.Schema(models.QuizTrigger{})
+
+	return b.Document()
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type OrganizationHandler struct {
+	service *services.QuotaService
+}
+
+func NewOrganizationHandler(service *services.QuotaService) *OrganizationHandler {
+	return &OrganizationHandler{service: service}
+}
+
+func (h *OrganizationHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/quota", h.GetQuota).Methods("GET")
+}
+
+func (h *OrganizationHandler) RegisterAdminRoutes(router *mux.Router) {
+	router.HandleFunc("/organizations", h.CreateOrganization).Methods("POST")
+	router.HandleFunc("/organizations/{id:[0-9]+}", h.GetOrganization).Methods("GET")
+	router.HandleFunc("/organizations/{id:[0-9]+}/quota", h.UpdateQuota).Methods("PUT")
+	router.HandleFunc("/organizations/{id:[0-9]+}/members", h.AddMember).Methods("POST")
+}
+
+// GetQuota returns the current user's plan limits and this month's usage
+func (h *OrganizationHandler) GetQuota(w http.ResponseWriter, r *http.Request) {
+	quota, err := h.service.GetQuota(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		writeError(w, err, "Failed to retrieve quota")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, quota)
+}
+
+func (h *OrganizationHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	organization, err := h.service.CreateOrganization(r.Context(), &req)
+	if err != nil {
+		writeError(w, err, "Failed to create organization")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, organization)
+}
+
+func (h *OrganizationHandler) GetOrganization(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid organization ID")
+		return
+	}
+
+	organization, err := h.service.GetOrganization(r.Context(), id)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve organization")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, organization)
+}
+
+// UpdateQuota changes an organization's plan and overrides its limits
+func (h *OrganizationHandler) UpdateQuota(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid organization ID")
+		return
+	}
+
+	var req models.UpdateQuotaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	organization, err := h.service.UpdateQuota(r.Context(), id, &req)
+	if err != nil {
+		writeError(w, err, "Failed to update quota")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, organization)
+}
+
+func (h *OrganizationHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid organization ID")
+		return
+	}
+
+	var req models.AddOrganizationMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	organization, err := h.service.AddMember(r.Context(), id, &req)
+	if err != nil {
+		writeError(w, err, "Failed to add organization member")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, organization)
+}
+
+func (h *OrganizationHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *OrganizationHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+)
+
+// parseListParams reads limit, offset, sort and order from the query string.
+// Defaults and range checks are applied by the services.
+func parseListParams(r *http.Request) (models.ListParams, error) {
+	query := r.URL.Query()
+	params := models.ListParams{
+		Sort:  query.Get("sort"),
+		Order: query.Get("order"),
+	}
+
+	var err error
+	if value := query.Get("limit"); value != "" {
+		if params.Limit, err = strconv.Atoi(value); err != nil {
+			return params, fmt.Errorf("limit must be an integer")
+		}
+	}
+
+	if value := query.Get("offset"); value != "" {
+		if params.Offset, err = strconv.Atoi(value); err != nil {
+			return params, fmt.Errorf("offset must be an integer")
+		}
+	}
+
+	return params, nil
+}
+
+// parseDeckIDFilter reads the optional deckId query parameter
+func parseDeckIDFilter(r *http.Request) (*int, error) {
+	value := r.URL.Query().Get("deckId")
+	if value == "" {
+		return nil, nil
+	}
+
+	deckID, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("deckId must be an integer")
+	}
+
+	return &deckID, nil
+}
+
+// setNextPageLink points page.Next at the following page, keeping the other
+// query parameters, when there are more items to fetch
+func setNextPageLink[T any](r *http.Request, page *models.Page[T]) {
+	nextOffset := page.Offset + len(page.Items)
+	if len(page.Items) == 0 || nextOffset >= page.Total {
+		return
+	}
+
+	query := r.URL.Query()
+	query.Set("limit", strconv.Itoa(page.Limit))
+	query.Set("offset", strconv.Itoa(nextOffset))
+	page.Next = r.URL.Path + "?" + query.Encode()
+}
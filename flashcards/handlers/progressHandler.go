@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type ProgressHandler struct {
+	service *services.ProgressService
+}
+
+func NewProgressHandler(service *services.ProgressService) *ProgressHandler {
+	return &ProgressHandler{service: service}
+}
+
+func (h *ProgressHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/reports/progress", h.GetProgressReport).Methods("GET")
+	router.HandleFunc("/reports/recipients", h.CreateRecipient).Methods("POST")
+	router.HandleFunc("/reports/recipients", h.GetAllRecipients).Methods("GET")
+	router.HandleFunc("/reports/recipients/{id:[0-9]+}", h.DeleteRecipient).Methods("DELETE")
+}
+
+func (h *ProgressHandler) GetProgressReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.service.BuildReport(r.Context(), userIDFromRequest(r), time.Now())
+	if err != nil {
+		writeError(w, err, "Failed to build progress report")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, report)
+}
+
+func (h *ProgressHandler) CreateRecipient(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateReportRecipientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	recipient, err := h.service.CreateRecipient(r.Context(), userIDFromRequest(r), &req)
+	if err != nil {
+		writeError(w, err, "Failed to add report recipient")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, recipient)
+}
+
+func (h *ProgressHandler) GetAllRecipients(w http.ResponseWriter, r *http.Request) {
+	recipients, err := h.service.GetAllRecipients(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		writeError(w, err, "Failed to retrieve report recipients")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, recipients)
+}
+
+func (h *ProgressHandler) DeleteRecipient(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid report recipient ID")
+		return
+	}
+
+	err = h.service.DeleteRecipient(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to delete report recipient")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ProgressHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *ProgressHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type PromptHandler struct {
+	registry *services.PromptRegistry
+}
+
+func NewPromptHandler(registry *services.PromptRegistry) *PromptHandler {
+	return &PromptHandler{registry: registry}
+}
+
+func (h *PromptHandler) RegisterAdminRoutes(router *mux.Router) {
+	router.HandleFunc("/prompts", h.ListPromptTemplates).Methods("GET")
+	router.HandleFunc("/prompts/{name}/versions", h.ListPromptTemplateVersions).Methods("GET")
+	router.HandleFunc("/prompts/{name}", h.UpdatePromptTemplate).Methods("PUT")
+}
+
+// ListPromptTemplates returns the template in use for each prompt and where
+// it was loaded from
+func (h *PromptHandler) ListPromptTemplates(w http.ResponseWriter, r *http.Request) {
+	h.writeJSONResponse(w, http.StatusOK, h.registry.ListPromptTemplates(r.Context()))
+}
+
+func (h *PromptHandler) ListPromptTemplateVersions(w http.ResponseWriter, r *http.Request) {
+	versions, err := h.registry.ListPromptTemplateVersions(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		writeError(w, err, "Failed to retrieve prompt template versions")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, versions)
+}
+
+// UpdatePromptTemplate saves a new version of a prompt template and puts it
+// in use right away
+func (h *PromptHandler) UpdatePromptTemplate(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdatePromptTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	prompt, err := h.registry.UpdatePromptTemplate(r.Context(), mux.Vars(r)["name"], &req)
+	if err != nil {
+		writeError(w, err, "Failed to update prompt template")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, prompt)
+}
+
+func (h *PromptHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *PromptHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
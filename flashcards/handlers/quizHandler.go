@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -17,8 +19,11 @@ type QuizRequest struct {
 	NoteIds      []int            `json:"noteIds"`
 	Conversation []models.Message `json:"conversation"`
 	Options      struct {
-		Difficulty   string `json:"difficulty,omitempty"`
-		QuestionType string `json:"questionType,omitempty"`
+		Difficulty        string `json:"difficulty,omitempty"`
+		QuestionType      string `json:"questionType,omitempty"`
+		CompressionTarget int    `json:"compressionTarget,omitempty"`
+		Count             int    `json:"count,omitempty"`
+		ForceRegenerate   bool   `json:"forceRegenerate,omitempty"`
 	} `json:"options"`
 }
 
@@ -33,21 +38,47 @@ type QuizResponseData struct {
 }
 
 type QuizMetadata struct {
-	GeneratedAt      string `json:"generatedAt"`
-	TokensUsed       int    `json:"tokensUsed"`
-	ProcessingTimeMs int    `json:"processingTimeMs"`
+	GeneratedAt      string                `json:"generatedAt"`
+	TokensUsed       int                   `json:"tokensUsed"`
+	ProcessingTimeMs int                   `json:"processingTimeMs"`
+	CompressionRatio float64               `json:"compressionRatio"`
+	Context          services.ContextUsage `json:"context"`
+	// "question-bank" when the LLM was unavailable and earlier questions
+	// were served instead, "cache" when the same quiz was generated recently
+	Source string `json:"source"`
+	// The difficulty used and whether it came from the options, the message
+	// or the user's recent answers
+	Difficulty models.DifficultyCalibration `json:"difficulty"`
+}
+
+type EssayGradeRequest struct {
+	Question models.QuestionData `json:"question"`
+	Answer   string              `json:"answer"`
 }
 
 type QuizHandler struct {
-	service *services.QuizService
+	service     *services.QuizService
+	idempotency *services.IdempotencyService
+	rateLimit   *RateLimit
 }
 
-func NewQuizHandler(service *services.QuizService) *QuizHandler {
-	return &QuizHandler{service: service}
+// NewQuizHandler takes the rate limit for LLM-backed generation, on top of
+// the limit applied to every route
+func NewQuizHandler(service *services.QuizService, idempotency *services.IdempotencyService, rateLimit *RateLimit) *QuizHandler {
+	return &QuizHandler{service: service, idempotency: idempotency, rateLimit: rateLimit}
 }
 
 func (h *QuizHandler) RegisterRoutes(router *mux.Router) {
-	router.HandleFunc("/notes/generate-quiz", h.GenerateQuiz).Methods("POST")
+	// Generation is paid for, so it has its own rate limit and retries with
+	// the same Idempotency-Key replay the first response
+	router.HandleFunc("/notes/generate-quiz", h.rateLimit.Wrap(idempotent(h.idempotency, h.GenerateQuiz))).Methods("POST")
+	router.HandleFunc("/quiz/questions/plain-language", h.PlainLanguageVariant).Methods("POST")
+}
+
+// RegisterStreamingRoutes registers the routes that stream LLM output, so
+// the router can cancel them when the server shuts down
+func (h *QuizHandler) RegisterStreamingRoutes(router *mux.Router) {
+	router.HandleFunc("/quiz/grade-essay", h.GradeEssay).Methods("POST")
 }
 
 func (h *QuizHandler) GenerateQuiz(w http.ResponseWriter, r *http.Request) {
@@ -66,14 +97,24 @@ func (h *QuizHandler) GenerateQuiz(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate new assistant message
-	newMessage, err := h.service.GenerateQuiz(req.Conversation, req.NoteIds)
+	result, err := h.service.GenerateQuiz(r.Context(), userIDFromRequest(r), req.Conversation, req.NoteIds, services.QuizOptions{
+		CompressionTarget: req.Options.CompressionTarget,
+		Count:             req.Options.Count,
+		Difficulty:        req.Options.Difficulty,
+		QuestionType:      req.Options.QuestionType,
+		ForceRegenerate:   req.Options.ForceRegenerate,
+	})
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to generate quiz: "+err.Error())
+		if errors.Is(err, context.DeadlineExceeded) {
+			h.writeErrorResponse(w, http.StatusGatewayTimeout, "Quiz generation timed out")
+		} else {
+			writeError(w, err, "Failed to generate quiz: "+err.Error())
+		}
 		return
 	}
 
-	// Append new assistant message to conversation
-	updatedConversation := append(req.Conversation, newMessage)
+	// Append new assistant messages to conversation
+	updatedConversation := append(req.Conversation, result.Messages...)
 
 	// Build response
 	response := QuizResponse{
@@ -83,14 +124,89 @@ func (h *QuizHandler) GenerateQuiz(w http.ResponseWriter, r *http.Request) {
 		},
 		Metadata: QuizMetadata{
 			GeneratedAt:      time.Now().Format(time.RFC3339),
-			TokensUsed:       150, // Hardcoded for now
+			TokensUsed:       result.Context.PromptTokens,
 			ProcessingTimeMs: int(time.Since(startTime).Milliseconds()),
+			CompressionRatio: result.CompressionRatio,
+			Context:          result.Context,
+			Source:           result.Source,
+			Difficulty:       result.Difficulty,
 		},
 	}
 
 	h.writeJSONResponse(w, http.StatusOK, response)
 }
 
+// GradeEssay streams essay feedback as Server-Sent Events: a "score" event
+// first, then "feedback" events with narrative chunks, and a final "done"
+// event carrying the complete result.
+func (h *QuizHandler) GradeEssay(w http.ResponseWriter, r *http.Request) {
+	var req EssayGradeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if req.Question.Text == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "question text is required")
+		return
+	}
+	if req.Answer == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "answer cannot be empty")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	onScore := func(score int) error {
+		return h.writeSSEEvent(w, flusher, "score", map[string]int{
+			"score":    score,
+			"maxScore": services.ESSAY_MAX_SCORE,
+		})
+	}
+	onChunk := func(chunk string) error {
+		return h.writeSSEEvent(w, flusher, "feedback", map[string]string{"text": chunk})
+	}
+
+	feedback, err := h.service.StreamEssayFeedback(r.Context(), req.Question, req.Answer, onScore, onChunk)
+	if err != nil {
+		h.writeSSEEvent(w, flusher, "error", map[string]string{"error": "Failed to grade essay: " + err.Error()})
+		return
+	}
+
+	h.writeSSEEvent(w, flusher, "done", feedback)
+}
+
+func (h *QuizHandler) PlainLanguageVariant(w http.ResponseWriter, r *http.Request) {
+	var question models.QuestionData
+	if err := json.NewDecoder(r.Body).Decode(&question); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if question.Text == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "question text is required")
+		return
+	}
+
+	updated, err := h.service.PlainLanguageVariant(r.Context(), userIDFromRequest(r), question)
+	if err != nil {
+		writeError(w, err, "Failed to generate plain-language variant: "+err.Error())
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, updated)
+}
+
 func (h *QuizHandler) validateQuizRequest(req *QuizRequest) error {
 	if len(req.Conversation) == 0 {
 		return fmt.Errorf("conversation cannot be empty")
@@ -105,6 +221,19 @@ func (h *QuizHandler) validateQuizRequest(req *QuizRequest) error {
 		return fmt.Errorf("user message cannot be empty")
 	}
 
+	if req.Options.CompressionTarget < 0 || req.Options.CompressionTarget > services.MAX_COMPRESSION_TARGET {
+		return fmt.Errorf("compressionTarget must be between 0 and %d", services.MAX_COMPRESSION_TARGET)
+	}
+
+	if req.Options.Count < 0 || req.Options.Count > services.MAX_QUESTIONS_PER_REQUEST {
+		return fmt.Errorf("count must be between 1 and %d", services.MAX_QUESTIONS_PER_REQUEST)
+	}
+
+	options := services.QuizOptions{Difficulty: req.Options.Difficulty, QuestionType: req.Options.QuestionType}
+	if err := options.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -114,11 +243,20 @@ func (h *QuizHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, d
 	json.NewEncoder(w).Encode(data)
 }
 
+func (h *QuizHandler) writeSSEEvent(w http.ResponseWriter, flusher http.Flusher, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
+}
+
 func (h *QuizHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": false,
-		"error":   message,
-	})
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
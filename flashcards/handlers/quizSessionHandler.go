@@ -0,0 +1,435 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+// Room for multipart boundaries and form fields around the image itself
+const MULTIPART_OVERHEAD_BYTES = 64 * 1024
+
+type QuizSessionHandler struct {
+	service *services.QuizSessionService
+}
+
+func NewQuizSessionHandler(service *services.QuizSessionService) *QuizSessionHandler {
+	return &QuizSessionHandler{service: service}
+}
+
+func (h *QuizSessionHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/quiz/sessions", h.CreateSession).Methods("POST")
+	router.HandleFunc("/quiz/sessions/queued/{id:[0-9]+}", h.GetQueuedSession).Methods("GET")
+	router.HandleFunc("/quiz/sessions/{id:[0-9]+}", h.GetSession).Methods("GET")
+	router.HandleFunc("/quiz/sessions/{id:[0-9]+}/questions/{position:[0-9]+}", h.UpdateQuestion).Methods("PUT")
+	router.HandleFunc("/quiz/sessions/{id:[0-9]+}/questions/{position:[0-9]+}/image-answer", h.SubmitImageAnswer).Methods("POST")
+	router.HandleFunc("/quiz/sessions/{id:[0-9]+}/questions/{position:[0-9]+}/audio-answer", h.SubmitAudioAnswer).Methods("POST")
+	router.HandleFunc("/quiz/sessions/{id:[0-9]+}/questions/{position:[0-9]+}/skip", h.SkipQuestion).Methods("POST")
+	router.HandleFunc("/quiz/sessions/{id:[0-9]+}/questions/{position:[0-9]+}/flag", h.FlagQuestion).Methods("POST")
+	router.HandleFunc("/quiz/sessions/{id:[0-9]+}/questions/{position:[0-9]+}/flag", h.UnflagQuestion).Methods("DELETE")
+	router.HandleFunc("/quiz/sessions/{id:[0-9]+}/questions/{position:[0-9]+}/explain", h.ExplainQuestion).Methods("POST")
+	router.HandleFunc("/quiz/sessions/{id:[0-9]+}/questions/{position:[0-9]+}/regenerate", h.RegenerateQuestion).Methods("POST")
+	router.HandleFunc("/quiz/sessions/{id:[0-9]+}/next", h.NextQuestion).Methods("GET")
+	router.HandleFunc("/quiz/sessions/{id:[0-9]+}/complete", h.CompleteSession).Methods("POST")
+	router.HandleFunc("/quiz/sessions/{id:[0-9]+}/report", h.GetReport).Methods("GET")
+	router.HandleFunc("/quiz/sessions/{id:[0-9]+}/report/email", h.EmailReport).Methods("POST")
+}
+
+func (h *QuizSessionHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateQuizSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	session, err := h.service.CreateSession(r.Context(), userIDFromRequest(r), &req)
+	if errors.Is(err, services.ErrLLMUnavailable) && len(req.Questions) == 0 {
+		h.queueSession(w, r, &req, err)
+		return
+	}
+	if err != nil {
+		writeError(w, err, "Failed to create quiz session")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, session)
+}
+
+// queueSession answers a generated session request that cannot be served
+// while the LLM provider is down with 202 and the queued request to poll
+func (h *QuizSessionHandler) queueSession(w http.ResponseWriter, r *http.Request, req *models.CreateQuizSessionRequest, cause error) {
+	queued, err := h.service.QueueSession(r.Context(), userIDFromRequest(r), req)
+	if err != nil {
+		if !writeLLMUnavailable(w, cause) {
+			h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to queue quiz session")
+		}
+		return
+	}
+
+	var unavailable *services.LLMUnavailableError
+	if errors.As(cause, &unavailable) {
+		w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(unavailable.RetryAfter))))
+	}
+	w.Header().Set("Location", fmt.Sprintf("/quiz/sessions/queued/%d", queued.ID))
+	h.writeJSONResponse(w, http.StatusAccepted, queued)
+}
+
+// GetQueuedSession reports a queued session request; once completed it
+// carries the ID of the created session
+func (h *QuizSessionHandler) GetQueuedSession(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid queued quiz session ID")
+		return
+	}
+
+	queued, err := h.service.GetQueuedSession(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve queued quiz session")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, queued)
+}
+
+func (h *QuizSessionHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid quiz session ID")
+		return
+	}
+
+	session, err := h.service.GetSessionByID(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve quiz session")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, session)
+}
+
+func (h *QuizSessionHandler) UpdateQuestion(w http.ResponseWriter, r *http.Request) {
+	id, position, ok := h.parseQuestionVars(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateSessionQuestionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	session, err := h.service.UpdateQuestion(r.Context(), userIDFromRequest(r), id, position, &req)
+	if err != nil {
+		writeError(w, err, "Failed to update question")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, session)
+}
+
+// SubmitImageAnswer accepts a multipart upload with the photo of a worked
+// answer in the "image" field and an optional "timeSpentMs" field
+func (h *QuizSessionHandler) SubmitImageAnswer(w http.ResponseWriter, r *http.Request) {
+	id, position, ok := h.parseQuestionVars(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, services.MAX_ATTACHMENT_BYTES+MULTIPART_OVERHEAD_BYTES)
+	if err := r.ParseMultipartForm(services.MAX_ATTACHMENT_BYTES); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid multipart upload or image too large")
+		return
+	}
+
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "image file is required")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Failed to read image")
+		return
+	}
+
+	submission := &models.ImageAnswerSubmission{
+		Filename: header.Filename,
+		Data:     data,
+	}
+	if value := r.FormValue("timeSpentMs"); value != "" {
+		timeSpentMs, err := strconv.Atoi(value)
+		if err != nil {
+			h.writeErrorResponse(w, http.StatusBadRequest, "timeSpentMs must be an integer")
+			return
+		}
+		submission.TimeSpentMs = &timeSpentMs
+	}
+
+	session, err := h.service.SubmitImageAnswer(r.Context(), userIDFromRequest(r), id, position, submission)
+	if err != nil {
+		writeError(w, err, "Failed to grade image answer: "+err.Error())
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, session)
+}
+
+// SubmitAudioAnswer accepts a multipart upload with a spoken answer in the
+// "audio" field and an optional "timeSpentMs" field. The transcript is
+// graded and saved as the answer.
+func (h *QuizSessionHandler) SubmitAudioAnswer(w http.ResponseWriter, r *http.Request) {
+	id, position, ok := h.parseQuestionVars(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, services.MAX_AUDIO_ANSWER_BYTES+MULTIPART_OVERHEAD_BYTES)
+	if err := r.ParseMultipartForm(services.MAX_AUDIO_ANSWER_BYTES); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid multipart upload or audio too large")
+		return
+	}
+
+	file, header, err := r.FormFile("audio")
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "audio file is required")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Failed to read audio")
+		return
+	}
+
+	submission := &models.AudioAnswerSubmission{
+		Filename: header.Filename,
+		Data:     data,
+	}
+	if value := r.FormValue("timeSpentMs"); value != "" {
+		timeSpentMs, err := strconv.Atoi(value)
+		if err != nil {
+			h.writeErrorResponse(w, http.StatusBadRequest, "timeSpentMs must be an integer")
+			return
+		}
+		submission.TimeSpentMs = &timeSpentMs
+	}
+
+	session, err := h.service.SubmitAudioAnswer(r.Context(), userIDFromRequest(r), id, position, submission)
+	if err != nil {
+		writeError(w, err, "Failed to grade audio answer: "+err.Error())
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, session)
+}
+
+func (h *QuizSessionHandler) SkipQuestion(w http.ResponseWriter, r *http.Request) {
+	id, position, ok := h.parseQuestionVars(w, r)
+	if !ok {
+		return
+	}
+
+	session, err := h.service.SkipQuestion(r.Context(), userIDFromRequest(r), id, position)
+	if err != nil {
+		writeError(w, err, "Failed to skip question")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, session)
+}
+
+func (h *QuizSessionHandler) FlagQuestion(w http.ResponseWriter, r *http.Request) {
+	h.setFlag(w, r, true)
+}
+
+func (h *QuizSessionHandler) UnflagQuestion(w http.ResponseWriter, r *http.Request) {
+	h.setFlag(w, r, false)
+}
+
+func (h *QuizSessionHandler) setFlag(w http.ResponseWriter, r *http.Request, flagged bool) {
+	id, position, ok := h.parseQuestionVars(w, r)
+	if !ok {
+		return
+	}
+
+	session, err := h.service.FlagQuestion(r.Context(), userIDFromRequest(r), id, position, flagged)
+	if err != nil {
+		writeError(w, err, "Failed to flag question")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, session)
+}
+
+func (h *QuizSessionHandler) ExplainQuestion(w http.ResponseWriter, r *http.Request) {
+	id, position, ok := h.parseQuestionVars(w, r)
+	if !ok {
+		return
+	}
+
+	explanation, err := h.service.ExplainQuestion(r.Context(), userIDFromRequest(r), id, position)
+	if err != nil {
+		writeError(w, err, "Failed to generate explanation")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, explanation)
+}
+
+func (h *QuizSessionHandler) RegenerateQuestion(w http.ResponseWriter, r *http.Request) {
+	id, position, ok := h.parseQuestionVars(w, r)
+	if !ok {
+		return
+	}
+
+	question, err := h.service.RegenerateQuestion(r.Context(), userIDFromRequest(r), id, position)
+	if err != nil {
+		writeError(w, err, "Failed to regenerate question")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, question)
+}
+
+func (h *QuizSessionHandler) NextQuestion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid quiz session ID")
+		return
+	}
+
+	question, err := h.service.NextQuestion(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve next question")
+		return
+	}
+
+	if question == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, question)
+}
+
+func (h *QuizSessionHandler) CompleteSession(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid quiz session ID")
+		return
+	}
+
+	report, err := h.service.CompleteSession(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to complete quiz session")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, report)
+}
+
+// GetReport returns the session report; ?format=markdown or ?format=pdf
+// downloads it as a document instead of JSON
+func (h *QuizSessionHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid quiz session ID")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" || format == services.REPORT_FORMAT_JSON {
+		report, err := h.service.GetReport(r.Context(), userIDFromRequest(r), id)
+		if err != nil {
+			writeError(w, err, "Failed to build session report")
+			return
+		}
+
+		h.writeJSONResponse(w, http.StatusOK, report)
+		return
+	}
+
+	if services.ReportContentType(format) == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "format must be one of: json, markdown, pdf")
+		return
+	}
+
+	document, err := h.service.RenderReport(r.Context(), userIDFromRequest(r), id, format)
+	if err != nil {
+		writeError(w, err, "Failed to render session report")
+		return
+	}
+
+	w.Header().Set("Content-Type", services.ReportContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, services.ReportFilename(id, format)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(document)
+}
+
+func (h *QuizSessionHandler) EmailReport(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid quiz session ID")
+		return
+	}
+
+	var req models.EmailReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if err := h.service.EmailReport(r.Context(), userIDFromRequest(r), id, &req); err != nil {
+		writeError(w, err, "Failed to send report email")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]string{"status": "sent"})
+}
+
+func (h *QuizSessionHandler) parseQuestionVars(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid quiz session ID")
+		return 0, 0, false
+	}
+
+	position, err := strconv.Atoi(vars["position"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid question position")
+		return 0, 0, false
+	}
+
+	return id, position, true
+}
+
+func (h *QuizSessionHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *QuizSessionHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"flashcards/services"
+)
+
+// RateLimit refuses requests over a limiter's rate with 429. Authenticated
+// requests are limited per user and the rest per client IP.
+type RateLimit struct {
+	limiter        services.RateLimiter
+	clientIPHeader string
+}
+
+// NewRateLimit takes the header a trusted proxy puts the client IP in, such
+// as X-Forwarded-For; when empty the connection's address is used
+func NewRateLimit(limiter services.RateLimiter, clientIPHeader string) *RateLimit {
+	return &RateLimit{limiter: limiter, clientIPHeader: clientIPHeader}
+}
+
+func (rl *RateLimit) Middleware(next http.Handler) http.Handler {
+	return rl.Wrap(next.ServeHTTP)
+}
+
+// Wrap limits a single route, on top of any limit applied by Middleware
+func (rl *RateLimit) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if rl == nil || !rl.limiter.Enabled() {
+			next(w, r)
+			return
+		}
+
+		decision := rl.limiter.Allow(r.Context(), rl.key(r))
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.Reset)))
+
+		if !decision.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(decision.RetryAfter)))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Rate limit exceeded, retry later"})
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (rl *RateLimit) key(r *http.Request) string {
+	if userID := userIDFromRequest(r); userID != 0 {
+		return "user:" + strconv.Itoa(userID)
+	}
+	return "ip:" + rl.clientIP(r)
+}
+
+func (rl *RateLimit) clientIP(r *http.Request) string {
+	if rl.clientIPHeader != "" {
+		// The first address of a forwarded chain is the original client
+		value, _, _ := strings.Cut(r.Header.Get(rl.clientIPHeader), ",")
+		if ip := strings.TrimSpace(value); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
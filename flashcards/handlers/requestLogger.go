@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
+
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const REQUEST_ID_HEADER = "X-Request-ID"
+
+// Client-supplied request IDs are kept only if they look like IDs, so they
+// cannot inject arbitrary text into logs
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type requestLogContextKey struct{}
+
+// requestLog collects fields learned while handling a request, such as the
+// user set by the auth middleware, for the completion log line
+type requestLog struct {
+	userID int
+}
+
+// RequestLogger assigns each request an ID, returned in the X-Request-ID
+// header, and stores a logger tagged with it in the request context. When
+// the request finishes it logs the route, status, latency and user.
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+
+		requestID := r.Header.Get(REQUEST_ID_HEADER)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(REQUEST_ID_HEADER, requestID)
+
+		logger := slog.Default().With("request_id", requestID, "method", r.Method, "route", routeTemplate(r))
+		if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.IsValid() {
+			logger = logger.With("trace_id", spanContext.TraceID().String())
+		}
+		entry := &requestLog{}
+		ctx := services.ContextWithLogger(r.Context(), logger)
+		ctx = context.WithValue(ctx, requestLogContextKey{}, entry)
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		attrs := []any{
+			"status", recorder.status,
+			"duration_ms", time.Since(startTime).Milliseconds(),
+			"bytes", recorder.bytes,
+		}
+		if entry.userID != 0 {
+			attrs = append(attrs, "user_id", entry.userID)
+		}
+
+		level := slog.LevelInfo
+		if recorder.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.Log(ctx, level, "Request completed", attrs...)
+	})
+}
+
+// withRequestUser records the authenticated user for the request log and
+// adds it to the context logger
+func withRequestUser(r *http.Request, userID int) context.Context {
+	ctx := r.Context()
+	if entry, ok := ctx.Value(requestLogContextKey{}).(*requestLog); ok {
+		entry.userID = userID
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("enduser.id", userID))
+	return services.ContextWithLogger(ctx, services.LoggerFromContext(ctx).With("user_id", userID))
+}
+
+// routeTemplate returns the matched route pattern, such as /notes/{id}, so
+// requests to the same endpoint group together
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return r.URL.Path
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder captures the status code and body size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Flush keeps streaming responses working through the recorder
+func (rec *statusRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets WebSocket connections take over through the recorder
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	rec.status = http.StatusSwitchingProtocols
+	rec.wroteHeader = true
+	return hijacker.Hijack()
+}
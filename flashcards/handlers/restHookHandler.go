@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+// RestHookHandler serves the REST hook endpoints automation platforms such
+// as Zapier and Make call to subscribe to triggers
+type RestHookHandler struct {
+	service *services.WebhookService
+}
+
+func NewRestHookHandler(service *services.WebhookService) *RestHookHandler {
+	return &RestHookHandler{service: service}
+}
+
+func (h *RestHookHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/hooks", h.Subscribe).Methods("POST")
+	router.HandleFunc("/hooks/{id:[0-9]+}", h.Unsubscribe).Methods("DELETE")
+	router.HandleFunc("/hooks/samples/{event}", h.GetSamples).Methods("GET")
+}
+
+// Subscribe is called when a zap using a trigger is turned on
+func (h *RestHookHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	var req models.SubscribeRestHookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	hook, err := h.service.SubscribeRestHook(r.Context(), userIDFromRequest(r), &req)
+	if err != nil {
+		writeError(w, err, "Failed to subscribe REST hook")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, hook)
+}
+
+// Unsubscribe is called when the zap is turned off
+func (h *RestHookHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid REST hook ID")
+		return
+	}
+
+	if err := h.service.UnsubscribeRestHook(r.Context(), userIDFromRequest(r), id); err != nil {
+		writeError(w, err, "Failed to unsubscribe REST hook")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSamples returns recent payloads of a trigger, which the platform shows
+// while a zap is built
+func (h *RestHookHandler) GetSamples(w http.ResponseWriter, r *http.Request) {
+	samples, err := h.service.RestHookSamples(r.Context(), userIDFromRequest(r), mux.Vars(r)["event"])
+	if err != nil {
+		writeError(w, err, "Failed to load REST hook samples")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, samples)
+}
+
+func (h *RestHookHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *RestHookHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
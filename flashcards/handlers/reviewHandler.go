@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type ReviewHandler struct {
+	service     *services.ReviewService
+	authService *services.AuthService
+}
+
+func NewReviewHandler(service *services.ReviewService, authService *services.AuthService) *ReviewHandler {
+	return &ReviewHandler{service: service, authService: authService}
+}
+
+// RegisterPublicRoutes registers the review link endpoint, which needs no
+// token since the link itself signs the user in
+func (h *ReviewHandler) RegisterPublicRoutes(router *mux.Router) {
+	router.HandleFunc("/review-links/redeem", h.RedeemReviewLink).Methods("POST")
+}
+
+func (h *ReviewHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/reviews/due", h.GetDueQueue).Methods("GET")
+	router.HandleFunc("/reviews/cram", h.GetCramQueue).Methods("GET")
+	router.HandleFunc("/reviews", h.SubmitReview).Methods("POST")
+	router.HandleFunc("/reviews/undo", h.UndoLastReview).Methods("POST")
+	router.HandleFunc("/reviews/sync", h.SyncReviews).Methods("POST")
+	router.HandleFunc("/reviews/leeches", h.ListLeeches).Methods("GET")
+	router.HandleFunc("/reviews/leeches/{id:[0-9]+}/unsuspend", h.UnsuspendCard).Methods("POST")
+	router.HandleFunc("/reviews/leeches/{id:[0-9]+}/reformulate", h.ReformulateLeech).Methods("POST")
+}
+
+// GetDueQueue lists the cards due now. Supports deckId (including its
+// subdecks), limit, and order to override the deck's review order. With
+// prefetch=N it returns the next N cards rendered for display, with the
+// audio and image URLs to preload.
+func (h *ReviewHandler) GetDueQueue(w http.ResponseWriter, r *http.Request) {
+	deckID, limit, err := parseQueueParams(r)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	order := r.URL.Query().Get("order")
+	var queue *models.DueQueue
+	if value := r.URL.Query().Get("prefetch"); value != "" {
+		prefetch, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			h.writeErrorResponse(w, http.StatusBadRequest, "prefetch must be an integer")
+			return
+		}
+		if limit != 0 {
+			h.writeErrorResponse(w, http.StatusBadRequest, "limit cannot be combined with prefetch")
+			return
+		}
+		queue, err = h.service.PrefetchDueCards(r.Context(), userIDFromRequest(r), deckID, order, prefetch)
+	} else {
+		queue, err = h.service.GetDueQueue(r.Context(), userIDFromRequest(r), deckID, order, limit)
+	}
+	if err != nil {
+		writeError(w, err, "Failed to retrieve due cards")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, queue)
+}
+
+// GetCramQueue lists cards to drill regardless of due date, with the same
+// parameters as GetDueQueue. Submit answers with mode "cram" to leave their
+// schedules unchanged.
+func (h *ReviewHandler) GetCramQueue(w http.ResponseWriter, r *http.Request) {
+	deckID, limit, err := parseQueueParams(r)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	queue, err := h.service.GetCramQueue(r.Context(), userIDFromRequest(r), deckID, r.URL.Query().Get("order"), limit)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve due cards")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, queue)
+}
+
+func parseQueueParams(r *http.Request) (*int, int, error) {
+	deckID, err := parseDeckIDFilter(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil {
+			return nil, 0, fmt.Errorf("limit must be an integer")
+		}
+	}
+
+	return deckID, limit, nil
+}
+
+// RedeemReviewLink exchanges the token from a digest email's review link
+// for a short-lived session and the queue of cards the link was sent for
+func (h *ReviewHandler) RedeemReviewLink(w http.ResponseWriter, r *http.Request) {
+	var req models.RedeemReviewLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	auth, flashcardIDs, err := h.authService.RedeemReviewLink(r.Context(), req.Token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidToken) {
+			h.writeErrorResponse(w, http.StatusUnauthorized, err.Error())
+		} else {
+			writeError(w, err, "Failed to redeem review link")
+		}
+		return
+	}
+
+	queue, err := h.service.GetCardQueue(withRequestUser(r, auth.User.ID), auth.User.ID, flashcardIDs)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve due cards")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, &models.ReviewLinkSession{Auth: auth, Queue: queue})
+}
+
+func (h *ReviewHandler) SubmitReview(w http.ResponseWriter, r *http.Request) {
+	var req models.SubmitReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	result, err := h.service.SubmitReview(r.Context(), userIDFromRequest(r), &req)
+	if err != nil {
+		writeError(w, err, "Failed to save review")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, result)
+}
+
+// SyncReviews applies a batch of reviews answered offline and reports the
+// outcome of each one
+func (h *ReviewHandler) SyncReviews(w http.ResponseWriter, r *http.Request) {
+	var req models.SyncReviewsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	response, err := h.service.SyncReviews(r.Context(), userIDFromRequest(r), &req)
+	if err != nil {
+		writeError(w, err, "Failed to sync reviews")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, response)
+}
+
+// UndoLastReview reverts the most recent review and returns it along with
+// the card's restored schedule
+func (h *ReviewHandler) UndoLastReview(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.UndoLastReview(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		writeError(w, err, "Failed to undo review")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, result)
+}
+
+// ListLeeches lists the cards marked as leeches, in deckId and its subdecks
+// when given
+func (h *ReviewHandler) ListLeeches(w http.ResponseWriter, r *http.Request) {
+	deckID, err := parseDeckIDFilter(r)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	leeches, err := h.service.ListLeeches(r.Context(), userIDFromRequest(r), deckID)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve leeches")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, leeches)
+}
+
+// UnsuspendCard returns a suspended leech to the review queues and
+// answers with its schedule
+func (h *ReviewHandler) UnsuspendCard(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid flashcard ID")
+		return
+	}
+
+	schedule, err := h.service.UnsuspendCard(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to unsuspend flashcard")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, schedule)
+}
+
+// ReformulateLeech rewrites a leech with the LLM and answers with the new
+// card and its reset schedule
+func (h *ReviewHandler) ReformulateLeech(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid flashcard ID")
+		return
+	}
+
+	card, err := h.service.ReformulateLeech(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to reformulate flashcard")
+		return
+	}
+
+	setETag(w, card.Flashcard.Version)
+	h.writeJSONResponse(w, http.StatusOK, card)
+}
+
+func (h *ReviewHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *ReviewHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type RoutingHandler struct {
+	service *services.RoutingService
+}
+
+func NewRoutingHandler(service *services.RoutingService) *RoutingHandler {
+	return &RoutingHandler{service: service}
+}
+
+func (h *RoutingHandler) RegisterAdminRoutes(router *mux.Router) {
+	router.HandleFunc("/routing-rules", h.CreateRule).Methods("POST")
+	router.HandleFunc("/routing-rules", h.GetAllRules).Methods("GET")
+	router.HandleFunc("/routing-rules/{id:[0-9]+}", h.DeleteRule).Methods("DELETE")
+}
+
+func (h *RoutingHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateRoutingRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	rule, err := h.service.CreateRule(r.Context(), &req)
+	if err != nil {
+		writeError(w, err, "Failed to create routing rule")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, rule)
+}
+
+func (h *RoutingHandler) GetAllRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.service.GetAllRules(r.Context())
+	if err != nil {
+		writeError(w, err, "Failed to retrieve routing rules")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, rules)
+}
+
+func (h *RoutingHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid routing rule ID")
+		return
+	}
+
+	err = h.service.DeleteRule(r.Context(), id)
+	if err != nil {
+		writeError(w, err, "Failed to delete routing rule")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *RoutingHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *RoutingHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
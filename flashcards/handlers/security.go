@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"flashcards/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// Policy for responses that are not pages: nothing they contain may load or
+// run, and no site may frame them. Handlers serving HTML set their own.
+const DEFAULT_CONTENT_SECURITY_POLICY = "default-src 'none'; frame-ancestors 'none'"
+
+// SecurityHeaders sets the standard security headers on every response,
+// including Strict-Transport-Security when hstsMaxAge is positive. Like
+// CORS it wraps the whole router, so errors for unknown routes get them too.
+func SecurityHeaders(hstsMaxAge time.Duration) mux.MiddlewareFunc {
+	hsts := ""
+	if hstsMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(hstsMaxAge.Seconds())) + "; includeSubDomains"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Content-Type-Options", "nosniff")
+			w.Header().Set("X-Frame-Options", "DENY")
+			w.Header().Set("Referrer-Policy", "no-referrer")
+			w.Header().Set("Content-Security-Policy", DEFAULT_CONTENT_SECURITY_POLICY)
+			if hsts != "" {
+				w.Header().Set("Strict-Transport-Security", hsts)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// uploadRoutes are the routes that take file uploads. Each caps its own
+// body at a limit sized to the files it takes, so LimitRequestBody leaves
+// them alone.
+var uploadRoutes = map[string]bool{
+	"/decks/import":  true,
+	"/notes/import":  true,
+	"/imports/notes": true,
+	"/quiz/sessions/{id:[0-9]+}/questions/{position:[0-9]+}/image-answer": true,
+	"/quiz/sessions/{id:[0-9]+}/questions/{position:[0-9]+}/audio-answer": true,
+	storage.LOCAL_URL_PATH + "{key:.+}":                                   true,
+}
+
+// LimitRequestBody rejects request bodies over limit bytes with 413, and
+// caps the body of requests that don't declare their length. Upload routes
+// are matched by their path, never by what the request says it contains,
+// so a multipart body sent anywhere else gets the same limit as JSON.
+func LimitRequestBody(limit int64) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limit <= 0 || uploadRoutes[routeTemplate(r)] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.ContentLength > limit {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				w.Write([]byte(`{"error": "Request body too large"}`))
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
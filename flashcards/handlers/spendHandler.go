@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type SpendHandler struct {
+	service *services.SpendService
+}
+
+func NewSpendHandler(service *services.SpendService) *SpendHandler {
+	return &SpendHandler{service: service}
+}
+
+func (h *SpendHandler) RegisterAdminRoutes(router *mux.Router) {
+	router.HandleFunc("/spend-anomalies", h.GetAnomalies).Methods("GET")
+	router.HandleFunc("/spend-anomalies/{id:[0-9]+}/throttle", h.LiftThrottle).Methods("DELETE")
+}
+
+// GetAnomalies lists the most recent LLM spend spikes. Supports limit.
+func (h *SpendHandler) GetAnomalies(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			h.writeErrorResponse(w, http.StatusBadRequest, "limit must be an integer")
+			return
+		}
+		limit = parsed
+	}
+
+	anomalies, err := h.service.GetAnomalies(r.Context(), limit)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve spend anomalies")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, anomalies)
+}
+
+// LiftThrottle ends the throttle set when the anomaly was detected
+func (h *SpendHandler) LiftThrottle(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid spend anomaly ID")
+		return
+	}
+
+	anomaly, err := h.service.LiftThrottle(r.Context(), id)
+	if err != nil {
+		writeError(w, err, "Failed to lift spend throttle")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, anomaly)
+}
+
+func (h *SpendHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *SpendHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
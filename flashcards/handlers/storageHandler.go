@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"flashcards/services"
+	"flashcards/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// StorageHandler serves the pre-signed URLs of a local object store, which
+// point at the API server instead of a storage service. The signature in
+// the URL is the only authorization.
+type StorageHandler struct {
+	store *storage.LocalStore
+}
+
+func NewStorageHandler(store *storage.LocalStore) *StorageHandler {
+	return &StorageHandler{store: store}
+}
+
+func (h *StorageHandler) RegisterPublicRoutes(router *mux.Router) {
+	router.HandleFunc(storage.LOCAL_URL_PATH+"{key:.+}", h.PutObject).Methods("PUT")
+	router.HandleFunc(storage.LOCAL_URL_PATH+"{key:.+}", h.GetObject).Methods("GET")
+}
+
+// PutObject stores an upload of exactly the size and content type that
+// were signed
+func (h *StorageHandler) PutObject(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+	query := r.URL.Query()
+	if err := h.store.Verify("PUT", key, query); err != nil {
+		h.writeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	size, err := strconv.ParseInt(query.Get("size"), 10, 64)
+	if err != nil || r.ContentLength != size {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Content-Length must match the size the URL was signed for")
+		return
+	}
+	if r.Header.Get("Content-Type") != query.Get("type") {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Content-Type must match the type the URL was signed for")
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, size)
+	if err := h.store.Put(r.Context(), key, body, size, query.Get("type")); err != nil {
+		services.LoggerFromContext(r.Context()).Error("Failed to store upload", "key", key, "error", err)
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to store upload")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetObject downloads an object under the filename that was signed. Its
+// content type is taken from the filename or, failing that, sniffed.
+func (h *StorageHandler) GetObject(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+	query := r.URL.Query()
+	if err := h.store.Verify("GET", key, query); err != nil {
+		h.writeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	body, err := h.store.Open(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			h.writeErrorResponse(w, http.StatusNotFound, "Object not found")
+		} else {
+			services.LoggerFromContext(r.Context()).Error("Failed to read object", "key", key, "error", err)
+			h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to read object")
+		}
+		return
+	}
+	defer body.Close()
+
+	content, ok := body.(io.ReadSeeker)
+	if !ok {
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to read object")
+		return
+	}
+
+	filename := query.Get("filename")
+	if filename != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+	w.Header().Del("Content-Type")
+	http.ServeContent(w, r, filename, time.Time{}, content)
+}
+
+func (h *StorageHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type StudyAnalyticsHandler struct {
+	service *services.StudyAnalyticsService
+}
+
+func NewStudyAnalyticsHandler(service *services.StudyAnalyticsService) *StudyAnalyticsHandler {
+	return &StudyAnalyticsHandler{service: service}
+}
+
+func (h *StudyAnalyticsHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/analytics/study", h.GetStudyAnalytics).Methods("GET")
+}
+
+// GetStudyAnalytics summarizes the user's reviews per day, quiz accuracy by
+// difficulty, study streaks and most missed notes. Supports days, the range
+// ending today (default 30).
+func (h *StudyAnalyticsHandler) GetStudyAnalytics(w http.ResponseWriter, r *http.Request) {
+	days := 0
+	if value := r.URL.Query().Get("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			h.writeErrorResponse(w, http.StatusBadRequest, "days must be a number")
+			return
+		}
+		days = parsed
+	}
+
+	analytics, err := h.service.GetStudyAnalytics(r.Context(), userIDFromRequest(r), days)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve study analytics")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, analytics)
+}
+
+func (h *StudyAnalyticsHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *StudyAnalyticsHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
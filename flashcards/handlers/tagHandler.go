@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type TagHandler struct {
+	service *services.TagService
+}
+
+func NewTagHandler(service *services.TagService) *TagHandler {
+	return &TagHandler{service: service}
+}
+
+func (h *TagHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/tags", h.GetAllTags).Methods("GET")
+	router.HandleFunc("/notes/{id:[0-9]+}/tags", h.AddTagsToNote).Methods("POST")
+	router.HandleFunc("/notes/{id:[0-9]+}/tags/{tag}", h.RemoveTagFromNote).Methods("DELETE")
+}
+
+func (h *TagHandler) GetAllTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.service.GetAllTags(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		writeError(w, err, "Failed to retrieve tags")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, tags)
+}
+
+func (h *TagHandler) AddTagsToNote(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid note ID")
+		return
+	}
+
+	var req models.AddTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	note, err := h.service.AddTagsToNote(r.Context(), userIDFromRequest(r), id, &req)
+	if err != nil {
+		writeError(w, err, "Failed to add tags")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, note)
+}
+
+func (h *TagHandler) RemoveTagFromNote(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid note ID")
+		return
+	}
+
+	err = h.service.RemoveTagFromNote(r.Context(), userIDFromRequest(r), id, vars["tag"])
+	if err != nil {
+		writeError(w, err, "Failed to remove tag")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TagHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *TagHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
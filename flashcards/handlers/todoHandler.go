@@ -34,9 +34,9 @@ func (h *TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	todo, err := h.service.CreateTodo(&req)
+	todo, err := h.service.CreateTodo(r.Context(), userIDFromRequest(r), &req)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		writeError(w, err, "Failed to create todo")
 		return
 	}
 
@@ -44,9 +44,9 @@ func (h *TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *TodoHandler) GetAllTodos(w http.ResponseWriter, r *http.Request) {
-	todos, err := h.service.GetAllTodos()
+	todos, err := h.service.GetAllTodos(r.Context(), userIDFromRequest(r))
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve todos")
+		writeError(w, err, "Failed to retrieve todos")
 		return
 	}
 
@@ -61,13 +61,9 @@ func (h *TodoHandler) GetTodoByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	todo, err := h.service.GetTodoByID(id)
+	todo, err := h.service.GetTodoByID(r.Context(), userIDFromRequest(r), id)
 	if err != nil {
-		if containsNotFound(err.Error()) {
-			h.writeErrorResponse(w, http.StatusNotFound, err.Error())
-		} else {
-			h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve todo")
-		}
+		writeError(w, err, "Failed to retrieve todo")
 		return
 	}
 
@@ -88,13 +84,9 @@ func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	todo, err := h.service.UpdateTodo(id, &req)
+	todo, err := h.service.UpdateTodo(r.Context(), userIDFromRequest(r), id, &req)
 	if err != nil {
-		if containsNotFound(err.Error()) {
-			h.writeErrorResponse(w, http.StatusNotFound, err.Error())
-		} else {
-			h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
-		}
+		writeError(w, err, "Failed to update todo")
 		return
 	}
 
@@ -109,13 +101,9 @@ func (h *TodoHandler) DeleteTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.DeleteTodo(id)
+	err = h.service.DeleteTodo(r.Context(), userIDFromRequest(r), id)
 	if err != nil {
-		if containsNotFound(err.Error()) {
-			h.writeErrorResponse(w, http.StatusNotFound, err.Error())
-		} else {
-			h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete todo")
-		}
+		writeError(w, err, "Failed to delete todo")
 		return
 	}
 
@@ -133,8 +121,3 @@ func (h *TodoHandler) writeErrorResponse(w http.ResponseWriter, statusCode int,
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
-
-func containsNotFound(message string) bool {
-	return len(message) > 0 && (message[len(message)-9:] == "not found" ||
-		message[:len("todo with id")] == "todo with id")
-}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type UsageHandler struct {
+	service *services.UsageService
+}
+
+func NewUsageHandler(service *services.UsageService) *UsageHandler {
+	return &UsageHandler{service: service}
+}
+
+func (h *UsageHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/usage", h.GetUsage).Methods("GET")
+}
+
+func (h *UsageHandler) RegisterAdminRoutes(router *mux.Router) {
+	router.HandleFunc("/usage", h.GetUsageByUser).Methods("GET")
+}
+
+// GetUsage summarizes the user's LLM calls, tokens and estimated cost per
+// day and per model, with their monthly budget. Supports days, the range
+// ending today (default 30).
+func (h *UsageHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	days, err := queryInt(r, "days")
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	summary, err := h.service.GetSummary(r.Context(), userIDFromRequest(r), days)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve usage")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, summary)
+}
+
+// GetUsageByUser lists the users whose LLM calls cost the most. Supports
+// days and limit.
+func (h *UsageHandler) GetUsageByUser(w http.ResponseWriter, r *http.Request) {
+	days, err := queryInt(r, "days")
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := queryInt(r, "limit")
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	users, err := h.service.GetUsageByUser(r.Context(), days, limit)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve usage by user")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, users)
+}
+
+// queryInt reads an optional integer query parameter, 0 when absent
+func queryInt(r *http.Request, name string) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+	return parsed, nil
+}
+
+func (h *UsageHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *UsageHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type VocabularyHandler struct {
+	service *services.VocabularyService
+}
+
+func NewVocabularyHandler(service *services.VocabularyService) *VocabularyHandler {
+	return &VocabularyHandler{service: service}
+}
+
+func (h *VocabularyHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/flashcards/{id:[0-9]+}/vocabulary", h.GetVocabulary).Methods("GET")
+	router.HandleFunc("/flashcards/{id:[0-9]+}/vocabulary", h.UpdateVocabulary).Methods("PUT")
+	router.HandleFunc("/flashcards/{id:[0-9]+}/vocabulary/examples", h.GenerateExamples).Methods("POST")
+	router.HandleFunc("/flashcards/{id:[0-9]+}/vocabulary/audio", h.GenerateAudio).Methods("POST")
+	router.HandleFunc("/flashcards/{id:[0-9]+}/vocabulary/audio", h.GetAudio).Methods("GET")
+}
+
+func (h *VocabularyHandler) GetVocabulary(w http.ResponseWriter, r *http.Request) {
+	flashcardID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid flashcard ID")
+		return
+	}
+
+	vocabulary, err := h.service.GetVocabulary(r.Context(), userIDFromRequest(r), flashcardID)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve vocabulary")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, vocabulary)
+}
+
+func (h *VocabularyHandler) UpdateVocabulary(w http.ResponseWriter, r *http.Request) {
+	flashcardID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid flashcard ID")
+		return
+	}
+
+	var req models.UpdateVocabularyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	vocabulary, err := h.service.UpdateVocabulary(r.Context(), userIDFromRequest(r), flashcardID, &req)
+	if err != nil {
+		writeError(w, err, "Failed to save vocabulary")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, vocabulary)
+}
+
+// GenerateExamples writes new example sentences for the card at the CEFR
+// level given in the body, e.g. {"cefrLevel": "B1", "count": 3}
+func (h *VocabularyHandler) GenerateExamples(w http.ResponseWriter, r *http.Request) {
+	flashcardID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid flashcard ID")
+		return
+	}
+
+	var req models.GenerateExamplesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	vocabulary, err := h.service.GenerateExamples(r.Context(), userIDFromRequest(r), flashcardID, &req)
+	if err != nil {
+		writeError(w, err, "Failed to generate examples: "+err.Error())
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, vocabulary)
+}
+
+func (h *VocabularyHandler) GenerateAudio(w http.ResponseWriter, r *http.Request) {
+	flashcardID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid flashcard ID")
+		return
+	}
+
+	vocabulary, err := h.service.GenerateAudio(r.Context(), userIDFromRequest(r), flashcardID)
+	if err != nil {
+		writeError(w, err, "Failed to generate audio: "+err.Error())
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, vocabulary)
+}
+
+// GetAudio streams the stored pronunciation audio for the card
+func (h *VocabularyHandler) GetAudio(w http.ResponseWriter, r *http.Request) {
+	flashcardID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid flashcard ID")
+		return
+	}
+
+	audio, contentType, err := h.service.GetAudio(r.Context(), userIDFromRequest(r), flashcardID)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve audio")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(audio)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(audio)
+}
+
+func (h *VocabularyHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *VocabularyHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
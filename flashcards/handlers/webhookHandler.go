@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"flashcards/models"
+	"flashcards/services"
+
+	"github.com/gorilla/mux"
+)
+
+type WebhookHandler struct {
+	service *services.WebhookService
+}
+
+func NewWebhookHandler(service *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+func (h *WebhookHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/webhooks", h.GetWebhooks).Methods("GET")
+	router.HandleFunc("/webhooks", h.CreateWebhook).Methods("POST")
+	router.HandleFunc("/webhooks/{id:[0-9]+}", h.GetWebhook).Methods("GET")
+	router.HandleFunc("/webhooks/{id:[0-9]+}", h.UpdateWebhook).Methods("PUT")
+	router.HandleFunc("/webhooks/{id:[0-9]+}", h.DeleteWebhook).Methods("DELETE")
+	router.HandleFunc("/webhooks/{id:[0-9]+}/deliveries", h.ListDeliveries).Methods("GET")
+}
+
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.service.GetWebhooks(r.Context(), userIDFromRequest(r))
+	if err != nil {
+		writeError(w, err, "Failed to retrieve webhooks")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, webhooks)
+}
+
+// CreateWebhook registers a webhook and answers with its signing secret,
+// which is only shown this once
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	webhook, err := h.service.CreateWebhook(r.Context(), userIDFromRequest(r), &req)
+	if err != nil {
+		writeError(w, err, "Failed to create webhook")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, webhook)
+}
+
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	webhook, err := h.service.GetWebhook(r.Context(), userIDFromRequest(r), id)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve webhook")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, webhook)
+}
+
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	webhook, err := h.service.UpdateWebhook(r.Context(), userIDFromRequest(r), id, &req)
+	if err != nil {
+		writeError(w, err, "Failed to update webhook")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, webhook)
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	if err := h.service.DeleteWebhook(r.Context(), userIDFromRequest(r), id); err != nil {
+		writeError(w, err, "Failed to delete webhook")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries returns the webhook's delivery log with the usual list
+// parameters
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	params, err := parseListParams(r)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.service.ListDeliveries(r.Context(), userIDFromRequest(r), id, params)
+	if err != nil {
+		writeError(w, err, "Failed to retrieve webhook deliveries")
+		return
+	}
+
+	setNextPageLink(r, page)
+	h.writeJSONResponse(w, http.StatusOK, page)
+}
+
+func (h *WebhookHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *WebhookHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Kinds of activity feed items
+const (
+	ActivityFlashcardsCreated = "flashcards_created"
+	ActivityCardsReviewed     = "cards_reviewed"
+	ActivitySessionCompleted  = "session_completed"
+	ActivityImportFinished    = "import_finished"
+)
+
+// ActivityFeed lists what a user did over the last Days days, newest first
+type ActivityFeed struct {
+	Days  int            `json:"days"`
+	Since time.Time      `json:"since"`
+	Items []ActivityItem `json:"items"`
+}
+
+// ActivityItem is one entry of the activity feed. Flashcards created and
+// reviewed are grouped per deck and day, with OccurredAt the latest of the
+// group. Count is the cards created or reviewed, the questions of a quiz
+// session or the items an import processed. Correct is the session's
+// correct answers, or the reviewed cards not rated again. ResourceID is
+// the quiz session or import job, and Status how the import ended.
+type ActivityItem struct {
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Count      int       `json:"count"`
+	Correct    *int      `json:"correct,omitempty"`
+	DeckID     *int      `json:"deckId,omitempty"`
+	ResourceID *int      `json:"resourceId,omitempty"`
+	Status     string    `json:"status,omitempty"`
+}
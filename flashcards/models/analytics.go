@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// RequestStats aggregates requests to one route by one user within an hour.
+// UserID is 0 for unauthenticated requests.
+type RequestStats struct {
+	Bucket         time.Time `json:"bucket" db:"bucket"`
+	Route          string    `json:"route" db:"route"`
+	Method         string    `json:"method" db:"method"`
+	UserID         int       `json:"userId" db:"user_id"`
+	Requests       int       `json:"requests" db:"requests"`
+	ClientErrors   int       `json:"clientErrors" db:"clientErrors"`
+	ServerErrors   int       `json:"serverErrors" db:"serverErrors"`
+	TotalLatencyMs int64     `json:"totalLatencyMs" db:"totalLatencyMs"`
+	MaxLatencyMs   int64     `json:"maxLatencyMs" db:"maxLatencyMs"`
+}
+
+// RouteAnalytics summarises one route over the requested window
+type RouteAnalytics struct {
+	Route        string  `json:"route"`
+	Method       string  `json:"method"`
+	Requests     int     `json:"requests"`
+	ClientErrors int     `json:"clientErrors"`
+	ServerErrors int     `json:"serverErrors"`
+	ErrorRate    float64 `json:"errorRate"`
+	AvgLatencyMs float64 `json:"avgLatencyMs"`
+	MaxLatencyMs int64   `json:"maxLatencyMs"`
+	Users        int     `json:"users"`
+}
+
+// UserAnalytics summarises one user's requests over the requested window
+type UserAnalytics struct {
+	UserID       int     `json:"userId"`
+	Requests     int     `json:"requests"`
+	ClientErrors int     `json:"clientErrors"`
+	ServerErrors int     `json:"serverErrors"`
+	ErrorRate    float64 `json:"errorRate"`
+	AvgLatencyMs float64 `json:"avgLatencyMs"`
+	MaxLatencyMs int64   `json:"maxLatencyMs"`
+	Routes       int     `json:"routes"`
+}
+
+// AnalyticsQuery selects the window and order of an analytics report
+type AnalyticsQuery struct {
+	Since time.Time
+	Sort  string
+	Limit int
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// API key scopes. A read-only key can make GET requests; a generate-quiz
+// key can generate quizzes and start and take quiz sessions.
+const (
+	APIKeyScopeReadOnly     = "read-only"
+	APIKeyScopeGenerateQuiz = "generate-quiz"
+)
+
+// APIKey authenticates a programmatic client as its user, within its
+// scopes. Key is only set in the response creating it.
+type APIKey struct {
+	ID         int        `json:"id" db:"id"`
+	UserID     int        `json:"userId" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	Key        string     `json:"key,omitempty" db:"-"`
+	KeyHash    string     `json:"-" db:"keyHash"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	Requests   int64      `json:"requests" db:"requests"`
+	LastUsedAt *time.Time `json:"lastUsedAt" db:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt" db:"createdAt"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty" db:"revokedAt"`
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// APIKeyUsage is the number of requests made with a key on one day
+type APIKeyUsage struct {
+	APIKeyID int       `json:"-" db:"api_key_id"`
+	Day      time.Time `json:"day" db:"day"`
+	Requests int64     `json:"requests" db:"requests"`
+}
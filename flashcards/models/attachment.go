@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+const (
+	// A pending attachment has been handed an upload URL but its upload
+	// has not been completed
+	AttachmentStatusPending = "pending"
+	AttachmentStatusReady   = "ready"
+)
+
+// Attachment is an uploaded file owned by a user, such as the photo of a
+// handwritten answer. Its data is kept in Postgres, or in object storage
+// under StorageKey when that is configured, and shared with every other
+// attachment with the same ContentHash.
+type Attachment struct {
+	ID          int       `json:"id" db:"id"`
+	UserID      int       `json:"userId" db:"user_id"`
+	Filename    string    `json:"filename" db:"filename"`
+	ContentType string    `json:"contentType" db:"contentType"`
+	Size        int       `json:"size" db:"size"`
+	Status      string    `json:"status" db:"status"`
+	Data        []byte    `json:"-" db:"data"`
+	StorageKey  string    `json:"-" db:"storageKey"`
+	ContentHash string    `json:"-" db:"contentHash"`
+	CreatedAt   time.Time `json:"createdAt" db:"createdAt"`
+}
+
+// AttachmentBlob is the content of the attachments with one SHA-256 hash,
+// stored once. RefCount is the number of attachments referencing it.
+type AttachmentBlob struct {
+	Hash        string     `json:"hash" db:"hash"`
+	ContentType string     `json:"contentType" db:"contentType"`
+	Size        int        `json:"size" db:"size"`
+	StorageKey  string     `json:"-" db:"storageKey"`
+	RefCount    int        `json:"refCount" db:"refCount"`
+	ReleasedAt  *time.Time `json:"releasedAt,omitempty" db:"releasedAt"`
+	CreatedAt   time.Time  `json:"createdAt" db:"createdAt"`
+}
+
+// CreateAttachmentUploadRequest describes a file the client is about to
+// upload straight to object storage
+type CreateAttachmentUploadRequest struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Size        int    `json:"size"`
+}
+
+// AttachmentUpload tells the client where to upload the file: a PUT of its
+// bytes to UploadURL with Headers, before ExpiresAt. The upload is then
+// completed to make the attachment ready.
+type AttachmentUpload struct {
+	Attachment *Attachment       `json:"attachment"`
+	UploadURL  string            `json:"uploadUrl"`
+	Method     string            `json:"method"`
+	Headers    map[string]string `json:"headers"`
+	ExpiresAt  time.Time         `json:"expiresAt"`
+}
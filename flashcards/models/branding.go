@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	DefaultBrandingName         = "Flashcards"
+	DefaultBrandingPrimaryColor = "#2563eb"
+	DefaultBrandingAccentColor  = "#f59e0b"
+)
+
+// Branding is how the deployment presents itself, so clients can be themed
+// without being forked. Empty URLs and support email are unset.
+type Branding struct {
+	Name         string    `json:"name" db:"name"`
+	LogoURL      string    `json:"logoUrl" db:"logoUrl"`
+	PrimaryColor string    `json:"primaryColor" db:"primaryColor"`
+	AccentColor  string    `json:"accentColor" db:"accentColor"`
+	SupportEmail string    `json:"supportEmail" db:"supportEmail"`
+	SupportURL   string    `json:"supportUrl" db:"supportUrl"`
+	UpdatedAt    time.Time `json:"updatedAt" db:"updatedAt"`
+}
+
+type UpdateBrandingRequest struct {
+	Name         *string `json:"name,omitempty"`
+	LogoURL      *string `json:"logoUrl,omitempty"`
+	PrimaryColor *string `json:"primaryColor,omitempty"`
+	AccentColor  *string `json:"accentColor,omitempty"`
+	SupportEmail *string `json:"supportEmail,omitempty"`
+	SupportURL   *string `json:"supportUrl,omitempty"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Curator decisions on a similarity flag
+const (
+	FlagStatusPending   = "pending"
+	FlagStatusDismissed = "dismissed"
+	FlagStatusConfirmed = "confirmed"
+)
+
+// PublishedDeck is a deck listed in the public catalog
+type PublishedDeck struct {
+	DeckID      int       `json:"deckId" db:"deck_id"`
+	UserID      int       `json:"userId" db:"user_id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	CardCount   int       `json:"cardCount"`
+	PublishedAt time.Time `json:"publishedAt" db:"publishedAt"`
+}
+
+// CardEmbedding is the embedding of one card of a published deck
+type CardEmbedding struct {
+	FlashcardID int
+	DeckID      int
+	Model       string
+	Vector      []float64
+}
+
+// SimilarityFlag marks a published deck whose cards nearly duplicate
+// another published deck's. MatchedCards of its TotalCards had a near
+// duplicate in the matched deck.
+type SimilarityFlag struct {
+	ID            int        `json:"id" db:"id"`
+	DeckID        int        `json:"deckId" db:"deck_id"`
+	MatchedDeckID int        `json:"matchedDeckId" db:"matched_deck_id"`
+	MatchedCards  int        `json:"matchedCards" db:"matchedCards"`
+	TotalCards    int        `json:"totalCards" db:"totalCards"`
+	MaxSimilarity float64    `json:"maxSimilarity" db:"maxSimilarity"`
+	Status        string     `json:"status" db:"status"`
+	CreatedAt     time.Time  `json:"createdAt" db:"createdAt"`
+	ReviewedAt    *time.Time `json:"reviewedAt" db:"reviewedAt"`
+}
+
+// Confirming a flag removes the copy from the catalog
+type ReviewSimilarityFlagRequest struct {
+	Status string `json:"status"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+const (
+	AgeLevelChildren = "children"
+	AgeLevelTeen     = "teen"
+	AgeLevelAdult    = "adult"
+)
+
+type ContentFilterSettings struct {
+	Enabled      bool      `json:"enabled" db:"enabled"`
+	AgeLevel     string    `json:"ageLevel" db:"ageLevel"`
+	BannedTopics []string  `json:"bannedTopics" db:"bannedTopics"`
+	UpdatedAt    time.Time `json:"updatedAt" db:"updatedAt"`
+}
+
+type UpdateContentFilterRequest struct {
+	Enabled      *bool    `json:"enabled,omitempty"`
+	AgeLevel     *string  `json:"ageLevel,omitempty"`
+	BannedTopics []string `json:"bannedTopics,omitempty"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// DailyQuestionSubscription emails the user one question from a deck each
+// morning. LastSessionID is the one-question quiz session last sent.
+type DailyQuestionSubscription struct {
+	ID            int        `json:"id" db:"id"`
+	UserID        int        `json:"userId" db:"user_id"`
+	DeckID        int        `json:"deckId" db:"deck_id"`
+	LastSessionID *int       `json:"lastSessionId,omitempty" db:"last_session_id"`
+	LastSentAt    *time.Time `json:"lastSentAt,omitempty" db:"lastSentAt"`
+	CreatedAt     time.Time  `json:"createdAt" db:"createdAt"`
+
+	// Set when loading subscriptions for the scheduled job
+	Email string `json:"-"`
+}
+
+type CreateDailyQuestionSubscriptionRequest struct {
+	DeckID int `json:"deckId"`
+}
+
+// AnswerDailyQuestionRequest answers the question from a daily question
+// email with the token from one of its links
+type AnswerDailyQuestionRequest struct {
+	Token  string `json:"token"`
+	Answer string `json:"answer"`
+}
+
+// DailyQuestionReply is an email reply to a daily question, as forwarded by
+// the mail provider's inbound webhook. The token is found in the quoted
+// original.
+type DailyQuestionReply struct {
+	Text string `json:"text"`
+}
+
+// DailyQuestionResult is the graded answer to a daily question. Correct is
+// nil for questions that cannot be graded automatically.
+type DailyQuestionResult struct {
+	SessionID     int    `json:"sessionId"`
+	Question      string `json:"question"`
+	Answer        string `json:"answer"`
+	Correct       *bool  `json:"correct"`
+	CorrectAnswer string `json:"correctAnswer,omitempty"`
+	Explanation   string `json:"explanation,omitempty"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	DeadLetterStatusRetrying = "retrying"
+	DeadLetterStatusDead     = "dead"
+	DeadLetterStatusResolved = "resolved"
+)
+
+// Kinds of background work that can fail into the dead-letter queue
+const (
+	DeadLetterKindStripeWebhook   = "stripe-webhook"
+	DeadLetterKindSimilarityCheck = "similarity-check"
+	DeadLetterKindImportJob       = "import-job"
+)
+
+// DeadLetter is a piece of background work that failed. Key identifies
+// the work within its kind, such as a Stripe event or import job ID, and
+// Payload holds what a retry needs. NextAttemptAt is only set while the
+// entry is retrying.
+type DeadLetter struct {
+	ID            int             `json:"id" db:"id"`
+	Kind          string          `json:"kind" db:"kind"`
+	Key           string          `json:"key" db:"key"`
+	UserID        *int            `json:"userId,omitempty" db:"user_id"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	Error         string          `json:"error" db:"error"`
+	Attempts      int             `json:"attempts" db:"attempts"`
+	Status        string          `json:"status" db:"status"`
+	NextAttemptAt *time.Time      `json:"nextAttemptAt,omitempty" db:"nextAttemptAt"`
+	CreatedAt     time.Time       `json:"createdAt" db:"createdAt"`
+	UpdatedAt     time.Time       `json:"updatedAt" db:"updatedAt"`
+	ResolvedAt    *time.Time      `json:"resolvedAt,omitempty" db:"resolvedAt"`
+}
+
+type DeadLetterFilter struct {
+	Status string
+	Kind   string
+}
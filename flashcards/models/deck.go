@@ -0,0 +1,90 @@
+package models
+
+import "time"
+
+type Deck struct {
+	ID          int       `json:"id" db:"id"`
+	UserID      int       `json:"userId" db:"user_id"`
+	ParentID    *int      `json:"parentId" db:"parent_id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	ReviewOrder string    `json:"reviewOrder" db:"reviewOrder"`
+	CreatedAt   time.Time `json:"createdAt" db:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt" db:"updatedAt"`
+}
+
+type CreateDeckRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ParentID    *int   `json:"parentId,omitempty"`
+	ReviewOrder string `json:"reviewOrder,omitempty"`
+}
+
+// A ParentID of 0 moves the deck to the top level
+type UpdateDeckRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	ParentID    *int    `json:"parentId,omitempty"`
+	ReviewOrder *string `json:"reviewOrder,omitempty"`
+}
+
+// DeckTerminology is the course vocabulary injected into generation prompts
+// for notes in a deck
+type DeckTerminology struct {
+	DeckID         int             `json:"deckId" db:"deck_id"`
+	PreferredTerms []PreferredTerm `json:"preferredTerms" db:"preferredTerms"`
+	Abbreviations  []Abbreviation  `json:"abbreviations" db:"abbreviations"`
+	DoNotSimplify  []string        `json:"doNotSimplify" db:"doNotSimplify"`
+	StopWords      []string        `json:"stopWords" db:"stopWords"`
+	UpdatedAt      time.Time       `json:"updatedAt" db:"updatedAt"`
+}
+
+// PreferredTerm is a term to use instead of its alternatives
+type PreferredTerm struct {
+	Term  string   `json:"term"`
+	Avoid []string `json:"avoid,omitempty"`
+}
+
+type Abbreviation struct {
+	Short     string `json:"short"`
+	Expansion string `json:"expansion"`
+}
+
+type UpdateDeckTerminologyRequest struct {
+	PreferredTerms []PreferredTerm `json:"preferredTerms,omitempty"`
+	Abbreviations  []Abbreviation  `json:"abbreviations,omitempty"`
+	DoNotSimplify  []string        `json:"doNotSimplify,omitempty"`
+	StopWords      []string        `json:"stopWords,omitempty"`
+}
+
+// DeckStudySettings controls how a deck introduces new cards and the
+// learning steps, such as "1m" and "10m", cards climb before they graduate
+// to day intervals. Relearning steps apply after a lapse. A card becomes a
+// leech after LeechThreshold lapses, and LeechAction decides what happens
+// to it then; a threshold of 0 turns leech detection off.
+type DeckStudySettings struct {
+	DeckID                 int       `json:"deckId" db:"deck_id"`
+	LearningSteps          []string  `json:"learningSteps" db:"learningSteps"`
+	RelearningSteps        []string  `json:"relearningSteps" db:"relearningSteps"`
+	GraduatingIntervalDays int       `json:"graduatingIntervalDays" db:"graduatingIntervalDays"`
+	EasyIntervalDays       int       `json:"easyIntervalDays" db:"easyIntervalDays"`
+	NewCardsPerDay         int       `json:"newCardsPerDay" db:"newCardsPerDay"`
+	NewCardOrder           string    `json:"newCardOrder" db:"newCardOrder"`
+	NewCardPosition        string    `json:"newCardPosition" db:"newCardPosition"`
+	LeechThreshold         int       `json:"leechThreshold" db:"leechThreshold"`
+	LeechAction            string    `json:"leechAction" db:"leechAction"`
+	UpdatedAt              time.Time `json:"updatedAt" db:"updatedAt"`
+}
+
+// Omitted fields take their defaults; an empty steps list skips that ladder
+type UpdateDeckStudySettingsRequest struct {
+	LearningSteps          []string `json:"learningSteps,omitempty"`
+	RelearningSteps        []string `json:"relearningSteps,omitempty"`
+	GraduatingIntervalDays *int     `json:"graduatingIntervalDays,omitempty"`
+	EasyIntervalDays       *int     `json:"easyIntervalDays,omitempty"`
+	NewCardsPerDay         *int     `json:"newCardsPerDay,omitempty"`
+	NewCardOrder           string   `json:"newCardOrder,omitempty"`
+	NewCardPosition        string   `json:"newCardPosition,omitempty"`
+	LeechThreshold         *int     `json:"leechThreshold,omitempty"`
+	LeechAction            string   `json:"leechAction,omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// StoredDeckExport is a deck export written to object storage, downloaded
+// from a pre-signed URL until it expires
+type StoredDeckExport struct {
+	ID          int       `json:"id" db:"id"`
+	UserID      int       `json:"userId" db:"user_id"`
+	DeckID      *int      `json:"deckId" db:"deck_id"`
+	Format      string    `json:"format" db:"format"`
+	Filename    string    `json:"filename" db:"filename"`
+	ContentType string    `json:"contentType" db:"contentType"`
+	Size        int64     `json:"size" db:"size"`
+	StorageKey  string    `json:"-" db:"storageKey"`
+	ExpiresAt   time.Time `json:"expiresAt" db:"expiresAt"`
+	CreatedAt   time.Time `json:"createdAt" db:"createdAt"`
+	// Pre-signed and short-lived; fetch the export again for a fresh one
+	DownloadURL string `json:"downloadUrl" db:"-"`
+}
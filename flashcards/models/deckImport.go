@@ -0,0 +1,40 @@
+package models
+
+type ImportDeckRequest struct {
+	File ImportFile
+	// DeckID is the deck the imported decks are created under; they are
+	// created at the top level when it is nil
+	DeckID       *int
+	ValidateOnly bool
+}
+
+// DeckImportItem is one card of an imported file. Row is its line in a CSV
+// file or its position among the notes of an Anki package. ExistingID is
+// the flashcard it duplicates or conflicts with.
+type DeckImportItem struct {
+	Row         int    `json:"row"`
+	Deck        string `json:"deck,omitempty"`
+	Front       string `json:"front,omitempty"`
+	FlashcardID *int   `json:"flashcardId,omitempty"`
+	ExistingID  *int   `json:"existingId,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// DeckImportDeck is a deck that receives imported cards. New decks are
+// created by the import, so DeckID is only set for them once they exist.
+type DeckImportDeck struct {
+	Path   string `json:"path"`
+	DeckID *int   `json:"deckId,omitempty"`
+	New    bool   `json:"new"`
+}
+
+// DeckImportReport lists every card of the file as created, skipped or in
+// conflict with a card that has the same front but a different back
+type DeckImportReport struct {
+	ValidateOnly bool             `json:"validateOnly"`
+	Summary      ImportSummary    `json:"summary"`
+	Decks        []DeckImportDeck `json:"decks"`
+	Created      []DeckImportItem `json:"created"`
+	Skipped      []DeckImportItem `json:"skipped"`
+	Conflicts    []DeckImportItem `json:"conflicts"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Kinds of deck maintenance suggestions
+const (
+	SuggestionDuplicate = "duplicate"
+	SuggestionLeech     = "leech"
+	SuggestionStale     = "stale"
+	SuggestionUntagged  = "untagged"
+)
+
+// Statuses of a deck maintenance suggestion
+const (
+	SuggestionOpen      = "open"
+	SuggestionApplied   = "applied"
+	SuggestionDismissed = "dismissed"
+)
+
+// DeckSuggestion is a maintenance change the weekly analysis proposes for
+// a deck, and Action what applying it does:
+//   - duplicate: cards with the same content, ignoring case; applying keeps
+//     the oldest and deletes the rest
+//   - leech: a card forgotten again and again; applying resets its
+//     schedule so it is learned from scratch
+//   - stale: cards generated from a note edited since; applying generates
+//     as many new cards from the note and deletes these
+//   - untagged: cards whose notes have no tags; applying tags the notes
+//     with the deck's name
+type DeckSuggestion struct {
+	ID           int        `json:"id" db:"id"`
+	UserID       int        `json:"userId" db:"user_id"`
+	DeckID       int        `json:"deckId" db:"deck_id"`
+	Kind         string     `json:"kind" db:"kind"`
+	FlashcardIDs []int      `json:"flashcardIds" db:"flashcard_ids"`
+	NoteIDs      []int      `json:"noteIds" db:"note_ids"`
+	Action       string     `json:"action"`
+	Status       string     `json:"status" db:"status"`
+	CreatedAt    time.Time  `json:"createdAt" db:"createdAt"`
+	ResolvedAt   *time.Time `json:"resolvedAt,omitempty" db:"resolvedAt"`
+}
+
+// AppliedSuggestion is a suggestion once applied, with the cards applying
+// it created and deleted
+type AppliedSuggestion struct {
+	Suggestion DeckSuggestion `json:"suggestion"`
+	Created    []*Flashcard   `json:"created"`
+	DeletedIDs []int          `json:"deletedIds"`
+}
@@ -0,0 +1,23 @@
+package models
+
+// DigestCard is a card for a user's daily review digest: one failed in a
+// review during the day or added that day
+type DigestCard struct {
+	UserID    int        `json:"userId"`
+	Email     string     `json:"email"`
+	Flashcard *Flashcard `json:"flashcard"`
+	Failed    bool       `json:"failed"`
+}
+
+// RedeemReviewLinkRequest exchanges the token from a digest email's review
+// link for a session
+type RedeemReviewLinkRequest struct {
+	Token string `json:"token"`
+}
+
+// ReviewLinkSession signs the user in for a review of the cards a review
+// link was sent for
+type ReviewLinkSession struct {
+	Auth  *AuthResponse `json:"auth"`
+	Queue *DueQueue     `json:"queue"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Kinds of content an embed can show
+const (
+	EmbedKindFlashcard = "flashcard"
+	EmbedKindDeck      = "deck"
+)
+
+// Embed publishes one flashcard or a preview of one deck, read-only, to
+// anyone with its token. Token is only returned when the embed is created.
+type Embed struct {
+	ID          int       `json:"id" db:"id"`
+	UserID      int       `json:"userId" db:"user_id"`
+	Kind        string    `json:"kind"`
+	FlashcardID *int      `json:"flashcardId" db:"flashcard_id"`
+	DeckID      *int      `json:"deckId" db:"deck_id"`
+	Token       string    `json:"token,omitempty"`
+	CreatedAt   time.Time `json:"createdAt" db:"createdAt"`
+}
+
+// Exactly one of FlashcardID and DeckID must be set
+type CreateEmbedRequest struct {
+	FlashcardID *int `json:"flashcardId,omitempty"`
+	DeckID      *int `json:"deckId,omitempty"`
+}
+
+// EmbedContent is what viewers of an embed see: a single card, or a deck's
+// name and description with its first few cards
+type EmbedContent struct {
+	Kind        string          `json:"kind"`
+	DeckName    string          `json:"deckName,omitempty"`
+	Description string          `json:"description,omitempty"`
+	CardCount   int             `json:"cardCount"`
+	Cards       []FlashcardHTML `json:"cards"`
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of domain errors. Repositories and services return them wrapped in
+// an Error so callers can test the kind with errors.Is and handlers can map
+// it to a status without reading the message.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrValidation    = errors.New("invalid request")
+	ErrConflict      = errors.New("conflict")
+	ErrForbidden     = errors.New("forbidden")
+	ErrGone          = errors.New("gone")
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	ErrUnavailable   = errors.New("unavailable")
+)
+
+// Kinds of failures that are not the request's fault: storage and the
+// server's own code, or a service the request depends on, such as Stripe
+// or the mail server. Their messages are for the logs, not the user.
+var (
+	ErrInternal = errors.New("internal error")
+	ErrUpstream = errors.New("upstream error")
+)
+
+// Error is a domain error of one kind. Its message is meant for the user.
+// Err is the failure it wraps, if any.
+type Error struct {
+	Kind    error
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// NotFound reports that the requested record does not exist, or is not the
+// user's
+func NotFound(format string, args ...any) error {
+	return &Error{Kind: ErrNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+// Invalid reports a request that cannot be acted on as given
+func Invalid(format string, args ...any) error {
+	return &Error{Kind: ErrValidation, Message: fmt.Sprintf(format, args...)}
+}
+
+// Conflict reports a request that clashes with the current state, such as a
+// duplicate or a change that was already made
+func Conflict(format string, args ...any) error {
+	return &Error{Kind: ErrConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// Forbidden reports a request the user is not allowed to make
+func Forbidden(format string, args ...any) error {
+	return &Error{Kind: ErrForbidden, Message: fmt.Sprintf(format, args...)}
+}
+
+// Gone reports a record that existed but can no longer be used, such as an
+// expired invite
+func Gone(format string, args ...any) error {
+	return &Error{Kind: ErrGone, Message: fmt.Sprintf(format, args...)}
+}
+
+// QuotaExceeded reports a request refused by a plan limit or budget
+func QuotaExceeded(format string, args ...any) error {
+	return &Error{Kind: ErrQuotaExceeded, Message: fmt.Sprintf(format, args...)}
+}
+
+// Unavailable reports a feature this server is not configured for
+func Unavailable(format string, args ...any) error {
+	return &Error{Kind: ErrUnavailable, Message: fmt.Sprintf(format, args...)}
+}
+
+// Internal reports a failure of storage or of the server itself. Like
+// fmt.Errorf, format may wrap the cause with %w.
+func Internal(format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	return &Error{Kind: ErrInternal, Message: err.Error(), Err: err}
+}
+
+// Upstream reports a failure of a service the request depends on. Like
+// fmt.Errorf, format may wrap the cause with %w.
+func Upstream(format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	return &Error{Kind: ErrUpstream, Message: err.Error(), Err: err}
+}
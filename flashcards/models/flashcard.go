@@ -0,0 +1,83 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Flashcard is a card with a front to prompt recall and a back with the
+// answer. Mnemonic holds memory aids generated for the card, one per line.
+// Content holds both sides in one string, "Q: <front>\nA: <back>"
+// or the front alone for a card without a back, for clients that predate
+// the separate sides; it is kept in step with them. Version goes up by one
+// with every update and is sent as the ETag, which an update must name in
+// If-Match.
+type Flashcard struct {
+	ID           int       `json:"id" db:"id"`
+	UserID       int       `json:"userId" db:"user_id"`
+	DeckID       *int      `json:"deckId" db:"deck_id"`
+	Front        string    `json:"front" db:"front"`
+	Back         string    `json:"back" db:"back"`
+	Hint         string    `json:"hint,omitempty" db:"hint"`
+	Mnemonic     string    `json:"mnemonic,omitempty" db:"mnemonic"`
+	SourceNoteID *int      `json:"sourceNoteId,omitempty" db:"source_note_id"`
+	Content      string    `json:"content" db:"content"`
+	Version      int       `json:"version" db:"version"`
+	CreatedAt    time.Time `json:"createdAt" db:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt" db:"updatedAt"`
+}
+
+// CreateFlashcardRequest takes the card's sides, or for older clients its
+// Content, which is split into sides the same way stored content is
+type CreateFlashcardRequest struct {
+	Front        string `json:"front,omitempty"`
+	Back         string `json:"back,omitempty"`
+	Hint         string `json:"hint,omitempty"`
+	SourceNoteID *int   `json:"sourceNoteId,omitempty"`
+	Content      string `json:"content,omitempty"`
+	DeckID       *int   `json:"deckId,omitempty"`
+}
+
+// An empty Back or Hint clears it, and a DeckID or SourceNoteID of 0
+// removes the link. Content replaces both sides and cannot be combined
+// with Front or Back.
+type UpdateFlashcardRequest struct {
+	Front        *string `json:"front,omitempty"`
+	Back         *string `json:"back,omitempty"`
+	Hint         *string `json:"hint,omitempty"`
+	SourceNoteID *int    `json:"sourceNoteId,omitempty"`
+	Content      *string `json:"content,omitempty"`
+	DeckID       *int    `json:"deckId,omitempty"`
+}
+
+type GenerateFlashcardsRequest struct {
+	Count int `json:"count,omitempty"`
+}
+
+// FlashcardHTML is a flashcard's sides rendered from Markdown to sanitized
+// HTML. Back is empty for cards without a separate answer.
+type FlashcardHTML struct {
+	FlashcardID int    `json:"flashcardId"`
+	Front       string `json:"front"`
+	Back        string `json:"back,omitempty"`
+}
+
+// FlashcardContent joins a card's sides into its Content
+func FlashcardContent(front, back string) string {
+	if back == "" {
+		return front
+	}
+	return "Q: " + front + "\nA: " + back
+}
+
+// SplitFlashcardContent splits Content in the "Q: ...\nA: ..." form at the
+// answer; anything else is all front
+func SplitFlashcardContent(content string) (string, string) {
+	front, back := content, ""
+	if trimmed := strings.TrimPrefix(content, "Q:"); trimmed != content {
+		if index := strings.Index(trimmed, "\nA:"); index >= 0 {
+			front, back = trimmed[:index], trimmed[index+len("\nA:"):]
+		}
+	}
+	return strings.TrimSpace(front), strings.TrimSpace(back)
+}
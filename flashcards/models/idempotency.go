@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// IdempotencyRecord is the stored outcome of a request sent with an
+// Idempotency-Key. StatusCode is nil while the request is in progress.
+type IdempotencyRecord struct {
+	UserID       int       `json:"userId" db:"user_id"`
+	Key          string    `json:"key" db:"idempotencyKey"`
+	RequestHash  string    `json:"requestHash" db:"requestHash"`
+	StatusCode   *int      `json:"statusCode" db:"statusCode"`
+	ResponseBody []byte    `json:"-" db:"responseBody"`
+	CreatedAt    time.Time `json:"createdAt" db:"createdAt"`
+	ExpiresAt    time.Time `json:"expiresAt" db:"expiresAt"`
+}
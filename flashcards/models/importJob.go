@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Import job statuses. A failed job can be resumed.
+const (
+	ImportJobStatusRunning   = "running"
+	ImportJobStatusFailed    = "failed"
+	ImportJobStatusCompleted = "completed"
+)
+
+// What an import job creates
+const (
+	ImportKindNotes = "notes"
+)
+
+// ImportJob is a large import committed in chunks in the background.
+// ProcessedItems of TotalItems have been created so far.
+type ImportJob struct {
+	ID              int              `json:"id" db:"id"`
+	UserID          int              `json:"userId" db:"user_id"`
+	Kind            string           `json:"kind" db:"kind"`
+	Status          string           `json:"status" db:"status"`
+	DeckID          *int             `json:"deckId" db:"deck_id"`
+	ChunkSize       int              `json:"chunkSize" db:"chunkSize"`
+	TotalItems      int              `json:"totalItems" db:"totalItems"`
+	ProcessedItems  int              `json:"processedItems" db:"processedItems"`
+	TotalChunks     int              `json:"totalChunks"`
+	CommittedChunks int              `json:"committedChunks" db:"committedChunks"`
+	Skipped         []NoteImportItem `json:"skipped" db:"skipped"`
+	Error           *string          `json:"error" db:"error"`
+	CreatedAt       time.Time        `json:"createdAt" db:"createdAt"`
+	UpdatedAt       time.Time        `json:"updatedAt" db:"updatedAt"`
+	CompletedAt     *time.Time       `json:"completedAt" db:"completedAt"`
+}
+
+// ImportJobItem is one record an import job creates, in the chunk that
+// commits it
+type ImportJobItem struct {
+	Position int
+	Chunk    int
+	File     string
+	Heading  string
+	Content  string
+}
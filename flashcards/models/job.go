@@ -0,0 +1,60 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+)
+
+// Kinds of background job
+const (
+	JobKindGenerateFlashcards = "generate-flashcards"
+	JobKindSummarizeNotes     = "summarize-notes"
+	JobKindReembedCatalog     = "reembed-catalog"
+)
+
+// Job is expensive work queued to run in the background. Result is set
+// once it completes and Error while it is retrying or after it failed.
+type Job struct {
+	ID          int             `json:"id" db:"id"`
+	UserID      int             `json:"userId" db:"user_id"`
+	Kind        string          `json:"kind" db:"kind"`
+	Payload     json.RawMessage `json:"payload" db:"payload"`
+	Status      string          `json:"status" db:"status"`
+	Result      json.RawMessage `json:"result,omitempty" db:"result"`
+	Error       string          `json:"error,omitempty" db:"error"`
+	Attempts    int             `json:"attempts" db:"attempts"`
+	CreatedAt   time.Time       `json:"createdAt" db:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt" db:"updatedAt"`
+	StartedAt   *time.Time      `json:"startedAt,omitempty" db:"startedAt"`
+	CompletedAt *time.Time      `json:"completedAt,omitempty" db:"completedAt"`
+}
+
+type CreateJobRequest struct {
+	Kind    string          `json:"kind"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type JobFilter struct {
+	Status string
+	Kind   string
+}
+
+// GenerateFlashcardsJobPayload generates Count flashcards from each of the
+// user's notes
+type GenerateFlashcardsJobPayload struct {
+	Count        int  `json:"count,omitempty"`
+	SkipExisting bool `json:"skipExisting,omitempty"`
+}
+
+// SummarizeNotesJobPayload summarizes the user's notes again, or only
+// those without a summary when OnlyMissing is set
+type SummarizeNotesJobPayload struct {
+	OnlyMissing bool `json:"onlyMissing,omitempty"`
+}
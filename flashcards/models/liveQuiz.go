@@ -0,0 +1,53 @@
+package models
+
+// Types of the JSON messages exchanged over a live quiz WebSocket
+const (
+	// Sent by the client
+	LiveQuizMessageStart  = "start"
+	LiveQuizMessageResume = "resume"
+	LiveQuizMessageAnswer = "answer"
+	LiveQuizMessageSkip   = "skip"
+
+	// Sent by the server
+	LiveQuizMessageSession  = "session"
+	LiveQuizMessageQuestion = "question"
+	LiveQuizMessageResult   = "result"
+	LiveQuizMessageComplete = "complete"
+	LiveQuizMessageError    = "error"
+
+	// Heartbeats, sent by either side and answered with a pong
+	LiveQuizMessagePing = "ping"
+	LiveQuizMessagePong = "pong"
+)
+
+// LiveQuizMessage is one message of a live quiz. Type says which of the
+// other fields are set.
+type LiveQuizMessage struct {
+	Type      string `json:"type"`
+	SessionID int    `json:"sessionId,omitempty"`
+
+	// start: the bearer token the connection signs in with
+	Token string `json:"token,omitempty"`
+	// resume, and sent with session and every question: a token that lets a
+	// dropped connection rejoin the session without signing in again
+	ResumeToken string `json:"resumeToken,omitempty"`
+
+	// answer and skip: the position of the question, which must be the one
+	// served last; result: the position graded
+	Position    *int   `json:"position,omitempty"`
+	Answer      string `json:"answer,omitempty"`
+	TimeSpentMs int    `json:"timeSpentMs,omitempty"`
+
+	// question: the question to answer, without its answer key, and how
+	// many questions are left including it
+	Question  *SessionQuestion `json:"question,omitempty"`
+	Remaining int              `json:"remaining,omitempty"`
+
+	// result: nil Correct means the answer cannot be graded automatically
+	Correct       *bool  `json:"correct,omitempty"`
+	CorrectAnswer string `json:"correctAnswer,omitempty"`
+	Explanation   string `json:"explanation,omitempty"`
+
+	Report *SessionReport `json:"report,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
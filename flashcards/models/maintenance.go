@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	MaintenanceModeOff      = "off"
+	MaintenanceModeReadOnly = "read-only"
+	MaintenanceModeFull     = "full"
+)
+
+// Maintenance is whether the API is in maintenance, with what clients show
+// in a banner meanwhile. EndsAt is when it is expected to end, if known.
+type Maintenance struct {
+	Mode      string     `json:"mode" db:"mode"`
+	Message   string     `json:"message,omitempty" db:"message"`
+	StartedAt *time.Time `json:"startedAt,omitempty" db:"startedAt"`
+	EndsAt    *time.Time `json:"endsAt,omitempty" db:"endsAt"`
+	UpdatedAt time.Time  `json:"updatedAt" db:"updatedAt"`
+}
+
+// Active reports whether any requests are refused
+func (m *Maintenance) Active() bool {
+	return m.Mode != MaintenanceModeOff
+}
+
+type UpdateMaintenanceRequest struct {
+	Mode    string     `json:"mode"`
+	Message string     `json:"message,omitempty"`
+	EndsAt  *time.Time `json:"endsAt,omitempty"`
+}
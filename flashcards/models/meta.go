@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// ServiceMeta tells clients what the server can currently do, so they can
+// hide features instead of failing on them
+type ServiceMeta struct {
+	Capabilities ServiceCapabilities `json:"capabilities"`
+	LLM          LLMStatus           `json:"llm"`
+	// Set while the API is in maintenance
+	Maintenance *Maintenance `json:"maintenance,omitempty"`
+}
+
+type ServiceCapabilities struct {
+	// Questions, flashcards and feedback can be generated on demand
+	Generation bool `json:"generation"`
+	// Quiz sessions can be started from previously generated questions
+	QuestionBank bool `json:"questionBank"`
+	// Session requests are queued while generation is unavailable
+	GenerationQueue bool `json:"generationQueue"`
+}
+
+// LLMStatus is the state of the LLM provider as seen by this server.
+// RetryAfterSeconds is when the next call is let through to check whether
+// the provider is back.
+type LLMStatus struct {
+	Available         bool       `json:"available"`
+	UnavailableSince  *time.Time `json:"unavailableSince,omitempty"`
+	RetryAfterSeconds int        `json:"retryAfterSeconds,omitempty"`
+}
@@ -2,17 +2,43 @@ package models
 
 import "time"
 
+// Note is a user's study note. Version goes up by one with every update
+// and is sent as the ETag, which an update must name in If-Match.
 type Note struct {
-	ID        int       `json:"id" db:"id"`
+	ID     int    `json:"id" db:"id"`
+	UserID int    `json:"userId" db:"user_id"`
+	DeckID *int   `json:"deckId" db:"deck_id"`
+	Title  string `json:"title" db:"title"`
+	// Summary is written by the LLM after the note is created or its
+	// content changes, and is empty until then
+	Summary string `json:"summary" db:"summary"`
+	// Source is where the note came from, such as a URL or a book chapter
+	Source string `json:"source" db:"source"`
+	// Language is the ISO 639-1 code of the note's language, detected
+	// from its content unless set, and empty until it is known
+	Language  string    `json:"language" db:"language"`
 	Content   string    `json:"content" db:"content"`
+	Tags      []string  `json:"tags"`
+	Version   int       `json:"version" db:"version"`
 	CreatedAt time.Time `json:"createdAt" db:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt" db:"updatedAt"`
 }
 
 type CreateNoteRequest struct {
-	Content string `json:"content"`
+	Title  string `json:"title,omitempty"`
+	Source string `json:"source,omitempty"`
+	// Language overrides detection, as an ISO 639-1 code
+	Language string `json:"language,omitempty"`
+	Content  string `json:"content"`
+	DeckID   *int   `json:"deckId,omitempty"`
 }
 
+// A DeckID of 0 removes the note from its deck
 type UpdateNoteRequest struct {
-	Content *string `json:"content,omitempty"`
+	Title  *string `json:"title,omitempty"`
+	Source *string `json:"source,omitempty"`
+	// An empty Language detects it again from the content
+	Language *string `json:"language,omitempty"`
+	Content  *string `json:"content,omitempty"`
+	DeckID   *int    `json:"deckId,omitempty"`
 }
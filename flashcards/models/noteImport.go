@@ -0,0 +1,48 @@
+package models
+
+// ImportFile is one uploaded Markdown file or zip archive
+type ImportFile struct {
+	Name string
+	Data []byte
+}
+
+type ImportNotesRequest struct {
+	Files  []ImportFile
+	DeckID *int
+	// Headings at this level or above start a new note; deeper headings
+	// stay inside the note they belong to
+	HeadingLevel int
+	// ValidateOnly parses and checks the whole upload and reports what
+	// would be imported without writing anything
+	ValidateOnly bool
+}
+
+// NoteImportItem is one section of an imported file, or a file that could
+// not be read. Line is where the section starts. NoteID is only set for
+// notes that were actually created.
+type NoteImportItem struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Heading string `json:"heading,omitempty"`
+	NoteID  *int   `json:"noteId,omitempty"`
+	Length  int    `json:"length,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// ImportSummary counts the outcome of every section or row of an import.
+// QuotaError is set when a validate-only import would exceed the quota.
+type ImportSummary struct {
+	Total      int    `json:"total"`
+	Importable int    `json:"importable"`
+	Duplicates int    `json:"duplicates"`
+	Conflicts  int    `json:"conflicts"`
+	Errors     int    `json:"errors"`
+	QuotaError string `json:"quotaError,omitempty"`
+}
+
+type NoteImportReport struct {
+	ValidateOnly bool             `json:"validateOnly"`
+	Summary      ImportSummary    `json:"summary"`
+	Created      []NoteImportItem `json:"created"`
+	Skipped      []NoteImportItem `json:"skipped"`
+}
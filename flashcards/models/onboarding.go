@@ -0,0 +1,25 @@
+package models
+
+// OnboardingProgress counts what a user has set up so far
+type OnboardingProgress struct {
+	Membership  *Membership
+	Decks       int
+	Notes       int
+	Flashcards  int
+	Reviews     int
+	InvitesSent int
+}
+
+// OnboardingStep is one item of the checklist shown to new users
+type OnboardingStep struct {
+	Key   string `json:"key"`
+	Title string `json:"title"`
+	Done  bool   `json:"done"`
+}
+
+type OnboardingChecklist struct {
+	Steps     []OnboardingStep `json:"steps"`
+	Completed int              `json:"completed"`
+	Total     int              `json:"total"`
+	Done      bool             `json:"done"`
+}
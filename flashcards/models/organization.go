@@ -0,0 +1,149 @@
+package models
+
+import "time"
+
+// Plan tiers an organization can be on
+const (
+	PlanFree       = "free"
+	PlanPro        = "pro"
+	PlanEnterprise = "enterprise"
+)
+
+// Limits caps what an organization can store and spend. Zero means
+// unlimited.
+type Limits struct {
+	MaxNotes         int `json:"maxNotes"`
+	MaxCards         int `json:"maxCards"`
+	MonthlyLLMTokens int `json:"monthlyLlmTokens"`
+}
+
+// Organization groups users under a plan. Each override, when set, replaces
+// the plan's limit for this organization. The Stripe fields are set once the
+// organization subscribes through billing; StripeEventAt is when the last
+// Stripe event applied to it was created.
+type Organization struct {
+	ID                       int        `json:"id" db:"id"`
+	Name                     string     `json:"name" db:"name"`
+	Plan                     string     `json:"plan" db:"plan"`
+	MaxNotesOverride         *int       `json:"maxNotesOverride" db:"maxNotes"`
+	MaxCardsOverride         *int       `json:"maxCardsOverride" db:"maxCards"`
+	MonthlyLLMTokensOverride *int       `json:"monthlyLlmTokensOverride" db:"monthlyLlmTokens"`
+	StripeCustomerID         *string    `json:"-" db:"stripeCustomerId"`
+	StripeSubscriptionID     *string    `json:"-" db:"stripeSubscriptionId"`
+	SubscriptionStatus       *string    `json:"subscriptionStatus,omitempty" db:"subscriptionStatus"`
+	StripeEventAt            *time.Time `json:"-" db:"stripeEventAt"`
+	CreatedAt                time.Time  `json:"createdAt" db:"createdAt"`
+	UpdatedAt                time.Time  `json:"updatedAt" db:"updatedAt"`
+}
+
+// Usage is what an organization, or a user outside one, has stored and
+// spent this month
+type Usage struct {
+	Notes     int `json:"notes"`
+	Cards     int `json:"cards"`
+	LLMTokens int `json:"llmTokens"`
+}
+
+// Quota reports the limits in effect next to current usage. OrganizationID
+// is nil for users outside an organization.
+type Quota struct {
+	OrganizationID     *int      `json:"organizationId"`
+	Plan               string    `json:"plan"`
+	SubscriptionStatus string    `json:"subscriptionStatus,omitempty"`
+	Limits             Limits    `json:"limits"`
+	Usage              Usage     `json:"usage"`
+	Month              time.Time `json:"month"`
+}
+
+type OrganizationDetails struct {
+	Organization *Organization `json:"organization"`
+	Quota        *Quota        `json:"quota"`
+}
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name"`
+	Plan string `json:"plan,omitempty"`
+}
+
+// UpdateQuotaRequest changes an organization's plan and limit overrides.
+// Omitted fields are unchanged; ResetOverrides clears all overrides first.
+type UpdateQuotaRequest struct {
+	Plan             *string `json:"plan,omitempty"`
+	MaxNotes         *int    `json:"maxNotes,omitempty"`
+	MaxCards         *int    `json:"maxCards,omitempty"`
+	MonthlyLLMTokens *int    `json:"monthlyLlmTokens,omitempty"`
+	ResetOverrides   bool    `json:"resetOverrides,omitempty"`
+}
+
+// Roles a user can hold in their organization. Owners and admins can invite
+// members.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// AddOrganizationMemberRequest adds a user with Role, member by default
+type AddOrganizationMemberRequest struct {
+	UserID int    `json:"userId"`
+	Role   string `json:"role,omitempty"`
+}
+
+// Membership is the organization a user belongs to and their role in it
+type Membership struct {
+	OrganizationID int    `json:"organizationId"`
+	Role           string `json:"role"`
+}
+
+// OrganizationInvite lets the holder of its token join an organization with
+// Role. Token is only returned when the invite is created.
+type OrganizationInvite struct {
+	ID             int        `json:"id" db:"id"`
+	OrganizationID int        `json:"organizationId" db:"organization_id"`
+	Email          string     `json:"email" db:"email"`
+	Role           string     `json:"role" db:"role"`
+	InvitedBy      *int       `json:"invitedBy" db:"invited_by"`
+	AcceptedBy     *int       `json:"acceptedBy,omitempty" db:"accepted_by"`
+	ExpiresAt      time.Time  `json:"expiresAt" db:"expiresAt"`
+	AcceptedAt     *time.Time `json:"acceptedAt,omitempty" db:"acceptedAt"`
+	CreatedAt      time.Time  `json:"createdAt" db:"createdAt"`
+	Token          string     `json:"token,omitempty"`
+	URL            string     `json:"url,omitempty"`
+	EmailSent      bool       `json:"emailSent"`
+}
+
+type CreateInviteRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role,omitempty"`
+}
+
+// CreateOwnedOrganizationRequest creates an organization on the free plan
+// with the caller as its owner
+type CreateOwnedOrganizationRequest struct {
+	Name string `json:"name"`
+}
+
+// MembershipDetails is returned when a user creates or joins an
+// organization
+type MembershipDetails struct {
+	Organization *Organization `json:"organization"`
+	Role         string        `json:"role"`
+}
+
+// Stripe subscription statuses the plan mapping acts on
+const (
+	SubscriptionActive   = "active"
+	SubscriptionTrialing = "trialing"
+	SubscriptionPastDue  = "past_due"
+	SubscriptionUnpaid   = "unpaid"
+)
+
+type CreateCheckoutRequest struct {
+	Plan string `json:"plan"`
+}
+
+// CheckoutSession is a Stripe-hosted checkout page the client redirects to
+type CheckoutSession struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
@@ -0,0 +1,30 @@
+package models
+
+// ListParams controls paging and ordering of list endpoints
+type ListParams struct {
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+	Sort   string `json:"sort"`
+	Order  string `json:"order"`
+}
+
+// Page wraps one page of a list response
+type Page[T any] struct {
+	Items  []T    `json:"items"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+	Next   string `json:"next,omitempty"`
+}
+
+type NoteFilter struct {
+	Tag      string
+	DeckID   *int
+	Query    string
+	Language string
+}
+
+type FlashcardFilter struct {
+	DeckID *int
+	Query  string
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type ProgressReport struct {
+	PeriodStart       time.Time       `json:"periodStart"`
+	PeriodEnd         time.Time       `json:"periodEnd"`
+	SessionsCompleted int             `json:"sessionsCompleted"`
+	QuestionsAnswered int             `json:"questionsAnswered"`
+	Accuracy          float64         `json:"accuracy"`
+	PreviousAccuracy  float64         `json:"previousAccuracy"`
+	AccuracyTrend     string          `json:"accuracyTrend"` // "up", "down" or "flat"
+	CurrentStreakDays int             `json:"currentStreakDays"`
+	DailyActivity     []DailyActivity `json:"dailyActivity"`
+}
+
+type DailyActivity struct {
+	Date     string `json:"date"`
+	Answered int    `json:"answered"`
+	Correct  int    `json:"correct"`
+}
+
+type ReportRecipient struct {
+	ID        int       `json:"id" db:"id"`
+	UserID    int       `json:"userId" db:"user_id"`
+	Email     string    `json:"email" db:"email"`
+	CreatedAt time.Time `json:"createdAt" db:"createdAt"`
+}
+
+type CreateReportRecipientRequest struct {
+	Email string `json:"email"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Where the prompt template in use was loaded from, from lowest to highest
+// precedence
+const (
+	PromptSourceBuiltIn  = "builtin"
+	PromptSourceFile     = "file"
+	PromptSourceDatabase = "database"
+)
+
+type PromptTemplate struct {
+	Name      string     `json:"name" db:"name"`
+	Version   int        `json:"version" db:"version"`
+	Source    string     `json:"source"`
+	Template  string     `json:"template" db:"template"`
+	CreatedAt *time.Time `json:"createdAt,omitempty" db:"createdAt"`
+}
+
+type UpdatePromptTemplateRequest struct {
+	Template string `json:"template"`
+}
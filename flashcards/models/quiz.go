@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type Message struct {
 	Role     string        `json:"role"` // "user" or "assistant"
 	Content  string        `json:"content"`
@@ -15,4 +17,61 @@ type QuestionData struct {
 	Explanation   string   `json:"explanation,omitempty"`
 	Difficulty    string   `json:"difficulty"`
 	BasedOnNotes  []int    `json:"basedOnNotes"`
+	ImageURL      string   `json:"imageUrl,omitempty"`
+	ImageAltText  string   `json:"imageAltText,omitempty"`
+
+	Accessibility *AccessibilityMetadata `json:"accessibility,omitempty"`
+}
+
+// AccessibilityMetadata helps clients serve questions to screen readers and
+// learners who need simpler wording
+type AccessibilityMetadata struct {
+	// OptionLabels are spoken-friendly versions of Options, index-aligned
+	OptionLabels []string `json:"optionLabels,omitempty"`
+	// PlainLanguageText is a simplified rewording of the question stem,
+	// generated on demand
+	PlainLanguageText string `json:"plainLanguageText,omitempty"`
+}
+
+type EssayFeedback struct {
+	QuestionID string `json:"questionId"`
+	Score      int    `json:"score"`
+	MaxScore   int    `json:"maxScore"`
+	Feedback   string `json:"feedback"`
+}
+
+// QuestionEmbedding is the embedding of a generated question's text, kept
+// to recognize the same question when it is generated again
+type QuestionEmbedding struct {
+	UserID       int
+	NoteIDs      []int
+	QuestionText string
+	Model        string
+	Vector       []float64
+	CreatedAt    time.Time
+}
+
+// How the difficulty of generated questions was chosen
+const (
+	DifficultySourceOption     = "option"
+	DifficultySourceMessage    = "message"
+	DifficultySourceCalibrated = "calibrated"
+	DifficultySourceDefault    = "default"
+)
+
+// GradedAnswer is a learner's graded answer to a session question
+type GradedAnswer struct {
+	Difficulty string
+	Correct    bool
+	AnsweredAt time.Time
+}
+
+// DifficultyCalibration is the difficulty chosen for generated questions and
+// why. Accuracy and Answers describe the recent answers a calibrated
+// difficulty was based on.
+type DifficultyCalibration struct {
+	Difficulty string   `json:"difficulty"`
+	Source     string   `json:"source"`
+	Accuracy   *float64 `json:"accuracy,omitempty"`
+	Answers    int      `json:"answers,omitempty"`
 }
@@ -0,0 +1,164 @@
+package models
+
+import "time"
+
+const (
+	SessionStatusInProgress = "in_progress"
+	SessionStatusCompleted  = "completed"
+
+	QuestionStatusUnanswered = "unanswered"
+	QuestionStatusAnswered   = "answered"
+	QuestionStatusSkipped    = "skipped"
+
+	// Where a generated session's questions came from. Quizzes repeated
+	// over unchanged notes may also come from the cache.
+	QuestionSourceGenerated = "generated"
+	QuestionSourceBank      = "question-bank"
+	QuestionSourceCache     = "cache"
+
+	QueuedSessionStatusQueued    = "queued"
+	QueuedSessionStatusCompleted = "completed"
+	QueuedSessionStatusFailed    = "failed"
+)
+
+type QuizSession struct {
+	ID              int               `json:"id" db:"id"`
+	UserID          int               `json:"userId" db:"user_id"`
+	Status          string            `json:"status" db:"status"`
+	CurrentPosition int               `json:"currentPosition" db:"currentPosition"`
+	Questions       []SessionQuestion `json:"questions"`
+	CreatedAt       time.Time         `json:"createdAt" db:"createdAt"`
+	UpdatedAt       time.Time         `json:"updatedAt" db:"updatedAt"`
+
+	// Set on a newly created session whose questions were generated
+	// rather than given, see QuestionSourceGenerated
+	QuestionSource string `json:"questionSource,omitempty"`
+}
+
+type SessionQuestion struct {
+	Position    int          `json:"position" db:"position"`
+	Question    QuestionData `json:"question" db:"question"`
+	Status      string       `json:"status" db:"status"`
+	Answer      string       `json:"answer" db:"answer"`
+	Flagged     bool         `json:"flagged" db:"flagged"`
+	TimeSpentMs int          `json:"timeSpentMs" db:"timeSpentMs"`
+	UpdatedAt   time.Time    `json:"updatedAt" db:"updatedAt"`
+
+	// Set when the answer was submitted as an image
+	AttachmentID *int           `json:"attachmentId,omitempty" db:"attachment_id"`
+	Grading      *AnswerGrading `json:"grading,omitempty" db:"grading"`
+}
+
+// AnswerGrading is the result of grading an image answer with a vision
+// model. Score gives partial credit for the work shown.
+type AnswerGrading struct {
+	FinalAnswer string `json:"finalAnswer"`
+	Correct     bool   `json:"correct"`
+	Score       int    `json:"score"`
+	MaxScore    int    `json:"maxScore"`
+	Feedback    string `json:"feedback"`
+}
+
+// ImageAnswerSubmission is a photo of a worked answer uploaded for a question
+type ImageAnswerSubmission struct {
+	Filename    string
+	Data        []byte
+	TimeSpentMs *int
+}
+
+// AudioAnswerSubmission is a spoken answer recorded for a question
+type AudioAnswerSubmission struct {
+	Filename    string
+	Data        []byte
+	TimeSpentMs *int
+}
+
+// CreateQuizSessionRequest starts a session over the given questions or,
+// when Questions is empty, over Count questions generated from the selected
+// notes and decks
+type CreateQuizSessionRequest struct {
+	Questions []QuestionData `json:"questions"`
+
+	NoteIDs      []int  `json:"noteIds,omitempty"`
+	DeckIDs      []int  `json:"deckIds,omitempty"`
+	Count        int    `json:"count,omitempty"`
+	Difficulty   string `json:"difficulty,omitempty"`
+	QuestionType string `json:"questionType,omitempty"`
+}
+
+// QueuedQuizSession is a request for a generated session that arrived while
+// the LLM provider was unavailable. SessionID is set once it is completed.
+type QueuedQuizSession struct {
+	ID            int                      `json:"id" db:"id"`
+	UserID        int                      `json:"userId" db:"user_id"`
+	Request       CreateQuizSessionRequest `json:"request" db:"request"`
+	Status        string                   `json:"status" db:"status"`
+	SessionID     *int                     `json:"sessionId,omitempty" db:"session_id"`
+	Error         string                   `json:"error,omitempty" db:"error"`
+	Attempts      int                      `json:"attempts" db:"attempts"`
+	NextAttemptAt *time.Time               `json:"nextAttemptAt,omitempty" db:"nextAttemptAt"`
+	CreatedAt     time.Time                `json:"createdAt" db:"createdAt"`
+	UpdatedAt     time.Time                `json:"updatedAt" db:"updatedAt"`
+}
+
+type UpdateSessionQuestionRequest struct {
+	Answer      *string `json:"answer,omitempty"`
+	Status      *string `json:"status,omitempty"`
+	Flagged     *bool   `json:"flagged,omitempty"`
+	TimeSpentMs *int    `json:"timeSpentMs,omitempty"`
+}
+
+// SessionReport summarizes a quiz session, listing flagged questions with
+// the follow-up actions available for each.
+type SessionReport struct {
+	SessionID      int              `json:"sessionId"`
+	Status         string           `json:"status"`
+	TotalQuestions int              `json:"totalQuestions"`
+	Answered       int              `json:"answered"`
+	Skipped        int              `json:"skipped"`
+	Unanswered     int              `json:"unanswered"`
+	Correct        int              `json:"correct"`
+	Incorrect      int              `json:"incorrect"`
+	Ungraded       int              `json:"ungraded"`
+	ScorePercent   float64          `json:"scorePercent"`
+	TotalTimeMs    int              `json:"totalTimeMs"`
+	Results        []QuestionResult `json:"results"`
+	WeakTopics     []string         `json:"weakTopics"`
+	Flagged        []FlaggedItem    `json:"flagged"`
+	GeneratedAt    time.Time        `json:"generatedAt"`
+	CompletedAt    *time.Time       `json:"completedAt,omitempty"`
+}
+
+// QuestionResult is the outcome of one question. Correct is nil for
+// questions that cannot be graded automatically, such as essays.
+type QuestionResult struct {
+	Position      int    `json:"position"`
+	QuestionText  string `json:"questionText"`
+	Type          string `json:"type"`
+	Difficulty    string `json:"difficulty"`
+	Status        string `json:"status"`
+	Answer        string `json:"answer"`
+	CorrectAnswer string `json:"correctAnswer,omitempty"`
+	Correct       *bool  `json:"correct"`
+	Explanation   string `json:"explanation,omitempty"`
+	TimeSpentMs   int    `json:"timeSpentMs"`
+
+	Grading *AnswerGrading `json:"grading,omitempty"`
+}
+
+type EmailReportRequest struct {
+	To     string `json:"to"`
+	Format string `json:"format,omitempty"`
+}
+
+type FlaggedItem struct {
+	Position int          `json:"position"`
+	Question QuestionData `json:"question"`
+	Answer   string       `json:"answer"`
+	Actions  []string     `json:"actions"`
+}
+
+type QuestionExplanation struct {
+	QuestionID  string `json:"questionId"`
+	Explanation string `json:"explanation"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// REST hook triggers, named the way Zapier and Make expect
+const (
+	RestHookTriggerNewNote       = "new_note"
+	RestHookTriggerQuizCompleted = "quiz_completed"
+	RestHookTriggerLowScore      = "low_score"
+)
+
+// SubscribeRestHookRequest is what an automation platform sends when a
+// user turns on a zap that uses one of the triggers
+type SubscribeRestHookRequest struct {
+	HookURL string `json:"hookUrl"`
+	Event   string `json:"event"`
+}
+
+// RestHook is a subscription to one trigger. The platform keeps ID to
+// unsubscribe when the zap is turned off.
+type RestHook struct {
+	ID        int       `json:"id"`
+	HookURL   string    `json:"hookUrl"`
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// NoteTrigger is the new_note payload. Trigger payloads are flat, with lists
+// joined by commas, and their fields are only ever added to, so automations
+// built on them keep working.
+type NoteTrigger struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Source    string    `json:"source"`
+	Language  string    `json:"language"`
+	Tags      string    `json:"tags"`
+	DeckID    *int      `json:"deckId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// QuizTrigger is the quiz_completed and low_score payload. ID is the quiz
+// session's.
+type QuizTrigger struct {
+	ID             int       `json:"id"`
+	TotalQuestions int       `json:"totalQuestions"`
+	Answered       int       `json:"answered"`
+	Correct        int       `json:"correct"`
+	Incorrect      int       `json:"incorrect"`
+	ScorePercent   float64   `json:"scorePercent"`
+	WeakTopics     string    `json:"weakTopics"`
+	CompletedAt    time.Time `json:"completedAt"`
+}
@@ -0,0 +1,161 @@
+package models
+
+import "time"
+
+const (
+	RatingAgain = "again"
+	RatingHard  = "hard"
+	RatingGood  = "good"
+	RatingEasy  = "easy"
+
+	CardStateNew        = "new"
+	CardStateLearning   = "learning"
+	CardStateReview     = "review"
+	CardStateRelearning = "relearning"
+
+	// Scheduled reviews move cards through the scheduler; cram reviews drill
+	// cards without changing when they are next due
+	ReviewModeScheduled = "scheduled"
+	ReviewModeCram      = "cram"
+
+	// Review orders a deck can use for its due queue
+	ReviewOrderDue          = "due"
+	ReviewOrderRandom       = "random"
+	ReviewOrderHardestFirst = "hardest-first"
+	ReviewOrderBySubdeck    = "by-subdeck"
+	ReviewOrderInterleaved  = "interleaved"
+
+	// Order new cards are introduced in
+	NewCardOrderSequential = "sequential"
+	NewCardOrderRandom     = "random"
+
+	// Where new cards go in the due queue relative to reviews
+	NewCardPositionAfterReviews  = "after-reviews"
+	NewCardPositionBeforeReviews = "before-reviews"
+	NewCardPositionMixed         = "mixed"
+
+	// Outcomes of a review synced from an offline client
+	SyncStatusApplied   = "applied"
+	SyncStatusDuplicate = "duplicate"
+	SyncStatusConflict  = "conflict"
+	SyncStatusRejected  = "rejected"
+
+	// What happens to a card when it becomes a leech: tag only marks it,
+	// suspend also keeps it out of the review queues
+	LeechActionTag     = "tag"
+	LeechActionSuspend = "suspend"
+)
+
+// Schedule is the spaced-repetition state of a flashcard. Step is the
+// card's position on the learning or relearning ladder. Leech marks a card
+// lapsed so often it needs rewriting, and a suspended card is left out of
+// the review queues until it is unsuspended.
+type Schedule struct {
+	FlashcardID    int        `json:"flashcardId" db:"flashcard_id"`
+	State          string     `json:"state" db:"state"`
+	DueAt          time.Time  `json:"dueAt" db:"dueAt"`
+	IntervalDays   int        `json:"intervalDays" db:"intervalDays"`
+	EaseFactor     float64    `json:"easeFactor" db:"easeFactor"`
+	Repetitions    int        `json:"repetitions" db:"repetitions"`
+	Lapses         int        `json:"lapses" db:"lapses"`
+	Step           int        `json:"step" db:"step"`
+	Leech          bool       `json:"leech" db:"leech"`
+	Suspended      bool       `json:"suspended" db:"suspended"`
+	LastReviewedAt *time.Time `json:"lastReviewedAt" db:"lastReviewedAt"`
+	UpdatedAt      time.Time  `json:"updatedAt" db:"updatedAt"`
+}
+
+// Review is one answered card in the review log
+type Review struct {
+	ID            int       `json:"id" db:"id"`
+	UserID        int       `json:"userId" db:"user_id"`
+	FlashcardID   int       `json:"flashcardId" db:"flashcard_id"`
+	Mode          string    `json:"mode" db:"mode"`
+	Rating        string    `json:"rating" db:"rating"`
+	PreviousState string    `json:"previousState" db:"previousState"`
+	IntervalDays  int       `json:"intervalDays" db:"intervalDays"`
+	EaseFactor    float64   `json:"easeFactor" db:"easeFactor"`
+	DueAt         time.Time `json:"dueAt" db:"dueAt"`
+	ReviewedAt    time.Time `json:"reviewedAt" db:"reviewedAt"`
+	// Set for reviews synced from an offline client
+	ClientID *string `json:"clientId,omitempty" db:"clientId"`
+	// Schedule before this review, nil if the card had never been scheduled
+	PreviousSchedule *Schedule `json:"-" db:"previousSchedule"`
+}
+
+type DueCard struct {
+	Flashcard *Flashcard    `json:"flashcard"`
+	Schedule  *Schedule     `json:"schedule"`
+	Rendered  *RenderedCard `json:"rendered,omitempty"`
+}
+
+// RenderedCard is a flashcard split into the sides shown during review,
+// with the media a client should preload before showing it. Mnemonic is
+// only set for cards the learner has found hard.
+type RenderedCard struct {
+	Front     string   `json:"front"`
+	Back      string   `json:"back"`
+	Hint      string   `json:"hint,omitempty"`
+	Mnemonic  string   `json:"mnemonic,omitempty"`
+	AudioURL  string   `json:"audioUrl,omitempty"`
+	ImageURLs []string `json:"imageUrls,omitempty"`
+}
+
+// DueQueue is the ordered list of cards to review now. Total counts the
+// cards due, with new cards capped at what is left of the daily limit. In
+// cram mode the queue covers every card regardless of due date.
+type DueQueue struct {
+	DeckID   *int      `json:"deckId"`
+	Mode     string    `json:"mode"`
+	Order    string    `json:"order"`
+	Total    int       `json:"total"`
+	NewCards int       `json:"newCards"`
+	Cards    []DueCard `json:"cards"`
+}
+
+// Mode defaults to scheduled
+type SubmitReviewRequest struct {
+	FlashcardID int    `json:"flashcardId"`
+	Rating      string `json:"rating"`
+	Mode        string `json:"mode,omitempty"`
+}
+
+// NewLeech is set when this review made the card a leech, for clients to
+// offer reformulating it
+type ReviewResult struct {
+	Review   *Review   `json:"review"`
+	Schedule *Schedule `json:"schedule"`
+	NewLeech bool      `json:"newLeech,omitempty"`
+}
+
+// SyncReview is a review answered while offline. ClientID identifies it
+// across retries and ReviewedAt is when it was answered on the client.
+type SyncReview struct {
+	ClientID    string    `json:"clientId"`
+	FlashcardID int       `json:"flashcardId"`
+	Rating      string    `json:"rating"`
+	Mode        string    `json:"mode,omitempty"`
+	ReviewedAt  time.Time `json:"reviewedAt"`
+}
+
+type SyncReviewsRequest struct {
+	Reviews []SyncReview `json:"reviews"`
+}
+
+// SyncReviewResult reports what happened to one synced review
+type SyncReviewResult struct {
+	ClientID string    `json:"clientId"`
+	Status   string    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+	Review   *Review   `json:"review,omitempty"`
+	Schedule *Schedule `json:"schedule,omitempty"`
+}
+
+// SyncReviewsResponse lists results in the order the reviews were applied
+type SyncReviewsResponse struct {
+	Applied    int                `json:"applied"`
+	Duplicates int                `json:"duplicates"`
+	Conflicts  int                `json:"conflicts"`
+	Rejected   int                `json:"rejected"`
+	Results    []SyncReviewResult `json:"results"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// RoutingRule maps a generation request to a provider and model. Empty
+// match fields act as wildcards; rules are evaluated in ascending priority
+// order. OrganizationID matches the members of one organization and Plan
+// the users on a plan, where users outside an organization are on the
+// free plan. An empty Provider keeps the configured LLM_PROVIDER.
+type RoutingRule struct {
+	ID             int       `json:"id" db:"id"`
+	QuestionType   string    `json:"questionType,omitempty" db:"questionType"`
+	Difficulty     string    `json:"difficulty,omitempty" db:"difficulty"`
+	OrganizationID *int      `json:"organizationId,omitempty" db:"organizationId"`
+	Plan           string    `json:"plan,omitempty" db:"plan"`
+	Provider       string    `json:"provider,omitempty" db:"provider"`
+	Model          string    `json:"model" db:"model"`
+	Priority       int       `json:"priority" db:"priority"`
+	CreatedAt      time.Time `json:"createdAt" db:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt" db:"updatedAt"`
+}
+
+type CreateRoutingRuleRequest struct {
+	QuestionType   string `json:"questionType,omitempty"`
+	Difficulty     string `json:"difficulty,omitempty"`
+	OrganizationID *int   `json:"organizationId,omitempty"`
+	Plan           string `json:"plan,omitempty"`
+	Provider       string `json:"provider,omitempty"`
+	Model          string `json:"model"`
+	Priority       *int   `json:"priority,omitempty"`
+}
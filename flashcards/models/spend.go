@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Scopes spend is measured at
+const (
+	SpendScopeUser         = "user"
+	SpendScopeOrganization = "organization"
+)
+
+// SpendWindow is a user's or organization's token usage in the recent
+// window and in the baseline period before it
+type SpendWindow struct {
+	Scope          string
+	SubjectID      int
+	RecentTokens   int
+	BaselineTokens int
+}
+
+// SpendAnomaly records a spike in token usage over the trailing baseline.
+// SubjectID is a user or organization id depending on Scope.
+type SpendAnomaly struct {
+	ID                    int        `json:"id" db:"id"`
+	Scope                 string     `json:"scope" db:"scope"`
+	SubjectID             int        `json:"subjectId" db:"subjectId"`
+	RecentTokens          int        `json:"recentTokens" db:"recentTokens"`
+	BaselineTokensPerHour float64    `json:"baselineTokensPerHour" db:"baselineTokensPerHour"`
+	DetectedAt            time.Time  `json:"detectedAt" db:"detectedAt"`
+	ThrottledUntil        *time.Time `json:"throttledUntil" db:"throttledUntil"`
+}
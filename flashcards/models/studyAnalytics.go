@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// StudyAnalytics summarizes a user's flashcard reviews and quiz answers
+// over the last Days days
+type StudyAnalytics struct {
+	Days                 int                  `json:"days"`
+	Since                time.Time            `json:"since"`
+	DailyReviews         []DailyReviews       `json:"dailyReviews"`
+	AccuracyByDifficulty []DifficultyAccuracy `json:"accuracyByDifficulty"`
+	CurrentStreakDays    int                  `json:"currentStreakDays"`
+	LongestStreakDays    int                  `json:"longestStreakDays"`
+	MostMissedNotes      []MissedNote         `json:"mostMissedNotes"`
+}
+
+// DailyReviews counts the flashcard reviews of one day; Recalled excludes
+// cards rated again
+type DailyReviews struct {
+	Date     string `json:"date"`
+	Reviewed int    `json:"reviewed"`
+	Recalled int    `json:"recalled"`
+}
+
+// DifficultyAccuracy is the share of graded quiz answers that were correct
+// for questions of one difficulty
+type DifficultyAccuracy struct {
+	Difficulty string  `json:"difficulty"`
+	Graded     int     `json:"graded"`
+	Correct    int     `json:"correct"`
+	Accuracy   float64 `json:"accuracy"`
+}
+
+// MissedNote is a note behind quiz questions answered wrongly
+type MissedNote struct {
+	NoteID   int     `json:"noteId"`
+	Excerpt  string  `json:"excerpt"`
+	Missed   int     `json:"missed"`
+	Graded   int     `json:"graded"`
+	MissRate float64 `json:"missRate"`
+}
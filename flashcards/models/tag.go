@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type Tag struct {
+	ID        int       `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	NoteCount int       `json:"noteCount"`
+	CreatedAt time.Time `json:"createdAt" db:"createdAt"`
+}
+
+type AddTagsRequest struct {
+	Tags []string `json:"tags"`
+}
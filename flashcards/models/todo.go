@@ -4,6 +4,7 @@ import "time"
 
 type Todo struct {
 	ID          int       `json:"id" db:"id"`
+	UserID      int       `json:"userId" db:"user_id"`
 	Title       string    `json:"title" db:"title"`
 	Description string    `json:"description" db:"description"`
 	Completed   bool      `json:"completed" db:"completed"`
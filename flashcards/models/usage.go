@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// LLMCall is one metered call to the LLM provider. CostUSD is estimated
+// from the model's price per token.
+type LLMCall struct {
+	ID           int64     `json:"id" db:"id"`
+	UserID       int       `json:"userId" db:"user_id"`
+	Model        string    `json:"model" db:"model"`
+	InputTokens  int       `json:"inputTokens" db:"inputTokens"`
+	OutputTokens int       `json:"outputTokens" db:"outputTokens"`
+	CostUSD      float64   `json:"costUsd" db:"costUsd"`
+	CreatedAt    time.Time `json:"createdAt" db:"createdAt"`
+}
+
+// LLMUsageTotals adds up calls, tokens and cost
+type LLMUsageTotals struct {
+	Calls        int     `json:"calls"`
+	InputTokens  int     `json:"inputTokens"`
+	OutputTokens int     `json:"outputTokens"`
+	CostUSD      float64 `json:"costUsd"`
+}
+
+// DailyLLMUsage is a user's LLM usage on one day (UTC)
+type DailyLLMUsage struct {
+	Day          time.Time `json:"day"`
+	Calls        int       `json:"calls"`
+	InputTokens  int       `json:"inputTokens"`
+	OutputTokens int       `json:"outputTokens"`
+	CostUSD      float64   `json:"costUsd"`
+}
+
+// ModelLLMUsage is a user's LLM usage of one model
+type ModelLLMUsage struct {
+	Model        string  `json:"model"`
+	Calls        int     `json:"calls"`
+	InputTokens  int     `json:"inputTokens"`
+	OutputTokens int     `json:"outputTokens"`
+	CostUSD      float64 `json:"costUsd"`
+}
+
+// UserLLMUsage is one user's LLM usage over a window
+type UserLLMUsage struct {
+	UserID       int     `json:"userId"`
+	Calls        int     `json:"calls"`
+	InputTokens  int     `json:"inputTokens"`
+	OutputTokens int     `json:"outputTokens"`
+	CostUSD      float64 `json:"costUsd"`
+}
+
+// LLMBudget is the user's spend this month against the monthly budget
+type LLMBudget struct {
+	Month        time.Time `json:"month"`
+	MonthlyUSD   float64   `json:"monthlyUsd"`
+	SpentUSD     float64   `json:"spentUsd"`
+	RemainingUSD float64   `json:"remainingUsd"`
+	Exceeded     bool      `json:"exceeded"`
+}
+
+// LLMUsageSummary reports the user's LLM usage since From, in total, per
+// day and per model. Budget is set when a monthly budget is configured.
+type LLMUsageSummary struct {
+	From   time.Time        `json:"from"`
+	Total  LLMUsageTotals   `json:"total"`
+	Days   []*DailyLLMUsage `json:"days"`
+	Models []*ModelLLMUsage `json:"models"`
+	Budget *LLMBudget       `json:"budget,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type User struct {
+	ID           int       `json:"id" db:"id"`
+	Email        string    `json:"email" db:"email"`
+	PasswordHash string    `json:"-" db:"passwordHash"`
+	IsAdmin      bool      `json:"isAdmin" db:"isAdmin"`
+	CreatedAt    time.Time `json:"createdAt" db:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt" db:"updatedAt"`
+}
+
+type RegisterRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type AuthResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	User      *User     `json:"user"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// CEFR proficiency levels used when generating example sentences
+const (
+	CEFRLevelA1 = "A1"
+	CEFRLevelA2 = "A2"
+	CEFRLevelB1 = "B1"
+	CEFRLevelB2 = "B2"
+	CEFRLevelC1 = "C1"
+	CEFRLevelC2 = "C2"
+)
+
+// Vocabulary holds the language-learning details of a flashcard
+type Vocabulary struct {
+	FlashcardID      int               `json:"flashcardId" db:"flashcard_id"`
+	Term             string            `json:"term" db:"term"`
+	Language         string            `json:"language" db:"language"`
+	IPA              string            `json:"ipa" db:"ipa"`
+	Pronunciation    string            `json:"pronunciation" db:"pronunciation"`
+	CEFRLevel        string            `json:"cefrLevel" db:"cefrLevel"`
+	Examples         []ExampleSentence `json:"examples" db:"examples"`
+	HasAudio         bool              `json:"hasAudio"`
+	AudioContentType string            `json:"-" db:"audioContentType"`
+	CreatedAt        time.Time         `json:"createdAt" db:"createdAt"`
+	UpdatedAt        time.Time         `json:"updatedAt" db:"updatedAt"`
+}
+
+type ExampleSentence struct {
+	Text        string `json:"text"`
+	Translation string `json:"translation,omitempty"`
+}
+
+type UpdateVocabularyRequest struct {
+	Term          *string `json:"term,omitempty"`
+	Language      *string `json:"language,omitempty"`
+	IPA           *string `json:"ipa,omitempty"`
+	Pronunciation *string `json:"pronunciation,omitempty"`
+}
+
+type GenerateExamplesRequest struct {
+	CEFRLevel string `json:"cefrLevel"`
+	Count     int    `json:"count,omitempty"`
+}
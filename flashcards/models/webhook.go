@@ -0,0 +1,96 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Events a webhook can subscribe to
+const (
+	WebhookEventNoteCreated     = "note.created"
+	WebhookEventQuizGenerated   = "quiz.generated"
+	WebhookEventQuizCompleted   = "quiz.completed"
+	WebhookEventReviewCompleted = "review.completed"
+)
+
+// Webhooks are registered through /webhooks, and REST hooks by automation
+// platforms such as Zapier through /hooks
+const (
+	WebhookKindWebhook  = "webhook"
+	WebhookKindRestHook = "rest-hook"
+)
+
+const (
+	WebhookDeliveryStatusPending   = "pending"
+	WebhookDeliveryStatusDelivered = "delivered"
+	WebhookDeliveryStatusFailed    = "failed"
+)
+
+// Webhook POSTs the events it subscribes to to URL. Secret signs each
+// payload and is only returned when the webhook is created. A REST hook
+// subscribes to one trigger and is sent the trigger payload alone.
+type Webhook struct {
+	ID        int       `json:"id" db:"id"`
+	UserID    int       `json:"userId" db:"user_id"`
+	Kind      string    `json:"kind" db:"kind"`
+	URL       string    `json:"url" db:"url"`
+	Secret    string    `json:"secret,omitempty" db:"secret"`
+	Events    []string  `json:"events" db:"events"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"createdAt" db:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updatedAt"`
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// UpdateWebhookRequest changes only the fields that are set
+type UpdateWebhookRequest struct {
+	URL    *string  `json:"url,omitempty"`
+	Events []string `json:"events,omitempty"`
+	Active *bool    `json:"active,omitempty"`
+}
+
+// WebhookDelivery is one event sent to a webhook, with the outcome of its
+// last attempt. NextAttemptAt is only set while it is pending.
+type WebhookDelivery struct {
+	ID             int             `json:"id" db:"id"`
+	WebhookID      int             `json:"webhookId" db:"webhook_id"`
+	Event          string          `json:"event" db:"event"`
+	Payload        json.RawMessage `json:"payload" db:"payload"`
+	Status         string          `json:"status" db:"status"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	ResponseStatus *int            `json:"responseStatus,omitempty" db:"responseStatus"`
+	Error          string          `json:"error,omitempty" db:"error"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt,omitempty" db:"nextAttemptAt"`
+	CreatedAt      time.Time       `json:"createdAt" db:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt" db:"updatedAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty" db:"deliveredAt"`
+
+	// Where a claimed delivery is sent, the secret that signs it, and the
+	// webhook's owner and kind
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+	UserID int    `json:"-"`
+	Kind   string `json:"-"`
+}
+
+// QuizGeneratedEvent is the data of a quiz.generated event. Source tells
+// generated questions from ones served from the question bank.
+type QuizGeneratedEvent struct {
+	NoteIDs    []int          `json:"noteIds"`
+	Source     string         `json:"source"`
+	Difficulty string         `json:"difficulty,omitempty"`
+	Questions  []QuestionData `json:"questions"`
+}
+
+// WebhookEvent is the body POSTed to a webhook. ID is the delivery's, so
+// receivers can tell a retry from a new event.
+type WebhookEvent struct {
+	ID        int             `json:"id"`
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
@@ -0,0 +1,271 @@
+// Package openapi builds an OpenAPI 3 document for the API. Routes are
+// described next to the handlers that serve them, and request and response
+// schemas are derived from the Go types and their JSON tags, so the
+// document follows the models as they change.
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const OPENAPI_VERSION = "3.0.3"
+
+// Document is the subset of an OpenAPI 3 document the API needs
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem holds the operations on one path, keyed by lower case method
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	// An empty list marks a public operation that needs no token
+	Security *[]map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Schema is a JSON schema as OpenAPI 3.0 understands it
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// Route describes one operation. Request and Response are values of the Go
+// types sent and returned, such as models.Note{}; their schemas are derived
+// from the JSON tags. A RequestSchema or ResponseSchema replaces the derived
+// one for bodies that are not JSON.
+type Route struct {
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Tag         string
+	Query       []Parameter
+	Headers     []Parameter
+
+	Request            any
+	RequestSchema      *Schema
+	RequestContentType string
+
+	Status              int
+	Response            any
+	ResponseSchema      *Schema
+	ResponseContentType string
+	// Other successful responses by status, with the Go value returned or
+	// nil for an empty body
+	OtherResponses map[int]any
+
+	// Error statuses the operation can answer with besides 400 and 500
+	Errors []int
+	Public bool
+}
+
+const SECURITY_SCHEME = "bearerAuth"
+
+var pathParameter = regexp.MustCompile(`\{([a-zA-Z]+)(:[^}]*)?\}`)
+
+// Builder collects routes into a Document
+type Builder struct {
+	doc     *Document
+	schemas *schemaRegistry
+}
+
+func NewBuilder(info Info) *Builder {
+	doc := &Document{
+		OpenAPI: OPENAPI_VERSION,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+			SecuritySchemes: map[string]*SecurityScheme{
+				SECURITY_SCHEME: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+		Security: []map[string][]string{{SECURITY_SCHEME: {}}},
+	}
+	doc.Components.Schemas["Error"] = &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"error": {Type: "string"}},
+		Required:   []string{"error"},
+	}
+	return &Builder{doc: doc, schemas: &schemaRegistry{components: doc.Components.Schemas}}
+}
+
+// Add adds an operation. Paths use the router's syntax; parameters with a
+// [0-9]+ pattern are documented as integers.
+func (b *Builder) Add(route Route) {
+	path := route.Path
+	var params []Parameter
+	for _, match := range pathParameter.FindAllStringSubmatch(route.Path, -1) {
+		schema := &Schema{Type: "string"}
+		if match[2] == ":[0-9]+" {
+			schema = &Schema{Type: "integer"}
+		}
+		params = append(params, Parameter{Name: match[1], In: "path", Required: true, Schema: schema})
+		path = strings.Replace(path, match[0], "{"+match[1]+"}", 1)
+	}
+
+	op := &Operation{
+		OperationID: operationID(route.Method, path),
+		Summary:     route.Summary,
+		Description: route.Description,
+		Parameters:  append(append(params, route.Query...), route.Headers...),
+		Responses:   make(map[string]*Response),
+	}
+	if route.Tag != "" {
+		op.Tags = []string{route.Tag}
+	}
+	if route.Public {
+		op.Security = &[]map[string][]string{}
+	}
+
+	if schema := b.bodySchema(route.RequestSchema, route.Request); schema != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{contentType(route.RequestContentType): {Schema: schema}},
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	if schema := b.bodySchema(route.ResponseSchema, route.Response); schema != nil {
+		success.Content = map[string]*MediaType{contentType(route.ResponseContentType): {Schema: schema}}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+	for code, v := range route.OtherResponses {
+		response := &Response{Description: http.StatusText(code)}
+		if v != nil {
+			response.Content = map[string]*MediaType{"application/json": {Schema: b.schemas.schemaOf(v)}}
+		}
+		op.Responses[strconv.Itoa(code)] = response
+	}
+
+	statuses := append([]int{http.StatusBadRequest, http.StatusInternalServerError}, route.Errors...)
+	if !route.Public {
+		statuses = append(statuses, http.StatusUnauthorized, http.StatusTooManyRequests)
+	}
+	for _, code := range statuses {
+		op.Responses[strconv.Itoa(code)] = &Response{
+			Description: http.StatusText(code),
+			Content:     map[string]*MediaType{"application/json": {Schema: &Schema{Ref: "#/components/schemas/Error"}}},
+		}
+	}
+
+	item, ok := b.doc.Paths[path]
+	if !ok {
+		item = make(PathItem)
+		b.doc.Paths[path] = item
+	}
+	item[strings.ToLower(route.Method)] = op
+}
+
+// Schema returns the schema of a Go value, registering the named types it
+// refers to as components
+func (b *Builder) Schema(v any) *Schema {
+	return b.schemas.schemaOf(v)
+}
+
+func (b *Builder) Document() *Document {
+	return b.doc
+}
+
+func (b *Builder) bodySchema(schema *Schema, v any) *Schema {
+	if schema != nil {
+		return schema
+	}
+	if v == nil {
+		return nil
+	}
+	return b.schemas.schemaOf(v)
+}
+
+// QueryParameter documents an optional query string parameter
+func QueryParameter(name, typ, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: typ}}
+}
+
+// HeaderParameter documents a request header the operation requires
+func HeaderParameter(name, description string) Parameter {
+	return Parameter{Name: name, In: "header", Description: description, Required: true, Schema: &Schema{Type: "string"}}
+}
+
+func contentType(value string) string {
+	if value == "" {
+		return "application/json"
+	}
+	return value
+}
+
+// operationID names an operation after its method and path, for example
+// getQuizSessionsIdReport
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '{' || r == '}' }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	DEFAULT_ACTIVITY_DAYS  = 7
+	MAX_ACTIVITY_DAYS      = 90
+	DEFAULT_ACTIVITY_LIMIT = 50
+	MAX_ACTIVITY_LIMIT     = 200
+)
+
+// ActivityService builds a user's feed of recent actions: flashcards
+// created, cards reviewed, quiz sessions completed and imports finished
+type ActivityService struct {
+	repo db.ActivityRepository
+}
+
+func NewActivityService(repo db.ActivityRepository) *ActivityService {
+	return &ActivityService{repo: repo}
+}
+
+// GetActivity covers the last days days, including today, and returns at
+// most limit items
+func (s *ActivityService) GetActivity(ctx context.Context, userID, days, limit int) (*models.ActivityFeed, error) {
+	if days == 0 {
+		days = DEFAULT_ACTIVITY_DAYS
+	}
+	if days < 1 || days > MAX_ACTIVITY_DAYS {
+		return nil, models.Invalid("days must be between 1 and %d", MAX_ACTIVITY_DAYS)
+	}
+	if limit == 0 {
+		limit = DEFAULT_ACTIVITY_LIMIT
+	}
+	if limit < 1 || limit > MAX_ACTIVITY_LIMIT {
+		return nil, models.Invalid("limit must be between 1 and %d", MAX_ACTIVITY_LIMIT)
+	}
+
+	since := truncateToDay(time.Now()).AddDate(0, 0, -(days - 1))
+	items, err := s.repo.GetActivity(ctx, userID, since, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ActivityFeed{Days: days, Since: since, Items: items}, nil
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	ANALYTICS_FLUSH_INTERVAL = time.Minute
+	ANALYTICS_BUCKET         = time.Hour
+	DEFAULT_ANALYTICS_WINDOW = 24 * time.Hour
+	DEFAULT_ANALYTICS_SORT   = "requests"
+	DEFAULT_ANALYTICS_LIMIT  = 50
+)
+
+// Orders analytics reports can be sorted by, largest first
+var analyticsSortFields = []string{"requests", "errors", "errorRate", "avgLatency", "maxLatency"}
+
+type requestStatsKey struct {
+	bucket time.Time
+	route  string
+	method string
+	userID int
+}
+
+// AnalyticsService counts requests, errors and latency per route and user.
+// Requests are aggregated in memory and flushed to the database every
+// ANALYTICS_FLUSH_INTERVAL, so recording one costs no query.
+type AnalyticsService struct {
+	repo      db.AnalyticsRepository
+	retention time.Duration
+
+	mu      sync.Mutex
+	pending map[requestStatsKey]*models.RequestStats
+}
+
+func NewAnalyticsService(repo db.AnalyticsRepository, retention time.Duration) *AnalyticsService {
+	return &AnalyticsService{
+		repo:      repo,
+		retention: retention,
+		pending:   map[requestStatsKey]*models.RequestStats{},
+	}
+}
+
+// Record adds a finished request to the current hour's totals
+func (s *AnalyticsService) Record(route, method string, userID, status int, latency time.Duration) {
+	key := requestStatsKey{
+		bucket: time.Now().UTC().Truncate(ANALYTICS_BUCKET),
+		route:  route,
+		method: method,
+		userID: userID,
+	}
+	latencyMs := latency.Milliseconds()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.pending[key]
+	if !ok {
+		stats = &models.RequestStats{Bucket: key.bucket, Route: route, Method: method, UserID: userID}
+		s.pending[key] = stats
+	}
+	stats.Requests++
+	stats.TotalLatencyMs += latencyMs
+	if latencyMs > stats.MaxLatencyMs {
+		stats.MaxLatencyMs = latencyMs
+	}
+	switch {
+	case status >= http.StatusInternalServerError:
+		stats.ServerErrors++
+	case status >= http.StatusBadRequest:
+		stats.ClientErrors++
+	}
+}
+
+// Flush writes the totals gathered since the last flush. On failure they
+// are merged back so the next flush retries them.
+func (s *AnalyticsService) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = map[requestStatsKey]*models.RequestStats{}
+	s.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	stats := make([]*models.RequestStats, 0, len(pending))
+	for _, stat := range pending {
+		stats = append(stats, stat)
+	}
+
+	if err := s.repo.SaveRequestStats(ctx, stats); err != nil {
+		s.restore(pending)
+		return err
+	}
+
+	LoggerFromContext(ctx).Debug("Flushed request analytics", "rows", len(stats))
+	return nil
+}
+
+func (s *AnalyticsService) restore(pending map[requestStatsKey]*models.RequestStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, stat := range pending {
+		current, ok := s.pending[key]
+		if !ok {
+			s.pending[key] = stat
+			continue
+		}
+		current.Requests += stat.Requests
+		current.ClientErrors += stat.ClientErrors
+		current.ServerErrors += stat.ServerErrors
+		current.TotalLatencyMs += stat.TotalLatencyMs
+		current.MaxLatencyMs = max(current.MaxLatencyMs, stat.MaxLatencyMs)
+	}
+}
+
+// DeleteExpired removes stats older than the retention period
+func (s *AnalyticsService) DeleteExpired(ctx context.Context) error {
+	deleted, err := s.repo.DeleteRequestStatsBefore(ctx, time.Now().Add(-s.retention))
+	if err != nil {
+		return err
+	}
+
+	LoggerFromContext(ctx).Info("Deleted expired request analytics", "count", deleted)
+	return nil
+}
+
+// GetRouteAnalytics reports per-route totals over the window, such as the
+// slowest or most failing routes
+func (s *AnalyticsService) GetRouteAnalytics(ctx context.Context, window time.Duration, sort string, limit int) ([]*models.RouteAnalytics, error) {
+	query, err := s.analyticsQuery(window, sort, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetRouteAnalytics(ctx, query)
+}
+
+// GetUserAnalytics reports per-user totals over the window, such as the
+// clients sending the most requests or errors
+func (s *AnalyticsService) GetUserAnalytics(ctx context.Context, window time.Duration, sort string, limit int) ([]*models.UserAnalytics, error) {
+	query, err := s.analyticsQuery(window, sort, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetUserAnalytics(ctx, query)
+}
+
+func (s *AnalyticsService) analyticsQuery(window time.Duration, sort string, limit int) (models.AnalyticsQuery, error) {
+	if window == 0 {
+		window = DEFAULT_ANALYTICS_WINDOW
+	}
+	if window < 0 || window > s.retention {
+		return models.AnalyticsQuery{}, models.Invalid("window must be positive and at most %s", s.retention)
+	}
+
+	if sort == "" {
+		sort = DEFAULT_ANALYTICS_SORT
+	}
+	if !containsValue(analyticsSortFields, sort) {
+		return models.AnalyticsQuery{}, models.Invalid("sort must be one of: %s", strings.Join(analyticsSortFields, ", "))
+	}
+
+	if limit == 0 {
+		limit = DEFAULT_ANALYTICS_LIMIT
+	}
+	if limit < 1 || limit > MAX_PAGE_LIMIT {
+		return models.AnalyticsQuery{}, models.Invalid("limit must be between 1 and %d", MAX_PAGE_LIMIT)
+	}
+
+	// Whole buckets are reported, so the window starts at the top of its
+	// first hour
+	since := time.Now().UTC().Add(-window).Truncate(ANALYTICS_BUCKET)
+	return models.AnalyticsQuery{Since: since, Sort: sort, Limit: limit}, nil
+}
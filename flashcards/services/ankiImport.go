@@ -0,0 +1,245 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+
+	"flashcards/models"
+	"flashcards/sqlitefile"
+)
+
+// Largest collection database read from an Anki package
+const MAX_ANKI_COLLECTION_BYTES = 100 * 1024 * 1024
+
+var (
+	htmlLineBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</(div|p|li|h[1-6]|tr)>`)
+	ankiSoundPattern     = regexp.MustCompile(`\[sound:[^\]]*\]`)
+	ankiClozePattern     = regexp.MustCompile(`\{\{c\d+::(.*?)(?:::(.*?))?\}\}`)
+	blankLinesPattern    = regexp.MustCompile(`\n{3,}`)
+)
+
+// deckImportRow is one card read from an imported file, with both sides as
+// plain text. deck is a "Parent::Child" path, or "" for the deck the
+// import goes into.
+type deckImportRow struct {
+	row   int
+	deck  string
+	front string
+	back  string
+}
+
+// readAnkiPackage reads the notes of an .apkg file as cards. The first two
+// fields of a note become the front and back; cloze notes are turned into
+// a front with the deletions blanked out and a back with them filled in.
+// Media, scheduling and review history are not imported.
+func readAnkiPackage(ctx context.Context, data []byte) ([]deckImportRow, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, models.Invalid("file is not a valid Anki package")
+	}
+
+	files := make(map[string]*zip.File)
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	// Packages from recent Anki versions hold a compressed collection and a
+	// placeholder in the legacy slot, unless exported for older versions
+	collection := files["collection.anki21"]
+	if collection == nil && files["collection.anki21b"] == nil {
+		collection = files["collection.anki2"]
+	}
+	if collection == nil {
+		if files["collection.anki21b"] != nil {
+			return nil, models.Invalid("this Anki package uses a newer format; export it again with \"Support older Anki versions\" checked")
+		}
+		return nil, models.Invalid("file is not a valid Anki package")
+	}
+	if collection.UncompressedSize64 > MAX_ANKI_COLLECTION_BYTES {
+		return nil, models.Invalid("Anki collection exceeds %d bytes", MAX_ANKI_COLLECTION_BYTES)
+	}
+
+	reader, err := collection.Open()
+	if err != nil {
+		return nil, models.Invalid("file is not a valid Anki package")
+	}
+	defer reader.Close()
+	database, err := io.ReadAll(io.LimitReader(reader, MAX_ANKI_COLLECTION_BYTES+1))
+	if err != nil || len(database) > MAX_ANKI_COLLECTION_BYTES {
+		return nil, models.Invalid("file is not a valid Anki package")
+	}
+
+	collectionDB, err := sqlitefile.Open(ctx, database)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("invalid Anki collection: %w", err)
+	}
+	defer collectionDB.Close()
+
+	rows, err := readAnkiCollection(ctx, collectionDB)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("invalid Anki collection: %w", err)
+	}
+	return rows, nil
+}
+
+func readAnkiCollection(ctx context.Context, collectionDB *sqlitefile.File) ([]deckImportRow, error) {
+	deckNames, err := ankiDeckNames(ctx, collectionDB)
+	if err != nil {
+		return nil, err
+	}
+
+	// A note's deck is the deck of its first card
+	cards, err := collectionDB.Query(ctx, "SELECT nid, did FROM cards ORDER BY ord, rowid")
+	if err != nil {
+		return nil, err
+	}
+	defer cards.Close()
+	noteDecks := make(map[int64]int64)
+	for cards.Next() {
+		var noteID, deckID int64
+		if err := cards.Scan(&noteID, &deckID); err != nil {
+			return nil, err
+		}
+		if _, ok := noteDecks[noteID]; !ok {
+			noteDecks[noteID] = deckID
+		}
+	}
+	if err := cards.Err(); err != nil {
+		return nil, err
+	}
+
+	notes, err := collectionDB.Query(ctx, "SELECT id, flds FROM notes ORDER BY rowid")
+	if err != nil {
+		return nil, err
+	}
+	defer notes.Close()
+
+	var rows []deckImportRow
+	for notes.Next() {
+		var noteID int64
+		var value any
+		if err := notes.Scan(&noteID, &value); err != nil {
+			return nil, err
+		}
+		fields, _ := value.(string)
+		parts := strings.Split(fields, "\x1f")
+
+		row := deckImportRow{row: len(rows) + 1, deck: deckNames[noteDecks[noteID]]}
+		if ankiClozePattern.MatchString(parts[0]) {
+			row.front, row.back = ankiCloze(ankiFieldText(parts[0]))
+			if len(parts) > 1 {
+				if extra := ankiFieldText(parts[1]); extra != "" {
+					row.back += "\n\n" + extra
+				}
+			}
+		} else {
+			row.front = ankiFieldText(parts[0])
+			if len(parts) > 1 {
+				row.back = ankiFieldText(parts[1])
+			}
+		}
+		rows = append(rows, row)
+	}
+	if err := notes.Err(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// ankiDeckNames maps deck ids to "Parent::Child" names. Older collections
+// keep the decks as JSON in the col table; newer ones in a decks table
+// whose names separate levels with 0x1f.
+func ankiDeckNames(ctx context.Context, collectionDB *sqlitefile.File) (map[int64]string, error) {
+	names := make(map[int64]string)
+
+	hasDecksTable, err := collectionDB.HasTable(ctx, "decks")
+	if err != nil {
+		return nil, err
+	}
+	if hasDecksTable {
+		decks, err := collectionDB.Query(ctx, "SELECT id, name FROM decks")
+		if err != nil {
+			return nil, err
+		}
+		defer decks.Close()
+		for decks.Next() {
+			var id int64
+			var value any
+			if err := decks.Scan(&id, &value); err != nil {
+				return nil, err
+			}
+			name, _ := value.(string)
+			names[id] = strings.ReplaceAll(name, "\x1f", "::")
+		}
+		if err := decks.Err(); err != nil {
+			return nil, err
+		}
+		if len(names) > 0 {
+			return names, nil
+		}
+	}
+
+	var value any
+	err = collectionDB.QueryRow(ctx, "SELECT decks FROM col ORDER BY rowid LIMIT 1").Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.Invalid("collection has no col row")
+	}
+	if err != nil {
+		return nil, err
+	}
+	decksJSON, _ := value.(string)
+	var decks map[string]struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(decksJSON), &decks); err != nil {
+		return nil, models.Invalid("invalid deck list")
+	}
+	for _, deck := range decks {
+		names[deck.ID] = deck.Name
+	}
+	return names, nil
+}
+
+// ankiFieldText turns the HTML of a field into plain text, keeping line
+// breaks and dropping images and sounds
+func ankiFieldText(field string) string {
+	text := htmlLineBreakPattern.ReplaceAllString(field, "\n")
+	text = ankiSoundPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(htmlTagPattern.ReplaceAllString(text, ""))
+	text = strings.ReplaceAll(text, "\u00a0", " ")
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(blankLinesPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// ankiCloze returns the text with every {{c1::answer::hint}} deletion
+// replaced by its hint or [...], and with every deletion filled in
+func ankiCloze(text string) (string, string) {
+	front := ankiClozePattern.ReplaceAllStringFunc(text, func(match string) string {
+		if hint := ankiClozePattern.FindStringSubmatch(match)[2]; hint != "" {
+			return "[" + hint + "]"
+		}
+		return "[...]"
+	})
+	back := ankiClozePattern.ReplaceAllString(text, "$1")
+	return front, back
+}
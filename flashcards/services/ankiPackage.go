@@ -0,0 +1,207 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"flashcards/models"
+	"flashcards/sqlitefile"
+)
+
+// Anki note type shared by every export, so repeated imports reuse it
+// instead of adding a copy each time
+const (
+	ANKI_MODEL_ID   = 1755648000000
+	ANKI_MODEL_NAME = "Flashcards (Basic)"
+)
+
+// Collection schema of the legacy .apkg format, which every Anki 2.1
+// release can import
+const ankiSchemaVersion = 11
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// The collection tables of an Anki 2.1 package, as created by Anki itself
+var ankiTables = []struct{ name, sql string }{
+	{"col", "CREATE TABLE col (id integer primary key, crt integer not null, mod integer not null, scm integer not null, ver integer not null, dty integer not null, usn integer not null, ls integer not null, conf text not null, models text not null, decks text not null, dconf text not null, tags text not null)"},
+	{"notes", "CREATE TABLE notes (id integer primary key, guid text not null, mid integer not null, mod integer not null, usn integer not null, tags text not null, flds text not null, sfld integer not null, csum integer not null, flags integer not null, data text not null)"},
+	{"cards", "CREATE TABLE cards (id integer primary key, nid integer not null, did integer not null, ord integer not null, mod integer not null, usn integer not null, type integer not null, queue integer not null, due integer not null, ivl integer not null, factor integer not null, reps integer not null, lapses integer not null, left integer not null, odue integer not null, odid integer not null, flags integer not null, data text not null)"},
+	{"revlog", "CREATE TABLE revlog (id integer primary key, cid integer not null, usn integer not null, ease integer not null, ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null, type integer not null)"},
+	{"graves", "CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null)"},
+}
+
+// ankiDeck is one deck of an export; Name uses Anki's "Parent::Child" form
+type ankiDeck struct {
+	Name        string
+	Description string
+	Cards       []ankiCard
+}
+
+// ankiCard holds the HTML of both fields. GUID stays the same across
+// exports so Anki updates a card it already has instead of duplicating it.
+type ankiCard struct {
+	GUID  string
+	Front string
+	Back  string
+}
+
+// writeAnkiPackage writes an .apkg file: a zip of the collection database
+// and an empty media manifest. Every card is new in Anki. The zip is
+// streamed to w; only the collection database is built in memory.
+func writeAnkiPackage(ctx context.Context, w io.Writer, decks []ankiDeck, now time.Time) error {
+	nowMs := now.UnixMilli()
+	nowSec := now.Unix()
+
+	var notes, cards [][]any
+	deckConfigs := map[string]any{"1": ankiDeckJSON(1, "Default", "", nowSec)}
+	due := 0
+	for i, deck := range decks {
+		// Anki ids are millisecond timestamps; consecutive ones from now
+		// keep them unique within the package
+		deckID := nowMs + int64(i)
+		deckConfigs[strconv.FormatInt(deckID, 10)] = ankiDeckJSON(deckID, deck.Name, deck.Description, nowSec)
+
+		for _, card := range deck.Cards {
+			id := nowMs + int64(len(notes))
+			front := strings.ReplaceAll(card.Front, "\x1f", "")
+			back := strings.ReplaceAll(card.Back, "\x1f", "")
+			sortField := ankiStripHTML(front)
+			due++
+
+			notes = append(notes, []any{id, card.GUID, ANKI_MODEL_ID, nowSec, -1, "",
+				front + "\x1f" + back, sortField, ankiChecksum(sortField), 0, ""})
+			cards = append(cards, []any{id, id, deckID, 0, nowSec, -1, 0, 0, due, 0, 0, 0, 0, 0, 0, 0, 0, ""})
+		}
+	}
+
+	var firstDeck int64 = 1
+	if len(decks) > 0 {
+		firstDeck = nowMs
+	}
+	conf := map[string]any{
+		"nextPos": due + 1, "estTimes": true, "activeDecks": []int64{firstDeck}, "sortType": "noteFld",
+		"timeLim": 0, "sortBackwards": false, "addToCur": true, "curDeck": firstDeck, "newBury": true,
+		"newSpread": 0, "dueCounts": true, "curModel": strconv.Itoa(ANKI_MODEL_ID), "collapseTime": 1200,
+	}
+	noteTypes := map[string]any{strconv.Itoa(ANKI_MODEL_ID): ankiModelJSON(firstDeck, nowSec)}
+
+	jsonColumns := make([]string, 4)
+	for i, value := range []any{conf, noteTypes, deckConfigs, map[string]any{"1": ankiDeckOptionsJSON()}} {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return models.Internal("failed to encode anki collection: %w", err)
+		}
+		jsonColumns[i] = string(encoded)
+	}
+
+	rows := map[string][][]any{
+		"col": {{1, truncateToDay(now).Unix(), nowMs, nowMs, ankiSchemaVersion, 0, 0, 0,
+			jsonColumns[0], jsonColumns[1], jsonColumns[2], jsonColumns[3], "{}"}},
+		"notes": notes,
+		"cards": cards,
+	}
+	tables := make([]sqlitefile.Table, len(ankiTables))
+	for i, table := range ankiTables {
+		tables[i] = sqlitefile.Table{Name: table.name, SQL: table.sql, Rows: rows[table.name]}
+	}
+
+	collection, err := sqlitefile.Build(ctx, tables)
+	if err != nil {
+		return models.Internal("failed to build anki collection: %w", err)
+	}
+
+	archive := zip.NewWriter(w)
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{"collection.anki2", collection},
+		{"media", []byte("{}")},
+	} {
+		entry, err := archive.Create(file.name)
+		if err != nil {
+			return models.Internal("failed to write anki package: %w", err)
+		}
+		if _, err := entry.Write(file.data); err != nil {
+			return models.Internal("failed to write anki package: %w", err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return models.Internal("failed to write anki package: %w", err)
+	}
+
+	return nil
+}
+
+func ankiDeckJSON(id int64, name, description string, mod int64) map[string]any {
+	return map[string]any{
+		"id": id, "name": name, "desc": description, "mod": mod, "usn": -1, "dyn": 0, "conf": 1,
+		"collapsed": false, "browserCollapsed": false, "extendNew": 10, "extendRev": 50,
+		"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+	}
+}
+
+// ankiModelJSON describes a front/back note type whose card shows the
+// front, then both sides on the answer
+func ankiModelJSON(deckID, mod int64) map[string]any {
+	field := func(name string, ord int) map[string]any {
+		return map[string]any{"name": name, "ord": ord, "sticky": false, "rtl": false, "font": "Arial", "size": 20, "media": []string{}}
+	}
+
+	return map[string]any{
+		"id": ANKI_MODEL_ID, "name": ANKI_MODEL_NAME, "type": 0, "mod": mod, "usn": -1, "sortf": 0,
+		"did": deckID, "tags": []string{}, "vers": []string{}, "req": []any{[]any{0, "any", []int{0}}},
+		"flds": []any{field("Front", 0), field("Back", 1)},
+		"tmpls": []any{map[string]any{
+			"name": "Card 1", "ord": 0, "did": nil, "bqfmt": "", "bafmt": "",
+			"qfmt": "{{Front}}",
+			"afmt": "{{FrontSide}}\n\n<hr id=answer>\n\n{{Back}}",
+		}},
+		// The same classes the HTML renderer emits for code and math
+		"css":       ".card {font-size: 20px; text-align: left; color: black; background-color: white;}\n" + RENDERED_HTML_STYLESHEET,
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+	}
+}
+
+// Anki's default study options
+func ankiDeckOptionsJSON() map[string]any {
+	return map[string]any{
+		"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true, "timer": 0,
+		"replayq": true, "dyn": false,
+		"new": map[string]any{
+			"delays": []float64{1, 10}, "ints": []int{1, 4, 7}, "initialFactor": 2500, "separate": true,
+			"order": 1, "perDay": 20, "bury": false,
+		},
+		"rev": map[string]any{
+			"perDay": 200, "ease4": 1.3, "fuzz": 0.05, "minSpace": 1, "ivlFct": 1, "maxIvl": 36500,
+			"bury": false, "hardFactor": 1.2,
+		},
+		"lapse": map[string]any{
+			"delays": []float64{10}, "mult": 0, "minInt": 1, "leechFails": 8, "leechAction": 1,
+		},
+	}
+}
+
+// ankiStripHTML reduces a field to the plain text Anki sorts and checks
+// duplicates by
+func ankiStripHTML(field string) string {
+	return strings.TrimSpace(html.UnescapeString(htmlTagPattern.ReplaceAllString(field, "")))
+}
+
+// ankiChecksum is the first 32 bits of the SHA-1 of the sort field, which
+// Anki uses to find duplicate notes
+func ankiChecksum(sortField string) int64 {
+	sum := sha1.Sum([]byte(sortField))
+	checksum, _ := strconv.ParseInt(hex.EncodeToString(sum[:4]), 16, 64)
+	return checksum
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAnkiPackageRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	decks := []ankiDeck{
+		{Name: "Biology", Cards: []ankiCard{
+			{GUID: "a", Front: "What is a cell?", Back: "The basic unit of life"},
+			{GUID: "b", Front: "Mitochondria", Back: "Powerhouse<br>of the cell"},
+		}},
+		{Name: "Biology::Genetics", Cards: []ankiCard{
+			{GUID: "c", Front: "DNA stands for", Back: "Deoxyribonucleic acid"},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := writeAnkiPackage(ctx, &buf, decks, time.Date(2025, 8, 20, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("writeAnkiPackage: %v", err)
+	}
+
+	rows, err := readAnkiPackage(ctx, buf.Bytes())
+	if err != nil {
+		t.Fatalf("readAnkiPackage: %v", err)
+	}
+
+	want := []deckImportRow{
+		{row: 1, deck: "Biology", front: "What is a cell?", back: "The basic unit of life"},
+		{row: 2, deck: "Biology", front: "Mitochondria", back: "Powerhouse\nof the cell"},
+		{row: 3, deck: "Biology::Genetics", front: "DNA stands for", back: "Deoxyribonucleic acid"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("read %d rows, want %d: %+v", len(rows), len(want), rows)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i+1, rows[i], want[i])
+		}
+	}
+}
+
+func TestReadAnkiPackageRejectsCorruptCollection(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	if err := writeAnkiPackage(ctx, &buf, []ankiDeck{{Name: "Deck", Cards: []ankiCard{{GUID: "a", Front: "Q", Back: "A"}}}}, time.Now()); err != nil {
+		t.Fatalf("writeAnkiPackage: %v", err)
+	}
+
+	if _, err := readAnkiPackage(ctx, buf.Bytes()[:buf.Len()/2]); err == nil {
+		t.Error("readAnkiPackage accepted a truncated package")
+	}
+	if _, err := readAnkiPackage(ctx, []byte("not a zip")); err == nil {
+		t.Error("readAnkiPackage accepted a file that is not a zip")
+	}
+}
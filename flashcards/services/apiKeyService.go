@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	// Keys are "fck_" and 32 random bytes, so they are recognizable in
+	// logs and secret scanners
+	API_KEY_PREFIX        = "fck_"
+	API_KEY_BYTES         = 32
+	API_KEY_PREFIX_CHARS  = 12
+	MAX_API_KEYS_PER_USER = 10
+	MAX_API_KEY_NAME      = 100
+
+	// Authenticated keys are remembered this long, so a revoked key can
+	// still be used on other instances for up to this long
+	API_KEY_CACHE_TTL          = 30 * time.Second
+	API_KEY_FLUSH_INTERVAL     = time.Minute
+	DEFAULT_API_KEY_USAGE_DAYS = 30
+	MAX_API_KEY_USAGE_DAYS     = 365
+)
+
+var ErrInvalidAPIKey = errors.New("invalid or revoked API key")
+
+var apiKeyScopes = []string{models.APIKeyScopeReadOnly, models.APIKeyScopeGenerateQuiz}
+
+type apiKeyUsageKey struct {
+	keyID int
+	day   time.Time
+}
+
+type cachedAPIKey struct {
+	key       *models.APIKey
+	expiresAt time.Time
+}
+
+// APIKeyService issues API keys and authenticates requests made with them.
+// Only a key's SHA-256 hash is stored. Usage is counted in memory and
+// flushed every API_KEY_FLUSH_INTERVAL, like request analytics, so
+// authenticating a cached key costs no query.
+type APIKeyService struct {
+	repo db.APIKeyRepository
+
+	mu         sync.Mutex
+	cache      map[string]*cachedAPIKey
+	pending    map[apiKeyUsageKey]int64
+	lastUsedAt map[int]time.Time
+}
+
+func NewAPIKeyService(repo db.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{
+		repo:       repo,
+		cache:      map[string]*cachedAPIKey{},
+		pending:    map[apiKeyUsageKey]int64{},
+		lastUsedAt: map[int]time.Time{},
+	}
+}
+
+// CreateAPIKey issues a key for the user. The returned key is the only
+// time its secret is shown.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, userID int, req *models.CreateAPIKeyRequest) (*models.APIKey, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, models.Invalid("name is required")
+	}
+	if len(name) > MAX_API_KEY_NAME {
+		return nil, models.Invalid("name must be at most %d characters", MAX_API_KEY_NAME)
+	}
+	if len(req.Scopes) == 0 {
+		return nil, models.Invalid("at least one scope is required")
+	}
+	var scopes []string
+	for _, scope := range req.Scopes {
+		if !slices.Contains(apiKeyScopes, scope) {
+			return nil, models.Invalid("unknown scope %q, must be one of %s", scope, strings.Join(apiKeyScopes, ", "))
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	count, err := s.repo.CountAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= MAX_API_KEYS_PER_USER {
+		return nil, models.Conflict("at most %d API keys can be active, revoke one first", MAX_API_KEYS_PER_USER)
+	}
+
+	secret := make([]byte, API_KEY_BYTES)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	raw := API_KEY_PREFIX + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := &models.APIKey{
+		UserID:  userID,
+		Name:    name,
+		Prefix:  raw[:API_KEY_PREFIX_CHARS],
+		KeyHash: hashAPIKey(raw),
+		Scopes:  scopes,
+	}
+	if err := s.repo.CreateAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+
+	key.Key = raw
+	return key, nil
+}
+
+func (s *APIKeyService) GetAPIKeys(ctx context.Context, userID int) ([]*models.APIKey, error) {
+	keys, err := s.repo.GetAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = []*models.APIKey{}
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey stops the key from authenticating. This instance forgets it
+// at once; others within API_KEY_CACHE_TTL.
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, userID, id int) error {
+	if err := s.repo.RevokeAPIKey(ctx, userID, id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, cached := range s.cache {
+		if cached.key.ID == id {
+			delete(s.cache, hash)
+		}
+	}
+	return nil
+}
+
+// GetUsage returns the key's requests per day over the last days days,
+// including any not flushed yet
+func (s *APIKeyService) GetUsage(ctx context.Context, userID, id, days int) ([]*models.APIKeyUsage, error) {
+	if days <= 0 {
+		days = DEFAULT_API_KEY_USAGE_DAYS
+	}
+	if days > MAX_API_KEY_USAGE_DAYS {
+		return nil, models.Invalid("days must be at most %d", MAX_API_KEY_USAGE_DAYS)
+	}
+
+	if _, err := s.repo.GetAPIKey(ctx, userID, id); err != nil {
+		return nil, err
+	}
+
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+	usage, err := s.repo.GetAPIKeyUsage(ctx, id, since)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, requests := range s.pending {
+		if key.keyID != id || key.day.Before(since) {
+			continue
+		}
+		i := slices.IndexFunc(usage, func(day *models.APIKeyUsage) bool { return day.Day.Equal(key.day) })
+		if i < 0 {
+			usage = append(usage, &models.APIKeyUsage{APIKeyID: id, Day: key.day, Requests: requests})
+			continue
+		}
+		usage[i].Requests += requests
+	}
+	slices.SortFunc(usage, func(a, b *models.APIKeyUsage) int { return a.Day.Compare(b.Day) })
+
+	return usage, nil
+}
+
+// Authenticate returns the active key matching raw, or ErrInvalidAPIKey
+func (s *APIKeyService) Authenticate(ctx context.Context, raw string) (*models.APIKey, error) {
+	if !strings.HasPrefix(raw, API_KEY_PREFIX) {
+		return nil, ErrInvalidAPIKey
+	}
+	hash := hashAPIKey(raw)
+	now := time.Now()
+
+	s.mu.Lock()
+	cached, ok := s.cache[hash]
+	s.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.key, nil
+	}
+
+	key, err := s.repo.GetAPIKeyByHash(ctx, hash)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[hash] = &cachedAPIKey{key: key, expiresAt: now.Add(API_KEY_CACHE_TTL)}
+	s.mu.Unlock()
+	return key, nil
+}
+
+// Record counts a request made with the key towards today's usage
+func (s *APIKeyService) Record(keyID int) {
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[apiKeyUsageKey{keyID: keyID, day: now.Truncate(24 * time.Hour)}]++
+	s.lastUsedAt[keyID] = now
+}
+
+// Flush writes the usage counted since the last flush and drops expired
+// keys from the cache. On failure the usage is merged back so the next
+// flush retries it.
+func (s *APIKeyService) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	pending, lastUsedAt := s.pending, s.lastUsedAt
+	s.pending, s.lastUsedAt = map[apiKeyUsageKey]int64{}, map[int]time.Time{}
+	for hash, cached := range s.cache {
+		if time.Now().After(cached.expiresAt) {
+			delete(s.cache, hash)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	usage := make([]*models.APIKeyUsage, 0, len(pending))
+	for key, requests := range pending {
+		usage = append(usage, &models.APIKeyUsage{APIKeyID: key.keyID, Day: key.day, Requests: requests})
+	}
+
+	if err := s.repo.SaveAPIKeyUsage(ctx, usage, lastUsedAt); err != nil {
+		s.restore(pending, lastUsedAt)
+		return err
+	}
+
+	LoggerFromContext(ctx).Debug("Flushed API key usage", "rows", len(usage))
+	return nil
+}
+
+func (s *APIKeyService) restore(pending map[apiKeyUsageKey]int64, lastUsedAt map[int]time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, requests := range pending {
+		s.pending[key] += requests
+	}
+	for id, at := range lastUsedAt {
+		if at.After(s.lastUsedAt[id]) {
+			s.lastUsedAt[id] = at
+		}
+	}
+}
+
+func hashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+	"flashcards/storage"
+)
+
+const (
+	MAX_ATTACHMENT_BYTES    = 5 * 1024 * 1024
+	MAX_ATTACHMENT_FILENAME = 255
+	// Pre-signed upload and download URLs stay valid this long
+	STORAGE_URL_EXPIRY = 15 * time.Minute
+	// Direct uploads not completed within this long are deleted, this many
+	// at a time
+	PENDING_UPLOAD_TTL   = 24 * time.Hour
+	PENDING_UPLOAD_BATCH = 100
+	// Blobs no attachment references any more are deleted after this long,
+	// this many at a time
+	RELEASED_BLOB_TTL   = time.Hour
+	RELEASED_BLOB_BATCH = 100
+)
+
+var allowedImageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/webp": true,
+	"image/gif":  true,
+}
+
+// AttachmentService keeps uploaded images. With object storage they are
+// stored there and can be uploaded and downloaded directly; without it
+// their data is kept in Postgres. Either way each distinct file is stored
+// once, as a blob addressed by its SHA-256 hash that every attachment of
+// it references.
+type AttachmentService struct {
+	repo  db.AttachmentRepository
+	store storage.Store
+}
+
+// NewAttachmentService takes the object store, or nil to keep attachments
+// in Postgres
+func NewAttachmentService(repo db.AttachmentRepository, store storage.Store) *AttachmentService {
+	return &AttachmentService{repo: repo, store: store}
+}
+
+// CreateImage stores an uploaded image. The content type is sniffed from
+// the data rather than trusted from the client.
+func (s *AttachmentService) CreateImage(ctx context.Context, userID int, filename string, data []byte) (*models.Attachment, error) {
+	if len(data) == 0 {
+		return nil, models.Invalid("image cannot be empty")
+	}
+	if len(data) > MAX_ATTACHMENT_BYTES {
+		return nil, models.Invalid("image cannot exceed %d bytes", MAX_ATTACHMENT_BYTES)
+	}
+
+	contentType := DetectImageType(data)
+	if contentType == "" {
+		return nil, models.Invalid("image must be a PNG, JPEG, WebP or GIF file")
+	}
+
+	attachment := &models.Attachment{
+		UserID:      userID,
+		Filename:    cleanFilename(filename),
+		ContentType: contentType,
+		Size:        len(data),
+		Status:      models.AttachmentStatusReady,
+		Data:        data,
+		ContentHash: contentHash(data),
+	}
+
+	// A file already stored is only referenced again. The data goes along
+	// regardless, so if the blob is deleted before the reference is added,
+	// it is created again in Postgres. A new file is put under a fresh key,
+	// deleted again if a concurrent upload of it created the blob first.
+	// The data is kept on the returned attachment either way, for the
+	// caller to use without reading it back.
+	if s.store != nil {
+		_, err := s.repo.GetBlob(ctx, attachment.ContentHash)
+		if err != nil && !errors.Is(err, models.ErrNotFound) {
+			return nil, err
+		}
+		if err != nil {
+			attachment.StorageKey = attachmentKey(userID)
+			if err := s.store.Put(ctx, attachment.StorageKey, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+				return nil, models.Internal("failed to store image: %w", err)
+			}
+		}
+	}
+
+	uploadKey := attachment.StorageKey
+	if err := s.repo.CreateAttachment(ctx, attachment); err != nil {
+		s.deleteObject(ctx, uploadKey)
+		return nil, err
+	}
+	if uploadKey != "" && attachment.StorageKey != uploadKey {
+		s.deleteObject(ctx, uploadKey)
+	}
+
+	return attachment, nil
+}
+
+// CreateUpload starts a direct upload of an image to object storage. The
+// attachment is pending until CompleteUpload checks the uploaded file.
+func (s *AttachmentService) CreateUpload(ctx context.Context, userID int, req *models.CreateAttachmentUploadRequest) (*models.AttachmentUpload, error) {
+	if s.store == nil {
+		return nil, models.Invalid("direct uploads need object storage, which is not configured")
+	}
+	if req == nil {
+		return nil, models.Invalid("request cannot be nil")
+	}
+	if !allowedImageTypes[req.ContentType] {
+		return nil, models.Invalid("contentType must be one of: image/png, image/jpeg, image/webp, image/gif")
+	}
+	if req.Size <= 0 || req.Size > MAX_ATTACHMENT_BYTES {
+		return nil, models.Invalid("size must be between 1 and %d bytes", MAX_ATTACHMENT_BYTES)
+	}
+
+	attachment := &models.Attachment{
+		UserID:      userID,
+		Filename:    cleanFilename(req.Filename),
+		ContentType: req.ContentType,
+		Size:        req.Size,
+		Status:      models.AttachmentStatusPending,
+		StorageKey:  attachmentKey(userID),
+	}
+
+	uploadURL, err := s.store.PresignPut(attachment.StorageKey, req.ContentType, int64(req.Size), STORAGE_URL_EXPIRY)
+	if err != nil {
+		return nil, models.Internal("failed to sign upload URL: %w", err)
+	}
+	if err := s.repo.CreateAttachment(ctx, attachment); err != nil {
+		return nil, err
+	}
+
+	return &models.AttachmentUpload{
+		Attachment: attachment,
+		UploadURL:  uploadURL,
+		Method:     http.MethodPut,
+		Headers:    map[string]string{"Content-Type": req.ContentType},
+		ExpiresAt:  time.Now().Add(STORAGE_URL_EXPIRY),
+	}, nil
+}
+
+// CompleteUpload checks a directly uploaded file and marks the attachment
+// ready. A file that is too large or not a supported image is deleted
+// along with its attachment.
+func (s *AttachmentService) CompleteUpload(ctx context.Context, userID, id int) (*models.Attachment, error) {
+	attachment, err := s.GetAttachment(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if attachment.Status != models.AttachmentStatusPending || s.store == nil {
+		return nil, models.Conflict("attachment with id %d is not pending", id)
+	}
+
+	object, err := s.store.Stat(ctx, attachment.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, models.Invalid("the file has not been uploaded yet")
+	}
+	if err != nil {
+		return nil, models.Internal("failed to check upload: %w", err)
+	}
+	if object.Size > MAX_ATTACHMENT_BYTES {
+		s.discard(ctx, attachment)
+		return nil, models.Invalid("the upload must be a PNG, JPEG, WebP or GIF file of at most %d bytes", MAX_ATTACHMENT_BYTES)
+	}
+
+	data, err := s.readUpload(ctx, attachment.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	contentType := DetectImageType(data)
+	if object.Size == 0 || len(data) > MAX_ATTACHMENT_BYTES || contentType == "" {
+		s.discard(ctx, attachment)
+		return nil, models.Invalid("the upload must be a PNG, JPEG, WebP or GIF file of at most %d bytes", MAX_ATTACHMENT_BYTES)
+	}
+
+	// The upload becomes the blob for its content, or is deleted when the
+	// same file is already stored
+	uploadKey := attachment.StorageKey
+	attachment.ContentType = contentType
+	attachment.Size = len(data)
+	attachment.ContentHash = contentHash(data)
+	if err := s.repo.CompleteAttachment(ctx, attachment); err != nil {
+		return nil, err
+	}
+	deduplicated := attachment.StorageKey != uploadKey
+	if deduplicated {
+		s.deleteObject(ctx, uploadKey)
+	}
+
+	LoggerFromContext(ctx).Info("Completed attachment upload", "attachment_id", id, "size", attachment.Size, "deduplicated", deduplicated)
+	return attachment, nil
+}
+
+func (s *AttachmentService) GetAttachment(ctx context.Context, userID, id int) (*models.Attachment, error) {
+	if id <= 0 {
+		return nil, models.Invalid("invalid attachment ID: %d", id)
+	}
+
+	return s.repo.GetAttachment(ctx, userID, id)
+}
+
+// DeleteAttachment deletes one of the user's attachments. Its file is
+// deleted once no other attachment shares it.
+func (s *AttachmentService) DeleteAttachment(ctx context.Context, userID, id int) error {
+	attachment, err := s.GetAttachment(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteAttachment(ctx, userID, id); err != nil {
+		return err
+	}
+	// Pending uploads and files stored before deduplication have an
+	// object of their own
+	if attachment.ContentHash == "" {
+		s.deleteObject(ctx, attachment.StorageKey)
+	}
+
+	return nil
+}
+
+// DownloadURL returns a pre-signed URL for an attachment kept in object
+// storage
+func (s *AttachmentService) DownloadURL(attachment *models.Attachment) (string, error) {
+	if s.store == nil || attachment.StorageKey == "" {
+		return "", fmt.Errorf("attachment %d is not in object storage", attachment.ID)
+	}
+	return s.store.PresignGet(attachment.StorageKey, attachment.Filename, STORAGE_URL_EXPIRY)
+}
+
+// DeletePendingUploads deletes direct uploads that were never completed,
+// and their files if any were uploaded
+func (s *AttachmentService) DeletePendingUploads(ctx context.Context) error {
+	if s.store == nil {
+		return nil
+	}
+
+	attachments, err := s.repo.GetPendingAttachments(ctx, time.Now().Add(-PENDING_UPLOAD_TTL), PENDING_UPLOAD_BATCH)
+	if err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		s.discard(ctx, attachment)
+	}
+
+	if len(attachments) > 0 {
+		LoggerFromContext(ctx).Info("Deleted pending attachment uploads", "count", len(attachments))
+	}
+	return nil
+}
+
+// DeleteReleasedBlobs deletes the files no attachment has referenced for
+// RELEASED_BLOB_TTL
+func (s *AttachmentService) DeleteReleasedBlobs(ctx context.Context) error {
+	blobs, err := s.repo.DeleteReleasedBlobs(ctx, time.Now().Add(-RELEASED_BLOB_TTL), RELEASED_BLOB_BATCH)
+	if err != nil {
+		return err
+	}
+	for _, blob := range blobs {
+		s.deleteObject(ctx, blob.StorageKey)
+	}
+
+	if len(blobs) > 0 {
+		LoggerFromContext(ctx).Info("Deleted unreferenced attachment files", "count", len(blobs))
+	}
+	return nil
+}
+
+// readUpload reads a directly uploaded file, up to one byte past
+// MAX_ATTACHMENT_BYTES so an oversized one can be told apart
+func (s *AttachmentService) readUpload(ctx context.Context, key string) ([]byte, error) {
+	body, err := s.store.Open(ctx, key)
+	if err != nil {
+		return nil, models.Internal("failed to read upload: %w", err)
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, MAX_ATTACHMENT_BYTES+1))
+	if err != nil {
+		return nil, models.Internal("failed to read upload: %w", err)
+	}
+	return data, nil
+}
+
+// discard deletes a pending attachment and its upload
+func (s *AttachmentService) discard(ctx context.Context, attachment *models.Attachment) {
+	s.deleteObject(ctx, attachment.StorageKey)
+	if err := s.repo.DeleteAttachment(ctx, attachment.UserID, attachment.ID); err != nil && !errors.Is(err, models.ErrNotFound) {
+		LoggerFromContext(ctx).Error("Failed to delete attachment", "attachment_id", attachment.ID, "error", err)
+	}
+}
+
+func (s *AttachmentService) deleteObject(ctx context.Context, key string) {
+	if s.store == nil || key == "" {
+		return
+	}
+	if err := s.store.Delete(ctx, key); err != nil {
+		LoggerFromContext(ctx).Error("Failed to delete attachment file", "key", key, "error", err)
+	}
+}
+
+// attachmentKey returns a fresh object key for one of the user's
+// attachments
+func attachmentKey(userID int) string {
+	return storage.NewKey("attachments/"+strconv.Itoa(userID), "image")
+}
+
+// contentHash returns the hex SHA-256 hash blobs are addressed by
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// cleanFilename keeps the base name of an uploaded file, cut to
+// MAX_ATTACHMENT_FILENAME
+func cleanFilename(filename string) string {
+	filename = filepath.Base(strings.TrimSpace(filename))
+	if filename == "." || filename == string(filepath.Separator) {
+		filename = ""
+	}
+	if len(filename) > MAX_ATTACHMENT_FILENAME {
+		filename = filename[:MAX_ATTACHMENT_FILENAME]
+	}
+	return filename
+}
+
+// DetectImageType returns the MIME type of a supported image, or an empty
+// string when the data is not one
+func DetectImageType(data []byte) string {
+	contentType := http.DetectContentType(data)
+	if !allowedImageTypes[contentType] {
+		return ""
+	}
+	return contentType
+}
@@ -0,0 +1,453 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	MIN_PASSWORD_LENGTH = 8
+	MAX_PASSWORD_LENGTH = 128
+	PASSWORD_ITERATIONS = 210000
+	PASSWORD_SALT_BYTES = 16
+	PASSWORD_KEY_BYTES  = 32
+
+	// Sessions opened from a review link last at most this long, whatever
+	// the token TTL, since the link travelled by email
+	REVIEW_LINK_SESSION_TTL = time.Hour
+	MAX_REVIEW_LINK_CARDS   = 100
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+)
+
+// Only HS256 tokens are issued and accepted
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+type tokenClaims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Review link tokens are "<payload>.<signature>", two parts rather than a
+// JWT's three, and signed under their own prefix, so one can never pass as
+// a bearer token
+const reviewLinkSigningPrefix = "review-link."
+
+type reviewLinkClaims struct {
+	Subject      string `json:"sub"`
+	FlashcardIDs []int  `json:"cards"`
+	ExpiresAt    int64  `json:"exp"`
+}
+
+// Live quiz resume tokens take the same two-part form under their own prefix
+const liveQuizSigningPrefix = "live-quiz."
+
+type liveQuizClaims struct {
+	Subject   string `json:"sub"`
+	SessionID int    `json:"session"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Daily question tokens also take the two-part form, under their own prefix
+const dailyQuestionSigningPrefix = "daily-question."
+
+type dailyQuestionClaims struct {
+	Subject   string `json:"sub"`
+	SessionID int    `json:"session"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type AuthService struct {
+	repo     db.UserRepository
+	secret   []byte
+	tokenTTL time.Duration
+}
+
+func NewAuthService(repo db.UserRepository, secret string, tokenTTL time.Duration) *AuthService {
+	return &AuthService{
+		repo:     repo,
+		secret:   []byte(secret),
+		tokenTTL: tokenTTL,
+	}
+}
+
+func (s *AuthService) Register(ctx context.Context, req *models.RegisterRequest) (*models.AuthResponse, error) {
+	if err := s.validateRegisterRequest(req); err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := hashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Email:        normalizeEmail(req.Email),
+		PasswordHash: passwordHash,
+	}
+
+	if err := s.repo.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return s.issueToken(user)
+}
+
+func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*models.AuthResponse, error) {
+	if req == nil || strings.TrimSpace(req.Email) == "" || req.Password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, normalizeEmail(req.Email))
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if !verifyPassword(req.Password, user.PasswordHash) {
+		return nil, ErrInvalidCredentials
+	}
+
+	return s.issueToken(user)
+}
+
+func (s *AuthService) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	return s.repo.GetUserByID(ctx, id)
+}
+
+// IsAdmin reports whether the user may use the admin endpoints. It reads
+// the user on every call, so a revoked admin loses access at once rather
+// than when their token expires.
+func (s *AuthService) IsAdmin(ctx context.Context, userID int) (bool, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if errors.Is(err, models.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return user.IsAdmin, nil
+}
+
+// SetAdmin grants or revokes the admin role of the user with email
+func (s *AuthService) SetAdmin(ctx context.Context, email string, isAdmin bool) (*models.User, error) {
+	user, err := s.repo.GetUserByEmail(ctx, normalizeEmail(email))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetUserAdmin(ctx, user.ID, isAdmin); err != nil {
+		return nil, err
+	}
+	user.IsAdmin = isAdmin
+	return user, nil
+}
+
+// Authenticate validates a token and returns the ID of the user it was
+// issued to
+func (s *AuthService) Authenticate(token string) (int, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return 0, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, s.sign(parts[0]+"."+parts[1])) {
+		return 0, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return 0, ErrInvalidToken
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return 0, ErrInvalidToken
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil || userID <= 0 {
+		return 0, ErrInvalidToken
+	}
+
+	return userID, nil
+}
+
+// SignReviewLink returns a token for a one-click link that opens a review
+// of the given cards as the user. The token can be used until it expires.
+func (s *AuthService) SignReviewLink(userID int, flashcardIDs []int, ttl time.Duration) (string, error) {
+	token, err := s.signClaims(reviewLinkSigningPrefix, reviewLinkClaims{
+		Subject:      strconv.Itoa(userID),
+		FlashcardIDs: flashcardIDs,
+		ExpiresAt:    time.Now().Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", models.Internal("failed to encode review link: %w", err)
+	}
+	return token, nil
+}
+
+// RedeemReviewLink validates a review link token and signs its user in for
+// up to REVIEW_LINK_SESSION_TTL, returning the cards the link is for
+func (s *AuthService) RedeemReviewLink(ctx context.Context, token string) (*models.AuthResponse, []int, error) {
+	var claims reviewLinkClaims
+	if err := s.verifyClaims(reviewLinkSigningPrefix, token, &claims); err != nil {
+		return nil, nil, err
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt || len(claims.FlashcardIDs) == 0 || len(claims.FlashcardIDs) > MAX_REVIEW_LINK_CARDS {
+		return nil, nil, ErrInvalidToken
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil || userID <= 0 {
+		return nil, nil, ErrInvalidToken
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		// The account was deleted after the link was sent
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, nil, ErrInvalidToken
+		}
+		return nil, nil, err
+	}
+
+	auth, err := s.issueTokenWithTTL(user, min(s.tokenTTL, REVIEW_LINK_SESSION_TTL))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return auth, claims.FlashcardIDs, nil
+}
+
+// SignLiveQuizResume returns a token that lets a dropped live quiz
+// connection rejoin the session as the user until it expires
+func (s *AuthService) SignLiveQuizResume(userID, sessionID int, ttl time.Duration) (string, error) {
+	token, err := s.signClaims(liveQuizSigningPrefix, liveQuizClaims{
+		Subject:   strconv.Itoa(userID),
+		SessionID: sessionID,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", models.Internal("failed to encode live quiz resume token: %w", err)
+	}
+	return token, nil
+}
+
+// VerifyLiveQuizResume validates a live quiz resume token and returns the
+// user and session it is for
+func (s *AuthService) VerifyLiveQuizResume(token string) (int, int, error) {
+	var claims liveQuizClaims
+	if err := s.verifyClaims(liveQuizSigningPrefix, token, &claims); err != nil {
+		return 0, 0, err
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt || claims.SessionID <= 0 {
+		return 0, 0, ErrInvalidToken
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil || userID <= 0 {
+		return 0, 0, ErrInvalidToken
+	}
+
+	return userID, claims.SessionID, nil
+}
+
+// SignDailyQuestion returns a token that answers the one-question session
+// sent in a daily question email as the user until it expires
+func (s *AuthService) SignDailyQuestion(userID, sessionID int, ttl time.Duration) (string, error) {
+	token, err := s.signClaims(dailyQuestionSigningPrefix, dailyQuestionClaims{
+		Subject:   strconv.Itoa(userID),
+		SessionID: sessionID,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", models.Internal("failed to encode daily question token: %w", err)
+	}
+	return token, nil
+}
+
+// VerifyDailyQuestion validates a daily question token and returns the user
+// and session it is for
+func (s *AuthService) VerifyDailyQuestion(token string) (int, int, error) {
+	var claims dailyQuestionClaims
+	if err := s.verifyClaims(dailyQuestionSigningPrefix, token, &claims); err != nil {
+		return 0, 0, err
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt || claims.SessionID <= 0 {
+		return 0, 0, ErrInvalidToken
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil || userID <= 0 {
+		return 0, 0, ErrInvalidToken
+	}
+
+	return userID, claims.SessionID, nil
+}
+
+func (s *AuthService) issueToken(user *models.User) (*models.AuthResponse, error) {
+	return s.issueTokenWithTTL(user, s.tokenTTL)
+}
+
+func (s *AuthService) issueTokenWithTTL(user *models.User, ttl time.Duration) (*models.AuthResponse, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	payload, err := json.Marshal(tokenClaims{
+		Subject:   strconv.Itoa(user.ID),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, models.Internal("failed to encode token: %w", err)
+	}
+
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	token := unsigned + "." + base64.RawURLEncoding.EncodeToString(s.sign(unsigned))
+
+	return &models.AuthResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User:      user,
+	}, nil
+}
+
+func (s *AuthService) sign(data string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// signClaims encodes claims as a "<payload>.<signature>" token signed under
+// prefix
+func (s *AuthService) signClaims(prefix string, claims any) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.sign(prefix+encoded)), nil
+}
+
+// verifyClaims checks a token from signClaims against prefix and decodes
+// its claims. Expiry is left to the caller.
+func (s *AuthService) verifyClaims(prefix, token string, claims any) error {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalidToken
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(decoded, s.sign(prefix+encoded)) {
+		return ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+func (s *AuthService) validateRegisterRequest(req *models.RegisterRequest) error {
+	if req == nil {
+		return models.Invalid("request cannot be nil")
+	}
+
+	if !isValidEmail(normalizeEmail(req.Email)) {
+		return models.Invalid("a valid email address is required")
+	}
+
+	if len(req.Password) < MIN_PASSWORD_LENGTH {
+		return models.Invalid("password must be at least %d characters", MIN_PASSWORD_LENGTH)
+	}
+
+	if len(req.Password) > MAX_PASSWORD_LENGTH {
+		return models.Invalid("password cannot exceed %d characters", MAX_PASSWORD_LENGTH)
+	}
+
+	return nil
+}
+
+// Emails are matched case-insensitively
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Passwords are stored as "pbkdf2-sha256$<iterations>$<salt>$<key>"
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, PASSWORD_SALT_BYTES)
+	if _, err := rand.Read(salt); err != nil {
+		return "", models.Internal("failed to generate salt: %w", err)
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, PASSWORD_ITERATIONS, PASSWORD_KEY_BYTES)
+	if err != nil {
+		return "", models.Internal("failed to hash password: %w", err)
+	}
+
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s",
+		PASSWORD_ITERATIONS,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func verifyPassword(password, encoded string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+
+	expected, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(expected))
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(key, expected) == 1
+}
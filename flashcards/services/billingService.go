@@ -0,0 +1,397 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	STRIPE_API_BASE_URL        = "https://api.stripe.com/v1"
+	STRIPE_REQUEST_TIMEOUT     = 30 * time.Second
+	STRIPE_SIGNATURE_TOLERANCE = 5 * time.Minute
+	MAX_STRIPE_RESPONSE_BYTES  = 1024 * 1024
+)
+
+// StripeConfig holds billing settings for hosted deployments. An empty
+// SecretKey disables billing; Prices maps each paid plan to its Stripe
+// price ID.
+type StripeConfig struct {
+	SecretKey     string
+	WebhookSecret string
+	Prices        map[string]string
+	SuccessURL    string
+	CancelURL     string
+}
+
+// BillingService sells plan tiers through Stripe Checkout and keeps each
+// organization's plan in step with its subscription
+type BillingService struct {
+	repo        db.OrganizationRepository
+	cfg         StripeConfig
+	httpClient  *http.Client
+	deadLetters *DeadLetterService
+}
+
+func NewBillingService(repo db.OrganizationRepository, cfg StripeConfig, deadLetters *DeadLetterService) *BillingService {
+	if cfg.SecretKey == "" {
+		slog.Info("Stripe secret key not configured, billing disabled")
+	}
+	return &BillingService{
+		repo:        repo,
+		cfg:         cfg,
+		httpClient:  &http.Client{Timeout: STRIPE_REQUEST_TIMEOUT},
+		deadLetters: deadLetters,
+	}
+}
+
+func (s *BillingService) Enabled() bool {
+	return s != nil && s.cfg.SecretKey != ""
+}
+
+// CreateCheckoutSession starts a Stripe Checkout for the user's
+// organization to subscribe to a paid plan
+func (s *BillingService) CreateCheckoutSession(ctx context.Context, userID int, req *models.CreateCheckoutRequest) (*models.CheckoutSession, error) {
+	if !s.Enabled() {
+		return nil, models.Invalid("billing is not configured")
+	}
+
+	price, ok := s.cfg.Prices[req.Plan]
+	if !ok || price == "" {
+		return nil, models.Invalid("plan must be one of: %s", strings.Join(s.paidPlans(), ", "))
+	}
+
+	organization, err := s.repo.GetUserOrganization(ctx, userID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.Invalid("you must belong to an organization to subscribe")
+		}
+		return nil, err
+	}
+
+	organizationID := strconv.Itoa(organization.ID)
+	form := url.Values{}
+	form.Set("mode", "subscription")
+	form.Set("line_items[0][price]", price)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("success_url", s.cfg.SuccessURL)
+	form.Set("cancel_url", s.cfg.CancelURL)
+	form.Set("client_reference_id", organizationID)
+	form.Set("subscription_data[metadata][organization_id]", organizationID)
+	if organization.StripeCustomerID != nil {
+		form.Set("customer", *organization.StripeCustomerID)
+	}
+
+	session := &models.CheckoutSession{}
+	if err := s.post(ctx, "/checkout/sessions", form, session); err != nil {
+		return nil, err
+	}
+
+	LoggerFromContext(ctx).Info("Created checkout session", "organization_id", organization.ID, "plan", req.Plan)
+	return session, nil
+}
+
+// stripeEvent is a Stripe event; Created is in Unix seconds
+type stripeEvent struct {
+	ID      string `json:"id"`
+	Created int64  `json:"created"`
+	Type    string `json:"type"`
+	Data    struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+type stripeCheckoutSession struct {
+	ClientReferenceID string `json:"client_reference_id"`
+	Customer          string `json:"customer"`
+	Subscription      string `json:"subscription"`
+}
+
+type stripeSubscription struct {
+	ID       string            `json:"id"`
+	Customer string            `json:"customer"`
+	Status   string            `json:"status"`
+	Metadata map[string]string `json:"metadata"`
+	Items    struct {
+		Data []struct {
+			Price struct {
+				ID string `json:"id"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// HandleWebhook verifies a Stripe event and applies it. Events for
+// organizations that no longer exist, event types that do not affect plans,
+// events already applied and events older than the last one applied to the
+// organization are acknowledged and ignored. An event that fails to apply
+// is queued for retry and acknowledged, unless it cannot be queued either.
+func (s *BillingService) HandleWebhook(ctx context.Context, payload []byte, signature string) error {
+	if !s.Enabled() || s.cfg.WebhookSecret == "" {
+		return models.Invalid("billing is not configured")
+	}
+	if err := verifyStripeSignature(payload, signature, s.cfg.WebhookSecret, time.Now()); err != nil {
+		return err
+	}
+
+	var event stripeEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return models.Invalid("invalid event payload")
+	}
+
+	err := s.applyEvent(ctx, &event)
+	if errors.Is(err, models.ErrInternal) {
+		if recordErr := s.deadLetters.Record(ctx, models.DeadLetterKindStripeWebhook, event.ID, nil, json.RawMessage(payload), err); recordErr != nil {
+			LoggerFromContext(ctx).Error("Failed to queue Stripe event for retry", "event_id", event.ID, "error", recordErr)
+			return err
+		}
+		return nil
+	}
+	return err
+}
+
+// RetryWebhook applies a queued Stripe event again. Its signature was
+// verified when it was received.
+func (s *BillingService) RetryWebhook(ctx context.Context, payload json.RawMessage) error {
+	var event stripeEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return models.Invalid("invalid event payload")
+	}
+
+	return s.applyEvent(ctx, &event)
+}
+
+func (s *BillingService) applyEvent(ctx context.Context, event *stripeEvent) error {
+	logger := LoggerFromContext(ctx).With("event_id", event.ID, "event_type", event.Type)
+
+	switch event.Type {
+	case "checkout.session.completed", "customer.subscription.created", "customer.subscription.updated", "customer.subscription.deleted":
+	default:
+		logger.Debug("Ignoring Stripe event")
+		return nil
+	}
+
+	applied, err := s.repo.HasStripeEvent(ctx, event.ID)
+	if err != nil {
+		return err
+	}
+	if applied {
+		logger.Info("Ignoring Stripe event that was already applied")
+		return nil
+	}
+
+	createdAt := time.Unix(event.Created, 0).UTC()
+	if event.Type == "checkout.session.completed" {
+		var session stripeCheckoutSession
+		if err := json.Unmarshal(event.Data.Object, &session); err != nil {
+			return models.Invalid("invalid checkout session")
+		}
+		err = s.linkCustomer(ctx, &session, createdAt)
+	} else {
+		var subscription stripeSubscription
+		if err := json.Unmarshal(event.Data.Object, &subscription); err != nil {
+			return models.Invalid("invalid subscription")
+		}
+		err = s.syncSubscription(ctx, &subscription, event.Type == "customer.subscription.deleted", createdAt)
+	}
+
+	if errors.Is(err, models.ErrNotFound) {
+		logger.Warn("Stripe event does not match an organization", "error", err)
+	} else if errors.Is(err, errStaleStripeEvent) {
+		logger.Info("Ignoring Stripe event older than the last one applied to the organization")
+	} else if err != nil {
+		return err
+	} else {
+		logger.Info("Applied Stripe event")
+	}
+
+	return s.repo.RecordStripeEvent(ctx, event.ID)
+}
+
+// errStaleStripeEvent is returned for an event created before the last one
+// applied to its organization. Stripe does not deliver events in order, so
+// applying it could undo a newer change.
+var errStaleStripeEvent = errors.New("stripe event is older than the last one applied")
+
+// Records the Stripe customer and subscription created by a completed
+// checkout on the organization that started it
+func (s *BillingService) linkCustomer(ctx context.Context, session *stripeCheckoutSession, createdAt time.Time) error {
+	organizationID, err := strconv.Atoi(session.ClientReferenceID)
+	if err != nil {
+		return models.NotFound("organization %q not found", session.ClientReferenceID)
+	}
+
+	organization, err := s.repo.GetOrganization(ctx, organizationID)
+	if err != nil {
+		return err
+	}
+	if err := markStripeEvent(organization, createdAt); err != nil {
+		return err
+	}
+
+	organization.StripeCustomerID = &session.Customer
+	if session.Subscription != "" {
+		organization.StripeSubscriptionID = &session.Subscription
+	}
+	return s.repo.UpdateOrganizationBilling(ctx, organization)
+}
+
+// Moves the organization to the plan its subscription pays for. The
+// subscription can arrive before the checkout that created it, so the
+// organization is found from the subscription's metadata first.
+func (s *BillingService) syncSubscription(ctx context.Context, subscription *stripeSubscription, deleted bool, createdAt time.Time) error {
+	var organization *models.Organization
+	var err error
+	if id, convErr := strconv.Atoi(subscription.Metadata["organization_id"]); convErr == nil {
+		organization, err = s.repo.GetOrganization(ctx, id)
+	} else {
+		organization, err = s.repo.GetOrganizationByStripeCustomer(ctx, subscription.Customer)
+	}
+	if err != nil {
+		return err
+	}
+	if err := markStripeEvent(organization, createdAt); err != nil {
+		return err
+	}
+
+	status := subscription.Status
+	if deleted {
+		status = "canceled"
+	}
+
+	organization.Plan = s.planForSubscription(status, subscription)
+	organization.StripeCustomerID = &subscription.Customer
+	organization.StripeSubscriptionID = &subscription.ID
+	organization.SubscriptionStatus = &status
+	return s.repo.UpdateOrganizationBilling(ctx, organization)
+}
+
+// markStripeEvent moves the organization's last applied event to one
+// created at createdAt, or returns errStaleStripeEvent when a newer one was
+// applied already. Events created in the same second are applied in the
+// order they arrive.
+func markStripeEvent(organization *models.Organization, createdAt time.Time) error {
+	if organization.StripeEventAt != nil && createdAt.Before(*organization.StripeEventAt) {
+		return errStaleStripeEvent
+	}
+	organization.StripeEventAt = &createdAt
+	return nil
+}
+
+// Active, trialing and past-due subscriptions keep the plan they pay for;
+// past-due organizations are held off LLM features by the quota check
+// until payment succeeds. Anything else drops to the free plan. The plan is
+// read from the first item with a known price, as a subscription can also
+// carry add-ons and metered prices.
+func (s *BillingService) planForSubscription(status string, subscription *stripeSubscription) string {
+	switch status {
+	case models.SubscriptionActive, models.SubscriptionTrialing, models.SubscriptionPastDue:
+		for _, item := range subscription.Items.Data {
+			for plan, price := range s.cfg.Prices {
+				if price != "" && price == item.Price.ID {
+					return plan
+				}
+			}
+		}
+	}
+	return models.PlanFree
+}
+
+func (s *BillingService) paidPlans() []string {
+	plans := []string{}
+	for _, plan := range validPlans {
+		if s.cfg.Prices[plan] != "" {
+			plans = append(plans, plan)
+		}
+	}
+	return plans
+}
+
+func (s *BillingService) post(ctx context.Context, path string, form url.Values, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, STRIPE_API_BASE_URL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return models.Internal("failed to create stripe request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.cfg.SecretKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return models.Upstream("failed to call stripe: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MAX_STRIPE_RESPONSE_BYTES))
+	if err != nil {
+		return models.Upstream("failed to read stripe response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var stripeErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(body, &stripeErr)
+		LoggerFromContext(ctx).Error("Stripe request returned an error status", "path", path, "status", resp.StatusCode, "error", stripeErr.Error.Message)
+		return models.Upstream("failed to call stripe: status %d", resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(body, result); err != nil {
+		return models.Upstream("failed to decode stripe response: %w", err)
+	}
+	return nil
+}
+
+// verifyStripeSignature checks the Stripe-Signature header, "t=<unix>,v1=<hex
+// hmac>", against the payload and rejects events older than the tolerance
+func verifyStripeSignature(payload []byte, header, secret string, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return models.Invalid("invalid signature header")
+	}
+	if now.Sub(time.Unix(seconds, 0)).Abs() > STRIPE_SIGNATURE_TOLERANCE {
+		return models.Invalid("signature timestamp is outside the tolerance")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+
+	for _, signature := range signatures {
+		decoded, err := hex.DecodeString(signature)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return models.Invalid("signature does not match")
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const TEST_WEBHOOK_SECRET = "whsec_test"
+
+// newTestBillingService builds a BillingService on in-memory repositories
+// selling the pro plan, and an organization to bill
+func newTestBillingService(t *testing.T) (*BillingService, db.OrganizationRepository, *models.Organization) {
+	t.Helper()
+	store := db.NewMemoryStore(nil)
+	organizations := db.NewMemoryOrganizationRepository(store)
+	service := NewBillingService(organizations, StripeConfig{
+		SecretKey:     "sk_test",
+		WebhookSecret: TEST_WEBHOOK_SECRET,
+		Prices:        map[string]string{models.PlanPro: "price_pro"},
+	}, NewDeadLetterService(db.NewMemoryDeadLetterRepository(store)))
+
+	organization := &models.Organization{Name: "Lab", Plan: models.PlanFree}
+	if err := organizations.CreateOrganization(context.Background(), organization); err != nil {
+		t.Fatalf("CreateOrganization: %v", err)
+	}
+	return service, organizations, organization
+}
+
+// testStripeEvent is a subscription event: its ID, when it was created, the
+// subscription's status and the prices of its items
+type testStripeEvent struct {
+	id      string
+	created int64
+	status  string
+	prices  []string
+}
+
+// sendSubscriptionEvent delivers a signed customer.subscription.updated
+// event for the organization
+func sendSubscriptionEvent(t *testing.T, service *BillingService, organizationID int, event testStripeEvent) {
+	t.Helper()
+	items := ""
+	for i, price := range event.prices {
+		if i > 0 {
+			items += ","
+		}
+		items += fmt.Sprintf(`{"price":{"id":%q}}`, price)
+	}
+	payload := fmt.Sprintf(`{"id":%q,"created":%d,"type":"customer.subscription.updated","data":{"object":`+
+		`{"id":"sub_1","customer":"cus_1","status":%q,"metadata":{"organization_id":"%d"},"items":{"data":[%s]}}}}`,
+		event.id, event.created, event.status, organizationID, items)
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(TEST_WEBHOOK_SECRET))
+	mac.Write([]byte(timestamp + "." + payload))
+	signature := "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+
+	if err := service.HandleWebhook(context.Background(), []byte(payload), signature); err != nil {
+		t.Fatalf("HandleWebhook(%s): %v", event.id, err)
+	}
+}
+
+func TestBillingWebhookPlans(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// In delivery order
+		events []testStripeEvent
+		want   string
+	}{
+		{
+			name: "plan from the item with a known price",
+			events: []testStripeEvent{
+				{"evt_1", 100, models.SubscriptionActive, []string{"price_addon", "price_pro"}},
+			},
+			want: models.PlanPro,
+		},
+		{
+			name: "no known price",
+			events: []testStripeEvent{
+				{"evt_1", 100, models.SubscriptionActive, []string{"price_addon"}},
+			},
+			want: models.PlanFree,
+		},
+		{
+			name: "redelivered event is skipped",
+			events: []testStripeEvent{
+				{"evt_1", 100, models.SubscriptionActive, []string{"price_pro"}},
+				{"evt_2", 200, "canceled", []string{"price_pro"}},
+				{"evt_1", 100, models.SubscriptionActive, []string{"price_pro"}},
+			},
+			want: models.PlanFree,
+		},
+		{
+			name: "older event is skipped",
+			events: []testStripeEvent{
+				{"evt_2", 200, models.SubscriptionActive, []string{"price_pro"}},
+				{"evt_1", 100, "canceled", []string{"price_pro"}},
+			},
+			want: models.PlanPro,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, organizations, organization := newTestBillingService(t)
+			for _, event := range test.events {
+				sendSubscriptionEvent(t, service, organization.ID, event)
+			}
+
+			got, err := organizations.GetOrganization(ctx, organization.ID)
+			if err != nil {
+				t.Fatalf("GetOrganization: %v", err)
+			}
+			if got.Plan != test.want {
+				t.Errorf("plan = %q, want %q", got.Plan, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	MAX_BRANDING_NAME_LENGTH = 100
+	MAX_BRANDING_URL_LENGTH  = 2048
+)
+
+// Colors are hex, as in CSS, so every client platform can parse them
+var brandingColorPattern = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// BrandingService holds the name, logo, colors and support contact of the
+// deployment, which admins set and clients theme themselves with
+type BrandingService struct {
+	repo db.BrandingRepository
+}
+
+func NewBrandingService(repo db.BrandingRepository) *BrandingService {
+	return &BrandingService{repo: repo}
+}
+
+func (s *BrandingService) GetBranding(ctx context.Context) (*models.Branding, error) {
+	branding, err := s.repo.GetBranding(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get branding: %w", err)
+	}
+
+	return branding, nil
+}
+
+// UpdateBranding changes the fields set in the request. Empty strings clear
+// the logo, support email and support URL.
+func (s *BrandingService) UpdateBranding(ctx context.Context, req *models.UpdateBrandingRequest) (*models.Branding, error) {
+	branding, err := s.GetBranding(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		branding.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.LogoURL != nil {
+		branding.LogoURL = strings.TrimSpace(*req.LogoURL)
+	}
+	if req.PrimaryColor != nil {
+		branding.PrimaryColor = strings.ToLower(strings.TrimSpace(*req.PrimaryColor))
+	}
+	if req.AccentColor != nil {
+		branding.AccentColor = strings.ToLower(strings.TrimSpace(*req.AccentColor))
+	}
+	if req.SupportEmail != nil {
+		branding.SupportEmail = normalizeEmail(*req.SupportEmail)
+	}
+	if req.SupportURL != nil {
+		branding.SupportURL = strings.TrimSpace(*req.SupportURL)
+	}
+
+	if err := validateBranding(branding); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SaveBranding(ctx, branding); err != nil {
+		return nil, err
+	}
+
+	return branding, nil
+}
+
+func validateBranding(branding *models.Branding) error {
+	if branding.Name == "" {
+		return models.Invalid("name is required")
+	}
+	if len(branding.Name) > MAX_BRANDING_NAME_LENGTH {
+		return models.Invalid("name must be at most %d characters", MAX_BRANDING_NAME_LENGTH)
+	}
+
+	for field, color := range map[string]string{"primaryColor": branding.PrimaryColor, "accentColor": branding.AccentColor} {
+		if !brandingColorPattern.MatchString(color) {
+			return models.Invalid("%s must be a hex color such as #2563eb", field)
+		}
+	}
+
+	for field, value := range map[string]string{"logoUrl": branding.LogoURL, "supportUrl": branding.SupportURL} {
+		if value == "" {
+			continue
+		}
+		if len(value) > MAX_BRANDING_URL_LENGTH {
+			return models.Invalid("%s must be at most %d characters", field, MAX_BRANDING_URL_LENGTH)
+		}
+		parsed, err := url.Parse(value)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return models.Invalid("%s must be an absolute http or https URL", field)
+		}
+	}
+
+	if branding.SupportEmail != "" && !isValidEmail(branding.SupportEmail) {
+		return models.Invalid("supportEmail must be a valid email address")
+	}
+
+	return nil
+}
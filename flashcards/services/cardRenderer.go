@@ -0,0 +1,68 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"flashcards/models"
+)
+
+// Markdown images, ![alt](url), in flashcard content
+var markdownImagePattern = regexp.MustCompile(`!\[[^\]]*\]\(([^)\s]+)[^)]*\)`)
+
+// renderFlashcard prepares a flashcard's sides for review. Markdown images
+// are removed from the text and listed in ImageURLs.
+func renderFlashcard(flashcard *models.Flashcard, hasAudio bool) *models.RenderedCard {
+	rendered := &models.RenderedCard{Hint: flashcard.Hint}
+
+	for _, side := range []string{flashcard.Front, flashcard.Back} {
+		for _, match := range markdownImagePattern.FindAllStringSubmatch(side, -1) {
+			if !containsValue(rendered.ImageURLs, match[1]) {
+				rendered.ImageURLs = append(rendered.ImageURLs, match[1])
+			}
+		}
+	}
+	rendered.Front = strings.TrimSpace(markdownImagePattern.ReplaceAllString(flashcard.Front, ""))
+	rendered.Back = strings.TrimSpace(markdownImagePattern.ReplaceAllString(flashcard.Back, ""))
+
+	if hasAudio {
+		rendered.AudioURL = fmt.Sprintf("/flashcards/%d/vocabulary/audio", flashcard.ID)
+	}
+
+	return rendered
+}
+
+// renderDueCard renders a card for review, adding its mnemonic when the
+// learner has found it hard
+func renderDueCard(card models.DueCard, hasAudio bool) *models.RenderedCard {
+	rendered := renderFlashcard(card.Flashcard, hasAudio)
+	if card.Schedule != nil && (card.Schedule.Leech || card.Schedule.Lapses >= HARD_CARD_LAPSES) {
+		rendered.Mnemonic = card.Flashcard.Mnemonic
+	}
+	return rendered
+}
+
+// RenderFlashcardHTML renders both sides of a flashcard as sanitized HTML
+func RenderFlashcardHTML(flashcard *models.Flashcard) *models.FlashcardHTML {
+	rendered := &models.FlashcardHTML{FlashcardID: flashcard.ID, Front: RenderMarkdownHTML(flashcard.Front)}
+	if flashcard.Back != "" {
+		rendered.Back = RenderMarkdownHTML(flashcard.Back)
+	}
+	return rendered
+}
+
+// FlashcardHTMLDocument wraps a rendered flashcard in a standalone HTML page
+// with the stylesheet inlined
+func FlashcardHTMLDocument(rendered *models.FlashcardHTML) string {
+	var doc strings.Builder
+	doc.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&doc, "<title>Flashcard %d</title>\n", rendered.FlashcardID)
+	doc.WriteString("<style>\n" + RENDERED_HTML_STYLESHEET + "\n</style>\n</head>\n<body>\n")
+	doc.WriteString("<div class=\"card-front\">\n" + rendered.Front + "</div>\n")
+	if rendered.Back != "" {
+		doc.WriteString("<div class=\"card-back\">\n" + rendered.Back + "</div>\n")
+	}
+	doc.WriteString("</body>\n</html>\n")
+	return doc.String()
+}
@@ -0,0 +1,417 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	// Cards at least this similar count as near duplicates
+	CARD_SIMILARITY_THRESHOLD = 0.95
+	// Share of a deck's cards that must nearly duplicate another deck's
+	// before it is flagged
+	DUPLICATE_DECK_RATIO = 0.5
+	DEFAULT_FLAG_LIMIT   = 50
+)
+
+var flagReviewStatuses = []string{models.FlagStatusDismissed, models.FlagStatusConfirmed}
+
+// CatalogService publishes decks to the public catalog. Each published deck
+// is compared against the others through card embeddings, and near copies
+// are flagged for curators.
+type CatalogService struct {
+	repo          db.CatalogRepository
+	deckService   *DeckService
+	embeddings    *EmbeddingClient
+	mailer        *Mailer
+	curatorEmails []string
+	deadLetters   *DeadLetterService
+	appURL        string
+}
+
+// similarityCheckPayload identifies a deck whose similarity check failed
+// at publish time and is retried from the dead-letter queue
+type similarityCheckPayload struct {
+	UserID int `json:"userId"`
+	DeckID int `json:"deckId"`
+}
+
+func NewCatalogService(repo db.CatalogRepository, deckService *DeckService, embeddings *EmbeddingClient, mailer *Mailer, curatorEmails []string, deadLetters *DeadLetterService, appURL string) *CatalogService {
+	return &CatalogService{
+		repo:          repo,
+		deckService:   deckService,
+		embeddings:    embeddings,
+		mailer:        mailer,
+		curatorEmails: curatorEmails,
+		deadLetters:   deadLetters,
+		appURL:        appURL,
+	}
+}
+
+// PublishDeck lists the user's deck in the catalog. The deck is published
+// even when it is flagged; curators decide whether to take it down. Cards
+// added later are not compared until the deck is published again. When
+// the similarity check fails, the deck is published without it and the
+// check is queued for retry.
+func (s *CatalogService) PublishDeck(ctx context.Context, userID, deckID int) (*models.PublishedDeck, error) {
+	logger := LoggerFromContext(ctx)
+
+	deck, err := s.deckService.GetDeckByID(ctx, userID, deckID)
+	if err != nil {
+		return nil, err
+	}
+
+	published, err := s.repo.IsPublished(ctx, deckID)
+	if err != nil {
+		return nil, err
+	}
+	if published {
+		return nil, models.Conflict("deck %d is already published", deckID)
+	}
+
+	cards, err := s.repo.GetDeckCards(ctx, userID, deckID)
+	if err != nil {
+		return nil, err
+	}
+	if len(cards) == 0 {
+		return nil, models.Invalid("deck has no flashcards to publish")
+	}
+
+	var embeddings []*models.CardEmbedding
+	var flags []*models.SimilarityFlag
+	var checkErr error
+	if s.embeddings.Enabled() {
+		embeddings, flags, checkErr = s.checkSimilarity(ctx, userID, deckID, cards)
+		if checkErr != nil {
+			if ctx.Err() != nil {
+				return nil, checkErr
+			}
+			logger.Warn("Similarity check failed; publishing without it", "deck_id", deckID, "error", checkErr)
+		}
+	} else {
+		logger.Warn("Publishing deck without a similarity check", "deck_id", deckID)
+	}
+
+	if err := s.repo.PublishDeck(ctx, userID, deckID, embeddings, flags); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Published deck", "deck_id", deckID, "cards", len(cards), "flags", len(flags))
+	if len(flags) > 0 {
+		s.notifyCurators(ctx, deck, flags)
+	}
+	if checkErr != nil {
+		payload := similarityCheckPayload{UserID: userID, DeckID: deckID}
+		if err := s.deadLetters.Record(ctx, models.DeadLetterKindSimilarityCheck, deadLetterKey(deckID), &userID, payload, checkErr); err != nil {
+			logger.Error("Failed to queue similarity check", "deck_id", deckID, "error", err)
+		}
+	}
+
+	return &models.PublishedDeck{
+		DeckID:      deck.ID,
+		UserID:      deck.UserID,
+		Name:        deck.Name,
+		Description: deck.Description,
+		CardCount:   len(cards),
+		PublishedAt: time.Now(),
+	}, nil
+}
+
+// RetrySimilarityCheck runs the similarity check of a deck that was
+// published without one. It is the dead-letter handler for similarity
+// checks; a deck unpublished in the meantime needs no check.
+func (s *CatalogService) RetrySimilarityCheck(ctx context.Context, payload json.RawMessage) error {
+	var check similarityCheckPayload
+	if err := json.Unmarshal(payload, &check); err != nil {
+		return fmt.Errorf("invalid similarity check payload: %w", err)
+	}
+	if !s.embeddings.Enabled() {
+		return models.Unavailable("embeddings are not configured")
+	}
+
+	published, err := s.repo.IsPublished(ctx, check.DeckID)
+	if err != nil {
+		return err
+	}
+	if !published {
+		return nil
+	}
+
+	deck, err := s.deckService.GetDeckByID(ctx, check.UserID, check.DeckID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	cards, err := s.repo.GetDeckCards(ctx, check.UserID, check.DeckID)
+	if err != nil {
+		return err
+	}
+	if len(cards) == 0 {
+		return nil
+	}
+
+	embeddings, flags, err := s.checkSimilarity(ctx, check.UserID, check.DeckID, cards)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.SaveSimilarityCheck(ctx, check.DeckID, embeddings, flags); err != nil {
+		return err
+	}
+
+	LoggerFromContext(ctx).Info("Checked published deck for similarity", "deck_id", check.DeckID, "cards", len(cards), "flags", len(flags))
+	if len(flags) > 0 {
+		s.notifyCurators(ctx, deck, flags)
+	}
+	return nil
+}
+
+// checkSimilarity embeds the deck's cards and flags each published deck of
+// another user that they nearly duplicate. Every stored embedding is
+// compared, which is fine while the catalog is small.
+func (s *CatalogService) checkSimilarity(ctx context.Context, userID, deckID int, cards []*models.Flashcard) ([]*models.CardEmbedding, []*models.SimilarityFlag, error) {
+	texts := make([]string, len(cards))
+	for i, card := range cards {
+		texts[i] = card.Content
+	}
+
+	vectors, err := s.embeddings.Embed(ctx, texts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	embeddings := make([]*models.CardEmbedding, len(cards))
+	for i, card := range cards {
+		embeddings[i] = &models.CardEmbedding{FlashcardID: card.ID, DeckID: deckID, Model: s.embeddings.Model(), Vector: vectors[i]}
+	}
+
+	existing, err := s.repo.GetPublishedEmbeddings(ctx, userID, s.embeddings.Model())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return embeddings, findNearDuplicates(deckID, embeddings, existing), nil
+}
+
+// findNearDuplicates counts, for each other deck, how many of the new
+// deck's cards have a near duplicate in it
+func findNearDuplicates(deckID int, cards, existing []*models.CardEmbedding) []*models.SimilarityFlag {
+	matches := map[int]*models.SimilarityFlag{}
+	for _, card := range cards {
+		best := map[int]float64{}
+		for _, other := range existing {
+			similarity := cosineSimilarity(card.Vector, other.Vector)
+			if similarity >= CARD_SIMILARITY_THRESHOLD && similarity > best[other.DeckID] {
+				best[other.DeckID] = similarity
+			}
+		}
+
+		for matchedDeckID, similarity := range best {
+			flag, ok := matches[matchedDeckID]
+			if !ok {
+				flag = &models.SimilarityFlag{DeckID: deckID, MatchedDeckID: matchedDeckID, TotalCards: len(cards)}
+				matches[matchedDeckID] = flag
+			}
+			flag.MatchedCards++
+			flag.MaxSimilarity = math.Max(flag.MaxSimilarity, similarity)
+		}
+	}
+
+	flags := []*models.SimilarityFlag{}
+	for _, flag := range matches {
+		if float64(flag.MatchedCards) >= DUPLICATE_DECK_RATIO*float64(flag.TotalCards) {
+			flags = append(flags, flag)
+		}
+	}
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].MatchedCards > flags[j].MatchedCards
+	})
+	return flags
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+func (s *CatalogService) notifyCurators(ctx context.Context, deck *models.Deck, flags []*models.SimilarityFlag) {
+	if !s.mailer.Enabled() || len(s.curatorEmails) == 0 {
+		return
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "The newly published deck %q (%d) looks like a copy of other published decks.\n\n", deck.Name, deck.ID)
+	for _, flag := range flags {
+		fmt.Fprintf(&body, "- Flag %d: %d of %d cards nearly duplicate deck %d (max similarity %.3f)\n",
+			flag.ID, flag.MatchedCards, flag.TotalCards, flag.MatchedDeckID, flag.MaxSimilarity)
+	}
+
+	if err := s.mailer.Send(s.curatorEmails, "Possible copied deck: "+deck.Name, body.String()); err != nil {
+		LoggerFromContext(ctx).Error("Failed to notify curators", "deck_id", deck.ID, "error", err)
+	}
+}
+
+// UnpublishDeck takes the user's deck out of the catalog
+func (s *CatalogService) UnpublishDeck(ctx context.Context, userID, deckID int) error {
+	if _, err := s.deckService.GetDeckByID(ctx, userID, deckID); err != nil {
+		return err
+	}
+
+	return s.repo.UnpublishDeck(ctx, deckID)
+}
+
+// ReembedPublishedDecks embeds the cards of every published deck again with
+// the configured model, such as after the model changed, and returns how
+// many decks and cards were embedded. No similarity flags are raised.
+func (s *CatalogService) ReembedPublishedDecks(ctx context.Context) (int, int, error) {
+	if !s.embeddings.Enabled() {
+		return 0, 0, models.Unavailable("embeddings are not configured")
+	}
+	logger := LoggerFromContext(ctx)
+
+	var published []*models.PublishedDeck
+	for offset := 0; ; offset += MAX_PAGE_LIMIT {
+		decks, total, err := s.repo.ListPublishedDecks(ctx, MAX_PAGE_LIMIT, offset)
+		if err != nil {
+			return 0, 0, err
+		}
+		published = append(published, decks...)
+		if offset+MAX_PAGE_LIMIT >= total {
+			break
+		}
+	}
+
+	decks, cards := 0, 0
+	for _, deck := range published {
+		deckCards, err := s.repo.GetDeckCards(ctx, deck.UserID, deck.DeckID)
+		if err != nil {
+			return decks, cards, err
+		}
+		if len(deckCards) == 0 {
+			continue
+		}
+
+		texts := make([]string, len(deckCards))
+		for i, card := range deckCards {
+			texts[i] = card.Content
+		}
+		vectors, err := s.embeddings.Embed(ctx, texts)
+		if err != nil {
+			return decks, cards, fmt.Errorf("failed to embed deck %d: %w", deck.DeckID, err)
+		}
+
+		embeddings := make([]*models.CardEmbedding, len(deckCards))
+		for i, card := range deckCards {
+			embeddings[i] = &models.CardEmbedding{FlashcardID: card.ID, DeckID: deck.DeckID, Model: s.embeddings.Model(), Vector: vectors[i]}
+		}
+		if err := s.repo.SaveSimilarityCheck(ctx, deck.DeckID, embeddings, nil); err != nil {
+			return decks, cards, err
+		}
+
+		logger.Info("Re-embedded published deck", "deck_id", deck.DeckID, "cards", len(deckCards))
+		decks++
+		cards += len(deckCards)
+	}
+	return decks, cards, nil
+}
+
+// ReembedResult counts what ReembedPublishedDecks embedded
+type ReembedResult struct {
+	Decks int `json:"decks"`
+	Cards int `json:"cards"`
+}
+
+// RunReembedJob is the JobRunner for models.JobKindReembedCatalog. It is
+// not scoped to the user who queued it.
+func (s *CatalogService) RunReembedJob(ctx context.Context, userID int, payload json.RawMessage) (any, error) {
+	if err := decodeJobPayload(payload, &struct{}{}); err != nil {
+		return nil, err
+	}
+	if !s.embeddings.Enabled() {
+		return nil, models.Invalid("embeddings are not configured")
+	}
+
+	decks, cards, err := s.ReembedPublishedDecks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ReembedResult{Decks: decks, Cards: cards}, nil
+}
+
+// ListPublishedDecks returns one page of the catalog, newest first
+func (s *CatalogService) ListPublishedDecks(ctx context.Context, params models.ListParams) (*models.Page[*models.PublishedDeck], error) {
+	params, err := normalizeListParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	decks, total, err := s.repo.ListPublishedDecks(ctx, params.Limit, params.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Page[*models.PublishedDeck]{
+		Items:  decks,
+		Total:  total,
+		Limit:  params.Limit,
+		Offset: params.Offset,
+	}, nil
+}
+
+// GetSimilarityFlags lists flags with the given status, pending by default
+func (s *CatalogService) GetSimilarityFlags(ctx context.Context, status string, limit int) ([]*models.SimilarityFlag, error) {
+	if status == "" {
+		status = models.FlagStatusPending
+	}
+	if status != models.FlagStatusPending && !containsValue(flagReviewStatuses, status) {
+		return nil, models.Invalid("status must be one of: %s, %s", models.FlagStatusPending, strings.Join(flagReviewStatuses, ", "))
+	}
+
+	if limit == 0 {
+		limit = DEFAULT_FLAG_LIMIT
+	}
+	if limit < 1 || limit > MAX_PAGE_LIMIT {
+		return nil, models.Invalid("limit must be between 1 and %d", MAX_PAGE_LIMIT)
+	}
+
+	return s.repo.GetSimilarityFlags(ctx, status, limit)
+}
+
+// ReviewSimilarityFlag dismisses a flag or confirms it, which takes the
+// flagged deck out of the catalog
+func (s *CatalogService) ReviewSimilarityFlag(ctx context.Context, id int, req *models.ReviewSimilarityFlagRequest) (*models.SimilarityFlag, error) {
+	if !containsValue(flagReviewStatuses, req.Status) {
+		return nil, models.Invalid("status must be one of: %s", strings.Join(flagReviewStatuses, ", "))
+	}
+
+	flag, err := s.repo.ReviewSimilarityFlag(ctx, id, req.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	LoggerFromContext(ctx).Info("Reviewed similarity flag", "flag_id", id, "deck_id", flag.DeckID, "status", flag.Status)
+	return flag, nil
+}
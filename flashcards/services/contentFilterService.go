@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	MAX_FILTER_REGENERATIONS = 2
+	MAX_BANNED_TOPICS        = 50
+)
+
+var validAgeLevels = []string{models.AgeLevelChildren, models.AgeLevelTeen, models.AgeLevelAdult}
+
+// Always blocked while the filter is enabled
+var profanityTerms = []string{
+	"fuck", "shit", "bitch", "bastard", "asshole", "damn", "crap", "dick", "piss", "slut", "whore",
+}
+
+// Additionally blocked for younger audiences
+var matureTermsByAgeLevel = map[string][]string{
+	models.AgeLevelChildren: {
+		"sex", "sexual", "porn", "nude", "drug", "cocaine", "heroin", "alcohol", "beer", "vodka",
+		"gambling", "casino", "suicide", "murder", "gore", "torture", "kill", "weapon", "gun",
+	},
+	models.AgeLevelTeen: {
+		"porn", "nude", "cocaine", "heroin", "gambling", "casino", "gore", "torture",
+	},
+}
+
+var ageLevelDescriptions = map[string]string{
+	models.AgeLevelChildren: "children under 13",
+	models.AgeLevelTeen:     "teenagers aged 13 to 17",
+}
+
+type ContentFilterService struct {
+	repo db.ContentFilterRepository
+}
+
+func NewContentFilterService(repo db.ContentFilterRepository) *ContentFilterService {
+	return &ContentFilterService{repo: repo}
+}
+
+func (s *ContentFilterService) GetSettings(ctx context.Context) (*models.ContentFilterSettings, error) {
+	settings, err := s.repo.GetSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get content filter settings: %w", err)
+	}
+
+	return settings, nil
+}
+
+func (s *ContentFilterService) UpdateSettings(ctx context.Context, req *models.UpdateContentFilterRequest) (*models.ContentFilterSettings, error) {
+	if err := s.validateUpdateRequest(req); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.GetSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Enabled != nil {
+		settings.Enabled = *req.Enabled
+	}
+
+	if req.AgeLevel != nil {
+		settings.AgeLevel = *req.AgeLevel
+	}
+
+	if req.BannedTopics != nil {
+		topics := make([]string, 0, len(req.BannedTopics))
+		for _, topic := range req.BannedTopics {
+			if topic = strings.ToLower(strings.TrimSpace(topic)); topic != "" {
+				topics = append(topics, topic)
+			}
+		}
+		settings.BannedTopics = topics
+	}
+
+	if err := s.repo.SaveSettings(ctx, settings); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// PromptGuidance returns instructions to append to generation prompts, or an
+// empty string when the filter is disabled
+func (s *ContentFilterService) PromptGuidance(ctx context.Context) string {
+	settings, err := s.repo.GetSettings(ctx)
+	if err != nil {
+		LoggerFromContext(ctx).Error("Failed to load content filter settings", "error", err)
+		return ""
+	}
+
+	if !settings.Enabled {
+		return ""
+	}
+
+	var guidance []string
+	guidance = append(guidance, "Use clean, respectful language with no profanity.")
+	if audience, ok := ageLevelDescriptions[settings.AgeLevel]; ok {
+		guidance = append(guidance, fmt.Sprintf("All content must be appropriate for %s.", audience))
+	}
+	if len(settings.BannedTopics) > 0 {
+		guidance = append(guidance, fmt.Sprintf("Do not mention these topics: %s.", strings.Join(settings.BannedTopics, ", ")))
+	}
+
+	return strings.Join(guidance, " ")
+}
+
+// Check returns a description of the first violation found in the given
+// texts, or an empty string when they pass the filter
+func (s *ContentFilterService) Check(ctx context.Context, texts ...string) string {
+	settings, err := s.repo.GetSettings(ctx)
+	if err != nil {
+		LoggerFromContext(ctx).Error("Failed to load content filter settings", "error", err)
+		return ""
+	}
+
+	if !settings.Enabled {
+		return ""
+	}
+
+	combined := strings.ToLower(strings.Join(texts, "\n"))
+
+	for _, term := range profanityTerms {
+		if containsWord(combined, term) {
+			return "profanity"
+		}
+	}
+
+	for _, term := range matureTermsByAgeLevel[settings.AgeLevel] {
+		if containsWord(combined, term) {
+			return fmt.Sprintf("content not appropriate for age level %s (%q)", settings.AgeLevel, term)
+		}
+	}
+
+	for _, topic := range settings.BannedTopics {
+		if strings.Contains(combined, topic) {
+			return fmt.Sprintf("banned topic %q", topic)
+		}
+	}
+
+	return ""
+}
+
+// Match a term as a whole word, allowing simple plural and verb suffixes
+func containsWord(text, term string) bool {
+	pattern := `\b` + regexp.QuoteMeta(term) + `(s|es|ed|ing)?\b`
+	matched, _ := regexp.MatchString(pattern, text)
+	return matched
+}
+
+func (s *ContentFilterService) validateUpdateRequest(req *models.UpdateContentFilterRequest) error {
+	if req == nil {
+		return models.Invalid("request cannot be nil")
+	}
+
+	if req.Enabled == nil && req.AgeLevel == nil && req.BannedTopics == nil {
+		return models.Invalid("at least one field must be provided for update")
+	}
+
+	if req.AgeLevel != nil && !containsValue(validAgeLevels, *req.AgeLevel) {
+		return models.Invalid("ageLevel must be one of: %s", strings.Join(validAgeLevels, ", "))
+	}
+
+	if len(req.BannedTopics) > MAX_BANNED_TOPICS {
+		return models.Invalid("cannot ban more than %d topics", MAX_BANNED_TOPICS)
+	}
+
+	return nil
+}
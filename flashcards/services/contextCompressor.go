@@ -0,0 +1,135 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const MAX_COMPRESSION_TARGET = 90
+
+var compressionStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "but": true, "not": true,
+	"you": true, "all": true, "any": true, "can": true, "had": true, "her": true,
+	"was": true, "one": true, "our": true, "out": true, "has": true, "have": true,
+	"this": true, "that": true, "with": true, "from": true, "they": true, "will": true,
+	"would": true, "there": true, "their": true, "what": true, "about": true, "which": true,
+	"when": true, "were": true, "been": true, "into": true, "than": true, "then": true,
+	"them": true, "these": true, "some": true, "its": true, "also": true, "such": true,
+}
+
+// compressText performs extractive compression: sentences are scored by the
+// frequency of their content words and the highest scoring ones are kept, in
+// their original order, until the token budget implied by targetPercent is
+// reached. extraStopWords are ignored when scoring, in addition to the
+// built-in list. Returns the compressed text and its token counts before and
+// after.
+func compressText(text string, targetPercent int, extraStopWords map[string]bool) (string, int, int) {
+	sentences := splitSentences(text)
+	originalTokens := countWords(text)
+	if targetPercent <= 0 || len(sentences) <= 1 {
+		return text, originalTokens, originalTokens
+	}
+
+	frequencies := make(map[string]int)
+	for _, sentence := range sentences {
+		for _, word := range contentWords(sentence, extraStopWords) {
+			frequencies[word]++
+		}
+	}
+
+	type scoredSentence struct {
+		index  int
+		score  float64
+		tokens int
+	}
+
+	scored := make([]scoredSentence, len(sentences))
+	for i, sentence := range sentences {
+		words := contentWords(sentence, extraStopWords)
+		total := 0
+		for _, word := range words {
+			total += frequencies[word]
+		}
+		score := 0.0
+		if len(words) > 0 {
+			score = float64(total) / math.Sqrt(float64(len(words)))
+		}
+		scored[i] = scoredSentence{index: i, score: score, tokens: countWords(sentence)}
+	}
+
+	sort.SliceStable(scored, func(a, b int) bool {
+		return scored[a].score > scored[b].score
+	})
+
+	budget := originalTokens * (100 - targetPercent) / 100
+	keep := make(map[int]bool)
+	used := 0
+	for _, s := range scored {
+		if used > 0 && used+s.tokens > budget {
+			continue
+		}
+		keep[s.index] = true
+		used += s.tokens
+	}
+
+	kept := make([]string, 0, len(keep))
+	for i, sentence := range sentences {
+		if keep[i] {
+			kept = append(kept, sentence)
+		}
+	}
+
+	compressed := strings.Join(kept, " ")
+	return compressed, originalTokens, countWords(compressed)
+}
+
+// Split text into sentences on terminal punctuation and line breaks
+func splitSentences(text string) []string {
+	var sentences []string
+	var current strings.Builder
+
+	flush := func() {
+		sentence := strings.TrimSpace(current.String())
+		if sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		current.Reset()
+	}
+
+	runes := []rune(text)
+	for i, r := range runes {
+		if r == '\n' {
+			flush()
+			continue
+		}
+		current.WriteRune(r)
+		if (r == '.' || r == '!' || r == '?') && (i+1 == len(runes) || unicode.IsSpace(runes[i+1])) {
+			flush()
+		}
+	}
+	flush()
+
+	return sentences
+}
+
+// Lowercased words worth scoring, skipping short words and stop words
+func contentWords(sentence string, extraStopWords map[string]bool) []string {
+	fields := strings.FieldsFunc(strings.ToLower(sentence), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	words := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if len([]rune(field)) < 3 || compressionStopWords[field] || extraStopWords[field] {
+			continue
+		}
+		words = append(words, field)
+	}
+	return words
+}
+
+func countWords(text string) int {
+	return len(strings.Fields(text))
+}
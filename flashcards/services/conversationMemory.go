@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"flashcards/models"
+)
+
+const (
+	// Earlier questions listed in the prompt; older ones are only deduped
+	MAX_CONVERSATION_HISTORY = 20
+	// Characters of a learner's answer repeated in the prompt
+	MAX_HISTORY_ANSWER_CHARS = 300
+	// Times a question that repeats an earlier one is regenerated, unless
+	// configured otherwise
+	MAX_DUPLICATE_REGENERATIONS = 2
+	// Share of words two questions have in common to count as the same one
+	DUPLICATE_QUESTION_SIMILARITY = 0.8
+)
+
+// Follow-up requests about the last question in a conversation
+const (
+	followUpHarder    = "harder"
+	followUpEasier    = "easier"
+	followUpSameTopic = "same-topic"
+)
+
+var followUpKeywords = map[string][]string{
+	followUpHarder:    {"harder", "more difficult", "more challenging", "tougher", "step it up"},
+	followUpEasier:    {"easier", "simpler", "less difficult", "less challenging"},
+	followUpSameTopic: {"same topic", "same concept", "same thing", "differently", "different way", "another way", "different angle", "rephrase", "reword", "similar question"},
+}
+
+// Phrases that name a question type outright, as opposed to the looser
+// keywords extractQuestionType matches
+var explicitQuestionTypes = map[string][]string{
+	"multiple-choice":  {"multiple choice", "multiple-choice"},
+	"essay":            {"essay"},
+	"true-false":       {"true/false", "true-false", "true or false"},
+	QUESTION_TYPE_MATH: {"math"},
+}
+
+// askedQuestion is a question from earlier in the conversation and the
+// learner's reply to it, if any
+type askedQuestion struct {
+	question models.QuestionData
+	answer   string
+}
+
+// conversationMemory is what a quiz conversation has covered so far: the
+// questions asked, the answers given and what the latest message asks of
+// the last question. recent, when set, holds the user's recent questions on
+// the same notes from other conversations and sessions. A nil memory is an
+// empty one.
+type conversationMemory struct {
+	asked    []askedQuestion
+	followUp string
+	recent   *recentQuestions
+}
+
+// newConversationMemory reads the questions from the assistant messages and
+// takes the user message after each as its answer. The last message is the
+// new request rather than an answer.
+func newConversationMemory(conversation []models.Message) *conversationMemory {
+	memory := &conversationMemory{}
+	if len(conversation) == 0 {
+		return memory
+	}
+
+	request := conversation[len(conversation)-1]
+	history := conversation[:len(conversation)-1]
+	for i, message := range history {
+		if message.Role != "assistant" || message.Question == nil {
+			continue
+		}
+		asked := askedQuestion{question: *message.Question}
+		if i+1 < len(history) && history[i+1].Role == "user" {
+			asked.answer = strings.TrimSpace(history[i+1].Content)
+		}
+		memory.asked = append(memory.asked, asked)
+	}
+
+	if len(memory.asked) > 0 {
+		memory.followUp = detectFollowUp(request.Content)
+	}
+	return memory
+}
+
+func detectFollowUp(message string) string {
+	lower := strings.ToLower(message)
+	for _, followUp := range []string{followUpHarder, followUpEasier, followUpSameTopic} {
+		for _, keyword := range followUpKeywords[followUp] {
+			if strings.Contains(lower, keyword) {
+				return followUp
+			}
+		}
+	}
+	return ""
+}
+
+func (m *conversationMemory) recentQuestions() *recentQuestions {
+	if m == nil {
+		return nil
+	}
+	return m.recent
+}
+
+func (m *conversationMemory) last() *models.QuestionData {
+	if m == nil || len(m.asked) == 0 {
+		return nil
+	}
+	return &m.asked[len(m.asked)-1].question
+}
+
+// resolve adjusts the difficulty and type read from the request for a
+// follow-up: "harder" and "easier" step from the last question's difficulty,
+// another question on the same topic keeps it unless the request names one,
+// and a follow-up keeps the last question's type unless the request names
+// another one. An empty difficulty means the request named none.
+func (m *conversationMemory) resolve(ctx context.Context, request, difficulty, questionType string) (string, string) {
+	last := m.last()
+	if last == nil || m.followUp == "" {
+		return difficulty, questionType
+	}
+
+	lastDifficulty := last.Difficulty
+	if !containsValue(validDifficulties, lastDifficulty) {
+		lastDifficulty = "medium"
+	}
+	switch m.followUp {
+	case followUpHarder:
+		difficulty = stepDifficulty(lastDifficulty, 1)
+	case followUpEasier:
+		difficulty = stepDifficulty(lastDifficulty, -1)
+	case followUpSameTopic:
+		if difficulty == "" {
+			difficulty = lastDifficulty
+		}
+	}
+
+	if named := namedQuestionType(request); named != "" {
+		questionType = named
+	} else if containsValue(validQuestionTypes, last.Type) {
+		questionType = last.Type
+	}
+
+	LoggerFromContext(ctx).Info("Resolved follow-up request", "follow_up", m.followUp,
+		"last_question_id", last.ID, "difficulty", difficulty, "question_type", questionType)
+	return difficulty, questionType
+}
+
+func stepDifficulty(difficulty string, step int) string {
+	for i, level := range validDifficulties {
+		if level == difficulty {
+			return validDifficulties[min(max(i+step, 0), len(validDifficulties)-1)]
+		}
+	}
+	return difficulty
+}
+
+func namedQuestionType(request string) string {
+	lower := strings.ToLower(request)
+	for _, questionType := range validQuestionTypes {
+		for _, phrase := range explicitQuestionTypes[questionType] {
+			if strings.Contains(lower, phrase) {
+				return questionType
+			}
+		}
+	}
+	return ""
+}
+
+// guidance is the part of the prompt describing the conversation so far:
+// the recent questions with the learner's answers, so the model neither
+// repeats them nor ignores how the learner is doing, and what a follow-up
+// asks for
+func (m *conversationMemory) guidance() string {
+	if m == nil || len(m.asked) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Questions already asked in this conversation, oldest first. Do not repeat any of them or ask the same thing in other words:")
+	recent := m.asked[max(len(m.asked)-MAX_CONVERSATION_HISTORY, 0):]
+	for i, asked := range recent {
+		fmt.Fprintf(&b, "\n%d. [%s, %s] %s", i+1, asked.question.Difficulty, asked.question.Type, asked.question.Text)
+		if asked.question.CorrectAnswer != "" {
+			fmt.Fprintf(&b, "\n   Correct answer: %s", asked.question.CorrectAnswer)
+		}
+		if asked.answer != "" {
+			fmt.Fprintf(&b, "\n   Learner's answer: %s", historyAnswer(asked.answer))
+		}
+	}
+
+	switch m.followUp {
+	case followUpHarder:
+		b.WriteString("\n\nThe learner asked for a harder question than the last one, on the same material.")
+	case followUpEasier:
+		b.WriteString("\n\nThe learner asked for an easier question than the last one, on the same material.")
+	case followUpSameTopic:
+		b.WriteString("\n\nThe learner asked for another question on the same topic as the last one. Test the same concept from a different angle rather than rewording it.")
+	}
+	return b.String()
+}
+
+func historyAnswer(answer string) string {
+	if runes := []rune(answer); len(runes) > MAX_HISTORY_ANSWER_CHARS {
+		return string(runes[:MAX_HISTORY_ANSWER_CHARS]) + "…"
+	}
+	return answer
+}
+
+// duplicateOf returns the earlier question text is a repeat of, or ""
+func (m *conversationMemory) duplicateOf(text string) string {
+	if m == nil {
+		return ""
+	}
+	for _, asked := range m.asked {
+		if sameQuestion(asked.question.Text, text) {
+			return asked.question.Text
+		}
+	}
+	return ""
+}
+
+// dedupeQuestions drops questions that repeat an earlier one in the same
+// batch, which parallel generations from overlapping notes can produce
+func dedupeQuestions(ctx context.Context, messages []models.Message) []models.Message {
+	kept := make([]models.Message, 0, len(messages))
+	for _, message := range messages {
+		duplicate := false
+		for _, earlier := range kept {
+			if message.Question != nil && earlier.Question != nil && sameQuestion(earlier.Question.Text, message.Question.Text) {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			LoggerFromContext(ctx).Info("Dropped duplicate question from batch", "question_id", message.Question.ID)
+			continue
+		}
+		kept = append(kept, message)
+	}
+	return kept
+}
+
+// sameQuestion compares the words of two questions, ignoring case and
+// punctuation
+func sameQuestion(a, b string) bool {
+	wordsA, wordsB := questionWords(a), questionWords(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return false
+	}
+
+	shared := 0
+	for word := range wordsA {
+		if wordsB[word] {
+			shared++
+		}
+	}
+	union := len(wordsA) + len(wordsB) - shared
+	return float64(shared)/float64(union) >= DUPLICATE_QUESTION_SIMILARITY
+}
+
+func questionWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		words[word] = true
+	}
+	return words
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	// How long the answer links and reply code in a daily question work
+	DAILY_QUESTION_LINK_TTL = 48 * time.Hour
+	// How often the scheduled job looks for subscriptions to send; each is
+	// sent once per day, on the first run after the configured hour
+	DAILY_QUESTION_CHECK_INTERVAL = time.Hour
+	MAX_DAILY_QUESTION_ANSWER     = 2000
+)
+
+// The reply code line in the email, found again in the quoted original of a
+// reply
+var dailyQuestionCodePattern = regexp.MustCompile(`Daily question code:\s*([A-Za-z0-9_-]+\.[A-Za-z0-9_-]+)`)
+
+// DailyQuestionService emails subscribers one question from a deck each
+// morning. The question is asked in a one-question quiz session, answered
+// by clicking an option in the email or by replying to it.
+type DailyQuestionService struct {
+	repo           db.DailyQuestionRepository
+	deckService    *DeckService
+	sessionService *QuizSessionService
+	authService    *AuthService
+	mailer         *Mailer
+	appURL         string
+	replyTo        string
+	hour           int
+}
+
+// NewDailyQuestionService sends questions after hour (UTC). Without replyTo
+// the emails offer only the answer links.
+func NewDailyQuestionService(repo db.DailyQuestionRepository, deckService *DeckService, sessionService *QuizSessionService, authService *AuthService, mailer *Mailer, appURL, replyTo string, hour int) *DailyQuestionService {
+	return &DailyQuestionService{
+		repo:           repo,
+		deckService:    deckService,
+		sessionService: sessionService,
+		authService:    authService,
+		mailer:         mailer,
+		appURL:         appURL,
+		replyTo:        replyTo,
+		hour:           hour,
+	}
+}
+
+func (s *DailyQuestionService) CreateSubscription(ctx context.Context, userID int, req *models.CreateDailyQuestionSubscriptionRequest) (*models.DailyQuestionSubscription, error) {
+	if req == nil || req.DeckID <= 0 {
+		return nil, models.Invalid("deckId is required")
+	}
+	if _, err := s.deckService.GetDeckByID(ctx, userID, req.DeckID); err != nil {
+		return nil, err
+	}
+
+	subscription := &models.DailyQuestionSubscription{UserID: userID, DeckID: req.DeckID}
+	if err := s.repo.CreateSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (s *DailyQuestionService) GetSubscriptions(ctx context.Context, userID int) ([]*models.DailyQuestionSubscription, error) {
+	return s.repo.GetSubscriptions(ctx, userID)
+}
+
+func (s *DailyQuestionService) DeleteSubscription(ctx context.Context, userID, id int) error {
+	if id <= 0 {
+		return models.Invalid("invalid daily question subscription ID: %d", id)
+	}
+	return s.repo.DeleteSubscription(ctx, userID, id)
+}
+
+// SendDailyQuestions sends today's question for every subscription that has
+// not had one yet, once the configured hour has passed. A failure for one
+// subscription does not stop the others; it is tried again on the next run.
+func (s *DailyQuestionService) SendDailyQuestions(ctx context.Context) error {
+	logger := LoggerFromContext(ctx)
+	if !s.mailer.Enabled() {
+		logger.Info("Skipping daily question emails, email delivery is not configured")
+		return nil
+	}
+
+	now := time.Now().UTC()
+	if now.Hour() < s.hour {
+		return nil
+	}
+
+	subscriptions, err := s.repo.GetSubscriptionsNotSentSince(ctx, truncateToDay(now))
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, subscription := range subscriptions {
+		if err := s.sendQuestion(ctx, subscription, now); err != nil {
+			logger.Error("Failed to send daily question", "subscription_id", subscription.ID, "user_id", subscription.UserID, "error", err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to send %d of %d daily questions", failed, len(subscriptions))
+	}
+
+	logger.Info("Sent daily questions", "subscriptions", len(subscriptions))
+	return nil
+}
+
+func (s *DailyQuestionService) sendQuestion(ctx context.Context, subscription *models.DailyQuestionSubscription, now time.Time) error {
+	deck, err := s.deckService.GetDeckByID(ctx, subscription.UserID, subscription.DeckID)
+	if err != nil {
+		return err
+	}
+
+	session, err := s.sessionService.CreateSession(ctx, subscription.UserID, &models.CreateQuizSessionRequest{
+		DeckIDs: []int{subscription.DeckID},
+		Count:   1,
+	})
+	if err != nil {
+		return err
+	}
+
+	token, err := s.authService.SignDailyQuestion(subscription.UserID, session.ID, DAILY_QUESTION_LINK_TTL)
+	if err != nil {
+		return err
+	}
+
+	email := dailyQuestionEmail{
+		deckName: deck.Name,
+		question: session.Questions[0].Question,
+		appLink:  s.appURL + "/daily-question?token=" + url.QueryEscape(token),
+		token:    token,
+		canReply: s.replyTo != "",
+	}
+
+	LoggerFromContext(ctx).Info("Sending daily question", "user_id", subscription.UserID, "deck_id", subscription.DeckID, "session_id", session.ID)
+	subject := "Today's question from " + deck.Name
+	if err := s.mailer.SendHTMLWithReplyTo([]string{subscription.Email}, s.replyTo, subject, email.text(), email.html()); err != nil {
+		return err
+	}
+
+	return s.repo.MarkSubscriptionSent(ctx, subscription.ID, session.ID, now)
+}
+
+// Answer records the answer to a daily question in its session, completes
+// the session and returns the graded result
+func (s *DailyQuestionService) Answer(ctx context.Context, req *models.AnswerDailyQuestionRequest) (*models.DailyQuestionResult, error) {
+	if req == nil {
+		return nil, models.Invalid("request cannot be nil")
+	}
+
+	userID, sessionID, err := s.authService.VerifyDailyQuestion(req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	answer := strings.TrimSpace(req.Answer)
+	if answer == "" {
+		return nil, models.Invalid("answer is required")
+	}
+	if len(answer) > MAX_DAILY_QUESTION_ANSWER {
+		return nil, models.Invalid("answer must be at most %d characters", MAX_DAILY_QUESTION_ANSWER)
+	}
+
+	session, err := s.sessionService.GetSessionByID(ctx, userID, sessionID)
+	if err != nil {
+		// The session was deleted after the email was sent
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if session.Status == models.SessionStatusCompleted {
+		return nil, models.Conflict("this daily question was already answered")
+	}
+
+	if _, err := s.sessionService.UpdateQuestion(ctx, userID, sessionID, 0, &models.UpdateSessionQuestionRequest{Answer: &answer}); err != nil {
+		return nil, err
+	}
+	if _, err := s.sessionService.CompleteSession(ctx, userID, sessionID); err != nil {
+		return nil, err
+	}
+
+	question := session.Questions[0].Question
+	LoggerFromContext(ctx).Info("Daily question answered", "user_id", userID, "session_id", sessionID)
+	return &models.DailyQuestionResult{
+		SessionID:     sessionID,
+		Question:      question.Text,
+		Answer:        answer,
+		Correct:       gradeAnswer(question, answer),
+		CorrectAnswer: question.CorrectAnswer,
+		Explanation:   question.Explanation,
+	}, nil
+}
+
+// AnswerReply answers a daily question from an email reply. The answer is
+// the first line written above the quoted original, and the token comes
+// from the reply code quoted below it.
+func (s *DailyQuestionService) AnswerReply(ctx context.Context, reply *models.DailyQuestionReply) (*models.DailyQuestionResult, error) {
+	if reply == nil {
+		return nil, models.Invalid("request cannot be nil")
+	}
+
+	match := dailyQuestionCodePattern.FindStringSubmatch(reply.Text)
+	if match == nil {
+		return nil, models.Invalid("no daily question code found in the reply")
+	}
+
+	return s.Answer(ctx, &models.AnswerDailyQuestionRequest{Token: match[1], Answer: replyAnswer(reply.Text)})
+}
+
+// replyAnswer returns the first non-empty line of a reply before the quoted
+// original starts
+func replyAnswer(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, ">") || dailyQuestionCodePattern.MatchString(line) ||
+			(strings.HasPrefix(line, "On ") && strings.HasSuffix(line, "wrote:")) ||
+			strings.HasPrefix(line, "-----Original Message-----") {
+			return ""
+		}
+		if line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+type dailyQuestionEmail struct {
+	deckName string
+	question models.QuestionData
+	appLink  string
+	token    string
+	canReply bool
+}
+
+// options returns the choices that can be answered with one click. Other
+// question types are answered in the app or by reply.
+func (e dailyQuestionEmail) options() []string {
+	if len(e.question.Options) > 0 {
+		return e.question.Options
+	}
+	if e.question.Type == "true-false" {
+		return []string{"True", "False"}
+	}
+	return nil
+}
+
+func (e dailyQuestionEmail) optionLink(option string) string {
+	return e.appLink + "&answer=" + url.QueryEscape(option)
+}
+
+func (e dailyQuestionEmail) text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Today's question from %s:\n\n%s\n\n", e.deckName, e.question.Text)
+
+	options := e.options()
+	if len(options) > 0 {
+		b.WriteString("Click your answer:\n")
+		for _, option := range options {
+			fmt.Fprintf(&b, "  %s: %s\n", option, e.optionLink(option))
+		}
+	} else {
+		fmt.Fprintf(&b, "Answer it here: %s\n", e.appLink)
+	}
+	if e.canReply {
+		b.WriteString("\nOr reply to this email with your answer on the first line.\n")
+	}
+
+	fmt.Fprintf(&b, "\nDaily question code: %s\n", e.token)
+	return b.String()
+}
+
+func (e dailyQuestionEmail) html() string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	b.WriteString("<style>\n" + RENDERED_HTML_STYLESHEET + "\n.option{display:block;border:1px solid #ddd;border-radius:4px;padding:.5em 1em;margin:.5em 0;text-decoration:none}\n.code{color:#888;font-size:small}\n</style>\n")
+	b.WriteString("</head>\n<body>\n")
+
+	fmt.Fprintf(&b, "<h2>Today's question from %s</h2>\n", html.EscapeString(e.deckName))
+	b.WriteString(RenderMarkdownHTML(e.question.Text))
+
+	options := e.options()
+	for _, option := range options {
+		fmt.Fprintf(&b, "<a class=\"option\" href=\"%s\">%s</a>\n", html.EscapeString(e.optionLink(option)), html.EscapeString(option))
+	}
+	if len(options) == 0 {
+		fmt.Fprintf(&b, "<p><a href=\"%s\">Answer it now</a></p>\n", html.EscapeString(e.appLink))
+	}
+	if e.canReply {
+		b.WriteString("<p>Or reply to this email with your answer on the first line.</p>\n")
+	}
+
+	fmt.Fprintf(&b, "<p class=\"code\">Daily question code: %s</p>\n", html.EscapeString(e.token))
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	// Attempts, the original failure included, before an entry is dead
+	MAX_DEAD_LETTER_ATTEMPTS = 6
+	// The first retry waits this long and each later one twice as long as
+	// the last, up to the maximum
+	DEAD_LETTER_RETRY_BASE = time.Minute
+	DEAD_LETTER_RETRY_MAX  = time.Hour
+	// Entries retried per poll, and how long a claimed entry is held
+	DEAD_LETTER_RETRY_BATCH = 20
+	DEAD_LETTER_RETRY_LEASE = 10 * time.Minute
+	// How often due entries are polled for
+	DEAD_LETTER_POLL_INTERVAL = 30 * time.Second
+)
+
+var deadLetterStatuses = []string{models.DeadLetterStatusRetrying, models.DeadLetterStatusDead, models.DeadLetterStatusResolved}
+
+// DeadLetterHandler retries one failed piece of work from its payload
+type DeadLetterHandler func(ctx context.Context, payload json.RawMessage) error
+
+// DeadLetterService keeps background work that failed, so it is retried
+// with backoff and, once out of attempts, left for an admin instead of
+// being lost. Each kind of work registers the handler that retries it.
+type DeadLetterService struct {
+	repo     db.DeadLetterRepository
+	handlers map[string]DeadLetterHandler
+}
+
+func NewDeadLetterService(repo db.DeadLetterRepository) *DeadLetterService {
+	return &DeadLetterService{repo: repo, handlers: make(map[string]DeadLetterHandler)}
+}
+
+// Handle registers the retry handler for a kind. It is called at startup,
+// before the scheduler starts polling.
+func (s *DeadLetterService) Handle(kind string, handler DeadLetterHandler) {
+	s.handlers[kind] = handler
+}
+
+// Record adds failed work to the queue with its first retry scheduled. key
+// identifies the work within its kind, so a failure of work that is
+// already queued updates that entry rather than adding another.
+func (s *DeadLetterService) Record(ctx context.Context, kind, key string, userID *int, payload any, cause error) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return models.Internal("failed to encode dead letter payload: %w", err)
+	}
+
+	nextAttemptAt := time.Now().Add(deadLetterBackoff(1))
+	letter := &models.DeadLetter{
+		Kind:          kind,
+		Key:           key,
+		UserID:        userID,
+		Payload:       data,
+		Error:         cause.Error(),
+		Attempts:      1,
+		Status:        models.DeadLetterStatusRetrying,
+		NextAttemptAt: &nextAttemptAt,
+	}
+	if err := s.repo.RecordDeadLetter(ctx, letter); err != nil {
+		return err
+	}
+
+	LoggerFromContext(ctx).Warn("Recorded failed background work", "dead_letter_id", letter.ID, "kind", kind, "key", key,
+		"attempts", letter.Attempts, "error", cause)
+	return nil
+}
+
+func (s *DeadLetterService) GetDeadLetter(ctx context.Context, id int) (*models.DeadLetter, error) {
+	if id <= 0 {
+		return nil, models.Invalid("invalid dead letter ID: %d", id)
+	}
+
+	return s.repo.GetDeadLetter(ctx, id)
+}
+
+// ListDeadLetters lists entries, newest first by default, optionally only
+// those with the given status or kind
+func (s *DeadLetterService) ListDeadLetters(ctx context.Context, filter models.DeadLetterFilter, params models.ListParams) (*models.Page[*models.DeadLetter], error) {
+	params, err := normalizeListParams(params)
+	if err != nil {
+		return nil, err
+	}
+	if filter.Status != "" && !containsValue(deadLetterStatuses, filter.Status) {
+		return nil, models.Invalid("status must be one of: retrying, dead, resolved")
+	}
+
+	letters, total, err := s.repo.ListDeadLetters(ctx, filter, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Page[*models.DeadLetter]{
+		Items:  letters,
+		Total:  total,
+		Limit:  params.Limit,
+		Offset: params.Offset,
+	}, nil
+}
+
+// RetryDeadLetter makes an entry due now, dead ones included, so the next
+// poll retries it. A dead entry that fails again goes straight back to
+// dead.
+func (s *DeadLetterService) RetryDeadLetter(ctx context.Context, id int) (*models.DeadLetter, error) {
+	if id <= 0 {
+		return nil, models.Invalid("invalid dead letter ID: %d", id)
+	}
+
+	letter, err := s.repo.RequeueDeadLetter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	LoggerFromContext(ctx).Info("Queued dead letter for retry", "dead_letter_id", id, "kind", letter.Kind)
+	return letter, nil
+}
+
+// DeleteDeadLetter discards an entry without retrying it
+func (s *DeadLetterService) DeleteDeadLetter(ctx context.Context, id int) error {
+	if id <= 0 {
+		return models.Invalid("invalid dead letter ID: %d", id)
+	}
+
+	return s.repo.DeleteDeadLetter(ctx, id)
+}
+
+// RetryDue retries the entries whose next attempt is due. It runs on a
+// schedule.
+func (s *DeadLetterService) RetryDue(ctx context.Context) error {
+	letters, err := s.repo.ClaimDueDeadLetters(ctx, DEAD_LETTER_RETRY_BATCH, DEAD_LETTER_RETRY_LEASE)
+	if err != nil {
+		return err
+	}
+
+	for _, letter := range letters {
+		if ctx.Err() != nil {
+			// The rest are retried once their lease runs out
+			return nil
+		}
+		s.retry(ctx, letter)
+	}
+	return nil
+}
+
+func (s *DeadLetterService) retry(ctx context.Context, letter *models.DeadLetter) {
+	logger := LoggerFromContext(ctx).With("dead_letter_id", letter.ID, "kind", letter.Kind, "key", letter.Key)
+
+	var err error
+	if handler, ok := s.handlers[letter.Kind]; ok {
+		err = handler(ContextWithLogger(ctx, logger), letter.Payload)
+	} else {
+		err = fmt.Errorf("no retry handler for %s", letter.Kind)
+	}
+
+	if err == nil {
+		if err := s.repo.ResolveDeadLetter(ctx, letter.ID); err != nil {
+			logger.Error("Failed to resolve dead letter", "error", err)
+			return
+		}
+		logger.Info("Retried failed background work", "attempts", letter.Attempts+1)
+		return
+	}
+	if ctx.Err() != nil {
+		// Interrupted by shutdown; not counted as an attempt
+		return
+	}
+
+	attempts := letter.Attempts + 1
+	var nextAttemptAt *time.Time
+	if attempts < MAX_DEAD_LETTER_ATTEMPTS {
+		next := time.Now().Add(deadLetterBackoff(attempts))
+		nextAttemptAt = &next
+	}
+	if updateErr := s.repo.FailDeadLetterAttempt(ctx, letter.ID, err.Error(), nextAttemptAt); updateErr != nil {
+		logger.Error("Failed to update dead letter", "error", updateErr)
+		return
+	}
+
+	if nextAttemptAt == nil {
+		logger.Error("Background work is dead after its last retry", "attempts", attempts, "error", err)
+	} else {
+		logger.Warn("Retry of failed background work failed", "attempts", attempts, "next_attempt_at", nextAttemptAt, "error", err)
+	}
+}
+
+// deadLetterBackoff is the wait before the retry that follows the given
+// number of failed attempts
+func deadLetterBackoff(attempts int) time.Duration {
+	delay := DEAD_LETTER_RETRY_BASE
+	for i := 1; i < attempts && delay < DEAD_LETTER_RETRY_MAX; i++ {
+		delay *= 2
+	}
+	return min(delay, DEAD_LETTER_RETRY_MAX)
+}
+
+// deadLetterKey formats an ID as the key of a dead letter
+func deadLetterKey(id int) string {
+	return strconv.Itoa(id)
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+	"flashcards/storage"
+)
+
+const (
+	EXPORT_FORMAT_APKG = "apkg"
+	EXPORT_FORMAT_CSV  = "csv"
+)
+
+var exportContentTypes = map[string]string{
+	EXPORT_FORMAT_APKG: "application/zip",
+	EXPORT_FORMAT_CSV:  "text/csv; charset=utf-8",
+}
+
+var filenameUnsafePattern = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// ExportContentType returns the MIME type for an export format, or "" when
+// the format is not supported
+func ExportContentType(format string) string {
+	return exportContentTypes[format]
+}
+
+// DeckExportService serializes a deck and its subdecks for other flashcard
+// tools: an Anki package that keeps the deck hierarchy, or a CSV file.
+// Exports are streamed, or with object storage written there for download
+// until retention has passed.
+type DeckExportService struct {
+	deckService      *DeckService
+	flashcardService *FlashcardService
+	repo             db.DeckExportRepository
+	store            storage.Store
+	retention        time.Duration
+}
+
+// NewDeckExportService takes the object store, or nil to only stream
+// exports
+func NewDeckExportService(deckService *DeckService, flashcardService *FlashcardService, repo db.DeckExportRepository, store storage.Store, retention time.Duration) *DeckExportService {
+	return &DeckExportService{
+		deckService:      deckService,
+		flashcardService: flashcardService,
+		repo:             repo,
+		store:            store,
+		retention:        retention,
+	}
+}
+
+// DeckExport is a deck export ready to write. Its cards are loaded, but the
+// file is encoded as it is written, so a large export is never held in
+// memory whole.
+type DeckExport struct {
+	Filename    string
+	ContentType string
+	encode      func(w io.Writer) error
+}
+
+// Encode writes the exported file to w
+func (e *DeckExport) Encode(w io.Writer) error {
+	return e.encode(w)
+}
+
+// ExportDeck loads the deck and its cards for export. Failures to find or
+// load them are returned here, before anything is written.
+func (s *DeckExportService) ExportDeck(ctx context.Context, userID, deckID int, format string) (*DeckExport, error) {
+	if ExportContentType(format) == "" {
+		return nil, models.Invalid("format must be one of: apkg, csv")
+	}
+
+	deck, err := s.deckService.GetDeckByID(ctx, userID, deckID)
+	if err != nil {
+		return nil, err
+	}
+
+	deckIDs, err := s.deckService.GetDeckTreeIDs(ctx, userID, deckID)
+	if err != nil {
+		return nil, err
+	}
+	decks, err := s.deckService.GetAllDecks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	flashcards, err := s.flashcardService.GetFlashcardsByDecks(ctx, userID, deckIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	exported := exportDeckTree(deck, deckIDs, decks, flashcards)
+	export := &DeckExport{Filename: exportFilename(deck, format), ContentType: ExportContentType(format)}
+	if format == EXPORT_FORMAT_APKG {
+		export.encode = func(w io.Writer) error {
+			return writeAnkiPackage(ctx, w, ankiDecks(exported), time.Now())
+		}
+	} else {
+		export.encode = func(w io.Writer) error {
+			return writeCSVExport(w, exported)
+		}
+	}
+
+	LoggerFromContext(ctx).Info("Exporting deck", "deck_id", deckID, "format", format, "cards", len(flashcards))
+	return export, nil
+}
+
+type exportedDeck struct {
+	// path names the deck from the exported deck down, "Parent::Child"
+	path       string
+	deck       *models.Deck
+	flashcards []*models.Flashcard
+}
+
+// exportDeckTree groups the flashcards by deck, ordered by deck path so
+// parents come before their subdecks
+func exportDeckTree(root *models.Deck, deckIDs []int, decks []*models.Deck, flashcards []*models.Flashcard) []*exportedDeck {
+	byID := make(map[int]*models.Deck, len(decks))
+	for _, deck := range decks {
+		byID[deck.ID] = deck
+	}
+	byID[root.ID] = root
+
+	exported := make(map[int]*exportedDeck, len(deckIDs))
+	var ordered []*exportedDeck
+	for _, id := range deckIDs {
+		deck, ok := byID[id]
+		if !ok {
+			continue
+		}
+
+		// Anki reads "::" as a deck separator, so it cannot appear in a name
+		var names []string
+		for current := deck; ; {
+			names = append([]string{strings.ReplaceAll(current.Name, "::", ":")}, names...)
+			if current.ID == root.ID || current.ParentID == nil || byID[*current.ParentID] == nil {
+				break
+			}
+			current = byID[*current.ParentID]
+		}
+
+		exported[id] = &exportedDeck{path: strings.Join(names, "::"), deck: deck}
+		ordered = append(ordered, exported[id])
+	}
+
+	for _, flashcard := range flashcards {
+		if flashcard.DeckID != nil && exported[*flashcard.DeckID] != nil {
+			deck := exported[*flashcard.DeckID]
+			deck.flashcards = append(deck.flashcards, flashcard)
+		}
+	}
+
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].path < ordered[j].path })
+	return ordered
+}
+
+func ankiDecks(exported []*exportedDeck) []ankiDeck {
+	decks := make([]ankiDeck, len(exported))
+	for i, deck := range exported {
+		decks[i] = ankiDeck{Name: deck.path, Description: deck.deck.Description}
+		for _, flashcard := range deck.flashcards {
+			rendered := RenderFlashcardHTML(flashcard)
+			decks[i].Cards = append(decks[i].Cards, ankiCard{
+				GUID:  fmt.Sprintf("flashcards-%d", flashcard.ID),
+				Front: rendered.Front,
+				Back:  rendered.Back,
+			})
+		}
+	}
+	return decks
+}
+
+// writeCSVExport writes one row per card with the Markdown of each side,
+// which Anki and spreadsheet tools can both import. Rows are flushed to w
+// as the writer's buffer fills.
+func writeCSVExport(w io.Writer, exported []*exportedDeck) error {
+	writer := csv.NewWriter(w)
+
+	writer.Write([]string{"deck", "front", "back"})
+	for _, deck := range exported {
+		for _, flashcard := range deck.flashcards {
+			if err := writer.Write([]string{deck.path, flashcard.Front, flashcard.Back}); err != nil {
+				return models.Internal("failed to write csv export: %w", err)
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return models.Internal("failed to write csv export: %w", err)
+	}
+	return nil
+}
+
+func exportFilename(deck *models.Deck, format string) string {
+	name := strings.Trim(filenameUnsafePattern.ReplaceAllString(deck.Name, "-"), "-")
+	if name == "" {
+		name = fmt.Sprintf("deck-%d", deck.ID)
+	}
+	return name + "." + format
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"flashcards/models"
+)
+
+const (
+	// Cards listed in a deck's feed; readers keep the entries they have seen
+	DECK_FEED_ENTRIES     = 50
+	DECK_FEED_TITLE_CHARS = 80
+	ATOM_CONTENT_TYPE     = "application/atom+xml; charset=utf-8"
+)
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   atomAuthor  `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Link      atomLink    `xml:"link"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// GetDeckFeed returns an Atom feed of the cards most recently added to a
+// published deck. The deck's cards are its history: each card is an entry
+// published when it was added, or when the deck was published for cards
+// that were already in it. selfURL is where the feed was requested from.
+func (s *CatalogService) GetDeckFeed(ctx context.Context, deckID int, selfURL string) ([]byte, error) {
+	if deckID <= 0 {
+		return nil, models.Invalid("invalid deck ID: %d", deckID)
+	}
+
+	deck, err := s.repo.GetPublishedDeck(ctx, deckID)
+	if err != nil {
+		return nil, err
+	}
+
+	cards, err := s.repo.GetRecentDeckCards(ctx, deckID, DECK_FEED_ENTRIES)
+	if err != nil {
+		return nil, err
+	}
+
+	deckURL := fmt.Sprintf("%s/catalog/decks/%d", s.appURL, deck.DeckID)
+	feed := atomFeed{
+		ID:       deckURL,
+		Title:    deck.Name,
+		Subtitle: deck.Description,
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: selfURL},
+			{Rel: "alternate", Type: "text/html", Href: deckURL},
+		},
+		Author:  atomAuthor{Name: deck.Name},
+		Entries: make([]atomEntry, 0, len(cards)),
+	}
+
+	updated := deck.PublishedAt
+	for _, card := range cards {
+		added := card.CreatedAt
+		if added.Before(deck.PublishedAt) {
+			added = deck.PublishedAt
+		}
+		edited := card.UpdatedAt
+		if edited.Before(added) {
+			edited = added
+		}
+		if edited.After(updated) {
+			updated = edited
+		}
+
+		cardURL := fmt.Sprintf("%s/cards/%d", deckURL, card.ID)
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        cardURL,
+			Title:     feedEntryTitle(card.Front),
+			Published: atomTime(added),
+			Updated:   atomTime(edited),
+			Link:      atomLink{Rel: "alternate", Type: "text/html", Href: cardURL},
+			Content: atomContent{
+				Type: "html",
+				Body: RenderMarkdownHTML(card.Front) + "<hr>" + RenderMarkdownHTML(card.Back),
+			},
+		})
+	}
+
+	feed.Updated = atomTime(updated)
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, models.Internal("failed to encode deck feed: %w", err)
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}
+
+// feedEntryTitle is the first line of a card's front, shortened for feed
+// readers' entry lists
+func feedEntryTitle(front string) string {
+	title, _, _ := strings.Cut(strings.TrimSpace(front), "\n")
+	title = strings.TrimSpace(title)
+	if runes := []rune(title); len(runes) > DECK_FEED_TITLE_CHARS {
+		title = string(runes[:DECK_FEED_TITLE_CHARS]) + "…"
+	}
+	return title
+}
+
+func atomTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,482 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	// Largest file accepted by a deck import, media included
+	MAX_DECK_IMPORT_BYTES = 50 * 1024 * 1024
+	// Cards created by one deck import; further cards are skipped
+	MAX_DECK_IMPORT_CARDS = 5000
+)
+
+// csvSeparators are the names Anki writes in a "#separator:" header
+var csvSeparators = map[string]rune{
+	"comma": ',', "semicolon": ';', "tab": '\t', "space": ' ', "pipe": '|', "colon": ':',
+}
+
+// DeckImportService creates decks and flashcards from files exported by
+// other flashcard tools: Anki packages and CSV, including our own exports
+type DeckImportService struct {
+	deckService      *DeckService
+	flashcardService *FlashcardService
+	quotas           *QuotaService
+	uow              db.UnitOfWork
+}
+
+func NewDeckImportService(deckService *DeckService, flashcardService *FlashcardService, quotas *QuotaService, uow db.UnitOfWork) *DeckImportService {
+	return &DeckImportService{deckService: deckService, flashcardService: flashcardService, quotas: quotas, uow: uow}
+}
+
+// importDeckNode is a deck that imported cards go into. deck is nil until
+// a new deck has been created.
+type importDeckNode struct {
+	path     string
+	name     string
+	parent   *importDeckNode
+	children map[string]*importDeckNode
+	deck     *models.Deck
+	isNew    bool
+	needed   bool
+}
+
+func (n *importDeckNode) deckID() *int {
+	if n.deck == nil {
+		return nil
+	}
+	return &n.deck.ID
+}
+
+type plannedCard struct {
+	node    *importDeckNode
+	content string
+	item    int
+}
+
+// ImportDeck reads an Anki package or a CSV file and creates its cards,
+// creating any decks in the file that do not exist yet. Cards the user
+// already has are skipped by content hash, and cards that share a front
+// with a card in the same deck but have a different back are reported as
+// conflicts and left out. A validate-only import builds the same report
+// without creating anything.
+func (s *DeckImportService) ImportDeck(ctx context.Context, userID int, req *models.ImportDeckRequest) (*models.DeckImportReport, error) {
+	rows, err := s.readImportFile(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &importDeckNode{children: make(map[string]*importDeckNode)}
+	if req.DeckID != nil {
+		root.deck, err = s.deckService.GetDeckByID(ctx, userID, *req.DeckID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	decks, err := s.deckService.GetAllDecks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[int]map[string]*models.Deck)
+	for _, deck := range decks {
+		parent := 0
+		if deck.ParentID != nil {
+			parent = *deck.ParentID
+		}
+		if children[parent] == nil {
+			children[parent] = make(map[string]*models.Deck)
+		}
+		children[parent][deck.Name] = deck
+	}
+
+	report := &models.DeckImportReport{
+		ValidateOnly: req.ValidateOnly,
+		Decks:        []models.DeckImportDeck{},
+		Created:      []models.DeckImportItem{},
+		Skipped:      []models.DeckImportItem{},
+		Conflicts:    []models.DeckImportItem{},
+	}
+
+	// Resolve every deck path first so existing cards in the destination
+	// decks can be loaded in one query
+	var ordered []*importDeckNode
+	nodes := make([]*importDeckNode, len(rows))
+	reasons := make([]string, len(rows))
+	for i, row := range rows {
+		nodes[i], reasons[i] = resolveImportDeck(root, row.deck, children, &ordered)
+	}
+
+	var existingDeckIDs []int
+	if root.deck != nil {
+		existingDeckIDs = append(existingDeckIDs, root.deck.ID)
+	}
+	for _, node := range ordered {
+		if !node.isNew {
+			existingDeckIDs = append(existingDeckIDs, node.deck.ID)
+		}
+	}
+	existingFronts := make(map[int]map[string]*models.Flashcard)
+	if len(existingDeckIDs) > 0 {
+		existing, err := s.flashcardService.GetFlashcardsByDecks(ctx, userID, existingDeckIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, flashcard := range existing {
+			if existingFronts[*flashcard.DeckID] == nil {
+				existingFronts[*flashcard.DeckID] = make(map[string]*models.Flashcard)
+			}
+			existingFronts[*flashcard.DeckID][flashcard.Front] = flashcard
+		}
+	}
+
+	contents := make([]string, len(rows))
+	hashes := make([]string, 0, len(rows))
+	for i, row := range rows {
+		contents[i] = models.FlashcardContent(row.front, row.back)
+		hashes = append(hashes, FlashcardContentHash(contents[i]))
+	}
+	existingHashes, err := s.flashcardService.GetFlashcardIDsByContentHash(ctx, userID, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	skip := func(item models.DeckImportItem, reason string) {
+		item.Reason = reason
+		report.Skipped = append(report.Skipped, item)
+		report.Summary.Errors++
+	}
+	duplicate := func(item models.DeckImportItem, reason string, existingID *int) {
+		item.Reason, item.ExistingID = reason, existingID
+		report.Skipped = append(report.Skipped, item)
+		report.Summary.Duplicates++
+	}
+	conflict := func(item models.DeckImportItem, reason string, existingID *int) {
+		item.Reason, item.ExistingID = reason, existingID
+		report.Conflicts = append(report.Conflicts, item)
+		report.Summary.Conflicts++
+	}
+
+	var planned []plannedCard
+	importedHashes := make(map[string]int)
+	importedFronts := make(map[*importDeckNode]map[string]int)
+	for i, row := range rows {
+		item := models.DeckImportItem{Row: row.row, Deck: row.deck, Front: importPreview(row.front)}
+		hash := hashes[i]
+		node := nodes[i]
+
+		var existingFront *models.Flashcard
+		if node != nil && node.deck != nil {
+			existingFront = existingFronts[node.deck.ID][row.front]
+		}
+		var importedFront int
+		if node != nil && importedFronts[node] != nil {
+			importedFront = importedFronts[node][row.front]
+		}
+
+		switch {
+		case reasons[i] != "":
+			skip(item, reasons[i])
+		case row.front == "":
+			skip(item, "card has no text on the front")
+		case len(contents[i]) > MAX_FLASHCARD_LENGTH:
+			skip(item, fmt.Sprintf("card exceeds %d characters", MAX_FLASHCARD_LENGTH))
+		case existingHashes[hash] != 0:
+			existingID := existingHashes[hash]
+			duplicate(item, "duplicate of an existing flashcard", &existingID)
+		case importedHashes[hash] != 0:
+			duplicate(item, fmt.Sprintf("duplicate of row %d", importedHashes[hash]), nil)
+		case existingFront != nil:
+			conflict(item, "an existing flashcard in this deck has the same front and a different back", &existingFront.ID)
+		case importedFront != 0:
+			conflict(item, fmt.Sprintf("row %d has the same front and a different back", importedFront), nil)
+		case len(planned) >= MAX_DECK_IMPORT_CARDS:
+			skip(item, fmt.Sprintf("import is limited to %d cards", MAX_DECK_IMPORT_CARDS))
+		default:
+			importedHashes[hash] = row.row
+			if importedFronts[node] == nil {
+				importedFronts[node] = make(map[string]int)
+			}
+			importedFronts[node][row.front] = row.row
+			for n := node; n != nil && n != root; n = n.parent {
+				n.needed = true
+			}
+			planned = append(planned, plannedCard{node: node, content: contents[i], item: len(report.Created)})
+			report.Created = append(report.Created, item)
+		}
+	}
+	report.Summary.Total = len(rows)
+	report.Summary.Importable = len(planned)
+
+	if len(planned) > 0 {
+		if err := s.quotas.CheckCards(ctx, userID, len(planned)); err != nil {
+			if !req.ValidateOnly || !errors.Is(err, models.ErrQuotaExceeded) {
+				return nil, err
+			}
+			report.Summary.QuotaError = err.Error()
+		}
+	}
+
+	if !req.ValidateOnly && len(planned) > 0 {
+		// New decks and the cards go in one transaction, so a failed import
+		// leaves no empty decks behind
+		flashcards := make([]*models.Flashcard, len(planned))
+		err := s.uow.Do(ctx, func(ctx context.Context) error {
+			for _, node := range ordered {
+				if !node.needed || !node.isNew {
+					continue
+				}
+				deck, err := s.deckService.CreateDeck(ctx, userID, &models.CreateDeckRequest{Name: node.name, ParentID: node.parent.deckID()})
+				if err != nil {
+					return err
+				}
+				node.deck = deck
+			}
+
+			for i, card := range planned {
+				flashcards[i] = &models.Flashcard{UserID: userID, DeckID: card.node.deckID(), Content: card.content}
+			}
+			return s.flashcardService.CreateFlashcards(ctx, userID, flashcards)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for i, card := range planned {
+			report.Created[card.item].FlashcardID = &flashcards[i].ID
+		}
+	}
+
+	for _, node := range ordered {
+		if node.needed {
+			report.Decks = append(report.Decks, models.DeckImportDeck{Path: node.path, DeckID: node.deckID(), New: node.isNew})
+		}
+	}
+
+	LoggerFromContext(ctx).Info("Imported deck", "file", req.File.Name, "created", len(report.Created),
+		"skipped", len(report.Skipped), "conflicts", len(report.Conflicts), "validate_only", req.ValidateOnly)
+	return report, nil
+}
+
+// FlashcardContentHash matches the contentHash column of a flashcard
+func FlashcardContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *DeckImportService) readImportFile(ctx context.Context, req *models.ImportDeckRequest) ([]deckImportRow, error) {
+	if req == nil {
+		return nil, models.Invalid("request cannot be nil")
+	}
+	if len(req.File.Data) == 0 {
+		return nil, models.Invalid("file is required")
+	}
+	if len(req.File.Data) > MAX_DECK_IMPORT_BYTES {
+		return nil, models.Invalid("file exceeds %d bytes", MAX_DECK_IMPORT_BYTES)
+	}
+
+	switch ext := strings.ToLower(path.Ext(req.File.Name)); ext {
+	case ".apkg", ".colpkg":
+		return readAnkiPackage(ctx, req.File.Data)
+	case ".csv", ".tsv", ".txt":
+		return readCSVDeck(req.File.Data, ext == ".tsv")
+	default:
+		return nil, models.Invalid("file must be an Anki package (.apkg) or CSV (.csv, .tsv, .txt)")
+	}
+}
+
+// resolveImportDeck finds or adds the node for a "Parent::Child" path
+// below root, matching existing decks by name. New nodes are appended to
+// ordered, parents before children. It returns a reason instead when the
+// path cannot be a deck.
+func resolveImportDeck(root *importDeckNode, deckPath string, children map[int]map[string]*models.Deck, ordered *[]*importDeckNode) (*importDeckNode, string) {
+	node := root
+	for _, name := range strings.Split(deckPath, "::") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if len(name) > MAX_DECK_NAME_LENGTH {
+			return nil, fmt.Sprintf("deck name exceeds %d characters", MAX_DECK_NAME_LENGTH)
+		}
+
+		child := node.children[name]
+		if child == nil {
+			child = &importDeckNode{name: name, parent: node, children: make(map[string]*importDeckNode), isNew: true}
+			child.path = name
+			if node.path != "" {
+				child.path = node.path + "::" + name
+			}
+			if !node.isNew {
+				parentID := 0
+				if node.deck != nil {
+					parentID = node.deck.ID
+				}
+				if deck := children[parentID][name]; deck != nil {
+					child.deck, child.isNew = deck, false
+				}
+			}
+			node.children[name] = child
+			*ordered = append(*ordered, child)
+		}
+		node = child
+	}
+	return node, ""
+}
+
+// readCSVDeck reads one card per row. A header row naming "front" and
+// optionally "back" and "deck" columns, as our own export writes, maps the
+// columns; without one the first two columns are the front and back. The
+// "#separator", "#html" and "#... column" headers of Anki's plain text
+// export are honoured.
+func readCSVDeck(data []byte, tabs bool) ([]deckImportRow, error) {
+	if !utf8.Valid(data) {
+		return nil, models.Invalid("file is not valid UTF-8 text")
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	separator := ','
+	if tabs {
+		separator = '\t'
+	}
+	isHTML := false
+	deckColumn := -1
+	// Columns Anki uses for something other than the note's fields
+	reserved := make(map[int]bool)
+
+	// Anki writes its headers as "#key:value" lines before the rows
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	headerLines := 0
+	for _, line := range lines {
+		key, value, ok := strings.Cut(strings.TrimSpace(string(line)), ":")
+		if !strings.HasPrefix(key, "#") || !ok {
+			break
+		}
+		headerLines++
+		key, value = strings.ToLower(key), strings.TrimSpace(value)
+		switch {
+		case key == "#separator":
+			if sep, ok := csvSeparators[strings.ToLower(value)]; ok {
+				separator = sep
+			} else if r, size := utf8.DecodeRuneInString(value); size == len(value) && size > 0 {
+				separator = r
+			}
+		case key == "#html":
+			isHTML = value == "true"
+		case strings.HasSuffix(key, " column"):
+			column, err := strconv.Atoi(value)
+			if err != nil || column < 1 {
+				return nil, models.Invalid("invalid %s header", key)
+			}
+			reserved[column-1] = true
+			if key == "#deck column" {
+				deckColumn = column - 1
+			}
+		}
+	}
+	frontColumn, backColumn := -1, -1
+	for column := 0; backColumn < 0; column++ {
+		if reserved[column] {
+			continue
+		}
+		if frontColumn < 0 {
+			frontColumn = column
+		} else {
+			backColumn = column
+		}
+	}
+	if !tabs && headerLines == 0 && len(lines) > 0 && bytes.ContainsRune(lines[0], '\t') && !bytes.ContainsRune(lines[0], ',') {
+		separator = '\t'
+	}
+
+	reader := csv.NewReader(bytes.NewReader(bytes.Join(lines[headerLines:], nil)))
+	reader.Comma = separator
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var rows []deckImportRow
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, models.Invalid("invalid CSV on line %d: %v", parseErr.Line+headerLines, parseErr.Err)
+			}
+			return nil, models.Invalid("invalid CSV: %v", err)
+		}
+
+		if first && headerLines == 0 {
+			if columns, ok := csvHeaderColumns(record); ok {
+				frontColumn, backColumn, deckColumn = columns[0], columns[1], columns[2]
+				continue
+			}
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := deckImportRow{row: line + headerLines}
+		for i, value := range record {
+			if isHTML {
+				value = ankiFieldText(value)
+			} else {
+				value = strings.TrimSpace(strings.ReplaceAll(value, "\r\n", "\n"))
+			}
+			switch i {
+			case frontColumn:
+				row.front = value
+			case backColumn:
+				row.back = value
+			case deckColumn:
+				row.deck = value
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, models.Invalid("file has no cards")
+	}
+	return rows, nil
+}
+
+// csvHeaderColumns returns the front, back and deck columns of a header
+// row, -1 for a missing back or deck, or false when the row is not a
+// header
+func csvHeaderColumns(record []string) ([3]int, bool) {
+	columns := [3]int{-1, -1, -1}
+	for i, name := range record {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "front", "question":
+			columns[0] = i
+		case "back", "answer":
+			columns[1] = i
+		case "deck":
+			columns[2] = i
+		}
+	}
+	return columns, columns[0] >= 0
+}
+
+// importPreview shortens a card front to its first line for reports
+func importPreview(text string) string {
+	text, _, _ = strings.Cut(text, "\n")
+	if utf8.RuneCountInString(text) > 80 {
+		text = string([]rune(text)[:80]) + "…"
+	}
+	return text
+}
@@ -0,0 +1,479 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	MAX_DECK_NAME_LENGTH        = 100
+	MAX_TERMINOLOGY_ENTRIES     = 200
+	MAX_TERMINOLOGY_TERM_LENGTH = 100
+)
+
+type DeckService struct {
+	repo      db.DeckRepository
+	noteCache NoteCache
+}
+
+// NewDeckService takes the note cache, if any, because deleting a deck
+// moves its notes out of it
+func NewDeckService(repo db.DeckRepository, noteCache NoteCache) *DeckService {
+	return &DeckService{repo: repo, noteCache: noteCache}
+}
+
+func (s *DeckService) CreateDeck(ctx context.Context, userID int, req *models.CreateDeckRequest) (*models.Deck, error) {
+	if err := s.validateCreateRequest(req); err != nil {
+		return nil, err
+	}
+
+	if req.ParentID != nil {
+		if _, err := s.GetDeckByID(ctx, userID, *req.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	reviewOrder := req.ReviewOrder
+	if reviewOrder == "" {
+		reviewOrder = models.ReviewOrderDue
+	}
+
+	deck := &models.Deck{
+		UserID:      userID,
+		ParentID:    req.ParentID,
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		ReviewOrder: reviewOrder,
+	}
+
+	if err := s.repo.CreateDeck(ctx, deck); err != nil {
+		return nil, fmt.Errorf("failed to create deck: %w", err)
+	}
+
+	return deck, nil
+}
+
+func (s *DeckService) GetDeckByID(ctx context.Context, userID, id int) (*models.Deck, error) {
+	if id <= 0 {
+		return nil, models.Invalid("invalid deck ID: %d", id)
+	}
+
+	return s.repo.GetDeckByID(ctx, userID, id)
+}
+
+func (s *DeckService) GetAllDecks(ctx context.Context, userID int) ([]*models.Deck, error) {
+	decks, err := s.repo.GetAllDecks(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get decks: %w", err)
+	}
+
+	return decks, nil
+}
+
+func (s *DeckService) UpdateDeck(ctx context.Context, userID, id int, req *models.UpdateDeckRequest) (*models.Deck, error) {
+	if id <= 0 {
+		return nil, models.Invalid("invalid deck ID: %d", id)
+	}
+
+	if err := s.validateUpdateRequest(req); err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]any)
+
+	if req.Name != nil {
+		trimmedName := strings.TrimSpace(*req.Name)
+		if trimmedName == "" {
+			return nil, models.Invalid("name cannot be empty")
+		}
+		updates["name"] = trimmedName
+	}
+
+	if req.Description != nil {
+		updates["description"] = strings.TrimSpace(*req.Description)
+	}
+
+	if req.ReviewOrder != nil {
+		updates["reviewOrder"] = *req.ReviewOrder
+	}
+
+	if req.ParentID != nil {
+		if *req.ParentID == 0 {
+			updates["parent_id"] = nil
+		} else {
+			// A deck cannot be moved under itself or one of its subdecks
+			tree, err := s.repo.GetDeckTreeIDs(ctx, userID, id)
+			if err != nil {
+				return nil, err
+			}
+			if containsID(tree, *req.ParentID) {
+				return nil, models.Invalid("parentId cannot be the deck itself or one of its subdecks")
+			}
+			if _, err := s.GetDeckByID(ctx, userID, *req.ParentID); err != nil {
+				return nil, err
+			}
+			updates["parent_id"] = *req.ParentID
+		}
+	}
+
+	if err := s.repo.UpdateDeck(ctx, userID, id, updates); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetDeckByID(ctx, userID, id)
+}
+
+// GetDeckTreeIDs returns the deck and the IDs of all its subdecks
+func (s *DeckService) GetDeckTreeIDs(ctx context.Context, userID, id int) ([]int, error) {
+	if id <= 0 {
+		return nil, models.Invalid("invalid deck ID: %d", id)
+	}
+
+	return s.repo.GetDeckTreeIDs(ctx, userID, id)
+}
+
+func (s *DeckService) DeleteDeck(ctx context.Context, userID, id int) error {
+	if id <= 0 {
+		return models.Invalid("invalid deck ID: %d", id)
+	}
+
+	if err := s.repo.DeleteDeck(ctx, userID, id); err != nil {
+		return err
+	}
+	if s.noteCache != nil {
+		s.noteCache.Invalidate(ctx, userID)
+	}
+	return nil
+}
+
+// GetTerminology returns the deck's terminology dictionary, which is empty
+// until one is saved
+func (s *DeckService) GetTerminology(ctx context.Context, userID, deckID int) (*models.DeckTerminology, error) {
+	if _, err := s.GetDeckByID(ctx, userID, deckID); err != nil {
+		return nil, err
+	}
+
+	terminologies, err := s.repo.GetTerminology(ctx, userID, []int{deckID})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(terminologies) == 0 {
+		return &models.DeckTerminology{
+			DeckID:         deckID,
+			PreferredTerms: []models.PreferredTerm{},
+			Abbreviations:  []models.Abbreviation{},
+			DoNotSimplify:  []string{},
+			StopWords:      []string{},
+		}, nil
+	}
+
+	return terminologies[0], nil
+}
+
+// UpdateTerminology replaces the deck's terminology dictionary
+func (s *DeckService) UpdateTerminology(ctx context.Context, userID, deckID int, req *models.UpdateDeckTerminologyRequest) (*models.DeckTerminology, error) {
+	if req == nil {
+		return nil, models.Invalid("request cannot be nil")
+	}
+
+	if _, err := s.GetDeckByID(ctx, userID, deckID); err != nil {
+		return nil, err
+	}
+
+	terminology := &models.DeckTerminology{
+		DeckID:         deckID,
+		PreferredTerms: []models.PreferredTerm{},
+		Abbreviations:  []models.Abbreviation{},
+	}
+
+	for _, preferred := range req.PreferredTerms {
+		term := strings.TrimSpace(preferred.Term)
+		if term == "" {
+			return nil, models.Invalid("preferred terms cannot be empty")
+		}
+		avoid, err := cleanTerms(preferred.Avoid, "avoided terms")
+		if err != nil {
+			return nil, err
+		}
+		terminology.PreferredTerms = append(terminology.PreferredTerms, models.PreferredTerm{Term: term, Avoid: avoid})
+	}
+
+	for _, abbreviation := range req.Abbreviations {
+		short := strings.TrimSpace(abbreviation.Short)
+		expansion := strings.TrimSpace(abbreviation.Expansion)
+		if short == "" || expansion == "" {
+			return nil, models.Invalid("abbreviations need both short and expansion")
+		}
+		terminology.Abbreviations = append(terminology.Abbreviations, models.Abbreviation{Short: short, Expansion: expansion})
+	}
+
+	var err error
+	if terminology.DoNotSimplify, err = cleanTerms(req.DoNotSimplify, "do-not-simplify terms"); err != nil {
+		return nil, err
+	}
+	if terminology.StopWords, err = cleanTerms(req.StopWords, "stop words"); err != nil {
+		return nil, err
+	}
+	for i, word := range terminology.StopWords {
+		terminology.StopWords[i] = strings.ToLower(word)
+	}
+
+	entries := len(terminology.PreferredTerms) + len(terminology.Abbreviations) + len(terminology.DoNotSimplify) + len(terminology.StopWords)
+	if entries > MAX_TERMINOLOGY_ENTRIES {
+		return nil, models.Invalid("a deck cannot have more than %d terminology entries", MAX_TERMINOLOGY_ENTRIES)
+	}
+
+	if err := s.repo.SaveTerminology(ctx, terminology); err != nil {
+		return nil, err
+	}
+
+	return terminology, nil
+}
+
+// TerminologyForDecks returns the saved dictionaries for the given decks,
+// keyed by deck ID
+func (s *DeckService) TerminologyForDecks(ctx context.Context, userID int, deckIDs []int) (map[int]*models.DeckTerminology, error) {
+	byDeck := make(map[int]*models.DeckTerminology)
+	if len(deckIDs) == 0 {
+		return byDeck, nil
+	}
+
+	terminologies, err := s.repo.GetTerminology(ctx, userID, deckIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, terminology := range terminologies {
+		byDeck[terminology.DeckID] = terminology
+	}
+
+	return byDeck, nil
+}
+
+// GetStudySettings returns the deck's study settings, or the defaults until
+// some are saved
+func (s *DeckService) GetStudySettings(ctx context.Context, userID, deckID int) (*models.DeckStudySettings, error) {
+	if _, err := s.GetDeckByID(ctx, userID, deckID); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.repo.GetStudySettings(ctx, userID, deckID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return defaultStudySettings(deckID), nil
+		}
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// UpdateStudySettings replaces the deck's study settings
+func (s *DeckService) UpdateStudySettings(ctx context.Context, userID, deckID int, req *models.UpdateDeckStudySettingsRequest) (*models.DeckStudySettings, error) {
+	if req == nil {
+		return nil, models.Invalid("request cannot be nil")
+	}
+
+	if _, err := s.GetDeckByID(ctx, userID, deckID); err != nil {
+		return nil, err
+	}
+
+	settings := defaultStudySettings(deckID)
+
+	var err error
+	if req.LearningSteps != nil {
+		if settings.LearningSteps, err = cleanLearningSteps(req.LearningSteps, "learning steps"); err != nil {
+			return nil, err
+		}
+	}
+	if req.RelearningSteps != nil {
+		if settings.RelearningSteps, err = cleanLearningSteps(req.RelearningSteps, "relearning steps"); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.GraduatingIntervalDays != nil {
+		settings.GraduatingIntervalDays = *req.GraduatingIntervalDays
+	}
+	if req.EasyIntervalDays != nil {
+		settings.EasyIntervalDays = *req.EasyIntervalDays
+	}
+	if settings.GraduatingIntervalDays < 1 || settings.GraduatingIntervalDays > MAX_INTERVAL_DAYS {
+		return nil, models.Invalid("graduatingIntervalDays must be between 1 and %d", MAX_INTERVAL_DAYS)
+	}
+	if settings.EasyIntervalDays < settings.GraduatingIntervalDays || settings.EasyIntervalDays > MAX_INTERVAL_DAYS {
+		return nil, models.Invalid("easyIntervalDays must be between graduatingIntervalDays and %d", MAX_INTERVAL_DAYS)
+	}
+
+	if req.NewCardsPerDay != nil {
+		settings.NewCardsPerDay = *req.NewCardsPerDay
+	}
+	if settings.NewCardsPerDay < 0 || settings.NewCardsPerDay > MAX_NEW_CARDS_PER_DAY {
+		return nil, models.Invalid("newCardsPerDay must be between 0 and %d", MAX_NEW_CARDS_PER_DAY)
+	}
+
+	if req.NewCardOrder != "" {
+		if !containsValue(validNewCardOrders, req.NewCardOrder) {
+			return nil, models.Invalid("newCardOrder must be one of: %s", strings.Join(validNewCardOrders, ", "))
+		}
+		settings.NewCardOrder = req.NewCardOrder
+	}
+	if req.NewCardPosition != "" {
+		if !containsValue(validNewCardPositions, req.NewCardPosition) {
+			return nil, models.Invalid("newCardPosition must be one of: %s", strings.Join(validNewCardPositions, ", "))
+		}
+		settings.NewCardPosition = req.NewCardPosition
+	}
+
+	if req.LeechThreshold != nil {
+		settings.LeechThreshold = *req.LeechThreshold
+	}
+	if settings.LeechThreshold < 0 || settings.LeechThreshold > MAX_LEECH_THRESHOLD {
+		return nil, models.Invalid("leechThreshold must be between 0 and %d", MAX_LEECH_THRESHOLD)
+	}
+	if req.LeechAction != "" {
+		if !containsValue(validLeechActions, req.LeechAction) {
+			return nil, models.Invalid("leechAction must be one of: %s", strings.Join(validLeechActions, ", "))
+		}
+		settings.LeechAction = req.LeechAction
+	}
+
+	if err := s.repo.SaveStudySettings(ctx, settings); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// terminologyGuidance renders deck dictionaries as prompt instructions.
+// Returns an empty string when there is nothing to enforce.
+func terminologyGuidance(terminologies map[int]*models.DeckTerminology) string {
+	deckIDs := make([]int, 0, len(terminologies))
+	for deckID := range terminologies {
+		deckIDs = append(deckIDs, deckID)
+	}
+	sort.Ints(deckIDs)
+
+	var preferred, abbreviations, keep []string
+	for _, deckID := range deckIDs {
+		terminology := terminologies[deckID]
+		for _, term := range terminology.PreferredTerms {
+			if len(term.Avoid) > 0 {
+				preferred = append(preferred, fmt.Sprintf("%q (not %s)", term.Term, quoteJoin(term.Avoid)))
+			} else {
+				preferred = append(preferred, fmt.Sprintf("%q", term.Term))
+			}
+		}
+		for _, abbreviation := range terminology.Abbreviations {
+			abbreviations = append(abbreviations, fmt.Sprintf("%s = %s", abbreviation.Short, abbreviation.Expansion))
+		}
+		keep = append(keep, terminology.DoNotSimplify...)
+	}
+
+	var b strings.Builder
+	if len(preferred) > 0 {
+		fmt.Fprintf(&b, "Use these exact terms from the course: %s.\n", strings.Join(preferred, ", "))
+	}
+	if len(abbreviations) > 0 {
+		fmt.Fprintf(&b, "Use these abbreviations as defined in the course: %s.\n", strings.Join(abbreviations, "; "))
+	}
+	if len(keep) > 0 {
+		fmt.Fprintf(&b, "Never simplify, paraphrase or replace these terms: %s.\n", quoteJoin(keep))
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+func quoteJoin(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = fmt.Sprintf("%q", term)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// Trim terms and reject blanks and overly long entries
+func cleanTerms(terms []string, label string) ([]string, error) {
+	cleaned := make([]string, 0, len(terms))
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			return nil, models.Invalid("%s cannot be empty", label)
+		}
+		if len(term) > MAX_TERMINOLOGY_TERM_LENGTH {
+			return nil, models.Invalid("%s cannot exceed %d characters", label, MAX_TERMINOLOGY_TERM_LENGTH)
+		}
+		cleaned = append(cleaned, term)
+	}
+	return cleaned, nil
+}
+
+func (s *DeckService) validateCreateRequest(req *models.CreateDeckRequest) error {
+	if req == nil {
+		return models.Invalid("request cannot be nil")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return models.Invalid("name is required")
+	}
+
+	if len(name) > MAX_DECK_NAME_LENGTH {
+		return models.Invalid("name cannot exceed %d characters", MAX_DECK_NAME_LENGTH)
+	}
+
+	if req.ReviewOrder != "" && !containsValue(validReviewOrders, req.ReviewOrder) {
+		return models.Invalid("reviewOrder must be one of: %s", strings.Join(validReviewOrders, ", "))
+	}
+
+	return nil
+}
+
+func (s *DeckService) validateUpdateRequest(req *models.UpdateDeckRequest) error {
+	if req == nil {
+		return models.Invalid("request cannot be nil")
+	}
+
+	if req.Name == nil && req.Description == nil && req.ParentID == nil && req.ReviewOrder == nil {
+		return models.Invalid("at least one field must be provided for update")
+	}
+
+	if req.Name != nil && len(strings.TrimSpace(*req.Name)) > MAX_DECK_NAME_LENGTH {
+		return models.Invalid("name cannot exceed %d characters", MAX_DECK_NAME_LENGTH)
+	}
+
+	if req.ReviewOrder != nil && !containsValue(validReviewOrders, *req.ReviewOrder) {
+		return models.Invalid("reviewOrder must be one of: %s", strings.Join(validReviewOrders, ", "))
+	}
+
+	return nil
+}
+
+// Stop words of a deck as a lookup set for the context compressor
+func stopWordSet(terminology *models.DeckTerminology) map[string]bool {
+	if terminology == nil || len(terminology.StopWords) == 0 {
+		return nil
+	}
+
+	set := make(map[string]bool, len(terminology.StopWords))
+	for _, word := range terminology.StopWords {
+		set[word] = true
+	}
+	return set
+}
+
+func containsID(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
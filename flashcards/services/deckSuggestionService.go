@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+// Cards that lapsed this many times are suggested as leeches, the
+// threshold Anki uses. It is also the leech threshold of decks without
+// saved study settings.
+const LEECH_LAPSES = 8
+
+// DeckSuggestionService finds maintenance work in decks once a week and
+// applies or dismisses what it suggests
+type DeckSuggestionService struct {
+	repo             db.DeckSuggestionRepository
+	deckService      *DeckService
+	flashcardService *FlashcardService
+	tagService       *TagService
+}
+
+func NewDeckSuggestionService(repo db.DeckSuggestionRepository, deckService *DeckService, flashcardService *FlashcardService, tagService *TagService) *DeckSuggestionService {
+	return &DeckSuggestionService{repo: repo, deckService: deckService, flashcardService: flashcardService, tagService: tagService}
+}
+
+// AnalyzeDecks replaces the open suggestions of every deck with ones for
+// the decks as they are now
+func (s *DeckSuggestionService) AnalyzeDecks(ctx context.Context) error {
+	suggestions, err := s.repo.FindSuggestions(ctx, LEECH_LAPSES)
+	if err != nil {
+		return err
+	}
+
+	stored, err := s.repo.ReplaceOpenSuggestions(ctx, suggestions)
+	if err != nil {
+		return err
+	}
+
+	LoggerFromContext(ctx).Info("Analyzed decks for maintenance suggestions", "found", len(suggestions), "stored", stored)
+	return nil
+}
+
+// ListSuggestions returns the deck's open suggestions from the last
+// analysis
+func (s *DeckSuggestionService) ListSuggestions(ctx context.Context, userID, deckID int) ([]*models.DeckSuggestion, error) {
+	deck, err := s.deckService.GetDeckByID(ctx, userID, deckID)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions, err := s.repo.ListOpenSuggestions(ctx, userID, deckID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, suggestion := range suggestions {
+		suggestion.Action = suggestionAction(suggestion, deck)
+	}
+	return suggestions, nil
+}
+
+// ApplySuggestion makes the change an open suggestion describes and marks
+// it applied. Cards deleted since the analysis are skipped.
+func (s *DeckSuggestionService) ApplySuggestion(ctx context.Context, userID, deckID, id int) (*models.AppliedSuggestion, error) {
+	deck, err := s.deckService.GetDeckByID(ctx, userID, deckID)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestion, err := s.repo.GetSuggestion(ctx, userID, deckID, id)
+	if err != nil {
+		return nil, err
+	}
+	if suggestion.Status != models.SuggestionOpen {
+		return nil, models.Conflict("suggestion %d is already %s", id, suggestion.Status)
+	}
+
+	applied := &models.AppliedSuggestion{Created: []*models.Flashcard{}, DeletedIDs: []int{}}
+	switch suggestion.Kind {
+	case models.SuggestionDuplicate:
+		if err := s.deleteFlashcards(ctx, userID, suggestion.FlashcardIDs[1:], applied); err != nil {
+			return nil, err
+		}
+	case models.SuggestionLeech:
+		if err := s.repo.ResetSchedules(ctx, userID, suggestion.FlashcardIDs); err != nil {
+			return nil, err
+		}
+	case models.SuggestionStale:
+		count := min(len(suggestion.FlashcardIDs), MAX_GENERATED_FLASHCARDS)
+		created, err := s.flashcardService.GenerateFromNote(ctx, userID, suggestion.NoteIDs[0], &models.GenerateFlashcardsRequest{Count: count})
+		if err != nil {
+			return nil, err
+		}
+		applied.Created = created
+		if err := s.deleteFlashcards(ctx, userID, suggestion.FlashcardIDs, applied); err != nil {
+			return nil, err
+		}
+	case models.SuggestionUntagged:
+		tags := &models.AddTagsRequest{Tags: []string{deckTag(deck.Name)}}
+		for _, noteID := range suggestion.NoteIDs {
+			if _, err := s.tagService.AddTagsToNote(ctx, userID, noteID, tags); err != nil && !errors.Is(err, models.ErrNotFound) {
+				return nil, err
+			}
+		}
+	default:
+		return nil, models.Invalid("unknown suggestion kind %q", suggestion.Kind)
+	}
+
+	resolved, err := s.repo.ResolveSuggestion(ctx, userID, deckID, id, models.SuggestionApplied)
+	if err != nil {
+		return nil, err
+	}
+	resolved.Action = suggestionAction(resolved, deck)
+	applied.Suggestion = *resolved
+
+	return applied, nil
+}
+
+// DismissSuggestion closes an open suggestion without changing the deck.
+// Later analyses do not suggest it again for the same cards.
+func (s *DeckSuggestionService) DismissSuggestion(ctx context.Context, userID, deckID, id int) (*models.DeckSuggestion, error) {
+	deck, err := s.deckService.GetDeckByID(ctx, userID, deckID)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestion, err := s.repo.ResolveSuggestion(ctx, userID, deckID, id, models.SuggestionDismissed)
+	if err != nil {
+		return nil, err
+	}
+	suggestion.Action = suggestionAction(suggestion, deck)
+
+	return suggestion, nil
+}
+
+func (s *DeckSuggestionService) deleteFlashcards(ctx context.Context, userID int, ids []int, applied *models.AppliedSuggestion) error {
+	for _, id := range ids {
+		err := s.flashcardService.DeleteFlashcard(ctx, userID, id)
+		if errors.Is(err, models.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		applied.DeletedIDs = append(applied.DeletedIDs, id)
+	}
+	return nil
+}
+
+// suggestionAction describes what applying the suggestion does
+func suggestionAction(suggestion *models.DeckSuggestion, deck *models.Deck) string {
+	ids := suggestion.FlashcardIDs
+	switch suggestion.Kind {
+	case models.SuggestionDuplicate:
+		return fmt.Sprintf("Keep card %d and delete the other %d", ids[0], len(ids)-1)
+	case models.SuggestionLeech:
+		return fmt.Sprintf("Reset card %d so it is learned from scratch", ids[0])
+	case models.SuggestionStale:
+		return fmt.Sprintf("Generate %d new cards from note %d and delete these", min(len(ids), MAX_GENERATED_FLASHCARDS), suggestion.NoteIDs[0])
+	case models.SuggestionUntagged:
+		return fmt.Sprintf("Tag %d notes with %q", len(suggestion.NoteIDs), deckTag(deck.Name))
+	}
+	return ""
+}
+
+// deckTag is the tag untagged notes get: the deck's name, cut to the
+// longest tag allowed
+func deckTag(name string) string {
+	tag := normalizeTag(name)
+	for len(tag) > MAX_TAG_LENGTH {
+		_, size := utf8.DecodeLastRuneInString(tag)
+		tag = tag[:len(tag)-size]
+	}
+	return strings.TrimSpace(tag)
+}
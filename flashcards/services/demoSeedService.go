@@ -0,0 +1,355 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"flashcards/models"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+const (
+	DEFAULT_DEMO_DECKS              = 3
+	DEFAULT_DEMO_NOTES_PER_DECK     = 3
+	DEFAULT_DEMO_CARDS_PER_DECK     = 6
+	DEFAULT_DEMO_QUESTIONS_PER_DECK = 5
+	MAX_DEMO_DECKS                  = 10
+	MAX_DEMO_ITEMS_PER_DECK         = 20
+
+	DEMO_DATASET_PROMPT_TEMPLATE = `You are building a realistic demo account for a flashcard study app. The learner is studying: %s
+
+Create %d decks covering different parts of the subject. For each deck write:
+- a short name and a one-sentence description
+- %d study notes, each a few paragraphs in the learner's own words, the way a diligent student takes notes
+- %d flashcards drawn from the notes, with self-contained questions and short, precise answers
+- %d quiz questions the learner has already answered, mixing multiple-choice and true-false at varied difficulty. Multiple-choice options are labelled "A) ", "B) " and so on, and the correct answer is the letter. True-false answers are "True" or "False". "note" is the index, from 0, of the note the question is based on. "learnerAnswer" is what the learner answered: about two thirds correct and the rest plausible mistakes, or an empty string for a skipped question.
+
+Keep the content factually accurate. Respond with valid JSON in this exact format:
+{
+  "decks": [
+    {
+      "name": "Deck name",
+      "description": "What the deck covers",
+      "notes": [{"content": "Note text"}],
+      "flashcards": [{"question": "The question text", "answer": "The answer text"}],
+      "questions": [
+        {
+          "question": "The question text",
+          "type": "multiple-choice",
+          "options": ["A) Option 1", "B) Option 2", "C) Option 3", "D) Option 4"],
+          "correctAnswer": "A",
+          "explanation": "Why the answer is correct",
+          "difficulty": "medium",
+          "note": 0,
+          "learnerAnswer": "B"
+        }
+      ]
+    }
+  ]
+}`
+)
+
+var demoDatasetSchema = outputSchema{
+	Name:        "demo_dataset",
+	Description: "Return the demo decks with their notes, flashcards and answered quiz questions",
+	Schema: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"decks": map[string]any{
+				"type":     "array",
+				"minItems": 1,
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"name":        map[string]any{"type": "string"},
+						"description": map[string]any{"type": "string"},
+						"notes": map[string]any{
+							"type":     "array",
+							"minItems": 1,
+							"items": map[string]any{
+								"type": "object",
+								"properties": map[string]any{
+									"content": map[string]any{"type": "string"},
+								},
+								"required": []string{"content"},
+							},
+						},
+						"flashcards": map[string]any{
+							"type": "array",
+							"items": map[string]any{
+								"type": "object",
+								"properties": map[string]any{
+									"question": map[string]any{"type": "string"},
+									"answer":   map[string]any{"type": "string"},
+								},
+								"required": []string{"question", "answer"},
+							},
+						},
+						"questions": map[string]any{
+							"type": "array",
+							"items": map[string]any{
+								"type": "object",
+								"properties": map[string]any{
+									"question":      map[string]any{"type": "string"},
+									"type":          map[string]any{"type": "string", "enum": []string{"multiple-choice", "true-false"}},
+									"options":       map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+									"correctAnswer": map[string]any{"type": "string"},
+									"explanation":   map[string]any{"type": "string"},
+									"difficulty":    map[string]any{"type": "string", "enum": validDifficulties},
+									"note":          map[string]any{"type": "integer"},
+									"learnerAnswer": map[string]any{"type": "string"},
+								},
+								"required": []string{"question", "type", "correctAnswer", "explanation", "difficulty", "note", "learnerAnswer"},
+							},
+						},
+					},
+					"required": []string{"name", "description", "notes", "flashcards", "questions"},
+				},
+			},
+		},
+		"required": []string{"decks"},
+	},
+}
+
+// DemoDatasetOptions sizes a generated demo dataset. Zero values select the
+// defaults.
+type DemoDatasetOptions struct {
+	Subject          string
+	Decks            int
+	NotesPerDeck     int
+	CardsPerDeck     int
+	QuestionsPerDeck int
+}
+
+// DemoDataset is the content of a demo account as generated by the LLM
+type DemoDataset struct {
+	Decks []DemoDeck `json:"decks"`
+}
+
+type DemoDeck struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Notes       []DemoNote      `json:"notes"`
+	Flashcards  []FlashcardPair `json:"flashcards"`
+	Questions   []DemoQuestion  `json:"questions"`
+}
+
+type DemoNote struct {
+	Content string `json:"content"`
+}
+
+// DemoQuestion is a quiz question with the learner's earlier answer. Note
+// is the index of the deck note it is based on.
+type DemoQuestion struct {
+	llmQuestion
+	Note          int    `json:"note"`
+	LearnerAnswer string `json:"learnerAnswer"`
+}
+
+func (o *DemoDatasetOptions) normalize() error {
+	o.Subject = strings.TrimSpace(o.Subject)
+	if o.Subject == "" {
+		return models.Invalid("subject is required")
+	}
+
+	o.Decks = demoCount(o.Decks, DEFAULT_DEMO_DECKS, MAX_DEMO_DECKS)
+	o.NotesPerDeck = demoCount(o.NotesPerDeck, DEFAULT_DEMO_NOTES_PER_DECK, MAX_DEMO_ITEMS_PER_DECK)
+	o.CardsPerDeck = demoCount(o.CardsPerDeck, DEFAULT_DEMO_CARDS_PER_DECK, MAX_DEMO_ITEMS_PER_DECK)
+	o.QuestionsPerDeck = demoCount(o.QuestionsPerDeck, DEFAULT_DEMO_QUESTIONS_PER_DECK, min(MAX_DEMO_ITEMS_PER_DECK, MAX_SESSION_QUESTIONS))
+	return nil
+}
+
+func demoCount(count, defaultCount, maxCount int) int {
+	if count <= 0 {
+		return defaultCount
+	}
+	return min(count, maxCount)
+}
+
+// GenerateDemoDataset asks the LLM for decks, notes, flashcards and answered
+// quiz questions on a subject. Questions that point at a missing note or
+// lack what their type needs are sent back for correction.
+func (s *QuizService) GenerateDemoDataset(ctx context.Context, options DemoDatasetOptions) (*DemoDataset, error) {
+	if err := options.normalize(); err != nil {
+		return nil, err
+	}
+
+	logger := LoggerFromContext(ctx)
+	logger.Info("Generating demo dataset", "subject", options.Subject, "decks", options.Decks,
+		"notes_per_deck", options.NotesPerDeck, "cards_per_deck", options.CardsPerDeck, "questions_per_deck", options.QuestionsPerDeck)
+	startTime := time.Now()
+
+	prompt := fmt.Sprintf(DEMO_DATASET_PROMPT_TEMPLATE, options.Subject, options.Decks, options.NotesPerDeck, options.CardsPerDeck, options.QuestionsPerDeck)
+	if guidance := s.contentFilter.PromptGuidance(ctx); guidance != "" {
+		prompt += "\n\n" + guidance
+	}
+
+	ctx, cancel := s.withLLMTimeout(ctx)
+	defer cancel()
+	var dataset DemoDataset
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, prompt)}
+	err := s.generateStructured(ctx, s.llmClient, messages, demoDatasetSchema, &dataset, dataset.validate, llms.WithTemperature(0.7))
+	if err != nil {
+		if errors.Is(err, errInvalidStructuredOutput) {
+			logger.Error("Failed to parse demo dataset response", "error", err)
+			return nil, models.Upstream("failed to parse demo dataset response: %w", err)
+		}
+		logger.Error("Demo dataset LLM call failed", "duration_ms", time.Since(startTime).Milliseconds(), "error", err)
+		return nil, fmt.Errorf("demo dataset generation failed: %w", err)
+	}
+
+	logger.Info("Demo dataset generated", "duration_ms", time.Since(startTime).Milliseconds(), "decks", len(dataset.Decks))
+	return &dataset, nil
+}
+
+func (d *DemoDataset) validate() error {
+	for i, deck := range d.Decks {
+		if strings.TrimSpace(deck.Name) == "" {
+			return models.Invalid("decks[%d].name must not be empty", i)
+		}
+		for j, question := range deck.Questions {
+			if err := question.validate(); err != nil {
+				return fmt.Errorf("decks[%d].questions[%d]: %w", i, j, err)
+			}
+			if question.Note < 0 || question.Note >= len(deck.Notes) {
+				return models.Invalid("decks[%d].questions[%d].note must be the index of one of the deck's %d notes", i, j, len(deck.Notes))
+			}
+		}
+	}
+	return nil
+}
+
+// DemoSeedSummary counts what was created for a demo account
+type DemoSeedSummary struct {
+	Decks      int
+	Notes      int
+	Flashcards int
+	Sessions   int
+}
+
+// DemoSeedService fills an account with a generated demo dataset so demos
+// and screenshots don't rely on hand-written fixtures. Everything is created
+// through the regular services, so it passes the same validation and quotas
+// as content a user adds.
+type DemoSeedService struct {
+	quizService      *QuizService
+	deckService      *DeckService
+	noteService      *NoteService
+	flashcardService *FlashcardService
+	sessionService   *QuizSessionService
+}
+
+func NewDemoSeedService(quizService *QuizService, deckService *DeckService, noteService *NoteService, flashcardService *FlashcardService, sessionService *QuizSessionService) *DemoSeedService {
+	return &DemoSeedService{
+		quizService:      quizService,
+		deckService:      deckService,
+		noteService:      noteService,
+		flashcardService: flashcardService,
+		sessionService:   sessionService,
+	}
+}
+
+// Seed generates a dataset and stores it for the user: a deck per generated
+// deck holding its notes and flashcards, and a completed quiz session per
+// deck with the learner's answers. Sessions are dated when they are seeded.
+func (s *DemoSeedService) Seed(ctx context.Context, userID int, options DemoDatasetOptions) (*DemoSeedSummary, error) {
+	dataset, err := s.quizService.GenerateDemoDataset(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &DemoSeedSummary{}
+	for _, demoDeck := range dataset.Decks {
+		if err := s.seedDeck(ctx, userID, demoDeck, summary); err != nil {
+			return summary, err
+		}
+	}
+
+	LoggerFromContext(ctx).Info("Seeded demo dataset", "user_id", userID, "decks", summary.Decks,
+		"notes", summary.Notes, "flashcards", summary.Flashcards, "sessions", summary.Sessions)
+	return summary, nil
+}
+
+func (s *DemoSeedService) seedDeck(ctx context.Context, userID int, demoDeck DemoDeck, summary *DemoSeedSummary) error {
+	deck, err := s.deckService.CreateDeck(ctx, userID, &models.CreateDeckRequest{
+		Name:        strings.TrimSpace(demoDeck.Name),
+		Description: strings.TrimSpace(demoDeck.Description),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create demo deck %q: %w", demoDeck.Name, err)
+	}
+	summary.Decks++
+
+	noteIDs := make([]int, len(demoDeck.Notes))
+	for i, demoNote := range demoDeck.Notes {
+		note, err := s.noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Content: demoNote.Content, DeckID: &deck.ID})
+		if err != nil {
+			return fmt.Errorf("failed to create demo note in deck %q: %w", deck.Name, err)
+		}
+		noteIDs[i] = note.ID
+		summary.Notes++
+	}
+
+	for _, pair := range demoDeck.Flashcards {
+		req := &models.CreateFlashcardRequest{Front: pair.Question, Back: pair.Answer, DeckID: &deck.ID}
+		if _, err := s.flashcardService.CreateFlashcard(ctx, userID, req); err != nil {
+			return fmt.Errorf("failed to create demo flashcard in deck %q: %w", deck.Name, err)
+		}
+		summary.Flashcards++
+	}
+
+	if len(demoDeck.Questions) == 0 {
+		return nil
+	}
+	if err := s.seedSession(ctx, userID, demoDeck.Questions, noteIDs); err != nil {
+		return fmt.Errorf("failed to create demo quiz session for deck %q: %w", deck.Name, err)
+	}
+	summary.Sessions++
+	return nil
+}
+
+// seedSession records a quiz session over the questions as the learner
+// answered them, then completes it
+func (s *DemoSeedService) seedSession(ctx context.Context, userID int, demoQuestions []DemoQuestion, noteIDs []int) error {
+	questions := make([]models.QuestionData, len(demoQuestions))
+	for i, demoQuestion := range demoQuestions {
+		questions[i] = s.quizService.buildQuestionData(ctx, demoQuestion.llmQuestion, []int{noteIDs[demoQuestion.Note]})
+	}
+
+	session, err := s.sessionService.CreateSession(ctx, userID, &models.CreateQuizSessionRequest{Questions: questions})
+	if err != nil {
+		return err
+	}
+
+	for position, demoQuestion := range demoQuestions {
+		update := &models.UpdateSessionQuestionRequest{}
+		if answer := strings.TrimSpace(demoQuestion.LearnerAnswer); answer != "" {
+			timeSpentMs := demoAnswerTime(demoQuestion.Difficulty, position)
+			update.Answer = &answer
+			update.TimeSpentMs = &timeSpentMs
+		} else {
+			status := models.QuestionStatusSkipped
+			update.Status = &status
+		}
+		if _, err := s.sessionService.UpdateQuestion(ctx, userID, session.ID, position, update); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.sessionService.CompleteSession(ctx, userID, session.ID)
+	return err
+}
+
+// demoAnswerTime is how long the learner took over a question: longer for
+// harder ones, varied a little so charts don't show a flat line
+func demoAnswerTime(difficulty string, position int) int {
+	base := map[string]int{"easy": 8000, "medium": 15000, "hard": 25000}[difficulty]
+	if base == 0 {
+		base = 15000
+	}
+	return base + (position%4)*2500
+}
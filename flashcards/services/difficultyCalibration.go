@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+
+	"flashcards/models"
+)
+
+const (
+	// Recent graded answers on a topic the difficulty is calibrated from
+	DIFFICULTY_CALIBRATION_WINDOW = 20
+	// Fewer answers than this say too little, so medium is used
+	MIN_CALIBRATION_ANSWERS = 5
+	// Accuracy at the current difficulty that moves the learner up a level,
+	// and below which they move down one
+	DIFFICULTY_STEP_UP_ACCURACY   = 0.8
+	DIFFICULTY_STEP_DOWN_ACCURACY = 0.5
+)
+
+// calibrateDifficulty picks the difficulty for a user who did not ask for
+// one, from their recent answers on the topics of the notes. The learner is
+// taken to be at the difficulty of their latest answer; answering most
+// questions at that level correctly moves them up one, missing half or more
+// moves them down one. When the answers cannot be read, or there are too
+// few, medium is used.
+func (s *QuizService) calibrateDifficulty(ctx context.Context, userID int, noteIds []int) models.DifficultyCalibration {
+	logger := LoggerFromContext(ctx)
+	calibration := models.DifficultyCalibration{Difficulty: "medium", Source: models.DifficultySourceDefault}
+
+	answers, err := s.bank.GetRecentGradedAnswers(ctx, userID, noteIds, DIFFICULTY_CALIBRATION_WINDOW)
+	if err != nil {
+		logger.Error("Failed to load recent answers, using default difficulty", "error", err)
+		return calibration
+	}
+	calibration.Answers = len(answers)
+	if len(answers) < MIN_CALIBRATION_ANSWERS {
+		logger.Info("Too few recent answers to calibrate difficulty, using default", "answers", len(answers))
+		return calibration
+	}
+
+	current := answers[0].Difficulty
+	if !containsValue(validDifficulties, current) {
+		current = "medium"
+	}
+
+	accuracy := answerAccuracy(answers, current)
+	if accuracy == nil {
+		// Too few answers at this level; judge by all of them
+		accuracy = answerAccuracy(answers, "")
+	}
+
+	difficulty := current
+	switch {
+	case *accuracy >= DIFFICULTY_STEP_UP_ACCURACY:
+		difficulty = stepDifficulty(current, 1)
+	case *accuracy < DIFFICULTY_STEP_DOWN_ACCURACY:
+		difficulty = stepDifficulty(current, -1)
+	}
+
+	logger.Info("Calibrated difficulty from recent answers", "difficulty", difficulty, "current", current,
+		"accuracy", *accuracy, "answers", len(answers))
+	return models.DifficultyCalibration{
+		Difficulty: difficulty,
+		Source:     models.DifficultySourceCalibrated,
+		Accuracy:   accuracy,
+		Answers:    len(answers),
+	}
+}
+
+// answerAccuracy is the share of correct answers at the difficulty, or of
+// all answers when difficulty is empty. It is nil with fewer than
+// MIN_CALIBRATION_ANSWERS to go on.
+func answerAccuracy(answers []models.GradedAnswer, difficulty string) *float64 {
+	graded, correct := 0, 0
+	for _, answer := range answers {
+		if difficulty != "" && answer.Difficulty != difficulty {
+			continue
+		}
+		graded++
+		if answer.Correct {
+			correct++
+		}
+	}
+	if graded < MIN_CALIBRATION_ANSWERS {
+		return nil
+	}
+
+	accuracy := float64(correct) / float64(graded)
+	return &accuracy
+}
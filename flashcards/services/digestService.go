@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	// How long the review link in a digest email works
+	DIGEST_LINK_TTL = 72 * time.Hour
+	// Cards listed in and linked from one digest, failed cards first
+	MAX_DIGEST_CARDS = 20
+)
+
+// DigestService emails users the cards they failed or added the day before,
+// with a signed link that opens a review of exactly those cards
+type DigestService struct {
+	repo        db.DigestRepository
+	authService *AuthService
+	mailer      *Mailer
+	appURL      string
+}
+
+func NewDigestService(repo db.DigestRepository, authService *AuthService, mailer *Mailer, appURL string) *DigestService {
+	return &DigestService{
+		repo:        repo,
+		authService: authService,
+		mailer:      mailer,
+		appURL:      appURL,
+	}
+}
+
+// SendDailyDigests emails each user with cards failed or added yesterday.
+// A failure for one user does not stop the others.
+func (s *DigestService) SendDailyDigests(ctx context.Context) error {
+	logger := LoggerFromContext(ctx)
+	if !s.mailer.Enabled() {
+		logger.Info("Skipping review digest emails, email delivery is not configured")
+		return nil
+	}
+
+	today := truncateToDay(time.Now())
+	yesterday := today.AddDate(0, 0, -1)
+
+	cards, err := s.repo.GetDigestCards(ctx, yesterday, today)
+	if err != nil {
+		return err
+	}
+
+	cardsByUser := make(map[int][]*models.DigestCard)
+	var userIDs []int
+	for _, card := range cards {
+		if _, ok := cardsByUser[card.UserID]; !ok {
+			userIDs = append(userIDs, card.UserID)
+		}
+		cardsByUser[card.UserID] = append(cardsByUser[card.UserID], card)
+	}
+
+	failed := 0
+	for _, userID := range userIDs {
+		if err := s.sendDigest(ctx, userID, cardsByUser[userID], yesterday); err != nil {
+			logger.Error("Failed to send review digest", "user_id", userID, "error", err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to send review digests for %d of %d users", failed, len(userIDs))
+	}
+
+	logger.Info("Sent review digests", "users", len(userIDs))
+	return nil
+}
+
+func (s *DigestService) sendDigest(ctx context.Context, userID int, cards []*models.DigestCard, day time.Time) error {
+	total := len(cards)
+	if len(cards) > MAX_DIGEST_CARDS {
+		cards = cards[:MAX_DIGEST_CARDS]
+	}
+
+	flashcardIDs := make([]int, len(cards))
+	for i, card := range cards {
+		flashcardIDs[i] = card.Flashcard.ID
+	}
+
+	token, err := s.authService.SignReviewLink(userID, flashcardIDs, DIGEST_LINK_TTL)
+	if err != nil {
+		return err
+	}
+	link := s.appURL + "/review?link=" + url.QueryEscape(token)
+	expiresAt := time.Now().Add(DIGEST_LINK_TTL)
+
+	LoggerFromContext(ctx).Info("Sending review digest", "user_id", userID, "cards", total)
+	subject := fmt.Sprintf("%d cards to review from %s", total, day.Format("Jan 2"))
+	return s.mailer.SendHTML([]string{cards[0].Email}, subject,
+		renderDigestText(cards, total, link, expiresAt), renderDigestHTML(cards, total, link, expiresAt))
+}
+
+func renderDigestText(cards []*models.DigestCard, total int, link string, expiresAt time.Time) string {
+	var b strings.Builder
+
+	for _, section := range digestSections(cards) {
+		fmt.Fprintf(&b, "%s:\n", section.title)
+		for _, card := range section.cards {
+			front := renderFlashcard(card.Flashcard, false).Front
+			fmt.Fprintf(&b, "  - %s\n", strings.ReplaceAll(front, "\n", " "))
+		}
+		b.WriteString("\n")
+	}
+	if total > len(cards) {
+		fmt.Fprintf(&b, "...and %d more.\n\n", total-len(cards))
+	}
+
+	fmt.Fprintf(&b, "Review these cards now: %s\n\n", link)
+	fmt.Fprintf(&b, "The link signs you in and works until %s.\n", expiresAt.UTC().Format("January 2, 2006 15:04 UTC"))
+	return b.String()
+}
+
+func renderDigestHTML(cards []*models.DigestCard, total int, link string, expiresAt time.Time) string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	b.WriteString("<style>\n" + RENDERED_HTML_STYLESHEET + "\n.card{border:1px solid #ddd;border-radius:4px;padding:.5em 1em;margin:.5em 0}\n</style>\n")
+	b.WriteString("</head>\n<body>\n")
+
+	for _, section := range digestSections(cards) {
+		fmt.Fprintf(&b, "<h2>%s</h2>\n", html.EscapeString(section.title))
+		for _, card := range section.cards {
+			b.WriteString("<div class=\"card\">\n" + RenderMarkdownHTML(card.Flashcard.Front) + "</div>\n")
+		}
+	}
+	if total > len(cards) {
+		fmt.Fprintf(&b, "<p>...and %d more.</p>\n", total-len(cards))
+	}
+
+	fmt.Fprintf(&b, "<p><a href=\"%s\">Review these cards now</a></p>\n", html.EscapeString(link))
+	fmt.Fprintf(&b, "<p>The link signs you in and works until %s.</p>\n", expiresAt.UTC().Format("January 2, 2006 15:04 UTC"))
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
+
+type digestSection struct {
+	title string
+	cards []*models.DigestCard
+}
+
+func digestSections(cards []*models.DigestCard) []digestSection {
+	failed := digestSection{title: "Cards you missed"}
+	added := digestSection{title: "New cards"}
+	for _, card := range cards {
+		if card.Failed {
+			failed.cards = append(failed.cards, card)
+		} else {
+			added.cards = append(added.cards, card)
+		}
+	}
+
+	var sections []digestSection
+	for _, section := range []digestSection{failed, added} {
+		if len(section.cards) > 0 {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
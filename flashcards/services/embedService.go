@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"strings"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+// Cards shown by a deck embed, in the order they were created
+const MAX_EMBED_PREVIEW_CARDS = 5
+
+// EmbedService lets users publish a flashcard or a deck preview for
+// embedding in blogs and course pages. Viewers need no account, only the
+// token in the embed URL, and see nothing but the embedded cards.
+type EmbedService struct {
+	repo             db.EmbedRepository
+	flashcardService *FlashcardService
+	deckService      *DeckService
+}
+
+func NewEmbedService(repo db.EmbedRepository, flashcardService *FlashcardService, deckService *DeckService) *EmbedService {
+	return &EmbedService{repo: repo, flashcardService: flashcardService, deckService: deckService}
+}
+
+// CreateEmbed returns the new embed with its token. The token is not stored
+// and cannot be retrieved again.
+func (s *EmbedService) CreateEmbed(ctx context.Context, userID int, req *models.CreateEmbedRequest) (*models.Embed, error) {
+	if req == nil {
+		return nil, models.Invalid("request cannot be nil")
+	}
+	if (req.FlashcardID == nil) == (req.DeckID == nil) {
+		return nil, models.Invalid("exactly one of flashcardId and deckId is required")
+	}
+
+	if req.FlashcardID != nil {
+		if _, err := s.flashcardService.GetFlashcardByID(ctx, userID, *req.FlashcardID); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := s.deckService.GetDeckByID(ctx, userID, *req.DeckID); err != nil {
+			return nil, err
+		}
+	}
+
+	token, tokenHash, err := newEmbedToken()
+	if err != nil {
+		return nil, err
+	}
+
+	embed := &models.Embed{
+		UserID:      userID,
+		FlashcardID: req.FlashcardID,
+		DeckID:      req.DeckID,
+	}
+	if err := s.repo.CreateEmbed(ctx, embed, tokenHash); err != nil {
+		return nil, err
+	}
+	embed.Token = token
+
+	LoggerFromContext(ctx).Info("Created embed", "embed_id", embed.ID, "kind", embed.Kind)
+	return embed, nil
+}
+
+func (s *EmbedService) ListEmbeds(ctx context.Context, userID int) ([]*models.Embed, error) {
+	return s.repo.ListEmbeds(ctx, userID)
+}
+
+func (s *EmbedService) DeleteEmbed(ctx context.Context, userID, id int) error {
+	if id <= 0 {
+		return models.Invalid("invalid embed ID: %d", id)
+	}
+
+	return s.repo.DeleteEmbed(ctx, userID, id)
+}
+
+// GetEmbedContent renders what the embed with the token shows. Anyone with
+// the token may call it.
+func (s *EmbedService) GetEmbedContent(ctx context.Context, token string) (*models.EmbedContent, error) {
+	if token == "" {
+		return nil, models.NotFound("embed not found")
+	}
+
+	embed, err := s.repo.GetEmbedByTokenHash(ctx, hashEmbedToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	if embed.FlashcardID != nil {
+		flashcard, err := s.flashcardService.GetFlashcardByID(ctx, embed.UserID, *embed.FlashcardID)
+		if err != nil {
+			return nil, err
+		}
+		return &models.EmbedContent{
+			Kind:      models.EmbedKindFlashcard,
+			CardCount: 1,
+			Cards:     []models.FlashcardHTML{*RenderFlashcardHTML(flashcard)},
+		}, nil
+	}
+
+	deck, err := s.deckService.GetDeckByID(ctx, embed.UserID, *embed.DeckID)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := s.flashcardService.ListFlashcards(ctx, embed.UserID, models.FlashcardFilter{DeckID: embed.DeckID},
+		models.ListParams{Limit: MAX_EMBED_PREVIEW_CARDS, Sort: "id", Order: "asc"})
+	if err != nil {
+		return nil, err
+	}
+
+	content := &models.EmbedContent{
+		Kind:        models.EmbedKindDeck,
+		DeckName:    deck.Name,
+		Description: deck.Description,
+		CardCount:   page.Total,
+		Cards:       make([]models.FlashcardHTML, len(page.Items)),
+	}
+	for i, flashcard := range page.Items {
+		content.Cards[i] = *RenderFlashcardHTML(flashcard)
+	}
+	return content, nil
+}
+
+// EmbedHTMLDocument lays out embed content as a standalone page for an
+// iframe. Answers are hidden in <details> so they can be revealed without
+// running any script.
+func EmbedHTMLDocument(content *models.EmbedContent) string {
+	var doc strings.Builder
+	doc.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	title := "Flashcard"
+	if content.Kind == models.EmbedKindDeck {
+		title = content.DeckName
+	}
+	fmt.Fprintf(&doc, "<title>%s</title>\n", html.EscapeString(title))
+	doc.WriteString("<style>\n" + RENDERED_HTML_STYLESHEET + "\n.card{border:1px solid #ddd;border-radius:4px;padding:.5em 1em;margin:.5em 0}\nsummary{cursor:pointer;color:#555}\n</style>\n")
+	doc.WriteString("</head>\n<body>\n")
+
+	if content.Kind == models.EmbedKindDeck {
+		fmt.Fprintf(&doc, "<h2>%s</h2>\n", html.EscapeString(content.DeckName))
+		if content.Description != "" {
+			fmt.Fprintf(&doc, "<p>%s</p>\n", html.EscapeString(content.Description))
+		}
+	}
+
+	for _, card := range content.Cards {
+		doc.WriteString("<div class=\"card\">\n<div class=\"card-front\">\n" + card.Front + "</div>\n")
+		if card.Back != "" {
+			doc.WriteString("<details class=\"card-back\">\n<summary>Show answer</summary>\n" + card.Back + "</details>\n")
+		}
+		doc.WriteString("</div>\n")
+	}
+
+	if content.Kind == models.EmbedKindDeck && content.CardCount > len(content.Cards) {
+		fmt.Fprintf(&doc, "<p>Showing %d of %d cards.</p>\n", len(content.Cards), content.CardCount)
+	}
+
+	doc.WriteString("</body>\n</html>\n")
+	return doc.String()
+}
+
+// Embed tokens are 32 random bytes; only their SHA-256 is stored
+func newEmbedToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", models.Internal("failed to generate embed token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashEmbedToken(token), nil
+}
+
+func hashEmbedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
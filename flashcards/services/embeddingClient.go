@@ -0,0 +1,134 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"flashcards/models"
+)
+
+const (
+	EMBEDDING_REQUEST_TIMEOUT    = 60 * time.Second
+	EMBEDDING_BATCH_SIZE         = 100
+	MAX_EMBEDDING_RESPONSE_BYTES = 32 * 1024 * 1024
+)
+
+// EmbeddingConfig holds settings for an OpenAI-compatible /embeddings
+// endpoint. An empty APIKey disables embeddings.
+type EmbeddingConfig struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+}
+
+type EmbeddingClient struct {
+	cfg        EmbeddingConfig
+	httpClient *http.Client
+}
+
+func NewEmbeddingClient(cfg EmbeddingConfig) *EmbeddingClient {
+	if cfg.APIKey == "" {
+		slog.Info("Embedding API key not configured, embeddings disabled")
+	}
+	return &EmbeddingClient{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: EMBEDDING_REQUEST_TIMEOUT},
+	}
+}
+
+func (c *EmbeddingClient) Enabled() bool {
+	return c != nil && c.cfg.APIKey != ""
+}
+
+// Model names the embedding model, since vectors from different models
+// cannot be compared
+func (c *EmbeddingClient) Model() string {
+	return c.cfg.Model
+}
+
+// Embed returns one vector per text, in order, sending at most
+// EMBEDDING_BATCH_SIZE texts per request
+func (c *EmbeddingClient) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	logger := LoggerFromContext(ctx)
+	if !c.Enabled() {
+		return nil, models.Unavailable("embeddings are not configured")
+	}
+
+	logger.Info("Creating embeddings", "texts", len(texts), "model", c.cfg.Model)
+	startTime := time.Now()
+
+	vectors := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += EMBEDDING_BATCH_SIZE {
+		end := min(start+EMBEDDING_BATCH_SIZE, len(texts))
+		batch, err := c.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			logger.Error("Embedding request failed", "duration_ms", time.Since(startTime).Milliseconds(), "error", err)
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+
+	logger.Info("Created embeddings", "count", len(vectors), "duration_ms", time.Since(startTime).Milliseconds())
+	return vectors, nil
+}
+
+func (c *EmbeddingClient) embedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	payload, err := json.Marshal(map[string]any{
+		"model": c.cfg.Model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, models.Internal("failed to encode embedding request: %w", err)
+	}
+
+	url := strings.TrimRight(c.cfg.BaseURL, "/") + "/embeddings"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, models.Internal("failed to create embedding request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, models.Upstream("failed to create embeddings: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MAX_EMBEDDING_RESPONSE_BYTES))
+	if err != nil {
+		return nil, models.Upstream("failed to read embedding response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, models.Upstream("failed to create embeddings: provider returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, models.Upstream("failed to decode embedding response: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, models.Upstream("failed to create embeddings: got %d vectors for %d texts", len(result.Data), len(texts))
+	}
+
+	vectors := make([][]float64, len(texts))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, models.Upstream("failed to create embeddings: unexpected index %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
+}
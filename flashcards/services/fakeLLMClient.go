@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// FakeLLMResponse is one scripted answer of a FakeLLMClient: Content as the
+// reply text, or Err as the failure of the call
+type FakeLLMResponse struct {
+	Content string
+	Err     error
+}
+
+// FakeLLMCall is a call a FakeLLMClient received, with the text of its
+// messages joined in Prompt for easy matching
+type FakeLLMCall struct {
+	Messages []llms.MessageContent
+	Options  llms.CallOptions
+	Prompt   string
+}
+
+// FakeLLMClient is an LLMClient that answers from a script instead of
+// calling a provider, so code that generates with the LLM can be tested
+// offline. It reports no token usage, so metering counts the text. Canned
+// responses answer any call whose prompt contains their key; other calls
+// take the next scripted response in order. It records every call it
+// receives and is safe for concurrent use.
+type FakeLLMClient struct {
+	mu       sync.Mutex
+	canned   []fakeCannedResponse
+	script   []FakeLLMResponse
+	calls    []FakeLLMCall
+	fallback *FakeLLMResponse
+}
+
+type fakeCannedResponse struct {
+	contains string
+	response FakeLLMResponse
+}
+
+// NewFakeLLMClient returns a fake that replies with responses in order
+func NewFakeLLMClient(responses ...string) *FakeLLMClient {
+	fake := &FakeLLMClient{}
+	for _, content := range responses {
+		fake.script = append(fake.script, FakeLLMResponse{Content: content})
+	}
+	return fake
+}
+
+// Respond queues a reply for the next unmatched call
+func (f *FakeLLMClient) Respond(content string) *FakeLLMClient {
+	return f.enqueue(FakeLLMResponse{Content: content})
+}
+
+// Fail queues an error for the next unmatched call
+func (f *FakeLLMClient) Fail(err error) *FakeLLMClient {
+	return f.enqueue(FakeLLMResponse{Err: err})
+}
+
+// RespondTo answers every call whose prompt contains substring with
+// content. The first matching canned response wins.
+func (f *FakeLLMClient) RespondTo(substring, content string) *FakeLLMClient {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.canned = append(f.canned, fakeCannedResponse{contains: substring, response: FakeLLMResponse{Content: content}})
+	return f
+}
+
+// Otherwise sets the reply for calls once the script has run out, which
+// otherwise fail
+func (f *FakeLLMClient) Otherwise(content string) *FakeLLMClient {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fallback = &FakeLLMResponse{Content: content}
+	return f
+}
+
+func (f *FakeLLMClient) enqueue(response FakeLLMResponse) *FakeLLMClient {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.script = append(f.script, response)
+	return f
+}
+
+// Calls returns the calls received so far, oldest first
+func (f *FakeLLMClient) Calls() []FakeLLMCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeLLMCall(nil), f.calls...)
+}
+
+// Pending returns how many scripted responses have not been used
+func (f *FakeLLMClient) Pending() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.script)
+}
+
+func (f *FakeLLMClient) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var opts llms.CallOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	call := FakeLLMCall{Messages: messages, Options: opts, Prompt: fakePromptText(messages)}
+
+	response, err := f.next(call)
+	if err != nil {
+		return nil, err
+	}
+	if response.Err != nil {
+		return nil, response.Err
+	}
+
+	if opts.StreamingFunc != nil {
+		if err := opts.StreamingFunc(ctx, []byte(response.Content)); err != nil {
+			return nil, err
+		}
+	}
+
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: response.Content}}}, nil
+}
+
+// next records call and picks its response: a matching canned one, the
+// head of the script or the fallback
+func (f *FakeLLMClient) next(call FakeLLMCall) (FakeLLMResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call)
+
+	for _, canned := range f.canned {
+		if strings.Contains(call.Prompt, canned.contains) {
+			return canned.response, nil
+		}
+	}
+	if len(f.script) > 0 {
+		response := f.script[0]
+		f.script = f.script[1:]
+		return response, nil
+	}
+	if f.fallback != nil {
+		return *f.fallback, nil
+	}
+	return FakeLLMResponse{}, fmt.Errorf("fake LLM client has no response for call %d", len(f.calls))
+}
+
+// fakePromptText joins the text parts of messages, one message per line
+func fakePromptText(messages []llms.MessageContent) string {
+	var texts []string
+	for _, message := range messages {
+		for _, part := range message.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				texts = append(texts, text.Text)
+			}
+		}
+	}
+	return strings.Join(texts, "\n")
+}
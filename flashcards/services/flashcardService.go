@@ -0,0 +1,553 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	DEFAULT_GENERATED_FLASHCARDS  = 5
+	MAX_GENERATED_FLASHCARDS      = 20
+	MAX_FLASHCARD_LENGTH          = 2000
+	MAX_FLASHCARD_HINT_LENGTH     = 500
+	MAX_FLASHCARD_MNEMONIC_LENGTH = 1000
+)
+
+// Labels for each mnemonic technique in a card's stored mnemonic
+var mnemonicTechniqueLabels = map[string]string{
+	"acronym":       "Acronym",
+	"imagery":       "Imagery",
+	"memory_palace": "Memory palace",
+}
+
+type FlashcardService struct {
+	repo        db.FlashcardRepository
+	noteService *NoteService
+	deckService *DeckService
+	quizService *QuizService
+	quotas      *QuotaService
+}
+
+func NewFlashcardService(repo db.FlashcardRepository, noteService *NoteService, deckService *DeckService, quizService *QuizService, quotas *QuotaService) *FlashcardService {
+	return &FlashcardService{
+		repo:        repo,
+		noteService: noteService,
+		deckService: deckService,
+		quizService: quizService,
+		quotas:      quotas,
+	}
+}
+
+func (s *FlashcardService) CreateFlashcard(ctx context.Context, userID int, req *models.CreateFlashcardRequest) (*models.Flashcard, error) {
+	if err := s.validateCreateRequest(req); err != nil {
+		return nil, err
+	}
+
+	if req.DeckID != nil {
+		if _, err := s.deckService.GetDeckByID(ctx, userID, *req.DeckID); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.SourceNoteID != nil {
+		if _, err := s.noteService.GetNoteByID(ctx, userID, *req.SourceNoteID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.quotas.CheckCards(ctx, userID, 1); err != nil {
+		return nil, err
+	}
+
+	front, back := flashcardSides(req.Front, req.Back, req.Content)
+	flashcard := &models.Flashcard{
+		UserID:       userID,
+		DeckID:       req.DeckID,
+		Front:        front,
+		Back:         back,
+		Hint:         strings.TrimSpace(req.Hint),
+		SourceNoteID: req.SourceNoteID,
+		Content:      models.FlashcardContent(front, back),
+	}
+
+	if err := s.repo.CreateFlashcard(ctx, flashcard); err != nil {
+		return nil, fmt.Errorf("failed to create flashcard: %w", err)
+	}
+
+	return flashcard, nil
+}
+
+// GenerateFromNote extracts question/answer pairs from a note with the LLM
+// and stores each pair as a flashcard
+func (s *FlashcardService) GenerateFromNote(ctx context.Context, userID, noteID int, req *models.GenerateFlashcardsRequest) ([]*models.Flashcard, error) {
+	count := DEFAULT_GENERATED_FLASHCARDS
+	if req != nil && req.Count != 0 {
+		count = req.Count
+	}
+	if count < 1 || count > MAX_GENERATED_FLASHCARDS {
+		return nil, models.Invalid("count must be between 1 and %d", MAX_GENERATED_FLASHCARDS)
+	}
+
+	note, err := s.noteService.GetNoteByID(ctx, userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Checked before generating so no tokens are spent on cards that cannot
+	// be saved
+	if err := s.quotas.CheckCards(ctx, userID, count); err != nil {
+		return nil, err
+	}
+
+	pairs, err := s.quizService.GenerateFlashcardPairs(ctx, note, count)
+	if err != nil {
+		return nil, err
+	}
+
+	flashcards := make([]*models.Flashcard, 0, len(pairs))
+	for _, pair := range pairs {
+		front, back := strings.TrimSpace(pair.Question), strings.TrimSpace(pair.Answer)
+		if front == "" || len(front) > MAX_FLASHCARD_LENGTH || len(back) > MAX_FLASHCARD_LENGTH {
+			continue
+		}
+		flashcards = append(flashcards, &models.Flashcard{
+			UserID:       userID,
+			DeckID:       note.DeckID,
+			Front:        front,
+			Back:         back,
+			SourceNoteID: &note.ID,
+			Content:      models.FlashcardContent(front, back),
+		})
+	}
+
+	if len(flashcards) == 0 {
+		return nil, fmt.Errorf("no valid flashcards generated")
+	}
+
+	if err := s.repo.CreateFlashcards(ctx, flashcards); err != nil {
+		return nil, fmt.Errorf("failed to save generated flashcards: %w", err)
+	}
+
+	return flashcards, nil
+}
+
+// BatchGenerateResult counts the outcome of GenerateFromAllNotes
+type BatchGenerateResult struct {
+	Notes      int `json:"notes"`
+	Skipped    int `json:"skipped"`
+	Failed     int `json:"failed"`
+	Flashcards int `json:"flashcards"`
+}
+
+// GenerateFromAllNotes generates count flashcards from each of the user's
+// notes, for batch jobs. With skipExisting, notes that already have cards
+// generated from them are left alone. A note that fails is logged and
+// counted, and the rest carry on; running out of quota or losing the LLM
+// provider stops the batch.
+func (s *FlashcardService) GenerateFromAllNotes(ctx context.Context, userID, count int, skipExisting bool) (*BatchGenerateResult, error) {
+	logger := LoggerFromContext(ctx)
+
+	notes, err := s.noteService.GetAllNotes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	withCards := map[int]bool{}
+	if skipExisting {
+		params := models.ListParams{Limit: MAX_PAGE_LIMIT}
+		for {
+			page, err := s.ListFlashcards(ctx, userID, models.FlashcardFilter{}, params)
+			if err != nil {
+				return nil, err
+			}
+			for _, flashcard := range page.Items {
+				if flashcard.SourceNoteID != nil {
+					withCards[*flashcard.SourceNoteID] = true
+				}
+			}
+			params.Offset += params.Limit
+			if params.Offset >= page.Total {
+				break
+			}
+		}
+	}
+
+	result := &BatchGenerateResult{}
+	for _, note := range notes {
+		if withCards[note.ID] {
+			result.Skipped++
+			continue
+		}
+
+		flashcards, err := s.GenerateFromNote(ctx, userID, note.ID, &models.GenerateFlashcardsRequest{Count: count})
+		if err != nil {
+			if errors.Is(err, models.ErrQuotaExceeded) || errors.Is(err, ErrLLMUnavailable) || ctx.Err() != nil {
+				return result, err
+			}
+			logger.Warn("Failed to generate flashcards from note", "note_id", note.ID, "error", err)
+			result.Failed++
+			continue
+		}
+		result.Notes++
+		result.Flashcards += len(flashcards)
+	}
+	return result, nil
+}
+
+// RunGenerateFlashcardsJob is the JobRunner for models.JobKindGenerateFlashcards
+func (s *FlashcardService) RunGenerateFlashcardsJob(ctx context.Context, userID int, payload json.RawMessage) (any, error) {
+	var req models.GenerateFlashcardsJobPayload
+	if err := decodeJobPayload(payload, &req); err != nil {
+		return nil, err
+	}
+	if req.Count == 0 {
+		req.Count = DEFAULT_GENERATED_FLASHCARDS
+	}
+	if req.Count < 1 || req.Count > MAX_GENERATED_FLASHCARDS {
+		return nil, models.Invalid("count must be between 1 and %d", MAX_GENERATED_FLASHCARDS)
+	}
+
+	result, err := s.GenerateFromAllNotes(ctx, userID, req.Count, req.SkipExisting)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *FlashcardService) GetFlashcardByID(ctx context.Context, userID, id int) (*models.Flashcard, error) {
+	if id <= 0 {
+		return nil, models.Invalid("invalid flashcard ID: %d", id)
+	}
+
+	flashcard, err := s.repo.GetFlashcardByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return flashcard, nil
+}
+
+// FlashcardLanguage returns the language of the note a flashcard was made
+// from, or "" when it has none or it is not known
+func (s *FlashcardService) FlashcardLanguage(ctx context.Context, userID int, flashcard *models.Flashcard) string {
+	if flashcard.SourceNoteID == nil {
+		return ""
+	}
+
+	note, err := s.noteService.GetNoteByID(ctx, userID, *flashcard.SourceNoteID)
+	if err != nil {
+		LoggerFromContext(ctx).Warn("Failed to load flashcard source note", "flashcard_id", flashcard.ID, "note_id", *flashcard.SourceNoteID, "error", err)
+		return ""
+	}
+	return note.Language
+}
+
+// RenderFlashcard renders the flashcard's Markdown to sanitized HTML
+func (s *FlashcardService) RenderFlashcard(ctx context.Context, userID, id int) (*models.FlashcardHTML, error) {
+	flashcard, err := s.GetFlashcardByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return RenderFlashcardHTML(flashcard), nil
+}
+
+// ListFlashcards returns one page of the user's flashcards, optionally
+// filtered by deck and a content search
+func (s *FlashcardService) ListFlashcards(ctx context.Context, userID int, filter models.FlashcardFilter, params models.ListParams) (*models.Page[*models.Flashcard], error) {
+	params, err := normalizeListParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.Query, err = validateSearchQuery(filter.Query); err != nil {
+		return nil, err
+	}
+
+	flashcards, total, err := s.repo.ListFlashcards(ctx, userID, filter, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flashcards: %w", err)
+	}
+
+	return &models.Page[*models.Flashcard]{
+		Items:  flashcards,
+		Total:  total,
+		Limit:  params.Limit,
+		Offset: params.Offset,
+	}, nil
+}
+
+// GetFlashcardsByDecks returns every flashcard of the user in deckIDs,
+// oldest first
+func (s *FlashcardService) GetFlashcardsByDecks(ctx context.Context, userID int, deckIDs []int) ([]*models.Flashcard, error) {
+	flashcards, err := s.repo.GetFlashcardsByDecks(ctx, userID, deckIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flashcards: %w", err)
+	}
+
+	return flashcards, nil
+}
+
+// GetFlashcardIDsByContentHash finds the user's flashcards whose content
+// has one of the given FlashcardContentHash values
+func (s *FlashcardService) GetFlashcardIDsByContentHash(ctx context.Context, userID int, hashes []string) (map[string]int, error) {
+	if len(hashes) == 0 {
+		return map[string]int{}, nil
+	}
+
+	ids, err := s.repo.GetFlashcardIDsByContentHash(ctx, userID, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flashcards: %w", err)
+	}
+
+	return ids, nil
+}
+
+// CreateFlashcards saves already validated flashcards in one transaction,
+// after checking they fit in the user's quota
+func (s *FlashcardService) CreateFlashcards(ctx context.Context, userID int, flashcards []*models.Flashcard) error {
+	if err := s.quotas.CheckCards(ctx, userID, len(flashcards)); err != nil {
+		return err
+	}
+
+	if err := s.repo.CreateFlashcards(ctx, flashcards); err != nil {
+		return fmt.Errorf("failed to create flashcards: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateFlashcard applies req to the flashcard if it is still at version,
+// the version the caller last read. A flashcard changed since is a
+// conflict.
+func (s *FlashcardService) UpdateFlashcard(ctx context.Context, userID, id, version int, req *models.UpdateFlashcardRequest) (*models.Flashcard, error) {
+	if id <= 0 {
+		return nil, models.Invalid("invalid flashcard ID: %d", id)
+	}
+
+	if err := s.validateUpdateRequest(req); err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]any)
+
+	if req.Content != nil {
+		front, back := models.SplitFlashcardContent(*req.Content)
+		if front == "" {
+			return nil, models.Invalid("content cannot be empty")
+		}
+		updates["front"], updates["back"] = front, back
+		updates["content"] = models.FlashcardContent(front, back)
+	}
+
+	// Changing one side needs the other to keep content in step
+	if req.Front != nil || req.Back != nil {
+		current, err := s.repo.GetFlashcardByID(ctx, userID, id)
+		if err != nil {
+			return nil, err
+		}
+
+		front, back := current.Front, current.Back
+		if req.Front != nil {
+			front = strings.TrimSpace(*req.Front)
+			if front == "" {
+				return nil, models.Invalid("front cannot be empty")
+			}
+		}
+		if req.Back != nil {
+			back = strings.TrimSpace(*req.Back)
+		}
+		updates["front"], updates["back"] = front, back
+		updates["content"] = models.FlashcardContent(front, back)
+	}
+
+	if req.Hint != nil {
+		updates["hint"] = strings.TrimSpace(*req.Hint)
+	}
+
+	if req.SourceNoteID != nil {
+		if *req.SourceNoteID == 0 {
+			updates["source_note_id"] = nil
+		} else {
+			if _, err := s.noteService.GetNoteByID(ctx, userID, *req.SourceNoteID); err != nil {
+				return nil, err
+			}
+			updates["source_note_id"] = *req.SourceNoteID
+		}
+	}
+
+	if req.DeckID != nil {
+		if *req.DeckID == 0 {
+			updates["deck_id"] = nil
+		} else {
+			if _, err := s.deckService.GetDeckByID(ctx, userID, *req.DeckID); err != nil {
+				return nil, err
+			}
+			updates["deck_id"] = *req.DeckID
+		}
+	}
+
+	if len(updates) == 0 {
+		return nil, models.Invalid("no valid updates provided")
+	}
+
+	if err := s.repo.UpdateFlashcard(ctx, userID, id, version, updates); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetFlashcardByID(ctx, userID, id)
+}
+
+// ReformulateFlashcard has the LLM rewrite a flashcard the user keeps
+// forgetting into one that is easier to remember, and saves the new sides
+// and hint over the old ones
+func (s *FlashcardService) ReformulateFlashcard(ctx context.Context, userID, id int) (*models.Flashcard, error) {
+	flashcard, err := s.GetFlashcardByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rewrite, err := s.quizService.ReformulateFlashcard(ctx, flashcard, s.FlashcardLanguage(ctx, userID, flashcard))
+	if err != nil {
+		return nil, err
+	}
+	if err := validateFlashcardFields(&rewrite.Front, &rewrite.Back, &rewrite.Hint); err != nil {
+		return nil, fmt.Errorf("reformulated flashcard is unusable: %v", err)
+	}
+
+	updates := map[string]any{
+		"front":   rewrite.Front,
+		"back":    rewrite.Back,
+		"hint":    rewrite.Hint,
+		"content": models.FlashcardContent(rewrite.Front, rewrite.Back),
+	}
+	if err := s.repo.UpdateFlashcard(ctx, userID, id, flashcard.Version, updates); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetFlashcardByID(ctx, userID, id)
+}
+
+// GenerateMnemonic has the LLM write memory aids for a flashcard and saves
+// them on the card, one "<technique>: <aid>" per line, replacing any it had.
+// Aids that would take the mnemonic past MAX_FLASHCARD_MNEMONIC_LENGTH are
+// dropped.
+func (s *FlashcardService) GenerateMnemonic(ctx context.Context, userID, id int) (*models.Flashcard, error) {
+	flashcard, err := s.GetFlashcardByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	mnemonics, err := s.quizService.GenerateMnemonics(ctx, flashcard, s.FlashcardLanguage(ctx, userID, flashcard))
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	length := 0
+	for _, mnemonic := range mnemonics {
+		line := mnemonicTechniqueLabels[mnemonic.Technique] + ": " + mnemonic.Text
+		if length+len(line)+1 > MAX_FLASHCARD_MNEMONIC_LENGTH {
+			continue
+		}
+		lines = append(lines, line)
+		length += len(line) + 1
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("generated mnemonics exceed %d characters", MAX_FLASHCARD_MNEMONIC_LENGTH)
+	}
+
+	updates := map[string]any{"mnemonic": strings.Join(lines, "\n")}
+	if err := s.repo.UpdateFlashcard(ctx, userID, id, flashcard.Version, updates); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetFlashcardByID(ctx, userID, id)
+}
+
+func (s *FlashcardService) DeleteFlashcard(ctx context.Context, userID, id int) error {
+	if id <= 0 {
+		return models.Invalid("invalid flashcard ID: %d", id)
+	}
+
+	return s.repo.DeleteFlashcard(ctx, userID, id)
+}
+
+func (s *FlashcardService) validateCreateRequest(req *models.CreateFlashcardRequest) error {
+	if req == nil {
+		return models.Invalid("request cannot be nil")
+	}
+
+	sides := strings.TrimSpace(req.Front) != "" || strings.TrimSpace(req.Back) != ""
+	content := strings.TrimSpace(req.Content)
+	if sides && content != "" {
+		return models.Invalid("content cannot be combined with front or back")
+	}
+
+	front, back := flashcardSides(req.Front, req.Back, req.Content)
+	if front == "" {
+		return models.Invalid("front is required")
+	}
+
+	if len(content) > MAX_FLASHCARD_LENGTH {
+		return models.Invalid("content cannot exceed %d characters", MAX_FLASHCARD_LENGTH)
+	}
+
+	return validateFlashcardFields(&front, &back, &req.Hint)
+}
+
+func (s *FlashcardService) validateUpdateRequest(req *models.UpdateFlashcardRequest) error {
+	if req == nil {
+		return models.Invalid("request cannot be nil")
+	}
+
+	if req.Front == nil && req.Back == nil && req.Hint == nil && req.SourceNoteID == nil && req.Content == nil && req.DeckID == nil {
+		return models.Invalid("at least one field must be provided for update")
+	}
+
+	if req.Content != nil && (req.Front != nil || req.Back != nil) {
+		return models.Invalid("content cannot be combined with front or back")
+	}
+
+	if req.Content != nil {
+		content := strings.TrimSpace(*req.Content)
+		if len(content) > MAX_FLASHCARD_LENGTH {
+			return models.Invalid("content cannot exceed %d characters", MAX_FLASHCARD_LENGTH)
+		}
+	}
+
+	return validateFlashcardFields(req.Front, req.Back, req.Hint)
+}
+
+// flashcardSides returns the trimmed sides of a new card, split from
+// content when no front or back is given
+func flashcardSides(front, back, content string) (string, string) {
+	front, back = strings.TrimSpace(front), strings.TrimSpace(back)
+	if front == "" && back == "" {
+		return models.SplitFlashcardContent(content)
+	}
+	return front, back
+}
+
+// validateFlashcardFields checks the lengths of the fields that are set
+func validateFlashcardFields(front, back, hint *string) error {
+	if front != nil && len(strings.TrimSpace(*front)) > MAX_FLASHCARD_LENGTH {
+		return models.Invalid("front cannot exceed %d characters", MAX_FLASHCARD_LENGTH)
+	}
+
+	if back != nil && len(strings.TrimSpace(*back)) > MAX_FLASHCARD_LENGTH {
+		return models.Invalid("back cannot exceed %d characters", MAX_FLASHCARD_LENGTH)
+	}
+
+	if hint != nil && len(strings.TrimSpace(*hint)) > MAX_FLASHCARD_HINT_LENGTH {
+		return models.Invalid("hint cannot exceed %d characters", MAX_FLASHCARD_HINT_LENGTH)
+	}
+
+	return nil
+}
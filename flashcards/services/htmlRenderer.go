@@ -0,0 +1,503 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Stylesheet for the classes RenderMarkdownHTML emits, for clients that
+// show the HTML as is
+const RENDERED_HTML_STYLESHEET = `body{font-family:sans-serif;line-height:1.5;margin:1em}
+pre{background:#f6f8fa;padding:.75em;overflow:auto}
+code{font-family:monospace}
+blockquote{border-left:3px solid #ddd;margin-left:0;padding-left:1em;color:#555}
+img{max-width:100%}
+.math{font-family:serif;font-style:italic}
+.math.display{display:block;text-align:center;margin:.5em 0}
+.hl-keyword{color:#d73a49}
+.hl-string{color:#032f62}
+.hl-number{color:#005cc5}
+.hl-comment{color:#6a737d;font-style:italic}
+.card-back{border-top:1px solid #ddd;margin-top:1em;padding-top:1em}`
+
+var (
+	headingPattern     = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	unorderedPattern   = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	orderedPattern     = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	rulePattern        = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	inlineLinkPattern  = regexp.MustCompile(`^(!?)\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	fenceLanguageClean = regexp.MustCompile(`[^a-zA-Z0-9_+-]`)
+)
+
+// RenderMarkdownHTML converts card Markdown to HTML. It supports headings,
+// paragraphs, lists, block quotes, rules, emphasis, links, images, inline
+// and fenced code with highlighting, and $...$ and $$...$$ LaTeX, which is
+// approximated with Unicode and sub/superscripts so no script is needed.
+// All text is escaped and only those elements are produced, so the output
+// is safe to embed whatever the input; raw HTML in the input is shown as
+// text.
+func RenderMarkdownHTML(markdown string) string {
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+
+	var out strings.Builder
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			out.WriteString("<p>" + renderInline(strings.Join(paragraph, "\n")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flush()
+			fence := trimmed[:3]
+			language := fenceLanguageClean.ReplaceAllString(strings.TrimSpace(trimmed[3:]), "")
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			class := ""
+			if language != "" {
+				class = ` class="language-` + strings.ToLower(language) + `"`
+			}
+			out.WriteString("<pre><code" + class + ">" + highlightCode(strings.Join(code, "\n"), language) + "</code></pre>\n")
+
+		case strings.HasPrefix(trimmed, "$$"):
+			flush()
+			source := strings.TrimPrefix(trimmed, "$$")
+			if end := strings.Index(source, "$$"); end >= 0 {
+				source = source[:end]
+			} else {
+				var body []string
+				if source != "" {
+					body = append(body, source)
+				}
+				for i++; i < len(lines); i++ {
+					if end := strings.Index(lines[i], "$$"); end >= 0 {
+						body = append(body, lines[i][:end])
+						break
+					}
+					body = append(body, lines[i])
+				}
+				source = strings.Join(body, "\n")
+			}
+			out.WriteString(renderMath(source, true) + "\n")
+
+		case headingPattern.MatchString(trimmed):
+			flush()
+			match := headingPattern.FindStringSubmatch(trimmed)
+			level := len(match[1])
+			fmt.Fprintf(&out, "<h%d>%s</h%d>\n", level, renderInline(strings.TrimRight(match[2], " #")), level)
+
+		case rulePattern.MatchString(trimmed):
+			flush()
+			out.WriteString("<hr>\n")
+
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quoted := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quote = append(quote, strings.TrimPrefix(quoted, " "))
+			}
+			i--
+			out.WriteString("<blockquote>\n" + RenderMarkdownHTML(strings.Join(quote, "\n")) + "</blockquote>\n")
+
+		case unorderedPattern.MatchString(line) || orderedPattern.MatchString(line):
+			flush()
+			pattern, tag := unorderedPattern, "ul"
+			if !unorderedPattern.MatchString(line) {
+				pattern, tag = orderedPattern, "ol"
+			}
+			out.WriteString("<" + tag + ">\n")
+			for ; i < len(lines) && pattern.MatchString(lines[i]); i++ {
+				out.WriteString("<li>" + renderInline(pattern.FindStringSubmatch(lines[i])[1]) + "</li>\n")
+			}
+			i--
+			out.WriteString("</" + tag + ">\n")
+
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+
+	return out.String()
+}
+
+// renderInline renders emphasis, code, math, links and images within a
+// block, escaping everything else. Line breaks within a block are kept,
+// since card text is usually written line by line.
+func renderInline(text string) string {
+	var out strings.Builder
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && strings.ContainsRune("\\`*_{}[]()#+-.!$~>", rune(rest[1])):
+			out.WriteString(html.EscapeString(rest[1:2]))
+			i += 2
+			continue
+
+		case rest[0] == '\n':
+			out.WriteString("<br>\n")
+			i++
+			continue
+
+		case rest[0] == '`':
+			ticks := len(rest) - len(strings.TrimLeft(rest, "`"))
+			if end := strings.Index(rest[ticks:], rest[:ticks]); end >= 0 {
+				out.WriteString("<code>" + html.EscapeString(strings.TrimSpace(rest[ticks:ticks+end])) + "</code>")
+				i += 2*ticks + end
+				continue
+			}
+
+		case strings.HasPrefix(rest, "$$"):
+			if end := strings.Index(rest[2:], "$$"); end > 0 {
+				out.WriteString(renderMath(rest[2:2+end], true))
+				i += 4 + end
+				continue
+			}
+
+		case rest[0] == '$':
+			// A closing $ must follow a non-space, so prices like $5 and $10
+			// are left alone
+			if end := strings.Index(rest[1:], "$"); end > 0 && rest[1] != ' ' && rest[end] != ' ' {
+				out.WriteString(renderMath(rest[1:1+end], false))
+				i += 2 + end
+				continue
+			}
+
+		case rest[0] == '[' || strings.HasPrefix(rest, "!["):
+			if match := inlineLinkPattern.FindStringSubmatch(rest); match != nil {
+				out.WriteString(renderLink(match[1] == "!", match[2], match[3]))
+				i += len(match[0])
+				continue
+			}
+
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if end := strings.Index(rest[2:], rest[:2]); end > 0 {
+				out.WriteString("<strong>" + renderInline(rest[2:2+end]) + "</strong>")
+				i += 4 + end
+				continue
+			}
+
+		case strings.HasPrefix(rest, "~~"):
+			if end := strings.Index(rest[2:], "~~"); end > 0 {
+				out.WriteString("<del>" + renderInline(rest[2:2+end]) + "</del>")
+				i += 4 + end
+				continue
+			}
+
+		case rest[0] == '*' || (rest[0] == '_' && (i == 0 || !isWordByte(text[i-1]))):
+			// Underscores inside words, as in snake_case, are not emphasis
+			if end := strings.IndexByte(rest[1:], rest[0]); end > 0 && rest[1] != ' ' {
+				after := 2 + end
+				if rest[0] == '*' || after >= len(rest) || !isWordByte(rest[after]) {
+					out.WriteString("<em>" + renderInline(rest[1:1+end]) + "</em>")
+					i += after
+					continue
+				}
+			}
+		}
+
+		out.WriteString(html.EscapeString(rest[:1]))
+		i++
+	}
+	return out.String()
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || isLetterByte(b)
+}
+
+func isLetterByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// renderLink keeps links and images only when they point somewhere safe:
+// http(s) or a path on this server, plus mailto for links
+func renderLink(image bool, text, target string) string {
+	if !safeURL(target, !image) {
+		return renderInline(text)
+	}
+
+	if image {
+		return fmt.Sprintf(`<img src="%s" alt="%s">`, html.EscapeString(target), html.EscapeString(text))
+	}
+	return fmt.Sprintf(`<a href="%s" rel="nofollow noopener noreferrer">%s</a>`, html.EscapeString(target), renderInline(text))
+}
+
+func safeURL(target string, allowMailto bool) bool {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https":
+		return parsed.Host != ""
+	case "mailto":
+		return allowMailto
+	case "":
+		// Relative paths only; //host would be another site
+		return parsed.Host == "" && !strings.HasPrefix(target, "//")
+	}
+	return false
+}
+
+var codeKeywords = map[string]bool{
+	"break": true, "case": true, "catch": true, "class": true, "const": true, "continue": true,
+	"def": true, "default": true, "defer": true, "do": true, "elif": true, "else": true,
+	"enum": true, "except": true, "export": true, "extends": true, "false": true, "finally": true,
+	"fn": true, "for": true, "from": true, "func": true, "function": true, "go": true,
+	"if": true, "import": true, "in": true, "interface": true, "lambda": true, "let": true,
+	"map": true, "match": true, "new": true, "nil": true, "None": true, "not": true,
+	"null": true, "package": true, "pass": true, "private": true, "public": true, "range": true,
+	"return": true, "self": true, "static": true, "struct": true, "switch": true, "this": true,
+	"throw": true, "true": true, "True": true, "False": true, "try": true, "type": true,
+	"var": true, "void": true, "while": true, "with": true, "yield": true, "async": true,
+	"await": true, "and": true, "or": true, "select": true, "where": true, "SELECT": true,
+	"FROM": true, "WHERE": true, "JOIN": true, "ORDER": true, "BY": true, "GROUP": true,
+}
+
+// Languages whose line comments start with #
+var hashCommentLanguages = map[string]bool{
+	"python": true, "py": true, "ruby": true, "rb": true, "sh": true, "bash": true,
+	"shell": true, "yaml": true, "yml": true, "r": true, "perl": true, "toml": true,
+}
+
+// highlightCode marks comments, strings, numbers and common keywords with
+// hl-* classes. It is a lexer shared by all languages rather than a parser,
+// so it can be fooled, but only ever changes colors.
+func highlightCode(code, language string) string {
+	lineComment := "//"
+	if hashCommentLanguages[strings.ToLower(language)] {
+		lineComment = "#"
+	} else if strings.EqualFold(language, "sql") {
+		lineComment = "--"
+	}
+
+	var out strings.Builder
+	span := func(class, text string) {
+		out.WriteString(`<span class="hl-` + class + `">` + html.EscapeString(text) + "</span>")
+	}
+
+	for i := 0; i < len(code); {
+		rest := code[i:]
+		switch {
+		case strings.HasPrefix(rest, lineComment):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			span("comment", rest[:end])
+			i += end
+
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				end = len(rest)
+			} else {
+				end += 4
+			}
+			span("comment", rest[:end])
+			i += end
+
+		case rest[0] == '"' || rest[0] == '\'' || rest[0] == '`':
+			end := 1
+			for end < len(rest) && rest[end] != rest[0] && (rest[0] == '`' || rest[end] != '\n') {
+				if rest[end] == '\\' && rest[0] != '`' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(rest))
+			span("string", rest[:end])
+			i += end
+
+		case rest[0] >= '0' && rest[0] <= '9' && (i == 0 || !isWordByte(code[i-1])):
+			end := 1
+			for end < len(rest) && (isWordByte(rest[end]) || rest[end] == '.') {
+				end++
+			}
+			span("number", rest[:end])
+			i += end
+
+		case isWordByte(rest[0]):
+			end := 1
+			for end < len(rest) && isWordByte(rest[end]) {
+				end++
+			}
+			if codeKeywords[rest[:end]] {
+				span("keyword", rest[:end])
+			} else {
+				out.WriteString(html.EscapeString(rest[:end]))
+			}
+			i += end
+
+		default:
+			out.WriteString(html.EscapeString(rest[:1]))
+			i++
+		}
+	}
+	return out.String()
+}
+
+var texSymbols = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ε", "varepsilon": "ε",
+	"zeta": "ζ", "eta": "η", "theta": "θ", "iota": "ι", "kappa": "κ", "lambda": "λ",
+	"mu": "μ", "nu": "ν", "xi": "ξ", "pi": "π", "rho": "ρ", "sigma": "σ", "tau": "τ",
+	"upsilon": "υ", "phi": "φ", "varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ", "Pi": "Π",
+	"Sigma": "Σ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+	"times": "×", "cdot": "·", "div": "÷", "pm": "±", "mp": "∓", "leq": "≤", "le": "≤",
+	"geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠", "approx": "≈", "equiv": "≡", "sim": "∼",
+	"propto": "∝", "infty": "∞", "sum": "∑", "prod": "∏", "int": "∫", "oint": "∮",
+	"partial": "∂", "nabla": "∇", "to": "→", "rightarrow": "→", "leftarrow": "←",
+	"Rightarrow": "⇒", "Leftarrow": "⇐", "leftrightarrow": "↔", "Leftrightarrow": "⇔",
+	"implies": "⇒", "iff": "⇔", "in": "∈", "notin": "∉", "subset": "⊂", "subseteq": "⊆",
+	"supset": "⊃", "cup": "∪", "cap": "∩", "emptyset": "∅", "forall": "∀", "exists": "∃",
+	"neg": "¬", "land": "∧", "lor": "∨", "ldots": "…", "cdots": "⋯", "dots": "…",
+	"circ": "∘", "degree": "°", "angle": "∠", "perp": "⊥", "parallel": "∥", "mid": "|",
+	"langle": "⟨", "rangle": "⟩", "hbar": "ħ", "ell": "ℓ", "Re": "ℜ", "Im": "ℑ",
+	"sin": "sin", "cos": "cos", "tan": "tan", "log": "log", "ln": "ln", "exp": "exp",
+	"lim": "lim", "max": "max", "min": "min",
+	",": " ", ";": " ", ":": " ", "quad": " ", "qquad": "  ", " ": " ", "!": "",
+	"{": "{", "}": "}", "%": "%", "$": "$", "&": "&", "#": "#", "_": "_",
+	"left": "", "right": "", "displaystyle": "",
+}
+
+// renderMath approximates LaTeX with Unicode symbols and sub/superscripts,
+// which email clients and bots can show without a math library. The source
+// is kept in the title so it can still be read when the approximation falls
+// short.
+func renderMath(source string, display bool) string {
+	class := "math"
+	if display {
+		class = "math display"
+	}
+	source = strings.TrimSpace(source)
+	parser := &texParser{input: source}
+	return fmt.Sprintf(`<span class="%s" title="%s">%s</span>`, class, html.EscapeString(source), parser.parse(false))
+}
+
+type texParser struct {
+	input string
+	pos   int
+}
+
+// parse renders up to the end of the input or, within a group, up to the
+// closing brace
+func (p *texParser) parse(inGroup bool) string {
+	var out strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		switch {
+		case c == '}':
+			p.pos++
+			if inGroup {
+				return out.String()
+			}
+
+		case c == '{':
+			p.pos++
+			out.WriteString(p.parse(true))
+
+		case c == '^' || c == '_':
+			p.pos++
+			tag := "sup"
+			if c == '_' {
+				tag = "sub"
+			}
+			out.WriteString("<" + tag + ">" + p.argument() + "</" + tag + ">")
+
+		case c == '\\':
+			out.WriteString(p.command())
+
+		case c == '&':
+			p.pos++
+			out.WriteString(" ")
+
+		default:
+			_, size := utf8.DecodeRuneInString(p.input[p.pos:])
+			out.WriteString(html.EscapeString(p.input[p.pos : p.pos+size]))
+			p.pos += size
+		}
+	}
+	return out.String()
+}
+
+// argument renders a braced group or a single token
+func (p *texParser) argument() string {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+	if p.pos >= len(p.input) {
+		return ""
+	}
+
+	switch p.input[p.pos] {
+	case '{':
+		p.pos++
+		return p.parse(true)
+	case '\\':
+		return p.command()
+	}
+	_, size := utf8.DecodeRuneInString(p.input[p.pos:])
+	p.pos += size
+	return html.EscapeString(p.input[p.pos-size : p.pos])
+}
+
+func (p *texParser) command() string {
+	p.pos++ // backslash
+	if p.pos >= len(p.input) {
+		return "\\"
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && isLetterByte(p.input[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start {
+		p.pos++ // a single symbol such as \, or \\
+	}
+	name := p.input[start:p.pos]
+
+	switch name {
+	case "\\":
+		return "<br>"
+	case "frac", "dfrac", "tfrac":
+		numerator := p.argument()
+		denominator := p.argument()
+		return "<sup>" + numerator + "</sup>⁄<sub>" + denominator + "</sub>"
+	case "sqrt":
+		return "√(" + p.argument() + ")"
+	case "text", "textrm", "mathrm", "operatorname", "mathit", "mathcal", "mathbb":
+		return p.argument()
+	case "mathbf", "textbf", "boldsymbol":
+		return "<b>" + p.argument() + "</b>"
+	case "vec":
+		return p.argument() + "⃗"
+	case "hat":
+		return p.argument() + "̂"
+	case "bar", "overline":
+		return p.argument() + "̄"
+	}
+
+	if symbol, ok := texSymbols[name]; ok {
+		return html.EscapeString(symbol)
+	}
+	return html.EscapeString("\\" + name)
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	MAX_IDEMPOTENCY_KEY = 255
+	// An in-progress request older than this is assumed to have died and
+	// its key can be claimed by a retry
+	IDEMPOTENCY_LOCK_TIMEOUT = 5 * time.Minute
+	// Times Begin tries to reserve a key that is released between its
+	// reservation and reading the holder
+	IDEMPOTENCY_RESERVE_ATTEMPTS = 3
+)
+
+// IdempotencyService remembers responses to requests sent with an
+// Idempotency-Key so a retried request gets the original response instead
+// of running again. A zero TTL disables it.
+type IdempotencyService struct {
+	repo db.IdempotencyRepository
+	ttl  time.Duration
+}
+
+func NewIdempotencyService(repo db.IdempotencyRepository, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{repo: repo, ttl: ttl}
+}
+
+func (s *IdempotencyService) Enabled() bool {
+	return s != nil && s.ttl > 0
+}
+
+// Begin claims the key for a request. It returns nil when the caller should
+// handle the request and then call Complete or Release, or the stored record
+// when a response is ready to replay. Reusing a key for a different request
+// or while the first is running is an error.
+func (s *IdempotencyService) Begin(ctx context.Context, userID int, key string, requestHash string) (*models.IdempotencyRecord, error) {
+	if len(key) > MAX_IDEMPOTENCY_KEY {
+		return nil, models.Invalid("Idempotency-Key cannot exceed %d characters", MAX_IDEMPOTENCY_KEY)
+	}
+
+	var existing *models.IdempotencyRecord
+	for attempt := 0; existing == nil; attempt++ {
+		if attempt == IDEMPOTENCY_RESERVE_ATTEMPTS {
+			return nil, models.Conflict("a request with this Idempotency-Key is still in progress")
+		}
+
+		record := &models.IdempotencyRecord{
+			UserID:      userID,
+			Key:         key,
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().Add(s.ttl),
+		}
+		reserved, err := s.repo.ReserveKey(ctx, record, time.Now().Add(-IDEMPOTENCY_LOCK_TIMEOUT))
+		if err != nil {
+			return nil, err
+		}
+		if reserved {
+			return nil, nil
+		}
+
+		// The holder may release the key before it is read; reserve again
+		existing, err = s.repo.GetKey(ctx, userID, key)
+		if err != nil && !errors.Is(err, models.ErrNotFound) {
+			return nil, err
+		}
+	}
+	if existing.RequestHash != requestHash {
+		return nil, models.Invalid("Idempotency-Key was already used for a different request")
+	}
+	if existing.StatusCode == nil {
+		return nil, models.Conflict("a request with this Idempotency-Key is still in progress")
+	}
+
+	LoggerFromContext(ctx).Info("Replaying idempotent response", "idempotency_key", key)
+	return existing, nil
+}
+
+// Complete stores the response to replay for the key. It runs even after
+// the request context is cancelled so a finished generation is not lost.
+func (s *IdempotencyService) Complete(ctx context.Context, userID int, key string, statusCode int, body []byte) {
+	ctx = context.WithoutCancel(ctx)
+	if err := s.repo.CompleteKey(ctx, userID, key, statusCode, body); err != nil {
+		LoggerFromContext(ctx).Error("Failed to save idempotent response", "idempotency_key", key, "error", err)
+	}
+}
+
+// Release frees the key after a failed request so a retry runs again
+func (s *IdempotencyService) Release(ctx context.Context, userID int, key string) {
+	ctx = context.WithoutCancel(ctx)
+	if err := s.repo.DeleteKey(ctx, userID, key); err != nil {
+		LoggerFromContext(ctx).Error("Failed to release idempotency key", "idempotency_key", key, "error", err)
+	}
+}
+
+// DeleteExpired removes keys past their TTL; run periodically by the
+// scheduler
+func (s *IdempotencyService) DeleteExpired(ctx context.Context) error {
+	deleted, err := s.repo.DeleteExpiredKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	LoggerFromContext(ctx).Info("Deleted expired idempotency keys", "count", deleted)
+	return nil
+}
+
+// HashRequest identifies a request by method, path and body, so a key
+// reused with a different payload is detected
+func HashRequest(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
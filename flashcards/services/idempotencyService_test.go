@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+// releasingIdempotencyRepository releases the key once, just after a
+// reservation of it fails, as a request finishing with an error would
+type releasingIdempotencyRepository struct {
+	db.IdempotencyRepository
+	released bool
+}
+
+func (r *releasingIdempotencyRepository) ReserveKey(ctx context.Context, record *models.IdempotencyRecord, staleBefore time.Time) (bool, error) {
+	reserved, err := r.IdempotencyRepository.ReserveKey(ctx, record, staleBefore)
+	if err == nil && !reserved && !r.released {
+		r.released = true
+		err = r.DeleteKey(ctx, record.UserID, record.Key)
+	}
+	return reserved, err
+}
+
+func TestIdempotencyBegin(t *testing.T) {
+	ctx := context.Background()
+	hash := HashRequest("POST", "/notes/generate-quiz", []byte(`{"noteIds":[1]}`))
+
+	t.Run("replays a completed response", func(t *testing.T) {
+		service := NewIdempotencyService(db.NewMemoryIdempotencyRepository(db.NewMemoryStore(nil)), time.Hour)
+		if record, err := service.Begin(ctx, 1, "key", hash); record != nil || err != nil {
+			t.Fatalf("first Begin = %v, %v, want the key reserved", record, err)
+		}
+		if _, err := service.Begin(ctx, 1, "key", hash); !errors.Is(err, models.ErrConflict) {
+			t.Fatalf("Begin while in progress = %v, want a conflict", err)
+		}
+		service.Complete(ctx, 1, "key", 201, []byte(`{}`))
+
+		record, err := service.Begin(ctx, 1, "key", hash)
+		if err != nil || record == nil || *record.StatusCode != 201 {
+			t.Fatalf("Begin after completion = %v, %v, want the 201 to replay", record, err)
+		}
+		if _, err := service.Begin(ctx, 1, "key", "other"); !errors.Is(err, models.ErrValidation) {
+			t.Fatalf("Begin with another request = %v, want a validation error", err)
+		}
+	})
+
+	t.Run("reserves a key released while it was read", func(t *testing.T) {
+		repo := &releasingIdempotencyRepository{IdempotencyRepository: db.NewMemoryIdempotencyRepository(db.NewMemoryStore(nil))}
+		service := NewIdempotencyService(repo, time.Hour)
+		if _, err := service.Begin(ctx, 1, "key", hash); err != nil {
+			t.Fatalf("first Begin: %v", err)
+		}
+
+		if record, err := service.Begin(ctx, 1, "key", hash); record != nil || err != nil {
+			t.Fatalf("Begin after release = %v, %v, want the key reserved again", record, err)
+		}
+		if !repo.released {
+			t.Fatal("the key was never released")
+		}
+	})
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	DEFAULT_IMPORT_CHUNK_SIZE = 50
+	MAX_IMPORT_CHUNK_SIZE     = 500
+	// Records one import job can create
+	MAX_IMPORT_JOB_ITEMS = 10000
+	// A running job that has committed nothing for this long is assumed to
+	// have died with its server and is failed so it can be resumed
+	IMPORT_JOB_STALE_AFTER = 5 * time.Minute
+)
+
+// ImportJobService runs large imports in the background. Everything is
+// parsed and validated up front and stored with the job; the records are
+// then created a chunk at a time, each chunk in its own transaction, so
+// progress can be polled and a failed job resumes from its first
+// uncommitted chunk without creating anything twice. Failed jobs are
+// queued in the dead-letter queue and resumed from there.
+type ImportJobService struct {
+	repo        db.ImportJobRepository
+	noteService *NoteService
+	quotas      *QuotaService
+	deadLetters *DeadLetterService
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// importJobPayload identifies a failed import job in the dead-letter queue
+type importJobPayload struct {
+	UserID int `json:"userId"`
+	JobID  int `json:"jobId"`
+}
+
+func NewImportJobService(repo db.ImportJobRepository, noteService *NoteService, quotas *QuotaService, deadLetters *DeadLetterService) *ImportJobService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ImportJobService{repo: repo, noteService: noteService, quotas: quotas, deadLetters: deadLetters, ctx: ctx, cancel: cancel}
+}
+
+// StartNoteImport parses a Markdown or zip upload as ImportNotes does and
+// starts a job that creates the notes. A chunkSize of 0 uses the default.
+func (s *ImportJobService) StartNoteImport(ctx context.Context, userID int, req *models.ImportNotesRequest, chunkSize int) (*models.ImportJob, error) {
+	chunkSize, err := importChunkSize(chunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	notes, report, err := s.noteService.PlanNoteImport(ctx, userID, req, MAX_IMPORT_JOB_ITEMS)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]models.ImportJobItem, len(notes))
+	for i, note := range notes {
+		items[i] = models.ImportJobItem{
+			Position: i,
+			Chunk:    i / chunkSize,
+			File:     report.Created[i].File,
+			Heading:  report.Created[i].Heading,
+			Content:  note.Content,
+		}
+	}
+
+	job := &models.ImportJob{
+		UserID:     userID,
+		Kind:       models.ImportKindNotes,
+		Status:     models.ImportJobStatusRunning,
+		DeckID:     req.DeckID,
+		ChunkSize:  chunkSize,
+		TotalItems: len(items),
+		Skipped:    report.Skipped,
+	}
+	if err := s.repo.CreateImportJob(ctx, job, items); err != nil {
+		return nil, err
+	}
+
+	LoggerFromContext(ctx).Info("Started import job", "import_job_id", job.ID, "kind", job.Kind, "items", job.TotalItems, "chunks", job.TotalChunks)
+	s.start(ctx, job)
+	return job, nil
+}
+
+// ValidateNoteImport reports what StartNoteImport would do with the same
+// upload, without starting a job or creating anything
+func (s *ImportJobService) ValidateNoteImport(ctx context.Context, userID int, req *models.ImportNotesRequest, chunkSize int) (*models.NoteImportReport, error) {
+	if _, err := importChunkSize(chunkSize); err != nil {
+		return nil, err
+	}
+
+	req.ValidateOnly = true
+	_, report, err := s.noteService.PlanNoteImport(ctx, userID, req, MAX_IMPORT_JOB_ITEMS)
+	if err != nil {
+		return nil, err
+	}
+
+	LoggerFromContext(ctx).Info("Validated import", "kind", models.ImportKindNotes, "importable", report.Summary.Importable, "skipped", len(report.Skipped))
+	return report, nil
+}
+
+func (s *ImportJobService) GetImportJob(ctx context.Context, userID, id int) (*models.ImportJob, error) {
+	if id <= 0 {
+		return nil, models.Invalid("invalid import job ID: %d", id)
+	}
+
+	return s.repo.GetImportJob(ctx, userID, id)
+}
+
+func (s *ImportJobService) ListImportJobs(ctx context.Context, userID int) ([]*models.ImportJob, error) {
+	return s.repo.ListImportJobs(ctx, userID)
+}
+
+// ResumeImportJob restarts a failed job from its first uncommitted chunk
+func (s *ImportJobService) ResumeImportJob(ctx context.Context, userID, id int) (*models.ImportJob, error) {
+	if id <= 0 {
+		return nil, models.Invalid("invalid import job ID: %d", id)
+	}
+
+	if err := s.repo.ResumeImportJob(ctx, userID, id); err != nil {
+		return nil, err
+	}
+
+	job, err := s.repo.GetImportJob(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	LoggerFromContext(ctx).Info("Resuming import job", "import_job_id", job.ID, "committed_chunks", job.CommittedChunks, "chunks", job.TotalChunks)
+	s.start(ctx, job)
+	return job, nil
+}
+
+// FailStaleJobs fails running jobs that stopped making progress, such as
+// those on a server that crashed. It runs on a schedule.
+func (s *ImportJobService) FailStaleJobs(ctx context.Context) error {
+	message := "import stopped making progress; resume it to continue"
+	failed, err := s.repo.FailStaleImportJobs(ctx, time.Now().Add(-IMPORT_JOB_STALE_AFTER), message)
+	if err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		LoggerFromContext(ctx).Warn("Failed stale import jobs", "jobs", len(failed))
+	}
+	for _, job := range failed {
+		s.queueRetry(ctx, job, errors.New(message))
+	}
+	return nil
+}
+
+// RetryImportJob resumes a failed job and runs it to the end. It is the
+// dead-letter handler for import jobs; a job the user already resumed or
+// deleted needs no retry.
+func (s *ImportJobService) RetryImportJob(ctx context.Context, payload json.RawMessage) error {
+	var retry importJobPayload
+	if err := json.Unmarshal(payload, &retry); err != nil {
+		return fmt.Errorf("invalid import job payload: %w", err)
+	}
+
+	if err := s.repo.ResumeImportJob(ctx, retry.UserID, retry.JobID); err != nil {
+		if errors.Is(err, models.ErrNotFound) || errors.Is(err, models.ErrConflict) {
+			return nil
+		}
+		return err
+	}
+
+	job, err := s.repo.GetImportJob(ctx, retry.UserID, retry.JobID)
+	if err != nil {
+		return err
+	}
+
+	LoggerFromContext(ctx).Info("Retrying import job", "import_job_id", job.ID, "committed_chunks", job.CommittedChunks, "chunks", job.TotalChunks)
+	if err := s.run(ctx, job); err != nil && !errors.Is(err, models.ErrQuotaExceeded) {
+		return err
+	}
+	return nil
+}
+
+// Stop cancels running jobs and waits for them to record where they
+// stopped. The chunk in flight is rolled back and redone on resume.
+func (s *ImportJobService) Stop(ctx context.Context) error {
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+// start runs the job in the background. It outlives the request that
+// started it but not the service.
+func (s *ImportJobService) start(ctx context.Context, job *models.ImportJob) {
+	logger := LoggerFromContext(ctx).With("import_job_id", job.ID)
+	runCtx := ContextWithLogger(s.ctx, logger)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.run(runCtx, job); err != nil && !errors.Is(err, models.ErrQuotaExceeded) {
+			s.queueRetry(runCtx, job, err)
+		}
+	}()
+}
+
+// run commits the job's remaining chunks. On failure the job is marked
+// failed and the error returned.
+func (s *ImportJobService) run(ctx context.Context, job *models.ImportJob) error {
+	logger := LoggerFromContext(ctx)
+
+	for {
+		chunk, count, ok, err := s.repo.GetNextImportChunk(ctx, job.ID)
+		if err != nil {
+			return s.fail(ctx, job, err)
+		}
+		if !ok {
+			break
+		}
+
+		if err := s.quotas.CheckNotes(ctx, job.UserID, count); err != nil {
+			return s.fail(ctx, job, err)
+		}
+
+		created, err := s.repo.CommitImportChunk(ctx, job.ID, chunk)
+		if err != nil {
+			return s.fail(ctx, job, err)
+		}
+		s.noteService.InvalidateCache(ctx, job.UserID)
+
+		logger.Info("Committed import chunk", "chunk", chunk, "created", created)
+	}
+
+	if err := s.repo.FinishImportJob(ctx, job.ID, models.ImportJobStatusCompleted, nil); err != nil {
+		logger.Error("Failed to complete import job", "error", err)
+		return err
+	}
+	logger.Info("Completed import job")
+	return nil
+}
+
+// fail records why the job stopped and returns that reason. It still runs
+// when the service is stopping, so the job can be resumed after a restart.
+func (s *ImportJobService) fail(ctx context.Context, job *models.ImportJob, cause error) error {
+	message := cause.Error()
+	if ctx.Err() != nil {
+		message = "import was interrupted by a server shutdown"
+	}
+
+	logger := LoggerFromContext(ctx)
+	logger.Error("Import job failed", "error", message)
+	if err := s.repo.FinishImportJob(context.WithoutCancel(ctx), job.ID, models.ImportJobStatusFailed, &message); err != nil {
+		logger.Error("Failed to record import job failure", "error", err)
+	}
+	return errors.New(message)
+}
+
+// queueRetry adds a failed job to the dead-letter queue, which resumes it
+// with backoff. Jobs out of quota are not queued; they wait for the user.
+func (s *ImportJobService) queueRetry(ctx context.Context, job *models.ImportJob, cause error) {
+	ctx = context.WithoutCancel(ctx)
+	payload := importJobPayload{UserID: job.UserID, JobID: job.ID}
+	if err := s.deadLetters.Record(ctx, models.DeadLetterKindImportJob, deadLetterKey(job.ID), &job.UserID, payload, cause); err != nil {
+		LoggerFromContext(ctx).Error("Failed to queue import job for retry", "import_job_id", job.ID, "error", err)
+	}
+}
+
+// importChunkSize applies the default to a chunk size of 0 and checks the
+// limits
+func importChunkSize(chunkSize int) (int, error) {
+	if chunkSize == 0 {
+		chunkSize = DEFAULT_IMPORT_CHUNK_SIZE
+	}
+	if chunkSize < 1 || chunkSize > MAX_IMPORT_CHUNK_SIZE {
+		return 0, models.Invalid("chunk size must be between 1 and %d", MAX_IMPORT_CHUNK_SIZE)
+	}
+	return chunkSize, nil
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	// Attempts before a job fails for good; validation errors and exceeded
+	// quotas fail it at once
+	MAX_JOB_ATTEMPTS = 3
+	// The first retry waits this long and each later one twice as long
+	JOB_RETRY_BASE = time.Minute
+	// How long a claimed job is held; the worker extends the lease every
+	// third of it while the job runs
+	JOB_LEASE = 5 * time.Minute
+	// How often each instance polls for due jobs
+	JOB_POLL_INTERVAL = 5 * time.Second
+	MAX_JOB_PAYLOAD   = 64 * 1024
+)
+
+var jobStatuses = []string{models.JobStatusQueued, models.JobStatusRunning, models.JobStatusCompleted, models.JobStatusFailed}
+
+// JobRunner does the work of one kind of job for the user who queued it and
+// returns what GET /jobs/{id} reports as its result
+type JobRunner func(ctx context.Context, userID int, payload json.RawMessage) (any, error)
+
+type jobKind struct {
+	run   JobRunner
+	admin bool
+}
+
+// JobService queues expensive LLM work to run in the background and
+// reports its status. Each kind of job registers its runner at startup;
+// every instance polls for due jobs and runs them one at a time.
+type JobService struct {
+	repo  db.JobRepository
+	kinds map[string]jobKind
+}
+
+func NewJobService(repo db.JobRepository) *JobService {
+	return &JobService{repo: repo, kinds: make(map[string]jobKind)}
+}
+
+// Handle registers the runner for a kind any user can queue
+func (s *JobService) Handle(kind string, run JobRunner) {
+	s.kinds[kind] = jobKind{run: run}
+}
+
+// HandleAdmin registers the runner for a kind only queued through the admin
+// endpoint, for work that is not scoped to one user
+func (s *JobService) HandleAdmin(kind string, run JobRunner) {
+	s.kinds[kind] = jobKind{run: run, admin: true}
+}
+
+// Enqueue queues a job. admin allows the kinds registered with HandleAdmin,
+// and only those.
+func (s *JobService) Enqueue(ctx context.Context, userID int, req *models.CreateJobRequest, admin bool) (*models.Job, error) {
+	if req == nil {
+		return nil, models.Invalid("request cannot be nil")
+	}
+	kind, ok := s.kinds[req.Kind]
+	if !ok || kind.admin != admin {
+		return nil, models.Invalid("unknown job kind %q", req.Kind)
+	}
+
+	payload := req.Payload
+	if len(payload) == 0 || string(payload) == "null" {
+		payload = json.RawMessage("{}")
+	}
+	if len(payload) > MAX_JOB_PAYLOAD {
+		return nil, models.Invalid("payload must be at most %d bytes", MAX_JOB_PAYLOAD)
+	}
+	var object map[string]any
+	if err := json.Unmarshal(payload, &object); err != nil {
+		return nil, models.Invalid("payload must be a JSON object")
+	}
+
+	job := &models.Job{UserID: userID, Kind: req.Kind, Payload: payload}
+	if err := s.repo.CreateJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	LoggerFromContext(ctx).Info("Queued job", "job_id", job.ID, "kind", job.Kind)
+	return job, nil
+}
+
+func (s *JobService) GetJob(ctx context.Context, userID, id int) (*models.Job, error) {
+	if id <= 0 {
+		return nil, models.Invalid("invalid job ID: %d", id)
+	}
+
+	return s.repo.GetJob(ctx, userID, id)
+}
+
+// ListJobs lists the user's jobs, newest first by default, optionally only
+// those with the given status or kind
+func (s *JobService) ListJobs(ctx context.Context, userID int, filter models.JobFilter, params models.ListParams) (*models.Page[*models.Job], error) {
+	params, err := normalizeListParams(params)
+	if err != nil {
+		return nil, models.Invalid("%s", err.Error())
+	}
+	if filter.Status != "" && !containsValue(jobStatuses, filter.Status) {
+		return nil, models.Invalid("status must be one of: queued, running, completed, failed")
+	}
+
+	jobs, total, err := s.repo.ListJobs(ctx, userID, filter, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Page[*models.Job]{
+		Items:  jobs,
+		Total:  total,
+		Limit:  params.Limit,
+		Offset: params.Offset,
+	}, nil
+}
+
+// RunDue claims and runs due jobs one at a time until none is left or the
+// worker is stopped. It runs on a schedule on every instance.
+func (s *JobService) RunDue(ctx context.Context) error {
+	for ctx.Err() == nil {
+		job, err := s.repo.ClaimNextJob(ctx, JOB_LEASE)
+		if err != nil {
+			return err
+		}
+		if job == nil {
+			return nil
+		}
+		s.run(ctx, job)
+	}
+	return nil
+}
+
+func (s *JobService) run(ctx context.Context, job *models.Job) {
+	logger := LoggerFromContext(ctx).With("job_id", job.ID, "kind", job.Kind, "user_id", job.UserID)
+	runCtx := ContextWithUser(ContextWithLogger(ctx, logger), job.UserID)
+
+	stopLease := s.keepLease(runCtx, job.ID)
+	result, err := s.runKind(runCtx, job)
+	stopLease()
+
+	// Shutting down; another worker picks the job up again
+	if ctx.Err() != nil {
+		if releaseErr := s.repo.ReleaseJob(context.WithoutCancel(ctx), job.ID); releaseErr != nil {
+			logger.Error("Failed to release job", "error", releaseErr)
+		}
+		return
+	}
+
+	var data []byte
+	if err == nil {
+		data, err = json.Marshal(result)
+	}
+	if err == nil {
+		if err := s.repo.CompleteJob(ctx, job.ID, data); err != nil {
+			logger.Error("Failed to complete job", "error", err)
+			return
+		}
+		logger.Info("Job completed", "attempts", job.Attempts+1)
+		return
+	}
+
+	attempts := job.Attempts + 1
+	var nextAttemptAt *time.Time
+	if attempts < MAX_JOB_ATTEMPTS && jobRetryable(err) {
+		next := time.Now().Add(jobBackoff(attempts))
+		nextAttemptAt = &next
+	}
+	if updateErr := s.repo.FailJobAttempt(ctx, job.ID, err.Error(), nextAttemptAt); updateErr != nil {
+		logger.Error("Failed to update job", "error", updateErr)
+		return
+	}
+
+	if nextAttemptAt == nil {
+		logger.Error("Job failed", "attempts", attempts, "error", err)
+	} else {
+		logger.Warn("Job attempt failed", "attempts", attempts, "next_attempt_at", nextAttemptAt, "error", err)
+	}
+}
+
+func (s *JobService) runKind(ctx context.Context, job *models.Job) (any, error) {
+	kind, ok := s.kinds[job.Kind]
+	if !ok {
+		return nil, models.Invalid("no runner for job kind %q", job.Kind)
+	}
+	return kind.run(ctx, job.UserID, job.Payload)
+}
+
+// keepLease extends the job's lease while it runs, so a long job is not
+// claimed by another worker. The returned function stops it.
+func (s *JobService) keepLease(ctx context.Context, id int) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(JOB_LEASE / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.repo.ExtendJobLease(ctx, id, JOB_LEASE); err != nil && ctx.Err() == nil {
+					LoggerFromContext(ctx).Warn("Failed to extend job lease", "error", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// jobRetryable reports whether a failed job may succeed on a later attempt.
+// Bad payloads and exceeded quotas will not.
+func jobRetryable(err error) bool {
+	return !errors.Is(err, models.ErrValidation) && !errors.Is(err, models.ErrNotFound) && !errors.Is(err, models.ErrQuotaExceeded)
+}
+
+// jobBackoff is the wait before the attempt that follows the given number
+// of failed attempts
+func jobBackoff(attempts int) time.Duration {
+	delay := JOB_RETRY_BASE
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+	}
+	return delay
+}
+
+// decodeJobPayload decodes a job's payload into v, rejecting unknown fields
+func decodeJobPayload(payload json.RawMessage, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return models.Invalid("invalid job payload: %s", err.Error())
+	}
+	return nil
+}
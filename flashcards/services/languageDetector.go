@@ -0,0 +1,200 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"flashcards/models"
+)
+
+// Fewest stop word hits a Latin-script text needs before its language is
+// trusted, and how far ahead of the runner-up the top language must be
+const (
+	MIN_LANGUAGE_STOP_WORDS = 3
+	LANGUAGE_LEAD_RATIO     = 1.5
+)
+
+// Languages are stored as ISO 639-1 codes
+var languageCodePattern = regexp.MustCompile(`^[a-z]{2}$`)
+
+// languageNames names the languages the detector knows, for prompts
+var languageNames = map[string]string{
+	"ar": "Arabic",
+	"de": "German",
+	"el": "Greek",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"he": "Hebrew",
+	"hi": "Hindi",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"pl": "Polish",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"sv": "Swedish",
+	"th": "Thai",
+	"tr": "Turkish",
+	"uk": "Ukrainian",
+	"zh": "Chinese",
+}
+
+// Common function words of the Latin-script languages, chosen to overlap
+// little between languages
+var languageStopWords = map[string][]string{
+	"en": {"the", "and", "is", "are", "of", "to", "in", "that", "it", "with", "for", "was", "this", "which", "by", "from", "be", "have", "not", "or"},
+	"es": {"el", "los", "las", "del", "y", "que", "es", "en", "por", "para", "con", "una", "como", "pero", "más", "son", "su", "se", "fue", "muy"},
+	"fr": {"le", "les", "des", "et", "est", "une", "dans", "que", "qui", "pour", "pas", "sur", "avec", "au", "aux", "ce", "sont", "il", "du", "mais"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "mit", "den", "dem", "zu", "auf", "für", "sich", "von", "wird", "sind", "auch", "oder"},
+	"it": {"il", "gli", "della", "delle", "che", "è", "per", "non", "una", "sono", "nel", "alla", "dei", "anche", "come", "lo", "questo", "con", "ha", "più"},
+	"pt": {"os", "as", "da", "do", "das", "dos", "não", "uma", "em", "que", "é", "com", "para", "mais", "como", "foi", "ao", "pela", "pelo", "são"},
+	"nl": {"de", "het", "een", "en", "van", "is", "niet", "dat", "op", "zijn", "voor", "met", "ook", "maar", "bij", "wordt", "naar", "deze", "er", "om"},
+	"sv": {"och", "att", "det", "som", "är", "en", "på", "för", "med", "av", "inte", "den", "till", "har", "om", "ett", "var", "jag", "men", "från"},
+	"pl": {"i", "w", "nie", "się", "na", "jest", "że", "do", "to", "z", "jak", "ale", "od", "po", "czy", "tak", "są", "przez", "dla", "jego"},
+	"tr": {"ve", "bir", "bu", "da", "de", "için", "ile", "olarak", "çok", "daha", "gibi", "olan", "ama", "en", "var", "ne", "değil", "kadar", "sonra", "ise"},
+}
+
+var languageStopWordSets = func() map[string]map[string]bool {
+	sets := make(map[string]map[string]bool, len(languageStopWords))
+	for language, words := range languageStopWords {
+		sets[language] = make(map[string]bool, len(words))
+		for _, word := range words {
+			sets[language][word] = true
+		}
+	}
+	return sets
+}()
+
+// Scripts used by a single language the detector knows, checked in order
+var languageScripts = []struct {
+	language string
+	table    *unicode.RangeTable
+}{
+	{"ja", unicode.Hiragana},
+	{"ja", unicode.Katakana},
+	{"ko", unicode.Hangul},
+	{"zh", unicode.Han},
+	{"el", unicode.Greek},
+	{"ar", unicode.Arabic},
+	{"he", unicode.Hebrew},
+	{"hi", unicode.Devanagari},
+	{"th", unicode.Thai},
+}
+
+// DetectLanguage returns the ISO 639-1 code of the text's language and
+// whether the guess can be trusted. Texts in a script used by one language
+// are identified by script; Latin-script texts by their stop words, which
+// needs a few sentences to be reliable.
+func DetectLanguage(text string) (string, bool) {
+	letters, latin, cyrillic := 0, 0, 0
+	scripts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		default:
+			for _, script := range languageScripts {
+				if unicode.Is(script.table, r) {
+					scripts[script.language]++
+					break
+				}
+			}
+		}
+	}
+	if letters == 0 {
+		return "", false
+	}
+
+	// Japanese mixes kana into Han text, so any kana at all means Japanese
+	if scripts["ja"] > 0 && scripts["ja"]+scripts["zh"] > letters/2 {
+		return "ja", true
+	}
+	for _, script := range languageScripts {
+		if scripts[script.language] > letters/2 {
+			return script.language, true
+		}
+	}
+	if cyrillic > letters/2 {
+		if strings.ContainsAny(strings.ToLower(text), "іїєґ") {
+			return "uk", true
+		}
+		return "ru", true
+	}
+	if latin <= letters/2 {
+		return "", false
+	}
+
+	hits := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	}) {
+		for language, words := range languageStopWordSets {
+			if words[word] {
+				hits[language]++
+			}
+		}
+	}
+
+	best, bestHits, runnerUp := "", 0, 0
+	for language, count := range hits {
+		if count > bestHits || (count == bestHits && language < best) {
+			best, bestHits, runnerUp = language, count, max(runnerUp, bestHits)
+		} else if count > runnerUp {
+			runnerUp = count
+		}
+	}
+	if bestHits < MIN_LANGUAGE_STOP_WORDS || float64(bestHits) < LANGUAGE_LEAD_RATIO*float64(runnerUp) {
+		return best, false
+	}
+	return best, true
+}
+
+// LanguageName returns the English name of a language code, or the code
+// itself for languages the detector does not know
+func LanguageName(code string) string {
+	if name, ok := languageNames[code]; ok {
+		return name
+	}
+	return code
+}
+
+// normalizeLanguage turns a language code or English name, such as "ES" or
+// "Spanish", into its ISO 639-1 code, or "" when it is neither
+func normalizeLanguage(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if languageCodePattern.MatchString(value) {
+		return value
+	}
+	for code, name := range languageNames {
+		if strings.ToLower(name) == value {
+			return code
+		}
+	}
+	return ""
+}
+
+// languageInstruction asks for what is written to be in the notes'
+// language when they share one other than English, which prompts assume
+func languageInstruction(notes []*models.Note, what string) string {
+	language := ""
+	for _, note := range notes {
+		if note.Language == "" || (language != "" && note.Language != language) {
+			return ""
+		}
+		language = note.Language
+	}
+	if language == "" || language == "en" {
+		return ""
+	}
+	return fmt.Sprintf("Write %s in %s, the language of the notes.", what, LanguageName(language))
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"flashcards/models"
+)
+
+// Resume tokens are refreshed with every question served, so a connection
+// can rejoin for this long after its last question
+const LIVE_QUIZ_RESUME_TTL = 15 * time.Minute
+
+// LiveQuizService runs a quiz session over a live connection: it serves
+// one question at a time, grades each answer as it arrives and completes
+// the session after the last one. Progress is saved to the session as it
+// goes, so a live quiz can also be finished over the REST endpoints.
+type LiveQuizService struct {
+	sessions *QuizSessionService
+	auth     *AuthService
+}
+
+func NewLiveQuizService(sessions *QuizSessionService, auth *AuthService) *LiveQuizService {
+	return &LiveQuizService{sessions: sessions, auth: auth}
+}
+
+// Join authenticates the first message of a connection, either a start
+// with a bearer token and session ID or a resume with a resume token, and
+// returns the user and the session to run
+func (s *LiveQuizService) Join(ctx context.Context, msg *models.LiveQuizMessage) (int, int, error) {
+	var userID, sessionID int
+	switch msg.Type {
+	case models.LiveQuizMessageStart:
+		id, err := s.auth.Authenticate(msg.Token)
+		if err != nil {
+			return 0, 0, err
+		}
+		userID, sessionID = id, msg.SessionID
+	case models.LiveQuizMessageResume:
+		id, session, err := s.auth.VerifyLiveQuizResume(msg.ResumeToken)
+		if err != nil {
+			return 0, 0, err
+		}
+		userID, sessionID = id, session
+	default:
+		return 0, 0, models.Invalid("the first message must be %q or %q", models.LiveQuizMessageStart, models.LiveQuizMessageResume)
+	}
+
+	if _, err := s.sessions.GetSessionByID(ctx, userID, sessionID); err != nil {
+		return 0, 0, err
+	}
+	return userID, sessionID, nil
+}
+
+// Welcome returns the message acknowledging a joined connection
+func (s *LiveQuizService) Welcome(userID, sessionID int) (*models.LiveQuizMessage, error) {
+	token, err := s.auth.SignLiveQuizResume(userID, sessionID, LIVE_QUIZ_RESUME_TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LiveQuizMessage{
+		Type:        models.LiveQuizMessageSession,
+		SessionID:   sessionID,
+		ResumeToken: token,
+	}, nil
+}
+
+// Next returns the message serving the next question or, once none are
+// left, the message completing the session with its report. The question
+// is sent without its answer key, which comes with the result.
+func (s *LiveQuizService) Next(ctx context.Context, userID, sessionID int) (*models.LiveQuizMessage, error) {
+	session, err := s.sessions.GetSessionByID(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Status == models.SessionStatusCompleted {
+		report, err := s.sessions.GetReport(ctx, userID, sessionID)
+		if err != nil {
+			return nil, err
+		}
+		return completeMessage(sessionID, report), nil
+	}
+
+	next, err := s.sessions.NextQuestion(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		report, err := s.sessions.CompleteSession(ctx, userID, sessionID)
+		if err != nil {
+			return nil, err
+		}
+		return completeMessage(sessionID, report), nil
+	}
+
+	token, err := s.auth.SignLiveQuizResume(userID, sessionID, LIVE_QUIZ_RESUME_TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := 0
+	for _, question := range session.Questions {
+		if question.Status != models.QuestionStatusAnswered {
+			remaining++
+		}
+	}
+
+	served := *next
+	served.Question.CorrectAnswer = ""
+	served.Question.Explanation = ""
+	return &models.LiveQuizMessage{
+		Type:        models.LiveQuizMessageQuestion,
+		SessionID:   sessionID,
+		ResumeToken: token,
+		Question:    &served,
+		Remaining:   remaining,
+	}, nil
+}
+
+// Answer saves and grades the answer to a question, returning the result
+// with the correct answer and its explanation
+func (s *LiveQuizService) Answer(ctx context.Context, userID, sessionID, position int, answer string, timeSpentMs int) (*models.LiveQuizMessage, error) {
+	req := &models.UpdateSessionQuestionRequest{Answer: &answer}
+	if timeSpentMs > 0 {
+		req.TimeSpentMs = &timeSpentMs
+	}
+
+	session, err := s.sessions.UpdateQuestion(ctx, userID, sessionID, position, req)
+	if err != nil {
+		return nil, err
+	}
+
+	question := session.Questions[position].Question
+	return &models.LiveQuizMessage{
+		Type:          models.LiveQuizMessageResult,
+		SessionID:     sessionID,
+		Position:      &position,
+		Correct:       gradeAnswer(question, strings.TrimSpace(answer)),
+		CorrectAnswer: question.CorrectAnswer,
+		Explanation:   question.Explanation,
+	}, nil
+}
+
+// Skip puts a question back to be served after the unanswered ones
+func (s *LiveQuizService) Skip(ctx context.Context, userID, sessionID, position int) error {
+	_, err := s.sessions.SkipQuestion(ctx, userID, sessionID, position)
+	return err
+}
+
+func completeMessage(sessionID int, report *models.SessionReport) *models.LiveQuizMessage {
+	return &models.LiveQuizMessage{
+		Type:      models.LiveQuizMessageComplete,
+		SessionID: sessionID,
+		Report:    report,
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"flashcards/models"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+const (
+	DEFAULT_LLM_FAILURE_THRESHOLD = 5
+	DEFAULT_LLM_RETRY_INTERVAL    = 30 * time.Second
+)
+
+// ErrLLMUnavailable is returned without calling the provider while it is
+// considered down. Errors wrapping it are *LLMUnavailableError.
+var ErrLLMUnavailable = errors.New("LLM provider is unavailable")
+
+// LLMUnavailableError carries how long until the provider is tried again
+type LLMUnavailableError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LLMUnavailableError) Error() string {
+	return fmt.Sprintf("%v, try again in %ds", ErrLLMUnavailable, retryAfterSeconds(e.RetryAfter))
+}
+
+func (e *LLMUnavailableError) Is(target error) bool {
+	return target == ErrLLMUnavailable
+}
+
+// LLMAvailability tracks whether the LLM provider is answering. After
+// threshold calls in a row fail, calls are refused for retryInterval; then a
+// single call is let through, and its outcome closes the circuit again or
+// restarts the wait. Calls cancelled by the caller do not count.
+type LLMAvailability struct {
+	threshold     int
+	retryInterval time.Duration
+
+	mu               sync.Mutex
+	failures         int
+	unavailableSince time.Time
+	retryAt          time.Time
+	probing          bool
+}
+
+func NewLLMAvailability(threshold int, retryInterval time.Duration) *LLMAvailability {
+	if threshold <= 0 {
+		threshold = DEFAULT_LLM_FAILURE_THRESHOLD
+	}
+	if retryInterval <= 0 {
+		retryInterval = DEFAULT_LLM_RETRY_INTERVAL
+	}
+	return &LLMAvailability{threshold: threshold, retryInterval: retryInterval}
+}
+
+// Available reports whether a call would be let through now
+func (a *LLMAvailability) Available() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.failures < a.threshold || (!a.probing && !time.Now().Before(a.retryAt))
+}
+
+// Status describes the provider for the /meta endpoint
+func (a *LLMAvailability) Status() models.LLMStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.failures < a.threshold {
+		return models.LLMStatus{Available: true}
+	}
+	since := a.unavailableSince
+	return models.LLMStatus{
+		Available:         false,
+		UnavailableSince:  &since,
+		RetryAfterSeconds: retryAfterSeconds(time.Until(a.retryAt)),
+	}
+}
+
+// acquire returns an error when the call must not reach the provider
+func (a *LLMAvailability) acquire() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.failures < a.threshold {
+		return nil
+	}
+	now := time.Now()
+	if a.probing || now.Before(a.retryAt) {
+		return &LLMUnavailableError{RetryAfter: a.retryAt.Sub(now)}
+	}
+	a.probing = true
+	return nil
+}
+
+func (a *LLMAvailability) record(ctx context.Context, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	wasDown := a.failures >= a.threshold
+	a.probing = false
+
+	switch {
+	case err == nil:
+		if wasDown {
+			LoggerFromContext(ctx).Info("LLM provider is available again", "unavailable_for", time.Since(a.unavailableSince).Round(time.Second))
+		}
+		a.failures = 0
+		a.unavailableSince = time.Time{}
+	case errors.Is(err, context.Canceled):
+		// The caller went away; this says nothing about the provider
+	default:
+		a.failures++
+		if a.failures < a.threshold {
+			return
+		}
+		a.retryAt = time.Now().Add(a.retryInterval)
+		if !wasDown {
+			a.unavailableSince = time.Now()
+			LoggerFromContext(ctx).Error("LLM provider marked unavailable", "failures", a.failures, "retry_in", a.retryInterval, "error", err)
+		}
+	}
+}
+
+// availableModel refuses calls while the provider is considered down and
+// reports the outcome of the calls it lets through
+type availableModel struct {
+	LLMClient
+	availability *LLMAvailability
+}
+
+func (m *availableModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	if err := m.availability.acquire(); err != nil {
+		return nil, err
+	}
+
+	response, err := m.LLMClient.GenerateContent(ctx, messages, options...)
+	m.availability.record(ctx, err)
+	return response, err
+}
+
+func retryAfterSeconds(d time.Duration) int {
+	return max(1, int(math.Ceil(d.Seconds())))
+}
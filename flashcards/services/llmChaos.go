@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"flashcards/chaos"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// chaosModel delays, fails and corrupts LLM calls at the injector's rates.
+// It sits next to the provider so the availability tracking, retries and
+// output validation above it handle the faults like real ones.
+type chaosModel struct {
+	LLMClient
+	injector *chaos.Injector
+}
+
+func (m *chaosModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	if err := m.injector.Delay(ctx); err != nil {
+		return nil, err
+	}
+	if m.injector.LLMError() {
+		return nil, fmt.Errorf("LLM provider error: %w", chaos.ErrInjected)
+	}
+
+	response, err := m.LLMClient.GenerateContent(ctx, messages, options...)
+	if err != nil || response == nil {
+		return response, err
+	}
+	for _, choice := range response.Choices {
+		choice.Content = m.injector.MalformLLMOutput(choice.Content)
+	}
+	return response, nil
+}
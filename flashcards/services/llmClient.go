@@ -0,0 +1,29 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// LLMClient is the part of a language model the services call. The
+// langchaingo provider clients satisfy it, as do the wrappers NewLLMClient
+// stacks on them and FakeLLMClient.
+type LLMClient interface {
+	GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error)
+}
+
+// generateFromPrompt sends prompt to client as a single human message and
+// returns the text of the first choice
+func generateFromPrompt(ctx context.Context, client LLMClient, prompt string, options ...llms.CallOption) (string, error) {
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, prompt)}
+	response, err := client.GenerateContent(ctx, messages, options...)
+	if err != nil {
+		return "", err
+	}
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("empty response from model")
+	}
+	return response.Choices[0].Content, nil
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"flashcards/chaos"
+	"flashcards/models"
+
+	"github.com/tmc/langchaingo/llms/anthropic"
+	"github.com/tmc/langchaingo/llms/ollama"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+const (
+	PROVIDER_OPENAI    = "openai"
+	PROVIDER_ANTHROPIC = "anthropic"
+	PROVIDER_OLLAMA    = "ollama"
+)
+
+var defaultProviderModels = map[string]string{
+	PROVIDER_OPENAI:    "gpt-4o-mini",
+	PROVIDER_ANTHROPIC: "claude-3-5-haiku-latest",
+	PROVIDER_OLLAMA:    "llama3.1",
+}
+
+// Endpoints the provider clients call when no base URL is configured
+var defaultProviderBaseURLs = map[string]string{
+	PROVIDER_OPENAI:    "https://api.openai.com/v1",
+	PROVIDER_ANTHROPIC: "https://api.anthropic.com/v1",
+	PROVIDER_OLLAMA:    "http://127.0.0.1:11434",
+}
+
+// ProviderConfig selects and configures the LLM backend. VisionModel, when
+// set, is used instead of Model for requests that include images.
+// ContextWindow overrides the context size looked up from the model name.
+type ProviderConfig struct {
+	Provider      string
+	Model         string
+	VisionModel   string
+	BaseURL       string
+	APIKey        string
+	ContextWindow int
+	// API keys and base URLs by provider name, for routing rules that send
+	// requests to another provider
+	ProviderAPIKeys  map[string]string
+	ProviderBaseURLs map[string]string
+	// Upper bound on a single generation request; 0 means no limit
+	Timeout time.Duration
+	// Meters calls against the caller's token quota when set
+	Quotas *QuotaService
+	// Records each call's cost and enforces the monthly budget when set
+	Usage *UsageService
+	// Stops calling the provider while it is down when set
+	Availability *LLMAvailability
+	// Injects latency, errors and malformed output when set
+	Chaos *chaos.Injector
+	// Answers in place of the provider's client when set, such as a
+	// FakeLLMClient in tests. Provider and Model still name it in traces
+	// and metering.
+	Client LLMClient
+}
+
+// ProviderName returns the configured provider in lower case, defaulting
+// to OpenAI
+func (cfg ProviderConfig) ProviderName() string {
+	provider := strings.ToLower(strings.TrimSpace(cfg.Provider))
+	if provider == "" {
+		return PROVIDER_OPENAI
+	}
+	return provider
+}
+
+// ModelName returns the configured model or the provider's default
+func (cfg ProviderConfig) ModelName() string {
+	if cfg.Model != "" {
+		return cfg.Model
+	}
+	return defaultProviderModels[cfg.ProviderName()]
+}
+
+// BaseURLOrDefault returns the configured base URL or the provider's
+// default endpoint
+func (cfg ProviderConfig) BaseURLOrDefault() string {
+	if cfg.BaseURL != "" {
+		return cfg.BaseURL
+	}
+	return defaultProviderBaseURLs[cfg.ProviderName()]
+}
+
+// ForRoute returns the configuration for the provider and model a routing
+// rule picked; an empty provider keeps this one. Another provider gets its
+// own key and base URL, and is not counted against this one's availability.
+func (cfg ProviderConfig) ForRoute(provider, model string) ProviderConfig {
+	route := cfg
+	route.Model = model
+	route.VisionModel = ""
+	if provider != "" && provider != cfg.ProviderName() {
+		route.Provider = provider
+		route.APIKey = cfg.ProviderAPIKeys[provider]
+		route.BaseURL = cfg.ProviderBaseURLs[provider]
+		route.Availability = nil
+	}
+	return route
+}
+
+// WarmLLMConnection opens a connection to the provider, including the TLS
+// handshake, and leaves it idle for the first LLM call to reuse. The
+// provider clients send through http.DefaultClient, so its pool is the one
+// warmed. Any HTTP response counts, since the request carries no key.
+func WarmLLMConnection(ctx context.Context, cfg ProviderConfig) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, cfg.BaseURLOrDefault(), nil)
+	if err != nil {
+		return fmt.Errorf("invalid LLM base URL: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return models.Upstream("failed to reach LLM provider: %w", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return nil
+}
+
+// NewLLMClient builds a client for the configured provider, or wraps
+// cfg.Client when set. Calls through it are traced and, with Quotas, Usage
+// and Availability set, metered, costed and refused while the provider is
+// down.
+func NewLLMClient(cfg ProviderConfig) (LLMClient, error) {
+	provider := cfg.ProviderName()
+	model := cfg.ModelName()
+	client := cfg.Client
+	if client == nil {
+		var err error
+		client, err = newProviderClient(cfg, provider, model)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Chaos != nil {
+		client = &chaosModel{LLMClient: client, injector: cfg.Chaos}
+	}
+	if cfg.Availability != nil {
+		client = &availableModel{LLMClient: client, availability: cfg.Availability}
+	}
+	if cfg.Usage != nil {
+		client = &costedModel{LLMClient: client, usage: cfg.Usage, model: model}
+	}
+	if cfg.Quotas != nil {
+		client = &meteredModel{LLMClient: client, quotas: cfg.Quotas, model: model}
+	}
+	return &tracedModel{LLMClient: client, provider: provider, model: model}, nil
+}
+
+func newProviderClient(cfg ProviderConfig, provider, model string) (LLMClient, error) {
+
+	slog.Info("Creating LLM client", "provider", provider, "model", model)
+
+	switch provider {
+	case PROVIDER_OPENAI:
+		if cfg.APIKey == "" {
+			slog.Error("OpenAI API key is required but not provided")
+			return nil, fmt.Errorf("OpenAI API key is required")
+		}
+		opts := []openai.Option{openai.WithModel(model), openai.WithToken(cfg.APIKey)}
+		if cfg.BaseURL != "" {
+			opts = append(opts, openai.WithBaseURL(cfg.BaseURL))
+		}
+		return openai.New(opts...)
+	case PROVIDER_ANTHROPIC:
+		if cfg.APIKey == "" {
+			slog.Error("Anthropic API key is required but not provided")
+			return nil, fmt.Errorf("Anthropic API key is required")
+		}
+		opts := []anthropic.Option{anthropic.WithModel(model), anthropic.WithToken(cfg.APIKey)}
+		if cfg.BaseURL != "" {
+			opts = append(opts, anthropic.WithBaseURL(cfg.BaseURL))
+		}
+		return anthropic.New(opts...)
+	case PROVIDER_OLLAMA:
+		opts := []ollama.Option{ollama.WithModel(model), ollama.WithHTTPClient(http.DefaultClient)}
+		if cfg.BaseURL != "" {
+			opts = append(opts, ollama.WithServerURL(cfg.BaseURL))
+		}
+		return ollama.New(opts...)
+	default:
+		slog.Error("Unsupported LLM provider", "provider", cfg.Provider)
+		return nil, fmt.Errorf("unsupported LLM provider: %s", cfg.Provider)
+	}
+}
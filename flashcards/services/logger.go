@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+type loggerContextKey struct{}
+
+// NewLogger builds the application logger. format is "json" or "text";
+// level is one of debug, info, warn or error and defaults to info.
+func NewLogger(format, level string) *slog.Logger {
+	var lvl slog.Level
+	switch strings.ToLower(level) {
+	case "debug":
+		lvl = slog.LevelDebug
+	case "warn":
+		lvl = slog.LevelWarn
+	case "error":
+		lvl = slog.LevelError
+	default:
+		lvl = slog.LevelInfo
+	}
+
+	options := &slog.HandlerOptions{Level: lvl}
+	if strings.ToLower(format) == "text" {
+		return slog.New(slog.NewTextHandler(os.Stdout, options))
+	}
+	return slog.New(slog.NewJSONHandler(os.Stdout, options))
+}
+
+// ContextWithLogger returns a context carrying logger, so code further down
+// the call chain logs with the same request fields
+func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// LoggerFromContext returns the logger stored in ctx, or the default logger
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"mime/multipart"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"flashcards/models"
+)
+
+// MailerConfig holds SMTP settings. An empty Host disables email delivery.
+type MailerConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+type Mailer struct {
+	cfg MailerConfig
+}
+
+func NewMailer(cfg MailerConfig) *Mailer {
+	if cfg.Host == "" {
+		slog.Info("SMTP host not configured, email delivery disabled")
+	}
+	return &Mailer{cfg: cfg}
+}
+
+func (m *Mailer) Enabled() bool {
+	return m != nil && m.cfg.Host != ""
+}
+
+// Send delivers a plain text email with optional attachments
+func (m *Mailer) Send(to []string, subject, body string, attachments ...Attachment) error {
+	return m.send(to, "", subject, body, "", attachments)
+}
+
+// SendHTML delivers an email with an HTML body and a plain text alternative
+// for clients that do not show HTML
+func (m *Mailer) SendHTML(to []string, subject, text, htmlBody string) error {
+	return m.send(to, "", subject, text, htmlBody, nil)
+}
+
+// SendHTMLWithReplyTo is SendHTML with replies addressed to replyTo rather
+// than the sender
+func (m *Mailer) SendHTMLWithReplyTo(to []string, replyTo, subject, text, htmlBody string) error {
+	return m.send(to, replyTo, subject, text, htmlBody, nil)
+}
+
+func (m *Mailer) send(to []string, replyTo, subject, body, htmlBody string, attachments []Attachment) error {
+	if !m.Enabled() {
+		return fmt.Errorf("email delivery is not configured")
+	}
+
+	slog.Info("Sending email", "recipients", len(to), "attachments", len(attachments))
+	startTime := time.Now()
+
+	message, err := buildMessage(m.cfg.From, to, replyTo, subject, body, htmlBody, attachments)
+	if err != nil {
+		slog.Error("Failed to build email message", "error", err)
+		return models.Internal("failed to build email: %w", err)
+	}
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+
+	addr := m.cfg.Host + ":" + m.cfg.Port
+	if err := smtp.SendMail(addr, auth, m.cfg.From, to, message); err != nil {
+		slog.Error("Failed to send email", "duration_ms", time.Since(startTime).Milliseconds(), "error", err)
+		return models.Upstream("failed to send email: %w", err)
+	}
+
+	slog.Info("Email sent", "duration_ms", time.Since(startTime).Milliseconds())
+	return nil
+}
+
+func buildMessage(from string, to []string, replyTo, subject, body, htmlBody string, attachments []Attachment) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	if replyTo != "" {
+		fmt.Fprintf(&buf, "Reply-To: %s\r\n", replyTo)
+	}
+	fmt.Fprintf(&buf, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+
+	if htmlBody == "" {
+		textPart, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {"text/plain; charset=utf-8"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		textPart.Write([]byte(body))
+	} else if err := writeAlternativePart(writer, body, htmlBody); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range attachments {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {fmt.Sprintf(`attachment; filename="%s"`, attachment.Filename)},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		part.Write([]byte(encoded + "\r\n"))
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeAlternativePart nests the text and HTML bodies in a
+// multipart/alternative part, which clients show one of, preferring the last
+func writeAlternativePart(writer *multipart.Writer, text, htmlBody string) error {
+	var buf bytes.Buffer
+	alternative := multipart.NewWriter(&buf)
+
+	for _, body := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", htmlBody},
+	} {
+		part, err := alternative.CreatePart(textproto.MIMEHeader{"Content-Type": {body.contentType}})
+		if err != nil {
+			return err
+		}
+		part.Write([]byte(body.content))
+	}
+	if err := alternative.Close(); err != nil {
+		return err
+	}
+
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"multipart/alternative; boundary=" + alternative.Boundary()},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(buf.Bytes())
+	return err
+}
+
+func isValidEmail(address string) bool {
+	parsed, err := mail.ParseAddress(address)
+	return err == nil && parsed.Address == address
+}
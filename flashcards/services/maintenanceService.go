@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	// How long an instance serves the maintenance state it last loaded, and
+	// so how long a toggle takes to reach the other instances
+	MAINTENANCE_CACHE_TTL = 5 * time.Second
+	// Retry-After for refused requests when no end time is set, or it has
+	// passed
+	DEFAULT_MAINTENANCE_RETRY_AFTER = time.Minute
+	MAX_MAINTENANCE_MESSAGE_LENGTH  = 500
+)
+
+var validMaintenanceModes = []string{models.MaintenanceModeOff, models.MaintenanceModeReadOnly, models.MaintenanceModeFull}
+
+// MaintenanceService holds whether the API is in read-only or full
+// maintenance. It is checked on every request, so the state is cached
+// briefly.
+type MaintenanceService struct {
+	repo db.MaintenanceRepository
+
+	mu          sync.Mutex
+	maintenance *models.Maintenance
+	loadedAt    time.Time
+}
+
+func NewMaintenanceService(repo db.MaintenanceRepository) *MaintenanceService {
+	return &MaintenanceService{repo: repo, maintenance: &models.Maintenance{Mode: models.MaintenanceModeOff}}
+}
+
+// GetMaintenance returns the current state. When it cannot be loaded the
+// last known one is kept, so a database outage doesn't take the API down
+// with it.
+func (s *MaintenanceService) GetMaintenance(ctx context.Context) *models.Maintenance {
+	s.mu.Lock()
+	maintenance, loadedAt := s.maintenance, s.loadedAt
+	s.mu.Unlock()
+	if time.Since(loadedAt) < MAINTENANCE_CACHE_TTL {
+		return maintenance
+	}
+
+	loaded, err := s.repo.GetMaintenance(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadedAt = time.Now()
+	if err != nil {
+		LoggerFromContext(ctx).Error("Failed to load maintenance state", "error", err)
+		return s.maintenance
+	}
+	s.maintenance = loaded
+	return loaded
+}
+
+// UpdateMaintenance switches the mode. Turning maintenance off clears the
+// message and end time.
+func (s *MaintenanceService) UpdateMaintenance(ctx context.Context, req *models.UpdateMaintenanceRequest) (*models.Maintenance, error) {
+	mode := strings.ToLower(strings.TrimSpace(req.Mode))
+	if !containsValue(validMaintenanceModes, mode) {
+		return nil, models.Invalid("mode must be one of: %s", strings.Join(validMaintenanceModes, ", "))
+	}
+	message := strings.TrimSpace(req.Message)
+	if len(message) > MAX_MAINTENANCE_MESSAGE_LENGTH {
+		return nil, models.Invalid("message must be at most %d characters", MAX_MAINTENANCE_MESSAGE_LENGTH)
+	}
+	if req.EndsAt != nil && !req.EndsAt.After(time.Now()) {
+		return nil, models.Invalid("endsAt must be in the future")
+	}
+
+	current, err := s.repo.GetMaintenance(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get maintenance: %w", err)
+	}
+
+	maintenance := &models.Maintenance{Mode: mode}
+	if maintenance.Active() {
+		maintenance.Message = message
+		maintenance.EndsAt = req.EndsAt
+		// Switching between read-only and full keeps when it started
+		maintenance.StartedAt = current.StartedAt
+		if !current.Active() || maintenance.StartedAt == nil {
+			now := time.Now()
+			maintenance.StartedAt = &now
+		}
+	}
+
+	if err := s.repo.SaveMaintenance(ctx, maintenance); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.maintenance, s.loadedAt = maintenance, time.Now()
+	s.mu.Unlock()
+
+	LoggerFromContext(ctx).Info("Maintenance mode changed", "from", current.Mode, "to", maintenance.Mode)
+	return maintenance, nil
+}
+
+// MaintenanceRetryAfter is how long clients should wait before retrying a
+// request refused for maintenance
+func MaintenanceRetryAfter(maintenance *models.Maintenance) time.Duration {
+	if maintenance.EndsAt != nil {
+		if remaining := time.Until(*maintenance.EndsAt); remaining > 0 {
+			return remaining
+		}
+	}
+	return DEFAULT_MAINTENANCE_RETRY_AFTER
+}
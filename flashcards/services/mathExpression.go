@@ -0,0 +1,383 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	MAX_MATH_EXPRESSION_LENGTH = 200
+	MATH_EQUALITY_TOLERANCE    = 1e-9
+)
+
+// Values substituted for variables when comparing expressions like "2x+2"
+// and "2(x+1)". Chosen to avoid roots and poles of common expressions.
+var mathSamplePoints = []float64{0.37, 1.91, -2.53, 3.17, 0.83}
+
+var mathFunctions = map[string]func(float64) float64{
+	"sqrt": math.Sqrt,
+	"abs":  math.Abs,
+	"sin":  math.Sin,
+	"cos":  math.Cos,
+	"tan":  math.Tan,
+	"ln":   math.Log,
+	"log":  math.Log10,
+	"exp":  math.Exp,
+}
+
+var mathConstants = map[string]float64{
+	"pi": math.Pi,
+	"π":  math.Pi,
+	"e":  math.E,
+}
+
+// mathNode is a parsed expression: a number, a variable, an operator
+// applied to args, or a function call
+type mathNode struct {
+	op    string
+	value float64
+	name  string
+	args  []*mathNode
+}
+
+func (n *mathNode) eval(vars map[string]float64) float64 {
+	switch n.op {
+	case "num":
+		return n.value
+	case "var":
+		return vars[n.name]
+	case "neg":
+		return -n.args[0].eval(vars)
+	case "+":
+		return n.args[0].eval(vars) + n.args[1].eval(vars)
+	case "-":
+		return n.args[0].eval(vars) - n.args[1].eval(vars)
+	case "*":
+		return n.args[0].eval(vars) * n.args[1].eval(vars)
+	case "/":
+		return n.args[0].eval(vars) / n.args[1].eval(vars)
+	case "^":
+		return math.Pow(n.args[0].eval(vars), n.args[1].eval(vars))
+	case "%":
+		return n.args[0].eval(vars) / 100
+	default:
+		return mathFunctions[n.op](n.args[0].eval(vars))
+	}
+}
+
+func (n *mathNode) collectVars(vars map[string]bool) {
+	if n.op == "var" {
+		vars[n.name] = true
+	}
+	for _, arg := range n.args {
+		arg.collectVars(vars)
+	}
+}
+
+// mathAnswersEquivalent reports whether two answers denote the same value,
+// so "1/2", "0.5", "50%" and "2^-1" all match, as do "2(x+1)" and "2x+2".
+// The second result is false when either answer is not a parsable
+// expression and the caller should fall back to comparing text.
+func mathAnswersEquivalent(answer, expected string) (bool, bool) {
+	a, err := parseMathExpression(answer)
+	if err != nil {
+		return false, false
+	}
+	b, err := parseMathExpression(expected)
+	if err != nil {
+		return false, false
+	}
+
+	varSet := make(map[string]bool)
+	a.collectVars(varSet)
+	b.collectVars(varSet)
+	names := make([]string, 0, len(varSet))
+	for name := range varSet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	samples := 1
+	if len(names) > 0 {
+		samples = len(mathSamplePoints)
+	}
+
+	compared := 0
+	for i := 0; i < samples; i++ {
+		vars := make(map[string]float64, len(names))
+		for j, name := range names {
+			vars[name] = mathSamplePoints[(i+j)%len(mathSamplePoints)] + float64(j)
+		}
+
+		x, y := a.eval(vars), b.eval(vars)
+		xInvalid := math.IsNaN(x) || math.IsInf(x, 0)
+		yInvalid := math.IsNaN(y) || math.IsInf(y, 0)
+		if xInvalid && yInvalid {
+			continue
+		}
+		if xInvalid != yInvalid || !floatsEqual(x, y) {
+			return false, true
+		}
+		compared++
+	}
+
+	return compared > 0, true
+}
+
+func floatsEqual(x, y float64) bool {
+	scale := math.Max(1, math.Max(math.Abs(x), math.Abs(y)))
+	return math.Abs(x-y) <= MATH_EQUALITY_TOLERANCE*scale
+}
+
+// parseMathExpression parses an answer such as "x = 3/4", "2^-1", "50%" or
+// "sqrt(2)/2". A leading "x =" is dropped so the right-hand side is compared.
+func parseMathExpression(input string) (*mathNode, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil, fmt.Errorf("empty expression")
+	}
+	if len(input) > MAX_MATH_EXPRESSION_LENGTH {
+		return nil, fmt.Errorf("expression too long")
+	}
+
+	if left, right, found := strings.Cut(input, "="); found {
+		left = strings.TrimSpace(left)
+		if !isMathIdentifier(left) || strings.Contains(right, "=") {
+			return nil, fmt.Errorf("unsupported equation")
+		}
+		input = right
+	}
+	input = strings.TrimSuffix(strings.TrimSpace(input), ".")
+
+	tokens, err := tokenizeMath(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &mathParser{tokens: tokens}
+	node, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return node, nil
+}
+
+func isMathIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func tokenizeMath(input string) ([]string, error) {
+	replacer := strings.NewReplacer("×", "*", "·", "*", "÷", "/", "−", "-", "**", "^")
+	runes := []rune(replacer.Replace(input))
+
+	var tokens []string
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		case unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && unicode.IsLetter(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, strings.ToLower(string(runes[start:i])))
+		case strings.ContainsRune("+-*/^()%", r):
+			tokens = append(tokens, string(r))
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q", r)
+		}
+	}
+	return tokens, nil
+}
+
+type mathParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *mathParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *mathParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+// expr := term (("+" | "-") term)*
+func (p *mathParser) parseExpr() (*mathNode, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "+" || p.peek() == "-" {
+		op := p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &mathNode{op: op, args: []*mathNode{left, right}}
+	}
+	return left, nil
+}
+
+// term := unary (("*" | "/" | implicit multiplication) unary)*
+func (p *mathParser) parseTerm() (*mathNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		switch {
+		case op == "*" || op == "/":
+			p.next()
+		case op == "(" || startsOperand(op):
+			// "2x" and "2(x+1)" multiply implicitly
+			op = "*"
+		default:
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &mathNode{op: op, args: []*mathNode{left, right}}
+	}
+}
+
+// unary := ("-" | "+") unary | power
+func (p *mathParser) parseUnary() (*mathNode, error) {
+	switch p.peek() {
+	case "-":
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &mathNode{op: "neg", args: []*mathNode{operand}}, nil
+	case "+":
+		p.next()
+		return p.parseUnary()
+	}
+	return p.parsePower()
+}
+
+// power := postfix ("^" unary)?, right associative so 2^-1 and 2^3^2 work
+func (p *mathParser) parsePower() (*mathNode, error) {
+	base, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != "^" {
+		return base, nil
+	}
+	p.next()
+	exponent, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &mathNode{op: "^", args: []*mathNode{base, exponent}}, nil
+}
+
+// postfix := primary "%"?
+func (p *mathParser) parsePostfix() (*mathNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() == "%" {
+		p.next()
+		node = &mathNode{op: "%", args: []*mathNode{node}}
+	}
+	return node, nil
+}
+
+// primary := number | constant | function "(" expr ")" | variable | "(" expr ")"
+func (p *mathParser) parsePrimary() (*mathNode, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case token == "(":
+		return p.parseGroup()
+	case unicode.IsDigit(rune(token[0])) || token[0] == '.':
+		value, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", token)
+		}
+		return &mathNode{op: "num", value: value}, nil
+	case mathFunctions[token] != nil:
+		if p.next() != "(" {
+			return nil, fmt.Errorf("expected ( after %s", token)
+		}
+		arg, err := p.parseGroup()
+		if err != nil {
+			return nil, err
+		}
+		return &mathNode{op: token, args: []*mathNode{arg}}, nil
+	case isMathIdentifier(token):
+		return identifierNode(token)
+	default:
+		return nil, fmt.Errorf("unexpected %q", token)
+	}
+}
+
+func (p *mathParser) parseGroup() (*mathNode, error) {
+	node, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.next() != ")" {
+		return nil, fmt.Errorf("missing closing parenthesis")
+	}
+	return node, nil
+}
+
+// identifierNode reads a constant or a single-letter variable. Other words
+// are not math, so "no solution" and "dog" fail to parse and the answer is
+// compared as text; write "x*y" or "x y" for a product.
+func identifierNode(token string) (*mathNode, error) {
+	if value, ok := mathConstants[token]; ok {
+		return &mathNode{op: "num", value: value}, nil
+	}
+	if len([]rune(token)) != 1 {
+		return nil, fmt.Errorf("unknown identifier %q", token)
+	}
+	return &mathNode{op: "var", name: token}, nil
+}
+
+func startsOperand(token string) bool {
+	if token == "" {
+		return false
+	}
+	r := []rune(token)[0]
+	return unicode.IsDigit(r) || r == '.' || unicode.IsLetter(r)
+}
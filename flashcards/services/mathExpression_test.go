@@ -0,0 +1,107 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseMathExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		vars  map[string]float64
+		want  float64
+	}{
+		{"1 + 2 * 3", nil, 7},
+		{"(1 + 2) * 3", nil, 9},
+		{"8 / 4 / 2", nil, 1},
+		{"10 - 4 - 3", nil, 3},
+		{"2^3^2", nil, 512},
+		{"2^-1", nil, 0.5},
+		{"-2^2", nil, -4},
+		{"(-2)^2", nil, 4},
+		{"--3", nil, 3},
+		{"3 * -2", nil, -6},
+		{"50%", nil, 0.5},
+		{"2 × 3 ÷ 4", nil, 1.5},
+		{"2**3", nil, 8},
+		{"x = 3/4", nil, 0.75},
+		{"0.5.", nil, 0.5},
+		{"sqrt(16) + abs(-2)", nil, 6},
+		{"ln(e) + log(100)", nil, 3},
+		{"cos(0) + sin(0)", nil, 1},
+		{"2pi", nil, 2 * math.Pi},
+		{"2x + 1", map[string]float64{"x": 3}, 7},
+		{"2(x+1)", map[string]float64{"x": 3}, 8},
+		{"x y", map[string]float64{"x": 2, "y": 5}, 10},
+		{"X^2", map[string]float64{"x": 3}, 9},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			node, err := parseMathExpression(test.input)
+			if err != nil {
+				t.Fatalf("parseMathExpression: %v", err)
+			}
+			if got := node.eval(test.vars); !floatsEqual(got, test.want) {
+				t.Errorf("eval = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseMathExpressionRejects(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"no solution",
+		"dog",
+		"xy",
+		"2 +",
+		"(1 + 2",
+		"sqrt 4",
+		"x = y = 1",
+		"2x = 4",
+		"1 $ 2",
+		"1..2",
+	} {
+		if _, err := parseMathExpression(input); err == nil {
+			t.Errorf("parseMathExpression(%q) succeeded, want an error", input)
+		}
+	}
+}
+
+func TestMathAnswersEquivalent(t *testing.T) {
+	tests := []struct {
+		answer, expected string
+		want, parsed     bool
+	}{
+		{"1/2", "0.5", true, true},
+		{"50%", "1/2", true, true},
+		{"2^-1", "0.5", true, true},
+		{"x = 3/4", "0.75", true, true},
+		{"2(x+1)", "2x+2", true, true},
+		{"(x+1)^2", "x^2 + 2x + 1", true, true},
+		{"sqrt(2)/2", "1/sqrt(2)", true, true},
+		{"2x*y", "2y x", true, true},
+
+		{"1/3", "0.33", false, true},
+		{"2x+1", "2x+2", false, true},
+		{"x^2", "x", false, true},
+		{"x", "y", false, true},
+		{"-1", "1", false, true},
+
+		// Words are not math and fall back to comparing text
+		{"no solution", "solution no", false, false},
+		{"dog", "god", false, false},
+		{"xy", "yx", false, false},
+		{"", "1", false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.answer+" vs "+test.expected, func(t *testing.T) {
+			got, parsed := mathAnswersEquivalent(test.answer, test.expected)
+			if got != test.want || parsed != test.parsed {
+				t.Errorf("mathAnswersEquivalent = %v, %v, want %v, %v", got, parsed, test.want, test.parsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const INVITE_TTL = 7 * 24 * time.Hour
+
+// Roles an invite can grant; ownership is only held by the creator
+var inviteRoles = []string{models.RoleAdmin, models.RoleMember}
+
+// MembershipService lets users create their own organization and bring in
+// teammates through emailed invite links
+type MembershipService struct {
+	organizations db.OrganizationRepository
+	invites       db.InviteRepository
+	users         db.UserRepository
+	mailer        *Mailer
+	appURL        string
+}
+
+func NewMembershipService(organizations db.OrganizationRepository, invites db.InviteRepository, users db.UserRepository, mailer *Mailer, appURL string) *MembershipService {
+	return &MembershipService{
+		organizations: organizations,
+		invites:       invites,
+		users:         users,
+		mailer:        mailer,
+		appURL:        strings.TrimRight(appURL, "/"),
+	}
+}
+
+// CreateOrganization creates a free organization owned by the user
+func (s *MembershipService) CreateOrganization(ctx context.Context, userID int, req *models.CreateOwnedOrganizationRequest) (*models.MembershipDetails, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, models.Invalid("name is required")
+	}
+	if len(name) > MAX_ORGANIZATION_NAME {
+		return nil, models.Invalid("name cannot exceed %d characters", MAX_ORGANIZATION_NAME)
+	}
+
+	organization := &models.Organization{Name: name, Plan: models.PlanFree}
+	if err := s.organizations.CreateOwnedOrganization(ctx, organization, userID); err != nil {
+		return nil, err
+	}
+
+	LoggerFromContext(ctx).Info("Created organization", "organization_id", organization.ID)
+	return &models.MembershipDetails{Organization: organization, Role: models.RoleOwner}, nil
+}
+
+// CreateInvite issues an invite to the organization and emails its link.
+// The token is returned too, so the link can be shared by hand when email
+// is not configured or fails.
+func (s *MembershipService) CreateInvite(ctx context.Context, userID, organizationID int, req *models.CreateInviteRequest) (*models.OrganizationInvite, error) {
+	membership, err := s.organizations.GetMembership(ctx, userID)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		return nil, err
+	}
+	if membership == nil || membership.OrganizationID != organizationID || membership.Role == models.RoleMember {
+		return nil, models.Forbidden("only organization owners and admins can invite members")
+	}
+
+	email := normalizeEmail(req.Email)
+	if !isValidEmail(email) {
+		return nil, models.Invalid("a valid email address is required")
+	}
+
+	role := req.Role
+	if role == "" {
+		role = models.RoleMember
+	}
+	if !containsValue(inviteRoles, role) {
+		return nil, models.Invalid("role must be one of: %s", strings.Join(inviteRoles, ", "))
+	}
+
+	organization, err := s.organizations.GetOrganization(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, tokenHash, err := newInviteToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invite := &models.OrganizationInvite{
+		OrganizationID: organizationID,
+		Email:          email,
+		Role:           role,
+		InvitedBy:      &userID,
+		ExpiresAt:      time.Now().Add(INVITE_TTL),
+	}
+	if err := s.invites.CreateInvite(ctx, invite, tokenHash); err != nil {
+		return nil, err
+	}
+
+	invite.Token = token
+	invite.URL = s.appURL + "/invites/" + token
+
+	logger := LoggerFromContext(ctx)
+	if s.mailer.Enabled() {
+		body := fmt.Sprintf("You have been invited to join %s as %s.\n\nAccept the invite: %s\n\nThe link expires on %s.\n",
+			organization.Name, role, invite.URL, invite.ExpiresAt.UTC().Format("January 2, 2006"))
+		if err := s.mailer.Send([]string{email}, "Join "+organization.Name, body); err != nil {
+			logger.Error("Failed to email invite", "invite_id", invite.ID, "error", err)
+		} else {
+			invite.EmailSent = true
+		}
+	}
+
+	logger.Info("Created organization invite", "invite_id", invite.ID, "organization_id", organizationID, "role", role)
+	return invite, nil
+}
+
+// AcceptInvite adds the user to the invite's organization with the role it
+// grants. The invite must be unused, unexpired and sent to the user's email.
+func (s *MembershipService) AcceptInvite(ctx context.Context, userID int, token string) (*models.MembershipDetails, error) {
+	invite, err := s.invites.GetInviteByTokenHash(ctx, hashInviteToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if invite.AcceptedAt != nil {
+		return nil, models.Conflict("invite has already been accepted")
+	}
+	if time.Now().After(invite.ExpiresAt) {
+		return nil, models.Gone("invite has expired")
+	}
+
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if normalizeEmail(user.Email) != invite.Email {
+		return nil, models.Forbidden("invite was sent to a different email address")
+	}
+
+	if err := s.invites.AcceptInvite(ctx, invite, userID); err != nil {
+		return nil, err
+	}
+
+	organization, err := s.organizations.GetOrganization(ctx, invite.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	LoggerFromContext(ctx).Info("Accepted organization invite", "invite_id", invite.ID, "organization_id", organization.ID, "role", invite.Role)
+	return &models.MembershipDetails{Organization: organization, Role: invite.Role}, nil
+}
+
+// Invite tokens are 32 random bytes; only their SHA-256 is stored
+func newInviteToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", models.Internal("failed to generate invite token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashInviteToken(token), nil
+}
+
+func hashInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
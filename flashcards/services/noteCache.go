@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"flashcards/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	MAX_NOTE_CACHE_USERS  = 10000
+	REDIS_NOTE_KEY_PREFIX = "flashcards:notes:"
+)
+
+// NoteCache keeps users' notes between quiz requests so prompts do not
+// re-query every note each time. Entries are per user and dropped whole by
+// Invalidate whenever one of the user's notes changes. Failures count as
+// misses, so a cache outage only costs the queries it would have saved.
+type NoteCache interface {
+	GetNotes(ctx context.Context, userID int) ([]*models.Note, bool)
+	SetNotes(ctx context.Context, userID int, notes []*models.Note)
+	GetNote(ctx context.Context, userID, id int) (*models.Note, bool)
+	SetNote(ctx context.Context, userID int, note *models.Note)
+	Invalidate(ctx context.Context, userID int)
+}
+
+type memoryNoteEntry struct {
+	all       []*models.Note
+	byID      map[int]*models.Note
+	expiresAt time.Time
+}
+
+// MemoryNoteCache holds notes in process. Only suitable for a single
+// instance, since other instances never see its invalidations.
+type MemoryNoteCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[int]*memoryNoteEntry
+}
+
+func NewMemoryNoteCache(ttl time.Duration) *MemoryNoteCache {
+	return &MemoryNoteCache{ttl: ttl, entries: map[int]*memoryNoteEntry{}}
+}
+
+func (c *MemoryNoteCache) GetNotes(ctx context.Context, userID int) ([]*models.Note, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := c.entry(userID, false)
+	if entry == nil || entry.all == nil {
+		return nil, false
+	}
+	return cloneNotes(entry.all), true
+}
+
+func (c *MemoryNoteCache) SetNotes(ctx context.Context, userID int, notes []*models.Note) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := c.entry(userID, true)
+	entry.all = cloneNotes(notes)
+	for _, note := range entry.all {
+		entry.byID[note.ID] = note
+	}
+}
+
+// GetNote also finds notes cached by GetNotes
+func (c *MemoryNoteCache) GetNote(ctx context.Context, userID, id int) (*models.Note, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := c.entry(userID, false)
+	if entry == nil {
+		return nil, false
+	}
+	note, ok := entry.byID[id]
+	if !ok {
+		return nil, false
+	}
+	return cloneNote(note), true
+}
+
+func (c *MemoryNoteCache) SetNote(ctx context.Context, userID int, note *models.Note) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entry(userID, true).byID[note.ID] = cloneNote(note)
+}
+
+func (c *MemoryNoteCache) Invalidate(ctx context.Context, userID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, userID)
+}
+
+// entry returns the user's live entry, creating one when asked. The caller
+// holds c.mu.
+func (c *MemoryNoteCache) entry(userID int, create bool) *memoryNoteEntry {
+	now := time.Now()
+	entry, ok := c.entries[userID]
+	if ok && now.After(entry.expiresAt) {
+		delete(c.entries, userID)
+		ok = false
+	}
+	if ok || !create {
+		return entry
+	}
+
+	if len(c.entries) >= MAX_NOTE_CACHE_USERS {
+		c.evict(now)
+	}
+	entry = &memoryNoteEntry{byID: map[int]*models.Note{}, expiresAt: now.Add(c.ttl)}
+	c.entries[userID] = entry
+	return entry
+}
+
+// evict drops expired entries, or the one closest to expiring when none
+// have expired yet
+func (c *MemoryNoteCache) evict(now time.Time) {
+	oldestID := 0
+	var oldest *memoryNoteEntry
+	for userID, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, userID)
+			continue
+		}
+		if oldest == nil || entry.expiresAt.Before(oldest.expiresAt) {
+			oldestID, oldest = userID, entry
+		}
+	}
+	if len(c.entries) >= MAX_NOTE_CACHE_USERS && oldest != nil {
+		delete(c.entries, oldestID)
+	}
+}
+
+// RedisNoteCache shares cached notes and their invalidation between
+// instances. Each user's notes live in one hash, with the full list under
+// the "all" field and single notes under their IDs.
+type RedisNoteCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedisNoteCache(client *redis.Client, ttl time.Duration) *RedisNoteCache {
+	return &RedisNoteCache{client: client, ttl: ttl}
+}
+
+func (c *RedisNoteCache) GetNotes(ctx context.Context, userID int) ([]*models.Note, bool) {
+	var notes []*models.Note
+	if !c.get(ctx, userID, "all", &notes) {
+		return nil, false
+	}
+	return notes, true
+}
+
+func (c *RedisNoteCache) SetNotes(ctx context.Context, userID int, notes []*models.Note) {
+	c.set(ctx, userID, "all", notes)
+}
+
+func (c *RedisNoteCache) GetNote(ctx context.Context, userID, id int) (*models.Note, bool) {
+	var note *models.Note
+	if !c.get(ctx, userID, strconv.Itoa(id), &note) || note == nil {
+		return nil, false
+	}
+	return note, true
+}
+
+func (c *RedisNoteCache) SetNote(ctx context.Context, userID int, note *models.Note) {
+	c.set(ctx, userID, strconv.Itoa(note.ID), note)
+}
+
+func (c *RedisNoteCache) Invalidate(ctx context.Context, userID int) {
+	if err := c.client.Del(ctx, noteCacheKey(userID)).Err(); err != nil {
+		// A missed invalidation would serve stale notes until the TTL, so
+		// it is logged louder than other cache failures
+		LoggerFromContext(ctx).Error("Failed to invalidate note cache", "user_id", userID, "error", err)
+	}
+}
+
+func (c *RedisNoteCache) get(ctx context.Context, userID int, field string, value any) bool {
+	data, err := c.client.HGet(ctx, noteCacheKey(userID), field).Bytes()
+	if err == redis.Nil {
+		return false
+	}
+	if err != nil {
+		LoggerFromContext(ctx).Warn("Failed to read note cache", "user_id", userID, "error", err)
+		return false
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		LoggerFromContext(ctx).Warn("Failed to decode cached notes", "user_id", userID, "error", err)
+		return false
+	}
+	return true
+}
+
+func (c *RedisNoteCache) set(ctx context.Context, userID int, field string, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	// The field and the expiry are written together, so a failure cannot
+	// leave a hash that never expires
+	key := noteCacheKey(userID)
+	_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, field, data)
+		pipe.PExpire(ctx, key, c.ttl)
+		return nil
+	})
+	if err != nil {
+		LoggerFromContext(ctx).Warn("Failed to write note cache", "user_id", userID, "error", err)
+	}
+}
+
+func noteCacheKey(userID int) string {
+	return REDIS_NOTE_KEY_PREFIX + strconv.Itoa(userID)
+}
+
+// Cached notes are copied in and out so callers cannot change them
+func cloneNotes(notes []*models.Note) []*models.Note {
+	cloned := make([]*models.Note, len(notes))
+	for i, note := range notes {
+		cloned[i] = cloneNote(note)
+	}
+	return cloned
+}
+
+func cloneNote(note *models.Note) *models.Note {
+	cloned := *note
+	cloned.Tags = make([]string, len(note.Tags))
+	copy(cloned.Tags, note.Tags)
+	if note.DeckID != nil {
+		deckID := *note.DeckID
+		cloned.DeckID = &deckID
+	}
+	return &cloned
+}
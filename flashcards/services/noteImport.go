@@ -0,0 +1,381 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"flashcards/models"
+)
+
+const (
+	// Largest upload accepted by a single import request
+	MAX_IMPORT_BYTES = 10 * 1024 * 1024
+	// Largest Markdown file accepted, including files extracted from a zip
+	MAX_IMPORT_FILE_BYTES = 1024 * 1024
+	// Total Markdown extracted from the zip archives of one import
+	MAX_IMPORT_EXTRACTED_BYTES = 20 * 1024 * 1024
+	MAX_IMPORT_ZIP_ENTRIES     = 500
+	// Notes created by one import; further sections are skipped
+	MAX_IMPORT_NOTES = 500
+	// Headings at this level or above start a new note by default
+	DEFAULT_IMPORT_HEADING_LEVEL = 2
+)
+
+// ImportNotes splits uploaded Markdown files, or the Markdown files inside
+// zip archives, into one note per heading. Sections that cannot become a
+// note are skipped and reported rather than failing the import. A
+// validate-only import builds the same report without creating anything.
+func (s *NoteService) ImportNotes(ctx context.Context, userID int, req *models.ImportNotesRequest) (*models.NoteImportReport, error) {
+	notes, report, err := s.PlanNoteImport(ctx, userID, req, MAX_IMPORT_NOTES)
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.ValidateOnly && len(notes) > 0 {
+		if err := s.repo.CreateNotes(ctx, notes); err != nil {
+			return nil, fmt.Errorf("failed to import notes: %w", err)
+		}
+		s.InvalidateCache(ctx, userID)
+		for i, note := range notes {
+			report.Created[i].NoteID = &note.ID
+		}
+	}
+
+	LoggerFromContext(ctx).Info("Imported notes", "created", len(report.Created), "skipped", len(report.Skipped), "validate_only", req.ValidateOnly)
+	return report, nil
+}
+
+// PlanNoteImport parses an import into the notes it would create, at most
+// limit, without creating them. report.Created lists the sections of those
+// notes in the same order. Exceeding the note quota is an error, except
+// for a validate-only import, which reports it in the summary instead.
+func (s *NoteService) PlanNoteImport(ctx context.Context, userID int, req *models.ImportNotesRequest, limit int) ([]*models.Note, *models.NoteImportReport, error) {
+	if err := s.validateImportRequest(req); err != nil {
+		return nil, nil, err
+	}
+
+	if req.DeckID != nil {
+		if _, err := s.deckService.GetDeckByID(ctx, userID, *req.DeckID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	existing, err := s.GetAllNotes(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	existingContent := make(map[string]bool, len(existing))
+	for _, note := range existing {
+		existingContent[note.Content] = true
+	}
+
+	report := &models.NoteImportReport{
+		ValidateOnly: req.ValidateOnly,
+		Created:      []models.NoteImportItem{},
+		Skipped:      []models.NoteImportItem{},
+	}
+	skip := func(item models.NoteImportItem, reason string) {
+		item.Reason = reason
+		report.Skipped = append(report.Skipped, item)
+		report.Summary.Errors++
+	}
+	duplicate := func(item models.NoteImportItem, reason string) {
+		item.Reason = reason
+		report.Skipped = append(report.Skipped, item)
+		report.Summary.Duplicates++
+	}
+
+	var notes []*models.Note
+	imported := make(map[string]bool)
+	for _, file := range extractMarkdownFiles(req.Files, skip) {
+		for _, section := range splitMarkdownSections(file.Data, req.HeadingLevel) {
+			item := models.NoteImportItem{File: file.Name, Line: section.line, Heading: section.heading, Length: len(section.content)}
+			switch {
+			case section.body == "":
+				skip(item, "section has no content")
+			case len(section.content) > MAX_NOTE_LENGTH:
+				skip(item, fmt.Sprintf("section exceeds %d characters", MAX_NOTE_LENGTH))
+			case existingContent[section.content]:
+				duplicate(item, "duplicate of an existing note")
+			case imported[section.content]:
+				duplicate(item, "duplicate of an earlier section")
+			case len(notes) >= limit:
+				skip(item, fmt.Sprintf("import is limited to %d notes", limit))
+			default:
+				imported[section.content] = true
+				note := &models.Note{
+					UserID:  userID,
+					DeckID:  req.DeckID,
+					Content: section.content,
+					Tags:    []string{},
+				}
+				// Headings and file names too long to keep are left out
+				if len(section.heading) <= MAX_NOTE_TITLE_LENGTH {
+					note.Title = section.heading
+				}
+				if len(file.Name) <= MAX_NOTE_SOURCE_LENGTH {
+					note.Source = file.Name
+				}
+				notes = append(notes, note)
+				report.Created = append(report.Created, item)
+			}
+		}
+	}
+	report.Summary.Importable = len(notes)
+	report.Summary.Total = len(report.Created) + len(report.Skipped)
+
+	if len(notes) > 0 {
+		if err := s.quotas.CheckNotes(ctx, userID, len(notes)); err != nil {
+			if !req.ValidateOnly || !errors.Is(err, models.ErrQuotaExceeded) {
+				return nil, nil, err
+			}
+			report.Summary.QuotaError = err.Error()
+		}
+	}
+
+	return notes, report, nil
+}
+
+func (s *NoteService) validateImportRequest(req *models.ImportNotesRequest) error {
+	if req == nil {
+		return models.Invalid("request cannot be nil")
+	}
+
+	if len(req.Files) == 0 {
+		return models.Invalid("at least one file is required")
+	}
+
+	if req.HeadingLevel == 0 {
+		req.HeadingLevel = DEFAULT_IMPORT_HEADING_LEVEL
+	}
+	if req.HeadingLevel < 1 || req.HeadingLevel > 6 {
+		return models.Invalid("heading level must be between 1 and 6")
+	}
+
+	return nil
+}
+
+// extractMarkdownFiles expands zip archives and drops files that are not
+// Markdown, reporting each dropped file through skip
+func extractMarkdownFiles(files []models.ImportFile, skip func(models.NoteImportItem, string)) []models.ImportFile {
+	var markdown []models.ImportFile
+	extracted := 0
+
+	for _, file := range files {
+		switch strings.ToLower(path.Ext(file.Name)) {
+		case ".md", ".markdown":
+			if reason := checkMarkdownFile(file.Data); reason != "" {
+				skip(models.NoteImportItem{File: file.Name}, reason)
+				continue
+			}
+			markdown = append(markdown, file)
+		case ".zip":
+			archive, err := zip.NewReader(bytes.NewReader(file.Data), int64(len(file.Data)))
+			if err != nil {
+				skip(models.NoteImportItem{File: file.Name}, "file is not a valid zip archive")
+				continue
+			}
+			if len(archive.File) > MAX_IMPORT_ZIP_ENTRIES {
+				skip(models.NoteImportItem{File: file.Name}, fmt.Sprintf("archive has more than %d entries", MAX_IMPORT_ZIP_ENTRIES))
+				continue
+			}
+
+			for _, entry := range archive.File {
+				name := file.Name + "/" + path.Clean(entry.Name)
+				ext := strings.ToLower(path.Ext(entry.Name))
+				if entry.FileInfo().IsDir() || isHiddenZipEntry(entry.Name) {
+					continue
+				}
+				if ext != ".md" && ext != ".markdown" {
+					skip(models.NoteImportItem{File: name}, "unsupported file type")
+					continue
+				}
+				if entry.UncompressedSize64 > MAX_IMPORT_FILE_BYTES {
+					skip(models.NoteImportItem{File: name}, fmt.Sprintf("file exceeds %d bytes", MAX_IMPORT_FILE_BYTES))
+					continue
+				}
+				if extracted+int(entry.UncompressedSize64) > MAX_IMPORT_EXTRACTED_BYTES {
+					skip(models.NoteImportItem{File: name}, fmt.Sprintf("archives exceed %d bytes uncompressed", MAX_IMPORT_EXTRACTED_BYTES))
+					continue
+				}
+
+				data, err := readZipEntry(entry)
+				if err != nil {
+					skip(models.NoteImportItem{File: name}, "file could not be extracted")
+					continue
+				}
+				extracted += len(data)
+
+				if reason := checkMarkdownFile(data); reason != "" {
+					skip(models.NoteImportItem{File: name}, reason)
+					continue
+				}
+				markdown = append(markdown, models.ImportFile{Name: name, Data: data})
+			}
+		default:
+			skip(models.NoteImportItem{File: file.Name}, "unsupported file type")
+		}
+	}
+
+	return markdown
+}
+
+// readZipEntry reads at most one byte more than the file limit, so an entry
+// whose header understates its size cannot expand without bound
+func readZipEntry(entry *zip.File) ([]byte, error) {
+	reader, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(io.LimitReader(reader, MAX_IMPORT_FILE_BYTES+1))
+}
+
+// Directories and files such as __MACOSX/ and .DS_Store that archivers add
+func isHiddenZipEntry(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if (strings.HasPrefix(part, ".") && part != "." && part != "..") || part == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
+
+func checkMarkdownFile(data []byte) string {
+	if len(data) > MAX_IMPORT_FILE_BYTES {
+		return fmt.Sprintf("file exceeds %d bytes", MAX_IMPORT_FILE_BYTES)
+	}
+	if !utf8.Valid(data) {
+		return "file is not valid UTF-8 text"
+	}
+	return ""
+}
+
+type markdownSection struct {
+	// line is where the section starts, counting from 1
+	line    int
+	heading string
+	// body is the section without its heading line
+	body    string
+	content string
+}
+
+// splitMarkdownSections starts a new section at every heading at or above
+// level. Lines inside fenced code blocks are never treated as headings, so
+// code is kept intact in the section it appears in. Text before the first
+// heading becomes a section of its own.
+func splitMarkdownSections(data []byte, level int) []markdownSection {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.TrimPrefix(text, "\ufeff")
+
+	var sections []markdownSection
+	var headingLine, heading string
+	var body []string
+	start := 1
+	flush := func() {
+		bodyText := strings.TrimSpace(strings.Join(body, "\n"))
+		if headingLine == "" && bodyText == "" {
+			return
+		}
+		content := bodyText
+		if headingLine != "" {
+			content = strings.TrimSpace(headingLine + "\n\n" + bodyText)
+		}
+		sections = append(sections, markdownSection{line: start, heading: heading, body: bodyText, content: content})
+	}
+
+	var fence string
+	for i, line := range strings.Split(text, "\n") {
+		if fence != "" {
+			if isClosingFence(line, fence) {
+				fence = ""
+			}
+			body = append(body, line)
+			continue
+		}
+		if marker := openingFence(line); marker != "" {
+			fence = marker
+			body = append(body, line)
+			continue
+		}
+
+		if headingLevel, title := parseHeading(line); headingLevel > 0 && headingLevel <= level {
+			flush()
+			headingLine, heading, body, start = strings.TrimSpace(line), title, nil, i+1
+			continue
+		}
+		body = append(body, line)
+	}
+	flush()
+
+	return sections
+}
+
+// openingFence returns the ``` or ~~~ run that opens a fenced code block,
+// or "" when the line does not open one
+func openingFence(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || len(trimmed) < 3 {
+		return ""
+	}
+
+	char := trimmed[0]
+	if char != '`' && char != '~' {
+		return ""
+	}
+	n := 0
+	for n < len(trimmed) && trimmed[n] == char {
+		n++
+	}
+	if n < 3 || (char == '`' && strings.Contains(trimmed[n:], "`")) {
+		return ""
+	}
+	return trimmed[:n]
+}
+
+// A fence closes with at least as many of the same character and nothing
+// else on the line
+func isClosingFence(line, fence string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return false
+	}
+	trimmed = strings.TrimRight(trimmed, " \t")
+	return len(trimmed) >= len(fence) && strings.Trim(trimmed, fence[:1]) == ""
+}
+
+// parseHeading returns the level and text of an ATX heading such as
+// "## Title ##", or 0 when the line is not a heading
+func parseHeading(line string) (int, string) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return 0, ""
+	}
+
+	level := 0
+	for level < len(trimmed) && trimmed[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, ""
+	}
+
+	rest := trimmed[level:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0, ""
+	}
+
+	title := strings.TrimSpace(rest)
+	if closing := strings.TrimRight(title, "#"); closing != title && (closing == "" || strings.HasSuffix(closing, " ")) {
+		title = strings.TrimSpace(closing)
+	}
+	return level, title
+}
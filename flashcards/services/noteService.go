@@ -1,62 +1,195 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
 	"flashcards/db"
 	"flashcards/models"
 )
 
+// Longest note content, title and source accepted, in characters
+const (
+	MAX_NOTE_LENGTH        = 2000
+	MAX_NOTE_TITLE_LENGTH  = 200
+	MAX_NOTE_SOURCE_LENGTH = 500
+)
+
+// NoteEnricher fills in what the LLM adds to a note: its summary, and its
+// language when DetectLanguage cannot tell
+type NoteEnricher interface {
+	SummarizeNote(ctx context.Context, note *models.Note) (string, error)
+	DetectNoteLanguage(ctx context.Context, note *models.Note) (string, error)
+}
+
 type NoteService struct {
-	repo db.NoteRepository
+	repo        db.NoteRepository
+	deckService *DeckService
+	quotas      *QuotaService
+	cache       NoteCache
+	enricher    NoteEnricher
+	events      EventPublisher
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewNoteService takes an optional cache for the notes read while building
+// prompts; nil disables caching
+func NewNoteService(repo db.NoteRepository, deckService *DeckService, quotas *QuotaService, cache NoteCache) *NoteService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &NoteService{repo: repo, deckService: deckService, quotas: quotas, cache: cache, ctx: ctx, cancel: cancel}
 }
 
-func NewNoteService(repo db.NoteRepository) *NoteService {
-	return &NoteService{repo: repo}
+// EnrichWith sets the enricher run in the background after a note is
+// created or its content changes. It is set after construction because the
+// enricher reads notes through this service.
+func (s *NoteService) EnrichWith(enricher NoteEnricher) {
+	s.enricher = enricher
 }
 
-func (s *NoteService) CreateNote(req *models.CreateNoteRequest) (*models.Note, error) {
+// PublishEventsTo sets where note.created events are published, such as
+// to the user's webhooks
+func (s *NoteService) PublishEventsTo(events EventPublisher) {
+	s.events = events
+}
+
+// Stop cancels enrichment in progress and waits for it to return. Notes
+// whose enrichment was cancelled keep an empty summary or language.
+func (s *NoteService) Stop(ctx context.Context) error {
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+func (s *NoteService) CreateNote(ctx context.Context, userID int, req *models.CreateNoteRequest) (*models.Note, error) {
 	if err := s.validateCreateRequest(req); err != nil {
 		return nil, err
 	}
 
+	if req.DeckID != nil {
+		if _, err := s.deckService.GetDeckByID(ctx, userID, *req.DeckID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.quotas.CheckNotes(ctx, userID, 1); err != nil {
+		return nil, err
+	}
+
 	note := &models.Note{
-		Content: strings.TrimSpace(req.Content),
+		UserID:   userID,
+		DeckID:   req.DeckID,
+		Title:    strings.TrimSpace(req.Title),
+		Source:   strings.TrimSpace(req.Source),
+		Language: strings.ToLower(strings.TrimSpace(req.Language)),
+		Content:  strings.TrimSpace(req.Content),
+		Tags:     []string{},
+	}
+	if note.Language == "" {
+		note.Language = detectNoteLanguage(note.Content)
 	}
 
-	if err := s.repo.CreateNote(note); err != nil {
+	if err := s.repo.CreateNote(ctx, note); err != nil {
 		return nil, fmt.Errorf("failed to create note: %w", err)
 	}
+	s.InvalidateCache(ctx, userID)
+	s.enrich(ctx, note, true)
+	if s.events != nil {
+		s.events.Publish(ctx, userID, models.WebhookEventNoteCreated, note)
+	}
 
 	return note, nil
 }
 
-func (s *NoteService) GetNoteByID(id int) (*models.Note, error) {
+func (s *NoteService) GetNoteByID(ctx context.Context, userID, id int) (*models.Note, error) {
 	if id <= 0 {
-		return nil, fmt.Errorf("invalid note ID: %d", id)
+		return nil, models.Invalid("invalid note ID: %d", id)
+	}
+
+	if s.cache != nil {
+		if note, ok := s.cache.GetNote(ctx, userID, id); ok {
+			return note, nil
+		}
 	}
 
-	note, err := s.repo.GetNoteByID(id)
+	note, err := s.repo.GetNoteByID(ctx, userID, id)
 	if err != nil {
 		return nil, err
 	}
 
+	if s.cache != nil {
+		s.cache.SetNote(ctx, userID, note)
+	}
 	return note, nil
 }
 
-func (s *NoteService) GetAllNotes() ([]*models.Note, error) {
-	notes, err := s.repo.GetAllNotes()
+func (s *NoteService) GetAllNotes(ctx context.Context, userID int) ([]*models.Note, error) {
+	if s.cache != nil {
+		if notes, ok := s.cache.GetNotes(ctx, userID); ok {
+			return notes, nil
+		}
+	}
+
+	notes, err := s.repo.GetAllNotes(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get notes: %w", err)
 	}
 
+	if s.cache != nil {
+		s.cache.SetNotes(ctx, userID, notes)
+	}
 	return notes, nil
 }
 
-func (s *NoteService) UpdateNote(id int, req *models.UpdateNoteRequest) (*models.Note, error) {
+// InvalidateCache drops the user's cached notes. Anything that changes a
+// note outside this service, such as its tags or deck, must call it.
+func (s *NoteService) InvalidateCache(ctx context.Context, userID int) {
+	if s.cache != nil {
+		s.cache.Invalidate(ctx, userID)
+	}
+}
+
+// ListNotes returns one page of the user's notes, optionally filtered by
+// tag, deck and a content search
+func (s *NoteService) ListNotes(ctx context.Context, userID int, filter models.NoteFilter, params models.ListParams) (*models.Page[*models.Note], error) {
+	params, err := normalizeListParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.Tag = normalizeTag(filter.Tag)
+	filter.Language = strings.ToLower(strings.TrimSpace(filter.Language))
+	if filter.Language != "" && !languageCodePattern.MatchString(filter.Language) {
+		return nil, models.Invalid("language must be an ISO 639-1 code such as \"en\"")
+	}
+	if filter.Query, err = validateSearchQuery(filter.Query); err != nil {
+		return nil, err
+	}
+
+	notes, total, err := s.repo.ListNotes(ctx, userID, filter, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notes: %w", err)
+	}
+
+	return &models.Page[*models.Note]{
+		Items:  notes,
+		Total:  total,
+		Limit:  params.Limit,
+		Offset: params.Offset,
+	}, nil
+}
+
+// UpdateNote applies req to the note if it is still at version, the
+// version the caller last read. A note changed since is a conflict.
+func (s *NoteService) UpdateNote(ctx context.Context, userID, id, version int, req *models.UpdateNoteRequest) (*models.Note, error) {
 	if id <= 0 {
-		return nil, fmt.Errorf("invalid note ID: %d", id)
+		return nil, models.Invalid("invalid note ID: %d", id)
 	}
 
 	if err := s.validateUpdateRequest(req); err != nil {
@@ -68,62 +201,247 @@ func (s *NoteService) UpdateNote(id int, req *models.UpdateNoteRequest) (*models
 	if req.Content != nil {
 		trimmedContent := strings.TrimSpace(*req.Content)
 		if trimmedContent == "" {
-			return nil, fmt.Errorf("content cannot be empty")
+			return nil, models.Invalid("content cannot be empty")
 		}
 		updates["content"] = trimmedContent
+		// The old summary and language no longer describe the note
+		updates["summary"] = ""
+		updates["language"] = detectNoteLanguage(trimmedContent)
+	}
+
+	if req.Language != nil {
+		language := strings.ToLower(strings.TrimSpace(*req.Language))
+		if language == "" && req.Content == nil {
+			current, err := s.repo.GetNoteByID(ctx, userID, id)
+			if err != nil {
+				return nil, err
+			}
+			language = detectNoteLanguage(current.Content)
+		}
+		if language != "" {
+			updates["language"] = language
+		}
+	}
+
+	if req.Title != nil {
+		updates["title"] = strings.TrimSpace(*req.Title)
+	}
+
+	if req.Source != nil {
+		updates["source"] = strings.TrimSpace(*req.Source)
+	}
+
+	if req.DeckID != nil {
+		if *req.DeckID == 0 {
+			updates["deck_id"] = nil
+		} else {
+			if _, err := s.deckService.GetDeckByID(ctx, userID, *req.DeckID); err != nil {
+				return nil, err
+			}
+			updates["deck_id"] = *req.DeckID
+		}
 	}
 
 	if len(updates) == 0 {
-		return nil, fmt.Errorf("no valid updates provided")
+		return nil, models.Invalid("no valid updates provided")
 	}
 
-	if err := s.repo.UpdateNote(id, updates); err != nil {
+	if err := s.repo.UpdateNote(ctx, userID, id, version, updates); err != nil {
 		return nil, err
 	}
+	s.InvalidateCache(ctx, userID)
 
-	return s.repo.GetNoteByID(id)
+	note, err := s.repo.GetNoteByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Content != nil || req.Language != nil {
+		s.enrich(ctx, note, req.Content != nil)
+	}
+	return note, nil
 }
 
-func (s *NoteService) DeleteNote(id int) error {
+func (s *NoteService) DeleteNote(ctx context.Context, userID, id int) error {
 	if id <= 0 {
-		return fmt.Errorf("invalid note ID: %d", id)
+		return models.Invalid("invalid note ID: %d", id)
 	}
 
-	return s.repo.DeleteNote(id)
+	if err := s.repo.DeleteNote(ctx, userID, id); err != nil {
+		return err
+	}
+	s.InvalidateCache(ctx, userID)
+	return nil
 }
 
 func (s *NoteService) validateCreateRequest(req *models.CreateNoteRequest) error {
 	if req == nil {
-		return fmt.Errorf("request cannot be nil")
+		return models.Invalid("request cannot be nil")
 	}
 
 	content := strings.TrimSpace(req.Content)
 	if content == "" {
-		return fmt.Errorf("content is required")
+		return models.Invalid("content is required")
 	}
 
-	if len(content) > 2000 {
-		return fmt.Errorf("content cannot exceed 2000 characters")
+	if len(content) > MAX_NOTE_LENGTH {
+		return models.Invalid("content cannot exceed %d characters", MAX_NOTE_LENGTH)
 	}
 
-	return nil
+	return validateNoteMetadata(req.Title, req.Source, req.Language)
 }
 
 func (s *NoteService) validateUpdateRequest(req *models.UpdateNoteRequest) error {
 	if req == nil {
-		return fmt.Errorf("request cannot be nil")
+		return models.Invalid("request cannot be nil")
 	}
 
-	if req.Content == nil {
-		return fmt.Errorf("content field must be provided for update")
+	if req.Content == nil && req.DeckID == nil && req.Title == nil && req.Source == nil && req.Language == nil {
+		return models.Invalid("at least one field must be provided for update")
 	}
 
 	if req.Content != nil {
 		content := strings.TrimSpace(*req.Content)
-		if len(content) > 2000 {
-			return fmt.Errorf("content cannot exceed 2000 characters")
+		if len(content) > MAX_NOTE_LENGTH {
+			return models.Invalid("content cannot exceed %d characters", MAX_NOTE_LENGTH)
 		}
 	}
 
+	var title, source, language string
+	if req.Title != nil {
+		title = *req.Title
+	}
+	if req.Source != nil {
+		source = *req.Source
+	}
+	if req.Language != nil {
+		language = *req.Language
+	}
+	return validateNoteMetadata(title, source, language)
+}
+
+func validateNoteMetadata(title, source, language string) error {
+	if len(strings.TrimSpace(title)) > MAX_NOTE_TITLE_LENGTH {
+		return models.Invalid("title cannot exceed %d characters", MAX_NOTE_TITLE_LENGTH)
+	}
+
+	if len(strings.TrimSpace(source)) > MAX_NOTE_SOURCE_LENGTH {
+		return models.Invalid("source cannot exceed %d characters", MAX_NOTE_SOURCE_LENGTH)
+	}
+
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language != "" && !languageCodePattern.MatchString(language) {
+		return models.Invalid("language must be an ISO 639-1 code such as \"en\"")
+	}
+
 	return nil
 }
+
+// SummarizeNotesResult counts the outcome of SummarizeNotes
+type SummarizeNotesResult struct {
+	Summarized int `json:"summarized"`
+	Skipped    int `json:"skipped"`
+	Failed     int `json:"failed"`
+}
+
+// SummarizeNotes writes the summary of each of the user's notes again, or
+// only of those without one when onlyMissing is set, for batch jobs. A note
+// that fails is logged and counted, and the rest carry on; running out of
+// quota or losing the LLM provider stops the batch.
+func (s *NoteService) SummarizeNotes(ctx context.Context, userID int, onlyMissing bool) (*SummarizeNotesResult, error) {
+	if s.enricher == nil {
+		return nil, models.Unavailable("note summaries are not configured")
+	}
+	logger := LoggerFromContext(ctx)
+
+	notes, err := s.GetAllNotes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer s.InvalidateCache(ctx, userID)
+
+	result := &SummarizeNotesResult{}
+	for _, note := range notes {
+		if onlyMissing && note.Summary != "" {
+			result.Skipped++
+			continue
+		}
+
+		summary, err := s.enricher.SummarizeNote(ctx, note)
+		if err == nil {
+			err = s.repo.SetNoteSummary(ctx, note.ID, note.Content, summary)
+		}
+		if err != nil {
+			if errors.Is(err, models.ErrQuotaExceeded) || errors.Is(err, ErrLLMUnavailable) || ctx.Err() != nil {
+				return result, err
+			}
+			logger.Warn("Failed to summarize note", "note_id", note.ID, "error", err)
+			result.Failed++
+			continue
+		}
+		result.Summarized++
+	}
+	return result, nil
+}
+
+// RunSummarizeNotesJob is the JobRunner for models.JobKindSummarizeNotes
+func (s *NoteService) RunSummarizeNotesJob(ctx context.Context, userID int, payload json.RawMessage) (any, error) {
+	var req models.SummarizeNotesJobPayload
+	if err := decodeJobPayload(payload, &req); err != nil {
+		return nil, err
+	}
+
+	result, err := s.SummarizeNotes(ctx, userID, req.OnlyMissing)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// detectNoteLanguage returns the language DetectLanguage is sure of, or ""
+// to leave it to the enricher
+func detectNoteLanguage(content string) string {
+	if language, ok := DetectLanguage(content); ok {
+		return language
+	}
+	return ""
+}
+
+// enrich writes the note's summary, when summarize is set, and its language,
+// when it is still unknown, in the background. It outlives the request that
+// changed the note but not the service. Failures are logged and leave the
+// field empty.
+func (s *NoteService) enrich(ctx context.Context, note *models.Note, summarize bool) {
+	if s.enricher == nil || (!summarize && note.Language != "") {
+		return
+	}
+
+	logger := LoggerFromContext(ctx).With("note_id", note.ID)
+	runCtx := ContextWithUser(ContextWithLogger(s.ctx, logger), note.UserID)
+	enriched := *note
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.InvalidateCache(runCtx, enriched.UserID)
+
+		if enriched.Language == "" {
+			if language, err := s.enricher.DetectNoteLanguage(runCtx, &enriched); err != nil {
+				logger.Warn("Failed to detect note language", "error", err)
+			} else if err := s.repo.SetNoteLanguage(runCtx, enriched.ID, enriched.Content, language); err != nil {
+				logger.Warn("Failed to save note language", "error", err)
+			}
+		}
+
+		if !summarize {
+			return
+		}
+		summary, err := s.enricher.SummarizeNote(runCtx, &enriched)
+		if err != nil {
+			logger.Warn("Failed to summarize note", "error", err)
+			return
+		}
+		if err := s.repo.SetNoteSummary(runCtx, enriched.ID, enriched.Content, summary); err != nil {
+			logger.Warn("Failed to save note summary", "error", err)
+		}
+	}()
+}
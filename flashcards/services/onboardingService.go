@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+type OnboardingService struct {
+	repo db.OnboardingRepository
+}
+
+func NewOnboardingService(repo db.OnboardingRepository) *OnboardingService {
+	return &OnboardingService{repo: repo}
+}
+
+// GetChecklist returns the setup steps for a new user in the order the
+// frontend shows them. Inviting teammates is only listed for owners and
+// admins, who are the ones able to do it.
+func (s *OnboardingService) GetChecklist(ctx context.Context, userID int) (*models.OnboardingChecklist, error) {
+	progress, err := s.repo.GetOnboardingProgress(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := []models.OnboardingStep{
+		{Key: "organization", Title: "Create or join an organization", Done: progress.Membership != nil},
+		{Key: "deck", Title: "Create your first deck", Done: progress.Decks > 0},
+		{Key: "note", Title: "Add a note", Done: progress.Notes > 0},
+		{Key: "flashcards", Title: "Create flashcards from a note", Done: progress.Flashcards > 0},
+		{Key: "review", Title: "Review a flashcard", Done: progress.Reviews > 0},
+	}
+	if progress.Membership != nil && progress.Membership.Role != models.RoleMember {
+		steps = append(steps, models.OnboardingStep{Key: "invite", Title: "Invite a teammate", Done: progress.InvitesSent > 0})
+	}
+
+	checklist := &models.OnboardingChecklist{Steps: steps, Total: len(steps)}
+	for _, step := range steps {
+		if step.Done {
+			checklist.Completed++
+		}
+	}
+	checklist.Done = checklist.Completed == checklist.Total
+
+	return checklist, nil
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"strings"
+
+	"flashcards/models"
+)
+
+const (
+	DEFAULT_PAGE_LIMIT = 50
+	MAX_PAGE_LIMIT     = 200
+	DEFAULT_SORT_FIELD = "createdAt"
+	MAX_SEARCH_LENGTH  = 200
+)
+
+// Fields list endpoints can be sorted by
+var sortableFields = []string{"createdAt", "updatedAt", "id"}
+
+var validSortOrders = []string{"asc", "desc"}
+
+// normalizeListParams fills in defaults and validates paging and ordering
+func normalizeListParams(params models.ListParams) (models.ListParams, error) {
+	if params.Limit == 0 {
+		params.Limit = DEFAULT_PAGE_LIMIT
+	}
+	if params.Limit < 1 || params.Limit > MAX_PAGE_LIMIT {
+		return params, models.Invalid("limit must be between 1 and %d", MAX_PAGE_LIMIT)
+	}
+
+	if params.Offset < 0 {
+		return params, models.Invalid("offset cannot be negative")
+	}
+
+	if params.Sort == "" {
+		params.Sort = DEFAULT_SORT_FIELD
+	}
+	if !containsValue(sortableFields, params.Sort) {
+		return params, models.Invalid("sort must be one of: %s", strings.Join(sortableFields, ", "))
+	}
+
+	params.Order = strings.ToLower(params.Order)
+	if params.Order == "" {
+		params.Order = "desc"
+	}
+	if !containsValue(validSortOrders, params.Order) {
+		return params, models.Invalid("order must be one of: %s", strings.Join(validSortOrders, ", "))
+	}
+
+	return params, nil
+}
+
+func validateSearchQuery(query string) (string, error) {
+	query = strings.TrimSpace(query)
+	if len(query) > MAX_SEARCH_LENGTH {
+		return "", models.Invalid("search query cannot exceed %d characters", MAX_SEARCH_LENGTH)
+	}
+	return query, nil
+}
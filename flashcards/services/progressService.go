@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	PROGRESS_PERIOD_DAYS = 7
+	MAX_STREAK_DAYS      = 60
+)
+
+type ProgressService struct {
+	sessionRepo   db.QuizSessionRepository
+	recipientRepo db.ReportRecipientRepository
+	mailer        *Mailer
+}
+
+func NewProgressService(sessionRepo db.QuizSessionRepository, recipientRepo db.ReportRecipientRepository, mailer *Mailer) *ProgressService {
+	return &ProgressService{
+		sessionRepo:   sessionRepo,
+		recipientRepo: recipientRepo,
+		mailer:        mailer,
+	}
+}
+
+// BuildReport summarizes a user's quiz activity over the last week,
+// comparing accuracy with the week before
+func (s *ProgressService) BuildReport(ctx context.Context, userID int, now time.Time) (*models.ProgressReport, error) {
+	today := truncateToDay(now)
+	periodStart := today.AddDate(0, 0, -(PROGRESS_PERIOD_DAYS - 1))
+	previousStart := periodStart.AddDate(0, 0, -PROGRESS_PERIOD_DAYS)
+	historyStart := today.AddDate(0, 0, -MAX_STREAK_DAYS)
+
+	sessions, err := s.sessionRepo.GetSessionsUpdatedSince(ctx, userID, historyStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load quiz sessions: %w", err)
+	}
+
+	report := &models.ProgressReport{
+		PeriodStart:   periodStart,
+		PeriodEnd:     now,
+		DailyActivity: make([]models.DailyActivity, PROGRESS_PERIOD_DAYS),
+	}
+	for i := range report.DailyActivity {
+		report.DailyActivity[i].Date = periodStart.AddDate(0, 0, i).Format("2006-01-02")
+	}
+
+	activeDays := make(map[string]bool)
+	var correct, graded, previousCorrect, previousGraded int
+
+	for _, session := range sessions {
+		if session.Status == models.SessionStatusCompleted && !session.UpdatedAt.Before(periodStart) {
+			report.SessionsCompleted++
+		}
+
+		for _, question := range session.Questions {
+			if question.Status != models.QuestionStatusAnswered {
+				continue
+			}
+
+			answeredAt := question.UpdatedAt
+			activeDays[answeredAt.Format("2006-01-02")] = true
+			result := gradeAnswer(question.Question, question.Answer)
+
+			switch {
+			case !answeredAt.Before(periodStart):
+				report.QuestionsAnswered++
+				day := int(truncateToDay(answeredAt).Sub(periodStart).Hours() / 24)
+				if day >= 0 && day < PROGRESS_PERIOD_DAYS {
+					report.DailyActivity[day].Answered++
+					if result != nil && *result {
+						report.DailyActivity[day].Correct++
+					}
+				}
+				if result != nil {
+					graded++
+					if *result {
+						correct++
+					}
+				}
+			case !answeredAt.Before(previousStart) && result != nil:
+				previousGraded++
+				if *result {
+					previousCorrect++
+				}
+			}
+		}
+	}
+
+	report.Accuracy = percentage(correct, graded)
+	report.PreviousAccuracy = percentage(previousCorrect, previousGraded)
+	switch {
+	case graded == 0 || previousGraded == 0 || report.Accuracy == report.PreviousAccuracy:
+		report.AccuracyTrend = "flat"
+	case report.Accuracy > report.PreviousAccuracy:
+		report.AccuracyTrend = "up"
+	default:
+		report.AccuracyTrend = "down"
+	}
+
+	// A streak may end yesterday if nothing has been answered yet today
+	day := today
+	if !activeDays[day.Format("2006-01-02")] {
+		day = day.AddDate(0, 0, -1)
+	}
+	for activeDays[day.Format("2006-01-02")] && report.CurrentStreakDays < MAX_STREAK_DAYS {
+		report.CurrentStreakDays++
+		day = day.AddDate(0, 0, -1)
+	}
+
+	return report, nil
+}
+
+// SendWeeklyReports emails each user's current progress report to that
+// user's recipients. A failure for one user does not stop the others.
+func (s *ProgressService) SendWeeklyReports(ctx context.Context) error {
+	logger := LoggerFromContext(ctx)
+	if !s.mailer.Enabled() {
+		logger.Info("Skipping progress report emails, email delivery is not configured")
+		return nil
+	}
+
+	recipients, err := s.recipientRepo.GetRecipientsForAllUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load report recipients: %w", err)
+	}
+
+	if len(recipients) == 0 {
+		logger.Info("No progress report recipients configured")
+		return nil
+	}
+
+	recipientsByUser := make(map[int][]string)
+	var userIDs []int
+	for _, recipient := range recipients {
+		if _, ok := recipientsByUser[recipient.UserID]; !ok {
+			userIDs = append(userIDs, recipient.UserID)
+		}
+		recipientsByUser[recipient.UserID] = append(recipientsByUser[recipient.UserID], recipient.Email)
+	}
+
+	now := time.Now()
+	failed := 0
+	for _, userID := range userIDs {
+		report, err := s.BuildReport(ctx, userID, now)
+		if err == nil {
+			to := recipientsByUser[userID]
+			logger.Info("Sending weekly progress report", "user_id", userID, "recipients", len(to))
+			err = s.mailer.Send(to, "Weekly study progress report", renderProgressReport(report))
+		}
+		if err != nil {
+			logger.Error("Failed to send progress report", "user_id", userID, "error", err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to send progress reports for %d of %d users", failed, len(userIDs))
+	}
+
+	return nil
+}
+
+func (s *ProgressService) CreateRecipient(ctx context.Context, userID int, req *models.CreateReportRecipientRequest) (*models.ReportRecipient, error) {
+	if req == nil {
+		return nil, models.Invalid("request cannot be nil")
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if !isValidEmail(email) {
+		return nil, models.Invalid("a valid email address is required")
+	}
+
+	recipient := &models.ReportRecipient{UserID: userID, Email: email}
+	if err := s.recipientRepo.CreateRecipient(ctx, recipient); err != nil {
+		return nil, fmt.Errorf("failed to create report recipient: %w", err)
+	}
+
+	return recipient, nil
+}
+
+func (s *ProgressService) GetAllRecipients(ctx context.Context, userID int) ([]*models.ReportRecipient, error) {
+	recipients, err := s.recipientRepo.GetAllRecipients(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report recipients: %w", err)
+	}
+
+	return recipients, nil
+}
+
+func (s *ProgressService) DeleteRecipient(ctx context.Context, userID, id int) error {
+	if id <= 0 {
+		return models.Invalid("invalid report recipient ID: %d", id)
+	}
+
+	return s.recipientRepo.DeleteRecipient(ctx, userID, id)
+}
+
+func renderProgressReport(report *models.ProgressReport) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Study progress for %s to %s\n\n", report.PeriodStart.Format("Jan 2"), report.PeriodEnd.Format("Jan 2, 2006"))
+	fmt.Fprintf(&b, "Questions answered: %d\n", report.QuestionsAnswered)
+	fmt.Fprintf(&b, "Sessions completed: %d\n", report.SessionsCompleted)
+	fmt.Fprintf(&b, "Accuracy: %.0f%% (previous week %.0f%%, trend %s)\n", report.Accuracy, report.PreviousAccuracy, report.AccuracyTrend)
+	fmt.Fprintf(&b, "Current streak: %d days\n\n", report.CurrentStreakDays)
+
+	b.WriteString("Daily activity:\n")
+	for _, day := range report.DailyActivity {
+		fmt.Fprintf(&b, "  %s  %d answered, %d correct\n", day.Date, day.Answered, day.Correct)
+	}
+
+	return b.String()
+}
+
+func truncateToDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+func percentage(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
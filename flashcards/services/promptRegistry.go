@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+// Names of the prompt templates the code renders
+const (
+	PROMPT_QUIZ_SYSTEM = "quiz-system"
+	PROMPT_QUIZ_USER   = "quiz-user"
+)
+
+const (
+	MAX_PROMPT_TEMPLATE_LENGTH = 20000
+	// How often versions saved through another server are picked up
+	PROMPT_RELOAD_INTERVAL = time.Minute
+)
+
+// Template files are named <name>.tmpl, or <name>.v<version>.tmpl when a
+// directory keeps several versions
+var promptFilePattern = regexp.MustCompile(`^([a-z0-9-]+?)(?:\.v(\d+))?\.tmpl$`)
+
+// quizPromptData is what the quiz prompt templates are rendered with
+type quizPromptData struct {
+	Notes        string
+	Difficulty   string
+	QuestionType string
+}
+
+// promptDefinition is a prompt the code renders: the built-in template used
+// when no other version is loaded, and sample data that a replacement must
+// render with
+type promptDefinition struct {
+	template string
+	sample   any
+}
+
+var quizPromptSample = quizPromptData{Notes: "Photosynthesis turns light into chemical energy.", Difficulty: "medium", QuestionType: "multiple-choice"}
+
+var promptDefinitions = map[string]promptDefinition{
+	PROMPT_QUIZ_SYSTEM: {
+		template: `You are a quiz generator AI. Create educational quiz questions based on the provided study notes. Generate questions that test comprehension, application, and analysis of the material. Respond with valid JSON in this exact format:
+{
+  "question": "The question text here",
+  "type": "multiple-choice",
+  "options": ["A) Option 1", "B) Option 2", "C) Option 3", "D) Option 4"],
+  "correctAnswer": "A",
+  "explanation": "Explanation of why this is correct",
+  "difficulty": "medium"
+}
+
+For essay questions, omit the options and correctAnswer fields. For math questions, omit the options and give the exact answer as a number or expression in correctAnswer (e.g. "3/4" or "2x+1"). Valid difficulty levels are: easy, medium, hard. Valid types are: multiple-choice, essay, true-false, math.`,
+		sample: quizPromptSample,
+	},
+	PROMPT_QUIZ_USER: {
+		template: `Based on these study notes:
+
+{{.Notes}}
+
+Generate a quiz question. Make it {{.Difficulty}} difficulty and format it as {{.QuestionType}}. The question should test understanding of the key concepts from the notes.`,
+		sample: quizPromptSample,
+	},
+}
+
+type activePrompt struct {
+	info *models.PromptTemplate
+	tmpl *template.Template
+}
+
+// PromptRegistry holds the prompt templates in use. Each name is loaded from
+// the highest version stored in the database, else from the template
+// directory, else from the built-in template, so prompts can be changed
+// without a redeploy. Templates use text/template syntax.
+type PromptRegistry struct {
+	repo  db.PromptTemplateRepository
+	files map[string]*models.PromptTemplate
+
+	mu     sync.RWMutex
+	active map[string]*activePrompt
+}
+
+// NewPromptRegistry loads the templates in dir, which may be empty, and
+// the stored versions. A template file that does not parse or render is an
+// error, so a bad deploy fails at startup.
+func NewPromptRegistry(ctx context.Context, repo db.PromptTemplateRepository, dir string) (*PromptRegistry, error) {
+	files, err := loadPromptFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := &PromptRegistry{repo: repo, files: files}
+	if err := registry.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// loadPromptFiles reads the highest version of each template in dir
+func loadPromptFiles(dir string) (map[string]*models.PromptTemplate, error) {
+	files := make(map[string]*models.PromptTemplate)
+	if dir == "" {
+		return files, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, models.Internal("failed to read prompt template directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		match := promptFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		name := match[1]
+		definition, ok := promptDefinitions[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown prompt template %s in %s", name, entry.Name())
+		}
+		version := 1
+		if match[2] != "" {
+			version, _ = strconv.Atoi(match[2])
+		}
+		if current, ok := files[name]; ok && current.Version >= version {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, models.Internal("failed to read prompt template %s: %w", entry.Name(), err)
+		}
+		if _, err := checkPromptTemplate(name, string(content), definition); err != nil {
+			return nil, fmt.Errorf("prompt template %s: %w", entry.Name(), err)
+		}
+		files[name] = &models.PromptTemplate{Name: name, Version: version, Source: models.PromptSourceFile, Template: string(content)}
+	}
+	return files, nil
+}
+
+// Reload picks up versions saved through other servers. It runs on a
+// schedule. A stored version that no longer renders, such as after the
+// data it uses was renamed, is skipped for the next source.
+func (r *PromptRegistry) Reload(ctx context.Context) error {
+	stored, err := r.repo.GetLatestPromptTemplates(ctx)
+	if err != nil {
+		return err
+	}
+	latest := make(map[string]*models.PromptTemplate, len(stored))
+	for _, prompt := range stored {
+		latest[prompt.Name] = prompt
+	}
+
+	active := make(map[string]*activePrompt, len(promptDefinitions))
+	for name, definition := range promptDefinitions {
+		builtIn := &models.PromptTemplate{Name: name, Source: models.PromptSourceBuiltIn, Template: definition.template}
+		for _, prompt := range []*models.PromptTemplate{latest[name], r.files[name], builtIn} {
+			if prompt == nil {
+				continue
+			}
+			tmpl, err := checkPromptTemplate(name, prompt.Template, definition)
+			if err != nil {
+				LoggerFromContext(ctx).Error("Skipping invalid prompt template", "name", name, "source", prompt.Source,
+					"version", prompt.Version, "error", err)
+				continue
+			}
+			active[name] = &activePrompt{info: prompt, tmpl: tmpl}
+			break
+		}
+	}
+
+	r.mu.Lock()
+	r.active = active
+	r.mu.Unlock()
+	return nil
+}
+
+// Render executes the named template with data
+func (r *PromptRegistry) Render(name string, data any) (string, error) {
+	r.mu.RLock()
+	prompt, ok := r.active[name]
+	r.mu.RUnlock()
+	if !ok {
+		return "", models.NotFound("prompt template %s not found", name)
+	}
+
+	var rendered strings.Builder
+	if err := prompt.tmpl.Execute(&rendered, data); err != nil {
+		return "", models.Internal("failed to render prompt template %s: %w", name, err)
+	}
+	return rendered.String(), nil
+}
+
+// Version identifies the template in use for name by where it was loaded
+// from and its version, such as "database:3"
+func (r *PromptRegistry) Version(name string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	prompt, ok := r.active[name]
+	if !ok {
+		return ""
+	}
+	return prompt.info.Source + ":" + strconv.Itoa(prompt.info.Version)
+}
+
+// ListPromptTemplates returns the template in use for each name
+func (r *PromptRegistry) ListPromptTemplates(ctx context.Context) []*models.PromptTemplate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	prompts := make([]*models.PromptTemplate, 0, len(r.active))
+	for _, prompt := range r.active {
+		prompts = append(prompts, prompt.info)
+	}
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+	return prompts
+}
+
+// ListPromptTemplateVersions returns the stored versions of a template,
+// newest first
+func (r *PromptRegistry) ListPromptTemplateVersions(ctx context.Context, name string) ([]*models.PromptTemplate, error) {
+	if _, ok := promptDefinitions[name]; !ok {
+		return nil, models.NotFound("prompt template %s not found", name)
+	}
+
+	return r.repo.ListPromptTemplateVersions(ctx, name)
+}
+
+// UpdatePromptTemplate stores a new version of a template and puts it in
+// use. It must parse and render with sample data first, so a typo cannot
+// break generation.
+func (r *PromptRegistry) UpdatePromptTemplate(ctx context.Context, name string, req *models.UpdatePromptTemplateRequest) (*models.PromptTemplate, error) {
+	definition, ok := promptDefinitions[name]
+	if !ok {
+		return nil, models.NotFound("prompt template %s not found", name)
+	}
+	if strings.TrimSpace(req.Template) == "" {
+		return nil, models.Invalid("template cannot be empty")
+	}
+	if len(req.Template) > MAX_PROMPT_TEMPLATE_LENGTH {
+		return nil, models.Invalid("template cannot exceed %d characters", MAX_PROMPT_TEMPLATE_LENGTH)
+	}
+
+	tmpl, err := checkPromptTemplate(name, req.Template, definition)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt, err := r.repo.CreatePromptTemplateVersion(ctx, name, req.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.active[name] = &activePrompt{info: prompt, tmpl: tmpl}
+	r.mu.Unlock()
+
+	LoggerFromContext(ctx).Info("Updated prompt template", "name", name, "version", prompt.Version)
+	return prompt, nil
+}
+
+// checkPromptTemplate parses text and renders it with the sample data, so
+// references to data the prompt is not given fail here
+func checkPromptTemplate(name, text string, definition promptDefinition) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, models.Invalid("invalid template: %v", err)
+	}
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, definition.sample); err != nil {
+		return nil, models.Invalid("template does not render: %v", err)
+	}
+	return tmpl, nil
+}
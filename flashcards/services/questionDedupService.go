@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	DEFAULT_QUESTION_SIMILARITY_THRESHOLD = 0.9
+	// Recent questions on the same notes compared against a new one
+	QUESTION_DEDUP_WINDOW = 200
+	// Question embeddings are only compared while they are recent
+	QUESTION_EMBEDDING_RETENTION = 90 * 24 * time.Hour
+)
+
+// QuestionDedupService recognizes generated questions that repeat one the
+// user was recently asked on the same notes, comparing embeddings of the
+// question text so a reworded repeat is caught too. Without embeddings
+// configured it does nothing.
+type QuestionDedupService struct {
+	repo             db.QuestionEmbeddingRepository
+	embeddings       *EmbeddingClient
+	threshold        float64
+	maxRegenerations int
+}
+
+// NewQuestionDedupService treats questions at least threshold similar as
+// the same and lets a repeat be regenerated up to maxRegenerations times.
+// Zero values select the defaults.
+func NewQuestionDedupService(repo db.QuestionEmbeddingRepository, embeddings *EmbeddingClient, threshold float64, maxRegenerations int) *QuestionDedupService {
+	if threshold <= 0 {
+		threshold = DEFAULT_QUESTION_SIMILARITY_THRESHOLD
+	}
+	if maxRegenerations <= 0 {
+		maxRegenerations = MAX_DUPLICATE_REGENERATIONS
+	}
+	return &QuestionDedupService{repo: repo, embeddings: embeddings, threshold: threshold, maxRegenerations: maxRegenerations}
+}
+
+// recentQuestions are the questions one generation request is compared
+// against. Questions accepted during the request are added, so parallel
+// generations in a batch are compared with each other as they finish.
+type recentQuestions struct {
+	userID  int
+	noteIDs []int
+
+	mu         sync.Mutex
+	embeddings []*models.QuestionEmbedding
+}
+
+// Load reads the user's recent questions on the notes. It returns nil when
+// embeddings are not configured or cannot be read, which disables the check
+// for the request rather than failing it.
+func (s *QuestionDedupService) Load(ctx context.Context, userID int, noteIDs []int) *recentQuestions {
+	if s == nil || !s.embeddings.Enabled() {
+		return nil
+	}
+
+	embeddings, err := s.repo.GetRecentQuestionEmbeddings(ctx, userID, noteIDs, s.embeddings.Model(), QUESTION_DEDUP_WINDOW)
+	if err != nil {
+		LoggerFromContext(ctx).Error("Failed to load recent questions, skipping duplicate check", "error", err)
+		return nil
+	}
+	return &recentQuestions{userID: userID, noteIDs: noteIDs, embeddings: embeddings}
+}
+
+// Check embeds text and returns the most similar recent question when it
+// is at least the threshold similar, along with the embedding to pass to
+// Remember. An embedding failure is logged and treated as no match.
+func (s *QuestionDedupService) Check(ctx context.Context, recent *recentQuestions, text string) (string, float64, []float64) {
+	if recent == nil {
+		return "", 0, nil
+	}
+
+	vectors, err := s.embeddings.Embed(ctx, []string{text})
+	if err != nil {
+		LoggerFromContext(ctx).Error("Failed to embed question, skipping duplicate check", "error", err)
+		return "", 0, nil
+	}
+	vector := vectors[0]
+
+	recent.mu.Lock()
+	defer recent.mu.Unlock()
+
+	match, best := "", 0.0
+	for _, embedding := range recent.embeddings {
+		if similarity := cosineSimilarity(vector, embedding.Vector); similarity > best {
+			match, best = embedding.QuestionText, similarity
+		}
+	}
+	if best < s.threshold {
+		return "", best, vector
+	}
+	return match, best, vector
+}
+
+// Remember stores the embedding of a question that was accepted, for this
+// request and later ones
+func (s *QuestionDedupService) Remember(ctx context.Context, recent *recentQuestions, text string, vector []float64) {
+	if recent == nil || vector == nil {
+		return
+	}
+
+	embedding := &models.QuestionEmbedding{
+		UserID:       recent.userID,
+		NoteIDs:      recent.noteIDs,
+		QuestionText: text,
+		Model:        s.embeddings.Model(),
+		Vector:       vector,
+	}
+
+	recent.mu.Lock()
+	recent.embeddings = append(recent.embeddings, embedding)
+	recent.mu.Unlock()
+
+	if err := s.repo.SaveQuestionEmbedding(ctx, embedding); err != nil {
+		LoggerFromContext(ctx).Error("Failed to save question embedding", "error", err)
+	}
+}
+
+// MaxRegenerations is how many times a repeated question is regenerated
+// before giving up
+func (s *QuestionDedupService) MaxRegenerations() int {
+	if s == nil {
+		return MAX_DUPLICATE_REGENERATIONS
+	}
+	return s.maxRegenerations
+}
+
+// DeleteExpired removes question embeddings past the retention period
+func (s *QuestionDedupService) DeleteExpired(ctx context.Context) error {
+	deleted, err := s.repo.DeleteQuestionEmbeddingsBefore(ctx, time.Now().Add(-QUESTION_EMBEDDING_RETENTION))
+	if err != nil {
+		return err
+	}
+
+	LoggerFromContext(ctx).Info("Deleted expired question embeddings", "count", deleted)
+	return nil
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"flashcards/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	MAX_QUIZ_CACHE_ENTRIES = 10000
+	REDIS_QUIZ_KEY_PREFIX  = "flashcards:quizzes:"
+)
+
+// QuizCache keeps generated quiz questions so a repeated request over
+// unchanged notes is answered without calling the LLM. Keys hash everything
+// the prompt is built from, so editing a note or a prompt template misses
+// rather than needing an invalidation. Failures count as misses.
+type QuizCache interface {
+	GetQuiz(ctx context.Context, key string) ([]models.Message, bool)
+	SetQuiz(ctx context.Context, key string, messages []models.Message)
+}
+
+type memoryQuizEntry struct {
+	messages  []models.Message
+	expiresAt time.Time
+}
+
+// MemoryQuizCache holds quizzes in process, so each instance generates its
+// own
+type MemoryQuizCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*memoryQuizEntry
+}
+
+func NewMemoryQuizCache(ttl time.Duration) *MemoryQuizCache {
+	return &MemoryQuizCache{ttl: ttl, entries: map[string]*memoryQuizEntry{}}
+}
+
+func (c *MemoryQuizCache) GetQuiz(ctx context.Context, key string) ([]models.Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return cloneQuizMessages(entry.messages), true
+}
+
+func (c *MemoryQuizCache) SetQuiz(ctx context.Context, key string, messages []models.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= MAX_QUIZ_CACHE_ENTRIES {
+		c.evict(now)
+	}
+	c.entries[key] = &memoryQuizEntry{messages: cloneQuizMessages(messages), expiresAt: now.Add(c.ttl)}
+}
+
+// evict drops expired entries, or the one closest to expiring when none
+// have expired yet. The caller holds c.mu.
+func (c *MemoryQuizCache) evict(now time.Time) {
+	oldestKey := ""
+	var oldest *memoryQuizEntry
+	for key, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest == nil || entry.expiresAt.Before(oldest.expiresAt) {
+			oldestKey, oldest = key, entry
+		}
+	}
+	if len(c.entries) >= MAX_QUIZ_CACHE_ENTRIES && oldest != nil {
+		delete(c.entries, oldestKey)
+	}
+}
+
+// RedisQuizCache shares cached quizzes between instances
+type RedisQuizCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedisQuizCache(client *redis.Client, ttl time.Duration) *RedisQuizCache {
+	return &RedisQuizCache{client: client, ttl: ttl}
+}
+
+func (c *RedisQuizCache) GetQuiz(ctx context.Context, key string) ([]models.Message, bool) {
+	data, err := c.client.Get(ctx, REDIS_QUIZ_KEY_PREFIX+key).Bytes()
+	if err == redis.Nil {
+		return nil, false
+	}
+	if err != nil {
+		LoggerFromContext(ctx).Warn("Failed to read quiz cache", "error", err)
+		return nil, false
+	}
+
+	var messages []models.Message
+	if err := json.Unmarshal(data, &messages); err != nil {
+		LoggerFromContext(ctx).Warn("Failed to decode cached quiz", "error", err)
+		return nil, false
+	}
+	return messages, true
+}
+
+func (c *RedisQuizCache) SetQuiz(ctx context.Context, key string, messages []models.Message) {
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return
+	}
+
+	if err := c.client.Set(ctx, REDIS_QUIZ_KEY_PREFIX+key, data, c.ttl).Err(); err != nil {
+		LoggerFromContext(ctx).Warn("Failed to write quiz cache", "error", err)
+	}
+}
+
+// Cached questions are copied in and out so callers cannot change them
+func cloneQuizMessages(messages []models.Message) []models.Message {
+	cloned := make([]models.Message, len(messages))
+	for i, message := range messages {
+		cloned[i] = message
+		if message.Question == nil {
+			continue
+		}
+		question := *message.Question
+		question.Options = append([]string(nil), question.Options...)
+		question.BasedOnNotes = append([]int(nil), question.BasedOnNotes...)
+		if question.Accessibility != nil {
+			accessibility := *question.Accessibility
+			accessibility.OptionLabels = append([]string(nil), accessibility.OptionLabels...)
+			question.Accessibility = &accessibility
+		}
+		cloned[i].Question = &question
+	}
+	return cloned
+}
@@ -2,101 +2,743 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"flashcards/db"
 	"flashcards/models"
 
 	"github.com/tmc/langchaingo/llms"
-	"github.com/tmc/langchaingo/llms/openai"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 const (
-	SYSTEM_PROMPT = `You are a quiz generator AI. Create educational quiz questions based on the provided study notes. Generate questions that test comprehension, application, and analysis of the material. Respond with valid JSON in this exact format:
+	ESSAY_GRADING_PROMPT_TEMPLATE = `You are a fair and encouraging teacher grading a student's essay answer.
+
+Question: %s
+
+Reference explanation: %s
+
+Student answer: %s
+
+Start your response with a single line in this exact format:
+SCORE: <0-10>/10
+
+Then, on the following lines, give narrative feedback: what the student got right, what is missing or incorrect, and how to improve.`
+
+	ESSAY_MAX_SCORE = 10
+
+	FLASHCARD_PROMPT_TEMPLATE = `You are a study assistant that turns notes into flashcards. Extract the %d most important facts or concepts from the note below as question/answer pairs. Questions must be self-contained and answers short and precise. Respond with valid JSON in this exact format:
+{
+  "flashcards": [
+    {"question": "The question text", "answer": "The answer text"}
+  ]
+}
+
+Note:
+%s`
+
+	NOTE_SUMMARY_PROMPT_TEMPLATE = `Summarize the following study note in one or two sentences for a learner skimming their notes. Keep the key terms, add nothing that is not in the note, and respond with only the summary.
+
+Note:
+%s`
+
+	LANGUAGE_DETECTION_PROMPT_TEMPLATE = `Which language is the following study note written in? Respond with only its two-letter ISO 639-1 code, such as "en" or "es".
+
+Note:
+%s`
+
+	PLAIN_LANGUAGE_PROMPT_TEMPLATE = `Rewrite the following quiz question in plain language for a learner who needs simpler wording. Use short sentences and common words, keep every fact needed to answer it, and do not reveal the answer. Respond with only the rewritten question.
+
+Question: %s`
+
+	EXPLANATION_PROMPT_TEMPLATE = `You are a patient tutor. A student flagged this quiz question for review.
+
+Question: %s
+Options: %s
+Correct answer: %s
+Original explanation: %s
+Student answer: %s
+
+Give a deeper explanation of the underlying concept: why the correct answer is right, why the alternatives are wrong, a concrete example, and a tip for remembering it. Respond in plain text.`
+
+	IMAGE_GRADING_PROMPT_TEMPLATE = `You are a fair math teacher grading a photo of a student's handwritten worked answer.
+
+Question: %s
+Expected answer: %s
+Reference explanation: %s
+
+Read the student's work in the image. Identify their final answer and compare it with the expected answer, accepting equivalent forms (e.g. 1/2 and 0.5). Then evaluate the work shown and award partial credit out of %d for correct method and intermediate steps, even when the final answer is wrong. A correct final answer with no supporting work should not receive full marks unless the question only asks for the result. Respond with valid JSON in this exact format:
+{
+  "finalAnswer": "The student's final answer as written",
+  "correct": true,
+  "score": 7,
+  "feedback": "Which steps were right, where the work went wrong, and how to fix it"
+}
+
+If the image is unreadable or does not contain an answer, respond with score 0, correct false and explain why in feedback.`
+
+	IMAGE_ANSWER_MAX_SCORE = 10
+
+	VOCABULARY_PROMPT_TEMPLATE = `You are a language teacher preparing a vocabulary card. For the %s term below, give its IPA transcription, a simple respelled pronunciation for English speakers, and %d example sentences that use the term naturally. Every sentence must use only grammar and vocabulary appropriate for CEFR level %s. Include an English translation for each sentence. Respond with valid JSON in this exact format:
+{
+  "ipa": "/ˈeksəmpəl/",
+  "pronunciation": "EK-sum-pul",
+  "examples": [
+    {"text": "Sentence in the target language", "translation": "English translation"}
+  ]
+}
+
+Term: %s
+Meaning on the card: %s`
+
+	REFORMULATE_PROMPT_TEMPLATE = `You are a study coach. A learner keeps forgetting the flashcard below. Rewrite it so it is easier to remember: ask about a single fact, make the question specific and unambiguous, keep the answer short, and give a hint with a memory cue such as an association or a mnemonic that does not give the answer away. Keep every fact correct and add nothing the card does not state or imply. Respond with valid JSON in this exact format:
 {
-  "question": "The question text here",
-  "type": "multiple-choice",
-  "options": ["A) Option 1", "B) Option 2", "C) Option 3", "D) Option 4"],
-  "correctAnswer": "A",
-  "explanation": "Explanation of why this is correct",
-  "difficulty": "medium"
+  "front": "The rewritten question",
+  "back": "The rewritten answer",
+  "hint": "A short memory cue"
 }
 
-For essay questions, omit the options and correctAnswer fields. Valid difficulty levels are: easy, medium, hard. Valid types are: multiple-choice, essay, true-false.`
+Question: %s
+Answer: %s
+Hint: %s`
+
+	MNEMONIC_PROMPT_TEMPLATE = `You are a memory coach. Write up to 3 memory aids that help a learner remember the answer to the flashcard below, each using one of these techniques: "acronym" (a word or phrase built from initials), "imagery" (a vivid, concrete and ideally absurd scene) or "memory_palace" (a hook placing the answer at a location along a familiar route). Tie each aid to the specific content of the card, keep it to one or two sentences, and keep every fact correct. Respond with valid JSON in this exact format:
+{
+  "mnemonics": [
+    {
+      "technique": "acronym",
+      "text": "The memory aid"
+    }
+  ]
+}
 
-	USER_PROMPT_TEMPLATE = `Based on these study notes: 
+Question: %s
+Answer: %s`
 
-%s
+	// Free-form answers graded by value, see mathAnswersEquivalent
+	QUESTION_TYPE_MATH = "math"
 
-Generate a quiz question. Make it %s difficulty and format it as %s. The question should test understanding of the key concepts from the notes.`
+	MAX_QUESTIONS_PER_REQUEST = 10
+	MAX_PARALLEL_GENERATIONS  = 3
 )
 
-type QuizService struct {
-	noteService *NoteService
-	llmClient   llms.Model
+var questionCounter atomic.Uint64
+
+var essayScorePattern = regexp.MustCompile(`(?i)score:\s*(\d+)\s*/\s*10`)
+
+// Output schemas of the prompts that generate JSON, see generateStructured
+var (
+	questionSchema = outputSchema{
+		Name:        "quiz_question",
+		Description: "Return a quiz question generated from the study notes",
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"question":      map[string]any{"type": "string"},
+				"type":          map[string]any{"type": "string", "enum": validQuestionTypes},
+				"options":       map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+				"correctAnswer": map[string]any{"type": "string"},
+				"explanation":   map[string]any{"type": "string"},
+				"difficulty":    map[string]any{"type": "string", "enum": validDifficulties},
+			},
+			"required": []string{"question", "type", "explanation", "difficulty"},
+		},
+	}
+
+	imageGradingSchema = outputSchema{
+		Name:        "answer_grading",
+		Description: "Return the grading of the student's worked answer",
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"finalAnswer": map[string]any{"type": "string"},
+				"correct":     map[string]any{"type": "boolean"},
+				"score":       map[string]any{"type": "integer"},
+				"feedback":    map[string]any{"type": "string"},
+			},
+			"required": []string{"finalAnswer", "correct", "score", "feedback"},
+		},
+	}
+
+	flashcardsSchema = outputSchema{
+		Name:        "flashcards",
+		Description: "Return the flashcards extracted from the note",
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"flashcards": map[string]any{
+					"type":     "array",
+					"minItems": 1,
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"question": map[string]any{"type": "string"},
+							"answer":   map[string]any{"type": "string"},
+						},
+						"required": []string{"question", "answer"},
+					},
+				},
+			},
+			"required": []string{"flashcards"},
+		},
+	}
+
+	flashcardRewriteSchema = outputSchema{
+		Name:        "flashcard_rewrite",
+		Description: "Return the rewritten flashcard",
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"front": map[string]any{"type": "string"},
+				"back":  map[string]any{"type": "string"},
+				"hint":  map[string]any{"type": "string"},
+			},
+			"required": []string{"front", "back", "hint"},
+		},
+	}
+
+	mnemonicSchema = outputSchema{
+		Name:        "flashcard_mnemonics",
+		Description: "Return the memory aids for the flashcard",
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"mnemonics": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"technique": map[string]any{"type": "string", "enum": validMnemonicTechniques},
+							"text":      map[string]any{"type": "string"},
+						},
+						"required": []string{"technique", "text"},
+					},
+				},
+			},
+			"required": []string{"mnemonics"},
+		},
+	}
+
+	vocabularySchema = outputSchema{
+		Name:        "vocabulary_details",
+		Description: "Return the pronunciation and example sentences of the term",
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"ipa":           map[string]any{"type": "string"},
+				"pronunciation": map[string]any{"type": "string"},
+				"examples": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"text":        map[string]any{"type": "string"},
+							"translation": map[string]any{"type": "string"},
+						},
+						"required": []string{"text", "translation"},
+					},
+				},
+			},
+			"required": []string{"ipa", "pronunciation", "examples"},
+		},
+	}
+)
+
+// QuizOptions tunes a single quiz generation request
+type QuizOptions struct {
+	// CompressionTarget is the percentage of note tokens to cut before
+	// prompting; 0 disables compression.
+	CompressionTarget int
+	// Count is the number of questions to generate; 0 means one.
+	Count int
+	// Difficulty overrides both the difficulty named in the message and the
+	// one calibrated from the user's recent answers.
+	Difficulty string
+	// QuestionType overrides the type named in the message
+	QuestionType string
+	// ForceRegenerate calls the LLM even when the quiz is cached, and
+	// caches the new questions in its place
+	ForceRegenerate bool
 }
 
-func NewQuizService(noteService *NoteService, apiKey string) (*QuizService, error) {
-	log.Printf("[INFO] Initializing QuizService with OpenAI integration")
-	
-	if apiKey == "" {
-		log.Printf("[ERROR] OpenAI API key is required but not provided")
-		return nil, fmt.Errorf("OpenAI API key is required")
+// Validate checks the difficulty and question type against the allowed
+// values; empty ones are left to the message
+func (o QuizOptions) Validate() error {
+	if o.Difficulty != "" && !containsValue(validDifficulties, o.Difficulty) {
+		return models.Invalid("difficulty must be one of: %s", strings.Join(validDifficulties, ", "))
+	}
+	if o.QuestionType != "" && !containsValue(validQuestionTypes, o.QuestionType) {
+		return models.Invalid("questionType must be one of: %s", strings.Join(validQuestionTypes, ", "))
 	}
+	return nil
+}
 
-	llmClient, err := openai.New(
-		openai.WithModel("gpt-4o-mini"),
-		openai.WithToken(apiKey),
-	)
+// QuizResult is the generated messages plus details for the response metadata.
+// Source is models.QuestionSourceBank when the questions were reused because
+// the LLM provider was unavailable.
+type QuizResult struct {
+	Messages         []models.Message
+	CompressionRatio float64
+	Context          ContextUsage
+	Source           string
+	Difficulty       models.DifficultyCalibration
+}
+
+// ContextUsage reports how notes content was fitted into the model's
+// context window. DroppedTokens is the part of the notes no prompt saw.
+type ContextUsage struct {
+	Model         string `json:"model"`
+	ContextWindow int    `json:"contextWindow"`
+	NoteTokens    int    `json:"noteTokens"`
+	PromptTokens  int    `json:"promptTokens"`
+	DroppedTokens int    `json:"droppedTokens"`
+	Chunks        int    `json:"chunks"`
+}
+
+type QuizService struct {
+	noteService    *NoteService
+	deckService    *DeckService
+	routingService *RoutingService
+	contentFilter  *ContentFilterService
+	prompts        *PromptRegistry
+	dedup          *QuestionDedupService
+	bank           db.QuizSessionRepository
+	availability   *LLMAvailability
+	llmClient      LLMClient
+	visionClient   LLMClient
+	provider       string
+	model          string
+	contextWindow  int
+	timeout        time.Duration
+	events         EventPublisher
+	cache          QuizCache
+
+	// Clients for the providers and models routing rules pick, by
+	// provider and model
+	providerCfg   ProviderConfig
+	routedMu      sync.Mutex
+	routedClients map[string]LLMClient
+}
+
+func NewQuizService(noteService *NoteService, deckService *DeckService, routingService *RoutingService, contentFilter *ContentFilterService, prompts *PromptRegistry, dedup *QuestionDedupService, bank db.QuizSessionRepository, providerCfg ProviderConfig) (*QuizService, error) {
+	slog.Info("Initializing QuizService", "provider", providerCfg.Provider)
+
+	llmClient, err := NewLLMClient(providerCfg)
 	if err != nil {
-		log.Printf("[ERROR] Failed to initialize OpenAI client: %v", err)
+		slog.Error("Failed to initialize LLM client", "error", err)
 		return nil, fmt.Errorf("failed to initialize LLM client: %w", err)
 	}
 
-	log.Printf("[INFO] QuizService initialized successfully with OpenAI GPT-4o-mini model")
+	visionClient := llmClient
+	if providerCfg.VisionModel != "" && providerCfg.VisionModel != providerCfg.Model {
+		visionCfg := providerCfg
+		visionCfg.Model = providerCfg.VisionModel
+		visionClient, err = NewLLMClient(visionCfg)
+		if err != nil {
+			slog.Error("Failed to initialize vision LLM client", "error", err)
+			return nil, fmt.Errorf("failed to initialize vision LLM client: %w", err)
+		}
+	}
+
+	slog.Info("QuizService initialized", "provider", providerCfg.Provider, "model", providerCfg.ModelName())
 	return &QuizService{
-		noteService: noteService,
-		llmClient:   llmClient,
+		noteService:    noteService,
+		deckService:    deckService,
+		routingService: routingService,
+		contentFilter:  contentFilter,
+		prompts:        prompts,
+		dedup:          dedup,
+		bank:           bank,
+		availability:   providerCfg.Availability,
+		llmClient:      llmClient,
+		visionClient:   visionClient,
+		provider:       providerCfg.ProviderName(),
+		model:          providerCfg.ModelName(),
+		contextWindow:  providerCfg.ContextWindow,
+		timeout:        providerCfg.Timeout,
+		providerCfg:    providerCfg,
+		routedClients:  make(map[string]LLMClient),
 	}, nil
 }
 
-func (s *QuizService) GenerateQuiz(conversation []models.Message, noteIds []int) (models.Message, error) {
-	log.Printf("[INFO] Starting quiz generation with %d conversation messages and %d note IDs", len(conversation), len(noteIds))
-	
+// PublishEventsTo sets where quiz.generated events are published, such as
+// to the user's webhooks
+func (s *QuizService) PublishEventsTo(events EventPublisher) {
+	s.events = events
+}
+
+// CacheQuizzesIn sets where generated quizzes are cached, so repeating a
+// request over unchanged notes doesn't call the LLM again
+func (s *QuizService) CacheQuizzesIn(cache QuizCache) {
+	s.cache = cache
+}
+
+// withLLMTimeout bounds a model call by the configured timeout. The call
+// still ends early when ctx is cancelled, such as when the client disconnects.
+func (s *QuizService) withLLMTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.timeout)
+}
+
+// LLMStatus reports whether generation is currently possible
+func (s *QuizService) LLMStatus() models.LLMStatus {
+	if s.availability == nil {
+		return models.LLMStatus{Available: true}
+	}
+	return s.availability.Status()
+}
+
+// BankQuestions returns up to count questions the user was asked before on
+// the given notes, for when new ones cannot be generated. Questions of the
+// requested difficulty and type are preferred; without any, others are used.
+func (s *QuizService) BankQuestions(ctx context.Context, userID int, noteIds []int, count int, difficulty, questionType string) ([]models.QuestionData, error) {
+	questions, err := s.bank.GetBankQuestions(ctx, userID, noteIds, difficulty, questionType, count)
+	if err != nil {
+		return nil, err
+	}
+	if len(questions) == 0 && (difficulty != "" || questionType != "") {
+		questions, err = s.bank.GetBankQuestions(ctx, userID, noteIds, "", "", count)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	LoggerFromContext(ctx).Info("Loaded questions from question bank", "requested", count, "found", len(questions))
+	return questions, nil
+}
+
+// GenerateQuiz answers the last user message of a quiz conversation with
+// new questions. Questions asked earlier in the conversation, and the
+// learner's answers, are given to the model so they are not repeated, and
+// follow-ups such as "make it harder" are read relative to the last question.
+// A difficulty or question type set in options is used as given; only what
+// the options leave out is read from the message.
+func (s *QuizService) GenerateQuiz(ctx context.Context, userID int, conversation []models.Message, noteIds []int, options QuizOptions) (*QuizResult, error) {
+	ctx, span := tracer.Start(ctx, "QuizService.GenerateQuiz")
+	defer span.End()
+
+	logger := LoggerFromContext(ctx)
+	logger.Info("Starting quiz generation", "messages", len(conversation), "note_ids", noteIds)
+
 	if len(conversation) == 0 {
-		log.Printf("[ERROR] Quiz generation failed: conversation cannot be empty")
-		return models.Message{}, fmt.Errorf("conversation cannot be empty")
+		logger.Error("Quiz generation failed: conversation cannot be empty")
+		return nil, models.Invalid("conversation cannot be empty")
 	}
 
 	lastMessage := conversation[len(conversation)-1]
 	if lastMessage.Role != "user" {
-		log.Printf("[ERROR] Quiz generation failed: last message must be from user, got role: %s", lastMessage.Role)
-		return models.Message{}, fmt.Errorf("last message must be from user")
+		logger.Error("Quiz generation failed: last message must be from user", "role", lastMessage.Role)
+		return nil, models.Invalid("last message must be from user")
+	}
+
+	if options.CompressionTarget < 0 || options.CompressionTarget > MAX_COMPRESSION_TARGET {
+		logger.Error("Quiz generation failed: invalid compression target", "compression_target", options.CompressionTarget)
+		return nil, models.Invalid("compression target must be between 0 and %d", MAX_COMPRESSION_TARGET)
+	}
+
+	count := options.Count
+	if count == 0 {
+		count = 1
+	}
+	if count < 0 || count > MAX_QUESTIONS_PER_REQUEST {
+		logger.Error("Quiz generation failed: invalid question count", "count", options.Count)
+		return nil, models.Invalid("count must be between 1 and %d", MAX_QUESTIONS_PER_REQUEST)
+	}
+
+	if err := options.Validate(); err != nil {
+		logger.Error("Quiz generation failed: invalid options", "difficulty", options.Difficulty, "question_type", options.QuestionType)
+		return nil, err
 	}
 
 	// Get notes content
-	log.Printf("[INFO] Retrieving notes content for quiz generation")
-	notesContent, err := s.getNotesContent(noteIds)
+	logger.Info("Retrieving notes content for quiz generation")
+	notesContent, terminology, compressionRatio, err := s.getNotesContent(ctx, userID, noteIds, options.CompressionTarget)
 	if err != nil {
-		log.Printf("[ERROR] Failed to retrieve notes content: %v", err)
-		return models.Message{}, fmt.Errorf("failed to retrieve notes: %w", err)
+		logger.Error("Failed to retrieve notes content", "error", err)
+		return nil, fmt.Errorf("failed to retrieve notes: %w", err)
+	}
+
+	memory := newConversationMemory(conversation)
+	memory.recent = s.dedup.Load(ctx, userID, noteIds)
+	calibration := models.DifficultyCalibration{Difficulty: options.Difficulty, Source: models.DifficultySourceOption}
+	questionType := options.QuestionType
+	if options.Difficulty == "" || options.QuestionType == "" {
+		// What the options leave out is read from the message
+		messageDifficulty, messageType := "", ""
+		if options.Difficulty == "" {
+			messageDifficulty = s.extractDifficulty(ctx, lastMessage.Content)
+		}
+		if options.QuestionType == "" {
+			messageType = s.extractQuestionType(ctx, lastMessage.Content)
+		}
+		messageDifficulty, messageType = memory.resolve(ctx, lastMessage.Content, messageDifficulty, messageType)
+
+		if options.QuestionType == "" {
+			questionType = messageType
+		}
+		if options.Difficulty == "" {
+			if messageDifficulty != "" {
+				calibration = models.DifficultyCalibration{Difficulty: messageDifficulty, Source: models.DifficultySourceMessage}
+			} else {
+				calibration = s.calibrateDifficulty(ctx, userID, noteIds)
+			}
+		}
+	}
+	difficulty := calibration.Difficulty
+	span.SetAttributes(
+		attribute.Int("quiz.count", count),
+		attribute.String("quiz.difficulty", difficulty),
+		attribute.String("quiz.question_type", questionType),
+	)
+	chunks, usage, err := s.fitNotesToContext(ctx, notesContent, terminology, difficulty, questionType, count, memory)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only a conversation's first request is cached, since later ones are
+	// prompted with the questions already asked
+	cacheKey := ""
+	if s.cache != nil && len(memory.asked) == 0 {
+		cacheKey = s.quizCacheKey(ctx, userID, noteIds, chunks, difficulty, questionType, count)
+		if !options.ForceRegenerate {
+			if messages, ok := s.cache.GetQuiz(ctx, cacheKey); ok {
+				logger.Info("Serving quiz from cache", "questions", len(messages), "question_type", questionType, "difficulty", difficulty)
+				result := &QuizResult{
+					Messages:         renewQuestionIDs(messages),
+					CompressionRatio: compressionRatio,
+					Context:          usage,
+					Source:           models.QuestionSourceCache,
+					Difficulty:       calibration,
+				}
+				s.publishQuiz(ctx, userID, noteIds, result)
+				return result, nil
+			}
+		}
 	}
 
 	// Generate quiz using LLM
-	log.Printf("[INFO] Generating quiz using LLM with notes content length: %d characters", len(notesContent))
-	difficulty := s.extractDifficulty(lastMessage.Content)
-	questionType := s.extractQuestionType(lastMessage.Content)
-	response, err := s.generateQuizWithLLM(notesContent, difficulty, questionType, noteIds)
+	logger.Info("Generating quiz using LLM", "notes_chars", len(notesContent), "chunks", len(chunks))
+	messages, err := s.generateQuestions(ctx, count, chunks, difficulty, questionType, noteIds, memory)
+	if err != nil {
+		if errors.Is(err, ErrLLMUnavailable) {
+			if result := s.bankQuizResult(ctx, userID, noteIds, count, difficulty, questionType); result != nil {
+				result.CompressionRatio = compressionRatio
+				result.Context = usage
+				result.Difficulty = calibration
+				s.publishQuiz(ctx, userID, noteIds, result)
+				return result, nil
+			}
+		}
+		logger.Error("LLM quiz generation failed", "error", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, models.Upstream("failed to generate quiz with LLM: %w", err)
+	}
+
+	logger.Info("Quiz generation completed", "questions", len(messages), "question_type", questionType, "difficulty", difficulty)
+	if cacheKey != "" {
+		s.cache.SetQuiz(ctx, cacheKey, messages)
+	}
+	result := &QuizResult{
+		Messages:         messages,
+		CompressionRatio: compressionRatio,
+		Context:          usage,
+		Source:           models.QuestionSourceGenerated,
+		Difficulty:       calibration,
+	}
+	s.publishQuiz(ctx, userID, noteIds, result)
+	return result, nil
+}
+
+// quizCacheKey hashes everything a quiz prompt is built from: the notes as
+// fitted into the context, the difficulty, type and count, the template
+// versions, the content filter's guidance and the provider and model. The
+// user and their note IDs, in any order, are part of it too, so a quiz is
+// only served again to the user it was generated for, from the same notes.
+func (s *QuizService) quizCacheKey(ctx context.Context, userID int, noteIds []int, chunks []string, difficulty, questionType string, count int) string {
+	sortedNoteIds := slices.Clone(noteIds)
+	slices.Sort(sortedNoteIds)
+
+	provider, model := s.provider, s.model
+	if rule := s.routingService.Resolve(ctx, questionType, difficulty); rule != nil {
+		model = rule.Model
+		if rule.Provider != "" {
+			provider = rule.Provider
+		}
+	}
+
+	parts, _ := json.Marshal([]any{
+		userID, sortedNoteIds, chunks, difficulty, questionType, count,
+		s.prompts.Version(PROMPT_QUIZ_SYSTEM), s.prompts.Version(PROMPT_QUIZ_USER),
+		s.contentFilter.PromptGuidance(ctx), provider, model,
+	})
+	hash := sha256.Sum256(parts)
+	return hex.EncodeToString(hash[:])
+}
+
+// routedClient returns the client for the provider and model of a routing
+// rule, creating it on first use. It returns nil, and the default client is
+// used, when the provider cannot be set up, such as without an API key.
+func (s *QuizService) routedClient(ctx context.Context, rule *models.RoutingRule) LLMClient {
+	key := rule.Provider + "/" + rule.Model
+
+	s.routedMu.Lock()
+	defer s.routedMu.Unlock()
+	if client, ok := s.routedClients[key]; ok {
+		return client
+	}
+
+	client, err := NewLLMClient(s.providerCfg.ForRoute(rule.Provider, rule.Model))
+	if err != nil {
+		LoggerFromContext(ctx).Error("Failed to create LLM client for routing rule, using the default", "rule_id", rule.ID, "provider", rule.Provider, "model", rule.Model, "error", err)
+		return nil
+	}
+	s.routedClients[key] = client
+	return client
+}
+
+// renewQuestionIDs gives cached questions new IDs, so answers to a repeated
+// quiz are told apart from the first one's
+func renewQuestionIDs(messages []models.Message) []models.Message {
+	for i := range messages {
+		if messages[i].Question != nil {
+			messages[i].Question.ID = newQuestionID()
+		}
+	}
+	return messages
+}
+
+func newQuestionID() string {
+	return fmt.Sprintf("q_llm_%d_%d", time.Now().Unix(), questionCounter.Add(1))
+}
+
+// publishQuiz publishes a quiz.generated event with the questions asked
+func (s *QuizService) publishQuiz(ctx context.Context, userID int, noteIds []int, result *QuizResult) {
+	if s.events == nil {
+		return
+	}
+
+	questions := make([]models.QuestionData, 0, len(result.Messages))
+	for _, message := range result.Messages {
+		if message.Question != nil {
+			questions = append(questions, *message.Question)
+		}
+	}
+	s.events.Publish(ctx, userID, models.WebhookEventQuizGenerated, models.QuizGeneratedEvent{
+		NoteIDs:    noteIds,
+		Source:     result.Source,
+		Difficulty: result.Difficulty.Difficulty,
+		Questions:  questions,
+	})
+}
+
+// bankQuizResult answers a quiz request from the question bank while the
+// LLM provider is unavailable. It returns nil when the bank has nothing, so
+// the caller reports the outage instead.
+func (s *QuizService) bankQuizResult(ctx context.Context, userID int, noteIds []int, count int, difficulty, questionType string) *QuizResult {
+	logger := LoggerFromContext(ctx)
+
+	questions, err := s.BankQuestions(ctx, userID, noteIds, count, difficulty, questionType)
 	if err != nil {
-		log.Printf("[ERROR] LLM quiz generation failed: %v", err)
-		return models.Message{}, fmt.Errorf("failed to generate quiz with LLM: %w", err)
+		logger.Error("Failed to load questions from question bank", "error", err)
+		return nil
+	}
+	if len(questions) == 0 {
+		return nil
+	}
+
+	messages := make([]models.Message, len(questions))
+	for i := range questions {
+		messages[i] = models.Message{
+			Role:     "assistant",
+			Content:  "Question generation is unavailable right now, so here's a question from your earlier sessions:",
+			Question: &questions[i],
+		}
+	}
+
+	logger.Warn("Served quiz from question bank while LLM is unavailable", "questions", len(messages), "requested", count)
+	return &QuizResult{Messages: messages, Source: models.QuestionSourceBank}
+}
+
+// Generate count questions with a bounded pool of parallel LLM calls.
+// Question i is generated from chunk i modulo the number of chunks. Failed
+// generations and repeats of another question in the batch are dropped; an
+// error is returned only if none succeed. memory, which may be nil, holds
+// the questions asked earlier in the conversation.
+func (s *QuizService) generateQuestions(ctx context.Context, count int, chunks []string, difficulty, questionType string, noteIds []int, memory *conversationMemory) ([]models.Message, error) {
+	logger := LoggerFromContext(ctx)
+	if count == 1 {
+		message, err := s.generateQuizWithLLM(ctx, chunks[0], difficulty, questionType, noteIds, memory)
+		if err != nil {
+			return nil, err
+		}
+		return []models.Message{message}, nil
+	}
+
+	logger.Info("Generating questions in parallel", "count", count, "max_parallel", MAX_PARALLEL_GENERATIONS)
+
+	results := make([]*models.Message, count)
+	errs := make([]error, count)
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(count, MAX_PARALLEL_GENERATIONS); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				message, err := s.generateQuizWithLLM(ctx, chunks[i%len(chunks)], difficulty, questionType, noteIds, memory)
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				results[i] = &message
+			}
+		}()
+	}
+
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	messages := make([]models.Message, 0, count)
+	var firstErr error
+	for i, message := range results {
+		if message == nil {
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			continue
+		}
+		messages = append(messages, *message)
+	}
+
+	if len(messages) == 0 {
+		return nil, firstErr
+	}
+	messages = dedupeQuestions(ctx, messages)
+	if len(messages) < count {
+		logger.Error("Some questions failed to generate", "generated", len(messages), "requested", count, "error", firstErr)
 	}
 
-	log.Printf("[INFO] Quiz generation completed successfully with question type: %s, difficulty: %s", questionType, difficulty)
-	return response, nil
+	return messages, nil
 }
 
 // Helper function to check if text contains any of the keywords
@@ -110,28 +752,31 @@ func (s *QuizService) containsKeywords(text string, keywords []string) bool {
 	return false
 }
 
-// Get notes content for LLM input
-func (s *QuizService) getNotesContent(noteIds []int) (string, error) {
-	log.Printf("[INFO] Getting notes content - noteIds: %v", noteIds)
-	
+// Get notes content for LLM input, compressed by compressionTarget percent,
+// and the terminology guidance of their decks. The returned ratio is
+// compressed tokens over original tokens.
+func (s *QuizService) getNotesContent(ctx context.Context, userID int, noteIds []int, compressionTarget int) (string, string, float64, error) {
+	logger := LoggerFromContext(ctx)
+	logger.Info("Getting notes content", "note_ids", noteIds)
+
 	var notes []*models.Note
 	var err error
 
 	if len(noteIds) == 0 {
 		// Get all notes if no specific IDs provided
-		log.Printf("[INFO] No specific note IDs provided, fetching all notes")
-		notes, err = s.noteService.GetAllNotes()
+		logger.Info("No specific note IDs provided, fetching all notes")
+		notes, err = s.noteService.GetAllNotes(ctx, userID)
 		if err != nil {
-			log.Printf("[ERROR] Failed to get all notes: %v", err)
-			return "", err
+			logger.Error("Failed to get all notes", "error", err)
+			return "", "", 0, err
 		}
 	} else {
 		// Get specific notes by IDs
-		log.Printf("[INFO] Fetching %d specific notes by ID", len(noteIds))
+		logger.Info("Fetching specific notes by ID", "count", len(noteIds))
 		for _, id := range noteIds {
-			note, noteErr := s.noteService.GetNoteByID(id)
+			note, noteErr := s.noteService.GetNoteByID(ctx, userID, id)
 			if noteErr != nil {
-				log.Printf("[ERROR] Failed to get note with ID %d: %v", id, noteErr)
+				logger.Error("Failed to get note", "note_id", id, "error", noteErr)
 				continue // Skip invalid note IDs
 			}
 			notes = append(notes, note)
@@ -139,84 +784,284 @@ func (s *QuizService) getNotesContent(noteIds []int) (string, error) {
 	}
 
 	if len(notes) == 0 {
-		log.Printf("[ERROR] No notes found for quiz generation")
-		return "", fmt.Errorf("no notes found")
+		logger.Error("No notes found for quiz generation")
+		return "", "", 0, models.NotFound("no notes found")
 	}
 
+	terminologies := s.loadTerminology(ctx, userID, notes)
+
 	// Combine all notes content
 	var contentBuilder strings.Builder
+	originalTokens, compressedTokens := 0, 0
 	for i, note := range notes {
 		if i > 0 {
 			contentBuilder.WriteString("\n\n---\n\n")
 		}
-		contentBuilder.WriteString(fmt.Sprintf("Note %d: %s", note.ID, note.Content))
+		var stopWords map[string]bool
+		if note.DeckID != nil {
+			stopWords = stopWordSet(terminologies[*note.DeckID])
+		}
+		noteContent, before, after := compressText(note.Content, compressionTarget, stopWords)
+		originalTokens += before
+		compressedTokens += after
+		// Titles ground the questions in what the note is about
+		if note.Title != "" {
+			contentBuilder.WriteString(fmt.Sprintf("Note %d (%s): %s", note.ID, note.Title, noteContent))
+		} else {
+			contentBuilder.WriteString(fmt.Sprintf("Note %d: %s", note.ID, noteContent))
+		}
+	}
+
+	ratio := 1.0
+	if originalTokens > 0 {
+		ratio = float64(compressedTokens) / float64(originalTokens)
+	}
+	if compressionTarget > 0 {
+		logger.Info("Compressed notes", "original_tokens", originalTokens, "compressed_tokens", compressedTokens, "ratio", ratio, "target_percent", compressionTarget)
+	}
+
+	// Deck terminology rides along with every chunk of the notes so each
+	// prompt built from them uses the course vocabulary
+	terminology := ""
+	if guidance := terminologyGuidance(terminologies); guidance != "" {
+		logger.Info("Adding deck terminology to notes content", "decks", len(terminologies))
+		terminology = "\n\n---\n\nCourse terminology:\n" + guidance
+	}
+	// Questions are asked in the language the notes are written in
+	if instruction := languageInstruction(notes, "the questions, options and explanations"); instruction != "" {
+		terminology += "\n\n---\n\n" + instruction
 	}
 
 	content := contentBuilder.String()
-	log.Printf("[INFO] Successfully combined %d notes into content with %d characters", len(notes), len(content))
-	return content, nil
+	logger.Info("Combined notes into content", "notes", len(notes), "chars", len(content))
+	return content, terminology, ratio, nil
+}
+
+// fitNotesToContext measures the assembled quiz prompt with the routed
+// model's tokenizer and splits the notes into chunks that fit its context
+// window, each followed by the terminology. With several questions each is
+// generated from a different chunk so more of the notes are covered; chunks
+// beyond the question count are dropped and reported in the usage.
+func (s *QuizService) fitNotesToContext(ctx context.Context, notesContent, terminology, difficulty, questionType string, count int, memory *conversationMemory) ([]string, ContextUsage, error) {
+	logger := LoggerFromContext(ctx)
+	counter := s.tokenCounter(s.routingService.ResolveModel(ctx, questionType, difficulty))
+
+	fixedPrompt, err := s.quizPrompt("", difficulty, questionType)
+	if err != nil {
+		return nil, ContextUsage{}, err
+	}
+	fixedPrompt += terminology
+	if guidance := s.contentFilter.PromptGuidance(ctx); guidance != "" {
+		fixedPrompt += "\n\n" + guidance
+	}
+	if history := memory.guidance(); history != "" {
+		fixedPrompt += "\n\n" + history
+	}
+	fixedTokens := counter.Count(fixedPrompt)
+
+	budget := counter.Budget(fixedPrompt)
+	if budget < MIN_NOTES_TOKEN_BUDGET {
+		logger.Error("Prompt leaves too little room for notes", "fixed_tokens", fixedTokens, "budget", budget, "context_window", counter.ContextWindow())
+		return nil, ContextUsage{}, fmt.Errorf("prompt leaves no room for notes in the %d token context window of %s", counter.ContextWindow(), counter.Model())
+	}
+
+	chunks := counter.Chunk(notesContent, budget)
+	used := min(len(chunks), count)
+
+	usage := ContextUsage{
+		Model:         counter.Model(),
+		ContextWindow: counter.ContextWindow(),
+		NoteTokens:    counter.Count(notesContent),
+		Chunks:        used,
+	}
+
+	chunkTokens := make([]int, len(chunks))
+	for i, chunk := range chunks {
+		chunkTokens[i] = counter.Count(chunk)
+		if i >= used {
+			usage.DroppedTokens += chunkTokens[i]
+		}
+	}
+	for i := 0; i < count; i++ {
+		usage.PromptTokens += fixedTokens + chunkTokens[i%used]
+	}
+
+	if usage.DroppedTokens > 0 {
+		logger.Info("Notes exceed the token budget, dropping chunks", "note_tokens", usage.NoteTokens, "budget", budget,
+			"model", counter.Model(), "chunks_used", used, "chunks", len(chunks), "dropped_tokens", usage.DroppedTokens)
+	} else if len(chunks) > 1 {
+		logger.Info("Split notes into chunks", "note_tokens", usage.NoteTokens, "chunks", len(chunks), "budget", budget, "model", counter.Model())
+	}
+
+	fitted := make([]string, used)
+	for i := range fitted {
+		fitted[i] = chunks[i] + terminology
+	}
+	return fitted, usage, nil
+}
+
+// Token counter for the given model, or the default model when empty. The
+// configured context window only applies to the default model.
+func (s *QuizService) tokenCounter(model string) *TokenCounter {
+	if model == "" || model == s.model {
+		return NewTokenCounter(s.model, s.contextWindow)
+	}
+	return NewTokenCounter(model, 0)
 }
 
-// Extract difficulty from user message
-func (s *QuizService) extractDifficulty(message string) string {
+// Load the terminology dictionaries of the decks the notes belong to. A
+// failure here degrades to generation without terminology.
+func (s *QuizService) loadTerminology(ctx context.Context, userID int, notes []*models.Note) map[int]*models.DeckTerminology {
+	logger := LoggerFromContext(ctx)
+	var deckIDs []int
+	seen := make(map[int]bool)
+	for _, note := range notes {
+		if note.DeckID != nil && !seen[*note.DeckID] {
+			seen[*note.DeckID] = true
+			deckIDs = append(deckIDs, *note.DeckID)
+		}
+	}
+
+	terminologies, err := s.deckService.TerminologyForDecks(ctx, userID, deckIDs)
+	if err != nil {
+		logger.Error("Failed to load deck terminology", "error", err)
+		return map[int]*models.DeckTerminology{}
+	}
+
+	return terminologies
+}
+
+// Extract difficulty from user message, or "" when it names none
+func (s *QuizService) extractDifficulty(ctx context.Context, message string) string {
+	logger := LoggerFromContext(ctx)
 	if s.containsKeywords(message, []string{"easy", "simple", "basic", "beginner"}) {
-		log.Printf("[INFO] Extracted difficulty: easy from user message")
+		logger.Info("Extracted difficulty from user message", "difficulty", "easy")
 		return "easy"
 	}
 	if s.containsKeywords(message, []string{"hard", "difficult", "challenging", "advanced"}) {
-		log.Printf("[INFO] Extracted difficulty: hard from user message")
+		logger.Info("Extracted difficulty from user message", "difficulty", "hard")
 		return "hard"
 	}
-	log.Printf("[INFO] Using default difficulty: medium")
-	return "medium" // default
+	return ""
 }
 
 // Extract question type from user message
-func (s *QuizService) extractQuestionType(message string) string {
+func (s *QuizService) extractQuestionType(ctx context.Context, message string) string {
+	logger := LoggerFromContext(ctx)
 	if s.containsKeywords(message, []string{"essay", "explain", "describe", "discuss"}) {
-		log.Printf("[INFO] Extracted question type: essay from user message")
+		logger.Info("Extracted question type from user message", "question_type", "essay")
 		return "essay"
 	}
+	if s.containsKeywords(message, []string{"math", "calculate", "solve", "compute"}) {
+		logger.Info("Extracted question type from user message", "question_type", QUESTION_TYPE_MATH)
+		return QUESTION_TYPE_MATH
+	}
 	if s.containsKeywords(message, []string{"true", "false", "yes", "no"}) {
-		log.Printf("[INFO] Extracted question type: true-false from user message")
+		logger.Info("Extracted question type from user message", "question_type", "true-false")
 		return "true-false"
 	}
-	log.Printf("[INFO] Using default question type: multiple-choice")
+	logger.Info("Using default question type", "question_type", "multiple-choice")
 	return "multiple-choice" // default
 }
 
+// quizPrompt renders the system and user quiz prompt templates for one
+// question from the notes
+func (s *QuizService) quizPrompt(notes, difficulty, questionType string) (string, error) {
+	data := quizPromptData{Notes: notes, Difficulty: difficulty, QuestionType: questionType}
+
+	system, err := s.prompts.Render(PROMPT_QUIZ_SYSTEM, data)
+	if err != nil {
+		return "", err
+	}
+	user, err := s.prompts.Render(PROMPT_QUIZ_USER, data)
+	if err != nil {
+		return "", err
+	}
+	return system + "\n\n" + user, nil
+}
+
 // Generate quiz using LLM
-func (s *QuizService) generateQuizWithLLM(notesContent, difficulty, questionType string, noteIds []int) (models.Message, error) {
-	log.Printf("[INFO] Starting LLM quiz generation - difficulty: %s, type: %s, noteIds: %v", difficulty, questionType, noteIds)
+func (s *QuizService) generateQuizWithLLM(ctx context.Context, notesContent, difficulty, questionType string, noteIds []int, memory *conversationMemory) (models.Message, error) {
+	logger := LoggerFromContext(ctx)
+	logger.Info("Starting LLM quiz generation", "difficulty", difficulty, "question_type", questionType, "note_ids", noteIds)
 	startTime := time.Now()
-	
-	ctx := context.Background()
 
-	// Prepare the prompt
-	userPrompt := fmt.Sprintf(USER_PROMPT_TEMPLATE, notesContent, difficulty, questionType)
-	log.Printf("[INFO] Prepared LLM prompt with %d characters", len(SYSTEM_PROMPT+"\n\n"+userPrompt))
+	ctx, cancel := s.withLLMTimeout(ctx)
+	defer cancel()
 
-	// Call LLM
-	log.Printf("[INFO] Calling OpenAI LLM with temperature 0.9")
-	completion, err := llms.GenerateFromSinglePrompt(
-		ctx,
-		s.llmClient,
-		SYSTEM_PROMPT+"\n\n"+userPrompt,
-		llms.WithTemperature(0.9),
-	)
+	// Prepare the prompt
+	prompt, err := s.quizPrompt(notesContent, difficulty, questionType)
 	if err != nil {
-		log.Printf("[ERROR] LLM API call failed after %v: %v", time.Since(startTime), err)
-		return models.Message{}, fmt.Errorf("LLM generation failed: %w", err)
+		logger.Error("Failed to render quiz prompt", "error", err)
+		return models.Message{}, err
+	}
+	if guidance := s.contentFilter.PromptGuidance(ctx); guidance != "" {
+		prompt += "\n\n" + guidance
+	}
+	if history := memory.guidance(); history != "" {
+		prompt += "\n\n" + history
+	}
+	logger.Info("Prepared LLM prompt", "chars", len(prompt))
+
+	callOptions := []llms.CallOption{llms.WithTemperature(0.9)}
+	client := s.llmClient
+	if rule := s.routingService.Resolve(ctx, questionType, difficulty); rule != nil {
+		if routed := s.routedClient(ctx, rule); routed != nil {
+			client = routed
+		}
 	}
 
-	log.Printf("[INFO] LLM API call completed successfully in %v, response length: %d characters", time.Since(startTime), len(completion))
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, prompt)}
 
-	// Parse LLM response
-	log.Printf("[INFO] Parsing LLM response to question data")
-	questionData, err := s.parseLLMResponse(completion, noteIds)
-	if err != nil {
-		log.Printf("[ERROR] Failed to parse LLM response: %v", err)
-		return models.Message{}, fmt.Errorf("failed to parse LLM response: %w", err)
+	var questionData models.QuestionData
+	for attempt, filtered, repeated := 0, 0, 0; ; attempt++ {
+		// Call LLM
+		logger.Info("Calling LLM", "temperature", 0.9, "attempt", attempt+1)
+		var question llmQuestion
+		err := s.generateStructured(ctx, client, messages, questionSchema, &question, question.validate, callOptions...)
+		if err != nil {
+			if errors.Is(err, errInvalidStructuredOutput) {
+				logger.Error("Failed to parse LLM response", "error", err)
+				return models.Message{}, models.Upstream("failed to parse LLM response: %w", err)
+			}
+			logger.Error("LLM API call failed", "duration_ms", time.Since(startTime).Milliseconds(), "error", err)
+			return models.Message{}, fmt.Errorf("LLM generation failed: %w", err)
+		}
+
+		logger.Info("LLM API call completed", "duration_ms", time.Since(startTime).Milliseconds())
+		questionData = s.buildQuestionData(ctx, question, noteIds)
+
+		if violation := s.contentFilter.Check(ctx, questionText(questionData)...); violation != "" {
+			if filtered >= MAX_FILTER_REGENERATIONS {
+				logger.Error("Generated question still blocked by content filter", "attempts", attempt+1, "violation", violation)
+				return models.Message{}, fmt.Errorf("generated question was blocked by the content filter: %s", violation)
+			}
+			filtered++
+			logger.Info("Generated question blocked by content filter, regenerating", "violation", violation)
+			continue
+		}
+
+		earlier := memory.duplicateOf(questionData.Text)
+		var vector []float64
+		if earlier == "" {
+			var similarity float64
+			earlier, similarity, vector = s.dedup.Check(ctx, memory.recentQuestions(), questionData.Text)
+			if earlier != "" {
+				logger.Info("Generated question is similar to a recent one", "similarity", similarity)
+			}
+		}
+		if earlier == "" {
+			s.dedup.Remember(ctx, memory.recentQuestions(), questionData.Text, vector)
+			break
+		}
+		if repeated >= s.dedup.MaxRegenerations() {
+			logger.Error("Generated question still repeats an earlier one", "attempts", attempt+1)
+			return models.Message{}, fmt.Errorf("could not generate a question that was not already asked")
+		}
+		repeated++
+		logger.Info("Generated question repeats an earlier one, regenerating", "earlier", earlier)
+		messages = append(messages, llms.TextParts(llms.ChatMessageTypeHuman, fmt.Sprintf(
+			"The question %q repeats one the learner was already asked. Ask about something else.", questionData.Text)))
 	}
 
 	// Create message
@@ -226,61 +1071,739 @@ func (s *QuizService) generateQuizWithLLM(notesContent, difficulty, questionType
 		Question: &questionData,
 	}
 
-	log.Printf("[INFO] LLM quiz generation completed successfully - question ID: %s, type: %s", questionData.ID, questionData.Type)
+	logger.Info("LLM quiz generation completed", "question_id", questionData.ID, "question_type", questionData.Type)
 	return message, nil
 }
 
-// Parse LLM JSON response into QuestionData
-func (s *QuizService) parseLLMResponse(response string, noteIds []int) (models.QuestionData, error) {
-	log.Printf("[INFO] Parsing LLM response with length: %d characters", len(response))
-	
-	var llmResponse struct {
-		Question      string   `json:"question"`
-		Type          string   `json:"type"`
-		Options       []string `json:"options,omitempty"`
-		CorrectAnswer string   `json:"correctAnswer,omitempty"`
-		Explanation   string   `json:"explanation"`
-		Difficulty    string   `json:"difficulty"`
-	}
-
-	// Clean the response - sometimes LLM adds extra text
-	jsonStart := strings.Index(response, "{")
-	jsonEnd := strings.LastIndex(response, "}") + 1
-	if jsonStart == -1 || jsonEnd == 0 {
-		log.Printf("[ERROR] No valid JSON found in LLM response")
-		return models.QuestionData{}, fmt.Errorf("no valid JSON found in response")
-	}
-	
-	jsonResponse := response[jsonStart:jsonEnd]
-	log.Printf("[INFO] Extracted JSON from LLM response: %d characters", len(jsonResponse))
+// llmQuestion is a question as the model returns it, see questionSchema
+type llmQuestion struct {
+	Question      string   `json:"question"`
+	Type          string   `json:"type"`
+	Options       []string `json:"options,omitempty"`
+	CorrectAnswer string   `json:"correctAnswer,omitempty"`
+	Explanation   string   `json:"explanation"`
+	Difficulty    string   `json:"difficulty"`
+}
 
-	if err := json.Unmarshal([]byte(jsonResponse), &llmResponse); err != nil {
-		log.Printf("[ERROR] Failed to unmarshal JSON response: %v", err)
-		return models.QuestionData{}, fmt.Errorf("failed to parse JSON: %w", err)
+// validate checks what each question type needs beyond the schema
+func (q *llmQuestion) validate() error {
+	if strings.TrimSpace(q.Question) == "" {
+		return fmt.Errorf("question must not be empty")
 	}
 
-	// Validate required fields
-	if llmResponse.Question == "" {
-		log.Printf("[ERROR] LLM response missing required question field")
-		return models.QuestionData{}, fmt.Errorf("question field is required")
+	switch q.Type {
+	case "multiple-choice":
+		if len(q.Options) < 2 {
+			return fmt.Errorf("a multiple-choice question needs at least 2 options")
+		}
+		if strings.TrimSpace(q.CorrectAnswer) == "" {
+			return fmt.Errorf("a multiple-choice question needs a correctAnswer")
+		}
+	case "true-false", QUESTION_TYPE_MATH:
+		if strings.TrimSpace(q.CorrectAnswer) == "" {
+			return fmt.Errorf("a %s question needs a correctAnswer", q.Type)
+		}
 	}
+	return nil
+}
 
+// Build QuestionData from a validated LLM question
+func (s *QuizService) buildQuestionData(ctx context.Context, question llmQuestion, noteIds []int) models.QuestionData {
 	// Generate unique ID
-	questionID := fmt.Sprintf("q_llm_%d", time.Now().Unix())
+	questionID := newQuestionID()
 
 	questionData := models.QuestionData{
 		ID:            questionID,
-		Text:          llmResponse.Question,
-		Type:          llmResponse.Type,
-		Options:       llmResponse.Options,
-		CorrectAnswer: llmResponse.CorrectAnswer,
-		Explanation:   llmResponse.Explanation,
-		Difficulty:    llmResponse.Difficulty,
+		Text:          question.Question,
+		Type:          question.Type,
+		Options:       question.Options,
+		CorrectAnswer: question.CorrectAnswer,
+		Explanation:   question.Explanation,
+		Difficulty:    question.Difficulty,
 		BasedOnNotes:  noteIds,
+		Accessibility: &models.AccessibilityMetadata{
+			OptionLabels: buildOptionLabels(question.Type, question.Options),
+		},
+	}
+
+	LoggerFromContext(ctx).Info("Built question data from LLM response",
+		"question_id", questionData.ID, "question_type", questionData.Type, "difficulty", questionData.Difficulty)
+	return questionData
+}
+
+// StreamEssayFeedback grades an essay answer, calling onScore as soon as the
+// score line has been produced and onChunk for each piece of the narrative
+// feedback that follows.
+func (s *QuizService) StreamEssayFeedback(ctx context.Context, question models.QuestionData, answer string, onScore func(score int) error, onChunk func(chunk string) error) (*models.EssayFeedback, error) {
+	logger := LoggerFromContext(ctx)
+	logger.Info("Starting essay grading", "question_id", question.ID, "answer_chars", len(answer))
+	startTime := time.Now()
+
+	if strings.TrimSpace(question.Text) == "" {
+		return nil, models.Invalid("question text is required")
+	}
+	if strings.TrimSpace(answer) == "" {
+		return nil, models.Invalid("answer cannot be empty")
+	}
+
+	prompt := fmt.Sprintf(ESSAY_GRADING_PROMPT_TEMPLATE, question.Text, question.Explanation, answer)
+
+	var header strings.Builder
+	var narrative strings.Builder
+	score := -1
+	scoreSent := false
+
+	streamingFunc := func(ctx context.Context, chunk []byte) error {
+		if scoreSent {
+			narrative.Write(chunk)
+			return onChunk(string(chunk))
+		}
+
+		header.Write(chunk)
+		firstLine, rest, found := strings.Cut(header.String(), "\n")
+		if !found {
+			return nil
+		}
+
+		if match := essayScorePattern.FindStringSubmatch(firstLine); match != nil {
+			score, _ = strconv.Atoi(match[1])
+			score = min(score, ESSAY_MAX_SCORE)
+		} else {
+			// No score line: treat everything as narrative
+			rest = header.String()
+		}
+		scoreSent = true
+		if score >= 0 {
+			if err := onScore(score); err != nil {
+				return err
+			}
+		}
+
+		rest = strings.TrimLeft(rest, "\n")
+		if rest == "" {
+			return nil
+		}
+		narrative.WriteString(rest)
+		return onChunk(rest)
+	}
+
+	ctx, cancel := s.withLLMTimeout(ctx)
+	defer cancel()
+	_, err := generateFromPrompt(
+		ctx,
+		s.llmClient,
+		prompt,
+		llms.WithTemperature(0.2),
+		llms.WithStreamingFunc(streamingFunc),
+	)
+	if err != nil {
+		logger.Error("Essay grading LLM call failed", "duration_ms", time.Since(startTime).Milliseconds(), "error", err)
+		return nil, fmt.Errorf("essay grading failed: %w", err)
+	}
+
+	// Response ended without a newline after the header
+	if !scoreSent {
+		if match := essayScorePattern.FindStringSubmatch(header.String()); match != nil {
+			score, _ = strconv.Atoi(match[1])
+			score = min(score, ESSAY_MAX_SCORE)
+			if err := onScore(score); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if score < 0 {
+		logger.Error("Essay grading response did not contain a score")
+		return nil, fmt.Errorf("no score found in grading response")
+	}
+
+	feedback := &models.EssayFeedback{
+		QuestionID: question.ID,
+		Score:      score,
+		MaxScore:   ESSAY_MAX_SCORE,
+		Feedback:   strings.TrimSpace(narrative.String()),
+	}
+
+	logger.Info("Essay grading completed", "duration_ms", time.Since(startTime).Milliseconds(), "question_id", question.ID, "score", score, "max_score", ESSAY_MAX_SCORE)
+	return feedback, nil
+}
+
+// GradeImageAnswer grades a photo of a worked answer with the vision model,
+// awarding partial credit for the work shown
+func (s *QuizService) GradeImageAnswer(ctx context.Context, question models.QuestionData, contentType string, image []byte) (*models.AnswerGrading, error) {
+	logger := LoggerFromContext(ctx)
+	logger.Info("Grading image answer", "question_id", question.ID, "image_bytes", len(image))
+	startTime := time.Now()
+
+	expected := question.CorrectAnswer
+	if expected == "" {
+		expected = "(not given, use the reference explanation)"
+	}
+	prompt := fmt.Sprintf(IMAGE_GRADING_PROMPT_TEMPLATE, question.Text, expected, question.Explanation, IMAGE_ANSWER_MAX_SCORE)
+
+	messages := []llms.MessageContent{
+		{
+			Role: llms.ChatMessageTypeHuman,
+			Parts: []llms.ContentPart{
+				llms.TextPart(prompt),
+				llms.BinaryPart(contentType, image),
+			},
+		},
+	}
+
+	ctx, cancel := s.withLLMTimeout(ctx)
+	defer cancel()
+	var grading models.AnswerGrading
+	err := s.generateStructured(ctx, s.visionClient, messages, imageGradingSchema, &grading, nil, llms.WithTemperature(0.1))
+	if err != nil {
+		if errors.Is(err, errInvalidStructuredOutput) {
+			logger.Error("Failed to parse image grading response", "error", err)
+			return nil, models.Upstream("failed to parse image grading response: %w", err)
+		}
+		logger.Error("Image grading LLM call failed", "duration_ms", time.Since(startTime).Milliseconds(), "error", err)
+		return nil, models.Upstream("image grading failed: %w", err)
+	}
+
+	grading.FinalAnswer = strings.TrimSpace(grading.FinalAnswer)
+	grading.Feedback = strings.TrimSpace(grading.Feedback)
+	grading.Score = max(0, min(grading.Score, IMAGE_ANSWER_MAX_SCORE))
+	grading.MaxScore = IMAGE_ANSWER_MAX_SCORE
+
+	logger.Info("Image grading completed", "duration_ms", time.Since(startTime).Milliseconds(), "question_id", question.ID, "score", grading.Score, "max_score", grading.MaxScore)
+	return &grading, nil
+}
+
+// SummarizeNote asks the LLM for a one or two sentence summary of a note.
+// Long notes are summarized from the part that fits the context window.
+func (s *QuizService) SummarizeNote(ctx context.Context, note *models.Note) (string, error) {
+	logger := LoggerFromContext(ctx)
+	logger.Info("Summarizing note", "note_id", note.ID, "chars", len(note.Content))
+	startTime := time.Now()
+
+	counter := s.tokenCounter("")
+	content := note.Content
+	budget := counter.Budget(fmt.Sprintf(NOTE_SUMMARY_PROMPT_TEMPLATE, ""))
+	if budget < MIN_NOTES_TOKEN_BUDGET {
+		return "", fmt.Errorf("prompt leaves no room for the note in the %d token context window of %s", counter.ContextWindow(), counter.Model())
+	}
+	if chunks := counter.Chunk(content, budget); len(chunks) > 1 {
+		content = chunks[0]
+	}
+	if note.Title != "" {
+		content = note.Title + "\n\n" + content
+	}
+
+	prompt := fmt.Sprintf(NOTE_SUMMARY_PROMPT_TEMPLATE, content)
+	if instruction := languageInstruction([]*models.Note{note}, "the summary"); instruction != "" {
+		prompt += "\n\n" + instruction
+	}
+	ctx, cancel := s.withLLMTimeout(ctx)
+	defer cancel()
+	completion, err := generateFromPrompt(ctx, s.llmClient, prompt, llms.WithTemperature(0.2))
+	if err != nil {
+		logger.Error("Note summary LLM call failed", "duration_ms", time.Since(startTime).Milliseconds(), "error", err)
+		return "", fmt.Errorf("note summary failed: %w", err)
+	}
+
+	summary := strings.TrimSpace(completion)
+	if summary == "" {
+		return "", fmt.Errorf("note summary response was empty")
+	}
+
+	logger.Info("Note summary completed", "duration_ms", time.Since(startTime).Milliseconds(), "note_id", note.ID)
+	return summary, nil
+}
+
+// DetectNoteLanguage asks the LLM which language a note is written in, for
+// notes too short or mixed for DetectLanguage to tell. Only the start of
+// the note is sent.
+func (s *QuizService) DetectNoteLanguage(ctx context.Context, note *models.Note) (string, error) {
+	logger := LoggerFromContext(ctx)
+	startTime := time.Now()
+
+	counter := s.tokenCounter("")
+	content := note.Content
+	if chunks := counter.Chunk(content, MIN_NOTES_TOKEN_BUDGET); len(chunks) > 1 {
+		content = chunks[0]
+	}
+	if note.Title != "" {
+		content = note.Title + "\n\n" + content
+	}
+
+	prompt := fmt.Sprintf(LANGUAGE_DETECTION_PROMPT_TEMPLATE, content)
+	ctx, cancel := s.withLLMTimeout(ctx)
+	defer cancel()
+	completion, err := generateFromPrompt(ctx, s.llmClient, prompt, llms.WithTemperature(0))
+	if err != nil {
+		logger.Error("Note language LLM call failed", "duration_ms", time.Since(startTime).Milliseconds(), "error", err)
+		return "", fmt.Errorf("note language detection failed: %w", err)
+	}
+
+	language := normalizeLanguage(strings.Trim(strings.TrimSpace(completion), `."'`))
+	if language == "" {
+		return "", fmt.Errorf("note language response %q is not a language code", completion)
+	}
+
+	logger.Info("Note language detected", "duration_ms", time.Since(startTime).Milliseconds(), "note_id", note.ID, "language", language)
+	return language, nil
+}
+
+// FlashcardPair is a question/answer pair extracted from a note
+type FlashcardPair struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+// GenerateFlashcardPairs asks the LLM to extract up to count question/answer
+// pairs from a note, dropping incomplete pairs from the response.
+func (s *QuizService) GenerateFlashcardPairs(ctx context.Context, note *models.Note, count int) ([]FlashcardPair, error) {
+	logger := LoggerFromContext(ctx)
+	logger.Info("Generating flashcards from note", "count", count, "note_id", note.ID, "chars", len(note.Content))
+	startTime := time.Now()
+
+	terminology := ""
+	if guidance := terminologyGuidance(s.loadTerminology(ctx, note.UserID, []*models.Note{note})); guidance != "" {
+		terminology = "\n\nCourse terminology:\n" + guidance
+	}
+	if instruction := languageInstruction([]*models.Note{note}, "the questions and answers"); instruction != "" {
+		terminology += "\n\n" + instruction
+	}
+
+	// Long notes are cut to what fits the context window
+	counter := s.tokenCounter("")
+	content := note.Content
+	budget := counter.Budget(fmt.Sprintf(FLASHCARD_PROMPT_TEMPLATE, count, "") + terminology)
+	if budget < MIN_NOTES_TOKEN_BUDGET {
+		return nil, fmt.Errorf("prompt leaves no room for the note in the %d token context window of %s", counter.ContextWindow(), counter.Model())
+	}
+	if chunks := counter.Chunk(content, budget); len(chunks) > 1 {
+		content = chunks[0]
+		logger.Info("Note exceeds the token budget, using the first chunk", "note_id", note.ID, "budget", budget, "model", counter.Model(), "chunks", len(chunks))
+	}
+
+	if note.Title != "" {
+		content = note.Title + "\n\n" + content
+	}
+
+	prompt := fmt.Sprintf(FLASHCARD_PROMPT_TEMPLATE, count, content) + terminology
+	ctx, cancel := s.withLLMTimeout(ctx)
+	defer cancel()
+	var llmResponse struct {
+		Flashcards []FlashcardPair `json:"flashcards"`
+	}
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, prompt)}
+	err := s.generateStructured(ctx, s.llmClient, messages, flashcardsSchema, &llmResponse, nil, llms.WithTemperature(0.3))
+	if err != nil {
+		if errors.Is(err, errInvalidStructuredOutput) {
+			logger.Error("Failed to parse flashcard generation response", "error", err)
+			return nil, models.Upstream("failed to parse flashcard generation response: %w", err)
+		}
+		logger.Error("Flashcard generation LLM call failed", "duration_ms", time.Since(startTime).Milliseconds(), "error", err)
+		return nil, fmt.Errorf("flashcard generation failed: %w", err)
+	}
+
+	logger.Info("Flashcard generation LLM call completed", "duration_ms", time.Since(startTime).Milliseconds(), "flashcards", len(llmResponse.Flashcards))
+
+	pairs := make([]FlashcardPair, 0, count)
+	for _, pair := range llmResponse.Flashcards {
+		pair.Question = strings.TrimSpace(pair.Question)
+		pair.Answer = strings.TrimSpace(pair.Answer)
+		if pair.Question == "" || pair.Answer == "" {
+			continue
+		}
+		if violation := s.contentFilter.Check(ctx, pair.Question, pair.Answer); violation != "" {
+			logger.Info("Dropping flashcard blocked by content filter", "violation", violation)
+			continue
+		}
+		pairs = append(pairs, pair)
+		if len(pairs) == count {
+			break
+		}
+	}
+
+	if len(pairs) == 0 {
+		logger.Error("Flashcard generation response contained no valid pairs")
+		return nil, fmt.Errorf("no valid flashcards in response")
+	}
+
+	logger.Info("Extracted flashcards from note", "count", len(pairs), "note_id", note.ID)
+	return pairs, nil
+}
+
+// FlashcardRewrite is a flashcard rewritten to be easier to remember
+type FlashcardRewrite struct {
+	Front string `json:"front"`
+	Back  string `json:"back"`
+	Hint  string `json:"hint"`
+}
+
+// ReformulateFlashcard asks the LLM to rewrite a card the learner keeps
+// forgetting, in language when it is known. A rewrite blocked by the
+// content filter is an error, as there is nothing else to fall back on.
+func (s *QuizService) ReformulateFlashcard(ctx context.Context, flashcard *models.Flashcard, language string) (*FlashcardRewrite, error) {
+	logger := LoggerFromContext(ctx)
+	logger.Info("Reformulating flashcard", "flashcard_id", flashcard.ID)
+	startTime := time.Now()
+
+	prompt := fmt.Sprintf(REFORMULATE_PROMPT_TEMPLATE, flashcard.Front, flashcard.Back, flashcard.Hint)
+	if language != "" && language != "en" {
+		prompt += fmt.Sprintf("\n\nWrite the card in %s, the language of the original.", LanguageName(language))
+	}
+	if guidance := s.contentFilter.PromptGuidance(ctx); guidance != "" {
+		prompt += "\n\n" + guidance
+	}
+	ctx, cancel := s.withLLMTimeout(ctx)
+	defer cancel()
+	var rewrite FlashcardRewrite
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, prompt)}
+	validate := func() error {
+		if strings.TrimSpace(rewrite.Front) == "" {
+			return fmt.Errorf("front cannot be empty")
+		}
+		return nil
+	}
+	err := s.generateStructured(ctx, s.llmClient, messages, flashcardRewriteSchema, &rewrite, validate, llms.WithTemperature(0.4))
+	if err != nil {
+		if errors.Is(err, errInvalidStructuredOutput) {
+			logger.Error("Failed to parse flashcard rewrite response", "error", err)
+			return nil, models.Upstream("failed to parse flashcard rewrite response: %w", err)
+		}
+		logger.Error("Flashcard rewrite LLM call failed", "duration_ms", time.Since(startTime).Milliseconds(), "error", err)
+		return nil, fmt.Errorf("flashcard rewrite failed: %w", err)
+	}
+
+	rewrite.Front = strings.TrimSpace(rewrite.Front)
+	rewrite.Back = strings.TrimSpace(rewrite.Back)
+	rewrite.Hint = strings.TrimSpace(rewrite.Hint)
+	if violation := s.contentFilter.Check(ctx, rewrite.Front, rewrite.Back, rewrite.Hint); violation != "" {
+		logger.Info("Flashcard rewrite blocked by content filter", "violation", violation)
+		return nil, fmt.Errorf("flashcard rewrite was blocked by the content filter")
+	}
+
+	logger.Info("Flashcard reformulated", "duration_ms", time.Since(startTime).Milliseconds(), "flashcard_id", flashcard.ID)
+	return &rewrite, nil
+}
+
+// Mnemonic is one memory aid for a flashcard
+type Mnemonic struct {
+	Technique string `json:"technique"`
+	Text      string `json:"text"`
+}
+
+var validMnemonicTechniques = []string{"acronym", "imagery", "memory_palace"}
+
+// GenerateMnemonics asks the LLM for memory aids tailored to a flashcard, in
+// language when it is known. Aids blocked by the content filter are left
+// out, and an error is returned when none are left.
+func (s *QuizService) GenerateMnemonics(ctx context.Context, flashcard *models.Flashcard, language string) ([]Mnemonic, error) {
+	logger := LoggerFromContext(ctx)
+	logger.Info("Generating mnemonics", "flashcard_id", flashcard.ID)
+	startTime := time.Now()
+
+	prompt := fmt.Sprintf(MNEMONIC_PROMPT_TEMPLATE, flashcard.Front, flashcard.Back)
+	if language != "" && language != "en" {
+		prompt += fmt.Sprintf("\n\nWrite the memory aids in %s, the language of the card.", LanguageName(language))
+	}
+	if guidance := s.contentFilter.PromptGuidance(ctx); guidance != "" {
+		prompt += "\n\n" + guidance
+	}
+	ctx, cancel := s.withLLMTimeout(ctx)
+	defer cancel()
+	var response struct {
+		Mnemonics []Mnemonic `json:"mnemonics"`
+	}
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, prompt)}
+	validate := func() error {
+		if len(response.Mnemonics) == 0 {
+			return fmt.Errorf("no mnemonics in response")
+		}
+		return nil
+	}
+	err := s.generateStructured(ctx, s.llmClient, messages, mnemonicSchema, &response, validate, llms.WithTemperature(0.8))
+	if err != nil {
+		if errors.Is(err, errInvalidStructuredOutput) {
+			logger.Error("Failed to parse mnemonic response", "error", err)
+			return nil, models.Upstream("failed to parse mnemonic response: %w", err)
+		}
+		logger.Error("Mnemonic LLM call failed", "duration_ms", time.Since(startTime).Milliseconds(), "error", err)
+		return nil, fmt.Errorf("mnemonic generation failed: %w", err)
+	}
+
+	var mnemonics []Mnemonic
+	for _, mnemonic := range response.Mnemonics {
+		mnemonic.Text = strings.TrimSpace(mnemonic.Text)
+		if mnemonic.Text == "" || !containsValue(validMnemonicTechniques, mnemonic.Technique) {
+			continue
+		}
+		if violation := s.contentFilter.Check(ctx, mnemonic.Text); violation != "" {
+			logger.Info("Mnemonic blocked by content filter", "violation", violation)
+			continue
+		}
+		mnemonics = append(mnemonics, mnemonic)
+	}
+	if len(mnemonics) == 0 {
+		return nil, fmt.Errorf("no usable mnemonics were generated")
+	}
+
+	logger.Info("Mnemonics generated", "duration_ms", time.Since(startTime).Milliseconds(), "flashcard_id", flashcard.ID, "count", len(mnemonics))
+	return mnemonics, nil
+}
+
+// VocabularyDetails is the pronunciation and example sentences generated for
+// a vocabulary card
+type VocabularyDetails struct {
+	IPA           string                   `json:"ipa"`
+	Pronunciation string                   `json:"pronunciation"`
+	Examples      []models.ExampleSentence `json:"examples"`
+}
+
+// GenerateVocabularyDetails asks the LLM for the IPA, a readable
+// pronunciation and count example sentences at the given CEFR level.
+// Sentences blocked by the content filter are dropped.
+func (s *QuizService) GenerateVocabularyDetails(ctx context.Context, term, language, meaning, cefrLevel string, count int) (*VocabularyDetails, error) {
+	logger := LoggerFromContext(ctx)
+	logger.Info("Generating vocabulary details", "term", term, "language", language, "cefr_level", cefrLevel, "examples", count)
+	startTime := time.Now()
+
+	if language == "" {
+		language = "target-language"
+	}
+
+	prompt := fmt.Sprintf(VOCABULARY_PROMPT_TEMPLATE, language, count, cefrLevel, term, meaning)
+	if guidance := s.contentFilter.PromptGuidance(ctx); guidance != "" {
+		prompt += "\n\n" + guidance
+	}
+	ctx, cancel := s.withLLMTimeout(ctx)
+	defer cancel()
+	var details VocabularyDetails
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, prompt)}
+	err := s.generateStructured(ctx, s.llmClient, messages, vocabularySchema, &details, nil, llms.WithTemperature(0.4))
+	if err != nil {
+		if errors.Is(err, errInvalidStructuredOutput) {
+			logger.Error("Failed to parse vocabulary response", "error", err)
+			return nil, models.Upstream("failed to parse vocabulary response: %w", err)
+		}
+		logger.Error("Vocabulary LLM call failed", "duration_ms", time.Since(startTime).Milliseconds(), "error", err)
+		return nil, fmt.Errorf("vocabulary generation failed: %w", err)
+	}
+
+	details.IPA = strings.TrimSpace(details.IPA)
+	details.Pronunciation = strings.TrimSpace(details.Pronunciation)
+
+	examples := make([]models.ExampleSentence, 0, count)
+	for _, example := range details.Examples {
+		example.Text = strings.TrimSpace(example.Text)
+		example.Translation = strings.TrimSpace(example.Translation)
+		if example.Text == "" {
+			continue
+		}
+		if violation := s.contentFilter.Check(ctx, example.Text, example.Translation); violation != "" {
+			logger.Info("Dropping example sentence blocked by content filter", "violation", violation)
+			continue
+		}
+		examples = append(examples, example)
+		if len(examples) == count {
+			break
+		}
+	}
+	details.Examples = examples
+
+	logger.Info("Vocabulary details generated", "duration_ms", time.Since(startTime).Milliseconds(), "examples", len(examples))
+	return &details, nil
+}
+
+// PlainLanguageVariant asks the LLM for a simplified wording of the question
+// stem and returns the question with its accessibility metadata filled in
+func (s *QuizService) PlainLanguageVariant(ctx context.Context, userID int, question models.QuestionData) (models.QuestionData, error) {
+	logger := LoggerFromContext(ctx)
+	logger.Info("Generating plain-language variant", "question_id", question.ID)
+	startTime := time.Now()
+
+	if strings.TrimSpace(question.Text) == "" {
+		return models.QuestionData{}, models.Invalid("question text is required")
+	}
+
+	prompt := fmt.Sprintf(PLAIN_LANGUAGE_PROMPT_TEMPLATE, question.Text)
+
+	// Terms the course marks as "do not simplify" must survive the rewrite
+	var notes []*models.Note
+	for _, noteID := range question.BasedOnNotes {
+		if note, err := s.noteService.GetNoteByID(ctx, userID, noteID); err == nil {
+			notes = append(notes, note)
+		}
+	}
+	if guidance := terminologyGuidance(s.loadTerminology(ctx, userID, notes)); guidance != "" {
+		prompt += "\n\nCourse terminology:\n" + guidance
+	}
+	ctx, cancel := s.withLLMTimeout(ctx)
+	defer cancel()
+	completion, err := generateFromPrompt(ctx, s.llmClient, prompt, llms.WithTemperature(0.2))
+	if err != nil {
+		logger.Error("Plain-language LLM call failed", "duration_ms", time.Since(startTime).Milliseconds(), "error", err)
+		return models.QuestionData{}, fmt.Errorf("plain-language generation failed: %w", err)
 	}
 
-	log.Printf("[INFO] Successfully parsed LLM response into question data - ID: %s, type: %s, difficulty: %s", 
-		questionData.ID, questionData.Type, questionData.Difficulty)
-	
-	return questionData, nil
+	if question.Accessibility == nil {
+		question.Accessibility = &models.AccessibilityMetadata{}
+	}
+	if len(question.Accessibility.OptionLabels) == 0 {
+		question.Accessibility.OptionLabels = buildOptionLabels(question.Type, question.Options)
+	}
+	question.Accessibility.PlainLanguageText = strings.TrimSpace(completion)
+
+	logger.Info("Plain-language variant generated", "duration_ms", time.Since(startTime).Milliseconds(), "question_id", question.ID)
+	return question, nil
+}
+
+// Build screen-reader labels such as "Option A: Paris" from options like
+// "A) Paris"
+func buildOptionLabels(questionType string, options []string) []string {
+	if len(options) == 0 {
+		if questionType == "true-false" {
+			return []string{"Option: True", "Option: False"}
+		}
+		return nil
+	}
+
+	labels := make([]string, len(options))
+	for i, option := range options {
+		letter, text, found := strings.Cut(option, ")")
+		letter = strings.TrimSpace(letter)
+		if !found || len(letter) != 1 {
+			letter = string(rune('A' + i))
+			text = option
+		}
+		labels[i] = fmt.Sprintf("Option %s of %d: %s", letter, len(options), strings.TrimSpace(text))
+	}
+	return labels
+}
+
+// ExplainQuestion asks the LLM for a deeper explanation of a question,
+// taking the student's answer into account when one was given.
+func (s *QuizService) ExplainQuestion(ctx context.Context, question models.QuestionData, answer string) (string, error) {
+	logger := LoggerFromContext(ctx)
+	logger.Info("Generating deeper explanation", "question_id", question.ID)
+	startTime := time.Now()
+
+	if answer == "" {
+		answer = "(no answer given)"
+	}
+
+	prompt := fmt.Sprintf(EXPLANATION_PROMPT_TEMPLATE,
+		question.Text,
+		strings.Join(question.Options, "; "),
+		question.CorrectAnswer,
+		question.Explanation,
+		answer,
+	)
+	if guidance := s.contentFilter.PromptGuidance(ctx); guidance != "" {
+		prompt += "\n\n" + guidance
+	}
+
+	ctx, cancel := s.withLLMTimeout(ctx)
+	defer cancel()
+	for attempt := 0; ; attempt++ {
+		completion, err := generateFromPrompt(ctx, s.llmClient, prompt, llms.WithTemperature(0.3))
+		if err != nil {
+			logger.Error("Explanation LLM call failed", "duration_ms", time.Since(startTime).Milliseconds(), "error", err)
+			return "", fmt.Errorf("explanation generation failed: %w", err)
+		}
+
+		violation := s.contentFilter.Check(ctx, completion)
+		if violation == "" {
+			logger.Info("Explanation generated", "duration_ms", time.Since(startTime).Milliseconds(), "chars", len(completion))
+			return strings.TrimSpace(completion), nil
+		}
+		if attempt >= MAX_FILTER_REGENERATIONS {
+			logger.Error("Explanation still blocked by content filter", "question_id", question.ID, "attempts", attempt+1, "violation", violation)
+			return "", fmt.Errorf("explanation was blocked by the content filter: %s", violation)
+		}
+		logger.Info("Explanation blocked by content filter, regenerating", "violation", violation)
+	}
+}
+
+// questionText collects the user-visible text of a question for filtering
+func questionText(question models.QuestionData) []string {
+	texts := []string{question.Text, question.Explanation, question.CorrectAnswer}
+	return append(texts, question.Options...)
+}
+
+// RegenerateQuestion produces a fresh question from the same notes, type and
+// difficulty as the given one.
+func (s *QuizService) RegenerateQuestion(ctx context.Context, userID int, question models.QuestionData) (models.QuestionData, error) {
+	logger := LoggerFromContext(ctx)
+	logger.Info("Regenerating question", "question_id", question.ID, "note_ids", question.BasedOnNotes)
+
+	notesContent, terminology, _, err := s.getNotesContent(ctx, userID, question.BasedOnNotes, 0)
+	if err != nil {
+		logger.Error("Failed to retrieve notes content", "error", err)
+		return models.QuestionData{}, fmt.Errorf("failed to retrieve notes: %w", err)
+	}
+
+	difficulty := question.Difficulty
+	if !containsValue(validDifficulties, difficulty) {
+		difficulty = "medium"
+	}
+	questionType := question.Type
+	if !containsValue(validQuestionTypes, questionType) {
+		questionType = "multiple-choice"
+	}
+
+	// The replacement must not repeat the question it replaces
+	memory := &conversationMemory{asked: []askedQuestion{{question: question}}, recent: s.dedup.Load(ctx, userID, question.BasedOnNotes)}
+	chunks, _, err := s.fitNotesToContext(ctx, notesContent, terminology, difficulty, questionType, 1, memory)
+	if err != nil {
+		return models.QuestionData{}, err
+	}
+
+	message, err := s.generateQuizWithLLM(ctx, chunks[0], difficulty, questionType, question.BasedOnNotes, memory)
+	if err != nil {
+		return models.QuestionData{}, err
+	}
+
+	return *message.Question, nil
+}
+
+// GenerateQuestions generates count questions of one type and difficulty
+// from the given notes, for callers that need the questions rather than chat
+// messages. An empty difficulty is calibrated from the user's recent answers
+// and an empty question type defaults to multiple-choice.
+func (s *QuizService) GenerateQuestions(ctx context.Context, userID int, noteIds []int, count int, difficulty, questionType string) ([]models.QuestionData, error) {
+	logger := LoggerFromContext(ctx)
+	logger.Info("Generating questions", "note_ids", noteIds, "count", count)
+
+	if difficulty == "" {
+		difficulty = s.calibrateDifficulty(ctx, userID, noteIds).Difficulty
+	}
+	if questionType == "" {
+		questionType = "multiple-choice"
+	}
+
+	notesContent, terminology, _, err := s.getNotesContent(ctx, userID, noteIds, 0)
+	if err != nil {
+		logger.Error("Failed to retrieve notes content", "error", err)
+		return nil, fmt.Errorf("failed to retrieve notes: %w", err)
+	}
+
+	chunks, _, err := s.fitNotesToContext(ctx, notesContent, terminology, difficulty, questionType, count, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	memory := &conversationMemory{recent: s.dedup.Load(ctx, userID, noteIds)}
+	messages, err := s.generateQuestions(ctx, count, chunks, difficulty, questionType, noteIds, memory)
+	if err != nil {
+		return nil, models.Upstream("failed to generate questions with LLM: %w", err)
+	}
+
+	questions := make([]models.QuestionData, 0, len(messages))
+	for _, message := range messages {
+		if message.Question != nil {
+			questions = append(questions, *message.Question)
+		}
+	}
+	if len(questions) == 0 {
+		return nil, models.Upstream("failed to generate questions with LLM: no questions returned")
+	}
+	return questions, nil
 }
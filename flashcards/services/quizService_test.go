@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+// newTestQuizService builds a QuizService on in-memory repositories that
+// generates with fake, metering its calls against the user's quota
+func newTestQuizService(t *testing.T, fake *FakeLLMClient) (*QuizService, *NoteService, *QuotaService) {
+	t.Helper()
+	ctx := context.Background()
+	store := db.NewMemoryStore(func() time.Time { return time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC) })
+
+	organizations := db.NewMemoryOrganizationRepository(store)
+	quotas := NewQuotaService(organizations, NewSpendService(db.NewMemorySpendRepository(store), nil, SpendConfig{}))
+	deckService := NewDeckService(db.NewMemoryDeckRepository(store), nil)
+	noteService := NewNoteService(db.NewMemoryNoteRepository(store), deckService, quotas, nil)
+	t.Cleanup(func() { noteService.Stop(ctx) })
+
+	prompts, err := NewPromptRegistry(ctx, db.NewMemoryPromptTemplateRepository(store), "")
+	if err != nil {
+		t.Fatalf("NewPromptRegistry: %v", err)
+	}
+
+	quizService, err := NewQuizService(
+		noteService,
+		deckService,
+		NewRoutingService(db.NewMemoryRoutingRuleRepository(store), organizations),
+		NewContentFilterService(db.NewMemoryContentFilterRepository(store)),
+		prompts,
+		nil,
+		db.NewMemoryQuizSessionRepository(store),
+		ProviderConfig{Provider: PROVIDER_OPENAI, Model: "gpt-4o-mini", Quotas: quotas, Client: fake},
+	)
+	if err != nil {
+		t.Fatalf("NewQuizService: %v", err)
+	}
+	return quizService, noteService, quotas
+}
+
+func TestGenerateQuizWithFakeLLM(t *testing.T) {
+	fake := NewFakeLLMClient(
+		"not json",
+		`{"question": "What do mitochondria produce?", "type": "multiple-choice", "options": ["ATP", "DNA", "Starch"], "correctAnswer": "ATP", "explanation": "Mitochondria make ATP.", "difficulty": "easy"}`,
+	)
+	quizService, noteService, quotas := newTestQuizService(t, fake)
+	ctx := ContextWithUser(context.Background(), 1)
+
+	note, err := noteService.CreateNote(ctx, 1, &models.CreateNoteRequest{Title: "Cells", Content: "Mitochondria produce ATP for the cell."})
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+
+	conversation := []models.Message{{Role: "user", Content: "Quiz me on my notes"}}
+	result, err := quizService.GenerateQuiz(ctx, 1, conversation, []int{note.ID}, QuizOptions{Difficulty: "easy", QuestionType: "multiple-choice"})
+	if err != nil {
+		t.Fatalf("GenerateQuiz: %v", err)
+	}
+
+	if result.Source != models.QuestionSourceGenerated || len(result.Messages) != 1 {
+		t.Fatalf("GenerateQuiz = %d messages from %q, want 1 generated", len(result.Messages), result.Source)
+	}
+	question := result.Messages[0].Question
+	if question == nil || question.Text != "What do mitochondria produce?" || question.CorrectAnswer != "ATP" || len(question.Options) != 3 {
+		t.Fatalf("question = %+v", question)
+	}
+	if result.Difficulty.Difficulty != "easy" || result.Difficulty.Source != models.DifficultySourceOption {
+		t.Errorf("difficulty = %+v, want easy from the options", result.Difficulty)
+	}
+
+	// The malformed first reply is sent back for a correction
+	calls := fake.Calls()
+	if len(calls) != 2 || fake.Pending() != 0 {
+		t.Fatalf("LLM calls = %d with %d pending, want 2 with none pending", len(calls), fake.Pending())
+	}
+	if !strings.Contains(calls[0].Prompt, "Mitochondria produce ATP") {
+		t.Errorf("prompt does not include the note: %q", calls[0].Prompt)
+	}
+	if !strings.Contains(calls[1].Prompt, "not json") {
+		t.Errorf("correction prompt does not include the invalid reply: %q", calls[1].Prompt)
+	}
+
+	quota, err := quotas.GetQuota(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetQuota: %v", err)
+	}
+	if quota.Usage.LLMTokens == 0 {
+		t.Error("LLM tokens were not metered")
+	}
+}
+
+func TestGenerateQuizReportsLLMFailure(t *testing.T) {
+	fake := NewFakeLLMClient().Fail(errors.New("provider down"))
+	quizService, noteService, _ := newTestQuizService(t, fake)
+	ctx := context.Background()
+
+	note, err := noteService.CreateNote(ctx, 1, &models.CreateNoteRequest{Title: "Stars", Content: "Stars fuse hydrogen into helium."})
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+
+	conversation := []models.Message{{Role: "user", Content: "Ask me a true or false question"}}
+	_, err = quizService.GenerateQuiz(ctx, 1, conversation, []int{note.ID}, QuizOptions{Difficulty: "medium", QuestionType: "true-false"})
+	if !errors.Is(err, models.ErrUpstream) {
+		t.Fatalf("GenerateQuiz = %v, want an upstream error", err)
+	}
+}
@@ -0,0 +1,847 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	MAX_SESSION_QUESTIONS = 50
+	// Questions generated when a session is started from notes or decks
+	// without a count
+	DEFAULT_SESSION_QUESTIONS = 10
+
+	// Queued session requests are generated in batches once the LLM
+	// provider is back, each given a lease while it runs
+	QUEUED_SESSION_BATCH_SIZE    = 10
+	QUEUED_SESSION_LEASE         = 5 * time.Minute
+	QUEUED_SESSION_MAX_ATTEMPTS  = 3
+	QUEUED_SESSION_POLL_INTERVAL = 30 * time.Second
+)
+
+var validQuestionStatuses = []string{
+	models.QuestionStatusUnanswered,
+	models.QuestionStatusAnswered,
+	models.QuestionStatusSkipped,
+}
+
+type QuizSessionService struct {
+	repo              db.QuizSessionRepository
+	quizService       *QuizService
+	noteService       *NoteService
+	deckService       *DeckService
+	attachmentService *AttachmentService
+	mailer            *Mailer
+	transcriber       *TranscriptionClient
+	events            EventPublisher
+}
+
+func NewQuizSessionService(repo db.QuizSessionRepository, quizService *QuizService, noteService *NoteService, deckService *DeckService, attachmentService *AttachmentService, mailer *Mailer, transcriber *TranscriptionClient) *QuizSessionService {
+	return &QuizSessionService{
+		repo:              repo,
+		quizService:       quizService,
+		noteService:       noteService,
+		deckService:       deckService,
+		attachmentService: attachmentService,
+		mailer:            mailer,
+		transcriber:       transcriber,
+	}
+}
+
+// PublishEventsTo sets where quiz.completed events are published, such as
+// to the user's webhooks
+func (s *QuizSessionService) PublishEventsTo(events EventPublisher) {
+	s.events = events
+}
+
+// CreateSession starts a session over the given questions or, when none are
+// given, over questions generated from the selected notes and decks. While
+// the LLM provider is unavailable generated sessions are served from the
+// question bank; with nothing there the error wraps ErrLLMUnavailable and
+// the request can be queued with QueueSession.
+func (s *QuizSessionService) CreateSession(ctx context.Context, userID int, req *models.CreateQuizSessionRequest) (*models.QuizSession, error) {
+	source := ""
+	if req != nil && len(req.Questions) == 0 {
+		if err := s.validateGenerateRequest(req); err != nil {
+			return nil, err
+		}
+
+		questions, questionSource, err := s.generateSessionQuestions(ctx, userID, req)
+		if err != nil {
+			return nil, err
+		}
+		req.Questions = questions
+		source = questionSource
+	}
+
+	if err := s.validateCreateRequest(req); err != nil {
+		return nil, err
+	}
+
+	session := &models.QuizSession{
+		UserID:          userID,
+		Status:          models.SessionStatusInProgress,
+		CurrentPosition: 0,
+		Questions:       make([]models.SessionQuestion, len(req.Questions)),
+		QuestionSource:  source,
+	}
+	for i, question := range req.Questions {
+		session.Questions[i] = models.SessionQuestion{
+			Position: i,
+			Question: question,
+			Status:   models.QuestionStatusUnanswered,
+		}
+	}
+
+	if err := s.repo.CreateSession(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create quiz session: %w", err)
+	}
+
+	return session, nil
+}
+
+// generateSessionQuestions generates questions from the selected notes and
+// the notes in the selected decks and their subdecks. With nothing selected
+// every note is used. It also returns where the questions came from.
+func (s *QuizSessionService) generateSessionQuestions(ctx context.Context, userID int, req *models.CreateQuizSessionRequest) ([]models.QuestionData, string, error) {
+	noteIDs := append([]int{}, req.NoteIDs...)
+	if len(req.DeckIDs) > 0 {
+		deckNoteIDs, err := s.deckNoteIDs(ctx, userID, req.DeckIDs)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(deckNoteIDs) == 0 && len(noteIDs) == 0 {
+			return nil, "", models.Invalid("no notes found in the selected decks")
+		}
+		seen := make(map[int]bool)
+		for _, id := range noteIDs {
+			seen[id] = true
+		}
+		for _, id := range deckNoteIDs {
+			if !seen[id] {
+				seen[id] = true
+				noteIDs = append(noteIDs, id)
+			}
+		}
+	}
+
+	count := req.Count
+	if count == 0 {
+		count = DEFAULT_SESSION_QUESTIONS
+	}
+
+	questions, err := s.quizService.GenerateQuestions(ctx, userID, noteIDs, count, req.Difficulty, req.QuestionType)
+	if err == nil {
+		return questions, models.QuestionSourceGenerated, nil
+	}
+	if !errors.Is(err, ErrLLMUnavailable) {
+		return nil, "", err
+	}
+
+	banked, bankErr := s.quizService.BankQuestions(ctx, userID, noteIDs, count, req.Difficulty, req.QuestionType)
+	if bankErr != nil {
+		LoggerFromContext(ctx).Error("Failed to load questions from question bank", "error", bankErr)
+		return nil, "", err
+	}
+	if len(banked) == 0 {
+		return nil, "", err
+	}
+	LoggerFromContext(ctx).Warn("Started quiz session from question bank while LLM is unavailable", "questions", len(banked), "requested", count)
+	return banked, models.QuestionSourceBank, nil
+}
+
+// QueueSession stores a request for a generated session to be created once
+// the LLM provider is available again. Poll it with GetQueuedSession.
+func (s *QuizSessionService) QueueSession(ctx context.Context, userID int, req *models.CreateQuizSessionRequest) (*models.QueuedQuizSession, error) {
+	if req == nil || len(req.Questions) > 0 {
+		return nil, models.Invalid("only sessions generated from notes or decks can be queued")
+	}
+	if err := s.validateGenerateRequest(req); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	queued := &models.QueuedQuizSession{
+		UserID:        userID,
+		Request:       *req,
+		Status:        models.QueuedSessionStatusQueued,
+		NextAttemptAt: &now,
+	}
+	if err := s.repo.CreateQueuedSession(ctx, queued); err != nil {
+		return nil, err
+	}
+
+	LoggerFromContext(ctx).Info("Queued quiz session until the LLM is available", "queued_session_id", queued.ID)
+	return queued, nil
+}
+
+func (s *QuizSessionService) GetQueuedSession(ctx context.Context, userID, id int) (*models.QueuedQuizSession, error) {
+	return s.repo.GetQueuedSession(ctx, userID, id)
+}
+
+// ProcessQueuedSessions creates the sessions queued while the LLM provider
+// was unavailable. It runs on a schedule and does nothing while the
+// provider is still down.
+func (s *QuizSessionService) ProcessQueuedSessions(ctx context.Context) error {
+	if !s.quizService.LLMStatus().Available {
+		return nil
+	}
+
+	queued, err := s.repo.ClaimDueQueuedSessions(ctx, QUEUED_SESSION_BATCH_SIZE, QUEUED_SESSION_LEASE)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range queued {
+		if ctx.Err() != nil {
+			// The rest are picked up once their lease runs out
+			return nil
+		}
+		if !s.processQueuedSession(ctx, item) {
+			// The provider went down again; the rest wait for their lease
+			return nil
+		}
+	}
+	return nil
+}
+
+// processQueuedSession returns false when the LLM provider became
+// unavailable, leaving the request queued without counting an attempt
+func (s *QuizSessionService) processQueuedSession(ctx context.Context, queued *models.QueuedQuizSession) bool {
+	logger := LoggerFromContext(ctx).With("queued_session_id", queued.ID, "user_id", queued.UserID)
+
+	req := queued.Request
+	session, err := s.CreateSession(ContextWithLogger(ContextWithUser(ctx, queued.UserID), logger), queued.UserID, &req)
+	if err == nil {
+		if err := s.repo.CompleteQueuedSession(ctx, queued.ID, session.ID); err != nil {
+			logger.Error("Failed to complete queued quiz session", "session_id", session.ID, "error", err)
+			return true
+		}
+		logger.Info("Created queued quiz session", "session_id", session.ID)
+		return true
+	}
+	if errors.Is(err, ErrLLMUnavailable) || ctx.Err() != nil {
+		return false
+	}
+
+	attempts := queued.Attempts + 1
+	var nextAttemptAt *time.Time
+	if attempts < QUEUED_SESSION_MAX_ATTEMPTS && !errors.Is(err, models.ErrQuotaExceeded) {
+		next := time.Now().Add(deadLetterBackoff(attempts))
+		nextAttemptAt = &next
+	}
+	if updateErr := s.repo.FailQueuedSessionAttempt(ctx, queued.ID, err.Error(), nextAttemptAt); updateErr != nil {
+		logger.Error("Failed to update queued quiz session", "error", updateErr)
+		return true
+	}
+
+	if nextAttemptAt == nil {
+		logger.Error("Queued quiz session failed", "attempts", attempts, "error", err)
+	} else {
+		logger.Warn("Queued quiz session attempt failed", "attempts", attempts, "next_attempt_at", nextAttemptAt, "error", err)
+	}
+	return true
+}
+
+func (s *QuizSessionService) deckNoteIDs(ctx context.Context, userID int, deckIDs []int) ([]int, error) {
+	inDecks := map[int]bool{}
+	for _, deckID := range deckIDs {
+		treeIDs, err := s.deckService.GetDeckTreeIDs(ctx, userID, deckID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range treeIDs {
+			inDecks[id] = true
+		}
+	}
+
+	notes, err := s.noteService.GetAllNotes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var noteIDs []int
+	for _, note := range notes {
+		if note.DeckID != nil && inDecks[*note.DeckID] {
+			noteIDs = append(noteIDs, note.ID)
+		}
+	}
+	return noteIDs, nil
+}
+
+func (s *QuizSessionService) GetSessionByID(ctx context.Context, userID, id int) (*models.QuizSession, error) {
+	if id <= 0 {
+		return nil, models.Invalid("invalid quiz session ID: %d", id)
+	}
+
+	return s.repo.GetSessionByID(ctx, userID, id)
+}
+
+// UpdateQuestion autosaves the state of a single question and moves the
+// session's current position to it, so a resumed session reopens there.
+func (s *QuizSessionService) UpdateQuestion(ctx context.Context, userID, sessionID, position int, req *models.UpdateSessionQuestionRequest) (*models.QuizSession, error) {
+	if sessionID <= 0 {
+		return nil, models.Invalid("invalid quiz session ID: %d", sessionID)
+	}
+
+	if err := s.validateUpdateQuestionRequest(req); err != nil {
+		return nil, err
+	}
+
+	session, err := s.repo.GetSessionByID(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Status == models.SessionStatusCompleted {
+		return nil, models.Invalid("quiz session is already completed")
+	}
+
+	if position < 0 || position >= len(session.Questions) {
+		return nil, models.NotFound("question %d in quiz session with id %d not found", position, sessionID)
+	}
+
+	updates := make(map[string]any)
+
+	if req.Answer != nil {
+		answer := strings.TrimSpace(*req.Answer)
+		updates["answer"] = answer
+		updates["correct"] = gradeAnswer(session.Questions[position].Question, answer)
+		if req.Status == nil {
+			updates["status"] = models.QuestionStatusAnswered
+		}
+		// A typed answer replaces any earlier image answer
+		updates["attachment_id"] = nil
+		updates["grading"] = nil
+	}
+
+	if req.Status != nil {
+		updates["status"] = *req.Status
+	}
+
+	if req.Flagged != nil {
+		updates["flagged"] = *req.Flagged
+	}
+
+	if req.TimeSpentMs != nil {
+		updates["timeSpentMs"] = *req.TimeSpentMs
+	}
+
+	if err := s.repo.UpdateSessionQuestion(ctx, userID, sessionID, position, updates); err != nil {
+		return nil, err
+	}
+
+	if session.CurrentPosition != position {
+		if err := s.repo.UpdateSession(ctx, userID, sessionID, map[string]any{"currentPosition": position}); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.repo.GetSessionByID(ctx, userID, sessionID)
+}
+
+// SubmitImageAnswer stores a photo of a worked answer as an attachment and
+// grades it against the expected answer with the vision model. The final
+// answer read from the image becomes the question's answer.
+func (s *QuizSessionService) SubmitImageAnswer(ctx context.Context, userID, sessionID, position int, submission *models.ImageAnswerSubmission) (*models.QuizSession, error) {
+	if submission.TimeSpentMs != nil && *submission.TimeSpentMs < 0 {
+		return nil, models.Invalid("timeSpentMs cannot be negative")
+	}
+
+	session, err := s.GetSessionByID(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Status == models.SessionStatusCompleted {
+		return nil, models.Invalid("quiz session is already completed")
+	}
+
+	if position < 0 || position >= len(session.Questions) {
+		return nil, models.NotFound("question %d in quiz session with id %d not found", position, sessionID)
+	}
+	question := session.Questions[position]
+
+	if question.Question.CorrectAnswer == "" && question.Question.Explanation == "" {
+		return nil, models.Invalid("question has no expected answer to grade against")
+	}
+
+	attachment, err := s.attachmentService.CreateImage(ctx, userID, submission.Filename, submission.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	grading, err := s.quizService.GradeImageAnswer(ctx, question.Question, attachment.ContentType, attachment.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]any{
+		"answer":        grading.FinalAnswer,
+		"status":        models.QuestionStatusAnswered,
+		"attachment_id": attachment.ID,
+		"grading":       grading,
+		"correct":       grading.Correct,
+	}
+	if submission.TimeSpentMs != nil {
+		updates["timeSpentMs"] = *submission.TimeSpentMs
+	}
+
+	if err := s.repo.UpdateSessionQuestion(ctx, userID, sessionID, position, updates); err != nil {
+		return nil, err
+	}
+
+	if session.CurrentPosition != position {
+		if err := s.repo.UpdateSession(ctx, userID, sessionID, map[string]any{"currentPosition": position}); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.repo.GetSessionByID(ctx, userID, sessionID)
+}
+
+// SubmitAudioAnswer transcribes a spoken answer and grades the transcript
+// like a typed one, for answering hands-free
+func (s *QuizSessionService) SubmitAudioAnswer(ctx context.Context, userID, sessionID, position int, submission *models.AudioAnswerSubmission) (*models.QuizSession, error) {
+	if !s.transcriber.Enabled() {
+		return nil, models.Unavailable("audio answers are not configured")
+	}
+	if err := validateAudioAnswer(submission.Filename, submission.Data); err != nil {
+		return nil, err
+	}
+	if submission.TimeSpentMs != nil && *submission.TimeSpentMs < 0 {
+		return nil, models.Invalid("timeSpentMs cannot be negative")
+	}
+
+	// Checked before paying for the transcription
+	session, err := s.GetSessionByID(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status == models.SessionStatusCompleted {
+		return nil, models.Invalid("quiz session is already completed")
+	}
+	if position < 0 || position >= len(session.Questions) {
+		return nil, models.NotFound("question %d in quiz session with id %d not found", position, sessionID)
+	}
+
+	transcript, err := s.transcriber.Transcribe(ctx, submission.Filename, submission.Data)
+	if err != nil {
+		return nil, err
+	}
+	if transcript == "" {
+		return nil, models.Invalid("audio contained no recognizable speech")
+	}
+
+	return s.UpdateQuestion(ctx, userID, sessionID, position, &models.UpdateSessionQuestionRequest{
+		Answer:      &transcript,
+		TimeSpentMs: submission.TimeSpentMs,
+	})
+}
+
+// SkipQuestion marks a question as skipped so it is served again once the
+// remaining unanswered questions are done.
+func (s *QuizSessionService) SkipQuestion(ctx context.Context, userID, sessionID, position int) (*models.QuizSession, error) {
+	status := models.QuestionStatusSkipped
+	return s.UpdateQuestion(ctx, userID, sessionID, position, &models.UpdateSessionQuestionRequest{Status: &status})
+}
+
+// FlagQuestion sets or clears the review flag on a question
+func (s *QuizSessionService) FlagQuestion(ctx context.Context, userID, sessionID, position int, flagged bool) (*models.QuizSession, error) {
+	return s.UpdateQuestion(ctx, userID, sessionID, position, &models.UpdateSessionQuestionRequest{Flagged: &flagged})
+}
+
+// NextQuestion returns the next question to serve: the first unanswered
+// question after the current position (wrapping around), then any skipped
+// questions. Returns nil when every question has been answered.
+func (s *QuizSessionService) NextQuestion(ctx context.Context, userID, sessionID int) (*models.SessionQuestion, error) {
+	session, err := s.GetSessionByID(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Status == models.SessionStatusCompleted {
+		return nil, models.Invalid("quiz session is already completed")
+	}
+
+	next := findNextQuestion(session)
+	if next == nil {
+		return nil, nil
+	}
+
+	if next.Position != session.CurrentPosition {
+		if err := s.repo.UpdateSession(ctx, userID, sessionID, map[string]any{"currentPosition": next.Position}); err != nil {
+			return nil, err
+		}
+	}
+
+	return next, nil
+}
+
+// CompleteSession closes the session and returns its report
+func (s *QuizSessionService) CompleteSession(ctx context.Context, userID, sessionID int) (*models.SessionReport, error) {
+	session, err := s.GetSessionByID(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Status == models.SessionStatusCompleted {
+		return nil, models.Invalid("quiz session is already completed")
+	}
+
+	if err := s.repo.UpdateSession(ctx, userID, sessionID, map[string]any{"status": models.SessionStatusCompleted}); err != nil {
+		return nil, err
+	}
+	session.Status = models.SessionStatusCompleted
+	session.UpdatedAt = time.Now()
+
+	report := s.buildSessionReport(ctx, session)
+	if s.events != nil {
+		s.events.Publish(ctx, userID, models.WebhookEventQuizCompleted, report)
+	}
+	return report, nil
+}
+
+// RecentReports returns the reports of the user's sessions completed since
+// the given time, most recently completed first, at most limit of them
+func (s *QuizSessionService) RecentReports(ctx context.Context, userID int, since time.Time, limit int) ([]*models.SessionReport, error) {
+	sessions, err := s.repo.GetSessionsUpdatedSince(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*models.SessionReport, 0, limit)
+	for i := len(sessions) - 1; i >= 0 && len(reports) < limit; i-- {
+		if sessions[i].Status == models.SessionStatusCompleted {
+			reports = append(reports, s.buildSessionReport(ctx, sessions[i]))
+		}
+	}
+	return reports, nil
+}
+
+func (s *QuizSessionService) GetReport(ctx context.Context, userID, sessionID int) (*models.SessionReport, error) {
+	session, err := s.GetSessionByID(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.buildSessionReport(ctx, session), nil
+}
+
+// ExplainQuestion generates a deeper explanation for a question in the session
+func (s *QuizSessionService) ExplainQuestion(ctx context.Context, userID, sessionID, position int) (*models.QuestionExplanation, error) {
+	question, err := s.getSessionQuestion(ctx, userID, sessionID, position)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation, err := s.quizService.ExplainQuestion(ctx, question.Question, question.Answer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.QuestionExplanation{
+		QuestionID:  question.Question.ID,
+		Explanation: explanation,
+	}, nil
+}
+
+// RegenerateQuestion generates a new practice question covering the same
+// notes as a question in the session. The session itself is not modified.
+func (s *QuizSessionService) RegenerateQuestion(ctx context.Context, userID, sessionID, position int) (*models.QuestionData, error) {
+	question, err := s.getSessionQuestion(ctx, userID, sessionID, position)
+	if err != nil {
+		return nil, err
+	}
+
+	regenerated, err := s.quizService.RegenerateQuestion(ctx, userID, question.Question)
+	if err != nil {
+		return nil, err
+	}
+
+	return &regenerated, nil
+}
+
+func (s *QuizSessionService) getSessionQuestion(ctx context.Context, userID, sessionID, position int) (*models.SessionQuestion, error) {
+	session, err := s.GetSessionByID(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if position < 0 || position >= len(session.Questions) {
+		return nil, models.NotFound("question %d in quiz session with id %d not found", position, sessionID)
+	}
+
+	return &session.Questions[position], nil
+}
+
+func findNextQuestion(session *models.QuizSession) *models.SessionQuestion {
+	total := len(session.Questions)
+	for offset := 1; offset <= total; offset++ {
+		question := &session.Questions[(session.CurrentPosition+offset)%total]
+		if question.Status == models.QuestionStatusUnanswered {
+			return question
+		}
+	}
+
+	for i := range session.Questions {
+		if session.Questions[i].Status == models.QuestionStatusSkipped {
+			return &session.Questions[i]
+		}
+	}
+
+	return nil
+}
+
+// RenderReport renders the session report in the given export format
+func (s *QuizSessionService) RenderReport(ctx context.Context, userID, sessionID int, format string) ([]byte, error) {
+	report, err := s.GetReport(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return RenderSessionReport(report, format)
+}
+
+// EmailReport sends the session report to the learner, with the report
+// attached in the requested format
+func (s *QuizSessionService) EmailReport(ctx context.Context, userID, sessionID int, req *models.EmailReportRequest) error {
+	if req == nil || !isValidEmail(req.To) {
+		return models.Invalid("a valid recipient email address is required")
+	}
+
+	format := req.Format
+	if format == "" {
+		format = REPORT_FORMAT_PDF
+	}
+
+	if !s.mailer.Enabled() {
+		return models.Unavailable("email delivery is not configured")
+	}
+
+	report, err := s.GetReport(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	attachment, err := RenderSessionReport(report, format)
+	if err != nil {
+		return err
+	}
+
+	body, err := RenderSessionReport(report, REPORT_FORMAT_MARKDOWN)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(
+		[]string{req.To},
+		fmt.Sprintf("Your quiz session report (#%d)", sessionID),
+		string(body),
+		Attachment{
+			Filename:    ReportFilename(sessionID, format),
+			ContentType: ReportContentType(format),
+			Data:        attachment,
+		},
+	)
+}
+
+func (s *QuizSessionService) buildSessionReport(ctx context.Context, session *models.QuizSession) *models.SessionReport {
+	report := &models.SessionReport{
+		SessionID:      session.ID,
+		Status:         session.Status,
+		TotalQuestions: len(session.Questions),
+		Results:        make([]models.QuestionResult, 0, len(session.Questions)),
+		WeakTopics:     []string{},
+		Flagged:        make([]models.FlaggedItem, 0),
+		GeneratedAt:    time.Now(),
+	}
+	// A completed session is not changed again, so its last update is when
+	// it was completed
+	if session.Status == models.SessionStatusCompleted {
+		completedAt := session.UpdatedAt
+		report.CompletedAt = &completedAt
+	}
+
+	missedNotes := make(map[int]bool)
+	for _, question := range session.Questions {
+		switch question.Status {
+		case models.QuestionStatusAnswered:
+			report.Answered++
+		case models.QuestionStatusSkipped:
+			report.Skipped++
+		default:
+			report.Unanswered++
+		}
+		report.TotalTimeMs += question.TimeSpentMs
+
+		var correct *bool
+		if question.Status == models.QuestionStatusAnswered {
+			if question.Grading != nil {
+				correct = &question.Grading.Correct
+			} else {
+				correct = gradeAnswer(question.Question, question.Answer)
+			}
+		}
+		switch {
+		case correct == nil:
+			report.Ungraded++
+		case *correct:
+			report.Correct++
+		default:
+			report.Incorrect++
+		}
+		if correct == nil || !*correct {
+			for _, noteID := range question.Question.BasedOnNotes {
+				missedNotes[noteID] = true
+			}
+		}
+
+		report.Results = append(report.Results, models.QuestionResult{
+			Position:      question.Position,
+			QuestionText:  question.Question.Text,
+			Type:          question.Question.Type,
+			Difficulty:    question.Question.Difficulty,
+			Status:        question.Status,
+			Answer:        question.Answer,
+			CorrectAnswer: question.Question.CorrectAnswer,
+			Correct:       correct,
+			Explanation:   question.Question.Explanation,
+			TimeSpentMs:   question.TimeSpentMs,
+			Grading:       question.Grading,
+		})
+
+		if question.Flagged {
+			basePath := fmt.Sprintf("/quiz/sessions/%d/questions/%d", session.ID, question.Position)
+			report.Flagged = append(report.Flagged, models.FlaggedItem{
+				Position: question.Position,
+				Question: question.Question,
+				Answer:   question.Answer,
+				Actions:  []string{basePath + "/regenerate", basePath + "/explain"},
+			})
+		}
+	}
+
+	if graded := report.Correct + report.Incorrect; graded > 0 {
+		report.ScorePercent = float64(report.Correct) * 100 / float64(graded)
+	}
+
+	report.WeakTopics = s.weakTopics(ctx, session.UserID, missedNotes)
+	return report
+}
+
+// Weak topics are the tags of notes behind questions that were missed
+func (s *QuizSessionService) weakTopics(ctx context.Context, userID int, noteIDs map[int]bool) []string {
+	topics := []string{}
+	seen := make(map[string]bool)
+
+	for noteID := range noteIDs {
+		note, err := s.noteService.GetNoteByID(ctx, userID, noteID)
+		if err != nil {
+			continue
+		}
+		for _, tag := range note.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				topics = append(topics, tag)
+			}
+		}
+	}
+
+	sort.Strings(topics)
+	return topics
+}
+
+// gradeAnswer checks choice answers against the correct answer. Math
+// answers are compared by value so equivalent forms count as correct.
+// Returns nil when the question has no gradable answer key (e.g. essays).
+func gradeAnswer(question models.QuestionData, answer string) *bool {
+	if question.Type == "essay" || question.CorrectAnswer == "" {
+		return nil
+	}
+
+	correct := normalizeChoice(answer) == normalizeChoice(question.CorrectAnswer)
+	if !correct && question.Type == QUESTION_TYPE_MATH {
+		if equivalent, ok := mathAnswersEquivalent(answer, question.CorrectAnswer); ok {
+			correct = equivalent
+		}
+	}
+	return &correct
+}
+
+// Reduce "B) Some option", "b" or "True" to a comparable form
+func normalizeChoice(answer string) string {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if len(answer) >= 2 && answer[1] == ')' {
+		return answer[:1]
+	}
+	return strings.TrimSuffix(answer, ".")
+}
+
+func (s *QuizSessionService) validateCreateRequest(req *models.CreateQuizSessionRequest) error {
+	if req == nil {
+		return models.Invalid("request cannot be nil")
+	}
+
+	if len(req.Questions) == 0 {
+		return models.Invalid("at least one question is required")
+	}
+
+	if len(req.Questions) > MAX_SESSION_QUESTIONS {
+		return models.Invalid("a session cannot have more than %d questions", MAX_SESSION_QUESTIONS)
+	}
+
+	for i, question := range req.Questions {
+		if strings.TrimSpace(question.Text) == "" {
+			return models.Invalid("question %d is missing text", i)
+		}
+		if question.ImageURL != "" && strings.TrimSpace(question.ImageAltText) == "" {
+			return models.Invalid("question %d has an image without alt text", i)
+		}
+	}
+
+	return nil
+}
+
+func (s *QuizSessionService) validateGenerateRequest(req *models.CreateQuizSessionRequest) error {
+	if req.Count < 0 || req.Count > MAX_SESSION_QUESTIONS {
+		return models.Invalid("count must be between 1 and %d", MAX_SESSION_QUESTIONS)
+	}
+
+	if req.Difficulty != "" && !containsValue(validDifficulties, req.Difficulty) {
+		return models.Invalid("difficulty must be one of: %s", strings.Join(validDifficulties, ", "))
+	}
+
+	if req.QuestionType != "" && !containsValue(validQuestionTypes, req.QuestionType) {
+		return models.Invalid("question type must be one of: %s", strings.Join(validQuestionTypes, ", "))
+	}
+
+	return nil
+}
+
+func (s *QuizSessionService) validateUpdateQuestionRequest(req *models.UpdateSessionQuestionRequest) error {
+	if req == nil {
+		return models.Invalid("request cannot be nil")
+	}
+
+	if req.Answer == nil && req.Status == nil && req.Flagged == nil && req.TimeSpentMs == nil {
+		return models.Invalid("at least one field must be provided for update")
+	}
+
+	if req.Status != nil && !containsValue(validQuestionStatuses, *req.Status) {
+		return models.Invalid("status must be one of: %s", strings.Join(validQuestionStatuses, ", "))
+	}
+
+	if req.TimeSpentMs != nil && *req.TimeSpentMs < 0 {
+		return models.Invalid("timeSpentMs cannot be negative")
+	}
+
+	return nil
+}
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+const MAX_ORGANIZATION_NAME = 255
+
+// Limits of each plan tier; zero means unlimited
+var planLimits = map[string]models.Limits{
+	models.PlanFree:       {MaxNotes: 100, MaxCards: 1000, MonthlyLLMTokens: 200000},
+	models.PlanPro:        {MaxNotes: 5000, MaxCards: 50000, MonthlyLLMTokens: 5000000},
+	models.PlanEnterprise: {},
+}
+
+var validPlans = []string{models.PlanFree, models.PlanPro, models.PlanEnterprise}
+
+var validRoles = []string{models.RoleOwner, models.RoleAdmin, models.RoleMember}
+
+type QuotaService struct {
+	repo  db.OrganizationRepository
+	spend *SpendService
+}
+
+func NewQuotaService(repo db.OrganizationRepository, spend *SpendService) *QuotaService {
+	return &QuotaService{repo: repo, spend: spend}
+}
+
+// GetQuota returns the limits that apply to the user, from their
+// organization or the free plan, and current usage against them
+func (s *QuotaService) GetQuota(ctx context.Context, userID int) (*models.Quota, error) {
+	organization, err := s.repo.GetUserOrganization(ctx, userID)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		return nil, err
+	}
+
+	month := startOfMonth(time.Now())
+	usage, err := s.repo.GetUserUsage(ctx, userID, month)
+	if err != nil {
+		return nil, err
+	}
+
+	quota := &models.Quota{Plan: models.PlanFree, Limits: planLimits[models.PlanFree], Usage: *usage, Month: month}
+	if organization != nil {
+		quota.OrganizationID = &organization.ID
+		quota.Plan = organization.Plan
+		quota.Limits = effectiveLimits(organization)
+		if organization.SubscriptionStatus != nil {
+			quota.SubscriptionStatus = *organization.SubscriptionStatus
+		}
+	}
+	return quota, nil
+}
+
+// CheckNotes returns a quota error when adding notes would exceed the limit
+func (s *QuotaService) CheckNotes(ctx context.Context, userID, adding int) error {
+	quota, err := s.GetQuota(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if exceedsLimit(quota.Usage.Notes, adding, quota.Limits.MaxNotes) {
+		return models.QuotaExceeded("note quota exceeded: the %s plan allows %d notes", quota.Plan, quota.Limits.MaxNotes)
+	}
+	return nil
+}
+
+// CheckCards returns a quota error when adding cards would exceed the limit
+func (s *QuotaService) CheckCards(ctx context.Context, userID, adding int) error {
+	quota, err := s.GetQuota(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if exceedsLimit(quota.Usage.Cards, adding, quota.Limits.MaxCards) {
+		return models.QuotaExceeded("flashcard quota exceeded: the %s plan allows %d flashcards", quota.Plan, quota.Limits.MaxCards)
+	}
+	return nil
+}
+
+// CheckLLMTokens returns a quota error once this month's tokens are used up,
+// while the organization's subscription payment is overdue, or while the
+// user or organization is throttled after a spend spike
+func (s *QuotaService) CheckLLMTokens(ctx context.Context, userID int) error {
+	if err := s.spend.CheckThrottle(ctx, userID); err != nil {
+		return err
+	}
+
+	quota, err := s.GetQuota(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if quota.SubscriptionStatus == models.SubscriptionPastDue || quota.SubscriptionStatus == models.SubscriptionUnpaid {
+		return models.QuotaExceeded("LLM quota exceeded: the subscription is %s, update the payment method to continue", quota.SubscriptionStatus)
+	}
+	if quota.Limits.MonthlyLLMTokens > 0 && quota.Usage.LLMTokens >= quota.Limits.MonthlyLLMTokens {
+		return models.QuotaExceeded("monthly LLM token quota exceeded: the %s plan allows %d tokens per month", quota.Plan, quota.Limits.MonthlyLLMTokens)
+	}
+	return nil
+}
+
+// RecordLLMTokens adds tokens to the user's usage for the current month
+func (s *QuotaService) RecordLLMTokens(ctx context.Context, userID, tokens int) error {
+	if tokens <= 0 {
+		return nil
+	}
+	return s.repo.AddLLMTokens(ctx, userID, time.Now(), tokens)
+}
+
+func (s *QuotaService) CreateOrganization(ctx context.Context, req *models.CreateOrganizationRequest) (*models.OrganizationDetails, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, models.Invalid("name is required")
+	}
+	if len(name) > MAX_ORGANIZATION_NAME {
+		return nil, models.Invalid("name cannot exceed %d characters", MAX_ORGANIZATION_NAME)
+	}
+
+	plan := req.Plan
+	if plan == "" {
+		plan = models.PlanFree
+	}
+	if !containsValue(validPlans, plan) {
+		return nil, models.Invalid("plan must be one of: %s", strings.Join(validPlans, ", "))
+	}
+
+	organization := &models.Organization{Name: name, Plan: plan}
+	if err := s.repo.CreateOrganization(ctx, organization); err != nil {
+		return nil, err
+	}
+
+	return s.organizationDetails(ctx, organization)
+}
+
+func (s *QuotaService) GetOrganization(ctx context.Context, id int) (*models.OrganizationDetails, error) {
+	organization, err := s.repo.GetOrganization(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.organizationDetails(ctx, organization)
+}
+
+// UpdateQuota changes an organization's plan and per-organization limit
+// overrides
+func (s *QuotaService) UpdateQuota(ctx context.Context, id int, req *models.UpdateQuotaRequest) (*models.OrganizationDetails, error) {
+	if req.Plan != nil && !containsValue(validPlans, *req.Plan) {
+		return nil, models.Invalid("plan must be one of: %s", strings.Join(validPlans, ", "))
+	}
+	for _, limit := range []*int{req.MaxNotes, req.MaxCards, req.MonthlyLLMTokens} {
+		if limit != nil && *limit < 0 {
+			return nil, models.Invalid("limits cannot be negative")
+		}
+	}
+
+	organization, err := s.repo.GetOrganization(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ResetOverrides {
+		organization.MaxNotesOverride = nil
+		organization.MaxCardsOverride = nil
+		organization.MonthlyLLMTokensOverride = nil
+	}
+	if req.Plan != nil {
+		organization.Plan = *req.Plan
+	}
+	if req.MaxNotes != nil {
+		organization.MaxNotesOverride = req.MaxNotes
+	}
+	if req.MaxCards != nil {
+		organization.MaxCardsOverride = req.MaxCards
+	}
+	if req.MonthlyLLMTokens != nil {
+		organization.MonthlyLLMTokensOverride = req.MonthlyLLMTokens
+	}
+
+	if err := s.repo.UpdateOrganization(ctx, organization); err != nil {
+		return nil, err
+	}
+
+	return s.organizationDetails(ctx, organization)
+}
+
+// AddMember moves a user into the organization, leaving any previous one
+func (s *QuotaService) AddMember(ctx context.Context, id int, req *models.AddOrganizationMemberRequest) (*models.OrganizationDetails, error) {
+	role := req.Role
+	if role == "" {
+		role = models.RoleMember
+	}
+	if !containsValue(validRoles, role) {
+		return nil, models.Invalid("role must be one of: %s", strings.Join(validRoles, ", "))
+	}
+
+	organization, err := s.repo.GetOrganization(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetUserOrganization(ctx, req.UserID, &organization.ID, role); err != nil {
+		return nil, err
+	}
+
+	return s.organizationDetails(ctx, organization)
+}
+
+func (s *QuotaService) organizationDetails(ctx context.Context, organization *models.Organization) (*models.OrganizationDetails, error) {
+	month := startOfMonth(time.Now())
+	usage, err := s.repo.GetOrganizationUsage(ctx, organization.ID, month)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptionStatus := ""
+	if organization.SubscriptionStatus != nil {
+		subscriptionStatus = *organization.SubscriptionStatus
+	}
+
+	return &models.OrganizationDetails{
+		Organization: organization,
+		Quota: &models.Quota{
+			OrganizationID:     &organization.ID,
+			Plan:               organization.Plan,
+			SubscriptionStatus: subscriptionStatus,
+			Limits:             effectiveLimits(organization),
+			Usage:              *usage,
+			Month:              month,
+		},
+	}, nil
+}
+
+// Plan limits with the organization's overrides applied
+func effectiveLimits(organization *models.Organization) models.Limits {
+	limits := planLimits[organization.Plan]
+	if organization.MaxNotesOverride != nil {
+		limits.MaxNotes = *organization.MaxNotesOverride
+	}
+	if organization.MaxCardsOverride != nil {
+		limits.MaxCards = *organization.MaxCardsOverride
+	}
+	if organization.MonthlyLLMTokensOverride != nil {
+		limits.MonthlyLLMTokens = *organization.MonthlyLLMTokensOverride
+	}
+	return limits
+}
+
+func exceedsLimit(used, adding, limit int) bool {
+	return limit > 0 && used+adding > limit
+}
+
+func startOfMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+type usageUserContextKey struct{}
+
+// ContextWithUser attributes LLM calls made under ctx to the user, so the
+// tokens they use count against the user's quota
+func ContextWithUser(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, usageUserContextKey{}, userID)
+}
+
+func userFromContext(ctx context.Context) (int, bool) {
+	userID, ok := ctx.Value(usageUserContextKey{}).(int)
+	return userID, ok
+}
+
+// meteredModel refuses LLM calls from users over their monthly token quota
+// and adds the tokens each call uses to the caller's usage. Calls without a
+// user in the context, such as background jobs, are not metered.
+type meteredModel struct {
+	LLMClient
+	quotas *QuotaService
+	model  string
+}
+
+func (m *meteredModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		return m.LLMClient.GenerateContent(ctx, messages, options...)
+	}
+
+	if err := m.quotas.CheckLLMTokens(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	response, err := m.LLMClient.GenerateContent(ctx, messages, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.quotas.RecordLLMTokens(ctx, userID, m.tokensUsed(messages, response)); err != nil {
+		LoggerFromContext(ctx).Error("Failed to record LLM token usage", "error", err)
+	}
+	return response, nil
+}
+
+// Tokens reported by the provider, or counted from the prompt and
+// completion text when the provider does not report them
+func (m *meteredModel) tokensUsed(messages []llms.MessageContent, response *llms.ContentResponse) int {
+	if len(response.Choices) > 0 {
+		if total, ok := response.Choices[0].GenerationInfo["TotalTokens"].(int); ok {
+			return total
+		}
+	}
+	input, output := tokenCounts(m.model, messages, response)
+	return input + output
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// RATE_LIMIT_IDLE_TTL is how long an untouched bucket is kept; by then
+	// it has refilled, so dropping it changes nothing
+	RATE_LIMIT_IDLE_TTL = 10 * time.Minute
+
+	REDIS_RATE_LIMIT_KEY_PREFIX = "flashcards:ratelimit:"
+)
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// RateLimitDecision is the outcome of one request against a bucket.
+// RetryAfter is set when the request was refused; Reset is how long until
+// the bucket is full again.
+type RateLimitDecision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// RateLimiter keeps a token bucket per key, such as a user or client IP,
+// refilled at a fixed rate of tokens per second up to a burst
+type RateLimiter interface {
+	Enabled() bool
+	// Allow takes a token from the key's bucket if one is available
+	Allow(ctx context.Context, key string) RateLimitDecision
+	// Sweep drops buckets that have been idle long enough to be full
+	Sweep(ctx context.Context) error
+}
+
+// MemoryRateLimiter keeps buckets in memory, so each instance enforces its
+// own limits. Only suitable for a single instance.
+type MemoryRateLimiter struct {
+	rate  float64
+	burst int
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// NewMemoryRateLimiter returns a limiter allowing rate requests per second
+// with bursts of up to burst. A rate of zero disables it.
+func NewMemoryRateLimiter(rate float64, burst int) *MemoryRateLimiter {
+	return &MemoryRateLimiter{
+		rate:    rate,
+		burst:   max(burst, 1),
+		buckets: map[string]*tokenBucket{},
+	}
+}
+
+func (l *MemoryRateLimiter) Enabled() bool {
+	return l != nil && l.rate > 0
+}
+
+func (l *MemoryRateLimiter) Allow(ctx context.Context, key string) RateLimitDecision {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(l.burst), lastSeen: now}
+		l.buckets[key] = bucket
+	}
+
+	elapsed := now.Sub(bucket.lastSeen).Seconds()
+	bucket.tokens = math.Min(float64(l.burst), bucket.tokens+elapsed*l.rate)
+	bucket.lastSeen = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+	return bucketDecision(l.rate, l.burst, bucket.tokens, allowed)
+}
+
+// Sweep drops buckets idle for RATE_LIMIT_IDLE_TTL so keys from one-off
+// clients do not pile up
+func (l *MemoryRateLimiter) Sweep(ctx context.Context) error {
+	cutoff := time.Now().Add(-RATE_LIMIT_IDLE_TTL)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, bucket := range l.buckets {
+		if bucket.lastSeen.Before(cutoff) {
+			delete(l.buckets, key)
+		}
+	}
+	return nil
+}
+
+// redisTokenBucketScript refills and takes from a bucket atomically, on the
+// Redis server's clock so instances with skewed clocks agree. Tokens are
+// returned as a string since Redis truncates Lua numbers to integers.
+var redisTokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1]) or burst
+local last = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - last) / 1000 * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return {allowed, tostring(tokens)}
+`)
+
+// RedisRateLimiter keeps buckets in Redis, so every instance draws from the
+// same bucket for a key. Requests are let through while Redis is
+// unreachable, so an outage loosens limits rather than taking the API down.
+type RedisRateLimiter struct {
+	client *redis.Client
+	prefix string
+	rate   float64
+	burst  int
+}
+
+// NewRedisRateLimiter returns a limiter like NewMemoryRateLimiter whose
+// buckets are stored under a name, so limiters with different rates do not
+// share buckets
+func NewRedisRateLimiter(client *redis.Client, name string, rate float64, burst int) *RedisRateLimiter {
+	return &RedisRateLimiter{
+		client: client,
+		prefix: REDIS_RATE_LIMIT_KEY_PREFIX + name + ":",
+		rate:   rate,
+		burst:  max(burst, 1),
+	}
+}
+
+func (l *RedisRateLimiter) Enabled() bool {
+	return l != nil && l.rate > 0
+}
+
+func (l *RedisRateLimiter) Allow(ctx context.Context, key string) RateLimitDecision {
+	allowed, tokens, err := l.take(ctx, key)
+	if err != nil {
+		LoggerFromContext(ctx).Warn("Failed to check rate limit; allowing request", "key", key, "error", err)
+		return RateLimitDecision{Allowed: true, Limit: l.burst, Remaining: l.burst - 1}
+	}
+	return bucketDecision(l.rate, l.burst, tokens, allowed)
+}
+
+func (l *RedisRateLimiter) take(ctx context.Context, key string) (bool, float64, error) {
+	// Run sends the script's hash and falls back to its source when the
+	// server has not cached it yet
+	reply, err := redisTokenBucketScript.Run(ctx, l.client, []string{l.prefix + key},
+		strconv.FormatFloat(l.rate, 'f', -1, 64), l.burst, RATE_LIMIT_IDLE_TTL.Milliseconds()).Result()
+	if err != nil {
+		return false, 0, err
+	}
+
+	values, ok := reply.([]any)
+	if !ok || len(values) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limit reply: %v", reply)
+	}
+	allowed, ok := values[0].(int64)
+	if !ok {
+		return false, 0, fmt.Errorf("unexpected rate limit reply: %v", reply)
+	}
+	text, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return false, 0, fmt.Errorf("unexpected rate limit reply: %v", reply)
+	}
+	return allowed == 1, tokens, nil
+}
+
+// Sweep does nothing, since Redis expires idle buckets itself
+func (l *RedisRateLimiter) Sweep(ctx context.Context) error {
+	return nil
+}
+
+// bucketDecision describes a bucket left with tokens after a request
+func bucketDecision(rate float64, burst int, tokens float64, allowed bool) RateLimitDecision {
+	refillTime := func(tokens float64) time.Duration {
+		return time.Duration(tokens / rate * float64(time.Second))
+	}
+
+	decision := RateLimitDecision{
+		Allowed:   allowed,
+		Limit:     burst,
+		Remaining: int(tokens),
+		Reset:     refillTime(float64(burst) - tokens),
+	}
+	if !allowed {
+		decision.RetryAfter = refillTime(1 - tokens)
+	}
+	return decision
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	REDIS_DIAL_TIMEOUT    = 5 * time.Second
+	REDIS_COMMAND_TIMEOUT = 2 * time.Second
+	REDIS_MAX_IDLE_CONNS  = 10
+)
+
+// NewRedisClient connects to a redis:// or rediss:// URL such as
+// redis://:password@localhost:6379/0 and checks the server answers. Options
+// in the URL's query, such as pool_size, override the defaults here.
+func NewRedisClient(ctx context.Context, rawURL string) (*redis.Client, error) {
+	options, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	if options.DialTimeout == 0 {
+		options.DialTimeout = REDIS_DIAL_TIMEOUT
+	}
+	if options.ReadTimeout == 0 {
+		options.ReadTimeout = REDIS_COMMAND_TIMEOUT
+	}
+	if options.WriteTimeout == 0 {
+		options.WriteTimeout = REDIS_COMMAND_TIMEOUT
+	}
+	if options.MaxIdleConns == 0 {
+		options.MaxIdleConns = REDIS_MAX_IDLE_CONNS
+	}
+
+	client := redis.NewClient(options)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to ping Redis: %w", err)
+	}
+
+	return client, nil
+}
@@ -0,0 +1,94 @@
+//go:build integration
+
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"flashcards/models"
+	"flashcards/testcontainer"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const TEST_REDIS_IMAGE = "redis:7-alpine"
+
+// newContainerRedis starts a Redis server for the test, where newTestRedis
+// fakes one, for behaviour miniredis does not reproduce
+func newContainerRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	container, err := testcontainer.Start(ctx, TEST_REDIS_IMAGE, "6379")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { container.Close() })
+
+	var client *redis.Client
+	err = container.Ready(ctx, func(ctx context.Context) error {
+		client, err = NewRedisClient(ctx, "redis://"+container.Addr+"/0")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// The token bucket script runs atomically, so requests racing on one key
+// never let more than the burst through
+func TestRedisRateLimiterUnderConcurrency(t *testing.T) {
+	ctx := context.Background()
+	client := newContainerRedis(t)
+	const burst = 10
+	limiter := NewRedisRateLimiter(client, "race", 0.001, burst)
+
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for range 5 * burst {
+		wg.Go(func() {
+			if limiter.Allow(ctx, "user:1").Allowed {
+				allowed.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+
+	if got := allowed.Load(); got != burst {
+		t.Errorf("%d racing requests allowed, want the burst of %d", got, burst)
+	}
+	if ttl := client.PTTL(ctx, REDIS_RATE_LIMIT_KEY_PREFIX+"race:user:1").Val(); ttl <= 0 || ttl > RATE_LIMIT_IDLE_TTL {
+		t.Errorf("bucket TTL = %v, want up to %v", ttl, RATE_LIMIT_IDLE_TTL)
+	}
+}
+
+func TestRedisCachesExpire(t *testing.T) {
+	ctx := context.Background()
+	client := newContainerRedis(t)
+	quizzes := NewRedisQuizCache(client, time.Second)
+	notes := NewRedisNoteCache(client, time.Second)
+
+	quizzes.SetQuiz(ctx, "key", []models.Message{{Role: "assistant", Content: "Question 1"}})
+	notes.SetNote(ctx, 1, &models.Note{ID: 3, UserID: 1, Title: "Stars", Tags: []string{}})
+	if got, ok := quizzes.GetQuiz(ctx, "key"); !ok || len(got) != 1 || got[0].Content != "Question 1" {
+		t.Fatalf("GetQuiz = %v, %v", got, ok)
+	}
+	if note, ok := notes.GetNote(ctx, 1, 3); !ok || note.Title != "Stars" {
+		t.Fatalf("GetNote = %v, %v", note, ok)
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+	if _, ok := quizzes.GetQuiz(ctx, "key"); ok {
+		t.Error("GetQuiz hit after the TTL")
+	}
+	if _, ok := notes.GetNote(ctx, 1, 3); ok {
+		t.Error("GetNote hit after the TTL")
+	}
+}
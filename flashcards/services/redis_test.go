@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"flashcards/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client, err := NewRedisClient(context.Background(), "redis://"+server.Addr()+"/0")
+	if err != nil {
+		t.Fatalf("NewRedisClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+func TestNewRedisClientRejectsBadURLs(t *testing.T) {
+	for _, url := range []string{"http://localhost:6379", "redis://localhost:6379/db"} {
+		if _, err := NewRedisClient(context.Background(), url); err == nil {
+			t.Errorf("NewRedisClient(%q) succeeded", url)
+		}
+	}
+}
+
+func TestRedisNoteCache(t *testing.T) {
+	ctx := context.Background()
+	server, client := newTestRedis(t)
+	cache := NewRedisNoteCache(client, time.Minute)
+
+	if _, ok := cache.GetNotes(ctx, 1); ok {
+		t.Fatal("GetNotes hit an empty cache")
+	}
+
+	notes := []*models.Note{{ID: 1, UserID: 1, Title: "Cells", Tags: []string{"biology"}}, {ID: 2, UserID: 1, Title: "Atoms", Tags: []string{}}}
+	cache.SetNotes(ctx, 1, notes)
+	cache.SetNote(ctx, 1, &models.Note{ID: 3, UserID: 1, Title: "Stars", Tags: []string{}})
+
+	got, ok := cache.GetNotes(ctx, 1)
+	if !ok || len(got) != 2 || got[0].Title != "Cells" || got[1].Title != "Atoms" {
+		t.Fatalf("GetNotes = %v, %v", got, ok)
+	}
+	if note, ok := cache.GetNote(ctx, 1, 3); !ok || note.Title != "Stars" {
+		t.Fatalf("GetNote = %v, %v", note, ok)
+	}
+	if _, ok := cache.GetNote(ctx, 2, 3); ok {
+		t.Fatal("GetNote returned another user's note")
+	}
+	if ttl := server.TTL(noteCacheKey(1)); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("note cache TTL = %v, want up to a minute", ttl)
+	}
+
+	cache.Invalidate(ctx, 1)
+	if _, ok := cache.GetNotes(ctx, 1); ok {
+		t.Fatal("GetNotes hit after Invalidate")
+	}
+
+	cache.SetNote(ctx, 1, notes[0])
+	server.FastForward(2 * time.Minute)
+	if _, ok := cache.GetNote(ctx, 1, 1); ok {
+		t.Fatal("GetNote hit after the TTL")
+	}
+}
+
+func TestRedisNoteCacheMissesWhenRedisIsDown(t *testing.T) {
+	ctx := context.Background()
+	server, client := newTestRedis(t)
+	cache := NewRedisNoteCache(client, time.Minute)
+
+	cache.SetNote(ctx, 1, &models.Note{ID: 1, Title: "Cells"})
+	server.Close()
+
+	if _, ok := cache.GetNote(ctx, 1, 1); ok {
+		t.Fatal("GetNote hit with Redis down")
+	}
+	cache.SetNote(ctx, 1, &models.Note{ID: 2})
+	cache.Invalidate(ctx, 1)
+}
+
+func TestRedisQuizCache(t *testing.T) {
+	ctx := context.Background()
+	server, client := newTestRedis(t)
+	cache := NewRedisQuizCache(client, time.Hour)
+
+	if _, ok := cache.GetQuiz(ctx, "key"); ok {
+		t.Fatal("GetQuiz hit an empty cache")
+	}
+
+	messages := []models.Message{
+		{Role: "assistant", Content: "Question 1", Question: &models.QuestionData{ID: "q1", Text: "What is a cell?", Type: "multiple-choice", Options: []string{"A", "B"}}},
+	}
+	cache.SetQuiz(ctx, "key", messages)
+
+	got, ok := cache.GetQuiz(ctx, "key")
+	if !ok || !reflect.DeepEqual(got, messages) {
+		t.Fatalf("GetQuiz = %v, %v; want %v", got, ok, messages)
+	}
+
+	server.FastForward(2 * time.Hour)
+	if _, ok := cache.GetQuiz(ctx, "key"); ok {
+		t.Fatal("GetQuiz hit after the TTL")
+	}
+}
+
+func TestRedisRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	server, client := newTestRedis(t)
+	limiter := NewRedisRateLimiter(client, "test", 1, 2)
+	other := NewRedisRateLimiter(client, "other", 1, 2)
+
+	for i := range 2 {
+		if decision := limiter.Allow(ctx, "user:1"); !decision.Allowed {
+			t.Fatalf("request %d refused within the burst: %+v", i+1, decision)
+		}
+	}
+	decision := limiter.Allow(ctx, "user:1")
+	if decision.Allowed || decision.RetryAfter <= 0 {
+		t.Fatalf("request over the burst = %+v, want refused with a retry", decision)
+	}
+
+	if decision := limiter.Allow(ctx, "user:2"); !decision.Allowed {
+		t.Fatal("another key shared the bucket")
+	}
+	if decision := other.Allow(ctx, "user:1"); !decision.Allowed {
+		t.Fatal("another limiter shared the bucket")
+	}
+
+	if ttl := server.TTL(REDIS_RATE_LIMIT_KEY_PREFIX + "test:user:1"); ttl <= 0 || ttl > RATE_LIMIT_IDLE_TTL {
+		t.Errorf("bucket TTL = %v, want up to %v", ttl, RATE_LIMIT_IDLE_TTL)
+	}
+
+	server.Close()
+	if decision := limiter.Allow(ctx, "user:1"); !decision.Allowed {
+		t.Fatal("request refused with Redis down")
+	}
+}
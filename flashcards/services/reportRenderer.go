@@ -0,0 +1,267 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"flashcards/models"
+)
+
+const (
+	REPORT_FORMAT_JSON     = "json"
+	REPORT_FORMAT_MARKDOWN = "markdown"
+	REPORT_FORMAT_PDF      = "pdf"
+)
+
+var reportContentTypes = map[string]string{
+	REPORT_FORMAT_JSON:     "application/json",
+	REPORT_FORMAT_MARKDOWN: "text/markdown; charset=utf-8",
+	REPORT_FORMAT_PDF:      "application/pdf",
+}
+
+var reportExtensions = map[string]string{
+	REPORT_FORMAT_JSON:     "json",
+	REPORT_FORMAT_MARKDOWN: "md",
+	REPORT_FORMAT_PDF:      "pdf",
+}
+
+// ReportContentType returns the MIME type for a report format
+func ReportContentType(format string) string {
+	return reportContentTypes[format]
+}
+
+// ReportFilename returns the download filename for a session report
+func ReportFilename(sessionID int, format string) string {
+	return fmt.Sprintf("session-%d-report.%s", sessionID, reportExtensions[format])
+}
+
+// RenderSessionReport serializes a report as JSON, Markdown or PDF
+func RenderSessionReport(report *models.SessionReport, format string) ([]byte, error) {
+	switch format {
+	case REPORT_FORMAT_JSON:
+		return json.MarshalIndent(report, "", "  ")
+	case REPORT_FORMAT_MARKDOWN:
+		return []byte(renderReportMarkdown(report)), nil
+	case REPORT_FORMAT_PDF:
+		return renderTextPDF(fmt.Sprintf("Quiz Session Report #%d", report.SessionID), reportLines(report)), nil
+	default:
+		return nil, models.Invalid("unsupported report format: %s (use json, markdown or pdf)", format)
+	}
+}
+
+func renderReportMarkdown(report *models.SessionReport) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Quiz Session Report #%d\n\n", report.SessionID)
+	fmt.Fprintf(&b, "Generated: %s\n\n", report.GeneratedAt.Format("2006-01-02 15:04 MST"))
+
+	b.WriteString("## Score\n\n")
+	fmt.Fprintf(&b, "| Metric | Value |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Score | %.0f%% |\n", report.ScorePercent)
+	fmt.Fprintf(&b, "| Correct | %d |\n", report.Correct)
+	fmt.Fprintf(&b, "| Incorrect | %d |\n", report.Incorrect)
+	fmt.Fprintf(&b, "| Ungraded | %d |\n", report.Ungraded)
+	fmt.Fprintf(&b, "| Skipped | %d |\n", report.Skipped)
+	fmt.Fprintf(&b, "| Unanswered | %d |\n", report.Unanswered)
+	fmt.Fprintf(&b, "| Time spent | %ds |\n\n", report.TotalTimeMs/1000)
+
+	if len(report.WeakTopics) > 0 {
+		b.WriteString("## Weak Topics\n\n")
+		for _, topic := range report.WeakTopics {
+			fmt.Fprintf(&b, "- %s\n", topic)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Questions\n\n")
+	for _, result := range report.Results {
+		fmt.Fprintf(&b, "### %d. %s\n\n", result.Position+1, result.QuestionText)
+		fmt.Fprintf(&b, "- Result: %s\n", resultLabel(result))
+		fmt.Fprintf(&b, "- Your answer: %s\n", valueOrDash(result.Answer))
+		if result.CorrectAnswer != "" {
+			fmt.Fprintf(&b, "- Correct answer: %s\n", result.CorrectAnswer)
+		}
+		if result.Explanation != "" {
+			fmt.Fprintf(&b, "\n%s\n", result.Explanation)
+		}
+		b.WriteString("\n")
+	}
+
+	if len(report.Flagged) > 0 {
+		b.WriteString("## Flagged for Review\n\n")
+		for _, item := range report.Flagged {
+			fmt.Fprintf(&b, "- Question %d: %s\n", item.Position+1, item.Question.Text)
+		}
+	}
+
+	return b.String()
+}
+
+// Plain text lines used for the PDF layout
+func reportLines(report *models.SessionReport) []string {
+	lines := []string{
+		fmt.Sprintf("Generated: %s", report.GeneratedAt.Format("2006-01-02 15:04 MST")),
+		"",
+		fmt.Sprintf("Score: %.0f%%   Correct: %d   Incorrect: %d   Ungraded: %d", report.ScorePercent, report.Correct, report.Incorrect, report.Ungraded),
+		fmt.Sprintf("Skipped: %d   Unanswered: %d   Time spent: %ds", report.Skipped, report.Unanswered, report.TotalTimeMs/1000),
+		"",
+	}
+
+	if len(report.WeakTopics) > 0 {
+		lines = append(lines, "Weak topics: "+strings.Join(report.WeakTopics, ", "), "")
+	}
+
+	for _, result := range report.Results {
+		lines = append(lines,
+			fmt.Sprintf("%d. %s", result.Position+1, result.QuestionText),
+			fmt.Sprintf("   Result: %s", resultLabel(result)),
+			fmt.Sprintf("   Your answer: %s", valueOrDash(result.Answer)),
+		)
+		if result.CorrectAnswer != "" {
+			lines = append(lines, fmt.Sprintf("   Correct answer: %s", result.CorrectAnswer))
+		}
+		if result.Explanation != "" {
+			lines = append(lines, "   "+result.Explanation)
+		}
+		lines = append(lines, "")
+	}
+
+	return lines
+}
+
+func resultLabel(result models.QuestionResult) string {
+	switch {
+	case result.Status == models.QuestionStatusSkipped:
+		return "skipped"
+	case result.Status == models.QuestionStatusUnanswered:
+		return "unanswered"
+	case result.Correct == nil:
+		return "not graded"
+	case *result.Correct:
+		return "correct"
+	default:
+		return "incorrect"
+	}
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+const (
+	pdfPageWidth    = 612
+	pdfPageHeight   = 792
+	pdfMargin       = 50
+	pdfFontSize     = 10
+	pdfLineHeight   = 14
+	pdfCharsPerLine = 95
+	pdfLinesPerPage = (pdfPageHeight - 2*pdfMargin) / pdfLineHeight
+)
+
+// renderTextPDF lays out wrapped lines of text on letter-sized pages using
+// the built-in Helvetica font, producing a minimal standalone PDF.
+func renderTextPDF(title string, lines []string) []byte {
+	wrapped := []string{title, ""}
+	for _, line := range lines {
+		wrapped = append(wrapped, wrapLine(line, pdfCharsPerLine)...)
+	}
+
+	var pages [][]string
+	for start := 0; start < len(wrapped); start += pdfLinesPerPage {
+		end := min(start+pdfLinesPerPage, len(wrapped))
+		pages = append(pages, wrapped[start:end])
+	}
+
+	// Object layout: 1 catalog, 2 pages, 3 font, then a page and a content
+	// stream object per page
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	)
+
+	for i, page := range pages {
+		var content strings.Builder
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d TL %d %d Td\n", pdfFontSize, pdfLineHeight, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, line := range page {
+			fmt.Fprintf(&content, "(%s) Tj T*\n", escapePDFText(line))
+		}
+		content.WriteString("ET")
+
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, 5+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xrefOffset)
+
+	return buf.Bytes()
+}
+
+// Wrap a line at word boundaries to at most width characters
+func wrapLine(line string, width int) []string {
+	if len(line) <= width {
+		return []string{line}
+	}
+
+	indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+	var wrapped []string
+	current := ""
+	for _, word := range strings.Fields(line) {
+		switch {
+		case current == "":
+			current = indent + word
+		case len(current)+1+len(word) > width:
+			wrapped = append(wrapped, current)
+			current = indent + word
+		default:
+			current += " " + word
+		}
+	}
+	if current != "" {
+		wrapped = append(wrapped, current)
+	}
+	return wrapped
+}
+
+// Escape PDF string delimiters and replace characters outside Latin-1
+func escapePDFText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r > 255 || r < 32:
+			b.WriteRune('?')
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"flashcards/models"
+)
+
+// REST hooks follow Zapier's model, which Make and n8n also speak: the
+// platform subscribes a hook URL to one trigger when a zap is turned on,
+// unsubscribes it when the zap is turned off, and loads samples to show
+// while the zap is built. Each delivery is the trigger payload alone.
+
+const (
+	MAX_REST_HOOKS_PER_USER = 50
+	// A completed quiz scoring below this fires low_score
+	LOW_SCORE_PERCENT = 60
+	// Samples returned while a zap is built, from this far back; up to
+	// REST_HOOK_SAMPLE_SCAN recent quizzes are looked through for low scores
+	REST_HOOK_SAMPLES       = 3
+	REST_HOOK_SAMPLE_PERIOD = 30 * 24 * time.Hour
+	REST_HOOK_SAMPLE_SCAN   = 20
+)
+
+var restHookTriggers = []string{models.RestHookTriggerNewNote, models.RestHookTriggerQuizCompleted, models.RestHookTriggerLowScore}
+
+// SubscribeRestHook subscribes the platform's hook URL to a trigger
+func (s *WebhookService) SubscribeRestHook(ctx context.Context, userID int, req *models.SubscribeRestHookRequest) (*models.RestHook, error) {
+	if req == nil {
+		return nil, models.Invalid("request cannot be nil")
+	}
+
+	webhook := &models.Webhook{
+		UserID: userID,
+		Kind:   models.WebhookKindRestHook,
+		URL:    strings.TrimSpace(req.HookURL),
+		Events: []string{req.Event},
+		Active: true,
+	}
+	if err := s.create(ctx, webhook, MAX_REST_HOOKS_PER_USER, "REST hooks"); err != nil {
+		return nil, err
+	}
+
+	LoggerFromContext(ctx).Info("Subscribed REST hook", "webhook_id", webhook.ID, "event", req.Event)
+	return &models.RestHook{ID: webhook.ID, HookURL: webhook.URL, Event: req.Event, CreatedAt: webhook.CreatedAt}, nil
+}
+
+// UnsubscribeRestHook removes a REST hook. Webhooks registered through
+// /webhooks are not found here.
+func (s *WebhookService) UnsubscribeRestHook(ctx context.Context, userID, id int) error {
+	webhook, err := s.GetWebhook(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if webhook.Kind != models.WebhookKindRestHook {
+		return models.NotFound("REST hook with id %d not found", id)
+	}
+
+	return s.repo.DeleteWebhook(ctx, userID, id)
+}
+
+// RestHookSamples returns the trigger's payloads for the user's most recent
+// notes or quizzes, newest first, for the platform to show while a zap is
+// built
+func (s *WebhookService) RestHookSamples(ctx context.Context, userID int, trigger string) ([]any, error) {
+	samples := make([]any, 0, REST_HOOK_SAMPLES)
+
+	switch trigger {
+	case models.RestHookTriggerNewNote:
+		page, err := s.noteService.ListNotes(ctx, userID, models.NoteFilter{}, models.ListParams{
+			Limit: REST_HOOK_SAMPLES,
+			Sort:  "createdAt",
+			Order: "desc",
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, note := range page.Items {
+			samples = append(samples, noteTrigger(note))
+		}
+
+	case models.RestHookTriggerQuizCompleted, models.RestHookTriggerLowScore:
+		limit := REST_HOOK_SAMPLES
+		if trigger == models.RestHookTriggerLowScore {
+			limit = REST_HOOK_SAMPLE_SCAN
+		}
+		reports, err := s.sessionService.RecentReports(ctx, userID, time.Now().Add(-REST_HOOK_SAMPLE_PERIOD), limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, report := range reports {
+			if len(samples) == REST_HOOK_SAMPLES {
+				break
+			}
+			if trigger == models.RestHookTriggerQuizCompleted || lowScore(report) {
+				samples = append(samples, quizTrigger(report))
+			}
+		}
+
+	default:
+		return nil, models.Invalid("event must be one of: %s", strings.Join(restHookTriggers, ", "))
+	}
+
+	return samples, nil
+}
+
+// restHookPayloads returns the triggers an event fires, each with its
+// payload
+func restHookPayloads(event string, data any) map[string]any {
+	switch event {
+	case models.WebhookEventNoteCreated:
+		if note, ok := data.(*models.Note); ok {
+			return map[string]any{models.RestHookTriggerNewNote: noteTrigger(note)}
+		}
+	case models.WebhookEventQuizCompleted:
+		if report, ok := data.(*models.SessionReport); ok {
+			payloads := map[string]any{models.RestHookTriggerQuizCompleted: quizTrigger(report)}
+			if lowScore(report) {
+				payloads[models.RestHookTriggerLowScore] = quizTrigger(report)
+			}
+			return payloads
+		}
+	}
+	return nil
+}
+
+// lowScore reports whether a completed quiz with graded answers scored
+// below LOW_SCORE_PERCENT
+func lowScore(report *models.SessionReport) bool {
+	return report.Correct+report.Incorrect > 0 && report.ScorePercent < LOW_SCORE_PERCENT
+}
+
+func noteTrigger(note *models.Note) models.NoteTrigger {
+	return models.NoteTrigger{
+		ID:        note.ID,
+		Title:     note.Title,
+		Content:   note.Content,
+		Source:    note.Source,
+		Language:  note.Language,
+		Tags:      strings.Join(note.Tags, ","),
+		DeckID:    note.DeckID,
+		CreatedAt: note.CreatedAt,
+	}
+}
+
+func quizTrigger(report *models.SessionReport) models.QuizTrigger {
+	completedAt := report.GeneratedAt
+	if report.CompletedAt != nil {
+		completedAt = *report.CompletedAt
+	}
+	return models.QuizTrigger{
+		ID:             report.SessionID,
+		TotalQuestions: report.TotalQuestions,
+		Answered:       report.Answered,
+		Correct:        report.Correct,
+		Incorrect:      report.Incorrect,
+		ScorePercent:   report.ScorePercent,
+		WeakTopics:     strings.Join(report.WeakTopics, ","),
+		CompletedAt:    completedAt,
+	}
+}
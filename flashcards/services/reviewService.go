@@ -0,0 +1,914 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	DEFAULT_EASE_FACTOR  = 2.5
+	MIN_EASE_FACTOR      = 1.3
+	HARD_INTERVAL_FACTOR = 1.2
+	EASY_BONUS           = 1.3
+	MAX_INTERVAL_DAYS    = 36500
+
+	DEFAULT_GRADUATING_INTERVAL_DAYS = 1
+	DEFAULT_EASY_INTERVAL_DAYS       = 4
+	DEFAULT_NEW_CARDS_PER_DAY        = 20
+	MAX_NEW_CARDS_PER_DAY            = 1000
+	MAX_LEARNING_STEPS               = 10
+	MAX_LEARNING_STEP                = 7 * 24 * time.Hour
+
+	DEFAULT_DUE_LIMIT  = 20
+	MAX_DUE_LIMIT      = 200
+	MAX_PREFETCH_CARDS = 50
+	// How long after answering a review can still be undone
+	REVIEW_UNDO_WINDOW = 5 * time.Minute
+
+	// Limits on reviews synced from offline clients: how many per request,
+	// how far ahead of the server a client clock may run, and how old a
+	// review may be
+	MAX_SYNC_REVIEWS       = 500
+	MAX_SYNC_CLOCK_SKEW    = 5 * time.Minute
+	MAX_OFFLINE_REVIEW_AGE = 30 * 24 * time.Hour
+
+	// Due cards loaded before ordering, so strategies like random and
+	// interleaved draw from more than the first page
+	MAX_DUE_CANDIDATES = 1000
+
+	MAX_LEECH_THRESHOLD = 100
+	// Lapses after which a card counts as hard and is reviewed with its
+	// mnemonic
+	HARD_CARD_LAPSES = 2
+)
+
+// Learning steps used by decks without saved settings
+var (
+	DEFAULT_LEARNING_STEPS   = []string{"1m", "10m"}
+	DEFAULT_RELEARNING_STEPS = []string{"10m"}
+)
+
+var validRatings = []string{models.RatingAgain, models.RatingHard, models.RatingGood, models.RatingEasy}
+
+var validReviewOrders = []string{
+	models.ReviewOrderDue,
+	models.ReviewOrderRandom,
+	models.ReviewOrderHardestFirst,
+	models.ReviewOrderBySubdeck,
+	models.ReviewOrderInterleaved,
+}
+
+var validReviewModes = []string{models.ReviewModeScheduled, models.ReviewModeCram}
+
+// Client IDs of synced reviews, such as UUIDs
+var clientIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+var validNewCardOrders = []string{models.NewCardOrderSequential, models.NewCardOrderRandom}
+
+var validNewCardPositions = []string{
+	models.NewCardPositionAfterReviews,
+	models.NewCardPositionBeforeReviews,
+	models.NewCardPositionMixed,
+}
+
+var validLeechActions = []string{models.LeechActionTag, models.LeechActionSuspend}
+
+type ReviewService struct {
+	repo              db.ReviewRepository
+	flashcardService  *FlashcardService
+	deckService       *DeckService
+	vocabularyService *VocabularyService
+	events            EventPublisher
+}
+
+func NewReviewService(repo db.ReviewRepository, flashcardService *FlashcardService, deckService *DeckService, vocabularyService *VocabularyService) *ReviewService {
+	return &ReviewService{
+		repo:              repo,
+		flashcardService:  flashcardService,
+		deckService:       deckService,
+		vocabularyService: vocabularyService,
+	}
+}
+
+// PublishEventsTo sets where review.completed events are published, such
+// as to the user's webhooks
+func (s *ReviewService) PublishEventsTo(events EventPublisher) {
+	s.events = events
+}
+
+// GetDueQueue returns the cards due now, ordered by the deck's review order
+// unless order overrides it. Without a deck the queue covers every card and
+// defaults to due order. A deck's queue includes its subdecks. New cards are
+// capped by the deck's daily limit and placed by its new-card settings;
+// outside a deck the default settings apply.
+func (s *ReviewService) GetDueQueue(ctx context.Context, userID int, deckID *int, order string, limit int) (*models.DueQueue, error) {
+	limit, err := validateQueueParams(order, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	deckIDs, order, err := s.queueScope(ctx, userID, deckID, order)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := defaultStudySettings(0)
+	if deckID != nil {
+		if settings, err = s.deckService.GetStudySettings(ctx, userID, *deckID); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	cards, total, newTotal, err := s.repo.GetDueCards(ctx, userID, deckIDs, now, MAX_DUE_CANDIDATES)
+	if err != nil {
+		return nil, err
+	}
+
+	introduced, err := s.repo.CountNewCardsReviewed(ctx, userID, deckIDs, startOfDay(now))
+	if err != nil {
+		return nil, err
+	}
+	newAllowed := min(newTotal, max(0, settings.NewCardsPerDay-introduced))
+
+	var reviews, newCards []*models.DueCard
+	for _, card := range cards {
+		if card.Schedule.State == models.CardStateNew {
+			newCards = append(newCards, card)
+		} else {
+			reviews = append(reviews, card)
+		}
+	}
+	if len(newCards) > newAllowed {
+		newCards = newCards[:newAllowed]
+	}
+	if settings.NewCardOrder == models.NewCardOrderRandom {
+		rand.Shuffle(len(newCards), func(i, j int) {
+			newCards[i], newCards[j] = newCards[j], newCards[i]
+		})
+	}
+
+	cards = placeNewCards(orderDueCards(reviews, order), newCards, settings.NewCardPosition)
+	if len(cards) > limit {
+		cards = cards[:limit]
+	}
+
+	queue := &models.DueQueue{
+		DeckID:   deckID,
+		Mode:     models.ReviewModeScheduled,
+		Order:    order,
+		Total:    total - newTotal + newAllowed,
+		NewCards: newAllowed,
+		Cards:    make([]models.DueCard, len(cards)),
+	}
+	for i, card := range cards {
+		queue.Cards[i] = *card
+	}
+	return queue, nil
+}
+
+// WarmDueQueues loads the default due queue of up to limit users who
+// reviewed in the last week, so their cards and schedules are in the
+// database's cache when they come back after a deploy. Failures for single
+// users are skipped.
+func (s *ReviewService) WarmDueQueues(ctx context.Context, limit int) error {
+	userIDs, err := s.repo.GetActiveUserIDs(ctx, time.Now().Add(-7*24*time.Hour), limit)
+	if err != nil {
+		return err
+	}
+
+	warmed := 0
+	for _, userID := range userIDs {
+		if _, err := s.GetDueQueue(ctx, userID, nil, "", DEFAULT_DUE_LIMIT); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+		warmed++
+	}
+
+	LoggerFromContext(ctx).Info("Warmed due queues", "users", warmed)
+	return nil
+}
+
+// PrefetchDueCards returns the next count cards of the due queue with their
+// rendered sides and media URLs, so clients can preload them and show each
+// card without waiting on the network
+func (s *ReviewService) PrefetchDueCards(ctx context.Context, userID int, deckID *int, order string, count int) (*models.DueQueue, error) {
+	if count < 1 || count > MAX_PREFETCH_CARDS {
+		return nil, models.Invalid("prefetch must be between 1 and %d", MAX_PREFETCH_CARDS)
+	}
+
+	queue, err := s.GetDueQueue(ctx, userID, deckID, order, count)
+	if err != nil {
+		return nil, err
+	}
+
+	flashcardIDs := make([]int, len(queue.Cards))
+	for i, card := range queue.Cards {
+		flashcardIDs[i] = card.Flashcard.ID
+	}
+	withAudio, err := s.vocabularyService.FlashcardIDsWithAudio(ctx, userID, flashcardIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, card := range queue.Cards {
+		queue.Cards[i].Rendered = renderDueCard(card, withAudio[card.Flashcard.ID])
+	}
+	return queue, nil
+}
+
+// GetCardQueue returns a scheduled review of the given cards with their
+// rendered sides, such as the cards a digest email links to. Cards deleted
+// since are left out.
+func (s *ReviewService) GetCardQueue(ctx context.Context, userID int, flashcardIDs []int) (*models.DueQueue, error) {
+	cards, err := s.repo.GetCardsByIDs(ctx, userID, flashcardIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	withAudio, err := s.vocabularyService.FlashcardIDsWithAudio(ctx, userID, flashcardIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	queue := &models.DueQueue{
+		Mode:  models.ReviewModeScheduled,
+		Order: models.ReviewOrderDue,
+		Total: len(cards),
+		Cards: make([]models.DueCard, len(cards)),
+	}
+	for i, card := range cards {
+		queue.Cards[i] = *card
+		queue.Cards[i].Rendered = renderDueCard(*card, withAudio[card.Flashcard.ID])
+		if card.Schedule.State == models.CardStateNew {
+			queue.NewCards++
+		}
+	}
+	return queue, nil
+}
+
+// GetCramQueue returns cards to drill regardless of when they are due, for
+// last-minute practice before an exam. Scope and ordering work as in
+// GetDueQueue, but new cards are not held back by the daily limit.
+func (s *ReviewService) GetCramQueue(ctx context.Context, userID int, deckID *int, order string, limit int) (*models.DueQueue, error) {
+	limit, err := validateQueueParams(order, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	deckIDs, order, err := s.queueScope(ctx, userID, deckID, order)
+	if err != nil {
+		return nil, err
+	}
+
+	cards, total, err := s.repo.GetCramCards(ctx, userID, deckIDs, MAX_DUE_CANDIDATES)
+	if err != nil {
+		return nil, err
+	}
+
+	cards = orderDueCards(cards, order)
+	if len(cards) > limit {
+		cards = cards[:limit]
+	}
+
+	queue := &models.DueQueue{
+		DeckID: deckID,
+		Mode:   models.ReviewModeCram,
+		Order:  order,
+		Total:  total,
+		Cards:  make([]models.DueCard, len(cards)),
+	}
+	for i, card := range cards {
+		queue.Cards[i] = *card
+	}
+	return queue, nil
+}
+
+// Resolve a queue's deck tree and review order. Without a deck the queue
+// covers every card and defaults to due order.
+func (s *ReviewService) queueScope(ctx context.Context, userID int, deckID *int, order string) ([]int, string, error) {
+	var deckIDs []int
+	if deckID != nil {
+		deck, err := s.deckService.GetDeckByID(ctx, userID, *deckID)
+		if err != nil {
+			return nil, "", err
+		}
+		if order == "" {
+			order = deck.ReviewOrder
+		}
+		deckIDs, err = s.deckService.GetDeckTreeIDs(ctx, userID, *deckID)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	if order == "" {
+		order = models.ReviewOrderDue
+	}
+	return deckIDs, order, nil
+}
+
+func validateQueueParams(order string, limit int) (int, error) {
+	if limit == 0 {
+		limit = DEFAULT_DUE_LIMIT
+	}
+	if limit < 1 || limit > MAX_DUE_LIMIT {
+		return 0, models.Invalid("limit must be between 1 and %d", MAX_DUE_LIMIT)
+	}
+	if order != "" && !containsValue(validReviewOrders, order) {
+		return 0, models.Invalid("order must be one of: %s", strings.Join(validReviewOrders, ", "))
+	}
+	return limit, nil
+}
+
+// SubmitReview grades a card and reschedules it. Cram reviews are logged
+// but leave the schedule as it was.
+func (s *ReviewService) SubmitReview(ctx context.Context, userID int, req *models.SubmitReviewRequest) (*models.ReviewResult, error) {
+	result, _, err := s.recordReview(ctx, userID, req, time.Now(), nil)
+	return result, err
+}
+
+// Record a review answered at reviewedAt. A scheduled review older than the
+// card's last review is logged without changing the schedule, so the latest
+// answer wins; stale reports when that happened.
+func (s *ReviewService) recordReview(ctx context.Context, userID int, req *models.SubmitReviewRequest, reviewedAt time.Time, clientID *string) (*models.ReviewResult, bool, error) {
+	if !containsValue(validRatings, req.Rating) {
+		return nil, false, models.Invalid("rating must be one of: %s", strings.Join(validRatings, ", "))
+	}
+	mode := req.Mode
+	if mode == "" {
+		mode = models.ReviewModeScheduled
+	}
+	if !containsValue(validReviewModes, mode) {
+		return nil, false, models.Invalid("mode must be one of: %s", strings.Join(validReviewModes, ", "))
+	}
+
+	flashcard, err := s.flashcardService.GetFlashcardByID(ctx, userID, req.FlashcardID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	settings := defaultStudySettings(0)
+	if flashcard.DeckID != nil {
+		if settings, err = s.deckService.GetStudySettings(ctx, userID, *flashcard.DeckID); err != nil {
+			return nil, false, err
+		}
+	}
+	steps, err := learningStepsFromSettings(settings)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var previous *models.Schedule
+	schedule, err := s.repo.GetSchedule(ctx, userID, req.FlashcardID)
+	if err != nil {
+		if !errors.Is(err, models.ErrNotFound) {
+			return nil, false, err
+		}
+		schedule = newSchedule(req.FlashcardID)
+	} else {
+		previous = schedule
+	}
+
+	stale := mode == models.ReviewModeScheduled && schedule.LastReviewedAt != nil && schedule.LastReviewedAt.After(reviewedAt)
+	next := schedule
+	newLeech := false
+	if mode == models.ReviewModeScheduled && !stale {
+		next = scheduleReview(schedule, req.Rating, reviewedAt, steps)
+		newLeech = markLeech(schedule, next, settings)
+	}
+
+	review := &models.Review{
+		UserID:        userID,
+		FlashcardID:   req.FlashcardID,
+		Mode:          mode,
+		Rating:        req.Rating,
+		PreviousState: schedule.State,
+		IntervalDays:  next.IntervalDays,
+		EaseFactor:    next.EaseFactor,
+		DueAt:         next.DueAt,
+		ReviewedAt:    reviewedAt,
+		ClientID:      clientID,
+
+		PreviousSchedule: previous,
+	}
+
+	saved := next
+	if mode == models.ReviewModeCram || stale {
+		saved = nil
+	}
+	if err := s.repo.SaveReview(ctx, review, saved); err != nil {
+		return nil, false, err
+	}
+
+	if newLeech {
+		LoggerFromContext(ctx).Info("Flashcard became a leech", "flashcard_id", req.FlashcardID, "lapses", next.Lapses, "suspended", next.Suspended)
+	}
+	result := &models.ReviewResult{Review: review, Schedule: next, NewLeech: newLeech}
+	if s.events != nil {
+		s.events.Publish(ctx, userID, models.WebhookEventReviewCompleted, result)
+	}
+	return result, stale, nil
+}
+
+// markLeech marks a card as a leech when a lapse brings it to the deck's
+// leech threshold, and again every half threshold after that as Anki does,
+// suspending it too when the deck asks for that. It reports whether the
+// card was marked.
+func markLeech(current, next *models.Schedule, settings *models.DeckStudySettings) bool {
+	threshold := settings.LeechThreshold
+	if threshold <= 0 || next.Lapses <= current.Lapses || next.Lapses < threshold {
+		return false
+	}
+	if (next.Lapses-threshold)%max(1, threshold/2) != 0 {
+		return false
+	}
+
+	next.Leech = true
+	if settings.LeechAction == models.LeechActionSuspend {
+		next.Suspended = true
+	}
+	return true
+}
+
+// ListLeeches returns the user's leeches, in the deck and its subdecks
+// when deckID is set, most lapses first
+func (s *ReviewService) ListLeeches(ctx context.Context, userID int, deckID *int) ([]*models.DueCard, error) {
+	deckIDs, _, err := s.queueScope(ctx, userID, deckID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetLeechCards(ctx, userID, deckIDs)
+}
+
+// UnsuspendCard puts a suspended card back in the review queues. It stays
+// marked as a leech, and is suspended again if it keeps lapsing.
+func (s *ReviewService) UnsuspendCard(ctx context.Context, userID, flashcardID int) (*models.Schedule, error) {
+	if err := s.repo.SetSuspended(ctx, userID, flashcardID, false); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetSchedule(ctx, userID, flashcardID)
+}
+
+// ReformulateLeech has the LLM rewrite a leech into a card that is easier
+// to remember, then resets its schedule so the new card is learned from
+// scratch, which also clears its leech mark and suspension
+func (s *ReviewService) ReformulateLeech(ctx context.Context, userID, flashcardID int) (*models.DueCard, error) {
+	schedule, err := s.repo.GetSchedule(ctx, userID, flashcardID)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		return nil, err
+	}
+	if schedule == nil || !schedule.Leech {
+		if _, err := s.flashcardService.GetFlashcardByID(ctx, userID, flashcardID); err != nil {
+			return nil, err
+		}
+		return nil, models.Conflict("flashcard %d is not a leech", flashcardID)
+	}
+
+	flashcard, err := s.flashcardService.ReformulateFlashcard(ctx, userID, flashcardID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.ResetSchedule(ctx, userID, flashcardID); err != nil {
+		return nil, err
+	}
+
+	return &models.DueCard{Flashcard: flashcard, Schedule: newSchedule(flashcardID)}, nil
+}
+
+// SyncReviews applies reviews answered while offline. Reviews are applied in
+// the order they were answered on the client, and each is reported as:
+//   - duplicate when its client ID was already synced; nothing changes, so
+//     retrying a batch is safe
+//   - rejected when it is invalid, answered too far in the future or more
+//     than MAX_OFFLINE_REVIEW_AGE ago, or its card no longer exists
+//   - conflict when the card was reviewed after it was answered, e.g. on
+//     another device; it is logged but the schedule keeps the later review
+//   - applied otherwise, scheduling the card from when it was answered
+//
+// A storage error stops the sync; reviews applied before it stay applied.
+func (s *ReviewService) SyncReviews(ctx context.Context, userID int, req *models.SyncReviewsRequest) (*models.SyncReviewsResponse, error) {
+	if len(req.Reviews) == 0 {
+		return nil, models.Invalid("reviews cannot be empty")
+	}
+	if len(req.Reviews) > MAX_SYNC_REVIEWS {
+		return nil, models.Invalid("cannot sync more than %d reviews at once", MAX_SYNC_REVIEWS)
+	}
+
+	reviews := make([]models.SyncReview, len(req.Reviews))
+	copy(reviews, req.Reviews)
+	sort.SliceStable(reviews, func(i, j int) bool {
+		return reviews[i].ReviewedAt.Before(reviews[j].ReviewedAt)
+	})
+
+	now := time.Now()
+	response := &models.SyncReviewsResponse{Results: make([]models.SyncReviewResult, 0, len(reviews))}
+	for _, item := range reviews {
+		result, err := s.syncReview(ctx, userID, item, now)
+		if err != nil {
+			return nil, err
+		}
+
+		switch result.Status {
+		case models.SyncStatusApplied:
+			response.Applied++
+		case models.SyncStatusDuplicate:
+			response.Duplicates++
+		case models.SyncStatusConflict:
+			response.Conflicts++
+		case models.SyncStatusRejected:
+			response.Rejected++
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	return response, nil
+}
+
+func (s *ReviewService) syncReview(ctx context.Context, userID int, item models.SyncReview, now time.Time) (models.SyncReviewResult, error) {
+	result := models.SyncReviewResult{ClientID: item.ClientID, Status: models.SyncStatusRejected}
+
+	if !clientIDPattern.MatchString(item.ClientID) {
+		result.Error = "clientId must be 1 to 64 letters, digits, '.', '_' or '-'"
+		return result, nil
+	}
+	if item.ReviewedAt.IsZero() {
+		result.Error = "reviewedAt is required"
+		return result, nil
+	}
+	if item.ReviewedAt.After(now.Add(MAX_SYNC_CLOCK_SKEW)) {
+		result.Error = "reviewedAt cannot be in the future"
+		return result, nil
+	}
+	if item.ReviewedAt.Before(now.Add(-MAX_OFFLINE_REVIEW_AGE)) {
+		result.Error = fmt.Sprintf("reviewedAt cannot be more than %v ago", MAX_OFFLINE_REVIEW_AGE)
+		return result, nil
+	}
+
+	existing, err := s.repo.GetReviewByClientID(ctx, userID, item.ClientID)
+	if err == nil {
+		result.Status = models.SyncStatusDuplicate
+		result.Review = existing
+		return result, nil
+	}
+	if !errors.Is(err, models.ErrNotFound) {
+		return result, err
+	}
+
+	req := &models.SubmitReviewRequest{FlashcardID: item.FlashcardID, Rating: item.Rating, Mode: item.Mode}
+	clientID := item.ClientID
+	recorded, stale, err := s.recordReview(ctx, userID, req, item.ReviewedAt.UTC(), &clientID)
+	if err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			result.Status = models.SyncStatusDuplicate
+			return result, nil
+		}
+		if errors.Is(err, models.ErrInternal) {
+			return result, err
+		}
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	result.Status = models.SyncStatusApplied
+	if stale {
+		result.Status = models.SyncStatusConflict
+	}
+	result.Review = recorded.Review
+	result.Schedule = recorded.Schedule
+	return result, nil
+}
+
+// UndoLastReview reverts the user's most recent review, restoring the card's
+// schedule and removing the review from the log. Only reviews answered
+// within REVIEW_UNDO_WINDOW can be undone.
+func (s *ReviewService) UndoLastReview(ctx context.Context, userID int) (*models.ReviewResult, error) {
+	review, err := s.repo.GetLatestReview(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Since(review.ReviewedAt) > REVIEW_UNDO_WINDOW {
+		return nil, models.Conflict("the last review is older than %v and can no longer be undone", REVIEW_UNDO_WINDOW)
+	}
+
+	if err := s.repo.UndoReview(ctx, review); err != nil {
+		return nil, err
+	}
+
+	schedule, err := s.repo.GetSchedule(ctx, userID, review.FlashcardID)
+	if err != nil {
+		if !errors.Is(err, models.ErrNotFound) {
+			return nil, err
+		}
+		schedule = newSchedule(review.FlashcardID)
+	}
+
+	return &models.ReviewResult{Review: review, Schedule: schedule}, nil
+}
+
+func newSchedule(flashcardID int) *models.Schedule {
+	return &models.Schedule{
+		FlashcardID: flashcardID,
+		State:       models.CardStateNew,
+		EaseFactor:  DEFAULT_EASE_FACTOR,
+	}
+}
+
+// learningSteps is a deck's study settings parsed for scheduling
+type learningSteps struct {
+	learning           []time.Duration
+	relearning         []time.Duration
+	graduatingInterval int
+	easyInterval       int
+}
+
+func learningStepsFromSettings(settings *models.DeckStudySettings) (*learningSteps, error) {
+	steps := &learningSteps{
+		graduatingInterval: settings.GraduatingIntervalDays,
+		easyInterval:       settings.EasyIntervalDays,
+	}
+	for _, step := range settings.LearningSteps {
+		delay, err := parseLearningStep(step)
+		if err != nil {
+			return nil, err
+		}
+		steps.learning = append(steps.learning, delay)
+	}
+	for _, step := range settings.RelearningSteps {
+		delay, err := parseLearningStep(step)
+		if err != nil {
+			return nil, err
+		}
+		steps.relearning = append(steps.relearning, delay)
+	}
+	return steps, nil
+}
+
+// scheduleReview moves a card through its learning steps and then SM-2.
+// New cards climb the learning ladder and graduate to day intervals; a
+// lapse sends a review card down the relearning ladder. Once graduated,
+// "again" resets the repetition count, passing grades grow the interval by
+// the ease factor, and the ease factor moves with each rating.
+func scheduleReview(current *models.Schedule, rating string, now time.Time, steps *learningSteps) *models.Schedule {
+	next := *current
+	next.LastReviewedAt = &now
+
+	switch {
+	case current.State == models.CardStateRelearning:
+		easyInterval := max(current.IntervalDays+1, int(math.Round(float64(current.IntervalDays)*EASY_BONUS)))
+		return stepLearning(&next, rating, now, steps.relearning, max(1, current.IntervalDays), easyInterval)
+	case current.State != models.CardStateReview && len(steps.learning) > 0:
+		if current.State == models.CardStateNew {
+			next.State = models.CardStateLearning
+			next.Step = 0
+		}
+		return stepLearning(&next, rating, now, steps.learning, steps.graduatingInterval, steps.easyInterval)
+	}
+
+	next.State = models.CardStateReview
+	next.Step = 0
+
+	switch rating {
+	case models.RatingAgain:
+		if current.State == models.CardStateReview {
+			next.Lapses++
+		}
+		next.Repetitions = 0
+		next.IntervalDays = 1
+		next.EaseFactor -= 0.2
+	case models.RatingHard:
+		next.IntervalDays = max(1, int(math.Round(float64(current.IntervalDays)*HARD_INTERVAL_FACTOR)))
+		next.Repetitions++
+		next.EaseFactor -= 0.15
+	case models.RatingGood, models.RatingEasy:
+		switch current.Repetitions {
+		case 0:
+			next.IntervalDays = 1
+		case 1:
+			next.IntervalDays = 6
+		default:
+			next.IntervalDays = int(math.Round(float64(current.IntervalDays) * current.EaseFactor))
+		}
+		if rating == models.RatingEasy {
+			next.IntervalDays = int(math.Round(float64(next.IntervalDays) * EASY_BONUS))
+			next.EaseFactor += 0.15
+		}
+		next.Repetitions++
+	}
+
+	next.EaseFactor = math.Max(MIN_EASE_FACTOR, next.EaseFactor)
+	next.IntervalDays = min(next.IntervalDays, MAX_INTERVAL_DAYS)
+
+	if rating == models.RatingAgain && current.State == models.CardStateReview && len(steps.relearning) > 0 {
+		next.State = models.CardStateRelearning
+		next.DueAt = now.Add(steps.relearning[0])
+		return &next
+	}
+
+	next.DueAt = now.AddDate(0, 0, next.IntervalDays)
+	return &next
+}
+
+// stepLearning moves a learning or relearning card along its ladder:
+// "again" restarts it, "hard" repeats the current step, "good" advances and
+// graduates past the last step, and "easy" graduates straight away
+func stepLearning(next *models.Schedule, rating string, now time.Time, ladder []time.Duration, graduateDays, easyDays int) *models.Schedule {
+	if len(ladder) == 0 {
+		return graduateCard(next, now, graduateDays)
+	}
+	// Settings may have shortened the ladder since the card's last review
+	next.Step = min(next.Step, len(ladder)-1)
+
+	switch rating {
+	case models.RatingAgain:
+		next.Step = 0
+	case models.RatingGood:
+		next.Step++
+	case models.RatingEasy:
+		return graduateCard(next, now, easyDays)
+	}
+
+	if next.Step >= len(ladder) {
+		return graduateCard(next, now, graduateDays)
+	}
+
+	delay := ladder[next.Step]
+	if rating == models.RatingHard && next.Step+1 < len(ladder) {
+		// Halfway to the next step, so hard still makes some progress
+		delay = (ladder[next.Step] + ladder[next.Step+1]) / 2
+	}
+	next.DueAt = now.Add(delay)
+	return next
+}
+
+func graduateCard(next *models.Schedule, now time.Time, intervalDays int) *models.Schedule {
+	next.State = models.CardStateReview
+	next.Step = 0
+	next.Repetitions = max(1, next.Repetitions)
+	next.IntervalDays = intervalDays
+	next.DueAt = now.AddDate(0, 0, intervalDays)
+	return next
+}
+
+// parseLearningStep reads a step such as "30s", "10m", "1h" or "1d"
+func parseLearningStep(step string) (time.Duration, error) {
+	step = strings.ToLower(strings.TrimSpace(step))
+	units := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour}
+
+	if len(step) < 2 {
+		return 0, models.Invalid("invalid learning step %q", step)
+	}
+	unit, ok := units[step[len(step)-1:]]
+	amount, err := strconv.Atoi(step[:len(step)-1])
+	if !ok || err != nil || amount <= 0 {
+		return 0, models.Invalid("invalid learning step %q: use a whole number with s, m, h or d, like 10m", step)
+	}
+
+	delay := time.Duration(amount) * unit
+	if delay > MAX_LEARNING_STEP {
+		return 0, models.Invalid("learning step %q cannot exceed %d days", step, int(MAX_LEARNING_STEP.Hours()/24))
+	}
+	return delay, nil
+}
+
+// Normalize and validate a list of learning steps
+func cleanLearningSteps(steps []string, label string) ([]string, error) {
+	if len(steps) > MAX_LEARNING_STEPS {
+		return nil, models.Invalid("%s cannot have more than %d entries", label, MAX_LEARNING_STEPS)
+	}
+	cleaned := make([]string, len(steps))
+	for i, step := range steps {
+		if _, err := parseLearningStep(step); err != nil {
+			return nil, err
+		}
+		cleaned[i] = strings.ToLower(strings.TrimSpace(step))
+	}
+	return cleaned, nil
+}
+
+func defaultStudySettings(deckID int) *models.DeckStudySettings {
+	return &models.DeckStudySettings{
+		DeckID:                 deckID,
+		LearningSteps:          append([]string{}, DEFAULT_LEARNING_STEPS...),
+		RelearningSteps:        append([]string{}, DEFAULT_RELEARNING_STEPS...),
+		GraduatingIntervalDays: DEFAULT_GRADUATING_INTERVAL_DAYS,
+		EasyIntervalDays:       DEFAULT_EASY_INTERVAL_DAYS,
+		NewCardsPerDay:         DEFAULT_NEW_CARDS_PER_DAY,
+		NewCardOrder:           models.NewCardOrderSequential,
+		NewCardPosition:        models.NewCardPositionAfterReviews,
+		LeechThreshold:         LEECH_LAPSES,
+		LeechAction:            models.LeechActionTag,
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// placeNewCards puts new cards before or after the reviews, or spreads them
+// evenly between reviews
+func placeNewCards(reviews, newCards []*models.DueCard, position string) []*models.DueCard {
+	placed := make([]*models.DueCard, 0, len(reviews)+len(newCards))
+	switch {
+	case position == models.NewCardPositionBeforeReviews:
+		placed = append(append(placed, newCards...), reviews...)
+	case position == models.NewCardPositionMixed && len(newCards) > 0:
+		// One new card after every gap reviews, rounding so the new cards
+		// span the whole queue
+		gap := float64(len(reviews)+len(newCards)) / float64(len(newCards))
+		next := 0
+		for len(placed) < cap(placed) {
+			if next < len(newCards) && float64(len(placed)) >= math.Floor(float64(next)*gap) {
+				placed = append(placed, newCards[next])
+				next++
+				continue
+			}
+			placed = append(placed, reviews[len(placed)-next])
+		}
+	default:
+		placed = append(append(placed, reviews...), newCards...)
+	}
+	return placed
+}
+
+// orderDueCards arranges due cards by a review order strategy. Cards arrive
+// sorted by due date, which every strategy uses as its tiebreaker.
+func orderDueCards(cards []*models.DueCard, order string) []*models.DueCard {
+	switch order {
+	case models.ReviewOrderRandom:
+		rand.Shuffle(len(cards), func(i, j int) {
+			cards[i], cards[j] = cards[j], cards[i]
+		})
+	case models.ReviewOrderHardestFirst:
+		// Lowest ease first, then most lapses; new cards have no history
+		// and go last
+		sort.SliceStable(cards, func(i, j int) bool {
+			a, b := cards[i].Schedule, cards[j].Schedule
+			if (a.State == models.CardStateNew) != (b.State == models.CardStateNew) {
+				return b.State == models.CardStateNew
+			}
+			if a.EaseFactor != b.EaseFactor {
+				return a.EaseFactor < b.EaseFactor
+			}
+			return a.Lapses > b.Lapses
+		})
+	case models.ReviewOrderBySubdeck:
+		sort.SliceStable(cards, func(i, j int) bool {
+			return deckKey(cards[i]) < deckKey(cards[j])
+		})
+	case models.ReviewOrderInterleaved:
+		cards = interleaveByDeck(cards)
+	}
+	return cards
+}
+
+// Round-robin across subdecks so consecutive cards come from different
+// topics, keeping due order within each subdeck
+func interleaveByDeck(cards []*models.DueCard) []*models.DueCard {
+	var keys []int
+	groups := make(map[int][]*models.DueCard)
+	for _, card := range cards {
+		key := deckKey(card)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], card)
+	}
+
+	interleaved := make([]*models.DueCard, 0, len(cards))
+	for len(interleaved) < len(cards) {
+		for _, key := range keys {
+			if group := groups[key]; len(group) > 0 {
+				interleaved = append(interleaved, group[0])
+				groups[key] = group[1:]
+			}
+		}
+	}
+	return interleaved
+}
+
+// Cards outside any deck group together under 0
+func deckKey(card *models.DueCard) int {
+	if card.Flashcard.DeckID == nil {
+		return 0
+	}
+	return *card.Flashcard.DeckID
+}
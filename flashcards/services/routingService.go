@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+var (
+	validQuestionTypes = []string{"multiple-choice", "essay", "true-false", QUESTION_TYPE_MATH}
+	validDifficulties  = []string{"easy", "medium", "hard"}
+	validProviders     = []string{PROVIDER_OPENAI, PROVIDER_ANTHROPIC, PROVIDER_OLLAMA}
+)
+
+type RoutingService struct {
+	repo          db.RoutingRuleRepository
+	organizations db.OrganizationRepository
+}
+
+func NewRoutingService(repo db.RoutingRuleRepository, organizations db.OrganizationRepository) *RoutingService {
+	return &RoutingService{repo: repo, organizations: organizations}
+}
+
+func (s *RoutingService) CreateRule(ctx context.Context, req *models.CreateRoutingRuleRequest) (*models.RoutingRule, error) {
+	if err := s.validateCreateRequest(req); err != nil {
+		return nil, err
+	}
+
+	if req.OrganizationID != nil {
+		_, err := s.organizations.GetOrganization(ctx, *req.OrganizationID)
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.Invalid("organization %d not found", *req.OrganizationID)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rule := &models.RoutingRule{
+		QuestionType:   strings.TrimSpace(req.QuestionType),
+		Difficulty:     strings.TrimSpace(req.Difficulty),
+		OrganizationID: req.OrganizationID,
+		Plan:           strings.TrimSpace(req.Plan),
+		Provider:       strings.TrimSpace(req.Provider),
+		Model:          strings.TrimSpace(req.Model),
+		Priority:       100,
+	}
+	if req.Priority != nil {
+		rule.Priority = *req.Priority
+	}
+
+	if err := s.repo.CreateRule(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to create routing rule: %w", err)
+	}
+
+	return rule, nil
+}
+
+func (s *RoutingService) GetAllRules(ctx context.Context) ([]*models.RoutingRule, error) {
+	rules, err := s.repo.GetAllRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get routing rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+func (s *RoutingService) DeleteRule(ctx context.Context, id int) error {
+	if id <= 0 {
+		return models.Invalid("invalid routing rule ID: %d", id)
+	}
+
+	return s.repo.DeleteRule(ctx, id)
+}
+
+// Resolve returns the first rule matching the request of the user in ctx,
+// or nil when no rule applies and the configured provider and model should
+// be used. The user's organization and plan are only looked up when a rule
+// matches on them; without a user in ctx such rules never match.
+func (s *RoutingService) Resolve(ctx context.Context, questionType, difficulty string) *models.RoutingRule {
+	logger := LoggerFromContext(ctx)
+	rules, err := s.repo.GetAllRules(ctx)
+	if err != nil {
+		logger.Error("Failed to load routing rules, falling back to default model", "error", err)
+		return nil
+	}
+
+	var requester *routingRequester
+	for _, rule := range rules {
+		if rule.QuestionType != "" && rule.QuestionType != questionType {
+			continue
+		}
+		if rule.Difficulty != "" && rule.Difficulty != difficulty {
+			continue
+		}
+		if rule.OrganizationID != nil || rule.Plan != "" {
+			if requester == nil {
+				requester = s.requester(ctx)
+			}
+			if !requester.matches(rule) {
+				continue
+			}
+		}
+		logger.Info("Routing rule matched", "rule_id", rule.ID, "question_type", questionType, "difficulty", difficulty, "provider", rule.Provider, "model", rule.Model)
+		return rule
+	}
+
+	return nil
+}
+
+// ResolveModel returns the model of the rule Resolve picks, or an empty
+// string when no rule applies and the client default should be used
+func (s *RoutingService) ResolveModel(ctx context.Context, questionType, difficulty string) string {
+	if rule := s.Resolve(ctx, questionType, difficulty); rule != nil {
+		return rule.Model
+	}
+	return ""
+}
+
+// routingRequester is the organization and plan of the user a request is
+// routed for. known is false when there is no user, or their organization
+// could not be read.
+type routingRequester struct {
+	known          bool
+	organizationID int
+	plan           string
+}
+
+func (s *RoutingService) requester(ctx context.Context) *routingRequester {
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		return &routingRequester{}
+	}
+
+	organization, err := s.organizations.GetUserOrganization(ctx, userID)
+	if errors.Is(err, models.ErrNotFound) {
+		return &routingRequester{known: true, plan: models.PlanFree}
+	}
+	if err != nil {
+		LoggerFromContext(ctx).Error("Failed to load organization for routing, skipping organization and plan rules", "user_id", userID, "error", err)
+		return &routingRequester{}
+	}
+	return &routingRequester{known: true, organizationID: organization.ID, plan: organization.Plan}
+}
+
+func (r *routingRequester) matches(rule *models.RoutingRule) bool {
+	if !r.known {
+		return false
+	}
+	if rule.OrganizationID != nil && *rule.OrganizationID != r.organizationID {
+		return false
+	}
+	return rule.Plan == "" || rule.Plan == r.plan
+}
+
+func (s *RoutingService) validateCreateRequest(req *models.CreateRoutingRuleRequest) error {
+	if req == nil {
+		return models.Invalid("request cannot be nil")
+	}
+
+	if strings.TrimSpace(req.Model) == "" {
+		return models.Invalid("model is required")
+	}
+
+	if req.QuestionType != "" && !containsValue(validQuestionTypes, req.QuestionType) {
+		return models.Invalid("questionType must be one of: %s", strings.Join(validQuestionTypes, ", "))
+	}
+
+	if req.Difficulty != "" && !containsValue(validDifficulties, req.Difficulty) {
+		return models.Invalid("difficulty must be one of: %s", strings.Join(validDifficulties, ", "))
+	}
+
+	if req.OrganizationID != nil && *req.OrganizationID <= 0 {
+		return models.Invalid("organizationId must be positive")
+	}
+
+	if req.Plan != "" && !containsValue(validPlans, req.Plan) {
+		return models.Invalid("plan must be one of: %s", strings.Join(validPlans, ", "))
+	}
+
+	if req.Provider != "" && !containsValue(validProviders, req.Provider) {
+		return models.Invalid("provider must be one of: %s", strings.Join(validProviders, ", "))
+	}
+
+	return nil
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"flashcards/db"
+)
+
+type scheduledJob struct {
+	name        string
+	interval    time.Duration
+	run         func(ctx context.Context) error
+	perInstance bool
+}
+
+// Scheduler runs registered jobs on fixed intervals in background goroutines.
+// Jobs get a context that is cancelled when the scheduler stops.
+type Scheduler struct {
+	jobs     []scheduledJob
+	claims   db.ScheduledJobRepository
+	instance string
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewScheduler returns a scheduler that coordinates with other instances
+// through claims. Without claims every instance runs every job.
+func NewScheduler(claims db.ScheduledJobRepository) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	hostname, _ := os.Hostname()
+	return &Scheduler{
+		claims:   claims,
+		instance: fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Every registers a job to run once per interval across all instances
+// after the scheduler starts. At each tick the instance that claims the run
+// does the work and the others skip it.
+func (s *Scheduler) Every(name string, interval time.Duration, run func(ctx context.Context) error) {
+	s.jobs = append(s.jobs, scheduledJob{name: name, interval: interval, run: run})
+}
+
+// EveryInstance registers a job that every instance runs once per interval,
+// for work on its own state such as in-memory buffers, or work claimed row
+// by row that instances can share
+func (s *Scheduler) EveryInstance(name string, interval time.Duration, run func(ctx context.Context) error) {
+	s.jobs = append(s.jobs, scheduledJob{name: name, interval: interval, run: run, perInstance: true})
+}
+
+func (s *Scheduler) Start() {
+	for _, job := range s.jobs {
+		slog.Info("Scheduling job", "job", job.name, "interval", job.interval.String())
+		s.wg.Add(1)
+		go s.loop(job)
+	}
+}
+
+// Stop cancels running jobs and waits for them to return
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(job scheduledJob) {
+	defer s.wg.Done()
+
+	logger := slog.Default().With("job", job.name)
+	ctx := ContextWithLogger(s.ctx, logger)
+
+	ticker := time.NewTicker(job.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !s.claim(ctx, job) {
+				continue
+			}
+			logger.Info("Running scheduled job")
+			startTime := time.Now()
+			if err := job.run(ctx); err != nil {
+				logger.Error("Scheduled job failed", "duration_ms", time.Since(startTime).Milliseconds(), "error", err)
+				continue
+			}
+			logger.Info("Scheduled job completed", "duration_ms", time.Since(startTime).Milliseconds())
+		}
+	}
+}
+
+// claim reports whether this instance should run the job now. A run is due
+// once most of an interval has passed since the last claim, so instances
+// whose tickers drift apart still take turns instead of skipping runs.
+func (s *Scheduler) claim(ctx context.Context, job scheduledJob) bool {
+	if job.perInstance || s.claims == nil {
+		return true
+	}
+
+	claimed, err := s.claims.ClaimJobRun(ctx, job.name, s.instance, job.interval-job.interval/10)
+	if err != nil {
+		LoggerFromContext(ctx).Error("Failed to claim scheduled job", "error", err)
+		return false
+	}
+	if !claimed {
+		LoggerFromContext(ctx).Debug("Scheduled job run claimed by another instance")
+	}
+	return claimed
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	SPEND_RECENT_WINDOW         = time.Hour
+	SPEND_BASELINE_PERIOD       = 7 * 24 * time.Hour
+	SPEND_ALERT_COOLDOWN        = 6 * time.Hour
+	DEFAULT_SPEND_ANOMALY_LIMIT = 50
+)
+
+// SpendConfig holds LLM spend anomaly settings. A spike is recent hourly
+// usage above SpikeMultiplier times the baseline hourly average, and at
+// least MinTokens. A zero ThrottleDuration only alerts.
+type SpendConfig struct {
+	SpikeMultiplier  int
+	MinTokens        int
+	AlertEmails      []string
+	ThrottleDuration time.Duration
+}
+
+// SpendService watches LLM token usage per user and organization for
+// spikes over their trailing baseline, alerts on them and can throttle
+// the subject's LLM calls for a while
+type SpendService struct {
+	repo   db.SpendRepository
+	mailer *Mailer
+	cfg    SpendConfig
+}
+
+func NewSpendService(repo db.SpendRepository, mailer *Mailer, cfg SpendConfig) *SpendService {
+	if cfg.ThrottleDuration <= 0 {
+		slog.Info("Spend throttle duration not configured, spend anomalies only alert")
+	}
+	return &SpendService{repo: repo, mailer: mailer, cfg: cfg}
+}
+
+// DetectAnomalies compares recent token usage with the hourly average over
+// the week before it. Each subject is reported at most once
+// per SPEND_ALERT_COOLDOWN.
+func (s *SpendService) DetectAnomalies(ctx context.Context) error {
+	logger := LoggerFromContext(ctx)
+
+	// Usage is stored in hourly buckets, so the recent window covers the
+	// current hour and the one before it
+	recentStart := time.Now().UTC().Truncate(time.Hour).Add(-SPEND_RECENT_WINDOW)
+	baselineStart := recentStart.Add(-SPEND_BASELINE_PERIOD)
+
+	windows, err := s.repo.GetSpendWindows(ctx, baselineStart, recentStart)
+	if err != nil {
+		return err
+	}
+
+	detected := 0
+	for _, window := range windows {
+		baselinePerHour := float64(window.BaselineTokens) / SPEND_BASELINE_PERIOD.Hours()
+		if !s.isSpike(window, baselinePerHour) {
+			continue
+		}
+
+		reported, err := s.repo.HasAnomalySince(ctx, window.Scope, window.SubjectID, time.Now().Add(-SPEND_ALERT_COOLDOWN))
+		if err != nil {
+			return err
+		}
+		if reported {
+			continue
+		}
+
+		anomaly := &models.SpendAnomaly{
+			Scope:                 window.Scope,
+			SubjectID:             window.SubjectID,
+			RecentTokens:          window.RecentTokens,
+			BaselineTokensPerHour: baselinePerHour,
+		}
+		if s.cfg.ThrottleDuration > 0 {
+			throttledUntil := time.Now().Add(s.cfg.ThrottleDuration)
+			anomaly.ThrottledUntil = &throttledUntil
+		}
+		if err := s.repo.CreateAnomaly(ctx, anomaly); err != nil {
+			return err
+		}
+		detected++
+
+		logger.Warn("LLM spend anomaly detected",
+			"scope", anomaly.Scope,
+			"subject_id", anomaly.SubjectID,
+			"recent_tokens", anomaly.RecentTokens,
+			"baseline_tokens_per_hour", anomaly.BaselineTokensPerHour,
+			"throttled", anomaly.ThrottledUntil != nil)
+		s.notify(ctx, anomaly)
+	}
+
+	logger.Info("Checked LLM spend", "subjects", len(windows), "anomalies", detected)
+	return nil
+}
+
+// The recent window spans two hourly buckets, so it is compared against
+// twice the hourly baseline
+func (s *SpendService) isSpike(window *models.SpendWindow, baselinePerHour float64) bool {
+	if window.RecentTokens < s.cfg.MinTokens {
+		return false
+	}
+	expected := baselinePerHour * 2 * float64(s.cfg.SpikeMultiplier)
+	return float64(window.RecentTokens) > expected
+}
+
+func (s *SpendService) notify(ctx context.Context, anomaly *models.SpendAnomaly) {
+	if !s.mailer.Enabled() || len(s.cfg.AlertEmails) == 0 {
+		return
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "LLM token usage spiked for %s %d.\n\n", anomaly.Scope, anomaly.SubjectID)
+	fmt.Fprintf(&body, "Tokens in the previous and current hour: %d\n", anomaly.RecentTokens)
+	fmt.Fprintf(&body, "Baseline tokens per hour: %.0f\n", anomaly.BaselineTokensPerHour)
+	if anomaly.ThrottledUntil != nil {
+		fmt.Fprintf(&body, "\nLLM calls are throttled until %s (anomaly %d).\n",
+			anomaly.ThrottledUntil.UTC().Format(time.RFC1123), anomaly.ID)
+	}
+
+	subject := "LLM spend spike for " + anomaly.Scope + " " + strconv.Itoa(anomaly.SubjectID)
+	if err := s.mailer.Send(s.cfg.AlertEmails, subject, body.String()); err != nil {
+		LoggerFromContext(ctx).Error("Failed to send spend anomaly alert", "anomaly_id", anomaly.ID, "error", err)
+	}
+}
+
+// CheckThrottle returns a quota error while the user or their organization
+// is throttled after a spend anomaly
+func (s *SpendService) CheckThrottle(ctx context.Context, userID int) error {
+	throttledUntil, err := s.repo.GetThrottledUntil(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if throttledUntil != nil {
+		return models.QuotaExceeded("LLM quota exceeded: unusual token usage was detected, LLM features are paused until %s",
+			throttledUntil.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (s *SpendService) GetAnomalies(ctx context.Context, limit int) ([]*models.SpendAnomaly, error) {
+	if limit == 0 {
+		limit = DEFAULT_SPEND_ANOMALY_LIMIT
+	}
+	if limit < 1 || limit > MAX_PAGE_LIMIT {
+		return nil, models.Invalid("limit must be between 1 and %d", MAX_PAGE_LIMIT)
+	}
+
+	return s.repo.GetAnomalies(ctx, limit)
+}
+
+// LiftThrottle lets a throttled subject use LLM features again
+func (s *SpendService) LiftThrottle(ctx context.Context, id int) (*models.SpendAnomaly, error) {
+	anomaly, err := s.repo.LiftThrottle(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	LoggerFromContext(ctx).Info("Lifted LLM spend throttle", "anomaly_id", id)
+	return anomaly, nil
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"flashcards/models"
+	"flashcards/storage"
+)
+
+// Expired exports are deleted this many at a time
+const DECK_EXPORT_CLEANUP_BATCH = 100
+
+// StoreExport writes the deck's export to object storage, for the client
+// to download from a pre-signed URL rather than through the API server
+func (s *DeckExportService) StoreExport(ctx context.Context, userID, deckID int, format string) (*models.StoredDeckExport, error) {
+	if s.store == nil {
+		return nil, models.Invalid("stored exports need object storage, which is not configured")
+	}
+	if ExportContentType(format) == "" {
+		return nil, models.Invalid("format must be one of: apkg, csv")
+	}
+
+	export, err := s.ExportDeck(ctx, userID, deckID, format)
+	if err != nil {
+		return nil, err
+	}
+
+	// The export is encoded to a temporary file first, since the store
+	// needs its size up front
+	file, err := os.CreateTemp("", "deck-export-*")
+	if err != nil {
+		return nil, models.Internal("failed to create export file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := export.Encode(file); err != nil {
+		return nil, models.Internal("failed to encode export: %w", err)
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, models.Internal("failed to read export file: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, models.Internal("failed to read export file: %w", err)
+	}
+
+	stored := &models.StoredDeckExport{
+		UserID:      userID,
+		DeckID:      &deckID,
+		Format:      format,
+		Filename:    export.Filename,
+		ContentType: export.ContentType,
+		Size:        size,
+		StorageKey:  storage.NewKey("exports/"+strconv.Itoa(userID), export.Filename),
+		ExpiresAt:   time.Now().Add(s.retention),
+	}
+	if err := s.store.Put(ctx, stored.StorageKey, file, size, stored.ContentType); err != nil {
+		return nil, models.Internal("failed to store export: %w", err)
+	}
+	if err := s.repo.CreateExport(ctx, stored); err != nil {
+		if err := s.store.Delete(ctx, stored.StorageKey); err != nil {
+			LoggerFromContext(ctx).Error("Failed to delete export file", "key", stored.StorageKey, "error", err)
+		}
+		return nil, err
+	}
+
+	LoggerFromContext(ctx).Info("Stored deck export", "deck_id", deckID, "export_id", stored.ID, "size", size)
+	return s.withDownloadURL(stored)
+}
+
+// GetExport returns a stored export with a fresh download URL
+func (s *DeckExportService) GetExport(ctx context.Context, userID, id int) (*models.StoredDeckExport, error) {
+	if s.store == nil {
+		return nil, models.NotFound("deck export with id %d not found", id)
+	}
+
+	export, err := s.repo.GetExport(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.withDownloadURL(export)
+}
+
+// GetExports returns the user's stored exports that have not expired,
+// newest first
+func (s *DeckExportService) GetExports(ctx context.Context, userID int) ([]*models.StoredDeckExport, error) {
+	if s.store == nil {
+		return []*models.StoredDeckExport{}, nil
+	}
+
+	exports, err := s.repo.GetExports(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// Exports expiring this moment are left out rather than signed
+	current := make([]*models.StoredDeckExport, 0, len(exports))
+	for _, export := range exports {
+		if _, err := s.withDownloadURL(export); errors.Is(err, models.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		current = append(current, export)
+	}
+	return current, nil
+}
+
+// DeleteExpired deletes expired exports and their files. An export whose
+// file cannot be deleted is kept for the next run.
+func (s *DeckExportService) DeleteExpired(ctx context.Context) error {
+	if s.store == nil {
+		return nil
+	}
+
+	exports, err := s.repo.GetExpiredExports(ctx, time.Now(), DECK_EXPORT_CLEANUP_BATCH)
+	if err != nil {
+		return err
+	}
+
+	deleted := 0
+	for _, export := range exports {
+		if err := s.store.Delete(ctx, export.StorageKey); err != nil {
+			LoggerFromContext(ctx).Error("Failed to delete export file", "export_id", export.ID, "error", err)
+			continue
+		}
+		if err := s.repo.DeleteExport(ctx, export.ID); err != nil {
+			return err
+		}
+		deleted++
+	}
+
+	if deleted > 0 {
+		LoggerFromContext(ctx).Info("Deleted expired deck exports", "count", deleted)
+	}
+	return nil
+}
+
+// withDownloadURL signs the export's download URL, valid for
+// STORAGE_URL_EXPIRY or until the export expires
+func (s *DeckExportService) withDownloadURL(export *models.StoredDeckExport) (*models.StoredDeckExport, error) {
+	expiry := min(STORAGE_URL_EXPIRY, time.Until(export.ExpiresAt).Truncate(time.Second))
+	if expiry < time.Second {
+		return nil, models.NotFound("deck export with id %d not found", export.ID)
+	}
+
+	url, err := s.store.PresignGet(export.StorageKey, export.Filename, expiry)
+	if err != nil {
+		return nil, models.Internal("failed to sign download URL: %w", err)
+	}
+	export.DownloadURL = url
+	return export, nil
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Times output that fails to parse or validate is sent back to the model
+// for correction before giving up
+const MAX_STRUCTURED_OUTPUT_RETRIES = 2
+
+// errInvalidStructuredOutput is returned when the model still produces
+// unusable output after every correction
+var errInvalidStructuredOutput = errors.New("model output did not match the expected format")
+
+// outputSchema describes the JSON a prompt must produce. Schema is a JSON
+// Schema object: it is sent to the provider as the parameters of a tool and
+// checked locally against the reply, since not every provider enforces it.
+// Only the keywords validateJSONSchema understands should be used.
+type outputSchema struct {
+	Name        string
+	Description string
+	Schema      map[string]any
+}
+
+// structuredOutputOptions makes the provider answer with JSON. OpenAI and
+// Anthropic are made to call a tool whose parameters are the schema; Ollama
+// only offers a JSON mode, so there the schema is enforced by validation.
+func (s *QuizService) structuredOutputOptions(schema outputSchema) []llms.CallOption {
+	if s.provider == PROVIDER_OLLAMA {
+		return []llms.CallOption{llms.WithJSONMode()}
+	}
+
+	tool := llms.Tool{
+		Type: "function",
+		Function: &llms.FunctionDefinition{
+			Name:        schema.Name,
+			Description: schema.Description,
+			Parameters:  schema.Schema,
+		},
+	}
+	return []llms.CallOption{
+		llms.WithTools([]llms.Tool{tool}),
+		llms.WithToolChoice(llms.ToolChoice{Type: "function", Function: &llms.FunctionReference{Name: schema.Name}}),
+	}
+}
+
+// generateStructured sends messages to client asking for JSON matching
+// schema and decodes the reply into target. validate, when set, runs after
+// decoding for rules the schema cannot express. Output that fails either
+// check is sent back with the problem so the model can correct it. Errors
+// from the model call are returned as is; unusable output wraps
+// errInvalidStructuredOutput.
+func (s *QuizService) generateStructured(ctx context.Context, client LLMClient, messages []llms.MessageContent, schema outputSchema, target any, validate func() error, options ...llms.CallOption) error {
+	logger := LoggerFromContext(ctx).With("schema", schema.Name)
+
+	messages = append([]llms.MessageContent(nil), messages...)
+	options = append(options, s.structuredOutputOptions(schema)...)
+
+	for attempt := 0; ; attempt++ {
+		response, err := client.GenerateContent(ctx, messages, options...)
+		if err != nil {
+			return err
+		}
+
+		output := structuredOutputText(response, schema.Name)
+		err = decodeStructuredOutput(output, schema, target)
+		if err == nil && validate != nil {
+			err = validate()
+		}
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= MAX_STRUCTURED_OUTPUT_RETRIES {
+			logger.Error("Model output still invalid after corrections", "attempts", attempt+1, "error", err)
+			return fmt.Errorf("%w: %v", errInvalidStructuredOutput, err)
+		}
+		logger.Warn("Model output invalid, asking for a correction", "attempt", attempt+1, "error", err)
+
+		messages = append(messages, llms.TextParts(llms.ChatMessageTypeHuman, fmt.Sprintf(
+			"Your previous response could not be used: %v\n\nPrevious response:\n%s\n\nReply again with output that fixes this and matches the %s schema.",
+			err, output, schema.Name)))
+	}
+}
+
+// structuredOutputText returns the arguments of the call to the schema's
+// tool, or the text of the reply when the provider answered without one
+func structuredOutputText(response *llms.ContentResponse, name string) string {
+	if len(response.Choices) == 0 {
+		return ""
+	}
+
+	choice := response.Choices[0]
+	for _, call := range choice.ToolCalls {
+		if call.FunctionCall != nil && call.FunctionCall.Name == name {
+			return call.FunctionCall.Arguments
+		}
+	}
+
+	// Some models wrap JSON mode output in a Markdown code fence
+	text := strings.TrimSpace(choice.Content)
+	if strings.HasPrefix(text, "```") && strings.HasSuffix(text, "```") {
+		text = strings.TrimSuffix(text, "```")
+		if _, rest, found := strings.Cut(text, "\n"); found {
+			text = rest
+		}
+	}
+	return strings.TrimSpace(text)
+}
+
+// decodeStructuredOutput checks output against the schema before decoding
+// it into target
+func decodeStructuredOutput(output string, schema outputSchema, target any) error {
+	if output == "" {
+		return fmt.Errorf("response was empty")
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(output))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("response is not valid JSON: %v", err)
+	}
+	if decoder.More() {
+		return fmt.Errorf("response has text after the JSON value")
+	}
+
+	if err := validateJSONSchema(schema.Schema, value, ""); err != nil {
+		return err
+	}
+
+	// Fields left over from a rejected attempt must not survive a retry
+	reflect.ValueOf(target).Elem().SetZero()
+	if err := json.Unmarshal([]byte(output), target); err != nil {
+		return fmt.Errorf("response does not match the schema: %v", err)
+	}
+	return nil
+}
+
+// validateJSONSchema checks value, decoded with UseNumber, against the
+// subset of JSON Schema the output schemas use: type, properties, required,
+// items, minItems, maxItems and enum. path names the value in errors.
+func validateJSONSchema(schema map[string]any, value any, path string) error {
+	name := path
+	if name == "" {
+		name = "response"
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s must be an object", name)
+		}
+		required, _ := schema["required"].([]string)
+		for _, field := range required {
+			if _, ok := object[field]; !ok {
+				return fmt.Errorf("%s is required", schemaPath(path, field))
+			}
+		}
+
+		properties, _ := schema["properties"].(map[string]any)
+		fields := make([]string, 0, len(object))
+		for field := range object {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			property, ok := properties[field].(map[string]any)
+			if !ok {
+				continue
+			}
+			if err := validateJSONSchema(property, object[field], schemaPath(path, field)); err != nil {
+				return err
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s must be an array", name)
+		}
+		if minItems, ok := schema["minItems"].(int); ok && len(items) < minItems {
+			return fmt.Errorf("%s must have at least %d items", name, minItems)
+		}
+		if maxItems, ok := schema["maxItems"].(int); ok && len(items) > maxItems {
+			return fmt.Errorf("%s must have at most %d items", name, maxItems)
+		}
+		if itemSchema, ok := schema["items"].(map[string]any); ok {
+			for i, item := range items {
+				if err := validateJSONSchema(itemSchema, item, fmt.Sprintf("%s[%d]", name, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", name)
+		}
+		if enum, ok := schema["enum"].([]string); ok && !containsValue(enum, text) {
+			return fmt.Errorf("%s must be one of: %s", name, strings.Join(enum, ", "))
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if _, err := number.Int64(); !ok || err != nil {
+			return fmt.Errorf("%s must be an integer", name)
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return fmt.Errorf("%s must be a number", name)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be a boolean", name)
+		}
+	}
+	return nil
+}
+
+func schemaPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
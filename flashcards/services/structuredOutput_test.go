@@ -0,0 +1,80 @@
+package services
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// FuzzStructuredOutput feeds arbitrary model replies, as text or as the
+// arguments of the schema's tool call, through the parser of generated
+// questions. Whatever the reply, parsing must not panic, and a question it
+// accepts must hold to questionSchema.
+func FuzzStructuredOutput(f *testing.F) {
+	for _, seed := range []string{
+		`{"question":"What do mitochondria make?","type":"essay","explanation":"They make ATP.","difficulty":"easy"}`,
+		"```json\n{\"question\":\"2+2?\",\"type\":\"multiple-choice\",\"options\":[\"3\",\"4\"],\"correctAnswer\":\"4\",\"explanation\":\"Sum\",\"difficulty\":\"easy\"}\n```",
+		`{"question":"光合作用とは？ 🌱","type":"essay","explanation":"été","difficulty":"hard"}`,
+		`{"question":"Cut off","type":`,
+		`{"question":1,"type":"essay","explanation":null,"difficulty":"medium"}`,
+		`{"question":"Twice","type":"essay","explanation":"e","difficulty":"easy"} {"again":true}`,
+		`[{"question":"In an array"}]`,
+		"```",
+		"\xff\xfe not utf-8",
+		"",
+	} {
+		f.Add(seed, false)
+		f.Add(seed, true)
+	}
+
+	f.Fuzz(func(t *testing.T, reply string, asToolCall bool) {
+		choice := &llms.ContentChoice{Content: reply}
+		if asToolCall {
+			choice = &llms.ContentChoice{ToolCalls: []llms.ToolCall{{
+				FunctionCall: &llms.FunctionCall{Name: questionSchema.Name, Arguments: reply},
+			}}}
+		}
+
+		output := structuredOutputText(&llms.ContentResponse{Choices: []*llms.ContentChoice{choice}}, questionSchema.Name)
+		if asToolCall && output != reply {
+			t.Fatalf("tool call arguments %q came back as %q", reply, output)
+		}
+
+		var question llmQuestion
+		if err := decodeStructuredOutput(output, questionSchema, &question); err != nil {
+			return
+		}
+		if err := question.validate(); err != nil {
+			return
+		}
+		if strings.TrimSpace(question.Question) == "" {
+			t.Errorf("accepted a question with no text: %+v", question)
+		}
+		if question.Type == "multiple-choice" && len(question.Options) < 2 {
+			t.Errorf("accepted a multiple-choice question with options %q", question.Options)
+		}
+
+		if !slices.Contains(validQuestionTypes, question.Type) {
+			t.Errorf("accepted question type %q", question.Type)
+		}
+		if !slices.Contains(validDifficulties, question.Difficulty) {
+			t.Errorf("accepted difficulty %q", question.Difficulty)
+		}
+
+		// An accepted question is itself a reply the parser accepts
+		encoded, err := json.Marshal(question)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		var again llmQuestion
+		if err := decodeStructuredOutput(string(encoded), questionSchema, &again); err != nil {
+			t.Fatalf("accepted %q but not its own encoding %s: %v", output, encoded, err)
+		}
+		if err := again.validate(); err != nil {
+			t.Fatalf("accepted %q but its own encoding %s is invalid: %v", output, encoded, err)
+		}
+	})
+}
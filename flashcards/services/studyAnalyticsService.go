@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	DEFAULT_STUDY_ANALYTICS_DAYS = 30
+	MAX_STUDY_ANALYTICS_DAYS     = 365
+	MOST_MISSED_NOTES_LIMIT      = 10
+	// Streaks are counted over this many days, whatever the requested range
+	STREAK_LOOKBACK_DAYS = 365
+)
+
+// StudyAnalyticsService summarizes a user's study history from flashcard
+// reviews and quiz answers, aggregated in SQL
+type StudyAnalyticsService struct {
+	repo db.StudyAnalyticsRepository
+}
+
+func NewStudyAnalyticsService(repo db.StudyAnalyticsRepository) *StudyAnalyticsService {
+	return &StudyAnalyticsService{repo: repo}
+}
+
+// GetStudyAnalytics covers the last days days, including today
+func (s *StudyAnalyticsService) GetStudyAnalytics(ctx context.Context, userID, days int) (*models.StudyAnalytics, error) {
+	if days == 0 {
+		days = DEFAULT_STUDY_ANALYTICS_DAYS
+	}
+	if days < 1 || days > MAX_STUDY_ANALYTICS_DAYS {
+		return nil, models.Invalid("days must be between 1 and %d", MAX_STUDY_ANALYTICS_DAYS)
+	}
+
+	today := truncateToDay(time.Now())
+	since := today.AddDate(0, 0, -(days - 1))
+
+	reviews, err := s.repo.GetDailyReviews(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	accuracy, err := s.repo.GetAccuracyByDifficulty(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	for i := range accuracy {
+		accuracy[i].Accuracy = percentage(accuracy[i].Correct, accuracy[i].Graded)
+	}
+
+	activeDays, err := s.repo.GetActiveDays(ctx, userID, today.AddDate(0, 0, -STREAK_LOOKBACK_DAYS))
+	if err != nil {
+		return nil, err
+	}
+
+	missed, err := s.repo.GetMostMissedNotes(ctx, userID, since, MOST_MISSED_NOTES_LIMIT)
+	if err != nil {
+		return nil, err
+	}
+	for i := range missed {
+		missed[i].MissRate = percentage(missed[i].Missed, missed[i].Graded)
+	}
+
+	analytics := &models.StudyAnalytics{
+		Days:                 days,
+		Since:                since,
+		DailyReviews:         fillDailyReviews(reviews, since, days),
+		AccuracyByDifficulty: accuracy,
+		MostMissedNotes:      missed,
+	}
+	analytics.CurrentStreakDays, analytics.LongestStreakDays = studyStreaks(activeDays, today)
+	return analytics, nil
+}
+
+// fillDailyReviews returns one entry per day of the range, with zeros for
+// days without reviews
+func fillDailyReviews(reviews []models.DailyReviews, since time.Time, days int) []models.DailyReviews {
+	byDate := make(map[string]models.DailyReviews, len(reviews))
+	for _, day := range reviews {
+		byDate[day.Date] = day
+	}
+
+	filled := make([]models.DailyReviews, days)
+	for i := range filled {
+		date := since.AddDate(0, 0, i).Format("2006-01-02")
+		filled[i] = byDate[date]
+		filled[i].Date = date
+	}
+	return filled
+}
+
+// studyStreaks returns the current and longest runs of consecutive active
+// days, given the active days most recent first. As in progress reports, the
+// current streak may end yesterday if nothing was studied yet today.
+func studyStreaks(activeDays []time.Time, today time.Time) (int, int) {
+	current, longest, run := 0, 0, 0
+	var previous time.Time
+
+	for i, day := range activeDays {
+		// Dates come back from the database in UTC
+		day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, today.Location())
+		if i > 0 && previous.AddDate(0, 0, -1).Equal(day) {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+
+		// The current streak is the first run, if it reaches today or yesterday
+		if run == i+1 && !day.Before(today.AddDate(0, 0, -i-1)) {
+			current = run
+		}
+		previous = day
+	}
+	return current, longest
+}
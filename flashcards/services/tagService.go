@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	MAX_TAG_LENGTH    = 50
+	MAX_TAGS_PER_NOTE = 20
+)
+
+type TagService struct {
+	repo        db.TagRepository
+	noteService *NoteService
+}
+
+func NewTagService(repo db.TagRepository, noteService *NoteService) *TagService {
+	return &TagService{repo: repo, noteService: noteService}
+}
+
+func (s *TagService) GetAllTags(ctx context.Context, userID int) ([]*models.Tag, error) {
+	tags, err := s.repo.GetAllTags(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// AddTagsToNote attaches the requested tags to a note and returns the
+// updated note
+func (s *TagService) AddTagsToNote(ctx context.Context, userID, noteID int, req *models.AddTagsRequest) (*models.Note, error) {
+	if noteID <= 0 {
+		return nil, models.Invalid("invalid note ID: %d", noteID)
+	}
+
+	names, err := s.validateAddTagsRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.AddTagsToNote(ctx, userID, noteID, names); err != nil {
+		return nil, err
+	}
+	s.noteService.InvalidateCache(ctx, userID)
+
+	return s.noteService.GetNoteByID(ctx, userID, noteID)
+}
+
+func (s *TagService) RemoveTagFromNote(ctx context.Context, userID, noteID int, tag string) error {
+	if noteID <= 0 {
+		return models.Invalid("invalid note ID: %d", noteID)
+	}
+
+	name := normalizeTag(tag)
+	if name == "" {
+		return models.Invalid("tag cannot be empty")
+	}
+
+	if err := s.repo.RemoveTagFromNote(ctx, userID, noteID, name); err != nil {
+		return err
+	}
+	s.noteService.InvalidateCache(ctx, userID)
+	return nil
+}
+
+func (s *TagService) validateAddTagsRequest(req *models.AddTagsRequest) ([]string, error) {
+	if req == nil {
+		return nil, models.Invalid("request cannot be nil")
+	}
+
+	if len(req.Tags) == 0 {
+		return nil, models.Invalid("at least one tag is required")
+	}
+
+	if len(req.Tags) > MAX_TAGS_PER_NOTE {
+		return nil, models.Invalid("cannot add more than %d tags at once", MAX_TAGS_PER_NOTE)
+	}
+
+	seen := make(map[string]bool)
+	names := make([]string, 0, len(req.Tags))
+	for _, tag := range req.Tags {
+		name := normalizeTag(tag)
+		if name == "" {
+			return nil, models.Invalid("tags cannot be empty")
+		}
+		if len(name) > MAX_TAG_LENGTH {
+			return nil, models.Invalid("tags cannot exceed %d characters", MAX_TAG_LENGTH)
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+// Tags are case-insensitive and stored lowercased
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -16,30 +17,31 @@ func NewTodoService(repo db.TodoRepository) *TodoService {
 	return &TodoService{repo: repo}
 }
 
-func (s *TodoService) CreateTodo(req *models.CreateTodoRequest) (*models.Todo, error) {
+func (s *TodoService) CreateTodo(ctx context.Context, userID int, req *models.CreateTodoRequest) (*models.Todo, error) {
 	if err := s.validateCreateRequest(req); err != nil {
 		return nil, err
 	}
 
 	todo := &models.Todo{
+		UserID:      userID,
 		Title:       strings.TrimSpace(req.Title),
 		Description: strings.TrimSpace(req.Description),
 		Completed:   false,
 	}
 
-	if err := s.repo.CreateTodo(todo); err != nil {
+	if err := s.repo.CreateTodo(ctx, todo); err != nil {
 		return nil, fmt.Errorf("failed to create todo: %w", err)
 	}
 
 	return todo, nil
 }
 
-func (s *TodoService) GetTodoByID(id int) (*models.Todo, error) {
+func (s *TodoService) GetTodoByID(ctx context.Context, userID, id int) (*models.Todo, error) {
 	if id <= 0 {
-		return nil, fmt.Errorf("invalid todo ID: %d", id)
+		return nil, models.Invalid("invalid todo ID: %d", id)
 	}
 
-	todo, err := s.repo.GetTodoByID(id)
+	todo, err := s.repo.GetTodoByID(ctx, userID, id)
 	if err != nil {
 		return nil, err
 	}
@@ -47,8 +49,8 @@ func (s *TodoService) GetTodoByID(id int) (*models.Todo, error) {
 	return todo, nil
 }
 
-func (s *TodoService) GetAllTodos() ([]*models.Todo, error) {
-	todos, err := s.repo.GetAllTodos()
+func (s *TodoService) GetAllTodos(ctx context.Context, userID int) ([]*models.Todo, error) {
+	todos, err := s.repo.GetAllTodos(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get todos: %w", err)
 	}
@@ -56,9 +58,9 @@ func (s *TodoService) GetAllTodos() ([]*models.Todo, error) {
 	return todos, nil
 }
 
-func (s *TodoService) UpdateTodo(id int, req *models.UpdateTodoRequest) (*models.Todo, error) {
+func (s *TodoService) UpdateTodo(ctx context.Context, userID, id int, req *models.UpdateTodoRequest) (*models.Todo, error) {
 	if id <= 0 {
-		return nil, fmt.Errorf("invalid todo ID: %d", id)
+		return nil, models.Invalid("invalid todo ID: %d", id)
 	}
 
 	if err := s.validateUpdateRequest(req); err != nil {
@@ -70,7 +72,7 @@ func (s *TodoService) UpdateTodo(id int, req *models.UpdateTodoRequest) (*models
 	if req.Title != nil {
 		trimmedTitle := strings.TrimSpace(*req.Title)
 		if trimmedTitle == "" {
-			return nil, fmt.Errorf("title cannot be empty")
+			return nil, models.Invalid("title cannot be empty")
 		}
 		updates["title"] = trimmedTitle
 	}
@@ -84,36 +86,36 @@ func (s *TodoService) UpdateTodo(id int, req *models.UpdateTodoRequest) (*models
 	}
 
 	if len(updates) == 0 {
-		return nil, fmt.Errorf("no valid updates provided")
+		return nil, models.Invalid("no valid updates provided")
 	}
 
-	if err := s.repo.UpdateTodo(id, updates); err != nil {
+	if err := s.repo.UpdateTodo(ctx, userID, id, updates); err != nil {
 		return nil, err
 	}
 
-	return s.repo.GetTodoByID(id)
+	return s.repo.GetTodoByID(ctx, userID, id)
 }
 
-func (s *TodoService) DeleteTodo(id int) error {
+func (s *TodoService) DeleteTodo(ctx context.Context, userID, id int) error {
 	if id <= 0 {
-		return fmt.Errorf("invalid todo ID: %d", id)
+		return models.Invalid("invalid todo ID: %d", id)
 	}
 
-	return s.repo.DeleteTodo(id)
+	return s.repo.DeleteTodo(ctx, userID, id)
 }
 
 func (s *TodoService) validateCreateRequest(req *models.CreateTodoRequest) error {
 	if req == nil {
-		return fmt.Errorf("request cannot be nil")
+		return models.Invalid("request cannot be nil")
 	}
 
 	title := strings.TrimSpace(req.Title)
 	if title == "" {
-		return fmt.Errorf("title is required")
+		return models.Invalid("title is required")
 	}
 
 	if len(title) > 255 {
-		return fmt.Errorf("title cannot exceed 255 characters")
+		return models.Invalid("title cannot exceed 255 characters")
 	}
 
 	return nil
@@ -121,17 +123,17 @@ func (s *TodoService) validateCreateRequest(req *models.CreateTodoRequest) error
 
 func (s *TodoService) validateUpdateRequest(req *models.UpdateTodoRequest) error {
 	if req == nil {
-		return fmt.Errorf("request cannot be nil")
+		return models.Invalid("request cannot be nil")
 	}
 
 	if req.Title == nil && req.Description == nil && req.Completed == nil {
-		return fmt.Errorf("at least one field must be provided for update")
+		return models.Invalid("at least one field must be provided for update")
 	}
 
 	if req.Title != nil {
 		title := strings.TrimSpace(*req.Title)
 		if len(title) > 255 {
-			return fmt.Errorf("title cannot exceed 255 characters")
+			return models.Invalid("title cannot exceed 255 characters")
 		}
 	}
 
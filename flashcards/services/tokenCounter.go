@@ -0,0 +1,165 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+const (
+	DEFAULT_CONTEXT_WINDOW = 8192
+	// Tokens kept free for the model's response
+	RESPONSE_TOKEN_RESERVE = 1024
+	// Below this many tokens for notes a prompt is not worth sending
+	MIN_NOTES_TOKEN_BUDGET = 256
+)
+
+// Context window sizes by model name prefix. More specific prefixes come
+// first so "gpt-4o" wins over "gpt-4".
+var modelContextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-4o", 128000},
+	{"gpt-4.1", 1047576},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+	{"o1", 200000},
+	{"o3", 200000},
+	{"claude-", 200000},
+	{"llama3.1", 131072},
+	{"llama3.2", 131072},
+	{"llama3", 8192},
+	{"mistral", 32768},
+	{"qwen2.5", 32768},
+}
+
+// TokenCounter measures text in model tokens using tiktoken encodings.
+// Models without a tiktoken encoding fall back to cl100k_base, which is
+// close enough for budgeting.
+type TokenCounter struct {
+	model         string
+	contextWindow int
+}
+
+// NewTokenCounter returns a counter for the model. A contextWindow of 0
+// looks the size up from the model name.
+func NewTokenCounter(model string, contextWindow int) *TokenCounter {
+	if contextWindow <= 0 {
+		contextWindow = contextWindowForModel(model)
+	}
+	return &TokenCounter{model: model, contextWindow: contextWindow}
+}
+
+func contextWindowForModel(model string) int {
+	model = strings.ToLower(model)
+	for _, entry := range modelContextWindows {
+		if strings.HasPrefix(model, entry.prefix) {
+			return entry.tokens
+		}
+	}
+	return DEFAULT_CONTEXT_WINDOW
+}
+
+func (c *TokenCounter) Model() string {
+	return c.model
+}
+
+func (c *TokenCounter) ContextWindow() int {
+	return c.contextWindow
+}
+
+func (c *TokenCounter) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	return llms.CountTokens(c.model, text)
+}
+
+// Budget returns how many tokens are left for variable content once the
+// fixed part of a prompt and the response reserve are accounted for
+func (c *TokenCounter) Budget(fixedPrompt string) int {
+	return c.contextWindow - RESPONSE_TOKEN_RESERVE - c.Count(fixedPrompt)
+}
+
+// Chunk splits text into pieces of at most maxTokens, breaking between
+// paragraphs where possible, then between sentences, then between words.
+// Joining the chunks back together loses no content.
+func (c *TokenCounter) Chunk(text string, maxTokens int) []string {
+	if maxTokens <= 0 || c.Count(text) <= maxTokens {
+		return []string{text}
+	}
+
+	type unit struct {
+		text      string
+		tokens    int
+		separator string
+	}
+
+	var units []unit
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+		if tokens := c.Count(paragraph); tokens <= maxTokens {
+			units = append(units, unit{text: paragraph, tokens: tokens, separator: "\n\n"})
+			continue
+		}
+
+		separator := "\n\n"
+		for _, sentence := range splitSentences(paragraph) {
+			if tokens := c.Count(sentence); tokens <= maxTokens {
+				units = append(units, unit{text: sentence, tokens: tokens, separator: separator})
+			} else {
+				for _, piece := range c.splitWords(sentence, maxTokens) {
+					units = append(units, unit{text: piece, tokens: c.Count(piece), separator: separator})
+					separator = " "
+				}
+			}
+			separator = " "
+		}
+	}
+
+	var chunks []string
+	var current strings.Builder
+	used := 0
+	for _, u := range units {
+		// One token of slack per join keeps the running total conservative
+		if used > 0 && used+u.tokens+1 > maxTokens {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			used = 0
+		}
+		if used > 0 {
+			current.WriteString(u.separator)
+			used++
+		}
+		current.WriteString(u.text)
+		used += u.tokens
+	}
+	if current.Len() > 0 {
+		chunks = append(chunks, current.String())
+	}
+
+	return chunks
+}
+
+// Split an overlong sentence into word runs of at most maxTokens
+func (c *TokenCounter) splitWords(sentence string, maxTokens int) []string {
+	var pieces []string
+	var current []string
+	for _, word := range strings.Fields(sentence) {
+		candidate := append(current, word)
+		if len(current) > 0 && c.Count(strings.Join(candidate, " ")) > maxTokens {
+			pieces = append(pieces, strings.Join(current, " "))
+			current = []string{word}
+			continue
+		}
+		current = candidate
+	}
+	if len(current) > 0 {
+		pieces = append(pieces, strings.Join(current, " "))
+	}
+	return pieces
+}
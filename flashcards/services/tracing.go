@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tmc/langchaingo/llms"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("flashcards/services")
+
+// SetupTracing exports spans over OTLP/HTTP. The exporter's endpoint,
+// headers and the sampler are read from the standard OTEL_* environment
+// variables. The returned function flushes pending spans on shutdown.
+func SetupTracing(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+// tracedModel wraps an LLM client so each generation is recorded as a
+// client span, with the model that served it and the tokens it used
+type tracedModel struct {
+	LLMClient
+	provider string
+	model    string
+}
+
+func (m *tracedModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{Model: m.model}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	ctx, span := tracer.Start(ctx, "chat "+opts.Model,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("gen_ai.system", m.provider),
+			attribute.String("gen_ai.request.model", opts.Model),
+		))
+	defer span.End()
+
+	response, err := m.LLMClient.GenerateContent(ctx, messages, options...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	if len(response.Choices) > 0 {
+		info := response.Choices[0].GenerationInfo
+		for key, attr := range llmUsageAttributes {
+			if tokens, ok := info[key].(int); ok {
+				span.SetAttributes(attribute.Int(attr, tokens))
+			}
+		}
+	}
+
+	return response, nil
+}
+
+// Token counts reported in GenerationInfo by the langchaingo providers
+var llmUsageAttributes = map[string]string{
+	"PromptTokens":     "gen_ai.usage.input_tokens",
+	"CompletionTokens": "gen_ai.usage.output_tokens",
+	"InputTokens":      "gen_ai.usage.input_tokens",
+	"OutputTokens":     "gen_ai.usage.output_tokens",
+}
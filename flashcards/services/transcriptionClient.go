@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"flashcards/models"
+)
+
+const (
+	TRANSCRIPTION_REQUEST_TIMEOUT = 60 * time.Second
+	MAX_AUDIO_ANSWER_BYTES        = 10 * 1024 * 1024
+)
+
+// Audio formats the transcription endpoint accepts, by file extension
+var audioAnswerExtensions = []string{".flac", ".m4a", ".mp3", ".mp4", ".mpeg", ".mpga", ".ogg", ".wav", ".webm"}
+
+// TranscriptionConfig holds speech-to-text settings for an OpenAI-compatible
+// /audio/transcriptions endpoint, such as Whisper. An empty APIKey disables
+// transcription.
+type TranscriptionConfig struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+}
+
+type TranscriptionClient struct {
+	cfg        TranscriptionConfig
+	httpClient *http.Client
+}
+
+func NewTranscriptionClient(cfg TranscriptionConfig) *TranscriptionClient {
+	if cfg.APIKey == "" {
+		slog.Info("Transcription API key not configured, audio answers disabled")
+	}
+	return &TranscriptionClient{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: TRANSCRIPTION_REQUEST_TIMEOUT},
+	}
+}
+
+func (c *TranscriptionClient) Enabled() bool {
+	return c != nil && c.cfg.APIKey != ""
+}
+
+// Transcribe converts recorded speech to text. The filename's extension
+// tells the provider the audio format.
+func (c *TranscriptionClient) Transcribe(ctx context.Context, filename string, audio []byte) (string, error) {
+	logger := LoggerFromContext(ctx)
+	if !c.Enabled() {
+		return "", models.Unavailable("audio transcription is not configured")
+	}
+
+	logger.Info("Transcribing audio", "bytes", len(audio), "model", c.cfg.Model)
+	startTime := time.Now()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("model", c.cfg.Model)
+	form.WriteField("response_format", "json")
+	part, err := form.CreateFormFile("file", filepath.Base(filename))
+	if err != nil {
+		return "", models.Internal("failed to encode transcription request: %w", err)
+	}
+	part.Write(audio)
+	if err := form.Close(); err != nil {
+		return "", models.Internal("failed to encode transcription request: %w", err)
+	}
+
+	url := strings.TrimRight(c.cfg.BaseURL, "/") + "/audio/transcriptions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return "", models.Internal("failed to create transcription request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logger.Error("Transcription request failed", "duration_ms", time.Since(startTime).Milliseconds(), "error", err)
+		return "", models.Upstream("failed to transcribe audio: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		logger.Error("Transcription request returned an error status", "status", resp.StatusCode, "duration_ms", time.Since(startTime).Milliseconds())
+		return "", models.Upstream("failed to transcribe audio: provider returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", models.Upstream("failed to read transcription response: %w", err)
+	}
+
+	logger.Info("Transcribed audio", "chars", len(result.Text), "duration_ms", time.Since(startTime).Milliseconds())
+	return strings.TrimSpace(result.Text), nil
+}
+
+// validateAudioAnswer checks an uploaded recording's size and format
+func validateAudioAnswer(filename string, audio []byte) error {
+	if len(audio) == 0 {
+		return models.Invalid("audio file is empty")
+	}
+	if len(audio) > MAX_AUDIO_ANSWER_BYTES {
+		return models.Invalid("audio cannot exceed %d bytes", MAX_AUDIO_ANSWER_BYTES)
+	}
+	if !containsValue(audioAnswerExtensions, strings.ToLower(filepath.Ext(filename))) {
+		return models.Invalid("audio must be one of: %s", strings.Join(audioAnswerExtensions, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"flashcards/models"
+)
+
+const (
+	TTS_REQUEST_TIMEOUT = 30 * time.Second
+	MAX_TTS_AUDIO_BYTES = 5 * 1024 * 1024
+	TTS_CONTENT_TYPE    = "audio/mpeg"
+)
+
+// TTSConfig holds text-to-speech settings for an OpenAI-compatible
+// /audio/speech endpoint. An empty APIKey disables audio generation.
+type TTSConfig struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+	Voice   string
+	// LanguageVoices overrides Voice for text in a language, keyed by
+	// ISO 639-1 code
+	LanguageVoices map[string]string
+}
+
+type TTSClient struct {
+	cfg        TTSConfig
+	httpClient *http.Client
+}
+
+func NewTTSClient(cfg TTSConfig) *TTSClient {
+	if cfg.APIKey == "" {
+		slog.Info("TTS API key not configured, audio generation disabled")
+	}
+	return &TTSClient{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: TTS_REQUEST_TIMEOUT},
+	}
+}
+
+func (c *TTSClient) Enabled() bool {
+	return c != nil && c.cfg.APIKey != ""
+}
+
+// Synthesize converts text to speech and returns the audio bytes with
+// their content type. The language, when known, picks the voice.
+func (c *TTSClient) Synthesize(ctx context.Context, text, language string) ([]byte, string, error) {
+	logger := LoggerFromContext(ctx)
+	if !c.Enabled() {
+		return nil, "", models.Unavailable("audio generation is not configured")
+	}
+
+	voice := c.cfg.Voice
+	if languageVoice, ok := c.cfg.LanguageVoices[language]; ok {
+		voice = languageVoice
+	}
+
+	logger.Info("Synthesizing speech", "chars", len(text), "model", c.cfg.Model, "voice", voice)
+	startTime := time.Now()
+
+	payload, err := json.Marshal(map[string]string{
+		"model":           c.cfg.Model,
+		"voice":           voice,
+		"input":           text,
+		"response_format": "mp3",
+	})
+	if err != nil {
+		return nil, "", models.Internal("failed to encode speech request: %w", err)
+	}
+
+	url := strings.TrimRight(c.cfg.BaseURL, "/") + "/audio/speech"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, "", models.Internal("failed to create speech request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logger.Error("Speech request failed", "duration_ms", time.Since(startTime).Milliseconds(), "error", err)
+		return nil, "", models.Upstream("failed to synthesize speech: %w", err)
+	}
+	defer resp.Body.Close()
+
+	audio, err := io.ReadAll(io.LimitReader(resp.Body, MAX_TTS_AUDIO_BYTES+1))
+	if err != nil {
+		return nil, "", models.Upstream("failed to read speech response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		logger.Error("Speech request returned an error status", "status", resp.StatusCode, "duration_ms", time.Since(startTime).Milliseconds())
+		return nil, "", models.Upstream("failed to synthesize speech: provider returned status %d", resp.StatusCode)
+	}
+
+	if len(audio) > MAX_TTS_AUDIO_BYTES {
+		return nil, "", models.Upstream("failed to synthesize speech: audio exceeds %d bytes", MAX_TTS_AUDIO_BYTES)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = TTS_CONTENT_TYPE
+	}
+
+	logger.Info("Synthesized audio", "bytes", len(audio), "duration_ms", time.Since(startTime).Milliseconds())
+	return audio, contentType, nil
+}
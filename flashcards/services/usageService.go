@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+const (
+	DEFAULT_USAGE_DAYS       = 30
+	MAX_USAGE_DAYS           = 365
+	DEFAULT_USER_USAGE_LIMIT = 50
+	// Per-call records are kept this long, past the longest summary
+	LLM_CALL_RETENTION = (MAX_USAGE_DAYS + 35) * 24 * time.Hour
+)
+
+// ModelPrice is what a model costs in USD per million prompt and
+// completion tokens
+type ModelPrice struct {
+	InputPerMillion  float64
+	OutputPerMillion float64
+}
+
+// List prices of the hosted models. A model is priced by the longest name
+// its own starts with, so dated and -latest versions match; models without
+// a price, such as local Ollama ones, cost nothing.
+var defaultModelPrices = map[string]ModelPrice{
+	"gpt-4o-mini":       {InputPerMillion: 0.15, OutputPerMillion: 0.60},
+	"gpt-4o":            {InputPerMillion: 2.50, OutputPerMillion: 10.00},
+	"gpt-4.1-nano":      {InputPerMillion: 0.10, OutputPerMillion: 0.40},
+	"gpt-4.1-mini":      {InputPerMillion: 0.40, OutputPerMillion: 1.60},
+	"gpt-4.1":           {InputPerMillion: 2.00, OutputPerMillion: 8.00},
+	"claude-3-5-haiku":  {InputPerMillion: 0.80, OutputPerMillion: 4.00},
+	"claude-3-5-sonnet": {InputPerMillion: 3.00, OutputPerMillion: 15.00},
+	"claude-3-7-sonnet": {InputPerMillion: 3.00, OutputPerMillion: 15.00},
+	"claude-sonnet-4":   {InputPerMillion: 3.00, OutputPerMillion: 15.00},
+}
+
+// UsageService records the model, tokens and estimated cost of each LLM
+// call, summarizes them per day and model, and refuses calls from users
+// who have spent their monthly budget
+type UsageService struct {
+	repo      db.UsageRepository
+	prices    map[string]ModelPrice
+	budgetUSD float64
+}
+
+// NewUsageService takes the monthly budget per user in USD, 0 for none,
+// and prices overriding or adding to the defaults, as "input/output" USD
+// per million tokens keyed by model name
+func NewUsageService(repo db.UsageRepository, budgetUSD float64, prices map[string]string) (*UsageService, error) {
+	if budgetUSD < 0 {
+		return nil, models.Invalid("monthly LLM budget cannot be negative")
+	}
+
+	merged := make(map[string]ModelPrice, len(defaultModelPrices)+len(prices))
+	for model, price := range defaultModelPrices {
+		merged[model] = price
+	}
+	for model, value := range prices {
+		price, err := parseModelPrice(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price for model %s: %w", model, err)
+		}
+		merged[strings.ToLower(model)] = price
+	}
+
+	if budgetUSD == 0 {
+		slog.Info("Monthly LLM budget not configured, LLM spend is only tracked")
+	}
+	return &UsageService{repo: repo, prices: merged, budgetUSD: budgetUSD}, nil
+}
+
+func parseModelPrice(value string) (ModelPrice, error) {
+	input, output, ok := strings.Cut(value, "/")
+	if !ok {
+		return ModelPrice{}, fmt.Errorf("%q must be input/output USD per million tokens, such as 0.15/0.60", value)
+	}
+	inputPrice, err := strconv.ParseFloat(strings.TrimSpace(input), 64)
+	if err != nil || inputPrice < 0 {
+		return ModelPrice{}, fmt.Errorf("%q is not a valid input price", input)
+	}
+	outputPrice, err := strconv.ParseFloat(strings.TrimSpace(output), 64)
+	if err != nil || outputPrice < 0 {
+		return ModelPrice{}, fmt.Errorf("%q is not a valid output price", output)
+	}
+	return ModelPrice{InputPerMillion: inputPrice, OutputPerMillion: outputPrice}, nil
+}
+
+// Cost estimates the USD cost of a call to the model
+func (s *UsageService) Cost(model string, inputTokens, outputTokens int) float64 {
+	price, ok := s.price(model)
+	if !ok {
+		return 0
+	}
+	return (float64(inputTokens)*price.InputPerMillion + float64(outputTokens)*price.OutputPerMillion) / 1e6
+}
+
+func (s *UsageService) price(model string) (ModelPrice, bool) {
+	model = strings.ToLower(model)
+	if price, ok := s.prices[model]; ok {
+		return price, true
+	}
+
+	var best string
+	for name := range s.prices {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	price, ok := s.prices[best]
+	return price, ok && best != ""
+}
+
+// RecordCall stores a call the user made, with its estimated cost
+func (s *UsageService) RecordCall(ctx context.Context, userID int, model string, inputTokens, outputTokens int) error {
+	return s.repo.RecordLLMCall(ctx, &models.LLMCall{
+		UserID:       userID,
+		Model:        model,
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+		CostUSD:      s.Cost(model, inputTokens, outputTokens),
+	})
+}
+
+// CheckBudget returns a quota error once the user has spent this month's
+// budget
+func (s *UsageService) CheckBudget(ctx context.Context, userID int) error {
+	budget, err := s.budget(ctx, userID)
+	if err != nil || budget == nil {
+		return err
+	}
+	if budget.Exceeded {
+		return models.QuotaExceeded("LLM quota exceeded: the monthly budget of $%.2f is spent, generation resumes on %s",
+			budget.MonthlyUSD, budget.Month.AddDate(0, 1, 0).Format("2006-01-02"))
+	}
+	return nil
+}
+
+// budget returns the user's spend against the monthly budget, or nil when
+// no budget is configured
+func (s *UsageService) budget(ctx context.Context, userID int) (*models.LLMBudget, error) {
+	if s.budgetUSD == 0 {
+		return nil, nil
+	}
+
+	month := startOfMonth(time.Now())
+	spent, err := s.repo.GetCostSince(ctx, userID, month)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LLMBudget{
+		Month:        month,
+		MonthlyUSD:   s.budgetUSD,
+		SpentUSD:     spent,
+		RemainingUSD: math.Max(s.budgetUSD-spent, 0),
+		Exceeded:     spent >= s.budgetUSD,
+	}, nil
+}
+
+// GetSummary reports the user's LLM usage over the last days days (default
+// 30), ending today, and their budget
+func (s *UsageService) GetSummary(ctx context.Context, userID, days int) (*models.LLMUsageSummary, error) {
+	if days == 0 {
+		days = DEFAULT_USAGE_DAYS
+	}
+	if days < 1 || days > MAX_USAGE_DAYS {
+		return nil, models.Invalid("days must be between 1 and %d", MAX_USAGE_DAYS)
+	}
+
+	from := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+	daily, err := s.repo.GetDailyUsage(ctx, userID, from)
+	if err != nil {
+		return nil, err
+	}
+	byModel, err := s.repo.GetModelUsage(ctx, userID, from)
+	if err != nil {
+		return nil, err
+	}
+	budget, err := s.budget(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.LLMUsageSummary{From: from, Days: daily, Models: byModel, Budget: budget}
+	for _, day := range daily {
+		summary.Total.Calls += day.Calls
+		summary.Total.InputTokens += day.InputTokens
+		summary.Total.OutputTokens += day.OutputTokens
+		summary.Total.CostUSD += day.CostUSD
+	}
+	return summary, nil
+}
+
+// GetUsageByUser lists the users with the costliest LLM usage over the
+// last days days
+func (s *UsageService) GetUsageByUser(ctx context.Context, days, limit int) ([]*models.UserLLMUsage, error) {
+	if days == 0 {
+		days = DEFAULT_USAGE_DAYS
+	}
+	if days < 1 || days > MAX_USAGE_DAYS {
+		return nil, models.Invalid("days must be between 1 and %d", MAX_USAGE_DAYS)
+	}
+	if limit == 0 {
+		limit = DEFAULT_USER_USAGE_LIMIT
+	}
+	if limit < 1 || limit > MAX_PAGE_LIMIT {
+		return nil, models.Invalid("limit must be between 1 and %d", MAX_PAGE_LIMIT)
+	}
+
+	from := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+	return s.repo.GetUsageByUser(ctx, from, limit)
+}
+
+// DeleteExpired removes call records older than LLM_CALL_RETENTION
+func (s *UsageService) DeleteExpired(ctx context.Context) error {
+	deleted, err := s.repo.DeleteLLMCallsBefore(ctx, time.Now().Add(-LLM_CALL_RETENTION))
+	if err != nil {
+		return err
+	}
+
+	LoggerFromContext(ctx).Info("Deleted expired LLM call records", "count", deleted)
+	return nil
+}
+
+// costedModel records each LLM call a user makes with its cost, and
+// refuses calls once the user's monthly budget is spent
+type costedModel struct {
+	LLMClient
+	usage *UsageService
+	model string
+}
+
+func (m *costedModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		return m.LLMClient.GenerateContent(ctx, messages, options...)
+	}
+
+	if err := m.usage.CheckBudget(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	response, err := m.LLMClient.GenerateContent(ctx, messages, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calls routed to another model are priced as that model
+	opts := llms.CallOptions{Model: m.model}
+	for _, option := range options {
+		option(&opts)
+	}
+	input, output := tokenCounts(opts.Model, messages, response)
+	if err := m.usage.RecordCall(ctx, userID, opts.Model, input, output); err != nil {
+		LoggerFromContext(ctx).Error("Failed to record LLM call", "error", err)
+	}
+	return response, nil
+}
+
+// tokenCounts returns the prompt and completion tokens the provider
+// reported, or counts them from the text when it did not
+func tokenCounts(model string, messages []llms.MessageContent, response *llms.ContentResponse) (int, int) {
+	if len(response.Choices) > 0 {
+		info := response.Choices[0].GenerationInfo
+		for _, keys := range [][2]string{{"InputTokens", "OutputTokens"}, {"PromptTokens", "CompletionTokens"}} {
+			input, inputOK := info[keys[0]].(int)
+			output, outputOK := info[keys[1]].(int)
+			if inputOK && outputOK {
+				return input, output
+			}
+		}
+	}
+
+	counter := NewTokenCounter(model, 0)
+	input, output := 0, 0
+	for _, message := range messages {
+		for _, part := range message.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				input += counter.Count(text.Text)
+			}
+		}
+	}
+	for _, choice := range response.Choices {
+		output += counter.Count(choice.Content)
+	}
+	return input, output
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	DEFAULT_EXAMPLE_SENTENCES = 3
+	MAX_EXAMPLE_SENTENCES     = 10
+	MAX_VOCABULARY_TERM       = 200
+	MAX_LANGUAGE_CODE_LENGTH  = 20
+)
+
+var cefrLevels = map[string]bool{
+	models.CEFRLevelA1: true,
+	models.CEFRLevelA2: true,
+	models.CEFRLevelB1: true,
+	models.CEFRLevelB2: true,
+	models.CEFRLevelC1: true,
+	models.CEFRLevelC2: true,
+}
+
+type VocabularyService struct {
+	repo             db.VocabularyRepository
+	flashcardService *FlashcardService
+	quizService      *QuizService
+	tts              *TTSClient
+}
+
+func NewVocabularyService(repo db.VocabularyRepository, flashcardService *FlashcardService, quizService *QuizService, tts *TTSClient) *VocabularyService {
+	return &VocabularyService{
+		repo:             repo,
+		flashcardService: flashcardService,
+		quizService:      quizService,
+		tts:              tts,
+	}
+}
+
+func (s *VocabularyService) GetVocabulary(ctx context.Context, userID, flashcardID int) (*models.Vocabulary, error) {
+	if _, err := s.flashcardService.GetFlashcardByID(ctx, userID, flashcardID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetVocabulary(ctx, userID, flashcardID)
+}
+
+// UpdateVocabulary creates or edits the vocabulary details of a flashcard.
+// The term is required the first time; changing it discards stored audio.
+func (s *VocabularyService) UpdateVocabulary(ctx context.Context, userID, flashcardID int, req *models.UpdateVocabularyRequest) (*models.Vocabulary, error) {
+	if err := s.validateUpdateRequest(req); err != nil {
+		return nil, err
+	}
+
+	vocabulary, err := s.loadOrNew(ctx, userID, flashcardID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Term != nil {
+		vocabulary.Term = strings.TrimSpace(*req.Term)
+	}
+	if req.Language != nil {
+		vocabulary.Language = strings.TrimSpace(*req.Language)
+	}
+	if req.IPA != nil {
+		vocabulary.IPA = strings.TrimSpace(*req.IPA)
+	}
+	if req.Pronunciation != nil {
+		vocabulary.Pronunciation = strings.TrimSpace(*req.Pronunciation)
+	}
+
+	if vocabulary.Term == "" {
+		return nil, models.Invalid("term is required")
+	}
+
+	if err := s.repo.SaveVocabulary(ctx, vocabulary); err != nil {
+		return nil, err
+	}
+
+	return vocabulary, nil
+}
+
+// GenerateExamples replaces the example sentences of a vocabulary card with
+// new ones written at the requested CEFR level. IPA and pronunciation are
+// filled in from the same response when they are still empty.
+func (s *VocabularyService) GenerateExamples(ctx context.Context, userID, flashcardID int, req *models.GenerateExamplesRequest) (*models.Vocabulary, error) {
+	level := strings.ToUpper(strings.TrimSpace(req.CEFRLevel))
+	if !cefrLevels[level] {
+		return nil, models.Invalid("cefrLevel must be one of A1, A2, B1, B2, C1, C2")
+	}
+
+	count := DEFAULT_EXAMPLE_SENTENCES
+	if req.Count != 0 {
+		count = req.Count
+	}
+	if count < 1 || count > MAX_EXAMPLE_SENTENCES {
+		return nil, models.Invalid("count must be between 1 and %d", MAX_EXAMPLE_SENTENCES)
+	}
+
+	flashcard, err := s.flashcardService.GetFlashcardByID(ctx, userID, flashcardID)
+	if err != nil {
+		return nil, err
+	}
+
+	vocabulary, err := s.repo.GetVocabulary(ctx, userID, flashcardID)
+	if err != nil {
+		return nil, err
+	}
+
+	language := vocabulary.Language
+	if language == "" {
+		language = LanguageName(s.flashcardService.FlashcardLanguage(ctx, userID, flashcard))
+	}
+
+	details, err := s.quizService.GenerateVocabularyDetails(ctx, vocabulary.Term, language, flashcard.Content, level, count)
+	if err != nil {
+		return nil, err
+	}
+
+	if vocabulary.IPA == "" {
+		vocabulary.IPA = details.IPA
+	}
+	if vocabulary.Pronunciation == "" {
+		vocabulary.Pronunciation = details.Pronunciation
+	}
+	vocabulary.CEFRLevel = level
+	vocabulary.Examples = details.Examples
+
+	if err := s.repo.SaveVocabulary(ctx, vocabulary); err != nil {
+		return nil, err
+	}
+
+	return vocabulary, nil
+}
+
+// GenerateAudio synthesizes the spoken term with the TTS client and stores
+// it with the vocabulary card
+func (s *VocabularyService) GenerateAudio(ctx context.Context, userID, flashcardID int) (*models.Vocabulary, error) {
+	if !s.tts.Enabled() {
+		return nil, models.Unavailable("audio generation is not configured")
+	}
+
+	vocabulary, err := s.GetVocabulary(ctx, userID, flashcardID)
+	if err != nil {
+		return nil, err
+	}
+
+	language := normalizeLanguage(vocabulary.Language)
+	if language == "" {
+		flashcard, err := s.flashcardService.GetFlashcardByID(ctx, userID, flashcardID)
+		if err != nil {
+			return nil, err
+		}
+		language = s.flashcardService.FlashcardLanguage(ctx, userID, flashcard)
+	}
+
+	audio, contentType, err := s.tts.Synthesize(ctx, vocabulary.Term, language)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SaveAudio(ctx, flashcardID, audio, contentType); err != nil {
+		return nil, err
+	}
+
+	vocabulary.HasAudio = true
+	vocabulary.AudioContentType = contentType
+	return vocabulary, nil
+}
+
+func (s *VocabularyService) GetAudio(ctx context.Context, userID, flashcardID int) ([]byte, string, error) {
+	return s.repo.GetAudio(ctx, userID, flashcardID)
+}
+
+// FlashcardIDsWithAudio returns the set of the given flashcards that have
+// generated audio
+func (s *VocabularyService) FlashcardIDsWithAudio(ctx context.Context, userID int, flashcardIDs []int) (map[int]bool, error) {
+	withAudio := make(map[int]bool)
+	if len(flashcardIDs) == 0 {
+		return withAudio, nil
+	}
+
+	ids, err := s.repo.GetFlashcardIDsWithAudio(ctx, userID, flashcardIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		withAudio[id] = true
+	}
+	return withAudio, nil
+}
+
+func (s *VocabularyService) loadOrNew(ctx context.Context, userID, flashcardID int) (*models.Vocabulary, error) {
+	if _, err := s.flashcardService.GetFlashcardByID(ctx, userID, flashcardID); err != nil {
+		return nil, err
+	}
+
+	vocabulary, err := s.repo.GetVocabulary(ctx, userID, flashcardID)
+	if err == nil {
+		return vocabulary, nil
+	}
+	if !errors.Is(err, models.ErrNotFound) {
+		return nil, err
+	}
+
+	return &models.Vocabulary{
+		FlashcardID: flashcardID,
+		Examples:    []models.ExampleSentence{},
+	}, nil
+}
+
+func (s *VocabularyService) validateUpdateRequest(req *models.UpdateVocabularyRequest) error {
+	if req.Term == nil && req.Language == nil && req.IPA == nil && req.Pronunciation == nil {
+		return models.Invalid("at least one field must be provided for update")
+	}
+
+	if req.Term != nil && len(strings.TrimSpace(*req.Term)) > MAX_VOCABULARY_TERM {
+		return models.Invalid("term cannot exceed %d characters", MAX_VOCABULARY_TERM)
+	}
+
+	if req.Language != nil && len(strings.TrimSpace(*req.Language)) > MAX_LANGUAGE_CODE_LENGTH {
+		return models.Invalid("language cannot exceed %d characters", MAX_LANGUAGE_CODE_LENGTH)
+	}
+
+	if req.IPA != nil && len(strings.TrimSpace(*req.IPA)) > MAX_VOCABULARY_TERM {
+		return models.Invalid("ipa cannot exceed %d characters", MAX_VOCABULARY_TERM)
+	}
+
+	if req.Pronunciation != nil && len(strings.TrimSpace(*req.Pronunciation)) > MAX_VOCABULARY_TERM {
+		return models.Invalid("pronunciation cannot exceed %d characters", MAX_VOCABULARY_TERM)
+	}
+
+	return nil
+}
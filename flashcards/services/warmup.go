@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type warmupStep struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// Warmup runs startup work ahead of the first requests, such as opening
+// connections and loading caches, so the first requests after a deploy are
+// not the slow ones. Steps run in parallel within a time limit. Warm-up is
+// best effort: a failed or unfinished step is logged and the server starts
+// anyway, doing the work on first use as it would without warm-up.
+type Warmup struct {
+	timeout time.Duration
+	steps   []warmupStep
+}
+
+func NewWarmup(timeout time.Duration) *Warmup {
+	return &Warmup{timeout: timeout}
+}
+
+// Add registers a step to run during warm-up
+func (w *Warmup) Add(name string, run func(ctx context.Context) error) {
+	w.steps = append(w.steps, warmupStep{name: name, run: run})
+}
+
+// Run runs the steps and returns once all have finished or the timeout has
+// passed, whichever comes first
+func (w *Warmup) Run(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	slog.Info("Warming up", "steps", len(w.steps), "timeout", w.timeout.String())
+	startTime := time.Now()
+
+	var wg sync.WaitGroup
+	for _, step := range w.steps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger := slog.Default().With("step", step.name)
+			stepStart := time.Now()
+			if err := step.run(ContextWithLogger(ctx, logger)); err != nil {
+				logger.Warn("Warm-up step failed", "duration_ms", time.Since(stepStart).Milliseconds(), "error", err)
+				return
+			}
+			logger.Info("Warm-up step completed", "duration_ms", time.Since(stepStart).Milliseconds())
+		}()
+	}
+	wg.Wait()
+
+	slog.Info("Warm-up completed", "duration_ms", time.Since(startTime).Milliseconds())
+}
@@ -0,0 +1,462 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"flashcards/db"
+	"flashcards/models"
+)
+
+const (
+	MAX_WEBHOOKS_PER_USER  = 10
+	MAX_WEBHOOK_URL_LENGTH = 2000
+	// Attempts before a delivery fails for good
+	MAX_WEBHOOK_DELIVERY_ATTEMPTS = 6
+	// The first retry waits this long and each later one twice as long as
+	// the last, up to the maximum
+	WEBHOOK_RETRY_BASE = time.Minute
+	WEBHOOK_RETRY_MAX  = time.Hour
+	// Deliveries sent per poll, and how long a claimed batch is held; the
+	// lease covers every delivery in the batch timing out
+	WEBHOOK_DELIVERY_BATCH  = 20
+	WEBHOOK_DELIVERY_LEASE  = 5 * time.Minute
+	WEBHOOK_POLL_INTERVAL   = 5 * time.Second
+	WEBHOOK_REQUEST_TIMEOUT = 10 * time.Second
+	// Finished deliveries stay in the log this long
+	WEBHOOK_DELIVERY_RETENTION = 30 * 24 * time.Hour
+	MAX_WEBHOOK_ERROR_CHARS    = 500
+	MAX_WEBHOOK_RESPONSE_BYTES = 64 * 1024
+)
+
+var webhookEvents = []string{models.WebhookEventNoteCreated, models.WebhookEventQuizGenerated, models.WebhookEventQuizCompleted,
+	models.WebhookEventReviewCompleted}
+
+// EventPublisher is told about lifecycle events, such as a note being
+// created, along with the data describing them
+type EventPublisher interface {
+	Publish(ctx context.Context, userID int, event string, data any)
+}
+
+// WebhookService lets users register URLs that are POSTed signed payloads
+// when events happen to their notes, quizzes and reviews. Events are queued
+// as deliveries and sent in the background; every instance polls for due
+// deliveries, and failed ones are retried with backoff. REST hooks for
+// automation platforms share the queue, see restHooks.go.
+type WebhookService struct {
+	repo           db.WebhookRepository
+	noteService    *NoteService
+	sessionService *QuizSessionService
+	httpClient     *http.Client
+}
+
+// NewWebhookService reads recent notes and quiz sessions through the given
+// services for REST hook samples
+func NewWebhookService(repo db.WebhookRepository, noteService *NoteService, sessionService *QuizSessionService) *WebhookService {
+	return &WebhookService{
+		repo:           repo,
+		noteService:    noteService,
+		sessionService: sessionService,
+		httpClient:     newWebhookHTTPClient(),
+	}
+}
+
+// newWebhookHTTPClient only connects to public addresses, so a webhook
+// cannot reach the server's own network, and does not follow redirects
+func newWebhookHTTPClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: WEBHOOK_REQUEST_TIMEOUT,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return fmt.Errorf("webhook address %s is not public", host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   WEBHOOK_REQUEST_TIMEOUT,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() && !ip.IsMulticast()
+}
+
+// CreateWebhook registers a webhook. The returned webhook carries the
+// secret its payloads are signed with, which is not shown again.
+func (s *WebhookService) CreateWebhook(ctx context.Context, userID int, req *models.CreateWebhookRequest) (*models.Webhook, error) {
+	if req == nil {
+		return nil, models.Invalid("request cannot be nil")
+	}
+
+	webhook := &models.Webhook{
+		UserID: userID,
+		Kind:   models.WebhookKindWebhook,
+		URL:    strings.TrimSpace(req.URL),
+		Events: req.Events,
+		Active: true,
+	}
+	if err := s.create(ctx, webhook, MAX_WEBHOOKS_PER_USER, "webhooks"); err != nil {
+		return nil, err
+	}
+
+	LoggerFromContext(ctx).Info("Created webhook", "webhook_id", webhook.ID, "events", webhook.Events)
+	return webhook, nil
+}
+
+// create validates and stores a webhook with a new secret, unless the user
+// already has limit webhooks of its kind, which are called plural in the
+// error
+func (s *WebhookService) create(ctx context.Context, webhook *models.Webhook, limit int, plural string) error {
+	if err := validateWebhook(webhook); err != nil {
+		return err
+	}
+
+	count, err := s.repo.CountWebhooks(ctx, webhook.UserID, webhook.Kind)
+	if err != nil {
+		return err
+	}
+	if count >= limit {
+		return models.Invalid("at most %d %s are allowed", limit, plural)
+	}
+
+	if webhook.Secret, err = newWebhookSecret(); err != nil {
+		return err
+	}
+	return s.repo.CreateWebhook(ctx, webhook)
+}
+
+func (s *WebhookService) GetWebhooks(ctx context.Context, userID int) ([]*models.Webhook, error) {
+	webhooks, err := s.repo.GetWebhooks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, webhook := range webhooks {
+		webhook.Secret = ""
+	}
+	return webhooks, nil
+}
+
+func (s *WebhookService) GetWebhook(ctx context.Context, userID, id int) (*models.Webhook, error) {
+	if id <= 0 {
+		return nil, models.Invalid("invalid webhook ID: %d", id)
+	}
+
+	webhook, err := s.repo.GetWebhook(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// UpdateWebhook changes a webhook's URL, events or whether it is active.
+// Deliveries already queued are still sent to the URL they were queued for.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, userID, id int, req *models.UpdateWebhookRequest) (*models.Webhook, error) {
+	if req == nil {
+		return nil, models.Invalid("request cannot be nil")
+	}
+
+	webhook, err := s.GetWebhook(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != nil {
+		webhook.URL = strings.TrimSpace(*req.URL)
+	}
+	if req.Events != nil {
+		webhook.Events = req.Events
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	if err := validateWebhook(webhook); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook along with its delivery log
+func (s *WebhookService) DeleteWebhook(ctx context.Context, userID, id int) error {
+	if id <= 0 {
+		return models.Invalid("invalid webhook ID: %d", id)
+	}
+
+	return s.repo.DeleteWebhook(ctx, userID, id)
+}
+
+// ListDeliveries returns one page of a webhook's delivery log, newest first
+// by default
+func (s *WebhookService) ListDeliveries(ctx context.Context, userID, webhookID int, params models.ListParams) (*models.Page[*models.WebhookDelivery], error) {
+	if _, err := s.GetWebhook(ctx, userID, webhookID); err != nil {
+		return nil, err
+	}
+
+	params, err := normalizeListParams(params)
+	if err != nil {
+		return nil, models.Invalid("%s", err.Error())
+	}
+
+	deliveries, total, err := s.repo.ListDeliveries(ctx, webhookID, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Page[*models.WebhookDelivery]{
+		Items:  deliveries,
+		Total:  total,
+		Limit:  params.Limit,
+		Offset: params.Offset,
+	}, nil
+}
+
+// Publish queues the event for each of the user's webhooks subscribed to
+// it, and the triggers it fires for their REST hooks. A failure is logged
+// rather than returned, so it never fails the request that caused the event.
+func (s *WebhookService) Publish(ctx context.Context, userID int, event string, data any) {
+	s.queue(ctx, userID, models.WebhookKindWebhook, event, data)
+	for trigger, payload := range restHookPayloads(event, data) {
+		s.queue(ctx, userID, models.WebhookKindRestHook, trigger, payload)
+	}
+}
+
+func (s *WebhookService) queue(ctx context.Context, userID int, kind, event string, data any) {
+	logger := LoggerFromContext(ctx)
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		logger.Error("Failed to encode webhook event", "event", event, "error", err)
+		return
+	}
+
+	queued, err := s.repo.CreateDeliveries(context.WithoutCancel(ctx), userID, kind, event, payload)
+	if err != nil {
+		logger.Error("Failed to queue webhook deliveries", "event", event, "error", err)
+		return
+	}
+	if queued > 0 {
+		logger.Info("Queued webhook deliveries", "kind", kind, "event", event, "deliveries", queued)
+	}
+}
+
+// DeliverDue sends the deliveries whose next attempt is due. It runs on a
+// schedule on every instance.
+func (s *WebhookService) DeliverDue(ctx context.Context) error {
+	deliveries, err := s.repo.ClaimDueDeliveries(ctx, WEBHOOK_DELIVERY_BATCH, WEBHOOK_DELIVERY_LEASE)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			// The rest are sent once their lease runs out
+			return nil
+		}
+		s.deliver(ctx, delivery)
+	}
+	return nil
+}
+
+func (s *WebhookService) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	logger := LoggerFromContext(ctx).With("delivery_id", delivery.ID, "webhook_id", delivery.WebhookID, "event", delivery.Event)
+
+	responseStatus, err := s.send(ctx, delivery)
+	if err == nil {
+		if err := s.repo.MarkDelivered(ctx, delivery.ID, *responseStatus); err != nil {
+			logger.Error("Failed to update webhook delivery", "error", err)
+			return
+		}
+		logger.Info("Delivered webhook", "attempts", delivery.Attempts+1, "status", *responseStatus)
+		return
+	}
+	if ctx.Err() != nil {
+		// Interrupted by shutdown; not counted as an attempt
+		return
+	}
+	if delivery.Kind == models.WebhookKindRestHook && responseStatus != nil && *responseStatus == http.StatusGone {
+		// The platform answers 410 for a subscription it no longer wants
+		if err := s.repo.DeleteWebhook(ctx, delivery.UserID, delivery.WebhookID); err != nil {
+			logger.Error("Failed to remove REST hook", "error", err)
+			return
+		}
+		logger.Info("Removed REST hook the platform unsubscribed")
+		return
+	}
+
+	attempts := delivery.Attempts + 1
+	var nextAttemptAt *time.Time
+	if attempts < MAX_WEBHOOK_DELIVERY_ATTEMPTS {
+		next := time.Now().Add(webhookBackoff(attempts))
+		nextAttemptAt = &next
+	}
+
+	message := err.Error()
+	if runes := []rune(message); len(runes) > MAX_WEBHOOK_ERROR_CHARS {
+		message = string(runes[:MAX_WEBHOOK_ERROR_CHARS])
+	}
+	if updateErr := s.repo.FailDeliveryAttempt(ctx, delivery.ID, responseStatus, message, nextAttemptAt); updateErr != nil {
+		logger.Error("Failed to update webhook delivery", "error", updateErr)
+		return
+	}
+
+	if nextAttemptAt == nil {
+		logger.Error("Webhook delivery failed", "attempts", attempts, "error", err)
+	} else {
+		logger.Warn("Webhook delivery attempt failed", "attempts", attempts, "next_attempt_at", nextAttemptAt, "error", err)
+	}
+}
+
+// send POSTs the delivery and returns the response status, when there was
+// a response. Anything but a 2xx is a failed attempt. REST hooks are sent
+// the trigger payload alone, webhooks the event envelope.
+func (s *WebhookService) send(ctx context.Context, delivery *models.WebhookDelivery) (*int, error) {
+	body := []byte(delivery.Payload)
+	if delivery.Kind != models.WebhookKindRestHook {
+		var err error
+		body, err = json.Marshal(models.WebhookEvent{
+			ID:        delivery.ID,
+			Event:     delivery.Event,
+			CreatedAt: delivery.CreatedAt,
+			Data:      delivery.Payload,
+		})
+		if err != nil {
+			return nil, models.Internal("failed to encode webhook payload: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, models.Internal("failed to create webhook request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "flashcards-webhooks")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.Itoa(delivery.ID))
+	req.Header.Set("X-Webhook-Signature", "t="+timestamp+",v1="+signWebhookPayload(delivery.Secret, timestamp, body))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, MAX_WEBHOOK_RESPONSE_BYTES))
+
+	status := resp.StatusCode
+	if status < 200 || status > 299 {
+		return &status, fmt.Errorf("webhook answered with status %d", status)
+	}
+	return &status, nil
+}
+
+// DeleteExpired removes finished deliveries past the retention period
+func (s *WebhookService) DeleteExpired(ctx context.Context) error {
+	deleted, err := s.repo.DeleteDeliveriesBefore(ctx, time.Now().Add(-WEBHOOK_DELIVERY_RETENTION))
+	if err != nil {
+		return err
+	}
+
+	LoggerFromContext(ctx).Info("Deleted expired webhook deliveries", "count", deleted)
+	return nil
+}
+
+func validateWebhook(webhook *models.Webhook) error {
+	if webhook.URL == "" {
+		return models.Invalid("url is required")
+	}
+	if len(webhook.URL) > MAX_WEBHOOK_URL_LENGTH {
+		return models.Invalid("url must be at most %d characters", MAX_WEBHOOK_URL_LENGTH)
+	}
+	parsed, err := url.Parse(webhook.URL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return models.Invalid("url must be an absolute http or https URL")
+	}
+
+	if webhook.Kind == models.WebhookKindRestHook {
+		if len(webhook.Events) != 1 || !containsValue(restHookTriggers, webhook.Events[0]) {
+			return models.Invalid("event must be one of: %s", strings.Join(restHookTriggers, ", "))
+		}
+		return nil
+	}
+
+	if len(webhook.Events) == 0 {
+		return models.Invalid("events must name at least one event")
+	}
+	events := make([]string, 0, len(webhook.Events))
+	for _, event := range webhook.Events {
+		if !containsValue(webhookEvents, event) {
+			return models.Invalid("events must be among: %s", strings.Join(webhookEvents, ", "))
+		}
+		if !containsValue(events, event) {
+			events = append(events, event)
+		}
+	}
+	webhook.Events = events
+	return nil
+}
+
+// webhookBackoff is the wait before the attempt that follows the given
+// number of failed attempts
+func webhookBackoff(attempts int) time.Duration {
+	delay := WEBHOOK_RETRY_BASE
+	for i := 1; i < attempts && delay < WEBHOOK_RETRY_MAX; i++ {
+		delay *= 2
+	}
+	return min(delay, WEBHOOK_RETRY_MAX)
+}
+
+// Webhook secrets are 32 random bytes. Receivers check the signature with
+// HMAC-SHA256 over the timestamp, a dot and the body.
+func newWebhookSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", models.Internal("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func signWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
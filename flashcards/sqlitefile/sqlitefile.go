@@ -0,0 +1,222 @@
+// Package sqlitefile builds SQLite database files in memory and reads
+// untrusted ones, such as the collection inside an Anki package, through
+// the modernc.org/sqlite driver. Nothing touches the disk: files are
+// serialized out of, and deserialized into, private in-memory databases.
+package sqlitefile
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// Defensive mode stops a crafted file's schema from writing to the
+// database or reaching into the driver while it is read
+const memoryDSN = "file::memory:?_defensive=1"
+
+// ErrInvalid marks data that is not a well-formed SQLite database
+var ErrInvalid = errors.New("not a valid SQLite database")
+
+// Table is a table to create with its rows, whose values are in the column
+// order of sql's CREATE TABLE statement
+type Table struct {
+	Name string
+	SQL  string
+	Rows [][]any
+}
+
+// Build creates a database with the tables and returns its file
+func Build(ctx context.Context, tables []Table) ([]byte, error) {
+	db, conn, err := open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	defer conn.Close()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range tables {
+		if _, err := tx.ExecContext(ctx, table.SQL); err != nil {
+			return nil, fmt.Errorf("failed to create table %s: %w", table.Name, err)
+		}
+		if len(table.Rows) == 0 {
+			continue
+		}
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(table.Rows[0])), ", ")
+		stmt, err := tx.PrepareContext(ctx, "INSERT INTO "+quote(table.Name)+" VALUES ("+placeholders+")")
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare insert into %s: %w", table.Name, err)
+		}
+		for i, row := range table.Rows {
+			if _, err := stmt.ExecContext(ctx, row...); err != nil {
+				stmt.Close()
+				return nil, fmt.Errorf("failed to insert row %d into %s: %w", i+1, table.Name, err)
+			}
+		}
+		stmt.Close()
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	var data []byte
+	err = conn.Raw(func(driverConn any) error {
+		var err error
+		data, err = driverConn.(serializer).Serialize()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize database: %w", err)
+	}
+	return data, nil
+}
+
+// File is an open, read-only database file
+type File struct {
+	db   *sql.DB
+	conn *sql.Conn
+}
+
+// Open loads data as a read-only database after SQLite's integrity check,
+// so a corrupt file fails here rather than partway through reading it. The
+// check and every query stop when ctx is done.
+func Open(ctx context.Context, data []byte) (*File, error) {
+	if len(data) == 0 {
+		return nil, ErrInvalid
+	}
+
+	db, conn, err := open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	file := &File{db: db, conn: conn}
+
+	err = conn.Raw(func(driverConn any) error {
+		return driverConn.(serializer).Deserialize(data)
+	})
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	// Views and triggers in the file are not trusted to call functions
+	// with side effects, and nothing may write to it
+	for _, pragma := range []string{"PRAGMA trusted_schema = OFF", "PRAGMA cell_size_check = ON", "PRAGMA query_only = ON"} {
+		if _, err := conn.ExecContext(ctx, pragma); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+	}
+
+	var result string
+	if err := conn.QueryRowContext(ctx, "PRAGMA quick_check(1)").Scan(&result); err != nil {
+		file.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if result != "ok" {
+		file.Close()
+		return nil, fmt.Errorf("%w: %s", ErrInvalid, result)
+	}
+
+	return file, nil
+}
+
+// HasTable reports whether the schema lists a table called name
+func (f *File) HasTable(ctx context.Context, name string) (bool, error) {
+	var count int
+	err := f.conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_schema WHERE type = 'table' AND name = ?", name).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to read schema: %w", err)
+	}
+	return count > 0, nil
+}
+
+// Query runs a read-only query against the file
+func (f *File) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return f.conn.QueryContext(ctx, query, args...)
+}
+
+// QueryRow runs a read-only query expected to return at most one row
+func (f *File) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return f.conn.QueryRowContext(ctx, query, args...)
+}
+
+// ReadTable returns every row of the named table in rowid order, with the
+// values in column order
+func (f *File) ReadTable(ctx context.Context, name string) ([][]any, error) {
+	rows, err := f.conn.QueryContext(ctx, "SELECT * FROM "+quote(name)+" ORDER BY rowid")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table %s: %w", name, err)
+	}
+
+	var values [][]any
+	for rows.Next() {
+		row := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range row {
+			pointers[i] = &row[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("failed to read table %s: %w", name, err)
+		}
+		values = append(values, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read table %s: %w", name, err)
+	}
+	return values, nil
+}
+
+func (f *File) Close() error {
+	f.conn.Close()
+	return f.db.Close()
+}
+
+// serializer is implemented by the driver's connections
+type serializer interface {
+	Serialize() ([]byte, error)
+	Deserialize([]byte) error
+}
+
+// open returns a private in-memory database on its single connection,
+// which must be used for everything since another would see an empty
+// database
+func open(ctx context.Context) (*sql.DB, *sql.Conn, error) {
+	db, err := sql.Open("sqlite", memoryDSN)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open in-memory database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("failed to open in-memory database: %w", err)
+	}
+	return db, conn, nil
+}
+
+// quote makes name safe to use as an identifier
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,165 @@
+package sqlitefile
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testTables = []Table{
+	{
+		Name: "notes",
+		SQL:  "CREATE TABLE notes (id integer primary key, title text not null, body text, score real, data blob)",
+		Rows: [][]any{
+			{int64(1), "First", "Body", 1.5, []byte{0, 1, 2}},
+			{int64(2), "Second", nil, 0.0, nil},
+			{int64(1 << 40), strings.Repeat("long ", 2000), "overflows a page", -3.25, []byte(strings.Repeat("x", 10000))},
+		},
+	},
+	{
+		Name: "graves",
+		SQL:  "CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null)",
+	},
+	{
+		Name: "odd \"name\"",
+		SQL:  `CREATE TABLE "odd ""name""" (value text)`,
+		Rows: [][]any{{"quoted"}},
+	},
+}
+
+func buildTestFile(t testing.TB) []byte {
+	t.Helper()
+	data, err := Build(context.Background(), testTables)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	return data
+}
+
+func TestBuildOpenRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	data := buildTestFile(t)
+	if !strings.HasPrefix(string(data), "SQLite format 3\x00") {
+		t.Fatalf("Build did not return a SQLite file")
+	}
+
+	file, err := Open(ctx, data)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer file.Close()
+
+	for _, table := range testTables {
+		ok, err := file.HasTable(ctx, table.Name)
+		if err != nil || !ok {
+			t.Fatalf("HasTable(%q) = %v, %v; want true", table.Name, ok, err)
+		}
+
+		rows, err := file.ReadTable(ctx, table.Name)
+		if err != nil {
+			t.Fatalf("ReadTable(%q): %v", table.Name, err)
+		}
+		if !reflect.DeepEqual(rows, table.Rows) {
+			t.Errorf("ReadTable(%q) = %v, want %v", table.Name, rows, table.Rows)
+		}
+	}
+
+	if ok, err := file.HasTable(ctx, "missing"); err != nil || ok {
+		t.Errorf("HasTable(missing) = %v, %v; want false", ok, err)
+	}
+}
+
+func TestOpenIsReadOnly(t *testing.T) {
+	ctx := context.Background()
+	file, err := Open(ctx, buildTestFile(t))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer file.Close()
+
+	rows, err := file.Query(ctx, "DELETE FROM notes RETURNING id")
+	if err == nil {
+		rows.Close()
+		t.Fatal("DELETE succeeded on a read-only file")
+	}
+}
+
+func TestOpenRejectsInvalidFiles(t *testing.T) {
+	data := buildTestFile(t)
+
+	corrupt := append([]byte(nil), data...)
+	// Scribble over the b-tree pages after the schema page
+	for i := 4096; i < len(corrupt); i += 7 {
+		corrupt[i] ^= 0xa5
+	}
+
+	for name, input := range map[string][]byte{
+		"empty":       nil,
+		"not sqlite":  []byte("PK\x03\x04 this is a zip file"),
+		"header only": data[:100],
+		"truncated":   data[:len(data)/2],
+		"corrupt":     corrupt,
+	} {
+		t.Run(name, func(t *testing.T) {
+			file, err := Open(context.Background(), input)
+			if err == nil {
+				// Damage SQLite tolerates must still fail when read
+				defer file.Close()
+				if _, err := file.ReadTable(context.Background(), "notes"); err == nil {
+					t.Fatal("corrupt file opened and read without error")
+				}
+			}
+		})
+	}
+
+	if _, err := Open(context.Background(), nil); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Open(nil) = %v, want ErrInvalid", err)
+	}
+}
+
+func TestOpenStopsWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if file, err := Open(ctx, buildTestFile(t)); err == nil {
+		file.Close()
+		t.Fatal("Open succeeded with a cancelled context")
+	}
+}
+
+// FuzzOpen feeds damaged files to Open and ReadTable, which must fail
+// cleanly rather than panic or hang
+func FuzzOpen(f *testing.F) {
+	data := buildTestFile(f)
+	f.Add(data)
+	f.Add(data[:len(data)/3])
+	f.Add([]byte("SQLite format 3\x00"))
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		ctx := context.Background()
+		file, err := Open(ctx, input)
+		if err != nil {
+			return
+		}
+		defer file.Close()
+
+		rows, err := file.Query(ctx, "SELECT name FROM sqlite_schema WHERE type = 'table'")
+		if err != nil {
+			return
+		}
+		var names []string
+		for rows.Next() {
+			var name string
+			if rows.Scan(&name) == nil {
+				names = append(names, name)
+			}
+		}
+		rows.Close()
+
+		for _, name := range names {
+			file.ReadTable(ctx, name)
+		}
+	})
+}
@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	GCS_ENDPOINT = "https://storage.googleapis.com"
+	// Requests the server makes itself are signed for this long
+	S3_REQUEST_EXPIRY = 15 * time.Minute
+	// Error responses are read this far for the message
+	MAX_S3_ERROR_BYTES = 1024
+)
+
+// S3Store keeps objects in an S3 bucket, or one on any service speaking
+// the S3 API, such as MinIO, R2 or GCS's XML API. Every request, including
+// the server's own, is authorized with a pre-signed URL (AWS Signature
+// Version 4), so nothing beyond net/http is needed.
+type S3Store struct {
+	client    *http.Client
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+}
+
+func NewS3Store(cfg Config) (*S3Store, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("object storage needs a bucket")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("object storage needs an access key ID and secret")
+	}
+
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid object storage endpoint %q", endpoint)
+	}
+
+	return &S3Store{
+		client:    &http.Client{Timeout: 5 * time.Minute},
+		endpoint:  parsed,
+		bucket:    cfg.Bucket,
+		region:    region,
+		accessKey: cfg.AccessKeyID,
+		secretKey: cfg.SecretAccessKey,
+		pathStyle: cfg.PathStyle,
+	}, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	signed, err := s.presign("PUT", key, map[string]string{
+		"content-length": strconv.FormatInt(size, 10),
+		"content-type":   contentType,
+	}, nil, S3_REQUEST_EXPIRY)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", signed, body)
+	if err != nil {
+		return fmt.Errorf("failed to create storage request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Store) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.request(ctx, "GET", key)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Store) Stat(ctx context.Context, key string) (*Object, error) {
+	resp, err := s.request(ctx, "HEAD", key)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return &Object{Key: key, Size: resp.ContentLength}, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	resp, err := s.request(ctx, "DELETE", key)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Store) PresignPut(key, contentType string, size int64, expires time.Duration) (string, error) {
+	return s.presign("PUT", key, map[string]string{
+		"content-length": strconv.FormatInt(size, 10),
+		"content-type":   contentType,
+	}, nil, expires)
+}
+
+func (s *S3Store) PresignGet(key, filename string, expires time.Duration) (string, error) {
+	query := url.Values{}
+	if filename != "" {
+		query.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+	return s.presign("GET", key, nil, query, expires)
+}
+
+// request makes a bodiless request for the object, answering a 404 with
+// ErrNotFound
+func (s *S3Store) request(ctx context.Context, method, key string) (*http.Response, error) {
+	signed, err := s.presign(method, key, nil, nil, S3_REQUEST_EXPIRY)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, signed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage request: %w", err)
+	}
+	return s.do(req)
+}
+
+func (s *S3Store) do(req *http.Request) (*http.Response, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("storage request failed: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, MAX_S3_ERROR_BYTES))
+		return nil, fmt.Errorf("storage %s returned status %d: %s", req.Method, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return resp, nil
+}
+
+// presign returns the object's URL signed for method with the given
+// headers, which the request must then carry, and extra query parameters
+func (s *S3Store) presign(method, key string, headers map[string]string, query url.Values, expires time.Duration) (string, error) {
+	if err := checkExpiry(expires); err != nil {
+		return "", err
+	}
+	if key == "" || strings.HasPrefix(key, "/") {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+
+	host, path := s.endpoint.Host, s.endpoint.Path+"/"+s.bucket+"/"+key
+	if !s.pathStyle {
+		host, path = s.bucket+"."+s.endpoint.Host, s.endpoint.Path+"/"+key
+	}
+
+	now := time.Now().UTC()
+	date := now.Format("20060102")
+	scope := date + "/" + s.region + "/s3/aws4_request"
+
+	signedHeaders := map[string]string{"host": host}
+	for name, value := range headers {
+		signedHeaders[strings.ToLower(name)] = strings.TrimSpace(value)
+	}
+	names := make([]string, 0, len(signedHeaders))
+	for name := range signedHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + signedHeaders[name] + "\n")
+	}
+
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", s.accessKey+"/"+scope)
+	query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", strings.Join(names, ";"))
+	canonicalQuery := canonicalQueryString(query)
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		uriEncode(path, false),
+		canonicalQuery,
+		canonicalHeaders.String(),
+		strings.Join(names, ";"),
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + query.Get("X-Amz-Date") + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	for _, part := range []string{s.region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	return s.endpoint.Scheme + "://" + host + uriEncode(path, false) + "?" + canonicalQuery + "&X-Amz-Signature=" + signature, nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQueryString encodes query the way Signature Version 4 signs it:
+// sorted by name, with everything but unreserved characters escaped
+func canonicalQueryString(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		for _, value := range query[name] {
+			pairs = append(pairs, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode escapes all but the unreserved characters A-Z, a-z, 0-9, '-',
+// '.', '_' and '~', and '/' unless encodeSlash is set
+func uriEncode(value string, encodeSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(value) {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,103 @@
+// Package storage keeps files such as attachments and deck exports in
+// object storage: a local directory, S3 or an S3-compatible service, or
+// Google Cloud Storage through its S3-compatible XML API. Clients upload
+// and download through pre-signed URLs, so large files don't have to pass
+// through the API server.
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Pre-signed URLs can't outlive this; S3 refuses longer signatures
+const MAX_PRESIGN_EXPIRY = 7 * 24 * time.Hour
+
+// ErrNotFound is returned for a key with no object
+var ErrNotFound = errors.New("storage: object not found")
+
+// Object describes a stored object
+type Object struct {
+	Key  string
+	Size int64
+}
+
+// Store reads and writes objects by key. Keys are slash-separated paths
+// made by NewKey.
+type Store interface {
+	// Put stores size bytes from body under key
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (*Object, error)
+	// Delete removes the object; deleting a missing one is not an error
+	Delete(ctx context.Context, key string) error
+
+	// PresignPut returns a URL the client uploads the object to, with a PUT
+	// of exactly size bytes carrying contentType
+	PresignPut(key, contentType string, size int64, expires time.Duration) (string, error)
+	// PresignGet returns a URL the object is downloaded from, saved as
+	// filename
+	PresignGet(key, filename string, expires time.Duration) (string, error)
+}
+
+// Config selects and configures the store
+type Config struct {
+	// "local", "s3" or "gcs"
+	Driver string
+
+	// Local stores keep objects under Dir and sign URLs to the API server
+	// at BaseURL with SigningSecret
+	Dir           string
+	BaseURL       string
+	SigningSecret string
+
+	// S3 and GCS stores. GCS takes HMAC keys; Endpoint and Region default
+	// to the provider's.
+	Bucket          string
+	Region          string
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Address the bucket in the path rather than the host name, as MinIO
+	// and most self-hosted services expect
+	PathStyle bool
+}
+
+// New returns the store cfg.Driver names
+func New(cfg Config) (Store, error) {
+	switch cfg.Driver {
+	case "local":
+		return NewLocalStore(cfg.Dir, cfg.BaseURL, cfg.SigningSecret)
+	case "s3":
+		return NewS3Store(cfg)
+	case "gcs":
+		if cfg.Endpoint == "" {
+			cfg.Endpoint = GCS_ENDPOINT
+		}
+		if cfg.Region == "" {
+			cfg.Region = "auto"
+		}
+		return NewS3Store(cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
+
+// NewKey returns a fresh, unguessable key under prefix, ending in name
+func NewKey(prefix, name string) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return prefix + "/" + hex.EncodeToString(b) + "/" + name
+}
+
+func checkExpiry(expires time.Duration) error {
+	if expires <= 0 || expires > MAX_PRESIGN_EXPIRY {
+		return fmt.Errorf("pre-signed URL expiry must be between 0 and %s", MAX_PRESIGN_EXPIRY)
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS gocourse.model_routing_rules (
+    id SERIAL PRIMARY KEY,
+    questionType VARCHAR(50) NOT NULL DEFAULT '',
+    difficulty VARCHAR(20) NOT NULL DEFAULT '',
+    model VARCHAR(100) NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 100,
+    createdAt TIMESTAMP DEFAULT NOW(),
+    updatedAt TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_model_routing_rules_priority ON gocourse.model_routing_rules(priority);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS gocourse.quiz_sessions (
+    id SERIAL PRIMARY KEY,
+    status VARCHAR(20) NOT NULL DEFAULT 'in_progress',
+    currentPosition INTEGER NOT NULL DEFAULT 0,
+    createdAt TIMESTAMP DEFAULT NOW(),
+    updatedAt TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS gocourse.quiz_session_questions (
+    id SERIAL PRIMARY KEY,
+    session_id INTEGER NOT NULL REFERENCES gocourse.quiz_sessions(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    question JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'unanswered',
+    answer TEXT NOT NULL DEFAULT '',
+    flagged BOOLEAN NOT NULL DEFAULT FALSE,
+    timeSpentMs INTEGER NOT NULL DEFAULT 0,
+    updatedAt TIMESTAMP DEFAULT NOW(),
+    UNIQUE (session_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_quiz_sessions_created_at ON gocourse.quiz_sessions(createdAt);
+CREATE INDEX IF NOT EXISTS idx_quiz_session_questions_session_id ON gocourse.quiz_session_questions(session_id);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS gocourse.tags (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    createdAt TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS gocourse.note_tags (
+    note_id INTEGER NOT NULL REFERENCES gocourse.notes(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES gocourse.tags(id) ON DELETE CASCADE,
+    createdAt TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (note_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_tags_tag_id ON gocourse.note_tags(tag_id);
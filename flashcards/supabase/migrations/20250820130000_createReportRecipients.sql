@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS gocourse.report_recipients (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    createdAt TIMESTAMP DEFAULT NOW()
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS gocourse.content_filter_settings (
+    id INTEGER PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    ageLevel VARCHAR(20) NOT NULL DEFAULT 'adult',
+    bannedTopics TEXT[] NOT NULL DEFAULT '{}',
+    updatedAt TIMESTAMP DEFAULT NOW()
+);
+
+INSERT INTO gocourse.content_filter_settings (id) VALUES (1) ON CONFLICT DO NOTHING;
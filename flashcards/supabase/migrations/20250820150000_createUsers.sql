@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS gocourse.users (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    passwordHash TEXT NOT NULL,
+    createdAt TIMESTAMP DEFAULT NOW(),
+    updatedAt TIMESTAMP DEFAULT NOW()
+);
+
+-- Scope user data by owner. Rows created before accounts existed keep a NULL
+-- owner and are no longer visible through the API.
+ALTER TABLE gocourse.todos ADD COLUMN IF NOT EXISTS user_id INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE;
+ALTER TABLE gocourse.notes ADD COLUMN IF NOT EXISTS user_id INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE;
+ALTER TABLE gocourse.flashcards ADD COLUMN IF NOT EXISTS user_id INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE;
+ALTER TABLE gocourse.quiz_sessions ADD COLUMN IF NOT EXISTS user_id INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE;
+ALTER TABLE gocourse.report_recipients ADD COLUMN IF NOT EXISTS user_id INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE;
+
+-- Recipients are unique per user rather than globally
+ALTER TABLE gocourse.report_recipients DROP CONSTRAINT IF EXISTS report_recipients_email_key;
+ALTER TABLE gocourse.report_recipients ADD CONSTRAINT report_recipients_user_email_key UNIQUE (user_id, email);
+
+CREATE INDEX IF NOT EXISTS idx_todos_user_id ON gocourse.todos(user_id);
+CREATE INDEX IF NOT EXISTS idx_notes_user_id ON gocourse.notes(user_id);
+CREATE INDEX IF NOT EXISTS idx_flashcards_user_id ON gocourse.flashcards(user_id);
+CREATE INDEX IF NOT EXISTS idx_quiz_sessions_user_id ON gocourse.quiz_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_report_recipients_user_id ON gocourse.report_recipients(user_id);
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS gocourse.decks (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    createdAt TIMESTAMP DEFAULT NOW(),
+    updatedAt TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS gocourse.deck_terminology (
+    deck_id INTEGER PRIMARY KEY REFERENCES gocourse.decks(id) ON DELETE CASCADE,
+    preferredTerms JSONB NOT NULL DEFAULT '[]',
+    abbreviations JSONB NOT NULL DEFAULT '[]',
+    doNotSimplify TEXT[] NOT NULL DEFAULT '{}',
+    stopWords TEXT[] NOT NULL DEFAULT '{}',
+    updatedAt TIMESTAMP DEFAULT NOW()
+);
+
+-- Notes and flashcards can optionally belong to a deck
+ALTER TABLE gocourse.notes ADD COLUMN IF NOT EXISTS deck_id INTEGER REFERENCES gocourse.decks(id) ON DELETE SET NULL;
+ALTER TABLE gocourse.flashcards ADD COLUMN IF NOT EXISTS deck_id INTEGER REFERENCES gocourse.decks(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_decks_user_id ON gocourse.decks(user_id);
+CREATE INDEX IF NOT EXISTS idx_notes_deck_id ON gocourse.notes(deck_id);
+CREATE INDEX IF NOT EXISTS idx_flashcards_deck_id ON gocourse.flashcards(deck_id);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS gocourse.flashcard_vocabulary (
+    flashcard_id INTEGER PRIMARY KEY REFERENCES gocourse.flashcards(id) ON DELETE CASCADE,
+    term VARCHAR(200) NOT NULL,
+    language VARCHAR(20) NOT NULL DEFAULT '',
+    ipa VARCHAR(200) NOT NULL DEFAULT '',
+    pronunciation VARCHAR(200) NOT NULL DEFAULT '',
+    cefrLevel VARCHAR(2) NOT NULL DEFAULT '',
+    examples JSONB NOT NULL DEFAULT '[]',
+    audio BYTEA,
+    audioContentType VARCHAR(50) NOT NULL DEFAULT '',
+    createdAt TIMESTAMP DEFAULT NOW(),
+    updatedAt TIMESTAMP DEFAULT NOW()
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS gocourse.attachments (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL DEFAULT '',
+    contentType VARCHAR(100) NOT NULL,
+    size INTEGER NOT NULL,
+    data BYTEA NOT NULL,
+    createdAt TIMESTAMP DEFAULT NOW()
+);
+
+-- Answers submitted as an image keep the upload and the grading result
+ALTER TABLE gocourse.quiz_session_questions ADD COLUMN IF NOT EXISTS attachment_id INTEGER REFERENCES gocourse.attachments(id) ON DELETE SET NULL;
+ALTER TABLE gocourse.quiz_session_questions ADD COLUMN IF NOT EXISTS grading JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_attachments_user_id ON gocourse.attachments(user_id);
@@ -0,0 +1,32 @@
+-- Decks can be nested and choose how their due cards are ordered
+ALTER TABLE gocourse.decks ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES gocourse.decks(id) ON DELETE SET NULL;
+ALTER TABLE gocourse.decks ADD COLUMN IF NOT EXISTS reviewOrder VARCHAR(20) NOT NULL DEFAULT 'due';
+
+-- Scheduling state of a flashcard. Cards without a row are new and due now.
+CREATE TABLE IF NOT EXISTS gocourse.flashcard_schedules (
+    flashcard_id INTEGER PRIMARY KEY REFERENCES gocourse.flashcards(id) ON DELETE CASCADE,
+    state VARCHAR(20) NOT NULL DEFAULT 'new',
+    dueAt TIMESTAMP NOT NULL DEFAULT NOW(),
+    intervalDays INTEGER NOT NULL DEFAULT 0,
+    easeFactor REAL NOT NULL DEFAULT 2.5,
+    repetitions INTEGER NOT NULL DEFAULT 0,
+    lapses INTEGER NOT NULL DEFAULT 0,
+    lastReviewedAt TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS gocourse.reviews (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    flashcard_id INTEGER NOT NULL REFERENCES gocourse.flashcards(id) ON DELETE CASCADE,
+    rating VARCHAR(10) NOT NULL,
+    intervalDays INTEGER NOT NULL,
+    easeFactor REAL NOT NULL,
+    dueAt TIMESTAMP NOT NULL,
+    reviewedAt TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_decks_parent_id ON gocourse.decks(parent_id);
+CREATE INDEX IF NOT EXISTS idx_flashcard_schedules_due_at ON gocourse.flashcard_schedules(dueAt);
+CREATE INDEX IF NOT EXISTS idx_reviews_user_id_reviewed_at ON gocourse.reviews(user_id, reviewedAt);
+CREATE INDEX IF NOT EXISTS idx_reviews_flashcard_id ON gocourse.reviews(flashcard_id);
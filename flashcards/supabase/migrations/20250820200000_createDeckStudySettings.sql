@@ -0,0 +1,21 @@
+-- Position of a learning or relearning card on its step ladder
+ALTER TABLE gocourse.flashcard_schedules ADD COLUMN IF NOT EXISTS step INTEGER NOT NULL DEFAULT 0;
+
+-- State the card was in when reviewed, so new cards introduced today can be counted
+ALTER TABLE gocourse.reviews ADD COLUMN IF NOT EXISTS previousState VARCHAR(20) NOT NULL DEFAULT 'review';
+
+-- How a deck introduces new cards and steps them through learning. Decks
+-- without a row use the defaults.
+CREATE TABLE IF NOT EXISTS gocourse.deck_study_settings (
+    deck_id INTEGER PRIMARY KEY REFERENCES gocourse.decks(id) ON DELETE CASCADE,
+    learningSteps TEXT[] NOT NULL DEFAULT '{}',
+    relearningSteps TEXT[] NOT NULL DEFAULT '{}',
+    graduatingIntervalDays INTEGER NOT NULL DEFAULT 1,
+    easyIntervalDays INTEGER NOT NULL DEFAULT 4,
+    newCardsPerDay INTEGER NOT NULL DEFAULT 20,
+    newCardOrder VARCHAR(20) NOT NULL DEFAULT 'sequential',
+    newCardPosition VARCHAR(20) NOT NULL DEFAULT 'after-reviews',
+    updatedAt TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reviews_user_id_previous_state ON gocourse.reviews(user_id, previousState, reviewedAt);
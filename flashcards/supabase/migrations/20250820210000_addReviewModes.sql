@@ -0,0 +1,2 @@
+-- Cram reviews are logged without changing the card's schedule
+ALTER TABLE gocourse.reviews ADD COLUMN IF NOT EXISTS mode VARCHAR(20) NOT NULL DEFAULT 'scheduled';
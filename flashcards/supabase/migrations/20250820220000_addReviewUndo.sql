@@ -0,0 +1,3 @@
+-- Schedule before the review, so the review can be undone. NULL when the
+-- card had never been scheduled.
+ALTER TABLE gocourse.reviews ADD COLUMN IF NOT EXISTS previousSchedule JSONB;
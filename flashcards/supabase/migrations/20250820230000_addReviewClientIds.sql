@@ -0,0 +1,5 @@
+-- ID assigned by the client to reviews done offline, so a batch that is
+-- synced twice is only applied once
+ALTER TABLE gocourse.reviews ADD COLUMN IF NOT EXISTS clientId VARCHAR(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reviews_user_client_id ON gocourse.reviews(user_id, clientId) WHERE clientId IS NOT NULL;
@@ -0,0 +1,25 @@
+-- Organizations group users under a plan tier. Limits left NULL come from
+-- the plan; set, they override it for that organization.
+CREATE TABLE IF NOT EXISTS gocourse.organizations (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    plan VARCHAR(20) NOT NULL DEFAULT 'free',
+    maxNotes INTEGER,
+    maxCards INTEGER,
+    monthlyLlmTokens BIGINT,
+    createdAt TIMESTAMP DEFAULT NOW(),
+    updatedAt TIMESTAMP DEFAULT NOW()
+);
+
+-- Users outside an organization are held to the free plan on their own
+ALTER TABLE gocourse.users ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES gocourse.organizations(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_users_organization_id ON gocourse.users(organization_id);
+
+-- LLM tokens used per user per calendar month (UTC), summed per organization
+CREATE TABLE IF NOT EXISTS gocourse.llm_token_usage (
+    user_id INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    month DATE NOT NULL,
+    tokens BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, month)
+);
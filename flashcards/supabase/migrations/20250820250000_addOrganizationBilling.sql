@@ -0,0 +1,7 @@
+-- Stripe customer and subscription backing an organization's plan on hosted
+-- deployments. subscriptionStatus mirrors Stripe's subscription status.
+ALTER TABLE gocourse.organizations ADD COLUMN IF NOT EXISTS stripeCustomerId VARCHAR(255);
+ALTER TABLE gocourse.organizations ADD COLUMN IF NOT EXISTS stripeSubscriptionId VARCHAR(255);
+ALTER TABLE gocourse.organizations ADD COLUMN IF NOT EXISTS subscriptionStatus VARCHAR(50);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organizations_stripe_customer_id ON gocourse.organizations(stripeCustomerId) WHERE stripeCustomerId IS NOT NULL;
@@ -0,0 +1,19 @@
+-- Role of a user within their organization: owner, admin or member
+ALTER TABLE gocourse.users ADD COLUMN IF NOT EXISTS organizationRole VARCHAR(20);
+
+-- Invites are redeemed with a random token emailed to the invitee; only its
+-- SHA-256 hash is stored
+CREATE TABLE IF NOT EXISTS gocourse.organization_invites (
+    id SERIAL PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES gocourse.organizations(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL DEFAULT 'member',
+    tokenHash VARCHAR(64) NOT NULL UNIQUE,
+    invited_by INTEGER REFERENCES gocourse.users(id) ON DELETE SET NULL,
+    accepted_by INTEGER REFERENCES gocourse.users(id) ON DELETE SET NULL,
+    expiresAt TIMESTAMP NOT NULL,
+    acceptedAt TIMESTAMP,
+    createdAt TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_invites_organization_id ON gocourse.organization_invites(organization_id);
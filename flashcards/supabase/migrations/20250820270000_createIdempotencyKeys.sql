@@ -0,0 +1,15 @@
+-- Responses of requests sent with an Idempotency-Key header, replayed to
+-- retries of the same request until expiresAt. statusCode stays NULL while
+-- the first request is still being handled.
+CREATE TABLE IF NOT EXISTS gocourse.idempotency_keys (
+    user_id INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    idempotencyKey VARCHAR(255) NOT NULL,
+    requestHash VARCHAR(64) NOT NULL,
+    statusCode INTEGER,
+    responseBody BYTEA,
+    createdAt TIMESTAMP DEFAULT NOW(),
+    expiresAt TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, idempotencyKey)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON gocourse.idempotency_keys(expiresAt);
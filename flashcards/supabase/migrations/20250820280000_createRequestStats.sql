@@ -0,0 +1,16 @@
+-- Request counts and latency per hour, route and user. user_id is 0 for
+-- unauthenticated requests, so it has no foreign key.
+CREATE TABLE IF NOT EXISTS gocourse.request_stats (
+    bucket TIMESTAMP NOT NULL,
+    route VARCHAR(255) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    requests INTEGER NOT NULL DEFAULT 0,
+    clientErrors INTEGER NOT NULL DEFAULT 0,
+    serverErrors INTEGER NOT NULL DEFAULT 0,
+    totalLatencyMs BIGINT NOT NULL DEFAULT 0,
+    maxLatencyMs BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (bucket, route, method, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_request_stats_user_id ON gocourse.request_stats(user_id, bucket);
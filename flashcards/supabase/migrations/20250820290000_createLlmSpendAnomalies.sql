@@ -0,0 +1,25 @@
+-- Hourly LLM token usage per user (UTC), the baseline spend anomalies are
+-- measured against
+CREATE TABLE IF NOT EXISTS gocourse.llm_token_usage_hourly (
+    user_id INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    hour TIMESTAMP NOT NULL,
+    tokens BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, hour)
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_token_usage_hourly_hour ON gocourse.llm_token_usage_hourly(hour);
+
+-- Spikes in token usage of a user or organization over its trailing
+-- baseline. subjectId is a user or organization id depending on scope.
+-- While throttledUntil is in the future the subject's LLM calls are refused.
+CREATE TABLE IF NOT EXISTS gocourse.llm_spend_anomalies (
+    id SERIAL PRIMARY KEY,
+    scope VARCHAR(20) NOT NULL,
+    subjectId INTEGER NOT NULL,
+    recentTokens BIGINT NOT NULL,
+    baselineTokensPerHour DOUBLE PRECISION NOT NULL,
+    detectedAt TIMESTAMP DEFAULT NOW(),
+    throttledUntil TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_spend_anomalies_subject ON gocourse.llm_spend_anomalies(scope, subjectId, detectedAt);
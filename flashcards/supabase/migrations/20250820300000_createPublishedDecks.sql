@@ -0,0 +1,35 @@
+-- Decks shared in the public catalog
+CREATE TABLE IF NOT EXISTS gocourse.published_decks (
+    deck_id INTEGER PRIMARY KEY REFERENCES gocourse.decks(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    publishedAt TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_published_decks_published_at ON gocourse.published_decks(publishedAt);
+
+-- Embeddings of the cards of published decks, compared against when
+-- another deck is published
+CREATE TABLE IF NOT EXISTS gocourse.card_embeddings (
+    flashcard_id INTEGER PRIMARY KEY REFERENCES gocourse.flashcards(id) ON DELETE CASCADE,
+    deck_id INTEGER NOT NULL REFERENCES gocourse.published_decks(deck_id) ON DELETE CASCADE,
+    model VARCHAR(100) NOT NULL,
+    embedding DOUBLE PRECISION[] NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_card_embeddings_deck_id ON gocourse.card_embeddings(deck_id);
+
+-- Published decks whose cards nearly duplicate another published deck,
+-- waiting for a curator
+CREATE TABLE IF NOT EXISTS gocourse.deck_similarity_flags (
+    id SERIAL PRIMARY KEY,
+    deck_id INTEGER NOT NULL REFERENCES gocourse.decks(id) ON DELETE CASCADE,
+    matched_deck_id INTEGER NOT NULL REFERENCES gocourse.decks(id) ON DELETE CASCADE,
+    matchedCards INTEGER NOT NULL,
+    totalCards INTEGER NOT NULL,
+    maxSimilarity DOUBLE PRECISION NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    createdAt TIMESTAMP DEFAULT NOW(),
+    reviewedAt TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_deck_similarity_flags_status ON gocourse.deck_similarity_flags(status, createdAt);
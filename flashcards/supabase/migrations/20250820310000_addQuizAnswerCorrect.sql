@@ -0,0 +1,6 @@
+-- Whether an answered quiz question was correct, graded when the answer is
+-- saved so study analytics can aggregate it in SQL. NULL for answers that
+-- cannot be graded, such as essays, and for answers saved before this column.
+ALTER TABLE gocourse.quiz_session_questions ADD COLUMN IF NOT EXISTS correct BOOLEAN;
+
+CREATE INDEX IF NOT EXISTS idx_quiz_session_questions_updated_at ON gocourse.quiz_session_questions(updatedAt);
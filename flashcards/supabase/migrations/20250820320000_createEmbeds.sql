@@ -0,0 +1,14 @@
+-- Public read-only embeds of a single flashcard or a deck preview. Viewers
+-- need only the random token in the embed URL; only its SHA-256 hash is
+-- stored.
+CREATE TABLE IF NOT EXISTS gocourse.embeds (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    flashcard_id INTEGER REFERENCES gocourse.flashcards(id) ON DELETE CASCADE,
+    deck_id INTEGER REFERENCES gocourse.decks(id) ON DELETE CASCADE,
+    tokenHash VARCHAR(64) NOT NULL UNIQUE,
+    createdAt TIMESTAMP DEFAULT NOW(),
+    CHECK ((flashcard_id IS NULL) <> (deck_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_embeds_user_id ON gocourse.embeds(user_id);
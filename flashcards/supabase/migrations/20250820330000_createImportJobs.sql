@@ -0,0 +1,38 @@
+-- Large imports run in the background as jobs. Parsed items are stored up
+-- front and committed a chunk at a time, so a failed job resumes from its
+-- first uncommitted chunk without creating anything twice.
+CREATE TABLE IF NOT EXISTS gocourse.import_jobs (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'running',
+    deck_id INTEGER REFERENCES gocourse.decks(id) ON DELETE SET NULL,
+    chunkSize INTEGER NOT NULL,
+    totalItems INTEGER NOT NULL,
+    processedItems INTEGER NOT NULL DEFAULT 0,
+    committedChunks INTEGER NOT NULL DEFAULT 0,
+    skipped JSONB NOT NULL DEFAULT '[]',
+    error TEXT,
+    createdAt TIMESTAMP DEFAULT NOW(),
+    updatedAt TIMESTAMP DEFAULT NOW(),
+    completedAt TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_jobs_user_id ON gocourse.import_jobs(user_id, createdAt);
+CREATE INDEX IF NOT EXISTS idx_import_jobs_status ON gocourse.import_jobs(status, updatedAt);
+
+-- recordId is the note or flashcard created from the item once its chunk
+-- is committed
+CREATE TABLE IF NOT EXISTS gocourse.import_job_items (
+    job_id INTEGER NOT NULL REFERENCES gocourse.import_jobs(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    chunk INTEGER NOT NULL,
+    file TEXT NOT NULL DEFAULT '',
+    heading TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    committed BOOLEAN NOT NULL DEFAULT FALSE,
+    recordId INTEGER,
+    PRIMARY KEY (job_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_job_items_pending ON gocourse.import_job_items(job_id, chunk) WHERE NOT committed;
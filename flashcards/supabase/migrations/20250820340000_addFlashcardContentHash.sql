@@ -0,0 +1,6 @@
+-- SHA-256 of a flashcard's content, so imports can skip cards the user
+-- already has without loading every card
+ALTER TABLE gocourse.flashcards ADD COLUMN IF NOT EXISTS contentHash TEXT
+    GENERATED ALWAYS AS (encode(sha256(convert_to(content, 'UTF8')), 'hex')) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_flashcards_content_hash ON gocourse.flashcards(user_id, contentHash);
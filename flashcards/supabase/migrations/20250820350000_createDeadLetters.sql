@@ -0,0 +1,22 @@
+-- Background work that failed: Stripe webhooks, catalog similarity checks
+-- and import jobs. Entries are retried with backoff until they succeed or
+-- run out of attempts, when they stay as dead for an admin to look at.
+CREATE TABLE IF NOT EXISTS gocourse.dead_letters (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(50) NOT NULL,
+    key TEXT NOT NULL,
+    user_id INTEGER REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    payload JSONB NOT NULL DEFAULT '{}',
+    error TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    status VARCHAR(20) NOT NULL DEFAULT 'retrying',
+    nextAttemptAt TIMESTAMP,
+    createdAt TIMESTAMP DEFAULT NOW(),
+    updatedAt TIMESTAMP DEFAULT NOW(),
+    resolvedAt TIMESTAMP
+);
+
+-- One open entry per piece of work, so repeated failures update it
+CREATE UNIQUE INDEX IF NOT EXISTS idx_dead_letters_open ON gocourse.dead_letters(kind, key) WHERE status <> 'resolved';
+CREATE INDEX IF NOT EXISTS idx_dead_letters_due ON gocourse.dead_letters(nextAttemptAt) WHERE status = 'retrying';
+CREATE INDEX IF NOT EXISTS idx_dead_letters_created_at ON gocourse.dead_letters(createdAt);
@@ -0,0 +1,10 @@
+-- Versions of the LLM prompt templates edited by admins. The highest
+-- version of a name is the one in use; older ones are kept as history.
+CREATE TABLE IF NOT EXISTS gocourse.prompt_templates (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    version INTEGER NOT NULL,
+    template TEXT NOT NULL,
+    createdAt TIMESTAMP DEFAULT NOW(),
+    UNIQUE (name, version)
+);
@@ -0,0 +1,18 @@
+-- Quiz session requests that could not be served while the LLM provider
+-- was unavailable and the question bank had nothing for the selected notes.
+-- They are generated once the provider is back and the session is linked.
+CREATE TABLE IF NOT EXISTS gocourse.queued_quiz_sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    request JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    session_id INTEGER REFERENCES gocourse.quiz_sessions(id) ON DELETE SET NULL,
+    error TEXT NOT NULL DEFAULT '',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    nextAttemptAt TIMESTAMP DEFAULT NOW(),
+    createdAt TIMESTAMP DEFAULT NOW(),
+    updatedAt TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_queued_quiz_sessions_user_id ON gocourse.queued_quiz_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_queued_quiz_sessions_due ON gocourse.queued_quiz_sessions(nextAttemptAt) WHERE status = 'queued';
@@ -0,0 +1,14 @@
+-- Embeddings of generated question text, compared against new questions on
+-- the same notes so the same question is not generated again and again
+CREATE TABLE IF NOT EXISTS gocourse.question_embeddings (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    noteIds INTEGER[] NOT NULL DEFAULT '{}',
+    questionText TEXT NOT NULL,
+    model VARCHAR(100) NOT NULL,
+    embedding DOUBLE PRECISION[] NOT NULL,
+    createdAt TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_question_embeddings_user_id ON gocourse.question_embeddings(user_id, createdAt);
+CREATE INDEX IF NOT EXISTS idx_question_embeddings_note_ids ON gocourse.question_embeddings USING GIN (noteIds);
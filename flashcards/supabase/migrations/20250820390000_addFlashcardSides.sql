@@ -0,0 +1,20 @@
+-- Flashcards get a separate front and back, an optional hint and the note
+-- they were made from. content stays, holding both sides as
+-- "Q: <front>\nA: <back>" or the front alone, for older clients and the
+-- content hash.
+ALTER TABLE gocourse.flashcards ADD COLUMN IF NOT EXISTS front TEXT NOT NULL DEFAULT '';
+ALTER TABLE gocourse.flashcards ADD COLUMN IF NOT EXISTS back TEXT NOT NULL DEFAULT '';
+ALTER TABLE gocourse.flashcards ADD COLUMN IF NOT EXISTS hint TEXT NOT NULL DEFAULT '';
+ALTER TABLE gocourse.flashcards ADD COLUMN IF NOT EXISTS source_note_id INTEGER REFERENCES gocourse.notes(id) ON DELETE SET NULL;
+
+-- Existing cards are split the way they were shown in reviews: content
+-- starting "Q:" with a line starting "A:" is split there, anything else is
+-- all front
+UPDATE gocourse.flashcards
+SET front = btrim(substring(content FROM 3 FOR strpos(substring(content FROM 3), E'\nA:') - 1), E' \t\r\n'),
+    back = btrim(substring(content FROM 3 + strpos(substring(content FROM 3), E'\nA:') + 2), E' \t\r\n')
+WHERE front = '' AND content LIKE 'Q:%' AND strpos(substring(content FROM 3), E'\nA:') > 0;
+
+UPDATE gocourse.flashcards SET front = btrim(content, E' \t\r\n') WHERE front = '';
+
+CREATE INDEX IF NOT EXISTS idx_flashcards_source_note ON gocourse.flashcards(source_note_id);
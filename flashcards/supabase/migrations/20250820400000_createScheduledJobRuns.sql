@@ -0,0 +1,7 @@
+-- Last run of each scheduled job, claimed by one instance per interval so
+-- jobs run once across all instances rather than once on each
+CREATE TABLE IF NOT EXISTS gocourse.scheduled_job_runs (
+    name VARCHAR(100) PRIMARY KEY,
+    claimedBy VARCHAR(255) NOT NULL,
+    claimedAt TIMESTAMP NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+-- Notes get a title, an LLM-written summary and the source they came from
+ALTER TABLE gocourse.notes ADD COLUMN IF NOT EXISTS title VARCHAR(200) NOT NULL DEFAULT '';
+ALTER TABLE gocourse.notes ADD COLUMN IF NOT EXISTS summary TEXT NOT NULL DEFAULT '';
+ALTER TABLE gocourse.notes ADD COLUMN IF NOT EXISTS source VARCHAR(500) NOT NULL DEFAULT '';
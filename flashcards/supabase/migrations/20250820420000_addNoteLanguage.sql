@@ -0,0 +1,4 @@
+-- ISO 639-1 code of each note's language, detected from its content
+ALTER TABLE gocourse.notes ADD COLUMN IF NOT EXISTS language VARCHAR(2) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_notes_user_language ON gocourse.notes(user_id, language);
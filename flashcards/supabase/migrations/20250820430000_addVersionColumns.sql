@@ -0,0 +1,4 @@
+-- Row versions for optimistic concurrency: each update bumps the version,
+-- and an update naming a version other than the current one is refused
+ALTER TABLE gocourse.notes ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE gocourse.flashcards ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,16 @@
+-- Maintenance suggestions found by the weekly deck analysis. Open ones are
+-- replaced on every run; applied and dismissed ones are kept, so a
+-- dismissed suggestion is not made again for the same cards.
+CREATE TABLE IF NOT EXISTS gocourse.deck_suggestions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    deck_id INTEGER NOT NULL REFERENCES gocourse.decks(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    flashcard_ids INTEGER[] NOT NULL,
+    note_ids INTEGER[] NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    createdAt TIMESTAMP DEFAULT NOW(),
+    resolvedAt TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_deck_suggestions_deck_id ON gocourse.deck_suggestions(deck_id, status);
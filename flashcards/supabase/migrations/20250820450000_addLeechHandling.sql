@@ -0,0 +1,10 @@
+-- Leeches are cards lapsed leechThreshold times, and again every half
+-- threshold after that. The deck's leechAction decides whether a leech is
+-- only marked or also suspended, which keeps it out of the review queues.
+ALTER TABLE gocourse.flashcard_schedules ADD COLUMN IF NOT EXISTS leech BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE gocourse.flashcard_schedules ADD COLUMN IF NOT EXISTS suspended BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE gocourse.deck_study_settings ADD COLUMN IF NOT EXISTS leechThreshold INTEGER NOT NULL DEFAULT 8;
+ALTER TABLE gocourse.deck_study_settings ADD COLUMN IF NOT EXISTS leechAction VARCHAR(20) NOT NULL DEFAULT 'tag';
+
+CREATE INDEX IF NOT EXISTS idx_flashcard_schedules_leech ON gocourse.flashcard_schedules(flashcard_id) WHERE leech;
@@ -0,0 +1,3 @@
+-- Memory aids generated for a card, shown when reviewing it once it has
+-- proven hard to remember
+ALTER TABLE gocourse.flashcards ADD COLUMN IF NOT EXISTS mnemonic TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,14 @@
+-- Decks a user gets one quiz question a day by email from. The question is
+-- asked in a one-question quiz session, so the answer is recorded like any
+-- other attempt.
+CREATE TABLE IF NOT EXISTS gocourse.daily_question_subscriptions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    deck_id INTEGER NOT NULL REFERENCES gocourse.decks(id) ON DELETE CASCADE,
+    last_session_id INTEGER REFERENCES gocourse.quiz_sessions(id) ON DELETE SET NULL,
+    lastSentAt TIMESTAMP,
+    createdAt TIMESTAMP DEFAULT NOW(),
+    UNIQUE (user_id, deck_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_daily_question_subscriptions_last_sent_at ON gocourse.daily_question_subscriptions(lastSentAt);
@@ -0,0 +1,22 @@
+-- Expensive LLM work run in the background: bulk flashcard generation,
+-- note summaries and catalog re-embedding. Queued and running jobs hold a
+-- lease in nextAttemptAt; a job whose worker died is claimed again once it
+-- runs out.
+CREATE TABLE IF NOT EXISTS gocourse.jobs (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    kind VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    result JSONB,
+    error TEXT NOT NULL DEFAULT '',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    nextAttemptAt TIMESTAMP DEFAULT NOW(),
+    createdAt TIMESTAMP DEFAULT NOW(),
+    updatedAt TIMESTAMP DEFAULT NOW(),
+    startedAt TIMESTAMP,
+    completedAt TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_due ON gocourse.jobs(nextAttemptAt) WHERE status IN ('queued', 'running');
+CREATE INDEX IF NOT EXISTS idx_jobs_user_id ON gocourse.jobs(user_id, createdAt);
@@ -0,0 +1,34 @@
+-- Webhooks POST signed event payloads to a user's URL. The secret signs
+-- each payload, so it is kept as given rather than hashed.
+CREATE TABLE IF NOT EXISTS gocourse.webhooks (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret VARCHAR(100) NOT NULL,
+    events TEXT[] NOT NULL DEFAULT '{}',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    createdAt TIMESTAMP DEFAULT NOW(),
+    updatedAt TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON gocourse.webhooks(user_id);
+
+-- One row per event sent to a webhook, both the delivery queue and its log.
+-- Pending deliveries hold a lease in nextAttemptAt while they are sent.
+CREATE TABLE IF NOT EXISTS gocourse.webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    webhook_id INTEGER NOT NULL REFERENCES gocourse.webhooks(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    responseStatus INTEGER,
+    error TEXT NOT NULL DEFAULT '',
+    nextAttemptAt TIMESTAMP DEFAULT NOW(),
+    createdAt TIMESTAMP DEFAULT NOW(),
+    updatedAt TIMESTAMP DEFAULT NOW(),
+    deliveredAt TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON gocourse.webhook_deliveries(nextAttemptAt) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON gocourse.webhook_deliveries(webhook_id, createdAt);
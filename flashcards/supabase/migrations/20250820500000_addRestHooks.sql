@@ -0,0 +1,4 @@
+-- REST hooks subscribed by automation platforms such as Zapier share the
+-- webhook table and delivery queue. They are sent the trigger payload
+-- alone rather than the event envelope.
+ALTER TABLE gocourse.webhooks ADD COLUMN IF NOT EXISTS kind VARCHAR(20) NOT NULL DEFAULT 'webhook';
@@ -0,0 +1,25 @@
+-- Attachments kept in object storage hold their key instead of their data,
+-- and stay pending until a direct upload is completed
+ALTER TABLE gocourse.attachments ALTER COLUMN data DROP NOT NULL;
+ALTER TABLE gocourse.attachments ADD COLUMN IF NOT EXISTS storageKey VARCHAR(255);
+ALTER TABLE gocourse.attachments ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'ready';
+
+CREATE INDEX IF NOT EXISTS idx_attachments_pending ON gocourse.attachments(createdAt) WHERE status = 'pending';
+
+-- Deck exports written to object storage, deleted with their objects once
+-- they expire
+CREATE TABLE IF NOT EXISTS gocourse.deck_exports (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    deck_id INTEGER REFERENCES gocourse.decks(id) ON DELETE SET NULL,
+    format VARCHAR(10) NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    contentType VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    storageKey VARCHAR(255) NOT NULL,
+    expiresAt TIMESTAMP NOT NULL,
+    createdAt TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_deck_exports_user_id ON gocourse.deck_exports(user_id);
+CREATE INDEX IF NOT EXISTS idx_deck_exports_expires_at ON gocourse.deck_exports(expiresAt);
@@ -0,0 +1,24 @@
+-- API keys authenticate programmatic clients in place of a login token.
+-- Only a hash of each key is kept; prefix identifies it in listings.
+CREATE TABLE IF NOT EXISTS gocourse.api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    keyHash CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    requests BIGINT NOT NULL DEFAULT 0,
+    lastUsedAt TIMESTAMP,
+    createdAt TIMESTAMP DEFAULT NOW(),
+    revokedAt TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON gocourse.api_keys(user_id);
+
+-- Requests made with each key, per day
+CREATE TABLE IF NOT EXISTS gocourse.api_key_usage (
+    api_key_id INTEGER NOT NULL REFERENCES gocourse.api_keys(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (api_key_id, day)
+);
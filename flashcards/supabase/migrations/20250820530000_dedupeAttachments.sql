@@ -0,0 +1,33 @@
+-- Attachment contents are stored once per SHA-256 hash and shared by every
+-- attachment with that content. refCount counts the attachments referencing
+-- a blob; one released to zero is deleted, with its object, once it has
+-- stayed unreferenced for a while.
+CREATE TABLE IF NOT EXISTS gocourse.attachment_blobs (
+    hash CHAR(64) PRIMARY KEY,
+    contentType VARCHAR(100) NOT NULL,
+    size INTEGER NOT NULL,
+    data BYTEA,
+    storageKey VARCHAR(255),
+    refCount INTEGER NOT NULL DEFAULT 1,
+    releasedAt TIMESTAMP,
+    createdAt TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachment_blobs_released ON gocourse.attachment_blobs(releasedAt) WHERE refCount = 0;
+
+ALTER TABLE gocourse.attachments ADD COLUMN IF NOT EXISTS contentHash CHAR(64) REFERENCES gocourse.attachment_blobs(hash);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_content_hash ON gocourse.attachments(contentHash);
+
+-- Attachments kept in Postgres move their data into shared blobs. Those in
+-- object storage keep their own object, since it can't be hashed here.
+INSERT INTO gocourse.attachment_blobs (hash, contentType, size, data, refCount)
+SELECT encode(sha256(data), 'hex'), MIN(contentType), MIN(size), (array_agg(data))[1], COUNT(*)
+FROM gocourse.attachments
+WHERE data IS NOT NULL AND storageKey IS NULL
+GROUP BY encode(sha256(data), 'hex')
+ON CONFLICT (hash) DO NOTHING;
+
+UPDATE gocourse.attachments
+SET contentHash = encode(sha256(data), 'hex'), data = NULL
+WHERE data IS NOT NULL AND storageKey IS NULL;
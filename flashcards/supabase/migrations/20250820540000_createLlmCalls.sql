@@ -0,0 +1,14 @@
+-- One row per metered LLM call, with the model that served it, its tokens
+-- and its estimated cost in USD
+CREATE TABLE IF NOT EXISTS gocourse.llm_calls (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES gocourse.users(id) ON DELETE CASCADE,
+    model VARCHAR(100) NOT NULL,
+    inputTokens INTEGER NOT NULL,
+    outputTokens INTEGER NOT NULL,
+    costUsd NUMERIC(14, 8) NOT NULL,
+    createdAt TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_calls_user_created ON gocourse.llm_calls(user_id, createdAt);
+CREATE INDEX IF NOT EXISTS idx_llm_calls_created ON gocourse.llm_calls(createdAt);
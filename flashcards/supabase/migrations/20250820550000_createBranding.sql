@@ -0,0 +1,13 @@
+-- A single row holding how this deployment presents itself to clients
+CREATE TABLE IF NOT EXISTS gocourse.branding (
+    id INTEGER PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    name VARCHAR(100) NOT NULL DEFAULT 'Flashcards',
+    logoUrl VARCHAR(2048) NOT NULL DEFAULT '',
+    primaryColor VARCHAR(7) NOT NULL DEFAULT '#2563eb',
+    accentColor VARCHAR(7) NOT NULL DEFAULT '#f59e0b',
+    supportEmail VARCHAR(255) NOT NULL DEFAULT '',
+    supportUrl VARCHAR(2048) NOT NULL DEFAULT '',
+    updatedAt TIMESTAMP DEFAULT NOW()
+);
+
+INSERT INTO gocourse.branding (id) VALUES (1) ON CONFLICT DO NOTHING;
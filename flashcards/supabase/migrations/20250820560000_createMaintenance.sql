@@ -0,0 +1,12 @@
+-- A single row holding whether the API is in maintenance. mode is off,
+-- read-only (writes are refused) or full (everything is refused).
+CREATE TABLE IF NOT EXISTS gocourse.maintenance (
+    id INTEGER PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    mode VARCHAR(20) NOT NULL DEFAULT 'off',
+    message TEXT NOT NULL DEFAULT '',
+    startedAt TIMESTAMP,
+    endsAt TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT NOW()
+);
+
+INSERT INTO gocourse.maintenance (id) VALUES (1) ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+-- Admins can use the /admin endpoints: routing rules, prompts, quotas and
+-- the rest of the server's configuration. Granted with
+-- flashcards-cli users grant-admin EMAIL.
+ALTER TABLE gocourse.users ADD COLUMN IF NOT EXISTS isAdmin BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,7 @@
+-- Routing rules can match one organization or every user on a plan, and
+-- send the request to another provider than LLM_PROVIDER. An empty plan or
+-- provider matches or keeps any.
+ALTER TABLE gocourse.model_routing_rules
+    ADD COLUMN IF NOT EXISTS organizationId INTEGER REFERENCES gocourse.organizations(id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS plan VARCHAR(20) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS provider VARCHAR(20) NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+-- Stripe events that were applied, so redelivered events are skipped, and
+-- when the last one applied to each organization was created, so an event
+-- delivered out of order cannot undo a newer one.
+CREATE TABLE IF NOT EXISTS gocourse.stripe_events (
+    id VARCHAR(255) PRIMARY KEY,
+    processedAt TIMESTAMP DEFAULT NOW()
+);
+
+ALTER TABLE gocourse.organizations
+    ADD COLUMN IF NOT EXISTS stripeEventAt TIMESTAMP;
@@ -0,0 +1,85 @@
+//go:build integration
+
+// Package testcontainer starts throwaway Docker containers, such as
+// Postgres and Redis, for the integration tests. It drives the docker CLI,
+// so it needs Docker on the PATH and nothing in go.mod. It is only built
+// with the integration tag:
+//
+//	go test -tags integration ./db/ ./services/
+package testcontainer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// How often Ready checks a container again
+const READY_POLL_INTERVAL = 250 * time.Millisecond
+
+// Container is a running container with one port published on localhost
+type Container struct {
+	ID string
+	// Addr is the host:port the container's port is published on
+	Addr string
+}
+
+// Start runs image detached with port published on a free localhost port.
+// env holds KEY=value pairs for the container. Close removes it.
+func Start(ctx context.Context, image, port string, env ...string) (*Container, error) {
+	args := []string{"run", "--detach", "--rm", "--publish", "127.0.0.1::" + port}
+	for _, value := range env {
+		args = append(args, "--env", value)
+	}
+	id, err := docker(ctx, append(args, image)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", image, err)
+	}
+
+	container := &Container{ID: id}
+	addr, err := docker(ctx, "port", id, port+"/tcp")
+	if err != nil {
+		container.Close()
+		return nil, fmt.Errorf("failed to find the port of %s: %w", image, err)
+	}
+	// Docker lists an address per IP family; the first is the IPv4 one
+	container.Addr, _, _ = strings.Cut(addr, "\n")
+	return container, nil
+}
+
+// Ready calls check until it succeeds or ctx is done, for services that
+// accept connections a while after their container starts
+func (c *Container) Ready(ctx context.Context, check func(ctx context.Context) error) error {
+	for {
+		err := check(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			logs, _ := docker(context.Background(), "logs", "--tail", "20", c.ID)
+			return fmt.Errorf("container %s not ready: %w\n%s", c.ID, err, logs)
+		case <-time.After(READY_POLL_INTERVAL):
+		}
+	}
+}
+
+// Close stops and removes the container
+func (c *Container) Close() error {
+	_, err := docker(context.Background(), "rm", "--force", "--volumes", c.ID)
+	return err
+}
+
+func docker(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("docker %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}